@@ -36,20 +36,27 @@ require (
 	github.com/google/uuid v1.1.1
 	github.com/hashicorp/go-multierror v1.0.0
 	github.com/influxdata/tdigest v0.0.1
+	github.com/ishidawataru/sctp v0.0.0-20191218070446-00ab2ac2db07
+	github.com/klauspost/compress v1.10.11
 	github.com/labstack/echo v3.3.10+incompatible
 	github.com/lib/pq v1.2.0
 	github.com/olivere/elastic/v7 v7.0.6
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v1.5.1
 	github.com/prometheus/common v0.9.1
+	github.com/segmentio/kafka-go v0.3.5
 	github.com/stretchr/testify v1.6.1
 	github.com/thoas/go-funk v0.7.0
 	github.com/warthog618/sms v0.3.0
+	go.opentelemetry.io/otel v0.16.0
+	go.opentelemetry.io/otel/sdk v0.16.0
 	golang.org/x/net v0.0.0-20201031054903-ff519b6c9102
 	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55
 	google.golang.org/grpc v1.31.0
 	gopkg.in/DATA-DOG/go-sqlmock.v1 v1.3.0
+	gopkg.in/yaml.v2 v2.4.0
 	magma/feg/cloud/go v0.0.0
+	magma/feg/cloud/go/protos v0.0.0
 	magma/orc8r/cloud/go v0.0.0
 	magma/orc8r/lib/go v0.0.0
 	magma/orc8r/lib/go/protos v0.0.0
@@ -61,6 +61,7 @@ const (
 	// in configurator.
 	CellularNetworkConfigType   = "cellular_network"
 	NetworkSubscriberConfigType = "network_subscriber_config"
+	NetworkProbeConfigType      = "network_probe_config"
 
 	// APNEntityType etc. are configurator network entity types.
 	APNEntityType                     = "apn"
@@ -78,12 +79,13 @@ const (
 	NetworkProbeDestinationEntityType = "network_probe_destination"
 
 	// ApnRuleMappingsStreamName etc. are streamer stream names.
-	ApnRuleMappingsStreamName  = "apn_rule_mappings"
-	BaseNameStreamName         = "base_names"
-	NetworkWideRulesStreamName = "network_wide_rules"
-	PolicyStreamName           = "policydb"
-	RatingGroupStreamName      = "rating_groups"
-	SubscriberStreamName       = "subscriberdb"
+	ApnRuleMappingsStreamName     = "apn_rule_mappings"
+	BaseNameStreamName            = "base_names"
+	NetworkProbeTargetsStreamName = "nprobe_targets"
+	NetworkWideRulesStreamName    = "network_wide_rules"
+	PolicyStreamName              = "policydb"
+	RatingGroupStreamName         = "rating_groups"
+	SubscriberStreamName          = "subscriberdb"
 
 	// EnodebStateType etc. denote types of state replicated from AGWs.
 	EnodebStateType     = "single_enodeb"
@@ -29,7 +29,8 @@ var (
 	// used in the LTE module
 	Network = serdes.Network.
 		MustMerge(lte_models.NetworkSerdes).
-		MustMerge(policydb_models.NetworkSerdes)
+		MustMerge(policydb_models.NetworkSerdes).
+		MustMerge(nprobe_models.NetworkSerdes)
 	// Entity contains the full set of configurator network entity serdes used
 	// in the LTE module
 	Entity = serdes.Entity.
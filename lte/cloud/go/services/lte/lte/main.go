@@ -21,6 +21,9 @@ import (
 	lte_protos "magma/lte/cloud/go/services/lte/protos"
 	"magma/lte/cloud/go/services/lte/servicers"
 	lte_storage "magma/lte/cloud/go/services/lte/storage"
+	"magma/lte/cloud/go/services/nprobe"
+	nprobe_storage "magma/lte/cloud/go/services/nprobe/storage"
+	"magma/orc8r/cloud/go/blobstore"
 	"magma/orc8r/cloud/go/obsidian"
 	"magma/orc8r/cloud/go/obsidian/swagger"
 	swagger_protos "magma/orc8r/cloud/go/obsidian/swagger/protos"
@@ -46,17 +49,23 @@ func main() {
 
 	obsidian.AttachHandlers(srv.EchoServer, handlers.GetHandlers())
 
-	builder_protos.RegisterMconfigBuilderServer(srv.GrpcServer, servicers.NewBuilderServicer())
-	provider_protos.RegisterStreamProviderServer(srv.GrpcServer, servicers.NewProviderServicer())
-	state_protos.RegisterIndexerServer(srv.GrpcServer, servicers.NewIndexerServicer())
-
-	swagger_protos.RegisterSwaggerSpecServer(srv.GrpcServer, swagger.NewSpecServicerFromFile(lte_service.ServiceName))
-
 	// Init storage
 	db, err := sqorc.Open(storage.GetSQLDriver(), storage.GetDatabaseSource())
 	if err != nil {
 		glog.Fatalf("Error opening db connection: %v", err)
 	}
+	// the kill switch of the nprobe tasks is shared with the nprobe service
+	nprobeFact := blobstore.NewSQLBlobStorageFactory(nprobe.NProbeTableBlobstore, db, sqorc.GetSqlBuilder())
+	if err := nprobeFact.InitializeFactory(); err != nil {
+		glog.Fatalf("Error initializing nprobe blobstore: %v", err)
+	}
+
+	builder_protos.RegisterMconfigBuilderServer(srv.GrpcServer, servicers.NewBuilderServicer(nprobe_storage.NewNProbeBlobstore(nprobeFact)))
+	provider_protos.RegisterStreamProviderServer(srv.GrpcServer, servicers.NewProviderServicer())
+	state_protos.RegisterIndexerServer(srv.GrpcServer, servicers.NewIndexerServicer())
+
+	swagger_protos.RegisterSwaggerSpecServer(srv.GrpcServer, swagger.NewSpecServicerFromFile(lte_service.ServiceName))
+
 	enbStateStore := lte_storage.NewEnodebStateLookup(db, sqorc.GetSqlBuilder())
 	if err := enbStateStore.Initialize(); err != nil {
 		glog.Fatalf("Error initializing enodeb state lookup storage: %v", err)
@@ -17,6 +17,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	feg "magma/feg/cloud/go/feg"
 	feg_serdes "magma/feg/cloud/go/serdes"
@@ -26,6 +27,7 @@ import (
 	"magma/lte/cloud/go/serdes"
 	lte_models "magma/lte/cloud/go/services/lte/obsidian/models"
 	nprobe_models "magma/lte/cloud/go/services/nprobe/obsidian/models"
+	nprobe_storage "magma/lte/cloud/go/services/nprobe/storage"
 	"magma/orc8r/cloud/go/orc8r"
 	"magma/orc8r/cloud/go/services/configurator"
 	"magma/orc8r/cloud/go/services/configurator/mconfig"
@@ -43,10 +45,14 @@ import (
 	"github.com/thoas/go-funk"
 )
 
-type builderServicer struct{}
+type builderServicer struct {
+	// nprobeStorage holds the kill switch of the networks and the pause
+	// state of their nprobe tasks
+	nprobeStorage nprobe_storage.NProbeStorage
+}
 
-func NewBuilderServicer() builder_protos.MconfigBuilderServer {
-	return &builderServicer{}
+func NewBuilderServicer(nprobeStorage nprobe_storage.NProbeStorage) builder_protos.MconfigBuilderServer {
+	return &builderServicer{nprobeStorage: nprobeStorage}
 }
 
 func (s *builderServicer) Build(ctx context.Context, request *builder_protos.BuildRequest) (*builder_protos.BuildResponse, error) {
@@ -109,7 +115,7 @@ func (s *builderServicer) Build(ctx context.Context, request *builder_protos.Bui
 
 	enbConfigsBySerial := getEnodebConfigsBySerial(cellularNwConfig, cellularGwConfig, enodebs)
 	heConfig := getHEConfig(cellularGwConfig.HeConfig)
-	npTasks, liUes := getNetworkProbeConfig(network.ID)
+	npTasks, liUes := s.getNetworkProbeConfig(network.ID)
 
 	mmePoolRecord, mmeGroupID, err := getMMEPoolConfigs(network.ID, cellularGwConfig.Pooling, cellGW, graph)
 	if err != nil {
@@ -581,9 +587,21 @@ func getRestrictedImeis(imeis []*lte_models.Imei) []*lte_mconfig.MME_ImeiConfig
 	return ret
 }
 
-func getNetworkProbeConfig(networkID string) ([]*lte_mconfig.NProbeTask, *lte_mconfig.PipelineD_LiUes) {
+// getNetworkProbeConfig returns the nprobe tasks of a network and the UEs
+// they intercept, none while the kill switch of the network is active. The
+// tasks out of their warrant are left out and the UEs of the paused tasks
+// aren't intercepted.
+func (s *builderServicer) getNetworkProbeConfig(networkID string) ([]*lte_mconfig.NProbeTask, *lte_mconfig.PipelineD_LiUes) {
 	liUes := &lte_mconfig.PipelineD_LiUes{}
 	npTasks := []*lte_mconfig.NProbeTask{}
+	killSwitch, err := s.nprobeStorage.GetKillSwitch(networkID)
+	if err != nil {
+		glog.Errorf("Failed to get nprobe kill switch %v", err)
+		return npTasks, liUes
+	}
+	if killSwitch.Active {
+		return npTasks, liUes
+	}
 	ents, _, err := configurator.LoadAllEntitiesOfType(
 		networkID,
 		lte.NetworkProbeTaskEntityType,
@@ -595,9 +613,26 @@ func getNetworkProbeConfig(networkID string) ([]*lte_mconfig.NProbeTask, *lte_mc
 		return npTasks, liUes
 	}
 
+	now := time.Now()
 	for _, ent := range ents {
 		task := (&nprobe_models.NetworkProbeTask{}).FromBackendModels(ent)
+		// one-shot tasks are reported from the events already logged
+		if task.TaskDetails.OneShot {
+			continue
+		}
+		// tasks out of their warrant are not intercepted
+		if !nprobe_models.IsWithinWarrant(task.TaskDetails, now) {
+			continue
+		}
 		npTasks = append(npTasks, nprobe_models.ToMConfigNProbeTask(task))
+		pause, err := s.nprobeStorage.GetTaskPause(networkID, string(task.TaskID))
+		if err != nil {
+			glog.Errorf("Failed to get pause state of nprobe task %s %v", task.TaskID, err)
+			continue
+		}
+		if pause.Paused {
+			continue
+		}
 
 		switch task.TaskDetails.TargetType {
 		case nprobe_models.NetworkProbeTaskDetailsTargetTypeImsi:
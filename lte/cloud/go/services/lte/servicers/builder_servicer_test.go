@@ -15,6 +15,7 @@ package servicers_test
 
 import (
 	"testing"
+	"time"
 
 	"magma/feg/cloud/go/feg"
 	feg_serdes "magma/feg/cloud/go/serdes"
@@ -25,12 +26,16 @@ import (
 	lte_service "magma/lte/cloud/go/services/lte"
 	lte_models "magma/lte/cloud/go/services/lte/obsidian/models"
 	lte_test_init "magma/lte/cloud/go/services/lte/test_init"
+	nprobe_models "magma/lte/cloud/go/services/nprobe/obsidian/models"
+	nprobe_storage "magma/lte/cloud/go/services/nprobe/storage"
 	"magma/orc8r/cloud/go/orc8r"
 	"magma/orc8r/cloud/go/services/configurator"
 	"magma/orc8r/cloud/go/services/configurator/mconfig"
 	storage_configurator "magma/orc8r/cloud/go/services/configurator/storage"
+	configurator_test_init "magma/orc8r/cloud/go/services/configurator/test_init"
 	"magma/orc8r/cloud/go/services/orchestrator/obsidian/models"
 	"magma/orc8r/cloud/go/storage"
+	"magma/orc8r/cloud/go/test_utils"
 	"magma/orc8r/lib/go/protos"
 
 	"github.com/go-openapi/strfmt"
@@ -1359,6 +1364,74 @@ func TestBuilder_Build_MMEPool(t *testing.T) {
 }
 
 // buildLTEFederated builds a Federated_LTE network that comes from swagger feg_lte_network model
+func TestBuilder_Build_NetworkProbe(t *testing.T) {
+	configurator_test_init.StartTestService(t)
+	nprobeStorage := nprobe_storage.NewNProbeBlobstore(test_utils.NewSQLBlobstore(t, "lte_builder_nprobe_test_blobstore"))
+	lte_test_init.StartTestServiceInternal(t, nprobeStorage)
+
+	nw := configurator.Network{
+		ID: "n1",
+		Configs: map[string]interface{}{
+			lte.CellularNetworkConfigType: lte_models.NewDefaultTDDNetworkConfig(),
+		},
+	}
+	assert.NoError(t, configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network))
+	expired := newNetworkProbeTask("task5", "IMSI001010000000005", nprobe_models.NetworkProbeTaskDetailsTargetTypeImsi)
+	expired.Config.(*nprobe_models.NetworkProbeTaskDetails).EndTime = strfmt.DateTime(time.Now().Add(-time.Hour))
+	pending := newNetworkProbeTask("task6", "IMSI001010000000006", nprobe_models.NetworkProbeTaskDetailsTargetTypeImsi)
+	pending.Config.(*nprobe_models.NetworkProbeTaskDetails).StartTime = strfmt.DateTime(time.Now().Add(time.Hour))
+	oneShot := newNetworkProbeTask("task7", "IMSI001010000000007", nprobe_models.NetworkProbeTaskDetailsTargetTypeImsi)
+	oneShot.Config.(*nprobe_models.NetworkProbeTaskDetails).OneShot = true
+	_, err := configurator.CreateEntities("n1", []configurator.NetworkEntity{
+		newNetworkProbeTask("task1", "IMSI001010000000001", nprobe_models.NetworkProbeTaskDetailsTargetTypeImsi),
+		newNetworkProbeTask("task2", "356938035643809", nprobe_models.NetworkProbeTaskDetailsTargetTypeImei),
+		newNetworkProbeTask("task3", "33612345678", nprobe_models.NetworkProbeTaskDetailsTargetTypeMsisdn),
+		newNetworkProbeTask("task4", "IMSI001010000000004", nprobe_models.NetworkProbeTaskDetailsTargetTypeImsi),
+		expired,
+		pending,
+		oneShot,
+	}, serdes.Entity)
+	assert.NoError(t, err)
+	assert.NoError(t, nprobeStorage.StoreTaskPause("n1", "task4", nprobe_models.NetworkProbeTaskPause{Paused: true}))
+
+	gw := configurator.NetworkEntity{
+		Type: orc8r.MagmadGatewayType, Key: "gw1",
+		Associations: []storage.TypeAndKey{
+			{Type: lte.CellularGatewayEntityType, Key: "gw1"},
+		},
+	}
+	lteGW := configurator.NetworkEntity{
+		Type: lte.CellularGatewayEntityType, Key: "gw1",
+		Config:             newDefaultGatewayConfig(),
+		ParentAssociations: []storage.TypeAndKey{gw.GetTypeAndKey()},
+	}
+	graph := configurator.EntityGraph{
+		Entities: []configurator.NetworkEntity{lteGW, gw},
+		Edges: []configurator.GraphEdge{
+			{From: gw.GetTypeAndKey(), To: lteGW.GetTypeAndKey()},
+		},
+	}
+
+	// the one-shot tasks and the tasks out of their warrant are left out and
+	// the UEs of the paused tasks aren't intercepted
+	actual, err := buildNonFederated(&nw, &graph, "gw1")
+	assert.NoError(t, err)
+	expectedLiUes := &lte_mconfig.PipelineD_LiUes{
+		Imsis:   []string{"IMSI001010000000001"},
+		Imeis:   []string{"356938035643809"},
+		Msisdns: []string{"33612345678"},
+	}
+	assert.Equal(t, expectedLiUes, actual["pipelined"].(*lte_mconfig.PipelineD).LiUes)
+	assert.Len(t, actual["liagentd"].(*lte_mconfig.LIAgentD).NprobeTasks, 4)
+
+	// nothing is intercepted while the kill switch of the network is active
+	assert.NoError(t, nprobeStorage.StoreKillSwitch("n1", nprobe_models.NetworkProbeKillSwitch{Active: true}))
+	actual, err = buildNonFederated(&nw, &graph, "gw1")
+	assert.NoError(t, err)
+	assert.Equal(t, &lte_mconfig.PipelineD_LiUes{}, actual["pipelined"].(*lte_mconfig.PipelineD).LiUes)
+	assert.Empty(t, actual["liagentd"].(*lte_mconfig.LIAgentD).NprobeTasks)
+}
+
 func buildLTEFederated(network *configurator.Network, graph *configurator.EntityGraph, gatewayID string) (map[string]proto.Message, error) {
 	// use federated serded (this is still an LTE network)
 	networkProto, err := network.ToProto(feg_serdes.Network)
@@ -1397,6 +1470,18 @@ func buildImpl(networkProto *storage_configurator.Network, graph *configurator.E
 	return configs, nil
 }
 
+func newNetworkProbeTask(taskID, targetID, targetType string) configurator.NetworkEntity {
+	return configurator.NetworkEntity{
+		Type: lte.NetworkProbeTaskEntityType,
+		Key:  taskID,
+		Config: &nprobe_models.NetworkProbeTaskDetails{
+			TargetID:     targetID,
+			TargetType:   targetType,
+			DeliveryType: "all",
+		},
+	}
+}
+
 func newDefaultGatewayConfig() *lte_models.GatewayCellularConfigs {
 	return &lte_models.GatewayCellularConfigs{
 		Ran: &lte_models.GatewayRanConfigs{
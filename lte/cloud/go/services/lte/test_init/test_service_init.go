@@ -22,6 +22,7 @@ import (
 	lte_protos "magma/lte/cloud/go/services/lte/protos"
 	"magma/lte/cloud/go/services/lte/servicers"
 	"magma/lte/cloud/go/services/lte/storage"
+	nprobe_storage "magma/lte/cloud/go/services/nprobe/storage"
 	"magma/orc8r/cloud/go/orc8r"
 	builder_protos "magma/orc8r/cloud/go/services/configurator/mconfig/protos"
 	state_protos "magma/orc8r/cloud/go/services/state/protos"
@@ -33,6 +34,13 @@ import (
 )
 
 func StartTestService(t *testing.T) {
+	nprobeStorage := nprobe_storage.NewNProbeBlobstore(test_utils.NewSQLBlobstore(t, "lte_nprobe_test_blobstore"))
+	StartTestServiceInternal(t, nprobeStorage)
+}
+
+// StartTestServiceInternal starts the service with the storage holding the
+// kill switch and the pause state of the nprobe tasks
+func StartTestServiceInternal(t *testing.T, nprobeStorage nprobe_storage.NProbeStorage) {
 	streams := []string{
 		lte.SubscriberStreamName,
 		lte.PolicyStreamName,
@@ -50,7 +58,7 @@ func StartTestService(t *testing.T) {
 	}
 
 	srv, lis := test_utils.NewTestOrchestratorService(t, lte.ModuleName, lte_service.ServiceName, labels, annotations)
-	builder_protos.RegisterMconfigBuilderServer(srv.GrpcServer, servicers.NewBuilderServicer())
+	builder_protos.RegisterMconfigBuilderServer(srv.GrpcServer, servicers.NewBuilderServicer(nprobeStorage))
 	provider_protos.RegisterStreamProviderServer(srv.GrpcServer, servicers.NewProviderServicer())
 
 	// Init storage
@@ -0,0 +1,389 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package alert raises the operational alerts of the service, e.g. a task
+// activated or a delivery failure. Alerts are logged and, when a syslog
+// endpoint is configured, forwarded to it in CEF or text, since LI operations
+// teams monitor them through their SIEM rather than through orc8r metrics.
+package alert
+
+import (
+	"fmt"
+	"log/syslog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+)
+
+var logger = logging.New(logging.ComponentMain)
+
+// Names of the alerts raised by the service
+const (
+	TaskActivated       = "task_activated"
+	DeliveryFailure     = "delivery_failure"
+	ExportQueueOverflow = "export_queue_overflow"
+	CertificateExpiring = "certificate_expiring"
+	ClockUnreliable     = "clock_unreliable"
+)
+
+// Formats the alerts are forwarded in
+const (
+	// FormatCEF is the ArcSight Common Event Format
+	FormatCEF = "cef"
+	// FormatText is a line of key=value pairs
+	FormatText = "text"
+)
+
+// Severities of the alerts, on the 0 to 10 scale of CEF
+const (
+	SeverityLow    = 3
+	SeverityMedium = 6
+	SeverityHigh   = 8
+)
+
+const (
+	// queueSize is the number of alerts waiting to be forwarded past which
+	// alerts are dropped
+	queueSize = 256
+	// maxRaised is the number of alerts remembered for suppression past which
+	// the ones out of the repeat interval are forgotten
+	maxRaised = 4096
+)
+
+var titles = map[string]string{
+	TaskActivated:       "Task activated",
+	DeliveryFailure:     "Delivery failure",
+	ExportQueueOverflow: "Export queue overflow",
+	CertificateExpiring: "Certificate expiring",
+	ClockUnreliable:     "Clock unreliable",
+}
+
+// Alert is an operational event to be brought to the attention of operators
+type Alert struct {
+	Name     string
+	Severity int
+	Message  string
+	// NetworkID, TaskID, Destination and Certificate identify the subject of
+	// the alert, left empty when not relevant
+	NetworkID   string
+	TaskID      string
+	Destination string
+	Certificate string
+	At          time.Time
+}
+
+// key identifies the repeats of an alert
+func (a Alert) key() string {
+	return strings.Join([]string{a.Name, a.NetworkID, a.TaskID, a.Destination, a.Certificate}, "\x00")
+}
+
+// Config is the forwarding of the alerts
+type Config struct {
+	// Address is the syslog endpoint, alerts are only logged if empty
+	Address string
+	Network string
+	Format  string
+	// RepeatInterval is the time during which an alert raised again is
+	// neither logged nor forwarded again
+	RepeatInterval time.Duration
+	// CertificateExpiry is the time before the expiry of a certificate it is
+	// alerted about
+	CertificateExpiry time.Duration
+}
+
+// NewConfig returns the forwarding of the alerts set in the service config
+func NewConfig(config nprobe.Config) (Config, error) {
+	ret := Config{
+		Address:           config.AlertSyslogAddress,
+		Network:           config.AlertSyslogNetwork,
+		Format:            config.AlertFormat,
+		RepeatInterval:    time.Duration(config.AlertRepeatIntervalSecs) * time.Second,
+		CertificateExpiry: time.Duration(config.AlertCertificateExpiryDays) * 24 * time.Hour,
+	}
+	switch ret.Format {
+	case FormatCEF, FormatText:
+	default:
+		return Config{}, fmt.Errorf("unsupported alert format %s", ret.Format)
+	}
+	switch ret.Network {
+	case "udp", "tcp", "unix":
+	default:
+		return Config{}, fmt.Errorf("unsupported alert syslog network %s", ret.Network)
+	}
+	return ret, nil
+}
+
+// sender forwards formatted alerts, a syslog writer unless faked
+type sender interface {
+	Crit(m string) error
+	Err(m string) error
+	Warning(m string) error
+	Close() error
+}
+
+// dialSyslog connects to the syslog endpoint of a config
+func dialSyslog(config Config) (sender, error) {
+	return syslog.Dial(config.Network, config.Address, syslog.LOG_WARNING|syslog.LOG_DAEMON, nprobe.ServiceName)
+}
+
+// Alerter logs the alerts raised and forwards them to a syslog endpoint in
+// the background, so that raising an alert never blocks on the endpoint
+type Alerter struct {
+	mutex  sync.Mutex
+	config Config
+	sender sender
+	dial   func(config Config) (sender, error)
+	// raised holds the time each alert was last raised at, by key
+	raised map[string]time.Time
+	queue  chan Alert
+	start  sync.Once
+}
+
+// NewAlerter creates an alerter only logging alerts until configured
+func NewAlerter() *Alerter {
+	return &Alerter{
+		config: Config{Format: FormatCEF},
+		dial:   dialSyslog,
+		raised: map[string]time.Time{},
+		queue:  make(chan Alert, queueSize),
+	}
+}
+
+// Configure applies the forwarding of a config to the alerts raised from now
+// on, reconnecting to the syslog endpoint if it changed
+func (a *Alerter) Configure(config Config) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if config.Address != a.config.Address || config.Network != a.config.Network {
+		a.closeSender()
+	}
+	a.config = config
+}
+
+// Raise logs an alert and queues it to be forwarded, unless the same alert
+// was raised within the repeat interval. The alert is dropped if the queue is
+// full.
+func (a *Alerter) Raise(alert Alert) {
+	if alert.At.IsZero() {
+		alert.At = time.Now()
+	}
+	a.mutex.Lock()
+	key := alert.key()
+	if last, ok := a.raised[key]; ok && alert.At.Sub(last) < a.config.RepeatInterval {
+		a.mutex.Unlock()
+		return
+	}
+	if len(a.raised) >= maxRaised {
+		for k, last := range a.raised {
+			if alert.At.Sub(last) >= a.config.RepeatInterval {
+				delete(a.raised, k)
+			}
+		}
+	}
+	a.raised[key] = alert.At
+	forward := a.config.Address != ""
+	a.mutex.Unlock()
+
+	logger.Warningf("Alert %s: %s", alert.Name, alert.Message)
+	metrics.AlertsRaised.WithLabelValues(alert.Name).Inc()
+	if !forward {
+		return
+	}
+	a.start.Do(func() { go a.run() })
+	select {
+	case a.queue <- alert:
+	default:
+		logger.Errorf("Dropping alert %s, too many alerts waiting to be forwarded", alert.Name)
+		metrics.AlertFailures.WithLabelValues(alert.Name).Inc()
+	}
+}
+
+// CheckCertificate raises an alert if a certificate expires within the
+// certificate expiry of the config, or has expired
+func (a *Alerter) CheckCertificate(certificate string, notAfter, now time.Time) {
+	a.mutex.Lock()
+	expiry := a.config.CertificateExpiry
+	a.mutex.Unlock()
+	remaining := notAfter.Sub(now)
+	if expiry == 0 || remaining > expiry {
+		return
+	}
+	alert := Alert{
+		Name:        CertificateExpiring,
+		Severity:    SeverityMedium,
+		Message:     fmt.Sprintf("certificate %s expires on %s, in %d days", certificate, notAfter.UTC().Format(time.RFC3339), int(remaining.Hours()/24)),
+		Certificate: certificate,
+		At:          now,
+	}
+	if remaining <= 0 {
+		alert.Severity = SeverityHigh
+		alert.Message = fmt.Sprintf("certificate %s expired on %s", certificate, notAfter.UTC().Format(time.RFC3339))
+	}
+	a.Raise(alert)
+}
+
+// run forwards the queued alerts for the lifetime of the process
+func (a *Alerter) run() {
+	for alert := range a.queue {
+		a.forward(alert)
+	}
+}
+
+// forward sends an alert to the syslog endpoint, connecting to it first if
+// needed. The connection is dropped on failure to be re-established by the
+// next alert.
+func (a *Alerter) forward(alert Alert) {
+	a.mutex.Lock()
+	config := a.config
+	if config.Address == "" {
+		a.mutex.Unlock()
+		return
+	}
+	if a.sender == nil {
+		s, err := a.dial(config)
+		if err != nil {
+			a.mutex.Unlock()
+			logger.Errorf("Failed to connect to alert syslog endpoint %s: %v", config.Address, err)
+			metrics.AlertFailures.WithLabelValues(alert.Name).Inc()
+			return
+		}
+		a.sender = s
+	}
+	s := a.sender
+	a.mutex.Unlock()
+
+	var message string
+	if config.Format == FormatText {
+		message = FormatTextAlert(alert)
+	} else {
+		message = FormatCEFAlert(alert)
+	}
+	var err error
+	switch {
+	case alert.Severity >= SeverityHigh:
+		err = s.Crit(message)
+	case alert.Severity >= SeverityMedium:
+		err = s.Err(message)
+	default:
+		err = s.Warning(message)
+	}
+	if err != nil {
+		logger.Errorf("Failed to forward alert %s to syslog endpoint %s: %v", alert.Name, config.Address, err)
+		metrics.AlertFailures.WithLabelValues(alert.Name).Inc()
+		a.mutex.Lock()
+		if a.sender == s {
+			a.closeSender()
+		}
+		a.mutex.Unlock()
+	}
+}
+
+// closeSender closes the connection to the syslog endpoint, if any. The mutex
+// must be held.
+func (a *Alerter) closeSender() {
+	if a.sender == nil {
+		return
+	}
+	if err := a.sender.Close(); err != nil {
+		logger.Warningf("Failed to close alert syslog connection: %v", err)
+	}
+	a.sender = nil
+}
+
+// FormatCEFAlert returns an alert as a CEF message, its subject in custom
+// string extensions
+func FormatCEFAlert(alert Alert) string {
+	title, ok := titles[alert.Name]
+	if !ok {
+		title = alert.Name
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|Magma|%s|1.0|%s|%s|%d|rt=%d",
+		nprobe.ServiceName, escapeCEFHeader(alert.Name), escapeCEFHeader(title), alert.Severity,
+		alert.At.UnixNano()/int64(time.Millisecond))
+	extensions := []struct{ label, value string }{
+		{"networkId", alert.NetworkID},
+		{"taskId", alert.TaskID},
+		{"destination", alert.Destination},
+		{"certificate", alert.Certificate},
+	}
+	for i, ext := range extensions {
+		if ext.value == "" {
+			continue
+		}
+		fmt.Fprintf(&b, " cs%dLabel=%s cs%d=%s", i+1, ext.label, i+1, escapeCEFValue(ext.value))
+	}
+	if alert.Message != "" {
+		fmt.Fprintf(&b, " msg=%s", escapeCEFValue(alert.Message))
+	}
+	return b.String()
+}
+
+// FormatTextAlert returns an alert as a line of key=value pairs, its message
+// quoted
+func FormatTextAlert(alert Alert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "alert=%s severity=%d time=%s", alert.Name, alert.Severity, alert.At.UTC().Format(time.RFC3339))
+	fields := []struct{ key, value string }{
+		{"network_id", alert.NetworkID},
+		{"task_id", alert.TaskID},
+		{"destination", alert.Destination},
+		{"certificate", alert.Certificate},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			fmt.Fprintf(&b, " %s=%s", field.key, strconv.Quote(field.value))
+		}
+	}
+	fmt.Fprintf(&b, " msg=%s", strconv.Quote(alert.Message))
+	return b.String()
+}
+
+var (
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	cefValueEscaper  = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+// escapeCEFHeader escapes a field of the header of a CEF message
+func escapeCEFHeader(s string) string {
+	return cefHeaderEscaper.Replace(s)
+}
+
+// escapeCEFValue escapes a value of the extension of a CEF message
+func escapeCEFValue(s string) string {
+	return cefValueEscaper.Replace(s)
+}
+
+var defaultAlerter = NewAlerter()
+
+// Configure applies the forwarding of a config to the alerts raised by the
+// service
+func Configure(config Config) {
+	defaultAlerter.Configure(config)
+}
+
+// Raise raises an alert of the service
+func Raise(alert Alert) {
+	defaultAlerter.Raise(alert)
+}
+
+// CheckCertificate raises an alert of the service if a certificate expires
+// soon
+func CheckCertificate(certificate string, notAfter, now time.Time) {
+	defaultAlerter.CheckCertificate(certificate, notAfter, now)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alert
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSender records the messages forwarded with their syslog severity
+type fakeSender struct {
+	messages chan string
+	err      error
+	closed   int
+}
+
+func (s *fakeSender) send(level, m string) error {
+	err := s.err
+	s.messages <- level + " " + m
+	return err
+}
+
+func (s *fakeSender) Crit(m string) error    { return s.send("crit", m) }
+func (s *fakeSender) Err(m string) error     { return s.send("err", m) }
+func (s *fakeSender) Warning(m string) error { return s.send("warning", m) }
+func (s *fakeSender) Close() error           { s.closed++; return nil }
+
+func newTestAlerter(s *fakeSender, config Config) *Alerter {
+	a := NewAlerter()
+	a.dial = func(Config) (sender, error) { return s, nil }
+	a.Configure(config)
+	return a
+}
+
+func TestNewConfig(t *testing.T) {
+	config, err := NewConfig(nprobe.Config{
+		AlertSyslogAddress:         "siem:514",
+		AlertSyslogNetwork:         "tcp",
+		AlertFormat:                "cef",
+		AlertRepeatIntervalSecs:    60,
+		AlertCertificateExpiryDays: 30,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Config{
+		Address:           "siem:514",
+		Network:           "tcp",
+		Format:            FormatCEF,
+		RepeatInterval:    time.Minute,
+		CertificateExpiry: 30 * 24 * time.Hour,
+	}, config)
+
+	_, err = NewConfig(nprobe.Config{AlertSyslogNetwork: "udp", AlertFormat: "leef"})
+	assert.EqualError(t, err, "unsupported alert format leef")
+	_, err = NewConfig(nprobe.Config{AlertSyslogNetwork: "sctp", AlertFormat: "cef"})
+	assert.EqualError(t, err, "unsupported alert syslog network sctp")
+}
+
+func TestFormatCEFAlert(t *testing.T) {
+	at := time.Date(2021, 3, 6, 3, 0, 0, 0, time.UTC)
+	alert := Alert{
+		Name:      DeliveryFailure,
+		Severity:  SeverityMedium,
+		Message:   "dial tcp 10.0.0.1:6666: a=b\\c\nd",
+		NetworkID: "n1",
+		TaskID:    "task|1",
+		At:        at,
+	}
+	assert.Equal(t,
+		`CEF:0|Magma|nprobe|1.0|delivery_failure|Delivery failure|6|rt=1614999600000 `+
+			`cs1Label=networkId cs1=n1 cs2Label=taskId cs2=task|1 msg=dial tcp 10.0.0.1:6666: a\=b\\c\nd`,
+		FormatCEFAlert(alert),
+	)
+
+	alert = Alert{Name: "custom|alert", Severity: SeverityLow, Destination: "lemf:6666", At: at}
+	assert.Equal(t,
+		`CEF:0|Magma|nprobe|1.0|custom\|alert|custom\|alert|3|rt=1614999600000 cs3Label=destination cs3=lemf:6666`,
+		FormatCEFAlert(alert),
+	)
+}
+
+func TestFormatTextAlert(t *testing.T) {
+	alert := Alert{
+		Name:      TaskActivated,
+		Severity:  SeverityLow,
+		Message:   "interception of task t1 started",
+		NetworkID: "n1",
+		TaskID:    "t1",
+		At:        time.Date(2021, 3, 6, 3, 0, 0, 0, time.UTC),
+	}
+	assert.Equal(t,
+		`alert=task_activated severity=3 time=2021-03-06T03:00:00Z network_id="n1" task_id="t1" msg="interception of task t1 started"`,
+		FormatTextAlert(alert),
+	)
+}
+
+func TestRaise(t *testing.T) {
+	s := &fakeSender{messages: make(chan string, 10)}
+	a := newTestAlerter(s, Config{Address: "siem:514", Network: "udp", Format: FormatText, RepeatInterval: time.Minute})
+	at := time.Date(2021, 3, 6, 3, 0, 0, 0, time.UTC)
+	alert := Alert{Name: DeliveryFailure, Severity: SeverityMedium, Message: "refused", Destination: "lemf:6666", At: at}
+
+	// repeats within the repeat interval are suppressed
+	a.Raise(alert)
+	assert.Equal(t, `err alert=delivery_failure severity=6 time=2021-03-06T03:00:00Z destination="lemf:6666" msg="refused"`, <-s.messages)
+	alert.At = at.Add(30 * time.Second)
+	a.Raise(alert)
+	other := Alert{Name: DeliveryFailure, Severity: SeverityHigh, Message: "refused", Destination: "lemf2:6666", At: alert.At}
+	a.Raise(other)
+	assert.Equal(t, `crit alert=delivery_failure severity=8 time=2021-03-06T03:00:30Z destination="lemf2:6666" msg="refused"`, <-s.messages)
+	alert.At = at.Add(time.Minute)
+	alert.Severity = SeverityLow
+	a.Raise(alert)
+	assert.Equal(t, `warning alert=delivery_failure severity=3 time=2021-03-06T03:01:00Z destination="lemf:6666" msg="refused"`, <-s.messages)
+
+	// a failure drops the connection, re-established by the next alert
+	s.err = errors.New("closed")
+	alert.At = at.Add(2 * time.Minute)
+	a.Raise(alert)
+	<-s.messages
+	assert.Eventually(t, func() bool {
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		return a.sender == nil
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, s.closed)
+	s.err = nil
+	alert.At = at.Add(3 * time.Minute)
+	a.Raise(alert)
+	assert.Contains(t, <-s.messages, "time=2021-03-06T03:03:00Z")
+
+	// alerts are only logged once the endpoint is unset
+	a.Configure(Config{Format: FormatCEF, RepeatInterval: time.Minute})
+	alert.At = at.Add(4 * time.Minute)
+	a.Raise(alert)
+	assert.Equal(t, 2, s.closed)
+	assert.Empty(t, s.messages)
+}
+
+func TestCheckCertificate(t *testing.T) {
+	s := &fakeSender{messages: make(chan string, 10)}
+	a := newTestAlerter(s, Config{
+		Address:           "siem:514",
+		Network:           "udp",
+		Format:            FormatCEF,
+		RepeatInterval:    24 * time.Hour,
+		CertificateExpiry: 30 * 24 * time.Hour,
+	})
+	now := time.Date(2021, 3, 6, 3, 0, 0, 0, time.UTC)
+
+	a.CheckCertificate("client.crt", now.Add(60*24*time.Hour), now)
+	a.CheckCertificate("client.crt", now.Add(10*24*time.Hour), now)
+	assert.Equal(t,
+		`err CEF:0|Magma|nprobe|1.0|certificate_expiring|Certificate expiring|6|rt=1614999600000 `+
+			`cs4Label=certificate cs4=client.crt msg=certificate client.crt expires on 2021-03-16T03:00:00Z, in 10 days`,
+		<-s.messages,
+	)
+	a.CheckCertificate("client.crt", now.Add(-time.Hour), now.Add(24*time.Hour))
+	assert.Equal(t,
+		`crit CEF:0|Magma|nprobe|1.0|certificate_expiring|Certificate expiring|8|rt=1615086000000 `+
+			`cs4Label=certificate cs4=client.crt msg=certificate client.crt expired on 2021-03-06T02:00:00Z`,
+		<-s.messages,
+	)
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clock stamps the records the service builds itself, e.g. the
+// IRI-END of a deleted task or the report of a resumed one, and tracks the
+// quality of the time they are stamped with. LI records must be accurately
+// timestamped: the clock is checked against NTP servers, the records built
+// while it is unreliable are flagged and an alert is raised.
+package clock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/alert"
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+)
+
+var logger = logging.New(logging.ComponentMain)
+
+// Sources of the time records are stamped with
+const (
+	// SourceSystem stamps records with the clock of the host, which is only
+	// checked against the NTP servers
+	SourceSystem = "system"
+	// SourceNTP stamps records with the clock of the host corrected by its
+	// offset from the NTP servers, as last measured
+	SourceNTP = "ntp"
+)
+
+// Qualities of the time records are stamped with
+const (
+	// QualityUnchecked is the quality of a clock checked against no NTP
+	// server, whose records are not flagged
+	QualityUnchecked = "unchecked"
+	// QualitySynchronized is the quality of a clock within the skew
+	// threshold of the NTP servers when last checked
+	QualitySynchronized = "synchronized"
+	// QualitySkewed is the quality of a clock off the NTP servers by more
+	// than the skew threshold when last checked
+	QualitySkewed = "skewed"
+	// QualityUnsynchronized is the quality of a clock none of the NTP
+	// servers answered for when last checked, or which were unsynchronized
+	QualityUnsynchronized = "unsynchronized"
+)
+
+// queryTimeout bounds the query of an NTP server
+const queryTimeout = 2 * time.Second
+
+// Config is the source of the time records are stamped with and its check
+type Config struct {
+	Source string
+	// Servers are the NTP servers the clock is checked against, as host or
+	// host:port
+	Servers []string
+	// SkewThreshold is the offset from the NTP servers past which the clock
+	// is unreliable
+	SkewThreshold time.Duration
+	// CheckInterval is the time between two checks of the clock
+	CheckInterval time.Duration
+}
+
+// NewConfig returns the clock set in the service config
+func NewConfig(config nprobe.Config) (Config, error) {
+	ret := Config{
+		Source:        config.ClockSource,
+		Servers:       config.NTPServers,
+		SkewThreshold: time.Duration(config.ClockSkewThresholdMs) * time.Millisecond,
+		CheckInterval: time.Duration(config.ClockCheckIntervalSecs) * time.Second,
+	}
+	switch ret.Source {
+	case SourceSystem:
+	case SourceNTP:
+		if len(ret.Servers) == 0 {
+			return Config{}, fmt.Errorf("the %s clock source requires ntp_servers", SourceNTP)
+		}
+	default:
+		return Config{}, fmt.Errorf("unsupported clock source %s", ret.Source)
+	}
+	return ret, nil
+}
+
+// Status is the quality of the clock as last checked
+type Status struct {
+	Source  string
+	Quality string
+	// Skew is the offset of the clock stamping the records from the NTP
+	// servers, as last measured
+	Skew time.Duration
+	// Offset is the correction applied to the clock of the host by the NTP
+	// source, zero for the system one
+	Offset    time.Duration
+	Server    string
+	CheckedAt time.Time
+	Err       error
+}
+
+// IsReliable returns true if records stamped with a clock of the quality are
+// not flagged
+func IsReliable(quality string) bool {
+	return quality == QualitySynchronized || quality == QualityUnchecked
+}
+
+// Clock stamps the records with the time of its source. The clock of the
+// host, corrected or not, is checked against the NTP servers on each sync:
+// its skew is its offset from the server answering with the shortest round
+// trip. A clock whose skew exceeds the threshold, or which no server answered
+// for, is unreliable until the next sync finds it synchronized again.
+type Clock struct {
+	mutex  sync.RWMutex
+	config Config
+	status Status
+	query  func(server string, timeout time.Duration) (time.Duration, time.Duration, error)
+	raise  func(alert alert.Alert)
+}
+
+// NewClock creates a clock of the system source checked against no server
+func NewClock() *Clock {
+	return &Clock{
+		config: Config{Source: SourceSystem},
+		status: Status{Source: SourceSystem, Quality: QualityUnchecked},
+		query:  queryNTP,
+		raise:  alert.Raise,
+	}
+}
+
+// Configure applies a config to the clock. The correction of the NTP source
+// is kept until the next sync, and dropped when switching to the system one.
+func (c *Clock) Configure(config Config) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.config = config
+	c.status.Source = config.Source
+	if config.Source != SourceNTP {
+		c.status.Offset = 0
+	}
+	if len(config.Servers) == 0 {
+		c.setStatus(Status{Source: config.Source, Quality: QualityUnchecked})
+	}
+}
+
+// Now returns the time records are stamped with
+func (c *Clock) Now() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return time.Now().Add(c.status.Offset)
+}
+
+// GetStatus returns the quality of the clock as last checked
+func (c *Clock) GetStatus() Status {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.status
+}
+
+// Check returns the error of an unreliable clock, the service still stamping
+// records while degraded
+func (c *Clock) Check() error {
+	status := c.GetStatus()
+	if IsReliable(status.Quality) {
+		return nil
+	}
+	if status.Err != nil {
+		return health.Degraded(fmt.Errorf("clock %s: %v", status.Quality, status.Err))
+	}
+	return health.Degraded(fmt.Errorf("clock %s by %s from %s", status.Quality, status.Skew, status.Server))
+}
+
+// Sync checks the clock against the NTP servers, correcting it with its
+// offset for the NTP source, and returns its quality
+func (c *Clock) Sync() Status {
+	c.mutex.RLock()
+	config := c.config
+	c.mutex.RUnlock()
+	if len(config.Servers) == 0 {
+		return c.GetStatus()
+	}
+
+	type answer struct {
+		server        string
+		offset, delay time.Duration
+	}
+	var answers []answer
+	var errs []string
+	for _, server := range config.Servers {
+		offset, delay, err := c.query(server, queryTimeout)
+		if err != nil {
+			logger.Warningf("Failed to query NTP server %s: %v", server, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", server, err))
+			continue
+		}
+		answers = append(answers, answer{server: server, offset: offset, delay: delay})
+	}
+	sort.SliceStable(answers, func(i, j int) bool { return answers[i].delay < answers[j].delay })
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	status := Status{Source: config.Source, Offset: c.status.Offset, CheckedAt: time.Now()}
+	if len(answers) == 0 {
+		status.Quality = QualityUnsynchronized
+		status.Err = fmt.Errorf("no NTP server answered: %v", errs)
+		c.setStatus(status)
+		return status
+	}
+	best := answers[0]
+	// the corrected clock is off by the change of its offset
+	status.Skew = best.offset - c.status.Offset
+	status.Server = best.server
+	status.Quality = QualitySynchronized
+	if status.Skew > config.SkewThreshold || -status.Skew > config.SkewThreshold {
+		status.Quality = QualitySkewed
+	}
+	if config.Source == SourceNTP {
+		status.Offset = best.offset
+	}
+	c.setStatus(status)
+	return status
+}
+
+// Run syncs the clock on start and then once per check interval until ctx
+// is done
+func (c *Clock) Run(ctx context.Context) {
+	for {
+		c.Sync()
+		c.mutex.RLock()
+		interval := c.config.CheckInterval
+		c.mutex.RUnlock()
+		if interval <= 0 {
+			interval = time.Duration(nprobe.DefaultClockCheckIntervalSecs) * time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// setStatus replaces the status of the clock, alerting when it turns
+// unreliable. The mutex must be held.
+func (c *Clock) setStatus(status Status) {
+	wasReliable := IsReliable(c.status.Quality)
+	c.status = status
+	metrics.ClockSkew.Set(status.Skew.Seconds())
+	if !IsReliable(status.Quality) {
+		metrics.ClockReliable.Set(0)
+	} else {
+		metrics.ClockReliable.Set(1)
+	}
+
+	switch {
+	case wasReliable && !IsReliable(status.Quality):
+		message := fmt.Sprintf("clock %s by %s from NTP server %s, records flagged", status.Quality, status.Skew, status.Server)
+		if status.Err != nil {
+			message = fmt.Sprintf("clock %s, records flagged: %v", status.Quality, status.Err)
+		}
+		logger.Errorf("Time of the records unreliable: %s", message)
+		c.raise(alert.Alert{
+			Name:     alert.ClockUnreliable,
+			Severity: alert.SeverityHigh,
+			Message:  message,
+			At:       status.CheckedAt,
+		})
+	case !wasReliable && IsReliable(status.Quality):
+		logger.Infof("Time of the records reliable again, clock %s", status.Quality)
+	}
+}
+
+var defaultClock = NewClock()
+
+// Default returns the clock of the service
+func Default() *Clock {
+	return defaultClock
+}
+
+// Configure applies a config to the clock of the service
+func Configure(config Config) {
+	defaultClock.Configure(config)
+}
+
+// Now returns the time the records of the service are stamped with
+func Now() time.Time {
+	return defaultClock.Now()
+}
+
+// GetStatus returns the quality of the clock of the service
+func GetStatus() Status {
+	return defaultClock.GetStatus()
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clock
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/alert"
+	"magma/lte/cloud/go/services/nprobe/health"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ntpAnswer is the answer of a fake NTP server
+type ntpAnswer struct {
+	offset, delay time.Duration
+	err           error
+}
+
+func newTestClock(config Config, answers map[string]ntpAnswer) (*Clock, *[]alert.Alert) {
+	var raised []alert.Alert
+	c := NewClock()
+	c.query = func(server string, timeout time.Duration) (time.Duration, time.Duration, error) {
+		answer := answers[server]
+		return answer.offset, answer.delay, answer.err
+	}
+	c.raise = func(a alert.Alert) { raised = append(raised, a) }
+	c.Configure(config)
+	return c, &raised
+}
+
+func TestNewConfig(t *testing.T) {
+	config, err := NewConfig(nprobe.Config{
+		ClockSource:            SourceNTP,
+		NTPServers:             []string{"ntp1", "ntp2:1123"},
+		ClockSkewThresholdMs:   500,
+		ClockCheckIntervalSecs: 60,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Config{
+		Source:        SourceNTP,
+		Servers:       []string{"ntp1", "ntp2:1123"},
+		SkewThreshold: 500 * time.Millisecond,
+		CheckInterval: time.Minute,
+	}, config)
+
+	_, err = NewConfig(nprobe.Config{ClockSource: SourceSystem})
+	assert.NoError(t, err)
+	_, err = NewConfig(nprobe.Config{ClockSource: SourceNTP})
+	assert.EqualError(t, err, "the ntp clock source requires ntp_servers")
+	_, err = NewConfig(nprobe.Config{ClockSource: "ptp"})
+	assert.EqualError(t, err, "unsupported clock source ptp")
+}
+
+func TestClockSystemSource(t *testing.T) {
+	c, raised := newTestClock(
+		Config{Source: SourceSystem, Servers: []string{"ntp1", "ntp2"}, SkewThreshold: 100 * time.Millisecond},
+		map[string]ntpAnswer{
+			"ntp1": {offset: 50 * time.Millisecond, delay: 20 * time.Millisecond},
+			"ntp2": {offset: 30 * time.Millisecond, delay: 10 * time.Millisecond},
+		},
+	)
+	assert.Equal(t, QualityUnchecked, c.GetStatus().Quality)
+	assert.NoError(t, c.Check())
+
+	// the skew is measured from the server of the shortest round trip
+	status := c.Sync()
+	assert.Equal(t, QualitySynchronized, status.Quality)
+	assert.Equal(t, 30*time.Millisecond, status.Skew)
+	assert.Equal(t, "ntp2", status.Server)
+	assert.Zero(t, status.Offset)
+	assert.NoError(t, c.Check())
+	assert.Empty(t, *raised)
+
+	// the system clock isn't corrected, and turns skewed
+	c.query = func(server string, timeout time.Duration) (time.Duration, time.Duration, error) {
+		return -200 * time.Millisecond, 10 * time.Millisecond, nil
+	}
+	status = c.Sync()
+	assert.Equal(t, QualitySkewed, status.Quality)
+	assert.Equal(t, -200*time.Millisecond, status.Skew)
+	assert.WithinDuration(t, time.Now(), c.Now(), 50*time.Millisecond)
+	err := c.Check()
+	assert.True(t, health.IsDegraded(err))
+	assert.EqualError(t, err, "clock skewed by -200ms from ntp1")
+	assert.Len(t, *raised, 1)
+	assert.Equal(t, alert.ClockUnreliable, (*raised)[0].Name)
+	assert.Equal(t, alert.SeverityHigh, (*raised)[0].Severity)
+
+	// it is alerted about once, until reliable again
+	c.Sync()
+	assert.Len(t, *raised, 1)
+	c.query = func(server string, timeout time.Duration) (time.Duration, time.Duration, error) {
+		return time.Millisecond, 10 * time.Millisecond, nil
+	}
+	assert.Equal(t, QualitySynchronized, c.Sync().Quality)
+	assert.Len(t, *raised, 1)
+}
+
+func TestClockNTPSource(t *testing.T) {
+	answers := map[string]ntpAnswer{"ntp1": {offset: time.Hour, delay: 10 * time.Millisecond}}
+	c, raised := newTestClock(
+		Config{Source: SourceNTP, Servers: []string{"ntp1"}, SkewThreshold: 100 * time.Millisecond},
+		answers,
+	)
+
+	// the first sync corrects the clock, which is off by the whole offset
+	status := c.Sync()
+	assert.Equal(t, QualitySkewed, status.Quality)
+	assert.Equal(t, time.Hour, status.Skew)
+	assert.Equal(t, time.Hour, status.Offset)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), c.Now(), 50*time.Millisecond)
+	assert.Len(t, *raised, 1)
+
+	// and the next ones are off by the drift of the host since
+	answers["ntp1"] = ntpAnswer{offset: time.Hour + 20*time.Millisecond, delay: 10 * time.Millisecond}
+	status = c.Sync()
+	assert.Equal(t, QualitySynchronized, status.Quality)
+	assert.Equal(t, 20*time.Millisecond, status.Skew)
+	assert.Equal(t, time.Hour+20*time.Millisecond, status.Offset)
+
+	// the correction is kept while no server answers
+	answers["ntp1"] = ntpAnswer{err: errors.New("i/o timeout")}
+	status = c.Sync()
+	assert.Equal(t, QualityUnsynchronized, status.Quality)
+	assert.Equal(t, time.Hour+20*time.Millisecond, status.Offset)
+	assert.EqualError(t, c.Check(), "clock unsynchronized: no NTP server answered: [ntp1: i/o timeout]")
+	assert.Len(t, *raised, 2)
+
+	// and dropped when switching to the system source
+	c.Configure(Config{Source: SourceSystem})
+	assert.Zero(t, c.GetStatus().Offset)
+	assert.Equal(t, QualityUnchecked, c.GetStatus().Quality)
+	assert.WithinDuration(t, time.Now(), c.Now(), 50*time.Millisecond)
+}
+
+func TestQueryNTP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+	// the stratum answered is changed while the server runs
+	stratum := uint32(2)
+	go func() {
+		request := make([]byte, ntpPacketLen)
+		for {
+			_, addr, err := conn.ReadFrom(request)
+			if err != nil {
+				return
+			}
+			// a server ahead of the host by a minute
+			now := time.Now().Add(time.Minute)
+			answer := make([]byte, ntpPacketLen)
+			answer[0] = 4<<3 | ntpModeServer
+			answer[1] = byte(atomic.LoadUint32(&stratum))
+			copy(answer[24:32], request[40:48])
+			putNTPTime(answer[32:40], now)
+			putNTPTime(answer[40:48], now)
+			conn.WriteTo(answer, addr)
+		}
+	}()
+
+	offset, delay, err := queryNTP(conn.LocalAddr().String(), time.Second)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute, offset, float64(50*time.Millisecond))
+	assert.True(t, delay >= 0 && delay < 50*time.Millisecond)
+
+	atomic.StoreUint32(&stratum, ntpMaxStratum)
+	_, _, err = queryNTP(conn.LocalAddr().String(), time.Second)
+	assert.EqualError(t, err, "NTP server unsynchronized")
+}
+
+func TestNTPTime(t *testing.T) {
+	b := make([]byte, 8)
+	at := time.Date(2020, 6, 1, 12, 30, 0, 250000000, time.UTC)
+	putNTPTime(b, at)
+	assert.Equal(t, []byte{0xe2, 0x7f, 0x73, 0x48, 0x40, 0, 0, 0}, b)
+	assert.True(t, at.Equal(getNTPTime(b)))
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clock
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// ntpPort is the port NTP servers listen on
+	ntpPort = "123"
+	// ntpPacketLen is the length of an NTP packet without extension
+	ntpPacketLen = 48
+	// ntpEpochOffset is the number of seconds from the NTP epoch, 1900, to
+	// the Unix epoch
+	ntpEpochOffset = 2208988800
+	// ntpClientHeader is the first octet of the requests: no leap warning,
+	// version 4 and client mode
+	ntpClientHeader = 0<<6 | 4<<3 | 3
+	// ntpModeServer is the mode of the answers of a server
+	ntpModeServer = 4
+	// ntpLeapUnsynchronized is the leap indicator of an unsynchronized server
+	ntpLeapUnsynchronized = 3
+	// ntpMaxStratum is the stratum from which a server is unsynchronized
+	ntpMaxStratum = 16
+)
+
+// queryNTP queries an NTP server as an SNTP client of RFC 4330 and returns
+// the offset of the clock of the host from the server, along with the round
+// trip delay of the query
+func queryNTP(server string, timeout time.Duration) (time.Duration, time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, ntpPort)
+	}
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, 0, err
+	}
+
+	request := make([]byte, ntpPacketLen)
+	request[0] = ntpClientHeader
+	sentAt := time.Now()
+	// the transmit timestamp of the request is echoed as the originate
+	// timestamp of the answer
+	putNTPTime(request[40:48], sentAt)
+	if _, err := conn.Write(request); err != nil {
+		return 0, 0, err
+	}
+	answer := make([]byte, 2*ntpPacketLen)
+	n, err := conn.Read(answer)
+	receivedAt := time.Now()
+	if err != nil {
+		return 0, 0, err
+	}
+	receive, transmit, err := parseNTPAnswer(answer[:n], request[40:48])
+	if err != nil {
+		return 0, 0, err
+	}
+	offset := (receive.Sub(sentAt) + transmit.Sub(receivedAt)) / 2
+	delay := receivedAt.Sub(sentAt) - transmit.Sub(receive)
+	return offset, delay, nil
+}
+
+// parseNTPAnswer checks the answer of a server to a request of the given
+// transmit timestamp, and returns the times the server received the request
+// and transmitted the answer
+func parseNTPAnswer(answer, originate []byte) (time.Time, time.Time, error) {
+	if len(answer) < ntpPacketLen {
+		return time.Time{}, time.Time{}, fmt.Errorf("short NTP answer of %d bytes", len(answer))
+	}
+	if mode := answer[0] & 0x07; mode != ntpModeServer {
+		return time.Time{}, time.Time{}, fmt.Errorf("unexpected NTP mode %d", mode)
+	}
+	if !bytes.Equal(answer[24:32], originate) {
+		return time.Time{}, time.Time{}, errors.New("NTP answer to another request")
+	}
+	stratum := answer[1]
+	if stratum == 0 {
+		// kiss-o'-death, e.g. RATE for a rate limited client
+		return time.Time{}, time.Time{}, fmt.Errorf("NTP server refused the query: %s", bytes.TrimRight(answer[12:16], "\x00"))
+	}
+	if answer[0]>>6 == ntpLeapUnsynchronized || stratum >= ntpMaxStratum {
+		return time.Time{}, time.Time{}, errors.New("NTP server unsynchronized")
+	}
+	return getNTPTime(answer[32:40]), getNTPTime(answer[40:48]), nil
+}
+
+// putNTPTime writes a time as an NTP timestamp, its seconds since 1900 and
+// their fraction
+func putNTPTime(b []byte, t time.Time) {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	binary.BigEndian.PutUint32(b[0:4], uint32(seconds))
+	binary.BigEndian.PutUint32(b[4:8], uint32(fraction))
+}
+
+// getNTPTime reads an NTP timestamp
+func getNTPTime(b []byte) time.Time {
+	seconds := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+	nanos := int64(uint64(binary.BigEndian.Uint32(b[4:8])) * uint64(time.Second) >> 32)
+	return time.Unix(seconds, nanos)
+}
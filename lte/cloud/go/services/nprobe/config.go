@@ -15,11 +15,12 @@ package nprobe
 
 import (
 	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/services/nprobe/logging"
 	"magma/orc8r/lib/go/service/config"
-
-	"github.com/golang/glog"
 )
 
+var logger = logging.New(logging.ComponentMain)
+
 const (
 	// DefaultUpdateIntervalSecs is the default periodic time between runs in seconds
 	DefaultUpdateIntervalSecs = 60
@@ -27,6 +28,99 @@ const (
 	DefaultBackOffIntervalSecs = 360
 	// DefaultMaxExportRetries is the default maximum retries when exporting records
 	DefaultMaxExportRetries = 10
+	// DefaultShutdownTimeoutSecs is the default time given to drain in-flight records and end
+	// the HI2 streams on shutdown
+	DefaultShutdownTimeoutSecs = 30
+	// DefaultMaxWorkers is the default number of tasks processed concurrently
+	DefaultMaxWorkers = 8
+	// DefaultWarmupConcurrency is the default number of networks loaded concurrently when a term starts
+	DefaultWarmupConcurrency = 16
+	// DefaultTaskWeight is the default share of the delivery connection given to a task
+	DefaultTaskWeight = 1
+	// DefaultFetchPageSize is the default number of events of a task fetched at once
+	DefaultFetchPageSize = 50
+	// DefaultFetchMaxPages is the default number of pages of events a task processes per run
+	DefaultFetchMaxPages = 10
+	// DefaultCheckpointMaxRecords is the default number of records delivered by a task between checkpoints
+	DefaultCheckpointMaxRecords = 50
+	// DefaultCheckpointMaxIntervalSecs is the default maximum time between checkpoints of a task
+	DefaultCheckpointMaxIntervalSecs = 300
+	// DefaultRecordValidation is the default verification applied to encoded records
+	DefaultRecordValidation = "strict"
+	// DefaultExporterBackend is the default transport used to deliver records
+	DefaultExporterBackend = "tls"
+	// DefaultTLSPoolSize is the default number of connections kept to the delivery function
+	DefaultTLSPoolSize = 1
+	// DefaultReconnectMaxBackoffSecs is the default maximum delay between failed attempts to connect
+	DefaultReconnectMaxBackoffSecs = 30
+	// DefaultFailbackIntervalSecs is the default time delivery stays on a secondary delivery function before failing back
+	DefaultFailbackIntervalSecs = 300
+	// DefaultBreakerOpenSecs is the default time the circuit breaker of a destination sheds its records before probing it
+	DefaultBreakerOpenSecs = 30
+	// DefaultDestinationProbeTimeoutSecs is the default time given to resolve and connect to a destination when probed
+	DefaultDestinationProbeTimeoutSecs = 5
+	// DefaultOutputFormat is the default format records are delivered in
+	DefaultOutputFormat = "hi2"
+	// DefaultExportFraming is the default framing of the records written on the delivery connection
+	DefaultExportFraming = "native"
+	// DefaultExportEncoding is the default encoding of the BER elements written on the delivery connection
+	DefaultExportEncoding = "der"
+	// DefaultPcapDirectory is the default directory pcap files are written to
+	DefaultPcapDirectory = "/var/opt/magma/nprobe/pcap"
+	// DefaultPcapRotationSizeMB is the default size at which pcap files are rotated
+	DefaultPcapRotationSizeMB = 64
+	// DefaultPcapRetentionHours is the default time pcap files are kept for
+	DefaultPcapRetentionHours = 72
+	// DefaultConfigReloadIntervalSecs is the default time between checks for configuration changes
+	DefaultConfigReloadIntervalSecs = 30
+	// DefaultEventSource is the default source of the events of the tasks, orc8r eventd
+	DefaultEventSource = "eventd"
+	// DefaultIngestBufferSize is the default number of streamed events buffered per network
+	DefaultIngestBufferSize = 10000
+	// DefaultIngestFlushIntervalMs is the default time streamed events are batched for before being processed
+	DefaultIngestFlushIntervalMs = 200
+	// DefaultSubscriptionPollIntervalSecs is the default time between two polls of eventd while subscriber states are streamed
+	DefaultSubscriptionPollIntervalSecs = 300
+	// DefaultSubscriptionStaleSecs is the default time without streamed subscriber states after which eventd is polled again
+	DefaultSubscriptionStaleSecs = 180
+	// DefaultDeliveryAuditRetentionDays is the default time the audit trail of delivered records is kept for
+	DefaultDeliveryAuditRetentionDays = 365
+	// DefaultBearerCorrelationRetentionDays is the default time the correlation IDs of the released bearers are kept for
+	DefaultBearerCorrelationRetentionDays = 31
+	// DefaultLeaseDurationSecs is the default time the lease of the instance processing tasks lasts without renewal
+	DefaultLeaseDurationSecs = 15
+	// DefaultExportOverflowPolicy is the default handling of the records submitted while the export queue is full
+	DefaultExportOverflowPolicy = "spill"
+	// DefaultStateBackend is the default store of the export state of the tasks
+	DefaultStateBackend = "blobstore"
+	// DefaultSessionIdleTimeoutHours is the default time after which the open sessions of an idle target are ended
+	DefaultSessionIdleTimeoutHours = 168
+	// DefaultTaskDeletionTimeoutHours is the default time after which deleted tasks are deleted without their IRI-END
+	DefaultTaskDeletionTimeoutHours = 24
+	// DefaultStateResyncIntervalSecs is the default time between the syncs fetching all the states of the targets
+	DefaultStateResyncIntervalSecs = 3600
+	// DefaultHealthWindowSecs is the default time the encode failures are counted over for the service health
+	DefaultHealthWindowSecs = 300
+	// DefaultHealthEncodeFailureMin is the default number of encode failures from which the service is degraded
+	DefaultHealthEncodeFailureMin = 10
+	// DefaultHealthEncodeFailurePercent is the default share of failed encodings from which the service is degraded
+	DefaultHealthEncodeFailurePercent = 5
+	// DefaultAlertSyslogNetwork is the default transport alerts are forwarded to syslog over
+	DefaultAlertSyslogNetwork = "udp"
+	// DefaultAlertFormat is the default format alerts are forwarded in
+	DefaultAlertFormat = "cef"
+	// DefaultAlertRepeatIntervalSecs is the default time during which an alert raised again is not forwarded again
+	DefaultAlertRepeatIntervalSecs = 300
+	// DefaultAlertCertificateExpiryDays is the default time before the expiry of a certificate it is alerted about
+	DefaultAlertCertificateExpiryDays = 30
+	// DefaultClockSource is the default source of the time the records built by the service are stamped with
+	DefaultClockSource = "system"
+	// DefaultClockSkewThresholdMs is the default offset from the NTP servers past which the clock is unreliable
+	DefaultClockSkewThresholdMs = 500
+	// DefaultClockCheckIntervalSecs is the default time between two checks of the clock against the NTP servers
+	DefaultClockCheckIntervalSecs = 60
+	// DefaultTracingSampleRatio is the default share of the task runs traced once tracing is enabled
+	DefaultTracingSampleRatio = 0.01
 )
 
 // Config represents the configuration provided to nprobe service
@@ -35,19 +129,178 @@ type Config struct {
 	BackOffIntervalSecs uint32 `yaml:"backoff_interval_secs"`
 	OperatorID          uint32 `yaml:"operator_id"`
 	MaxExportRetries    uint32 `yaml:"max_export_retries"`
+	ShutdownTimeoutSecs uint32 `yaml:"shutdown_timeout_secs"`
+	MaxWorkers          uint32 `yaml:"max_workers"`
+	WarmupConcurrency   uint32 `yaml:"warmup_concurrency"`
+
+	TaskWeights map[string]uint32 `yaml:"task_weights"`
+
+	DeadLetterAfterRejections uint32 `yaml:"dead_letter_after_rejections"`
+
+	LawfulInterceptionID string `yaml:"lawful_interception_id"`
+	DeliveryCountryCode  string `yaml:"delivery_country_code"`
+
+	Region string `yaml:"region"`
+
+	RecordValidation   string   `yaml:"record_validation"`
+	RecordTransformers []string `yaml:"record_transformers"`
+
+	FetchPageSize uint32 `yaml:"fetch_page_size"`
+	FetchMaxPages uint32 `yaml:"fetch_max_pages"`
+
+	CheckpointMaxRecords      uint32 `yaml:"checkpoint_max_records"`
+	CheckpointMaxIntervalSecs uint32 `yaml:"checkpoint_max_interval_secs"`
+
+	StateBackend string `yaml:"state_backend"`
+
+	SessionIdleTimeoutHours  uint32 `yaml:"session_idle_timeout_hours"`
+	TaskDeletionTimeoutHours uint32 `yaml:"task_deletion_timeout_hours"`
+
+	BearerEnrichment    bool `yaml:"bearer_enrichment"`
+	LocationEnrichment  bool `yaml:"location_enrichment"`
+	ActiveBearerReports bool `yaml:"active_bearer_reports"`
+
+	StateDeltaFetch         bool   `yaml:"state_delta_fetch"`
+	StateResyncIntervalSecs uint32 `yaml:"state_resync_interval_secs"`
+
+	TimestampEncoding  string `yaml:"timestamp_encoding"`
+	TimestampPrecision string `yaml:"timestamp_precision"`
+	TimestampZone      string `yaml:"timestamp_zone"`
+
+	ClockSource            string   `yaml:"clock_source"`
+	NTPServers             []string `yaml:"ntp_servers"`
+	ClockSkewThresholdMs   uint32   `yaml:"clock_skew_threshold_ms"`
+	ClockCheckIntervalSecs uint32   `yaml:"clock_check_interval_secs"`
+
+	ExporterBackend      string   `yaml:"exporter_backend"`
+	OutputFormat         string   `yaml:"output_format"`
+	DeliveryFunctionAddr string   `yaml:"delivery_function_address"`
+	SkipVerifyServer     bool     `yaml:"skip_verify_server"`
+	ExporterKeyFile      string   `yaml:"exporter_key"`
+	ExporterCrtFile      string   `yaml:"exporter_crt"`
+	KafkaBrokers         []string `yaml:"kafka_brokers"`
+	KafkaTopic           string   `yaml:"kafka_topic"`
+	GRPCDeliveryService  string   `yaml:"grpc_delivery_service"`
+
+	NetworkCredentialsDir string `yaml:"network_credentials_directory"`
+
+	DevMode bool `yaml:"dev_mode"`
+
+	KeepaliveIntervalSecs uint32 `yaml:"keepalive_interval_secs"`
+	AckTimeoutSecs        uint32 `yaml:"ack_timeout_secs"`
+	ExportFraming         string `yaml:"export_framing"`
+	ExportEncoding        string `yaml:"export_encoding"`
+
+	ExportHeaderVersion    uint32 `yaml:"export_header_version"`
+	NegotiateHeaderVersion bool   `yaml:"negotiate_header_version"`
+
+	ExportMaxRecordSize uint32 `yaml:"export_max_record_size"`
+
+	TLSPoolSize             uint32 `yaml:"tls_pool_size"`
+	ReconnectMaxBackoffSecs uint32 `yaml:"reconnect_max_backoff_secs"`
+	ExportWriteTimeoutMs    uint32 `yaml:"export_write_timeout_ms"`
+
+	ExportTransport string   `yaml:"export_transport"`
+	SCTPLocalAddrs  []string `yaml:"sctp_local_addresses"`
+	SCTPSecurity    string   `yaml:"sctp_security"`
+
+	BreakerFailureThreshold uint32 `yaml:"breaker_failure_threshold"`
+	BreakerOpenSecs         uint32 `yaml:"breaker_open_secs"`
+
+	SecondaryDeliveryFunctionAddr string `yaml:"secondary_delivery_function_address"`
+	FailbackIntervalSecs          uint32 `yaml:"failback_interval_secs"`
 
-	DeliveryFunctionAddr string `yaml:"delivery_function_address"`
-	SkipVerifyServer     bool   `yaml:"skip_verify_server"`
-	ExporterKeyFile      string `yaml:"exporter_key"`
-	ExporterCrtFile      string `yaml:"exporter_crt"`
+	DestinationProbe            bool   `yaml:"destination_probe"`
+	DestinationProbeTimeoutSecs uint32 `yaml:"destination_probe_timeout_secs"`
+
+	HI1Notifications    bool   `yaml:"hi1_notifications"`
+	HI1NotificationAddr string `yaml:"hi1_notification_address"`
+
+	ExportRateLimit      uint32 `yaml:"export_rate_limit"`
+	ExportBurstSize      uint32 `yaml:"export_burst_size"`
+	ExportQueueSize      uint32 `yaml:"export_queue_size"`
+	ExportOverflowPolicy string `yaml:"export_overflow_policy"`
+
+	ExportQueueHighWatermark uint32 `yaml:"export_queue_high_watermark"`
+
+	ExportBatchMaxRecords uint32 `yaml:"export_batch_max_records"`
+	ExportBatchMaxBytes   uint32 `yaml:"export_batch_max_bytes"`
+	ExportBatchWindowMs   uint32 `yaml:"export_batch_window_ms"`
+
+	ExportAckWindow uint32 `yaml:"export_ack_window"`
+
+	ExportReorderTimeoutMs uint32 `yaml:"export_reorder_timeout_ms"`
+	ExportReorderMaxHeld   uint32 `yaml:"export_reorder_max_held"`
+
+	PcapMirror         bool   `yaml:"pcap_mirror"`
+	PcapDirectory      string `yaml:"pcap_directory"`
+	PcapRotationSizeMB uint32 `yaml:"pcap_rotation_size_mb"`
+	PcapRetentionHours uint32 `yaml:"pcap_retention_hours"`
+
+	EventSources []string `yaml:"event_sources"`
+
+	IngestBufferSize      uint32 `yaml:"ingest_buffer_size"`
+	IngestFlushIntervalMs uint32 `yaml:"ingest_flush_interval_ms"`
+
+	EventSubscription            bool   `yaml:"event_subscription"`
+	SubscriptionPollIntervalSecs uint32 `yaml:"subscription_poll_interval_secs"`
+	SubscriptionStaleSecs        uint32 `yaml:"subscription_stale_secs"`
+
+	DeliveryAudit              bool   `yaml:"delivery_audit"`
+	DeliveryAuditRetentionDays uint32 `yaml:"delivery_audit_retention_days"`
+
+	BearerCorrelation              bool   `yaml:"bearer_correlation"`
+	BearerCorrelationRetentionDays uint32 `yaml:"bearer_correlation_retention_days"`
+
+	SnapshotKeyFile string `yaml:"snapshot_key"`
+
+	AtRestKeyFiles   map[string]string `yaml:"at_rest_keys"`
+	AtRestPrimaryKey string            `yaml:"at_rest_primary_key"`
+
+	RecordSigning        string `yaml:"record_signing"`
+	RecordSigningKeyFile string `yaml:"record_signing_key"`
+
+	LeaderElection     bool   `yaml:"leader_election"`
+	LeaseDurationSecs  uint32 `yaml:"lease_duration_secs"`
+	StandbyReplication bool   `yaml:"standby_replication"`
+
+	ConfigReloadIntervalSecs uint32 `yaml:"config_reload_interval_secs"`
+
+	HealthWindowSecs           uint32 `yaml:"health_window_secs"`
+	HealthEncodeFailureMin     uint32 `yaml:"health_encode_failure_min"`
+	HealthEncodeFailurePercent uint32 `yaml:"health_encode_failure_percent"`
+
+	AlertSyslogAddress         string `yaml:"alert_syslog_address"`
+	AlertSyslogNetwork         string `yaml:"alert_syslog_network"`
+	AlertFormat                string `yaml:"alert_format"`
+	AlertRepeatIntervalSecs    uint32 `yaml:"alert_repeat_interval_secs"`
+	AlertCertificateExpiryDays uint32 `yaml:"alert_certificate_expiry_days"`
+
+	LogPseudonymKeyFile string            `yaml:"log_pseudonym_key"`
+	LogLevels           map[string]uint32 `yaml:"log_levels"`
+
+	TracingEndpoint    string  `yaml:"tracing_endpoint"`
+	TracingSampleRatio float64 `yaml:"tracing_sample_ratio"`
+
+	RecordStream bool `yaml:"record_stream"`
 }
 
 // GetServiceConfig parses nprobe service config and returns Config
 func GetServiceConfig() Config {
+	serviceConfig, _, err := loadServiceConfig()
+	if err != nil {
+		logger.Fatalf("Failed parsing nprobe config file: %v ", err)
+	}
+	return serviceConfig
+}
+
+// loadServiceConfig parses nprobe service config, applies the defaults of
+// the fields not set and returns the path of the config file read
+func loadServiceConfig() (Config, string, error) {
 	var serviceConfig Config
-	_, _, err := config.GetStructuredServiceConfig(lte.ModuleName, ServiceName, &serviceConfig)
+	path, _, err := config.GetStructuredServiceConfig(lte.ModuleName, ServiceName, &serviceConfig)
 	if err != nil {
-		glog.Fatalf("Failed parsing nprobe config file: %v ", err)
+		return Config{}, "", err
 	}
 	if serviceConfig.UpdateIntervalSecs == 0 {
 		serviceConfig.UpdateIntervalSecs = DefaultUpdateIntervalSecs
@@ -58,5 +311,140 @@ func GetServiceConfig() Config {
 	if serviceConfig.MaxExportRetries == 0 {
 		serviceConfig.MaxExportRetries = DefaultMaxExportRetries
 	}
-	return serviceConfig
+	if serviceConfig.ShutdownTimeoutSecs == 0 {
+		serviceConfig.ShutdownTimeoutSecs = DefaultShutdownTimeoutSecs
+	}
+	if serviceConfig.MaxWorkers == 0 {
+		serviceConfig.MaxWorkers = DefaultMaxWorkers
+	}
+	if serviceConfig.WarmupConcurrency == 0 {
+		serviceConfig.WarmupConcurrency = DefaultWarmupConcurrency
+	}
+	if serviceConfig.FetchPageSize == 0 {
+		serviceConfig.FetchPageSize = DefaultFetchPageSize
+	}
+	if serviceConfig.FetchMaxPages == 0 {
+		serviceConfig.FetchMaxPages = DefaultFetchMaxPages
+	}
+	if serviceConfig.CheckpointMaxRecords == 0 {
+		serviceConfig.CheckpointMaxRecords = DefaultCheckpointMaxRecords
+	}
+	if serviceConfig.CheckpointMaxIntervalSecs == 0 {
+		serviceConfig.CheckpointMaxIntervalSecs = DefaultCheckpointMaxIntervalSecs
+	}
+	if len(serviceConfig.RecordValidation) == 0 {
+		serviceConfig.RecordValidation = DefaultRecordValidation
+	}
+	if len(serviceConfig.StateBackend) == 0 {
+		serviceConfig.StateBackend = DefaultStateBackend
+	}
+	if serviceConfig.SessionIdleTimeoutHours == 0 {
+		serviceConfig.SessionIdleTimeoutHours = DefaultSessionIdleTimeoutHours
+	}
+	if serviceConfig.TaskDeletionTimeoutHours == 0 {
+		serviceConfig.TaskDeletionTimeoutHours = DefaultTaskDeletionTimeoutHours
+	}
+	if serviceConfig.StateResyncIntervalSecs == 0 {
+		serviceConfig.StateResyncIntervalSecs = DefaultStateResyncIntervalSecs
+	}
+	if len(serviceConfig.ExporterBackend) == 0 {
+		serviceConfig.ExporterBackend = DefaultExporterBackend
+	}
+	if serviceConfig.TLSPoolSize == 0 {
+		serviceConfig.TLSPoolSize = DefaultTLSPoolSize
+	}
+	if serviceConfig.ReconnectMaxBackoffSecs == 0 {
+		serviceConfig.ReconnectMaxBackoffSecs = DefaultReconnectMaxBackoffSecs
+	}
+	if serviceConfig.FailbackIntervalSecs == 0 {
+		serviceConfig.FailbackIntervalSecs = DefaultFailbackIntervalSecs
+	}
+	if serviceConfig.BreakerOpenSecs == 0 {
+		serviceConfig.BreakerOpenSecs = DefaultBreakerOpenSecs
+	}
+	if serviceConfig.DestinationProbeTimeoutSecs == 0 {
+		serviceConfig.DestinationProbeTimeoutSecs = DefaultDestinationProbeTimeoutSecs
+	}
+	if len(serviceConfig.OutputFormat) == 0 {
+		serviceConfig.OutputFormat = DefaultOutputFormat
+	}
+	if serviceConfig.ExportFraming == "" {
+		serviceConfig.ExportFraming = DefaultExportFraming
+	}
+	if serviceConfig.ExportEncoding == "" {
+		serviceConfig.ExportEncoding = DefaultExportEncoding
+	}
+	if len(serviceConfig.ExportOverflowPolicy) == 0 {
+		serviceConfig.ExportOverflowPolicy = DefaultExportOverflowPolicy
+	}
+	if len(serviceConfig.PcapDirectory) == 0 {
+		serviceConfig.PcapDirectory = DefaultPcapDirectory
+	}
+	if serviceConfig.PcapRotationSizeMB == 0 {
+		serviceConfig.PcapRotationSizeMB = DefaultPcapRotationSizeMB
+	}
+	if serviceConfig.PcapRetentionHours == 0 {
+		serviceConfig.PcapRetentionHours = DefaultPcapRetentionHours
+	}
+	if len(serviceConfig.EventSources) == 0 {
+		serviceConfig.EventSources = []string{DefaultEventSource}
+	}
+	if serviceConfig.IngestBufferSize == 0 {
+		serviceConfig.IngestBufferSize = DefaultIngestBufferSize
+	}
+	if serviceConfig.IngestFlushIntervalMs == 0 {
+		serviceConfig.IngestFlushIntervalMs = DefaultIngestFlushIntervalMs
+	}
+	if serviceConfig.SubscriptionPollIntervalSecs == 0 {
+		serviceConfig.SubscriptionPollIntervalSecs = DefaultSubscriptionPollIntervalSecs
+	}
+	if serviceConfig.SubscriptionStaleSecs == 0 {
+		serviceConfig.SubscriptionStaleSecs = DefaultSubscriptionStaleSecs
+	}
+	if serviceConfig.DeliveryAuditRetentionDays == 0 {
+		serviceConfig.DeliveryAuditRetentionDays = DefaultDeliveryAuditRetentionDays
+	}
+	if serviceConfig.BearerCorrelationRetentionDays == 0 {
+		serviceConfig.BearerCorrelationRetentionDays = DefaultBearerCorrelationRetentionDays
+	}
+	if serviceConfig.LeaseDurationSecs == 0 {
+		serviceConfig.LeaseDurationSecs = DefaultLeaseDurationSecs
+	}
+	if serviceConfig.ConfigReloadIntervalSecs == 0 {
+		serviceConfig.ConfigReloadIntervalSecs = DefaultConfigReloadIntervalSecs
+	}
+	if serviceConfig.HealthWindowSecs == 0 {
+		serviceConfig.HealthWindowSecs = DefaultHealthWindowSecs
+	}
+	if serviceConfig.HealthEncodeFailureMin == 0 {
+		serviceConfig.HealthEncodeFailureMin = DefaultHealthEncodeFailureMin
+	}
+	if serviceConfig.HealthEncodeFailurePercent == 0 {
+		serviceConfig.HealthEncodeFailurePercent = DefaultHealthEncodeFailurePercent
+	}
+	if serviceConfig.AlertSyslogNetwork == "" {
+		serviceConfig.AlertSyslogNetwork = DefaultAlertSyslogNetwork
+	}
+	if serviceConfig.AlertFormat == "" {
+		serviceConfig.AlertFormat = DefaultAlertFormat
+	}
+	if serviceConfig.AlertRepeatIntervalSecs == 0 {
+		serviceConfig.AlertRepeatIntervalSecs = DefaultAlertRepeatIntervalSecs
+	}
+	if serviceConfig.AlertCertificateExpiryDays == 0 {
+		serviceConfig.AlertCertificateExpiryDays = DefaultAlertCertificateExpiryDays
+	}
+	if serviceConfig.ClockSource == "" {
+		serviceConfig.ClockSource = DefaultClockSource
+	}
+	if serviceConfig.ClockSkewThresholdMs == 0 {
+		serviceConfig.ClockSkewThresholdMs = DefaultClockSkewThresholdMs
+	}
+	if serviceConfig.ClockCheckIntervalSecs == 0 {
+		serviceConfig.ClockCheckIntervalSecs = DefaultClockCheckIntervalSecs
+	}
+	if serviceConfig.TracingSampleRatio == 0 {
+		serviceConfig.TracingSampleRatio = DefaultTracingSampleRatio
+	}
+	return serviceConfig, path, nil
 }
@@ -0,0 +1,154 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nprobe
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/orc8r/lib/go/service/config"
+)
+
+// ConfigUpdate describes a change of the service config
+type ConfigUpdate struct {
+	Previous Config
+	Current  Config
+	// CertificatesChanged is true if the content of the exporter
+	// certificate or key files changed
+	CertificatesChanged bool
+}
+
+// ConfigWatcher reloads the service config when its files or the exporter
+// certificates change, so that settings can be changed without restarting
+// the service and losing the live interception state.
+type ConfigWatcher struct {
+	current  Config
+	path     string
+	files    map[string]fileVersion
+	interval time.Duration
+	trigger  chan struct{}
+}
+
+// fileVersion identifies the content of a watched file
+type fileVersion struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// NewConfigWatcher creates a watcher of the service config the service was
+// started with
+func NewConfigWatcher(initial Config) *ConfigWatcher {
+	return &ConfigWatcher{
+		current:  initial,
+		interval: time.Duration(initial.ConfigReloadIntervalSecs) * time.Second,
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+// Run checks the config files every ConfigReloadIntervalSecs, or when Reload
+// is called, until ctx is cancelled. onChange is called with each change of
+// the config from the same goroutine.
+func (w *ConfigWatcher) Run(ctx context.Context, onChange func(update ConfigUpdate)) {
+	w.check(true, onChange)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		force := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.trigger:
+			force = true
+		}
+		w.check(force, onChange)
+	}
+}
+
+// Reload requests the config to be reloaded even if its files didn't change
+func (w *ConfigWatcher) Reload() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// check reloads the config if its files changed or if forced and notifies
+// the changes. A config which fails to load is ignored until its files
+// change again.
+func (w *ConfigWatcher) check(force bool, onChange func(update ConfigUpdate)) {
+	if !force && reflect.DeepEqual(w.getFileVersions(w.current), w.files) {
+		return
+	}
+	config, path, err := loadServiceConfig()
+	if err != nil {
+		logger.Errorf("Failed to reload nprobe config: %v", err)
+		w.files = w.getFileVersions(w.current)
+		return
+	}
+	w.path = path
+
+	files := w.getFileVersions(config)
+	certsChanged := w.files != nil &&
+		(files[config.ExporterCrtFile] != w.files[config.ExporterCrtFile] ||
+			files[config.ExporterKeyFile] != w.files[config.ExporterKeyFile])
+	w.files = files
+	if !certsChanged && reflect.DeepEqual(config, w.current) {
+		return
+	}
+
+	if config.ConfigReloadIntervalSecs != w.current.ConfigReloadIntervalSecs ||
+		config.ShutdownTimeoutSecs != w.current.ShutdownTimeoutSecs ||
+		config.SnapshotKeyFile != w.current.SnapshotKeyFile ||
+		config.LeaderElection != w.current.LeaderElection ||
+		config.LeaseDurationSecs != w.current.LeaseDurationSecs ||
+		config.StateBackend != w.current.StateBackend {
+		logger.Warningf("Changes of config_reload_interval_secs, shutdown_timeout_secs, snapshot_key, leader_election, lease_duration_secs and state_backend apply on restart")
+	}
+	logger.Infof("Applying reloaded nprobe config")
+	update := ConfigUpdate{Previous: w.current, Current: config, CertificatesChanged: certsChanged}
+	w.current = config
+	onChange(update)
+}
+
+// getFileVersions returns the versions of the config files and of the
+// certificate files of a config
+func (w *ConfigWatcher) getFileVersions(c Config) map[string]fileVersion {
+	_, _, overrideDir := config.GetCurrentConfigDirectories()
+	paths := []string{
+		w.path,
+		filepath.Join(overrideDir, lte.ModuleName, ServiceName+".yml"),
+		c.ExporterCrtFile,
+		c.ExporterKeyFile,
+	}
+
+	versions := map[string]fileVersion{}
+	for _, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			versions[path] = fileVersion{}
+			continue
+		}
+		versions[path] = fileVersion{exists: true, modTime: info.ModTime(), size: info.Size()}
+	}
+	return versions
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nprobe
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"magma/orc8r/lib/go/service/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestConfigWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	mainDir, legacyDir, overrideDir := filepath.Join(dir, "configs"), filepath.Join(dir, "legacy"), filepath.Join(dir, "override")
+	for _, d := range []string{mainDir, legacyDir, overrideDir} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(d, "lte"), 0700))
+	}
+	main, legacy, overwrite := config.GetCurrentConfigDirectories()
+	config.SetConfigDirectories(mainDir, legacyDir, overrideDir)
+	defer config.SetConfigDirectories(main, legacy, overwrite)
+
+	start := time.Now().Add(-time.Hour)
+	configFile := filepath.Join(mainDir, "lte", "nprobe.yml")
+	crtFile := filepath.Join(dir, "client.crt")
+	writeFile(t, crtFile, "crt", start)
+	writeFile(t, configFile, "update_interval_secs: 60\nexporter_crt: "+crtFile+"\n", start)
+
+	initial, _, err := loadServiceConfig()
+	assert.NoError(t, err)
+	var updates []ConfigUpdate
+	onChange := func(update ConfigUpdate) {
+		updates = append(updates, update)
+	}
+	w := NewConfigWatcher(initial)
+	w.check(true, onChange)
+	w.check(false, onChange)
+	assert.Empty(t, updates)
+
+	// changed config file
+	writeFile(t, configFile, "update_interval_secs: 30\nexporter_crt: "+crtFile+"\n", start.Add(time.Minute))
+	w.check(false, onChange)
+	assert.Len(t, updates, 1)
+	assert.Equal(t, uint32(60), updates[0].Previous.UpdateIntervalSecs)
+	assert.Equal(t, uint32(30), updates[0].Current.UpdateIntervalSecs)
+	assert.Equal(t, uint32(DefaultBackOffIntervalSecs), updates[0].Current.BackOffIntervalSecs)
+	assert.False(t, updates[0].CertificatesChanged)
+
+	// override file without any change of the settings
+	writeFile(t, filepath.Join(overrideDir, "lte", "nprobe.yml"), "update_interval_secs: 30\n", start)
+	w.check(false, onChange)
+	assert.Len(t, updates, 1)
+
+	// rotated certificate
+	writeFile(t, crtFile, "new crt", start.Add(time.Minute))
+	w.check(false, onChange)
+	assert.Len(t, updates, 2)
+	assert.Equal(t, updates[1].Previous, updates[1].Current)
+	assert.True(t, updates[1].CertificatesChanged)
+
+	// forced reload without any change
+	w.Reload()
+	w.check(true, onChange)
+	assert.Len(t, updates, 2)
+}
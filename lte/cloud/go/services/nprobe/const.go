@@ -16,6 +16,12 @@ package nprobe
 const (
 	ESStreamMME      = "mme"
 	ESStreamSessionD = "sessiond"
+	// ESStreamPipelined is the stream of the usage of the flows of the
+	// sessions, reported by pipelined
+	ESStreamPipelined = "pipelined"
+	// ESStreamSGSN is the stream of the events of the PDP contexts of the
+	// subscribers attached to an interworking GPRS/UMTS core
+	ESStreamSGSN = "sgsn"
 
 	AttachSuccess        = "attach_success"
 	DetachSuccess        = "detach_success"
@@ -24,11 +30,37 @@ const (
 	SessionTerminated    = "session_terminated"
 	S1SetupSuccess       = "s1_setup_success"
 	SessionCreateFailure = "session_create_failure"
+
+	PDNConnectivityRequested  = "pdn_connectivity_requested"
+	PDNDisconnectionRequested = "pdn_disconnection_requested"
+	TrackingAreaUpdate        = "tracking_area_update"
+	ServingSystemChanged      = "serving_system_changed"
+	HandoverSuccess           = "handover_success"
+	SMSTransferred            = "sms_transferred"
+	// UpdateLocationSuccess is the S6a update location of a target answered
+	// by its HSS, reporting the PLMN the target visits and the realm of its
+	// serving MME
+	UpdateLocationSuccess = "update_location_success"
+
+	// FlowUsageReported is the periodic report of the bytes a flow of a
+	// session carried so far. It is not intercepted itself but makes up the
+	// usage reports of the sessions.
+	FlowUsageReported = "flow_usage_reported"
+
+	// TargetReported is the event composed by the service to report the
+	// location and state of the target of a one-shot task
+	TargetReported = "target_reported"
+	// UsageReported is the event composed by the service to report the
+	// usage of an intercepted session at the interval of its task
+	UsageReported = "usage_reported"
+	// BearerActive is the event composed by the service to report a bearer
+	// of the target already active when the interception of its task starts
+	BearerActive = "bearer_active"
 )
 
 // GetESStreams returns the list of Intercepted streams
 func GetESStreams() []string {
-	return []string{ESStreamMME, ESStreamSessionD}
+	return []string{ESStreamMME, ESStreamSessionD, ESStreamPipelined, ESStreamSGSN}
 }
 
 // GetESEventTypes returns the list of Intercepted events
@@ -41,5 +73,13 @@ func GetESEventTypes() []string {
 		SessionTerminated,
 		SessionCreateFailure,
 		S1SetupSuccess,
+		PDNConnectivityRequested,
+		PDNDisconnectionRequested,
+		TrackingAreaUpdate,
+		ServingSystemChanged,
+		HandoverSuccess,
+		SMSTransferred,
+		UpdateLocationSuccess,
+		FlowUsageReported,
 	}
 }
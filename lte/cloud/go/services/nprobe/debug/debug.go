@@ -0,0 +1,226 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package debug holds the runtime debug settings of the nprobe service.
+// Settings are kept in memory and reverted once they expire so that
+// production debugging does not require a restart.
+package debug
+
+import (
+	"flag"
+	"strconv"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+var logger = logging.New(logging.ComponentMain)
+
+// DefaultExpirySecs is the default lifetime of debug settings in seconds
+const DefaultExpirySecs = 600
+
+// MaxCapturedRecords is the number of recent records kept per debugged task
+const MaxCapturedRecords = 100
+
+// Settings tracks the active debug settings of each network.
+// The glog verbosity and the verbosity of the components are process-wide
+// and set to the highest level requested across networks.
+type Settings struct {
+	mutex         sync.Mutex
+	baseVerbosity uint64
+	configs       map[string]*models.NetworkProbeDebugConfig
+	timers        map[string]*time.Timer
+	records       map[string]map[string][][]byte
+}
+
+// NewSettings creates new debug settings. The verbosity in use at creation
+// is restored once no debug settings are active.
+func NewSettings() *Settings {
+	var base uint64
+	if f := flag.Lookup("v"); f != nil {
+		base, _ = strconv.ParseUint(f.Value.String(), 10, 32)
+	}
+	return &Settings{
+		baseVerbosity: base,
+		configs:       map[string]*models.NetworkProbeDebugConfig{},
+		timers:        map[string]*time.Timer{},
+		records:       map[string]map[string][][]byte{},
+	}
+}
+
+// Set replaces the debug settings of a network and schedules their expiry.
+func (s *Settings) Set(networkID string, config *models.NetworkProbeDebugConfig) error {
+	cfg := *config
+	if cfg.ExpirySecs == 0 {
+		cfg.ExpirySecs = DefaultExpirySecs
+	}
+	expiry := time.Duration(cfg.ExpirySecs) * time.Second
+	cfg.ExpiresAt = strfmt.DateTime(time.Now().Add(expiry).UTC())
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if timer, ok := s.timers[networkID]; ok {
+		timer.Stop()
+	}
+	s.configs[networkID] = &cfg
+	s.timers[networkID] = time.AfterFunc(expiry, func() { s.expire(networkID, &cfg) })
+	return s.applyVerbosity()
+}
+
+// Get returns the active debug settings of a network, nil if none.
+func (s *Settings) Get(networkID string) *models.NetworkProbeDebugConfig {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	cfg, ok := s.configs[networkID]
+	if !ok {
+		return nil
+	}
+	ret := *cfg
+	return &ret
+}
+
+// Clear reverts the debug settings of a network.
+func (s *Settings) Clear(networkID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.remove(networkID)
+	return s.applyVerbosity()
+}
+
+// IsTaskEnabled returns true if frame-level logging is enabled for the task.
+func (s *Settings) IsTaskEnabled(networkID, taskID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	cfg, ok := s.configs[networkID]
+	if !ok {
+		return false
+	}
+	for _, id := range cfg.TaskIds {
+		if string(id) == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDestinations returns the destinations for which frame-level
+// logging is enabled in a network.
+func (s *Settings) GetDestinations(networkID string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	cfg, ok := s.configs[networkID]
+	if !ok {
+		return nil
+	}
+	ret := make([]string, 0, len(cfg.DestinationIds))
+	for _, id := range cfg.DestinationIds {
+		ret = append(ret, string(id))
+	}
+	return ret
+}
+
+// CaptureRecord keeps an exported record of a debugged task, dropping the
+// oldest one once MaxCapturedRecords are kept. Records are only kept while
+// the debug settings of the network are active.
+func (s *Settings) CaptureRecord(networkID, taskID string, record []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.configs[networkID]; !ok {
+		return
+	}
+	if s.records[networkID] == nil {
+		s.records[networkID] = map[string][][]byte{}
+	}
+	records := append(s.records[networkID][taskID], append([]byte(nil), record...))
+	if len(records) > MaxCapturedRecords {
+		records = records[len(records)-MaxCapturedRecords:]
+	}
+	s.records[networkID][taskID] = records
+}
+
+// GetRecords returns up to count of the most recent records captured for a
+// task, oldest first.
+func (s *Settings) GetRecords(networkID, taskID string, count int) [][]byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	records := s.records[networkID][taskID]
+	if count < len(records) {
+		records = records[len(records)-count:]
+	}
+	return append([][]byte{}, records...)
+}
+
+// CapturedRecords returns the number of records captured across networks
+// and their size in bytes
+func (s *Settings) CapturedRecords() (int, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	records, bytes := 0, 0
+	for _, tasks := range s.records {
+		for _, captured := range tasks {
+			for _, record := range captured {
+				records++
+				bytes += len(record)
+			}
+		}
+	}
+	return records, bytes
+}
+
+// expire reverts the debug settings of a network if they were not
+// replaced since the expiry was scheduled.
+func (s *Settings) expire(networkID string, cfg *models.NetworkProbeDebugConfig) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.configs[networkID] != cfg {
+		return
+	}
+	logger.Infof("Debug settings of network %s expired", networkID)
+	s.remove(networkID)
+	if err := s.applyVerbosity(); err != nil {
+		logger.Errorf("Failed to restore log verbosity: %v", err)
+	}
+}
+
+func (s *Settings) remove(networkID string) {
+	if timer, ok := s.timers[networkID]; ok {
+		timer.Stop()
+	}
+	delete(s.timers, networkID)
+	delete(s.configs, networkID)
+	delete(s.records, networkID)
+}
+
+// applyVerbosity sets glog verbosity to the highest level requested,
+// or back to the base level when no debug settings are active. The
+// verbosity of each component is likewise the highest one requested for it.
+func (s *Settings) applyVerbosity() error {
+	level := s.baseVerbosity
+	components := map[string]uint32{}
+	for _, cfg := range s.configs {
+		if uint64(cfg.Verbosity) > level {
+			level = uint64(cfg.Verbosity)
+		}
+		for component, verbosity := range cfg.ComponentVerbosity {
+			if verbosity > components[component] {
+				components[component] = verbosity
+			}
+		}
+	}
+	logging.SetDebugLevels(components)
+	return flag.Set("v", strconv.FormatUint(level, 10))
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/golang/glog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettings(t *testing.T) {
+	assert.NoError(t, flag.Set("v", "0"))
+	settings := NewSettings()
+	assert.Nil(t, settings.Get("n1"))
+	assert.False(t, settings.IsTaskEnabled("n1", "task1"))
+
+	err := settings.Set("n1", &models.NetworkProbeDebugConfig{
+		Verbosity:          4,
+		ComponentVerbosity: map[string]uint32{logging.ComponentExporter: 6},
+		TaskIds:            []models.NetworkProbeTaskID{"task1"},
+		DestinationIds:     []models.NetworkProbeDestinationID{"dest1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "4", flag.Lookup("v").Value.String())
+	assert.Equal(t, glog.Level(6), logging.GetLevel(logging.ComponentExporter))
+	assert.True(t, settings.IsTaskEnabled("n1", "task1"))
+	assert.False(t, settings.IsTaskEnabled("n1", "task2"))
+	assert.False(t, settings.IsTaskEnabled("n2", "task1"))
+	assert.Equal(t, []string{"dest1"}, settings.GetDestinations("n1"))
+
+	cfg := settings.Get("n1")
+	assert.NotNil(t, cfg)
+	assert.Equal(t, uint32(DefaultExpirySecs), cfg.ExpirySecs)
+	assert.False(t, time.Time(cfg.ExpiresAt).IsZero())
+
+	// highest verbosity across networks wins
+	err = settings.Set("n2", &models.NetworkProbeDebugConfig{
+		Verbosity:          2,
+		ComponentVerbosity: map[string]uint32{logging.ComponentExporter: 3, logging.ComponentManager: 5},
+		ExpirySecs:         60,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "4", flag.Lookup("v").Value.String())
+	assert.Equal(t, glog.Level(6), logging.GetLevel(logging.ComponentExporter))
+	assert.Equal(t, glog.Level(5), logging.GetLevel(logging.ComponentManager))
+
+	assert.NoError(t, settings.Clear("n1"))
+	assert.Nil(t, settings.Get("n1"))
+	assert.False(t, settings.IsTaskEnabled("n1", "task1"))
+	assert.Equal(t, "2", flag.Lookup("v").Value.String())
+	assert.Equal(t, glog.Level(3), logging.GetLevel(logging.ComponentExporter))
+
+	assert.NoError(t, settings.Clear("n2"))
+	assert.Equal(t, "0", flag.Lookup("v").Value.String())
+	assert.Equal(t, glog.Level(0), logging.GetLevel(logging.ComponentManager))
+}
+
+func TestCaptureRecords(t *testing.T) {
+	settings := NewSettings()
+	// records aren't kept without active debug settings
+	settings.CaptureRecord("n1", "task1", []byte{0})
+	assert.Empty(t, settings.GetRecords("n1", "task1", MaxCapturedRecords))
+
+	assert.NoError(t, settings.Set("n1", &models.NetworkProbeDebugConfig{TaskIds: []models.NetworkProbeTaskID{"task1"}}))
+	for i := 0; i < MaxCapturedRecords+2; i++ {
+		settings.CaptureRecord("n1", "task1", []byte{byte(i)})
+	}
+	assert.Equal(t, [][]byte{{MaxCapturedRecords}, {MaxCapturedRecords + 1}}, settings.GetRecords("n1", "task1", 2))
+	records := settings.GetRecords("n1", "task1", 2*MaxCapturedRecords)
+	assert.Len(t, records, MaxCapturedRecords)
+	assert.Equal(t, []byte{2}, records[0])
+	assert.Empty(t, settings.GetRecords("n1", "task2", 2))
+	count, size := settings.CapturedRecords()
+	assert.Equal(t, MaxCapturedRecords, count)
+	assert.Equal(t, MaxCapturedRecords, size)
+
+	assert.NoError(t, settings.Clear("n1"))
+	assert.Empty(t, settings.GetRecords("n1", "task1", MaxCapturedRecords))
+	count, _ = settings.CapturedRecords()
+	assert.Zero(t, count)
+}
+
+func TestRuntime(t *testing.T) {
+	runtime := NewRuntime()
+	runtime.Register("export_queue", func() (int, int) { return 2, 300 })
+	runtime.Register("ingest", func() (int, int) { return 5, 0 })
+
+	stats := runtime.Get()
+	assert.NoError(t, stats.Validate(strfmt.Default))
+	assert.True(t, stats.Goroutines > 0)
+	assert.True(t, stats.HeapAllocBytes > 0)
+	assert.Equal(t, []*models.NetworkProbeSubsystemStats{
+		{Name: "export_queue", Items: 2, Bytes: 300},
+		{Name: "ingest", Items: 5},
+	}, stats.Subsystems)
+}
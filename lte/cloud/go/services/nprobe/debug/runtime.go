@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// SubsystemStats returns the items a subsystem holds in memory, e.g. its
+// queued records, and their size in bytes, 0 if unknown
+type SubsystemStats func() (int, int)
+
+// Runtime collects the runtime statistics of the process along with the
+// memory held by the registered subsystems, so that the growth of the heap
+// can be attributed without profiling.
+type Runtime struct {
+	mutex      sync.Mutex
+	subsystems map[string]SubsystemStats
+}
+
+// NewRuntime creates a new collector without subsystems
+func NewRuntime() *Runtime {
+	return &Runtime{subsystems: map[string]SubsystemStats{}}
+}
+
+// Register registers the statistics of a subsystem, replacing the ones
+// registered under the same name
+func (r *Runtime) Register(name string, stats SubsystemStats) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.subsystems[name] = stats
+}
+
+// Get returns the current runtime statistics. The world is briefly stopped
+// to read the memory statistics.
+func (r *Runtime) Get() *models.NetworkProbeRuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	ret := &models.NetworkProbeRuntimeStats{
+		Goroutines:      int64(runtime.NumGoroutine()),
+		HeapAllocBytes:  mem.HeapAlloc,
+		HeapInuseBytes:  mem.HeapInuse,
+		HeapObjects:     mem.HeapObjects,
+		HeapSysBytes:    mem.HeapSys,
+		StackInuseBytes: mem.StackInuse,
+		GcCycles:        mem.NumGC,
+		Subsystems:      []*models.NetworkProbeSubsystemStats{},
+	}
+	if mem.LastGC != 0 {
+		ret.LastGc = strfmt.DateTime(time.Unix(0, int64(mem.LastGC)).UTC())
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for name, stats := range r.subsystems {
+		items, bytes := stats()
+		ret.Subsystems = append(ret.Subsystems, &models.NetworkProbeSubsystemStats{
+			Name:  name,
+			Items: int64(items),
+			Bytes: int64(bytes),
+		})
+	}
+	sort.Slice(ret.Subsystems, func(i, j int) bool { return ret.Subsystems[i].Name < ret.Subsystems[j].Name })
+	return ret
+}
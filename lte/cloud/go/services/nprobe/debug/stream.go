@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// MaxStreamWatchers is the number of clients which may watch the
+	// exported records at once
+	MaxStreamWatchers = 8
+	// StreamBuffer is the number of records buffered for each watcher,
+	// beyond which the records are dropped for that watcher
+	StreamBuffer = 64
+)
+
+// ErrTooManyWatchers is returned when MaxStreamWatchers already watch the
+// exported records
+var ErrTooManyWatchers = errors.New("too many record stream watchers")
+
+// StreamedRecord is a record exported for a task, as pushed to the watchers
+type StreamedRecord struct {
+	NetworkID  string
+	TaskID     string
+	Record     []byte
+	ExportedAt time.Time
+}
+
+// RecordStream pushes the exported records to the clients watching them
+// live, e.g. while validating a new LEMF integration. Records are only
+// copied while watched, and dropped for the watchers too slow to keep up so
+// that the delivery is never held back.
+type RecordStream struct {
+	mutex    sync.Mutex
+	watchers map[*Watcher]bool
+	// watching is the number of watchers, read without the mutex on every
+	// exported record
+	watching int32
+}
+
+// Watcher receives the records exported for a network, or for a task
+type Watcher struct {
+	stream    *RecordStream
+	networkID string
+	taskID    string
+	records   chan StreamedRecord
+	dropped   uint64
+}
+
+// NewRecordStream creates a new stream without watchers
+func NewRecordStream() *RecordStream {
+	return &RecordStream{watchers: map[*Watcher]bool{}}
+}
+
+// Watch registers a watcher of the records exported for a network, or for
+// all networks if empty, optionally only those of a task. It must be closed
+// once done.
+func (s *RecordStream) Watch(networkID, taskID string) (*Watcher, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.watchers) >= MaxStreamWatchers {
+		return nil, ErrTooManyWatchers
+	}
+	w := &Watcher{
+		stream:    s,
+		networkID: networkID,
+		taskID:    taskID,
+		records:   make(chan StreamedRecord, StreamBuffer),
+	}
+	s.watchers[w] = true
+	atomic.StoreInt32(&s.watching, int32(len(s.watchers)))
+	return w, nil
+}
+
+// Watching returns the number of watchers
+func (s *RecordStream) Watching() int {
+	return int(atomic.LoadInt32(&s.watching))
+}
+
+// Publish pushes a record exported for a task to its watchers
+func (s *RecordStream) Publish(networkID, taskID string, record []byte, exportedAt time.Time) {
+	if s.Watching() == 0 {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var streamed *StreamedRecord
+	for w := range s.watchers {
+		if (len(w.networkID) != 0 && w.networkID != networkID) || (len(w.taskID) != 0 && w.taskID != taskID) {
+			continue
+		}
+		if streamed == nil {
+			streamed = &StreamedRecord{
+				NetworkID:  networkID,
+				TaskID:     taskID,
+				Record:     append([]byte(nil), record...),
+				ExportedAt: exportedAt,
+			}
+		}
+		select {
+		case w.records <- *streamed:
+		default:
+			atomic.AddUint64(&w.dropped, 1)
+		}
+	}
+}
+
+// Records returns the records exported since the watcher was registered
+func (w *Watcher) Records() <-chan StreamedRecord {
+	return w.records
+}
+
+// Dropped returns the number of records dropped for the watcher since it
+// was registered
+func (w *Watcher) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Close unregisters the watcher
+func (w *Watcher) Close() {
+	w.stream.mutex.Lock()
+	defer w.stream.mutex.Unlock()
+	delete(w.stream.watchers, w)
+	atomic.StoreInt32(&w.stream.watching, int32(len(w.stream.watchers)))
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordStream(t *testing.T) {
+	stream := NewRecordStream()
+	exportedAt := time.Unix(1615000000, 0)
+	// records are not copied while not watched
+	stream.Publish("n1", "task1", []byte{0x01}, exportedAt)
+
+	all, err := stream.Watch("", "")
+	assert.NoError(t, err)
+	task1, err := stream.Watch("n1", "task1")
+	assert.NoError(t, err)
+	n2, err := stream.Watch("n2", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stream.Watching())
+
+	record := []byte{0x02}
+	stream.Publish("n1", "task1", record, exportedAt)
+	stream.Publish("n1", "task2", []byte{0x03}, exportedAt)
+	record[0] = 0xff
+	assert.Equal(t, StreamedRecord{NetworkID: "n1", TaskID: "task1", Record: []byte{0x02}, ExportedAt: exportedAt}, <-all.Records())
+	assert.Equal(t, []byte{0x03}, (<-all.Records()).Record)
+	assert.Equal(t, []byte{0x02}, (<-task1.Records()).Record)
+	assert.Empty(t, task1.Records())
+	assert.Empty(t, n2.Records())
+
+	// the records are dropped for the watchers which can't keep up
+	for i := 0; i < StreamBuffer+2; i++ {
+		stream.Publish("n1", "task1", record, exportedAt)
+	}
+	assert.Len(t, all.Records(), StreamBuffer)
+	assert.Equal(t, uint64(2), all.Dropped())
+	assert.Zero(t, n2.Dropped())
+
+	all.Close()
+	task1.Close()
+	n2.Close()
+	assert.Zero(t, stream.Watching())
+
+	for i := 0; i < MaxStreamWatchers; i++ {
+		_, err = stream.Watch("", "")
+		assert.NoError(t, err)
+	}
+	_, err = stream.Watch("n1", "")
+	assert.Equal(t, ErrTooManyWatchers, err)
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package declarative exports and applies the declarative nprobe configuration
+// of a network, i.e. its delivery settings and destinations, so that it can be
+// versioned and converged from a git repository. Tasks are operational state
+// and are left out, as are templates and policies until the service has any.
+package declarative
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/orc8r/cloud/go/services/configurator"
+	"magma/orc8r/cloud/go/storage"
+	merrors "magma/orc8r/lib/go/errors"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// InvalidConfigError is returned by Apply when the desired configuration
+// can't be applied on top of the current one
+type InvalidConfigError struct {
+	err error
+}
+
+func (e *InvalidConfigError) Error() string {
+	return e.err.Error()
+}
+
+// Export returns the declarative configuration of a network, its
+// destinations sorted by ID so that exports only differ on changes. The
+// payload encryption keys of the destinations are replaced by their ID, and
+// kept when applied back.
+func Export(networkID string) (*models.NetworkProbeDeclarativeConfig, error) {
+	config, err := load(networkID)
+	if err != nil {
+		return nil, err
+	}
+	for _, destination := range config.Destinations {
+		destination.DestinationDetails.RedactPayloadEncryptionKey()
+	}
+	return config, nil
+}
+
+// load returns the declarative configuration of a network along with the
+// payload encryption keys of its destinations
+func load(networkID string) (*models.NetworkProbeDeclarativeConfig, error) {
+	config := &models.NetworkProbeDeclarativeConfig{Destinations: []*models.NetworkProbeDestination{}}
+
+	networkConfig, err := configurator.LoadNetworkConfig(networkID, lte.NetworkProbeConfigType, serdes.Network)
+	switch {
+	case err == merrors.ErrNotFound:
+	case err != nil:
+		return nil, errors.Wrap(err, "failed to load NetworkProbeNetworkConfig")
+	default:
+		config.NetworkConfig = networkConfig.(*models.NetworkProbeNetworkConfig)
+	}
+
+	ents, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeDestinationEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load NetworkProbeDestinations")
+	}
+	for _, ent := range ents {
+		config.Destinations = append(config.Destinations, (&models.NetworkProbeDestination{}).FromBackendModels(ent))
+	}
+	sort.Slice(config.Destinations, func(i, j int) bool {
+		return config.Destinations[i].DestinationID < config.Destinations[j].DestinationID
+	})
+	return config, nil
+}
+
+// Diff returns the changes turning the current configuration of a network
+// into the desired one. Destinations are matched by ID.
+func Diff(current, desired *models.NetworkProbeDeclarativeConfig) *models.NetworkProbeConfigDiff {
+	diff := &models.NetworkProbeConfigDiff{
+		Created: []string{},
+		Updated: []string{},
+		Deleted: []string{},
+	}
+	diff.NetworkConfigChanged = !isEqual(current.NetworkConfig, desired.NetworkConfig)
+
+	existing := map[models.NetworkProbeDestinationID]*models.NetworkProbeDestination{}
+	for _, destination := range current.Destinations {
+		existing[destination.DestinationID] = destination
+	}
+	for _, destination := range desired.Destinations {
+		previous, ok := existing[destination.DestinationID]
+		delete(existing, destination.DestinationID)
+		switch {
+		case !ok:
+			diff.Created = append(diff.Created, string(destination.DestinationID))
+		case !isEqual(previous.DestinationDetails, destination.DestinationDetails):
+			diff.Updated = append(diff.Updated, string(destination.DestinationID))
+		}
+	}
+	for destinationID := range existing {
+		diff.Deleted = append(diff.Deleted, string(destinationID))
+	}
+	sort.Strings(diff.Created)
+	sort.Strings(diff.Updated)
+	sort.Strings(diff.Deleted)
+	return diff
+}
+
+// Apply converges the configuration of a network to the desired one and
+// returns the changes. On a dry run, the changes are only computed.
+// Destinations are created and updated in a single transaction before the
+// others are deleted, so that a failure never leaves a network with fewer
+// destinations than either configuration.
+func Apply(networkID string, desired *models.NetworkProbeDeclarativeConfig, dryRun bool) (*models.NetworkProbeConfigDiff, error) {
+	current, err := load(networkID)
+	if err != nil {
+		return nil, err
+	}
+	existing := map[models.NetworkProbeDestinationID]*models.NetworkProbeDestinationDetails{}
+	for _, destination := range current.Destinations {
+		existing[destination.DestinationID] = destination.DestinationDetails
+	}
+	for _, destination := range desired.Destinations {
+		if err := destination.DestinationDetails.KeepPayloadEncryptionKey(existing[destination.DestinationID]); err != nil {
+			return nil, &InvalidConfigError{err: errors.Wrapf(err, "destination %s", destination.DestinationID)}
+		}
+	}
+	diff := Diff(current, desired)
+	if dryRun {
+		return diff, nil
+	}
+
+	destinations := map[string]*models.NetworkProbeDestination{}
+	for _, destination := range desired.Destinations {
+		destinations[string(destination.DestinationID)] = destination
+	}
+	var writes []configurator.EntityWriteOperation
+	for _, destinationID := range diff.Created {
+		writes = append(writes, configurator.NetworkEntity{
+			Type:   lte.NetworkProbeDestinationEntityType,
+			Key:    destinationID,
+			Config: destinations[destinationID].DestinationDetails,
+		})
+	}
+	for _, destinationID := range diff.Updated {
+		writes = append(writes, destinations[destinationID].ToEntityUpdateCriteria())
+	}
+	if len(writes) != 0 {
+		if err := configurator.WriteEntities(networkID, writes, serdes.Entity); err != nil {
+			return nil, errors.Wrap(err, "failed to write NetworkProbeDestinations")
+		}
+	}
+
+	if len(diff.Deleted) != 0 {
+		var ids storage.TKs
+		for _, destinationID := range diff.Deleted {
+			ids = append(ids, storage.TypeAndKey{Type: lte.NetworkProbeDestinationEntityType, Key: destinationID})
+		}
+		if err := configurator.DeleteEntities(networkID, ids); err != nil {
+			return nil, errors.Wrap(err, "failed to delete NetworkProbeDestinations")
+		}
+	}
+
+	if diff.NetworkConfigChanged {
+		update := configurator.NetworkUpdateCriteria{ID: networkID}
+		if desired.NetworkConfig == nil {
+			update.ConfigsToDelete = []string{lte.NetworkProbeConfigType}
+		} else {
+			update.ConfigsToAddOrUpdate = map[string]interface{}{lte.NetworkProbeConfigType: desired.NetworkConfig}
+		}
+		if err := configurator.UpdateNetworks([]configurator.NetworkUpdateCriteria{update}, serdes.Network); err != nil {
+			return nil, errors.Wrap(err, "failed to update NetworkProbeNetworkConfig")
+		}
+	}
+	diff.Applied = true
+	return diff, nil
+}
+
+// MarshalYAML serializes a configuration in its canonical YAML form: the
+// fields are named after the JSON API and sorted.
+func MarshalYAML(config *models.NetworkProbeDeclarativeConfig) ([]byte, error) {
+	content, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(content, &tree); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(tree)
+}
+
+// UnmarshalYAML deserializes a configuration in the YAML form returned by
+// MarshalYAML.
+func UnmarshalYAML(content []byte, config *models.NetworkProbeDeclarativeConfig) error {
+	var tree interface{}
+	if err := yaml.Unmarshal(content, &tree); err != nil {
+		return err
+	}
+	tree, err := toJSONTree(tree)
+	if err != nil {
+		return err
+	}
+	jsonContent, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonContent, config)
+}
+
+// toJSONTree converts the maps decoded from YAML, keyed by any value, to maps
+// keyed by strings which can be encoded in JSON
+func toJSONTree(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			strKey, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid key %v, expected a string", key)
+			}
+			converted, err := toJSONTree(item)
+			if err != nil {
+				return nil, err
+			}
+			ret[strKey] = converted
+		}
+		return ret, nil
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for i, item := range v {
+			converted, err := toJSONTree(item)
+			if err != nil {
+				return nil, err
+			}
+			ret[i] = converted
+		}
+		return ret, nil
+	}
+	return value, nil
+}
+
+// isEqual compares two models by their JSON encoding, so that unset and
+// empty fields are equal
+func isEqual(a, b interface{}) bool {
+	contentA, errA := json.Marshal(a)
+	contentB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(contentA) == string(contentB)
+}
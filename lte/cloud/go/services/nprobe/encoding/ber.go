@@ -0,0 +1,291 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// EncodingDER writes the ASN.1 elements with the definite lengths of
+	// the distinguished encoding rules, as the records are built
+	EncodingDER = "der"
+	// EncodingBER writes the constructed ASN.1 elements with indefinite
+	// lengths, terminated by end-of-contents octets, as required by some
+	// legacy LEMFs. Primitive elements keep their definite lengths.
+	EncodingBER = "ber"
+)
+
+// maxBERDepth bounds the nesting of the elements reencoded
+const maxBERDepth = 64
+
+var errBERTruncated = errors.New("truncated BER element")
+
+const (
+	// maxDecodedElements bounds the ASN.1 elements of the payloads decoded
+	// by DecodeWithLimit, well beyond those of the records built
+	maxDecodedElements = 4096
+	// budgetCheckInterval is the number of elements walked between two
+	// checks of the context
+	budgetCheckInterval = 64
+)
+
+// errTooManyElements is returned by DecodeWithLimit for payloads holding more
+// than maxDecodedElements elements
+var errTooManyElements = errors.New("too many BER elements")
+
+// berElement is an element of a BER encoding, holding its value when
+// primitive and the elements it contains when constructed
+type berElement struct {
+	identifier []byte
+	value      []byte
+	elements   []berElement
+}
+
+func (e *berElement) constructed() bool {
+	return e.identifier[0]&constructedForm != 0
+}
+
+// ValidateEncoding returns an encoding, EncodingDER if empty, or an error if
+// it isn't supported
+func ValidateEncoding(encoding string) (string, error) {
+	switch encoding {
+	case "":
+		return EncodingDER, nil
+	case EncodingDER, EncodingBER:
+		return encoding, nil
+	}
+	return "", fmt.Errorf("unsupported encoding %s", encoding)
+}
+
+// Reencode converts a sequence of BER elements, of definite or indefinite
+// lengths, to an encoding. Only the lengths are rewritten: EncodingDER
+// writes the minimal definite lengths, so that the records as built are left
+// unchanged, while EncodingBER writes the indefinite lengths of the
+// constructed elements.
+func Reencode(b []byte, encoding string) ([]byte, error) {
+	encoding, err := ValidateEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	elements, _, err := parseBERElements(b, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	return appendBERElements(make([]byte, 0, len(b)+len(b)/8), elements, encoding == EncodingBER), nil
+}
+
+// ReencodeRecord converts the payload of an encoded record to an encoding,
+// the payload length of its header being updated accordingly. PDUs without
+// payload, e.g. keepalives, are returned unchanged.
+func ReencodeRecord(record []byte, encoding string) ([]byte, error) {
+	hdr, err := ParsePDUHeader(record)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.PayloadLength == 0 {
+		return record, nil
+	}
+	if uint64(hdr.HeaderLength)+uint64(hdr.PayloadLength) > uint64(len(record)) {
+		return nil, errors.New("invalid input size")
+	}
+	payload, err := Reencode(record[hdr.HeaderLength:hdr.HeaderLength+hdr.PayloadLength], encoding)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]byte, int(hdr.HeaderLength)+len(payload))
+	copy(ret, record[:hdr.HeaderLength])
+	binary.BigEndian.PutUint32(ret[8:12], uint32(len(payload)))
+	copy(ret[hdr.HeaderLength:], payload)
+	return ret, nil
+}
+
+// parseBERElements parses the elements of b up to its end, or up to the
+// end-of-contents octets closing an indefinite length, returning the bytes
+// following them
+func parseBERElements(b []byte, depth int, indefinite bool) ([]berElement, []byte, error) {
+	if depth > maxBERDepth {
+		return nil, nil, errors.New("BER elements nested too deep")
+	}
+	var elements []berElement
+	for {
+		if len(b) == 0 {
+			if indefinite {
+				return nil, nil, errors.New("missing BER end-of-contents")
+			}
+			return elements, nil, nil
+		}
+		if indefinite && len(b) >= 2 && b[0] == 0 && b[1] == 0 {
+			return elements, b[2:], nil
+		}
+		element, rest, err := parseBERElement(b, depth)
+		if err != nil {
+			return nil, nil, err
+		}
+		elements = append(elements, element)
+		b = rest
+	}
+}
+
+// parseBERElement parses the element starting b and returns the bytes
+// following it
+func parseBERElement(b []byte, depth int) (berElement, []byte, error) {
+	element := berElement{}
+	identifier, n, i, err := parseBERHeader(b)
+	if err != nil {
+		return element, nil, err
+	}
+	element.identifier = identifier
+	if n < 0 {
+		elements, rest, err := parseBERElements(b[i:], depth+1, true)
+		if err != nil {
+			return element, nil, err
+		}
+		element.elements = elements
+		return element, rest, nil
+	}
+	contents := b[i : i+n]
+	if element.constructed() {
+		elements, _, err := parseBERElements(contents, depth+1, false)
+		if err != nil {
+			return element, nil, err
+		}
+		element.elements = elements
+	} else {
+		element.value = contents
+	}
+	return element, b[i+n:], nil
+}
+
+// parseBERHeader parses the identifier and length octets of the element
+// starting b. It returns the length of its contents, -1 for an indefinite
+// length, and the offset they start at.
+func parseBERHeader(b []byte) ([]byte, int, int, error) {
+	i := 1
+	if b[0]&tagHighForm == tagHighForm {
+		// high tag numbers continue while the high bit is set
+		for i < len(b) && b[i]&0x80 != 0 {
+			i++
+		}
+		i++
+	}
+	if i >= len(b) {
+		return nil, 0, 0, errBERTruncated
+	}
+	identifier := b[:i]
+	l := b[i]
+	i++
+	if l == 0x80 {
+		if identifier[0]&constructedForm == 0 {
+			return nil, 0, 0, errors.New("indefinite length of a primitive BER element")
+		}
+		return identifier, -1, i, nil
+	}
+	n := uint64(l)
+	if l&0x80 != 0 {
+		k := int(l & 0x7f)
+		if k > 4 {
+			return nil, 0, 0, fmt.Errorf("BER length of %d octets not supported", k)
+		}
+		if i+k > len(b) {
+			return nil, 0, 0, errBERTruncated
+		}
+		n = 0
+		for _, c := range b[i : i+k] {
+			n = n<<8 | uint64(c)
+		}
+		i += k
+	}
+	if uint64(len(b)-i) < n {
+		return nil, 0, 0, errBERTruncated
+	}
+	return identifier, int(n), i, nil
+}
+
+// berBudget walks the elements of an untrusted payload before it is decoded,
+// so that decoding it takes a bounded time. The walk fails once its context
+// is done, or once the payload holds more elements, or nests them deeper,
+// than the decoded records may.
+type berBudget struct {
+	ctx      context.Context
+	elements int
+}
+
+func (w *berBudget) walk(b []byte, depth int, indefinite bool) ([]byte, error) {
+	if depth > maxBERDepth {
+		return nil, errors.New("BER elements nested too deep")
+	}
+	for {
+		if len(b) == 0 {
+			if indefinite {
+				return nil, errors.New("missing BER end-of-contents")
+			}
+			return nil, nil
+		}
+		if indefinite && len(b) >= 2 && b[0] == 0 && b[1] == 0 {
+			return b[2:], nil
+		}
+		w.elements++
+		if w.elements > maxDecodedElements {
+			return nil, errTooManyElements
+		}
+		if w.elements%budgetCheckInterval == 0 {
+			if err := w.ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		identifier, n, i, err := parseBERHeader(b)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case n < 0:
+			if b, err = w.walk(b[i:], depth+1, true); err != nil {
+				return nil, err
+			}
+			continue
+		case identifier[0]&constructedForm != 0:
+			if _, err = w.walk(b[i:i+n], depth+1, false); err != nil {
+				return nil, err
+			}
+		}
+		b = b[i+n:]
+	}
+}
+
+// appendBERElements appends elements with the indefinite lengths of BER for
+// the constructed ones, or with the minimal definite lengths of DER
+func appendBERElements(b []byte, elements []berElement, indefinite bool) []byte {
+	for _, element := range elements {
+		b = append(b, element.identifier...)
+		switch {
+		case !element.constructed():
+			b = appendLength(b, len(element.value))
+			b = append(b, element.value...)
+		case indefinite:
+			b = append(b, 0x80)
+			b = appendBERElements(b, element.elements, indefinite)
+			b = append(b, 0x00, 0x00)
+		default:
+			offset := len(b)
+			b = append(b, 0)
+			b = appendBERElements(b, element.elements, indefinite)
+			b = endElement(b, offset)
+		}
+	}
+	return b
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testBERValue struct {
+	Number int64
+	Inner  struct {
+		Name  []byte `asn1:"tag:0"`
+		Flags []int  `asn1:"tag:31"`
+	} `asn1:"tag:1"`
+}
+
+func TestReencode(t *testing.T) {
+	value := testBERValue{Number: 42}
+	value.Inner.Name = bytes.Repeat([]byte{0x2a}, 200)
+	value.Inner.Flags = []int{1, 2}
+	der, err := asn1.Marshal(value)
+	assert.NoError(t, err)
+
+	// DER is left unchanged
+	reencoded, err := Reencode(der, "")
+	assert.NoError(t, err)
+	assert.Equal(t, der, reencoded)
+
+	// the constructed elements take indefinite lengths, the primitive ones
+	// keep their definite lengths
+	ber, err := Reencode(der, EncodingBER)
+	assert.NoError(t, err)
+	expected := []byte{
+		0x30, 0x80,
+		0x02, 0x01, 0x2a,
+		0xa1, 0x80,
+		0x80, 0x81, 0xc8,
+	}
+	expected = append(expected, value.Inner.Name...)
+	expected = append(expected,
+		0xbf, 0x1f, 0x80,
+		0x02, 0x01, 0x01,
+		0x02, 0x01, 0x02,
+		0x00, 0x00,
+		0x00, 0x00,
+		0x00, 0x00,
+	)
+	assert.Equal(t, expected, ber)
+	reencoded, err = Reencode(ber, EncodingBER)
+	assert.NoError(t, err)
+	assert.Equal(t, ber, reencoded)
+
+	// and convert back to DER
+	reencoded, err = Reencode(ber, EncodingDER)
+	assert.NoError(t, err)
+	assert.Equal(t, der, reencoded)
+	var decoded testBERValue
+	rest, err := asn1.Unmarshal(reencoded, &decoded)
+	assert.NoError(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, value, decoded)
+
+	// DER lengths are minimal
+	reencoded, err = Reencode([]byte{0x30, 0x82, 0x00, 0x04, 0x02, 0x81, 0x01, 0x05, 0x04, 0x00}, EncodingDER)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x30, 0x03, 0x02, 0x01, 0x05, 0x04, 0x00}, reencoded)
+
+	_, err = Reencode(der, "per")
+	assert.EqualError(t, err, "unsupported encoding per")
+	_, err = Reencode(der[:len(der)-1], EncodingBER)
+	assert.EqualError(t, err, "truncated BER element")
+	_, err = Reencode(ber[:len(ber)-2], EncodingDER)
+	assert.EqualError(t, err, "missing BER end-of-contents")
+	_, err = Reencode([]byte{0x04, 0x80, 0x00, 0x00}, EncodingDER)
+	assert.EqualError(t, err, "indefinite length of a primitive BER element")
+	_, err = Reencode([]byte{0x04, 0x85, 0x01, 0x00, 0x00, 0x00, 0x00}, EncodingDER)
+	assert.EqualError(t, err, "BER length of 5 octets not supported")
+	_, err = Reencode(bytes.Repeat([]byte{0x30, 0x80}, maxBERDepth+2), EncodingDER)
+	assert.EqualError(t, err, "BER elements nested too deep")
+}
+
+func TestReencodeRecord(t *testing.T) {
+	hdr, err := ParseHeader(encodedRecord)
+	assert.NoError(t, err)
+	ber, err := ReencodeRecord(encodedRecord, EncodingBER)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord[:8], ber[:8])
+	assert.Equal(t, encodedRecord[12:hdr.HeaderLength], ber[12:hdr.HeaderLength])
+	berHdr, err := ParseHeader(ber)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(len(ber))-hdr.HeaderLength, berHdr.PayloadLength)
+	assert.Equal(t, byte(0x80), ber[hdr.HeaderLength+1])
+
+	// a LEMF normalizing the BER payload decodes the record as built
+	der, err := ReencodeRecord(ber, EncodingDER)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, der)
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(der))
+
+	keepalive := MakeKeepalive(7)
+	reencoded, err := ReencodeRecord(keepalive, EncodingBER)
+	assert.NoError(t, err)
+	assert.Equal(t, keepalive, reencoded)
+}
+
+func TestBERFraming(t *testing.T) {
+	hdr, err := ParseHeader(encodedRecord)
+	assert.NoError(t, err)
+	for _, framing := range []string{FramingNative, FramingLengthPrefixed, FramingPSPDU, FramingRawBER} {
+		framer, err := NewFramer(framing, 0x00f110)
+		assert.NoError(t, err)
+		assert.Equal(t, EncodingDER, framer.Encoding())
+		derFrame, err := framer.Frame(encodedRecord)
+		assert.NoError(t, err)
+		assert.NoError(t, framer.SetEncoding(EncodingBER))
+		assert.Equal(t, EncodingBER, framer.Encoding())
+		frame, err := framer.Frame(encodedRecord)
+		assert.NoError(t, err)
+		assert.NotEqual(t, derFrame, frame, framing)
+
+		switch framing {
+		case FramingNative:
+			frame, err = ReencodeRecord(frame, EncodingDER)
+		case FramingLengthPrefixed:
+			assert.Equal(t, uint32(len(frame)-4), binary.BigEndian.Uint32(frame))
+			frame, err = ReencodeRecord(frame[4:], EncodingDER)
+			derFrame = derFrame[4:]
+		default:
+			assert.Equal(t, []byte{0x80}, frame[1:2])
+			if framing == FramingRawBER {
+				assert.Equal(t, encodedRecord[hdr.HeaderLength], frame[0])
+			}
+			frame, err = Reencode(frame, EncodingDER)
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, derFrame, frame, framing)
+
+		// keepalives carry no payload to reencode
+		if framer.CarriesPDUs() {
+			keepalive, err := framer.Frame(MakeKeepalive(1))
+			assert.NoError(t, err)
+			assert.Contains(t, string(keepalive), string(MakeKeepalive(1)))
+		}
+	}
+
+	framer, err := NewFramer("", 0)
+	assert.NoError(t, err)
+	assert.EqualError(t, framer.SetEncoding("per"), "unsupported encoding per")
+	assert.NoError(t, framer.SetEncoding(""))
+	assert.Equal(t, EncodingDER, framer.Encoding())
+}
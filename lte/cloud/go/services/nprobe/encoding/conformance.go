@@ -0,0 +1,157 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"context"
+	"encoding/asn1"
+	"fmt"
+	"time"
+)
+
+const (
+	// Rules checked by CheckConformance, in order
+	RuleDecode    = "decode"
+	RuleHeader    = "header"
+	RuleRecordTag = "record_tag"
+	RuleCanonical = "canonical_der"
+	RuleContent   = "content"
+	RuleTimestamp = "timestamp"
+)
+
+const (
+	// Results of the rules of a conformance report
+	ConformancePassed  = "passed"
+	ConformanceFailed  = "failed"
+	ConformanceSkipped = "skipped"
+)
+
+// MaxTimestampSkew is how far in the future the timestamp of a conformant
+// record may be, to account for the clock skew between the NE and the DF
+const MaxTimestampSkew = 5 * time.Minute
+
+// ConformanceCheck is the result of a rule checked against a record
+type ConformanceCheck struct {
+	Rule   string
+	Result string
+	// Field is the offending field of a failed rule
+	Field   string
+	Message string
+}
+
+// ConformanceReport details the conformance of an encoded record to the
+// rules the records built by Magma follow
+type ConformanceReport struct {
+	Conformant bool
+	Checks     []ConformanceCheck
+
+	// Fields of the record, set once its header and payload are decoded
+	Class          string
+	Event          asn1.Enumerated
+	XID            string
+	CorrelationID  uint64
+	SequenceNumber uint32
+	ModuleVersion  string
+}
+
+// CheckConformance checks an encoded record, e.g. built by a third-party
+// NE, against the rules Validate enforces on the records built by Magma.
+// Unlike Validate, it checks every rule rather than stopping at the first
+// failure, so that DF vendors get the whole picture of a record at once.
+// The timestamp of the record must not be later than now beyond
+// MaxTimestampSkew, and its domain ID must be the one of a release or one
+// of the domain IDs configured in its network. The rules needing a decoded
+// record are skipped when it fails to be decoded.
+func CheckConformance(record []byte, now time.Time, domainIDs DomainIDs) *ConformanceReport {
+	report := &ConformanceReport{Conformant: true}
+
+	var r EpsIRIRecord
+	var decodeErr error
+	if err := r.DecodeWithLimit(context.Background(), record, 0); err != nil {
+		decodeErr = &ValidationError{Field: FieldDER, Err: err}
+	}
+	report.addError(RuleDecode, "header and payload are decoded", decodeErr)
+	if decodeErr != nil {
+		for _, rule := range []string{RuleHeader, RuleRecordTag, RuleCanonical, RuleContent, RuleTimestamp} {
+			report.Checks = append(report.Checks, ConformanceCheck{
+				Rule:    rule,
+				Result:  ConformanceSkipped,
+				Message: "record failed to be decoded",
+			})
+		}
+		return report
+	}
+	report.Class = r.Class
+	report.Event = r.Payload.EPSEvent
+	report.XID = r.Header.XID.String()
+	report.CorrelationID = r.Header.CorrelationID
+	report.SequenceNumber, _ = GetSequenceNumber(&r.Header)
+	if version := domainIDs.get(r.Payload.Hi2epsDomainID); version != nil {
+		report.ModuleVersion = version.Name
+	}
+
+	report.addError(RuleHeader, "header is consistent with the payload", validateHeader(&r.Header, len(record)))
+
+	content := record[r.Header.HeaderLength:]
+	var tagErr error
+	if !isRecordClassAllowed(r.Payload.EPSEvent, r.Class) {
+		tagErr = newValidationError(FieldEventType, "record type %x does not match event %d", content[0], r.Payload.EPSEvent)
+	}
+	report.addError(RuleRecordTag, fmt.Sprintf("%s record type matches event %d", r.Class, r.Payload.EPSEvent), tagErr)
+
+	var canonicalErr error
+	canonical, err := asn1.MarshalWithParams(r.Payload, getRecordType(r.Class))
+	switch {
+	case err != nil:
+		canonicalErr = &ValidationError{Field: FieldDER, Err: err}
+	case !bytes.Equal(canonical, content):
+		canonicalErr = newValidationError(FieldDER, "payload is not in canonical form")
+	}
+	report.addError(RuleCanonical, "payload is in DER canonical form", canonicalErr)
+
+	report.addError(RuleContent, "fields mandatory for the event are present", validateContent(&r.Header, &r.Payload, domainIDs))
+	report.addError(RuleTimestamp, "timestamp is not in the future", checkTimestamp(&r.Payload, now))
+	return report
+}
+
+// checkTimestamp checks that the timestamp of a record is set and not later
+// than now, beyond the tolerated skew
+func checkTimestamp(content *EpsIRIContent, now time.Time) error {
+	timestamp, err := decodeGeneralizedTime(content.TimeStamp.LocalTime.GeneralizedTime)
+	if err != nil {
+		return &ValidationError{Field: FieldTimestamp, Err: err}
+	}
+	if timestamp.IsZero() {
+		return newValidationError(FieldTimestamp, "zero timestamp")
+	}
+	if timestamp.After(now.Add(MaxTimestampSkew)) {
+		return newValidationError(FieldTimestamp, "timestamp %s is later than %s", timestamp.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// addError adds the result of a rule, failed if err is not nil
+func (r *ConformanceReport) addError(rule, passedMessage string, err error) {
+	if err == nil {
+		r.Checks = append(r.Checks, ConformanceCheck{Rule: rule, Result: ConformancePassed, Message: passedMessage})
+		return
+	}
+	check := ConformanceCheck{Rule: rule, Result: ConformanceFailed, Field: FieldUnknown, Message: err.Error()}
+	if validationErr, ok := err.(*ValidationError); ok {
+		check.Field = validationErr.Field
+	}
+	r.Checks = append(r.Checks, check)
+	r.Conformant = false
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getResults returns the result of each rule of a report
+func getResults(report *ConformanceReport) map[string]string {
+	ret := map[string]string{}
+	for _, check := range report.Checks {
+		ret[check.Rule] = check.Result
+	}
+	return ret
+}
+
+func TestCheckConformance(t *testing.T) {
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	report := CheckConformance(encodedRecord, now, nil)
+	assert.True(t, report.Conformant)
+	assert.Len(t, report.Checks, 6)
+	for _, check := range report.Checks {
+		assert.Equal(t, ConformancePassed, check.Result, check.Rule)
+	}
+	assert.Equal(t, RecordClassBegin, report.Class)
+	assert.Equal(t, BearerActivation, report.Event)
+	assert.Equal(t, "609dcabd-5ab1-4c95-9681-a24681f105ac", report.XID)
+	assert.Equal(t, uint64(0x866cb397915ffe4), report.CorrelationID)
+
+	// the rules needing a decoded record are skipped
+	report = CheckConformance(encodedRecord[:20], now, nil)
+	assert.False(t, report.Conformant)
+	assert.Equal(t, ConformanceCheck{Rule: RuleDecode, Result: ConformanceFailed, Field: FieldDER, Message: "malformed der: input too small"}, report.Checks[0])
+	for _, check := range report.Checks[1:] {
+		assert.Equal(t, ConformanceSkipped, check.Result, check.Rule)
+	}
+	assert.Empty(t, report.XID)
+
+	// every failed rule is reported, not only the first one
+	mistyped := append([]byte(nil), encodedRecord...)
+	mistyped[0x66] = 0xa2
+	report = CheckConformance(mistyped, time.Date(2021, 4, 19, 15, 0, 0, 0, time.UTC), nil)
+	assert.False(t, report.Conformant)
+	assert.Equal(t, map[string]string{
+		RuleDecode:    ConformancePassed,
+		RuleHeader:    ConformancePassed,
+		RuleRecordTag: ConformanceFailed,
+		RuleCanonical: ConformancePassed,
+		RuleContent:   ConformancePassed,
+		RuleTimestamp: ConformanceFailed,
+	}, getResults(report))
+	assert.Equal(t, FieldEventType, report.Checks[2].Field)
+	assert.Equal(t, FieldTimestamp, report.Checks[5].Field)
+
+	report = CheckConformance(reencode(t, func(r *EpsIRIRecord) {
+		r.Payload.PartyInformation[0].PartyIdentity = PartyIdentity{}
+	}), now, nil)
+	assert.False(t, report.Conformant)
+	assert.Equal(t, ConformanceFailed, getResults(report)[RuleContent])
+	assert.Equal(t, FieldIdentity, report.Checks[4].Field)
+}
@@ -23,9 +23,19 @@ const (
 	IRIReportRecord   string = "tag:4"
 )
 
+const (
+	// Record classes reported by GetRecordClass
+	RecordClassBegin    = "begin"
+	RecordClassContinue = "continue"
+	RecordClassEnd      = "end"
+	RecordClassReport   = "report"
+	RecordClassUnknown  = "unknown"
+)
+
 const (
 	// Event types as defined in ETSI TS 133 108 R16 [B9].
 	UnsupportedEvent                 asn1.Enumerated = 0
+	SMS                              asn1.Enumerated = 11
 	EutranAttach                     asn1.Enumerated = 16
 	EutranDetach                     asn1.Enumerated = 17
 	BearerActivation                 asn1.Enumerated = 18
@@ -35,9 +45,23 @@ const (
 	UERequestedPDNConnectivity       asn1.Enumerated = 23
 	UERequestedPDNDisconnection      asn1.Enumerated = 24
 	LocationUpdate                   asn1.Enumerated = 25 // trackingAreaEpsLocationUpdate
+	ServingEvolvedPacketSystem       asn1.Enumerated = 26
 	StartInterceptWithEutranAttached asn1.Enumerated = 41
 )
 
+const (
+	// GPRS events of the UMTS domain as defined in ETSI TS 133 108 R15 [B9].
+	PDPContextActivation               asn1.Enumerated = 1
+	StartInterceptWithPDPContextActive asn1.Enumerated = 2
+	PDPContextDeactivation             asn1.Enumerated = 4
+	GPRSAttach                         asn1.Enumerated = 5
+	GPRSDetach                         asn1.Enumerated = 6
+	LocationInfoUpdate                 asn1.Enumerated = 10
+	GPRSSMS                            asn1.Enumerated = 11
+	PDPContextModification             asn1.Enumerated = 13
+	GPRSServingSystem                  asn1.Enumerated = 14
+)
+
 const (
 	// Bearer types as defined in ETSI TS 133 108 R16 [B9].
 	DefaultBearer   asn1.Enumerated = 0x01
@@ -49,6 +73,11 @@ const (
 	OriginatingTarget     asn1.Enumerated = 0x01 // UE requested
 	TerminatingTarget     asn1.Enumerated = 0x02 // Network initiated
 
+	// Roaming status of the target, a vendor extension of the EPS specific
+	// parameters
+	RoamingStatusHome    asn1.Enumerated = 0x01
+	RoamingStatusRoaming asn1.Enumerated = 0x02
+
 	// Winter/Summer Indication as defined in ETSI TS 133 108 R16 [B9]
 	IndicationNotAvailable asn1.Enumerated = 0x00
 	WinterTime             asn1.Enumerated = 0x01
@@ -57,7 +86,47 @@ const (
 	// IP Address type
 	IPV4Type asn1.Enumerated = 0x00
 	IPV6Type asn1.Enumerated = 0x01
+	// IPV4V6Type is the type of the PDN address allocation of dual-stack
+	// bearers only, which holds the IPv4 address followed by the IPv6 one
+	IPV4V6Type asn1.Enumerated = 0x02
+
+	// Party qualifiers as defined in ETSI TS 133 108 R16 [B9]
+	PartyQualifierOriginating asn1.Enumerated = 0x00 // originating-Party
+	PartyQualifierTerminating asn1.Enumerated = 0x01 // terminating-Party
+	PartyQualifierForwardedTo asn1.Enumerated = 0x02 // forwarded-to-Party
+	PartyQualifierTarget      asn1.Enumerated = 0x03 // gPRSorEPS-Target
+
+	RatTypeEutran uint8 = 0x06 // Ran access type EUTRAN
+
+	// PDN types as defined in 3GPP TS 24.301
+	PDNTypeIPv4   uint8 = 0x01
+	PDNTypeIPv6   uint8 = 0x02
+	PDNTypeIPv4v6 uint8 = 0x03
+
+	// PDP types as defined in 3GPP TS 29.060 7.7.27, the organisation
+	// octet is followed by the number of the type
+	PDPTypeOrganisationIETF uint8 = 0xf1
+	PDPTypeIPv4             uint8 = 0x21
+	PDPTypeIPv6             uint8 = 0x57
+	PDPTypeIPv4v6           uint8 = 0x8d
+
+	// PDN connectivity request types as defined in 3GPP TS 24.301
+	RequestTypeInitial   uint8 = 0x01
+	RequestTypeHandover  uint8 = 0x02
+	RequestTypeEmergency uint8 = 0x04
+
+	// SMS initiators as defined in ETSI TS 133 108 R16 [B9]
+	SMSInitiatorTarget    asn1.Enumerated = 0x00
+	SMSInitiatorServer    asn1.Enumerated = 0x01
+	SMSInitiatorUndefined asn1.Enumerated = 0x02
+
+	// SMS transfer status as defined in ETSI TS 133 108 R16 [B9]
+	SMSTransferSucceeded    asn1.Enumerated = 0x00
+	SMSTransferNotSucceeded asn1.Enumerated = 0x01
+	SMSTransferUndefined    asn1.Enumerated = 0x02
 
-	PartyQualifierTarget asn1.Enumerated = 0x03 // gPRSorEPS-Target
-	RatTypeEutran        uint8           = 0x06 // Ran access type EUTRAN
+	// SMS other message indication as defined in ETSI TS 133 108 R16 [B9]
+	SMSOtherMessageYes       asn1.Enumerated = 0x00
+	SMSOtherMessageNo        asn1.Enumerated = 0x01
+	SMSOtherMessageUndefined asn1.Enumerated = 0x02
 )
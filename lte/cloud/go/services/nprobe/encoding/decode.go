@@ -0,0 +1,396 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/gofrs/uuid"
+)
+
+// Records are decoded in two steps, which tooling can also run separately:
+// ParseHeader decodes the PS-PDU header of a record and checks that the
+// record holds the payload it declares, then DecodePayload decodes the
+// ASN.1 payload following the header. EpsIRIRecord.Decode runs both on a
+// single record while Records decodes the records of a stream, e.g. a file
+// or a socket holding records sent back to back. A StreamDecoder reads the
+// records of an untrusted stream piecewise instead, so that only the parts
+// being decoded are held in memory.
+
+// ParseHeader decodes the header of an encoded record. The record must hold
+// the payload declared by the header, which starts at its HeaderLength.
+func ParseHeader(record []byte) (*EpsIRIHeader, error) {
+	if len(record) < int(HeaderFixLen) {
+		return nil, errors.New("input too small")
+	}
+	hdrLen := binary.BigEndian.Uint32(record[4:8])
+	pldLen := binary.BigEndian.Uint32(record[8:12])
+	if hdrLen < HeaderFixLen || uint64(hdrLen)+uint64(pldLen) > uint64(len(record)) {
+		return nil, errors.New("invalid input size")
+	}
+	if pldLen == 0 {
+		return nil, errors.New("empty payload")
+	}
+	hdr := &EpsIRIHeader{}
+	if err := hdr.Unmarshal(record[:hdrLen]); err != nil {
+		return nil, err
+	}
+	return hdr, nil
+}
+
+// DecodePayload decodes the payload of an encoded record whose header was
+// parsed by ParseHeader. It returns the payload along with its record class,
+// e.g. RecordClassBegin, given by its tag. ErrUmtsPayload is returned for the
+// payloads of the UMTS domain.
+func DecodePayload(hdr *EpsIRIHeader, record []byte) (*EpsIRIContent, string, error) {
+	if uint64(hdr.HeaderLength)+uint64(hdr.PayloadLength) > uint64(len(record)) || hdr.PayloadLength == 0 {
+		return nil, "", errors.New("invalid input size")
+	}
+	return decodeContent(record[hdr.HeaderLength : hdr.HeaderLength+hdr.PayloadLength])
+}
+
+// decodeContent decodes the ASN.1 payload of a record along with its record
+// class
+func decodeContent(content []byte) (*EpsIRIContent, string, error) {
+	if isUmtsPayload(content) {
+		return nil, "", ErrUmtsPayload
+	}
+	payload := &EpsIRIContent{}
+	rest, err := asn1.UnmarshalWithParams(content, payload, decodeRecordType(content[0]))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(rest) != 0 {
+		return nil, "", errors.New("trailing data after payload")
+	}
+	return payload, decodeRecordClass(content[0]), nil
+}
+
+// RecordIterator decodes the records read from a stream one at a time:
+//
+//	it := encoding.Records(r)
+//	for it.Next() {
+//		record := it.Record()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Keepalives and their acknowledgements, which carry no payload, are
+// skipped. Iteration stops at the end of the stream or at the first record
+// which can't be read or decoded, reported by Err.
+type RecordIterator struct {
+	// MaxSize bounds the size of the records read, DefaultMaxRecordSize
+	// applying when not positive. It must be set before the first call to Next.
+	MaxSize int
+
+	reader io.Reader
+	raw    []byte
+	record *EpsIRIRecord
+	offset int64
+	next   int64
+	err    error
+}
+
+// Records returns an iterator decoding the records read from r
+func Records(r io.Reader) *RecordIterator {
+	return &RecordIterator{reader: r}
+}
+
+// Next reads and decodes the next record of the stream. It returns false at
+// the end of the stream or once a record fails.
+func (it *RecordIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	for {
+		it.offset = it.next
+		pdu, err := ReadPDU(it.reader, it.MaxSize)
+		if err == io.EOF {
+			it.raw, it.record = nil, nil
+			return false
+		}
+		if err == io.ErrUnexpectedEOF {
+			err = errors.New("truncated record")
+		}
+		if err != nil {
+			return it.fail(err)
+		}
+		it.next += int64(len(pdu))
+
+		pduType := binary.BigEndian.Uint16(pdu[2:4])
+		if pduType == HeaderPduTypeKeepalive || pduType == HeaderPduTypeKeepaliveAck {
+			continue
+		}
+		record := &EpsIRIRecord{}
+		if err := record.Decode(pdu); err != nil {
+			return it.fail(err)
+		}
+		it.raw, it.record = pdu, record
+		return true
+	}
+}
+
+// Record returns the record decoded by the last call to Next
+func (it *RecordIterator) Record() *EpsIRIRecord {
+	return it.record
+}
+
+// Raw returns the encoded record decoded by the last call to Next
+func (it *RecordIterator) Raw() []byte {
+	return it.raw
+}
+
+// Offset returns the offset in the stream of the record decoded by the last
+// call to Next, or of the record which failed
+func (it *RecordIterator) Offset() int64 {
+	return it.offset
+}
+
+// Err returns the error which stopped the iteration, nil at the end of the
+// stream
+func (it *RecordIterator) Err() error {
+	return it.err
+}
+
+func (it *RecordIterator) fail(err error) bool {
+	it.raw, it.record = nil, nil
+	it.err = fmt.Errorf("record at offset %d: %v", it.offset, err)
+	return false
+}
+
+// DecodeLimits bound the records read by a StreamDecoder
+type DecodeLimits struct {
+	// MaxHeaderLength bounds the headers, MaxHeaderLength applying when 0
+	MaxHeaderLength uint32
+	// MaxPayloadLength bounds the payloads, DefaultMaxRecordSize applying
+	// when 0
+	MaxPayloadLength uint32
+}
+
+// StreamDecoder decodes the records of a stream piecewise, protecting its
+// reader from hostile or corrupt records declaring gigantic lengths:
+//
+//	d := encoding.NewStreamDecoder(r, encoding.DecodeLimits{})
+//	for {
+//		hdr, err := d.ReadHeader()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//		for attr, err := d.NextAttribute(); err != io.EOF; attr, err = d.NextAttribute() {
+//			...
+//		}
+//		payload, class, err := d.ReadPayload()
+//		...
+//	}
+//
+// The lengths declared by the fixed header of a record are checked against
+// the limits of the decoder before anything else is read. The conditional
+// attributes are then read one at a time, and the payload is only read once
+// requested. The parts of a record left unread are skipped by the next call
+// to ReadHeader without being held in memory. The decoder fails for good on
+// the first error, the stream being out of sync.
+type StreamDecoder struct {
+	limits DecodeLimits
+	reader io.Reader
+	header *EpsIRIHeader
+	// attributesLeft and payloadLeft are the lengths of the attributes and
+	// payload of the current record left unread
+	attributesLeft uint32
+	payloadLeft    uint32
+	err            error
+}
+
+// NewStreamDecoder returns a decoder of the records read from r
+func NewStreamDecoder(r io.Reader, limits DecodeLimits) *StreamDecoder {
+	if limits.MaxHeaderLength == 0 {
+		limits.MaxHeaderLength = MaxHeaderLength
+	}
+	if limits.MaxPayloadLength == 0 {
+		limits.MaxPayloadLength = DefaultMaxRecordSize
+	}
+	return &StreamDecoder{limits: limits, reader: r}
+}
+
+// ReadHeader reads the fixed header of the next record of the stream, after
+// skipping what is left of the current record. The conditional attributes
+// of the header returned are read by NextAttribute. io.EOF is returned at
+// the end of the stream, and ErrRecordTooLarge for records whose payload is
+// beyond the limit of the decoder.
+func (d *StreamDecoder) ReadHeader() (*EpsIRIHeader, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := d.skip(uint64(d.attributesLeft) + uint64(d.payloadLeft)); err != nil {
+		return nil, d.fail(err)
+	}
+	d.header, d.attributesLeft, d.payloadLeft = nil, 0, 0
+
+	fixed := make([]byte, HeaderFixLen)
+	if _, err := io.ReadFull(d.reader, fixed); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, d.fail(truncated(err))
+	}
+	hdrLen := binary.BigEndian.Uint32(fixed[4:8])
+	pldLen := binary.BigEndian.Uint32(fixed[8:12])
+	if hdrLen < HeaderFixLen || hdrLen > d.limits.MaxHeaderLength {
+		return nil, d.fail(errors.New("invalid header length"))
+	}
+	if pldLen > d.limits.MaxPayloadLength {
+		return nil, d.fail(ErrRecordTooLarge)
+	}
+	xid, err := uuid.FromBytes(fixed[16:32])
+	if err != nil {
+		return nil, d.fail(err)
+	}
+	d.header = &EpsIRIHeader{
+		Version:          binary.BigEndian.Uint16(fixed[0:2]),
+		PduType:          binary.BigEndian.Uint16(fixed[2:4]),
+		HeaderLength:     hdrLen,
+		PayloadLength:    pldLen,
+		PayloadFormat:    binary.BigEndian.Uint16(fixed[12:14]),
+		PayloadDirection: binary.BigEndian.Uint16(fixed[14:16]),
+		XID:              xid,
+		CorrelationID:    binary.BigEndian.Uint64(fixed[32:40]),
+	}
+	d.attributesLeft, d.payloadLeft = hdrLen-HeaderFixLen, pldLen
+	return d.header, nil
+}
+
+// NextAttribute reads the next conditional attribute of the header of the
+// current record, io.EOF once all of them were read
+func (d *StreamDecoder) NextAttribute() (*Attribute, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.header == nil {
+		return nil, errors.New("no header read")
+	}
+	if d.attributesLeft == 0 {
+		return nil, io.EOF
+	}
+	if d.attributesLeft < 4 {
+		return nil, d.fail(errors.New("truncated attribute or wrong length"))
+	}
+	var tl [4]byte
+	if _, err := io.ReadFull(d.reader, tl[:]); err != nil {
+		return nil, d.fail(truncated(err))
+	}
+	d.attributesLeft -= 4
+	attr := &Attribute{Tag: binary.BigEndian.Uint16(tl[0:2]), Len: binary.BigEndian.Uint16(tl[2:4])}
+	if uint32(attr.Len) > d.attributesLeft {
+		return nil, d.fail(errors.New("truncated attribute or wrong length"))
+	}
+	attr.Value = make([]byte, attr.Len)
+	if _, err := io.ReadFull(d.reader, attr.Value); err != nil {
+		return nil, d.fail(truncated(err))
+	}
+	d.attributesLeft -= uint32(attr.Len)
+	return attr, nil
+}
+
+// ReadPayload reads and decodes the payload of the current record, skipping
+// the attributes left unread. It returns the payload along with its record
+// class like DecodePayload.
+func (d *StreamDecoder) ReadPayload() (*EpsIRIContent, string, error) {
+	if d.err != nil {
+		return nil, "", d.err
+	}
+	if d.header == nil {
+		return nil, "", errors.New("no header read")
+	}
+	if err := d.skip(uint64(d.attributesLeft)); err != nil {
+		return nil, "", d.fail(err)
+	}
+	d.attributesLeft = 0
+	if d.payloadLeft != d.header.PayloadLength {
+		return nil, "", errors.New("payload already read")
+	}
+	if d.payloadLeft == 0 {
+		return nil, "", errors.New("empty payload")
+	}
+	content := make([]byte, d.payloadLeft)
+	if _, err := io.ReadFull(d.reader, content); err != nil {
+		return nil, "", d.fail(truncated(err))
+	}
+	d.payloadLeft = 0
+	return decodeContent(content)
+}
+
+// Decode reads the next record of the stream whole, io.EOF being returned at
+// the end of the stream. Keepalives and their acknowledgements, which carry
+// no payload, are skipped.
+func (d *StreamDecoder) Decode(record *EpsIRIRecord) error {
+	for {
+		hdr, err := d.ReadHeader()
+		if err != nil {
+			return err
+		}
+		if hdr.PduType == HeaderPduTypeKeepalive || hdr.PduType == HeaderPduTypeKeepaliveAck {
+			continue
+		}
+		var attrs []Attribute
+		for {
+			attr, err := d.NextAttribute()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			attrs = append(attrs, *attr)
+		}
+		hdr.ConditionalAttributes = attrs
+		payload, class, err := d.ReadPayload()
+		if err != nil {
+			return err
+		}
+		record.Header, record.Payload, record.Class = *hdr, *payload, class
+		return nil
+	}
+}
+
+// skip discards n bytes of the stream
+func (d *StreamDecoder) skip(n uint64) error {
+	if n == 0 {
+		return nil
+	}
+	if _, err := io.CopyN(ioutil.Discard, d.reader, int64(n)); err != nil {
+		return truncated(err)
+	}
+	return nil
+}
+
+func (d *StreamDecoder) fail(err error) error {
+	d.err = err
+	return err
+}
+
+// truncated reports the end of the stream within a record as a truncated
+// record
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("truncated record")
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHeaderDecodePayload(t *testing.T) {
+	hdr, err := ParseHeader(encodedRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0x66), hdr.HeaderLength)
+	seqNbr, _ := GetSequenceNumber(hdr)
+	assert.Equal(t, uint32(6), seqNbr)
+
+	payload, class, err := DecodePayload(hdr, encodedRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, RecordClassBegin, class)
+	assert.Equal(t, BearerActivation, payload.EPSEvent)
+
+	_, err = ParseHeader(encodedRecord[:len(encodedRecord)-1])
+	assert.EqualError(t, err, "invalid input size")
+	_, _, err = DecodePayload(hdr, encodedRecord[:len(encodedRecord)-1])
+	assert.EqualError(t, err, "invalid input size")
+	_, err = ParseHeader(MakeKeepalive(1))
+	assert.EqualError(t, err, "empty payload")
+}
+
+func TestRecords(t *testing.T) {
+	var stream []byte
+	stream = append(stream, encodedRecord...)
+	stream = append(stream, MakeKeepalive(1)...)
+	stream = append(stream, encodedRecord...)
+
+	it := Records(bytes.NewReader(stream))
+	var offsets []int64
+	for it.Next() {
+		offsets = append(offsets, it.Offset())
+		assert.Equal(t, encodedRecord, it.Raw())
+		assert.Equal(t, BearerActivation, it.Record().Payload.EPSEvent)
+	}
+	assert.NoError(t, it.Err())
+	keepaliveLen := int64(len(MakeKeepalive(1)))
+	assert.Equal(t, []int64{0, int64(len(encodedRecord)) + keepaliveLen}, offsets)
+	assert.False(t, it.Next())
+
+	// iteration stops at the first record which can't be read
+	truncated := append(append([]byte(nil), encodedRecord...), encodedRecord[:20]...)
+	it = Records(bytes.NewReader(truncated))
+	assert.True(t, it.Next())
+	assert.False(t, it.Next())
+	assert.EqualError(t, it.Err(), "record at offset 337: truncated record")
+	assert.Nil(t, it.Record())
+
+	// and records beyond the size limit are rejected
+	it = Records(bytes.NewReader(encodedRecord))
+	it.MaxSize = len(encodedRecord) - 1
+	assert.False(t, it.Next())
+	assert.EqualError(t, it.Err(), "record at offset 0: "+ErrRecordTooLarge.Error())
+}
+
+func TestStreamDecoder(t *testing.T) {
+	var stream []byte
+	stream = append(stream, encodedRecord...)
+	stream = append(stream, MakeKeepalive(1)...)
+	stream = append(stream, encodedRecord...)
+	stream = append(stream, encodedRecord...)
+	expected := &EpsIRIRecord{}
+	assert.NoError(t, expected.Decode(encodedRecord))
+
+	// records are decoded whole, keepalives being skipped
+	d := NewStreamDecoder(bytes.NewReader(stream), DecodeLimits{})
+	record := &EpsIRIRecord{}
+	assert.NoError(t, d.Decode(record))
+	assert.Equal(t, expected, record)
+	record = &EpsIRIRecord{}
+	assert.NoError(t, d.Decode(record))
+	assert.Equal(t, expected, record)
+
+	// or piecewise, the parts left unread being skipped
+	hdr, err := d.ReadHeader()
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Header.XID, hdr.XID)
+	assert.Equal(t, expected.Header.PayloadLength, hdr.PayloadLength)
+	attr, err := d.NextAttribute()
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Header.ConditionalAttributes[0], *attr)
+	payload, class, err := d.ReadPayload()
+	assert.NoError(t, err)
+	assert.Equal(t, RecordClassBegin, class)
+	assert.Equal(t, BearerActivation, payload.EPSEvent)
+	_, err = d.NextAttribute()
+	assert.Equal(t, io.EOF, err)
+	_, _, err = d.ReadPayload()
+	assert.EqualError(t, err, "payload already read")
+	_, err = d.ReadHeader()
+	assert.Equal(t, io.EOF, err)
+
+	// the lengths declared are checked before anything else is read
+	d = NewStreamDecoder(bytes.NewReader(encodedRecord), DecodeLimits{MaxPayloadLength: expected.Header.PayloadLength - 1})
+	_, err = d.ReadHeader()
+	assert.Equal(t, ErrRecordTooLarge, err)
+	d = NewStreamDecoder(bytes.NewReader(encodedRecord), DecodeLimits{MaxHeaderLength: expected.Header.HeaderLength - 1})
+	_, err = d.ReadHeader()
+	assert.EqualError(t, err, "invalid header length")
+	forged := append([]byte(nil), encodedRecord...)
+	binary.BigEndian.PutUint32(forged[8:12], 0xFFFFFFFF)
+	d = NewStreamDecoder(io.MultiReader(bytes.NewReader(forged), &endlessReader{}), DecodeLimits{})
+	assert.Equal(t, ErrRecordTooLarge, d.Decode(record))
+	// the decoder fails for good
+	assert.Equal(t, ErrRecordTooLarge, d.Decode(record))
+
+	// so are the lengths of the attributes
+	forged = append([]byte(nil), encodedRecord...)
+	binary.BigEndian.PutUint16(forged[HeaderFixLen+2:HeaderFixLen+4], 0xFFFF)
+	d = NewStreamDecoder(bytes.NewReader(forged), DecodeLimits{})
+	assert.EqualError(t, d.Decode(record), "truncated attribute or wrong length")
+
+	// and truncated records are reported
+	d = NewStreamDecoder(bytes.NewReader(encodedRecord[:len(encodedRecord)-1]), DecodeLimits{})
+	assert.EqualError(t, d.Decode(record), "truncated record")
+	d = NewStreamDecoder(bytes.NewReader(encodedRecord[:20]), DecodeLimits{})
+	assert.EqualError(t, d.Decode(record), "truncated record")
+}
+
+// endlessReader stands for a stream never ending
+type endlessReader struct{}
+
+func (r *endlessReader) Read(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/asn1"
+	"errors"
+	"sync"
+)
+
+const (
+	classContextSpecific = 0x80
+	constructedForm      = 0x20
+	tagUniversalSequence = 0x10
+	tagHighForm          = 0x1f
+)
+
+var (
+	errInvalidOID       = errors.New("asn1: invalid object identifier")
+	errInvalidPrintable = errors.New("asn1: PrintableString contains invalid character")
+)
+
+// encoderPool holds the encoders used by EpsIRIRecord.Encode
+var encoderPool = sync.Pool{
+	New: func() interface{} {
+		return &Encoder{}
+	},
+}
+
+// Encoder encodes IRI records into a buffer reused from one record to the
+// next, so that encoding records no larger than the ones already encoded
+// doesn't allocate. Payloads are DER encoded exactly as encoding/asn1 does,
+// without reflection. Party information is encoded in the given order,
+// which MakeRecord sorts as DER requires.
+type Encoder struct {
+	buf []byte
+}
+
+// Encode encodes a record and updates its payload length. The returned
+// slice is only valid until the next call to Encode.
+func (e *Encoder) Encode(r *EpsIRIRecord) ([]byte, error) {
+	hdrLen := int(r.Header.HeaderLength)
+	b := grow(e.buf[:0], hdrLen)
+	class := r.Class
+	if class == "" {
+		class = getDefaultRecordClass(r.Payload.EPSEvent)
+	}
+	b, err := appendContent(b, &r.Payload, getRecordTag(class))
+	if err != nil {
+		return nil, diagnoseContentError(r.Payload, err)
+	}
+	r.Header.PayloadLength = uint32(len(b) - hdrLen)
+	r.Header.marshalTo(b[:hdrLen])
+	e.buf = b
+	return b, nil
+}
+
+// appendContent appends the IRI content of a record with the implicit tag
+// of its record type
+func appendContent(b []byte, c *EpsIRIContent, recordTag int) ([]byte, error) {
+	return appendIRIParameter(b, classContextSpecific|constructedForm, recordTag, (*IRIParameter)(c))
+}
+
+// marshalPSHeaderAttribute encodes the PSHeader attribute of a record
+// header as asn1.Marshal does, into a single allocation
+func marshalPSHeaderAttribute(v *PSHeaderAttribute) ([]byte, error) {
+	var err error
+	b := make([]byte, 0, 16+len(v.LawfulInterceptionIdentifier)+len(v.DeliveryCountryCode))
+	b, offset := beginElement(b, constructedForm, tagUniversalSequence)
+	b = appendOptionalBytes(b, 1, v.LawfulInterceptionIdentifier)
+	if len(v.DeliveryCountryCode) != 0 {
+		if b, err = appendPrintableString(b, 2, v.DeliveryCountryCode); err != nil {
+			return nil, err
+		}
+	}
+	return endElement(b, offset), nil
+}
+
+// appendOptionalBytes appends an optional octet string, which is omitted
+// when nil but not when empty
+func appendOptionalBytes(b []byte, tag int, value []byte) []byte {
+	if value == nil {
+		return b
+	}
+	return appendBytes(b, tag, value)
+}
+
+func appendBytes(b []byte, tag int, value []byte) []byte {
+	b = appendTag(b, classContextSpecific, tag)
+	b = appendLength(b, len(value))
+	return append(b, value...)
+}
+
+func appendEnumerated(b []byte, tag int, value asn1.Enumerated) []byte {
+	return appendInteger(b, tag, int64(value))
+}
+
+func appendInteger(b []byte, tag int, value int64) []byte {
+	n := int64Length(value)
+	b = appendTag(b, classContextSpecific, tag)
+	b = appendLength(b, n)
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(value>>uint(i*8)))
+	}
+	return b
+}
+
+// appendObjectIdentifier appends an OBJECT IDENTIFIER with a
+// context-specific tag
+func appendObjectIdentifier(b []byte, tag int, oid asn1.ObjectIdentifier) ([]byte, error) {
+	var err error
+	b, offset := beginElement(b, classContextSpecific, tag)
+	if b, err = appendOID(b, oid); err != nil {
+		return nil, err
+	}
+	return endElement(b, offset), nil
+}
+
+// appendPrintableString appends a PrintableString with a context-specific
+// tag, rejecting the characters encoding/asn1 rejects
+func appendPrintableString(b []byte, tag int, s string) ([]byte, error) {
+	for i := 0; i < len(s); i++ {
+		if !isPrintable(s[i]) {
+			return nil, errInvalidPrintable
+		}
+	}
+	b = appendTag(b, classContextSpecific, tag)
+	b = appendLength(b, len(s))
+	return append(b, s...), nil
+}
+
+// isPrintable returns true if a character is allowed in a PrintableString,
+// asterisks included as encoding/asn1 allows them
+func isPrintable(c byte) bool {
+	return 'a' <= c && c <= 'z' ||
+		'A' <= c && c <= 'Z' ||
+		'0' <= c && c <= '9' ||
+		'\'' <= c && c <= ')' ||
+		'+' <= c && c <= '/' ||
+		c == ' ' || c == ':' || c == '=' || c == '?' || c == '*'
+}
+
+func appendOID(b []byte, oid asn1.ObjectIdentifier) ([]byte, error) {
+	if len(oid) < 2 || oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
+		return nil, errInvalidOID
+	}
+	b = appendBase128(b, int64(oid[0]*40+oid[1]))
+	for _, component := range oid[2:] {
+		b = appendBase128(b, int64(component))
+	}
+	return b, nil
+}
+
+// beginElement appends the identifier of an element and reserves its
+// length, returning the offset to pass to endElement
+func beginElement(b []byte, class byte, tag int) ([]byte, int) {
+	b = appendTag(b, class, tag)
+	return append(b, 0), len(b)
+}
+
+// endElement writes the length of an element once its contents were
+// appended, moving them when the length takes more than a byte
+func endElement(b []byte, offset int) []byte {
+	n := len(b) - offset - 1
+	if n < 0x80 {
+		b[offset] = byte(n)
+		return b
+	}
+	l := lengthLength(n)
+	b = grow(b, l)
+	copy(b[offset+1+l:], b[offset+1:len(b)-l])
+	b[offset] = 0x80 | byte(l)
+	for i := l; i > 0; i-- {
+		b[offset+i] = byte(n)
+		n >>= 8
+	}
+	return b
+}
+
+func appendTag(b []byte, class byte, tag int) []byte {
+	if tag < tagHighForm {
+		return append(b, class|byte(tag))
+	}
+	b = append(b, class|tagHighForm)
+	return appendBase128(b, int64(tag))
+}
+
+func appendLength(b []byte, n int) []byte {
+	if n < 0x80 {
+		return append(b, byte(n))
+	}
+	l := lengthLength(n)
+	b = append(b, 0x80|byte(l))
+	for i := l - 1; i >= 0; i-- {
+		b = append(b, byte(n>>uint(i*8)))
+	}
+	return b
+}
+
+func lengthLength(n int) int {
+	l := 1
+	for n > 0xff {
+		l++
+		n >>= 8
+	}
+	return l
+}
+
+func int64Length(i int64) int {
+	n := 1
+	for i > 127 {
+		n++
+		i >>= 8
+	}
+	for i < -128 {
+		n++
+		i >>= 8
+	}
+	return n
+}
+
+func appendBase128(b []byte, n int64) []byte {
+	l := 1
+	for i := n >> 7; i > 0; i >>= 7 {
+		l++
+	}
+	for i := l - 1; i >= 0; i-- {
+		o := byte(n>>uint(i*7)) & 0x7f
+		if i != 0 {
+			o |= 0x80
+		}
+		b = append(b, o)
+	}
+	return b
+}
+
+// grow extends b by n zero bytes, reusing its capacity when possible
+func grow(b []byte, n int) []byte {
+	if cap(b)-len(b) < n {
+		grown := make([]byte, len(b), 2*cap(b)+n)
+		copy(grown, b)
+		b = grown
+	}
+	b = b[:len(b)+n]
+	tail := b[len(b)-n:]
+	for i := range tail {
+		tail[i] = 0
+	}
+	return b
+}
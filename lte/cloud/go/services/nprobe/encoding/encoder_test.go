@@ -0,0 +1,180 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoder(t *testing.T) {
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(encodedRecord))
+
+	var e Encoder
+	b, err := e.Encode(&record)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, b)
+	b, err = record.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, b)
+
+	// payloads are encoded as encoding/asn1 does
+	variants := []func(c *EpsIRIContent){
+		func(c *EpsIRIContent) { c.EPSSpecificParameters.APN = bytes.Repeat([]byte("a"), 200) },
+		func(c *EpsIRIContent) { c.EPSSpecificParameters.APN = bytes.Repeat([]byte("a"), 300) },
+		func(c *EpsIRIContent) { c.EPSSpecificParameters.EPSLocationOfTheTarget.UserLocationInfo = []byte{} },
+		func(c *EpsIRIContent) { c.PartyInformation = []PartyInformation{} },
+		func(c *EpsIRIContent) { c.PartyInformation[0].PartyIdentity.IMEI = []byte{} },
+		func(c *EpsIRIContent) { c.Initiator = -129 },
+		func(c *EpsIRIContent) { c.EPSEvent = BearerDeactivation },
+		func(c *EpsIRIContent) { c.NetworkIdentifier = NetworkIdentifier{} },
+		func(c *EpsIRIContent) { c.EPSSpecificParameters = EPSSpecificParameters{} },
+		func(c *EpsIRIContent) { c.EPSSpecificParameters.HandoverIndication = []byte{} },
+		func(c *EpsIRIContent) {
+			c.EPSSpecificParameters.EPSBearerIdentity = []byte{6}
+			c.EPSSpecificParameters.BearerSessionID = []byte("IMSI001010000000001-919642")
+		},
+		func(c *EpsIRIContent) {
+			c.EPSSpecificParameters.LinkedEPSBearerID = []byte{5}
+			c.EPSSpecificParameters.FailedTAUReason = []byte{9}
+			c.EPSSpecificParameters.ServingMMEAddress = []byte{10, 0, 0, 1}
+			c.EPSSpecificParameters.PDNType = []byte{PDNTypeIPv4v6}
+			c.EPSSpecificParameters.RequestType = []byte{RequestTypeInitial}
+			c.EPSSpecificParameters.UEReqPDNConnFailReason = []byte{27}
+		},
+		func(c *EpsIRIContent) {
+			c.SMS.SMSContents = SMSContents{Initiator: SMSInitiatorServer, OtherMessage: SMSOtherMessageNo}
+		},
+		func(c *EpsIRIContent) { c.SMS.SMSContents.Content = bytes.Repeat([]byte{0x11}, 140) },
+		func(c *EpsIRIContent) {
+			c.PartyInformation[0].ServicesDataInformation.GPRSParameters = GPRSParameters{
+				PDPAddress:          makeDataNodeAddress(IPV4Type, []byte{192, 168, 128, 12}, 0),
+				AdditionalIPAddress: makeDataNodeAddress(IPV6Type, bytes.Repeat([]byte{0x20}, 16), 64),
+			}
+		},
+		func(c *EpsIRIContent) {
+			c.PartyInformation[0].ServicesDataInformation.GPRSParameters.AdditionalIPAddress =
+				makeDataNodeAddress(IPV6Type, bytes.Repeat([]byte{0x20}, 16), 128)
+		},
+		func(c *EpsIRIContent) {
+			c.NetworkIdentifier.NetworkElementIdentifier.IPAddress.IPv6PrefixLength = 48
+		},
+		func(c *EpsIRIContent) {
+			c.NationalParameters = NationalParameters{
+				CountryCode: "FR",
+				UsageReport: UsageReport{UplinkVolume: 1 << 40, DownlinkVolume: 2048, Duration: 60},
+			}
+		},
+		func(c *EpsIRIContent) { c.NationalParameters.CountryCode = "FR" },
+	}
+	for i, variant := range variants {
+		r := EpsIRIRecord{}
+		assert.NoError(t, r.Decode(encodedRecord))
+		variant(&r.Payload)
+		expected, err := asn1.MarshalWithParams(r.Payload, getRecordType(r.Class))
+		assert.NoError(t, err)
+
+		b, err := e.Encode(&r)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, b[r.Header.HeaderLength:], "variant %d", i)
+		assert.Equal(t, uint32(len(expected)), r.Header.PayloadLength)
+	}
+
+	record.Payload.NationalParameters.CountryCode = "F&R"
+	_, err = e.Encode(&record)
+	assert.Error(t, err)
+
+	record.Payload.NationalParameters.CountryCode = ""
+	record.Payload.Hi2epsDomainID = asn1.ObjectIdentifier{3}
+	_, err = e.Encode(&record)
+	assert.Error(t, err)
+}
+
+func TestEncoderAllocations(t *testing.T) {
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(encodedRecord))
+
+	var e Encoder
+	_, err := e.Encode(&record)
+	assert.NoError(t, err)
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = e.Encode(&record)
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestMarshalPSHeaderAttribute(t *testing.T) {
+	for _, attr := range []PSHeaderAttribute{
+		{},
+		{LawfulInterceptionIdentifier: []byte("LIID-0042")},
+		{DeliveryCountryCode: "FR"},
+		{LawfulInterceptionIdentifier: bytes.Repeat([]byte("L"), 300), DeliveryCountryCode: "FR"},
+	} {
+		expected, err := asn1.Marshal(attr)
+		assert.NoError(t, err)
+		b, err := marshalPSHeaderAttribute(&attr)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, b)
+	}
+	_, err := marshalPSHeaderAttribute(&PSHeaderAttribute{DeliveryCountryCode: "F&R"})
+	assert.Error(t, err)
+}
+
+func TestSortPartyInformation(t *testing.T) {
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(encodedRecord))
+	target := record.Payload.PartyInformation[0]
+	correspondent := PartyInformation{
+		PartyQualified: PartyQualifierTerminating,
+		PartyIdentity:  PartyIdentity{MSISDN: []byte("33687654321")},
+	}
+
+	// parties are sorted as encoding/asn1 encodes them, without allocating
+	parties := []PartyInformation{target, correspondent}
+	sortPartyInformation(parties)
+	expected := [][]byte{}
+	for _, party := range parties {
+		b, err := asn1.Marshal(party)
+		assert.NoError(t, err)
+		expected = append(expected, b)
+	}
+	assert.Equal(t, -1, bytes.Compare(expected[0], expected[1]))
+	if raceEnabled {
+		return
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		parties[0], parties[1] = parties[1], parties[0]
+		sortPartyInformation(parties)
+	})
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	var record EpsIRIRecord
+	if err := record.Decode(encodedRecord); err != nil {
+		b.Fatal(err)
+	}
+	var e Encoder
+	b.ReportAllocs()
+	b.SetBytes(int64(len(encodedRecord)))
+	for i := 0; i < b.N; i++ {
+		if _, err := e.Encode(&record); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
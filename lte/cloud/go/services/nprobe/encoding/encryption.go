@@ -0,0 +1,189 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// EncryptionTypeAES192CBC is the AES-192 encryption in CBC mode as
+	// defined in ETSI TS 102 232-1
+	EncryptionTypeAES192CBC asn1.Enumerated = 3
+	// EncryptionTypeAES256CBC is the AES-256 encryption in CBC mode as
+	// defined in ETSI TS 102 232-1
+	EncryptionTypeAES256CBC asn1.Enumerated = 4
+	// EncryptedPayloadTypeUnknown is the type of the encrypted payloads not
+	// defined by ETSI TS 102 232, e.g. the IRI records of TS 133 108
+	EncryptedPayloadTypeUnknown asn1.Enumerated = 1
+	// EncryptedPayloadTypePart1 is the type of the encrypted payloads defined
+	// by ETSI TS 102 232-1, e.g. HI1 notifications
+	EncryptedPayloadTypePart1 asn1.Enumerated = 8
+
+	// PayloadEncryptionAES192CBC and PayloadEncryptionAES256CBC are the
+	// payload encryptions configured for the destinations
+	PayloadEncryptionAES192CBC = "aes-192-cbc"
+	PayloadEncryptionAES256CBC = "aes-256-cbc"
+)
+
+// payloadEncryptions maps the payload encryptions to their ETSI TS 102 232-1
+// encryption type and key size
+var payloadEncryptions = map[string]struct {
+	encryptionType asn1.Enumerated
+	keySize        int
+}{
+	PayloadEncryptionAES192CBC: {EncryptionTypeAES192CBC, 24},
+	PayloadEncryptionAES256CBC: {EncryptionTypeAES256CBC, 32},
+}
+
+// EncryptionContainer is the encryption container of ETSI TS 102 232-1,
+// carried as the payload of the encrypted records
+type EncryptionContainer struct {
+	EncryptionType       asn1.Enumerated `asn1:"tag:0"`
+	EncryptedPayload     []byte          `asn1:"optional,tag:1"`
+	EncryptedPayloadType asn1.Enumerated `asn1:"optional,tag:2"`
+}
+
+// PayloadEncryption encrypts the payload of the records delivered to a
+// destination with the key agreed with its LEA, on top of TLS. The header of
+// an encrypted record is left in clear for the delivery function to route
+// it, its payload format being the ETSI TS 102 232-1 defined payload. The
+// encrypted payload is the random IV followed by the payload encrypted in CBC
+// mode, padded as per PKCS #7.
+type PayloadEncryption struct {
+	encryptionType asn1.Enumerated
+	block          cipher.Block
+}
+
+// NewPayloadEncryption returns the payload encryption of a type with a key
+// of the size it requires
+func NewPayloadEncryption(encryption string, key []byte) (*PayloadEncryption, error) {
+	params, ok := payloadEncryptions[encryption]
+	if !ok {
+		return nil, fmt.Errorf("unsupported payload encryption %s", encryption)
+	}
+	if len(key) != params.keySize {
+		return nil, fmt.Errorf("payload encryption %s requires a key of %d bytes, got %d", encryption, params.keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &PayloadEncryption{encryptionType: params.encryptionType, block: block}, nil
+}
+
+// Encrypt returns a record with its payload encrypted in an encryption
+// container, the payload length and format of its header being updated
+// accordingly. Records are encrypted once validated and before they are
+// signed, their signature covering the encrypted record.
+func (e *PayloadEncryption) Encrypt(record []byte) ([]byte, error) {
+	hdr, err := ParsePDUHeader(record)
+	if err != nil {
+		return nil, err
+	}
+	payloadType := EncryptedPayloadTypeUnknown
+	switch hdr.PayloadFormat {
+	case HeaderPayloadFormat:
+	case HeaderPayloadFormatHI1:
+		payloadType = EncryptedPayloadTypePart1
+	default:
+		return nil, fmt.Errorf("unsupported payload format %d", hdr.PayloadFormat)
+	}
+	payload := record[hdr.HeaderLength:]
+
+	blockSize := e.block.BlockSize()
+	padding := blockSize - len(payload)%blockSize
+	encrypted := make([]byte, blockSize+len(payload)+padding)
+	iv := encrypted[:blockSize]
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	copy(encrypted[blockSize:], payload)
+	copy(encrypted[blockSize+len(payload):], bytes.Repeat([]byte{byte(padding)}, padding))
+	cipher.NewCBCEncrypter(e.block, iv).CryptBlocks(encrypted[blockSize:], encrypted[blockSize:])
+
+	container, err := asn1.Marshal(EncryptionContainer{
+		EncryptionType:       e.encryptionType,
+		EncryptedPayload:     encrypted,
+		EncryptedPayloadType: payloadType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]byte, int(hdr.HeaderLength)+len(container))
+	copy(ret, record[:hdr.HeaderLength])
+	binary.BigEndian.PutUint32(ret[8:12], uint32(len(container)))
+	binary.BigEndian.PutUint16(ret[12:14], HeaderPayloadFormatHI1)
+	copy(ret[hdr.HeaderLength:], container)
+	return ret, nil
+}
+
+// Decrypt returns the record encrypted by Encrypt, e.g. to check what was
+// delivered to a destination
+func (e *PayloadEncryption) Decrypt(record []byte) ([]byte, error) {
+	hdr, err := ParsePDUHeader(record)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.PayloadFormat != HeaderPayloadFormatHI1 {
+		return nil, errors.New("record payload is not encrypted")
+	}
+	container := EncryptionContainer{}
+	rest, err := asn1.Unmarshal(record[hdr.HeaderLength:], &container)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after encryption container")
+	}
+	if container.EncryptionType != e.encryptionType {
+		return nil, fmt.Errorf("unexpected encryption type %d", container.EncryptionType)
+	}
+	format := HeaderPayloadFormat
+	switch container.EncryptedPayloadType {
+	case EncryptedPayloadTypeUnknown:
+	case EncryptedPayloadTypePart1:
+		format = HeaderPayloadFormatHI1
+	default:
+		return nil, fmt.Errorf("unsupported encrypted payload type %d", container.EncryptedPayloadType)
+	}
+
+	blockSize := e.block.BlockSize()
+	encrypted := container.EncryptedPayload
+	if len(encrypted) < 2*blockSize || len(encrypted)%blockSize != 0 {
+		return nil, errors.New("invalid encrypted payload length")
+	}
+	payload := make([]byte, len(encrypted)-blockSize)
+	cipher.NewCBCDecrypter(e.block, encrypted[:blockSize]).CryptBlocks(payload, encrypted[blockSize:])
+	padding := int(payload[len(payload)-1])
+	if padding == 0 || padding > blockSize || !bytes.Equal(payload[len(payload)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("invalid encrypted payload padding")
+	}
+	payload = payload[:len(payload)-padding]
+
+	ret := make([]byte, int(hdr.HeaderLength)+len(payload))
+	copy(ret, record[:hdr.HeaderLength])
+	binary.BigEndian.PutUint32(ret[8:12], uint32(len(payload)))
+	binary.BigEndian.PutUint16(ret[12:14], format)
+	copy(ret[hdr.HeaderLength:], payload)
+	return ret, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayloadEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte{0x2a}, 32)
+	encryption, err := NewPayloadEncryption(PayloadEncryptionAES256CBC, key)
+	assert.NoError(t, err)
+
+	encrypted, err := encryption.Encrypt(encodedRecord)
+	assert.NoError(t, err)
+	hdr, err := ParsePDUHeader(encrypted)
+	assert.NoError(t, err)
+	original, err := ParsePDUHeader(encodedRecord)
+	assert.NoError(t, err)
+	// the header is left in clear but for the payload
+	assert.Equal(t, HeaderPayloadFormatHI1, hdr.PayloadFormat)
+	assert.Equal(t, original.XID, hdr.XID)
+	assert.Equal(t, original.ConditionalAttributes, hdr.ConditionalAttributes)
+	assert.Equal(t, uint32(len(encrypted))-hdr.HeaderLength, hdr.PayloadLength)
+	assert.Equal(t, RecordClassUnknown, GetRecordClass(encrypted))
+	_, isHI1 := GetHI1Operation(encrypted)
+	assert.False(t, isHI1)
+
+	container := EncryptionContainer{}
+	_, err = asn1.Unmarshal(encrypted[hdr.HeaderLength:], &container)
+	assert.NoError(t, err)
+	assert.Equal(t, EncryptionTypeAES256CBC, container.EncryptionType)
+	assert.Equal(t, EncryptedPayloadTypeUnknown, container.EncryptedPayloadType)
+	assert.False(t, bytes.Contains(container.EncryptedPayload, encodedRecord[original.HeaderLength:]))
+
+	decrypted, err := encryption.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, decrypted)
+
+	// each record is encrypted with its own IV
+	again, err := encryption.Encrypt(encodedRecord)
+	assert.NoError(t, err)
+	assert.NotEqual(t, encrypted, again)
+
+	// records encrypted with another key or type are rejected
+	other, err := NewPayloadEncryption(PayloadEncryptionAES256CBC, bytes.Repeat([]byte{0x2b}, 32))
+	assert.NoError(t, err)
+	decrypted, err = other.Decrypt(encrypted)
+	if err == nil {
+		assert.NotEqual(t, encodedRecord, decrypted)
+	}
+	aes192, err := NewPayloadEncryption(PayloadEncryptionAES192CBC, key[:24])
+	assert.NoError(t, err)
+	_, err = aes192.Decrypt(encrypted)
+	assert.EqualError(t, err, "unexpected encryption type 4")
+	_, err = encryption.Decrypt(encodedRecord)
+	assert.EqualError(t, err, "record payload is not encrypted")
+}
+
+func TestNewPayloadEncryption(t *testing.T) {
+	_, err := NewPayloadEncryption(PayloadEncryptionAES192CBC, make([]byte, 32))
+	assert.EqualError(t, err, "payload encryption aes-192-cbc requires a key of 24 bytes, got 32")
+	_, err = NewPayloadEncryption("des-cbc", make([]byte, 8))
+	assert.EqualError(t, err, "unsupported payload encryption des-cbc")
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+const (
+	// Fields reported by encoding errors
+	FieldEventType         = "event_type"
+	FieldTimestamp         = "timestamp"
+	FieldTaskID            = "task_id"
+	FieldTaskDetails       = "task_details"
+	FieldEventData         = "event_data"
+	FieldIdentity          = "identity"
+	FieldBearerParams      = "bearer_params"
+	FieldLocation          = "location"
+	FieldNetworkIdentifier = "network_identifier"
+	FieldSMS               = "sms"
+	FieldUsage             = "usage"
+	FieldTransformation    = "transformation"
+	FieldUnknown           = "unknown"
+)
+
+// EncodingError reports the field of an event or task which failed to be encoded
+type EncodingError struct {
+	Field string
+	Err   error
+	// Decoding is whether the data of the event failed to be decoded, i.e.
+	// held values of unexpected types, rather than to be encoded
+	Decoding bool
+}
+
+func (e *EncodingError) Error() string {
+	return fmt.Sprintf("invalid %s: %v", e.Field, e.Err)
+}
+
+func newEncodingError(field string, err error) *EncodingError {
+	return &EncodingError{Field: field, Err: err}
+}
+
+func newDecodingError(field string, err error) *EncodingError {
+	return &EncodingError{Field: field, Err: err, Decoding: true}
+}
+
+// IsDecodingError returns true if the record of an event failed to be built
+// with err because the data of the event could not be decoded
+func IsDecodingError(err error) bool {
+	e, ok := err.(*EncodingError)
+	return ok && e.Decoding
+}
+
+// GetErrorField returns the field reported by an encoding or validation
+// error, FieldUnknown if the error does not report any.
+func GetErrorField(err error) string {
+	switch e := err.(type) {
+	case *EncodingError:
+		return e.Field
+	case *ValidationError:
+		return e.Field
+	}
+	return FieldUnknown
+}
+
+// eventDataFields maps event data keys to the field they are encoded in
+var eventDataFields = []struct {
+	key   string
+	field string
+}{
+	{"imsi", FieldIdentity},
+	{"imei", FieldIdentity},
+	{"msisdn", FieldIdentity},
+	{"correspondent_imsi", FieldIdentity},
+	{"correspondent_imei", FieldIdentity},
+	{"correspondent_msisdn", FieldIdentity},
+	{"correspondent_qualifier", FieldIdentity},
+	{"session_id", FieldBearerParams},
+	{"apn", FieldBearerParams},
+	{"ip_addr", FieldBearerParams},
+	{"ipv6_addr", FieldBearerParams},
+	{"ipv6_prefix", FieldBearerParams},
+	{"user_location", FieldLocation},
+	{"tai", FieldLocation},
+	{"ecgi", FieldLocation},
+	{"cell_global_id", FieldLocation},
+	{"routing_area_id", FieldLocation},
+	{"service_area_id", FieldLocation},
+	{"spgw_ip", FieldNetworkIdentifier},
+	{"pdn_type", FieldBearerParams},
+	{"request_type", FieldBearerParams},
+	{"failure_cause", FieldBearerParams},
+	{"bearer_id", FieldBearerParams},
+	{"linked_bearer_id", FieldBearerParams},
+	{"mme_address", FieldBearerParams},
+	{"sgsn_ip", FieldBearerParams},
+	{"ggsn_ip", FieldBearerParams},
+	{"qos_profile", FieldBearerParams},
+	{"qci", FieldBearerParams},
+	{"apn_ambr_ul", FieldBearerParams},
+	{"apn_ambr_dl", FieldBearerParams},
+	{"mbr_ul", FieldBearerParams},
+	{"mbr_dl", FieldBearerParams},
+	{"gbr_ul", FieldBearerParams},
+	{"gbr_dl", FieldBearerParams},
+	{"arp_priority_level", FieldBearerParams},
+	{"arp_preemption_capability", FieldBearerParams},
+	{"arp_preemption_vulnerability", FieldBearerParams},
+	{"previous_qci", FieldBearerParams},
+	{"previous_apn_ambr_ul", FieldBearerParams},
+	{"previous_apn_ambr_dl", FieldBearerParams},
+	{"previous_mbr_ul", FieldBearerParams},
+	{"previous_mbr_dl", FieldBearerParams},
+	{"previous_gbr_ul", FieldBearerParams},
+	{"previous_gbr_dl", FieldBearerParams},
+	{"previous_arp_priority_level", FieldBearerParams},
+	{"previous_arp_preemption_capability", FieldBearerParams},
+	{"previous_arp_preemption_vulnerability", FieldBearerParams},
+	{"sms_initiator", FieldSMS},
+	{"sms_transfer_status", FieldSMS},
+	{"sms_other_message", FieldSMS},
+	{"sms_content", FieldSMS},
+	{"bytes_tx", FieldUsage},
+	{"bytes_rx", FieldUsage},
+	{"duration_secs", FieldUsage},
+}
+
+// correspondentPrefix prefixes the event data keys identifying the correspondent
+// party, e.g. correspondent_msisdn
+const correspondentPrefix = "correspondent_"
+
+// previousPrefix prefixes the event data keys of the bearer parameters of a
+// modified bearer before its modification, e.g. previous_qci
+const previousPrefix = "previous_"
+
+// correspondentQualifiers maps the role of the correspondent party in the
+// event data to its party qualifier
+var correspondentQualifiers = map[string]asn1.Enumerated{
+	"originating":  PartyQualifierOriginating,
+	"terminating":  PartyQualifierTerminating,
+	"forwarded_to": PartyQualifierForwardedTo,
+}
+
+// pdnTypes maps the PDN type in the event data to its encoding
+var pdnTypes = map[string]uint8{
+	"ipv4":   PDNTypeIPv4,
+	"ipv6":   PDNTypeIPv6,
+	"ipv4v6": PDNTypeIPv4v6,
+}
+
+// requestTypes maps the PDN connectivity request type in the event data to
+// its encoding
+var requestTypes = map[string]uint8{
+	"initial":   RequestTypeInitial,
+	"handover":  RequestTypeHandover,
+	"emergency": RequestTypeEmergency,
+}
+
+// smsInitiators maps the initiator of an SMS in the event data to its encoding
+var smsInitiators = map[string]asn1.Enumerated{
+	"target": SMSInitiatorTarget,
+	"server": SMSInitiatorServer,
+}
+
+// smsTransferStatuses maps the transfer status of an SMS in the event data
+// to its encoding
+var smsTransferStatuses = map[string]asn1.Enumerated{
+	"succeeded": SMSTransferSucceeded,
+	"failed":    SMSTransferNotSucceeded,
+}
+
+// smsOtherMessages maps whether more SMS are to be sent in the event data to
+// its encoding
+var smsOtherMessages = map[string]asn1.Enumerated{
+	"yes": SMSOtherMessageYes,
+	"no":  SMSOtherMessageNo,
+}
+
+// maxSMSContentLen is the maximum length of the content of an SMS report
+const maxSMSContentLen = 270
+
+// locationLengths are the least and most octets of the location of the
+// target, coded as in TS 29.274 in the EPS domain and as in TS 29.002 in the
+// UMTS domain
+var locationLengths = map[string][2]int{
+	"tai":             {5, 5},
+	"ecgi":            {7, 7},
+	"cell_global_id":  {5, 7},
+	"routing_area_id": {6, 6},
+	"service_area_id": {7, 7},
+}
+
+// maxQoSProfileLen is the maximum length of the QoS profile of a PDP context
+const maxQoSProfileLen = 255
+
+// enumeratedFields returns whether the value of the event data keys taking
+// a known set of values is one of them
+var enumeratedFields = map[string]func(string) bool{
+	"pdn_type":            func(v string) bool { _, ok := pdnTypes[v]; return ok },
+	"request_type":        func(v string) bool { _, ok := requestTypes[v]; return ok },
+	"sms_initiator":       func(v string) bool { _, ok := smsInitiators[v]; return ok },
+	"sms_transfer_status": func(v string) bool { _, ok := smsTransferStatuses[v]; return ok },
+	"sms_other_message":   func(v string) bool { _, ok := smsOtherMessages[v]; return ok },
+}
+
+// validateEventData checks that the event data used to build a record
+// is well-formed before encoding it.
+func validateEventData(event *models.Event) error {
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return newDecodingError(FieldEventData, fmt.Errorf("unexpected value type %T", event.Value))
+	}
+
+	for _, f := range eventDataFields {
+		v, ok := eventData[f.key]
+		if !ok {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			return newDecodingError(f.field, fmt.Errorf("%s has unexpected type %T", f.key, v))
+		}
+		switch strings.TrimPrefix(f.key, previousPrefix) {
+		case "ip_addr":
+			if ip := net.ParseIP(s); ip == nil || ip.To4() == nil {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid IPv4 address: %q", f.key, s))
+			}
+		case "ipv6_addr":
+			if ip := net.ParseIP(s); ip == nil || ip.To4() != nil {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid IPv6 address: %q", f.key, s))
+			}
+		case "ipv6_prefix":
+			if ip, _, err := net.ParseCIDR(s); err != nil || ip.To4() != nil {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid IPv6 prefix: %q", f.key, s))
+			}
+		case "mme_address", "sgsn_ip", "ggsn_ip":
+			if net.ParseIP(s) == nil {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid IP address: %q", f.key, s))
+			}
+		case "bearer_id", "linked_bearer_id":
+			if _, err := strconv.ParseUint(s, 10, 4); err != nil {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid EPS bearer ID: %q", f.key, s))
+			}
+		case "failure_cause", "qci":
+			if _, err := strconv.ParseUint(s, 10, 8); err != nil {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid octet: %q", f.key, s))
+			}
+		case "apn_ambr_ul", "apn_ambr_dl", "mbr_ul", "mbr_dl", "gbr_ul", "gbr_dl":
+			if _, err := strconv.ParseUint(s, 10, 32); err != nil {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid bit rate: %q", f.key, s))
+			}
+		case "arp_priority_level":
+			if v, err := strconv.ParseUint(s, 10, 4); err != nil || v == 0 {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid ARP priority level: %q", f.key, s))
+			}
+		case "arp_preemption_capability", "arp_preemption_vulnerability":
+			if s != "true" && s != "false" {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a boolean: %q", f.key, s))
+			}
+		case "bytes_tx", "bytes_rx", "duration_secs":
+			if v, err := strconv.ParseUint(s, 10, 64); err != nil || v > math.MaxInt64 {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid count: %q", f.key, s))
+			}
+		case "tai", "ecgi", "cell_global_id", "routing_area_id", "service_area_id":
+			if b, err := hex.DecodeString(s); err != nil || len(b) < locationLengths[f.key][0] || len(b) > locationLengths[f.key][1] {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid location: %q", f.key, s))
+			}
+		case "qos_profile":
+			if b, err := hex.DecodeString(s); err != nil || len(b) == 0 || len(b) > maxQoSProfileLen {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid QoS profile: %q", f.key, s))
+			}
+		case "sms_content":
+			if b, err := hex.DecodeString(s); err != nil || len(b) == 0 || len(b) > maxSMSContentLen {
+				return newEncodingError(f.field, fmt.Errorf("%s is not a valid SMS TPDU: %q", f.key, s))
+			}
+		default:
+			if values, ok := enumeratedFields[f.key]; ok && !values(s) {
+				return newEncodingError(f.field, fmt.Errorf("invalid %s %q", f.key, s))
+			}
+		}
+	}
+
+	// the role of a correspondent party must be known to qualify it
+	correspondent := makePartyIdentity(eventData, correspondentPrefix)
+	if isEmptyIdentity(&correspondent) {
+		return nil
+	}
+	qualifier, _ := eventData["correspondent_qualifier"].(string)
+	if _, ok := correspondentQualifiers[qualifier]; !ok {
+		return newEncodingError(FieldIdentity, fmt.Errorf("invalid correspondent_qualifier %q", qualifier))
+	}
+	return nil
+}
+
+// diagnoseContentError marshals each section of the IRI content separately
+// to find the one responsible for a marshaling failure.
+func diagnoseContentError(content EpsIRIContent, err error) error {
+	sections := []struct {
+		field string
+		value interface{}
+	}{
+		{FieldTimestamp, content.TimeStamp},
+		{FieldIdentity, content.PartyInformation},
+		{FieldSMS, content.SMS},
+		{FieldNetworkIdentifier, content.NetworkIdentifier},
+		{FieldBearerParams, content.EPSSpecificParameters},
+		{FieldUsage, content.NationalParameters},
+	}
+	for _, section := range sections {
+		if _, serr := asn1.Marshal(section.value); serr != nil {
+			return newEncodingError(section.field, serr)
+		}
+	}
+	return newEncodingError(FieldUnknown, err)
+}
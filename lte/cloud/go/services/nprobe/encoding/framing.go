@@ -0,0 +1,482 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	// FramingNative writes the records as built, each delimited by the
+	// lengths of its ETSI TS 103 221-2 header
+	FramingNative = "native"
+	// FramingLengthPrefixed writes each record after its length, 4 octets in
+	// network byte order, e.g. for FLIP-style mediation functions
+	FramingLengthPrefixed = "length_prefixed"
+	// FramingPSPDU writes each record as an ETSI TS 102 232-1 PS-PDU, its
+	// header parameters carried in the PSHeader
+	FramingPSPDU = "ps_pdu"
+	// FramingRawBER writes the BER payloads of the records back to back,
+	// without header
+	FramingRawBER = "raw_ber"
+)
+
+// lengthPrefixLen is the length of the prefix of the length-prefixed framing
+const lengthPrefixLen = 4
+
+// PSDomainID is the li-psDomainId of the PS-PDUs, version 21 of the
+// LI-PS-PDU module as defined in ETSI TS 102 232-1
+var PSDomainID = asn1.ObjectIdentifier{0, 4, 0, 2, 2, 5, 1, 21}
+
+// Tags of the PS-PDU and its components, as defined in ETSI TS 102 232-1
+const (
+	psPDUHeaderTag  = 1
+	psPDUPayloadTag = 2
+
+	psHeaderDomainIDTag       = 0
+	psHeaderLIIDTag           = 1
+	psHeaderCommunicationTag  = 3
+	psHeaderSequenceNumberTag = 4
+	psHeaderTimestampTag      = 5
+
+	communicationNetworkTag         = 0
+	communicationIdentityNumberTag  = 1
+	communicationDeliveryCountryTag = 2
+	networkOperatorTag              = 0
+
+	payloadIRISequenceTag      = 0
+	payloadHI1OperationTag     = 3
+	payloadEncryptionContainer = 4
+
+	iriPayloadTypeTag      = 0
+	iriPayloadTimestampTag = 1
+	iriPayloadContentsTag  = 2
+
+	iriContentsUmtsTag = 6
+	iriContentsEpsTag  = 8
+)
+
+// iriTypes maps the record classes to the IRIType of the IRI payloads
+var iriTypes = map[string]asn1.Enumerated{
+	RecordClassBegin:    1,
+	RecordClassEnd:      2,
+	RecordClassContinue: 3,
+	RecordClassReport:   4,
+}
+
+// psTimestampFormat is the format of the timestamps of the PS-PDUs
+var psTimestampFormat = TimestampFormat{
+	Encoding:  TimestampEncodingGeneralized,
+	Precision: TimestampPrecisionMilli,
+	Zone:      TimestampZoneUTC,
+}
+
+// Framer frames the records written on a delivery connection, and reads the
+// frames sent back by the delivery function
+type Framer struct {
+	framing       string
+	encoding      string
+	headerVersion uint16
+	operatorID    []byte
+	// maxRecordSize is the size limit of the records written, the larger
+	// ones being segmented, none when 0
+	maxRecordSize int
+}
+
+// NewFramer returns the framer of a framing, FramingNative if empty. The
+// operator ID identifies the network of the PS-PDUs.
+func NewFramer(framing string, operatorID uint32) (*Framer, error) {
+	if framing == "" {
+		framing = FramingNative
+	}
+	switch framing {
+	case FramingNative, FramingLengthPrefixed, FramingPSPDU, FramingRawBER:
+	default:
+		return nil, fmt.Errorf("unsupported framing %s", framing)
+	}
+	return &Framer{
+		framing:       framing,
+		encoding:      EncodingDER,
+		headerVersion: HeaderVersion,
+		operatorID:    convertUint32ToBytes(operatorID),
+	}, nil
+}
+
+// Framing returns the framing of the framer
+func (f *Framer) Framing() string {
+	return f.framing
+}
+
+// SetEncoding sets the encoding of the BER elements of the frames,
+// EncodingDER if empty
+func (f *Framer) SetEncoding(encoding string) error {
+	encoding, err := ValidateEncoding(encoding)
+	if err != nil {
+		return err
+	}
+	f.encoding = encoding
+	return nil
+}
+
+// Encoding returns the encoding of the BER elements of the frames
+func (f *Framer) Encoding() string {
+	return f.encoding
+}
+
+// SetHeaderVersion sets the header version of the PDUs of the frames,
+// HeaderVersion if 0
+func (f *Framer) SetHeaderVersion(version uint16) error {
+	version, err := ValidateHeaderVersion(version)
+	if err != nil {
+		return err
+	}
+	f.headerVersion = version
+	return nil
+}
+
+// HeaderVersion returns the header version of the PDUs of the frames
+func (f *Framer) HeaderVersion() uint16 {
+	return f.headerVersion
+}
+
+// SetMaxRecordSize sets the size limit of the records written, without the
+// length prefix, 0 for none. Larger records are segmented with the framings
+// carrying PDUs, and fail with the others whose frames exceed it.
+func (f *Framer) SetMaxRecordSize(size int) error {
+	if size < 0 {
+		return fmt.Errorf("invalid maximum record size %d", size)
+	}
+	f.maxRecordSize = size
+	return nil
+}
+
+// MaxRecordSize returns the size limit of the records written, 0 for none
+func (f *Framer) MaxRecordSize() int {
+	return f.maxRecordSize
+}
+
+// CarriesPDUs returns true if the frames carry ETSI TS 103 221-2 PDUs, so
+// that keepalives and acknowledgements can be exchanged on the connection
+func (f *Framer) CarriesPDUs() bool {
+	return f.framing == FramingNative || f.framing == FramingLengthPrefixed
+}
+
+// Frame returns an encoded record or PDU as written on the connection. The
+// BER elements of the frame are written in the encoding of the framer, the
+// PS-PDUs being built from the records as encoded before being reencoded,
+// and the PDUs carried in its header version. Records beyond the maximum
+// record size are written as their segments, framed back to back.
+func (f *Framer) Frame(record []byte) ([]byte, error) {
+	if f.encoding != EncodingDER && f.framing != FramingPSPDU {
+		var err error
+		if record, err = ReencodeRecord(record, f.encoding); err != nil {
+			return nil, err
+		}
+	}
+	if f.headerVersion != HeaderVersion && f.CarriesPDUs() {
+		var err error
+		if record, err = ConvertHeaderVersion(record, f.headerVersion); err != nil {
+			return nil, err
+		}
+	}
+	if f.maxRecordSize > 0 && len(record) > f.maxRecordSize && f.CarriesPDUs() {
+		return f.frameSegments(record)
+	}
+	framed, err := f.frameRecord(record)
+	if err == nil && f.maxRecordSize > 0 && len(framed) > f.maxRecordSize && !f.CarriesPDUs() {
+		return nil, fmt.Errorf("frame of %d bytes exceeds the maximum record size %d of the %s framing", len(framed), f.maxRecordSize, f.framing)
+	}
+	return framed, err
+}
+
+// frameRecord returns a record or PDU in the framing of the framer
+func (f *Framer) frameRecord(record []byte) ([]byte, error) {
+	switch f.framing {
+	case FramingLengthPrefixed:
+		if uint64(len(record)) > math.MaxUint32 {
+			return nil, fmt.Errorf("record length %d exceeds the length prefix", len(record))
+		}
+		b := make([]byte, lengthPrefixLen+len(record))
+		binary.BigEndian.PutUint32(b, uint32(len(record)))
+		copy(b[lengthPrefixLen:], record)
+		return b, nil
+	case FramingPSPDU:
+		pdu, err := MakePSPDU(record, f.operatorID)
+		if err != nil || f.encoding == EncodingDER {
+			return pdu, err
+		}
+		return Reencode(pdu, f.encoding)
+	case FramingRawBER:
+		hdr, err := ParseHeader(record)
+		if err != nil {
+			return nil, err
+		}
+		return record[hdr.HeaderLength : hdr.HeaderLength+hdr.PayloadLength], nil
+	}
+	return record, nil
+}
+
+// frameSegments returns the frames of the segments of a record beyond the
+// maximum record size, back to back
+func (f *Framer) frameSegments(record []byte) ([]byte, error) {
+	segments, err := SegmentRecord(record, f.maxRecordSize)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	for _, segment := range segments {
+		framed, err := f.frameRecord(segment)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, framed...)
+	}
+	return b, nil
+}
+
+// ReadFrame reads a frame from a stream. It returns the PDU it carries with
+// the native and length-prefixed framings, and the BER element read
+// otherwise. Frames whose size exceeds maxSize are rejected with
+// ErrRecordTooLarge.
+func (f *Framer) ReadFrame(r io.Reader, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxRecordSize
+	}
+	switch f.framing {
+	case FramingLengthPrefixed:
+		var prefix [lengthPrefixLen]byte
+		if _, err := io.ReadFull(r, prefix[:]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(prefix[:])
+		if uint64(n) > uint64(maxSize) {
+			return nil, ErrRecordTooLarge
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case FramingPSPDU, FramingRawBER:
+		return readBERElement(r, maxSize)
+	}
+	return ReadPDU(r, maxSize)
+}
+
+// MakePSPDU converts an encoded record to an ETSI TS 102 232-1 PS-PDU. The
+// PSHeader carries the LIID and delivery country code of the ETSI TS 102
+// 232-1 defined attribute of the record, its XID standing for the LIID
+// otherwise, along with its correlation ID, sequence number and timestamp.
+// IRI records are carried as IRI payloads, HI1 notifications and encrypted
+// payloads as their TS 102 232-1 payload.
+func MakePSPDU(record []byte, operatorID []byte) ([]byte, error) {
+	hdr, err := ParseHeader(record)
+	if err != nil {
+		return nil, err
+	}
+	payload := record[hdr.HeaderLength : hdr.HeaderLength+hdr.PayloadLength]
+	var timestamp []byte
+	if value := getAttribute(hdr, AttributeTimestamp); value != nil {
+		if t, err := decodeAttributeTimestamp(value); err == nil {
+			timestamp, _ = formatTimestamp(t, psTimestampFormat)
+		}
+	}
+
+	b := make([]byte, 0, 128+len(payload))
+	b, pdu := beginElement(b, constructedForm, tagUniversalSequence)
+	if b, err = appendPSHeader(b, hdr, operatorID, timestamp); err != nil {
+		return nil, err
+	}
+	b, pld := beginElement(b, classContextSpecific|constructedForm, psPDUPayloadTag)
+	switch hdr.PayloadFormat {
+	case HeaderPayloadFormat:
+		b, err = appendIRIPayload(b, payload, timestamp)
+		if err != nil {
+			return nil, err
+		}
+	case HeaderPayloadFormatHI1:
+		if payload[0] == constructedForm|tagUniversalSequence {
+			// the encryption container, implicitly tagged
+			b = append(b, classContextSpecific|constructedForm|payloadEncryptionContainer)
+			b = append(b, payload[1:]...)
+		} else {
+			inner := 0
+			b, inner = beginElement(b, classContextSpecific|constructedForm, payloadHI1OperationTag)
+			b = append(b, payload...)
+			b = endElement(b, inner)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported payload format %d", hdr.PayloadFormat)
+	}
+	b = endElement(b, pld)
+	return endElement(b, pdu), nil
+}
+
+// appendPSHeader appends the PSHeader of the PS-PDU of a record
+func appendPSHeader(b []byte, hdr *EpsIRIHeader, operatorID, timestamp []byte) ([]byte, error) {
+	var err error
+	var ps PSHeaderAttribute
+	for _, attr := range hdr.ConditionalAttributes {
+		if attr.Tag != AttributeETSI102232 {
+			continue
+		}
+		// integrity checks are told apart by their mandatory first field
+		var check IntegrityCheck
+		if rest, err := asn1.Unmarshal(attr.Value, &check); err == nil && len(rest) == 0 {
+			continue
+		}
+		var attrPS PSHeaderAttribute
+		if rest, err := asn1.Unmarshal(attr.Value, &attrPS); err == nil && len(rest) == 0 {
+			ps = attrPS
+			break
+		}
+	}
+	liid := ps.LawfulInterceptionIdentifier
+	if len(liid) == 0 {
+		liid = []byte(hdr.XID.String())
+	}
+
+	b, header := beginElement(b, classContextSpecific|constructedForm, psPDUHeaderTag)
+	if b, err = appendObjectIdentifier(b, psHeaderDomainIDTag, PSDomainID); err != nil {
+		return nil, err
+	}
+	b = appendBytes(b, psHeaderLIIDTag, liid)
+	b, communication := beginElement(b, classContextSpecific|constructedForm, psHeaderCommunicationTag)
+	b, network := beginElement(b, classContextSpecific|constructedForm, communicationNetworkTag)
+	b = appendBytes(b, networkOperatorTag, operatorID)
+	b = endElement(b, network)
+	if hdr.CorrelationID <= math.MaxUint32 {
+		b = appendInteger(b, communicationIdentityNumberTag, int64(hdr.CorrelationID))
+	}
+	if len(ps.DeliveryCountryCode) != 0 {
+		if b, err = appendPrintableString(b, communicationDeliveryCountryTag, ps.DeliveryCountryCode); err != nil {
+			return nil, err
+		}
+	}
+	b = endElement(b, communication)
+	seqNbr := uint32(0)
+	if value := getAttribute(hdr, AttributeSeqNumber); len(value) == 4 {
+		seqNbr = binary.BigEndian.Uint32(value)
+	}
+	b = appendInteger(b, psHeaderSequenceNumberTag, int64(seqNbr))
+	if timestamp != nil {
+		b = appendBytes(b, psHeaderTimestampTag, timestamp)
+	}
+	return endElement(b, header), nil
+}
+
+// appendIRIPayload appends the sequence of the IRI payload of a record,
+// carrying its TS 133 108 content in the EPS or UMTS IRI contents
+func appendIRIPayload(b []byte, payload, timestamp []byte) ([]byte, error) {
+	iriType, ok := iriTypes[decodeRecordClass(payload[0])]
+	if !ok {
+		return nil, fmt.Errorf("unexpected record type tag %#x", payload[0])
+	}
+	contentsTag := iriContentsEpsTag
+	if isUmtsPayload(payload) {
+		contentsTag = iriContentsUmtsTag
+	}
+	b, sequence := beginElement(b, classContextSpecific|constructedForm, payloadIRISequenceTag)
+	b, iri := beginElement(b, constructedForm, tagUniversalSequence)
+	b = appendEnumerated(b, iriPayloadTypeTag, iriType)
+	if timestamp != nil {
+		b = appendBytes(b, iriPayloadTimestampTag, timestamp)
+	}
+	b, contents := beginElement(b, classContextSpecific|constructedForm, iriPayloadContentsTag)
+	b, content := beginElement(b, classContextSpecific|constructedForm, contentsTag)
+	b = append(b, payload...)
+	b = endElement(b, content)
+	b = endElement(b, contents)
+	b = endElement(b, iri)
+	return endElement(b, sequence), nil
+}
+
+// decodeAttributeTimestamp returns the time of a timestamp attribute, binary,
+// GeneralizedTime or X2
+func decodeAttributeTimestamp(value []byte) (time.Time, error) {
+	if len(value) == x2TimestampLen {
+		return GetX2Timestamp(value)
+	}
+	return decodeGeneralizedTime(value)
+}
+
+// readBERElement reads a single BER element of definite length from a
+// stream, whose size must not exceed maxSize
+func readBERElement(r io.Reader, maxSize int) ([]byte, error) {
+	b := make([]byte, 0, 16)
+	readByte := func() (byte, error) {
+		var c [1]byte
+		if _, err := io.ReadFull(r, c[:]); err != nil {
+			return 0, err
+		}
+		b = append(b, c[0])
+		return c[0], nil
+	}
+	tag, err := readByte()
+	if err != nil {
+		return nil, err
+	}
+	if tag&tagHighForm == tagHighForm {
+		// high tag numbers continue while the high bit is set
+		for {
+			c, err := readByte()
+			if err != nil {
+				return nil, err
+			}
+			if c&0x80 == 0 {
+				break
+			}
+			if len(b) > 5 {
+				return nil, errors.New("tag number too large")
+			}
+		}
+	}
+	l, err := readByte()
+	if err != nil {
+		return nil, err
+	}
+	n := uint64(l)
+	if l&0x80 != 0 {
+		k := int(l & 0x7f)
+		if k == 0 {
+			return nil, errors.New("indefinite length not supported")
+		}
+		if k > 4 {
+			return nil, ErrRecordTooLarge
+		}
+		n = 0
+		for i := 0; i < k; i++ {
+			c, err := readByte()
+			if err != nil {
+				return nil, err
+			}
+			n = n<<8 | uint64(c)
+		}
+	}
+	if uint64(len(b))+n > uint64(maxSize) {
+		return nil, ErrRecordTooLarge
+	}
+	element := make([]byte, len(b)+int(n))
+	copy(element, b)
+	if _, err := io.ReadFull(r, element[len(b):]); err != nil {
+		return nil, err
+	}
+	return element, nil
+}
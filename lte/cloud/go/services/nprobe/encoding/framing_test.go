@@ -0,0 +1,267 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The types below mirror the PS-PDU of ETSI TS 102 232-1, independently of
+// the encoder, as a LEMF would decode it.
+type testPSPDU struct {
+	PSHeader testPSHeader  `asn1:"tag:1"`
+	Payload  asn1.RawValue `asn1:"tag:2"`
+}
+
+type testPSHeader struct {
+	DomainID                asn1.ObjectIdentifier       `asn1:"tag:0"`
+	LIID                    []byte                      `asn1:"tag:1"`
+	CommunicationIdentifier testCommunicationIdentifier `asn1:"tag:3"`
+	SequenceNumber          int64                       `asn1:"tag:4"`
+	TimeStamp               []byte                      `asn1:"optional,tag:5"`
+}
+
+type testCommunicationIdentifier struct {
+	NetworkIdentifier struct {
+		OperatorIdentifier []byte `asn1:"tag:0"`
+	} `asn1:"tag:0"`
+	CommunicationIdentityNumber int64  `asn1:"optional,tag:1"`
+	DeliveryCountryCode         string `asn1:"printable,optional,tag:2"`
+}
+
+type testIRIPayload struct {
+	IRIType     asn1.Enumerated `asn1:"tag:0"`
+	TimeStamp   []byte          `asn1:"optional,tag:1"`
+	IRIContents asn1.RawValue   `asn1:"tag:2"`
+}
+
+func TestLengthPrefixedFraming(t *testing.T) {
+	framer, err := NewFramer(FramingLengthPrefixed, 0)
+	assert.NoError(t, err)
+	assert.True(t, framer.CarriesPDUs())
+	frame, err := framer.Frame(encodedRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(len(encodedRecord)), binary.BigEndian.Uint32(frame))
+	assert.Equal(t, encodedRecord, frame[4:])
+
+	r := bytes.NewReader(append(frame, frame...))
+	for i := 0; i < 2; i++ {
+		record, err := framer.ReadFrame(r, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, encodedRecord, record)
+	}
+	_, err = framer.ReadFrame(r, 0)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = framer.ReadFrame(bytes.NewReader(frame), len(encodedRecord)-1)
+	assert.Equal(t, ErrRecordTooLarge, err)
+}
+
+func TestNativeFraming(t *testing.T) {
+	framer, err := NewFramer("", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, FramingNative, framer.Framing())
+	assert.True(t, framer.CarriesPDUs())
+	frame, err := framer.Frame(encodedRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, frame)
+	record, err := framer.ReadFrame(bytes.NewReader(frame), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, record)
+
+	// the PDUs are written in the header version of the framer
+	assert.Equal(t, HeaderVersion, framer.HeaderVersion())
+	assert.NoError(t, framer.SetHeaderVersion(HeaderVersion1))
+	frame, err = framer.Frame(encodedRecord)
+	assert.NoError(t, err)
+	v1, err := ConvertHeaderVersion(encodedRecord, HeaderVersion1)
+	assert.NoError(t, err)
+	assert.Equal(t, v1, frame)
+	assert.EqualError(t, framer.SetHeaderVersion(3), "unsupported header version 3")
+	assert.Equal(t, HeaderVersion1, framer.HeaderVersion())
+
+	_, err = NewFramer("asn1_per", 0)
+	assert.EqualError(t, err, "unsupported framing asn1_per")
+}
+
+func TestRawBERFraming(t *testing.T) {
+	framer, err := NewFramer(FramingRawBER, 0)
+	assert.NoError(t, err)
+	assert.False(t, framer.CarriesPDUs())
+	hdr, err := ParseHeader(encodedRecord)
+	assert.NoError(t, err)
+	frame, err := framer.Frame(encodedRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord[hdr.HeaderLength:], frame)
+
+	// the payloads are delimited by their BER lengths alone
+	r := bytes.NewReader(append(append([]byte{}, frame...), frame...))
+	for i := 0; i < 2; i++ {
+		payload, err := framer.ReadFrame(r, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, frame, payload)
+	}
+	_, err = framer.ReadFrame(r, 0)
+	assert.Equal(t, io.EOF, err)
+	_, err = framer.ReadFrame(bytes.NewReader(frame), len(frame)-1)
+	assert.Equal(t, ErrRecordTooLarge, err)
+	_, err = framer.ReadFrame(bytes.NewReader([]byte{0x30, 0x80, 0x00, 0x00}), 0)
+	assert.EqualError(t, err, "indefinite length not supported")
+	_, err = framer.ReadFrame(bytes.NewReader(frame[:len(frame)-1]), 0)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestPSPDUFraming(t *testing.T) {
+	framer, err := NewFramer(FramingPSPDU, 0x00f110)
+	assert.NoError(t, err)
+	assert.False(t, framer.CarriesPDUs())
+	frame, err := framer.Frame(encodedRecord)
+	assert.NoError(t, err)
+	read, err := framer.ReadFrame(bytes.NewReader(frame), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, frame, read)
+
+	hdr, err := ParseHeader(encodedRecord)
+	assert.NoError(t, err)
+	var pdu testPSPDU
+	rest, err := asn1.Unmarshal(frame, &pdu)
+	assert.NoError(t, err)
+	assert.Empty(t, rest)
+	assert.True(t, PSDomainID.Equal(pdu.PSHeader.DomainID))
+	assert.Equal(t, []byte(hdr.XID.String()), pdu.PSHeader.LIID)
+	assert.Equal(t, []byte{0x00, 0x00, 0xf1, 0x10}, pdu.PSHeader.CommunicationIdentifier.NetworkIdentifier.OperatorIdentifier)
+	// the correlation ID does not fit in the communication identity number
+	assert.Zero(t, pdu.PSHeader.CommunicationIdentifier.CommunicationIdentityNumber)
+	assert.Equal(t, int64(6), pdu.PSHeader.SequenceNumber)
+	assert.Equal(t, []byte("20210419153536.310Z"), pdu.PSHeader.TimeStamp)
+
+	var iris []testIRIPayload
+	_, err = asn1.UnmarshalWithParams(pdu.Payload.Bytes, &iris, "tag:0")
+	assert.NoError(t, err)
+	assert.Len(t, iris, 1)
+	assert.Equal(t, asn1.Enumerated(1), iris[0].IRIType)
+	assert.Equal(t, pdu.PSHeader.TimeStamp, iris[0].TimeStamp)
+	var contents asn1.RawValue
+	_, err = asn1.Unmarshal(iris[0].IRIContents.Bytes, &contents)
+	assert.NoError(t, err)
+	assert.Equal(t, asn1.ClassContextSpecific, contents.Class)
+	assert.Equal(t, 8, contents.Tag)
+	assert.Equal(t, encodedRecord[hdr.HeaderLength:], contents.Bytes)
+}
+
+func TestPSPDUFramingHI1(t *testing.T) {
+	framer, err := NewFramer(FramingPSPDU, 1)
+	assert.NoError(t, err)
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			TargetType:    models.NetworkProbeTaskDetailsTargetTypeImsi,
+			CorrelationID: 42,
+		},
+	}
+	notification, err := MakeHI1Notification(task, 1, HI1Activated, "", time.Unix(1615000000, 0))
+	assert.NoError(t, err)
+	hdr, err := ParseHeader(notification)
+	assert.NoError(t, err)
+
+	// HI1 notifications are carried as HI1 operations
+	frame, err := framer.Frame(notification)
+	assert.NoError(t, err)
+	var pdu testPSPDU
+	_, err = asn1.Unmarshal(frame, &pdu)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), pdu.PSHeader.CommunicationIdentifier.CommunicationIdentityNumber)
+	var operation asn1.RawValue
+	_, err = asn1.Unmarshal(pdu.Payload.Bytes, &operation)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, operation.Tag)
+	assert.Equal(t, notification[hdr.HeaderLength:], operation.Bytes)
+
+	// encrypted payloads are carried in the encryption container
+	encryption, err := NewPayloadEncryption(PayloadEncryptionAES256CBC, bytes.Repeat([]byte{0x2a}, 32))
+	assert.NoError(t, err)
+	encrypted, err := encryption.Encrypt(encodedRecord)
+	assert.NoError(t, err)
+	frame, err = framer.Frame(encrypted)
+	assert.NoError(t, err)
+	pdu = testPSPDU{}
+	_, err = asn1.Unmarshal(frame, &pdu)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), pdu.PSHeader.SequenceNumber)
+	var container EncryptionContainer
+	_, err = asn1.UnmarshalWithParams(pdu.Payload.Bytes, &container, "tag:4")
+	assert.NoError(t, err)
+	assert.Equal(t, EncryptionTypeAES256CBC, container.EncryptionType)
+}
+
+func TestFramingMaxRecordSize(t *testing.T) {
+	hdr, err := ParseHeader(encodedRecord)
+	assert.NoError(t, err)
+	maxSize := int(hdr.HeaderLength) + 32
+	segments, err := SegmentRecord(encodedRecord, maxSize)
+	assert.NoError(t, err)
+
+	// records beyond the limit are written as their segments, back to back
+	framer, err := NewFramer(FramingLengthPrefixed, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, framer.SetMaxRecordSize(maxSize))
+	assert.Equal(t, maxSize, framer.MaxRecordSize())
+	frame, err := framer.Frame(encodedRecord)
+	assert.NoError(t, err)
+	r := bytes.NewReader(frame)
+	var reassembler Reassembler
+	for i := range segments {
+		pdu, err := framer.ReadFrame(r, maxSize)
+		assert.NoError(t, err)
+		assert.Equal(t, segments[i], pdu)
+		record, err := reassembler.Add(pdu)
+		assert.NoError(t, err)
+		if i < len(segments)-1 {
+			assert.Nil(t, record)
+		} else {
+			assert.Equal(t, encodedRecord, record)
+		}
+	}
+	_, err = framer.ReadFrame(r, maxSize)
+	assert.Equal(t, io.EOF, err)
+
+	// records within the limit are written whole
+	assert.NoError(t, framer.SetMaxRecordSize(len(encodedRecord)))
+	frame, err = framer.Frame(encodedRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, frame[4:])
+	assert.EqualError(t, framer.SetMaxRecordSize(-1), "invalid maximum record size -1")
+
+	// frames of the framings not carrying PDUs can't be segmented
+	framer, err = NewFramer(FramingRawBER, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, framer.SetMaxRecordSize(int(hdr.PayloadLength)))
+	frame, err = framer.Frame(encodedRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord[hdr.HeaderLength:], frame)
+	assert.NoError(t, framer.SetMaxRecordSize(16))
+	_, err = framer.Frame(encodedRecord)
+	assert.EqualError(t, err, fmt.Sprintf("frame of %d bytes exceeds the maximum record size 16 of the raw_ber framing", hdr.PayloadLength))
+}
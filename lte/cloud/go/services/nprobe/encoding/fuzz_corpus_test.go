@@ -0,0 +1,189 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The decoders parse bytes received from peers, e.g. acknowledgements read
+// by the exporter or records read by nprobe_cli. The checks of their fuzz
+// targets run on the seeds below and on the corpus of testdata/fuzz with go
+// test, whatever the toolchain.
+
+// maxFuzzAllocation bounds the bytes the decoders may allocate per byte of
+// input, past a fixed allowance
+const maxFuzzAllocation = 64
+
+func headerUnmarshalSeeds() [][]byte {
+	return [][]byte{encodedRecord[:binary.BigEndian.Uint32(encodedRecord[4:8])], MakeKeepalive(1)}
+}
+
+func parsePDUHeaderSeeds() [][]byte {
+	return [][]byte{encodedRecord, MakeKeepalive(1)}
+}
+
+type readPDUSeed struct {
+	b       []byte
+	maxSize int
+}
+
+func readPDUSeeds() []readPDUSeed {
+	return []readPDUSeed{
+		{b: encodedRecord},
+		{b: append(MakeKeepalive(1), encodedRecord...), maxSize: 64},
+	}
+}
+
+func decodeSeeds() [][]byte {
+	return [][]byte{encodedRecord}
+}
+
+func checkHeaderUnmarshal(t *testing.T, b []byte) {
+	var hdr EpsIRIHeader
+	if err := hdr.Unmarshal(b); err != nil {
+		return
+	}
+	// the headers decoded are encoded back to the same bytes
+	if marshaled := hdr.Marshal(); !bytes.Equal(marshaled, b) {
+		t.Fatalf("header %x marshaled back to %x", b, marshaled)
+	}
+}
+
+func checkParsePDUHeader(t *testing.T, pdu []byte) {
+	hdr, err := ParsePDUHeader(pdu)
+	if err != nil {
+		return
+	}
+	if uint64(hdr.HeaderLength) > uint64(len(pdu)) {
+		t.Fatalf("header of %d bytes parsed from %d bytes", hdr.HeaderLength, len(pdu))
+	}
+	MakeKeepaliveAck(hdr)
+}
+
+func checkReadPDU(t *testing.T, b []byte, maxSize int) {
+	var pdu []byte
+	var err error
+	checkAllocations(t, len(b), func() {
+		pdu, err = ReadPDU(bytes.NewReader(b), maxSize)
+	})
+	if err != nil {
+		return
+	}
+	if len(pdu) > len(b) || (maxSize > 0 && len(pdu) > maxSize) {
+		t.Fatalf("read PDU of %d bytes from %d bytes with limit %d", len(pdu), len(b), maxSize)
+	}
+}
+
+func checkDecode(t *testing.T, b []byte) {
+	checkAllocations(t, len(b), func() {
+		var record EpsIRIRecord
+		if err := record.Decode(b); err != nil {
+			return
+		}
+		// the decoded records are rendered and checked without panics
+		if _, err := ToJSON(&record); err != nil {
+			t.Fatalf("failed to render decoded record %x: %v", b, err)
+		}
+		Validate(b)
+		GetRecordClass(b)
+		GetMissingParameters(&record.Header)
+		GetLinkedXID(&record.Header)
+	})
+}
+
+// checkAllocations fails the test if decoding n bytes with decode allocates
+// more than maxFuzzAllocation bytes per byte
+func checkAllocations(t *testing.T, n int, decode func()) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	decode()
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > uint64(maxFuzzAllocation*(n+1024)) {
+		t.Fatalf("%d bytes allocated decoding %d bytes", allocated, n)
+	}
+}
+
+// readCorpus returns the byte slices of the corpus entries of a fuzz target
+// in testdata/fuzz, each holding a single []byte value
+func readCorpus(t *testing.T, target string) map[string][]byte {
+	paths, err := filepath.Glob(filepath.Join("testdata", "fuzz", target, "*"))
+	assert.NoError(t, err)
+	ret := map[string][]byte{}
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		value, err := parseCorpusEntry(string(content))
+		assert.NoError(t, err, path)
+		ret[filepath.Base(path)] = value
+	}
+	return ret
+}
+
+// parseCorpusEntry parses a corpus entry of the go test fuzz v1 format
+// holding a single []byte value
+func parseCorpusEntry(content string) ([]byte, error) {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) != 2 || lines[0] != "go test fuzz v1" {
+		return nil, fmt.Errorf("expected a go test fuzz v1 entry with a single value")
+	}
+	value := strings.TrimSpace(lines[1])
+	if !strings.HasPrefix(value, "[]byte(") || !strings.HasSuffix(value, ")") {
+		return nil, fmt.Errorf("expected a []byte value, got %s", value)
+	}
+	unquoted, err := strconv.Unquote(value[len("[]byte(") : len(value)-1])
+	if err != nil {
+		return nil, err
+	}
+	return []byte(unquoted), nil
+}
+
+func TestFuzzCorpus(t *testing.T) {
+	targets := map[string]struct {
+		seeds [][]byte
+		check func(t *testing.T, b []byte)
+	}{
+		"FuzzHeaderUnmarshal": {seeds: headerUnmarshalSeeds(), check: checkHeaderUnmarshal},
+		"FuzzParsePDUHeader":  {seeds: parsePDUHeaderSeeds(), check: checkParsePDUHeader},
+		"FuzzDecode":          {seeds: decodeSeeds(), check: checkDecode},
+	}
+	for target, tc := range targets {
+		tc := tc
+		t.Run(target, func(t *testing.T) {
+			for i, seed := range tc.seeds {
+				t.Run(fmt.Sprintf("seed#%d", i), func(t *testing.T) { tc.check(t, seed) })
+			}
+			for name, entry := range readCorpus(t, target) {
+				entry := entry
+				t.Run(name, func(t *testing.T) { tc.check(t, entry) })
+			}
+		})
+	}
+	t.Run("FuzzReadPDU", func(t *testing.T) {
+		for i, seed := range readPDUSeeds() {
+			seed := seed
+			t.Run(fmt.Sprintf("seed#%d", i), func(t *testing.T) { checkReadPDU(t, seed.b, seed.maxSize) })
+		}
+	})
+}
@@ -0,0 +1,53 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+)
+
+// The fuzz targets need go 1.18, their seed corpus running with
+// TestFuzzCorpus on older toolchains. They are fuzzed with e.g.
+//   go test -run NONE -fuzz FuzzDecode ./encoding
+
+func FuzzHeaderUnmarshal(f *testing.F) {
+	for _, seed := range headerUnmarshalSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(checkHeaderUnmarshal)
+}
+
+func FuzzParsePDUHeader(f *testing.F) {
+	for _, seed := range parsePDUHeaderSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(checkParsePDUHeader)
+}
+
+func FuzzReadPDU(f *testing.F) {
+	for _, seed := range readPDUSeeds() {
+		f.Add(seed.b, seed.maxSize)
+	}
+	f.Fuzz(checkReadPDU)
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, seed := range decodeSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(checkDecode)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/hex"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden encodings of testdata/golden")
+
+// goldenContents are the IRI contents whose encodings are compared to the
+// reference vectors of testdata/golden, encoded by encoding/asn1
+var goldenContents = []struct {
+	name    string
+	class   string
+	content EpsIRIContent
+}{
+	{
+		name:  "bearer_activation",
+		class: RecordClassBegin,
+		content: EpsIRIContent{
+			Hi2epsDomainID:       GetOID(),
+			LawInterceptID:       []byte("LIID-0001"),
+			TimeStamp:            goldenTimestamp,
+			Initiator:            OriginatingTarget,
+			PartyInformation:     []PartyInformation{goldenParty},
+			EPSCorrelationNumber: []byte{0, 0, 0, 0, 0, 0, 0, 42},
+			EPSEvent:             BearerActivation,
+			NetworkIdentifier:    goldenNetwork,
+			EPSSpecificParameters: EPSSpecificParameters{
+				PDNAddressAllocation: []byte{PDNTypeIPv4, 192, 168, 128, 12},
+				APN:                  []byte("magma.ipv4"),
+				EPSBearerIdentity:    []byte{5},
+				RATType:              []byte{RatTypeEutran},
+				EPSBearerQoS:         []byte{9, 0, 0, 0, 0},
+				BearerActivationType: DefaultBearer,
+				ApnAmbr:              []byte{0x00, 0x0f, 0x42, 0x40, 0x00, 0x1e, 0x84, 0x80},
+				EPSLocationOfTheTarget: EPSLocation{
+					UserLocationInfo: []byte{0x18, 0x00, 0xf1, 0x10, 0x00, 0x01, 0x00, 0xf1, 0x10, 0x00, 0x00, 0x01, 0x01},
+				},
+			},
+		},
+	},
+	{
+		name:  "sms_report",
+		class: RecordClassReport,
+		content: EpsIRIContent{
+			Hi2epsDomainID:   GetOID(),
+			TimeStamp:        goldenTimestamp,
+			Initiator:        TerminatingTarget,
+			PartyInformation: []PartyInformation{goldenParty},
+			SMS: SMSReport{
+				SMSContents: SMSContents{
+					Initiator:      SMSInitiatorServer,
+					TransferStatus: SMSTransferSucceeded,
+					OtherMessage:   SMSOtherMessageNo,
+					Content:        bytes.Repeat([]byte{0x11}, 140),
+				},
+			},
+			EPSEvent:          SMS,
+			NetworkIdentifier: goldenNetwork,
+		},
+	},
+	{
+		name:  "usage_report",
+		class: RecordClassContinue,
+		content: EpsIRIContent{
+			Hi2epsDomainID:       GetOID(),
+			TimeStamp:            goldenTimestamp,
+			PartyInformation:     []PartyInformation{goldenParty},
+			EPSCorrelationNumber: []byte{0, 0, 0, 0, 0, 0, 0, 42},
+			EPSEvent:             BearerModification,
+			NetworkIdentifier:    goldenNetwork,
+			EPSSpecificParameters: EPSSpecificParameters{
+				EPSBearerIdentity: []byte{5},
+			},
+			NationalParameters: NationalParameters{
+				CountryCode: "FR",
+				UsageReport: UsageReport{UplinkVolume: 1 << 20, DownlinkVolume: 3 << 30, Duration: 600},
+			},
+		},
+	},
+	{
+		name:  "dual_stack_party",
+		class: RecordClassBegin,
+		content: EpsIRIContent{
+			Hi2epsDomainID: GetOID(),
+			TimeStamp:      goldenTimestamp,
+			PartyInformation: []PartyInformation{{
+				PartyQualified: PartyQualifierTarget,
+				PartyIdentity:  goldenParty.PartyIdentity,
+				ServicesDataInformation: ServicesDataInformation{
+					GPRSParameters: GPRSParameters{
+						PDPAddress:          makeDataNodeAddress(IPV4Type, []byte{192, 168, 128, 12}, 0),
+						AdditionalIPAddress: makeDataNodeAddress(IPV6Type, bytes.Repeat([]byte{0x20}, 16), 64),
+					},
+				},
+			}},
+			EPSEvent:          BearerActivation,
+			NetworkIdentifier: goldenNetwork,
+			EPSSpecificParameters: EPSSpecificParameters{
+				PDNAddressAllocation: []byte{PDNTypeIPv4v6},
+				PDNType:              []byte{PDNTypeIPv4v6},
+				RequestType:          []byte{RequestTypeInitial},
+			},
+		},
+	},
+	{
+		name:  "bearer_session_id",
+		class: RecordClassEnd,
+		content: EpsIRIContent{
+			Hi2epsDomainID:    GetOID(),
+			TimeStamp:         goldenTimestamp,
+			PartyInformation:  []PartyInformation{goldenParty},
+			EPSEvent:          BearerDeactivation,
+			NetworkIdentifier: goldenNetwork,
+			EPSSpecificParameters: EPSSpecificParameters{
+				EPSBearerIdentity:      []byte{6},
+				BearerDeactivationType: DedicatedBearer,
+				BearerSessionID:        []byte("IMSI001010000000001-919642"),
+			},
+		},
+	},
+}
+
+var (
+	goldenTimestamp = Timestamp{
+		LocalTime: LocalTimestamp{GeneralizedTime: []byte("20201016120000.000Z"), WinterSummerIndication: SummerTime},
+	}
+	goldenParty = PartyInformation{
+		PartyQualified: PartyQualifierTarget,
+		PartyIdentity: PartyIdentity{
+			IMEI:   []byte{0x53, 0x08, 0x91, 0x10, 0x73, 0x58, 0x61, 0xf0},
+			IMSI:   []byte{0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0xf1},
+			MSISDN: []byte{0x91, 0x33, 0x06, 0x12, 0x34, 0x56, 0x78},
+		},
+	}
+	goldenNetwork = NetworkIdentifier{
+		OperatorIdentifier: []byte("magma"),
+		NetworkElementIdentifier: NetworkElementIdentifier{
+			IPAddress: makeDataNodeAddress(IPV4Type, []byte{10, 0, 0, 1}, 0).IPAddress,
+		},
+	}
+)
+
+func TestGoldenEncodings(t *testing.T) {
+	for _, golden := range goldenContents {
+		path := filepath.Join("testdata", "golden", golden.name+".hex")
+		b, err := appendContent(nil, &golden.content, getRecordTag(golden.class))
+		assert.NoError(t, err, golden.name)
+		if *updateGolden {
+			assert.NoError(t, ioutil.WriteFile(path, formatGolden(b), 0644))
+		}
+
+		data, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		expected, err := hex.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, b, golden.name)
+
+		// the reference vectors are the encodings of encoding/asn1
+		reference, err := asn1.MarshalWithParams(golden.content, getRecordType(golden.class))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, reference, golden.name)
+	}
+}
+
+// formatGolden formats an encoding as hex lines of 32 bytes
+func formatGolden(b []byte) []byte {
+	var lines []string
+	for s := hex.EncodeToString(b); len(s) != 0; {
+		n := len(s)
+		if n > 64 {
+			n = 64
+		}
+		lines = append(lines, s[:n])
+		s = s[n:]
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
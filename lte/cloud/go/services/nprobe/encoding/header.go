@@ -22,21 +22,27 @@ import (
 
 var (
 	HeaderFixLen        uint32 = 40
-	HeaderVersion       uint16 = 2
+	HeaderVersion       uint16 = 2  // Headers built, the newest supported
 	HeaderPduType       uint16 = 1  // X2 PDU
 	HeaderPayloadFormat uint16 = 14 // ETSI TS 133 108 [B.9] Defined Payload
 
-	AttributeDomainID  uint16 = 5
-	AttributeNetworkFn uint16 = 6
-	AttributeTimestamp uint16 = 9
-	AttributeSeqNumber uint16 = 8
-	AttributeTargetID  uint16 = 17
+	AttributeETSI102232  uint16 = 1 // ETSI TS 102 232-1 Defined Attribute
+	AttributeProprietary uint16 = 4
+	AttributeDomainID    uint16 = 5
+	AttributeNetworkFn   uint16 = 6
+	AttributeTimestamp   uint16 = 9
+	AttributeSeqNumber   uint16 = 8
+	AttributeTargetID    uint16 = 17
 
 	PayloadDirectionUnkown     uint16 = 1
 	PayloadDirectionToTarget   uint16 = 2
 	PayloadDirectionFromTarget uint16 = 3
 )
 
+// MaxHeaderLength bounds the headers decoded, whose conditional attributes
+// take a few hundred bytes at most
+var MaxHeaderLength uint32 = 4096
+
 // Attribute represents an X2 IRI conditional attribute field
 // as defined in ETSI TS 103 221-2.
 type Attribute struct {
@@ -62,6 +68,16 @@ func (t *Attribute) marshal() []byte {
 	return append(b, t.Value...)
 }
 
+// marshalTo writes the attribute to b, truncated to the size of b, and
+// returns the number of bytes written
+func (t *Attribute) marshalTo(b []byte) int {
+	var tl [4]byte
+	binary.BigEndian.PutUint16(tl[0:2], t.Tag)
+	binary.BigEndian.PutUint16(tl[2:4], t.Len)
+	n := copy(b, tl[:])
+	return n + copy(b[n:], t.Value)
+}
+
 // marshalAttributes parses a slice of attributes and marshals them.
 func marshalAttributes(attrs []Attribute) []byte {
 	var b []byte
@@ -71,7 +87,8 @@ func marshalAttributes(attrs []Attribute) []byte {
 	return b
 }
 
-// Unmarshal parses a byte array to reconstruct an attribute.
+// Unmarshal parses a byte array to reconstruct an attribute. Its value
+// references b rather than a copy.
 func (t *Attribute) unmarshal(b []byte) error {
 	if len(b) < 4 {
 		return errors.New("invalid input size")
@@ -83,16 +100,26 @@ func (t *Attribute) unmarshal(b []byte) error {
 	if int(t.Len)+4 > len(b) {
 		return errors.New("truncated attribute or wrong length")
 	}
-	t.Value = make([]byte, t.Len)
-	copy(t.Value, b[4:])
+	t.Value = b[4 : 4+t.Len : 4+t.Len]
 	return nil
 }
 
+// parseAttributes parses the conditional attributes of a header. They take
+// an allocation for all their values, copied from b, and one for the
+// attributes themselves, counted beforehand.
 func parseAttributes(b []byte) ([]Attribute, error) {
-	var attrs []Attribute
-	for i := 0; i < len(b); {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	count := 0
+	for i := 0; len(b)-i >= 4; count++ {
+		i += int(binary.BigEndian.Uint16(b[i+2:i+4])) + 4
+	}
+	values := append([]byte(nil), b...)
+	attrs := make([]Attribute, 0, count)
+	for i := 0; i < len(values); {
 		var attr Attribute
-		if err := attr.unmarshal(b[i:]); err != nil {
+		if err := attr.unmarshal(values[i:]); err != nil {
 			return attrs, err
 		}
 		attrs = append(attrs, attr)
@@ -135,6 +162,8 @@ func (h *EpsIRIHeader) Marshal() []byte {
 	return b
 }
 
+// marshalTo writes the header to b, which is HeaderLength bytes long,
+// without allocating
 func (h *EpsIRIHeader) marshalTo(b []byte) {
 	binary.BigEndian.PutUint16(b[0:2], h.Version)
 	binary.BigEndian.PutUint16(b[2:4], h.PduType)
@@ -142,18 +171,26 @@ func (h *EpsIRIHeader) marshalTo(b []byte) {
 	binary.BigEndian.PutUint32(b[8:12], h.PayloadLength)
 	binary.BigEndian.PutUint16(b[12:14], h.PayloadFormat)
 	binary.BigEndian.PutUint16(b[14:16], h.PayloadDirection)
-	copy(b[16:32], h.XID.Bytes()) // append UUID
+	copy(b[16:32], h.XID[:]) // append UUID
 	binary.BigEndian.PutUint64(b[32:40], h.CorrelationID)
 
-	attrs := marshalAttributes(h.ConditionalAttributes)
-	copy(b[40:], attrs) // append attributes
+	// append attributes
+	off := int(HeaderFixLen)
+	for i := range h.ConditionalAttributes {
+		off += h.ConditionalAttributes[i].marshalTo(b[off:])
+	}
 }
 
 // Unmarshal parses the BER decoded ASN.1 as defined in ETSI TS 103 221-2.
+// The input must be exactly the length the header declares.
 func (h *EpsIRIHeader) Unmarshal(b []byte) error {
 	if len(b) < int(HeaderFixLen) {
 		return errors.New("invalid input size")
 	}
+	hdrLen := binary.BigEndian.Uint32(b[4:8])
+	if hdrLen > MaxHeaderLength || uint64(hdrLen) != uint64(len(b)) {
+		return errors.New("invalid header length")
+	}
 
 	var err error
 	h.XID, err = uuid.FromBytes(b[16:32])
@@ -167,7 +204,7 @@ func (h *EpsIRIHeader) Unmarshal(b []byte) error {
 	}
 	h.Version = binary.BigEndian.Uint16(b[0:2])
 	h.PduType = binary.BigEndian.Uint16(b[2:4])
-	h.HeaderLength = binary.BigEndian.Uint32(b[4:8])
+	h.HeaderLength = hdrLen
 	h.PayloadLength = binary.BigEndian.Uint32(b[8:12])
 	h.PayloadFormat = binary.BigEndian.Uint16(b[12:14])
 	h.PayloadDirection = binary.BigEndian.Uint16(b[14:16])
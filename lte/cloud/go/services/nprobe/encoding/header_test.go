@@ -148,3 +148,14 @@ func TestEpsIRIHeaderMarshaling(t *testing.T) {
 		})
 	}
 }
+
+func TestParseAttributesAllocations(t *testing.T) {
+	b := marshalAttributes(tc1[0].at)
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = parseAttributes(b)
+	})
+	// the attributes and their values take an allocation each
+	if allocs != 2 {
+		t.Fatalf("%v allocations parsing attributes", allocs)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Records and PDUs are built with the header of HeaderVersion, and written
+// in the header version of their delivery function. Version 1 is the header
+// of the first delivery functions, whose payload direction is reserved and
+// set to 0, the layout and conditional attributes being the same otherwise.
+// PDUs of all the versions are decoded, so that gateways and delivery
+// functions of different versions keep exchanging records during rolling
+// upgrades.
+var HeaderVersion1 uint16 = 1
+
+// headerVersions are the supported header versions, newest first
+var headerVersions = []uint16{HeaderVersion, HeaderVersion1}
+
+// GetHeaderVersions returns the supported header versions, newest first
+func GetHeaderVersions() []uint16 {
+	return append([]uint16{}, headerVersions...)
+}
+
+// IsHeaderVersionSupported returns true if PDUs of a header version can be
+// encoded and decoded
+func IsHeaderVersionSupported(version uint16) bool {
+	for _, v := range headerVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateHeaderVersion returns the header version given, HeaderVersion if
+// 0, or an error if it isn't supported
+func ValidateHeaderVersion(version uint16) (uint16, error) {
+	if version == 0 {
+		return HeaderVersion, nil
+	}
+	if !IsHeaderVersionSupported(version) {
+		return 0, fmt.Errorf("unsupported header version %d", version)
+	}
+	return version, nil
+}
+
+// GetOlderHeaderVersion returns the newest supported header version older
+// than the one given, false if it is the oldest
+func GetOlderHeaderVersion(version uint16) (uint16, bool) {
+	for _, v := range headerVersions {
+		if v < version {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// ConvertHeaderVersion returns a PDU with its header in a header version,
+// the PDU itself if it is already. Only the fixed part of the header
+// differs between the versions, so that the conditional attributes and the
+// payload are kept as is.
+func ConvertHeaderVersion(pdu []byte, version uint16) ([]byte, error) {
+	if !IsHeaderVersionSupported(version) {
+		return nil, fmt.Errorf("unsupported header version %d", version)
+	}
+	hdr, err := ParsePDUHeader(pdu)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Version == version {
+		return pdu, nil
+	}
+	direction := hdr.PayloadDirection
+	switch {
+	case version == HeaderVersion1:
+		direction = 0
+	case hdr.Version == HeaderVersion1:
+		direction = PayloadDirectionUnkown
+	}
+	b := append([]byte{}, pdu...)
+	binary.BigEndian.PutUint16(b[0:2], version)
+	binary.BigEndian.PutUint16(b[14:16], direction)
+	return b, nil
+}
+
+// validateHeaderVersion checks that a header only sets the fields its
+// version defines
+func validateHeaderVersion(hdr *EpsIRIHeader) error {
+	if !IsHeaderVersionSupported(hdr.Version) {
+		return fmt.Errorf("unsupported header version %d", hdr.Version)
+	}
+	if hdr.Version == HeaderVersion1 && hdr.PayloadDirection != 0 {
+		return fmt.Errorf("payload direction %d set in a header of version %d", hdr.PayloadDirection, hdr.Version)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderVersions(t *testing.T) {
+	assert.Equal(t, []uint16{2, 1}, GetHeaderVersions())
+	version, err := ValidateHeaderVersion(0)
+	assert.NoError(t, err)
+	assert.Equal(t, HeaderVersion, version)
+	_, err = ValidateHeaderVersion(3)
+	assert.EqualError(t, err, "unsupported header version 3")
+	older, ok := GetOlderHeaderVersion(HeaderVersion)
+	assert.True(t, ok)
+	assert.Equal(t, HeaderVersion1, older)
+	_, ok = GetOlderHeaderVersion(HeaderVersion1)
+	assert.False(t, ok)
+
+	// records converted to version 1 have their payload direction reserved,
+	// and are converted back as built
+	v1, err := ConvertHeaderVersion(encodedRecord, HeaderVersion1)
+	assert.NoError(t, err)
+	assert.Len(t, v1, len(encodedRecord))
+	hdr, err := ParseHeader(v1)
+	assert.NoError(t, err)
+	assert.Equal(t, HeaderVersion1, hdr.Version)
+	assert.Zero(t, hdr.PayloadDirection)
+	assert.Equal(t, encodedRecord[16:], v1[16:])
+	assert.NoError(t, Validate(v1))
+	v2, err := ConvertHeaderVersion(v1, HeaderVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, v2)
+	same, err := ConvertHeaderVersion(encodedRecord, HeaderVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, same)
+
+	_, err = ConvertHeaderVersion(encodedRecord, 3)
+	assert.EqualError(t, err, "unsupported header version 3")
+	_, err = ConvertHeaderVersion(encodedRecord[:20], HeaderVersion1)
+	assert.Error(t, err)
+
+	// version 1 headers setting a payload direction are malformed
+	invalid := append([]byte{}, v1...)
+	invalid[15] = 2
+	assert.EqualError(t, Validate(invalid), "malformed header: payload direction 2 set in a header of version 1")
+	invalid = append([]byte{}, encodedRecord...)
+	invalid[1] = 3
+	assert.EqualError(t, Validate(invalid), "malformed header: unexpected version 3, PDU type 1 or payload format 14")
+}
+
+func TestKeepaliveHeaderVersion(t *testing.T) {
+	keepalive, err := ConvertHeaderVersion(MakeKeepalive(3), HeaderVersion1)
+	assert.NoError(t, err)
+	hdr, err := ParsePDUHeader(keepalive)
+	assert.NoError(t, err)
+	assert.Equal(t, HeaderVersion1, hdr.Version)
+
+	// keepalives are acknowledged in their header version
+	ack, err := ParsePDUHeader(MakeKeepaliveAck(hdr))
+	assert.NoError(t, err)
+	assert.Equal(t, HeaderVersion1, ack.Version)
+	assert.Zero(t, ack.PayloadDirection)
+	hdr.Version = 7
+	ack, err = ParsePDUHeader(MakeKeepaliveAck(hdr))
+	assert.NoError(t, err)
+	assert.Equal(t, HeaderVersion, ack.Version)
+	assert.Equal(t, PayloadDirectionUnkown, ack.PayloadDirection)
+}
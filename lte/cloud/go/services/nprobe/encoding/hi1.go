@@ -0,0 +1,165 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/gofrs/uuid"
+)
+
+var (
+	// HeaderPayloadFormatHI1 is the payload format of HI1 notifications
+	HeaderPayloadFormatHI1 uint16 = 1 // ETSI TS 102 232-1 Defined Payload
+
+	// HI1DomainID is the OID of the HI1 notification operations, version 6,
+	// as defined in ETSI TS 102 232-1
+	HI1DomainID = asn1.ObjectIdentifier{0, 4, 0, 2, 2, 0, 1, 6}
+)
+
+const (
+	// HI1 notifications, the alternatives of the HI1-Operation CHOICE
+	HI1Activated   = "activated"
+	HI1Deactivated = "deactivated"
+	HI1Alarm       = "alarm"
+
+	// maxAlarmInformationLen is the size bound of the alarm information
+	maxAlarmInformationLen = 25
+)
+
+var hi1OperationTags = map[string]int{
+	HI1Activated:   1, // liActivated
+	HI1Deactivated: 2, // liDeactivated
+	HI1Alarm:       4, // alarms-indicator
+}
+
+// HI1Notification is the Notification reporting the activation or the
+// deactivation of the interception of a target, as defined in ETSI TS 102 232-1
+type HI1Notification struct {
+	DomainID                     asn1.ObjectIdentifier `asn1:"optional,tag:0"`
+	LawfulInterceptionIdentifier []byte                `asn1:"tag:1"`
+	TimeStamp                    Timestamp             `asn1:"tag:3"`
+	NetworkIdentifier            NetworkIdentifier     `asn1:"optional,tag:7"`
+}
+
+// HI1AlarmIndicator is the Alarm-Indicator reporting a failure of the
+// interception of a target, e.g. of the delivery of its records
+type HI1AlarmIndicator struct {
+	DomainID                     asn1.ObjectIdentifier `asn1:"optional,tag:0"`
+	TimeStamp                    Timestamp             `asn1:"tag:2"`
+	AlarmInformation             []byte                `asn1:"tag:3"`
+	LawfulInterceptionIdentifier []byte                `asn1:"optional,tag:4"`
+	NetworkIdentifier            NetworkIdentifier     `asn1:"optional,tag:6"`
+}
+
+// MakeHI1Notification builds the HI1 notification of the activation or the
+// deactivation of a task, or the alarm raised for it, whose information is
+// the alarm message truncated to the size allowed by the schema. The PDU
+// shares the header of the records of the task, without sequence number:
+// notifications are not part of the stream of its records. The lawful
+// interception identifier defaults to the task ID, which is mandatory in
+// notifications.
+func MakeHI1Notification(
+	task *models.NetworkProbeTask,
+	operatorID uint32,
+	operation string,
+	alarm string,
+	timestamp time.Time,
+) ([]byte, error) {
+	tag, ok := hi1OperationTags[operation]
+	if !ok {
+		return nil, fmt.Errorf("unsupported HI1 operation %q", operation)
+	}
+	bTimestamp, err := formatTimestamp(timestamp, getTimestampFormat())
+	if err != nil {
+		return nil, newEncodingError(FieldTimestamp, err)
+	}
+	details := task.TaskDetails
+	liid := makeLawInterceptID(details.AuthorizationReference)
+	if liid == nil {
+		liid = []byte(task.TaskID)
+	}
+	networkIdentifier := NetworkIdentifier{OperatorIdentifier: convertUint32ToBytes(operatorID)}
+
+	var payload []byte
+	if operation == HI1Alarm {
+		if len(alarm) == 0 {
+			return nil, errors.New("missing alarm information")
+		}
+		if len(alarm) > maxAlarmInformationLen {
+			alarm = alarm[:maxAlarmInformationLen]
+		}
+		payload, err = asn1.MarshalWithParams(HI1AlarmIndicator{
+			DomainID:                     HI1DomainID,
+			TimeStamp:                    makeTimestamp(bTimestamp),
+			AlarmInformation:             []byte(alarm),
+			LawfulInterceptionIdentifier: liid,
+			NetworkIdentifier:            networkIdentifier,
+		}, fmt.Sprintf("tag:%d", tag))
+	} else {
+		payload, err = asn1.MarshalWithParams(HI1Notification{
+			DomainID:                     HI1DomainID,
+			LawfulInterceptionIdentifier: liid,
+			TimeStamp:                    makeTimestamp(bTimestamp),
+			NetworkIdentifier:            networkIdentifier,
+		}, fmt.Sprintf("tag:%d", tag))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	psHeaderAttrs, err := makePSHeaderAttributes(details)
+	if err != nil {
+		return nil, newEncodingError(FieldTaskDetails, err)
+	}
+	attrs := append([]Attribute{
+		NewAttribute(AttributeNetworkFn, []byte(nprobe.ServiceName)),
+		NewAttribute(AttributeTargetID, []byte(details.TargetID)),
+		NewAttribute(AttributeTimestamp, bTimestamp),
+	}, psHeaderAttrs...)
+	attrsLen := uint32(0)
+	for _, attr := range attrs {
+		attrsLen += uint32(attr.Len) + 4
+	}
+	xid, err := uuid.FromString(string(task.TaskID))
+	if err != nil {
+		return nil, newEncodingError(FieldTaskID, err)
+	}
+	hdr := NewEpsIRIHeader(xid, details.CorrelationID, attrs, attrsLen)
+	hdr.PayloadFormat = HeaderPayloadFormatHI1
+	hdr.PayloadLength = uint32(len(payload))
+	return append(hdr.Marshal(), payload...), nil
+}
+
+// GetHI1Operation returns the HI1 operation of a notification PDU, false for
+// records and the other PDUs
+func GetHI1Operation(pdu []byte) (string, bool) {
+	hdr, err := ParsePDUHeader(pdu)
+	if err != nil || hdr.PayloadFormat != HeaderPayloadFormatHI1 || len(pdu) <= int(hdr.HeaderLength) {
+		return "", false
+	}
+	tag := int(pdu[hdr.HeaderLength] & 0x1f)
+	for operation, operationTag := range hi1OperationTags {
+		if operationTag == tag {
+			return operation, true
+		}
+	}
+	return "", false
+}
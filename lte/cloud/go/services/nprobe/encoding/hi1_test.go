@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeHI1Notification(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:               "IMSI001010000000001",
+			TargetType:             models.NetworkProbeTaskDetailsTargetTypeImsi,
+			CorrelationID:          42,
+			AuthorizationReference: "LIID-2021-0042",
+		},
+	}
+	b, err := MakeHI1Notification(task, 1, HI1Activated, "", time.Unix(1615000000, 0))
+	assert.NoError(t, err)
+	hdr, err := ParsePDUHeader(b)
+	assert.NoError(t, err)
+	assert.Equal(t, HeaderPayloadFormatHI1, hdr.PayloadFormat)
+	assert.Equal(t, string(task.TaskID), hdr.XID.String())
+	assert.Equal(t, uint64(42), hdr.CorrelationID)
+	// notifications are not numbered in the stream of the records
+	_, ok := GetSequenceNumber(hdr)
+	assert.False(t, ok)
+	operation, ok := GetHI1Operation(b)
+	assert.True(t, ok)
+	assert.Equal(t, HI1Activated, operation)
+	assert.Equal(t, RecordClassUnknown, GetRecordClass(b))
+
+	var notification HI1Notification
+	_, err = asn1.UnmarshalWithParams(b[hdr.HeaderLength:], &notification, "tag:1")
+	assert.NoError(t, err)
+	assert.True(t, HI1DomainID.Equal(notification.DomainID))
+	assert.Equal(t, []byte("LIID-2021-0042"), notification.LawfulInterceptionIdentifier)
+
+	// the alarm information is truncated to the size allowed by the schema
+	task.TaskDetails.AuthorizationReference = ""
+	b, err = MakeHI1Notification(task, 1, HI1Alarm, "failed to connect to 10.10.0.2:6666", time.Unix(1615000000, 0))
+	assert.NoError(t, err)
+	operation, _ = GetHI1Operation(b)
+	assert.Equal(t, HI1Alarm, operation)
+	hdr, err = ParsePDUHeader(b)
+	assert.NoError(t, err)
+	var alarm HI1AlarmIndicator
+	_, err = asn1.UnmarshalWithParams(b[hdr.HeaderLength:], &alarm, "tag:4")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("failed to connect to 10.1"), alarm.AlarmInformation)
+	assert.Equal(t, []byte(task.TaskID), alarm.LawfulInterceptionIdentifier)
+
+	_, err = MakeHI1Notification(task, 1, HI1Alarm, "", time.Unix(1615000000, 0))
+	assert.Error(t, err)
+	_, err = MakeHI1Notification(task, 1, "modified", "", time.Unix(1615000000, 0))
+	assert.Error(t, err)
+
+	// records are not notifications
+	record, err := MakeTestRecord(task, 1, 12, "test", time.Unix(1615000000, 0), moduleVersions[DefaultModuleVersion])
+	assert.NoError(t, err)
+	_, ok = GetHI1Operation(record)
+	assert.False(t, ok)
+}
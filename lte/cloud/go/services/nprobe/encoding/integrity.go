@@ -0,0 +1,163 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// IntegrityCheckSignature is the check type of a signed digest as
+	// defined in ETSI TS 102 232-1
+	IntegrityCheckSignature asn1.Enumerated = 2
+	// HashAlgorithmSHA256 is the SHA-256 hash algorithm as defined in
+	// ETSI TS 102 232-1
+	HashAlgorithmSHA256 asn1.Enumerated = 2
+)
+
+// ErrNoIntegrityCheck is returned when verifying a record which isn't signed
+var ErrNoIntegrityCheck = errors.New("record carries no integrity check")
+
+// IntegrityCheck is the signature of a record following the integrity
+// options of ETSI TS 102 232-1. It is carried by the last conditional
+// attribute of the header of the record, an ETSI TS 102 232-1 defined
+// attribute, and covers the record as encoded without it.
+type IntegrityCheck struct {
+	IncludedSequenceNumbers []int64         `asn1:"tag:0"`
+	CheckType               asn1.Enumerated `asn1:"tag:1"`
+	CheckValue              []byte          `asn1:"tag:2"`
+	HashAlgorithm           asn1.Enumerated `asn1:"optional,tag:3"`
+}
+
+// SignRecord signs the SHA-256 digest of an encoded record and returns the
+// record with the signature appended to the conditional attributes of its
+// header, the header length being updated accordingly
+func SignRecord(record []byte, signer crypto.Signer) ([]byte, error) {
+	var hdr EpsIRIHeader
+	hdrLen, err := unmarshalRecordHeader(record, &hdr)
+	if err != nil {
+		return nil, err
+	}
+	seq := getAttribute(&hdr, AttributeSeqNumber)
+	if len(seq) != 4 {
+		return nil, errors.New("record header carries no sequence number")
+	}
+
+	digest := sha256.Sum256(record)
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	value, err := asn1.Marshal(IntegrityCheck{
+		IncludedSequenceNumbers: []int64{int64(binary.BigEndian.Uint32(seq))},
+		CheckType:               IntegrityCheckSignature,
+		CheckValue:              signature,
+		HashAlgorithm:           HashAlgorithmSHA256,
+	})
+	if err != nil {
+		return nil, err
+	}
+	attr := NewAttribute(AttributeETSI102232, value)
+	signedHdrLen := hdrLen + uint32(len(value)) + 4
+	if signedHdrLen > MaxHeaderLength {
+		return nil, fmt.Errorf("signed header length %d exceeds %d", signedHdrLen, MaxHeaderLength)
+	}
+
+	return appendAttribute(record, hdrLen, attr), nil
+}
+
+// GetIntegrityCheck returns the integrity check of a signed record along
+// with the record as it was signed, without the integrity check. It returns
+// ErrNoIntegrityCheck if the record isn't signed.
+func GetIntegrityCheck(record []byte) (*IntegrityCheck, []byte, error) {
+	var hdr EpsIRIHeader
+	hdrLen, err := unmarshalRecordHeader(record, &hdr)
+	if err != nil {
+		return nil, nil, err
+	}
+	attrs := hdr.ConditionalAttributes
+	if len(attrs) == 0 || attrs[len(attrs)-1].Tag != AttributeETSI102232 {
+		return nil, nil, ErrNoIntegrityCheck
+	}
+	last := attrs[len(attrs)-1]
+	check := &IntegrityCheck{}
+	rest, err := asn1.Unmarshal(last.Value, check)
+	if err != nil || len(rest) != 0 || check.CheckType != IntegrityCheckSignature {
+		return nil, nil, ErrNoIntegrityCheck
+	}
+
+	unsignedHdrLen := hdrLen - uint32(last.Len) - 4
+	unsigned := make([]byte, 0, len(record)-int(last.Len)-4)
+	unsigned = append(unsigned, record[:unsignedHdrLen]...)
+	binary.BigEndian.PutUint32(unsigned[4:8], unsignedHdrLen)
+	unsigned = append(unsigned, record[hdrLen:]...)
+	return check, unsigned, nil
+}
+
+// VerifyRecord checks the integrity check of a signed record with the
+// verify function of the signing key, e.g. signing.Verify. Records are
+// signed as built, in DER with the header of HeaderVersion, so that the
+// payloads delivered in BER and the headers of older versions are converted
+// back before being verified.
+func VerifyRecord(record []byte, verify func(digest, signature []byte) bool) error {
+	check, unsigned, err := GetIntegrityCheck(record)
+	if err != nil {
+		return err
+	}
+	if unsigned, err = ReencodeRecord(unsigned, EncodingDER); err != nil {
+		return err
+	}
+	if unsigned, err = ConvertHeaderVersion(unsigned, HeaderVersion); err != nil {
+		return err
+	}
+	if check.HashAlgorithm != HashAlgorithmSHA256 {
+		return fmt.Errorf("unsupported hash algorithm %d", check.HashAlgorithm)
+	}
+	digest := sha256.Sum256(unsigned)
+	if !verify(digest[:], check.CheckValue) {
+		return errors.New("invalid record signature")
+	}
+	return nil
+}
+
+// appendAttribute returns a copy of a record of the given header length with
+// an attribute appended to the conditional attributes of its header
+func appendAttribute(record []byte, hdrLen uint32, attr Attribute) []byte {
+	newHdrLen := hdrLen + uint32(attr.Len) + 4
+	ret := make([]byte, len(record)+int(attr.Len)+4)
+	copy(ret, record[:hdrLen])
+	binary.BigEndian.PutUint32(ret[4:8], newHdrLen)
+	attr.marshalTo(ret[hdrLen:newHdrLen])
+	copy(ret[newHdrLen:], record[hdrLen:])
+	return ret
+}
+
+// unmarshalRecordHeader decodes the header of an encoded record and returns
+// its length
+func unmarshalRecordHeader(record []byte, hdr *EpsIRIHeader) (uint32, error) {
+	if len(record) < int(HeaderFixLen) {
+		return 0, errors.New("invalid input size")
+	}
+	hdrLen := binary.BigEndian.Uint32(record[4:8])
+	if uint64(hdrLen) > uint64(len(record)) {
+		return 0, errors.New("invalid header length")
+	}
+	return hdrLen, hdr.Unmarshal(record[:hdrLen])
+}
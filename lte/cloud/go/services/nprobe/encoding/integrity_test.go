@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/signing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignRecord(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	signer, err := signing.NewSigner(key)
+	assert.NoError(t, err)
+	verify := func(digest, signature []byte) bool {
+		return signing.Verify(&key.PublicKey, digest, signature)
+	}
+
+	assert.Equal(t, ErrNoIntegrityCheck, VerifyRecord(encodedRecord, verify))
+
+	signed, err := SignRecord(encodedRecord, signer)
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyRecord(signed, verify))
+
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(signed))
+	seqNbr, _ := GetSequenceNumber(&record.Header)
+	assert.Equal(t, uint32(6), seqNbr)
+
+	check, unsigned, err := GetIntegrityCheck(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, unsigned)
+	assert.Equal(t, []int64{6}, check.IncludedSequenceNumbers)
+	assert.Equal(t, HashAlgorithmSHA256, check.HashAlgorithm)
+
+	// the signature still holds once the payload is delivered in BER
+	ber, err := ReencodeRecord(signed, EncodingBER)
+	assert.NoError(t, err)
+	assert.NotEqual(t, signed, ber)
+	assert.NoError(t, VerifyRecord(ber, verify))
+	// and in an older header version
+	v1, err := ConvertHeaderVersion(signed, HeaderVersion1)
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyRecord(v1, verify))
+
+	// any change to the record breaks its signature
+	tampered := append([]byte{}, signed...)
+	tampered[len(tampered)-1] ^= 0xff
+	assert.Error(t, VerifyRecord(tampered, verify))
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	assert.Error(t, VerifyRecord(signed, func(digest, signature []byte) bool {
+		return signing.Verify(&other.PublicKey, digest, signature)
+	}))
+}
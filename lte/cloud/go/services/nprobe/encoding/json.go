@@ -0,0 +1,365 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"strconv"
+	"time"
+)
+
+// epsEventNames are the names of the EPS events as defined in ETSI TS 133 108 [B.9]
+var epsEventNames = map[asn1.Enumerated]string{
+	SMS:                              "sMS",
+	EutranAttach:                     "e-UTRAN-attach",
+	EutranDetach:                     "e-UTRAN-detach",
+	BearerActivation:                 "bearer-activation",
+	StartInterceptWithActiveBearer:   "start-of-interception-with-active-bearer",
+	BearerModification:               "bearer-modification",
+	BearerDeactivation:               "bearer-deactivation",
+	UERequestedPDNConnectivity:       "uE-requested-PDN-connectivity",
+	UERequestedPDNDisconnection:      "uE-requested-PDN-disconnection",
+	LocationUpdate:                   "trackingAreaEpsLocationUpdate",
+	ServingEvolvedPacketSystem:       "servingEvolvedPacketSystem",
+	StartInterceptWithEutranAttached: "startOfInterceptionWithEUTRANAttachedUE",
+}
+
+// attributeNames are the names of the conditional attributes of the header
+var attributeNames = map[uint16]string{
+	AttributeETSI102232:  "etsi_102_232_1",
+	AttributeProprietary: "proprietary",
+	AttributeDomainID:    "domain_id",
+	AttributeNetworkFn:   "network_function",
+	AttributeTimestamp:   "timestamp",
+	AttributeSeqNumber:   "sequence_number",
+	AttributeTargetID:    "target_id",
+}
+
+type jsonRecord struct {
+	Class   string      `json:"class"`
+	Header  jsonHeader  `json:"header"`
+	Payload jsonPayload `json:"payload"`
+}
+
+type jsonHeader struct {
+	Version          uint16          `json:"version"`
+	PduType          uint16          `json:"pdu_type"`
+	PayloadFormat    uint16          `json:"payload_format"`
+	PayloadDirection uint16          `json:"payload_direction"`
+	XID              string          `json:"xid"`
+	CorrelationID    uint64          `json:"correlation_id"`
+	Attributes       []jsonAttribute `json:"conditional_attributes"`
+}
+
+type jsonAttribute struct {
+	Tag   uint16      `json:"tag"`
+	Name  string      `json:"name,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+type jsonPSHeader struct {
+	LawfulInterceptionIdentifier string `json:"lawful_interception_identifier,omitempty"`
+	DeliveryCountryCode          string `json:"delivery_country_code,omitempty"`
+}
+
+type jsonIntegrityCheck struct {
+	IncludedSequenceNumbers []int64 `json:"included_sequence_numbers"`
+	CheckType               int     `json:"check_type"`
+	CheckValue              string  `json:"check_value"`
+	HashAlgorithm           int     `json:"hash_algorithm,omitempty"`
+}
+
+type jsonPayload struct {
+	DomainID             string             `json:"hi2eps_domain_id"`
+	ModuleVersion        string             `json:"module_version,omitempty"`
+	LawInterceptID       string             `json:"law_intercept_id,omitempty"`
+	Timestamp            string             `json:"timestamp"`
+	Initiator            int                `json:"initiator"`
+	EPSEvent             string             `json:"eps_event,omitempty"`
+	EPSEventID           int                `json:"eps_event_id,omitempty"`
+	EPSCorrelationNumber string             `json:"eps_correlation_number,omitempty"`
+	PartyInformation     []jsonParty        `json:"party_information,omitempty"`
+	NetworkIdentifier    *jsonNetwork       `json:"network_identifier,omitempty"`
+	SpecificParameters   *jsonSpecificParam `json:"eps_specific_parameters,omitempty"`
+	SMS                  *jsonSMS           `json:"sms,omitempty"`
+	UsageReport          *jsonUsageReport   `json:"usage_report,omitempty"`
+}
+
+type jsonParty struct {
+	Qualifier           int    `json:"party_qualifier"`
+	IMSI                string `json:"imsi,omitempty"`
+	IMEI                string `json:"imei,omitempty"`
+	MSISDN              string `json:"msisdn,omitempty"`
+	PDPAddress          string `json:"pdp_address,omitempty"`
+	AdditionalIPAddress string `json:"additional_ip_address,omitempty"`
+}
+
+type jsonNetwork struct {
+	OperatorIdentifier       string `json:"operator_identifier"`
+	NetworkElementIdentifier string `json:"network_element_identifier,omitempty"`
+}
+
+type jsonSpecificParam struct {
+	PDNAddressAllocation   string `json:"pdn_address_allocation,omitempty"`
+	APN                    string `json:"apn,omitempty"`
+	EPSBearerIdentity      string `json:"eps_bearer_identity,omitempty"`
+	DetachType             string `json:"detach_type,omitempty"`
+	RATType                string `json:"rat_type,omitempty"`
+	FailedBearerActReason  string `json:"failed_bearer_activation_reason,omitempty"`
+	EPSBearerQoS           string `json:"eps_bearer_qos,omitempty"`
+	BearerActivationType   int    `json:"bearer_activation_type,omitempty"`
+	ApnAmbr                string `json:"apn_ambr,omitempty"`
+	LinkedEPSBearerID      string `json:"linked_eps_bearer_id,omitempty"`
+	HandoverIndication     string `json:"handover_indication,omitempty"`
+	FailedTAUReason        string `json:"failed_tau_reason,omitempty"`
+	ServingMMEAddress      string `json:"serving_mme_address,omitempty"`
+	BearerDeactivationType int    `json:"bearer_deactivation_type,omitempty"`
+	UserLocationInfo       string `json:"user_location_info,omitempty"`
+	PDNType                string `json:"pdn_type,omitempty"`
+	RequestType            string `json:"request_type,omitempty"`
+	UEReqPDNConnFailReason string `json:"ue_requested_pdn_connectivity_failure_reason,omitempty"`
+	BearerSessionID        string `json:"bearer_session_id,omitempty"`
+	VisitedPLMNID          string `json:"visited_plmn_id,omitempty"`
+	ServingNetwork         string `json:"serving_network,omitempty"`
+	RoamingStatus          int    `json:"roaming_status,omitempty"`
+	BearerARP              string `json:"bearer_arp,omitempty"`
+	PreviousEPSBearerQoS   string `json:"previous_eps_bearer_qos,omitempty"`
+	PreviousApnAmbr        string `json:"previous_apn_ambr,omitempty"`
+	PreviousBearerARP      string `json:"previous_bearer_arp,omitempty"`
+}
+
+type jsonSMS struct {
+	Initiator      int    `json:"initiator"`
+	TransferStatus int    `json:"transfer_status"`
+	OtherMessage   int    `json:"other_message"`
+	Content        string `json:"content,omitempty"`
+}
+
+type jsonUsageReport struct {
+	CountryCode    string `json:"country_code"`
+	UplinkVolume   int64  `json:"uplink_volume"`
+	DownlinkVolume int64  `json:"downlink_volume"`
+	Duration       int64  `json:"duration"`
+}
+
+// ToJSON renders a decoded record as human-readable JSON, for operators to
+// check the content of records without an ASN.1 decoder. Identities, APNs and
+// bearer identities are rendered as text unless they aren't printable,
+// addresses and timestamps in their usual notation and the other octet
+// strings in hex.
+func ToJSON(record *EpsIRIRecord) ([]byte, error) {
+	hdr := &record.Header
+	ret := jsonRecord{
+		Class: record.Class,
+		Header: jsonHeader{
+			Version:          hdr.Version,
+			PduType:          hdr.PduType,
+			PayloadFormat:    hdr.PayloadFormat,
+			PayloadDirection: hdr.PayloadDirection,
+			XID:              hdr.XID.String(),
+			CorrelationID:    hdr.CorrelationID,
+			Attributes:       make([]jsonAttribute, 0, len(hdr.ConditionalAttributes)),
+		},
+		Payload: makeJSONPayload(&record.Payload),
+	}
+	for _, attr := range hdr.ConditionalAttributes {
+		ret.Header.Attributes = append(ret.Header.Attributes, jsonAttribute{
+			Tag:   attr.Tag,
+			Name:  attributeNames[attr.Tag],
+			Value: getAttributeJSONValue(&attr),
+		})
+	}
+	return json.Marshal(ret)
+}
+
+// getAttributeJSONValue returns the rendered value of a conditional attribute,
+// in hex when it can't be decoded
+func getAttributeJSONValue(attr *Attribute) interface{} {
+	switch attr.Tag {
+	case AttributeNetworkFn, AttributeTargetID, AttributeProprietary:
+		return string(attr.Value)
+	case AttributeTimestamp:
+		if timestamp, ok := formatGeneralizedTime(attr.Value); ok {
+			return timestamp
+		}
+	case AttributeSeqNumber:
+		if len(attr.Value) == 4 {
+			return binary.BigEndian.Uint32(attr.Value)
+		}
+	case AttributeETSI102232:
+		// integrity checks are told apart by their mandatory first field,
+		// which the PSHeader parameters would skip
+		var check IntegrityCheck
+		if rest, err := asn1.Unmarshal(attr.Value, &check); err == nil && len(rest) == 0 {
+			return jsonIntegrityCheck{
+				IncludedSequenceNumbers: check.IncludedSequenceNumbers,
+				CheckType:               int(check.CheckType),
+				CheckValue:              hex.EncodeToString(check.CheckValue),
+				HashAlgorithm:           int(check.HashAlgorithm),
+			}
+		}
+		var psHeader PSHeaderAttribute
+		if rest, err := asn1.Unmarshal(attr.Value, &psHeader); err == nil && len(rest) == 0 {
+			return jsonPSHeader{
+				LawfulInterceptionIdentifier: string(psHeader.LawfulInterceptionIdentifier),
+				DeliveryCountryCode:          psHeader.DeliveryCountryCode,
+			}
+		}
+	}
+	return hex.EncodeToString(attr.Value)
+}
+
+func makeJSONPayload(content *EpsIRIContent) jsonPayload {
+	timestamp, ok := formatGeneralizedTime(content.TimeStamp.LocalTime.GeneralizedTime)
+	if !ok {
+		timestamp = hex.EncodeToString(content.TimeStamp.LocalTime.GeneralizedTime)
+	}
+	ret := jsonPayload{
+		DomainID:             content.Hi2epsDomainID.String(),
+		LawInterceptID:       formatText(content.LawInterceptID),
+		Timestamp:            timestamp,
+		Initiator:            int(content.Initiator),
+		EPSEvent:             epsEventNames[content.EPSEvent],
+		EPSEventID:           int(content.EPSEvent),
+		EPSCorrelationNumber: hex.EncodeToString(content.EPSCorrelationNumber),
+	}
+	if version := getBuiltinModuleVersionByOID(content.Hi2epsDomainID); version != nil {
+		ret.ModuleVersion = version.Name
+	}
+	for i := range content.PartyInformation {
+		party := &content.PartyInformation[i]
+		gprs := &party.ServicesDataInformation.GPRSParameters
+		ret.PartyInformation = append(ret.PartyInformation, jsonParty{
+			Qualifier:           int(party.PartyQualified),
+			IMSI:                formatText(party.PartyIdentity.IMSI),
+			IMEI:                formatText(party.PartyIdentity.IMEI),
+			MSISDN:              formatText(party.PartyIdentity.MSISDN),
+			PDPAddress:          formatIPAddress(&gprs.PDPAddress.IPAddress),
+			AdditionalIPAddress: formatIPAddress(&gprs.AdditionalIPAddress.IPAddress),
+		})
+	}
+	if !isZeroNetworkIdentifier(&content.NetworkIdentifier) {
+		ret.NetworkIdentifier = &jsonNetwork{
+			OperatorIdentifier:       hex.EncodeToString(content.NetworkIdentifier.OperatorIdentifier),
+			NetworkElementIdentifier: formatIPAddress(&content.NetworkIdentifier.NetworkElementIdentifier.IPAddress),
+		}
+	}
+	if params := &content.EPSSpecificParameters; !isZeroEPSSpecificParameters(params) {
+		ret.SpecificParameters = &jsonSpecificParam{
+			PDNAddressAllocation:   formatPdnAddressAllocation(params.PDNAddressAllocation),
+			APN:                    formatText(params.APN),
+			EPSBearerIdentity:      formatText(params.EPSBearerIdentity),
+			DetachType:             hex.EncodeToString(params.DetachType),
+			RATType:                hex.EncodeToString(params.RATType),
+			FailedBearerActReason:  hex.EncodeToString(params.FailedBearerActReason),
+			EPSBearerQoS:           hex.EncodeToString(params.EPSBearerQoS),
+			BearerActivationType:   int(params.BearerActivationType),
+			ApnAmbr:                hex.EncodeToString(params.ApnAmbr),
+			LinkedEPSBearerID:      hex.EncodeToString(params.LinkedEPSBearerID),
+			HandoverIndication:     hex.EncodeToString(params.HandoverIndication),
+			FailedTAUReason:        hex.EncodeToString(params.FailedTAUReason),
+			ServingMMEAddress:      formatIP(params.ServingMMEAddress),
+			BearerDeactivationType: int(params.BearerDeactivationType),
+			UserLocationInfo:       hex.EncodeToString(params.EPSLocationOfTheTarget.UserLocationInfo),
+			PDNType:                hex.EncodeToString(params.PDNType),
+			RequestType:            hex.EncodeToString(params.RequestType),
+			UEReqPDNConnFailReason: hex.EncodeToString(params.UEReqPDNConnFailReason),
+			BearerSessionID:        formatText(params.BearerSessionID),
+			VisitedPLMNID:          formatPLMNID(params.VisitedPLMNID),
+			ServingNetwork:         formatText(params.ServingNetwork),
+			RoamingStatus:          int(params.RoamingStatus),
+			BearerARP:              hex.EncodeToString(params.BearerARP),
+			PreviousEPSBearerQoS:   hex.EncodeToString(params.PreviousEPSBearerQoS),
+			PreviousApnAmbr:        hex.EncodeToString(params.PreviousApnAmbr),
+			PreviousBearerARP:      hex.EncodeToString(params.PreviousBearerARP),
+		}
+	}
+	if sms := &content.SMS; !isZeroSMSReport(sms) {
+		ret.SMS = &jsonSMS{
+			Initiator:      int(sms.SMSContents.Initiator),
+			TransferStatus: int(sms.SMSContents.TransferStatus),
+			OtherMessage:   int(sms.SMSContents.OtherMessage),
+			Content:        hex.EncodeToString(sms.SMSContents.Content),
+		}
+	}
+	if national := content.NationalParameters; national != (NationalParameters{}) {
+		ret.UsageReport = &jsonUsageReport{
+			CountryCode:    national.CountryCode,
+			UplinkVolume:   national.UsageReport.UplinkVolume,
+			DownlinkVolume: national.UsageReport.DownlinkVolume,
+			Duration:       national.UsageReport.Duration,
+		}
+	}
+	return ret
+}
+
+// formatText returns an octet string as text if it is printable ASCII, in
+// hex otherwise
+func formatText(b []byte) string {
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			return hex.EncodeToString(b)
+		}
+	}
+	return string(b)
+}
+
+// formatGeneralizedTime returns a timestamp of a record in RFC 3339 format
+func formatGeneralizedTime(b []byte) (string, bool) {
+	timestamp, err := decodeGeneralizedTime(b)
+	if err != nil {
+		return "", false
+	}
+	return timestamp.UTC().Format(time.RFC3339Nano), true
+}
+
+// formatIPAddress returns an IP address of a record in its usual notation,
+// followed by its prefix length if any
+func formatIPAddress(address *IPAddress) string {
+	ret := formatIP(address.IPValue.IPBinaryAddress)
+	if len(ret) != 0 && address.IPv6PrefixLength != 0 {
+		ret += "/" + strconv.Itoa(address.IPv6PrefixLength)
+	}
+	return ret
+}
+
+// formatIP returns an IP address in its usual notation, or as text when it
+// isn't a binary address
+func formatIP(b []byte) string {
+	if len(b) == net.IPv4len || len(b) == net.IPv6len {
+		return net.IP(b).String()
+	}
+	return string(b)
+}
+
+// formatPdnAddressAllocation returns the addresses of a PDN address
+// allocation, separated by a comma for dual-stack bearers
+func formatPdnAddressAllocation(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	addresses := b[1:]
+	switch {
+	case asn1.Enumerated(b[0]) == IPV4Type && len(addresses) == net.IPv4len,
+		asn1.Enumerated(b[0]) == IPV6Type && len(addresses) == net.IPv6len:
+		return net.IP(addresses).String()
+	case asn1.Enumerated(b[0]) == IPV4V6Type && len(addresses) == net.IPv4len+net.IPv6len:
+		return net.IP(addresses[:net.IPv4len]).String() + "," + net.IP(addresses[net.IPv4len:]).String()
+	}
+	return hex.EncodeToString(b)
+}
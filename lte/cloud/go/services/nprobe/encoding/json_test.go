@@ -0,0 +1,69 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToJSON(t *testing.T) {
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(encodedRecord))
+	b, err := ToJSON(&record)
+	assert.NoError(t, err)
+
+	var rendered jsonRecord
+	assert.NoError(t, json.Unmarshal(b, &rendered))
+	assert.Equal(t, RecordClassBegin, rendered.Class)
+	assert.Equal(t, "609dcabd-5ab1-4c95-9681-a24681f105ac", rendered.Header.XID)
+	assert.Equal(t, uint64(0x866cb397915ffe4), rendered.Header.CorrelationID)
+	assert.Equal(t, jsonAttribute{Tag: AttributeNetworkFn, Name: "network_function", Value: "sessiond"}, rendered.Header.Attributes[0])
+	assert.Equal(t, jsonAttribute{Tag: AttributeTargetID, Name: "target_id", Value: "IMSI001010000000001"}, rendered.Header.Attributes[1])
+	assert.Equal(t, jsonAttribute{Tag: AttributeSeqNumber, Name: "sequence_number", Value: float64(6)}, rendered.Header.Attributes[3])
+	timestamp, ok := formatGeneralizedTime(record.Payload.TimeStamp.LocalTime.GeneralizedTime)
+	assert.True(t, ok)
+	assert.Equal(t, timestamp, rendered.Header.Attributes[2].Value)
+	assert.Equal(t, timestamp, rendered.Payload.Timestamp)
+
+	payload := rendered.Payload
+	assert.Equal(t, "bearer-activation", payload.EPSEvent)
+	assert.Equal(t, int(BearerActivation), payload.EPSEventID)
+	assert.Equal(t, "0866cb397915ffe4", payload.EPSCorrelationNumber)
+	assert.Equal(t, GetOID().String(), payload.DomainID)
+	assert.Equal(t, []jsonParty{{
+		Qualifier: int(PartyQualifierTarget),
+		IMSI:      "IMSI001010000000001",
+		IMEI:      "04080604050008030103010102030107",
+	}}, payload.PartyInformation)
+	assert.Equal(t, &jsonNetwork{OperatorIdentifier: "0000bf6a", NetworkElementIdentifier: "192.168.60.142"}, payload.NetworkIdentifier)
+	assert.Equal(t, "192.168.128.12", payload.SpecificParameters.PDNAddressAllocation)
+	assert.Equal(t, "magma.ipv4", payload.SpecificParameters.APN)
+	assert.Equal(t, "IMSI001010000000001-919642", payload.SpecificParameters.EPSBearerIdentity)
+	assert.Equal(t, "06", payload.SpecificParameters.RATType)
+	assert.Nil(t, payload.SMS)
+}
+
+func TestFormatPdnAddressAllocation(t *testing.T) {
+	assert.Equal(t, "", formatPdnAddressAllocation(nil))
+	assert.Equal(t, "10.0.0.1", formatPdnAddressAllocation([]byte{0, 10, 0, 0, 1}))
+	dualStack := append([]byte{2, 10, 0, 0, 1}, make([]byte, 16)...)
+	dualStack[5] = 0x20
+	dualStack[6] = 0x01
+	assert.Equal(t, "10.0.0.1,2001::", formatPdnAddressAllocation(dualStack))
+	// malformed allocations are rendered in hex
+	assert.Equal(t, "000a00", formatPdnAddressAllocation([]byte{0, 10, 0}))
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/gofrs/uuid"
+)
+
+var (
+	// Keepalive PDU types as defined in ETSI TS 103 221-2
+	HeaderPduTypeKeepalive    uint16 = 3
+	HeaderPduTypeKeepaliveAck uint16 = 4
+)
+
+// MakeKeepalive returns a keepalive PDU carrying a sequence number. Keepalives
+// have no payload and a nil XID.
+func MakeKeepalive(seqNbr uint32) []byte {
+	return makeKeepalivePDU(HeaderPduTypeKeepalive, uuid.Nil, 0, seqNbr, HeaderVersion)
+}
+
+// MakeKeepaliveAck returns the acknowledgement of a keepalive or, when
+// acknowledged delivery is used, of a record. The XID, correlation ID and
+// sequence number of the acknowledged PDU are echoed, in its header version
+// if supported.
+func MakeKeepaliveAck(hdr *EpsIRIHeader) []byte {
+	seqNbr, _ := GetSequenceNumber(hdr)
+	version := hdr.Version
+	if !IsHeaderVersionSupported(version) {
+		version = HeaderVersion
+	}
+	return makeKeepalivePDU(HeaderPduTypeKeepaliveAck, hdr.XID, hdr.CorrelationID, seqNbr, version)
+}
+
+func makeKeepalivePDU(pduType uint16, xid uuid.UUID, corrID uint64, seqNbr uint32, version uint16) []byte {
+	attrs := []Attribute{NewAttribute(AttributeSeqNumber, convertUint32ToBytes(seqNbr))}
+	direction := PayloadDirectionUnkown
+	if version == HeaderVersion1 {
+		direction = 0
+	}
+	hdr := EpsIRIHeader{
+		Version:               version,
+		PduType:               pduType,
+		HeaderLength:          HeaderFixLen + uint32(attrs[0].Len) + 4,
+		PayloadDirection:      direction,
+		XID:                   xid,
+		CorrelationID:         corrID,
+		ConditionalAttributes: attrs,
+	}
+	return hdr.Marshal()
+}
+
+// GetSequenceNumber returns the sequence number attribute of a header if any
+func GetSequenceNumber(hdr *EpsIRIHeader) (uint32, bool) {
+	for _, attr := range hdr.ConditionalAttributes {
+		if attr.Tag == AttributeSeqNumber && len(attr.Value) == 4 {
+			return binary.BigEndian.Uint32(attr.Value), true
+		}
+	}
+	return 0, false
+}
+
+// ParsePDUHeader parses the header of a PDU, ignoring its payload
+func ParsePDUHeader(pdu []byte) (*EpsIRIHeader, error) {
+	if len(pdu) < int(HeaderFixLen) {
+		return nil, errors.New("invalid input size")
+	}
+	hdrLen := binary.BigEndian.Uint32(pdu[4:8])
+	if hdrLen < HeaderFixLen || hdrLen > MaxHeaderLength || uint64(hdrLen) > uint64(len(pdu)) {
+		return nil, errors.New("invalid header length")
+	}
+	hdr := &EpsIRIHeader{}
+	if err := hdr.Unmarshal(pdu[:hdrLen]); err != nil {
+		return nil, err
+	}
+	return hdr, nil
+}
+
+// ReadPDU reads a single PDU from a stream. PDUs whose header declares
+// a size beyond maxSize are rejected with ErrRecordTooLarge.
+func ReadPDU(r io.Reader, maxSize int) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxRecordSize
+	}
+	fixed := make([]byte, HeaderFixLen)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+
+	hdrLen := uint64(binary.BigEndian.Uint32(fixed[4:8]))
+	pldLen := uint64(binary.BigEndian.Uint32(fixed[8:12]))
+	if hdrLen < uint64(HeaderFixLen) || hdrLen > uint64(MaxHeaderLength) {
+		return nil, errors.New("invalid header length")
+	}
+	if hdrLen+pldLen > uint64(maxSize) {
+		return nil, ErrRecordTooLarge
+	}
+
+	pdu := make([]byte, hdrLen+pldLen)
+	copy(pdu, fixed)
+	if _, err := io.ReadFull(r, pdu[HeaderFixLen:]); err != nil {
+		return nil, err
+	}
+	return pdu, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepalive(t *testing.T) {
+	keepalive := MakeKeepalive(7)
+	hdr, err := ParsePDUHeader(keepalive)
+	assert.NoError(t, err)
+	assert.Equal(t, HeaderPduTypeKeepalive, hdr.PduType)
+	assert.Equal(t, uint32(0), hdr.PayloadLength)
+	assert.Equal(t, uuid.Nil, hdr.XID)
+	seqNbr, ok := GetSequenceNumber(hdr)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(7), seqNbr)
+
+	// acknowledgements echo the acknowledged record
+	record, err := ParsePDUHeader(encodedRecord)
+	assert.NoError(t, err)
+	ack, err := ParsePDUHeader(MakeKeepaliveAck(record))
+	assert.NoError(t, err)
+	assert.Equal(t, HeaderPduTypeKeepaliveAck, ack.PduType)
+	assert.Equal(t, record.XID, ack.XID)
+	assert.Equal(t, record.CorrelationID, ack.CorrelationID)
+	recordSeqNbr, _ := GetSequenceNumber(record)
+	ackSeqNbr, _ := GetSequenceNumber(ack)
+	assert.Equal(t, recordSeqNbr, ackSeqNbr)
+}
+
+func TestReadPDU(t *testing.T) {
+	stream := bytes.NewReader(append(MakeKeepalive(1), encodedRecord...))
+	pdu, err := ReadPDU(stream, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, MakeKeepalive(1), pdu)
+	pdu, err = ReadPDU(stream, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, pdu)
+	_, err = ReadPDU(stream, 0)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = ReadPDU(bytes.NewReader(encodedRecord[:len(encodedRecord)-1]), 0)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	forged := append([]byte(nil), encodedRecord...)
+	binary.BigEndian.PutUint32(forged[8:12], 0xFFFFFFFF)
+	_, err = ReadPDU(bytes.NewReader(forged), 0)
+	assert.Equal(t, ErrRecordTooLarge, err)
+}
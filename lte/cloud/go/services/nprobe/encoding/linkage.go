@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/gofrs/uuid"
+)
+
+// linkedXIDPrefix prefixes the value of the linkage indication, followed by
+// the XID the stream of the record is linked to
+const linkedXIDPrefix = "linked_xid="
+
+// MakeLinkageRecords builds the records linking the streams of a task whose
+// XID is rotated: the IRI-END ending the stream of previousXID, numbered
+// sequenceNbr, and the IRI-REPORT starting the stream of xid, numbered
+// sequenceNbr+1. Each record carries the XID of the other stream in its
+// linkage indication, a proprietary conditional attribute, so that the
+// delivery function can chain the streams.
+func MakeLinkageRecords(
+	task *models.NetworkProbeTask,
+	operatorID, sequenceNbr uint32,
+	previousXID, xid string,
+	rotatedAt time.Time,
+	version *ModuleVersion,
+) ([][]byte, error) {
+	timestamp := rotatedAt.UTC().Format(time.RFC3339Nano)
+	end := &eventdM.Event{
+		EventType:  nprobe.SessionTerminated,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  timestamp,
+		Value:      map[string]interface{}{},
+	}
+	endRecord, err := makeVersionedRecord(
+		end, WithXID(task, previousXID), operatorID, sequenceNbr, RecordClassEnd, version,
+		[]Attribute{NewAttribute(AttributeProprietary, []byte(linkedXIDPrefix+xid))},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &eventdM.Event{
+		EventType:  nprobe.TargetReported,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  timestamp,
+		Value:      map[string]interface{}{},
+	}
+	reportRecord, err := makeVersionedRecord(
+		report, WithXID(task, xid), operatorID, sequenceNbr+1, RecordClassReport, version,
+		[]Attribute{NewAttribute(AttributeProprietary, []byte(linkedXIDPrefix+previousXID))},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{endRecord, reportRecord}, nil
+}
+
+// WithXID returns a copy of a task whose records are identified by xid
+// rather than by its ID, e.g. once its XID was rotated
+func WithXID(task *models.NetworkProbeTask, xid string) *models.NetworkProbeTask {
+	if xid == string(task.TaskID) {
+		return task
+	}
+	rotated := *task
+	rotated.TaskID = models.NetworkProbeTaskID(xid)
+	return &rotated
+}
+
+// GetLinkedXID returns the XID a record is linked to by its linkage
+// indication, false for records not linking streams
+func GetLinkedXID(hdr *EpsIRIHeader) (uuid.UUID, bool) {
+	value := getAttribute(hdr, AttributeProprietary)
+	if !bytes.HasPrefix(value, []byte(linkedXIDPrefix)) {
+		return uuid.Nil, false
+	}
+	xid, err := uuid.FromString(string(bytes.TrimPrefix(value, []byte(linkedXIDPrefix))))
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return xid, true
+}
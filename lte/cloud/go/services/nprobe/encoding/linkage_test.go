@@ -0,0 +1,67 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeLinkageRecords(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI1234",
+			TargetType:    models.NetworkProbeTaskDetailsTargetTypeImsi,
+			CorrelationID: 42,
+		},
+	}
+	previousXID, xid := "29f28e1c-f230-486a-a860-f5a784ab9177", "0a1d8c2e-0000-4000-8000-000000000001"
+	records, err := MakeLinkageRecords(task, 1, 7, previousXID, xid, time.Unix(1615000000, 0), moduleVersions[DefaultModuleVersion])
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	var end EpsIRIRecord
+	assert.NoError(t, Validate(records[0]))
+	assert.NoError(t, end.Decode(records[0]))
+	assert.Equal(t, previousXID, end.Header.XID.String())
+	assert.Equal(t, RecordClassEnd, end.Class)
+	seqNbr, _ := GetSequenceNumber(&end.Header)
+	assert.Equal(t, uint32(7), seqNbr)
+	linked, ok := GetLinkedXID(&end.Header)
+	assert.True(t, ok)
+	assert.Equal(t, xid, linked.String())
+
+	var report EpsIRIRecord
+	assert.NoError(t, Validate(records[1]))
+	assert.NoError(t, report.Decode(records[1]))
+	assert.Equal(t, xid, report.Header.XID.String())
+	assert.Equal(t, RecordClassReport, report.Class)
+	seqNbr, _ = GetSequenceNumber(&report.Header)
+	assert.Equal(t, uint32(8), seqNbr)
+	linked, ok = GetLinkedXID(&report.Header)
+	assert.True(t, ok)
+	assert.Equal(t, previousXID, linked.String())
+	assert.Equal(t, uint64(42), report.Header.CorrelationID)
+
+	// the task itself is left untouched
+	assert.Equal(t, models.NetworkProbeTaskID(previousXID), task.TaskID)
+	hdr := NewEpsIRIHeader(end.Header.XID, 42, nil, 0)
+	_, ok = GetLinkedXID(&hdr)
+	assert.False(t, ok)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/hex"
+
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// Flags of the identities the user location information of TS 29.274
+// 8.21 carries, followed by the identities in the order of their flags
+const (
+	uliFlagTAI  = 0x08
+	uliFlagECGI = 0x10
+)
+
+// LocationFields are the event data keys of the location of the target,
+// in the EPS and UMTS domains
+var LocationFields = []string{"user_location", "tai", "ecgi", "cell_global_id", "routing_area_id", "service_area_id"}
+
+// HasLocation returns true if an event reports the location of the target
+func HasLocation(event *eventdM.Event) bool {
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for _, key := range LocationFields {
+		if _, ok := eventData[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutLocation returns a copy of an event whose location of the target
+// is left out, or the event itself when it reports none
+func WithoutLocation(event *eventdM.Event) *eventdM.Event {
+	if !HasLocation(event) {
+		return event
+	}
+	eventData := event.Value.(map[string]interface{})
+	value := make(map[string]interface{}, len(eventData))
+	for key, v := range eventData {
+		value[key] = v
+	}
+	for _, key := range LocationFields {
+		delete(value, key)
+	}
+	withoutLocation := *event
+	withoutLocation.Value = value
+	return &withoutLocation
+}
+
+// makeUserLocationInfo returns the user location information of the TAI and
+// ECGI of an event, nil if it reports neither. The identities are coded as
+// in TS 29.274, the event data being checked before.
+func makeUserLocationInfo(eventData map[string]interface{}) []byte {
+	tai, hasTAI := eventData["tai"].(string)
+	ecgi, hasECGI := eventData["ecgi"].(string)
+	if !hasTAI && !hasECGI {
+		return nil
+	}
+	uli := []byte{0}
+	if hasTAI {
+		b, _ := hex.DecodeString(tai)
+		uli[0] |= uliFlagTAI
+		uli = append(uli, b...)
+	}
+	if hasECGI {
+		b, _ := hex.DecodeString(ecgi)
+		uli[0] |= uliFlagECGI
+		uli = append(uli, b...)
+	}
+	return uli
+}
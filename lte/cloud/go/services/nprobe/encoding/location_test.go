@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeUserLocationInfo(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	event := &eventdM.Event{
+		EventType:  nprobe.TrackingAreaUpdate,
+		StreamName: nprobe.ESStreamMME,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi": "IMSI001010000000001",
+			"tai":  "00f1100001",
+			"ecgi": "00f1100001a2b3",
+		},
+	}
+	getLocation := func(event *eventdM.Event) []byte {
+		b, err := MakeRecord(event, task, 49002, 1)
+		assert.NoError(t, err)
+		assert.NoError(t, Validate(b))
+		var record EpsIRIRecord
+		assert.NoError(t, record.Decode(b))
+		return record.Payload.EPSSpecificParameters.EPSLocationOfTheTarget.UserLocationInfo
+	}
+
+	expected := []byte{0x18, 0x00, 0xf1, 0x10, 0x00, 0x01, 0x00, 0xf1, 0x10, 0x00, 0x01, 0xa2, 0xb3}
+	assert.Equal(t, expected, getLocation(event))
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "ecgi": "00f1100001a2b3"}
+	assert.Equal(t, expected[6:], getLocation(event)[1:])
+	assert.Equal(t, byte(uliFlagECGI), getLocation(event)[0])
+
+	// the user location information reported prevails
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "tai": "00f1100001", "user_location": "18"}
+	assert.Equal(t, []byte("18"), getLocation(event))
+
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "tai": "00f110"}
+	_, err := MakeRecord(event, task, 49002, 2)
+	assert.EqualError(t, err, `invalid location: tai is not a valid location: "00f110"`)
+	assert.True(t, IsDegradable(err))
+}
+
+func TestWithoutLocation(t *testing.T) {
+	event := &eventdM.Event{
+		EventType: nprobe.SessionCreated,
+		Value: map[string]interface{}{
+			"imsi":          "IMSI001010000000001",
+			"user_location": "TAI:00101-1",
+			"tai":           "00f1100001",
+			"ecgi":          "00f1100001a2b3",
+		},
+	}
+	assert.True(t, HasLocation(event))
+	withoutLocation := WithoutLocation(event)
+	assert.False(t, HasLocation(withoutLocation))
+	assert.Equal(t, map[string]interface{}{"imsi": "IMSI001010000000001"}, withoutLocation.Value)
+	// the event is left as it is
+	assert.Len(t, event.Value, 4)
+	assert.True(t, withoutLocation == WithoutLocation(withoutLocation))
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// missingParametersPrefix prefixes the value of the missing-parameter
+// indicator, followed by the comma separated fields left out of the record
+const missingParametersPrefix = "missing_parameters="
+
+// minimalEventFields are the event data keys kept in a minimal record: the
+// identity of the target and the bearer the event relates to
+var minimalEventFields = []string{"imsi", "imei", "msisdn", "session_id"}
+
+// IsDegradable returns true if the event whose record failed to be built
+// with err can still be delivered in a minimal record, i.e. the failure
+// comes from the data of the event rather than from its type, timestamp or
+// task
+func IsDegradable(err error) bool {
+	switch GetErrorField(err) {
+	case FieldEventData, FieldIdentity, FieldBearerParams, FieldLocation, FieldNetworkIdentifier, FieldSMS:
+		return true
+	}
+	return false
+}
+
+// MakeMinimalRecord builds the record of an event from its mandatory fields
+// only, i.e. the identity of the target, the bearer of the event and its
+// timestamp, for events whose other fields can't be encoded. The fields left
+// out are listed in the missing-parameter indicator of the record header, a
+// proprietary conditional attribute, and are returned along with the record.
+func MakeMinimalRecord(
+	event *eventdM.Event,
+	task *models.NetworkProbeTask,
+	operatorID, sequenceNbr uint32,
+	class string,
+	version *ModuleVersion,
+) ([]byte, []string, error) {
+	minimal, missing := makeMinimalEvent(event)
+	attr := NewAttribute(AttributeProprietary, []byte(missingParametersPrefix+strings.Join(missing, ",")))
+	record, err := makeVersionedRecord(minimal, task, operatorID, sequenceNbr, class, version, []Attribute{attr})
+	if err != nil {
+		return []byte{}, nil, err
+	}
+	return record, missing, nil
+}
+
+// GetMissingParameters returns the fields left out of a minimal record, as
+// listed in its missing-parameter indicator. It returns nil for full records.
+func GetMissingParameters(hdr *EpsIRIHeader) []string {
+	value := getAttribute(hdr, AttributeProprietary)
+	if !bytes.HasPrefix(value, []byte(missingParametersPrefix)) {
+		return nil
+	}
+	fields := strings.TrimPrefix(string(value), missingParametersPrefix)
+	if len(fields) == 0 {
+		return []string{}
+	}
+	return strings.Split(fields, ",")
+}
+
+// makeMinimalEvent returns a copy of an event reduced to the fields of a
+// minimal record, and the sorted fields of the event left out
+func makeMinimalEvent(event *eventdM.Event) (*eventdM.Event, []string) {
+	minimal := *event
+	value := map[string]interface{}{}
+	minimal.Value = value
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return &minimal, []string{FieldEventData}
+	}
+
+	kept := map[string]bool{}
+	for _, key := range minimalEventFields {
+		if v, ok := eventData[key].(string); ok {
+			value[key] = v
+			kept[key] = true
+		}
+	}
+	missing := map[string]bool{}
+	for _, f := range eventDataFields {
+		if _, ok := eventData[f.key]; ok && !kept[f.key] {
+			missing[f.field] = true
+		}
+	}
+	ret := make([]string, 0, len(missing))
+	for field := range missing {
+		ret = append(ret, field)
+	}
+	sort.Strings(ret)
+	return &minimal, ret
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeMinimalRecord(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			TargetType:    "imsi",
+			DeliveryType:  "events_only",
+			CorrelationID: 0x866cb397915ffe4,
+		},
+	}
+	version := moduleVersions[DefaultModuleVersion]
+	event := eventdM.Event{
+		EventType:  nprobe.SessionCreated,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":          "IMSI001010000000001",
+			"session_id":    "IMSI001010000000001-919642",
+			"ip_addr":       "192.168.128",
+			"apn":           "magma.ipv4",
+			"user_location": "82 00 f1 10",
+		},
+	}
+	_, err := MakeRecord(&event, task, 49002, 1)
+	assert.True(t, IsDegradable(err))
+
+	b, missing, err := MakeMinimalRecord(&event, task, 49002, 1, RecordClassBegin, version)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{FieldBearerParams, FieldLocation}, missing)
+	assert.NoError(t, Validate(b))
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, missing, GetMissingParameters(&record.Header))
+	params := record.Payload.EPSSpecificParameters
+	assert.Equal(t, []byte("IMSI001010000000001-919642"), params.EPSBearerIdentity)
+	assert.Empty(t, params.APN)
+	assert.Empty(t, params.PDNAddressAllocation)
+	assert.Equal(t, []byte("IMSI001010000000001"), getTargetIdentity(record.Payload.PartyInformation).IMSI)
+	// the event is left untouched
+	assert.Len(t, event.Value, 5)
+
+	// events without usable data still identify the target of the task
+	event.Value = "malformed"
+	b, missing, err = MakeMinimalRecord(&event, task, 49002, 2, RecordClassBegin, version)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{FieldEventData}, missing)
+	assert.NoError(t, Validate(b))
+
+	// full records carry no missing-parameter indicator
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "session_id": "IMSI001010000000001-919642"}
+	b, err = MakeRecord(&event, task, 49002, 3)
+	assert.NoError(t, err)
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	assert.Nil(t, GetMissingParameters(&record.Header))
+
+	// events whose type or timestamp are invalid are not degraded
+	event.Timestamp = "18/02/2021"
+	_, err = MakeRecord(&event, task, 49002, 4)
+	assert.False(t, IsDegradable(err))
+	_, _, err = MakeMinimalRecord(&event, task, 49002, 4, RecordClassBegin, version)
+	assert.Equal(t, FieldTimestamp, GetErrorField(err))
+}
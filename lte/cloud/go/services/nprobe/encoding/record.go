@@ -14,58 +14,104 @@ limitations under the License.
 package encoding
 
 import (
+	"context"
 	"encoding/asn1"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"magma/lte/cloud/go/services/nprobe"
 	"magma/lte/cloud/go/services/nprobe/obsidian/models"
 	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
 
 	"github.com/gofrs/uuid"
 )
 
+// DefaultMaxRecordSize is the size cap applied by DecodeWithLimit when none is given
+const DefaultMaxRecordSize = 64 * 1024
+
+// ErrRecordTooLarge is returned by DecodeWithLimit for inputs beyond the size cap
+var ErrRecordTooLarge = errors.New("record exceeds size limit")
+
 // EpsIRIRecord represents a full IRI record combining header and payload
 type EpsIRIRecord struct {
 	Header  EpsIRIHeader
 	Payload EpsIRIContent
+	// Class is the class of the record, e.g. RecordClassBegin, which the
+	// payload is tagged with. It is derived from the EPS event when empty.
+	Class string
 }
 
-// Encode returns a byte sequence of the EpsIRIRecord in network byte order
+// Encode returns a byte sequence of the EpsIRIRecord in network byte order.
+// Records are encoded with pooled encoders so that only the returned byte
+// sequence is allocated.
 func (r *EpsIRIRecord) Encode() ([]byte, error) {
-	recordType := getRecordType(r.Payload.EPSEvent)
-	content, err := asn1.MarshalWithParams(r.Payload, recordType)
+	e := encoderPool.Get().(*Encoder)
+	defer encoderPool.Put(e)
+	msg, err := e.Encode(r)
 	if err != nil {
 		return []byte{}, err
 	}
-
-	// Update payload length before marshaling the header
-	r.Header.PayloadLength = uint32(len(content))
-	msg := r.Header.Marshal()
-	return append(msg, content...), nil
+	return append([]byte(nil), msg...), nil
 }
 
-// Decode constructs an IRI record from a byte sequence
+// Decode constructs an IRI record from a byte sequence, as parsed by
+// ParseHeader and DecodePayload
 func (r *EpsIRIRecord) Decode(b []byte) error {
-	if len(b) < int(HeaderFixLen) {
-		return errors.New("input too small")
+	hdr, err := ParseHeader(b)
+	if err != nil {
+		return err
 	}
-
-	hdr_len := binary.BigEndian.Uint32(b[4:8])
-	pld_len := binary.BigEndian.Uint32(b[8:12])
-	if int(hdr_len+pld_len) > len(b) {
-		return errors.New("invalid input size")
+	payload, class, err := DecodePayload(hdr, b)
+	if err != nil {
+		return err
 	}
+	r.Header, r.Payload, r.Class = *hdr, *payload, class
+	return nil
+}
 
-	if err := r.Header.Unmarshal(b[:hdr_len]); err != nil {
+// DecodeWithLimit constructs an IRI record from an untrusted byte sequence.
+// Inputs larger than maxSize bytes, or whose header declares a record larger
+// than maxSize, are rejected before parsing. DefaultMaxRecordSize applies when
+// maxSize is not positive. The elements of the payload are walked before it
+// is decoded, which fails once ctx is done or beyond maxDecodedElements, so
+// that hostile payloads can't hold the caller. The record is left unchanged
+// on failure.
+func (r *EpsIRIRecord) DecodeWithLimit(ctx context.Context, b []byte, maxSize int) error {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxRecordSize
+	}
+	if len(b) > maxSize {
+		return ErrRecordTooLarge
+	}
+	if len(b) >= int(HeaderFixLen) {
+		declared := uint64(binary.BigEndian.Uint32(b[4:8])) + uint64(binary.BigEndian.Uint32(b[8:12]))
+		if declared > uint64(maxSize) {
+			return ErrRecordTooLarge
+		}
+	}
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-
-	content := b[hdr_len : hdr_len+pld_len]
-	recordType := decodeRecordType(b[hdr_len])
-	if _, err := asn1.UnmarshalWithParams(content, &r.Payload, recordType); err != nil {
+	hdr, err := ParseHeader(b)
+	if err != nil {
 		return err
 	}
+	budget := &berBudget{ctx: ctx}
+	if _, err := budget.walk(b[hdr.HeaderLength:hdr.HeaderLength+hdr.PayloadLength], 0, false); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	payload, class, err := DecodePayload(hdr, b)
+	if err != nil {
+		return err
+	}
+	r.Header, r.Payload, r.Class = *hdr, *payload, class
 	return nil
 }
 
@@ -75,12 +121,20 @@ func makeConditionalAttributes(
 	streamName, targetID string,
 	timestamp []byte,
 	seqNbr uint32,
+	extraAttrs ...Attribute,
 ) ([]Attribute, uint32) {
-	attrs := []Attribute{}
-	attrs = append(attrs, NewAttribute(AttributeNetworkFn, []byte(streamName)))
-	attrs = append(attrs, NewAttribute(AttributeTargetID, []byte(targetID)))
+	// the values of the attributes share a single buffer
+	values := make([]byte, len(streamName)+len(targetID)+4)
+	n := copy(values, streamName)
+	m := n + copy(values[n:], targetID)
+	binary.BigEndian.PutUint32(values[m:], seqNbr)
+
+	attrs := make([]Attribute, 0, 4+len(extraAttrs))
+	attrs = append(attrs, NewAttribute(AttributeNetworkFn, values[:n:n]))
+	attrs = append(attrs, NewAttribute(AttributeTargetID, values[n:m:m]))
 	attrs = append(attrs, NewAttribute(AttributeTimestamp, timestamp))
-	attrs = append(attrs, NewAttribute(AttributeSeqNumber, convertUint32ToBytes(seqNbr)))
+	attrs = append(attrs, NewAttribute(AttributeSeqNumber, values[m:]))
+	attrs = append(attrs, extraAttrs...)
 
 	attrs_len := uint32(0)
 	for _, attr := range attrs {
@@ -89,6 +143,78 @@ func makeConditionalAttributes(
 	return attrs, attrs_len
 }
 
+// makePSHeaderAttributes builds the optional ETSI TS 102 232-1 defined attribute
+// carrying the lawful authorization reference and the delivery country code.
+// No attribute is returned when the task defines none of them.
+func makePSHeaderAttributes(details *models.NetworkProbeTaskDetails) ([]Attribute, error) {
+	if details.AuthorizationReference == "" && details.DeliveryCountryCode == "" {
+		return []Attribute{}, nil
+	}
+
+	value, err := marshalPSHeaderAttribute(&PSHeaderAttribute{
+		LawfulInterceptionIdentifier: makeLawInterceptID(details.AuthorizationReference),
+		DeliveryCountryCode:          details.DeliveryCountryCode,
+	})
+	if err != nil {
+		return []Attribute{}, err
+	}
+	return []Attribute{NewAttribute(AttributeETSI102232, value)}, nil
+}
+
+// makeLawInterceptID returns the encoded lawful interception identifier, or nil
+// when no authorization reference is set so that the optional field is omitted.
+func makeLawInterceptID(authorizationReference string) []byte {
+	if authorizationReference == "" {
+		return nil
+	}
+	return []byte(authorizationReference)
+}
+
+// WithHeaderIdentifiers returns a copy of a task whose records carry the
+// given LIID and delivery country code unless the task defines its own, e.g.
+// the defaults of the network of the task
+func WithHeaderIdentifiers(task *models.NetworkProbeTask, lawfulInterceptionID, deliveryCountryCode string) *models.NetworkProbeTask {
+	details := task.TaskDetails
+	if (details.AuthorizationReference != "" || lawfulInterceptionID == "") &&
+		(details.DeliveryCountryCode != "" || deliveryCountryCode == "") {
+		return task
+	}
+	identified := *details
+	if identified.AuthorizationReference == "" {
+		identified.AuthorizationReference = lawfulInterceptionID
+	}
+	if identified.DeliveryCountryCode == "" {
+		identified.DeliveryCountryCode = deliveryCountryCode
+	}
+	withIdentifiers := *task
+	withIdentifiers.TaskDetails = &identified
+	return &withIdentifiers
+}
+
+// makeNationalParameters returns the national parameters of a usage report,
+// qualified by the delivery country code of the task, the zero value for the
+// other events
+func makeNationalParameters(event *eventdM.Event, details *models.NetworkProbeTaskDetails) (NationalParameters, error) {
+	if event.EventType != nprobe.UsageReported {
+		return NationalParameters{}, nil
+	}
+	if len(details.DeliveryCountryCode) == 0 {
+		return NationalParameters{}, newEncodingError(FieldTaskDetails, errors.New("usage reports require a delivery country code"))
+	}
+	eventData := event.Value.(map[string]interface{})
+	var usage UsageReport
+	for key, v := range map[string]*int64{
+		"bytes_tx":      &usage.UplinkVolume,
+		"bytes_rx":      &usage.DownlinkVolume,
+		"duration_secs": &usage.Duration,
+	} {
+		if value, ok := eventData[key]; ok {
+			*v, _ = strconv.ParseInt(value.(string), 10, 64)
+		}
+	}
+	return NationalParameters{CountryCode: details.DeliveryCountryCode, UsageReport: usage}, nil
+}
+
 // makeEpsIRIContent builds the IRI Content structure with the available information
 // for each event
 func makeEpsIRIContent(
@@ -104,47 +230,187 @@ func makeEpsIRIContent(
 		TimeStamp:             makeTimestamp(timestamp),
 		Initiator:             InitiatorNotAvailable,
 		PartyInformation:      makePartyInformation(event),
+		SMS:                   makeSMSReport(event),
 		EPSCorrelationNumber:  convertUint64ToBytes(correlationID),
 		EPSEvent:              eventID,
 		NetworkIdentifier:     makeNetworkIdentifier(event, operatorID),
-		EPSSpecificParameters: processEventSpecificData(event),
+		EPSSpecificParameters: makeEPSSpecificParameters(event),
+	}
+}
+
+// setTargetIdentity adds the identity the task targets to the party information
+// when the event does not carry it, so that records always identify the target
+// the warrant is expressed with.
+func setTargetIdentity(parties []PartyInformation, details *models.NetworkProbeTaskDetails) {
+	identity := getTargetIdentity(parties)
+	if identity == nil {
+		return
+	}
+	switch details.TargetType {
+	case models.NetworkProbeTaskDetailsTargetTypeImsi:
+		if len(identity.IMSI) == 0 {
+			identity.IMSI = []byte(details.TargetID)
+		}
+	case models.NetworkProbeTaskDetailsTargetTypeImei:
+		if len(identity.IMEI) == 0 {
+			identity.IMEI = []byte(details.TargetID)
+		}
+	case models.NetworkProbeTaskDetailsTargetTypeMsisdn:
+		if len(identity.MSISDN) == 0 {
+			identity.MSISDN = []byte(strings.TrimPrefix(details.TargetID, "+"))
+		}
 	}
 }
 
-// MakeEpsIRIRecord build a new record and encode it to a byte sequence
+// getTargetIdentity returns the identity of the target party if any
+func getTargetIdentity(parties []PartyInformation) *PartyIdentity {
+	for i := range parties {
+		if parties[i].PartyQualified == PartyQualifierTarget {
+			return &parties[i].PartyIdentity
+		}
+	}
+	return nil
+}
+
+// MakeRecord builds a new record of the class of the event type, as
+// returned by GetEventRecordClass, and encodes it to a byte sequence
 func MakeRecord(
 	event *eventdM.Event,
 	task *models.NetworkProbeTask,
 	operatorID, sequenceNbr uint32,
 ) ([]byte, error) {
+	return MakeRecordWithClass(event, task, operatorID, sequenceNbr, GetEventRecordClass(event.EventType))
+}
+
+// MakeRecordWithClass builds a new record of the given class, e.g.
+// RecordClassContinue for a bearer activated within a session already
+// begun, and encodes it to a byte sequence
+func MakeRecordWithClass(
+	event *eventdM.Event,
+	task *models.NetworkProbeTask,
+	operatorID, sequenceNbr uint32,
+	class string,
+) ([]byte, error) {
+	return MakeVersionedRecord(event, task, operatorID, sequenceNbr, class, moduleVersions[DefaultModuleVersion])
+}
+
+// MakeVersionedRecord builds a new record of the given class with the module
+// version expected by its destination. The record is identified by the OID
+// of the version and the fields its module does not define are left out.
+// Records of the UMTS domain, selected by the task or the stream of the event
+// as getIRIDomain returns, are encoded with the single UMTS module version.
+func MakeVersionedRecord(
+	event *eventdM.Event,
+	task *models.NetworkProbeTask,
+	operatorID, sequenceNbr uint32,
+	class string,
+	version *ModuleVersion,
+) ([]byte, error) {
+	return makeVersionedRecord(event, task, operatorID, sequenceNbr, class, version, nil)
+}
+
+// makeVersionedRecord builds a new record with the module version expected
+// by its destination, adding extra conditional attributes to its header
+func makeVersionedRecord(
+	event *eventdM.Event,
+	task *models.NetworkProbeTask,
+	operatorID, sequenceNbr uint32,
+	class string,
+	version *ModuleVersion,
+	extraAttrs []Attribute,
+) ([]byte, error) {
 
 	// map event type to 3gpp event id
 	eventID := getEPSEventID(event.EventType)
 	if eventID == UnsupportedEvent {
-		return []byte{}, fmt.Errorf("Unsupported event type %s\n", event.EventType)
+		return []byte{}, newEncodingError(FieldEventType, fmt.Errorf("Unsupported event type %s", event.EventType))
+	}
+	if !isRecordClassAllowed(eventID, class) {
+		return []byte{}, newEncodingError(FieldEventType, fmt.Errorf("Record class %s does not apply to event type %s", class, event.EventType))
 	}
 
 	bTimestamp, err := encodeGeneralizedTime(event.Timestamp)
 	if err != nil {
+		return []byte{}, newEncodingError(FieldTimestamp, err)
+	}
+
+	if err := validateEventData(event); err != nil {
 		return []byte{}, err
 	}
 
+	psHeaderAttrs, err := makePSHeaderAttributes(task.TaskDetails)
+	if err != nil {
+		return []byte{}, newEncodingError(FieldTaskDetails, err)
+	}
+
 	attrs, attrs_len := makeConditionalAttributes(
 		event.StreamName,
 		task.TaskDetails.TargetID,
 		bTimestamp,
 		sequenceNbr,
+		append(psHeaderAttrs, extraAttrs...)...,
 	)
 
 	uuid, err := uuid.FromString(string(task.TaskID))
 	if err != nil {
-		return []byte{}, err
+		return []byte{}, newEncodingError(FieldTaskID, err)
 	}
 
 	correlationID := task.TaskDetails.CorrelationID
+	header := NewEpsIRIHeader(uuid, correlationID, attrs, attrs_len)
+	nationalParameters, err := makeNationalParameters(event, task.TaskDetails)
+	if err != nil {
+		return []byte{}, err
+	}
+	if getIRIDomain(event.StreamName, task.TaskDetails) == IRIDomainUMTS {
+		return makeUmtsRecord(event, task.TaskDetails, header, eventID, class, operatorID, bTimestamp, nationalParameters)
+	}
+
 	record := EpsIRIRecord{
-		Header:  NewEpsIRIHeader(uuid, correlationID, attrs, attrs_len),
+		Header:  header,
 		Payload: makeEpsIRIContent(event, eventID, correlationID, operatorID, bTimestamp),
+		Class:   class,
 	}
+	record.Payload.LawInterceptID = makeLawInterceptID(task.TaskDetails.AuthorizationReference)
+	record.Payload.NationalParameters = nationalParameters
+	setTargetIdentity(record.Payload.PartyInformation, task.TaskDetails)
+	version.restrict(&record.Payload)
+	sortPartyInformation(record.Payload.PartyInformation)
 	return record.Encode()
 }
+
+// MakeTerminalRecord builds the IRI-END record closing the intercepted session
+// of a task when interception is suspended. It carries no bearer information.
+func MakeTerminalRecord(
+	task *models.NetworkProbeTask,
+	operatorID, sequenceNbr uint32,
+	suspendedAt time.Time,
+	version *ModuleVersion,
+) ([]byte, error) {
+	event := &eventdM.Event{
+		EventType:  nprobe.SessionTerminated,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  suspendedAt.UTC().Format(time.RFC3339Nano),
+		Value:      map[string]interface{}{},
+	}
+	return MakeVersionedRecord(event, task, operatorID, sequenceNbr, GetEventRecordClass(event.EventType), version)
+}
+
+// MakeSessionEndRecord builds the IRI-END record ending the interception of
+// an open session of a task, e.g. once the session had no activity for long.
+// It carries no bearer information.
+func MakeSessionEndRecord(
+	task *models.NetworkProbeTask,
+	operatorID, sequenceNbr uint32,
+	sessionID string,
+	endedAt time.Time,
+	version *ModuleVersion,
+) ([]byte, error) {
+	event := &eventdM.Event{
+		EventType:  nprobe.SessionTerminated,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  endedAt.UTC().Format(time.RFC3339Nano),
+		Value:      map[string]interface{}{"session_id": sessionID},
+	}
+	return MakeVersionedRecord(event, task, operatorID, sequenceNbr, RecordClassEnd, version)
+}
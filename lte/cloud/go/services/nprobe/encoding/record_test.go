@@ -14,9 +14,18 @@ limitations under the License.
 package encoding
 
 import (
+	"bytes"
+	"context"
+	"encoding/asn1"
+	"encoding/binary"
 	"encoding/hex"
 	"reflect"
 	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -88,3 +97,952 @@ func TestEpsIRIRecord(t *testing.T) {
 	assert.Equal(t, InitiatorNotAvailable, record.Payload.Initiator)
 	assert.Equal(t, GetOID(), record.Payload.Hi2epsDomainID)
 }
+
+func TestDecodeBounds(t *testing.T) {
+	hdrLen := binary.BigEndian.Uint32(encodedRecord[4:8])
+	var record EpsIRIRecord
+
+	// payloads must end where declared
+	trailing := append(append([]byte(nil), encodedRecord...), 0, 0)
+	binary.BigEndian.PutUint32(trailing[8:12], binary.BigEndian.Uint32(trailing[8:12])+2)
+	assert.EqualError(t, record.Decode(trailing), "trailing data after payload")
+
+	// so must headers
+	var hdr EpsIRIHeader
+	assert.EqualError(t, hdr.Unmarshal(encodedRecord[:hdrLen-1]), "invalid header length")
+	short := append([]byte(nil), encodedRecord...)
+	binary.BigEndian.PutUint32(short[4:8], 8)
+	assert.EqualError(t, record.Decode(short), "invalid input size")
+
+	// headers beyond MaxHeaderLength are rejected
+	long := append([]byte(nil), encodedRecord[:hdrLen]...)
+	long = append(append(long, 0, 4, 0x10, 0x00), make([]byte, 0x1000)...)
+	binary.BigEndian.PutUint32(long[4:8], uint32(len(long)))
+	assert.EqualError(t, hdr.Unmarshal(long), "invalid header length")
+	_, err := ParsePDUHeader(long)
+	assert.EqualError(t, err, "invalid header length")
+	_, err = ReadPDU(bytes.NewReader(long), 0)
+	assert.EqualError(t, err, "invalid header length")
+}
+
+func TestDecodeWithLimit(t *testing.T) {
+	var record EpsIRIRecord
+	assert.NoError(t, record.DecodeWithLimit(context.Background(), encodedRecord, 0))
+	assert.Equal(t, BearerActivation, record.Payload.EPSEvent)
+
+	// inputs beyond the cap are rejected before parsing
+	err := record.DecodeWithLimit(context.Background(), encodedRecord, len(encodedRecord)-1)
+	assert.Equal(t, ErrRecordTooLarge, err)
+
+	// so are headers declaring a record beyond the cap
+	forged := append([]byte(nil), encodedRecord...)
+	binary.BigEndian.PutUint32(forged[8:12], 0xFFFFFFFF)
+	err = record.DecodeWithLimit(context.Background(), forged, 0)
+	assert.Equal(t, ErrRecordTooLarge, err)
+
+	// as are payloads holding too many elements to be decoded
+	hdrLen := binary.BigEndian.Uint32(encodedRecord[4:8])
+	flooded := append([]byte(nil), encodedRecord[:hdrLen]...)
+	flooded = append(flooded, bytes.Repeat([]byte{0x05, 0x00}, maxDecodedElements+1)...)
+	binary.BigEndian.PutUint32(flooded[8:12], uint32(len(flooded)-int(hdrLen)))
+	err = record.DecodeWithLimit(context.Background(), flooded, 0)
+	assert.Equal(t, errTooManyElements, err)
+
+	// the record is left unchanged when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	decoded := EpsIRIRecord{}
+	err = decoded.DecodeWithLimit(ctx, encodedRecord, 0)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, EpsIRIRecord{}, decoded)
+}
+
+func TestMakeTerminalRecord(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI1234",
+			CorrelationID: 42,
+		},
+	}
+	b, err := MakeTerminalRecord(task, 1, 7, time.Unix(1615000000, 0), moduleVersions[DefaultModuleVersion])
+	assert.NoError(t, err)
+
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, uint64(42), record.Header.CorrelationID)
+	seqNbr, _ := GetSequenceNumber(&record.Header)
+	assert.Equal(t, uint32(7), seqNbr)
+	assert.Equal(t, BearerDeactivation, record.Payload.EPSEvent)
+	assert.Equal(t, IRIEndRecord, decodeRecordType(b[record.Header.HeaderLength]))
+	assert.Equal(t, RecordClassEnd, record.Class)
+}
+
+func TestMakeSessionEndRecord(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI1234",
+			TargetType:    models.NetworkProbeTaskDetailsTargetTypeImsi,
+			CorrelationID: 42,
+		},
+	}
+	b, err := MakeSessionEndRecord(task, 1, 8, "IMSI1234-919642", time.Unix(1615000000, 0), moduleVersions[DefaultModuleVersion])
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	seqNbr, _ := GetSequenceNumber(&record.Header)
+	assert.Equal(t, uint32(8), seqNbr)
+	assert.Equal(t, BearerDeactivation, record.Payload.EPSEvent)
+	assert.Equal(t, RecordClassEnd, record.Class)
+}
+
+func TestMakeRecordWithAuthorizationReference(t *testing.T) {
+	event := eventdM.Event{
+		EventType:  nprobe.SessionCreated,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":       "IMSI001010000000001",
+			"session_id": "IMSI001010000000001-919642",
+			"apn":        "magma.ipv4",
+			"ip_addr":    "192.168.128.12",
+		},
+	}
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:               "IMSI001010000000001",
+			TargetType:             "imsi",
+			DeliveryType:           "events_only",
+			CorrelationID:          0x866cb397915ffe4,
+			AuthorizationReference: "LIID-0042",
+			DeliveryCountryCode:    "FR",
+		},
+	}
+
+	b, err := MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, []byte("LIID-0042"), record.Payload.LawInterceptID)
+
+	var psHeader PSHeaderAttribute
+	found := false
+	for _, attr := range record.Header.ConditionalAttributes {
+		if attr.Tag == AttributeETSI102232 {
+			_, err = asn1.Unmarshal(attr.Value, &psHeader)
+			assert.NoError(t, err)
+			found = true
+		}
+	}
+	assert.True(t, found)
+	assert.Equal(t, []byte("LIID-0042"), psHeader.LawfulInterceptionIdentifier)
+	assert.Equal(t, "FR", psHeader.DeliveryCountryCode)
+
+	// Without any reference, neither the attribute nor the LIID are encoded
+	task.TaskDetails.AuthorizationReference = ""
+	task.TaskDetails.DeliveryCountryCode = ""
+	b, err = MakeRecord(&event, task, 49002, 2)
+	assert.NoError(t, err)
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	assert.Empty(t, record.Payload.LawInterceptID)
+	for _, attr := range record.Header.ConditionalAttributes {
+		assert.NotEqual(t, AttributeETSI102232, attr.Tag)
+	}
+
+	// The defaults of the network are encoded in their place
+	b, err = MakeRecord(&event, WithHeaderIdentifiers(task, "LIID-0001", "BE"), 49002, 3)
+	assert.NoError(t, err)
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, []byte("LIID-0001"), record.Payload.LawInterceptID)
+	psHeader = PSHeaderAttribute{}
+	for _, attr := range record.Header.ConditionalAttributes {
+		if attr.Tag == AttributeETSI102232 {
+			_, err = asn1.Unmarshal(attr.Value, &psHeader)
+			assert.NoError(t, err)
+		}
+	}
+	assert.Equal(t, []byte("LIID-0001"), psHeader.LawfulInterceptionIdentifier)
+	assert.Equal(t, "BE", psHeader.DeliveryCountryCode)
+	assert.Empty(t, task.TaskDetails.AuthorizationReference)
+}
+
+func TestMakeRecordErrorField(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			TargetType:    "imsi",
+			DeliveryType:  "events_only",
+			CorrelationID: 0x866cb397915ffe4,
+		},
+	}
+	newEvent := func() eventdM.Event {
+		return eventdM.Event{
+			EventType:  nprobe.SessionCreated,
+			StreamName: nprobe.ESStreamSessionD,
+			Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+			Value: map[string]interface{}{
+				"imsi":       "IMSI001010000000001",
+				"session_id": "IMSI001010000000001-919642",
+				"ip_addr":    "192.168.128.12",
+			},
+		}
+	}
+
+	event := newEvent()
+	event.EventType = "unknown_event"
+	_, err := MakeRecord(&event, task, 49002, 1)
+	assert.Equal(t, FieldEventType, GetErrorField(err))
+
+	event = newEvent()
+	event.Timestamp = "18/02/2021"
+	_, err = MakeRecord(&event, task, 49002, 1)
+	assert.Equal(t, FieldTimestamp, GetErrorField(err))
+
+	event = newEvent()
+	event.Value = "malformed"
+	_, err = MakeRecord(&event, task, 49002, 1)
+	assert.Equal(t, FieldEventData, GetErrorField(err))
+	assert.True(t, IsDecodingError(err))
+
+	event = newEvent()
+	event.Value.(map[string]interface{})["imsi"] = 1010000000001
+	_, err = MakeRecord(&event, task, 49002, 1)
+	assert.Equal(t, FieldIdentity, GetErrorField(err))
+	assert.True(t, IsDecodingError(err))
+
+	event = newEvent()
+	event.Value.(map[string]interface{})["ip_addr"] = "192.168.128"
+	_, err = MakeRecord(&event, task, 49002, 1)
+	assert.Equal(t, FieldBearerParams, GetErrorField(err))
+	assert.EqualError(t, err, `invalid bearer_params: ip_addr is not a valid IPv4 address: "192.168.128"`)
+	assert.False(t, IsDecodingError(err))
+
+	event = newEvent()
+	_, err = MakeRecord(&event, &models.NetworkProbeTask{TaskID: "IMSI1234", TaskDetails: task.TaskDetails}, 49002, 1)
+	assert.Equal(t, FieldTaskID, GetErrorField(err))
+
+	assert.Equal(t, FieldUnknown, GetErrorField(assert.AnError))
+	assert.False(t, IsDecodingError(assert.AnError))
+}
+
+func TestMakeRecordTargetIdentity(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "+33612345678",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeMsisdn,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.AttachSuccess,
+		StreamName: nprobe.ESStreamMME,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+	}
+
+	// the identity the warrant is expressed with is added when missing from the event
+	b, err := MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	identity := record.Payload.PartyInformation[0].PartyIdentity
+	assert.Equal(t, []byte("IMSI001010000000001"), identity.IMSI)
+	assert.Equal(t, []byte("33612345678"), identity.MSISDN)
+	assert.Empty(t, identity.IMEI)
+
+	task.TaskDetails.TargetID = "490154203237518"
+	task.TaskDetails.TargetType = models.NetworkProbeTaskDetailsTargetTypeImei
+	event.Value.(map[string]interface{})["imei"] = "4901542032375101"
+	b, err = MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	identity = record.Payload.PartyInformation[0].PartyIdentity
+	assert.Equal(t, []byte("4901542032375101"), identity.IMEI)
+	assert.Empty(t, identity.MSISDN)
+}
+
+func TestMakeRecordCorrespondent(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.SessionCreated,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":                    "IMSI001010000000001",
+			"session_id":              "IMSI001010000000001-919642",
+			"correspondent_msisdn":    "33687654321",
+			"correspondent_qualifier": "terminating",
+		},
+	}
+
+	b, err := MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+
+	// parties are ordered by their encoding, the shorter correspondent first
+	parties := record.Payload.PartyInformation
+	assert.Len(t, parties, 2)
+	assert.Equal(t, PartyQualifierTerminating, parties[0].PartyQualified)
+	assert.Equal(t, []byte("33687654321"), parties[0].PartyIdentity.MSISDN)
+	assert.Equal(t, PartyQualifierTarget, parties[1].PartyQualified)
+	assert.Equal(t, []byte("IMSI001010000000001"), getTargetIdentity(parties).IMSI)
+
+	// the order of the parties does not depend on the event
+	parties[0], parties[1] = parties[1], parties[0]
+	sortPartyInformation(parties)
+	assert.Equal(t, record.Payload.PartyInformation, parties)
+	assert.Equal(t, PartyQualifierTerminating, parties[0].PartyQualified)
+
+	// the role of the correspondent is required
+	delete(event.Value.(map[string]interface{}), "correspondent_qualifier")
+	_, err = MakeRecord(&event, task, 49002, 1)
+	assert.EqualError(t, err, `invalid identity: invalid correspondent_qualifier ""`)
+}
+
+func TestGetRecordClass(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	expected := map[string]string{
+		nprobe.SessionCreated:    RecordClassBegin,
+		nprobe.SessionUpdated:    RecordClassContinue,
+		nprobe.SessionTerminated: RecordClassEnd,
+		nprobe.AttachSuccess:     RecordClassReport,
+	}
+	for eventType, class := range expected {
+		event := eventdM.Event{
+			EventType:  eventType,
+			StreamName: nprobe.ESStreamSessionD,
+			Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+			Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+		}
+		b, err := MakeRecord(&event, task, 49002, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, class, GetRecordClass(b), eventType)
+	}
+
+	assert.Equal(t, RecordClassUnknown, GetRecordClass(MakeKeepalive(1)))
+	assert.Equal(t, RecordClassUnknown, GetRecordClass(nil))
+}
+
+func TestMakeRecordWithClass(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.SessionUpdated,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":       "IMSI001010000000001",
+			"session_id": "IMSI001010000000001-919642",
+		},
+	}
+
+	// a session modified before being intercepted begins its interception
+	b, err := MakeRecordWithClass(&event, task, 49002, 1, RecordClassBegin)
+	assert.NoError(t, err)
+	assert.Equal(t, RecordClassBegin, GetRecordClass(b))
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, BearerModification, record.Payload.EPSEvent)
+	assert.Equal(t, RecordClassBegin, record.Class)
+	reencoded, err := record.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, b, reencoded)
+
+	_, err = MakeRecordWithClass(&event, task, 49002, 1, RecordClassEnd)
+	assert.EqualError(t, err, "invalid event_type: Record class end does not apply to event type session_updated")
+	event.EventType = nprobe.AttachSuccess
+	_, err = MakeRecordWithClass(&event, task, 49002, 1, RecordClassBegin)
+	assert.Equal(t, FieldEventType, GetErrorField(err))
+	b, err = MakeRecordWithClass(&event, task, 49002, 1, RecordClassReport)
+	assert.NoError(t, err)
+	assert.Equal(t, RecordClassReport, GetRecordClass(b))
+}
+
+func TestMakeTargetReportRecord(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.TargetReported,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":          "IMSI001010000000001",
+			"user_location": "TAI:00101-1",
+			"ip_addr":       "192.168.128.12",
+			"apn":           "magma.ipv4",
+		},
+	}
+	b, err := MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	assert.Equal(t, RecordClassReport, GetRecordClass(b))
+
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, LocationUpdate, record.Payload.EPSEvent)
+	params := record.Payload.EPSSpecificParameters
+	assert.Equal(t, []byte("TAI:00101-1"), params.EPSLocationOfTheTarget.UserLocationInfo)
+	assert.Equal(t, []byte{byte(IPV4Type), 192, 168, 128, 12}, params.PDNAddressAllocation)
+	assert.Equal(t, []byte("magma.ipv4"), params.APN)
+	assert.Nil(t, params.EPSBearerIdentity)
+
+	// targets without session only report their identity and location
+	event.Value = map[string]interface{}{"user_location": "TAI:00101-1"}
+	b, err = MakeRecord(&event, task, 49002, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	assert.Nil(t, record.Payload.EPSSpecificParameters.PDNAddressAllocation)
+	assert.Equal(t, []byte("IMSI001010000000001"), getTargetIdentity(record.Payload.PartyInformation).IMSI)
+}
+
+func TestMakeActiveBearerRecord(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.BearerActive,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":       "IMSI001010000000001",
+			"session_id": "IMSI001010000000001-919642",
+			"ip_addr":    "192.168.128.12",
+			"apn":        "magma.ipv4",
+			"qci":        "9",
+		},
+	}
+
+	// bearers active when interception starts begin their interception
+	b, err := MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	assert.Equal(t, RecordClassBegin, GetRecordClass(b))
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, StartInterceptWithActiveBearer, record.Payload.EPSEvent)
+	params := record.Payload.EPSSpecificParameters
+	assert.Equal(t, []byte{byte(IPV4Type), 192, 168, 128, 12}, params.PDNAddressAllocation)
+	assert.Equal(t, []byte("magma.ipv4"), params.APN)
+	assert.NotEmpty(t, params.EPSBearerIdentity)
+
+	_, err = MakeRecordWithClass(&event, task, 49002, 2, RecordClassContinue)
+	assert.EqualError(t, err, "invalid event_type: Record class continue does not apply to event type bearer_active")
+
+	// and start the interception of the PDP contexts in the UMTS domain
+	task.TaskDetails.IriDomain = IRIDomainUMTS
+	b, err = MakeRecord(&event, task, 49002, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	assert.Equal(t, RecordClassBegin, GetRecordClass(b))
+	var umtsRecord UmtsIRIRecord
+	assert.NoError(t, umtsRecord.Decode(b))
+	assert.Equal(t, StartInterceptWithPDPContextActive, umtsRecord.Payload.GPRSEvent)
+}
+
+func TestMakeUsageReportRecord(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:            "IMSI001010000000001",
+			TargetType:          models.NetworkProbeTaskDetailsTargetTypeImsi,
+			DeliveryCountryCode: "FR",
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.UsageReported,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":          "IMSI001010000000001",
+			"session_id":    "IMSI001010000000001-919642",
+			"bytes_tx":      "1200",
+			"bytes_rx":      "34000",
+			"duration_secs": "900",
+		},
+	}
+	b, err := MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	assert.Equal(t, RecordClassContinue, GetRecordClass(b))
+
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, BearerModification, record.Payload.EPSEvent)
+	assert.Equal(t, NationalParameters{
+		CountryCode: "FR",
+		UsageReport: UsageReport{UplinkVolume: 1200, DownlinkVolume: 34000, Duration: 900},
+	}, record.Payload.NationalParameters)
+
+	rendered, err := ToJSON(&record)
+	assert.NoError(t, err)
+	assert.Contains(t, string(rendered), `"usage_report":{"country_code":"FR","uplink_volume":1200,"downlink_volume":34000,"duration":900}`)
+
+	// usage reports are qualified by the delivery country code
+	task.TaskDetails.DeliveryCountryCode = ""
+	_, err = MakeRecord(&event, task, 49002, 2)
+	assert.Equal(t, FieldTaskDetails, GetErrorField(err))
+
+	task.TaskDetails.DeliveryCountryCode = "FR"
+	event.Value.(map[string]interface{})["bytes_rx"] = "-1"
+	_, err = MakeRecord(&event, task, 49002, 2)
+	assert.Equal(t, FieldUsage, GetErrorField(err))
+}
+
+func TestMakeEPSEventRecords(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	tests := []struct {
+		eventType string
+		value     map[string]interface{}
+		eventID   asn1.Enumerated
+		class     string
+		check     func(r *EpsIRIRecord)
+	}{
+		{
+			eventType: nprobe.PDNConnectivityRequested,
+			value: map[string]interface{}{
+				"session_id":    "IMSI001010000000001-919642",
+				"apn":           "magma.ipv4",
+				"pdn_type":      "ipv4v6",
+				"request_type":  "initial",
+				"failure_cause": "27",
+			},
+			eventID: UERequestedPDNConnectivity,
+			class:   RecordClassReport,
+			check: func(r *EpsIRIRecord) {
+				params := r.Payload.EPSSpecificParameters
+				assert.Equal(t, []byte("magma.ipv4"), params.APN)
+				assert.Equal(t, []byte{PDNTypeIPv4v6}, params.PDNType)
+				assert.Equal(t, []byte{RequestTypeInitial}, params.RequestType)
+				assert.Equal(t, []byte{27}, params.UEReqPDNConnFailReason)
+				assert.Nil(t, params.PDNAddressAllocation)
+			},
+		},
+		{
+			eventType: nprobe.PDNDisconnectionRequested,
+			value:     map[string]interface{}{"linked_bearer_id": "5"},
+			eventID:   UERequestedPDNDisconnection,
+			class:     RecordClassReport,
+			check: func(r *EpsIRIRecord) {
+				assert.Equal(t, []byte{5}, r.Payload.EPSSpecificParameters.LinkedEPSBearerID)
+			},
+		},
+		{
+			eventType: nprobe.SessionCreated,
+			value: map[string]interface{}{
+				"session_id":       "IMSI001010000000001-919642",
+				"bearer_id":        "6",
+				"linked_bearer_id": "5",
+			},
+			eventID: BearerActivation,
+			class:   RecordClassBegin,
+			check: func(r *EpsIRIRecord) {
+				params := r.Payload.EPSSpecificParameters
+				assert.Equal(t, []byte{6}, params.EPSBearerIdentity)
+				assert.Equal(t, []byte{5}, params.LinkedEPSBearerID)
+				assert.Equal(t, []byte("IMSI001010000000001-919642"), params.BearerSessionID)
+			},
+		},
+		{
+			eventType: nprobe.TrackingAreaUpdate,
+			value:     map[string]interface{}{"user_location": "TAI:00101-2", "failure_cause": "9"},
+			eventID:   LocationUpdate,
+			class:     RecordClassReport,
+			check: func(r *EpsIRIRecord) {
+				params := r.Payload.EPSSpecificParameters
+				assert.Equal(t, []byte("TAI:00101-2"), params.EPSLocationOfTheTarget.UserLocationInfo)
+				assert.Equal(t, []byte{9}, params.FailedTAUReason)
+			},
+		},
+		{
+			eventType: nprobe.ServingSystemChanged,
+			value:     map[string]interface{}{"mme_address": "10.0.2.1"},
+			eventID:   ServingEvolvedPacketSystem,
+			class:     RecordClassReport,
+			check: func(r *EpsIRIRecord) {
+				assert.Equal(t, []byte{10, 0, 2, 1}, r.Payload.EPSSpecificParameters.ServingMMEAddress)
+			},
+		},
+		{
+			eventType: nprobe.HandoverSuccess,
+			value: map[string]interface{}{
+				"session_id":    "IMSI001010000000001-919642",
+				"user_location": "TAI:00101-3",
+			},
+			eventID: BearerModification,
+			class:   RecordClassContinue,
+			check: func(r *EpsIRIRecord) {
+				assert.Equal(t, []byte{}, r.Payload.EPSSpecificParameters.HandoverIndication)
+			},
+		},
+		{
+			eventType: nprobe.SMSTransferred,
+			value: map[string]interface{}{
+				"sms_initiator":       "target",
+				"sms_transfer_status": "succeeded",
+				"sms_content":         "0011000b916407281553f80000aa0ae8329bfd4697d9ec37",
+			},
+			eventID: SMS,
+			class:   RecordClassReport,
+			check: func(r *EpsIRIRecord) {
+				contents := r.Payload.SMS.SMSContents
+				assert.Equal(t, SMSInitiatorTarget, contents.Initiator)
+				assert.Equal(t, SMSTransferSucceeded, contents.TransferStatus)
+				assert.Equal(t, SMSOtherMessageUndefined, contents.OtherMessage)
+				assert.Len(t, contents.Content, 24)
+				assert.True(t, isEmptyParams(&r.Payload.EPSSpecificParameters))
+			},
+		},
+	}
+	for _, test := range tests {
+		test.value["imsi"] = "IMSI001010000000001"
+		event := eventdM.Event{
+			EventType:  test.eventType,
+			StreamName: nprobe.ESStreamMME,
+			Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+			Value:      test.value,
+		}
+		b, err := MakeRecordWithClass(&event, task, 49002, 1, test.class)
+		assert.NoError(t, err, test.eventType)
+		assert.NoError(t, Validate(b), test.eventType)
+
+		var record EpsIRIRecord
+		assert.NoError(t, record.Decode(b), test.eventType)
+		assert.Equal(t, test.eventID, record.Payload.EPSEvent, test.eventType)
+		test.check(&record)
+	}
+}
+
+func TestMakeEPSEventRecordErrorField(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	invalid := []struct {
+		eventType string
+		key       string
+		value     string
+		field     string
+	}{
+		{nprobe.PDNConnectivityRequested, "pdn_type", "ipv5", FieldBearerParams},
+		{nprobe.PDNConnectivityRequested, "failure_cause", "256", FieldBearerParams},
+		{nprobe.ServingSystemChanged, "mme_address", "mme.local", FieldBearerParams},
+		{nprobe.SMSTransferred, "sms_initiator", "nobody", FieldSMS},
+		{nprobe.SMSTransferred, "sms_content", "zz", FieldSMS},
+		{nprobe.SessionCreated, "bearer_id", "16", FieldBearerParams},
+		{nprobe.SessionCreated, "linked_bearer_id", "x", FieldBearerParams},
+		{nprobe.SessionCreated, "qci", "300", FieldBearerParams},
+		{nprobe.SessionCreated, "apn_ambr_dl", "fast", FieldBearerParams},
+	}
+	for _, test := range invalid {
+		event := eventdM.Event{
+			EventType:  test.eventType,
+			StreamName: nprobe.ESStreamMME,
+			Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+			Value:      map[string]interface{}{"imsi": "IMSI001010000000001", test.key: test.value},
+		}
+		_, err := MakeRecord(&event, task, 49002, 1)
+		assert.Equal(t, test.field, GetErrorField(err), test.key)
+	}
+}
+
+func TestMakeRecordDualStack(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.SessionCreated,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":        "IMSI001010000000001",
+			"session_id":  "IMSI001010000000001-919642",
+			"ip_addr":     "192.168.128.12",
+			"ipv6_prefix": "2001:db8:1:2::/64",
+			"spgw_ip":     "2001:db8::10",
+		},
+	}
+	ipv6Prefix := []byte{0x20, 0x01, 0x0d, 0xb8, 0, 1, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	b, err := MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+
+	// dual-stack bearers are allocated both addresses
+	expected := append([]byte{byte(IPV4V6Type), 192, 168, 128, 12}, ipv6Prefix...)
+	assert.Equal(t, expected, record.Payload.EPSSpecificParameters.PDNAddressAllocation)
+	params := getTargetParty(record.Payload.PartyInformation).ServicesDataInformation.GPRSParameters
+	assert.Equal(t, makeDataNodeAddress(IPV4Type, []byte{192, 168, 128, 12}, 0), params.PDPAddress)
+	assert.Equal(t, makeDataNodeAddress(IPV6Type, ipv6Prefix, 64), params.AdditionalIPAddress)
+	assert.Equal(t, IPV6Type, record.Payload.NetworkIdentifier.NetworkElementIdentifier.IPAddress.IPType)
+
+	// IPv6 only bearers
+	delete(event.Value.(map[string]interface{}), "ip_addr")
+	b, err = MakeRecord(&event, task, 49002, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, append([]byte{byte(IPV6Type)}, ipv6Prefix...), record.Payload.EPSSpecificParameters.PDNAddressAllocation)
+	params = getTargetParty(record.Payload.PartyInformation).ServicesDataInformation.GPRSParameters
+	assert.Equal(t, makeDataNodeAddress(IPV6Type, ipv6Prefix, 64), params.PDPAddress)
+	assert.Equal(t, DataNodeAddress{}, params.AdditionalIPAddress)
+
+	event.Value.(map[string]interface{})["ipv6_prefix"] = "10.0.0.0/8"
+	_, err = MakeRecord(&event, task, 49002, 3)
+	assert.EqualError(t, err, `invalid bearer_params: ipv6_prefix is not a valid IPv6 prefix: "10.0.0.0/8"`)
+}
+
+func TestMakeRecordBearerQoS(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.SessionCreated,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":        "IMSI001010000000001",
+			"session_id":  "IMSI001010000000001-919642",
+			"qci":         "9",
+			"apn_ambr_ul": "100000000",
+			"apn_ambr_dl": "200000000",
+		},
+	}
+
+	b, err := MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	params := record.Payload.EPSSpecificParameters
+	assert.Equal(t, []byte{9}, params.EPSBearerQoS)
+	// rates beyond 8640 kbps are coded in the extended octets
+	assert.Equal(t, []byte{0xfe, 0xfe, 0xde, 0x9e}, params.ApnAmbr)
+
+	event.Value.(map[string]interface{})["apn_ambr_ul"] = "64000"
+	event.Value.(map[string]interface{})["apn_ambr_dl"] = "1000000"
+	b, err = MakeRecord(&event, task, 49002, 2)
+	assert.NoError(t, err)
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, []byte{0x86, 0x40}, record.Payload.EPSSpecificParameters.ApnAmbr)
+
+	// the APN-AMBR is left out unless both directions are known
+	delete(event.Value.(map[string]interface{}), "apn_ambr_ul")
+	b, err = MakeRecord(&event, task, 49002, 3)
+	assert.NoError(t, err)
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	assert.Nil(t, record.Payload.EPSSpecificParameters.ApnAmbr)
+}
+
+func TestMakeRecordBearerModification(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	eventData := map[string]interface{}{
+		"imsi":                         "IMSI001010000000001",
+		"session_id":                   "IMSI001010000000001-919642",
+		"qci":                          "1",
+		"mbr_ul":                       "64000",
+		"mbr_dl":                       "128000",
+		"gbr_ul":                       "32000",
+		"gbr_dl":                       "64000",
+		"apn_ambr_ul":                  "64000",
+		"apn_ambr_dl":                  "1000000",
+		"arp_priority_level":           "2",
+		"arp_preemption_capability":    "true",
+		"arp_preemption_vulnerability": "false",
+		"previous_qci":                 "9",
+		"previous_apn_ambr_ul":         "64000",
+		"previous_apn_ambr_dl":         "1000000",
+		"previous_arp_priority_level":  "2",
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.SessionCreated,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value:      eventData,
+	}
+
+	// activations carry the bit rates of GBR bearers and their ARP, but
+	// nothing of a modification
+	b, err := MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	params := record.Payload.EPSSpecificParameters
+	assert.Equal(t, []byte{1, 0x40, 0x48, 0x20, 0x40}, params.EPSBearerQoS)
+	assert.Equal(t, []byte{0x09}, params.BearerARP)
+	assert.Nil(t, params.PreviousEPSBearerQoS)
+	assert.Nil(t, params.PreviousApnAmbr)
+	assert.Nil(t, params.PreviousBearerARP)
+
+	// modifications carry the parameters changed along with their value
+	// before the modification
+	event.EventType = nprobe.SessionUpdated
+	b, err = MakeRecord(&event, task, 49002, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	params = record.Payload.EPSSpecificParameters
+	assert.Equal(t, BearerModification, record.Payload.EPSEvent)
+	assert.Equal(t, []byte{1, 0x40, 0x48, 0x20, 0x40}, params.EPSBearerQoS)
+	assert.Equal(t, []byte{9}, params.PreviousEPSBearerQoS)
+	assert.Equal(t, []byte{0x86, 0x40}, params.ApnAmbr)
+	assert.Nil(t, params.PreviousApnAmbr)
+	assert.Equal(t, []byte{0x09}, params.BearerARP)
+	assert.Equal(t, []byte{0x49}, params.PreviousBearerARP)
+
+	// rates beyond 8640 kbps are coded in the extended octets
+	eventData["mbr_dl"] = "20000000"
+	b, err = MakeRecord(&event, task, 49002, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, []byte{1, 0x40, 0xfe, 0x20, 0x40, 0, 0x4e, 0, 0}, record.Payload.EPSSpecificParameters.EPSBearerQoS)
+
+	eventData["arp_priority_level"] = "0"
+	_, err = MakeRecord(&event, task, 49002, 4)
+	assert.EqualError(t, err, `invalid bearer_params: arp_priority_level is not a valid ARP priority level: "0"`)
+	eventData["arp_priority_level"] = "2"
+	eventData["previous_arp_preemption_capability"] = "yes"
+	_, err = MakeRecord(&event, task, 49002, 4)
+	assert.EqualError(t, err, `invalid bearer_params: previous_arp_preemption_capability is not a boolean: "yes"`)
+}
+
+func getTargetParty(parties []PartyInformation) *PartyInformation {
+	for i := range parties {
+		if parties[i].PartyQualified == PartyQualifierTarget {
+			return &parties[i]
+		}
+	}
+	return nil
+}
+
+// The benchmarks cover the encoding of a record for every event delivered,
+// at 10k records/s, and its decoding by the tools reading records back, e.g.
+//   go test -run NONE -bench . -benchmem ./encoding
+
+func BenchmarkEpsIRIRecordEncode(b *testing.B) {
+	var record EpsIRIRecord
+	if err := record.Decode(encodedRecord); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(encodedRecord)))
+	for i := 0; i < b.N; i++ {
+		if _, err := record.Encode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEpsIRIRecordDecode(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(encodedRecord)))
+	for i := 0; i < b.N; i++ {
+		var record EpsIRIRecord
+		if err := record.Decode(encodedRecord); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMakeRecord(b *testing.B) {
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:               "IMSI001010000000001",
+			TargetType:             "imsi",
+			DeliveryType:           "events_only",
+			CorrelationID:          0x866cb397915ffe4,
+			AuthorizationReference: "LIID-0042",
+			DeliveryCountryCode:    "FR",
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.SessionCreated,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":                    "IMSI001010000000001",
+			"session_id":              "IMSI001010000000001-919642",
+			"apn":                     "magma.ipv4",
+			"ip_addr":                 "192.168.128.12",
+			"correspondent_msisdn":    "33687654321",
+			"correspondent_qualifier": "terminating",
+		},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := MakeRecord(&event, task, 49002, uint32(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
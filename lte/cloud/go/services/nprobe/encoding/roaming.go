@@ -0,0 +1,138 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"strings"
+
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// maxServingNetworkLength bounds the realm of the serving MME
+const maxServingNetworkLength = 255
+
+// RoamingFields are the event data keys of the network serving the target,
+// as reported by the S6a update location: the MCC and MNC digits of the
+// visited PLMN, the realm of the serving MME and whether the target roams
+var RoamingFields = []string{"visited_plmn_id", "serving_network", "roaming"}
+
+// HasRoaming returns true if an event reports the network serving the target
+func HasRoaming(event *eventdM.Event) bool {
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = eventData["visited_plmn_id"]
+	return ok
+}
+
+// IsRoaming returns true if an IMSI, prefixed by IMSI or not, is not one of
+// the home subscribers of the PLMN of the digits of an MCC and MNC
+func IsRoaming(imsi, mccMnc string) bool {
+	mccMnc = strings.TrimRight(mccMnc, "fF")
+	return !strings.HasPrefix(strings.TrimPrefix(imsi, "IMSI"), mccMnc)
+}
+
+// MakePLMNID returns the PLMN ID of the digits of an MCC and MNC, coded as
+// in TS 24.008, nil if they aren't 5 or 6 digits. The MNCs of 2 digits may
+// be padded with an F.
+func MakePLMNID(mccMnc string) []byte {
+	if len(mccMnc) == 6 && (mccMnc[5] == 'f' || mccMnc[5] == 'F') {
+		mccMnc = mccMnc[:5]
+	}
+	if len(mccMnc) != 5 && len(mccMnc) != 6 {
+		return nil
+	}
+	digits := make([]byte, 6)
+	digits[5] = 0xf
+	for i := range mccMnc {
+		if mccMnc[i] < '0' || mccMnc[i] > '9' {
+			return nil
+		}
+		digits[i] = mccMnc[i] - '0'
+	}
+	return []byte{
+		digits[1]<<4 | digits[0],
+		digits[5]<<4 | digits[2],
+		digits[4]<<4 | digits[3],
+	}
+}
+
+// formatPLMNID returns the MCC and MNC digits of a PLMN ID coded as in TS
+// 24.008, its hex encoding if it isn't
+func formatPLMNID(b []byte) string {
+	if len(b) != 3 {
+		return formatText(b)
+	}
+	digits := []byte{b[0] & 0xf, b[0] >> 4, b[1] & 0xf, b[2] & 0xf, b[2] >> 4, b[1] >> 4}
+	var ret strings.Builder
+	for i, digit := range digits {
+		if i == 5 && digit == 0xf {
+			break
+		}
+		if digit > 9 {
+			return formatText(b)
+		}
+		ret.WriteByte('0' + digit)
+	}
+	return ret.String()
+}
+
+// addRoamingParams adds the network serving the target reported by an event
+// to the EPS specific parameters of its record. The roaming status is the
+// one reported, given by the IMSI of the target otherwise.
+func addRoamingParams(params *EPSSpecificParameters, event *eventdM.Event) {
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	mccMnc, _ := eventData["visited_plmn_id"].(string)
+	plmnID := MakePLMNID(mccMnc)
+	if plmnID == nil {
+		return
+	}
+	params.VisitedPLMNID = plmnID
+	if servingNetwork, _ := eventData["serving_network"].(string); len(servingNetwork) != 0 {
+		params.ServingNetwork = []byte(servingNetwork)
+	}
+	roaming, ok := eventData["roaming"].(bool)
+	if !ok {
+		imsi, _ := eventData["imsi"].(string)
+		if len(imsi) == 0 {
+			return
+		}
+		roaming = IsRoaming(imsi, mccMnc)
+	}
+	params.RoamingStatus = RoamingStatusHome
+	if roaming {
+		params.RoamingStatus = RoamingStatusRoaming
+	}
+}
+
+// validateRoamingParams checks the network serving the target, which the
+// records of all the EPS events may carry
+func validateRoamingParams(params *EPSSpecificParameters) error {
+	if params.VisitedPLMNID != nil && len(params.VisitedPLMNID) != 3 {
+		return newValidationError(FieldBearerParams, "invalid visited PLMN ID length %d", len(params.VisitedPLMNID))
+	}
+	if params.ServingNetwork != nil && (len(params.ServingNetwork) == 0 || len(params.ServingNetwork) > maxServingNetworkLength) {
+		return newValidationError(FieldBearerParams, "invalid serving network length %d", len(params.ServingNetwork))
+	}
+	switch params.RoamingStatus {
+	case 0, RoamingStatusHome, RoamingStatusRoaming:
+	default:
+		return newValidationError(FieldBearerParams, "unknown roaming status %d", params.RoamingStatus)
+	}
+	return nil
+}
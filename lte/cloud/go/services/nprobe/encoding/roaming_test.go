@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakePLMNID(t *testing.T) {
+	assert.Equal(t, []byte{0x00, 0xf1, 0x10}, MakePLMNID("00101"))
+	assert.Equal(t, []byte{0x00, 0xf1, 0x10}, MakePLMNID("00101f"))
+	assert.Equal(t, []byte{0x13, 0x62, 0x54}, MakePLMNID("312456"))
+	assert.Nil(t, MakePLMNID("0010"))
+	assert.Nil(t, MakePLMNID("00a01"))
+
+	assert.Equal(t, "00101", formatPLMNID([]byte{0x00, 0xf1, 0x10}))
+	assert.Equal(t, "312456", formatPLMNID([]byte{0x13, 0x62, 0x54}))
+	assert.Equal(t, "0aff10", formatPLMNID([]byte{0x0a, 0xff, 0x10}))
+
+	assert.False(t, IsRoaming("IMSI001010000000001", "00101"))
+	assert.False(t, IsRoaming("001010000000001", "00101F"))
+	assert.True(t, IsRoaming("IMSI001010000000001", "20801"))
+	assert.True(t, IsRoaming("IMSI312456000000001", "312457"))
+}
+
+func TestRoamingParams(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	event := &eventdM.Event{
+		EventType:  nprobe.UpdateLocationSuccess,
+		StreamName: nprobe.ESStreamMME,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":            "IMSI001010000000001",
+			"visited_plmn_id": "20801",
+			"serving_network": "epc.mnc001.mcc208.3gppnetwork.org",
+		},
+	}
+	getRecord := func(event *eventdM.Event) *EpsIRIRecord {
+		b, err := MakeRecord(event, task, 49002, 1)
+		assert.NoError(t, err)
+		assert.NoError(t, Validate(b))
+		record := &EpsIRIRecord{}
+		assert.NoError(t, record.Decode(b))
+		return record
+	}
+	getParams := func(event *eventdM.Event) EPSSpecificParameters {
+		return getRecord(event).Payload.EPSSpecificParameters
+	}
+
+	// the update location reports the serving system of the target, roaming
+	// as its IMSI isn't one of the visited PLMN
+	assert.Equal(t, ServingEvolvedPacketSystem, getEPSEventID(event.EventType))
+	params := getParams(event)
+	assert.Equal(t, []byte{0x02, 0xf8, 0x10}, params.VisitedPLMNID)
+	assert.Equal(t, []byte("epc.mnc001.mcc208.3gppnetwork.org"), params.ServingNetwork)
+	assert.Equal(t, RoamingStatusRoaming, params.RoamingStatus)
+	record, err := ToJSON(getRecord(event))
+	assert.NoError(t, err)
+	assert.Contains(t, string(record), `"visited_plmn_id":"20801"`)
+	assert.Contains(t, string(record), `"roaming_status":2`)
+
+	// the records of the other events carry it as well, the status reported
+	// prevailing
+	event.EventType = nprobe.AttachSuccess
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "visited_plmn_id": "00101"}
+	params = getParams(event)
+	assert.Equal(t, []byte{0x00, 0xf1, 0x10}, params.VisitedPLMNID)
+	assert.Nil(t, params.ServingNetwork)
+	assert.Equal(t, RoamingStatusHome, params.RoamingStatus)
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "visited_plmn_id": "00101", "roaming": true}
+	assert.Equal(t, RoamingStatusRoaming, getParams(event).RoamingStatus)
+
+	// invalid visited PLMNs are left out
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "visited_plmn_id": "001"}
+	assert.True(t, isZeroEPSSpecificParameters(&EPSSpecificParameters{}))
+	params = getParams(event)
+	assert.True(t, isZeroEPSSpecificParameters(&params))
+
+	assert.EqualError(t, validateRoamingParams(&EPSSpecificParameters{VisitedPLMNID: []byte{0x00}}), "malformed bearer_params: invalid visited PLMN ID length 1")
+	assert.EqualError(t, validateRoamingParams(&EPSSpecificParameters{RoamingStatus: 3}), "malformed bearer_params: unknown roaming status 3")
+}
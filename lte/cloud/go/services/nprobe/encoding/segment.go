@@ -0,0 +1,205 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// Records whose PDU exceeds the size limit of a LEMF are split into
+// segments, each carrying a slice of the payload of the record after a copy
+// of its header. The segment indication of a segment, a proprietary
+// conditional attribute appended to its header, gives its index, from 1, and
+// the number of segments of the record, so that the LEMF reassembles the
+// record as built, signature included, once its last segment is received.
+// The segments of a record are written back to back on the connection.
+
+// segmentPrefix prefixes the value of the segment indication, followed by
+// the index of the segment and the number of segments, e.g. segment=2/3
+const segmentPrefix = "segment="
+
+// MaxReassembledRecordSize bounds the size of the records reassembled
+var MaxReassembledRecordSize = 16 * DefaultMaxRecordSize
+
+// SegmentRecord splits an encoded record whose size exceeds maxSize into
+// segments of at most maxSize bytes. It returns the record itself if it
+// fits or if maxSize is 0.
+func SegmentRecord(record []byte, maxSize int) ([][]byte, error) {
+	if maxSize <= 0 || len(record) <= maxSize {
+		return [][]byte{record}, nil
+	}
+	hdr, err := ParseHeader(record)
+	if err != nil {
+		return nil, err
+	}
+	if GetSegmentCount(hdr) != 0 {
+		return nil, errors.New("record is already a segment")
+	}
+	payload := record[hdr.HeaderLength : hdr.HeaderLength+hdr.PayloadLength]
+
+	// the segment indication of the last segment is the longest
+	longest := NewAttribute(AttributeProprietary, []byte(formatSegment(len(payload), len(payload))))
+	segmentHdrLen := int(hdr.HeaderLength) + int(longest.Len) + 4
+	if segmentHdrLen > int(MaxHeaderLength) {
+		return nil, fmt.Errorf("segment header length %d exceeds %d", segmentHdrLen, MaxHeaderLength)
+	}
+	if segmentHdrLen >= maxSize {
+		return nil, fmt.Errorf("maximum record size %d leaves no room for the payload of segments", maxSize)
+	}
+	chunkSize := maxSize - segmentHdrLen
+	count := (len(payload) + chunkSize - 1) / chunkSize
+
+	segments := make([][]byte, 0, count)
+	for index := 1; len(payload) != 0; index++ {
+		chunk := payload
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		payload = payload[len(chunk):]
+		attr := NewAttribute(AttributeProprietary, []byte(formatSegment(index, count)))
+		segment := appendAttribute(record[:hdr.HeaderLength], hdr.HeaderLength, attr)
+		binary.BigEndian.PutUint32(segment[8:12], uint32(len(chunk)))
+		segments = append(segments, append(segment, chunk...))
+	}
+	return segments, nil
+}
+
+// GetSegmentCount returns the number of segments of the record a segment is
+// part of, 0 for the records that aren't segmented
+func GetSegmentCount(hdr *EpsIRIHeader) int {
+	_, count, _ := getSegment(hdr)
+	return count
+}
+
+// Reassembler reassembles the records segmented by SegmentRecord, as read
+// from a single connection. The segments of a record being written back to
+// back, a single record is reassembled at a time.
+type Reassembler struct {
+	key      segmentKey
+	count    int
+	received int
+	// record is the header of the record being reassembled, without
+	// segment indication, followed by the payload of its segments received
+	record []byte
+}
+
+// segmentKey identifies the record a segment is part of
+type segmentKey struct {
+	xid    uuid.UUID
+	corrID uint64
+	seqNbr uint32
+}
+
+// Add adds a PDU read from the connection. It returns the PDU itself if it
+// isn't a segment, the record once its last segment is added, and nil
+// otherwise. Segments out of order fail, dropping the record being
+// reassembled.
+func (r *Reassembler) Add(pdu []byte) ([]byte, error) {
+	hdr, err := ParseHeader(pdu)
+	if err != nil {
+		return nil, err
+	}
+	index, count, err := getSegment(hdr)
+	if err != nil {
+		r.reset()
+		return nil, err
+	}
+	if count == 0 {
+		if r.count != 0 {
+			r.reset()
+			return nil, errors.New("record received before the last segment of the record being reassembled")
+		}
+		return pdu, nil
+	}
+
+	seqNbr, _ := GetSequenceNumber(hdr)
+	key := segmentKey{xid: hdr.XID, corrID: hdr.CorrelationID, seqNbr: seqNbr}
+	payload := pdu[hdr.HeaderLength : hdr.HeaderLength+hdr.PayloadLength]
+	if index == 1 {
+		if r.count != 0 {
+			r.reset()
+			return nil, errors.New("segment 1 received before the last segment of the record being reassembled")
+		}
+		// the segment indication is the last attribute of the header
+		attrs := hdr.ConditionalAttributes
+		hdrLen := hdr.HeaderLength - uint32(attrs[len(attrs)-1].Len) - 4
+		size := int(hdrLen) + len(payload)*count
+		if size > MaxReassembledRecordSize {
+			size = MaxReassembledRecordSize
+		}
+		r.record = append(make([]byte, 0, size), pdu[:hdrLen]...)
+		binary.BigEndian.PutUint32(r.record[4:8], hdrLen)
+		r.key, r.count = key, count
+	} else if r.count == 0 || key != r.key || count != r.count || index != r.received+1 {
+		r.reset()
+		return nil, fmt.Errorf("unexpected segment %d/%d", index, count)
+	}
+	if len(r.record)+len(payload) > MaxReassembledRecordSize {
+		r.reset()
+		return nil, ErrRecordTooLarge
+	}
+	r.record = append(r.record, payload...)
+	r.received = index
+	if r.received < r.count {
+		return nil, nil
+	}
+
+	record := r.record
+	hdrLen := binary.BigEndian.Uint32(record[4:8])
+	binary.BigEndian.PutUint32(record[8:12], uint32(len(record))-hdrLen)
+	r.reset()
+	return record, nil
+}
+
+func (r *Reassembler) reset() {
+	*r = Reassembler{}
+}
+
+// getSegment returns the index of a segment and the number of segments of
+// its record, 0 for the records that aren't segmented
+func getSegment(hdr *EpsIRIHeader) (int, int, error) {
+	attrs := hdr.ConditionalAttributes
+	if len(attrs) == 0 {
+		return 0, 0, nil
+	}
+	last := attrs[len(attrs)-1]
+	if last.Tag != AttributeProprietary || !bytes.HasPrefix(last.Value, []byte(segmentPrefix)) {
+		return 0, 0, nil
+	}
+	value := string(bytes.TrimPrefix(last.Value, []byte(segmentPrefix)))
+	fields := strings.Split(value, "/")
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("invalid segment indication %s", value)
+	}
+	index, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid segment indication %s", value)
+	}
+	count, err := strconv.Atoi(fields[1])
+	if err != nil || index < 1 || count < 2 || index > count {
+		return 0, 0, fmt.Errorf("invalid segment indication %s", value)
+	}
+	return index, count, nil
+}
+
+func formatSegment(index, count int) string {
+	return segmentPrefix + strconv.Itoa(index) + "/" + strconv.Itoa(count)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/signing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmentRecord(t *testing.T) {
+	// records within the limit are left whole
+	segments, err := SegmentRecord(encodedRecord, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{encodedRecord}, segments)
+	segments, err = SegmentRecord(encodedRecord, len(encodedRecord))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{encodedRecord}, segments)
+
+	hdr, err := ParseHeader(encodedRecord)
+	assert.NoError(t, err)
+	maxSize := int(hdr.HeaderLength) + 32
+	segments, err = SegmentRecord(encodedRecord, maxSize)
+	assert.NoError(t, err)
+	assert.True(t, len(segments) > 1)
+	var payload []byte
+	for i, segment := range segments {
+		assert.True(t, len(segment) <= maxSize)
+		segmentHdr, err := ParseHeader(segment)
+		assert.NoError(t, err)
+		// each segment carries the header of the record
+		assert.Equal(t, hdr.XID, segmentHdr.XID)
+		assert.Equal(t, hdr.CorrelationID, segmentHdr.CorrelationID)
+		seqNbr, _ := GetSequenceNumber(segmentHdr)
+		assert.Equal(t, uint32(6), seqNbr)
+		assert.Equal(t, len(segments), GetSegmentCount(segmentHdr))
+		index, _, err := getSegment(segmentHdr)
+		assert.NoError(t, err)
+		assert.Equal(t, i+1, index)
+		payload = append(payload, segment[segmentHdr.HeaderLength:]...)
+	}
+	assert.Equal(t, encodedRecord[hdr.HeaderLength:], payload)
+	assert.Equal(t, 0, GetSegmentCount(hdr))
+
+	_, err = SegmentRecord(segments[0], int(hdr.HeaderLength))
+	assert.EqualError(t, err, "record is already a segment")
+	_, err = SegmentRecord(encodedRecord, int(hdr.HeaderLength))
+	assert.EqualError(t, err, fmt.Sprintf("maximum record size %d leaves no room for the payload of segments", hdr.HeaderLength))
+}
+
+func TestReassembler(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	signer, err := signing.NewSigner(key)
+	assert.NoError(t, err)
+	signed, err := SignRecord(encodedRecord, signer)
+	assert.NoError(t, err)
+	hdr, err := ParseHeader(signed)
+	assert.NoError(t, err)
+	segments, err := SegmentRecord(signed, int(hdr.HeaderLength)+32)
+	assert.NoError(t, err)
+
+	// the record is reassembled as signed once its last segment is added
+	var reassembler Reassembler
+	for _, segment := range segments[:len(segments)-1] {
+		record, err := reassembler.Add(segment)
+		assert.NoError(t, err)
+		assert.Nil(t, record)
+	}
+	record, err := reassembler.Add(segments[len(segments)-1])
+	assert.NoError(t, err)
+	assert.Equal(t, signed, record)
+	assert.NoError(t, VerifyRecord(record, func(digest, signature []byte) bool {
+		return signing.Verify(&key.PublicKey, digest, signature)
+	}))
+
+	// records that aren't segmented are returned as is
+	record, err = reassembler.Add(encodedRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, encodedRecord, record)
+
+	// segments out of order drop the record being reassembled
+	_, err = reassembler.Add(segments[0])
+	assert.NoError(t, err)
+	_, err = reassembler.Add(segments[2])
+	assert.EqualError(t, err, fmt.Sprintf("unexpected segment 3/%d", len(segments)))
+	_, err = reassembler.Add(segments[1])
+	assert.EqualError(t, err, fmt.Sprintf("unexpected segment 2/%d", len(segments)))
+	_, err = reassembler.Add(segments[0])
+	assert.NoError(t, err)
+	_, err = reassembler.Add(encodedRecord)
+	assert.EqualError(t, err, "record received before the last segment of the record being reassembled")
+
+	// records are reassembled within the size limit
+	previous := MaxReassembledRecordSize
+	defer func() { MaxReassembledRecordSize = previous }()
+	MaxReassembledRecordSize = len(signed) - 1
+	for _, segment := range segments[:len(segments)-1] {
+		_, err = reassembler.Add(segment)
+		assert.NoError(t, err)
+	}
+	_, err = reassembler.Add(segments[len(segments)-1])
+	assert.Equal(t, ErrRecordTooLarge, err)
+}
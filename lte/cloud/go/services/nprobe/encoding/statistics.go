@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// statisticsPrefix prefixes the value of the statistics indication,
+// followed by the statistics of the interval it reports
+const statisticsPrefix = "statistics="
+
+// statisticsFormat formats the statistics of an interval: its start and end
+// in RFC3339 format, then the number of records and bytes delivered in it
+const statisticsFormat = "start=%s,end=%s,records=%d,bytes=%d"
+
+// Statistics are the records of the events of a target delivered over an
+// interval, and their bytes
+type Statistics struct {
+	Start   time.Time
+	End     time.Time
+	Records uint64
+	Bytes   uint64
+}
+
+// MakeStatisticsRecord builds the IRI-REPORT injected in the stream of a
+// task to report the records delivered for its target over an interval, as
+// some handover agreements require. It reports no event of the target: it is
+// marked by its statistics indication, a proprietary conditional attribute
+// carrying the statistics of the interval, and timestamped at its end.
+func MakeStatisticsRecord(
+	task *models.NetworkProbeTask,
+	operatorID, sequenceNbr uint32,
+	statistics Statistics,
+	version *ModuleVersion,
+) ([]byte, error) {
+	report := &eventdM.Event{
+		EventType:  nprobe.TargetReported,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  statistics.End.UTC().Format(time.RFC3339Nano),
+		Value:      map[string]interface{}{},
+	}
+	value := fmt.Sprintf(
+		statisticsFormat,
+		statistics.Start.UTC().Format(time.RFC3339), statistics.End.UTC().Format(time.RFC3339), statistics.Records, statistics.Bytes,
+	)
+	return makeVersionedRecord(
+		report, task, operatorID, sequenceNbr, RecordClassReport, version,
+		[]Attribute{NewAttribute(AttributeProprietary, []byte(statisticsPrefix+value))},
+	)
+}
+
+// GetStatistics returns the statistics reported by a record from its
+// statistics indication, false for the records not reporting statistics
+func GetStatistics(hdr *EpsIRIHeader) (Statistics, bool) {
+	value := getAttribute(hdr, AttributeProprietary)
+	if !bytes.HasPrefix(value, []byte(statisticsPrefix)) {
+		return Statistics{}, false
+	}
+	fields := map[string]string{}
+	for _, field := range strings.Split(string(bytes.TrimPrefix(value, []byte(statisticsPrefix))), ",") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return Statistics{}, false
+		}
+		fields[kv[0]] = kv[1]
+	}
+	var statistics Statistics
+	var errs [4]error
+	statistics.Start, errs[0] = time.Parse(time.RFC3339, fields["start"])
+	statistics.End, errs[1] = time.Parse(time.RFC3339, fields["end"])
+	statistics.Records, errs[2] = strconv.ParseUint(fields["records"], 10, 64)
+	statistics.Bytes, errs[3] = strconv.ParseUint(fields["bytes"], 10, 64)
+	for _, err := range errs {
+		if err != nil {
+			return Statistics{}, false
+		}
+	}
+	return statistics, true
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeStatisticsRecord(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI1234",
+			TargetType:    models.NetworkProbeTaskDetailsTargetTypeImsi,
+			CorrelationID: 42,
+		},
+	}
+	statistics := Statistics{
+		Start:   time.Unix(1615000000, 0).UTC(),
+		End:     time.Unix(1615086400, 0).UTC(),
+		Records: 1200,
+		Bytes:   345678,
+	}
+	record, err := MakeStatisticsRecord(task, 1, 9, statistics, moduleVersions[DefaultModuleVersion])
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(record))
+
+	var report EpsIRIRecord
+	assert.NoError(t, report.Decode(record))
+	assert.Equal(t, RecordClassReport, report.Class)
+	assert.Equal(t, string(task.TaskID), report.Header.XID.String())
+	assert.Equal(t, uint64(42), report.Header.CorrelationID)
+	seqNbr, _ := GetSequenceNumber(&report.Header)
+	assert.Equal(t, uint32(9), seqNbr)
+	reported, ok := GetStatistics(&report.Header)
+	assert.True(t, ok)
+	assert.Equal(t, statistics, reported)
+
+	// statistics records are told apart from test records
+	_, ok = GetTestRecordID(&report.Header)
+	assert.False(t, ok)
+	record, err = MakeTestRecord(task, 1, 10, "test1", statistics.End, moduleVersions[DefaultModuleVersion])
+	assert.NoError(t, err)
+	assert.NoError(t, report.Decode(record))
+	_, ok = GetStatistics(&report.Header)
+	assert.False(t, ok)
+}
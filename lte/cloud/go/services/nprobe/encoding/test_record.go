@@ -0,0 +1,78 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// testRecordPrefix prefixes the value of the test indication, followed by
+// the ID of the test record
+const testRecordPrefix = "test_record="
+
+// MakeTestRecord builds the IRI-REPORT injected in the stream of a task to
+// validate its delivery, e.g. with the LEA when the warrant is activated. It
+// reports no event of the target: it is marked by its test indication, a
+// proprietary conditional attribute carrying the ID of the test record.
+func MakeTestRecord(
+	task *models.NetworkProbeTask,
+	operatorID, sequenceNbr uint32,
+	testID string,
+	requestedAt time.Time,
+	version *ModuleVersion,
+) ([]byte, error) {
+	report := &eventdM.Event{
+		EventType:  nprobe.TargetReported,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  requestedAt.UTC().Format(time.RFC3339Nano),
+		Value:      map[string]interface{}{},
+	}
+	return makeVersionedRecord(
+		report, task, operatorID, sequenceNbr, RecordClassReport, version,
+		[]Attribute{NewAttribute(AttributeProprietary, []byte(testRecordPrefix+testID))},
+	)
+}
+
+// destinationTestTarget is the target ID of the test records delivered to
+// destinations
+const destinationTestTarget = "destination_test"
+
+// MakeDestinationTestRecord builds the test record delivered to a
+// destination to check the path to its LEMF, e.g. before the warrants of the
+// tasks delivered to it are activated. It belongs to no task: its XID is the
+// ID of the test, a UUID, its correlation ID 0 and its target
+// destinationTestTarget, and it identifies no target party. It is marked by
+// its test indication like the test records of the tasks.
+func MakeDestinationTestRecord(operatorID, sequenceNbr uint32, testID string, requestedAt time.Time, version *ModuleVersion) ([]byte, error) {
+	task := &models.NetworkProbeTask{
+		TaskID:      models.NetworkProbeTaskID(testID),
+		TaskDetails: &models.NetworkProbeTaskDetails{TargetID: destinationTestTarget},
+	}
+	return MakeTestRecord(task, operatorID, sequenceNbr, testID, requestedAt, version)
+}
+
+// GetTestRecordID returns the ID of a test record from its test indication,
+// false for the records reporting events
+func GetTestRecordID(hdr *EpsIRIHeader) (string, bool) {
+	value := getAttribute(hdr, AttributeProprietary)
+	if !bytes.HasPrefix(value, []byte(testRecordPrefix)) {
+		return "", false
+	}
+	return string(bytes.TrimPrefix(value, []byte(testRecordPrefix))), true
+}
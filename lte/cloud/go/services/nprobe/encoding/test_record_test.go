@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeTestRecord(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			TargetType:    models.NetworkProbeTaskDetailsTargetTypeImsi,
+			CorrelationID: 42,
+		},
+	}
+	testID := "5b0c3b9e-7d4a-4a4e-9d1e-3f1f2b6f0a11"
+	b, err := MakeTestRecord(task, 1, 12, testID, time.Unix(1615000000, 0), moduleVersions[DefaultModuleVersion])
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, RecordClassReport, record.Class)
+	assert.Equal(t, string(task.TaskID), record.Header.XID.String())
+	assert.Equal(t, uint64(42), record.Header.CorrelationID)
+	seqNbr, _ := GetSequenceNumber(&record.Header)
+	assert.Equal(t, uint32(12), seqNbr)
+	id, ok := GetTestRecordID(&record.Header)
+	assert.True(t, ok)
+	assert.Equal(t, testID, id)
+	// it identifies the target of the task without reporting any event
+	identity := getTargetIdentity(record.Payload.PartyInformation)
+	assert.Equal(t, []byte("IMSI001010000000001"), identity.IMSI)
+	assert.True(t, isEmptyParams(&record.Payload.EPSSpecificParameters))
+
+	js, err := ToJSON(&record)
+	assert.NoError(t, err)
+	assert.Contains(t, string(js), "test_record="+testID)
+
+	// neither event records nor linkage records are test records
+	_, ok = GetTestRecordID(&EpsIRIHeader{})
+	assert.False(t, ok)
+	records, err := MakeLinkageRecords(task, 1, 7, string(task.TaskID), testID, time.Unix(1615000000, 0), moduleVersions[DefaultModuleVersion])
+	assert.NoError(t, err)
+	assert.NoError(t, record.Decode(records[1]))
+	_, ok = GetTestRecordID(&record.Header)
+	assert.False(t, ok)
+}
+
+func TestMakeDestinationTestRecord(t *testing.T) {
+	testID := "5b0c3b9e-7d4a-4a4e-9d1e-3f1f2b6f0a11"
+	b, err := MakeDestinationTestRecord(1, 3, testID, time.Unix(1615000000, 0), moduleVersions[DefaultModuleVersion])
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+
+	// it belongs to no task
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, RecordClassReport, record.Class)
+	assert.Equal(t, testID, record.Header.XID.String())
+	assert.Equal(t, []byte(destinationTestTarget), getAttribute(&record.Header, AttributeTargetID))
+	assert.Equal(t, uint64(0), record.Header.CorrelationID)
+	seqNbr, _ := GetSequenceNumber(&record.Header)
+	assert.Equal(t, uint32(3), seqNbr)
+	id, ok := GetTestRecordID(&record.Header)
+	assert.True(t, ok)
+	assert.Equal(t, testID, id)
+}
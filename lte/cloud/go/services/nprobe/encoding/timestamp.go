@@ -0,0 +1,163 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// Encodings of the timestamps of records
+	TimestampEncodingBinary      = "binary"
+	TimestampEncodingGeneralized = "generalized"
+
+	// Precisions of the fraction of seconds of GeneralizedTime timestamps
+	TimestampPrecisionMilli = "ms"
+	TimestampPrecisionMicro = "us"
+
+	// Timezones of GeneralizedTime timestamps: UTC with the "Z" suffix, or
+	// the local time of the event with its offset from UTC
+	TimestampZoneUTC    = "utc"
+	TimestampZoneOffset = "offset"
+
+	// generalizedTimeLayout is the layout of GeneralizedTime timestamps without
+	// their fraction of seconds and timezone
+	generalizedTimeLayout = "20060102150405"
+)
+
+// TimestampFormat selects how the timestamps of records are encoded. Binary
+// timestamps keep the nanoseconds and the offset of the event, while
+// GeneralizedTime ones are written as TS 102 232 expects, e.g.
+// 20210218051326.019Z or 20210218061326.019519+0100.
+type TimestampFormat struct {
+	Encoding  string
+	Precision string
+	Zone      string
+}
+
+var (
+	timestampFormatMutex sync.RWMutex
+	timestampFormat      = TimestampFormat{Encoding: TimestampEncodingBinary}
+)
+
+// NewTimestampFormat returns the timestamp format of the given encoding,
+// precision and zone. Empty values select the binary encoding, milliseconds
+// and UTC respectively.
+func NewTimestampFormat(encoding, precision, zone string) (TimestampFormat, error) {
+	format := TimestampFormat{Encoding: encoding, Precision: precision, Zone: zone}
+	if format.Encoding == "" {
+		format.Encoding = TimestampEncodingBinary
+	}
+	if format.Precision == "" {
+		format.Precision = TimestampPrecisionMilli
+	}
+	if format.Zone == "" {
+		format.Zone = TimestampZoneUTC
+	}
+	switch format.Encoding {
+	case TimestampEncodingBinary, TimestampEncodingGeneralized:
+	default:
+		return TimestampFormat{}, fmt.Errorf("unsupported timestamp encoding %s", format.Encoding)
+	}
+	switch format.Precision {
+	case TimestampPrecisionMilli, TimestampPrecisionMicro:
+	default:
+		return TimestampFormat{}, fmt.Errorf("unsupported timestamp precision %s", format.Precision)
+	}
+	switch format.Zone {
+	case TimestampZoneUTC, TimestampZoneOffset:
+	default:
+		return TimestampFormat{}, fmt.Errorf("unsupported timestamp zone %s", format.Zone)
+	}
+	return format, nil
+}
+
+// SetTimestampFormat selects the format of the timestamps of the records
+// built from now on
+func SetTimestampFormat(format TimestampFormat) {
+	timestampFormatMutex.Lock()
+	defer timestampFormatMutex.Unlock()
+	timestampFormat = format
+}
+
+func getTimestampFormat() TimestampFormat {
+	timestampFormatMutex.RLock()
+	defer timestampFormatMutex.RUnlock()
+	return timestampFormat
+}
+
+// encodeGeneralizedTime parses timestamp and marshals it to
+// a byte sequence in the selected timestamp format
+func encodeGeneralizedTime(timestamp string) ([]byte, error) {
+	ptime, err := parseEventTimestamp(timestamp)
+	if err != nil {
+		return []byte{}, err
+	}
+	return formatTimestamp(ptime, getTimestampFormat())
+}
+
+// parseEventTimestamp parses the RFC 3339 timestamp of an event. A leap
+// second is held at the last instant of the second before it, since times
+// can't represent it.
+func parseEventTimestamp(timestamp string) (time.Time, error) {
+	ptime, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err == nil {
+		return ptime, nil
+	}
+	if len(timestamp) < 19 || timestamp[17:19] != "60" {
+		return time.Time{}, err
+	}
+	ptime, lerr := time.Parse(time.RFC3339Nano, timestamp[:17]+"59"+timestamp[19:])
+	if lerr != nil {
+		return time.Time{}, err
+	}
+	return ptime.Truncate(time.Second).Add(time.Second - time.Nanosecond), nil
+}
+
+// formatTimestamp marshals a time in the given timestamp format. The
+// fraction of seconds is truncated to the precision of the format.
+func formatTimestamp(t time.Time, format TimestampFormat) ([]byte, error) {
+	if format.Encoding != TimestampEncodingGeneralized {
+		return t.MarshalBinary()
+	}
+	if t.Year() < 0 || t.Year() > 9999 {
+		return nil, fmt.Errorf("timestamp year %d out of range", t.Year())
+	}
+	layout := generalizedTimeLayout + ".000"
+	precision := time.Millisecond
+	if format.Precision == TimestampPrecisionMicro {
+		layout = generalizedTimeLayout + ".000000"
+		precision = time.Microsecond
+	}
+	t = t.Truncate(precision)
+	if format.Zone == TimestampZoneOffset {
+		return []byte(t.Format(layout + "-0700")), nil
+	}
+	return []byte(t.UTC().Format(layout) + "Z"), nil
+}
+
+// decodeGeneralizedTime returns the time of the timestamp of a record,
+// whether it is binary or a GeneralizedTime
+func decodeGeneralizedTime(b []byte) (time.Time, error) {
+	var timestamp time.Time
+	if len(b) == 0 || b[0] < '0' || b[0] > '9' {
+		err := timestamp.UnmarshalBinary(b)
+		return timestamp, err
+	}
+	// the fraction of seconds is parsed although the layout leaves it out,
+	// and the timezone is mandatory
+	return time.Parse(generalizedTimeLayout+"Z0700", string(b))
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// timestampQualityPrefix prefixes the value of the timestamp-quality flag,
+// followed by the quality of the clock the record was built with
+const timestampQualityPrefix = "timestamp_quality="
+
+// MarkTimestampQuality flags a record built while the clock of the service
+// was unreliable with the quality of the clock, a proprietary conditional
+// attribute appended to its header. It is appended after the attributes the
+// record was built with, so that the missing-parameter indicator or the test
+// indication of the record remain its first proprietary attribute.
+func MarkTimestampQuality(record []byte, quality string) ([]byte, error) {
+	var hdr EpsIRIHeader
+	hdrLen, err := unmarshalRecordHeader(record, &hdr)
+	if err != nil {
+		return nil, err
+	}
+	attr := NewAttribute(AttributeProprietary, []byte(timestampQualityPrefix+quality))
+	markedHdrLen := hdrLen + uint32(attr.Len) + 4
+	if markedHdrLen > MaxHeaderLength {
+		return nil, fmt.Errorf("flagged header length %d exceeds %d", markedHdrLen, MaxHeaderLength)
+	}
+	return appendAttribute(record, hdrLen, attr), nil
+}
+
+// GetTimestampQuality returns the quality of the clock a record was flagged
+// with, empty for the records built with a reliable clock
+func GetTimestampQuality(hdr *EpsIRIHeader) string {
+	for _, attr := range hdr.ConditionalAttributes {
+		if attr.Tag == AttributeProprietary && bytes.HasPrefix(attr.Value, []byte(timestampQualityPrefix)) {
+			return string(bytes.TrimPrefix(attr.Value, []byte(timestampQualityPrefix)))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkTimestampQuality(t *testing.T) {
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(encodedRecord))
+	assert.Empty(t, GetTimestampQuality(&record.Header))
+
+	marked, err := MarkTimestampQuality(encodedRecord, "skewed")
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(marked))
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(marked))
+	assert.Equal(t, "skewed", GetTimestampQuality(&record.Header))
+	seqNbr, _ := GetSequenceNumber(&record.Header)
+	assert.Equal(t, uint32(6), seqNbr)
+	// the payload is left untouched
+	assert.Equal(t, encodedRecord[len(encodedRecord)-40:], marked[len(marked)-40:])
+
+	js, err := ToJSON(&record)
+	assert.NoError(t, err)
+	assert.Contains(t, string(js), "timestamp_quality=skewed")
+
+	// the other proprietary attributes of a flagged record are still found
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	testID := "5b0c3b9e-7d4a-4a4e-9d1e-3f1f2b6f0a11"
+	b, err := MakeTestRecord(task, 1, 12, testID, time.Unix(1615000000, 0), moduleVersions[DefaultModuleVersion])
+	assert.NoError(t, err)
+	marked, err = MarkTimestampQuality(b, "unsynchronized")
+	assert.NoError(t, err)
+	record = EpsIRIRecord{}
+	assert.NoError(t, record.Decode(marked))
+	id, ok := GetTestRecordID(&record.Header)
+	assert.True(t, ok)
+	assert.Equal(t, testID, id)
+	assert.Equal(t, "unsynchronized", GetTimestampQuality(&record.Header))
+
+	_, err = MarkTimestampQuality([]byte{1, 2}, "skewed")
+	assert.EqualError(t, err, "invalid input size")
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	milliUTC, err := NewTimestampFormat(TimestampEncodingGeneralized, "", "")
+	assert.NoError(t, err)
+	microUTC, err := NewTimestampFormat(TimestampEncodingGeneralized, TimestampPrecisionMicro, TimestampZoneUTC)
+	assert.NoError(t, err)
+	microOffset, err := NewTimestampFormat(TimestampEncodingGeneralized, TimestampPrecisionMicro, TimestampZoneOffset)
+	assert.NoError(t, err)
+	milliOffset, err := NewTimestampFormat(TimestampEncodingGeneralized, TimestampPrecisionMilli, TimestampZoneOffset)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		in     string
+		format TimestampFormat
+		out    string
+	}{
+		{"2021-02-18T05:13:26.019519+00:00", milliUTC, "20210218051326.019Z"},
+		{"2021-02-18T05:13:26.019519+00:00", microUTC, "20210218051326.019519Z"},
+		{"2021-02-18T05:13:26.019519+00:00", microOffset, "20210218051326.019519+0000"},
+		{"2021-02-18T06:13:26.019519+01:00", milliUTC, "20210218051326.019Z"},
+		{"2021-02-18T06:13:26.019519+01:00", microOffset, "20210218061326.019519+0100"},
+		{"2021-02-18T00:13:26-05:00", microOffset, "20210218001326.000000-0500"},
+		{"2021-02-18T05:13:26.999999999Z", milliUTC, "20210218051326.999Z"},
+		// leap seconds
+		{"2016-12-31T23:59:60Z", milliUTC, "20161231235959.999Z"},
+		{"2016-12-31T23:59:60.5Z", microUTC, "20161231235959.999999Z"},
+		{"2017-01-01T00:59:60.25+01:00", microOffset, "20170101005959.999999+0100"},
+		// the end of daylight saving time in Europe/Paris, the same local hour twice
+		{"2021-10-31T02:30:00.000+02:00", milliOffset, "20211031023000.000+0200"},
+		{"2021-10-31T02:30:00.000+01:00", milliOffset, "20211031023000.000+0100"},
+		{"2021-10-31T02:30:00.000+02:00", milliUTC, "20211031003000.000Z"},
+		{"2021-10-31T02:30:00.000+01:00", milliUTC, "20211031013000.000Z"},
+		// its start, an hour skipped
+		{"2021-03-28T01:59:59.999+01:00", milliUTC, "20210328005959.999Z"},
+		{"2021-03-28T03:00:00+02:00", milliUTC, "20210328010000.000Z"},
+	}
+	for _, test := range tests {
+		ptime, err := parseEventTimestamp(test.in)
+		assert.NoError(t, err, test.in)
+		b, err := formatTimestamp(ptime, test.format)
+		assert.NoError(t, err, test.in)
+		assert.Equal(t, test.out, string(b), test.in)
+
+		decoded, err := decodeGeneralizedTime(b)
+		assert.NoError(t, err, test.in)
+		assert.True(t, ptime.Sub(decoded) >= 0 && ptime.Sub(decoded) < time.Millisecond, test.in)
+	}
+
+	// binary timestamps keep the nanoseconds of the event, leap seconds aside
+	ptime, err := parseEventTimestamp("2016-12-31T23:59:60Z")
+	assert.NoError(t, err)
+	b, err := formatTimestamp(ptime, TimestampFormat{Encoding: TimestampEncodingBinary})
+	assert.NoError(t, err)
+	decoded, err := decodeGeneralizedTime(b)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2016, 12, 31, 23, 59, 59, 999999999, time.UTC), decoded.UTC())
+
+	for _, invalid := range []string{"2016-12-31T23:59:61Z", "2016-12-31T23:60:00Z", "2016-12-31 23:59:60"} {
+		_, err := parseEventTimestamp(invalid)
+		assert.Error(t, err, invalid)
+	}
+	for _, invalid := range []string{"20210218051326.019", "20210218051326.019+01", "2021021805"} {
+		_, err := decodeGeneralizedTime([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+
+	_, err = NewTimestampFormat("text", "", "")
+	assert.Error(t, err)
+	_, err = NewTimestampFormat("", "ns", "")
+	assert.Error(t, err)
+	_, err = NewTimestampFormat("", "", "local")
+	assert.Error(t, err)
+}
+
+func TestMakeRecordGeneralizedTime(t *testing.T) {
+	format, err := NewTimestampFormat(TimestampEncodingGeneralized, TimestampPrecisionMicro, TimestampZoneOffset)
+	assert.NoError(t, err)
+	SetTimestampFormat(format)
+	defer SetTimestampFormat(TimestampFormat{Encoding: TimestampEncodingBinary})
+
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.AttachSuccess,
+		StreamName: nprobe.ESStreamMME,
+		Timestamp:  "2021-02-18T06:13:26.019519+01:00",
+		Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+	}
+	b, err := MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, []byte("20210218061326.019519+0100"), record.Payload.TimeStamp.LocalTime.GeneralizedTime)
+	assert.Equal(t, []byte("20210218061326.019519+0100"), getAttribute(&record.Header, AttributeTimestamp))
+
+	js, err := ToJSON(&record)
+	assert.NoError(t, err)
+	assert.Contains(t, string(js), "2021-02-18T05:13:26.019519Z")
+
+	x2, err := MakeX2PDU(b)
+	assert.NoError(t, err)
+	hdr, err := ParsePDUHeader(x2)
+	assert.NoError(t, err)
+	timestamp, err := GetX2Timestamp(getAttribute(hdr, AttributeTimestamp))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 2, 18, 5, 13, 26, 19519000, time.UTC), timestamp)
+}
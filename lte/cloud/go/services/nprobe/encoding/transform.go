@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+)
+
+// RecordTransformer adapts the records encoded by the service to the
+// national handover variant of a country, e.g. adding the parameters its
+// delivery functions expect or reordering the ones of the base encoding.
+// Country-specific modules register their transformer from their init
+// function, the records being transformed once encoded and validated, and
+// before they are encrypted, signed and exported.
+type RecordTransformer interface {
+	// Transform returns the record of a task in the national variant. The
+	// task carries the LIID and the delivery country code of the record
+	// header. The record must not be modified in place.
+	Transform(record []byte, task *models.NetworkProbeTask) ([]byte, error)
+}
+
+// RecordTransformerFunc is a function used as a RecordTransformer
+type RecordTransformerFunc func(record []byte, task *models.NetworkProbeTask) ([]byte, error)
+
+// Transform calls f(record, task)
+func (f RecordTransformerFunc) Transform(record []byte, task *models.NetworkProbeTask) ([]byte, error) {
+	return f(record, task)
+}
+
+var countryCodeRegexp = regexp.MustCompile(`^[A-Z]{2}$`)
+
+var (
+	transformerMutex sync.RWMutex
+	// recordTransformers is the registry of the record transformers, by
+	// the delivery country code of the records they transform
+	recordTransformers = map[string]RecordTransformer{}
+)
+
+// RegisterRecordTransformer registers the transformer of the records
+// delivered to a country, identified by its ISO 3166-1 alpha-2 code as in the
+// delivery country code of the records. A single transformer is registered
+// per country.
+func RegisterRecordTransformer(countryCode string, transformer RecordTransformer) error {
+	if !countryCodeRegexp.MatchString(countryCode) {
+		return fmt.Errorf("invalid delivery country code %q", countryCode)
+	}
+	transformerMutex.Lock()
+	defer transformerMutex.Unlock()
+	if _, ok := recordTransformers[countryCode]; ok {
+		return fmt.Errorf("record transformer of country %s already registered", countryCode)
+	}
+	recordTransformers[countryCode] = transformer
+	return nil
+}
+
+// GetRecordTransformer returns the registered transformer of the records
+// delivered to a country
+func GetRecordTransformer(countryCode string) (RecordTransformer, error) {
+	transformerMutex.RLock()
+	defer transformerMutex.RUnlock()
+	transformer, ok := recordTransformers[countryCode]
+	if !ok {
+		return nil, fmt.Errorf("no record transformer registered for country %q", countryCode)
+	}
+	return transformer, nil
+}
+
+// GetRecordTransformers returns the country codes of the registered record
+// transformers, sorted
+func GetRecordTransformers() []string {
+	transformerMutex.RLock()
+	defer transformerMutex.RUnlock()
+	countryCodes := make([]string, 0, len(recordTransformers))
+	for countryCode := range recordTransformers {
+		countryCodes = append(countryCodes, countryCode)
+	}
+	sort.Strings(countryCodes)
+	return countryCodes
+}
+
+// TransformRecord returns the record of a task transformed by one of the
+// transformers given, selected by the delivery country code of the task, or
+// the record itself when none is selected. Transformation failures are
+// reported as encoding errors of the record.
+func TransformRecord(record []byte, task *models.NetworkProbeTask, transformers map[string]RecordTransformer) ([]byte, error) {
+	if task.TaskDetails == nil {
+		return record, nil
+	}
+	transformer, ok := transformers[task.TaskDetails.DeliveryCountryCode]
+	if !ok {
+		return record, nil
+	}
+	transformed, err := transformer.Transform(record, task)
+	if err != nil {
+		return nil, newEncodingError(FieldTransformation, err)
+	}
+	if len(transformed) == 0 {
+		return nil, newEncodingError(FieldTransformation, fmt.Errorf("empty record returned for country %s", task.TaskDetails.DeliveryCountryCode))
+	}
+	return transformed, nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"errors"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordTransformers(t *testing.T) {
+	appendParams := RecordTransformerFunc(func(record []byte, task *models.NetworkProbeTask) ([]byte, error) {
+		return append(append([]byte{}, record...), task.TaskDetails.AuthorizationReference...), nil
+	})
+	assert.NoError(t, RegisterRecordTransformer("ZX", appendParams))
+	assert.NoError(t, RegisterRecordTransformer("ZY", RecordTransformerFunc(func(record []byte, task *models.NetworkProbeTask) ([]byte, error) {
+		return nil, errors.New("missing national parameters")
+	})))
+	defer func() {
+		delete(recordTransformers, "ZX")
+		delete(recordTransformers, "ZY")
+	}()
+
+	assert.EqualError(t, RegisterRecordTransformer("ZX", appendParams), "record transformer of country ZX already registered")
+	assert.EqualError(t, RegisterRecordTransformer("zx", appendParams), `invalid delivery country code "zx"`)
+	assert.Contains(t, GetRecordTransformers(), "ZX")
+	_, err := GetRecordTransformer("ZW")
+	assert.EqualError(t, err, `no record transformer registered for country "ZW"`)
+
+	zx, err := GetRecordTransformer("ZX")
+	assert.NoError(t, err)
+	zy, err := GetRecordTransformer("ZY")
+	assert.NoError(t, err)
+	transformers := map[string]RecordTransformer{"ZX": zx, "ZY": zy}
+	makeTask := func(countryCode string) *models.NetworkProbeTask {
+		return &models.NetworkProbeTask{
+			TaskID:      "task1",
+			TaskDetails: &models.NetworkProbeTaskDetails{AuthorizationReference: "LIID", DeliveryCountryCode: countryCode},
+		}
+	}
+
+	// records are transformed by the transformer of their delivery country
+	record := []byte("record")
+	transformed, err := TransformRecord(record, makeTask("ZX"), transformers)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("recordLIID"), transformed)
+	assert.Equal(t, []byte("record"), record)
+
+	// and left as is for the other countries
+	transformed, err = TransformRecord(record, makeTask("FR"), transformers)
+	assert.NoError(t, err)
+	assert.Equal(t, record, transformed)
+	transformed, err = TransformRecord(record, makeTask("ZX"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, record, transformed)
+
+	// failures are encoding errors of the record
+	_, err = TransformRecord(record, makeTask("ZY"), transformers)
+	assert.EqualError(t, err, "invalid transformation: missing national parameters")
+	assert.Equal(t, FieldTransformation, GetErrorField(err))
+	assert.False(t, IsDegradable(err))
+}
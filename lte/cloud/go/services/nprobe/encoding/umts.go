@@ -0,0 +1,408 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+const (
+	// IRI domains of the records of a task, as set by its iri_domain
+	IRIDomainEPS  = models.NetworkProbeTaskDetailsIriDomainEps
+	IRIDomainUMTS = models.NetworkProbeTaskDetailsIriDomainUmts
+)
+
+// umtsDomainID identifies the records of the UMTS domain. Unlike the EPS
+// domain, a single version of the module is supported.
+var umtsDomainID = asn1.ObjectIdentifier{0, 4, 0, 2, 2, 4, 1, 15, 1}
+
+// ErrUmtsPayload is returned by DecodePayload for the payloads of the UMTS
+// domain, which DecodeUmtsPayload decodes
+var ErrUmtsPayload = errors.New("payload of the UMTS domain")
+
+// gprsEvents maps the EPS events to the GPRS events of the PDP contexts of
+// the UMTS domain. The PDN connectivity requests have no GPRS equivalent.
+var gprsEvents = map[asn1.Enumerated]asn1.Enumerated{
+	BearerActivation:               PDPContextActivation,
+	StartInterceptWithActiveBearer: StartInterceptWithPDPContextActive,
+	BearerModification:             PDPContextModification,
+	BearerDeactivation:             PDPContextDeactivation,
+	EutranAttach:                   GPRSAttach,
+	EutranDetach:                   GPRSDetach,
+	LocationUpdate:                 LocationInfoUpdate,
+	ServingEvolvedPacketSystem:     GPRSServingSystem,
+	SMS:                            GPRSSMS,
+}
+
+// UmtsIRIRecord represents a full IRI record of the UMTS domain combining
+// header and payload
+type UmtsIRIRecord struct {
+	Header  EpsIRIHeader
+	Payload UmtsIRIContent
+	// Class is the class of the record, e.g. RecordClassBegin, which the
+	// payload is tagged with. It is derived from the GPRS event when empty.
+	Class string
+}
+
+// Encode returns a byte sequence of the UmtsIRIRecord in network byte order,
+// encoded with pooled encoders as EpsIRIRecord.Encode does
+func (r *UmtsIRIRecord) Encode() ([]byte, error) {
+	e := encoderPool.Get().(*Encoder)
+	defer encoderPool.Put(e)
+	msg, err := e.EncodeUmts(r)
+	if err != nil {
+		return []byte{}, err
+	}
+	return append([]byte(nil), msg...), nil
+}
+
+// Decode constructs an IRI record of the UMTS domain from a byte sequence,
+// as parsed by ParseHeader and DecodeUmtsPayload
+func (r *UmtsIRIRecord) Decode(b []byte) error {
+	hdr, err := ParseHeader(b)
+	if err != nil {
+		return err
+	}
+	payload, class, err := DecodeUmtsPayload(hdr, b)
+	if err != nil {
+		return err
+	}
+	r.Header, r.Payload, r.Class = *hdr, *payload, class
+	return nil
+}
+
+// EncodeUmts encodes a record of the UMTS domain and updates its payload
+// length. The returned slice is only valid until the next call to the
+// encoder.
+func (e *Encoder) EncodeUmts(r *UmtsIRIRecord) ([]byte, error) {
+	hdrLen := int(r.Header.HeaderLength)
+	b := grow(e.buf[:0], hdrLen)
+	class := r.Class
+	if class == "" {
+		class = getDefaultRecordClass(getEPSEquivalent(r.Payload.GPRSEvent))
+	}
+	b, err := appendUmtsIRIParameter(b, classContextSpecific|constructedForm, getRecordTag(class), (*UmtsIRIParameter)(&r.Payload))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.PayloadLength = uint32(len(b) - hdrLen)
+	r.Header.marshalTo(b[:hdrLen])
+	e.buf = b
+	return b, nil
+}
+
+// DecodeUmtsPayload decodes the payload of an encoded record of the UMTS
+// domain whose header was parsed by ParseHeader, along with its record class
+func DecodeUmtsPayload(hdr *EpsIRIHeader, record []byte) (*UmtsIRIContent, string, error) {
+	if uint64(hdr.HeaderLength)+uint64(hdr.PayloadLength) > uint64(len(record)) || hdr.PayloadLength == 0 {
+		return nil, "", errors.New("invalid input size")
+	}
+	content := record[hdr.HeaderLength : hdr.HeaderLength+hdr.PayloadLength]
+	payload := &UmtsIRIContent{}
+	rest, err := asn1.UnmarshalWithParams(content, payload, decodeRecordType(content[0]))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(rest) != 0 {
+		return nil, "", errors.New("trailing data after payload")
+	}
+	if !payload.Hi2DomainID.Equal(umtsDomainID) {
+		return nil, "", fmt.Errorf("unexpected domain ID %v", payload.Hi2DomainID)
+	}
+	return payload, decodeRecordClass(content[0]), nil
+}
+
+// isUmtsPayload returns true if the domain ID of an encoded payload is the
+// one of the UMTS domain. Both domains carry it first, with the same tag.
+func isUmtsPayload(content []byte) bool {
+	if len(content) == 0 {
+		return false
+	}
+	var domain struct {
+		DomainID asn1.ObjectIdentifier `asn1:"tag:0"`
+	}
+	// the components following the domain ID are ignored
+	if _, err := asn1.UnmarshalWithParams(content, &domain, decodeRecordType(content[0])); err != nil {
+		return false
+	}
+	return domain.DomainID.Equal(umtsDomainID)
+}
+
+// getIRIDomain returns the domain of the records of an event, the one of its
+// task if set or else the one of its stream, UMTS for the SGSN stream
+func getIRIDomain(streamName string, details *models.NetworkProbeTaskDetails) string {
+	if len(details.IriDomain) != 0 {
+		return details.IriDomain
+	}
+	if streamName == nprobe.ESStreamSGSN {
+		return IRIDomainUMTS
+	}
+	return IRIDomainEPS
+}
+
+// getGPRSEventID returns the GPRS event of an EPS event, UnsupportedEvent
+// when it has none
+func getGPRSEventID(eventID asn1.Enumerated) asn1.Enumerated {
+	if gprsEvent, ok := gprsEvents[eventID]; ok {
+		return gprsEvent
+	}
+	return UnsupportedEvent
+}
+
+// getEPSEquivalent returns the EPS event of a GPRS event, whose record
+// classes are the ones of the GPRS event
+func getEPSEquivalent(gprsEvent asn1.Enumerated) asn1.Enumerated {
+	for eventID, event := range gprsEvents {
+		if event == gprsEvent {
+			return eventID
+		}
+	}
+	return UnsupportedEvent
+}
+
+// makeUmtsRecord builds a record of the UMTS domain of an event whose EPS
+// event is eventID, with the header and national parameters of its EPS
+// record. Module versions only apply to the EPS domain.
+func makeUmtsRecord(
+	event *eventdM.Event,
+	details *models.NetworkProbeTaskDetails,
+	header EpsIRIHeader,
+	eventID asn1.Enumerated,
+	class string,
+	operatorID uint32,
+	timestamp []byte,
+	nationalParameters NationalParameters,
+) ([]byte, error) {
+	gprsEvent := getGPRSEventID(eventID)
+	if gprsEvent == UnsupportedEvent {
+		return []byte{}, newEncodingError(FieldEventType, fmt.Errorf("Unsupported event type %s in the UMTS domain", event.EventType))
+	}
+	record := UmtsIRIRecord{
+		Header:  header,
+		Payload: makeUmtsIRIContent(event, gprsEvent, details.CorrelationID, operatorID, timestamp),
+		Class:   class,
+	}
+	record.Payload.LawInterceptID = makeLawInterceptID(details.AuthorizationReference)
+	record.Payload.NationalParameters = nationalParameters
+	setTargetIdentity(record.Payload.PartyInformation, details)
+	sortPartyInformation(record.Payload.PartyInformation)
+	return record.Encode()
+}
+
+// makeUmtsIRIContent builds the IRI Content structure of the UMTS domain with
+// the available information for each event
+func makeUmtsIRIContent(
+	event *eventdM.Event,
+	gprsEvent asn1.Enumerated,
+	correlationID uint64,
+	operatorID uint32,
+	timestamp []byte,
+) UmtsIRIContent {
+	eventData := event.Value.(map[string]interface{})
+	content := UmtsIRIContent{
+		Hi2DomainID:            umtsDomainID,
+		TimeStamp:              makeTimestamp(timestamp),
+		Initiator:              InitiatorNotAvailable,
+		LocationOfTheTarget:    makeUmtsLocation(eventData),
+		PartyInformation:       makePartyInformation(event),
+		SMS:                    makeSMSReport(event),
+		GPRSCorrelationNumber:  convertUint64ToBytes(correlationID),
+		GPRSEvent:              gprsEvent,
+		SGSNAddress:            makeNodeAddress(eventData, "sgsn_ip"),
+		GPRSOperationErrorCode: makeCause(eventData),
+		GGSNAddress:            makeNodeAddress(eventData, "ggsn_ip"),
+		NetworkIdentifier:      makeNetworkIdentifier(event, operatorID),
+	}
+	if hasUmtsQoS(gprsEvent) {
+		content.QoS = makeUmtsQoS(eventData)
+	}
+	setPDPContext(content.PartyInformation, eventData)
+	return content
+}
+
+// makeUmtsLocation returns the cell, routing area and service area of the
+// target, coded as in TS 29.002, if known
+func makeUmtsLocation(eventData map[string]interface{}) Location {
+	var location Location
+	for key, v := range map[string]*[]byte{
+		"cell_global_id":  &location.GlobalCellID,
+		"routing_area_id": &location.RAI,
+		"service_area_id": &location.SAI,
+	} {
+		if value, ok := eventData[key]; ok {
+			*v, _ = hex.DecodeString(value.(string))
+		}
+	}
+	return location
+}
+
+// makeNodeAddress returns the address of a GPRS support node of the event
+// data, e.g. sgsn_ip, if known
+func makeNodeAddress(eventData map[string]interface{}, key string) DataNodeAddress {
+	addr, ok := eventData[key]
+	if !ok {
+		return DataNodeAddress{}
+	}
+	ip := makeIPAddress(addr.(string))
+	if len(ip) == net.IPv4len {
+		return makeDataNodeAddress(IPV4Type, ip, 0)
+	}
+	return makeDataNodeAddress(IPV6Type, ip, 0)
+}
+
+// makeUmtsQoS returns the QoS profile of a PDP context, coded as the value
+// part of the IE of TS 29.060 7.7.34, if known
+func makeUmtsQoS(eventData map[string]interface{}) UmtsQoS {
+	profile, ok := eventData["qos_profile"]
+	if !ok {
+		return UmtsQoS{}
+	}
+	qos, _ := hex.DecodeString(profile.(string))
+	return UmtsQoS{QoSGn: qos}
+}
+
+// hasUmtsQoS returns true if the records of a GPRS event carry the QoS
+// profile of their PDP context
+func hasUmtsQoS(gprsEvent asn1.Enumerated) bool {
+	switch gprsEvent {
+	case PDPContextActivation, StartInterceptWithPDPContextActive, PDPContextModification:
+		return true
+	}
+	return false
+}
+
+// setPDPContext adds the APN and the PDP type of the PDP context of the
+// target to its party information, the type being given by its addresses
+func setPDPContext(parties []PartyInformation, eventData map[string]interface{}) {
+	for i := range parties {
+		if parties[i].PartyQualified != PartyQualifierTarget {
+			continue
+		}
+		params := &parties[i].ServicesDataInformation.GPRSParameters
+		if apn, ok := eventData["apn"]; ok {
+			params.APN = []byte(apn.(string))
+		}
+		switch ipv4, ipv6, _ := getUEAddresses(eventData); {
+		case ipv4 != nil && ipv6 != nil:
+			params.PDPType = []byte{PDPTypeOrganisationIETF, PDPTypeIPv4v6}
+		case ipv4 != nil:
+			params.PDPType = []byte{PDPTypeOrganisationIETF, PDPTypeIPv4}
+		case ipv6 != nil:
+			params.PDPType = []byte{PDPTypeOrganisationIETF, PDPTypeIPv6}
+		}
+	}
+}
+
+// validateUmtsRecord checks that an encoded record of the UMTS domain is
+// well-formed, as Validate does for the EPS domain
+func validateUmtsRecord(hdr *EpsIRIHeader, record []byte) error {
+	payload, class, err := DecodeUmtsPayload(hdr, record)
+	if err != nil {
+		return &ValidationError{Field: FieldDER, Err: err}
+	}
+	if err := validateHeader(hdr, len(record)); err != nil {
+		return err
+	}
+
+	content := record[hdr.HeaderLength:]
+	if !isRecordClassAllowed(getEPSEquivalent(payload.GPRSEvent), class) {
+		return newValidationError(FieldEventType, "record type %x does not match GPRS event %d", content[0], payload.GPRSEvent)
+	}
+	canonical, err := asn1.MarshalWithParams(*payload, getRecordType(class))
+	if err != nil {
+		return &ValidationError{Field: FieldDER, Err: err}
+	}
+	if !bytes.Equal(canonical, content) {
+		return newValidationError(FieldDER, "payload is not in canonical form")
+	}
+	return validateUmtsContent(hdr, payload)
+}
+
+// validateUmtsContent checks the fields mandatory for the GPRS event of a
+// record of the UMTS domain
+func validateUmtsContent(hdr *EpsIRIHeader, content *UmtsIRIContent) error {
+	timestamp := content.TimeStamp.LocalTime.GeneralizedTime
+	if _, err := decodeGeneralizedTime(timestamp); err != nil {
+		return &ValidationError{Field: FieldTimestamp, Err: err}
+	}
+	if !bytes.Equal(timestamp, getAttribute(hdr, AttributeTimestamp)) {
+		return newValidationError(FieldTimestamp, "payload and header timestamps differ")
+	}
+
+	if len(content.GPRSCorrelationNumber) != 8 ||
+		binary.BigEndian.Uint64(content.GPRSCorrelationNumber) != hdr.CorrelationID {
+		return newValidationError(FieldTaskDetails, "correlation number does not match header")
+	}
+	if len(content.NetworkIdentifier.OperatorIdentifier) == 0 {
+		return newValidationError(FieldNetworkIdentifier, "missing operator identifier")
+	}
+	if err := validatePartyInformation(content.PartyInformation); err != nil {
+		return err
+	}
+	if isEmptyIdentity(getTargetIdentity(content.PartyInformation)) {
+		return newValidationError(FieldIdentity, "missing target identity")
+	}
+
+	location := content.LocationOfTheTarget
+	if n := len(location.GlobalCellID); n != 0 && (n < 5 || n > 7) {
+		return newValidationError(FieldLocation, "invalid global cell ID length %d", n)
+	}
+	if n := len(location.RAI); n != 0 && n != 6 {
+		return newValidationError(FieldLocation, "invalid routing area ID length %d", n)
+	}
+	if n := len(location.SAI); n != 0 && n != 7 {
+		return newValidationError(FieldLocation, "invalid service area ID length %d", n)
+	}
+	for _, address := range []*IPAddress{&content.SGSNAddress.IPAddress, &content.GGSNAddress.IPAddress} {
+		if err := validateIPAddress(address); err != nil {
+			return &ValidationError{Field: FieldBearerParams, Err: err}
+		}
+	}
+	if err := validateOctets(content.GPRSOperationErrorCode); err != nil {
+		return err
+	}
+
+	if content.GPRSEvent != GPRSSMS && !isZeroSMSReport(&content.SMS) {
+		return newValidationError(FieldSMS, "unexpected SMS report")
+	}
+	if national := content.NationalParameters; national != (NationalParameters{}) {
+		if content.GPRSEvent != PDPContextModification {
+			return newValidationError(FieldUsage, "unexpected usage report")
+		}
+		if len(national.CountryCode) != 2 {
+			return newValidationError(FieldUsage, "invalid country code %q", national.CountryCode)
+		}
+	}
+	if !hasUmtsQoS(content.GPRSEvent) && !isZeroUmtsQoS(&content.QoS) {
+		return newValidationError(FieldBearerParams, "unexpected QoS profile")
+	}
+
+	if getEPSEquivalent(content.GPRSEvent) == UnsupportedEvent {
+		return newValidationError(FieldEventType, "unsupported GPRS event %d", content.GPRSEvent)
+	}
+	if content.GPRSEvent == GPRSSMS {
+		return validateSMSReport(&content.SMS)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/asn1"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeUmtsTestEvent(eventType string) *eventdM.Event {
+	return &eventdM.Event{
+		EventType:  eventType,
+		StreamName: nprobe.ESStreamSGSN,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":            "IMSI001010000000001",
+			"session_id":      "IMSI001010000000001-919642",
+			"apn":             "magma.ipv4",
+			"ip_addr":         "192.168.128.12",
+			"sgsn_ip":         "10.0.0.2",
+			"ggsn_ip":         "2001:db8::10",
+			"routing_area_id": "00f110000101",
+			"qos_profile":     "0b921f",
+		},
+	}
+}
+
+func TestMakeUmtsRecord(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			TargetType:    models.NetworkProbeTaskDetailsTargetTypeImsi,
+			CorrelationID: 42,
+		},
+	}
+
+	// the records of the events of the SGSN stream are of the UMTS domain
+	b, err := MakeRecord(makeUmtsTestEvent(nprobe.SessionCreated), task, 49002, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	assert.Equal(t, RecordClassBegin, GetRecordClass(b))
+	var record UmtsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	payload := record.Payload
+	assert.Equal(t, umtsDomainID, payload.Hi2DomainID)
+	assert.Equal(t, PDPContextActivation, payload.GPRSEvent)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 42}, payload.GPRSCorrelationNumber)
+	assert.Equal(t, []byte{0x00, 0xf1, 0x10, 0x00, 0x01, 0x01}, payload.LocationOfTheTarget.RAI)
+	assert.Equal(t, makeDataNodeAddress(IPV4Type, []byte{10, 0, 0, 2}, 0), payload.SGSNAddress)
+	assert.Equal(t, IPV6Type, payload.GGSNAddress.IPAddress.IPType)
+	assert.Equal(t, []byte{0x0b, 0x92, 0x1f}, payload.QoS.QoSGn)
+	params := getTargetParty(payload.PartyInformation).ServicesDataInformation.GPRSParameters
+	assert.Equal(t, []byte("magma.ipv4"), params.APN)
+	assert.Equal(t, []byte{PDPTypeOrganisationIETF, PDPTypeIPv4}, params.PDPType)
+	assert.Equal(t, makeDataNodeAddress(IPV4Type, []byte{192, 168, 128, 12}, 0), params.PDPAddress)
+
+	// they are encoded as encoding/asn1 does
+	reference, err := asn1.MarshalWithParams(payload, IRIBeginRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, reference, b[record.Header.HeaderLength:])
+
+	// and only decoded as such
+	hdr, err := ParseHeader(b)
+	assert.NoError(t, err)
+	_, _, err = DecodePayload(hdr, b)
+	assert.Equal(t, ErrUmtsPayload, err)
+
+	b, err = MakeRecord(makeUmtsTestEvent(nprobe.SessionTerminated), task, 49002, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	assert.Equal(t, RecordClassEnd, GetRecordClass(b))
+	record = UmtsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, PDPContextDeactivation, record.Payload.GPRSEvent)
+	// the QoS profile only applies to activations and modifications
+	assert.Equal(t, UmtsQoS{}, record.Payload.QoS)
+
+	b, err = MakeRecord(makeUmtsTestEvent(nprobe.TrackingAreaUpdate), task, 49002, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	record = UmtsIRIRecord{}
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, LocationInfoUpdate, record.Payload.GPRSEvent)
+
+	// PDN connectivity requests have no GPRS event
+	_, err = MakeRecord(makeUmtsTestEvent(nprobe.PDNConnectivityRequested), task, 49002, 4)
+	assert.EqualError(t, err, "invalid event_type: Unsupported event type pdn_connectivity_requested in the UMTS domain")
+	assert.Equal(t, FieldEventType, GetErrorField(err))
+
+	event := makeUmtsTestEvent(nprobe.SessionCreated)
+	event.Value.(map[string]interface{})["routing_area_id"] = "00f110"
+	_, err = MakeRecord(event, task, 49002, 5)
+	assert.EqualError(t, err, `invalid location: routing_area_id is not a valid location: "00f110"`)
+}
+
+func TestGetIRIDomain(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+			IriDomain:  IRIDomainUMTS,
+		},
+	}
+
+	// the domain of the task applies to the events of any stream
+	event := makeUmtsTestEvent(nprobe.AttachSuccess)
+	event.StreamName = nprobe.ESStreamMME
+	b, err := MakeRecord(event, task, 49002, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	var record UmtsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, GPRSAttach, record.Payload.GPRSEvent)
+
+	task.TaskDetails.IriDomain = IRIDomainEPS
+	event.StreamName = nprobe.ESStreamSGSN
+	b, err = MakeRecord(event, task, 49002, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+	var epsRecord EpsIRIRecord
+	assert.NoError(t, epsRecord.Decode(b))
+	assert.Equal(t, EutranAttach, epsRecord.Payload.EPSEvent)
+}
+
+func TestValidateUmtsRecord(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMSI001010000000001",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi,
+		},
+	}
+	b, err := MakeRecord(makeUmtsTestEvent(nprobe.SessionUpdated), task, 49002, 1)
+	assert.NoError(t, err)
+	var record UmtsIRIRecord
+	assert.NoError(t, record.Decode(b))
+
+	tests := []struct {
+		name  string
+		alter func(r *UmtsIRIRecord)
+		field string
+	}{
+		{"class", func(r *UmtsIRIRecord) { r.Class = RecordClassEnd }, FieldEventType},
+		{"event", func(r *UmtsIRIRecord) { r.Payload.GPRSEvent = 2 }, FieldEventType},
+		{"correlation", func(r *UmtsIRIRecord) { r.Payload.GPRSCorrelationNumber = []byte{1} }, FieldTaskDetails},
+		{"location", func(r *UmtsIRIRecord) { r.Payload.LocationOfTheTarget.SAI = []byte{1} }, FieldLocation},
+		{"sgsn", func(r *UmtsIRIRecord) { r.Payload.SGSNAddress.IPAddress.IPValue.IPBinaryAddress = []byte{1} }, FieldBearerParams},
+		{"sms", func(r *UmtsIRIRecord) { r.Payload.SMS.SMSContents.Content = []byte{1} }, FieldSMS},
+	}
+	for _, test := range tests {
+		altered := record
+		test.alter(&altered)
+		b, err := altered.Encode()
+		assert.NoError(t, err, test.name)
+		err = Validate(b)
+		assert.Error(t, err, test.name)
+		assert.Equal(t, test.field, GetErrorField(err), test.name)
+	}
+}
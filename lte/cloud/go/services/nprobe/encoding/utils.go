@@ -14,10 +14,14 @@ limitations under the License.
 package encoding
 
 import (
+	"bytes"
 	"encoding/asn1"
 	"encoding/binary"
+	"encoding/hex"
 	"net"
-	"time"
+	"sort"
+	"strconv"
+	"sync"
 
 	"magma/lte/cloud/go/services/nprobe"
 	"magma/orc8r/cloud/go/services/eventd/obsidian/models"
@@ -35,16 +39,6 @@ func convertUint32ToBytes(v uint32) []byte {
 	return o
 }
 
-// encodeGeneralizedTime parses timestamp and marshals it to
-// a byte sequence
-func encodeGeneralizedTime(timestamp string) ([]byte, error) {
-	ptime, err := time.Parse(time.RFC3339Nano, timestamp)
-	if err != nil {
-		return []byte{}, err
-	}
-	return ptime.MarshalBinary()
-}
-
 // decodeRecordType decodes record type (parent structure tag)
 func decodeRecordType(b byte) string {
 	switch b {
@@ -59,11 +53,44 @@ func decodeRecordType(b byte) string {
 	}
 }
 
+// GetRecordClass returns the class of an encoded record from the tag of its
+// payload, RecordClassUnknown if it carries no IRI payload, e.g. for HI1
+// notifications.
+func GetRecordClass(record []byte) string {
+	if len(record) < int(HeaderFixLen) || binary.BigEndian.Uint16(record[12:14]) == HeaderPayloadFormatHI1 {
+		return RecordClassUnknown
+	}
+	hdrLen := binary.BigEndian.Uint32(record[4:8])
+	if uint64(hdrLen) >= uint64(len(record)) {
+		return RecordClassUnknown
+	}
+	return decodeRecordClass(record[hdrLen])
+}
+
+// decodeRecordClass decodes the record class from the record type tag,
+// RecordClassUnknown if it is not an IRI record type
+func decodeRecordClass(b byte) string {
+	switch b {
+	case 0xa1:
+		return RecordClassBegin
+	case 0xa2:
+		return RecordClassEnd
+	case 0xa3:
+		return RecordClassContinue
+	case 0xa4:
+		return RecordClassReport
+	default:
+		return RecordClassUnknown
+	}
+}
+
 // getEPSEventID maps eventd event types to 3GPP event IDs
 func getEPSEventID(eventType string) asn1.Enumerated {
 	switch eventType {
 	case nprobe.SessionCreated:
 		return BearerActivation
+	case nprobe.BearerActive:
+		return StartInterceptWithActiveBearer
 	case nprobe.SessionUpdated:
 		return BearerModification
 	case nprobe.SessionTerminated:
@@ -72,35 +99,120 @@ func getEPSEventID(eventType string) asn1.Enumerated {
 		return EutranAttach
 	case nprobe.DetachSuccess:
 		return EutranDetach
+	case nprobe.TargetReported, nprobe.TrackingAreaUpdate:
+		return LocationUpdate
+	case nprobe.PDNConnectivityRequested:
+		return UERequestedPDNConnectivity
+	case nprobe.PDNDisconnectionRequested:
+		return UERequestedPDNDisconnection
+	case nprobe.ServingSystemChanged, nprobe.UpdateLocationSuccess:
+		return ServingEvolvedPacketSystem
+	case nprobe.HandoverSuccess, nprobe.UsageReported:
+		return BearerModification
+	case nprobe.SMSTransferred:
+		return SMS
 	}
 	return UnsupportedEvent
 }
 
-// getRecordType maps 3GPP event IDs to corresponding record type
-func getRecordType(eventID asn1.Enumerated) string {
+// getDefaultRecordClass returns the class of the record of a 3GPP event ID
+// when the session it concerns is not tracked
+func getDefaultRecordClass(eventID asn1.Enumerated) string {
 	switch eventID {
-	case BearerActivation:
-		return IRIBeginRecord
+	case BearerActivation, StartInterceptWithActiveBearer:
+		return RecordClassBegin
 	case BearerDeactivation:
-		return IRIEndRecord
+		return RecordClassEnd
 	case BearerModification:
+		return RecordClassContinue
+	default:
+		return RecordClassReport
+	}
+}
+
+// GetEventRecordClass returns the class of the record of an event type when
+// the session it concerns is not tracked, e.g. begin for a session created
+func GetEventRecordClass(eventType string) string {
+	return getDefaultRecordClass(getEPSEventID(eventType))
+}
+
+// isRecordClassAllowed returns true if the record of a 3GPP event ID may be
+// of the given class. Bearer activations and modifications begin the
+// interception of their session, or continue it once begun. Bearer
+// deactivations end it, or are reported if it was never begun. Bearers
+// active when interception starts always begin it.
+func isRecordClassAllowed(eventID asn1.Enumerated, class string) bool {
+	switch eventID {
+	case BearerActivation, BearerModification:
+		return class == RecordClassBegin || class == RecordClassContinue
+	case StartInterceptWithActiveBearer:
+		return class == RecordClassBegin
+	case BearerDeactivation:
+		return class == RecordClassEnd || class == RecordClassReport
+	default:
+		return class == RecordClassReport
+	}
+}
+
+// getRecordType returns the ASN.1 options of the record type of a class
+func getRecordType(class string) string {
+	switch class {
+	case RecordClassBegin:
+		return IRIBeginRecord
+	case RecordClassEnd:
+		return IRIEndRecord
+	case RecordClassContinue:
 		return IRIContinueRecord
 	default:
 		return IRIReportRecord
 	}
 }
 
+// getRecordTag returns the implicit tag of the record type of a class
+func getRecordTag(class string) int {
+	switch class {
+	case RecordClassBegin:
+		return 1
+	case RecordClassEnd:
+		return 2
+	case RecordClassContinue:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// makeEPSSpecificParameters returns the EPS specific parameters of the
+// record of an event, completed with the network serving the target
+func makeEPSSpecificParameters(event *models.Event) EPSSpecificParameters {
+	params := processEventSpecificData(event)
+	addRoamingParams(&params, event)
+	return params
+}
+
 // processEventSpecificData process specific data from each events to from
 // 3GPP EPSSpecificParameters structure.
 // Some events does not require this processing.
 func processEventSpecificData(event *models.Event) EPSSpecificParameters {
 	switch event.EventType {
-	case nprobe.SessionCreated:
+	case nprobe.SessionCreated, nprobe.BearerActive:
 		return makeBearerActivationParams(event)
 	case nprobe.SessionUpdated:
 		return makeBearerModificationParams(event)
 	case nprobe.SessionTerminated:
 		return makeBearerDeactivationParams(event)
+	case nprobe.TargetReported:
+		return makeTargetReportParams(event)
+	case nprobe.TrackingAreaUpdate:
+		return makeTrackingAreaUpdateParams(event)
+	case nprobe.PDNConnectivityRequested:
+		return makePDNConnectivityParams(event)
+	case nprobe.PDNDisconnectionRequested:
+		return makePDNDisconnectionParams(event)
+	case nprobe.ServingSystemChanged, nprobe.UpdateLocationSuccess:
+		return makeServingSystemParams(event)
+	case nprobe.HandoverSuccess:
+		return makeHandoverParams(event)
 	}
 	return EPSSpecificParameters{}
 }
@@ -115,22 +227,74 @@ func makeTimestamp(timestamp []byte) Timestamp {
 	}
 }
 
-// makePdnAddressAllocation returns an encoded PDN address allocation
+// makePdnAddressAllocation returns an encoded PDN address allocation, which
+// holds both addresses of dual-stack bearers
 func makePdnAddressAllocation(event *models.Event) []byte {
 	eventData := event.Value.(map[string]interface{})
-	if ipAddr, ok := eventData["ip_addr"]; ok {
-		allocatedIP := []byte{byte(IPV4Type)}
-		return append(allocatedIP, net.ParseIP(ipAddr.(string))[12:16]...)
+	ipv4, ipv6, _ := getUEAddresses(eventData)
+	switch {
+	case ipv4 != nil && ipv6 != nil:
+		allocatedIP := append([]byte{byte(IPV4V6Type)}, ipv4...)
+		return append(allocatedIP, ipv6...)
+	case ipv4 != nil:
+		return append([]byte{byte(IPV4Type)}, ipv4...)
+	case ipv6 != nil:
+		return append([]byte{byte(IPV6Type)}, ipv6...)
 	}
+	return []byte{}
+}
 
+// getUEAddresses returns the IPv4 and IPv6 addresses allocated to a UE, 4
+// and 16 bytes long, and the length of its IPv6 prefix. The IPv6 address is
+// the prefix itself when only the prefix is known.
+func getUEAddresses(eventData map[string]interface{}) (net.IP, net.IP, int) {
+	var ipv4, ipv6 net.IP
+	prefixLen := 0
+	if ipAddr, ok := eventData["ip_addr"]; ok {
+		ipv4 = net.ParseIP(ipAddr.(string)).To4()
+	}
+	if prefix, ok := eventData["ipv6_prefix"]; ok {
+		if _, ipNet, err := net.ParseCIDR(prefix.(string)); err == nil {
+			ipv6 = ipNet.IP.To16()
+			prefixLen, _ = ipNet.Mask.Size()
+		}
+	}
 	if ipv6Addr, ok := eventData["ipv6_addr"]; ok {
-		allocatedIPv6 := []byte{byte(IPV6Type)}
-		return append(allocatedIPv6, net.ParseIP(ipv6Addr.(string))...)
+		ipv6 = net.ParseIP(ipv6Addr.(string)).To16()
+	}
+	return ipv4, ipv6, prefixLen
+}
+
+// makeServicesDataInformation returns the addresses allocated to the target
+// as defined in the asn1 schema
+func makeServicesDataInformation(eventData map[string]interface{}) ServicesDataInformation {
+	ipv4, ipv6, prefixLen := getUEAddresses(eventData)
+	var params GPRSParameters
+	switch {
+	case ipv4 != nil:
+		params.PDPAddress = makeDataNodeAddress(IPV4Type, ipv4, 0)
+		if ipv6 != nil {
+			params.AdditionalIPAddress = makeDataNodeAddress(IPV6Type, ipv6, prefixLen)
+		}
+	case ipv6 != nil:
+		params.PDPAddress = makeDataNodeAddress(IPV6Type, ipv6, prefixLen)
+	}
+	return ServicesDataInformation{GPRSParameters: params}
+}
+
+func makeDataNodeAddress(ipType asn1.Enumerated, ip net.IP, prefixLen int) DataNodeAddress {
+	return DataNodeAddress{
+		IPAddress: IPAddress{
+			IPType:           ipType,
+			IPValue:          IPValue{IPBinaryAddress: []byte(ip)},
+			IPv6PrefixLength: prefixLen,
+		},
 	}
-	return []byte{}
 }
 
-// makeEPSLocation returns an EPSLocation object as definied in the asn1 schema
+// makeEPSLocation returns an EPSLocation object as definied in the asn1 schema.
+// The user location information reported by the event prevails over the one
+// of its TAI and ECGI.
 func makeEPSLocation(event *models.Event) EPSLocation {
 	eventData := event.Value.(map[string]interface{})
 	if userLocation, ok := eventData["user_location"]; ok {
@@ -138,28 +302,100 @@ func makeEPSLocation(event *models.Event) EPSLocation {
 			UserLocationInfo: []byte(userLocation.(string)),
 		}
 	}
-	return EPSLocation{}
+	return EPSLocation{UserLocationInfo: makeUserLocationInfo(eventData)}
 }
 
-// makePartyInformation returns a PartyInformation slice as defined in the asn1 schema
+// makePartyInformation returns a PartyInformation slice as defined in the asn1 schema.
+// It holds the target party and, when the event identifies it, the correspondent
+// party the target communicates with.
 func makePartyInformation(event *models.Event) []PartyInformation {
-	var infoIdentity PartyIdentity
 	eventData := event.Value.(map[string]interface{})
-	if imsi, ok := eventData["imsi"]; ok {
-		infoIdentity.IMSI = []byte(imsi.(string))
+	parties := []PartyInformation{
+		{
+			PartyQualified:          PartyQualifierTarget,
+			PartyIdentity:           makePartyIdentity(eventData, ""),
+			ServicesDataInformation: makeServicesDataInformation(eventData),
+		},
 	}
-	if imei, ok := eventData["imei"]; ok {
-		infoIdentity.IMEI = []byte(imei.(string))
+	correspondent := makePartyIdentity(eventData, correspondentPrefix)
+	if !isEmptyIdentity(&correspondent) {
+		parties = append(parties, PartyInformation{
+			PartyQualified: correspondentQualifiers[eventData["correspondent_qualifier"].(string)],
+			PartyIdentity:  correspondent,
+		})
 	}
-	if msisdn, ok := eventData["msisdn"]; ok {
-		infoIdentity.MSISDN = []byte(msisdn.(string))
+	return parties
+}
+
+// makePartyIdentity returns the identity of a party from the event data keys
+// with the given prefix
+func makePartyIdentity(eventData map[string]interface{}, prefix string) PartyIdentity {
+	var identity PartyIdentity
+	if imsi, ok := eventData[prefix+"imsi"]; ok {
+		identity.IMSI = []byte(imsi.(string))
 	}
-	return []PartyInformation{
-		{
-			PartyQualified: PartyQualifierTarget,
-			PartyIdentity:  infoIdentity,
-		},
+	if imei, ok := eventData[prefix+"imei"]; ok {
+		identity.IMEI = []byte(imei.(string))
+	}
+	if msisdn, ok := eventData[prefix+"msisdn"]; ok {
+		identity.MSISDN = []byte(msisdn.(string))
+	}
+	return identity
+}
+
+func isEmptyIdentity(identity *PartyIdentity) bool {
+	return len(identity.IMSI) == 0 && len(identity.IMEI) == 0 && len(identity.MSISDN) == 0
+}
+
+// partySortPool holds the scratch buffers parties are encoded into to be
+// sorted by sortPartyInformation
+var partySortPool = sync.Pool{
+	New: func() interface{} {
+		return &partiesByEncoding{}
+	},
+}
+
+// sortPartyInformation sorts parties in ascending order of their encoding,
+// as DER requires for the components of a SET OF. Parties are encoded
+// without reflection into a pooled buffer, so that sorting them doesn't
+// allocate once the buffer is large enough.
+func sortPartyInformation(parties []PartyInformation) {
+	if len(parties) < 2 {
+		return
+	}
+	p := partySortPool.Get().(*partiesByEncoding)
+	defer partySortPool.Put(p)
+	p.parties, p.buf, p.spans = parties, p.buf[:0], p.spans[:0]
+	for i := range parties {
+		start := len(p.buf)
+		p.buf = appendPartyInformation(p.buf, constructedForm, tagUniversalSequence, &parties[i])
+		p.spans = append(p.spans, [2]int{start, len(p.buf)})
 	}
+	sort.Sort(p)
+	p.parties = nil
+}
+
+// partiesByEncoding sorts parties by their encoding, the span of each party
+// in buf following it
+type partiesByEncoding struct {
+	parties []PartyInformation
+	buf     []byte
+	spans   [][2]int
+}
+
+func (p *partiesByEncoding) Len() int { return len(p.parties) }
+
+func (p *partiesByEncoding) Less(i, j int) bool {
+	return bytes.Compare(p.encoding(i), p.encoding(j)) < 0
+}
+
+func (p *partiesByEncoding) Swap(i, j int) {
+	p.parties[i], p.parties[j] = p.parties[j], p.parties[i]
+	p.spans[i], p.spans[j] = p.spans[j], p.spans[i]
+}
+
+func (p *partiesByEncoding) encoding(i int) []byte {
+	return p.buf[p.spans[i][0]:p.spans[i][1]]
 }
 
 // makeNetworkIdentifier returns a NetworkIdentifier object as defined in the asn1 schema
@@ -168,6 +404,9 @@ func makeNetworkIdentifier(event *models.Event, operatorID uint32) NetworkIdenti
 	var ipAddr IPAddress
 	if originIP, ok := eventData["spgw_ip"]; ok {
 		ipAddr.IPType = IPV4Type
+		if ip := net.ParseIP(originIP.(string)); ip != nil && ip.To4() == nil {
+			ipAddr.IPType = IPV6Type
+		}
 		ipAddr.IPValue = IPValue{
 			IPBinaryAddress: []byte(originIP.(string)),
 		}
@@ -190,41 +429,333 @@ func makeBearerActivationParams(event *models.Event) EPSSpecificParameters {
 			apn = append(apn, []byte(v.(string))...)
 
 		}
+		bearerID, bearerSessionID := makeBearerIdentity(eventData, sessionID.(string))
 		return EPSSpecificParameters{
-			EPSBearerIdentity:      []byte(sessionID.(string)),
+			EPSBearerIdentity:      bearerID,
+			BearerSessionID:        bearerSessionID,
+			LinkedEPSBearerID:      makeLinkedBearerID(eventData),
 			PDNAddressAllocation:   makePdnAddressAllocation(event),
 			APN:                    apn,
 			RATType:                []byte{RatTypeEutran},
+			EPSBearerQoS:           makeEPSBearerQoS(eventData, ""),
 			BearerActivationType:   DefaultBearer,
+			ApnAmbr:                makeApnAmbr(eventData, ""),
 			EPSLocationOfTheTarget: makeEPSLocation(event),
+			BearerARP:              makeBearerARP(eventData, ""),
 		}
 	}
 	return EPSSpecificParameters{}
 }
 
 // makeBearerModificationParams returns the corresponding EPSSpecificParameters
-// for bearer modification as defined in the asn1 schema
+// for bearer modification as defined in the asn1 schema. The QoS, APN-AMBR
+// and ARP of the bearer are the ones after the modification, along with the
+// ones before it reported by the event for those the modification changed.
 func makeBearerModificationParams(event *models.Event) EPSSpecificParameters {
 	eventData := event.Value.(map[string]interface{})
 	if sessionID, ok := eventData["session_id"]; ok {
-		return EPSSpecificParameters{
-			EPSBearerIdentity:      []byte(sessionID.(string)),
+		bearerID, bearerSessionID := makeBearerIdentity(eventData, sessionID.(string))
+		params := EPSSpecificParameters{
+			EPSBearerIdentity:      bearerID,
+			BearerSessionID:        bearerSessionID,
+			LinkedEPSBearerID:      makeLinkedBearerID(eventData),
+			EPSBearerQoS:           makeEPSBearerQoS(eventData, ""),
+			ApnAmbr:                makeApnAmbr(eventData, ""),
 			EPSLocationOfTheTarget: makeEPSLocation(event),
+			BearerARP:              makeBearerARP(eventData, ""),
 		}
+		params.PreviousEPSBearerQoS = makeModifiedParam(params.EPSBearerQoS, makeEPSBearerQoS(eventData, previousPrefix))
+		params.PreviousApnAmbr = makeModifiedParam(params.ApnAmbr, makeApnAmbr(eventData, previousPrefix))
+		params.PreviousBearerARP = makeModifiedParam(params.BearerARP, makeBearerARP(eventData, previousPrefix))
+		return params
 	}
 	return EPSSpecificParameters{}
 }
 
+// makeModifiedParam returns the value of a bearer parameter before a
+// modification if the modification changed it, nil otherwise
+func makeModifiedParam(current, previous []byte) []byte {
+	if previous == nil || bytes.Equal(current, previous) {
+		return nil
+	}
+	return previous
+}
+
 // makeBearerDeactivationParams returns the corresponding EPSSpecificParameters
 // for bearer deactivation as defined in the asn1 schema
 func makeBearerDeactivationParams(event *models.Event) EPSSpecificParameters {
 	eventData := event.Value.(map[string]interface{})
 	if sessionID, ok := eventData["session_id"]; ok {
+		bearerID, bearerSessionID := makeBearerIdentity(eventData, sessionID.(string))
 		return EPSSpecificParameters{
-			EPSBearerIdentity:      []byte(sessionID.(string)),
+			EPSBearerIdentity:      bearerID,
+			BearerSessionID:        bearerSessionID,
+			LinkedEPSBearerID:      makeLinkedBearerID(eventData),
 			BearerDeactivationType: DefaultBearer,
 			EPSLocationOfTheTarget: makeEPSLocation(event),
 		}
 	}
 	return EPSSpecificParameters{}
 }
+
+// makeTargetReportParams returns the corresponding EPSSpecificParameters for
+// the report of the location and state of a target, which carries the address
+// and APN of its session only when it has one
+func makeTargetReportParams(event *models.Event) EPSSpecificParameters {
+	eventData := event.Value.(map[string]interface{})
+	params := EPSSpecificParameters{
+		EPSLocationOfTheTarget: makeEPSLocation(event),
+	}
+	if pdnAddress := makePdnAddressAllocation(event); len(pdnAddress) != 0 {
+		params.PDNAddressAllocation = pdnAddress
+	}
+	if apn, ok := eventData["apn"]; ok {
+		params.APN = []byte(apn.(string))
+	}
+	return params
+}
+
+// makeTrackingAreaUpdateParams returns the corresponding EPSSpecificParameters
+// for a tracking area update, along with the reason it failed if it did
+func makeTrackingAreaUpdateParams(event *models.Event) EPSSpecificParameters {
+	eventData := event.Value.(map[string]interface{})
+	return EPSSpecificParameters{
+		FailedTAUReason:        makeCause(eventData),
+		EPSLocationOfTheTarget: makeEPSLocation(event),
+	}
+}
+
+// makePDNConnectivityParams returns the corresponding EPSSpecificParameters
+// for a PDN connectivity requested by the UE, along with the reason it failed
+// if it did
+func makePDNConnectivityParams(event *models.Event) EPSSpecificParameters {
+	eventData := event.Value.(map[string]interface{})
+	params := EPSSpecificParameters{
+		PDNAddressAllocation:   makePdnAddressAllocation(event),
+		UEReqPDNConnFailReason: makeCause(eventData),
+		EPSLocationOfTheTarget: makeEPSLocation(event),
+	}
+	if len(params.PDNAddressAllocation) == 0 {
+		params.PDNAddressAllocation = nil
+	}
+	if apn, ok := eventData["apn"]; ok {
+		params.APN = []byte(apn.(string))
+	}
+	if sessionID, ok := eventData["session_id"]; ok {
+		params.EPSBearerIdentity, params.BearerSessionID = makeBearerIdentity(eventData, sessionID.(string))
+	}
+	if pdnType, ok := eventData["pdn_type"]; ok {
+		params.PDNType = []byte{pdnTypes[pdnType.(string)]}
+	}
+	if requestType, ok := eventData["request_type"]; ok {
+		params.RequestType = []byte{requestTypes[requestType.(string)]}
+	}
+	return params
+}
+
+// makePDNDisconnectionParams returns the corresponding EPSSpecificParameters
+// for a PDN disconnection requested by the UE
+func makePDNDisconnectionParams(event *models.Event) EPSSpecificParameters {
+	eventData := event.Value.(map[string]interface{})
+	params := EPSSpecificParameters{
+		LinkedEPSBearerID:      makeLinkedBearerID(eventData),
+		EPSLocationOfTheTarget: makeEPSLocation(event),
+	}
+	if sessionID, ok := eventData["session_id"]; ok {
+		params.EPSBearerIdentity, params.BearerSessionID = makeBearerIdentity(eventData, sessionID.(string))
+	}
+	return params
+}
+
+// makeServingSystemParams returns the corresponding EPSSpecificParameters for
+// the report of the MME serving the target
+func makeServingSystemParams(event *models.Event) EPSSpecificParameters {
+	eventData := event.Value.(map[string]interface{})
+	params := EPSSpecificParameters{
+		EPSLocationOfTheTarget: makeEPSLocation(event),
+	}
+	if mmeAddr, ok := eventData["mme_address"]; ok {
+		params.ServingMMEAddress = makeIPAddress(mmeAddr.(string))
+	}
+	return params
+}
+
+// makeHandoverParams returns the corresponding EPSSpecificParameters for the
+// bearer modification of a handover, which carries the handover indication
+func makeHandoverParams(event *models.Event) EPSSpecificParameters {
+	params := makeBearerModificationParams(event)
+	if len(params.EPSBearerIdentity) != 0 {
+		params.HandoverIndication = []byte{}
+	}
+	return params
+}
+
+// makeSMSReport returns the SMSReport of the SMS transferred by an event, the
+// zero value for the other events
+func makeSMSReport(event *models.Event) SMSReport {
+	if event.EventType != nprobe.SMSTransferred {
+		return SMSReport{}
+	}
+	eventData := event.Value.(map[string]interface{})
+	contents := SMSContents{
+		Initiator:      SMSInitiatorUndefined,
+		TransferStatus: SMSTransferUndefined,
+		OtherMessage:   SMSOtherMessageUndefined,
+	}
+	if initiator, ok := eventData["sms_initiator"]; ok {
+		contents.Initiator = smsInitiators[initiator.(string)]
+	}
+	if status, ok := eventData["sms_transfer_status"]; ok {
+		contents.TransferStatus = smsTransferStatuses[status.(string)]
+	}
+	if otherMessage, ok := eventData["sms_other_message"]; ok {
+		contents.OtherMessage = smsOtherMessages[otherMessage.(string)]
+	}
+	if content, ok := eventData["sms_content"]; ok {
+		contents.Content, _ = hex.DecodeString(content.(string))
+	}
+	return SMSReport{SMSContents: contents}
+}
+
+// makeCause returns the encoded cause of a failed procedure if any
+func makeCause(eventData map[string]interface{}) []byte {
+	cause, ok := eventData["failure_cause"]
+	if !ok {
+		return nil
+	}
+	v, _ := strconv.ParseUint(cause.(string), 10, 8)
+	return []byte{byte(v)}
+}
+
+// makeBearerIdentity returns the EPS bearer identity of the bearer of a
+// session, which is the EPS bearer ID coded as the value of the EBI IE of
+// TS 29.274 when reported by the gateway, along with the session ID kept as
+// a vendor extension. Otherwise the identity is the session ID, e.g.
+// IMSI001010000000001-919642, without extension.
+func makeBearerIdentity(eventData map[string]interface{}, sessionID string) ([]byte, []byte) {
+	bearerID, ok := eventData["bearer_id"]
+	if !ok {
+		return []byte(sessionID), nil
+	}
+	v, _ := strconv.ParseUint(bearerID.(string), 10, 4)
+	return []byte{byte(v)}, []byte(sessionID)
+}
+
+// makeLinkedBearerID returns the encoded ID of the default bearer of a PDN
+// connection if any
+func makeLinkedBearerID(eventData map[string]interface{}) []byte {
+	bearerID, ok := eventData["linked_bearer_id"]
+	if !ok {
+		return nil
+	}
+	v, _ := strconv.ParseUint(bearerID.(string), 10, 8)
+	return []byte{byte(v)}
+}
+
+// makeEPSBearerQoS returns the EPS quality of service of a bearer, coded as
+// the value part of the IE of TS 24.301 9.9.4.3, if its QCI is known. The
+// maximum and guaranteed bit rates of a GBR bearer follow the QCI when all
+// four are known, along with their extended octets when any is needed. The
+// event data keys are prefixed by prefix, e.g. previous_qci.
+func makeEPSBearerQoS(eventData map[string]interface{}, prefix string) []byte {
+	qci, ok := eventData[prefix+"qci"]
+	if !ok {
+		return nil
+	}
+	v, _ := strconv.ParseUint(qci.(string), 10, 8)
+	qos := []byte{byte(v)}
+	rates := make([]uint64, 0, 4)
+	for _, key := range []string{"mbr_ul", "mbr_dl", "gbr_ul", "gbr_dl"} {
+		rate, ok := eventData[prefix+key]
+		if !ok {
+			return qos
+		}
+		bps, _ := strconv.ParseUint(rate.(string), 10, 32)
+		rates = append(rates, bps)
+	}
+	exts := make([]byte, 0, 4)
+	hasExt := false
+	for _, bps := range rates {
+		rate, ext := encodeBitRate(bps / 1000)
+		qos = append(qos, rate)
+		exts = append(exts, ext)
+		hasExt = hasExt || ext != 0
+	}
+	if hasExt {
+		qos = append(qos, exts...)
+	}
+	return qos
+}
+
+// makeApnAmbr returns the aggregate maximum bit rates of an APN, coded as
+// the value part of the IE of TS 24.301 9.9.4.2, if both are known. Rates
+// beyond 256 Mbps, which need the extended-2 octets, are capped to 256 Mbps.
+// The event data keys are prefixed by prefix, e.g. previous_apn_ambr_ul.
+func makeApnAmbr(eventData map[string]interface{}, prefix string) []byte {
+	ul, okUL := eventData[prefix+"apn_ambr_ul"]
+	dl, okDL := eventData[prefix+"apn_ambr_dl"]
+	if !okUL || !okDL {
+		return nil
+	}
+	ulBps, _ := strconv.ParseUint(ul.(string), 10, 32)
+	dlBps, _ := strconv.ParseUint(dl.(string), 10, 32)
+	dlRate, dlExt := encodeBitRate(dlBps / 1000)
+	ulRate, ulExt := encodeBitRate(ulBps / 1000)
+	if dlExt == 0 && ulExt == 0 {
+		return []byte{dlRate, ulRate}
+	}
+	return []byte{dlRate, ulRate, dlExt, ulExt}
+}
+
+// makeBearerARP returns the allocation and retention priority of a bearer,
+// coded as the octet of the ARP IE of TS 29.274 8.86, if its priority level
+// is known. Pre-emption capability and vulnerability not reported are
+// disabled. The event data keys are prefixed by prefix, e.g.
+// previous_arp_priority_level.
+func makeBearerARP(eventData map[string]interface{}, prefix string) []byte {
+	level, ok := eventData[prefix+"arp_priority_level"]
+	if !ok {
+		return nil
+	}
+	v, _ := strconv.ParseUint(level.(string), 10, 4)
+	arp := byte(v) << 2
+	if capability, _ := eventData[prefix+"arp_preemption_capability"].(string); capability != "true" {
+		arp |= 0x40
+	}
+	if vulnerability, _ := eventData[prefix+"arp_preemption_vulnerability"].(string); vulnerability != "true" {
+		arp |= 0x01
+	}
+	return []byte{arp}
+}
+
+// encodeBitRate returns the octet and extended octet coding a bit rate in
+// kbps as defined in TS 24.301 9.9.4.2, rounded down to the closest step
+func encodeBitRate(kbps uint64) (byte, byte) {
+	switch {
+	case kbps == 0:
+		return 0xff, 0
+	case kbps <= 63:
+		return byte(kbps), 0
+	case kbps <= 568:
+		return 0x40 + byte((kbps-64)/8), 0
+	case kbps <= 8640:
+		return 0x80 + byte((kbps-576)/64), 0
+	case kbps <= 16000:
+		return 0xfe, byte((kbps - 8600) / 100)
+	case kbps <= 128000:
+		return 0xfe, 0x4a + byte((kbps-16000)/1000)
+	case kbps <= 256000:
+		return 0xfe, 0xba + byte((kbps-128000)/2000)
+	}
+	return 0xfe, 0xfa
+}
+
+// makeIPAddress returns the binary form of an IP address, 4 bytes long for
+// IPv4 addresses
+func makeIPAddress(addr string) []byte {
+	ip := net.ParseIP(addr)
+	if ip4 := ip.To4(); ip4 != nil {
+		return []byte(ip4)
+	}
+	return []byte(ip)
+}
@@ -0,0 +1,469 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// ValidationStrict rejects malformed records
+	ValidationStrict = "strict"
+	// ValidationFlag reports malformed records but still exports them
+	ValidationFlag = "flag"
+	// ValidationDisabled skips record validation
+	ValidationDisabled = "disabled"
+)
+
+const (
+	// Fields reported by validation errors in addition to the encoding ones
+	FieldHeader        = "header"
+	FieldDER           = "der"
+	FieldModuleVersion = "module_version"
+)
+
+// ValidationError reports the field of an encoded record which is malformed
+type ValidationError struct {
+	Field string
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("malformed %s: %v", e.Field, e.Err)
+}
+
+func newValidationError(field string, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{Field: field, Err: fmt.Errorf(format, args...)}
+}
+
+// Validate decodes an encoded record and verifies that it is well-formed
+// before it is exported: the header is consistent with the payload, the
+// payload is in DER canonical form, i.e. re-encoding the decoded content
+// yields the same bytes, the fields mandatory for its event type are
+// present and the payload only carries fields defined by the module version
+// its domain ID identifies. A *ValidationError reporting the offending field is returned
+// for malformed records. Records of the UMTS domain are validated likewise.
+// Only the domain IDs of the releases are recognized.
+func Validate(record []byte) error {
+	return ValidateDomainIDs(record, nil)
+}
+
+// ValidateDomainIDs validates a record like Validate, recognizing the domain
+// IDs configured in the network of the record as well
+func ValidateDomainIDs(record []byte, domainIDs DomainIDs) error {
+	hdr, err := ParseHeader(record)
+	if err != nil {
+		return &ValidationError{Field: FieldDER, Err: err}
+	}
+	if isUmtsPayload(record[hdr.HeaderLength:]) {
+		return validateUmtsRecord(hdr, record)
+	}
+	var r EpsIRIRecord
+	if err := r.Decode(record); err != nil {
+		return &ValidationError{Field: FieldDER, Err: err}
+	}
+	if err := validateHeader(&r.Header, len(record)); err != nil {
+		return err
+	}
+
+	content := record[r.Header.HeaderLength:]
+	if !isRecordClassAllowed(r.Payload.EPSEvent, r.Class) {
+		return newValidationError(FieldEventType, "record type %x does not match event %d", content[0], r.Payload.EPSEvent)
+	}
+	canonical, err := asn1.MarshalWithParams(r.Payload, getRecordType(r.Class))
+	if err != nil {
+		return &ValidationError{Field: FieldDER, Err: err}
+	}
+	if !bytes.Equal(canonical, content) {
+		return newValidationError(FieldDER, "payload is not in canonical form")
+	}
+	return validateContent(&r.Header, &r.Payload, domainIDs)
+}
+
+// validateHeader checks the fixed fields and mandatory conditional
+// attributes of a record header
+func validateHeader(hdr *EpsIRIHeader, recordLen int) error {
+	if !IsHeaderVersionSupported(hdr.Version) || hdr.PduType != HeaderPduType || hdr.PayloadFormat != HeaderPayloadFormat {
+		return newValidationError(FieldHeader, "unexpected version %d, PDU type %d or payload format %d",
+			hdr.Version, hdr.PduType, hdr.PayloadFormat)
+	}
+	if err := validateHeaderVersion(hdr); err != nil {
+		return &ValidationError{Field: FieldHeader, Err: err}
+	}
+	if uint64(hdr.HeaderLength)+uint64(hdr.PayloadLength) != uint64(recordLen) {
+		return newValidationError(FieldHeader, "declared length %d+%d does not match record length %d",
+			hdr.HeaderLength, hdr.PayloadLength, recordLen)
+	}
+	if hdr.PayloadLength == 0 {
+		return newValidationError(FieldHeader, "empty payload")
+	}
+	if hdr.XID == uuid.Nil {
+		return newValidationError(FieldTaskID, "missing XID")
+	}
+
+	for _, tag := range []uint16{AttributeTargetID, AttributeTimestamp, AttributeSeqNumber} {
+		if len(getAttribute(hdr, tag)) == 0 {
+			return newValidationError(FieldHeader, "missing conditional attribute %d", tag)
+		}
+	}
+	if _, ok := GetSequenceNumber(hdr); !ok {
+		return newValidationError(FieldHeader, "invalid sequence number")
+	}
+	return nil
+}
+
+// validateContent checks the fields mandatory for the event type of a record
+func validateContent(hdr *EpsIRIHeader, content *EpsIRIContent, domainIDs DomainIDs) error {
+	version := domainIDs.get(content.Hi2epsDomainID)
+	if version == nil {
+		return newValidationError(FieldDER, "unexpected domain ID %v", content.Hi2epsDomainID)
+	}
+	if err := version.checkFields(content); err != nil {
+		return &ValidationError{Field: FieldModuleVersion, Err: err}
+	}
+
+	timestamp := content.TimeStamp.LocalTime.GeneralizedTime
+	if _, err := decodeGeneralizedTime(timestamp); err != nil {
+		return &ValidationError{Field: FieldTimestamp, Err: err}
+	}
+	if !bytes.Equal(timestamp, getAttribute(hdr, AttributeTimestamp)) {
+		return newValidationError(FieldTimestamp, "payload and header timestamps differ")
+	}
+
+	if len(content.EPSCorrelationNumber) != 8 ||
+		binary.BigEndian.Uint64(content.EPSCorrelationNumber) != hdr.CorrelationID {
+		return newValidationError(FieldTaskDetails, "correlation number does not match header")
+	}
+	if len(content.NetworkIdentifier.OperatorIdentifier) == 0 {
+		return newValidationError(FieldNetworkIdentifier, "missing operator identifier")
+	}
+	if err := validatePartyInformation(content.PartyInformation); err != nil {
+		return err
+	}
+	hasIdentity := !isEmptyIdentity(getTargetIdentity(content.PartyInformation))
+
+	if content.EPSEvent != SMS && !isZeroSMSReport(&content.SMS) {
+		return newValidationError(FieldSMS, "unexpected SMS report")
+	}
+	if national := content.NationalParameters; national != (NationalParameters{}) {
+		if content.EPSEvent != BearerModification {
+			return newValidationError(FieldUsage, "unexpected usage report")
+		}
+		if len(national.CountryCode) != 2 {
+			return newValidationError(FieldUsage, "invalid country code %q", national.CountryCode)
+		}
+	}
+
+	params := content.EPSSpecificParameters
+	if err := validateRoamingParams(&params); err != nil {
+		return err
+	}
+	// the network serving the target completes the records of all the events
+	params.VisitedPLMNID, params.ServingNetwork, params.RoamingStatus = nil, nil, 0
+	hasBearer := len(params.EPSBearerIdentity) != 0
+	switch content.EPSEvent {
+	case EutranAttach, EutranDetach:
+		if !hasIdentity {
+			return newValidationError(FieldIdentity, "missing target identity")
+		}
+		if !isEmptyParams(&params) {
+			return newValidationError(FieldBearerParams, "unexpected bearer parameters")
+		}
+	case BearerActivation, StartInterceptWithActiveBearer, BearerModification, BearerDeactivation:
+		// terminal records carry no bearer information
+		if !hasBearer {
+			if !isEmptyParams(&params) {
+				return newValidationError(FieldBearerParams, "bearer parameters without bearer identity")
+			}
+			return nil
+		}
+		if !hasIdentity {
+			return newValidationError(FieldIdentity, "missing target identity")
+		}
+		return validateBearerParams(content.EPSEvent, &params)
+	case LocationUpdate:
+		// the test records delivered to destinations report no target
+		if _, test := GetTestRecordID(hdr); !hasIdentity && !test {
+			return newValidationError(FieldIdentity, "missing target identity")
+		}
+		if err := validatePdnAddressAllocation(params.PDNAddressAllocation); err != nil {
+			return &ValidationError{Field: FieldBearerParams, Err: err}
+		}
+		if err := validateOctets(params.FailedTAUReason); err != nil {
+			return err
+		}
+		// reports only carry the location, address and APN of the target,
+		// and tracking area updates the reason they failed
+		params.PDNAddressAllocation, params.APN, params.EPSLocationOfTheTarget = nil, nil, EPSLocation{}
+		params.FailedTAUReason = nil
+		if !isEmptyParams(&params) {
+			return newValidationError(FieldBearerParams, "unexpected bearer parameters")
+		}
+	case UERequestedPDNConnectivity:
+		if !hasIdentity {
+			return newValidationError(FieldIdentity, "missing target identity")
+		}
+		if err := validatePdnAddressAllocation(params.PDNAddressAllocation); err != nil {
+			return &ValidationError{Field: FieldBearerParams, Err: err}
+		}
+		if err := validateOctets(params.PDNType, params.RequestType, params.UEReqPDNConnFailReason); err != nil {
+			return err
+		}
+		params.PDNAddressAllocation, params.APN, params.EPSBearerIdentity, params.EPSLocationOfTheTarget = nil, nil, nil, EPSLocation{}
+		params.PDNType, params.RequestType, params.UEReqPDNConnFailReason, params.BearerSessionID = nil, nil, nil, nil
+		if !isEmptyParams(&params) {
+			return newValidationError(FieldBearerParams, "unexpected bearer parameters")
+		}
+	case UERequestedPDNDisconnection:
+		if !hasIdentity {
+			return newValidationError(FieldIdentity, "missing target identity")
+		}
+		if err := validateOctets(params.LinkedEPSBearerID); err != nil {
+			return err
+		}
+		params.EPSBearerIdentity, params.LinkedEPSBearerID, params.EPSLocationOfTheTarget = nil, nil, EPSLocation{}
+		params.BearerSessionID = nil
+		if !isEmptyParams(&params) {
+			return newValidationError(FieldBearerParams, "unexpected bearer parameters")
+		}
+	case ServingEvolvedPacketSystem:
+		if !hasIdentity {
+			return newValidationError(FieldIdentity, "missing target identity")
+		}
+		if n := len(params.ServingMMEAddress); n != 0 && n != net.IPv4len && n != net.IPv6len {
+			return newValidationError(FieldBearerParams, "invalid serving MME address length %d", n)
+		}
+		params.ServingMMEAddress, params.EPSLocationOfTheTarget = nil, EPSLocation{}
+		if !isEmptyParams(&params) {
+			return newValidationError(FieldBearerParams, "unexpected bearer parameters")
+		}
+	case SMS:
+		if !hasIdentity {
+			return newValidationError(FieldIdentity, "missing target identity")
+		}
+		if !isEmptyParams(&params) {
+			return newValidationError(FieldBearerParams, "unexpected bearer parameters")
+		}
+		return validateSMSReport(&content.SMS)
+	default:
+		return newValidationError(FieldEventType, "unsupported event %d", content.EPSEvent)
+	}
+	return nil
+}
+
+// validatePartyInformation checks that a record identifies a single target
+// party and that the other parties are identified correspondents. The order
+// of the parties is verified with the DER canonical form.
+func validatePartyInformation(parties []PartyInformation) error {
+	targets := 0
+	for _, party := range parties {
+		switch party.PartyQualified {
+		case PartyQualifierTarget:
+			targets++
+		case PartyQualifierOriginating, PartyQualifierTerminating, PartyQualifierForwardedTo:
+			if isEmptyIdentity(&party.PartyIdentity) {
+				return newValidationError(FieldIdentity, "missing correspondent identity")
+			}
+		default:
+			return newValidationError(FieldIdentity, "unknown party qualifier %d", party.PartyQualified)
+		}
+		params := &party.ServicesDataInformation.GPRSParameters
+		for _, address := range []*IPAddress{&params.PDPAddress.IPAddress, &params.AdditionalIPAddress.IPAddress} {
+			if err := validateIPAddress(address); err != nil {
+				return &ValidationError{Field: FieldBearerParams, Err: err}
+			}
+		}
+	}
+	if targets != 1 {
+		return newValidationError(FieldIdentity, "expected a single target party, got %d", targets)
+	}
+	return nil
+}
+
+// validateBearerParams checks the bearer parameters mandatory for an event,
+// and the length of its bearer IDs. The bearer identity is the session ID
+// unless the numeric bearer ID is known, then carried by the vendor extension.
+func validateBearerParams(eventID asn1.Enumerated, params *EPSSpecificParameters) error {
+	if len(params.BearerSessionID) != 0 && len(params.EPSBearerIdentity) != 1 {
+		return newValidationError(FieldBearerParams, "invalid EPS bearer ID length %d", len(params.EPSBearerIdentity))
+	}
+	if err := validateOctets(params.LinkedEPSBearerID, params.BearerARP, params.PreviousBearerARP); err != nil {
+		return err
+	}
+	if err := validateEPSBearerQoS(params.EPSBearerQoS, params.PreviousEPSBearerQoS); err != nil {
+		return err
+	}
+	if eventID != BearerModification &&
+		(params.PreviousEPSBearerQoS != nil || params.PreviousApnAmbr != nil || params.PreviousBearerARP != nil) {
+		return newValidationError(FieldBearerParams, "bearer parameters before a modification of an unmodified bearer")
+	}
+	switch eventID {
+	case BearerActivation, StartInterceptWithActiveBearer:
+		if len(params.RATType) != 1 {
+			return newValidationError(FieldBearerParams, "invalid RAT type")
+		}
+		if !isBearerType(params.BearerActivationType) {
+			return newValidationError(FieldBearerParams, "invalid bearer activation type %d", params.BearerActivationType)
+		}
+		if err := validatePdnAddressAllocation(params.PDNAddressAllocation); err != nil {
+			return &ValidationError{Field: FieldBearerParams, Err: err}
+		}
+	case BearerDeactivation:
+		if !isBearerType(params.BearerDeactivationType) {
+			return newValidationError(FieldBearerParams, "invalid bearer deactivation type %d", params.BearerDeactivationType)
+		}
+	}
+	return nil
+}
+
+// validatePdnAddressAllocation checks the length of an allocated address
+// against its type, no address being allocated is valid
+func validatePdnAddressAllocation(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	switch asn1.Enumerated(b[0]) {
+	case IPV4Type:
+		if len(b) == 1+4 {
+			return nil
+		}
+	case IPV6Type:
+		if len(b) == 1+16 {
+			return nil
+		}
+	case IPV4V6Type:
+		if len(b) == 1+4+16 {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown PDN address type %d", b[0])
+	}
+	return errors.New("invalid PDN address length")
+}
+
+// validateIPAddress checks the length of an address against its type, and
+// that only IPv6 addresses have a prefix length. Omitted addresses are valid.
+func validateIPAddress(address *IPAddress) error {
+	if isZeroIPAddress(address) {
+		return nil
+	}
+	n := len(address.IPValue.IPBinaryAddress)
+	switch address.IPType {
+	case IPV4Type:
+		if n != net.IPv4len || address.IPv6PrefixLength != 0 {
+			return fmt.Errorf("invalid IPv4 address of length %d", n)
+		}
+	case IPV6Type:
+		if n != net.IPv6len || address.IPv6PrefixLength < 0 || address.IPv6PrefixLength > 128 {
+			return fmt.Errorf("invalid IPv6 address of length %d and prefix length %d", n, address.IPv6PrefixLength)
+		}
+	default:
+		return fmt.Errorf("unknown IP address type %d", address.IPType)
+	}
+	return nil
+}
+
+func isBearerType(bearerType asn1.Enumerated) bool {
+	return bearerType == DefaultBearer || bearerType == DedicatedBearer
+}
+
+func isEmptyParams(params *EPSSpecificParameters) bool {
+	return len(params.PDNAddressAllocation) == 0 &&
+		len(params.APN) == 0 &&
+		len(params.EPSBearerIdentity) == 0 &&
+		len(params.DetachType) == 0 &&
+		len(params.RATType) == 0 &&
+		len(params.FailedBearerActReason) == 0 &&
+		len(params.EPSBearerQoS) == 0 &&
+		len(params.ApnAmbr) == 0 &&
+		len(params.LinkedEPSBearerID) == 0 &&
+		params.HandoverIndication == nil &&
+		len(params.FailedTAUReason) == 0 &&
+		len(params.ServingMMEAddress) == 0 &&
+		params.BearerActivationType == 0 &&
+		params.BearerDeactivationType == 0 &&
+		len(params.EPSLocationOfTheTarget.UserLocationInfo) == 0 &&
+		len(params.PDNType) == 0 &&
+		len(params.RequestType) == 0 &&
+		len(params.UEReqPDNConnFailReason) == 0 &&
+		len(params.BearerSessionID) == 0 &&
+		len(params.BearerARP) == 0 &&
+		len(params.PreviousEPSBearerQoS) == 0 &&
+		len(params.PreviousApnAmbr) == 0 &&
+		len(params.PreviousBearerARP) == 0
+}
+
+// validateEPSBearerQoS checks that EPS bearer QoS are either omitted or
+// carry the QCI alone, along with the bit rates of a GBR bearer, or along
+// with their extended octets
+func validateEPSBearerQoS(qos ...[]byte) error {
+	for _, q := range qos {
+		switch len(q) {
+		case 0, 1, 5, 9:
+		default:
+			return newValidationError(FieldBearerParams, "invalid EPS bearer QoS length %d", len(q))
+		}
+	}
+	return nil
+}
+
+// validateOctets checks that single octet parameters, e.g. causes, are
+// either omitted or a single octet long
+func validateOctets(params ...[]byte) error {
+	for _, param := range params {
+		if param != nil && len(param) != 1 {
+			return newValidationError(FieldBearerParams, "invalid parameter length %d", len(param))
+		}
+	}
+	return nil
+}
+
+// validateSMSReport checks that an SMS report is present and its
+// enumerations are known
+func validateSMSReport(report *SMSReport) error {
+	if isZeroSMSReport(report) {
+		return newValidationError(FieldSMS, "missing SMS report")
+	}
+	contents := &report.SMSContents
+	if contents.Initiator < SMSInitiatorTarget || contents.Initiator > SMSInitiatorUndefined {
+		return newValidationError(FieldSMS, "unknown SMS initiator %d", contents.Initiator)
+	}
+	if contents.TransferStatus < SMSTransferSucceeded || contents.TransferStatus > SMSTransferUndefined {
+		return newValidationError(FieldSMS, "unknown SMS transfer status %d", contents.TransferStatus)
+	}
+	if contents.OtherMessage < SMSOtherMessageYes || contents.OtherMessage > SMSOtherMessageUndefined {
+		return newValidationError(FieldSMS, "unknown SMS other message indication %d", contents.OtherMessage)
+	}
+	if contents.Content != nil && (len(contents.Content) == 0 || len(contents.Content) > maxSMSContentLen) {
+		return newValidationError(FieldSMS, "invalid SMS content length %d", len(contents.Content))
+	}
+	return nil
+}
+
+// getAttribute returns the value of a conditional attribute of a header if any
+func getAttribute(hdr *EpsIRIHeader, tag uint16) []byte {
+	for _, attr := range hdr.ConditionalAttributes {
+		if attr.Tag == tag {
+			return attr.Value
+		}
+	}
+	return nil
+}
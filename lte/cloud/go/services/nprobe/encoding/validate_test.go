@@ -0,0 +1,285 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// reencode decodes the test record, alters it and encodes it back
+func reencode(t *testing.T, alter func(r *EpsIRIRecord)) []byte {
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(encodedRecord))
+	alter(&record)
+	b, err := record.Encode()
+	assert.NoError(t, err)
+	return b
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(encodedRecord))
+
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			CorrelationID: 0x866cb397915ffe4,
+		},
+	}
+	for _, eventType := range []string{nprobe.SessionCreated, nprobe.SessionUpdated, nprobe.SessionTerminated, nprobe.AttachSuccess} {
+		event := eventdM.Event{
+			EventType:  eventType,
+			StreamName: nprobe.ESStreamSessionD,
+			Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+			Value: map[string]interface{}{
+				"imsi":       "IMSI001010000000001",
+				"session_id": "IMSI001010000000001-919642",
+				"ipv6_addr":  "2001:db8::1",
+			},
+		}
+		b, err := MakeRecord(&event, task, 49002, 1)
+		assert.NoError(t, err)
+		assert.NoError(t, Validate(b), eventType)
+	}
+
+	// records whose class depends on the state of their session
+	transitions := map[string]string{
+		nprobe.SessionCreated:    RecordClassContinue,
+		nprobe.SessionUpdated:    RecordClassBegin,
+		nprobe.SessionTerminated: RecordClassReport,
+	}
+	for eventType, class := range transitions {
+		event := eventdM.Event{
+			EventType:  eventType,
+			StreamName: nprobe.ESStreamSessionD,
+			Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+			Value: map[string]interface{}{
+				"imsi":       "IMSI001010000000001",
+				"session_id": "IMSI001010000000001-919642",
+			},
+		}
+		b, err := MakeRecordWithClass(&event, task, 49002, 1, class)
+		assert.NoError(t, err)
+		assert.NoError(t, Validate(b), eventType)
+	}
+	b, err := MakeTerminalRecord(task, 49002, 1, time.Unix(1615000000, 0), moduleVersions[ModuleVersionR13])
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(b))
+}
+
+func TestValidateMalformed(t *testing.T) {
+	// trailing bytes
+	err := Validate(append(append([]byte(nil), encodedRecord...), 0x00))
+	assert.Equal(t, FieldHeader, GetErrorField(err))
+
+	// non-minimal length encoding of the payload
+	hdrLen := 0x66
+	nonDER := append([]byte(nil), encodedRecord[:hdrLen]...)
+	nonDER = append(nonDER, encodedRecord[hdrLen], 0x82, 0x00, 0xe8)
+	nonDER = append(nonDER, encodedRecord[hdrLen+3:]...)
+	nonDER[11]++
+	err = Validate(nonDER)
+	assert.Equal(t, FieldDER, GetErrorField(err))
+
+	// record type not matching the event
+	mistyped := append([]byte(nil), encodedRecord...)
+	mistyped[hdrLen] = 0xa2
+	err = Validate(mistyped)
+	assert.Equal(t, FieldEventType, GetErrorField(err))
+	assert.EqualError(t, err, "malformed event_type: record type a2 does not match event 18")
+
+	err = Validate(reencode(t, func(r *EpsIRIRecord) {
+		r.Payload.PartyInformation[0].PartyIdentity = PartyIdentity{}
+	}))
+	assert.Equal(t, FieldIdentity, GetErrorField(err))
+
+	err = Validate(reencode(t, func(r *EpsIRIRecord) {
+		r.Payload.EPSSpecificParameters.PDNAddressAllocation = []byte{byte(IPV4Type), 192, 168}
+	}))
+	assert.EqualError(t, err, "malformed bearer_params: invalid PDN address length")
+
+	err = Validate(reencode(t, func(r *EpsIRIRecord) {
+		r.Payload.PartyInformation[0].ServicesDataInformation.GPRSParameters.PDPAddress =
+			makeDataNodeAddress(IPV4Type, []byte{192, 168, 128, 12, 0}, 0)
+	}))
+	assert.EqualError(t, err, "malformed bearer_params: invalid IPv4 address of length 5")
+
+	err = Validate(reencode(t, func(r *EpsIRIRecord) {
+		r.Payload.EPSSpecificParameters.BearerActivationType = 0
+	}))
+	assert.Equal(t, FieldBearerParams, GetErrorField(err))
+
+	err = Validate(reencode(t, func(r *EpsIRIRecord) {
+		r.Payload.EPSCorrelationNumber = convertUint64ToBytes(1)
+	}))
+	assert.Equal(t, FieldTaskDetails, GetErrorField(err))
+
+	err = Validate(reencode(t, func(r *EpsIRIRecord) {
+		r.Payload.TimeStamp.LocalTime.GeneralizedTime = []byte("20210218051326Z")
+	}))
+	assert.Equal(t, FieldTimestamp, GetErrorField(err))
+
+	err = Validate(reencode(t, func(r *EpsIRIRecord) {
+		r.Header.ConditionalAttributes = r.Header.ConditionalAttributes[:2]
+		r.Header.HeaderLength = HeaderFixLen
+		for _, attr := range r.Header.ConditionalAttributes {
+			r.Header.HeaderLength += uint32(attr.Len) + 4
+		}
+	}))
+	assert.Equal(t, FieldHeader, GetErrorField(err))
+}
+
+func TestValidatePartyInformation(t *testing.T) {
+	target := PartyInformation{
+		PartyQualified: PartyQualifierTarget,
+		PartyIdentity:  PartyIdentity{IMSI: []byte("IMSI001010000000001")},
+	}
+	correspondent := PartyInformation{
+		PartyQualified: PartyQualifierOriginating,
+		PartyIdentity:  PartyIdentity{MSISDN: []byte("33687654321")},
+	}
+	assert.NoError(t, validatePartyInformation([]PartyInformation{correspondent, target}))
+
+	err := validatePartyInformation([]PartyInformation{correspondent})
+	assert.EqualError(t, err, "malformed identity: expected a single target party, got 0")
+	err = validatePartyInformation([]PartyInformation{target, target})
+	assert.EqualError(t, err, "malformed identity: expected a single target party, got 2")
+	err = validatePartyInformation([]PartyInformation{target, {PartyQualified: PartyQualifierTerminating}})
+	assert.EqualError(t, err, "malformed identity: missing correspondent identity")
+	err = validatePartyInformation([]PartyInformation{target, {PartyQualified: 7, PartyIdentity: correspondent.PartyIdentity}})
+	assert.EqualError(t, err, "malformed identity: unknown party qualifier 7")
+}
+
+func TestValidateModuleVersion(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			CorrelationID: 0x866cb397915ffe4,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.PDNConnectivityRequested,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":         "IMSI001010000000001",
+			"ipv6_prefix":  "2001:db8:1:2::/64",
+			"request_type": "initial",
+		},
+	}
+
+	for _, name := range GetModuleVersions() {
+		version, err := GetModuleVersion(name)
+		assert.NoError(t, err)
+		b, err := MakeVersionedRecord(&event, task, 49002, 1, RecordClassReport, version)
+		assert.NoError(t, err)
+		assert.NoError(t, Validate(b), name)
+
+		var record EpsIRIRecord
+		assert.NoError(t, record.Decode(b))
+		assert.True(t, version.OID.Equal(record.Payload.Hi2epsDomainID))
+		params := record.Payload.PartyInformation[0].ServicesDataInformation.GPRSParameters
+		assert.Equal(t, version.Release >= 14, record.Payload.EPSSpecificParameters.RequestType != nil, name)
+		assert.Equal(t, version.Release >= 15, params.PDPAddress.IPAddress.IPv6PrefixLength == 64, name)
+	}
+
+	// fields not defined by the version identified by the OID
+	err := Validate(reencode(t, func(r *EpsIRIRecord) {
+		r.Payload.Hi2epsDomainID = moduleVersions[ModuleVersionR14].OID
+		r.Payload.PartyInformation[0].ServicesDataInformation.GPRSParameters.PDPAddress =
+			makeDataNodeAddress(IPV6Type, make([]byte, 16), 64)
+	}))
+	assert.EqualError(t, err, "malformed module_version: iPv6PrefixLength is not defined by module version r14")
+
+	err = Validate(reencode(t, func(r *EpsIRIRecord) {
+		r.Payload.Hi2epsDomainID = []int{0, 4, 0, 2, 2, 4, 8, 12, 1}
+	}))
+	assert.EqualError(t, err, "malformed der: unexpected domain ID 0.4.0.2.2.4.8.12.1")
+
+	_, err = GetModuleVersion("r9")
+	assert.EqualError(t, err, `unknown module version "r9"`)
+}
+
+func TestDomainModuleVersion(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			CorrelationID: 0x866cb397915ffe4,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.PDNConnectivityRequested,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value:      map[string]interface{}{"imsi": "IMSI001010000000001", "request_type": "initial"},
+	}
+
+	// the records of a version with a domain ID are identified by it and
+	// carry the fields of its release
+	version, err := GetDomainModuleVersion(ModuleVersionR13, "1.3.6.1.4.1.28458.13")
+	assert.NoError(t, err)
+	assert.Equal(t, ModuleVersionR13, version.Name)
+	b, err := MakeVersionedRecord(&event, task, 49002, 1, RecordClassReport, version)
+	assert.NoError(t, err)
+	var record EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, "1.3.6.1.4.1.28458.13", record.Payload.Hi2epsDomainID.String())
+	assert.Nil(t, record.Payload.EPSSpecificParameters.RequestType)
+
+	// they are only recognized along with the domain IDs of their network
+	assert.EqualError(t, Validate(b), "malformed der: unexpected domain ID 1.3.6.1.4.1.28458.13")
+	domainIDs := DomainIDs{}
+	assert.NoError(t, domainIDs.Add(version))
+	assert.NoError(t, ValidateDomainIDs(b, domainIDs))
+	assert.Equal(t, ModuleVersionR13, CheckConformance(b, time.Now(), domainIDs).ModuleVersion)
+
+	// the domain ID of the release is the version itself
+	version, err = GetDomainModuleVersion(ModuleVersionR14, "0.4.0.2.2.4.8.14.2")
+	assert.NoError(t, err)
+	assert.True(t, moduleVersions[ModuleVersionR14] == version)
+	assert.NoError(t, domainIDs.Add(version))
+	assert.Len(t, domainIDs, 1)
+
+	// a domain ID can't identify module versions of different releases
+	_, err = GetDomainModuleVersion(ModuleVersionR15, "0.4.0.2.2.4.8.14.2")
+	assert.EqualError(t, err, "domain ID 0.4.0.2.2.4.8.14.2 already identifies module version r14")
+	version, err = GetDomainModuleVersion(ModuleVersionR15, "1.3.6.1.4.1.28458.13")
+	assert.NoError(t, err)
+	assert.EqualError(t, domainIDs.Add(version), "domain ID 1.3.6.1.4.1.28458.13 already identifies module version r13")
+	assert.Len(t, domainIDs, 1)
+	for domainID, expected := range map[string]string{
+		"1":          `domain ID "1" has less than 2 arcs`,
+		"1.3.x":      `domain ID "1.3.x" has an invalid arc "x"`,
+		"1.03":       `domain ID "1.03" has an invalid arc "03"`,
+		"1.-3":       `domain ID "1.-3" has an invalid arc "-3"`,
+		"1.40.1":     `domain ID "1.40.1" has invalid first arcs 1.40`,
+		"3.1.1":      `domain ID "3.1.1" has invalid first arcs 3.1`,
+		"1.3.6..1.4": `domain ID "1.3.6..1.4" has an invalid arc ""`,
+	} {
+		_, err := ParseDomainID(domainID)
+		assert.EqualError(t, err, expected, domainID)
+	}
+	oid, err := ParseDomainID("2.999.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2.999.1", oid.String())
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Releases of the HI2 EPS ASN.1 module records can be encoded with
+	ModuleVersionR13 = "r13"
+	ModuleVersionR14 = "r14"
+	ModuleVersionR15 = "r15"
+
+	// DefaultModuleVersion is the module version used when the destination
+	// of the records does not select one
+	DefaultModuleVersion = ModuleVersionR15
+)
+
+// ModuleVersion is a release of the HI2 EPS ASN.1 module. Delivery functions
+// of different release levels expect records identified by the domain OID of
+// their release and only carrying the fields their module defines.
+type ModuleVersion struct {
+	Name    string
+	Release int
+	OID     asn1.ObjectIdentifier
+}
+
+// moduleVersions is the registry of the module versions records can be encoded with
+var moduleVersions = map[string]*ModuleVersion{
+	ModuleVersionR13: {Name: ModuleVersionR13, Release: 13, OID: []int{0, 4, 0, 2, 2, 4, 8, 13, 1}},
+	ModuleVersionR14: {Name: ModuleVersionR14, Release: 14, OID: []int{0, 4, 0, 2, 2, 4, 8, 14, 2}},
+	ModuleVersionR15: {Name: ModuleVersionR15, Release: 15, OID: []int{0, 4, 0, 2, 2, 4, 8, 15, 4}},
+}
+
+// Jurisdictions and LEMF vendors may expect the records to be identified by
+// a domain OID of their own rather than the one of the release of their
+// module. A module version with such a domain ID carries the fields of its
+// release under the configured OID. The domain IDs configured in a network
+// are collected from its stored configs, so that the records identified by
+// their OID are recognized when validated.
+
+// DomainIDs are the module versions identified by the domain IDs configured
+// in a network, by OID. A domain ID can't identify module versions of
+// different releases.
+type DomainIDs map[string]*ModuleVersion
+
+// releaseField is a field of the records which the modules of the releases
+// before the one introducing it do not define
+type releaseField struct {
+	name    string
+	release int
+	isSet   func(c *EpsIRIContent) bool
+	clear   func(c *EpsIRIContent)
+}
+
+var releaseFields = []releaseField{
+	{
+		name:    "requestType",
+		release: 14,
+		isSet:   func(c *EpsIRIContent) bool { return c.EPSSpecificParameters.RequestType != nil },
+		clear:   func(c *EpsIRIContent) { c.EPSSpecificParameters.RequestType = nil },
+	},
+	{
+		name:    "uEReqPDNConnFailReason",
+		release: 14,
+		isSet:   func(c *EpsIRIContent) bool { return c.EPSSpecificParameters.UEReqPDNConnFailReason != nil },
+		clear:   func(c *EpsIRIContent) { c.EPSSpecificParameters.UEReqPDNConnFailReason = nil },
+	},
+	{
+		name:    "iPv6PrefixLength",
+		release: 15,
+		isSet: func(c *EpsIRIContent) bool {
+			for _, address := range getContentIPAddresses(c) {
+				if address.IPv6PrefixLength != 0 {
+					return true
+				}
+			}
+			return false
+		},
+		clear: func(c *EpsIRIContent) {
+			for _, address := range getContentIPAddresses(c) {
+				address.IPv6PrefixLength = 0
+			}
+		},
+	},
+}
+
+// GetModuleVersion returns the registered module version of the given name,
+// or the default one when the name is empty
+func GetModuleVersion(name string) (*ModuleVersion, error) {
+	if name == "" {
+		name = DefaultModuleVersion
+	}
+	version, ok := moduleVersions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown module version %q", name)
+	}
+	return version, nil
+}
+
+// GetDomainModuleVersion returns the registered module version of the given
+// name identified by the given domain ID in dotted notation. The module
+// version itself is returned when the domain ID is empty or its own. The
+// domain ID of the release of another module version is rejected.
+func GetDomainModuleVersion(name, domainID string) (*ModuleVersion, error) {
+	version, err := GetModuleVersion(name)
+	if err != nil || domainID == "" {
+		return version, err
+	}
+	oid, err := ParseDomainID(domainID)
+	if err != nil {
+		return nil, err
+	}
+	if oid.Equal(version.OID) {
+		return version, nil
+	}
+	if other := getBuiltinModuleVersionByOID(oid); other != nil {
+		return nil, fmt.Errorf("domain ID %s already identifies module version %s", domainID, other.Name)
+	}
+	return &ModuleVersion{Name: version.Name, Release: version.Release, OID: oid}, nil
+}
+
+// Add adds a module version identified by a configured domain ID. The domain
+// ID already identifying a module version of another release is rejected.
+func (d DomainIDs) Add(version *ModuleVersion) error {
+	if err := d.Check(version); err != nil {
+		return err
+	}
+	if getBuiltinModuleVersionByOID(version.OID) == nil {
+		d[version.OID.String()] = version
+	}
+	return nil
+}
+
+// Check returns an error if the domain ID of a module version identifies a
+// module version of another release
+func (d DomainIDs) Check(version *ModuleVersion) error {
+	if other := d.get(version.OID); other != nil && other.Release != version.Release {
+		return fmt.Errorf("domain ID %s already identifies module version %s", version.OID, other.Name)
+	}
+	return nil
+}
+
+// ParseDomainID parses a domain OID in dotted notation, e.g. 0.4.0.2.2.4.8.15.4,
+// whose arcs must be DER encodable
+func ParseDomainID(domainID string) (asn1.ObjectIdentifier, error) {
+	arcs := strings.Split(domainID, ".")
+	if len(arcs) < 2 {
+		return nil, fmt.Errorf("domain ID %q has less than 2 arcs", domainID)
+	}
+	oid := make(asn1.ObjectIdentifier, len(arcs))
+	for i, arc := range arcs {
+		n, err := strconv.Atoi(arc)
+		if err != nil || n < 0 || (len(arc) > 1 && arc[0] == '0') {
+			return nil, fmt.Errorf("domain ID %q has an invalid arc %q", domainID, arc)
+		}
+		oid[i] = n
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
+		return nil, fmt.Errorf("domain ID %q has invalid first arcs %d.%d", domainID, oid[0], oid[1])
+	}
+	return oid, nil
+}
+
+// GetModuleVersions returns the names of the registered module versions, sorted
+func GetModuleVersions() []string {
+	names := make([]string, 0, len(moduleVersions))
+	for name := range moduleVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// get returns the module version identified by a domain OID, if any,
+// whether the OID is the one of its release or a configured domain ID
+func (d DomainIDs) get(oid asn1.ObjectIdentifier) *ModuleVersion {
+	if version := getBuiltinModuleVersionByOID(oid); version != nil {
+		return version
+	}
+	return d[oid.String()]
+}
+
+// getBuiltinModuleVersionByOID returns the module version whose release is
+// identified by a domain OID, if any
+func getBuiltinModuleVersionByOID(oid asn1.ObjectIdentifier) *ModuleVersion {
+	for _, version := range moduleVersions {
+		if version.OID.Equal(oid) {
+			return version
+		}
+	}
+	return nil
+}
+
+// restrict identifies the content with the OID of the module version and
+// drops the fields its module does not define
+func (v *ModuleVersion) restrict(content *EpsIRIContent) {
+	content.Hi2epsDomainID = v.OID
+	for _, field := range releaseFields {
+		if field.release > v.Release {
+			field.clear(content)
+		}
+	}
+}
+
+// checkFields returns an error if the content carries a field which the
+// module of the version does not define
+func (v *ModuleVersion) checkFields(content *EpsIRIContent) error {
+	for _, field := range releaseFields {
+		if field.release > v.Release && field.isSet(content) {
+			return fmt.Errorf("%s is not defined by module version %s", field.name, v.Name)
+		}
+	}
+	return nil
+}
+
+// getContentIPAddresses returns the IP addresses carried by the content
+func getContentIPAddresses(c *EpsIRIContent) []*IPAddress {
+	addresses := []*IPAddress{&c.NetworkIdentifier.NetworkElementIdentifier.IPAddress}
+	for i := range c.PartyInformation {
+		params := &c.PartyInformation[i].ServicesDataInformation.GPRSParameters
+		addresses = append(addresses, &params.PDPAddress.IPAddress, &params.AdditionalIPAddress.IPAddress)
+	}
+	return addresses
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	// OutputFormatHI2 delivers records as built, their timestamp attribute
+	// holding the timestamp of the HI2 payload
+	OutputFormatHI2 = "hi2"
+	// OutputFormatX2 delivers records as X2 PDUs whose conditional
+	// attributes are encoded as defined in ETSI TS 103 221-2, e.g. for
+	// mediation functions consuming X2 rather than HI2
+	OutputFormatX2 = "x2"
+)
+
+// x2TimestampLen is the length of a timestamp attribute as defined in
+// ETSI TS 103 221-2: POSIX seconds and nanoseconds, 4 octets each
+const x2TimestampLen = 8
+
+// MakeX2PDU converts an encoded record to an X2 PDU. The XID, correlation
+// ID, payload format and payload are kept, the timestamp attribute is
+// encoded as defined in ETSI TS 103 221-2. Only IRI is intercepted, so no
+// X3 PDU is ever built.
+func MakeX2PDU(record []byte) ([]byte, error) {
+	hdr, err := ParsePDUHeader(record)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.PduType != HeaderPduType {
+		return nil, fmt.Errorf("unexpected PDU type %d", hdr.PduType)
+	}
+	if uint64(hdr.HeaderLength)+uint64(hdr.PayloadLength) != uint64(len(record)) {
+		return nil, fmt.Errorf("declared length %d+%d does not match record length %d",
+			hdr.HeaderLength, hdr.PayloadLength, len(record))
+	}
+
+	attrs := make([]Attribute, 0, len(hdr.ConditionalAttributes))
+	attrsLen := uint32(0)
+	for _, attr := range hdr.ConditionalAttributes {
+		if attr.Tag == AttributeTimestamp {
+			value, err := encodeX2Timestamp(attr.Value)
+			if err != nil {
+				return nil, newEncodingError(FieldTimestamp, err)
+			}
+			attr = NewAttribute(AttributeTimestamp, value)
+		}
+		attrs = append(attrs, attr)
+		attrsLen += uint32(attr.Len) + 4
+	}
+
+	x2 := NewEpsIRIHeader(hdr.XID, hdr.CorrelationID, attrs, attrsLen)
+	x2.PayloadLength = hdr.PayloadLength
+	x2.PayloadFormat = hdr.PayloadFormat
+	x2.PayloadDirection = hdr.PayloadDirection
+	b := make([]byte, int(x2.HeaderLength)+int(x2.PayloadLength))
+	x2.marshalTo(b)
+	copy(b[x2.HeaderLength:], record[hdr.HeaderLength:])
+	return b, nil
+}
+
+// GetX2Timestamp returns the time of an X2 timestamp attribute
+func GetX2Timestamp(value []byte) (time.Time, error) {
+	if len(value) != x2TimestampLen {
+		return time.Time{}, fmt.Errorf("invalid timestamp length %d", len(value))
+	}
+	sec := binary.BigEndian.Uint32(value[0:4])
+	nsec := binary.BigEndian.Uint32(value[4:8])
+	if nsec >= uint32(time.Second) {
+		return time.Time{}, fmt.Errorf("invalid timestamp nanoseconds %d", nsec)
+	}
+	return time.Unix(int64(sec), int64(nsec)).UTC(), nil
+}
+
+// encodeX2Timestamp converts the timestamp of a record to an X2 timestamp
+func encodeX2Timestamp(generalizedTime []byte) ([]byte, error) {
+	timestamp, err := decodeGeneralizedTime(generalizedTime)
+	if err != nil {
+		return nil, err
+	}
+	sec := timestamp.Unix()
+	if sec < 0 || sec > 1<<32-1 {
+		return nil, fmt.Errorf("timestamp %s out of range", timestamp.UTC().Format(time.RFC3339))
+	}
+	b := make([]byte, x2TimestampLen)
+	binary.BigEndian.PutUint32(b[0:4], uint32(sec))
+	binary.BigEndian.PutUint32(b[4:8], uint32(timestamp.Nanosecond()))
+	return b, nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encoding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeX2PDU(t *testing.T) {
+	pdu, err := MakeX2PDU(encodedRecord)
+	assert.NoError(t, err)
+
+	var record, x2 EpsIRIRecord
+	assert.NoError(t, record.Decode(encodedRecord))
+	assert.NoError(t, x2.Decode(pdu))
+	assert.Equal(t, record.Payload, x2.Payload)
+	assert.Equal(t, record.Class, x2.Class)
+	assert.Equal(t, record.Header.XID, x2.Header.XID)
+	assert.Equal(t, record.Header.CorrelationID, x2.Header.CorrelationID)
+	assert.Equal(t, HeaderPduType, x2.Header.PduType)
+	assert.Equal(t, HeaderPayloadFormat, x2.Header.PayloadFormat)
+	assert.Equal(t, uint32(len(pdu)), x2.Header.HeaderLength+x2.Header.PayloadLength)
+	assert.Equal(t, pdu[x2.Header.HeaderLength:], encodedRecord[record.Header.HeaderLength:])
+
+	// only the timestamp attribute is converted
+	seq, ok := GetSequenceNumber(&x2.Header)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(6), seq)
+	assert.Equal(t, getAttribute(&record.Header, AttributeTargetID), getAttribute(&x2.Header, AttributeTargetID))
+	timestamp, err := GetX2Timestamp(getAttribute(&x2.Header, AttributeTimestamp))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 4, 19, 15, 35, 36, 310140646, time.UTC), timestamp)
+
+	_, err = MakeX2PDU(encodedRecord[:len(encodedRecord)-1])
+	assert.Error(t, err)
+	_, err = MakeX2PDU(MakeKeepalive(1))
+	assert.EqualError(t, err, "unexpected PDU type 3")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventsource
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	eventdC "magma/orc8r/cloud/go/services/eventd/eventd_client"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/olivere/elastic/v7"
+)
+
+// SourceEventd is the source of the events logged to orc8r eventd by the
+// MME, sessiond, pipelined and the SGSN of the gateways
+const SourceEventd = "eventd"
+
+func init() {
+	Register(SourceEventd, newEventdSource)
+}
+
+// eventdSource fetches the intercepted events from the Elasticsearch
+// indices of eventd
+type eventdSource struct {
+	client *elastic.Client
+}
+
+func newEventdSource() (EventSource, error) {
+	client, err := eventdC.GetElasticClient()
+	if err != nil {
+		return nil, err
+	}
+	return &eventdSource{client: client}, nil
+}
+
+func (s *eventdSource) Name() string {
+	return SourceEventd
+}
+
+func (s *eventdSource) Fetch(ctx context.Context, query Query) ([]eventdM.Event, error) {
+	return eventdC.GetMultiStreamEvents(ctx, getQueryParams(query), s.client)
+}
+
+func (s *eventdSource) Count(ctx context.Context, query Query) (int64, error) {
+	return eventdC.GetEventCount(ctx, getQueryParams(query), s.client)
+}
+
+// Check counts the events of no network from now on, which only succeeds
+// once Elasticsearch serves the indices
+func (s *eventdSource) Check(ctx context.Context) error {
+	now := time.Now()
+	_, err := eventdC.GetEventCount(ctx, eventdC.MultiStreamEventQueryParams{Start: &now}, s.client)
+	return err
+}
+
+// getQueryParams returns the eventd query of the intercepted events of a
+// query
+func getQueryParams(query Query) eventdC.MultiStreamEventQueryParams {
+	return eventdC.MultiStreamEventQueryParams{
+		NetworkID: query.NetworkID,
+		Streams:   nprobe.GetESStreams(),
+		Events:    nprobe.GetESEventTypes(),
+		Tags:      query.Tags,
+		From:      query.From,
+		Size:      query.Size,
+		Start:     query.Start,
+		End:       query.End,
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventsource defines the sources the nprobe manager fetches the
+// events of the targets from, e.g. orc8r eventd. Sources register themselves
+// under a name from their init function, and the service config selects the
+// ones the tasks are processed from, so that a source is added without
+// changing how its events are processed.
+package eventsource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// Query selects the events of a network
+type Query struct {
+	NetworkID string
+	// Tags selects the events of the targets, all events when empty
+	Tags []string
+	// Start and End bound the timestamps of the events when set
+	Start *time.Time
+	End   *time.Time
+	// From skips the first events matching the query, and Size bounds the
+	// number of events returned
+	From int
+	Size int
+}
+
+// EventSource is a source of the events of the targets. The tasks have a
+// single cursor, their watermark, which the sources are queried from: the
+// events of a source must be returned in chronological order so that the
+// events before the watermark are never fetched again.
+type EventSource interface {
+	// Name returns the name the source is registered with
+	Name() string
+	// Fetch returns the events of a query in chronological order
+	Fetch(ctx context.Context, query Query) ([]eventdM.Event, error)
+	// Count returns the number of events of a query, regardless of its
+	// offset and size
+	Count(ctx context.Context, query Query) (int64, error)
+	// Check returns an error while the source can't be queried
+	Check(ctx context.Context) error
+}
+
+// Factory creates a source, e.g. connecting to its backend
+type Factory func() (EventSource, error)
+
+var (
+	registryMutex sync.Mutex
+	factories     = map[string]Factory{}
+)
+
+// Register makes a source available under a name. It panics if the name is
+// already registered.
+func Register(name string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("event source %s is already registered", name))
+	}
+	factories[name] = factory
+}
+
+// Available returns the names of the registered sources, sorted
+func Available() []string {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	ret := make([]string, 0, len(factories))
+	for name := range factories {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// New creates the sources of the given names, in order
+func New(names []string) ([]EventSource, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no event source selected, available sources: %v", Available())
+	}
+	ret := make([]EventSource, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		registryMutex.Lock()
+		factory, ok := factories[name]
+		registryMutex.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown event source %s, available sources: %v", name, Available())
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("event source %s is selected more than once", name)
+		}
+		seen[name] = true
+		source, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create event source %s: %v", name, err)
+		}
+		ret = append(ret, source)
+	}
+	return ret, nil
+}
+
+// eventKey identifies an event reported by several sources
+type eventKey struct {
+	streamName string
+	eventType  string
+	tag        string
+	timestamp  time.Time
+}
+
+// Merge merges the pages of events fetched from several sources with the
+// same query in chronological order, events reported by several sources
+// being kept once. A full page of size events only covers the events of its
+// source up to its last one, so the events of the other sources past the
+// earliest of these ends are left out: they are fetched with the next page.
+func Merge(pages [][]eventdM.Event, size int) []eventdM.Event {
+	var end time.Time
+	var merged []eventdM.Event
+	var times []time.Time
+	for _, page := range pages {
+		var last time.Time
+		for i := range page {
+			// events whose timestamp is invalid keep their position
+			t, err := time.Parse(time.RFC3339, page[i].Timestamp)
+			if err != nil {
+				t = last
+			}
+			last = t
+			merged = append(merged, page[i])
+			times = append(times, t)
+		}
+		if len(page) != 0 && len(page) >= size && (end.IsZero() || last.Before(end)) {
+			end = last
+		}
+	}
+	sort.Stable(byTime{events: merged, times: times})
+
+	ret := make([]eventdM.Event, 0, len(merged))
+	seen := map[eventKey]bool{}
+	for i := range merged {
+		if !end.IsZero() && times[i].After(end) {
+			break
+		}
+		key := eventKey{
+			streamName: merged[i].StreamName,
+			eventType:  merged[i].EventType,
+			tag:        merged[i].Tag,
+			timestamp:  times[i].UTC().Truncate(time.Millisecond),
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ret = append(ret, merged[i])
+	}
+	return ret
+}
+
+// byTime sorts events by their parsed timestamps
+type byTime struct {
+	events []eventdM.Event
+	times  []time.Time
+}
+
+func (b byTime) Len() int           { return len(b.events) }
+func (b byTime) Less(i, j int) bool { return b.times[i].Before(b.times[j]) }
+func (b byTime) Swap(i, j int) {
+	b.events[i], b.events[j] = b.events[j], b.events[i]
+	b.times[i], b.times[j] = b.times[j], b.times[i]
+}
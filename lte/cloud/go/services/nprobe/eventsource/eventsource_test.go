@@ -0,0 +1,79 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSource struct {
+	name string
+}
+
+func (s *fakeSource) Name() string { return s.name }
+func (s *fakeSource) Fetch(ctx context.Context, query Query) ([]eventdM.Event, error) {
+	return nil, nil
+}
+func (s *fakeSource) Count(ctx context.Context, query Query) (int64, error) { return 0, nil }
+func (s *fakeSource) Check(ctx context.Context) error                       { return nil }
+
+func TestRegistry(t *testing.T) {
+	Register("fake", func() (EventSource, error) { return &fakeSource{name: "fake"}, nil })
+	Register("broken", func() (EventSource, error) { return nil, errors.New("no backend") })
+	assert.Panics(t, func() { Register("fake", nil) })
+	assert.Equal(t, []string{"broken", SourceEventd, "fake"}, Available())
+
+	sources, err := New([]string{"fake"})
+	assert.NoError(t, err)
+	assert.Len(t, sources, 1)
+	assert.Equal(t, "fake", sources[0].Name())
+
+	_, err = New(nil)
+	assert.EqualError(t, err, "no event source selected, available sources: [broken eventd fake]")
+	_, err = New([]string{"amf"})
+	assert.EqualError(t, err, "unknown event source amf, available sources: [broken eventd fake]")
+	_, err = New([]string{"fake", "fake"})
+	assert.EqualError(t, err, "event source fake is selected more than once")
+	_, err = New([]string{"broken"})
+	assert.EqualError(t, err, "failed to create event source broken: no backend")
+}
+
+func TestMerge(t *testing.T) {
+	event := func(stream, timestamp string) eventdM.Event {
+		return eventdM.Event{StreamName: stream, EventType: "attach_success", Tag: "IMSI001", Timestamp: timestamp}
+	}
+	mme := []eventdM.Event{
+		event("mme", "2021-05-01T08:00:00Z"),
+		event("mme", "2021-05-01T08:00:02Z"),
+	}
+	sessiond := []eventdM.Event{
+		event("sessiond", "2021-05-01T08:00:01Z"),
+		event("sessiond", "2021-05-01T08:00:03Z"),
+		event("sessiond", "2021-05-01T08:00:04Z"),
+	}
+	// the page of the MME is full, the events of sessiond past its end are
+	// fetched with the next page
+	assert.Equal(t, []eventdM.Event{mme[0], sessiond[0], mme[1]}, Merge([][]eventdM.Event{mme, sessiond}, 2))
+	assert.Equal(t, []eventdM.Event{mme[0], sessiond[0], mme[1], sessiond[1], sessiond[2]}, Merge([][]eventdM.Event{mme, sessiond}, 3))
+
+	// events reported by several sources are kept once
+	assert.Equal(t, mme, Merge([][]eventdM.Event{mme, {mme[1]}}, 3))
+	assert.Empty(t, Merge(nil, 3))
+}
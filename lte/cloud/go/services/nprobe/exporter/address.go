@@ -0,0 +1,39 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"net"
+	"strings"
+)
+
+// NormalizeAddress returns the host:port form of a delivery function address
+// which can be dialed, e.g. [2001:db8::1]:4040. IPv6 delivery addresses are
+// accepted with or without brackets around the host, the port being the
+// part of unbracketed addresses after the last colon. Other addresses are
+// returned unchanged.
+func NormalizeAddress(addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	i := strings.LastIndex(addr, ":")
+	if i < 0 {
+		return addr
+	}
+	host, port := addr[:i], addr[i+1:]
+	if ip := net.ParseIP(host); ip == nil || ip.To4() != nil {
+		return addr
+	}
+	return net.JoinHostPort(host, port)
+}
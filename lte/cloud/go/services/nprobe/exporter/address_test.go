@@ -0,0 +1,30 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:4040", NormalizeAddress("127.0.0.1:4040"))
+	assert.Equal(t, "lemf.example.org:4040", NormalizeAddress("lemf.example.org:4040"))
+	assert.Equal(t, "[2001:db8::1]:4040", NormalizeAddress("[2001:db8::1]:4040"))
+	assert.Equal(t, "[2001:db8::1]:4040", NormalizeAddress("2001:db8::1:4040"))
+	assert.Equal(t, "[::1]:4040", NormalizeAddress("::1:4040"))
+	assert.Equal(t, "", NormalizeAddress(""))
+	assert.Equal(t, "lemf", NormalizeAddress("lemf"))
+}
@@ -0,0 +1,361 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BatchConfig aggregates the records delivered within a window into a
+// single write, cutting the syscall and tls overhead at scale. Records are
+// still delivered as PDUs of their own, ETSI TS 103 221-2 defining a single
+// payload per PDU, so that they are acknowledged one by one.
+type BatchConfig struct {
+	// MaxRecords is the number of records of a batch, records are
+	// delivered one at a time when lower than 2
+	MaxRecords uint32
+	// MaxBytes bounds the size of a batch, unbounded when 0. A record
+	// larger than MaxBytes is delivered in a batch of its own.
+	MaxBytes uint32
+	// Window is the time a batch waits for more records once its first
+	// record is picked up. Only the queued records are batched when 0.
+	Window time.Duration
+}
+
+// BatchRecord is a record delivered in a batch
+type BatchRecord struct {
+	Record        []byte
+	CorrelationID uint64
+}
+
+// batchBackend is implemented by the backends delivering several records
+// at once
+type batchBackend interface {
+	// SendBatch delivers records in order and returns the result of each
+	SendBatch(records []BatchRecord) []error
+}
+
+type batchSendFunc func(records []BatchRecord, retryCounts []uint32) []error
+
+// batchedRecord is a record picked up for a batch along with its flow
+type batchedRecord struct {
+	queuedRecord
+	flow *flow
+}
+
+// recordBatch holds the records picked up for a batch. Records rejected
+// by the queue are kept in the batch so that they are settled in order
+// with the records of their flow, but are not delivered.
+type recordBatch struct {
+	records []batchedRecord
+	// idle holds the flows without records left when picked up for the batch
+	idle  []*flow
+	count int
+	size  int
+	full  bool
+	// wait is the time to wait for the tokens of the paced records
+	wait time.Duration
+}
+
+// NewBatchConfig returns the batch export settings of the service config
+func NewBatchConfig(config nprobe.Config) BatchConfig {
+	return BatchConfig{
+		MaxRecords: config.ExportBatchMaxRecords,
+		MaxBytes:   config.ExportBatchMaxBytes,
+		Window:     time.Duration(config.ExportBatchWindowMs) * time.Millisecond,
+	}
+}
+
+// SetBatchConfig applies batch export settings to the records delivered
+// from now on
+func (c *RecordExporter) SetBatchConfig(config BatchConfig) {
+	c.queue.setBatchConfig(config)
+}
+
+// SendBatchWithRetries delivers records at once and returns the result of
+// each record. The failed records are retried together until they reach
+// their retry count, unless their failure would fail again right away. The
+// records without retries are sent once.
+func (c *RecordExporter) SendBatchWithRetries(records []BatchRecord, retryCounts []uint32) []error {
+	results := make([]error, len(records))
+	pending := make([]int, len(records))
+	for i := range records {
+		pending[i] = i
+	}
+	for attempt := uint32(1); len(pending) != 0; attempt++ {
+		batch := make([]BatchRecord, len(pending))
+		for j, i := range pending {
+			batch[j] = records[i]
+		}
+		start := time.Now()
+		backend := c.getBackend()
+		errs := sendBatch(backend, batch)
+		latency := time.Since(start).Seconds()
+		if backend != c.getBackend() {
+			// the backend was replaced while sending and may have reconnected
+			backend.Close()
+		}
+		metrics.ExportBatchSize.Observe(float64(len(batch)))
+
+		var failed []int
+		for j, i := range pending {
+			class := encoding.GetRecordClass(records[i].Record)
+			metrics.ExportLatency.WithLabelValues(class).Observe(latency)
+			results[i] = errs[j]
+			if errs[j] == nil {
+				metrics.RecordsSent.WithLabelValues(class).Inc()
+				metrics.BytesSent.WithLabelValues(class).Add(float64(len(records[i].Record)))
+				continue
+			}
+			failure := ClassifyError(errs[j])
+			metrics.DeliveryFailures.WithLabelValues(failure).Inc()
+			if attempt < retryCounts[i] && IsRetryable(failure) {
+				failed = append(failed, i)
+			}
+		}
+		pending = failed
+	}
+
+	var lastErr error
+	for _, err := range results {
+		if err != nil {
+			lastErr = err
+		}
+	}
+	c.setDeliveryOutcome(lastErr)
+	return results
+}
+
+// sendBatch delivers records through a backend, in a single write if the
+// backend supports it
+func sendBatch(backend Backend, records []BatchRecord) []error {
+	if len(records) == 0 {
+		return nil
+	}
+	if bb, ok := backend.(batchBackend); ok {
+		return bb.SendBatch(records)
+	}
+	errs := make([]error, len(records))
+	for i, r := range records {
+		errs[i] = backend.Send(r.Record, r.CorrelationID)
+	}
+	return errs
+}
+
+func (b BatchConfig) isEnabled() bool {
+	return b.MaxRecords > 1
+}
+
+// fits returns true if a record of size bytes can be added to the batch
+func (b *recordBatch) fits(config BatchConfig, size int) bool {
+	if b.count == 0 {
+		return true
+	}
+	return b.count < int(config.MaxRecords) && (config.MaxBytes == 0 || b.size+size <= int(config.MaxBytes))
+}
+
+func (b *recordBatch) add(r queuedRecord, f *flow) {
+	b.records = append(b.records, batchedRecord{queuedRecord: r, flow: f})
+	if r.rejected == nil {
+		b.count++
+		b.size += len(r.record)
+	}
+}
+
+// setBatchConfig applies batch export settings, batches being collected
+// keep the previous ones
+func (q *fairQueue) setBatchConfig(config BatchConfig) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.batch = config
+}
+
+// collectBatch picks up the records of a batch, serving the active flows in
+// turn, and waits for more records within the batch window unless the batch
+// is full. It is called with the queue locked and unlocks it.
+func (q *fairQueue) collectBatch() *recordBatch {
+	config := q.batch
+	b := &recordBatch{}
+	deadline := time.Now().Add(config.Window)
+	var timer *time.Timer
+	for {
+		for len(q.active) != 0 && !b.full {
+			f := q.active[0]
+			q.active = q.active[1:]
+			f.deficit += fairQueueQuantum * int(f.weight)
+			q.batchFlow(b, f, config)
+		}
+		if b.full || q.closed || !time.Now().Before(deadline) {
+			break
+		}
+		if timer == nil {
+			timer = time.AfterFunc(time.Until(deadline), func() {
+				q.mutex.Lock()
+				defer q.mutex.Unlock()
+				q.cond.Signal()
+			})
+		}
+		q.cond.Wait()
+	}
+	if timer != nil {
+		timer.Stop()
+	}
+	q.mutex.Unlock()
+	return b
+}
+
+// batchFlow adds the head records of a flow to a batch while its deficit
+// and the batch allow it. It is called with the queue locked.
+func (q *fairQueue) batchFlow(b *recordBatch, f *flow, config BatchConfig) {
+	for len(f.records) != 0 {
+		r := f.records[0]
+		if r.rejected != nil {
+			f.records = f.records[1:]
+			b.add(r, f)
+			if r.rejected != ErrRecordDropped {
+				// the records behind a spilled record fail once it is settled
+				for _, next := range f.records {
+					if next.rejected == nil {
+						q.queued--
+					}
+					next.rejected = ErrPreviousRecordFailed
+					b.add(next, f)
+				}
+				f.records = nil
+			}
+			continue
+		}
+		if !b.fits(config, len(r.record)) {
+			b.full = true
+			break
+		}
+		if len(r.record) > f.deficit && len(q.active) != 0 {
+			break
+		}
+		f.records = f.records[1:]
+		f.deficit -= len(r.record)
+		q.queued--
+		if q.bucket != nil {
+			if wait := q.bucket.take(time.Now()); wait > 0 {
+				metrics.RecordsThrottled.WithLabelValues("delayed").Inc()
+				if wait > b.wait {
+					b.wait = wait
+				}
+			}
+		}
+		b.add(r, f)
+	}
+	metrics.ExportQueueSize.Set(float64(q.queued))
+	if len(f.records) == 0 {
+		f.deficit = 0
+		b.idle = append(b.idle, f)
+	} else {
+		q.active = append(q.active, f)
+	}
+}
+
+// waitBatch waits for the tokens of a paced batch unless the submissions of
+// all its records are canceled, the records canceled meanwhile failing
+// rather than being sent
+func waitBatch(b *recordBatch, wait time.Duration) {
+	deadline := time.Now().Add(wait)
+	for _, r := range b.records {
+		if r.rejected != nil {
+			continue
+		}
+		// the next record is waited for once the submission of this one is
+		// canceled
+		if waitToken(r.delivery.ctx, time.Until(deadline)) == nil {
+			return
+		}
+	}
+}
+
+// deliverBatch delivers the records of a batch at once and settles them in
+// order. Once a record of a flow fails, the records of the flow behind it
+// fail with ErrPreviousRecordFailed.
+func (q *fairQueue) deliverBatch(b *recordBatch) {
+	// the records in flight are settled before
+	q.pipeline.reserve(1)
+	spans := make([]trace.Span, len(b.records))
+	for i, r := range b.records {
+		spans[i] = startDeliverSpan(r.queuedRecord)
+		if b.wait > 0 {
+			spans[i].AddEvent(spanEventPaced)
+		}
+	}
+	if b.wait > 0 {
+		waitBatch(b, b.wait)
+	}
+
+	results := make([]error, len(b.records))
+	var sent []int
+	var records []BatchRecord
+	var retryCounts []uint32
+	for i, r := range b.records {
+		d := r.delivery
+		switch {
+		case r.rejected != nil:
+			results[i] = r.rejected
+		case d.ctx.Err() != nil:
+			results[i] = d.ctx.Err()
+		default:
+			sent = append(sent, i)
+			records = append(records, BatchRecord{Record: r.record, CorrelationID: d.correlationID})
+			retryCounts = append(retryCounts, d.retryCount)
+			spans[i].AddEvent(spanEventSending, trace.WithAttributes(tracing.Records.Int(b.count)))
+		}
+	}
+	if len(records) != 0 {
+		for j, err := range q.sendBatch(records, retryCounts) {
+			results[sent[j]] = err
+		}
+	}
+
+	failed := map[*flow]bool{}
+	for i, r := range b.records {
+		err := results[i]
+		if failed[r.flow] {
+			err = ErrPreviousRecordFailed
+		}
+		endDeliverSpan(spans[i], err)
+		r.delivery.settle(r.index, err)
+		switch {
+		case err == nil:
+			metrics.DeliveryLatency.WithLabelValues(encoding.GetRecordClass(r.record)).Observe(time.Since(r.delivery.queuedAt).Seconds())
+		case err != ErrRecordDropped:
+			failed[r.flow] = true
+		}
+	}
+	for f := range failed {
+		q.failFlow(f)
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, f := range b.idle {
+		if len(f.records) == 0 {
+			delete(q.flows, f.id)
+		} else {
+			q.active = append(q.active, f)
+		}
+	}
+}
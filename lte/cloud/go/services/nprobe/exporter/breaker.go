@@ -0,0 +1,257 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+)
+
+// States of the circuit breaker of a destination
+const (
+	// BreakerClosed delivers the records to the destination
+	BreakerClosed = "closed"
+	// BreakerOpen sheds the records of the destination without writing them
+	BreakerOpen = "open"
+	// BreakerHalfOpen delivers a single record to probe the destination,
+	// shedding the others until it is delivered or fails
+	BreakerHalfOpen = "half_open"
+)
+
+var errBreakerOpen = &DeliveryError{Class: FailureBreakerOpen, Err: errors.New("circuit breaker of the destination open, record shed")}
+
+// BreakerBackend trips a circuit breaker once a number of records failed in
+// a row to be delivered to a destination, e.g. written past their deadline
+// to a stalled delivery function. While open, records fail right away with
+// FailureBreakerOpen instead of holding the export queue, the tasks keeping
+// them in their state to deliver them again on a later pass. Once the open
+// interval elapsed, the breaker is half-open and the next record probes the
+// destination: the breaker closes when it is delivered and opens for
+// another interval when it fails. Malformed records and the ones refused by
+// the queue don't count as failures of the destination. Transitions are
+// logged and exposed by the nprobe_delivery_breaker_state and
+// nprobe_delivery_breaker_transitions_total metrics.
+type BreakerBackend struct {
+	backend      Backend
+	name         string
+	threshold    uint32
+	openInterval time.Duration
+	logger       *logging.Logger
+
+	mutex    sync.Mutex
+	state    string
+	failures uint32
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreakerBackend creates a new backend delivering records through
+// backend, tripping after threshold failures in a row. name identifies the
+// destination in the logs and metrics.
+func NewBreakerBackend(backend Backend, name string, threshold uint32, openInterval time.Duration) *BreakerBackend {
+	metrics.DeliveryBreakerState.WithLabelValues(name).Set(0)
+	return &BreakerBackend{
+		backend:      backend,
+		name:         name,
+		threshold:    threshold,
+		openInterval: openInterval,
+		logger:       logger.With(logging.FieldDestination, name),
+		state:        BreakerClosed,
+	}
+}
+
+// Send delivers a record unless the breaker is open
+func (b *BreakerBackend) Send(record []byte, correlationID uint64) error {
+	return b.SendTraced(context.Background(), record, correlationID)
+}
+
+// SendTraced delivers a record along with its trace context unless the
+// breaker is open
+func (b *BreakerBackend) SendTraced(ctx context.Context, record []byte, correlationID uint64) error {
+	if !b.allow() {
+		return errBreakerOpen
+	}
+	err := sendTraced(ctx, b.backend, record, correlationID)
+	b.observe([]error{err})
+	return err
+}
+
+// SendAsync writes a record unless the breaker is open, its result being
+// observed once known
+func (b *BreakerBackend) SendAsync(record []byte, correlationID uint64) *PendingRecord {
+	if !b.allow() {
+		return settledRecord(errBreakerOpen)
+	}
+	pending := sendAsync(b.backend, record, correlationID)
+	return newPendingRecord(func() error {
+		err := pending.Wait()
+		b.observe([]error{err})
+		return err
+	})
+}
+
+// SendBatch delivers records unless the breaker is open, a half-open
+// breaker probing the destination with the whole batch
+func (b *BreakerBackend) SendBatch(records []BatchRecord) []error {
+	if !b.allow() {
+		errs := make([]error, len(records))
+		for i := range errs {
+			errs[i] = errBreakerOpen
+		}
+		return errs
+	}
+	errs := sendBatch(b.backend, records)
+	b.observe(errs)
+	return errs
+}
+
+// GetState returns the state of the breaker and the time it last opened,
+// zero if it never did
+func (b *BreakerBackend) GetState() (string, time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state, b.openedAt
+}
+
+// IsConnected returns the connection state of the backend
+func (b *BreakerBackend) IsConnected() bool {
+	return b.backend.IsConnected()
+}
+
+// SetHandshakeSettings applies the handshake settings to the backend
+func (b *BreakerBackend) SetHandshakeSettings(settings HandshakeSettings) {
+	if hb, ok := b.backend.(handshakeBackend); ok {
+		hb.SetHandshakeSettings(settings)
+	}
+}
+
+// GetHandshake describes the handshake of the backend connection
+func (b *BreakerBackend) GetHandshake() *HandshakeInfo {
+	if hb, ok := b.backend.(handshakeBackend); ok {
+		return hb.GetHandshake()
+	}
+	return nil
+}
+
+// Probe probes the delivery function of the backend
+func (b *BreakerBackend) Probe(timeout time.Duration) (*HandshakeInfo, error) {
+	if hb, ok := b.backend.(handshakeBackend); ok {
+		return hb.Probe(timeout)
+	}
+	return nil, ErrProbeUnsupported
+}
+
+// GetConnection describes the connection of the backend along with the
+// state of the breaker
+func (b *BreakerBackend) GetConnection() *ConnectionInfo {
+	info := &ConnectionInfo{}
+	if cb, ok := b.backend.(connectionBackend); ok {
+		info = cb.GetConnection()
+	}
+	info.Breaker, info.BreakerOpenedAt = b.GetState()
+	return info
+}
+
+// Reconnect reconnects the backend. The breaker is left as is, the next
+// probe restoring the delivery.
+func (b *BreakerBackend) Reconnect() error {
+	return reconnect(b.backend)
+}
+
+// Connect connects the backend
+func (b *BreakerBackend) Connect() error {
+	return connect(b.backend)
+}
+
+// Close closes the backend
+func (b *BreakerBackend) Close() {
+	b.backend.Close()
+}
+
+// allow returns true if a record can be delivered, turning the breaker
+// half-open once the open interval elapsed
+func (b *BreakerBackend) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.openInterval {
+			return false
+		}
+		b.setState(BreakerHalfOpen, metrics.TransitionBreakerHalfOpen)
+		b.logger.Infof("Probing destination %s, its circuit breaker open since %s", b.name, b.openedAt.Format(time.RFC3339))
+	}
+	// a single probe is in flight at a time
+	if b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// observe updates the breaker with the results of the records delivered,
+// in order
+func (b *BreakerBackend) observe(errs []error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.probing = false
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			b.failures = 0
+			if b.state != BreakerClosed {
+				b.setState(BreakerClosed, metrics.TransitionBreakerClose)
+				b.logger.Warningf("Destination %s reachable again, closing its circuit breaker", b.name)
+			}
+		case isDestinationFailure(err):
+			b.failures++
+			if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.threshold) {
+				b.openedAt = time.Now()
+				b.setState(BreakerOpen, metrics.TransitionBreakerOpen)
+				b.logger.Warningf("Opening circuit breaker of destination %s for %s after %d failures in a row: %v", b.name, b.openInterval, b.failures, err)
+			}
+		}
+	}
+}
+
+// setState switches the breaker to a state. The caller holds the mutex.
+func (b *BreakerBackend) setState(state, transition string) {
+	b.state = state
+	metrics.DeliveryBreakerTransitions.WithLabelValues(b.name, transition).Inc()
+	switch state {
+	case BreakerOpen:
+		metrics.DeliveryBreakerState.WithLabelValues(b.name).Set(1)
+	case BreakerHalfOpen:
+		metrics.DeliveryBreakerState.WithLabelValues(b.name).Set(2)
+	default:
+		metrics.DeliveryBreakerState.WithLabelValues(b.name).Set(0)
+	}
+}
+
+// isDestinationFailure returns true if a delivery failure is a failure of
+// the destination rather than of the record
+func isDestinationFailure(err error) bool {
+	switch ClassifyError(err) {
+	case FailureEncode, FailureQueue, FailureBreakerOpen:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakerBackend(t *testing.T) {
+	lemf := &lemfBackend{}
+	backend := NewBreakerBackend(lemf, "lemf:4040", 2, 50*time.Millisecond)
+	getState := func() string {
+		state, _ := backend.GetState()
+		return state
+	}
+
+	assert.NoError(t, backend.Send([]byte("r1"), 1))
+	assert.Equal(t, BreakerClosed, getState())
+
+	// the breaker trips after the failures in a row, and sheds the records
+	// right away
+	lemf.setDown(true)
+	assert.Error(t, backend.Send([]byte("r2"), 1))
+	assert.Equal(t, BreakerClosed, getState())
+	assert.Error(t, backend.Send([]byte("r3"), 1))
+	assert.Equal(t, BreakerOpen, getState())
+	lemf.setDown(false)
+	err := backend.Send([]byte("r4"), 1)
+	assert.Equal(t, FailureBreakerOpen, ClassifyError(err))
+	assert.False(t, IsRetryable(ClassifyError(err)))
+	errs := backend.SendBatch([]BatchRecord{{Record: []byte("r4")}, {Record: []byte("r5")}})
+	assert.Equal(t, []error{errBreakerOpen, errBreakerOpen}, errs)
+	assert.Equal(t, []string{"r1"}, lemf.getSent())
+	info := backend.GetConnection()
+	assert.Equal(t, BreakerOpen, info.Breaker)
+	assert.False(t, info.BreakerOpenedAt.IsZero())
+
+	// once the open interval elapsed, a failing probe opens it again
+	lemf.setDown(true)
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, errors.New("ack timeout"), backend.Send([]byte("r4"), 1))
+	assert.Equal(t, BreakerOpen, getState())
+	assert.Equal(t, FailureBreakerOpen, ClassifyError(backend.Send([]byte("r4"), 1)))
+
+	// and a delivered probe closes it
+	lemf.setDown(false)
+	time.Sleep(60 * time.Millisecond)
+	errs = backend.SendBatch([]BatchRecord{{Record: []byte("r4")}, {Record: []byte("r5")}})
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.Equal(t, BreakerClosed, getState())
+	assert.Equal(t, []string{"r1", "r4", "r5"}, lemf.getSent())
+
+	// malformed records are not failures of the destination
+	for i := 0; i < 3; i++ {
+		backend.observe([]error{newDeliveryError(FailureEncode, errors.New("malformed"))})
+	}
+	assert.Equal(t, BreakerClosed, getState())
+}
+
+func TestBreakerBackendProbe(t *testing.T) {
+	lemf := &lemfBackend{down: true}
+	backend := NewBreakerBackend(lemf, "lemf:4040", 1, 10*time.Millisecond)
+	assert.Error(t, backend.Send([]byte("r1"), 1))
+	assert.Equal(t, BreakerOpen, backend.state)
+
+	// a single record probes the destination at a time
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, backend.allow())
+	assert.Equal(t, BreakerHalfOpen, backend.state)
+	assert.False(t, backend.allow())
+
+	// a probe saying nothing of the destination lets the next record probe it
+	backend.observe([]error{newDeliveryError(FailureEncode, errors.New("malformed"))})
+	assert.Equal(t, BreakerHalfOpen, backend.state)
+	assert.True(t, backend.allow())
+	backend.observe([]error{nil})
+	assert.Equal(t, BreakerClosed, backend.state)
+}
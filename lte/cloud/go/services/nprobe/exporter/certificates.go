@@ -0,0 +1,159 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/health"
+)
+
+// tlsSessionCacheSize is the number of TLS sessions cached for resumption,
+// one per server name or delivery function address
+const tlsSessionCacheSize = 64
+
+// CertificateStore holds the exporter client certificate. The certificate is
+// presented through the GetClientCertificate callback of the TLS configs so
+// that a reloaded certificate is used from the next handshake on, while the
+// established delivery connections are kept. The TLS sessions cached for
+// resumption are dropped along with the certificate they were established with.
+type CertificateStore struct {
+	mutex    sync.RWMutex
+	crtFile  string
+	keyFile  string
+	cert     *tls.Certificate
+	leaf     *x509.Certificate
+	loadedAt time.Time
+	sessions tls.ClientSessionCache
+}
+
+// sessionCache is the tls.ClientSessionCache of the sessions established
+// with the current certificate of a store
+type sessionCache struct {
+	store *CertificateStore
+}
+
+// CertificateInfo describes the certificate currently held by a store
+type CertificateInfo struct {
+	Subject      string
+	Issuer       string
+	SerialNumber string
+	NotBefore    time.Time
+	NotAfter     time.Time
+	LoadedAt     time.Time
+}
+
+// NewCertificateStore creates a store loaded with the certificate and key files
+func NewCertificateStore(crtFile, keyFile string) (*CertificateStore, error) {
+	s := &CertificateStore{}
+	if err := s.SetFiles(crtFile, keyFile); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetFiles loads the certificate from new files. The current certificate and
+// files are kept if the new ones fail to load.
+func (s *CertificateStore) SetFiles(crtFile, keyFile string) error {
+	cert, leaf, err := loadCertificate(crtFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.crtFile) != 0 && s.crtFile != crtFile {
+		health.Certificates().Forget(health.CertificateClient, s.crtFile)
+	}
+	s.crtFile, s.keyFile = crtFile, keyFile
+	s.cert, s.leaf = cert, leaf
+	s.loadedAt = time.Now()
+	s.sessions = tls.NewLRUClientSessionCache(tlsSessionCacheSize)
+	health.Certificates().Observe(health.Certificate{
+		Kind:     health.CertificateClient,
+		Name:     crtFile,
+		Subject:  leaf.Subject.String(),
+		NotAfter: leaf.NotAfter,
+	})
+	logger.Infof("Loaded exporter certificate %s (serial %s, expires %s)", crtFile, leaf.SerialNumber, leaf.NotAfter)
+	return nil
+}
+
+// Reload loads the certificate again from its files, e.g. once rotated
+func (s *CertificateStore) Reload() error {
+	s.mutex.RLock()
+	crtFile, keyFile := s.crtFile, s.keyFile
+	s.mutex.RUnlock()
+	return s.SetFiles(crtFile, keyFile)
+}
+
+// GetClientCertificate returns the current certificate, it is meant to be
+// used as tls.Config.GetClientCertificate
+func (s *CertificateStore) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.cert, nil
+}
+
+// SessionCache returns the cache of the TLS sessions resumed by the
+// connections presenting the certificate, meant to be used as
+// tls.Config.ClientSessionCache
+func (s *CertificateStore) SessionCache() tls.ClientSessionCache {
+	return sessionCache{store: s}
+}
+
+func (c sessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	return c.current().Get(sessionKey)
+}
+
+func (c sessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	c.current().Put(sessionKey, cs)
+}
+
+func (c sessionCache) current() tls.ClientSessionCache {
+	c.store.mutex.RLock()
+	defer c.store.mutex.RUnlock()
+	return c.store.sessions
+}
+
+// GetInfo describes the current certificate
+func (s *CertificateStore) GetInfo() CertificateInfo {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return CertificateInfo{
+		Subject:      s.leaf.Subject.String(),
+		Issuer:       s.leaf.Issuer.String(),
+		SerialNumber: s.leaf.SerialNumber.String(),
+		NotBefore:    s.leaf.NotBefore,
+		NotAfter:     s.leaf.NotAfter,
+		LoadedAt:     s.loadedAt,
+	}
+}
+
+func loadCertificate(crtFile, keyFile string) (*tls.Certificate, *x509.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(crtFile, keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate %s: %v", crtFile, err)
+	}
+	cert.Leaf = leaf
+	return &cert, leaf, nil
+}
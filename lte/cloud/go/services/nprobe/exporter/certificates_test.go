@@ -0,0 +1,88 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCertificate writes a self-signed client certificate and its key
+func writeCertificate(t *testing.T, crtFile, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "nprobe"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	crtPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	assert.NoError(t, ioutil.WriteFile(crtFile, crtPem, 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, keyPem, 0600))
+}
+
+func TestCertificateStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_certs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	crtFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+
+	_, err = NewCertificateStore(crtFile, keyFile)
+	assert.Error(t, err)
+
+	writeCertificate(t, crtFile, keyFile, 1)
+	certs, err := NewCertificateStore(crtFile, keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", certs.GetInfo().SerialNumber)
+	assert.Equal(t, "CN=nprobe", certs.GetInfo().Subject)
+
+	tlsConfig := NewTlsConfig(certs, false)
+	cert, err := tlsConfig.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), cert.Leaf.SerialNumber.Int64())
+
+	// rotated certificate is presented on the next handshake
+	writeCertificate(t, crtFile, keyFile, 2)
+	assert.NoError(t, certs.Reload())
+	cert, err = tlsConfig.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), cert.Leaf.SerialNumber.Int64())
+
+	// the current certificate is kept if the rotated one is invalid
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte("truncated"), 0600))
+	assert.Error(t, certs.Reload())
+	cert, err = tlsConfig.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), cert.Leaf.SerialNumber.Int64())
+	assert.Equal(t, "2", certs.GetInfo().SerialNumber)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/metrics"
+
+	"github.com/gogf/gf/net/gtcp"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The compression of a delivery connection is negotiated with ALPN. The
+// exporter offers the application protocols of the destination suffixed with
+// the compression first, then the protocols themselves, hi2 standing in for
+// the protocols of the destinations setting none. Once the delivery function
+// selects a compressed protocol, the PDUs written on the connection make up
+// a single gzip or zstd stream, flushed after each write. The PDUs the
+// delivery function sends, e.g. keepalive acknowledgements, aren't
+// compressed.
+const (
+	// CompressionGzip compresses the PDUs written as a gzip stream
+	CompressionGzip = "gzip"
+	// CompressionZstd compresses the PDUs written as a zstd stream
+	CompressionZstd = "zstd"
+	// defaultALPNProtocol is the application protocol offered with the
+	// compression for the destinations setting none
+	defaultALPNProtocol = "hi2"
+)
+
+// getOfferedProtocols returns the application protocols offered for the
+// handshake settings of a destination, by preference
+func getOfferedProtocols(settings HandshakeSettings) []string {
+	if len(settings.Compression) == 0 {
+		return settings.ALPNProtocols
+	}
+	protocols := settings.ALPNProtocols
+	if len(protocols) == 0 {
+		protocols = []string{defaultALPNProtocol}
+	}
+	offered := make([]string, 0, 2*len(protocols))
+	for _, protocol := range protocols {
+		offered = append(offered, protocol+"+"+settings.Compression)
+	}
+	return append(offered, protocols...)
+}
+
+// getNegotiatedCompression returns the compression selected by the delivery
+// function with the application protocol it negotiated, none when empty
+func getNegotiatedCompression(negotiatedProtocol string) string {
+	for _, compression := range []string{CompressionGzip, CompressionZstd} {
+		if strings.HasSuffix(negotiatedProtocol, "+"+compression) {
+			return compression
+		}
+	}
+	return ""
+}
+
+// compressor writes the PDUs of a connection as a compressed stream
+type compressor struct {
+	mutex       sync.Mutex
+	compression string
+	out         *countingWriter
+	zw          flushWriter
+}
+
+// flushWriter is a compressed stream whose pending data can be flushed
+type flushWriter interface {
+	io.Writer
+	Flush() error
+}
+
+func newCompressor(compression string, w io.Writer) *compressor {
+	out := &countingWriter{w: w}
+	ret := &compressor{compression: compression, out: out}
+	if compression == CompressionZstd {
+		// the options are valid, the encoder can't fail to be created
+		ret.zw, _ = zstd.NewWriter(out, zstd.WithEncoderConcurrency(1))
+	} else {
+		ret.zw = gzip.NewWriter(out)
+	}
+	return ret
+}
+
+// send compresses a PDU, or a batch of PDUs, on the stream and flushes it
+// so that it is written right away
+func (c *compressor) send(b []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	start := time.Now()
+	c.out.n = 0
+	if _, err := c.zw.Write(b); err != nil {
+		return err
+	}
+	if err := c.zw.Flush(); err != nil {
+		return err
+	}
+	// the time spent writing on the connection is part of the measure,
+	// which bounds the cost of the compression
+	metrics.CompressionDuration.WithLabelValues(c.compression).Observe(time.Since(start).Seconds())
+	metrics.CompressedBytesSent.WithLabelValues(c.compression).Add(float64(c.out.n))
+	if len(b) != 0 {
+		metrics.CompressionRatio.WithLabelValues(c.compression).Observe(float64(c.out.n) / float64(len(b)))
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written since last reset
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += n
+	return n, err
+}
+
+// connWriter writes on a delivery connection, counting the bytes written
+// in sent
+type connWriter struct {
+	conn *gtcp.Conn
+	sent *uint64
+}
+
+func (w connWriter) Write(p []byte) (int, error) {
+	if err := w.conn.Send(p); err != nil {
+		return 0, err
+	}
+	atomic.AddUint64(w.sent, uint64(len(p)))
+	return len(p), nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfferedProtocols(t *testing.T) {
+	assert.Nil(t, getOfferedProtocols(HandshakeSettings{}))
+	assert.Equal(t, []string{"x2"}, getOfferedProtocols(HandshakeSettings{ALPNProtocols: []string{"x2"}}))
+	assert.Equal(t, []string{"hi2+gzip", "hi2"}, getOfferedProtocols(HandshakeSettings{Compression: CompressionGzip}))
+	assert.Equal(
+		t,
+		[]string{"x3+gzip", "x2+gzip", "x3", "x2"},
+		getOfferedProtocols(HandshakeSettings{ALPNProtocols: []string{"x3", "x2"}, Compression: CompressionGzip}),
+	)
+
+	assert.Equal(t, []string{"hi2+zstd", "hi2"}, getOfferedProtocols(HandshakeSettings{Compression: CompressionZstd}))
+
+	assert.Equal(t, CompressionGzip, getNegotiatedCompression("x2+gzip"))
+	assert.Equal(t, CompressionZstd, getNegotiatedCompression("x2+zstd"))
+	assert.Empty(t, getNegotiatedCompression("x2"))
+	assert.Empty(t, getNegotiatedCompression(""))
+}
+
+func TestCompressor(t *testing.T) {
+	var conn bytes.Buffer
+	c := newCompressor(CompressionGzip, &conn)
+	first, second := bytes.Repeat([]byte{0x30, 0x81}, 64), []byte{0xa1, 0x03, 0x80, 0x01, 0x01}
+
+	// each PDU is flushed as soon as it is written
+	assert.NoError(t, c.send(first))
+	assert.NotZero(t, conn.Len())
+	assert.Less(t, conn.Len(), len(first))
+	r, err := gzip.NewReader(&conn)
+	assert.NoError(t, err)
+	pdu := make([]byte, len(first))
+	_, err = io.ReadFull(r, pdu)
+	assert.NoError(t, err)
+	assert.Equal(t, first, pdu)
+
+	// the PDUs make up a single stream
+	assert.NoError(t, c.send(second))
+	pdu = make([]byte, len(second))
+	_, err = io.ReadFull(r, pdu)
+	assert.NoError(t, err)
+	assert.Equal(t, second, pdu)
+}
+
+func TestZstdCompressor(t *testing.T) {
+	var conn bytes.Buffer
+	c := newCompressor(CompressionZstd, &conn)
+	first, second := bytes.Repeat([]byte{0x30, 0x81}, 64), []byte{0xa1, 0x03, 0x80, 0x01, 0x01}
+
+	// each PDU is flushed as soon as it is written
+	assert.NoError(t, c.send(first))
+	assert.NotZero(t, conn.Len())
+	assert.Less(t, conn.Len(), len(first))
+	assert.Equal(t, first, readZstd(t, conn.Bytes(), len(first)))
+
+	// the PDUs make up a single stream
+	assert.NoError(t, c.send(second))
+	assert.Equal(t, append(first, second...), readZstd(t, conn.Bytes(), len(first)+len(second)))
+}
+
+// readZstd decodes the first n bytes of a zstd stream
+func readZstd(t *testing.T, stream []byte, n int) []byte {
+	r, err := zstd.NewReader(bytes.NewReader(stream))
+	assert.NoError(t, err)
+	defer r.Close()
+	ret := make([]byte, n)
+	_, err = io.ReadFull(r, ret)
+	assert.NoError(t, err)
+	return ret
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// drainPollInterval is the interval the queue of a draining exporter is
+// checked at
+const drainPollInterval = 100 * time.Millisecond
+
+var (
+	// ErrReconnectUnsupported is returned when reconnecting a backend which
+	// holds no connection to the delivery function, e.g. the pcap backend
+	ErrReconnectUnsupported = errors.New("backend holds no delivery connection")
+
+	// ErrUnknownConnection is returned when no exporter delivers to the
+	// destination of a connection request
+	ErrUnknownConnection = errors.New("no exporter delivers to the destination")
+
+	// ErrDrainTimeout is returned when the submitted records are not
+	// delivered before the drain deadline
+	ErrDrainTimeout = errors.New("submitted records not delivered before the drain deadline")
+)
+
+// ConnectionInfo describes the delivery connection of an exporter
+type ConnectionInfo struct {
+	// Address is the delivery function records are currently delivered to,
+	// i.e. the secondary one after a failover
+	Address string
+	// OnSecondary is true when records are delivered to the secondary
+	// delivery function
+	OnSecondary bool
+	// Connected is true when the backend can currently deliver records
+	Connected bool
+	// PeerAddress is the remote address of the connection, empty when not
+	// connected
+	PeerAddress string
+	// Handshake describes the TLS handshake of the connection
+	Handshake *HandshakeInfo
+	// Compression is the compression negotiated on the connection, none when
+	// empty
+	Compression string
+	// Acknowledged is true when the records delivered on the connection are
+	// acknowledged by the LEMF
+	Acknowledged bool
+	// ConnectedAt is the time the connection was established
+	ConnectedAt time.Time
+	// BytesSent counts the bytes written on the connection, after
+	// compression
+	BytesSent uint64
+	// StandbyConnections counts the established standby connections
+	StandbyConnections int
+	// QueuedRecords and QueuedBytes are the submitted records waiting for
+	// delivery and their size
+	QueuedRecords int
+	QueuedBytes   int
+	// LastError is the last delivery failure, nil if none
+	LastError   error
+	LastErrorAt time.Time
+	// Breaker is the state of the circuit breaker of the destination, empty
+	// when it has none, and BreakerOpenedAt the time it last opened
+	Breaker         string
+	BreakerOpenedAt time.Time
+}
+
+// connectionBackend is implemented by the backends holding a connection to
+// the delivery function which can be inspected and re-established
+type connectionBackend interface {
+	// GetConnection describes the current delivery connection
+	GetConnection() *ConnectionInfo
+	// Reconnect closes the delivery connection and its standby connections,
+	// and establishes a new one right away
+	Reconnect() error
+}
+
+// GetConnection describes the delivery connection of the exporter, along
+// with its queue and its last delivery failure
+func (c *RecordExporter) GetConnection() *ConnectionInfo {
+	info := &ConnectionInfo{}
+	backend := c.getBackend()
+	if cb, ok := backend.(connectionBackend); ok {
+		info = cb.GetConnection()
+	}
+	info.Connected = backend.IsConnected()
+	info.QueuedRecords, info.QueuedBytes = c.QueueStats()
+	c.deliveryMutex.Lock()
+	info.LastError, info.LastErrorAt = c.lastFailure, c.lastFailedAt
+	c.deliveryMutex.Unlock()
+	return info
+}
+
+// Reconnect closes the delivery connection of the exporter and establishes a
+// new one right away, without waiting for the reconnection backoff. Records
+// being sent on the closed connection fail and are retried on the new one.
+func (c *RecordExporter) Reconnect() error {
+	return reconnect(c.getBackend())
+}
+
+// Drain waits for the records submitted to the exporter to be delivered,
+// then reconnects, so that no record is retried on the new connection. The
+// records submitted while draining are also delivered first, so the drain
+// fails with ErrDrainTimeout when the queue doesn't empty before ctx is
+// done, leaving the connection as it was.
+func (c *RecordExporter) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if records, _ := c.QueueStats(); records == 0 {
+			return c.Reconnect()
+		}
+		select {
+		case <-ctx.Done():
+			return ErrDrainTimeout
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconnect re-establishes the connection of a backend holding one
+func reconnect(backend Backend) error {
+	if cb, ok := backend.(connectionBackend); ok {
+		return cb.Reconnect()
+	}
+	return ErrReconnectUnsupported
+}
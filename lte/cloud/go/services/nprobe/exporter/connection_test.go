@@ -0,0 +1,65 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconnect(t *testing.T) {
+	listener, accepted := startLEMF(t)
+	defer listener.Close()
+
+	backend := NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{})
+	exp := NewRecordExporter(backend)
+	defer exp.Close()
+	info := exp.GetConnection()
+	assert.True(t, info.Connected)
+	assert.Equal(t, listener.Addr().String(), info.Address)
+	assert.Equal(t, listener.Addr().String(), info.PeerAddress)
+	assert.NotNil(t, info.Handshake)
+	assert.False(t, info.ConnectedAt.IsZero())
+	// records aren't acknowledged without ack timeout
+	assert.False(t, info.Acknowledged)
+
+	backend.mutex.Lock()
+	session := backend.session
+	backend.mutex.Unlock()
+	assert.NoError(t, session.send([]byte("record")))
+	assert.Equal(t, uint64(6), exp.GetConnection().BytesSent)
+
+	// the connection is replaced right away
+	assert.NoError(t, exp.Reconnect())
+	assert.True(t, session.isClosed())
+	assert.Eventually(t, func() bool { return accepted() == 2 }, time.Second, 10*time.Millisecond)
+	assert.Zero(t, exp.GetConnection().BytesSent)
+
+	// the queue being empty, the exporter is reconnected once drained
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, exp.Drain(ctx))
+	assert.Eventually(t, func() bool { return accepted() == 3 }, time.Second, 10*time.Millisecond)
+
+	// the backends holding no connection can't be reconnected
+	failing := NewRecordExporter(&failingBackend{err: errors.New("failure")})
+	defer failing.Close()
+	assert.Equal(t, ErrReconnectUnsupported, failing.Reconnect())
+	assert.False(t, failing.GetConnection().Connected)
+}
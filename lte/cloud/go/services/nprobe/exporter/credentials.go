@@ -0,0 +1,223 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Files of the exporter credentials of a network, in its directory of the
+// network credentials directory
+const (
+	NetworkCrtFileName = "exporter.crt"
+	NetworkKeyFileName = "exporter.key"
+	NetworkCAFileName  = "ca.crt"
+)
+
+// CredentialManager provides the TLS configs the records are delivered with.
+// Records are delivered with the exporter certificate of the service config,
+// unless their network has its own credentials in the network credentials
+// directory: <directory>/<network ID>/exporter.crt and exporter.key, along
+// with an optional ca.crt bundle of the root CAs its delivery functions are
+// verified with, the system ones applying otherwise. A network whose
+// credentials fail to load is never delivered with the ones of the service.
+//
+// The credentials of a network are loaded on first use. Reloading them
+// rotates their certificate from the next handshake on, like the exporter
+// certificate, while the credentials whose root CAs changed get a new
+// generation, so that the connections verified with the previous ones are
+// replaced.
+type CredentialManager struct {
+	mutex      sync.Mutex
+	service    *CertificateStore
+	directory  string
+	networks   map[string]*networkCredentials
+	generation uint64
+}
+
+// networkCredentials are the loaded credentials of a network, nil when the
+// network has none
+type networkCredentials struct {
+	certs      *CertificateStore
+	caBundle   []byte
+	rootCAs    *x509.CertPool
+	generation uint64
+}
+
+// NewCredentialManager creates a manager of the credentials of the networks
+// found in directory, none if empty, the certificate of service applying to
+// the other networks
+func NewCredentialManager(service *CertificateStore, directory string) *CredentialManager {
+	return &CredentialManager{
+		service:   service,
+		directory: directory,
+		networks:  map[string]*networkCredentials{},
+	}
+}
+
+// TLSConfig creates the TLS config presenting the certificate of the service
+func (m *CredentialManager) TLSConfig(skipVerify bool) *tls.Config {
+	return NewTlsConfig(m.service, skipVerify)
+}
+
+// NetworkTLSConfig creates the TLS config presenting the credentials of a
+// network, along with their generation. It fails if the network has none.
+func (m *CredentialManager) NetworkTLSConfig(networkID string, skipVerify bool) (*tls.Config, uint64, error) {
+	credentials, err := m.get(networkID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if credentials == nil {
+		return nil, 0, fmt.Errorf("network %s has no exporter credentials", networkID)
+	}
+	tlsConfig := NewTlsConfig(credentials.certs, skipVerify)
+	tlsConfig.RootCAs = credentials.rootCAs
+	return tlsConfig, credentials.generation, nil
+}
+
+// HasCredentials returns true if a network has its own credentials. It fails
+// if they can't be loaded.
+func (m *CredentialManager) HasCredentials(networkID string) (bool, error) {
+	credentials, err := m.get(networkID)
+	return credentials != nil, err
+}
+
+// SetDirectory replaces the network credentials directory. The credentials
+// of the networks are loaded again from the new one.
+func (m *CredentialManager) SetDirectory(directory string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if directory == m.directory {
+		return
+	}
+	m.directory = directory
+	m.networks = map[string]*networkCredentials{}
+}
+
+// Reload loads the credentials of the networks again from their files, e.g.
+// once rotated. The networks found without credentials are looked up again
+// on their next use. The current credentials of a network are kept if the
+// new ones fail to load.
+func (m *CredentialManager) Reload() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var failure error
+	for networkID, credentials := range m.networks {
+		if credentials == nil {
+			delete(m.networks, networkID)
+			continue
+		}
+		dir := filepath.Join(m.directory, networkID)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			logger.Infof("Removed exporter credentials of network %s", networkID)
+			delete(m.networks, networkID)
+			continue
+		}
+		err := credentials.certs.Reload()
+		if err == nil {
+			err = m.reloadRootCAs(networkID, credentials)
+		}
+		if err != nil {
+			failure = fmt.Errorf("failed to reload exporter credentials of network %s: %v", networkID, err)
+			logger.Errorf("%v", failure)
+		}
+	}
+	return failure
+}
+
+// reloadRootCAs loads the root CAs of a network again, bumping the
+// generation of its credentials if they changed. It must be called with the
+// mutex locked.
+func (m *CredentialManager) reloadRootCAs(networkID string, credentials *networkCredentials) error {
+	caBundle, rootCAs, err := loadRootCAs(filepath.Join(m.directory, networkID, NetworkCAFileName))
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(caBundle, credentials.caBundle) {
+		return nil
+	}
+	m.generation++
+	m.networks[networkID] = &networkCredentials{
+		certs:      credentials.certs,
+		caBundle:   caBundle,
+		rootCAs:    rootCAs,
+		generation: m.generation,
+	}
+	logger.Infof("Reloaded root CAs of network %s", networkID)
+	return nil
+}
+
+// get returns the credentials of a network, loaded if needed, nil if the
+// network has none
+func (m *CredentialManager) get(networkID string) (*networkCredentials, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.directory) == 0 {
+		return nil, nil
+	}
+	if credentials, ok := m.networks[networkID]; ok {
+		return credentials, nil
+	}
+	// network IDs name the directories of the credentials, they can't
+	// reference the credentials of another network
+	if len(networkID) == 0 || networkID != filepath.Base(networkID) || networkID == "." || networkID == ".." {
+		return nil, fmt.Errorf("invalid network ID %q", networkID)
+	}
+	dir := filepath.Join(m.directory, networkID)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		m.networks[networkID] = nil
+		return nil, nil
+	}
+	certs, err := NewCertificateStore(filepath.Join(dir, NetworkCrtFileName), filepath.Join(dir, NetworkKeyFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exporter credentials of network %s: %v", networkID, err)
+	}
+	caBundle, rootCAs, err := loadRootCAs(filepath.Join(dir, NetworkCAFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exporter credentials of network %s: %v", networkID, err)
+	}
+	m.generation++
+	credentials := &networkCredentials{
+		certs:      certs,
+		caBundle:   caBundle,
+		rootCAs:    rootCAs,
+		generation: m.generation,
+	}
+	m.networks[networkID] = credentials
+	logger.Infof("Loaded exporter credentials of network %s", networkID)
+	return credentials, nil
+}
+
+// loadRootCAs loads a bundle of root CAs, none if the file doesn't exist
+func loadRootCAs(caFile string) ([]byte, *x509.CertPool, error) {
+	caBundle, err := ioutil.ReadFile(caFile)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caBundle) {
+		return nil, nil, fmt.Errorf("no certificate found in %s", caFile)
+	}
+	return caBundle, rootCAs, nil
+}
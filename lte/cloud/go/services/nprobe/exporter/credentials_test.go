@@ -0,0 +1,111 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeNetworkCredentials writes the credentials of a network, whose own
+// certificate is its root CA
+func writeNetworkCredentials(t *testing.T, dir, networkID string, serial int64) {
+	networkDir := filepath.Join(dir, networkID)
+	assert.NoError(t, os.MkdirAll(networkDir, 0700))
+	crtFile := filepath.Join(networkDir, NetworkCrtFileName)
+	writeCertificate(t, crtFile, filepath.Join(networkDir, NetworkKeyFileName), serial)
+	crt, err := ioutil.ReadFile(crtFile)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(networkDir, NetworkCAFileName), crt, 0600))
+}
+
+func TestCredentialManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_credentials")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	crtFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writeCertificate(t, crtFile, keyFile, 1)
+	service, err := NewCertificateStore(crtFile, keyFile)
+	assert.NoError(t, err)
+	networksDir := filepath.Join(dir, "networks")
+	writeNetworkCredentials(t, networksDir, "n1", 10)
+
+	// networks share the credentials of the service without a directory
+	credentials := NewCredentialManager(service, "")
+	has, err := credentials.HasCredentials("n1")
+	assert.NoError(t, err)
+	assert.False(t, has)
+	cert, err := credentials.TLSConfig(false).GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), cert.Leaf.SerialNumber.Int64())
+
+	credentials.SetDirectory(networksDir)
+	has, err = credentials.HasCredentials("n1")
+	assert.NoError(t, err)
+	assert.True(t, has)
+	tlsConfig, generation, err := credentials.NetworkTLSConfig("n1", false)
+	assert.NoError(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	cert, err = tlsConfig.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), cert.Leaf.SerialNumber.Int64())
+
+	// networks without credentials are never delivered with the ones of another
+	has, err = credentials.HasCredentials("n2")
+	assert.NoError(t, err)
+	assert.False(t, has)
+	_, _, err = credentials.NetworkTLSConfig("n2", false)
+	assert.Error(t, err)
+	_, err = credentials.HasCredentials("../networks/n1")
+	assert.Error(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Join(networksDir, "n3"), 0700))
+	_, err = credentials.HasCredentials("n3")
+	assert.Error(t, err)
+
+	// rotated certificates are presented on the next handshake, with the
+	// same root CAs
+	writeCertificate(t, filepath.Join(networksDir, "n1", NetworkCrtFileName), filepath.Join(networksDir, "n1", NetworkKeyFileName), 11)
+	assert.NoError(t, credentials.Reload())
+	cert, err = tlsConfig.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), cert.Leaf.SerialNumber.Int64())
+	_, reloaded, err := credentials.NetworkTLSConfig("n1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, generation, reloaded)
+
+	// changed root CAs get a new generation
+	writeNetworkCredentials(t, networksDir, "n1", 12)
+	assert.NoError(t, credentials.Reload())
+	_, reloaded, err = credentials.NetworkTLSConfig("n1", false)
+	assert.NoError(t, err)
+	assert.NotEqual(t, generation, reloaded)
+
+	// credentials provisioned for a network are found once reloaded
+	writeNetworkCredentials(t, networksDir, "n2", 20)
+	assert.NoError(t, credentials.Reload())
+	has, err = credentials.HasCredentials("n2")
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	// and the removed ones are no longer presented
+	assert.NoError(t, os.RemoveAll(filepath.Join(networksDir, "n2")))
+	assert.NoError(t, credentials.Reload())
+	has, err = credentials.HasCredentials("n2")
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
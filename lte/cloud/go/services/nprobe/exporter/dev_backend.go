@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// devDialTimeout bounds the time connecting to the local collector
+const devDialTimeout = 5 * time.Second
+
+// DevBackend sends records in plaintext over tcp to a collector listening on
+// the loopback interface, e.g. nprobe_cli listen, which decodes and validates
+// them. It is meant for development only: records are neither encrypted nor
+// authenticated, hence only loopback addresses are accepted.
+type DevBackend struct {
+	remoteAddr string
+	conn       net.Conn
+	mutex      sync.Mutex
+}
+
+// NewDevBackend creates a new dev backend delivering to a loopback address
+func NewDevBackend(remoteAddr string) (*DevBackend, error) {
+	remoteAddr = NormalizeAddress(remoteAddr)
+	if !IsLoopbackAddress(remoteAddr) {
+		return nil, fmt.Errorf("the %s exporter backend only delivers to loopback addresses, not %s", BackendDev, remoteAddr)
+	}
+	logger.Warningf("Delivering records in plaintext to %s, for development only", remoteAddr)
+	return &DevBackend{remoteAddr: remoteAddr}, nil
+}
+
+// IsLoopbackAddress returns true if the host of a host:port address is
+// localhost or a loopback IP address
+func IsLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Send writes a single record on the connection, establishing it if needed.
+// The connection is closed if the write fails.
+func (d *DevBackend) Send(record []byte, correlationID uint64) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.conn == nil {
+		conn, err := net.DialTimeout("tcp", d.remoteAddr, devDialTimeout)
+		if err != nil {
+			return err
+		}
+		d.conn = conn
+	}
+	if _, err := d.conn.Write(record); err != nil {
+		d.conn.Close()
+		d.conn = nil
+		return err
+	}
+	return nil
+}
+
+// IsConnected returns true if the connection to the collector is established
+func (d *DevBackend) IsConnected() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.conn != nil
+}
+
+// Close closes the connection, a new one is established on the next record
+func (d *DevBackend) Close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.conn != nil {
+		d.conn.Close()
+		d.conn = nil
+	}
+}
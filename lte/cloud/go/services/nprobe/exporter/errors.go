@@ -0,0 +1,126 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"net"
+)
+
+// Classes of the failures to deliver a record
+const (
+	// FailureHandshake is the failure of the TLS handshake with the delivery
+	// function, e.g. on an untrusted or rejected certificate
+	FailureHandshake = "tls_handshake"
+	// FailureConnect is the failure to reach the delivery function
+	FailureConnect = "connect"
+	// FailureConnectionReset is the loss of the connection while writing
+	FailureConnectionReset = "connection_reset"
+	// FailureWriteTimeout is a write which didn't complete in time
+	FailureWriteTimeout = "write_timeout"
+	// FailureAckTimeout is a record not acknowledged by the LEMF in time
+	FailureAckTimeout = "ack_timeout"
+	// FailureNack is a record the LEMF closed the connection on instead of
+	// acknowledging it, HI2 having no negative acknowledgement, or refused
+	// by a mediation function delivered to over gRPC
+	FailureNack = "remote_nack"
+	// FailureEncode is a record too malformed to be delivered
+	FailureEncode = "encode"
+	// FailureQueue is a record refused or abandoned by the export queue
+	FailureQueue = "queue"
+	// FailureBreakerOpen is a record shed while the circuit breaker of its
+	// destination is open
+	FailureBreakerOpen = "breaker_open"
+	// FailureUnknown is any other failure
+	FailureUnknown = "unknown"
+)
+
+// DeliveryError is the failure to deliver a record along with its class
+type DeliveryError struct {
+	Class string
+	Err   error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
+}
+
+// Cause returns the underlying error
+func (e *DeliveryError) Cause() error {
+	return e.Err
+}
+
+// newDeliveryError classifies an error, nil if err is nil
+func newDeliveryError(class string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &DeliveryError{Class: class, Err: err}
+}
+
+// ClassifyError returns the class of a delivery failure, empty if err is nil
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	for e := err; e != nil; {
+		if deliveryErr, ok := e.(*DeliveryError); ok {
+			return deliveryErr.Class
+		}
+		causer, ok := e.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		e = causer.Cause()
+	}
+	switch err {
+	case ErrPreviousRecordFailed, ErrQueueClosed, ErrThrottled, ErrRecordDropped:
+		return FailureQueue
+	}
+	return FailureUnknown
+}
+
+// IsRetryable returns false for the classes of failures which fail the same
+// way when retried right away: a handshake fails until the credentials or
+// the delivery function change, a malformed record stays malformed and an
+// open circuit breaker keeps shedding records until its next probe.
+func IsRetryable(class string) bool {
+	switch class {
+	case FailureHandshake, FailureEncode, FailureQueue, FailureBreakerOpen:
+		return false
+	}
+	return true
+}
+
+// classifyDialError returns the class of a failure to establish a TLS
+// connection. TLS alerts are reported as network errors and set apart from
+// the failures to reach the delivery function; the other errors of the
+// handshake, e.g. of the verification of the certificate, are not network
+// errors.
+func classifyDialError(err error) string {
+	if opErr, ok := err.(*net.OpError); ok && (opErr.Op == "remote error" || opErr.Op == "local error") {
+		return FailureHandshake
+	}
+	if _, ok := err.(net.Error); ok {
+		return FailureConnect
+	}
+	return FailureHandshake
+}
+
+// classifyWriteError returns the class of a failure to write on a connection
+func classifyWriteError(err error) string {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return FailureWriteTimeout
+	}
+	return FailureConnectionReset
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingBackend fails to deliver all records with the same error
+type failingBackend struct {
+	err   error
+	sends int
+}
+
+func (b *failingBackend) Send(record []byte, correlationID uint64) error {
+	b.sends++
+	return b.err
+}
+
+func (b *failingBackend) IsConnected() bool { return false }
+
+func (b *failingBackend) Close() {}
+
+func TestClassifyError(t *testing.T) {
+	assert.Equal(t, "", ClassifyError(nil))
+	assert.Equal(t, FailureUnknown, ClassifyError(errors.New("failure")))
+	assert.Equal(t, FailureQueue, ClassifyError(ErrThrottled))
+	assert.Equal(t, FailureQueue, ClassifyError(ErrPreviousRecordFailed))
+	assert.Equal(t, FailureAckTimeout, ClassifyError(errAckTimeout))
+	assert.Equal(t, FailureNack, ClassifyError(errors.Wrap(errConnectionClosed, "record 1")))
+
+	// the certificate of the delivery function isn't trusted
+	listener, _ := startLEMF(t)
+	defer listener.Close()
+	backend := NewTLSBackend(listener.Addr().String(), &tls.Config{}, TLSBackendConfig{MaxReconnectBackoff: time.Minute})
+	defer backend.Close()
+	err := backend.Send([]byte("record"), 1)
+	assert.Contains(t, err.Error(), "backing off")
+	assert.Equal(t, FailureHandshake, ClassifyError(err))
+
+	// the delivery function is unreachable
+	unreachable, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := unreachable.Addr().String()
+	unreachable.Close()
+	backend = NewTLSBackend(addr, &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{})
+	defer backend.Close()
+	assert.Equal(t, FailureConnect, ClassifyError(backend.Send([]byte("record"), 1)))
+}
+
+func TestRetryClasses(t *testing.T) {
+	backend := &failingBackend{err: &DeliveryError{Class: FailureConnect, Err: errors.New("connection refused")}}
+	c := NewRecordExporter(backend)
+	defer c.Close()
+	err := c.SendMessageWithRetries([]byte("record"), 1, 3)
+	assert.Equal(t, FailureConnect, ClassifyError(err))
+	assert.Equal(t, 3, backend.sends)
+
+	// records without retries are still sent once
+	backend = &failingBackend{err: &DeliveryError{Class: FailureConnect, Err: errors.New("connection refused")}}
+	c.SetBackend(backend)
+	assert.Error(t, c.SendMessageWithRetries([]byte("record"), 1, 0))
+	assert.Equal(t, 1, backend.sends)
+	errs := c.SendBatchWithRetries([]BatchRecord{{Record: []byte("record")}}, []uint32{0})
+	assert.Equal(t, FailureConnect, ClassifyError(errs[0]))
+	assert.Equal(t, 2, backend.sends)
+
+	// failing handshakes and malformed records are not retried
+	for _, class := range []string{FailureHandshake, FailureEncode} {
+		backend = &failingBackend{err: &DeliveryError{Class: class, Err: errors.New(class)}}
+		c.SetBackend(backend)
+		assert.Error(t, c.SendMessageWithRetries([]byte("record"), 1, 3))
+		assert.Equal(t, 1, backend.sends)
+
+		errs := c.SendBatchWithRetries([]BatchRecord{{Record: []byte("record")}}, []uint32{3})
+		assert.Equal(t, class, ClassifyError(errs[0]))
+		assert.Equal(t, 2, backend.sends)
+	}
+}
@@ -14,111 +14,407 @@ limitations under the License.
 package exporter
 
 import (
+	"context"
 	"crypto/tls"
-	"errors"
+	"fmt"
+	"math"
+	"reflect"
 	"sync"
+	"time"
 
-	"github.com/gogf/gf/net/gtcp"
-	"github.com/golang/glog"
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
 )
 
-// RecordExporter sends records to a remote host over tcp/tls
+var logger = logging.New(logging.ComponentExporter)
+
+const (
+	// BackendTLS delivers records over a tls socket
+	BackendTLS = "tls"
+	// BackendKafka delivers records to a kafka topic
+	BackendKafka = "kafka"
+	// BackendGRPC streams records over gRPC to a cloud-native mediation function
+	BackendGRPC = "grpc"
+	// BackendPcap writes records to local pcap-ng files instead of delivering them
+	BackendPcap = "pcap"
+	// BackendDev delivers records in plaintext to a local collector, in dev mode only
+	BackendDev = "dev"
+)
+
+// Backend is the transport delivering records to the remote collector
+type Backend interface {
+	// Send delivers a single record. The correlation ID identifies
+	// the intercepted session the record belongs to.
+	Send(record []byte, correlationID uint64) error
+
+	// IsConnected returns true if the backend can currently deliver records
+	IsConnected() bool
+
+	// Close releases the resources held by the backend
+	Close()
+}
+
+// RecordExporter sends records to the remote collector through a backend.
+// Records submitted by several tasks are fairly scheduled on the backend.
+// The backend can be replaced at runtime, e.g. when the service config is
+// reloaded.
 type RecordExporter struct {
-	tlsConfig  *tls.Config
-	conn       *gtcp.Conn
-	remoteAddr string
-	mutex      sync.Mutex
+	backend   Backend
+	handshake HandshakeSettings
+	queue     *fairQueue
+	mutex     sync.RWMutex
+	// pipelined counts the records in flight on each backend
+	pipelined pipelinedBackends
+
+	// the outcome of the last delivery, reported in the service health
+	deliveryMutex   sync.Mutex
+	lastDeliveredAt time.Time
+	lastFailedAt    time.Time
+	lastFailure     error
 }
 
-// NewTlsConfig creates a new TLS config from the client certificates
-func NewTlsConfig(crtFile, keyFile string, skipVerify bool) (*tls.Config, error) {
-	cert, err := tls.LoadX509KeyPair(crtFile, keyFile)
-	if err != nil {
-		return nil, err
-	}
+// NewTlsConfig creates a new TLS config presenting the current client
+// certificate of the store on each full handshake. Reconnections resume the
+// previous TLS session with session tickets or IDs when the delivery function
+// supports them.
+func NewTlsConfig(certs *CertificateStore, skipVerify bool) *tls.Config {
 	return &tls.Config{
-		Certificates:       []tls.Certificate{cert},
-		InsecureSkipVerify: skipVerify,
-	}, nil
+		GetClientCertificate: certs.GetClientCertificate,
+		ClientSessionCache:   certs.SessionCache(),
+		InsecureSkipVerify:   skipVerify,
+	}
 }
 
-// NewRecordExporter creates a new tls exporter and attempt to establish a connection at start
-func NewRecordExporter(remoteAddr string, tlsConfig *tls.Config) *RecordExporter {
-	client := &RecordExporter{
-		tlsConfig:  tlsConfig,
-		remoteAddr: remoteAddr,
+// NewBackend creates the backend selected in the service config. When
+// pcap mirroring is enabled, delivered records are also written to pcap files.
+// With the x2 output format, records are delivered and mirrored as X2 PDUs.
+func NewBackend(config nprobe.Config, tlsConfig *tls.Config) (Backend, error) {
+	switch config.OutputFormat {
+	case encoding.OutputFormatHI2:
+		return newTransportBackend(config, tlsConfig)
+	case encoding.OutputFormatX2:
+		backend, err := newTransportBackend(config, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		return NewX2Backend(backend), nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %s", config.OutputFormat)
+	}
+}
+
+// newTransportBackend creates the backend delivering records with the
+// transport selected in the service config, the tls backend connecting over
+// tcp or SCTP. With a secondary delivery function, the tls backend fails
+// over to it. The grpc backend streams records to a mediation function of
+// the service registry, or at the delivery function address. With a failure
+// threshold, a circuit breaker sheds the records of the delivery functions
+// failing.
+func newTransportBackend(config nprobe.Config, tlsConfig *tls.Config) (Backend, error) {
+	if len(config.SecondaryDeliveryFunctionAddr) != 0 && config.ExporterBackend != BackendTLS {
+		return nil, fmt.Errorf("a secondary delivery function requires the %s exporter backend, not %s", BackendTLS, config.ExporterBackend)
+	}
+	framer, err := encoding.NewFramer(config.ExportFraming, config.OperatorID)
+	if err != nil {
+		return nil, err
+	}
+	if framer.Framing() != encoding.FramingNative && config.ExporterBackend != BackendTLS {
+		return nil, fmt.Errorf("the %s framing requires the %s exporter backend, not %s", framer.Framing(), BackendTLS, config.ExporterBackend)
+	}
+	if err = framer.SetEncoding(config.ExportEncoding); err != nil {
+		return nil, err
+	}
+	if framer.Encoding() != encoding.EncodingDER && config.ExporterBackend != BackendTLS {
+		return nil, fmt.Errorf("the %s encoding requires the %s exporter backend, not %s", framer.Encoding(), BackendTLS, config.ExporterBackend)
+	}
+	if config.ExportHeaderVersion > math.MaxUint16 {
+		return nil, fmt.Errorf("unsupported header version %d", config.ExportHeaderVersion)
+	}
+	if err = framer.SetHeaderVersion(uint16(config.ExportHeaderVersion)); err != nil {
+		return nil, err
+	}
+	if (framer.HeaderVersion() != encoding.HeaderVersion || config.NegotiateHeaderVersion) && config.ExporterBackend != BackendTLS {
+		return nil, fmt.Errorf("header version %d or its negotiation requires the %s exporter backend, not %s", framer.HeaderVersion(), BackendTLS, config.ExporterBackend)
+	}
+	if len(config.ExportTransport) != 0 && config.ExporterBackend != BackendTLS {
+		return nil, fmt.Errorf("the %s transport requires the %s exporter backend, not %s", config.ExportTransport, BackendTLS, config.ExporterBackend)
+	}
+	if err = ValidateTransport(config.ExportTransport, config.SCTPSecurity); err != nil {
+		return nil, err
+	}
+	if config.ExportMaxRecordSize != 0 && config.ExporterBackend != BackendTLS {
+		return nil, fmt.Errorf("a maximum record size requires the %s exporter backend, not %s", BackendTLS, config.ExporterBackend)
+	}
+	if err = framer.SetMaxRecordSize(int(config.ExportMaxRecordSize)); err != nil {
+		return nil, err
+	}
+	var backend Backend
+	switch config.ExporterBackend {
+	case BackendTLS:
+		tlsBackendConfig := TLSBackendConfig{
+			KeepaliveInterval:      time.Duration(config.KeepaliveIntervalSecs) * time.Second,
+			AckTimeout:             time.Duration(config.AckTimeoutSecs) * time.Second,
+			PoolSize:               int(config.TLSPoolSize),
+			MaxReconnectBackoff:    time.Duration(config.ReconnectMaxBackoffSecs) * time.Second,
+			Framing:                framer.Framing(),
+			Encoding:               framer.Encoding(),
+			OperatorID:             config.OperatorID,
+			HeaderVersion:          framer.HeaderVersion(),
+			NegotiateHeaderVersion: config.NegotiateHeaderVersion,
+			WriteTimeout:           time.Duration(config.ExportWriteTimeoutMs) * time.Millisecond,
+			Transport:              config.ExportTransport,
+			SCTPLocalAddrs:         config.SCTPLocalAddrs,
+			SCTPSecurity:           config.SCTPSecurity,
+			MaxRecordSize:          framer.MaxRecordSize(),
+		}
+		backend = NewTLSBackend(config.DeliveryFunctionAddr, tlsConfig, tlsBackendConfig)
+		if len(config.SecondaryDeliveryFunctionAddr) != 0 {
+			backend = NewFailoverBackend(
+				backend,
+				NewTLSBackend(config.SecondaryDeliveryFunctionAddr, tlsConfig, tlsBackendConfig),
+				config.DeliveryFunctionAddr,
+				time.Duration(config.FailbackIntervalSecs)*time.Second,
+			)
+		}
+	case BackendKafka:
+		backend, err = NewKafkaBackend(config.KafkaBrokers, config.KafkaTopic, tlsConfig)
+	case BackendGRPC:
+		backend, err = NewGRPCBackend(GRPCBackendConfig{
+			Service:    config.GRPCDeliveryService,
+			Address:    config.DeliveryFunctionAddr,
+			AckTimeout: time.Duration(config.AckTimeoutSecs) * time.Second,
+		}, tlsConfig)
+	case BackendPcap:
+		return newPcapBackend(config)
+	case BackendDev:
+		if !config.DevMode {
+			return nil, fmt.Errorf("the %s exporter backend requires dev_mode", BackendDev)
+		}
+		backend, err = NewDevBackend(config.DeliveryFunctionAddr)
+	default:
+		return nil, fmt.Errorf("unsupported exporter backend %s", config.ExporterBackend)
 	}
-	conn, err := client.getTlsConnection() // attempt to establish connection at start
 	if err != nil {
-		glog.Errorf(
-			"Failed to establish new TLS connection from to '%s'; error: %v, will retry later.",
-			remoteAddr, err)
+		return nil, err
 	}
-	client.conn = conn
-	return client
+	if config.BreakerFailureThreshold != 0 {
+		backend = NewBreakerBackend(
+			backend,
+			GetDestinationName(config),
+			config.BreakerFailureThreshold,
+			time.Duration(config.BreakerOpenSecs)*time.Second,
+		)
+	}
+	if !config.PcapMirror {
+		return backend, nil
+	}
+
+	pcap, err := newPcapBackend(config)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+	return NewMirrorBackend(backend, pcap), nil
+}
+
+// GetDestinationName returns the destination the backend selected in the
+// service config delivers to
+func GetDestinationName(config nprobe.Config) string {
+	switch config.ExporterBackend {
+	case BackendKafka:
+		return BackendKafka + "/" + config.KafkaTopic
+	case BackendGRPC:
+		if len(config.GRPCDeliveryService) != 0 {
+			return BackendGRPC + "/" + config.GRPCDeliveryService
+		}
+	case BackendPcap:
+		return BackendPcap
+	}
+	return config.DeliveryFunctionAddr
 }
 
-// SendMessageWithRetries writes data to remote address with a retry counter
-func (c *RecordExporter) SendMessageWithRetries(message []byte, retryCount uint32) error {
+// IsBackendConfigChanged returns true if the backend settings differ between
+// two service configs, requiring a new backend to be created. Changes of the
+// certificate files are applied through the CertificateStore instead.
+func IsBackendConfigChanged(old, new nprobe.Config) bool {
+	return old.ExporterBackend != new.ExporterBackend ||
+		old.OutputFormat != new.OutputFormat ||
+		old.DeliveryFunctionAddr != new.DeliveryFunctionAddr ||
+		old.SecondaryDeliveryFunctionAddr != new.SecondaryDeliveryFunctionAddr ||
+		old.FailbackIntervalSecs != new.FailbackIntervalSecs ||
+		old.SkipVerifyServer != new.SkipVerifyServer ||
+		!reflect.DeepEqual(old.KafkaBrokers, new.KafkaBrokers) ||
+		old.KafkaTopic != new.KafkaTopic ||
+		old.GRPCDeliveryService != new.GRPCDeliveryService ||
+		old.KeepaliveIntervalSecs != new.KeepaliveIntervalSecs ||
+		old.AckTimeoutSecs != new.AckTimeoutSecs ||
+		old.ExportFraming != new.ExportFraming ||
+		old.ExportEncoding != new.ExportEncoding ||
+		old.ExportHeaderVersion != new.ExportHeaderVersion ||
+		old.ExportMaxRecordSize != new.ExportMaxRecordSize ||
+		old.NegotiateHeaderVersion != new.NegotiateHeaderVersion ||
+		old.OperatorID != new.OperatorID ||
+		old.TLSPoolSize != new.TLSPoolSize ||
+		old.ReconnectMaxBackoffSecs != new.ReconnectMaxBackoffSecs ||
+		old.ExportWriteTimeoutMs != new.ExportWriteTimeoutMs ||
+		old.ExportTransport != new.ExportTransport ||
+		!reflect.DeepEqual(old.SCTPLocalAddrs, new.SCTPLocalAddrs) ||
+		old.SCTPSecurity != new.SCTPSecurity ||
+		old.BreakerFailureThreshold != new.BreakerFailureThreshold ||
+		old.BreakerOpenSecs != new.BreakerOpenSecs ||
+		old.DevMode != new.DevMode ||
+		old.PcapMirror != new.PcapMirror ||
+		old.PcapDirectory != new.PcapDirectory ||
+		old.PcapRotationSizeMB != new.PcapRotationSizeMB ||
+		old.PcapRetentionHours != new.PcapRetentionHours
+}
+
+func newPcapBackend(config nprobe.Config) (*PcapBackend, error) {
+	return NewPcapBackend(
+		config.PcapDirectory,
+		int64(config.PcapRotationSizeMB)<<20,
+		time.Duration(config.PcapRetentionHours)*time.Hour,
+	)
+}
+
+// NewRecordExporter creates a new exporter delivering records through the backend
+func NewRecordExporter(backend Backend) *RecordExporter {
+	c := &RecordExporter{backend: backend}
+	c.queue = newFairQueue(c.sendWithRetries)
+	c.queue.sendBatch = c.SendBatchWithRetries
+	c.queue.sendAsync = c.sendPipelined
+	return c
+}
+
+// SubmitRecords queues the records of a task for delivery and returns the
+// Delivery reporting the result of each record. Records of a task are
+// delivered in order and tasks share the backend in proportion to their
+// weight. Once a record fails, the following ones of the task fail with
+// ErrPreviousRecordFailed. Records are not sent once ctx is cancelled.
+func (c *RecordExporter) SubmitRecords(
+	ctx context.Context,
+	taskKey string,
+	weight uint32,
+	correlationID uint64,
+	records [][]byte,
+	retryCount uint32,
+) *Delivery {
+	return c.queue.submit(ctx, taskKey, weight, correlationID, records, retryCount)
+}
+
+// SendMessageWithRetries writes data to remote address with a retry counter,
+// making a single attempt when it is zero. The failures which would fail
+// again right away are not retried.
+func (c *RecordExporter) SendMessageWithRetries(message []byte, correlationID uint64, retryCount uint32) error {
+	return c.sendWithRetries(context.Background(), message, correlationID, retryCount)
+}
+
+// sendWithRetries writes a record with a retry counter, propagating the
+// trace context of the span of ctx to the backends supporting it
+func (c *RecordExporter) sendWithRetries(ctx context.Context, message []byte, correlationID uint64, retryCount uint32) error {
 	var err error
-	for i := 0; i < int(retryCount); i++ {
-		err = c.sendMessage(message)
+	class := encoding.GetRecordClass(message)
+	attempts := int(retryCount)
+	if attempts == 0 {
+		attempts = 1
+	}
+	for i := 0; i < attempts; i++ {
+		start := time.Now()
+		backend := c.getBackend()
+		err = sendTraced(ctx, backend, message, correlationID)
+		metrics.ExportLatency.WithLabelValues(class).Observe(time.Since(start).Seconds())
+		if backend != c.getBackend() {
+			// the backend was replaced while sending and may have reconnected
+			backend.Close()
+		}
 		// send succeeded
 		if err == nil {
+			metrics.RecordsSent.WithLabelValues(class).Inc()
+			metrics.BytesSent.WithLabelValues(class).Add(float64(len(message)))
+			c.setDeliveryOutcome(nil)
 			return nil
 		}
+		failure := ClassifyError(err)
+		metrics.DeliveryFailures.WithLabelValues(failure).Inc()
+		if !IsRetryable(failure) {
+			break
+		}
 	}
+	c.setDeliveryOutcome(err)
 	return err
 }
 
-// sendMessage sends a single message on the connection. If the connection is
-// not established, this establishes it. If the message sending fails, the
-// connection is closed
-func (c *RecordExporter) sendMessage(message []byte) error {
-	conn, err := c.getTlsConnection()
-	if err != nil {
-		return err
+// CheckDelivery returns the error of the last delivery if it failed, nil
+// if it succeeded or no record was delivered yet
+func (c *RecordExporter) CheckDelivery() error {
+	c.deliveryMutex.Lock()
+	defer c.deliveryMutex.Unlock()
+	if c.lastFailure == nil || c.lastDeliveredAt.After(c.lastFailedAt) {
+		return nil
 	}
+	return fmt.Errorf(
+		"delivery failed at %s, connected: %t: %v",
+		c.lastFailedAt.UTC().Format(time.RFC3339), c.IsConnected(), c.lastFailure,
+	)
+}
 
-	// It's possible that the connection is closed here in contention for the
-	// connection. This is handled as an error and the sending can retry
-	err = c.conn.Send(message)
+func (c *RecordExporter) setDeliveryOutcome(err error) {
+	c.deliveryMutex.Lock()
+	defer c.deliveryMutex.Unlock()
 	if err != nil {
-		// write failed, close and cleanup connection
-		c.destroyConnection(conn)
+		c.lastFailure, c.lastFailedAt = err, time.Now()
+	} else {
+		c.lastDeliveredAt = time.Now()
 	}
-	return err
 }
 
-// getTlsConnection returns the existing connection or
-// dials and initializes a connection if it doesn't exist
-func (c *RecordExporter) getTlsConnection() (*gtcp.Conn, error) {
+// SetBackend replaces the backend delivering records and closes the previous
+// one. Records being sent on the previous backend fail and are retried on the
+// new one. The current handshake settings apply to the new backend.
+func (c *RecordExporter) SetBackend(backend Backend) {
 	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	if c.conn != nil {
-		return c.conn, nil
-	}
-	if len(c.remoteAddr) == 0 {
-		return nil, errors.New("Invalid remote address")
+	previous := c.backend
+	c.backend = backend
+	if hb, ok := backend.(handshakeBackend); ok {
+		hb.SetHandshakeSettings(c.handshake)
 	}
+	c.mutex.Unlock()
+	previous.Close()
+	// failures of the previous backend don't affect the health of the new one
+	c.deliveryMutex.Lock()
+	c.lastFailure = nil
+	c.deliveryMutex.Unlock()
+}
 
-	conn, err := gtcp.NewConnTLS(c.remoteAddr, c.tlsConfig)
-	c.conn = conn
-	return c.conn, err
+// Close waits for the submitted records to be delivered then closes the
+// backend. No record can be submitted afterwards.
+func (c *RecordExporter) Close() {
+	c.queue.close()
+	c.getBackend().Close()
 }
 
-// destroyConnection closes a bad connection. If the connection
-// passed is the same as the one stored in the locked connection, it is nullified.
-// If the passed connection is not the same, this probably means another go routine
-// already created a new connection - just try to close it and return.
-func (c *RecordExporter) destroyConnection(conn *gtcp.Conn) {
-	if conn == nil {
-		return
-	}
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	if conn == c.conn {
-		c.conn = nil
-	}
-	conn.Close()
+// Disconnect closes the backend connection without waiting for the submitted
+// records. With the tls backend, a new connection is established on the next
+// message sent.
+func (c *RecordExporter) Disconnect() {
+	c.getBackend().Close()
+}
+
+// QueueStats returns the submitted records waiting for delivery, including
+// the ones held for reordering, and their size in bytes
+func (c *RecordExporter) QueueStats() (int, int) {
+	return c.queue.stats()
+}
+
+// IsConnected returns true if the backend can currently deliver records
+func (c *RecordExporter) IsConnected() bool {
+	return c.getBackend().IsConnected()
+}
+
+func (c *RecordExporter) getBackend() Backend {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.backend
 }
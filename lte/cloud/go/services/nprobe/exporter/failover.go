@@ -0,0 +1,264 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+)
+
+// FailoverBackend delivers records to a primary delivery function and fails
+// over to a secondary one, e.g. the standby LEMF of a dual-homed LEA, once a
+// record fails on the primary, whether it couldn't be written or wasn't
+// acknowledged in time. The failed record is delivered again to the
+// secondary. Records carry their own sequence numbers so that their
+// numbering continues across both delivery functions. Once the failback
+// interval elapsed, the primary is connected again in the background and
+// delivery fails back to it when it succeeds. Both transitions are logged
+// and counted by the nprobe_delivery_failovers_total metric.
+type FailoverBackend struct {
+	primary          Backend
+	secondary        Backend
+	name             string
+	failbackInterval time.Duration
+	logger           *logging.Logger
+
+	mutex        sync.Mutex
+	onSecondary  bool
+	failedOverAt time.Time
+	failingBack  bool
+}
+
+// NewFailoverBackend creates a new backend delivering records through
+// primary, failing over to secondary. name identifies the primary delivery
+// function in the logs and metrics.
+func NewFailoverBackend(primary, secondary Backend, name string, failbackInterval time.Duration) *FailoverBackend {
+	metrics.DeliveryOnSecondary.WithLabelValues(name).Set(0)
+	return &FailoverBackend{
+		primary:          primary,
+		secondary:        secondary,
+		name:             name,
+		failbackInterval: failbackInterval,
+		logger:           logger.With(logging.FieldDestination, name),
+	}
+}
+
+// Send delivers a record to the active delivery function, failing over to
+// the secondary when it fails on the primary
+func (f *FailoverBackend) Send(record []byte, correlationID uint64) error {
+	return f.SendTraced(context.Background(), record, correlationID)
+}
+
+// SendTraced delivers a record along with its trace context to the active
+// delivery function, failing over to the secondary when it fails on the
+// primary
+func (f *FailoverBackend) SendTraced(ctx context.Context, record []byte, correlationID uint64) error {
+	backend := f.getActive()
+	err := sendTraced(ctx, backend, record, correlationID)
+	if err == nil || backend != f.primary {
+		return err
+	}
+	f.failover(err)
+	return sendTraced(ctx, f.secondary, record, correlationID)
+}
+
+// SendAsync writes a record to the active delivery function. When it fails
+// on the primary, it is delivered again to the secondary.
+func (f *FailoverBackend) SendAsync(record []byte, correlationID uint64) *PendingRecord {
+	backend := f.getActive()
+	pending := sendAsync(backend, record, correlationID)
+	if backend != f.primary {
+		return pending
+	}
+	return newPendingRecord(func() error {
+		err := pending.Wait()
+		if err == nil {
+			return nil
+		}
+		f.failover(err)
+		return f.secondary.Send(record, correlationID)
+	})
+}
+
+// SendBatch delivers records to the active delivery function. When some
+// fail on the primary, they are delivered again, in order, to the secondary.
+func (f *FailoverBackend) SendBatch(records []BatchRecord) []error {
+	backend := f.getActive()
+	errs := sendBatch(backend, records)
+	if backend != f.primary {
+		return errs
+	}
+	var failed []BatchRecord
+	var indexes []int
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, records[i])
+			indexes = append(indexes, i)
+		}
+	}
+	if len(failed) == 0 {
+		return errs
+	}
+	f.failover(errs[indexes[0]])
+	for j, err := range sendBatch(f.secondary, failed) {
+		errs[indexes[j]] = err
+	}
+	return errs
+}
+
+// IsConnected returns the connection state of the active delivery function
+func (f *FailoverBackend) IsConnected() bool {
+	return f.getActive().IsConnected()
+}
+
+// IsOnSecondary returns true if records are delivered to the secondary
+// delivery function
+func (f *FailoverBackend) IsOnSecondary() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.onSecondary
+}
+
+// SetHandshakeSettings applies the handshake settings to both delivery
+// functions
+func (f *FailoverBackend) SetHandshakeSettings(settings HandshakeSettings) {
+	for _, backend := range []Backend{f.primary, f.secondary} {
+		if hb, ok := backend.(handshakeBackend); ok {
+			hb.SetHandshakeSettings(settings)
+		}
+	}
+}
+
+// GetHandshake describes the handshake of the active delivery function
+func (f *FailoverBackend) GetHandshake() *HandshakeInfo {
+	if hb, ok := f.getActive().(handshakeBackend); ok {
+		return hb.GetHandshake()
+	}
+	return nil
+}
+
+// Probe probes the active delivery function
+func (f *FailoverBackend) Probe(timeout time.Duration) (*HandshakeInfo, error) {
+	if hb, ok := f.getActive().(handshakeBackend); ok {
+		return hb.Probe(timeout)
+	}
+	return nil, ErrProbeUnsupported
+}
+
+// Connect connects the active delivery function, failing over to the
+// secondary when the primary can't be connected
+func (f *FailoverBackend) Connect() error {
+	backend := f.getActive()
+	err := connect(backend)
+	if err == nil || backend != f.primary {
+		return err
+	}
+	f.failover(err)
+	return connect(f.secondary)
+}
+
+// GetConnection describes the connection of the active delivery function
+func (f *FailoverBackend) GetConnection() *ConnectionInfo {
+	info := &ConnectionInfo{}
+	if cb, ok := f.getActive().(connectionBackend); ok {
+		info = cb.GetConnection()
+	}
+	info.OnSecondary = f.IsOnSecondary()
+	return info
+}
+
+// Reconnect reconnects the primary delivery function, failing back to it
+// when records are delivered to the secondary. The secondary is reconnected
+// and records fail over to it when the primary can't be connected.
+func (f *FailoverBackend) Reconnect() error {
+	err := reconnect(f.primary)
+	if err != nil {
+		f.failover(err)
+		return reconnect(f.secondary)
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.onSecondary {
+		f.onSecondary = false
+		f.logger.Warningf("Primary delivery function %s reconnected, failing back to it", f.name)
+		metrics.DeliveryFailovers.WithLabelValues(f.name, metrics.TransitionFailback).Inc()
+		metrics.DeliveryOnSecondary.WithLabelValues(f.name).Set(0)
+	}
+	return nil
+}
+
+// Close closes both delivery functions
+func (f *FailoverBackend) Close() {
+	f.primary.Close()
+	f.secondary.Close()
+}
+
+// getActive returns the delivery function records are delivered to, and
+// starts failing back to the primary once the failback interval elapsed
+func (f *FailoverBackend) getActive() Backend {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if !f.onSecondary {
+		return f.primary
+	}
+	if !f.failingBack && time.Since(f.failedOverAt) >= f.failbackInterval {
+		f.failingBack = true
+		go f.failback()
+	}
+	return f.secondary
+}
+
+// failover switches the delivery to the secondary delivery function
+func (f *FailoverBackend) failover(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.onSecondary {
+		return
+	}
+	f.onSecondary, f.failedOverAt = true, time.Now()
+	f.logger.Warningf("Delivery to %s failed, failing over to its secondary delivery function: %v", f.name, err)
+	metrics.DeliveryFailovers.WithLabelValues(f.name, metrics.TransitionFailover).Inc()
+	metrics.DeliveryOnSecondary.WithLabelValues(f.name).Set(1)
+}
+
+// failback connects the primary delivery function and switches the delivery
+// back to it once connected. The delivery stays on the secondary for another
+// failback interval otherwise.
+func (f *FailoverBackend) failback() {
+	err := connect(f.primary)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failingBack = false
+	if err != nil {
+		f.failedOverAt = time.Now()
+		f.logger.V(2).Infof("Primary delivery function %s still unreachable, staying on its secondary: %v", f.name, err)
+		return
+	}
+	f.onSecondary = false
+	f.logger.Warningf("Primary delivery function %s reachable again, failing back to it", f.name)
+	metrics.DeliveryFailovers.WithLabelValues(f.name, metrics.TransitionFailback).Inc()
+	metrics.DeliveryOnSecondary.WithLabelValues(f.name).Set(0)
+}
+
+// connect establishes the connection of a backend holding one
+func connect(backend Backend) error {
+	if cb, ok := backend.(connectBackend); ok {
+		return cb.Connect()
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lemfBackend records the records delivered to a delivery function, which
+// can be made unreachable
+type lemfBackend struct {
+	mutex sync.Mutex
+	down  bool
+	sent  []string
+}
+
+func (b *lemfBackend) Send(record []byte, correlationID uint64) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.down {
+		return errors.New("ack timeout")
+	}
+	b.sent = append(b.sent, string(record))
+	return nil
+}
+
+func (b *lemfBackend) Connect() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (b *lemfBackend) IsConnected() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return !b.down
+}
+
+func (b *lemfBackend) Close() {}
+
+func (b *lemfBackend) setDown(down bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.down = down
+}
+
+func (b *lemfBackend) getSent() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]string{}, b.sent...)
+}
+
+func TestFailoverBackend(t *testing.T) {
+	primary, secondary := &lemfBackend{}, &lemfBackend{}
+	backend := NewFailoverBackend(primary, secondary, "primary:4040", 50*time.Millisecond)
+
+	assert.NoError(t, backend.Send([]byte("r1"), 1))
+	assert.False(t, backend.IsOnSecondary())
+
+	// the record failing on the primary is delivered again to the secondary,
+	// which then receives the following records
+	primary.setDown(true)
+	assert.NoError(t, backend.Send([]byte("r2"), 1))
+	assert.True(t, backend.IsOnSecondary())
+	assert.NoError(t, backend.Send([]byte("r3"), 1))
+	assert.Equal(t, []string{"r1"}, primary.getSent())
+	assert.Equal(t, []string{"r2", "r3"}, secondary.getSent())
+
+	// the delivery stays on the secondary while the primary is unreachable
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, backend.Send([]byte("r4"), 1))
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, backend.IsOnSecondary())
+
+	// and fails back once the primary is reachable again
+	primary.setDown(false)
+	assert.Eventually(t, func() bool {
+		backend.IsConnected()
+		return !backend.IsOnSecondary()
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, backend.Send([]byte("r5"), 1))
+	assert.Equal(t, []string{"r1", "r5"}, primary.getSent())
+	assert.Equal(t, []string{"r2", "r3", "r4"}, secondary.getSent())
+
+	// the failed records of a batch are delivered again to the secondary
+	primary.setDown(true)
+	errs := backend.SendBatch([]BatchRecord{{Record: []byte("r6")}, {Record: []byte("r7")}})
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.True(t, backend.IsOnSecondary())
+	assert.Equal(t, []string{"r2", "r3", "r4", "r6", "r7"}, secondary.getSent())
+
+	// records fail once both delivery functions are unreachable
+	secondary.setDown(true)
+	assert.Error(t, backend.Send([]byte("r8"), 1))
+}
@@ -0,0 +1,439 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/alert"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+
+	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fairQueueQuantum is the number of bytes a flow of weight 1 may
+// send on each round
+const fairQueueQuantum = 4096
+
+var (
+	// ErrPreviousRecordFailed is returned for the records queued behind a
+	// record of the same flow which failed to be delivered
+	ErrPreviousRecordFailed = errors.New("previous record of the flow failed to be delivered")
+	// ErrQueueClosed is returned for the records submitted after the queue is closed
+	ErrQueueClosed = errors.New("record queue is closed")
+)
+
+// sendFunc delivers a record, ctx carrying the span delivering it
+type sendFunc func(ctx context.Context, record []byte, correlationID uint64, retryCount uint32) error
+
+// Delivery reports the delivery results of records submitted together.
+// Results are shared by the whole submission instead of a channel per
+// record so that queuing small records doesn't allocate per record.
+type Delivery struct {
+	ctx           context.Context
+	correlationID uint64
+	retryCount    uint32
+	queuedAt      time.Time
+
+	mutex   sync.Mutex
+	settled *sync.Cond
+	// results holds the result of each record of the submission once
+	// isSettled is set, records being settled out of order when reordered
+	// by sequence number
+	results   []error
+	isSettled []bool
+}
+
+func newDelivery(ctx context.Context, correlationID uint64, retryCount uint32, count int) *Delivery {
+	d := &Delivery{
+		ctx:           ctx,
+		correlationID: correlationID,
+		retryCount:    retryCount,
+		queuedAt:      time.Now(),
+		results:       make([]error, count),
+		isSettled:     make([]bool, count),
+	}
+	d.settled = sync.NewCond(&d.mutex)
+	return d
+}
+
+// Wait waits for the delivery of the i-th record of the submission and
+// returns its result
+func (d *Delivery) Wait(i int) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for !d.isSettled[i] {
+		d.settled.Wait()
+	}
+	return d.results[i]
+}
+
+// settle records the result of the i-th record of the submission
+func (d *Delivery) settle(i int, err error) {
+	d.mutex.Lock()
+	d.results[i] = err
+	d.isSettled[i] = true
+	d.mutex.Unlock()
+	d.settled.Broadcast()
+}
+
+// queuedRecord is a record waiting in a flow
+type queuedRecord struct {
+	record   []byte
+	delivery *Delivery
+	// index is the position of the record in its submission
+	index int
+	// rejected is the result of a record submitted while the queue was
+	// full, settled in turn without delivering the record
+	rejected error
+}
+
+// flow is the FIFO of records submitted by a task
+type flow struct {
+	id      string
+	weight  uint32
+	deficit int
+	records []queuedRecord
+	// failures counts the failures of the flow, failing the records of the
+	// flow in flight behind a failed record
+	failures int
+}
+
+// fairQueue delivers the records of several flows sharing a single
+// backend using deficit round robin. Each flow gets a share of the
+// delivered bytes proportional to its weight so that a busy flow cannot
+// starve the others, and the records of a flow are delivered in order.
+type fairQueue struct {
+	send sendFunc
+	// sendBatch delivers the records of a batch with batch export
+	sendBatch batchSendFunc
+	// sendAsync writes a record without waiting for its acknowledgement
+	// with write pipelining
+	sendAsync func(record []byte, correlationID uint64) *PendingRecord
+	// pipeline holds the records in flight with write pipelining
+	pipeline *pipeline
+
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	flows  map[string]*flow
+	active []*flow
+	closed bool
+	done   chan struct{}
+	// limit paces the delivered records and bounds the queued ones
+	limit  RateLimit
+	bucket *tokenBucket
+	queued uint32
+	batch  BatchConfig
+	// pipelining applies to the records delivered one at a time
+	pipelining PipelineConfig
+	// sequencer holds the records submitted ahead of their sequence number
+	sequencer *sequencer
+}
+
+func newFairQueue(send sendFunc) *fairQueue {
+	q := &fairQueue{
+		send:  send,
+		flows: map[string]*flow{},
+		done:  make(chan struct{}),
+	}
+	q.sequencer = newSequencer(q.skipHole)
+	q.cond = sync.NewCond(&q.mutex)
+	q.pipeline = newPipeline()
+	go q.pipeline.run(q.settleInflight)
+	go q.dispatch()
+	return q
+}
+
+// submit queues records at the tail of a flow and returns the Delivery
+// reporting their results
+func (q *fairQueue) submit(
+	ctx context.Context,
+	flowID string,
+	weight uint32,
+	correlationID uint64,
+	records [][]byte,
+	retryCount uint32,
+) *Delivery {
+	delivery := newDelivery(ctx, correlationID, retryCount, len(records))
+	if weight == 0 {
+		weight = 1
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		for i := range records {
+			delivery.settle(i, ErrQueueClosed)
+		}
+		return delivery
+	}
+
+	for i, record := range records {
+		r := sequencedRecord{
+			queuedRecord: queuedRecord{record: record, delivery: delivery, index: i},
+			flowID:       flowID,
+			weight:       weight,
+		}
+		for _, released := range q.sequencer.order(r) {
+			q.enqueue(released)
+		}
+	}
+	metrics.ExportQueueSize.Set(float64(q.queued))
+	q.cond.Signal()
+	return delivery
+}
+
+// enqueue appends a record at the tail of its flow. It is called with the
+// queue locked.
+func (q *fairQueue) enqueue(r sequencedRecord) {
+	f, ok := q.flows[r.flowID]
+	if !ok {
+		f = &flow{id: r.flowID}
+		q.flows[r.flowID] = f
+		q.active = append(q.active, f)
+	}
+	f.weight = r.weight
+	if q.limit.QueueSize != 0 && q.queued >= q.limit.QueueSize {
+		r.rejected = q.limit.getOverflowError()
+		if r.rejected == ErrRecordDropped {
+			metrics.RecordsThrottled.WithLabelValues("dropped").Inc()
+		} else {
+			metrics.RecordsThrottled.WithLabelValues("spilled").Inc()
+		}
+		alert.Raise(alert.Alert{
+			Name:     alert.ExportQueueOverflow,
+			Severity: alert.SeverityMedium,
+			Message:  fmt.Sprintf("export queue is full with %d records: %v", q.queued, r.rejected),
+		})
+	} else {
+		q.queued++
+	}
+	f.records = append(f.records, r.queuedRecord)
+}
+
+// stats returns the records waiting in the flows or held by the sequencer,
+// and their size in bytes
+func (q *fairQueue) stats() (int, int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	records, bytes := 0, 0
+	for _, f := range q.flows {
+		for _, r := range f.records {
+			records++
+			bytes += len(r.record)
+		}
+	}
+	for _, sequence := range q.sequencer.xids {
+		for _, r := range sequence.held {
+			records++
+			bytes += len(r.record)
+		}
+	}
+	return records, bytes
+}
+
+// isBackpressured returns true if the queued records reach the high
+// watermark of the rate limit
+func (q *fairQueue) isBackpressured() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.limit.HighWatermark != 0 && q.queued >= q.limit.HighWatermark
+}
+
+// usage returns the queued records and the rate limit bounding them
+func (q *fairQueue) usage() (uint32, RateLimit) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.queued, q.limit
+}
+
+// skipHole releases the records of an XID held past the hole timeout
+func (q *fairQueue) skipHole(xid uuid.UUID) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, released := range q.sequencer.skipHole(xid) {
+		q.enqueue(released)
+	}
+	metrics.ExportQueueSize.Set(float64(q.queued))
+	q.cond.Signal()
+}
+
+// setRateLimit applies a rate limit, the token bucket is kept when the
+// rate is unchanged
+func (q *fairQueue) setRateLimit(limit RateLimit) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if limit.RecordsPerSecond != q.limit.RecordsPerSecond || limit.Burst != q.limit.Burst {
+		q.bucket = newTokenBucket(limit, time.Now())
+	}
+	q.limit = limit
+}
+
+// close stops accepting records and waits for the queued ones to be
+// delivered, the held records being released without waiting for holes
+func (q *fairQueue) close() {
+	q.mutex.Lock()
+	q.closed = true
+	for _, released := range q.sequencer.flush() {
+		q.enqueue(released)
+	}
+	q.cond.Signal()
+	q.mutex.Unlock()
+	<-q.done
+}
+
+// dispatch delivers queued records until the queue is closed and drained,
+// the records in flight included
+func (q *fairQueue) dispatch() {
+	defer close(q.done)
+	for {
+		q.mutex.Lock()
+		for len(q.active) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.active) == 0 {
+			q.mutex.Unlock()
+			q.pipeline.close()
+			return
+		}
+		if q.batch.isEnabled() && q.sendBatch != nil {
+			q.deliverBatch(q.collectBatch())
+			continue
+		}
+		f := q.active[0]
+		q.active = q.active[1:]
+		f.deficit += fairQueueQuantum * int(f.weight)
+		q.mutex.Unlock()
+
+		q.serveFlow(f)
+
+		q.mutex.Lock()
+		if len(f.records) == 0 {
+			delete(q.flows, f.id)
+		} else {
+			q.active = append(q.active, f)
+		}
+		q.mutex.Unlock()
+	}
+}
+
+// serveFlow delivers the head records of a flow while its deficit allows it.
+// A record always fits in an otherwise idle round so large records are not
+// stuck forever.
+func (q *fairQueue) serveFlow(f *flow) {
+	for {
+		q.mutex.Lock()
+		if len(f.records) == 0 {
+			f.deficit = 0
+			q.mutex.Unlock()
+			return
+		}
+		r := f.records[0]
+		if r.rejected != nil {
+			f.records = f.records[1:]
+			q.mutex.Unlock()
+			// the records in flight were queued before the rejected one
+			q.pipeline.reserve(1)
+			endDeliverSpan(startDeliverSpan(r), r.rejected)
+			r.delivery.settle(r.index, r.rejected)
+			if r.rejected != ErrRecordDropped {
+				q.failFlow(f)
+				return
+			}
+			continue
+		}
+		if len(r.record) > f.deficit && len(q.active) != 0 {
+			q.mutex.Unlock()
+			return
+		}
+		f.records = f.records[1:]
+		f.deficit -= len(r.record)
+		q.queued--
+		metrics.ExportQueueSize.Set(float64(q.queued))
+		var wait time.Duration
+		if q.bucket != nil {
+			wait = q.bucket.take(time.Now())
+		}
+		window, failures := 1, f.failures
+		if q.pipelining.isEnabled() && q.sendAsync != nil {
+			window = int(q.pipelining.Window)
+		}
+		q.mutex.Unlock()
+
+		d := r.delivery
+		span := startDeliverSpan(r)
+		err := d.ctx.Err()
+		if err == nil && wait > 0 {
+			metrics.RecordsThrottled.WithLabelValues("delayed").Inc()
+			span.AddEvent(spanEventPaced)
+			err = waitToken(d.ctx, wait)
+		}
+		if err == nil && window > 1 {
+			q.writeRecord(r, f, failures, window, span)
+			continue
+		}
+		if err == nil {
+			// the records in flight are settled before
+			q.pipeline.reserve(1)
+			span.AddEvent(spanEventSending)
+			err = q.send(trace.ContextWithSpan(d.ctx, span), r.record, d.correlationID, d.retryCount)
+		}
+		endDeliverSpan(span, err)
+		d.settle(r.index, err)
+		if err != nil {
+			q.failFlow(f)
+			return
+		}
+		metrics.DeliveryLatency.WithLabelValues(encoding.GetRecordClass(r.record)).Observe(time.Since(d.queuedAt).Seconds())
+	}
+}
+
+// failFlow fails the records queued or held behind a failed record of the
+// flow so that the flow is not delivered out of order
+func (q *fairQueue) failFlow(f *flow) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, r := range f.records {
+		if r.rejected == nil {
+			q.queued--
+		}
+		r.delivery.settle(r.index, ErrPreviousRecordFailed)
+	}
+	for _, r := range q.sequencer.dropFlow(f.id) {
+		r.delivery.settle(r.index, ErrPreviousRecordFailed)
+	}
+	metrics.ExportQueueSize.Set(float64(q.queued))
+	f.records = nil
+	f.deficit = 0
+	f.failures++
+}
+
+// waitToken waits for the token of a paced record unless the submission
+// is canceled
+func waitToken(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingSender records the correlation ID of each record sent and
+// blocks until released so that records can be queued first
+type recordingSender struct {
+	mutex   sync.Mutex
+	sent    []uint64
+	release chan struct{}
+	failOn  map[uint64]bool
+}
+
+func (s *recordingSender) send(ctx context.Context, record []byte, correlationID uint64, retryCount uint32) error {
+	<-s.release
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sent = append(s.sent, correlationID)
+	if s.failOn[correlationID] {
+		return errors.New("send failed")
+	}
+	return nil
+}
+
+func makeRecords(count, size int) [][]byte {
+	records := make([][]byte, count)
+	for i := range records {
+		records[i] = make([]byte, size)
+	}
+	return records
+}
+
+func waitAll(delivery *Delivery) []error {
+	errs := make([]error, 0, cap(delivery.results))
+	for i := 0; i < cap(delivery.results); i++ {
+		errs = append(errs, delivery.Wait(i))
+	}
+	return errs
+}
+
+func TestFairQueue(t *testing.T) {
+	sender := &recordingSender{release: make(chan struct{})}
+	q := newFairQueue(sender.send)
+	ctx := context.Background()
+
+	// the first record of the busy flow is picked up while the others are queued
+	busy := q.submit(ctx, "busy", 1, 1, makeRecords(8, fairQueueQuantum), 1)
+	quiet := q.submit(ctx, "quiet", 1, 2, makeRecords(2, fairQueueQuantum), 1)
+	heavy := q.submit(ctx, "heavy", 2, 3, makeRecords(4, fairQueueQuantum), 1)
+	records, bytes := q.stats()
+	assert.Contains(t, []int{13, 14}, records)
+	assert.Equal(t, records*fairQueueQuantum, bytes)
+	close(sender.release)
+
+	assert.Equal(t, make([]error, 8), waitAll(busy))
+	assert.Equal(t, make([]error, 2), waitAll(quiet))
+	assert.Equal(t, make([]error, 4), waitAll(heavy))
+	records, bytes = q.stats()
+	assert.Zero(t, records)
+	assert.Zero(t, bytes)
+	q.close()
+
+	// flows are served in turn, the heavy flow sending twice as much per round
+	expected := []uint64{1, 2, 3, 3, 1, 2, 3, 3, 1, 1, 1, 1, 1, 1}
+	assert.Equal(t, expected, sender.sent)
+}
+
+func TestFairQueueFailure(t *testing.T) {
+	sender := &recordingSender{release: make(chan struct{}), failOn: map[uint64]bool{1: true}}
+	close(sender.release)
+	q := newFairQueue(sender.send)
+
+	// the records following a failed one are not sent
+	errs := waitAll(q.submit(context.Background(), "failing", 1, 1, makeRecords(3, 10), 1))
+	assert.EqualError(t, errs[0], "send failed")
+	assert.Equal(t, []error{ErrPreviousRecordFailed, ErrPreviousRecordFailed}, errs[1:])
+
+	// records of a cancelled submission are not sent
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errs = waitAll(q.submit(ctx, "cancelled", 1, 2, makeRecords(2, 10), 1))
+	assert.Equal(t, []error{context.Canceled, ErrPreviousRecordFailed}, errs)
+
+	// the flow recovers on the next submission
+	errs = waitAll(q.submit(context.Background(), "cancelled", 1, 4, makeRecords(1, 10), 1))
+	assert.Equal(t, []error{nil}, errs)
+	assert.Equal(t, []uint64{1, 4}, sender.sent)
+
+	q.close()
+	errs = waitAll(q.submit(context.Background(), "closed", 1, 5, makeRecords(1, 10), 1))
+	assert.Equal(t, []error{ErrQueueClosed}, errs)
+}
+
+func TestFairQueueOverflow(t *testing.T) {
+	sender := &recordingSender{release: make(chan struct{})}
+	q := newFairQueue(sender.send)
+	ctx := context.Background()
+
+	// records beyond the queue size are spilled back, failing the records behind them
+	q.setRateLimit(RateLimit{QueueSize: 2, OverflowPolicy: OverflowSpill})
+	spilled := q.submit(ctx, "spill", 1, 1, makeRecords(4, 16), 1)
+	close(sender.release)
+	assert.Equal(t, []error{nil, nil, ErrThrottled, ErrPreviousRecordFailed}, waitAll(spilled))
+
+	// dropped records leave the records behind them queued
+	q.setRateLimit(RateLimit{QueueSize: 2, OverflowPolicy: OverflowDrop})
+	dropped := q.submit(ctx, "drop", 1, 2, makeRecords(3, 16), 1)
+	assert.Equal(t, []error{nil, nil, ErrRecordDropped}, waitAll(dropped))
+	q.close()
+
+	assert.Equal(t, []uint64{1, 1, 2, 2}, sender.sent)
+}
+
+func TestFairQueueBackpressure(t *testing.T) {
+	sender := &recordingSender{release: make(chan struct{})}
+	q := newFairQueue(sender.send)
+	ctx := context.Background()
+	assert.False(t, q.isBackpressured())
+
+	// the tasks are held back once the queued records reach the high watermark
+	q.setRateLimit(RateLimit{QueueSize: 8, OverflowPolicy: OverflowSpill, HighWatermark: 3})
+	first := q.submit(ctx, "task", 1, 1, makeRecords(1, 16), 1)
+	assert.False(t, q.isBackpressured())
+	second := q.submit(ctx, "task", 1, 1, makeRecords(4, 16), 1)
+	assert.True(t, q.isBackpressured())
+	queued, limit := q.usage()
+	assert.True(t, queued >= 3)
+	assert.Equal(t, uint32(8), limit.QueueSize)
+
+	close(sender.release)
+	assert.Equal(t, []error{nil}, waitAll(first))
+	assert.Equal(t, make([]error, 4), waitAll(second))
+	assert.False(t, q.isBackpressured())
+	queued, _ = q.usage()
+	assert.Zero(t, queued)
+	q.close()
+}
+
+func TestNewRateLimit(t *testing.T) {
+	config := nprobe.Config{ExportOverflowPolicy: OverflowSpill}
+	limit, err := NewRateLimit(config)
+	assert.NoError(t, err)
+	assert.Zero(t, limit.HighWatermark)
+
+	config.ExportQueueSize = 100
+	limit, err = NewRateLimit(config)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(75), limit.HighWatermark)
+
+	config.ExportQueueHighWatermark = 90
+	limit, err = NewRateLimit(config)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(90), limit.HighWatermark)
+
+	config.ExportQueueHighWatermark = 101
+	_, err = NewRateLimit(config)
+	assert.Error(t, err)
+	config.ExportOverflowPolicy = "block"
+	_, err = NewRateLimit(config)
+	assert.Error(t, err)
+}
+
+func TestFairQueueRateLimit(t *testing.T) {
+	sender := &recordingSender{release: make(chan struct{})}
+	close(sender.release)
+	q := newFairQueue(sender.send)
+	q.setRateLimit(RateLimit{RecordsPerSecond: 50, Burst: 1, OverflowPolicy: OverflowSpill})
+
+	start := time.Now()
+	delivery := q.submit(context.Background(), "paced", 1, 1, makeRecords(3, 16), 1)
+	assert.Equal(t, make([]error, 3), waitAll(delivery))
+	assert.True(t, time.Since(start) >= 35*time.Millisecond)
+
+	// records waiting for a token are not sent once their submission is canceled
+	q.setRateLimit(RateLimit{RecordsPerSecond: 1, OverflowPolicy: OverflowSpill})
+	ctx, cancel := context.WithCancel(context.Background())
+	delivery = q.submit(ctx, "canceled", 1, 2, makeRecords(2, 16), 1)
+	assert.NoError(t, delivery.Wait(0))
+	cancel()
+	assert.Equal(t, context.Canceled, delivery.Wait(1))
+	q.close()
+
+	assert.Equal(t, []uint64{1, 1, 1, 2}, sender.sent)
+}
+
+// recordingBatchSender records the correlation IDs of the records of each batch
+type recordingBatchSender struct {
+	mutex   sync.Mutex
+	batches [][]uint64
+	failOn  map[uint64]bool
+}
+
+func (s *recordingBatchSender) send(records []BatchRecord, retryCounts []uint32) []error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	batch := make([]uint64, len(records))
+	errs := make([]error, len(records))
+	for i, r := range records {
+		batch[i] = r.CorrelationID
+		if s.failOn[r.CorrelationID] {
+			errs[i] = errors.New("send failed")
+		}
+	}
+	s.batches = append(s.batches, batch)
+	return errs
+}
+
+func TestFairQueueBatch(t *testing.T) {
+	sender := &recordingSender{release: make(chan struct{})}
+	batchSender := &recordingBatchSender{failOn: map[uint64]bool{3: true}}
+	q := newFairQueue(sender.send)
+	q.sendBatch = batchSender.send
+	q.setBatchConfig(BatchConfig{MaxRecords: 4, Window: 200 * time.Millisecond})
+	ctx := context.Background()
+
+	// records submitted within the window are delivered together
+	first := q.submit(ctx, "first", 1, 1, makeRecords(3, 16), 1)
+	second := q.submit(ctx, "second", 1, 2, makeRecords(3, 16), 1)
+	assert.Equal(t, make([]error, 3), waitAll(first))
+	assert.Equal(t, make([]error, 3), waitAll(second))
+
+	// the records of a flow behind a failed one fail even if delivered
+	errs := waitAll(q.submit(ctx, "failing", 1, 3, makeRecords(2, 16), 1))
+	assert.EqualError(t, errs[0], "send failed")
+	assert.Equal(t, ErrPreviousRecordFailed, errs[1])
+
+	// batches are bounded by their size
+	q.setBatchConfig(BatchConfig{MaxRecords: 4, MaxBytes: 40})
+	assert.Equal(t, make([]error, 3), waitAll(q.submit(ctx, "large", 1, 4, makeRecords(3, 16), 1)))
+
+	// paced batches are not sent once their submissions are canceled, without
+	// waiting for their tokens
+	q.setRateLimit(RateLimit{RecordsPerSecond: 1, OverflowPolicy: OverflowSpill})
+	canceledCtx, cancel := context.WithCancel(ctx)
+	start := time.Now()
+	paced := q.submit(canceledCtx, "paced", 1, 5, makeRecords(3, 16), 1)
+	cancel()
+	assert.Equal(t, []error{context.Canceled, ErrPreviousRecordFailed, ErrPreviousRecordFailed}, waitAll(paced))
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	q.close()
+
+	expected := [][]uint64{{1, 1, 1, 2}, {2, 2}, {3, 3}, {4, 4}, {4}}
+	assert.Equal(t, expected, batchSender.batches)
+	assert.Empty(t, sender.sent)
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	assert.Nil(t, newTokenBucket(RateLimit{}, now))
+
+	bucket := newTokenBucket(RateLimit{RecordsPerSecond: 10, Burst: 2}, now)
+	assert.Equal(t, time.Duration(0), bucket.take(now))
+	assert.Equal(t, time.Duration(0), bucket.take(now))
+	assert.Equal(t, 100*time.Millisecond, bucket.take(now))
+	assert.Equal(t, 200*time.Millisecond, bucket.take(now))
+
+	// tokens refill up to the burst size
+	assert.Equal(t, time.Duration(0), bucket.take(now.Add(time.Hour)))
+	assert.Equal(t, time.Duration(0), bucket.take(now.Add(time.Hour)))
+	assert.Equal(t, 100*time.Millisecond, bucket.take(now.Add(time.Hour)))
+
+	// the burst size defaults to the rate
+	bucket = newTokenBucket(RateLimit{RecordsPerSecond: 3}, now)
+	assert.Equal(t, float64(3), bucket.burst)
+}
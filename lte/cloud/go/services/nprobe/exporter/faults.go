@@ -0,0 +1,50 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"errors"
+	"time"
+)
+
+// Faults are injected in the delivery path to test how the service copes
+// with failing delivery functions: records are held and delivered again,
+// connections are reestablished, and sequence numbers stay continuous. The
+// faults are only injected by the builds with the with_fault_injection build
+// tag, SetFaults failing with ErrFaultInjectionDisabled otherwise, so that
+// production builds carry no fault injection at all.
+type Faults struct {
+	// Destination is the address of the delivery function whose connections
+	// the faults are injected in, all of them when empty
+	Destination string
+	// DropAfterBytes drops each connection once it wrote this many bytes,
+	// the write crossing the limit being cut short. Disabled when 0.
+	DropAfterBytes uint64
+	// WriteDelay delays each write on the connections
+	WriteDelay time.Duration
+	// CorruptAcks corrupts the sequence number of the record
+	// acknowledgements received, which then acknowledge no record
+	CorruptAcks bool
+	// FailReads fails the connections on the next frame read from the
+	// delivery function, as if reading it failed
+	FailReads bool
+	// RefuseRenegotiation fails the connections on the next frame read from
+	// the delivery function with a handshake failure, as if the delivery
+	// function requested a TLS renegotiation and it was refused
+	RefuseRenegotiation bool
+}
+
+// ErrFaultInjectionDisabled is returned when injecting faults in a build
+// without the with_fault_injection build tag
+var ErrFaultInjectionDisabled = errors.New("fault injection requires a build with the with_fault_injection build tag")
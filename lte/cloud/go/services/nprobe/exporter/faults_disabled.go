@@ -0,0 +1,44 @@
+//go:build !with_fault_injection
+// +build !with_fault_injection
+
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+// empty stubs for the builds without fault injection
+
+// SetFaults stub
+func SetFaults(faults Faults) error {
+	return ErrFaultInjectionDisabled
+}
+
+// GetFaults stub
+func GetFaults() *Faults {
+	return nil
+}
+
+// ClearFaults stub
+func ClearFaults() {}
+
+func injectWriteFaults(destination string, sent uint64, n int) (int, error) {
+	return 0, nil
+}
+
+func injectReadFault(destination string) error {
+	return nil
+}
+
+func injectAckFault(destination string, key ackKey) ackKey {
+	return key
+}
@@ -0,0 +1,122 @@
+//go:build with_fault_injection
+// +build with_fault_injection
+
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	errInjectedDrop                 = &DeliveryError{Class: FailureConnectionReset, Err: errors.New("connection dropped by fault injection")}
+	errInjectedReadFailure          = errors.New("read failed by fault injection")
+	errInjectedRenegotiationRefused = &DeliveryError{Class: FailureHandshake, Err: errors.New("tls: renegotiation refused by fault injection")}
+)
+
+var (
+	faultsMutex sync.RWMutex
+	// injectedFaults are the faults injected, nil when none is
+	injectedFaults *Faults
+)
+
+// SetFaults injects faults in the connections of the delivery functions,
+// replacing the faults injected so far
+func SetFaults(faults Faults) error {
+	faults.Destination = NormalizeAddress(faults.Destination)
+	logger.Warningf("Injecting faults in the connections to the delivery functions: %+v", faults)
+	faultsMutex.Lock()
+	defer faultsMutex.Unlock()
+	injectedFaults = &faults
+	return nil
+}
+
+// GetFaults returns the faults injected, nil when none is
+func GetFaults() *Faults {
+	faultsMutex.RLock()
+	defer faultsMutex.RUnlock()
+	if injectedFaults == nil {
+		return nil
+	}
+	ret := *injectedFaults
+	return &ret
+}
+
+// ClearFaults stops injecting faults
+func ClearFaults() {
+	faultsMutex.Lock()
+	defer faultsMutex.Unlock()
+	if injectedFaults != nil {
+		logger.Warningf("Stopped injecting faults in the connections to %q", injectedFaults.Destination)
+	}
+	injectedFaults = nil
+}
+
+// getDestinationFaults returns the faults injected in the connections to a
+// destination, nil when none is
+func getDestinationFaults(destination string) *Faults {
+	faultsMutex.RLock()
+	defer faultsMutex.RUnlock()
+	if injectedFaults == nil || (len(injectedFaults.Destination) != 0 && injectedFaults.Destination != destination) {
+		return nil
+	}
+	return injectedFaults
+}
+
+// injectWriteFaults delays a write of n bytes on a connection which wrote
+// sent bytes so far, or fails it when the connection must be dropped. The
+// number of bytes written before dropping the connection is returned along.
+func injectWriteFaults(destination string, sent uint64, n int) (int, error) {
+	faults := getDestinationFaults(destination)
+	if faults == nil {
+		return 0, nil
+	}
+	if faults.WriteDelay > 0 {
+		time.Sleep(faults.WriteDelay)
+	}
+	if faults.DropAfterBytes == 0 || sent+uint64(n) <= faults.DropAfterBytes {
+		return 0, nil
+	}
+	if sent >= faults.DropAfterBytes {
+		return 0, errInjectedDrop
+	}
+	return int(faults.DropAfterBytes - sent), errInjectedDrop
+}
+
+// injectReadFault fails a frame read on a connection when reads must fail
+// or renegotiations must be refused
+func injectReadFault(destination string) error {
+	faults := getDestinationFaults(destination)
+	switch {
+	case faults == nil:
+		return nil
+	case faults.FailReads:
+		return errInjectedReadFailure
+	case faults.RefuseRenegotiation:
+		return errInjectedRenegotiationRefused
+	}
+	return nil
+}
+
+// injectAckFault returns the key of a record acknowledgement received on a
+// connection, corrupted when acknowledgements must be
+func injectAckFault(destination string, key ackKey) ackKey {
+	if faults := getDestinationFaults(destination); faults != nil && faults.CorruptAcks {
+		key.seqNbr = ^key.seqNbr
+	}
+	return key
+}
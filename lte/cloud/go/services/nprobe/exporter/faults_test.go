@@ -0,0 +1,203 @@
+//go:build with_fault_injection
+// +build with_fault_injection
+
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lemfLog is the log of a LEMF acknowledging the records it reads
+type lemfLog struct {
+	mutex   sync.Mutex
+	seqNbrs []uint32
+	conns   int
+}
+
+func (l *lemfLog) get() ([]uint32, int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]uint32{}, l.seqNbrs...), l.conns
+}
+
+// startLoggingLEMF starts a tls server acknowledging the native frames it
+// reads, logging the sequence numbers of the records and the connections
+func startLoggingLEMF(t *testing.T) (net.Listener, *lemfLog) {
+	listener := listenLEMF(t)
+	log := &lemfLog{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			log.mutex.Lock()
+			log.conns++
+			log.mutex.Unlock()
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					frame, err := readTestFrame(r, encoding.FramingNative)
+					if err != nil {
+						return
+					}
+					hdr, err := encoding.ParsePDUHeader(frame)
+					if err != nil {
+						return
+					}
+					if hdr.PduType == encoding.HeaderPduType {
+						seqNbr, _ := encoding.GetSequenceNumber(hdr)
+						log.mutex.Lock()
+						log.seqNbrs = append(log.seqNbrs, seqNbr)
+						log.mutex.Unlock()
+					}
+					conn.Write(encoding.MakeKeepaliveAck(hdr))
+				}
+			}()
+		}
+	}()
+	return listener, log
+}
+
+// firstOccurrences returns the sequence numbers in the order they were
+// first received, leaving out the retransmissions
+func firstOccurrences(seqNbrs []uint32) []uint32 {
+	seen := map[uint32]bool{}
+	var ret []uint32
+	for _, seqNbr := range seqNbrs {
+		if !seen[seqNbr] {
+			seen[seqNbr] = true
+			ret = append(ret, seqNbr)
+		}
+	}
+	return ret
+}
+
+func TestFaultInjection(t *testing.T) {
+	expected := []uint32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for _, tc := range []struct {
+		name   string
+		faults Faults
+		// clearAfter is the time after which the faults are cleared, the
+		// faults being kept when 0
+		clearAfter time.Duration
+		check      func(t *testing.T, seqNbrs []uint32, conns int, elapsed time.Duration)
+	}{
+		{
+			name:   "drop_after_bytes",
+			faults: Faults{DropAfterBytes: 1000},
+			check: func(t *testing.T, seqNbrs []uint32, conns int, elapsed time.Duration) {
+				// the records are delivered on new connections
+				assert.True(t, conns > 1)
+			},
+		},
+		{
+			name:   "write_delay",
+			faults: Faults{WriteDelay: 20 * time.Millisecond},
+			check: func(t *testing.T, seqNbrs []uint32, conns int, elapsed time.Duration) {
+				assert.Equal(t, expected, seqNbrs)
+				assert.Equal(t, 1, conns)
+				assert.True(t, elapsed >= 10*20*time.Millisecond)
+			},
+		},
+		{
+			name:       "corrupt_acks",
+			faults:     Faults{CorruptAcks: true},
+			clearAfter: 300 * time.Millisecond,
+			check: func(t *testing.T, seqNbrs []uint32, conns int, elapsed time.Duration) {
+				// the record whose acknowledgement is corrupted is
+				// retransmitted until the faults are cleared
+				assert.True(t, len(seqNbrs) > len(expected))
+				assert.Equal(t, uint32(1), seqNbrs[1])
+				assert.True(t, elapsed >= 300*time.Millisecond)
+			},
+		},
+		{
+			name:       "fail_reads",
+			faults:     Faults{FailReads: true},
+			clearAfter: 300 * time.Millisecond,
+			check: func(t *testing.T, seqNbrs []uint32, conns int, elapsed time.Duration) {
+				// the connections fail until the faults are cleared
+				assert.True(t, conns > 1)
+				assert.True(t, len(seqNbrs) > len(expected))
+			},
+		},
+		{
+			name:       "refuse_renegotiation",
+			faults:     Faults{RefuseRenegotiation: true},
+			clearAfter: 300 * time.Millisecond,
+			check: func(t *testing.T, seqNbrs []uint32, conns int, elapsed time.Duration) {
+				// the connections fail on the renegotiations until the
+				// faults are cleared, the records being delivered again on
+				// new ones
+				assert.True(t, conns > 1)
+				assert.True(t, len(seqNbrs) > len(expected))
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			listener, log := startLoggingLEMF(t)
+			defer listener.Close()
+			backend := NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{AckTimeout: 100 * time.Millisecond})
+			defer backend.Close()
+			exp := NewRecordExporter(backend)
+
+			faults := tc.faults
+			faults.Destination = listener.Addr().String()
+			assert.NoError(t, SetFaults(faults))
+			defer ClearFaults()
+			if tc.clearAfter > 0 {
+				time.AfterFunc(tc.clearAfter, ClearFaults)
+			}
+
+			// the records failing are held and delivered again, in order
+			start := time.Now()
+			delivery := exp.SubmitRecords(context.Background(), "task", 1, 1, makeSequencedRecords(t, expected...), 1000)
+			assert.Equal(t, make([]error, len(expected)), waitAll(delivery))
+			elapsed := time.Since(start)
+			seqNbrs, conns := log.get()
+			assert.Equal(t, expected, firstOccurrences(seqNbrs))
+			tc.check(t, seqNbrs, conns, elapsed)
+		})
+	}
+
+	// faults injected in the connections to another destination don't apply
+	listener, log := startLoggingLEMF(t)
+	defer listener.Close()
+	assert.NoError(t, SetFaults(Faults{Destination: "192.0.2.1:4040", DropAfterBytes: 1}))
+	defer ClearFaults()
+	assert.Equal(t, "192.0.2.1:4040", GetFaults().Destination)
+	backend := NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{AckTimeout: 100 * time.Millisecond})
+	defer backend.Close()
+	assert.NoError(t, backend.Send(makeSequencedRecords(t, 1)[0], 1))
+	seqNbrs, conns := log.get()
+	assert.Equal(t, []uint32{1}, seqNbrs)
+	assert.Equal(t, 1, conns)
+
+	ClearFaults()
+	assert.Nil(t, GetFaults())
+}
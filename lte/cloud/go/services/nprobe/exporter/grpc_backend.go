@@ -0,0 +1,432 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/protos"
+	"magma/orc8r/lib/go/registry"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	// grpcDialTimeout bounds the time taken to connect to the mediation function
+	grpcDialTimeout = 10 * time.Second
+	// grpcDefaultAckTimeout bounds the time a record waits for its
+	// acknowledgement when no ack timeout is configured
+	grpcDefaultAckTimeout = 30 * time.Second
+)
+
+var errStreamClosed = &DeliveryError{Class: FailureConnectionReset, Err: errors.New("stream closed before the record was acknowledged")}
+
+// GRPCBackendConfig holds the settings of the grpc backend
+type GRPCBackendConfig struct {
+	// Service is the name of the mediation function in the orc8r service
+	// registry. Address is used when empty.
+	Service string
+	// Address is the address of the mediation function
+	Address string
+	// AckTimeout bounds the time a record waits for its acknowledgement,
+	// grpcDefaultAckTimeout when 0
+	AckTimeout time.Duration
+}
+
+// GRPCBackend streams records over HTTP/2 to a mediation function
+// implementing the RecordDelivery service, for deployments where it is a
+// cloud service itself. The mediation function is looked up in the orc8r
+// service registry, and the connection is secured with the mutual TLS of the
+// exporter, its certificate being presented on each handshake.
+// Records are streamed on a single call so that they arrive in order, and
+// each of them is delivered once acknowledged by the mediation function.
+// A failed call is opened again on the next record, the connection
+// reconnecting on its own.
+type GRPCBackend struct {
+	config    GRPCBackendConfig
+	tlsConfig *tls.Config
+	// openStream opens a call streaming records to the mediation function
+	// at addr. The caller holds the mutex.
+	openStream func(ctx context.Context, addr string) (protos.RecordDelivery_DeliverRecordsClient, error)
+
+	mutex sync.Mutex
+	conn  *grpc.ClientConn
+	// connAddr is the address conn is connected to
+	connAddr string
+	stream   *recordStream
+}
+
+// recordStream is a call streaming records to the mediation function along
+// with the records waiting for their acknowledgement
+type recordStream struct {
+	client   protos.RecordDelivery_DeliverRecordsClient
+	cancel   context.CancelFunc
+	address  string
+	openedAt time.Time
+	// done is closed once the call ended
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// sendMutex serializes the records sent on the call
+	sendMutex sync.Mutex
+	mutex     sync.Mutex
+	sequence  uint64
+	pending   map[uint64]chan error
+}
+
+// NewGRPCBackend creates a new grpc backend. The mediation function is
+// connected to on the first record sent.
+func NewGRPCBackend(config GRPCBackendConfig, tlsConfig *tls.Config) (*GRPCBackend, error) {
+	if len(config.Service) == 0 && len(config.Address) == 0 {
+		return nil, errors.New("no mediation function service or address provided")
+	}
+	if config.AckTimeout == 0 {
+		config.AckTimeout = grpcDefaultAckTimeout
+	}
+	c := &GRPCBackend{config: config, tlsConfig: tlsConfig}
+	c.openStream = c.dialStream
+	return c, nil
+}
+
+// Send streams a single record and returns once it is acknowledged
+func (c *GRPCBackend) Send(record []byte, correlationID uint64) error {
+	return c.SendBatch([]BatchRecord{{Record: record, CorrelationID: correlationID}})[0]
+}
+
+// SendAsync streams a single record without waiting for its
+// acknowledgement, which is matched by the sequence number of the stream
+func (c *GRPCBackend) SendAsync(record []byte, correlationID uint64) *PendingRecord {
+	stream, err := c.getStream()
+	if err != nil {
+		return settledRecord(err)
+	}
+	sequence, acked, err := stream.send(record, correlationID)
+	if err != nil {
+		c.closeStream(stream)
+		return settledRecord(newDeliveryError(FailureConnectionReset, err))
+	}
+
+	deadline := time.Now().Add(c.config.AckTimeout)
+	return newPendingRecord(func() error {
+		defer stream.cancelAck(sequence)
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		select {
+		case err := <-acked:
+			return err
+		case <-stream.done:
+			return errStreamClosed
+		case <-timer.C:
+			metrics.AckTimeouts.Inc()
+			return errAckTimeout
+		}
+	})
+}
+
+// SendBatch streams records in order without waiting for their
+// acknowledgements, all the records of the batch being acknowledged within
+// the same ack timeout
+func (c *GRPCBackend) SendBatch(records []BatchRecord) []error {
+	errs := make([]error, len(records))
+	stream, err := c.getStream()
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	acks := make([]chan error, len(records))
+	for i, r := range records {
+		var sequence uint64
+		sequence, acks[i], err = stream.send(r.Record, r.CorrelationID)
+		if err != nil {
+			// the call failed, the records sent before fail along
+			c.closeStream(stream)
+			err = newDeliveryError(FailureConnectionReset, err)
+			for j := i; j < len(records); j++ {
+				errs[j] = err
+			}
+			break
+		}
+		defer stream.cancelAck(sequence)
+	}
+
+	timer := time.NewTimer(c.config.AckTimeout)
+	defer timer.Stop()
+	expired := false
+	for i, acked := range acks {
+		if acked == nil {
+			continue
+		}
+		if expired {
+			select {
+			case errs[i] = <-acked:
+			default:
+				metrics.AckTimeouts.Inc()
+				errs[i] = errAckTimeout
+			}
+			continue
+		}
+		select {
+		case errs[i] = <-acked:
+		case <-stream.done:
+			errs[i] = errStreamClosed
+		case <-timer.C:
+			expired = true
+			metrics.AckTimeouts.Inc()
+			errs[i] = errAckTimeout
+		}
+	}
+	return errs
+}
+
+// IsConnected returns true if a call streaming records is open
+func (c *GRPCBackend) IsConnected() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stream != nil && !c.stream.isClosed()
+}
+
+// Close ends the call streaming records and closes the connection. A new
+// connection is established on the next record sent.
+func (c *GRPCBackend) Close() {
+	c.mutex.Lock()
+	stream, conn := c.stream, c.conn
+	c.stream, c.conn, c.connAddr = nil, nil, ""
+	c.mutex.Unlock()
+	if stream != nil {
+		stream.close()
+	}
+	if conn != nil {
+		if err := conn.Close(); err != nil {
+			logger.Errorf("Failed to close connection to mediation function %s: %v", c.getName(), err)
+		}
+	}
+}
+
+// GetConnection describes the call streaming records, only the mediation
+// function when not connected
+func (c *GRPCBackend) GetConnection() *ConnectionInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	info := &ConnectionInfo{Address: c.getName()}
+	if c.stream == nil || c.stream.isClosed() {
+		return info
+	}
+	// the mediation function acknowledges each of the records streamed
+	info.PeerAddress, info.Acknowledged = c.stream.address, true
+	info.ConnectedAt = c.stream.openedAt
+	return info
+}
+
+// Reconnect closes the connection and opens a new call right away, looking
+// up the mediation function again
+func (c *GRPCBackend) Reconnect() error {
+	c.Close()
+	logger.Infof("Reconnecting to %s on request", c.getName())
+	return c.Connect()
+}
+
+// Connect opens the call streaming records if not open yet
+func (c *GRPCBackend) Connect() error {
+	_, err := c.getStream()
+	return err
+}
+
+// getName returns the mediation function records are delivered to
+func (c *GRPCBackend) getName() string {
+	if len(c.config.Service) != 0 {
+		return c.config.Service
+	}
+	return c.config.Address
+}
+
+// getStream returns the open call streaming records, or opens a new one
+func (c *GRPCBackend) getStream() (*recordStream, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.stream != nil && !c.stream.isClosed() {
+		return c.stream, nil
+	}
+
+	addr, err := c.getAddress()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	client, err := c.openStream(ctx, addr)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	c.stream = &recordStream{
+		client:   client,
+		cancel:   cancel,
+		address:  addr,
+		openedAt: time.Now(),
+		done:     make(chan struct{}),
+		pending:  map[uint64]chan error{},
+	}
+	go c.stream.receive()
+	logger.Infof("Opened record stream to mediation function %s at %s", c.getName(), addr)
+	return c.stream, nil
+}
+
+// getAddress returns the address of the mediation function, looked up in
+// the service registry if it is registered as a service
+func (c *GRPCBackend) getAddress() (string, error) {
+	if len(c.config.Service) == 0 {
+		return c.config.Address, nil
+	}
+	addr, err := registry.GetServiceAddress(c.config.Service)
+	if err != nil {
+		return "", newDeliveryError(FailureConnect, fmt.Errorf("failed to look up mediation function %s: %v", c.config.Service, err))
+	}
+	return addr, nil
+}
+
+// dialStream opens a call streaming records to the mediation function at
+// addr, connecting to it over TLS if not connected yet. The caller holds the
+// mutex.
+func (c *GRPCBackend) dialStream(ctx context.Context, addr string) (protos.RecordDelivery_DeliverRecordsClient, error) {
+	if c.conn != nil && c.connAddr != addr {
+		// the mediation function moved
+		c.conn.Close()
+		c.conn, c.connAddr = nil, ""
+	}
+	if c.conn == nil {
+		dialCtx, cancel := context.WithTimeout(ctx, grpcDialTimeout)
+		defer cancel()
+		conn, err := grpc.DialContext(
+			dialCtx,
+			addr,
+			grpc.WithTransportCredentials(credentials.NewTLS(c.tlsConfig)),
+			grpc.WithBlock(),
+			grpc.FailOnNonTempDialError(true),
+		)
+		if err != nil {
+			return nil, newDeliveryError(classifyGRPCDialError(err), err)
+		}
+		c.conn, c.connAddr = conn, addr
+	}
+	client, err := protos.NewRecordDeliveryClient(c.conn).DeliverRecords(ctx)
+	if err != nil {
+		return nil, newDeliveryError(FailureConnect, err)
+	}
+	return client, nil
+}
+
+// classifyGRPCDialError returns the class of a failure to connect to a
+// mediation function. gRPC reports the failures of the TLS handshake in the
+// description of the error only.
+func classifyGRPCDialError(err error) string {
+	if strings.Contains(err.Error(), "authentication handshake failed") {
+		return FailureHandshake
+	}
+	return FailureConnect
+}
+
+// closeStream ends a call streaming records, the next record opening a new one
+func (c *GRPCBackend) closeStream(stream *recordStream) {
+	c.mutex.Lock()
+	if c.stream == stream {
+		c.stream = nil
+	}
+	c.mutex.Unlock()
+	stream.close()
+}
+
+// send streams a record and returns its sequence and the channel its
+// acknowledgement is reported on
+func (s *recordStream) send(record []byte, correlationID uint64) (uint64, chan error, error) {
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
+	s.mutex.Lock()
+	s.sequence++
+	sequence := s.sequence
+	acked := make(chan error, 1)
+	s.pending[sequence] = acked
+	s.mutex.Unlock()
+
+	err := s.client.Send(&protos.DeliveredRecord{
+		Sequence:      sequence,
+		CorrelationId: correlationID,
+		Payload:       record,
+	})
+	if err != nil {
+		s.cancelAck(sequence)
+		return 0, nil, err
+	}
+	return sequence, acked, nil
+}
+
+// cancelAck stops waiting for the acknowledgement of a record
+func (s *recordStream) cancelAck(sequence uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.pending, sequence)
+}
+
+// receive reports the acknowledgements of the mediation function until the
+// call ends
+func (s *recordStream) receive() {
+	for {
+		ack, err := s.client.Recv()
+		if err != nil {
+			logger.With(logging.FieldDestination, s.address).Errorf("Record stream to %s ended: %v", s.address, err)
+			s.close()
+			return
+		}
+		s.mutex.Lock()
+		acked, ok := s.pending[ack.Sequence]
+		delete(s.pending, ack.Sequence)
+		s.mutex.Unlock()
+		if !ok {
+			logger.With(logging.FieldDestination, s.address, logging.FieldSeq, ack.Sequence).V(2).Infof("Ignoring acknowledgement of unknown record %d from %s", ack.Sequence, s.address)
+			continue
+		}
+		if len(ack.Error) != 0 {
+			acked <- &DeliveryError{Class: FailureNack, Err: fmt.Errorf("record refused by the mediation function: %s", ack.Error)}
+			continue
+		}
+		acked <- nil
+	}
+}
+
+// close ends the call, failing the records waiting for their acknowledgement
+func (s *recordStream) close() {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		close(s.done)
+	})
+}
+
+func (s *recordStream) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
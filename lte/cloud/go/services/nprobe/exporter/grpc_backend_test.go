@@ -0,0 +1,149 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/protos"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// mediationStream is the call of a mediation function acknowledging the
+// records streamed to it, refusing the empty ones. It stops acknowledging
+// once held.
+type mediationStream struct {
+	grpc.ClientStream
+	ctx      context.Context
+	received chan *protos.DeliveredRecord
+	acks     chan *protos.RecordAck
+	hold     bool
+}
+
+func newMediationStream(ctx context.Context) *mediationStream {
+	return &mediationStream{
+		ctx:      ctx,
+		received: make(chan *protos.DeliveredRecord, 16),
+		acks:     make(chan *protos.RecordAck, 16),
+	}
+}
+
+func (s *mediationStream) Send(record *protos.DeliveredRecord) error {
+	if s.ctx.Err() != nil {
+		return io.EOF
+	}
+	s.received <- record
+	if s.hold {
+		return nil
+	}
+	ack := &protos.RecordAck{Sequence: record.Sequence}
+	if len(record.Payload) == 0 {
+		ack.Error = "empty record"
+	}
+	s.acks <- ack
+	return nil
+}
+
+func (s *mediationStream) Recv() (*protos.RecordAck, error) {
+	select {
+	case ack := <-s.acks:
+		return ack, nil
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+func TestGRPCBackend(t *testing.T) {
+	_, err := NewGRPCBackend(GRPCBackendConfig{}, nil)
+	assert.EqualError(t, err, "no mediation function service or address provided")
+
+	backend, err := NewGRPCBackend(GRPCBackendConfig{Address: "mf.example:8443", AckTimeout: 100 * time.Millisecond}, nil)
+	assert.NoError(t, err)
+	var streams []*mediationStream
+	backend.openStream = func(ctx context.Context, addr string) (protos.RecordDelivery_DeliverRecordsClient, error) {
+		assert.Equal(t, "mf.example:8443", addr)
+		stream := newMediationStream(ctx)
+		streams = append(streams, stream)
+		return stream, nil
+	}
+	assert.False(t, backend.IsConnected())
+
+	// records are streamed in order on a single call and delivered once
+	// acknowledged
+	assert.NoError(t, backend.Send([]byte{0x30, 0x00}, 7))
+	errs := backend.SendBatch([]BatchRecord{{Record: []byte{0x30, 0x01}, CorrelationID: 8}, {Record: []byte{0x30, 0x02}, CorrelationID: 9}})
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.Len(t, streams, 1)
+	for i, correlationID := range []uint64{7, 8, 9} {
+		record := <-streams[0].received
+		assert.Equal(t, uint64(i+1), record.Sequence)
+		assert.Equal(t, correlationID, record.CorrelationId)
+		assert.Equal(t, []byte{0x30, byte(i)}, record.Payload)
+	}
+	assert.True(t, backend.IsConnected())
+	info := backend.GetConnection()
+	assert.Equal(t, "mf.example:8443", info.Address)
+	assert.Equal(t, "mf.example:8443", info.PeerAddress)
+	assert.True(t, info.Acknowledged)
+
+	// refused records fail without ending the call
+	err = backend.Send(nil, 7)
+	assert.EqualError(t, err, "record refused by the mediation function: empty record")
+	assert.Equal(t, FailureNack, ClassifyError(err))
+	assert.True(t, backend.IsConnected())
+	<-streams[0].received
+
+	// records not acknowledged in time fail
+	streams[0].hold = true
+	err = backend.Send([]byte{0x30, 0x03}, 7)
+	assert.Equal(t, errAckTimeout, err)
+	<-streams[0].received
+
+	// a failed call fails the records waiting for their acknowledgement and
+	// is opened again on the next record
+	done := make(chan error)
+	go func() { done <- backend.Send([]byte{0x30, 0x04}, 7) }()
+	<-streams[0].received
+	backend.mutex.Lock()
+	backend.stream.cancel()
+	backend.mutex.Unlock()
+	assert.Equal(t, errStreamClosed, <-done)
+	assert.False(t, backend.IsConnected())
+	assert.NoError(t, backend.Send([]byte{0x30, 0x05}, 7))
+	assert.Len(t, streams, 2)
+
+	// records fail while the mediation function is unreachable
+	backend.Close()
+	backend.openStream = func(ctx context.Context, addr string) (protos.RecordDelivery_DeliverRecordsClient, error) {
+		return nil, newDeliveryError(FailureConnect, errors.New("connection refused"))
+	}
+	err = backend.Send([]byte{0x30, 0x06}, 7)
+	assert.Equal(t, FailureConnect, ClassifyError(err))
+	assert.False(t, backend.IsConnected())
+	assert.Equal(t, FailureConnect, ClassifyError(backend.Reconnect()))
+}
+
+func TestGRPCBackendDestination(t *testing.T) {
+	assert.Equal(t, "grpc/mediation", GetDestinationName(nprobe.Config{ExporterBackend: BackendGRPC, GRPCDeliveryService: "mediation"}))
+	assert.Equal(t, "mf.example:8443", GetDestinationName(nprobe.Config{ExporterBackend: BackendGRPC, DeliveryFunctionAddr: "mf.example:8443"}))
+	assert.Equal(t, FailureHandshake, classifyGRPCDialError(errors.New("connection error: desc = \"transport: authentication handshake failed: x509: certificate signed by unknown authority\"")))
+	assert.Equal(t, FailureConnect, classifyGRPCDialError(context.DeadlineExceeded))
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ErrProbeUnsupported is returned when probing a backend which doesn't
+// deliver records over TLS
+var ErrProbeUnsupported = errors.New("backend does not deliver records over tls")
+
+// HandshakeSettings customizes the TLS handshake with the delivery function,
+// e.g. for mediation frontends routing connections by SNI or ALPN, and the
+// transport, framing, encoding and write deadline of the records written on
+// the connection
+type HandshakeSettings struct {
+	// ServerName overrides the SNI, which defaults to the host of the
+	// delivery function address. It is also the name the server
+	// certificate is verified against.
+	ServerName string
+	// ALPNProtocols lists the application protocols offered, by preference
+	ALPNProtocols []string
+	// Compression is offered to the delivery function along with the
+	// application protocols, none when empty
+	Compression string
+	// Framing overrides the framing of the records written on the
+	// connection, the one of the backend config when empty
+	Framing string
+	// Encoding overrides the BER encoding of the records written on the
+	// connection, e.g. encoding.EncodingBER for legacy LEMFs, the one of the
+	// backend config when empty
+	Encoding string
+	// HeaderVersion overrides the header version of the PDUs written on the
+	// connection, the one of the backend config when 0
+	HeaderVersion uint16
+	// NegotiateHeaderVersion negotiates the header version with the
+	// delivery function, even if the backend config doesn't
+	NegotiateHeaderVersion bool
+	// WriteTimeout overrides the deadline of the writes on the connection,
+	// the one of the backend config when 0
+	WriteTimeout time.Duration
+	// Transport overrides the transport of the connection, e.g.
+	// TransportSCTP, the one of the backend config when empty
+	Transport string
+}
+
+// HandshakeInfo describes the TLS handshake of the current delivery connection
+type HandshakeInfo struct {
+	ServerName         string
+	NegotiatedProtocol string
+	Version            string
+	CipherSuite        string
+	PeerSubject        string
+	// PeerNotAfter is the expiry of the certificate of the delivery function
+	PeerNotAfter time.Time
+	Resumed      bool
+	// HeaderVersion is the header version of the PDUs written on the
+	// connection, as configured or negotiated
+	HeaderVersion uint16
+}
+
+// handshakeBackend is implemented by the backends delivering records over TLS
+type handshakeBackend interface {
+	// SetHandshakeSettings applies the settings from the next connection on
+	SetHandshakeSettings(settings HandshakeSettings)
+	// GetHandshake describes the handshake of the current connection, nil
+	// when not connected
+	GetHandshake() *HandshakeInfo
+	// Probe completes a handshake on a new connection, which is closed
+	// right away
+	Probe(timeout time.Duration) (*HandshakeInfo, error)
+}
+
+// connectBackend is implemented by the backends holding a connection to the
+// delivery function
+type connectBackend interface {
+	// Connect establishes the delivery connection if not connected yet
+	Connect() error
+}
+
+// SetHandshakeSettings customizes the TLS handshake of the backend. The
+// backend reconnects when the settings change so that they apply right away.
+// Backends not delivering over TLS ignore the settings.
+func (c *RecordExporter) SetHandshakeSettings(settings HandshakeSettings) {
+	c.mutex.Lock()
+	c.handshake = settings
+	backend := c.backend
+	c.mutex.Unlock()
+	if hb, ok := backend.(handshakeBackend); ok {
+		hb.SetHandshakeSettings(settings)
+	}
+}
+
+// GetHandshake describes the TLS handshake of the current delivery
+// connection, nil when not connected or not delivering over TLS
+func (c *RecordExporter) GetHandshake() *HandshakeInfo {
+	if hb, ok := c.getBackend().(handshakeBackend); ok {
+		return hb.GetHandshake()
+	}
+	return nil
+}
+
+// Probe checks that the delivery function is reachable by completing a
+// handshake with the current settings and client certificate on a new
+// connection. The delivery connection is left untouched.
+func (c *RecordExporter) Probe(timeout time.Duration) (*HandshakeInfo, error) {
+	if hb, ok := c.getBackend().(handshakeBackend); ok {
+		return hb.Probe(timeout)
+	}
+	return nil, ErrProbeUnsupported
+}
+
+// Connect establishes the delivery connection ahead of the first record,
+// e.g. while the service warms up. Backends not holding a connection are
+// always ready.
+func (c *RecordExporter) Connect() error {
+	if cb, ok := c.getBackend().(connectBackend); ok {
+		return cb.Connect()
+	}
+	return nil
+}
+
+// probeTLS dials addr and completes a handshake within timeout
+func probeTLS(addr string, tlsConfig *tls.Config, timeout time.Duration) (*HandshakeInfo, error) {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return getHandshakeInfo(conn.ConnectionState()), nil
+}
+
+// applyHandshakeSettings clones a TLS config with the handshake settings
+func applyHandshakeSettings(tlsConfig *tls.Config, settings HandshakeSettings) *tls.Config {
+	cfg := tlsConfig.Clone()
+	if len(settings.ServerName) != 0 {
+		cfg.ServerName = settings.ServerName
+	}
+	if protocols := getOfferedProtocols(settings); len(protocols) != 0 {
+		cfg.NextProtos = protocols
+	}
+	return cfg
+}
+
+// getHandshakeInfo describes a completed handshake
+func getHandshakeInfo(state tls.ConnectionState) *HandshakeInfo {
+	info := &HandshakeInfo{
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		Version:            getTLSVersionName(state.Version),
+		CipherSuite:        getCipherSuiteName(state.CipherSuite),
+		Resumed:            state.DidResume,
+	}
+	if len(state.PeerCertificates) != 0 {
+		info.PeerSubject = state.PeerCertificates[0].Subject.String()
+		info.PeerNotAfter = state.PeerCertificates[0].NotAfter
+	}
+	return info
+}
+
+func getTLSVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
+
+// cipherSuiteNames names the cipher suites negotiated with current servers
+var cipherSuiteNames = map[uint16]string{
+	tls.TLS_AES_128_GCM_SHA256:                  "TLS_AES_128_GCM_SHA256",
+	tls.TLS_AES_256_GCM_SHA384:                  "TLS_AES_256_GCM_SHA384",
+	tls.TLS_CHACHA20_POLY1305_SHA256:            "TLS_CHACHA20_POLY1305_SHA256",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305:  "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:   "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:   "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305:    "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA:    "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA:    "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA:      "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA:      "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256:         "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384:         "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA:            "TLS_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_256_CBC_SHA:            "TLS_RSA_WITH_AES_256_CBC_SHA",
+}
+
+func getCipherSuiteName(id uint16) string {
+	if name, ok := cipherSuiteNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", id)
+}
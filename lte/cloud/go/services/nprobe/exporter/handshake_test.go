@@ -0,0 +1,119 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandshakeSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_handshake")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	crtFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeCertificate(t, crtFile, keyFile, 1)
+	serverCert, err := tls.LoadX509KeyPair(crtFile, keyFile)
+	assert.NoError(t, err)
+
+	// the mediation frontend records the SNI and negotiates x2
+	serverNames := make(chan string, 1)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		NextProtos:   []string{"x2"},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverNames <- hello.ServerName
+			return nil, nil
+		},
+	})
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	settings := HandshakeSettings{ServerName: "hi2.lemf.example.org", ALPNProtocols: []string{"x3", "x2"}}
+	conn, err := tls.Dial("tcp", listener.Addr().String(), applyHandshakeSettings(tlsConfig, settings))
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "hi2.lemf.example.org", <-serverNames)
+
+	info := getHandshakeInfo(conn.ConnectionState())
+	assert.Equal(t, "hi2.lemf.example.org", info.ServerName)
+	assert.Equal(t, "x2", info.NegotiatedProtocol)
+	assert.Equal(t, "TLS 1.3", info.Version)
+	assert.Equal(t, "CN=nprobe", info.PeerSubject)
+	assert.NotContains(t, info.CipherSuite, "0x")
+
+	// the shared config is left untouched
+	assert.Empty(t, tlsConfig.ServerName)
+	assert.Empty(t, tlsConfig.NextProtos)
+}
+
+func TestProbe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_probe")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	crtFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeCertificate(t, crtFile, keyFile, 1)
+	serverCert, err := tls.LoadX509KeyPair(crtFile, keyFile)
+	assert.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverCert}})
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	backend := NewTLSBackend(addr, &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{})
+	defer backend.Close()
+	backend.SetHandshakeSettings(HandshakeSettings{ServerName: "hi2.lemf.example.org"})
+	exp := NewRecordExporter(backend)
+	info, err := exp.Probe(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi2.lemf.example.org", info.ServerName)
+	assert.Equal(t, "CN=nprobe", info.PeerSubject)
+
+	// the server certificate is verified unless skipped
+	_, err = probeTLS(addr, &tls.Config{}, time.Second)
+	assert.Error(t, err)
+
+	listener.Close()
+	_, err = exp.Probe(time.Second)
+	assert.Error(t, err)
+
+	pcap, err := NewPcapBackend(dir, 1<<20, time.Hour)
+	assert.NoError(t, err)
+	_, err = NewRecordExporter(pcap).Probe(time.Second)
+	assert.Equal(t, ErrProbeUnsupported, err)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/tracing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaWriteTimeout bounds the time taken to deliver a single record
+const kafkaWriteTimeout = 10 * time.Second
+
+// KafkaBackend produces records to a kafka topic. Records are keyed
+// by correlation ID so that all records of an intercepted session are
+// delivered in order on the same partition.
+type KafkaBackend struct {
+	writer    *kafka.Writer
+	lastError error
+	mutex     sync.Mutex
+}
+
+// NewKafkaBackend creates a new kafka backend producing to topic over tls
+func NewKafkaBackend(brokers []string, topic string, tlsConfig *tls.Config) (*KafkaBackend, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("no kafka broker provided")
+	}
+	if len(topic) == 0 {
+		return nil, errors.New("no kafka topic provided")
+	}
+
+	writer := kafka.NewWriter(kafka.WriterConfig{
+		Brokers:  brokers,
+		Topic:    topic,
+		Dialer:   &kafka.Dialer{Timeout: kafkaWriteTimeout, TLS: tlsConfig},
+		Balancer: &kafka.Hash{},
+		// retries are handled by the record exporter
+		MaxAttempts: 1,
+		// records are delivered one at a time, don't wait for a batch
+		BatchSize: 1,
+	})
+	return &KafkaBackend{writer: writer}, nil
+}
+
+// Send produces a single record keyed by its correlation ID
+func (k *KafkaBackend) Send(record []byte, correlationID uint64) error {
+	return k.SendTraced(context.Background(), record, correlationID)
+}
+
+// SendTraced produces a single record keyed by its correlation ID, with the
+// context of the span of ctx in its traceparent header so that the
+// consumers of the topic can continue the trace
+func (k *KafkaBackend) SendTraced(ctx context.Context, record []byte, correlationID uint64) error {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, correlationID)
+	message := kafka.Message{Key: key, Value: record}
+	tracing.Inject(ctx, (*kafkaHeaderCarrier)(&message))
+
+	writeCtx, cancel := context.WithTimeout(context.Background(), kafkaWriteTimeout)
+	defer cancel()
+	err := k.writer.WriteMessages(writeCtx, message)
+
+	k.mutex.Lock()
+	k.lastError = err
+	k.mutex.Unlock()
+	return err
+}
+
+// IsConnected returns true if the last record was successfully produced
+func (k *KafkaBackend) IsConnected() bool {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.lastError == nil
+}
+
+// Close flushes pending records and closes the kafka writer
+func (k *KafkaBackend) Close() {
+	if err := k.writer.Close(); err != nil {
+		logger.Errorf("Failed to close kafka writer: %v", err)
+	}
+}
+
+// kafkaHeaderCarrier carries the trace context in the headers of a message
+type kafkaHeaderCarrier kafka.Message
+
+// Get returns the value of the header of key
+func (c *kafkaHeaderCarrier) Get(key string) string {
+	for _, header := range c.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// Set sets the header of key, the empty values being left out
+func (c *kafkaHeaderCarrier) Set(key string, value string) {
+	if len(value) == 0 {
+		return
+	}
+	for i, header := range c.Headers {
+		if header.Key == key {
+			c.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.Headers = append(c.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// PcapUDPPort is the destination port of the synthetic UDP datagrams
+	// carrying records. Records are ETSI TS 103 221-2 X2 PDUs which Wireshark
+	// decodes as "li5g" on this port.
+	PcapUDPPort = 6666
+
+	pcapSrcPort   = 50000
+	ipv4HeaderLen = 20
+	udpHeaderLen  = 8
+	maxUDPPayload = 65535 - ipv4HeaderLen - udpHeaderLen
+)
+
+var (
+	pcapSrcAddr = [4]byte{127, 0, 0, 1}
+	pcapDstAddr = [4]byte{127, 0, 0, 2}
+)
+
+// PcapBackend writes records to rotating pcap-ng files, each record
+// encapsulated in a synthetic IPv4/UDP datagram.
+type PcapBackend struct {
+	writer    *PcapWriter
+	lastError error
+	mutex     sync.Mutex
+}
+
+// NewPcapBackend creates a new pcap backend writing to dir
+func NewPcapBackend(dir string, rotationSize int64, retention time.Duration) (*PcapBackend, error) {
+	writer, err := NewPcapWriter(dir, rotationSize, retention)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Writing records to %s", writer)
+	return &PcapBackend{writer: writer}, nil
+}
+
+// Send writes a single record to the current pcap file. The correlation
+// ID is attached as a packet comment.
+func (p *PcapBackend) Send(record []byte, correlationID uint64) error {
+	var err error
+	if len(record) > maxUDPPayload {
+		err = fmt.Errorf("record of %d bytes exceeds the maximum udp payload", len(record))
+	} else {
+		comment := fmt.Sprintf("correlation_id=%d", correlationID)
+		err = p.writer.WritePacket(time.Now(), encapsulateRecord(record), comment)
+	}
+
+	p.mutex.Lock()
+	p.lastError = err
+	p.mutex.Unlock()
+	return err
+}
+
+// IsConnected returns true if the last record was successfully written
+func (p *PcapBackend) IsConnected() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.lastError == nil
+}
+
+// Close closes the current pcap file
+func (p *PcapBackend) Close() {
+	if err := p.writer.Close(); err != nil {
+		logger.Errorf("Failed to close pcap file: %v", err)
+	}
+}
+
+// MirrorBackend delivers records through a primary backend and additionally
+// writes the delivered records to pcap files. Failing to write a pcap
+// file doesn't fail the delivery.
+type MirrorBackend struct {
+	primary Backend
+	pcap    *PcapBackend
+}
+
+// NewMirrorBackend creates a new backend mirroring delivered records of primary to pcap
+func NewMirrorBackend(primary Backend, pcap *PcapBackend) *MirrorBackend {
+	return &MirrorBackend{primary: primary, pcap: pcap}
+}
+
+// Send delivers a record through the primary backend and mirrors it on success
+func (m *MirrorBackend) Send(record []byte, correlationID uint64) error {
+	return m.SendTraced(context.Background(), record, correlationID)
+}
+
+// SendTraced delivers a record along with its trace context through the
+// primary backend and mirrors it on success
+func (m *MirrorBackend) SendTraced(ctx context.Context, record []byte, correlationID uint64) error {
+	err := sendTraced(ctx, m.primary, record, correlationID)
+	if err != nil {
+		return err
+	}
+	if perr := m.pcap.Send(record, correlationID); perr != nil {
+		logger.Errorf("Failed to mirror record to pcap: %v", perr)
+	}
+	return nil
+}
+
+// SendAsync writes a record through the primary backend and mirrors it
+// once delivered
+func (m *MirrorBackend) SendAsync(record []byte, correlationID uint64) *PendingRecord {
+	pending := sendAsync(m.primary, record, correlationID)
+	return newPendingRecord(func() error {
+		if err := pending.Wait(); err != nil {
+			return err
+		}
+		if perr := m.pcap.Send(record, correlationID); perr != nil {
+			logger.Errorf("Failed to mirror record to pcap: %v", perr)
+		}
+		return nil
+	})
+}
+
+// SendBatch delivers records through the primary backend and mirrors the
+// delivered ones
+func (m *MirrorBackend) SendBatch(records []BatchRecord) []error {
+	errs := sendBatch(m.primary, records)
+	for i, r := range records {
+		if errs[i] != nil {
+			continue
+		}
+		if perr := m.pcap.Send(r.Record, r.CorrelationID); perr != nil {
+			logger.Errorf("Failed to mirror record to pcap: %v", perr)
+		}
+	}
+	return errs
+}
+
+// IsConnected returns the connection state of the primary backend
+func (m *MirrorBackend) IsConnected() bool {
+	return m.primary.IsConnected()
+}
+
+// SetHandshakeSettings applies the handshake settings to the primary backend
+func (m *MirrorBackend) SetHandshakeSettings(settings HandshakeSettings) {
+	if hb, ok := m.primary.(handshakeBackend); ok {
+		hb.SetHandshakeSettings(settings)
+	}
+}
+
+// GetHandshake describes the handshake of the primary backend connection
+func (m *MirrorBackend) GetHandshake() *HandshakeInfo {
+	if hb, ok := m.primary.(handshakeBackend); ok {
+		return hb.GetHandshake()
+	}
+	return nil
+}
+
+// Probe probes the delivery function of the primary backend
+func (m *MirrorBackend) Probe(timeout time.Duration) (*HandshakeInfo, error) {
+	if hb, ok := m.primary.(handshakeBackend); ok {
+		return hb.Probe(timeout)
+	}
+	return nil, ErrProbeUnsupported
+}
+
+// GetConnection describes the connection of the primary backend
+func (m *MirrorBackend) GetConnection() *ConnectionInfo {
+	if cb, ok := m.primary.(connectionBackend); ok {
+		return cb.GetConnection()
+	}
+	return &ConnectionInfo{}
+}
+
+// Reconnect reconnects the primary backend
+func (m *MirrorBackend) Reconnect() error {
+	return reconnect(m.primary)
+}
+
+// Connect connects the primary backend
+func (m *MirrorBackend) Connect() error {
+	if cb, ok := m.primary.(connectBackend); ok {
+		return cb.Connect()
+	}
+	return nil
+}
+
+// Close closes both backends
+func (m *MirrorBackend) Close() {
+	m.primary.Close()
+	m.pcap.Close()
+}
+
+// encapsulateRecord wraps a record in IPv4 and UDP headers
+func encapsulateRecord(record []byte) []byte {
+	totalLen := ipv4HeaderLen + udpHeaderLen + len(record)
+	b := make([]byte, totalLen)
+
+	ip := b[:ipv4HeaderLen]
+	ip[0] = 0x45 // version 4, header length 5 words
+	binary.BigEndian.PutUint16(ip[2:], uint16(totalLen))
+	binary.BigEndian.PutUint16(ip[6:], 0x4000) // don't fragment
+	ip[8] = 64                                 // ttl
+	ip[9] = 17                                 // udp
+	copy(ip[12:16], pcapSrcAddr[:])
+	copy(ip[16:20], pcapDstAddr[:])
+	binary.BigEndian.PutUint16(ip[10:], ipv4Checksum(ip))
+
+	udp := b[ipv4HeaderLen : ipv4HeaderLen+udpHeaderLen]
+	binary.BigEndian.PutUint16(udp[0:], pcapSrcPort)
+	binary.BigEndian.PutUint16(udp[2:], PcapUDPPort)
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpHeaderLen+len(record)))
+	// udp checksum is optional over IPv4 and left to 0
+
+	copy(b[ipv4HeaderLen+udpHeaderLen:], record)
+	return b
+}
+
+func ipv4Checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xFFFF) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// pcap-ng block types
+	blockTypeSHB = 0x0A0D0D0A
+	blockTypeIDB = 0x00000001
+	blockTypeEPB = 0x00000006
+
+	byteOrderMagic = 0x1A2B3C4D
+	optEndOfOpt    = 0
+	optComment     = 1
+
+	// linkTypeRaw identifies raw IPv4/IPv6 packets without link layer
+	linkTypeRaw = 101
+	snapLen     = 0
+
+	// PcapFilePrefix and PcapFileSuffix surround the creation time in pcap file names
+	PcapFilePrefix = "nprobe_"
+	PcapFileSuffix = ".pcapng"
+)
+
+// PcapWriter writes packets to pcap-ng files in a directory. A new file is
+// started when the current one reaches the rotation size, and files older
+// than the retention are deleted on rotation.
+type PcapWriter struct {
+	dir          string
+	rotationSize int64
+	retention    time.Duration
+
+	file  *os.File
+	size  int64
+	mutex sync.Mutex
+}
+
+// NewPcapWriter creates a new pcap-ng writer in dir
+func NewPcapWriter(dir string, rotationSize int64, retention time.Duration) (*PcapWriter, error) {
+	if len(dir) == 0 {
+		return nil, errors.New("no pcap directory provided")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &PcapWriter{dir: dir, rotationSize: rotationSize, retention: retention}, nil
+}
+
+// WritePacket appends a packet captured at ts to the current file,
+// rotating it first if it is full. The comment is attached to the packet.
+func (w *PcapWriter) WritePacket(ts time.Time, data []byte, comment string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	block := marshalEPB(ts, data, comment)
+	if w.file != nil && w.rotationSize > 0 && w.size+int64(len(block)) > w.rotationSize {
+		w.closeFile()
+	}
+	if w.file == nil {
+		if err := w.openFile(ts); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.file.Write(block)
+	w.size += int64(n)
+	if err != nil {
+		// start over with a new file rather than appending to a truncated block
+		w.closeFile()
+	}
+	return err
+}
+
+// Close closes the current file
+func (w *PcapWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.closeFile()
+}
+
+// String returns a description of the writer used in logs
+func (w *PcapWriter) String() string {
+	return fmt.Sprintf("pcap writer in %s (rotation %d bytes, retention %v)", w.dir, w.rotationSize, w.retention)
+}
+
+func (w *PcapWriter) openFile(ts time.Time) error {
+	w.deleteExpiredFiles(time.Now())
+
+	name := PcapFilePrefix + ts.UTC().Format("20060102T150405.000000000") + PcapFileSuffix
+	file, err := os.OpenFile(filepath.Join(w.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	header := append(marshalSHB(), marshalIDB()...)
+	if _, err := file.Write(header); err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = int64(len(header))
+	return nil
+}
+
+func (w *PcapWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	w.size = 0
+	return err
+}
+
+// deleteExpiredFiles removes the pcap files last modified before the retention
+func (w *PcapWriter) deleteExpiredFiles(now time.Time) {
+	if w.retention <= 0 {
+		return
+	}
+	files, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		logger.Errorf("Failed to list pcap directory %s: %v", w.dir, err)
+		return
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), PcapFilePrefix) || !strings.HasSuffix(f.Name(), PcapFileSuffix) {
+			continue
+		}
+		if now.Sub(f.ModTime()) <= w.retention {
+			continue
+		}
+		if err := os.Remove(filepath.Join(w.dir, f.Name())); err != nil {
+			logger.Errorf("Failed to delete expired pcap file %s: %v", f.Name(), err)
+		}
+	}
+}
+
+// marshalBlock wraps a block body with its type and total length
+func marshalBlock(blockType uint32, body []byte) []byte {
+	totalLen := uint32(12 + len(body))
+	b := make([]byte, 0, totalLen)
+	b = appendUint32(b, blockType)
+	b = appendUint32(b, totalLen)
+	b = append(b, body...)
+	return appendUint32(b, totalLen)
+}
+
+func marshalSHB() []byte {
+	var body []byte
+	body = appendUint32(body, byteOrderMagic)
+	body = appendUint16(body, 1) // major version
+	body = appendUint16(body, 0) // minor version
+	// section length is not specified
+	body = appendUint32(body, 0xFFFFFFFF)
+	body = appendUint32(body, 0xFFFFFFFF)
+	return marshalBlock(blockTypeSHB, body)
+}
+
+func marshalIDB() []byte {
+	var body []byte
+	body = appendUint16(body, linkTypeRaw)
+	body = appendUint16(body, 0) // reserved
+	body = appendUint32(body, snapLen)
+	return marshalBlock(blockTypeIDB, body)
+}
+
+// marshalEPB returns an enhanced packet block on interface 0 with a
+// microsecond resolution timestamp
+func marshalEPB(ts time.Time, data []byte, comment string) []byte {
+	micros := uint64(ts.UnixNano() / int64(time.Microsecond))
+	var body []byte
+	body = appendUint32(body, 0) // interface id
+	body = appendUint32(body, uint32(micros>>32))
+	body = appendUint32(body, uint32(micros))
+	body = appendUint32(body, uint32(len(data)))
+	body = appendUint32(body, uint32(len(data)))
+	body = appendPadded(body, data)
+	if len(comment) != 0 {
+		body = appendUint16(body, optComment)
+		body = appendUint16(body, uint16(len(comment)))
+		body = appendPadded(body, []byte(comment))
+		body = appendUint16(body, optEndOfOpt)
+		body = appendUint16(body, 0)
+	}
+	return marshalBlock(blockTypeEPB, body)
+}
+
+// appendPadded appends data padded to 32 bits
+func appendPadded(b []byte, data []byte) []byte {
+	b = append(b, data...)
+	if pad := len(data) % 4; pad != 0 {
+		b = append(b, make([]byte, 4-pad)...)
+	}
+	return b
+}
+
+// pcap-ng blocks are written in host byte order, which readers
+// detect from the byte order magic. Little endian is used here.
+func appendUint16(b []byte, v uint16) []byte {
+	var buf [2]byte
+	binary.LittleEndian.PutUint16(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parseBlocks returns the type and body of each block of a pcap-ng file
+func parseBlocks(t *testing.T, b []byte) ([]uint32, [][]byte) {
+	var types []uint32
+	var bodies [][]byte
+	for len(b) != 0 {
+		assert.True(t, len(b) >= 12)
+		blockType := binary.LittleEndian.Uint32(b)
+		totalLen := binary.LittleEndian.Uint32(b[4:])
+		assert.Equal(t, uint32(0), totalLen%4)
+		assert.Equal(t, totalLen, binary.LittleEndian.Uint32(b[totalLen-4:]))
+		types = append(types, blockType)
+		bodies = append(bodies, b[8:totalLen-4])
+		b = b[totalLen:]
+	}
+	return types, bodies
+}
+
+func TestPcapWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_pcap")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// expired files are deleted when a new file is started
+	expired := filepath.Join(dir, PcapFilePrefix+"expired"+PcapFileSuffix)
+	assert.NoError(t, ioutil.WriteFile(expired, []byte{}, 0600))
+	old := time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, os.Chtimes(expired, old, old))
+	unrelated := filepath.Join(dir, "unrelated.txt")
+	assert.NoError(t, ioutil.WriteFile(unrelated, []byte{}, 0600))
+	assert.NoError(t, os.Chtimes(unrelated, old, old))
+
+	// rotate after two packets
+	writer, err := NewPcapWriter(dir, 240, time.Hour)
+	assert.NoError(t, err)
+
+	record := []byte{0x00, 0x02, 0x00, 0x01, 0xAB}
+	ts := time.Unix(1615000000, 123456000)
+	for i := 0; i < 3; i++ {
+		err = writer.WritePacket(ts.Add(time.Duration(i)*time.Millisecond), encapsulateRecord(record), "correlation_id=1")
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, PcapFilePrefix+"*"+PcapFileSuffix))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.NotContains(t, files, expired)
+	_, err = os.Stat(unrelated)
+	assert.NoError(t, err)
+
+	content, err := ioutil.ReadFile(files[0])
+	assert.NoError(t, err)
+	types, bodies := parseBlocks(t, content)
+	assert.Equal(t, []uint32{blockTypeSHB, blockTypeIDB, blockTypeEPB, blockTypeEPB}, types)
+	assert.Equal(t, uint32(byteOrderMagic), binary.LittleEndian.Uint32(bodies[0]))
+	assert.Equal(t, uint16(linkTypeRaw), binary.LittleEndian.Uint16(bodies[1]))
+
+	epb := bodies[2]
+	micros := uint64(binary.LittleEndian.Uint32(epb[4:]))<<32 | uint64(binary.LittleEndian.Uint32(epb[8:]))
+	assert.Equal(t, uint64(1615000000123456), micros)
+	capLen := binary.LittleEndian.Uint32(epb[12:])
+	assert.Equal(t, uint32(ipv4HeaderLen+udpHeaderLen+len(record)), capLen)
+
+	packet := epb[20 : 20+capLen]
+	assert.Equal(t, byte(0x45), packet[0])
+	assert.Equal(t, uint16(0), ipv4Checksum(packet[:ipv4HeaderLen]))
+	assert.Equal(t, uint16(PcapUDPPort), binary.BigEndian.Uint16(packet[ipv4HeaderLen+2:]))
+	assert.Equal(t, record, packet[ipv4HeaderLen+udpHeaderLen:])
+
+	options := epb[20+4*((capLen+3)/4):]
+	assert.Equal(t, uint16(optComment), binary.LittleEndian.Uint16(options))
+	assert.Equal(t, "correlation_id=1", string(options[4:4+binary.LittleEndian.Uint16(options[2:])]))
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PipelineConfig pipelines the records delivered one at a time: with
+// acknowledged delivery, up to Window records are written to the delivery
+// function without waiting for the acknowledgement of the previous ones,
+// instead of stopping to wait for each of them, which bounds the throughput
+// by the round trip to high latency LEMFs. Acknowledgements are tracked by
+// the XID, correlation ID and sequence number of the records, which are
+// settled in the order they were written. A record which isn't acknowledged
+// in time is retransmitted alone, the records written behind it staying in
+// flight, and the records of its task behind it fail once it failed for
+// good.
+type PipelineConfig struct {
+	// Window is the number of records awaiting their acknowledgement,
+	// records are delivered one at a time when lower than 2
+	Window uint32
+}
+
+// PendingRecord is a record written to its destination whose delivery is
+// known once acknowledged
+type PendingRecord struct {
+	// err is the result of the record once settled
+	err error
+	// wait waits for the acknowledgement of the record, nil once settled
+	wait func() error
+}
+
+// pipelineBackend is implemented by the backends delivering records
+// without waiting for their acknowledgement
+type pipelineBackend interface {
+	// SendAsync writes a record and returns its pending delivery
+	SendAsync(record []byte, correlationID uint64) *PendingRecord
+}
+
+// inflightRecord is a record written to the backend by the queue, awaiting
+// its acknowledgement
+type inflightRecord struct {
+	queuedRecord
+	flow    *flow
+	pending *PendingRecord
+	span    trace.Span
+	// failures is the number of failures of the flow when the record was
+	// written, the record failing along with the records written before it
+	failures int
+}
+
+// pipelinedBackends counts the records in flight on each backend, so that a
+// backend replaced while records were in flight on it is closed once, after
+// they all settled
+type pipelinedBackends struct {
+	mutex   sync.Mutex
+	records map[Backend]int
+}
+
+// pipeline holds the records in flight, in the order they were written
+type pipeline struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	records []inflightRecord
+	closed  bool
+	done    chan struct{}
+}
+
+// NewPipelineConfig returns the write pipelining settings of the service config
+func NewPipelineConfig(config nprobe.Config) PipelineConfig {
+	return PipelineConfig{Window: config.ExportAckWindow}
+}
+
+// SetPipelineConfig applies write pipelining settings to the records
+// delivered from now on, the records in flight staying so
+func (c *RecordExporter) SetPipelineConfig(config PipelineConfig) {
+	c.queue.setPipelineConfig(config)
+}
+
+// sendPipelined writes a record through the backend without waiting for its
+// acknowledgement. The failures are retransmitted by the queue.
+func (c *RecordExporter) sendPipelined(message []byte, correlationID uint64) *PendingRecord {
+	class := encoding.GetRecordClass(message)
+	start := time.Now()
+	backend := c.getBackend()
+	c.pipelined.acquire(backend)
+	pending := sendAsync(backend, message, correlationID)
+	return newPendingRecord(func() error {
+		err := pending.Wait()
+		metrics.ExportLatency.WithLabelValues(class).Observe(time.Since(start).Seconds())
+		if c.pipelined.release(backend) && backend != c.getBackend() {
+			// the backend was replaced while records were in flight on it
+			// and may have reconnected
+			backend.Close()
+		}
+		c.setDeliveryOutcome(err)
+		if err != nil {
+			metrics.DeliveryFailures.WithLabelValues(ClassifyError(err)).Inc()
+			return err
+		}
+		metrics.RecordsSent.WithLabelValues(class).Inc()
+		metrics.BytesSent.WithLabelValues(class).Add(float64(len(message)))
+		return nil
+	})
+}
+
+// sendAsync writes a record through a backend without waiting for its
+// acknowledgement if the backend supports it
+func sendAsync(backend Backend, record []byte, correlationID uint64) *PendingRecord {
+	if pb, ok := backend.(pipelineBackend); ok {
+		return pb.SendAsync(record, correlationID)
+	}
+	return settledRecord(backend.Send(record, correlationID))
+}
+
+// acquire counts a record written to a backend
+func (p *pipelinedBackends) acquire(backend Backend) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.records == nil {
+		p.records = map[Backend]int{}
+	}
+	p.records[backend]++
+}
+
+// release counts a record of a backend settled, and returns true when no
+// other record is in flight on the backend
+func (p *pipelinedBackends) release(backend Backend) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.records[backend]--
+	if p.records[backend] > 0 {
+		return false
+	}
+	delete(p.records, backend)
+	return true
+}
+
+func newPendingRecord(wait func() error) *PendingRecord {
+	return &PendingRecord{wait: wait}
+}
+
+// settledRecord returns a record whose delivery is already known
+func settledRecord(err error) *PendingRecord {
+	return &PendingRecord{err: err}
+}
+
+// Wait waits for the acknowledgement of the record and returns its result.
+// It is called by a single goroutine.
+func (p *PendingRecord) Wait() error {
+	if p.wait != nil {
+		p.err = p.wait()
+		p.wait = nil
+	}
+	return p.err
+}
+
+func (b PipelineConfig) isEnabled() bool {
+	return b.Window > 1
+}
+
+func newPipeline() *pipeline {
+	p := &pipeline{done: make(chan struct{})}
+	p.cond = sync.NewCond(&p.mutex)
+	return p
+}
+
+// reserve waits until less than window records are in flight
+func (p *pipeline) reserve(window int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for len(p.records) >= window {
+		p.cond.Wait()
+	}
+}
+
+// push appends a record written to the backend
+func (p *pipeline) push(r inflightRecord) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.records = append(p.records, r)
+	p.cond.Broadcast()
+}
+
+// run settles the records in flight in order until the pipeline is closed
+// and drained. A record leaves the window once settled.
+func (p *pipeline) run(settle func(r inflightRecord)) {
+	defer close(p.done)
+	for {
+		p.mutex.Lock()
+		for len(p.records) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.records) == 0 {
+			p.mutex.Unlock()
+			return
+		}
+		r := p.records[0]
+		p.mutex.Unlock()
+
+		settle(r)
+
+		p.mutex.Lock()
+		p.records = p.records[1:]
+		p.cond.Broadcast()
+		p.mutex.Unlock()
+	}
+}
+
+// close waits for the records in flight to be settled
+func (p *pipeline) close() {
+	p.mutex.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mutex.Unlock()
+	<-p.done
+}
+
+func (q *fairQueue) setPipelineConfig(config PipelineConfig) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.pipelining = config
+}
+
+// writeRecord writes a record of a flow without waiting for its
+// acknowledgement, once the window has room for it. failures is the number
+// of failures of the flow when the record was dequeued.
+func (q *fairQueue) writeRecord(r queuedRecord, f *flow, failures int, window int, span trace.Span) {
+	q.pipeline.reserve(window)
+	d := r.delivery
+	span.AddEvent(spanEventSending)
+	q.pipeline.push(inflightRecord{
+		queuedRecord: r,
+		flow:         f,
+		pending:      q.sendAsync(r.record, d.correlationID),
+		span:         span,
+		failures:     failures,
+	})
+}
+
+// settleInflight waits for the acknowledgement of a record in flight and
+// settles it. A record which failed is retransmitted alone, unless a record
+// of its flow written before it failed.
+func (q *fairQueue) settleInflight(r inflightRecord) {
+	d := r.delivery
+	err := r.pending.Wait()
+	q.mutex.Lock()
+	failed := r.flow.failures != r.failures
+	q.mutex.Unlock()
+	switch {
+	case failed:
+		err = ErrPreviousRecordFailed
+	case err != nil && d.retryCount > 1 && IsRetryable(ClassifyError(err)) && d.ctx.Err() == nil:
+		r.span.AddEvent(spanEventRetransmitted)
+		err = q.send(trace.ContextWithSpan(d.ctx, r.span), r.record, d.correlationID, d.retryCount-1)
+	}
+	endDeliverSpan(r.span, err)
+	d.settle(r.index, err)
+	switch {
+	case err == nil:
+		metrics.DeliveryLatency.WithLabelValues(encoding.GetRecordClass(r.record)).Observe(time.Since(d.queuedAt).Seconds())
+	case !failed:
+		q.failFlow(r.flow)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pipelinedSender writes records without acknowledging them until the test
+// does. The records are identified by their first byte.
+type pipelinedSender struct {
+	mutex       sync.Mutex
+	acks        map[byte]chan error
+	written     []byte
+	retransmits []byte
+	inflight    int
+	maxInflight int
+	failRetry   bool
+}
+
+func (s *pipelinedSender) ack(id byte) chan error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.acks[id] == nil {
+		s.acks[id] = make(chan error, 1)
+	}
+	return s.acks[id]
+}
+
+func (s *pipelinedSender) sendAsync(record []byte, correlationID uint64) *PendingRecord {
+	acked := s.ack(record[0])
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.written = append(s.written, record[0])
+	s.inflight++
+	if s.inflight > s.maxInflight {
+		s.maxInflight = s.inflight
+	}
+	return newPendingRecord(func() error {
+		err := <-acked
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.inflight--
+		return err
+	})
+}
+
+func (s *pipelinedSender) send(ctx context.Context, record []byte, correlationID uint64, retryCount uint32) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.retransmits = append(s.retransmits, record[0])
+	if s.failRetry {
+		return errors.New("retransmission failed")
+	}
+	return nil
+}
+
+func (s *pipelinedSender) getWritten() []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]byte{}, s.written...)
+}
+
+// asyncBackend is a backend writing records without acknowledging them
+// until the test does
+type asyncBackend struct {
+	pipelinedSender
+	closes int32
+}
+
+func newAsyncBackend() *asyncBackend {
+	return &asyncBackend{pipelinedSender: pipelinedSender{acks: map[byte]chan error{}}}
+}
+
+func (b *asyncBackend) Send(record []byte, correlationID uint64) error {
+	return b.send(context.Background(), record, correlationID, 0)
+}
+
+func (b *asyncBackend) SendAsync(record []byte, correlationID uint64) *PendingRecord {
+	return b.sendAsync(record, correlationID)
+}
+
+func (b *asyncBackend) IsConnected() bool {
+	return true
+}
+
+func (b *asyncBackend) Close() {
+	atomic.AddInt32(&b.closes, 1)
+}
+
+func (b *asyncBackend) getCloses() int32 {
+	return atomic.LoadInt32(&b.closes)
+}
+
+func makeNumberedRecords(first, count int) [][]byte {
+	records := make([][]byte, count)
+	for i := range records {
+		records[i] = []byte{byte(first + i)}
+	}
+	return records
+}
+
+func TestFairQueuePipeline(t *testing.T) {
+	sender := &pipelinedSender{acks: map[byte]chan error{}}
+	q := newFairQueue(sender.send)
+	q.sendAsync = sender.sendAsync
+	q.setPipelineConfig(PipelineConfig{Window: 3})
+	ctx := context.Background()
+
+	// records are written without waiting for their acknowledgement, up to
+	// the window
+	delivery := q.submit(ctx, "task", 1, 1, makeNumberedRecords(0, 6), 3)
+	assert.Eventually(t, func() bool { return len(sender.getWritten()) == 3 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, []byte{0, 1, 2}, sender.getWritten())
+
+	// the record which isn't acknowledged is retransmitted alone while the
+	// records behind it are written
+	sender.ack(2) <- nil
+	sender.ack(1) <- errAckTimeout
+	sender.ack(0) <- nil
+	for id := byte(3); id < 6; id++ {
+		sender.ack(id) <- nil
+	}
+	assert.Equal(t, make([]error, 6), waitAll(delivery))
+	assert.Equal(t, []byte{0, 1, 2, 3, 4, 5}, sender.getWritten())
+	assert.Equal(t, []byte{1}, sender.retransmits)
+	assert.Equal(t, 3, sender.maxInflight)
+
+	// once a record failed for good, the records of its task written behind
+	// it fail
+	sender.failRetry = true
+	for id := byte(7); id < 9; id++ {
+		sender.ack(id) <- nil
+	}
+	sender.ack(6) <- errAckTimeout
+	errs := waitAll(q.submit(ctx, "task", 1, 1, makeNumberedRecords(6, 3), 3))
+	assert.EqualError(t, errs[0], "retransmission failed")
+	assert.Equal(t, []error{ErrPreviousRecordFailed, ErrPreviousRecordFailed}, errs[1:])
+	assert.Equal(t, []byte{1, 6}, sender.retransmits)
+
+	// records which can't be retried aren't retransmitted
+	sender.ack(9) <- errBreakerOpen
+	errs = waitAll(q.submit(ctx, "task", 1, 1, makeNumberedRecords(9, 1), 3))
+	assert.Equal(t, []error{errBreakerOpen}, errs)
+	assert.Equal(t, []byte{1, 6}, sender.retransmits)
+	q.close()
+}
+
+func TestSendPipelinedReplacedBackend(t *testing.T) {
+	previous := newAsyncBackend()
+	c := NewRecordExporter(previous)
+	defer c.Close()
+	pending := []*PendingRecord{c.sendPipelined([]byte{0}, 1), c.sendPipelined([]byte{1}, 1)}
+	c.SetBackend(newAsyncBackend())
+	assert.Equal(t, int32(1), previous.getCloses())
+
+	// the replaced backend is closed again once, after the records in flight
+	// on it all settled
+	previous.ack(0) <- nil
+	assert.NoError(t, pending[0].Wait())
+	assert.Equal(t, int32(1), previous.getCloses())
+	previous.ack(1) <- nil
+	assert.NoError(t, pending[1].Wait())
+	assert.Equal(t, int32(2), previous.getCloses())
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/health"
+)
+
+// Destination is a delivery function the records of some tasks are delivered
+// to on a connection of their own rather than by the exporter of the service
+// config, e.g. the one of another LEA or the one of the service config with
+// the credentials of a network
+type Destination struct {
+	// Address is the host:port address of the delivery function
+	Address string
+	// SecondaryAddress is the host:port address of the delivery function
+	// records fail over to when the one of Address fails, none when empty
+	SecondaryAddress string
+	// Handshake customizes the TLS handshake of its connection
+	Handshake HandshakeSettings
+	// Network is the network whose exporter credentials its connection
+	// presents, the ones of the service config when empty
+	Network string
+	// RecordsPerSecond and Burst pace the records delivered to it instead of
+	// the rate limit of the service config when RecordsPerSecond is set
+	RecordsPerSecond uint32
+	Burst            uint32
+}
+
+// key identifies the connection of a destination
+func (d Destination) key() string {
+	return strings.Join([]string{
+		NormalizeAddress(d.Address), NormalizeAddress(d.SecondaryAddress), d.Handshake.ServerName, strings.Join(d.Handshake.ALPNProtocols, ","), d.Handshake.Compression, d.Handshake.Framing, d.Handshake.Encoding,
+		strconv.Itoa(int(d.Handshake.HeaderVersion)), strconv.FormatBool(d.Handshake.NegotiateHeaderVersion), d.Handshake.WriteTimeout.String(), d.Handshake.Transport, d.Network,
+	}, "|")
+}
+
+// Pool maintains an exporter per destination, each with its own connection.
+// The exporters are created on first use with the transport, output format
+// and export settings of the service config, and closed once no task
+// delivers to their destination. The connections of the destinations of a
+// network present its credentials, and are replaced once their generation
+// changes.
+type Pool struct {
+	mutex        sync.Mutex
+	config       nprobe.Config
+	tlsConfig    *tls.Config
+	credentials  *CredentialManager
+	rateLimit    RateLimit
+	exporters    map[string]*RecordExporter
+	generations  map[string]uint64
+	destinations map[string]Destination
+}
+
+// PooledExporter is an exporter of the pool along with its destination
+type PooledExporter struct {
+	Destination Destination
+	Exporter    *RecordExporter
+}
+
+// NewPool creates an empty pool of exporters configured by the service config
+func NewPool(config nprobe.Config, tlsConfig *tls.Config) (*Pool, error) {
+	rateLimit, err := NewRateLimit(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Pool{
+		config:       config,
+		tlsConfig:    tlsConfig,
+		rateLimit:    rateLimit,
+		exporters:    map[string]*RecordExporter{},
+		generations:  map[string]uint64{},
+		destinations: map[string]Destination{},
+	}, nil
+}
+
+// SetCredentials sets the credentials presented on the connections of the
+// destinations of the networks
+func (p *Pool) SetCredentials(credentials *CredentialManager) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.credentials = credentials
+}
+
+// NewDestinationBackend creates the backend delivering records to another
+// delivery function than the one of the service config, with the same
+// transport and output format, failing over to secondaryAddr if set. Only
+// the tls and grpc backends deliver to addresses, the grpc backend
+// delivering to the address rather than to the mediation function of the
+// service registry.
+func NewDestinationBackend(config nprobe.Config, tlsConfig *tls.Config, addr, secondaryAddr string) (Backend, error) {
+	if config.ExporterBackend != BackendTLS && config.ExporterBackend != BackendGRPC {
+		return nil, fmt.Errorf("delivery to %s requires the %s or %s exporter backend, not %s", addr, BackendTLS, BackendGRPC, config.ExporterBackend)
+	}
+	config.DeliveryFunctionAddr = addr
+	config.GRPCDeliveryService = ""
+	config.SecondaryDeliveryFunctionAddr = secondaryAddr
+	// the records of the service delivery function only are mirrored
+	config.PcapMirror = false
+	return NewBackend(config, tlsConfig)
+}
+
+// Get returns the exporter delivering to a destination, created if needed
+func (p *Pool) Get(destination Destination) (*RecordExporter, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	key := destination.key()
+	tlsConfig, generation := p.tlsConfig, uint64(0)
+	if len(destination.Network) != 0 {
+		if p.credentials == nil {
+			return nil, fmt.Errorf("no exporter credentials to deliver the records of network %s", destination.Network)
+		}
+		var err error
+		tlsConfig, generation, err = p.credentials.NetworkTLSConfig(destination.Network, p.config.SkipVerifyServer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if exp, ok := p.exporters[key]; ok {
+		if p.generations[key] == generation {
+			if current := p.destinations[key]; current.RecordsPerSecond != destination.RecordsPerSecond || current.Burst != destination.Burst {
+				p.destinations[key] = destination
+				p.configure(exp, destination)
+			}
+			return exp, nil
+		}
+		// the credentials of the network were reloaded with other root CAs
+		delete(p.exporters, key)
+		go exp.Close()
+	}
+	backend, err := NewDestinationBackend(p.config, applyHandshakeSettings(tlsConfig, destination.Handshake), destination.Address, destination.SecondaryAddress)
+	if err != nil {
+		return nil, err
+	}
+	exp := NewRecordExporter(backend)
+	p.configure(exp, destination)
+	p.exporters[key] = exp
+	p.generations[key] = generation
+	p.destinations[key] = destination
+	return exp, nil
+}
+
+// SetConfig applies a reloaded service config to the exporters. When the
+// backend settings changed, the exporters are closed once their submitted
+// records are delivered, and created again on their next use.
+func (p *Pool) SetConfig(config nprobe.Config, tlsConfig *tls.Config) error {
+	rateLimit, err := NewRateLimit(config)
+	if err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	previous := p.config
+	p.config, p.tlsConfig, p.rateLimit = config, tlsConfig, rateLimit
+	if IsBackendConfigChanged(previous, config) {
+		for key, exp := range p.exporters {
+			delete(p.exporters, key)
+			delete(p.generations, key)
+			delete(p.destinations, key)
+			go exp.Close()
+		}
+		return nil
+	}
+	for key, exp := range p.exporters {
+		p.configure(exp, p.destinations[key])
+	}
+	return nil
+}
+
+// Retain closes the exporters of the destinations not listed, once their
+// submitted records are delivered
+func (p *Pool) Retain(destinations []Destination) {
+	retained := map[string]bool{}
+	for _, destination := range destinations {
+		retained[destination.key()] = true
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for key, exp := range p.exporters {
+		if !retained[key] {
+			forgotten := p.destinations[key]
+			delete(p.exporters, key)
+			delete(p.generations, key)
+			delete(p.destinations, key)
+			go exp.Close()
+			p.forgetCertificates(forgotten)
+		}
+	}
+}
+
+// forgetCertificates stops tracking the certificates of the delivery
+// functions of a destination no longer delivered to, unless the exporter of
+// the service config or another destination delivers to them. The caller
+// holds the mutex.
+func (p *Pool) forgetCertificates(forgotten Destination) {
+	for _, addr := range []string{forgotten.Address, forgotten.SecondaryAddress} {
+		addr = NormalizeAddress(addr)
+		if len(addr) == 0 {
+			continue
+		}
+		used := addr == NormalizeAddress(p.config.DeliveryFunctionAddr) || addr == NormalizeAddress(p.config.SecondaryDeliveryFunctionAddr)
+		for _, d := range p.destinations {
+			if NormalizeAddress(d.Address) == addr || NormalizeAddress(d.SecondaryAddress) == addr {
+				used = true
+				break
+			}
+		}
+		if !used {
+			health.Certificates().Forget(health.CertificateServer, addr)
+		}
+	}
+}
+
+// Disconnect closes the connections of the exporters without waiting for
+// their submitted records
+func (p *Pool) Disconnect() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, exp := range p.exporters {
+		exp.Disconnect()
+	}
+}
+
+// Close waits for the submitted records to be delivered then closes the
+// exporters
+func (p *Pool) Close() {
+	p.mutex.Lock()
+	exporters := p.exporters
+	p.exporters = map[string]*RecordExporter{}
+	p.generations = map[string]uint64{}
+	p.destinations = map[string]Destination{}
+	p.mutex.Unlock()
+	for _, exp := range exporters {
+		exp.Close()
+	}
+}
+
+// List returns the exporters of the pool, sorted by destination
+func (p *Pool) List() []PooledExporter {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	keys := make([]string, 0, len(p.exporters))
+	for key := range p.exporters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	ret := make([]PooledExporter, 0, len(keys))
+	for _, key := range keys {
+		ret = append(ret, PooledExporter{Destination: p.destinations[key], Exporter: p.exporters[key]})
+	}
+	return ret
+}
+
+// QueueStats returns the records submitted to the exporters waiting for
+// delivery and their size in bytes
+func (p *Pool) QueueStats() (int, int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var records, bytes int
+	for _, exp := range p.exporters {
+		n, size := exp.QueueStats()
+		records, bytes = records+n, bytes+size
+	}
+	return records, bytes
+}
+
+// configure applies the export settings of the service config to the
+// exporter of a destination, paced at the rate of the destination if set
+func (p *Pool) configure(exp *RecordExporter, destination Destination) {
+	rateLimit := p.rateLimit
+	if destination.RecordsPerSecond != 0 {
+		rateLimit.RecordsPerSecond, rateLimit.Burst = destination.RecordsPerSecond, destination.Burst
+	}
+	exp.SetRateLimit(rateLimit)
+	exp.SetBatchConfig(NewBatchConfig(p.config))
+	exp.SetPipelineConfig(NewPipelineConfig(p.config))
+	exp.SetSequencerConfig(NewSequencerConfig(p.config))
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPool(t *testing.T) {
+	config := nprobe.Config{
+		ExporterBackend:      BackendTLS,
+		OutputFormat:         encoding.OutputFormatHI2,
+		ExportOverflowPolicy: OverflowSpill,
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	pool, err := NewPool(config, tlsConfig)
+	assert.NoError(t, err)
+	defer pool.Close()
+
+	// tasks delivering to the same destination share its connection
+	lea1 := Destination{Address: "127.0.0.1:1"}
+	lea2 := Destination{Address: "127.0.0.1:1", Handshake: HandshakeSettings{ServerName: "hi2.lea2.example.org"}}
+	exp1, err := pool.Get(lea1)
+	assert.NoError(t, err)
+	same, err := pool.Get(Destination{Address: "127.0.0.1:1"})
+	assert.NoError(t, err)
+	assert.True(t, exp1 == same)
+	exp2, err := pool.Get(lea2)
+	assert.NoError(t, err)
+	assert.False(t, exp1 == exp2)
+
+	// the exporters of the destinations no longer used are closed
+	pool.Retain([]Destination{lea2})
+	kept, err := pool.Get(lea2)
+	assert.NoError(t, err)
+	assert.True(t, exp2 == kept)
+	recreated, err := pool.Get(lea1)
+	assert.NoError(t, err)
+	assert.False(t, exp1 == recreated)
+	listed := pool.List()
+	assert.Len(t, listed, 2)
+	assert.Equal(t, lea1, listed[1].Destination)
+	assert.True(t, recreated == listed[1].Exporter)
+
+	// a changed backend config recreates the exporters
+	config.KeepaliveIntervalSecs = 30
+	assert.NoError(t, pool.SetConfig(config, tlsConfig))
+	replaced, err := pool.Get(lea2)
+	assert.NoError(t, err)
+	assert.False(t, exp2 == replaced)
+	records, _ := pool.QueueStats()
+	assert.Equal(t, 0, records)
+
+	// records are delivered to addresses by the tls and grpc backends only
+	config.ExporterBackend = BackendKafka
+	assert.NoError(t, pool.SetConfig(config, tlsConfig))
+	_, err = pool.Get(lea1)
+	assert.Error(t, err)
+}
+
+func TestPoolNetworkCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_credentials")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	crtFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writeCertificate(t, crtFile, keyFile, 1)
+	service, err := NewCertificateStore(crtFile, keyFile)
+	assert.NoError(t, err)
+	writeNetworkCredentials(t, dir, "n1", 10)
+	credentials := NewCredentialManager(service, dir)
+
+	config := nprobe.Config{
+		ExporterBackend:      BackendTLS,
+		OutputFormat:         encoding.OutputFormatHI2,
+		ExportOverflowPolicy: OverflowSpill,
+	}
+	pool, err := NewPool(config, credentials.TLSConfig(true))
+	assert.NoError(t, err)
+	defer pool.Close()
+
+	// the network delivers on a connection presenting its credentials
+	n1 := Destination{Address: "127.0.0.1:1", Network: "n1"}
+	_, err = pool.Get(n1)
+	assert.Error(t, err)
+	pool.SetCredentials(credentials)
+	exp, err := pool.Get(n1)
+	assert.NoError(t, err)
+	shared, err := pool.Get(Destination{Address: "127.0.0.1:1"})
+	assert.NoError(t, err)
+	assert.False(t, exp == shared)
+	_, err = pool.Get(Destination{Address: "127.0.0.1:1", Network: "n2"})
+	assert.Error(t, err)
+
+	// and reconnects once its root CAs change
+	same, err := pool.Get(n1)
+	assert.NoError(t, err)
+	assert.True(t, exp == same)
+	writeNetworkCredentials(t, dir, "n1", 11)
+	assert.NoError(t, credentials.Reload())
+	replaced, err := pool.Get(n1)
+	assert.NoError(t, err)
+	assert.False(t, exp == replaced)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+)
+
+// GetProbedAddress returns the address of the delivery function of the
+// service config checked by the destination probes, false for the backends
+// which don't deliver to an address
+func GetProbedAddress(config nprobe.Config) (string, bool) {
+	switch config.ExporterBackend {
+	case BackendTLS, BackendDev:
+		return NormalizeAddress(config.DeliveryFunctionAddr), len(config.DeliveryFunctionAddr) != 0
+	case BackendGRPC:
+		// the mediation functions of the service registry aren't probed
+		return NormalizeAddress(config.DeliveryFunctionAddr), len(config.DeliveryFunctionAddr) != 0 && len(config.GRPCDeliveryService) == 0
+	}
+	return "", false
+}
+
+// ProbeDestination checks that a delivery function address resolves and
+// accepts TCP connections within timeout. Resolution failures are reported
+// apart from connection failures, e.g. to tell a missing DNS record from a
+// firewall. The connection is closed right away, without any TLS handshake.
+func ProbeDestination(ctx context.Context, addr string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	host, port, err := net.SplitHostPort(NormalizeAddress(addr))
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %v", addr, err)
+	}
+	ips, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %v", host, err)
+	}
+	dialer := net.Dialer{}
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to connect to %s: %v", addr, err)
+}
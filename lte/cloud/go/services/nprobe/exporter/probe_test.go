@@ -0,0 +1,59 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeDestination(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	ctx := context.Background()
+	assert.NoError(t, ProbeDestination(ctx, addr, time.Second))
+
+	// nothing listens on the port once closed
+	listener.Close()
+	err = ProbeDestination(ctx, addr, time.Second)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to connect")
+
+	err = ProbeDestination(ctx, "lemf", time.Second)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid address")
+}
+
+func TestGetProbedAddress(t *testing.T) {
+	addr, ok := GetProbedAddress(nprobe.Config{ExporterBackend: BackendTLS, DeliveryFunctionAddr: "2001:db8::1:4040"})
+	assert.True(t, ok)
+	assert.Equal(t, "[2001:db8::1]:4040", addr)
+	_, ok = GetProbedAddress(nprobe.Config{ExporterBackend: BackendKafka, KafkaTopic: "li"})
+	assert.False(t, ok)
+	_, ok = GetProbedAddress(nprobe.Config{ExporterBackend: BackendPcap})
+	assert.False(t, ok)
+	addr, ok = GetProbedAddress(nprobe.Config{ExporterBackend: BackendGRPC, DeliveryFunctionAddr: "mf.example:8443"})
+	assert.True(t, ok)
+	assert.Equal(t, "mf.example:8443", addr)
+	// the mediation functions of the service registry aren't probed
+	_, ok = GetProbedAddress(nprobe.Config{ExporterBackend: BackendGRPC, GRPCDeliveryService: "mediation"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+)
+
+const (
+	// OverflowSpill fails the records submitted while the queue is full, so
+	// that their task submits them again later
+	OverflowSpill = "spill"
+	// OverflowDrop discards the records submitted while the queue is full
+	OverflowDrop = "drop"
+)
+
+var (
+	// ErrThrottled is returned for the records spilled back to their task
+	// because the export queue is full
+	ErrThrottled = errors.New("export queue is full, record spilled back to its task")
+	// ErrRecordDropped is returned for the records discarded because the
+	// export queue is full
+	ErrRecordDropped = errors.New("export queue is full, record dropped")
+)
+
+// RateLimit shapes the records delivered to the delivery function
+type RateLimit struct {
+	// RecordsPerSecond is the rate records are delivered at, records are
+	// not paced when 0
+	RecordsPerSecond uint32
+	// Burst is the number of records delivered at once after an idle
+	// period, which defaults to RecordsPerSecond
+	Burst uint32
+	// QueueSize bounds the records waiting for delivery, the queue is
+	// unbounded when 0
+	QueueSize uint32
+	// OverflowPolicy handles the records submitted while the queue is full
+	OverflowPolicy string
+	// HighWatermark is the number of queued records from which the tasks
+	// stop fetching further events, which defaults to 3/4 of QueueSize. The
+	// tasks are not held back when 0.
+	HighWatermark uint32
+}
+
+// NewRateLimit returns the rate limit set in the service config
+func NewRateLimit(config nprobe.Config) (RateLimit, error) {
+	limit := RateLimit{
+		RecordsPerSecond: config.ExportRateLimit,
+		Burst:            config.ExportBurstSize,
+		QueueSize:        config.ExportQueueSize,
+		OverflowPolicy:   config.ExportOverflowPolicy,
+		HighWatermark:    config.ExportQueueHighWatermark,
+	}
+	switch limit.OverflowPolicy {
+	case OverflowSpill, OverflowDrop:
+	default:
+		return RateLimit{}, fmt.Errorf("unsupported export overflow policy %s", limit.OverflowPolicy)
+	}
+	if limit.QueueSize != 0 {
+		if limit.HighWatermark == 0 {
+			limit.HighWatermark = limit.QueueSize - limit.QueueSize/4
+		}
+		if limit.HighWatermark > limit.QueueSize {
+			return RateLimit{}, fmt.Errorf("export queue high watermark %d exceeds the queue size %d", limit.HighWatermark, limit.QueueSize)
+		}
+	}
+	return limit, nil
+}
+
+// SetRateLimit applies a rate limit to the records submitted from now on.
+// The records already queued are paced at the new rate.
+func (c *RecordExporter) SetRateLimit(limit RateLimit) {
+	c.queue.setRateLimit(limit)
+}
+
+// IsBackpressured returns true once the records waiting for delivery reach
+// the high watermark of the rate limit, until they are delivered
+func (c *RecordExporter) IsBackpressured() bool {
+	return c.queue.isBackpressured()
+}
+
+// CheckQueue returns an error once the records waiting for delivery reach
+// the high watermark of the rate limit, nil below it or if the queue is
+// unbounded
+func (c *RecordExporter) CheckQueue() error {
+	queued, limit := c.queue.usage()
+	if limit.HighWatermark == 0 || queued < limit.HighWatermark {
+		return nil
+	}
+	return fmt.Errorf("export queue holds %d of %d records", queued, limit.QueueSize)
+}
+
+// getOverflowError returns the result of the records submitted while the
+// queue is full
+func (l RateLimit) getOverflowError() error {
+	if l.OverflowPolicy == OverflowDrop {
+		return ErrRecordDropped
+	}
+	return ErrThrottled
+}
+
+// tokenBucket paces records at a rate, allowing bursts of its size
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full bucket for the limit, nil when records are
+// not paced
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	if limit.RecordsPerSecond == 0 {
+		return nil
+	}
+	burst := limit.Burst
+	if burst == 0 {
+		burst = limit.RecordsPerSecond
+	}
+	return &tokenBucket{
+		rate:   float64(limit.RecordsPerSecond),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// take takes a token and returns the time to wait until it is available.
+// Tokens may be owed, so that records waiting for a token are served in
+// the order they took it.
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ishidawataru/sctp"
+)
+
+// Transports of the connections of the tls backend
+const (
+	// TransportTCP delivers records over tcp/tls
+	TransportTCP = "tcp"
+	// TransportSCTP delivers records over a multi-homed SCTP association,
+	// as required by some national handover specifications
+	TransportSCTP = "sctp"
+)
+
+// Security of the SCTP associations, which the exporter doesn't encrypt.
+// Records are written in plaintext on the association, so that the path to
+// the delivery function must be protected outside of the service.
+const (
+	// SCTPSecurityIPsec assumes the associations are carried by IPsec
+	// tunnels or transport mode security associations set up on the host
+	SCTPSecurityIPsec = "ipsec"
+	// SCTPSecurityDTLS assumes the associations end on a DTLS gateway
+	// protecting them up to the delivery function, as in RFC 6083
+	SCTPSecurityDTLS = "dtls"
+)
+
+// ValidateTransport checks that a transport is supported, the SCTP one
+// requiring the security of its associations to be stated
+func ValidateTransport(transport, security string) error {
+	switch transport {
+	case "", TransportTCP:
+		return nil
+	case TransportSCTP:
+		if security != SCTPSecurityIPsec && security != SCTPSecurityDTLS {
+			return fmt.Errorf(
+				"the %s transport requires sctp_security to be %s or %s, its associations not being encrypted",
+				TransportSCTP, SCTPSecurityIPsec, SCTPSecurityDTLS,
+			)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported transport %s", transport)
+	}
+}
+
+// dialSCTP establishes an SCTP association with a delivery function. The
+// addresses of a multi-homed delivery function are separated by slashes, as
+// in 10.10.0.2/10.20.0.2:6666, the association failing over between them
+// on its own. The association is bound to the local addresses when set,
+// e.g. on the interfaces of both paths.
+func dialSCTP(addr string, localAddrs []string) (net.Conn, error) {
+	raddr, err := sctp.ResolveSCTPAddr(TransportSCTP, addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sctp address %s: %v", addr, err)
+	}
+	var laddr *sctp.SCTPAddr
+	if len(localAddrs) != 0 {
+		local := strings.Join(localAddrs, "/") + ":0"
+		if laddr, err = sctp.ResolveSCTPAddr(TransportSCTP, local); err != nil {
+			return nil, fmt.Errorf("invalid sctp local addresses %s: %v", local, err)
+		}
+	}
+	conn, err := sctp.DialSCTP(TransportSCTP, laddr, raddr)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTransport(t *testing.T) {
+	assert.NoError(t, ValidateTransport("", ""))
+	assert.NoError(t, ValidateTransport(TransportTCP, ""))
+	assert.NoError(t, ValidateTransport(TransportSCTP, SCTPSecurityIPsec))
+	assert.NoError(t, ValidateTransport(TransportSCTP, SCTPSecurityDTLS))
+	assert.EqualError(t, ValidateTransport(TransportSCTP, ""), "the sctp transport requires sctp_security to be ipsec or dtls, its associations not being encrypted")
+	assert.EqualError(t, ValidateTransport("udp", ""), "unsupported transport udp")
+}
+
+func TestTLSBackendSCTPTransport(t *testing.T) {
+	backend := NewTLSBackend("127.0.0.1/127.0.0.2:1", nil, TLSBackendConfig{WriteTimeout: time.Second})
+	defer backend.Close()
+
+	// the write deadline doesn't apply to the associations
+	assert.Equal(t, time.Second, backend.getConnSettings().writeTimeout)
+	backend.SetHandshakeSettings(HandshakeSettings{Transport: TransportSCTP})
+	settings := backend.getConnSettings()
+	assert.Equal(t, TransportSCTP, settings.transport)
+	assert.Zero(t, settings.writeTimeout)
+
+	// associations whose security isn't stated are refused, and never probed
+	err := backend.Send([]byte("r1"), 1)
+	assert.Equal(t, FailureConnect, ClassifyError(err))
+	assert.Contains(t, err.Error(), "requires sctp_security")
+	_, err = backend.Probe(time.Second)
+	assert.Equal(t, ErrProbeUnsupported, err)
+}
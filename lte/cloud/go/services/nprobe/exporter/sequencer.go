@@ -0,0 +1,269 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"sort"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+
+	"github.com/gofrs/uuid"
+)
+
+// sequenceIdleTimeout is the time after which the sequence of an XID
+// without submitted records is forgotten
+const sequenceIdleTimeout = 10 * time.Minute
+
+// SequencerConfig reorders the records of each XID by sequence number before
+// they are queued, for the records submitted slightly out of order, e.g. by
+// parallel workers. A record submitted ahead of its turn is held until the
+// records numbered before it are submitted, or until HoleTimeout elapses in
+// which case the missing sequence numbers are skipped. The first record
+// submitted for an XID starts its sequence and the records numbered before
+// the sequence, e.g. delivered again after a failure, are queued right away.
+type SequencerConfig struct {
+	// HoleTimeout is the time records wait for a missing sequence number,
+	// records are queued as submitted when 0
+	HoleTimeout time.Duration
+	// MaxHeld bounds the records held per XID, the hole is skipped once
+	// more are held. Unbounded when 0.
+	MaxHeld uint32
+}
+
+// sequencedRecord is a record submitted along with its flow
+type sequencedRecord struct {
+	queuedRecord
+	flowID string
+	weight uint32
+}
+
+// xidSequence tracks the sequence of the records of an XID
+type xidSequence struct {
+	// next is the sequence number released next
+	next uint32
+	// held are the records submitted ahead of next, by sequence number
+	held map[uint32]sequencedRecord
+	// flowID is the flow the records of the XID were last submitted by
+	flowID      string
+	timer       *time.Timer
+	heldSince   time.Time
+	submittedAt time.Time
+}
+
+// sequencer holds the records submitted ahead of their sequence number.
+// It is only accessed with the queue locked.
+type sequencer struct {
+	config   SequencerConfig
+	xids     map[uuid.UUID]*xidSequence
+	prunedAt time.Time
+	// onHole is called once the records of an XID were held for the hole
+	// timeout
+	onHole func(uuid.UUID)
+}
+
+// NewSequencerConfig returns the record reordering settings of the service config
+func NewSequencerConfig(config nprobe.Config) SequencerConfig {
+	return SequencerConfig{
+		HoleTimeout: time.Duration(config.ExportReorderTimeoutMs) * time.Millisecond,
+		MaxHeld:     config.ExportReorderMaxHeld,
+	}
+}
+
+// SetSequencerConfig applies record reordering settings to the records
+// submitted from now on. The held records are released when reordering is
+// disabled.
+func (c *RecordExporter) SetSequencerConfig(config SequencerConfig) {
+	c.queue.setSequencerConfig(config)
+}
+
+func (q *fairQueue) setSequencerConfig(config SequencerConfig) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.sequencer.config = config
+	if config.HoleTimeout != 0 {
+		return
+	}
+	for _, released := range q.sequencer.flush() {
+		q.enqueue(released)
+	}
+	metrics.ExportQueueSize.Set(float64(q.queued))
+	q.cond.Signal()
+}
+
+func newSequencer(onHole func(uuid.UUID)) *sequencer {
+	return &sequencer{xids: map[uuid.UUID]*xidSequence{}, onHole: onHole}
+}
+
+// order returns the records released by the submission of a record, in
+// sequence order
+func (s *sequencer) order(r sequencedRecord) []sequencedRecord {
+	if s.config.HoleTimeout == 0 {
+		return []sequencedRecord{r}
+	}
+	hdr, err := encoding.ParsePDUHeader(r.record)
+	if err != nil {
+		return []sequencedRecord{r}
+	}
+	seqNbr, ok := encoding.GetSequenceNumber(hdr)
+	if !ok {
+		return []sequencedRecord{r}
+	}
+
+	now := time.Now()
+	s.prune(now)
+	seq, ok := s.xids[hdr.XID]
+	if !ok {
+		seq = &xidSequence{next: seqNbr, held: map[uint32]sequencedRecord{}}
+		s.xids[hdr.XID] = seq
+	}
+	seq.flowID = r.flowID
+	seq.submittedAt = now
+	switch {
+	case seqNbr < seq.next:
+		metrics.LateRecords.Inc()
+		return []sequencedRecord{r}
+	case seqNbr == seq.next:
+		seq.next++
+		return append([]sequencedRecord{r}, seq.release()...)
+	}
+
+	metrics.RecordsReordered.Inc()
+	seq.held[seqNbr] = r
+	if s.config.MaxHeld != 0 && uint32(len(seq.held)) > s.config.MaxHeld {
+		return seq.skipHole()
+	}
+	if seq.timer == nil {
+		s.startTimer(hdr.XID, seq)
+	}
+	return nil
+}
+
+// skipHole releases the records of an XID held past the hole timeout
+func (s *sequencer) skipHole(xid uuid.UUID) []sequencedRecord {
+	seq, ok := s.xids[xid]
+	// the timer may fire after the hole it was started for was filled and
+	// another hole was met, which then waits for its own timer
+	if !ok || seq.timer == nil || time.Since(seq.heldSince) < s.config.HoleTimeout {
+		return nil
+	}
+	seq.timer = nil
+	released := seq.skipHole()
+	if len(seq.held) != 0 {
+		// the records held behind another hole wait for it in turn
+		s.startTimer(xid, seq)
+	}
+	return released
+}
+
+func (s *sequencer) startTimer(xid uuid.UUID, seq *xidSequence) {
+	seq.heldSince = time.Now()
+	seq.timer = time.AfterFunc(s.config.HoleTimeout, func() { s.onHole(xid) })
+}
+
+// dropFlow forgets the sequences of the XIDs of a failed flow, so that its
+// records submitted again start over, and returns their held records
+func (s *sequencer) dropFlow(flowID string) []sequencedRecord {
+	var dropped []sequencedRecord
+	for xid, seq := range s.xids {
+		if seq.flowID != flowID {
+			continue
+		}
+		dropped = append(dropped, seq.drain()...)
+		delete(s.xids, xid)
+	}
+	return dropped
+}
+
+// flush releases all held records without waiting for the holes
+func (s *sequencer) flush() []sequencedRecord {
+	var released []sequencedRecord
+	for xid, seq := range s.xids {
+		released = append(released, seq.drain()...)
+		delete(s.xids, xid)
+	}
+	return released
+}
+
+// prune forgets the sequences of the XIDs idle for long
+func (s *sequencer) prune(now time.Time) {
+	if now.Sub(s.prunedAt) < sequenceIdleTimeout {
+		return
+	}
+	s.prunedAt = now
+	for xid, seq := range s.xids {
+		if len(seq.held) == 0 && now.Sub(seq.submittedAt) > sequenceIdleTimeout {
+			delete(s.xids, xid)
+		}
+	}
+}
+
+// release returns the held records following the released ones
+func (seq *xidSequence) release() []sequencedRecord {
+	var released []sequencedRecord
+	for {
+		r, ok := seq.held[seq.next]
+		if !ok {
+			break
+		}
+		delete(seq.held, seq.next)
+		released = append(released, r)
+		seq.next++
+	}
+	if len(seq.held) == 0 && seq.timer != nil {
+		seq.timer.Stop()
+		seq.timer = nil
+	}
+	return released
+}
+
+// skipHole skips the sequence numbers missing before the lowest held record
+// and returns the records released
+func (seq *xidSequence) skipHole() []sequencedRecord {
+	if len(seq.held) == 0 {
+		return nil
+	}
+	lowest := uint32(0)
+	first := true
+	for seqNbr := range seq.held {
+		if first || seqNbr < lowest {
+			lowest, first = seqNbr, false
+		}
+	}
+	metrics.SequenceHoles.Inc()
+	metrics.SequenceHoleRecords.Add(float64(lowest - seq.next))
+	seq.next = lowest
+	return seq.release()
+}
+
+// drain returns all held records in sequence order
+func (seq *xidSequence) drain() []sequencedRecord {
+	if seq.timer != nil {
+		seq.timer.Stop()
+		seq.timer = nil
+	}
+	seqNbrs := make([]uint32, 0, len(seq.held))
+	for seqNbr := range seq.held {
+		seqNbrs = append(seqNbrs, seqNbr)
+	}
+	sort.Slice(seqNbrs, func(i, j int) bool { return seqNbrs[i] < seqNbrs[j] })
+	released := make([]sequencedRecord, 0, len(seqNbrs))
+	for _, seqNbr := range seqNbrs {
+		released = append(released, seq.held[seqNbr])
+	}
+	seq.held = map[uint32]sequencedRecord{}
+	return released
+}
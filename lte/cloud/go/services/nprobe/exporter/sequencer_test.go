@@ -0,0 +1,115 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sequenceSender records the sequence number of each record sent
+type sequenceSender struct {
+	mutex sync.Mutex
+	sent  []uint32
+}
+
+func (s *sequenceSender) send(ctx context.Context, record []byte, correlationID uint64, retryCount uint32) error {
+	hdr, err := encoding.ParsePDUHeader(record)
+	if err != nil {
+		return err
+	}
+	seqNbr, _ := encoding.GetSequenceNumber(hdr)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sent = append(s.sent, seqNbr)
+	return nil
+}
+
+func makeSequencedRecords(t *testing.T, seqNbrs ...uint32) [][]byte {
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			CorrelationID: 1,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.AttachSuccess,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+	}
+	records := make([][]byte, 0, len(seqNbrs))
+	for _, seqNbr := range seqNbrs {
+		record, err := encoding.MakeRecord(&event, task, 1, seqNbr)
+		assert.NoError(t, err)
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestSequencer(t *testing.T) {
+	sender := &sequenceSender{}
+	q := newFairQueue(sender.send)
+	q.setSequencerConfig(SequencerConfig{HoleTimeout: time.Hour, MaxHeld: 3})
+	ctx := context.Background()
+
+	// records submitted ahead of their turn wait for the ones before them
+	first := q.submit(ctx, "task", 1, 1, makeSequencedRecords(t, 1, 3, 4), 0)
+	assert.NoError(t, first.Wait(0))
+	second := q.submit(ctx, "task", 1, 1, makeSequencedRecords(t, 2), 0)
+	assert.Equal(t, []error{nil, nil, nil}, waitAll(first))
+	assert.Equal(t, []error{nil}, waitAll(second))
+
+	// late records are exported right away
+	assert.Equal(t, []error{nil}, waitAll(q.submit(ctx, "task", 1, 1, makeSequencedRecords(t, 2), 0)))
+
+	// the hole is skipped once more records than allowed are held
+	assert.Equal(t, []error{nil, nil, nil, nil}, waitAll(q.submit(ctx, "task", 1, 1, makeSequencedRecords(t, 9, 7, 8, 10), 0)))
+	assert.Equal(t, []uint32{1, 2, 3, 4, 2, 7, 8, 9, 10}, sender.sent)
+
+	// held records are released when the queue closes
+	pending := q.submit(ctx, "task", 1, 1, makeSequencedRecords(t, 12), 0)
+	q.close()
+	assert.Equal(t, []error{nil}, waitAll(pending))
+	assert.Equal(t, uint32(12), sender.sent[len(sender.sent)-1])
+}
+
+func TestSequencerHoleTimeout(t *testing.T) {
+	sender := &sequenceSender{}
+	q := newFairQueue(sender.send)
+	defer q.close()
+	q.setSequencerConfig(SequencerConfig{HoleTimeout: 20 * time.Millisecond})
+	ctx := context.Background()
+
+	assert.Equal(t, []error{nil}, waitAll(q.submit(ctx, "task", 1, 1, makeSequencedRecords(t, 1), 0)))
+	// the missing sequence number 2 is skipped after the timeout, then 4
+	delivery := q.submit(ctx, "task", 1, 1, makeSequencedRecords(t, 5, 3), 0)
+	assert.Equal(t, []error{nil, nil}, waitAll(delivery))
+	assert.Equal(t, []uint32{1, 3, 5}, sender.sent)
+
+	// records are exported as submitted once reordering is disabled
+	q.setSequencerConfig(SequencerConfig{})
+	assert.Equal(t, []error{nil, nil}, waitAll(q.submit(ctx, "task", 1, 1, makeSequencedRecords(t, 8, 7), 0)))
+	assert.Equal(t, []uint32{1, 3, 5, 8, 7}, sender.sent)
+}
@@ -0,0 +1,963 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogf/gf/net/gtcp"
+)
+
+const (
+	// keepaliveMissedLimit is the number of keepalive intervals without
+	// acknowledgement after which the connection is considered dead
+	keepaliveMissedLimit = 3
+	// reconnectMinBackoff is the delay before dialing again after a first
+	// failure, doubled on each consecutive failure
+	reconnectMinBackoff = 500 * time.Millisecond
+	// headerNegotiationTimeout is the time the keepalive offering a header
+	// version waits for its acknowledgement without acknowledged delivery
+	headerNegotiationTimeout = 5 * time.Second
+)
+
+var (
+	errAckTimeout       = &DeliveryError{Class: FailureAckTimeout, Err: errors.New("timed out waiting for record acknowledgement")}
+	errConnectionClosed = &DeliveryError{Class: FailureNack, Err: errors.New("connection closed before the record was acknowledged")}
+	errInvalidAddress   = &DeliveryError{Class: FailureConnect, Err: errors.New("Invalid remote address")}
+)
+
+// TLSBackendConfig holds the HI2 channel settings of the tls backend
+type TLSBackendConfig struct {
+	// KeepaliveInterval is the time between keepalives, 0 disables them
+	KeepaliveInterval time.Duration
+	// AckTimeout enables acknowledged delivery when not 0. Records are then
+	// delivered only once acknowledged by the LEMF within this time.
+	AckTimeout time.Duration
+	// PoolSize is the number of connections kept established, the ones
+	// beyond the first standing by to replace it when it fails
+	PoolSize int
+	// MaxReconnectBackoff bounds the jittered delay between failed attempts
+	// to connect, 0 dialing on each record
+	MaxReconnectBackoff time.Duration
+	// Framing delimits the records written on the connections, unless the
+	// handshake settings override it
+	Framing string
+	// Encoding is the encoding of the BER elements of the frames, unless the
+	// handshake settings override it
+	Encoding string
+	// OperatorID identifies the network of the records framed as PS-PDUs
+	OperatorID uint32
+	// HeaderVersion is the header version of the PDUs written on the
+	// connections, the newest one offered when negotiating, unless the
+	// handshake settings override it
+	HeaderVersion uint16
+	// NegotiateHeaderVersion offers the header version in a keepalive on
+	// each new connection, adopting the version of its acknowledgement. The
+	// next connections offer the older versions while it isn't acknowledged.
+	NegotiateHeaderVersion bool
+	// WriteTimeout is the deadline of each write on the connections, unless
+	// the handshake settings override it. A write past its deadline closes
+	// the connection. Writes block until the kernel takes them when 0.
+	WriteTimeout time.Duration
+	// Transport is the transport of the connections, TransportTCP when
+	// empty, unless the handshake settings override it
+	Transport string
+	// SCTPLocalAddrs are the local addresses the SCTP associations are bound
+	// to, any when empty
+	SCTPLocalAddrs []string
+	// SCTPSecurity states how the SCTP associations are protected, as they
+	// aren't encrypted. Associations are refused when it isn't set.
+	SCTPSecurity string
+	// MaxRecordSize is the size limit of the PDUs of the LEMF, the larger
+	// records being segmented. Records aren't segmented when 0.
+	MaxRecordSize int
+}
+
+// TLSBackend sends records to a remote host over tcp/tls. Keepalives are
+// exchanged on the connection as defined in ETSI TS 103 221-2 and, with
+// acknowledged delivery, the LEMF acknowledges each record with a keepalive
+// acknowledgement echoing the XID, correlation ID and sequence number of
+// the record.
+// Records are written in the framing of the connection, keepalives and
+// acknowledgements being exchanged only when it carries TS 103 221-2 PDUs.
+// The header version of the PDUs may be negotiated with the LEMF, so that
+// gateways and LEMFs of different versions keep exchanging records.
+// Records beyond the size limit of the LEMF are written as segments, which
+// the LEMF acknowledges once the record is reassembled.
+// Connections may instead be SCTP associations, multi-homed on the
+// addresses of the delivery function and carrying the records in plaintext,
+// their security being left to IPsec or a DTLS gateway.
+// Records are sent on a single connection so that they arrive in order. The
+// standby connections of the pool take over when it fails, and are replaced
+// in the background. Failed attempts to connect are retried after a jittered
+// exponential backoff, so that instances don't reconnect all at once.
+type TLSBackend struct {
+	tlsConfig  *tls.Config
+	config     TLSBackendConfig
+	handshake  HandshakeSettings
+	session    *hi2Session
+	standby    []*hi2Session
+	remoteAddr string
+	// logger logs the lines of the connections with the destination field
+	logger *logging.Logger
+	mutex  sync.Mutex
+
+	// generation changes when the connections are closed, discarding the
+	// standby connections being established
+	generation uint64
+	filling    bool
+
+	dialFailures int
+	nextDialAt   time.Time
+	lastDialErr  error
+
+	// headerVersion is the header version offered on the next connections
+	// when negotiating, the configured one when 0. It is accessed atomically.
+	headerVersion uint32
+}
+
+// connSettings are the framing settings of a connection
+type connSettings struct {
+	transport     string
+	framing       string
+	berEncoding   string
+	headerVersion uint16
+	negotiate     bool
+	writeTimeout  time.Duration
+}
+
+// ackKey identifies the record acknowledged by the LEMF
+type ackKey struct {
+	xid    uuid.UUID
+	corrID uint64
+	seqNbr uint32
+}
+
+// hi2Session holds the keepalive and acknowledgement state of a connection
+type hi2Session struct {
+	conn      *gtcp.Conn
+	handshake *HandshakeInfo
+	framer    *encoding.Framer
+	// destination is the address of the delivery function, selecting the
+	// faults injected in the connection
+	destination string
+	// compressor compresses the PDUs written, nil when the delivery
+	// function selected no compression
+	compressor *compressor
+	// writeTimeout is the deadline of each write, none when 0
+	writeTimeout time.Duration
+	done         chan struct{}
+	closeOnce    sync.Once
+	// connectedAt is the time the connection was established and bytesSent
+	// the bytes written on it, after compression
+	connectedAt time.Time
+	bytesSent   uint64
+
+	mutex            sync.Mutex
+	keepaliveSeqNbr  uint32
+	lastKeepaliveAck time.Time
+	pendingAcks      map[ackKey]chan struct{}
+}
+
+// NewTLSBackend creates a new tls backend and attempt to establish a connection at start
+func NewTLSBackend(remoteAddr string, tlsConfig *tls.Config, config TLSBackendConfig) *TLSBackend {
+	client := &TLSBackend{
+		tlsConfig:  tlsConfig,
+		config:     config,
+		remoteAddr: NormalizeAddress(remoteAddr),
+		logger:     logger.With(logging.FieldDestination, NormalizeAddress(remoteAddr)),
+	}
+	_, err := client.getSession() // attempt to establish connection at start
+	if err != nil {
+		client.logger.Errorf(
+			"Failed to establish new TLS connection from to '%s'; error: %v, will retry later.",
+			remoteAddr, err)
+	}
+	return client
+}
+
+// Send sends a single message on the connection. If the connection is
+// not established, this establishes it. If the message sending fails, the
+// connection is closed. With acknowledged delivery, Send returns once the
+// record is acknowledged and fails if it isn't within the ack timeout.
+func (c *TLSBackend) Send(message []byte, correlationID uint64) error {
+	return c.SendAsync(message, correlationID).Wait()
+}
+
+// SendAsync sends a single message on the connection like Send, without
+// waiting for its acknowledgement. The ack timeout of the record starts once
+// it is written, so that the records written behind it don't delay it.
+func (c *TLSBackend) SendAsync(message []byte, correlationID uint64) *PendingRecord {
+	session, err := c.getSession()
+	if err != nil {
+		return settledRecord(err)
+	}
+
+	framed, err := session.framer.Frame(message)
+	if err != nil {
+		return settledRecord(newDeliveryError(FailureEncode, err))
+	}
+	var key ackKey
+	var acked chan struct{}
+	if c.config.AckTimeout > 0 && session.framer.CarriesPDUs() {
+		key, err = getRecordAckKey(message)
+		if err != nil {
+			return settledRecord(newDeliveryError(FailureEncode, err))
+		}
+		acked = session.expectAck(key)
+	}
+
+	// It's possible that the connection is closed here in contention for the
+	// connection. This is handled as an error and the sending can retry
+	err = session.send(framed)
+	if err != nil {
+		if acked != nil {
+			session.cancelAck(key)
+		}
+		// write failed, close and cleanup connection
+		c.destroySession(session)
+		return settledRecord(newDeliveryError(classifyWriteError(err), err))
+	}
+	if acked == nil {
+		return settledRecord(nil)
+	}
+
+	deadline := time.Now().Add(c.config.AckTimeout)
+	return newPendingRecord(func() error {
+		defer session.cancelAck(key)
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		select {
+		case <-acked:
+			return nil
+		case <-session.done:
+			return errConnectionClosed
+		case <-timer.C:
+			metrics.AckTimeouts.Inc()
+			return errAckTimeout
+		}
+	})
+}
+
+// SendBatch sends records in a single write on the connection. With
+// acknowledged delivery, each record is delivered once acknowledged, all the
+// records of the batch being acknowledged within the same ack timeout.
+func (c *TLSBackend) SendBatch(records []BatchRecord) []error {
+	errs := make([]error, len(records))
+	session, err := c.getSession()
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	framed := make([][]byte, len(records))
+	size := 0
+	for i, r := range records {
+		if framed[i], err = session.framer.Frame(r.Record); err != nil {
+			errs[i] = newDeliveryError(FailureEncode, err)
+			continue
+		}
+		size += len(framed[i])
+	}
+	var acks []chan struct{}
+	if c.config.AckTimeout > 0 && session.framer.CarriesPDUs() {
+		acks = make([]chan struct{}, len(records))
+		for i, r := range records {
+			if errs[i] != nil {
+				continue
+			}
+			key, err := getRecordAckKey(r.Record)
+			if err != nil {
+				errs[i] = newDeliveryError(FailureEncode, err)
+				continue
+			}
+			acks[i] = session.expectAck(key)
+			defer session.cancelAck(key)
+		}
+	}
+
+	message := make([]byte, 0, size)
+	for i := range records {
+		if errs[i] == nil {
+			message = append(message, framed[i]...)
+		}
+	}
+	err = session.send(message)
+	if err != nil {
+		// write failed, close and cleanup connection
+		c.destroySession(session)
+		err = newDeliveryError(classifyWriteError(err), err)
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+		return errs
+	}
+	if acks == nil {
+		return errs
+	}
+
+	timer := time.NewTimer(c.config.AckTimeout)
+	defer timer.Stop()
+	expired := false
+	for i, acked := range acks {
+		if acked == nil {
+			continue
+		}
+		if expired {
+			select {
+			case <-acked:
+			default:
+				metrics.AckTimeouts.Inc()
+				errs[i] = errAckTimeout
+			}
+			continue
+		}
+		select {
+		case <-acked:
+		case <-session.done:
+			errs[i] = errConnectionClosed
+		case <-timer.C:
+			expired = true
+			metrics.AckTimeouts.Inc()
+			errs[i] = errAckTimeout
+		}
+	}
+	return errs
+}
+
+// Close closes the connections to the remote host if any. A new connection
+// is established on the next message sent.
+func (c *TLSBackend) Close() {
+	c.mutex.Lock()
+	sessions := c.resetSessions()
+	c.mutex.Unlock()
+	for _, session := range sessions {
+		session.close()
+	}
+}
+
+// resetSessions detaches the current and standby connections and returns
+// them to be closed. The caller holds the mutex.
+func (c *TLSBackend) resetSessions() []*hi2Session {
+	sessions := c.standby
+	if c.session != nil {
+		sessions = append(sessions, c.session)
+	}
+	c.session, c.standby = nil, nil
+	c.generation++
+	return sessions
+}
+
+// IsConnected returns true if a connection to the remote host is established
+func (c *TLSBackend) IsConnected() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.session != nil
+}
+
+// SetHandshakeSettings customizes the SNI and ALPN of the next handshakes.
+// When the settings change, the current connection is closed so that they
+// apply right away.
+func (c *TLSBackend) SetHandshakeSettings(settings HandshakeSettings) {
+	c.mutex.Lock()
+	if reflect.DeepEqual(c.handshake, settings) {
+		c.mutex.Unlock()
+		return
+	}
+	c.handshake = settings
+	sessions := c.resetSessions()
+	atomic.StoreUint32(&c.headerVersion, 0)
+	c.mutex.Unlock()
+	if len(sessions) != 0 {
+		c.logger.Infof("Reconnecting to %s with new handshake settings", c.remoteAddr)
+	}
+	for _, session := range sessions {
+		session.close()
+	}
+}
+
+// GetHandshake describes the handshake of the current connection, nil when
+// not connected
+func (c *TLSBackend) GetHandshake() *HandshakeInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.session == nil {
+		return nil
+	}
+	return c.session.handshake
+}
+
+// Probe completes a handshake with the delivery function on a new
+// connection, closed right away
+func (c *TLSBackend) Probe(timeout time.Duration) (*HandshakeInfo, error) {
+	c.mutex.Lock()
+	addr, tlsConfig := c.remoteAddr, applyHandshakeSettings(c.tlsConfig, c.handshake)
+	transport := c.getConnSettings().transport
+	c.mutex.Unlock()
+	if len(addr) == 0 {
+		return nil, errInvalidAddress
+	}
+	if transport == TransportSCTP {
+		return nil, ErrProbeUnsupported
+	}
+	return probeTLS(addr, tlsConfig, timeout)
+}
+
+// GetConnection describes the current delivery connection, only its address
+// when not connected
+func (c *TLSBackend) GetConnection() *ConnectionInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	info := &ConnectionInfo{Address: c.remoteAddr, StandbyConnections: len(c.standby)}
+	if c.session == nil {
+		return info
+	}
+	info.PeerAddress = c.session.conn.RemoteAddr().String()
+	info.Handshake = c.session.handshake
+	if c.session.compressor != nil {
+		info.Compression = c.session.compressor.compression
+	}
+	info.Acknowledged = c.config.AckTimeout > 0 && c.session.framer.CarriesPDUs()
+	info.ConnectedAt = c.session.connectedAt
+	info.BytesSent = atomic.LoadUint64(&c.session.bytesSent)
+	return info
+}
+
+// Reconnect closes the current and standby connections and establishes a
+// new connection right away, even while backing off from a failed attempt
+func (c *TLSBackend) Reconnect() error {
+	c.mutex.Lock()
+	sessions := c.resetSessions()
+	c.dialFailures, c.nextDialAt = 0, time.Time{}
+	c.mutex.Unlock()
+	c.logger.Infof("Reconnecting to %s on request", c.remoteAddr)
+	for _, session := range sessions {
+		session.close()
+	}
+	return c.Connect()
+}
+
+// Connect establishes the delivery connection and its standby connections
+// if not connected yet
+func (c *TLSBackend) Connect() error {
+	_, err := c.getSession()
+	return err
+}
+
+// getSession returns the existing session, or promotes a standby connection
+// or dials and initializes a connection if it doesn't exist. No connection is
+// dialed while backing off from a failed attempt.
+func (c *TLSBackend) getSession() (*hi2Session, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.session != nil {
+		return c.session, nil
+	}
+	if len(c.remoteAddr) == 0 {
+		return nil, errInvalidAddress
+	}
+
+	for len(c.standby) != 0 {
+		session := c.standby[0]
+		c.standby = c.standby[1:]
+		if !session.isClosed() {
+			c.session = session
+			go c.fillPool()
+			return session, nil
+		}
+	}
+
+	now := time.Now()
+	if now.Before(c.nextDialAt) {
+		// the failure is of the class of the last attempt, e.g. a handshake
+		// still failing
+		return nil, newDeliveryError(
+			ClassifyError(c.lastDialErr),
+			fmt.Errorf("reconnection to %s backing off until %s: %v", c.remoteAddr, c.nextDialAt.Format(time.RFC3339Nano), c.lastDialErr),
+		)
+	}
+	session, err := c.dial(c.remoteAddr, applyHandshakeSettings(c.tlsConfig, c.handshake), c.getConnSettings())
+	if err != nil {
+		c.dialFailures++
+		c.lastDialErr = err
+		c.nextDialAt = now.Add(getReconnectBackoff(c.dialFailures, c.config.MaxReconnectBackoff))
+		return nil, err
+	}
+	c.dialFailures = 0
+	c.session = session
+	go c.fillPool()
+	return session, nil
+}
+
+// getConnSettings returns the transport, the framing, the BER encoding, the
+// header version and the write deadline of the next connections. When negotiating, the
+// header version is the one last negotiated or offered. The caller holds the
+// mutex.
+func (c *TLSBackend) getConnSettings() connSettings {
+	settings := connSettings{
+		transport:     c.config.Transport,
+		framing:       c.config.Framing,
+		berEncoding:   c.config.Encoding,
+		headerVersion: c.config.HeaderVersion,
+		negotiate:     c.config.NegotiateHeaderVersion || c.handshake.NegotiateHeaderVersion,
+		writeTimeout:  c.config.WriteTimeout,
+	}
+	if len(c.handshake.Framing) != 0 {
+		settings.framing = c.handshake.Framing
+	}
+	if len(c.handshake.Encoding) != 0 {
+		settings.berEncoding = c.handshake.Encoding
+	}
+	if c.handshake.HeaderVersion != 0 {
+		settings.headerVersion = c.handshake.HeaderVersion
+	}
+	if c.handshake.WriteTimeout != 0 {
+		settings.writeTimeout = c.handshake.WriteTimeout
+	}
+	if version := atomic.LoadUint32(&c.headerVersion); settings.negotiate && version != 0 {
+		settings.headerVersion = uint16(version)
+	}
+	if len(c.handshake.Transport) != 0 {
+		settings.transport = c.handshake.Transport
+	}
+	if settings.transport == TransportSCTP {
+		// SCTP associations don't support write deadlines, aborting on their
+		// own once their retransmissions are exhausted on all their paths
+		settings.writeTimeout = 0
+	}
+	return settings
+}
+
+// dial establishes a new connection, negotiates its header version and
+// starts its keepalives
+func (c *TLSBackend) dial(addr string, tlsConfig *tls.Config, settings connSettings) (*hi2Session, error) {
+	framer, err := encoding.NewFramer(settings.framing, c.config.OperatorID)
+	if err != nil {
+		return nil, newDeliveryError(FailureEncode, err)
+	}
+	if err = framer.SetEncoding(settings.berEncoding); err != nil {
+		return nil, newDeliveryError(FailureEncode, err)
+	}
+	if err = framer.SetHeaderVersion(settings.headerVersion); err != nil {
+		return nil, newDeliveryError(FailureEncode, err)
+	}
+	if err = framer.SetMaxRecordSize(c.config.MaxRecordSize); err != nil {
+		return nil, newDeliveryError(FailureEncode, err)
+	}
+	conn, err := c.connect(addr, tlsConfig, settings.transport)
+	if err != nil {
+		return nil, err
+	}
+	metrics.TLSReconnects.Inc()
+	var handshake *HandshakeInfo
+	if tlsConn, ok := conn.Conn.(*tls.Conn); ok {
+		handshake = getHandshakeInfo(tlsConn.ConnectionState())
+		if handshake.Resumed {
+			metrics.TLSResumptions.Inc()
+		}
+		if len(handshake.PeerSubject) != 0 {
+			health.Certificates().Observe(health.Certificate{
+				Kind:     health.CertificateServer,
+				Name:     NormalizeAddress(addr),
+				Subject:  handshake.PeerSubject,
+				NotAfter: handshake.PeerNotAfter,
+			})
+		}
+	}
+	session := &hi2Session{
+		conn:             conn,
+		destination:      NormalizeAddress(addr),
+		handshake:        handshake,
+		framer:           framer,
+		writeTimeout:     settings.writeTimeout,
+		done:             make(chan struct{}),
+		lastKeepaliveAck: time.Now(),
+		pendingAcks:      map[ackKey]chan struct{}{},
+		connectedAt:      time.Now(),
+	}
+	if handshake != nil {
+		if compression := getNegotiatedCompression(handshake.NegotiatedProtocol); len(compression) != 0 {
+			session.compressor = newCompressor(compression, connWriter{conn: conn, sent: &session.bytesSent})
+		}
+	}
+	if settings.negotiate && framer.CarriesPDUs() {
+		if err = c.negotiateHeaderVersion(session); err != nil {
+			session.close()
+			return nil, err
+		}
+	}
+	go c.receive(session)
+	if handshake != nil {
+		handshake.HeaderVersion = framer.HeaderVersion()
+	}
+	if c.config.KeepaliveInterval > 0 && framer.CarriesPDUs() {
+		go c.keepalive(session)
+	}
+	return session, nil
+}
+
+// connect establishes a new connection over a transport: a tls connection,
+// or an SCTP association in plaintext
+func (c *TLSBackend) connect(addr string, tlsConfig *tls.Config, transport string) (*gtcp.Conn, error) {
+	if transport != TransportSCTP {
+		conn, err := gtcp.NewConnTLS(addr, tlsConfig)
+		if err != nil {
+			return nil, newDeliveryError(classifyDialError(err), err)
+		}
+		return conn, nil
+	}
+	if err := ValidateTransport(transport, c.config.SCTPSecurity); err != nil {
+		return nil, newDeliveryError(FailureConnect, err)
+	}
+	conn, err := dialSCTP(addr, c.config.SCTPLocalAddrs)
+	if err != nil {
+		return nil, newDeliveryError(FailureConnect, err)
+	}
+	return gtcp.NewConnByNetConn(conn), nil
+}
+
+// negotiateHeaderVersion offers the header version of a new connection in a
+// keepalive, and adopts the version of its acknowledgement if older. The
+// acknowledgement is read before the PDUs of the connection are received.
+// When it doesn't come, e.g. from a LEMF rejecting the PDUs of a newer
+// version, the next connections offer the previous version.
+func (c *TLSBackend) negotiateHeaderVersion(session *hi2Session) error {
+	offered := session.framer.HeaderVersion()
+	timeout := c.config.AckTimeout
+	if timeout <= 0 {
+		timeout = headerNegotiationTimeout
+	}
+	version, err := session.exchangeKeepalive(timeout)
+	if err != nil {
+		// the oldest version not acknowledged either, the configured one is
+		// offered again
+		older, _ := encoding.GetOlderHeaderVersion(offered)
+		atomic.StoreUint32(&c.headerVersion, uint32(older))
+		c.logger.Errorf("Header version %d not acknowledged by %s: %v", offered, c.remoteAddr, err)
+		return err
+	}
+	if encoding.IsHeaderVersionSupported(version) && version < offered {
+		session.framer.SetHeaderVersion(version)
+	}
+	atomic.StoreUint32(&c.headerVersion, uint32(session.framer.HeaderVersion()))
+	c.logger.V(2).Infof("Negotiated header version %d with %s", session.framer.HeaderVersion(), c.remoteAddr)
+	return nil
+}
+
+// fillPool establishes standby connections until the pool holds PoolSize
+// connections. A failure is retried when a standby connection is next
+// promoted or a new connection is established.
+func (c *TLSBackend) fillPool() {
+	c.mutex.Lock()
+	if c.filling || c.config.PoolSize <= 1 {
+		c.mutex.Unlock()
+		return
+	}
+	c.filling = true
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		c.filling = false
+		c.mutex.Unlock()
+	}()
+
+	for {
+		c.mutex.Lock()
+		if c.session == nil || 1+len(c.standby) >= c.config.PoolSize {
+			c.mutex.Unlock()
+			return
+		}
+		generation, addr, tlsConfig := c.generation, c.remoteAddr, applyHandshakeSettings(c.tlsConfig, c.handshake)
+		settings := c.getConnSettings()
+		c.mutex.Unlock()
+
+		session, err := c.dial(addr, tlsConfig, settings)
+		if err != nil {
+			c.logger.Errorf("Failed to establish standby connection to %s: %v", addr, err)
+			return
+		}
+		c.mutex.Lock()
+		if generation != c.generation {
+			// the connections were closed meanwhile
+			c.mutex.Unlock()
+			session.close()
+			return
+		}
+		c.standby = append(c.standby, session)
+		c.mutex.Unlock()
+	}
+}
+
+// getReconnectBackoff returns the delay before dialing again after
+// consecutive failures, drawn between half and all of an exponential backoff
+// bounded by maxBackoff
+func getReconnectBackoff(failures int, maxBackoff time.Duration) time.Duration {
+	if maxBackoff <= 0 {
+		return 0
+	}
+	backoff := maxBackoff
+	if failures < 32 && reconnectMinBackoff<<uint(failures-1) < maxBackoff {
+		backoff = reconnectMinBackoff << uint(failures-1)
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// destroySession closes a bad connection. If the session passed is the
+// current one or a standby one, it is removed. Otherwise another go routine
+// probably already created a new connection - just try to close it and return.
+func (c *TLSBackend) destroySession(session *hi2Session) {
+	if session == nil {
+		return
+	}
+	c.mutex.Lock()
+	if session == c.session {
+		c.session = nil
+	}
+	for i, standby := range c.standby {
+		if standby == session {
+			c.standby = append(c.standby[:i:i], c.standby[i+1:]...)
+			break
+		}
+	}
+	c.mutex.Unlock()
+	session.close()
+}
+
+// receive processes the PDUs sent by the LEMF until the connection is
+// closed. The frames of the framings not carrying PDUs are ignored.
+func (c *TLSBackend) receive(session *hi2Session) {
+	for {
+		pdu, err := session.framer.ReadFrame(session.conn, encoding.DefaultMaxRecordSize)
+		if err == nil {
+			err = injectReadFault(session.destination)
+		}
+		if err != nil {
+			if !session.isClosed() {
+				c.logger.Errorf("Failed to read from %s: %v", c.remoteAddr, err)
+				c.destroySession(session)
+			}
+			return
+		}
+		if !session.framer.CarriesPDUs() {
+			c.logger.V(2).Infof("Ignoring %s frame from %s", session.framer.Framing(), c.remoteAddr)
+			continue
+		}
+
+		hdr, err := encoding.ParsePDUHeader(pdu)
+		if err != nil {
+			c.logger.Errorf("Failed to parse PDU header from %s: %v", c.remoteAddr, err)
+			continue
+		}
+		seqNbr, _ := encoding.GetSequenceNumber(hdr)
+		switch hdr.PduType {
+		case encoding.HeaderPduTypeKeepalive:
+			if err := session.sendPDU(encoding.MakeKeepaliveAck(hdr)); err != nil {
+				c.logger.Errorf("Failed to acknowledge keepalive from %s: %v", c.remoteAddr, err)
+			}
+		case encoding.HeaderPduTypeKeepaliveAck:
+			if hdr.XID == uuid.Nil {
+				session.keepaliveAcked()
+			} else {
+				session.ack(injectAckFault(session.destination, ackKey{xid: hdr.XID, corrID: hdr.CorrelationID, seqNbr: seqNbr}))
+			}
+		default:
+			c.logger.V(2).Infof("Ignoring PDU of type %d from %s", hdr.PduType, c.remoteAddr)
+		}
+	}
+}
+
+// keepalive sends keepalives until the connection is closed. The connection
+// is closed when keepalives are not acknowledged anymore.
+func (c *TLSBackend) keepalive(session *hi2Session) {
+	ticker := time.NewTicker(c.config.KeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-session.done:
+			return
+		case <-ticker.C:
+		}
+
+		if session.sinceKeepaliveAck() > keepaliveMissedLimit*c.config.KeepaliveInterval {
+			c.logger.Errorf("Keepalives to %s not acknowledged, closing connection", c.remoteAddr)
+			metrics.KeepaliveFailures.Inc()
+			c.destroySession(session)
+			return
+		}
+		if err := session.sendPDU(encoding.MakeKeepalive(session.nextKeepaliveSeqNbr())); err != nil {
+			c.logger.Errorf("Failed to send keepalive to %s: %v", c.remoteAddr, err)
+			c.destroySession(session)
+			return
+		}
+	}
+}
+
+// sendPDU writes a PDU on the connection in its framing
+func (s *hi2Session) sendPDU(pdu []byte) error {
+	framed, err := s.framer.Frame(pdu)
+	if err != nil {
+		return err
+	}
+	return s.send(framed)
+}
+
+// send writes framed PDUs on the connection, compressed if negotiated,
+// within the write deadline. The faults injected in the connection apply
+// before.
+func (s *hi2Session) send(b []byte) error {
+	if n, err := injectWriteFaults(s.destination, atomic.LoadUint64(&s.bytesSent), len(b)); err != nil {
+		if n > 0 {
+			s.conn.Send(b[:n])
+		}
+		s.close()
+		return err
+	}
+	if s.writeTimeout > 0 {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(s.writeTimeout)); err != nil {
+			return err
+		}
+	}
+	if s.compressor != nil {
+		return s.compressor.send(b)
+	}
+	if err := s.conn.Send(b); err != nil {
+		return err
+	}
+	atomic.AddUint64(&s.bytesSent, uint64(len(b)))
+	return nil
+}
+
+func (s *hi2Session) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.conn.Close()
+	})
+}
+
+func (s *hi2Session) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *hi2Session) nextKeepaliveSeqNbr() uint32 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keepaliveSeqNbr++
+	return s.keepaliveSeqNbr
+}
+
+func (s *hi2Session) keepaliveAcked() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastKeepaliveAck = time.Now()
+}
+
+// exchangeKeepalive sends a keepalive and returns the header version of its
+// acknowledgement, received within timeout. The keepalives of the LEMF are
+// acknowledged meanwhile.
+func (s *hi2Session) exchangeKeepalive(timeout time.Duration) (uint16, error) {
+	if err := s.sendPDU(encoding.MakeKeepalive(s.nextKeepaliveSeqNbr())); err != nil {
+		return 0, newDeliveryError(classifyWriteError(err), err)
+	}
+	s.conn.SetReadDeadline(time.Now().Add(timeout))
+	defer s.conn.SetReadDeadline(time.Time{})
+	for {
+		pdu, err := s.framer.ReadFrame(s.conn, encoding.DefaultMaxRecordSize)
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return 0, &DeliveryError{Class: FailureAckTimeout, Err: errors.New("timed out waiting for the keepalive acknowledgement")}
+		}
+		if err != nil {
+			return 0, &DeliveryError{Class: FailureNack, Err: fmt.Errorf("connection closed before the keepalive was acknowledged: %v", err)}
+		}
+		hdr, err := encoding.ParsePDUHeader(pdu)
+		if err != nil {
+			return 0, newDeliveryError(FailureNack, err)
+		}
+		switch hdr.PduType {
+		case encoding.HeaderPduTypeKeepalive:
+			if err := s.sendPDU(encoding.MakeKeepaliveAck(hdr)); err != nil {
+				return 0, newDeliveryError(classifyWriteError(err), err)
+			}
+		case encoding.HeaderPduTypeKeepaliveAck:
+			if hdr.XID == uuid.Nil {
+				s.keepaliveAcked()
+				return hdr.Version, nil
+			}
+		}
+	}
+}
+
+func (s *hi2Session) sinceKeepaliveAck() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return time.Since(s.lastKeepaliveAck)
+}
+
+// expectAck registers a record waiting for acknowledgement
+func (s *hi2Session) expectAck(key ackKey) chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	acked := make(chan struct{})
+	s.pendingAcks[key] = acked
+	return acked
+}
+
+func (s *hi2Session) cancelAck(key ackKey) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.pendingAcks, key)
+}
+
+// ack marks a record as delivered. Unexpected acknowledgements,
+// e.g. received after the ack timeout, are ignored.
+func (s *hi2Session) ack(key ackKey) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if acked, ok := s.pendingAcks[key]; ok {
+		close(acked)
+		delete(s.pendingAcks, key)
+	}
+}
+
+// getRecordAckKey returns the key identifying the acknowledgement of a record
+func getRecordAckKey(record []byte) (ackKey, error) {
+	hdr, err := encoding.ParsePDUHeader(record)
+	if err != nil {
+		return ackKey{}, err
+	}
+	seqNbr, _ := encoding.GetSequenceNumber(hdr)
+	return ackKey{xid: hdr.XID, corrID: hdr.CorrelationID, seqNbr: seqNbr}, nil
+}
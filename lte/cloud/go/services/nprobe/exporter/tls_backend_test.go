@@ -0,0 +1,398 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/asn1"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listenLEMF returns the listener of a tls server
+func listenLEMF(t *testing.T) net.Listener {
+	dir, err := ioutil.TempDir("", "nprobe_tls_backend")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	crtFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeCertificate(t, crtFile, keyFile, 1)
+	serverCert, err := tls.LoadX509KeyPair(crtFile, keyFile)
+	assert.NoError(t, err)
+
+	// TLS 1.2 issues the session ticket within the handshake
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		MaxVersion:   tls.VersionTLS12,
+	})
+	assert.NoError(t, err)
+	return listener
+}
+
+// startLEMF starts a tls server reading connections until they are closed,
+// returning the number of connections accepted so far
+func startLEMF(t *testing.T) (net.Listener, func() int) {
+	listener := listenLEMF(t)
+	accepted := make(chan struct{}, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go func() {
+				ioutil.ReadAll(conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return listener, func() int { return len(accepted) }
+}
+
+// startFramingLEMF starts a tls server reading the frames of a framing,
+// delimited independently of the framer, and acknowledging the records of
+// the framings carrying PDUs. Only the PDUs of a header version are
+// acknowledged, unless it is 0.
+func startFramingLEMF(t *testing.T, framing string, headerVersion uint16) (net.Listener, chan []byte) {
+	listener := listenLEMF(t)
+	frames := make(chan []byte, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					frame, err := readTestFrame(r, framing)
+					if err != nil {
+						return
+					}
+					frames <- frame
+					if framing != encoding.FramingNative && framing != encoding.FramingLengthPrefixed {
+						continue
+					}
+					hdr, err := encoding.ParsePDUHeader(frame)
+					assert.NoError(t, err)
+					if headerVersion != 0 && hdr.Version != headerVersion {
+						continue
+					}
+					ack := encoding.MakeKeepaliveAck(hdr)
+					if framing == encoding.FramingLengthPrefixed {
+						prefix := make([]byte, 4)
+						binary.BigEndian.PutUint32(prefix, uint32(len(ack)))
+						ack = append(prefix, ack...)
+					}
+					conn.Write(ack)
+				}
+			}()
+		}
+	}()
+	return listener, frames
+}
+
+// readTestFrame reads a frame as the LEMF of a framing, the length prefix
+// being left out
+func readTestFrame(r *bufio.Reader, framing string) ([]byte, error) {
+	var head []byte
+	var n int
+	switch framing {
+	case encoding.FramingNative:
+		// the header and payload lengths follow the version and PDU type
+		head = make([]byte, 12)
+		if _, err := io.ReadFull(r, head); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint32(head[4:8])) + int(binary.BigEndian.Uint32(head[8:12])) - len(head)
+	case encoding.FramingLengthPrefixed:
+		prefix := make([]byte, 4)
+		if _, err := io.ReadFull(r, prefix); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint32(prefix))
+	default:
+		// a low tag number and a definite length
+		head = make([]byte, 2)
+		if _, err := io.ReadFull(r, head); err != nil {
+			return nil, err
+		}
+		n = int(head[1])
+		if head[1]&0x80 != 0 {
+			length := make([]byte, head[1]&0x7f)
+			if _, err := io.ReadFull(r, length); err != nil {
+				return nil, err
+			}
+			head = append(head, length...)
+			n = 0
+			for _, c := range length {
+				n = n<<8 | int(c)
+			}
+		}
+	}
+	frame := make([]byte, len(head)+n)
+	copy(frame, head)
+	_, err := io.ReadFull(r, frame[len(head):])
+	return frame, err
+}
+
+func TestFraming(t *testing.T) {
+	record := makeSequencedRecords(t, 7)[0]
+	hdr, err := encoding.ParsePDUHeader(record)
+	assert.NoError(t, err)
+	payload := record[hdr.HeaderLength:]
+
+	for _, framing := range []string{encoding.FramingNative, encoding.FramingLengthPrefixed, encoding.FramingPSPDU, encoding.FramingRawBER} {
+		listener, frames := startFramingLEMF(t, framing, 0)
+		// records are acknowledged only with the framings carrying PDUs
+		backend := NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{
+			AckTimeout: time.Second,
+			Framing:    framing,
+			OperatorID: 1,
+		})
+		assert.NoError(t, backend.Send(record, 1), framing)
+		frame := <-frames
+		switch framing {
+		case encoding.FramingNative, encoding.FramingLengthPrefixed:
+			assert.Equal(t, record, frame)
+		case encoding.FramingPSPDU:
+			var pdu struct {
+				PSHeader asn1.RawValue
+				Payload  asn1.RawValue
+			}
+			_, err := asn1.Unmarshal(frame, &pdu)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, pdu.PSHeader.Tag)
+			assert.Equal(t, 2, pdu.Payload.Tag)
+			assert.True(t, bytes.Contains(pdu.Payload.Bytes, payload))
+		case encoding.FramingRawBER:
+			assert.Equal(t, payload, frame)
+		}
+		backend.Close()
+		listener.Close()
+	}
+
+	// the framing of the handshake settings overrides the one of the config
+	listener, frames := startFramingLEMF(t, encoding.FramingRawBER, 0)
+	defer listener.Close()
+	backend := NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{Framing: encoding.FramingLengthPrefixed})
+	defer backend.Close()
+	backend.SetHandshakeSettings(HandshakeSettings{Framing: encoding.FramingRawBER})
+	assert.Equal(t, []error{nil, nil}, backend.SendBatch([]BatchRecord{{Record: record}, {Record: record}}))
+	assert.Equal(t, payload, <-frames)
+	assert.Equal(t, payload, <-frames)
+}
+
+func TestBEREncoding(t *testing.T) {
+	record := makeSequencedRecords(t, 7)[0]
+	listener, frames := startFramingLEMF(t, encoding.FramingNative, 0)
+	defer listener.Close()
+	backend := NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{AckTimeout: time.Second})
+	defer backend.Close()
+
+	assert.NoError(t, backend.Send(record, 1))
+	assert.Equal(t, record, <-frames)
+
+	// the encoding of the handshake settings overrides the one of the config,
+	// the records still being acknowledged
+	backend.SetHandshakeSettings(HandshakeSettings{Encoding: encoding.EncodingBER})
+	assert.NoError(t, backend.Send(record, 1))
+	frame := <-frames
+	assert.NotEqual(t, record, frame)
+	der, err := encoding.ReencodeRecord(frame, encoding.EncodingDER)
+	assert.NoError(t, err)
+	assert.Equal(t, record, der)
+
+	backend.SetHandshakeSettings(HandshakeSettings{Encoding: "per"})
+	assert.EqualError(t, backend.Send(record, 1), "unsupported encoding per")
+}
+
+func TestHeaderVersion(t *testing.T) {
+	record := makeSequencedRecords(t, 7)[0]
+	listener, frames := startFramingLEMF(t, encoding.FramingNative, encoding.HeaderVersion1)
+	defer listener.Close()
+	backend := NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{
+		AckTimeout:    time.Second,
+		HeaderVersion: encoding.HeaderVersion1,
+	})
+	defer backend.Close()
+
+	// the records are written in the header version of the config
+	assert.NoError(t, backend.Send(record, 1))
+	frame := <-frames
+	hdr, err := encoding.ParsePDUHeader(frame)
+	assert.NoError(t, err)
+	assert.Equal(t, encoding.HeaderVersion1, hdr.Version)
+	assert.Equal(t, uint16(0), hdr.PayloadDirection)
+	assert.NoError(t, encoding.Validate(frame))
+	assert.Equal(t, encoding.HeaderVersion1, backend.GetHandshake().HeaderVersion)
+
+	// the LEMF doesn't acknowledge the newer version of the handshake
+	// settings
+	backend.SetHandshakeSettings(HandshakeSettings{HeaderVersion: encoding.HeaderVersion})
+	assert.Equal(t, FailureAckTimeout, ClassifyError(backend.Send(record, 1)))
+	<-frames
+}
+
+func TestHeaderVersionNegotiation(t *testing.T) {
+	record := makeSequencedRecords(t, 7)[0]
+	readRecord := func(frames chan []byte) *encoding.EpsIRIHeader {
+		for frame := range frames {
+			hdr, err := encoding.ParsePDUHeader(frame)
+			assert.NoError(t, err)
+			if hdr.PduType == encoding.HeaderPduType {
+				return hdr
+			}
+		}
+		return nil
+	}
+
+	// a LEMF of the newest version acknowledges the version offered
+	listener, frames := startFramingLEMF(t, encoding.FramingNative, 0)
+	backend := NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{
+		AckTimeout:             200 * time.Millisecond,
+		NegotiateHeaderVersion: true,
+	})
+	assert.NoError(t, backend.Send(record, 1))
+	assert.Equal(t, encoding.HeaderVersion, readRecord(frames).Version)
+	assert.Equal(t, encoding.HeaderVersion, backend.GetHandshake().HeaderVersion)
+	backend.Close()
+	listener.Close()
+
+	// the keepalive offering the newest version is left unacknowledged by a
+	// LEMF not upgraded yet, the next connection offering the older version
+	listener, frames = startFramingLEMF(t, encoding.FramingNative, encoding.HeaderVersion1)
+	defer listener.Close()
+	backend = NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{AckTimeout: 200 * time.Millisecond})
+	defer backend.Close()
+	backend.SetHandshakeSettings(HandshakeSettings{NegotiateHeaderVersion: true})
+	assert.Equal(t, FailureAckTimeout, ClassifyError(backend.Connect()))
+	assert.NoError(t, backend.Send(record, 1))
+	assert.Equal(t, encoding.HeaderVersion1, readRecord(frames).Version)
+	assert.Equal(t, encoding.HeaderVersion1, backend.GetHandshake().HeaderVersion)
+
+	// the negotiated version is kept on reconnection
+	assert.NoError(t, backend.Reconnect())
+	assert.Equal(t, encoding.HeaderVersion1, backend.GetHandshake().HeaderVersion)
+}
+
+func TestSessionResumption(t *testing.T) {
+	listener, _ := startLEMF(t)
+	defer listener.Close()
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true, ClientSessionCache: tls.NewLRUClientSessionCache(1)}
+	backend := NewTLSBackend(listener.Addr().String(), tlsConfig, TLSBackendConfig{})
+	defer backend.Close()
+	assert.True(t, backend.IsConnected())
+	assert.False(t, backend.GetHandshake().Resumed)
+
+	// reconnections resume the session without a full handshake
+	backend.Close()
+	_, err := backend.getSession()
+	assert.NoError(t, err)
+	assert.True(t, backend.GetHandshake().Resumed)
+}
+
+func TestConnectionPool(t *testing.T) {
+	listener, accepted := startLEMF(t)
+	defer listener.Close()
+
+	backend := NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{PoolSize: 2})
+	defer backend.Close()
+	assert.Eventually(t, func() bool {
+		backend.mutex.Lock()
+		defer backend.mutex.Unlock()
+		return len(backend.standby) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, accepted())
+
+	// the standby connection takes over a failed one and is replaced
+	backend.mutex.Lock()
+	current, standby := backend.session, backend.standby[0]
+	backend.mutex.Unlock()
+	backend.destroySession(current)
+	session, err := backend.getSession()
+	assert.NoError(t, err)
+	assert.True(t, session == standby)
+	assert.Eventually(t, func() bool { return accepted() == 3 }, time.Second, 10*time.Millisecond)
+
+	// closing the backend closes the standby connections as well
+	backend.Close()
+	backend.mutex.Lock()
+	assert.Empty(t, backend.standby)
+	backend.mutex.Unlock()
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	backend := NewTLSBackend(addr, &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{MaxReconnectBackoff: time.Minute})
+	defer backend.Close()
+	_, err = backend.getSession()
+	assert.Contains(t, err.Error(), "backing off")
+
+	for failures := 1; failures < 40; failures++ {
+		backoff := getReconnectBackoff(failures, 30*time.Second)
+		assert.True(t, backoff <= 30*time.Second)
+		if failures == 1 {
+			assert.True(t, backoff >= reconnectMinBackoff/2 && backoff <= reconnectMinBackoff)
+		}
+		if failures > 10 {
+			assert.True(t, backoff >= 15*time.Second)
+		}
+	}
+	assert.Equal(t, time.Duration(0), getReconnectBackoff(3, 0))
+}
+
+func TestWriteTimeout(t *testing.T) {
+	// the LEMF completes the handshake and then stalls, never reading
+	listener := listenLEMF(t)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			defer conn.Close()
+		}
+	}()
+
+	backend := NewTLSBackend(listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, TLSBackendConfig{WriteTimeout: 50 * time.Millisecond})
+	defer backend.Close()
+	record := make([]byte, 1<<20)
+	var err error
+	for i := 0; i < 64 && err == nil; i++ {
+		err = backend.Send(record, 1)
+	}
+	assert.Equal(t, FailureWriteTimeout, ClassifyError(err))
+	assert.False(t, backend.IsConnected())
+}
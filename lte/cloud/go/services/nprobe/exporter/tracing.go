@@ -0,0 +1,85 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Events of the span delivering a record, the time between them showing
+// where the record stalled
+const (
+	// spanEventDequeued marks a record picked up by the queue, the span
+	// starting when the record was submitted
+	spanEventDequeued = "dequeued"
+	// spanEventPaced marks a record waiting for a token of the rate limit
+	spanEventPaced = "paced"
+	// spanEventSending marks a record written to the backend, retries
+	// included
+	spanEventSending = "sending"
+	// spanEventRetransmitted marks a pipelined record written again alone
+	// after it failed
+	spanEventRetransmitted = "retransmitted"
+)
+
+// startDeliverSpan starts the span delivering a queued record from the time
+// it was submitted, as a child of the span of its submission and linked to
+// the span encoding the record
+func startDeliverSpan(r queuedRecord) trace.Span {
+	d := r.delivery
+	opts := []trace.SpanOption{trace.WithTimestamp(d.queuedAt), trace.WithSpanKind(trace.SpanKindProducer)}
+	if encoded := tracing.GetRecordSpan(d.ctx, r.index); encoded.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: encoded}))
+	}
+	_, span := tracing.Start(d.ctx, tracing.SpanDeliver, opts...)
+	if span.IsRecording() {
+		span.SetAttributes(
+			tracing.RecordClass.String(encoding.GetRecordClass(r.record)),
+			tracing.RecordBytes.Int(len(r.record)),
+		)
+		span.AddEvent(spanEventDequeued)
+	}
+	return span
+}
+
+// endDeliverSpan ends the span delivering a record with its result
+func endDeliverSpan(span trace.Span, err error) {
+	if err != nil && span.IsRecording() {
+		span.SetAttributes(tracing.Failure.String(ClassifyError(err)))
+	}
+	tracing.End(span, err)
+}
+
+// tracedBackend is implemented by the backends propagating the trace context
+// of the records they deliver to the destination
+type tracedBackend interface {
+	// SendTraced delivers a single record along with the context of the span
+	// of ctx, which only carries the span, the write being bounded by the
+	// backend
+	SendTraced(ctx context.Context, record []byte, correlationID uint64) error
+}
+
+// sendTraced delivers a record through a backend, along with the context of
+// the span of ctx if the backend propagates it
+func sendTraced(ctx context.Context, backend Backend, record []byte, correlationID uint64) error {
+	if tb, ok := backend.(tracedBackend); ok {
+		return tb.SendTraced(ctx, record, correlationID)
+	}
+	return backend.Send(record, correlationID)
+}
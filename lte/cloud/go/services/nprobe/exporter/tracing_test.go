@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/tracing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/export/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// getDeliverSpans returns the ended spans delivering records
+func getDeliverSpans(exporter *tracetest.InMemoryExporter) []*export.SpanSnapshot {
+	var ret []*export.SpanSnapshot
+	for _, span := range exporter.GetSpans() {
+		if span.Name == tracing.SpanDeliver {
+			ret = append(ret, span)
+		}
+	}
+	return ret
+}
+
+func getEventNames(span *export.SpanSnapshot) []string {
+	var ret []string
+	for _, event := range span.MessageEvents {
+		ret = append(ret, event.Name)
+	}
+	return ret
+}
+
+// tracedBackendStub records the trace context the records are delivered
+// with
+type tracedBackendStub struct {
+	lemfBackend
+	traced []trace.SpanContext
+}
+
+func (b *tracedBackendStub) SendTraced(ctx context.Context, record []byte, correlationID uint64) error {
+	b.mutex.Lock()
+	b.traced = append(b.traced, trace.SpanContextFromContext(ctx))
+	b.mutex.Unlock()
+	return b.Send(record, correlationID)
+}
+
+func TestFairQueueTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	defer tracing.SetExporter(exporter, 1)()
+
+	backend := &tracedBackendStub{}
+	c := NewRecordExporter(NewBreakerBackend(backend, "traced", 5, time.Minute))
+	defer c.Close()
+	ctx, export := tracing.Start(context.Background(), tracing.SpanExport)
+	var encoded []trace.SpanContext
+	for i := 0; i < 2; i++ {
+		_, span := tracing.Start(ctx, tracing.SpanEncode)
+		span.End()
+		encoded = append(encoded, span.SpanContext())
+	}
+
+	// the spans delivering the records are children of the submission,
+	// linked to the spans encoding them, and the backends are handed their
+	// context through the wrappers
+	submittedAt := time.Now()
+	errs := waitAll(c.SubmitRecords(tracing.WithRecordSpans(ctx, encoded...), "traced", 1, 1, makeRecords(2, 16), 1))
+	assert.Equal(t, []error{nil, nil}, errs)
+	spans := getDeliverSpans(exporter)
+	assert.Len(t, spans, 2)
+	for i, span := range spans {
+		assert.Equal(t, export.SpanContext().SpanID, span.ParentSpanID)
+		assert.Equal(t, []trace.Link{{SpanContext: encoded[i]}}, span.Links)
+		assert.Equal(t, []string{spanEventDequeued, spanEventSending}, getEventNames(span))
+		assert.Equal(t, codes.Unset, span.StatusCode)
+		assert.Equal(t, span.SpanContext, backend.traced[i])
+		// the span starts once submitted, its time in the queue included
+		assert.False(t, span.StartTime.Before(submittedAt.Add(-time.Millisecond)))
+		assert.False(t, span.StartTime.After(span.MessageEvents[0].Time))
+	}
+
+	// failed deliveries are marked with their failure, the records of an
+	// untraced encoding aren't linked and the records failed behind them
+	// are never dequeued
+	sender := &recordingSender{release: make(chan struct{}), failOn: map[uint64]bool{2: true}}
+	close(sender.release)
+	q := newFairQueue(sender.send)
+	errs = waitAll(q.submit(ctx, "failing", 1, 2, makeRecords(2, 16), 1))
+	assert.EqualError(t, errs[0], "send failed")
+	assert.Equal(t, ErrPreviousRecordFailed, errs[1])
+	spans = getDeliverSpans(exporter)[2:]
+	assert.Len(t, spans, 1)
+	assert.Empty(t, spans[0].Links)
+	assert.Equal(t, codes.Error, spans[0].StatusCode)
+	assert.Equal(t, "send failed", spans[0].StatusMessage)
+	assert.Contains(t, spans[0].Attributes, tracing.Failure.String(FailureUnknown))
+	export.End()
+	q.close()
+}
+
+func TestKafkaHeaderCarrier(t *testing.T) {
+	defer tracing.SetExporter(tracetest.NewNoopExporter(), 1)()
+	ctx, span := tracing.Start(context.Background(), tracing.SpanDeliver)
+	defer span.End()
+
+	// the traceparent header continues the trace of the delivery, the empty
+	// tracestate being left out
+	message := kafka.Message{Headers: []kafka.Header{{Key: "traceparent", Value: []byte("stale")}}}
+	tracing.Inject(ctx, (*kafkaHeaderCarrier)(&message))
+	sc := span.SpanContext()
+	assert.Equal(t, []kafka.Header{
+		{Key: "traceparent", Value: []byte("00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-01")},
+	}, message.Headers)
+
+	// a consumer of the topic picks the trace up from the headers
+	remote := trace.RemoteSpanContextFromContext(tracing.Extract(context.Background(), (*kafkaHeaderCarrier)(&message)))
+	assert.Equal(t, sc.TraceID, remote.TraceID)
+	assert.Equal(t, sc.SpanID, remote.SpanID)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+
+	"github.com/pkg/errors"
+)
+
+// X2Backend delivers records as ETSI TS 103 221-2 X2 PDUs through another
+// backend, e.g. to OpenLI-style mediation functions
+type X2Backend struct {
+	backend Backend
+}
+
+// NewX2Backend creates a new backend converting records to X2 PDUs before
+// delivering them through backend
+func NewX2Backend(backend Backend) *X2Backend {
+	return &X2Backend{backend: backend}
+}
+
+// Send converts a record to an X2 PDU and delivers it
+func (x *X2Backend) Send(record []byte, correlationID uint64) error {
+	return x.SendTraced(context.Background(), record, correlationID)
+}
+
+// SendTraced converts a record to an X2 PDU and delivers it along with its
+// trace context
+func (x *X2Backend) SendTraced(ctx context.Context, record []byte, correlationID uint64) error {
+	pdu, err := encoding.MakeX2PDU(record)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert record to X2 PDU")
+	}
+	return sendTraced(ctx, x.backend, pdu, correlationID)
+}
+
+// SendAsync converts a record to an X2 PDU and writes it without waiting
+// for its acknowledgement
+func (x *X2Backend) SendAsync(record []byte, correlationID uint64) *PendingRecord {
+	pdu, err := encoding.MakeX2PDU(record)
+	if err != nil {
+		return settledRecord(errors.Wrap(err, "failed to convert record to X2 PDU"))
+	}
+	return sendAsync(x.backend, pdu, correlationID)
+}
+
+// SendBatch converts records to X2 PDUs and delivers them at once
+func (x *X2Backend) SendBatch(records []BatchRecord) []error {
+	errs := make([]error, len(records))
+	var pdus []BatchRecord
+	var converted []int
+	for i, r := range records {
+		pdu, err := encoding.MakeX2PDU(r.Record)
+		if err != nil {
+			errs[i] = errors.Wrap(err, "failed to convert record to X2 PDU")
+			continue
+		}
+		pdus = append(pdus, BatchRecord{Record: pdu, CorrelationID: r.CorrelationID})
+		converted = append(converted, i)
+	}
+	for j, err := range sendBatch(x.backend, pdus) {
+		errs[converted[j]] = err
+	}
+	return errs
+}
+
+// IsConnected returns the connection state of the backend
+func (x *X2Backend) IsConnected() bool {
+	return x.backend.IsConnected()
+}
+
+// SetHandshakeSettings applies the handshake settings to the backend
+func (x *X2Backend) SetHandshakeSettings(settings HandshakeSettings) {
+	if hb, ok := x.backend.(handshakeBackend); ok {
+		hb.SetHandshakeSettings(settings)
+	}
+}
+
+// GetHandshake describes the handshake of the backend connection
+func (x *X2Backend) GetHandshake() *HandshakeInfo {
+	if hb, ok := x.backend.(handshakeBackend); ok {
+		return hb.GetHandshake()
+	}
+	return nil
+}
+
+// Probe probes the delivery function of the backend
+func (x *X2Backend) Probe(timeout time.Duration) (*HandshakeInfo, error) {
+	if hb, ok := x.backend.(handshakeBackend); ok {
+		return hb.Probe(timeout)
+	}
+	return nil, ErrProbeUnsupported
+}
+
+// GetConnection describes the connection of the backend
+func (x *X2Backend) GetConnection() *ConnectionInfo {
+	if cb, ok := x.backend.(connectionBackend); ok {
+		return cb.GetConnection()
+	}
+	return &ConnectionInfo{}
+}
+
+// Reconnect reconnects the backend
+func (x *X2Backend) Reconnect() error {
+	return reconnect(x.backend)
+}
+
+// Connect connects the backend
+func (x *X2Backend) Connect() error {
+	if cb, ok := x.backend.(connectBackend); ok {
+		return cb.Connect()
+	}
+	return nil
+}
+
+// Close closes the backend
+func (x *X2Backend) Close() {
+	x.backend.Close()
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/metrics"
+)
+
+const (
+	// CertificateClient is an exporter certificate, presented to the
+	// delivery functions
+	CertificateClient = "client"
+	// CertificateServer is the certificate presented by a delivery function
+	CertificateServer = "server"
+	// componentCertificatePrefix prefixes the certificates whose expiry is
+	// tracked
+	componentCertificatePrefix = "certificate:"
+)
+
+// Certificate is a certificate whose expiry is tracked, named after its file
+// for the exporter certificates and after the address of its delivery
+// function otherwise
+type Certificate struct {
+	Kind     string
+	Name     string
+	Subject  string
+	NotAfter time.Time
+}
+
+// Describe returns the certificate as alerted about
+func (c Certificate) Describe() string {
+	if c.Kind == CertificateServer {
+		return fmt.Sprintf("%s of %s", c.Subject, c.Name)
+	}
+	return c.Subject
+}
+
+// IsExpiring returns true if the certificate expires within warning, or
+// has expired
+func (c Certificate) IsExpiring(warning time.Duration, now time.Time) bool {
+	return c.NotAfter.Sub(now) <= warning
+}
+
+// CertificateTracker tracks the expiry of the exporter certificates as they
+// are loaded and of the certificates of the delivery functions as they are
+// presented, since an expired certificate silently stops the delivery. The
+// certificates are reported as components of the registries tracking them,
+// degraded once they expire within the warning and unhealthy once expired,
+// and their expiry is exported in metrics.
+type CertificateTracker struct {
+	mutex        sync.Mutex
+	certificates map[string]Certificate
+	warning      time.Duration
+}
+
+// NewCertificateTracker creates a tracker without certificates
+func NewCertificateTracker() *CertificateTracker {
+	return &CertificateTracker{certificates: map[string]Certificate{}}
+}
+
+// CertificateComponent returns the component of a tracked certificate
+func CertificateComponent(kind, name string) string {
+	return componentCertificatePrefix + kind + ":" + name
+}
+
+// SetWarning sets the time before the expiry of the certificates from which
+// they are degraded
+func (t *CertificateTracker) SetWarning(warning time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.warning = warning
+	metrics.CertificateWarning.Set(warning.Seconds())
+}
+
+// GetWarning returns the time before the expiry of the certificates from
+// which they are degraded
+func (t *CertificateTracker) GetWarning() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.warning
+}
+
+// Observe tracks a certificate, replacing the one of the same kind and name
+func (t *CertificateTracker) Observe(cert Certificate) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.certificates[CertificateComponent(cert.Kind, cert.Name)] = cert
+	metrics.CertificateExpiry.WithLabelValues(cert.Kind, cert.Name).Set(float64(cert.NotAfter.Unix()))
+}
+
+// Forget stops tracking a certificate, e.g. once its file is replaced or its
+// delivery function no longer delivered to
+func (t *CertificateTracker) Forget(kind, name string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.certificates, CertificateComponent(kind, name))
+	metrics.CertificateExpiry.DeleteLabelValues(kind, name)
+	metrics.ComponentHealth.DeleteLabelValues(CertificateComponent(kind, name))
+}
+
+// List returns the tracked certificates by kind and name
+func (t *CertificateTracker) List() []Certificate {
+	t.mutex.Lock()
+	ret := make([]Certificate, 0, len(t.certificates))
+	for _, cert := range t.certificates {
+		ret = append(ret, cert)
+	}
+	t.mutex.Unlock()
+	sort.Slice(ret, func(i, j int) bool {
+		return CertificateComponent(ret[i].Kind, ret[i].Name) < CertificateComponent(ret[j].Kind, ret[j].Name)
+	})
+	return ret
+}
+
+// Expiring returns the certificates expiring within the warning, or expired
+func (t *CertificateTracker) Expiring(now time.Time) []Certificate {
+	warning := t.GetWarning()
+	var ret []Certificate
+	for _, cert := range t.List() {
+		if cert.IsExpiring(warning, now) {
+			ret = append(ret, cert)
+		}
+	}
+	return ret
+}
+
+// statuses returns the health of the tracked certificates
+func (t *CertificateTracker) statuses(now time.Time) []Status {
+	warning := t.GetWarning()
+	var ret []Status
+	for _, cert := range t.List() {
+		status := Status{
+			Component: CertificateComponent(cert.Kind, cert.Name),
+			Healthy:   true,
+			Message:   fmt.Sprintf("%s valid until %s", cert.Subject, cert.NotAfter.UTC().Format(time.RFC3339)),
+			CheckedAt: now,
+		}
+		remaining := cert.NotAfter.Sub(now)
+		switch {
+		case remaining <= 0:
+			status.Healthy = false
+			status.Message = fmt.Sprintf("%s expired on %s", cert.Subject, cert.NotAfter.UTC().Format(time.RFC3339))
+		case remaining <= warning:
+			status.Degraded = true
+			status.Message = fmt.Sprintf("%s expires on %s, in %d days", cert.Subject, cert.NotAfter.UTC().Format(time.RFC3339), int(remaining.Hours()/24))
+		}
+		ret = append(ret, status)
+	}
+	return ret
+}
+
+var defaultCertificates = NewCertificateTracker()
+
+// Certificates returns the tracker of the certificates of the service
+func Certificates() *CertificateTracker {
+	return defaultCertificates
+}
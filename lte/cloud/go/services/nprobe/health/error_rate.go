@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errorRateBuckets is the number of buckets the window of an error rate is
+// divided into, the failures expiring a bucket at a time
+const errorRateBuckets = 6
+
+// ErrorRate tracks the failures of the operations of a component over a
+// sliding window, e.g. of the events failing to be encoded. The component is
+// degraded while the failures of the window reach both a minimum count and a
+// ratio of the operations, so that a spike is reported rather than the odd
+// malformed event.
+type ErrorRate struct {
+	mutex       sync.Mutex
+	window      time.Duration
+	minFailures uint64
+	maxRatio    float64
+	buckets     []rateBucket
+}
+
+// rateBucket counts the operations started in a fraction of the window
+type rateBucket struct {
+	start  time.Time
+	total  uint64
+	failed uint64
+}
+
+// NewErrorRate creates an error rate without operations
+func NewErrorRate(window time.Duration, minFailures uint64, maxRatio float64) *ErrorRate {
+	r := &ErrorRate{}
+	r.SetThresholds(window, minFailures, maxRatio)
+	return r
+}
+
+// SetThresholds replaces the window and the thresholds of the error rate
+func (r *ErrorRate) SetThresholds(window time.Duration, minFailures uint64, maxRatio float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.window, r.minFailures, r.maxRatio = window, minFailures, maxRatio
+}
+
+// Add counts an operation, failed or not
+func (r *ErrorRate) Add(failed bool, now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.expire(now)
+	start := now.Truncate(r.window / errorRateBuckets)
+	if len(r.buckets) == 0 || !r.buckets[len(r.buckets)-1].start.Equal(start) {
+		r.buckets = append(r.buckets, rateBucket{start: start})
+	}
+	bucket := &r.buckets[len(r.buckets)-1]
+	bucket.total++
+	if failed {
+		bucket.failed++
+	}
+}
+
+// Check returns a Degraded error while the failures of the window reach the
+// thresholds, nil otherwise. It is meant to be registered as a Checker.
+func (r *ErrorRate) Check() error {
+	return r.check(time.Now())
+}
+
+func (r *ErrorRate) check(now time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.expire(now)
+	var total, failed uint64
+	for _, bucket := range r.buckets {
+		total, failed = total+bucket.total, failed+bucket.failed
+	}
+	if failed == 0 || failed < r.minFailures || float64(failed)/float64(total) < r.maxRatio {
+		return nil
+	}
+	return Degraded(fmt.Errorf("%d of %d operations failed in the last %s", failed, total, r.window))
+}
+
+// expire drops the buckets which left the window. It must be called with the
+// mutex locked.
+func (r *ErrorRate) expire(now time.Time) {
+	expired := 0
+	for expired < len(r.buckets) && !r.buckets[expired].start.After(now.Add(-r.window)) {
+		expired++
+	}
+	r.buckets = r.buckets[expired:]
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health aggregates the health of the components of the nprobe
+// service, e.g. the manager loop, the storage and the exporter connections,
+// so that tooling reports which component fails rather than only whether
+// the process is alive.
+package health
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/metrics"
+)
+
+const (
+	// ComponentManager is the loop processing the tasks
+	ComponentManager = "manager"
+	// ComponentStorage is the database holding the state of the tasks
+	ComponentStorage = "storage"
+	// ComponentExportQueue is the queue of the records waiting for delivery
+	ComponentExportQueue = "export_queue"
+	// ComponentEncoding is the encoding of the events into records
+	ComponentEncoding = "encoding"
+	// ComponentClock is the clock the records are stamped with
+	ComponentClock = "clock"
+	// componentDestinationPrefix prefixes the connections delivering records
+	componentDestinationPrefix = "destination:"
+	// componentReachabilityPrefix prefixes the destinations checked by the probes
+	componentReachabilityPrefix = "reachability:"
+	// componentSourcePrefix prefixes the sources the events are fetched from
+	componentSourcePrefix = "source:"
+)
+
+// Checker returns the error of an unhealthy component, nil if healthy. The
+// components which still operate return a Degraded error.
+type Checker func() error
+
+// Health of the service, summarizing the health of its components
+const (
+	// ServiceHealthy is the health of a service whose components are healthy
+	ServiceHealthy = "healthy"
+	// ServiceDegraded is the health of a service with degraded components,
+	// which still delivers records, e.g. with a nearly full export queue
+	ServiceDegraded = "degraded"
+	// ServiceUnhealthy is the health of a service with unhealthy components,
+	// e.g. with an unreachable delivery function
+	ServiceUnhealthy = "unhealthy"
+)
+
+// Status is the health of a component. A degraded component is still
+// healthy.
+type Status struct {
+	Component string
+	Healthy   bool
+	Degraded  bool
+	Message   string
+	CheckedAt time.Time
+}
+
+// degradedError is the error of a degraded component
+type degradedError struct {
+	error
+}
+
+// Degraded returns the error of a degraded component, nil if err is nil
+func Degraded(err error) error {
+	if err == nil {
+		return nil
+	}
+	return degradedError{err}
+}
+
+// IsDegraded returns true if err is the error of a degraded component
+func IsDegraded(err error) bool {
+	_, ok := err.(degradedError)
+	return ok
+}
+
+// report is the last health reported by a component
+type report struct {
+	err        error
+	reportedAt time.Time
+	// ttl is the time the report is valid for, the component being
+	// unhealthy once it stops reporting. It never expires when 0.
+	ttl time.Duration
+}
+
+// Registry holds the health of the components of the service. Components
+// either report their health as they run, or are checked when the health
+// is requested.
+type Registry struct {
+	mutex    sync.Mutex
+	checkers map[string]Checker
+	reports  map[string]report
+	// certificates are reported as components when set
+	certificates *CertificateTracker
+}
+
+// NewRegistry creates a registry without components
+func NewRegistry() *Registry {
+	return &Registry{
+		checkers: map[string]Checker{},
+		reports:  map[string]report{},
+	}
+}
+
+// DestinationComponent returns the component of the connection delivering
+// records to a destination
+func DestinationComponent(destination string) string {
+	return componentDestinationPrefix + destination
+}
+
+// ReachabilityComponent returns the component of the reachability of a
+// destination, as last probed
+func ReachabilityComponent(addr string) string {
+	return componentReachabilityPrefix + addr
+}
+
+// SourceComponent returns the component of a source the events of the
+// targets are fetched from
+func SourceComponent(source string) string {
+	return componentSourcePrefix + source
+}
+
+// TrackCertificates reports the certificates of a tracker as components
+func (r *Registry) TrackCertificates(certificates *CertificateTracker) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.certificates = certificates
+}
+
+// Register adds a component checked when the health is requested,
+// replacing any component of the same name
+func (r *Registry) Register(component string, checker Checker) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.reports, component)
+	r.checkers[component] = checker
+}
+
+// Unregister removes a component, e.g. a connection which was replaced
+func (r *Registry) Unregister(component string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.checkers, component)
+	delete(r.reports, component)
+	metrics.ComponentHealth.DeleteLabelValues(component)
+}
+
+// Report records the health of a component, healthy if err is nil. The
+// component is unhealthy once ttl elapses without a new report, unless 0.
+func (r *Registry) Report(component string, err error, ttl time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.checkers, component)
+	r.reports[component] = report{err: err, reportedAt: time.Now(), ttl: ttl}
+}
+
+// Check returns the health of every component, by name
+func (r *Registry) Check() []Status {
+	r.mutex.Lock()
+	checkers := make(map[string]Checker, len(r.checkers))
+	for component, checker := range r.checkers {
+		checkers[component] = checker
+	}
+	reports := make(map[string]report, len(r.reports))
+	for component, rep := range r.reports {
+		reports[component] = rep
+	}
+	certificates := r.certificates
+	r.mutex.Unlock()
+
+	// checkers may block, e.g. on the database, so they run unlocked
+	now := time.Now()
+	ret := make([]Status, 0, len(checkers)+len(reports))
+	for component, checker := range checkers {
+		ret = append(ret, newStatus(component, checker(), now))
+	}
+	for component, rep := range reports {
+		err := rep.err
+		if rep.ttl != 0 && now.Sub(rep.reportedAt) > rep.ttl {
+			err = fmt.Errorf("no report since %s", rep.reportedAt.UTC().Format(time.RFC3339))
+		}
+		ret = append(ret, newStatus(component, err, rep.reportedAt))
+	}
+	if certificates != nil {
+		ret = append(ret, certificates.statuses(now)...)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Component < ret[j].Component })
+
+	for _, status := range ret {
+		healthy := 0.0
+		if status.Degraded {
+			healthy = 0.5
+		} else if status.Healthy {
+			healthy = 1
+		}
+		metrics.ComponentHealth.WithLabelValues(status.Component).Set(healthy)
+	}
+	return ret
+}
+
+// IsHealthy returns true if every component of the statuses is healthy,
+// possibly degraded
+func IsHealthy(statuses []Status) bool {
+	for _, status := range statuses {
+		if !status.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// Summarize returns the health of a service from the statuses of its
+// components: ServiceUnhealthy if any of them is unhealthy, ServiceDegraded
+// if any of them is degraded, ServiceHealthy otherwise
+func Summarize(statuses []Status) string {
+	ret := ServiceHealthy
+	for _, status := range statuses {
+		if !status.Healthy {
+			return ServiceUnhealthy
+		}
+		if status.Degraded {
+			ret = ServiceDegraded
+		}
+	}
+	return ret
+}
+
+func newStatus(component string, err error, checkedAt time.Time) Status {
+	if IsDegraded(err) {
+		return Status{Component: component, Healthy: true, Degraded: true, Message: err.Error(), CheckedAt: checkedAt}
+	}
+	if err != nil {
+		return Status{Component: component, Message: err.Error(), CheckedAt: checkedAt}
+	}
+	return Status{Component: component, Healthy: true, Message: "healthy", CheckedAt: checkedAt}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	assert.Empty(t, registry.Check())
+	assert.True(t, IsHealthy(registry.Check()))
+
+	var storageErr error
+	registry.Register(ComponentStorage, func() error { return storageErr })
+	registry.Register(DestinationComponent("10.10.0.2:6666"), func() error { return nil })
+	registry.Report(ComponentManager, nil, time.Hour)
+
+	statuses := registry.Check()
+	assert.Equal(t, []string{"destination:10.10.0.2:6666", "manager", "storage"}, getComponents(statuses))
+	assert.True(t, IsHealthy(statuses))
+	assert.Equal(t, "healthy", statuses[2].Message)
+
+	// checked components are checked on each request
+	storageErr = errors.New("connection refused")
+	statuses = registry.Check()
+	assert.False(t, IsHealthy(statuses))
+	assert.Equal(t, Status{Component: ComponentStorage, Message: "connection refused", CheckedAt: statuses[2].CheckedAt}, statuses[2])
+
+	// reported components keep their last report
+	storageErr = nil
+	registry.Report(ComponentManager, errors.New("failed to load networks"), time.Hour)
+	statuses = registry.Check()
+	assert.False(t, statuses[1].Healthy)
+	assert.Equal(t, "failed to load networks", statuses[1].Message)
+
+	// reported components are unhealthy once they stop reporting
+	registry.Report(ComponentManager, nil, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	statuses = registry.Check()
+	assert.False(t, statuses[1].Healthy)
+	assert.True(t, strings.HasPrefix(statuses[1].Message, "no report since "))
+	registry.Report(ComponentManager, nil, 0)
+	assert.True(t, IsHealthy(registry.Check()))
+
+	registry.Unregister(DestinationComponent("10.10.0.2:6666"))
+	assert.Equal(t, []string{"manager", "storage"}, getComponents(registry.Check()))
+}
+
+func TestDegraded(t *testing.T) {
+	registry := NewRegistry()
+	var queueErr error
+	registry.Register(ComponentExportQueue, func() error { return Degraded(queueErr) })
+	registry.Register(ComponentStorage, func() error { return nil })
+	assert.Equal(t, ServiceHealthy, Summarize(registry.Check()))
+
+	// degraded components are still healthy
+	queueErr = errors.New("export queue holds 750 of 1000 records")
+	statuses := registry.Check()
+	assert.True(t, IsHealthy(statuses))
+	assert.Equal(t, ServiceDegraded, Summarize(statuses))
+	assert.Equal(t, Status{Component: ComponentExportQueue, Healthy: true, Degraded: true, Message: "export queue holds 750 of 1000 records", CheckedAt: statuses[0].CheckedAt}, statuses[0])
+
+	registry.Report(ComponentManager, errors.New("failed to load networks"), 0)
+	assert.Equal(t, ServiceUnhealthy, Summarize(registry.Check()))
+}
+
+func TestErrorRate(t *testing.T) {
+	rate := NewErrorRate(time.Minute, 3, 0.5)
+	now := time.Unix(1600000000, 0)
+	assert.NoError(t, rate.check(now))
+
+	// the odd failure doesn't degrade the component
+	rate.Add(true, now)
+	rate.Add(false, now)
+	assert.NoError(t, rate.check(now))
+
+	// a spike of failures does
+	rate.Add(true, now.Add(time.Second))
+	rate.Add(true, now.Add(2*time.Second))
+	err := rate.check(now.Add(2 * time.Second))
+	assert.True(t, IsDegraded(err))
+	assert.Equal(t, "3 of 4 operations failed in the last 1m0s", err.Error())
+
+	// below the ratio of the operations
+	for i := 0; i < 4; i++ {
+		rate.Add(false, now.Add(3*time.Second))
+	}
+	assert.NoError(t, rate.check(now.Add(3*time.Second)))
+
+	// and once the failures leave the window
+	rate.Add(true, now.Add(30*time.Second))
+	rate.Add(true, now.Add(30*time.Second))
+	rate.Add(true, now.Add(30*time.Second))
+	assert.Error(t, rate.check(now.Add(30*time.Second)))
+	assert.NoError(t, rate.check(now.Add(2*time.Minute)))
+}
+
+func TestCertificateTracker(t *testing.T) {
+	certificates := NewCertificateTracker()
+	certificates.SetWarning(30 * 24 * time.Hour)
+	registry := NewRegistry()
+	registry.TrackCertificates(certificates)
+	now := time.Now()
+	client := Certificate{Kind: CertificateClient, Name: "/var/opt/magma/certs/client.crt", Subject: "CN=nprobe", NotAfter: now.Add(365 * 24 * time.Hour)}
+	server := Certificate{Kind: CertificateServer, Name: "10.10.0.2:6666", Subject: "CN=lemf", NotAfter: now.Add(10 * 24 * time.Hour)}
+	certificates.Observe(client)
+	certificates.Observe(server)
+	assert.Equal(t, []Certificate{client, server}, certificates.List())
+	assert.Equal(t, "CN=lemf of 10.10.0.2:6666", server.Describe())
+
+	// the certificates expiring within the warning are degraded
+	assert.Equal(t, []Certificate{server}, certificates.Expiring(now))
+	statuses := registry.Check()
+	assert.Equal(t, []string{"certificate:client:/var/opt/magma/certs/client.crt", "certificate:server:10.10.0.2:6666"}, getComponents(statuses))
+	assert.True(t, strings.HasPrefix(statuses[0].Message, "CN=nprobe valid until "))
+	assert.True(t, statuses[1].Degraded)
+	assert.Contains(t, statuses[1].Message, "in 9 days")
+	assert.Equal(t, ServiceDegraded, Summarize(statuses))
+
+	// and the expired ones unhealthy
+	server.NotAfter = now.Add(-time.Hour)
+	certificates.Observe(server)
+	statuses = registry.Check()
+	assert.False(t, statuses[1].Healthy)
+	assert.True(t, strings.HasPrefix(statuses[1].Message, "CN=lemf expired on "))
+
+	certificates.Forget(CertificateServer, "10.10.0.2:6666")
+	assert.Equal(t, []Certificate{client}, certificates.List())
+	assert.Empty(t, certificates.Expiring(now))
+	assert.Equal(t, ServiceHealthy, Summarize(registry.Check()))
+}
+
+func getComponents(statuses []Status) []string {
+	var ret []string
+	for _, status := range statuses {
+		ret = append(ret, status.Component)
+	}
+	return ret
+}
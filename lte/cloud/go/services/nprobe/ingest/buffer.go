@@ -0,0 +1,139 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ingest buffers the events streamed by gateways until they are
+// processed by the nprobe manager.
+package ingest
+
+import (
+	"context"
+	"sync"
+
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// Buffer holds the events streamed by gateways per network. It is bounded:
+// writers block once a network holds capacity events until they are
+// drained, which pushes back on the streaming gateways.
+type Buffer struct {
+	mutex    sync.Mutex
+	capacity int
+	events   map[string][]eventdM.Event
+	// drained is closed and replaced each time events are drained
+	drained chan struct{}
+	ready   chan struct{}
+}
+
+// NewBuffer creates a buffer holding up to capacity events per network
+func NewBuffer(capacity int) *Buffer {
+	return &Buffer{
+		capacity: capacity,
+		events:   map[string][]eventdM.Event{},
+		drained:  make(chan struct{}),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// SetCapacity changes the number of events held per network. Events already
+// buffered are kept.
+func (b *Buffer) SetCapacity(capacity int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if capacity > b.capacity {
+		b.notifyDrained()
+	}
+	b.capacity = capacity
+}
+
+// Put adds an event of a network, waiting until the events of the network
+// are drained if it is full. An error is returned if ctx is done first.
+func (b *Buffer) Put(ctx context.Context, networkID string, event eventdM.Event) error {
+	for {
+		b.mutex.Lock()
+		if len(b.events[networkID]) < b.capacity {
+			b.events[networkID] = append(b.events[networkID], event)
+			b.mutex.Unlock()
+			select {
+			case b.ready <- struct{}{}:
+			default:
+			}
+			return nil
+		}
+		drained := b.drained
+		b.mutex.Unlock()
+
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Ready returns a channel receiving a value once events are buffered after
+// the previous value was received
+func (b *Buffer) Ready() <-chan struct{} {
+	return b.ready
+}
+
+// Drain removes and returns the events buffered for a network
+func (b *Buffer) Drain(networkID string) []eventdM.Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	events := b.events[networkID]
+	if len(events) == 0 {
+		return nil
+	}
+	delete(b.events, networkID)
+	b.notifyDrained()
+	return events
+}
+
+// Len returns the number of events buffered across networks
+func (b *Buffer) Len() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	n := 0
+	for _, events := range b.events {
+		n += len(events)
+	}
+	return n
+}
+
+// Retain drops the events of the networks not listed, e.g. deleted
+// networks, so that their gateways aren't blocked forever
+func (b *Buffer) Retain(networkIDs []string) {
+	keep := map[string]bool{}
+	for _, networkID := range networkIDs {
+		keep[networkID] = true
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	dropped := false
+	for networkID := range b.events {
+		if !keep[networkID] {
+			delete(b.events, networkID)
+			dropped = true
+		}
+	}
+	if dropped {
+		b.notifyDrained()
+	}
+}
+
+// notifyDrained wakes up the writers waiting for room. It must be called
+// with the mutex held.
+func (b *Buffer) notifyDrained() {
+	close(b.drained)
+	b.drained = make(chan struct{})
+}
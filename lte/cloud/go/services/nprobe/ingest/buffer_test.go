@@ -0,0 +1,72 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer(t *testing.T) {
+	b := NewBuffer(2)
+	ctx := context.Background()
+	assert.NoError(t, b.Put(ctx, "n1", eventdM.Event{Tag: "1"}))
+	assert.NoError(t, b.Put(ctx, "n1", eventdM.Event{Tag: "2"}))
+	assert.NoError(t, b.Put(ctx, "n2", eventdM.Event{Tag: "3"}))
+	select {
+	case <-b.Ready():
+	default:
+		assert.Fail(t, "buffer should be ready")
+	}
+
+	// the buffer of n1 is full until drained
+	put := make(chan error)
+	go func() {
+		put <- b.Put(ctx, "n1", eventdM.Event{Tag: "4"})
+	}()
+	select {
+	case <-put:
+		assert.Fail(t, "put should block while the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, 3, b.Len())
+	assert.Equal(t, []eventdM.Event{{Tag: "1"}, {Tag: "2"}}, b.Drain("n1"))
+	assert.NoError(t, <-put)
+	assert.Equal(t, []eventdM.Event{{Tag: "4"}}, b.Drain("n1"))
+	assert.Empty(t, b.Drain("n1"))
+
+	// a blocked put returns once its stream is closed
+	assert.NoError(t, b.Put(ctx, "n1", eventdM.Event{Tag: "5"}))
+	assert.NoError(t, b.Put(ctx, "n1", eventdM.Event{Tag: "6"}))
+	cancelled, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, b.Put(cancelled, "n1", eventdM.Event{Tag: "7"}))
+
+	// a larger capacity makes room right away
+	go func() {
+		put <- b.Put(ctx, "n1", eventdM.Event{Tag: "8"})
+	}()
+	b.SetCapacity(3)
+	assert.NoError(t, <-put)
+
+	// the events of networks no longer listed are dropped
+	b.Retain([]string{"n2"})
+	assert.Empty(t, b.Drain("n1"))
+	assert.Equal(t, []eventdM.Event{{Tag: "3"}}, b.Drain("n2"))
+}
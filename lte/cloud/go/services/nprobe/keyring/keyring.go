@@ -0,0 +1,256 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package keyring provides the envelope encryption of the interception
+// data persisted by the nprobe service. Each value is encrypted with its own
+// AES-256-GCM data key, itself encrypted with the primary key of the keyring.
+// The keys of the keyring can be rotated: values sealed with a former key are
+// still opened as long as the key is kept, and are sealed again with the
+// primary key when re-encrypted.
+package keyring
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// KeySize is the size in bytes of the AES-256 keys of the keyring
+	KeySize = 32
+	// maxKeyIDLength bounds the key ID carried by sealed values
+	maxKeyIDLength = 255
+
+	nonceSize = 12
+	tagSize   = 16
+)
+
+// sealedMagic prefixes sealed values, the values without it being stored
+// before encryption was enabled
+var sealedMagic = []byte("npe1")
+
+// Keyring holds the keys sealing persisted values, identified by their ID.
+// New values are sealed with the primary key. The keys can be replaced at
+// runtime, e.g. when the service config is reloaded.
+type Keyring struct {
+	mutex   sync.RWMutex
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring creates a keyring sealing new values with the primary key
+func NewKeyring(keys map[string][]byte, primary string) (*Keyring, error) {
+	k := &Keyring{}
+	if err := k.Set(keys, primary); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// LoadKeyring creates a keyring from hex encoded AES-256 key files, keyed by
+// the ID of their key
+func LoadKeyring(keyFiles map[string]string, primary string) (*Keyring, error) {
+	keys, err := LoadKeys(keyFiles)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyring(keys, primary)
+}
+
+// LoadKeys reads hex encoded AES-256 key files, keyed by the ID of their key
+func LoadKeys(keyFiles map[string]string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for id, keyFile := range keyFiles {
+		content, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid encoding of key %s", id)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// Set replaces the keys of the keyring. The keys are left unchanged if the
+// new ones are invalid.
+func (k *Keyring) Set(keys map[string][]byte, primary string) error {
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > maxKeyIDLength {
+			return fmt.Errorf("invalid key ID %q", id)
+		}
+		if len(key) != KeySize {
+			return fmt.Errorf("invalid size %d of key %s, expected %d", len(key), id, KeySize)
+		}
+	}
+	if _, ok := keys[primary]; !ok {
+		return fmt.Errorf("primary key %q not found", primary)
+	}
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.keys, k.primary = keys, primary
+	return nil
+}
+
+// Primary returns the ID of the key sealing new values
+func (k *Keyring) Primary() string {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.primary
+}
+
+// Seal encrypts a value with a new data key sealed with the primary key. A
+// nil keyring returns the value unencrypted.
+func (k *Keyring) Seal(plaintext []byte) ([]byte, error) {
+	if k == nil {
+		return plaintext, nil
+	}
+	k.mutex.RLock()
+	id, kek := k.primary, k.keys[k.primary]
+	k.mutex.RUnlock()
+
+	dek := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, err
+	}
+	wrappedKey, err := seal(kek, dek)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(dek, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, 0, len(sealedMagic)+1+len(id)+len(wrappedKey)+len(ciphertext))
+	sealed = append(sealed, sealedMagic...)
+	sealed = append(sealed, byte(len(id)))
+	sealed = append(sealed, id...)
+	sealed = append(sealed, wrappedKey...)
+	return append(sealed, ciphertext...), nil
+}
+
+// Open decrypts a value sealed with any key of the keyring. Values which are
+// not sealed are returned unchanged.
+func (k *Keyring) Open(value []byte) ([]byte, error) {
+	id, wrappedKey, ciphertext, err := parseSealed(value)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return value, nil
+	}
+	if k == nil {
+		return nil, fmt.Errorf("value sealed with key %s while encryption is disabled", id)
+	}
+	k.mutex.RLock()
+	kek, ok := k.keys[id]
+	k.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("value sealed with unknown key %s", id)
+	}
+
+	dek, err := open(kek, wrappedKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt data key sealed with key %s", id)
+	}
+	plaintext, err := open(dek, ciphertext)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt value")
+	}
+	return plaintext, nil
+}
+
+// IsCurrent returns true if a value is sealed as the keyring seals new
+// values: with its primary key, or unencrypted for a nil keyring
+func (k *Keyring) IsCurrent(value []byte) bool {
+	id := GetKeyID(value)
+	if k == nil {
+		return id == ""
+	}
+	return id == k.Primary()
+}
+
+// GetKeyID returns the ID of the key a value is sealed with, empty if the
+// value isn't sealed
+func GetKeyID(value []byte) string {
+	id, _, _, err := parseSealed(value)
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// parseSealed splits a sealed value into the ID of its key, its sealed data
+// key and its ciphertext. The ID is empty for values which are not sealed.
+func parseSealed(value []byte) (string, []byte, []byte, error) {
+	if !bytes.HasPrefix(value, sealedMagic) {
+		return "", nil, nil, nil
+	}
+	b := value[len(sealedMagic):]
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return "", nil, nil, errors.New("truncated sealed value")
+	}
+	id := string(b[1 : 1+b[0]])
+	b = b[1+b[0]:]
+	wrappedKeySize := nonceSize + KeySize + tagSize
+	if len(id) == 0 || len(b) < wrappedKeySize+nonceSize+tagSize {
+		return "", nil, nil, errors.New("truncated sealed value")
+	}
+	return id, b[:wrappedKeySize], b[wrappedKeySize:], nil
+}
+
+// seal encrypts plaintext with AES-GCM, prefixed by its random nonce
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts a ciphertext created by seal
+func open(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too small")
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
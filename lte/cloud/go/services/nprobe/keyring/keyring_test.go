@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keyring
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyring(t *testing.T) {
+	key1, key2 := bytes.Repeat([]byte{1}, KeySize), bytes.Repeat([]byte{2}, KeySize)
+	k, err := NewKeyring(map[string][]byte{"k1": key1}, "k1")
+	assert.NoError(t, err)
+
+	plaintext := []byte(`{"target_id":"IMSI001010000000001"}`)
+	sealed, err := k.Seal(plaintext)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(sealed, []byte("IMSI001010000000001")))
+	assert.Equal(t, "k1", GetKeyID(sealed))
+	assert.True(t, k.IsCurrent(sealed))
+	opened, err := k.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	// values stored before encryption was enabled are read as is
+	opened, err = k.Open(plaintext)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+	assert.False(t, k.IsCurrent(plaintext))
+
+	// after a rotation, values sealed with the former key are still opened
+	assert.NoError(t, k.Set(map[string][]byte{"k1": key1, "k2": key2}, "k2"))
+	assert.False(t, k.IsCurrent(sealed))
+	opened, err = k.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+	resealed, err := k.Seal(opened)
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", GetKeyID(resealed))
+
+	// until the former key is removed
+	assert.NoError(t, k.Set(map[string][]byte{"k2": key2}, "k2"))
+	_, err = k.Open(sealed)
+	assert.EqualError(t, err, "value sealed with unknown key k1")
+
+	// tampered values are rejected
+	resealed[len(resealed)-1] ^= 1
+	_, err = k.Open(resealed)
+	assert.Error(t, err)
+	_, err = k.Open(resealed[:len(sealedMagic)+4])
+	assert.Error(t, err)
+
+	// sealed values need a keyring
+	var disabled *Keyring
+	_, err = disabled.Open(sealed)
+	assert.Error(t, err)
+	unsealed, err := disabled.Seal(plaintext)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, unsealed)
+	assert.True(t, disabled.IsCurrent(plaintext))
+
+	// invalid keys are rejected and leave the keys unchanged
+	assert.Error(t, k.Set(map[string][]byte{"k3": key1[:16]}, "k3"))
+	assert.Error(t, k.Set(map[string][]byte{"k1": key1}, "k3"))
+	assert.Equal(t, "k2", k.Primary())
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_keyring")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "k1.key")
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte(hex.EncodeToString(bytes.Repeat([]byte{1}, KeySize))+"\n"), 0600))
+
+	k, err := LoadKeyring(map[string]string{"k1": keyFile}, "k1")
+	assert.NoError(t, err)
+	assert.Equal(t, "k1", k.Primary())
+	_, err = LoadKeyring(map[string]string{"k1": filepath.Join(dir, "missing.key")}, "k1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package killswitch implements the emergency suspension of all
+// interception of a network.
+package killswitch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+var logger = logging.New(logging.ComponentMain)
+
+var (
+	// ErrAlreadyActive is returned when activating an active kill switch
+	ErrAlreadyActive = errors.New("kill switch is already active")
+	// ErrNotActive is returned when deactivating an inactive kill switch
+	ErrNotActive = errors.New("kill switch is not active")
+)
+
+// Switch suspends all interception of a network. Its state is persisted so
+// that interception stays suspended across restarts, and every operation is
+// recorded in the audit log of the network before taking effect.
+type Switch struct {
+	storage storage.NProbeStorage
+
+	mutex      sync.Mutex
+	idle       *sync.Cond
+	kills      map[string]chan struct{}
+	inflight   map[string]int
+	onActivate []func(networkID string)
+}
+
+// NewSwitch creates a new kill switch persisted in storage
+func NewSwitch(storage storage.NProbeStorage) *Switch {
+	s := &Switch{
+		storage:  storage,
+		kills:    map[string]chan struct{}{},
+		inflight: map[string]int{},
+	}
+	s.idle = sync.NewCond(&s.mutex)
+	return s
+}
+
+// OnActivate registers a function called once the kill switch of a network is activated
+func (s *Switch) OnActivate(f func(networkID string)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.onActivate = append(s.onActivate, f)
+}
+
+// Activate suspends interception of a network. The state of all tasks is
+// marked suspended so that a terminal record is delivered for each of them
+// once interception is resumed. In-flight processing of the network is
+// cancelled through the contexts returned by Context, and tasks are only
+// marked once it has completed so that their state isn't overwritten.
+func (s *Switch) Activate(networkID, actor, reason string) (*models.NetworkProbeKillSwitch, error) {
+	current, err := s.storage.GetKillSwitch(networkID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Active {
+		return nil, ErrAlreadyActive
+	}
+
+	now := strfmt.DateTime(clock.Now())
+	err = s.storage.StoreAuditEntry(networkID, models.NetworkProbeAuditEntry{
+		Action:    models.NetworkProbeAuditEntryActionActivateKillSwitch,
+		Actor:     actor,
+		Reason:    reason,
+		Timestamp: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	killSwitch := models.NetworkProbeKillSwitch{
+		Active:      true,
+		ActivatedAt: now,
+		ActivatedBy: actor,
+		Reason:      reason,
+	}
+	if err := s.storage.StoreKillSwitch(networkID, killSwitch); err != nil {
+		return nil, err
+	}
+	logger.Warningf("Kill switch of network %s activated by %s: %s", networkID, actor, reason)
+
+	s.kill(networkID)
+	s.waitIdle(networkID)
+	if err := s.storage.SuspendAllNProbeData(networkID, time.Time(now)); err != nil {
+		logger.Errorf("Failed to queue terminal records of network %s: %v", networkID, err)
+		return &killSwitch, err
+	}
+	return &killSwitch, nil
+}
+
+// Deactivate resumes interception of a network
+func (s *Switch) Deactivate(networkID, actor string) error {
+	current, err := s.storage.GetKillSwitch(networkID)
+	if err != nil {
+		return err
+	}
+	if !current.Active {
+		return ErrNotActive
+	}
+
+	err = s.storage.StoreAuditEntry(networkID, models.NetworkProbeAuditEntry{
+		Action:    models.NetworkProbeAuditEntryActionDeactivateKillSwitch,
+		Actor:     actor,
+		Timestamp: strfmt.DateTime(time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.storage.DeleteKillSwitch(networkID); err != nil {
+		return err
+	}
+	logger.Warningf("Kill switch of network %s deactivated by %s", networkID, actor)
+
+	s.mutex.Lock()
+	delete(s.kills, networkID)
+	s.mutex.Unlock()
+	return nil
+}
+
+// Get returns the kill switch of a network
+func (s *Switch) Get(networkID string) (*models.NetworkProbeKillSwitch, error) {
+	return s.storage.GetKillSwitch(networkID)
+}
+
+// GetAuditEntries returns the audit log of the kill switch of a network,
+// leaving out the other entries of the audit log of the network
+func (s *Switch) GetAuditEntries(networkID string) ([]models.NetworkProbeAuditEntry, error) {
+	entries, err := s.storage.GetAuditEntries(networkID)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]models.NetworkProbeAuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		switch entry.Action {
+		case models.NetworkProbeAuditEntryActionActivateKillSwitch, models.NetworkProbeAuditEntryActionDeactivateKillSwitch:
+			ret = append(ret, entry)
+		}
+	}
+	return ret, nil
+}
+
+// IsActive returns true if interception of a network is suspended
+func (s *Switch) IsActive(networkID string) (bool, error) {
+	killSwitch, err := s.storage.GetKillSwitch(networkID)
+	if err != nil {
+		return false, err
+	}
+	return killSwitch.Active, nil
+}
+
+// Context returns a context cancelled when the parent is done or when
+// the kill switch of the network is activated. The processing of the
+// network is in-flight until the returned cancel function is called.
+func (s *Switch) Context(parent context.Context, networkID string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	s.mutex.Lock()
+	kill := s.getKillChannel(networkID)
+	s.inflight[networkID]++
+	s.mutex.Unlock()
+
+	go func() {
+		select {
+		case <-kill:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var once sync.Once
+	release := func() {
+		cancel()
+		once.Do(func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			s.inflight[networkID]--
+			if s.inflight[networkID] == 0 {
+				delete(s.inflight, networkID)
+				s.idle.Broadcast()
+			}
+		})
+	}
+	return ctx, release
+}
+
+// getKillChannel returns the kill channel of a network, s.mutex must be held
+func (s *Switch) getKillChannel(networkID string) chan struct{} {
+	kill, ok := s.kills[networkID]
+	if !ok {
+		kill = make(chan struct{})
+		s.kills[networkID] = kill
+	}
+	return kill
+}
+
+// waitIdle waits until no processing of the network is in-flight
+func (s *Switch) waitIdle(networkID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for s.inflight[networkID] > 0 {
+		s.idle.Wait()
+	}
+}
+
+// kill cancels the contexts of a network and notifies the registered functions
+func (s *Switch) kill(networkID string) {
+	s.mutex.Lock()
+	kill := s.getKillChannel(networkID)
+	select {
+	case <-kill:
+	default:
+		close(kill)
+	}
+	onActivate := s.onActivate
+	s.mutex.Unlock()
+
+	for _, f := range onActivate {
+		f(networkID)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package latency tracks the latency of the records delivered to each
+// destination, from their encoding and from the event they were encoded
+// from, so that operators can prove the delivery latency meets their SLAs.
+package latency
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+)
+
+// DefaultWindow is the number of records of each destination the
+// percentiles are computed over
+const DefaultWindow = 1024
+
+// window holds the latencies of the last records delivered to a destination
+type window struct {
+	encodeToDeliver []time.Duration
+	eventToDeliver  []time.Duration
+	// next is the index of the oldest samples once the window is full
+	next int
+}
+
+// Tracker holds the latency of the last records delivered to each destination
+type Tracker struct {
+	mutex   sync.Mutex
+	size    int
+	windows map[string]*window
+}
+
+// NewTracker creates a tracker keeping the latency of the last size records
+// delivered to each destination
+func NewTracker(size int) *Tracker {
+	if size <= 0 {
+		size = DefaultWindow
+	}
+	return &Tracker{size: size, windows: map[string]*window{}}
+}
+
+// Observe records the latency of a record delivered to a destination, from
+// its encoding and from the event it was encoded from
+func (t *Tracker) Observe(destination string, encodeToDeliver, eventToDeliver time.Duration) {
+	metrics.EncodeToDeliverLatency.WithLabelValues(destination).Observe(encodeToDeliver.Seconds())
+	metrics.EventToDeliverLatency.WithLabelValues(destination).Observe(eventToDeliver.Seconds())
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	w, ok := t.windows[destination]
+	if !ok {
+		w = &window{}
+		t.windows[destination] = w
+	}
+	if len(w.encodeToDeliver) < t.size {
+		w.encodeToDeliver = append(w.encodeToDeliver, encodeToDeliver)
+		w.eventToDeliver = append(w.eventToDeliver, eventToDeliver)
+		return
+	}
+	w.encodeToDeliver[w.next] = encodeToDeliver
+	w.eventToDeliver[w.next] = eventToDeliver
+	w.next = (w.next + 1) % t.size
+}
+
+// Get returns the latency percentiles of each destination, by destination
+func (t *Tracker) Get() []*models.NetworkProbeDestinationLatency {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	ret := make([]*models.NetworkProbeDestinationLatency, 0, len(t.windows))
+	for destination, w := range t.windows {
+		ret = append(ret, &models.NetworkProbeDestinationLatency{
+			Destination:     destination,
+			Records:         int64(len(w.encodeToDeliver)),
+			EncodeToDeliver: getPercentiles(w.encodeToDeliver),
+			EventToDeliver:  getPercentiles(w.eventToDeliver),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Destination < ret[j].Destination })
+	return ret
+}
+
+// getPercentiles returns the percentiles of latency samples, using the
+// nearest-rank method
+func getPercentiles(samples []time.Duration) *models.NetworkProbeLatencyPercentiles {
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return toMilliseconds(sorted[rank])
+	}
+	return &models.NetworkProbeLatencyPercentiles{
+		P50Ms: percentile(0.5),
+		P95Ms: percentile(0.95),
+		P99Ms: percentile(0.99),
+		MaxMs: toMilliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+func toMilliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package latency
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(100)
+	assert.Empty(t, tracker.Get())
+
+	for i := 1; i <= 100; i++ {
+		tracker.Observe("10.0.2.1:4000", time.Duration(i)*time.Millisecond, time.Duration(i)*time.Second)
+	}
+	tracker.Observe("kafka/records", time.Millisecond, time.Second)
+
+	stats := tracker.Get()
+	assert.Equal(t, []*models.NetworkProbeDestinationLatency{
+		{
+			Destination:     "10.0.2.1:4000",
+			Records:         100,
+			EncodeToDeliver: &models.NetworkProbeLatencyPercentiles{P50Ms: 50, P95Ms: 95, P99Ms: 99, MaxMs: 100},
+			EventToDeliver:  &models.NetworkProbeLatencyPercentiles{P50Ms: 50000, P95Ms: 95000, P99Ms: 99000, MaxMs: 100000},
+		},
+		{
+			Destination:     "kafka/records",
+			Records:         1,
+			EncodeToDeliver: &models.NetworkProbeLatencyPercentiles{P50Ms: 1, P95Ms: 1, P99Ms: 1, MaxMs: 1},
+			EventToDeliver:  &models.NetworkProbeLatencyPercentiles{P50Ms: 1000, P95Ms: 1000, P99Ms: 1000, MaxMs: 1000},
+		},
+	}, stats)
+
+	// the oldest records leave the window
+	for i := 0; i < 100; i++ {
+		tracker.Observe("10.0.2.1:4000", 2*time.Millisecond, 2*time.Second)
+	}
+	stats = tracker.Get()
+	assert.Equal(t, int64(100), stats[0].Records)
+	assert.Equal(t, &models.NetworkProbeLatencyPercentiles{P50Ms: 2, P95Ms: 2, P99Ms: 2, MaxMs: 2}, stats[0].EncodeToDeliver)
+}
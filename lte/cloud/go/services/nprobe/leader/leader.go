@@ -0,0 +1,264 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package leader elects the single nprobe instance processing tasks when
+// several replicas are deployed, the others standing by.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/storage"
+
+	"github.com/gofrs/uuid"
+)
+
+var logger = logging.New(logging.ComponentMain)
+
+// Elector campaigns for the lease granting the processing of tasks to a
+// single instance. The lease is renewed every third of its duration while
+// held. A term ends as soon as the lease is held by another instance, or
+// once it expires without being renewed, e.g. when storage is unreachable,
+// so that this instance stops processing before another one can take over.
+type Elector struct {
+	storage  storage.NProbeStorage
+	holder   string
+	duration time.Duration
+
+	// campaignMutex serializes the storage operations on the lease
+	campaignMutex sync.Mutex
+	resigned      bool
+
+	mutex     sync.Mutex
+	leading   bool
+	epoch     uint64
+	expiresAt time.Time
+	expiry    *time.Timer
+	ended     chan struct{}
+	elected   chan struct{}
+	onEnd     []func()
+}
+
+// NewElector creates a new elector campaigning for holder, the identity of
+// this instance, with a lease of the given duration
+func NewElector(storage storage.NProbeStorage, holder string, duration time.Duration) *Elector {
+	return &Elector{
+		storage:  storage,
+		holder:   holder,
+		duration: duration,
+		elected:  make(chan struct{}, 1),
+	}
+}
+
+// NewInstanceID returns the identity of this instance in the lease, unique
+// even among the processes of a host so that a duplicated process can't
+// share the lease of the running one
+func NewInstanceID() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%d/%s", hostname, os.Getpid(), id), nil
+}
+
+// OnTermEnd registers a function called once a term of this instance ends
+func (e *Elector) OnTermEnd(f func()) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.onEnd = append(e.onEnd, f)
+}
+
+// Register acquires the lease for an instance meant to be the only one
+// processing tasks, e.g. without leader election, and fails if another
+// instance is running. The lease left by an instance which stopped without
+// releasing it is awaited until it expires, while a lease renewed meanwhile
+// belongs to a running instance.
+func (e *Elector) Register(ctx context.Context) error {
+	var held *storage.Lease
+	for {
+		e.campaignMutex.Lock()
+		now := time.Now()
+		lease, err := e.storage.AcquireLease(e.holder, now, e.duration)
+		if err == nil && lease.Holder == e.holder {
+			e.renew(lease.Epoch, now.Add(e.duration))
+		}
+		e.campaignMutex.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to acquire lease: %v", err)
+		}
+		if lease.Holder == e.holder {
+			return nil
+		}
+		if held != nil && lease.Holder == held.Holder && lease.ExpiresAt.After(held.ExpiresAt) {
+			return fmt.Errorf("instance %s is running and holds the lease until %s", lease.Holder, lease.ExpiresAt)
+		}
+		held = lease
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(lease.ExpiresAt)):
+		}
+	}
+}
+
+// Run campaigns for the lease until ctx is done. The lease is not released
+// then, since pending state may still be persisted, until Resign is called.
+func (e *Elector) Run(ctx context.Context) {
+	for {
+		e.campaign()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.duration / 3):
+		}
+	}
+}
+
+// Leading returns the epoch of the current term if this instance holds the lease
+func (e *Elector) Leading() (uint64, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.epoch, e.leading
+}
+
+// Elected returns a channel receiving a value once this instance starts a term
+func (e *Elector) Elected() <-chan struct{} {
+	return e.elected
+}
+
+// Context returns a context cancelled when the parent is done or when the
+// current term ends, right away if this instance does not hold the lease.
+func (e *Elector) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	e.mutex.Lock()
+	ended := e.ended
+	leading := e.leading
+	e.mutex.Unlock()
+	if !leading {
+		cancel()
+		return ctx, cancel
+	}
+
+	go func() {
+		select {
+		case <-ended:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// Resign stops campaigning and releases the lease if held, so that another
+// instance takes over without waiting for it to expire
+func (e *Elector) Resign() {
+	e.campaignMutex.Lock()
+	defer e.campaignMutex.Unlock()
+	e.resigned = true
+	e.endTerm()
+	if err := e.storage.ReleaseLease(e.holder); err != nil {
+		logger.Errorf("Failed to release lease: %v", err)
+	}
+}
+
+// campaign acquires or renews the lease
+func (e *Elector) campaign() {
+	e.campaignMutex.Lock()
+	defer e.campaignMutex.Unlock()
+	if e.resigned {
+		return
+	}
+
+	// the term may not outlive the lease, whose duration starts before the request
+	now := time.Now()
+	lease, err := e.storage.AcquireLease(e.holder, now, e.duration)
+	if err != nil {
+		logger.Errorf("Failed to acquire lease: %v", err)
+		return
+	}
+	if lease.Holder != e.holder {
+		e.endTerm()
+		return
+	}
+	e.renew(lease.Epoch, now.Add(e.duration))
+}
+
+// renew extends the current term, or starts a new one if the lease was
+// acquired for another epoch
+func (e *Elector) renew(epoch uint64, expiresAt time.Time) {
+	e.mutex.Lock()
+	if e.leading && e.epoch == epoch {
+		e.expiresAt = expiresAt
+		e.expiry.Reset(time.Until(expiresAt))
+		e.mutex.Unlock()
+		return
+	}
+	e.mutex.Unlock()
+	e.endTerm()
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.leading = true
+	e.epoch = epoch
+	e.expiresAt = expiresAt
+	e.ended = make(chan struct{})
+	e.expiry = time.AfterFunc(time.Until(expiresAt), func() { e.expire(epoch) })
+	select {
+	case e.elected <- struct{}{}:
+	default:
+	}
+	metrics.Leader.Set(1)
+	logger.Infof("Instance %s started term %d", e.holder, epoch)
+}
+
+// expire ends a term once its lease expired without being renewed
+func (e *Elector) expire(epoch uint64) {
+	e.mutex.Lock()
+	expired := e.leading && e.epoch == epoch && !time.Now().Before(e.expiresAt)
+	e.mutex.Unlock()
+	if expired {
+		logger.Warningf("Lease of term %d expired without being renewed", epoch)
+		e.endTerm()
+	}
+}
+
+// endTerm ends the current term if any and notifies the registered functions
+func (e *Elector) endTerm() {
+	e.mutex.Lock()
+	if !e.leading {
+		e.mutex.Unlock()
+		return
+	}
+	e.leading = false
+	e.expiry.Stop()
+	close(e.ended)
+	onEnd := e.onEnd
+	epoch := e.epoch
+	e.mutex.Unlock()
+
+	metrics.Leader.Set(0)
+	logger.Infof("Instance %s ended term %d", e.holder, epoch)
+	for _, f := range onEnd {
+		f()
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leader
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// leaseStorage keeps the lease in memory
+type leaseStorage struct {
+	storage.NProbeStorage
+	mutex sync.Mutex
+	lease storage.Lease
+}
+
+func (s *leaseStorage) AcquireLease(holder string, now time.Time, duration time.Duration) (*storage.Lease, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.lease.Holder != holder && now.Before(s.lease.ExpiresAt) {
+		lease := s.lease
+		return &lease, nil
+	}
+	if s.lease.Holder != holder {
+		s.lease.Holder = holder
+		s.lease.Epoch++
+	}
+	s.lease.ExpiresAt = now.Add(duration)
+	lease := s.lease
+	return &lease, nil
+}
+
+func (s *leaseStorage) ReleaseLease(holder string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.lease.Holder == holder {
+		s.lease.ExpiresAt = time.Time{}
+	}
+	return nil
+}
+
+func TestElector(t *testing.T) {
+	store := &leaseStorage{}
+	a := NewElector(store, "nprobe-0", 100*time.Millisecond)
+	b := NewElector(store, "nprobe-1", 100*time.Millisecond)
+	ended := make(chan struct{}, 1)
+	a.OnTermEnd(func() { ended <- struct{}{} })
+
+	// a single instance holds the lease
+	a.campaign()
+	b.campaign()
+	epoch, leading := a.Leading()
+	assert.True(t, leading)
+	assert.Equal(t, uint64(1), epoch)
+	_, leading = b.Leading()
+	assert.False(t, leading)
+	<-a.Elected()
+
+	ctx, cancel := b.Context(context.Background())
+	defer cancel()
+	assert.Error(t, ctx.Err())
+
+	// the term ends once the lease expires without being renewed
+	ctx, cancel = a.Context(context.Background())
+	defer cancel()
+	a.campaign()
+	assert.NoError(t, ctx.Err())
+	<-ctx.Done()
+	<-ended
+	_, leading = a.Leading()
+	assert.False(t, leading)
+
+	// another instance then takes over for a new epoch
+	b.campaign()
+	epoch, leading = b.Leading()
+	assert.True(t, leading)
+	assert.Equal(t, uint64(2), epoch)
+	<-b.Elected()
+
+	// and hands over right away when resigning
+	a.campaign()
+	_, leading = a.Leading()
+	assert.False(t, leading)
+	b.Resign()
+	_, leading = b.Leading()
+	assert.False(t, leading)
+	a.campaign()
+	epoch, leading = a.Leading()
+	assert.True(t, leading)
+	assert.Equal(t, uint64(3), epoch)
+
+	// resigned instances no longer campaign
+	a.Resign()
+	b.campaign()
+	_, leading = b.Leading()
+	assert.False(t, leading)
+}
+
+func TestRegister(t *testing.T) {
+	store := &leaseStorage{}
+	a := NewElector(store, "nprobe-0", 100*time.Millisecond)
+	b := NewElector(store, "nprobe-1", 100*time.Millisecond)
+
+	// a single instance registers without waiting
+	assert.NoError(t, a.Register(context.Background()))
+	_, leading := a.Leading()
+	assert.True(t, leading)
+	<-a.Elected()
+
+	// a duplicated instance is refused while the running one renews the lease
+	ctx, cancel := context.WithCancel(context.Background())
+	go a.Run(ctx)
+	err := b.Register(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "instance nprobe-0 is running")
+	_, leading = b.Leading()
+	assert.False(t, leading)
+
+	// and registers once the running one resigned
+	cancel()
+	a.Resign()
+	assert.NoError(t, b.Register(context.Background()))
+	epoch, leading := b.Leading()
+	assert.True(t, leading)
+	assert.Equal(t, uint64(2), epoch)
+
+	// the lease of a stopped instance is awaited until it expires
+	c := NewElector(store, "nprobe-2", 100*time.Millisecond)
+	start := time.Now()
+	assert.NoError(t, c.Register(context.Background()))
+	assert.True(t, time.Since(start) < time.Second)
+	epoch, leading = c.Leading()
+	assert.True(t, leading)
+	assert.Equal(t, uint64(3), epoch)
+
+	// registration gives up when the context is done
+	d := NewElector(store, "nprobe-3", time.Minute)
+	c.Resign()
+	assert.NoError(t, d.Register(context.Background()))
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, b.Register(ctx))
+	d.Resign()
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging structures the logs of the nprobe service. Each line is
+// written by glog along with the component logging it and key=value fields,
+// e.g. the task, XID, destination and sequence number of a record, so that
+// the lines about a task can be followed across components:
+//
+//	component=manager task_id=t1 xid=5b5a... msg="Failed to export events: ..."
+//
+// The verbosity of each component can be raised at runtime, from the service
+// config or the debug settings, on top of the glog verbosity set by -v or
+// the SetLogVerbosity method of service303, which applies to all of them.
+// A component can thus be debugged without flooding the logs with the
+// others.
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// Components of the service logging with their own verbosity
+const (
+	ComponentMain      = "main"
+	ComponentManager   = "manager"
+	ComponentExporter  = "exporter"
+	ComponentHandlers  = "handlers"
+	ComponentServicers = "servicers"
+)
+
+// Keys of the fields of the lines
+const (
+	FieldNetworkID   = "network_id"
+	FieldTaskID      = "task_id"
+	FieldXID         = "xid"
+	FieldDestination = "destination"
+	FieldSeq         = "seq"
+)
+
+// Components are the components of the service, sorted
+var Components = []string{ComponentExporter, ComponentHandlers, ComponentMain, ComponentManager, ComponentServicers}
+
+var (
+	levelsMutex sync.RWMutex
+	// configuredLevels and debugLevels are the verbosities of the components
+	// set by the service config and by the debug settings, the highest one
+	// applying
+	configuredLevels = map[string]glog.Level{}
+	debugLevels      = map[string]glog.Level{}
+)
+
+// IsComponent returns true if a component of the service is named so
+func IsComponent(component string) bool {
+	i := sort.SearchStrings(Components, component)
+	return i < len(Components) && Components[i] == component
+}
+
+// SetConfiguredLevels replaces the verbosities of the components set by the
+// service config
+func SetConfiguredLevels(levels map[string]uint32) {
+	setLevels(&configuredLevels, levels)
+}
+
+// SetDebugLevels replaces the verbosities of the components set by the
+// debug settings
+func SetDebugLevels(levels map[string]uint32) {
+	setLevels(&debugLevels, levels)
+}
+
+func setLevels(dst *map[string]glog.Level, levels map[string]uint32) {
+	converted := make(map[string]glog.Level, len(levels))
+	for component, level := range levels {
+		converted[component] = glog.Level(level)
+	}
+	levelsMutex.Lock()
+	defer levelsMutex.Unlock()
+	*dst = converted
+}
+
+// GetLevel returns the verbosity of a component on top of the glog verbosity
+func GetLevel(component string) glog.Level {
+	levelsMutex.RLock()
+	defer levelsMutex.RUnlock()
+	level := configuredLevels[component]
+	if debugLevel := debugLevels[component]; debugLevel > level {
+		level = debugLevel
+	}
+	return level
+}
+
+// Logger writes the lines of a component with its fields
+type Logger struct {
+	component string
+	// prefix holds the component and fields rendered
+	prefix string
+}
+
+// New returns the logger of a component
+func New(component string) *Logger {
+	return &Logger{component: component, prefix: "component=" + formatValue(component)}
+}
+
+// With returns a logger adding fields to the lines of l, from alternating
+// keys and values, e.g. With(FieldTaskID, taskID). Empty values are left
+// out.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	var b strings.Builder
+	b.WriteString(l.prefix)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		value := fmt.Sprint(keyvals[i+1])
+		if len(value) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, " %v=%s", keyvals[i], formatValue(value))
+	}
+	return &Logger{component: l.component, prefix: b.String()}
+}
+
+// Infof logs a line at the info level
+func (l *Logger) Infof(format string, args ...interface{}) {
+	glog.InfoDepth(1, l.format(format, args...))
+}
+
+// Warningf logs a line at the warning level
+func (l *Logger) Warningf(format string, args ...interface{}) {
+	glog.WarningDepth(1, l.format(format, args...))
+}
+
+// Errorf logs a line at the error level
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	glog.ErrorDepth(1, l.format(format, args...))
+}
+
+// Fatalf logs a line at the fatal level and exits
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	glog.FatalDepth(1, l.format(format, args...))
+}
+
+// V returns a logger of the lines at a verbosity level, logged when the
+// verbosity of the component or the glog verbosity is at least the level
+func (l *Logger) V(level glog.Level) Verbose {
+	return Verbose{logger: l, enabled: bool(glog.V(level)) || GetLevel(l.component) >= level}
+}
+
+// format renders a line, the trailing newlines of printf-style messages
+// being trimmed so that they don't end up quoted in the message
+func (l *Logger) format(format string, args ...interface{}) string {
+	return l.prefix + " msg=" + formatValue(strings.TrimRight(fmt.Sprintf(format, args...), "\n"))
+}
+
+// Verbose logs the lines of a logger at a verbosity level
+type Verbose struct {
+	logger  *Logger
+	enabled bool
+}
+
+// Enabled returns true if the lines at the verbosity level are logged
+func (v Verbose) Enabled() bool {
+	return v.enabled
+}
+
+// Infof logs a line at the info level if its verbosity level is enabled
+func (v Verbose) Infof(format string, args ...interface{}) {
+	if v.enabled {
+		glog.InfoDepth(1, v.logger.format(format, args...))
+	}
+}
+
+// formatValue quotes the values holding spaces, quotes or equal signs
+func formatValue(value string) string {
+	if len(value) == 0 || strings.ContainsAny(value, " \t\n\"=") {
+		return fmt.Sprintf("%q", value)
+	}
+	return value
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"testing"
+
+	"github.com/golang/glog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerFields(t *testing.T) {
+	logger := New(ComponentManager)
+	assert.Equal(t, `component=manager msg="Exported 3 records"`, logger.format("Exported %d records", 3))
+
+	taskLogger := logger.With(FieldTaskID, "t1", FieldXID, "", FieldSeq, uint32(7))
+	assert.Equal(t, "component=manager task_id=t1 seq=7 msg=done", taskLogger.format("done"))
+	assert.Equal(t, `component=manager task_id=t1 seq=7 destination="a b" msg="x=\"y\""`,
+		taskLogger.With(FieldDestination, "a b").format(`x="y"`))
+	// the fields of the parent are left untouched
+	assert.Equal(t, "component=manager msg=done", logger.format("done"))
+	// trailing newlines aren't quoted in the message
+	assert.Equal(t, `component=manager msg="Failed to build record: bad"`, logger.format("Failed to build record: %s\n", "bad"))
+}
+
+func TestComponentLevels(t *testing.T) {
+	defer SetConfiguredLevels(nil)
+	defer SetDebugLevels(nil)
+	manager, exporter := New(ComponentManager), New(ComponentExporter)
+	assert.False(t, manager.V(2).Enabled())
+
+	// the highest verbosity of the config and the debug settings applies to
+	// its component alone
+	SetConfiguredLevels(map[string]uint32{ComponentManager: 1})
+	SetDebugLevels(map[string]uint32{ComponentManager: 2})
+	assert.Equal(t, glog.Level(2), GetLevel(ComponentManager))
+	assert.True(t, manager.V(2).Enabled())
+	assert.False(t, manager.V(3).Enabled())
+	assert.False(t, exporter.V(1).Enabled())
+	assert.True(t, manager.With(FieldTaskID, "t1").V(2).Enabled())
+
+	SetDebugLevels(nil)
+	assert.Equal(t, glog.Level(1), GetLevel(ComponentManager))
+	assert.False(t, manager.V(2).Enabled())
+
+	assert.True(t, IsComponent(ComponentHandlers))
+	assert.False(t, IsComponent("streamer"))
+}
@@ -0,0 +1,584 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics contains the prometheus metrics exposed by the nprobe service.
+package metrics
+
+import (
+	"magma/orc8r/lib/go/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// FieldLabelName is the label of the field responsible for an encoding or validation failure
+	FieldLabelName = "field"
+	// RecordClassLabelName is the label of the class of a record, e.g. begin or end
+	RecordClassLabelName = "record_class"
+	// ReasonLabelName is the label of the reason records are withheld, e.g. kill_switch
+	ReasonLabelName = "reason"
+	// ThrottleActionLabelName is the label of the handling of a throttled record, e.g. delayed
+	ThrottleActionLabelName = "action"
+	// ComponentLabelName is the label of a component of the service, e.g. manager
+	ComponentLabelName = "component"
+	// StateKindLabelName is the label of the kind of state reclaimed, e.g. session
+	StateKindLabelName = "kind"
+	// AlarmLabelName is the label of the rate alarm of a task, e.g. silence
+	AlarmLabelName = "alarm"
+	// DestinationLabelName is the label of the destination of a record, e.g. its delivery function
+	DestinationLabelName = "destination"
+	// NotificationLabelName is the label of the HI1 notification of a task, e.g. activated
+	NotificationLabelName = "notification"
+	// TransitionLabelName is the label of the switch between the delivery functions of a destination, e.g. failover
+	TransitionLabelName = "transition"
+	// QuotaLabelName is the label of the quota of a network, e.g. tasks
+	QuotaLabelName = "quota"
+	// DeletionLabelName is the label of the way a task was deleted, e.g. ended
+	DeletionLabelName = "deletion"
+	// FailureClassLabelName is the label of the class of a delivery failure, e.g. tls_handshake
+	FailureClassLabelName = "failure_class"
+	// CompressionLabelName is the label of the compression of a delivery connection, e.g. gzip
+	CompressionLabelName = "compression"
+	// SourceLabelName is the label of an event source, e.g. eventd
+	SourceLabelName = "source"
+	// AlertLabelName is the label of an operational alert, e.g. task_activated
+	AlertLabelName = "alert"
+	// CertificateKindLabelName is the label of the kind of a certificate, e.g. client
+	CertificateKindLabelName = "kind"
+	// CertificateLabelName is the label of a certificate, its file or the address of its delivery function
+	CertificateLabelName = "certificate"
+	// TaskStateLabelName is the label of a state of the lifecycle of a task, e.g. suspended
+	TaskStateLabelName = "state"
+
+	// FailClosedKillSwitch is the reason of networks whose kill switch can't be read
+	FailClosedKillSwitch = "kill_switch"
+	// FailClosedTargetFilter is the reason of tasks whose target can't be resolved
+	FailClosedTargetFilter = "target_filter"
+
+	// FetchDeferredMaxPages is the reason of tasks which fetched as many pages of events as allowed per run
+	FetchDeferredMaxPages = "max_pages"
+	// FetchDeferredBackpressure is the reason of tasks held back while the export queue is above its high watermark
+	FetchDeferredBackpressure = "backpressure"
+	// FetchDeferredQuota is the reason of tasks held back while their network exceeds its records quota
+	FetchDeferredQuota = "quota"
+
+	// QuotaTasks is the quota of the tasks provisioned in a network
+	QuotaTasks = "tasks"
+	// QuotaRecords is the quota of the records exported per second for a network
+	QuotaRecords = "records"
+
+	// TransitionFailover is the switch of a destination to its secondary delivery function
+	TransitionFailover = "failover"
+	// TransitionFailback is the switch of a destination back to its primary delivery function
+	TransitionFailback = "failback"
+	// TransitionBreakerOpen is the trip of the circuit breaker of a destination, shedding its records
+	TransitionBreakerOpen = "breaker_open"
+	// TransitionBreakerHalfOpen is the probe of a destination whose circuit breaker is open
+	TransitionBreakerHalfOpen = "breaker_half_open"
+	// TransitionBreakerClose is the restoration of the delivery to a destination by a successful probe
+	TransitionBreakerClose = "breaker_close"
+
+	// ReclaimedTaskState is the kind of the state left behind by deleted tasks
+	ReclaimedTaskState = "task_state"
+	// ReclaimedSession is the kind of the idle sessions whose interception was ended
+	ReclaimedSession = "session"
+	// ReclaimedNetwork is the kind of the state left behind by deleted networks
+	ReclaimedNetwork = "network"
+
+	// DeletionEnded is the deletion of a task whose interception was ended with an IRI-END
+	DeletionEnded = "ended"
+	// DeletionTimedOut is the deletion of a task whose IRI-END couldn't be delivered in time
+	DeletionTimedOut = "timed_out"
+	// DeletionHeld is the deletion of a task held beyond the task quota of its network
+	DeletionHeld = "held"
+)
+
+var (
+	EventsFetched = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_events_fetched_total",
+			Help: "Number of events fetched from the event sources for intercepted targets",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	SourceFetchFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_source_fetch_failures_total",
+			Help: "Number of failed fetches of events from an event source",
+		},
+		[]string{SourceLabelName},
+	)
+	FetchesDeferred = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_event_fetches_deferred_total",
+			Help: "Number of times tasks left events to fetch until the next run, by reason",
+		},
+		[]string{metrics.NetworkLabelName, ReasonLabelName},
+	)
+	EventsIngested = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_events_ingested_total",
+			Help: "Number of intercepted events streamed by gateways",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	RecordsEncoded = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_records_encoded_total",
+			Help: "Number of IRI records successfully encoded",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	EncodeFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_record_encode_failures_total",
+			Help: "Number of events that could not be encoded into an IRI record, by offending field",
+		},
+		[]string{metrics.NetworkLabelName, FieldLabelName},
+	)
+	DecodeFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_event_decode_failures_total",
+			Help: "Number of events whose data could not be decoded into an IRI record, by offending field",
+		},
+		[]string{metrics.NetworkLabelName, FieldLabelName},
+	)
+	ValidationFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_record_validation_failures_total",
+			Help: "Number of encoded IRI records found malformed before export, by offending field",
+		},
+		[]string{metrics.NetworkLabelName, FieldLabelName},
+	)
+	MinimalRecordsEncoded = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_minimal_records_encoded_total",
+			Help: "Number of minimal IRI records encoded from events whose fields could not be encoded, by offending field",
+		},
+		[]string{metrics.NetworkLabelName, FieldLabelName},
+	)
+	RecordsExported = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_records_exported_total",
+			Help: "Number of IRI records delivered to the remote collector",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	ExportFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_record_export_failures_total",
+			Help: "Number of IRI records that could not be delivered after all retries",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	RecordsDeadLettered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_records_dead_lettered_total",
+			Help: "Number of IRI records set aside after being rejected by the remote collector repeatedly",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	QuotaExceeded = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_quota_exceeded_total",
+			Help: "Number of times a network hit one of its quotas, by quota",
+		},
+		[]string{metrics.NetworkLabelName, QuotaLabelName},
+	)
+	ProtectedTargetsRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_protected_targets_rejected_total",
+			Help: "Number of tasks rejected for targeting a protected identity",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	TasksDeleted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_tasks_deleted_total",
+			Help: "Number of tasks deleted once their deletion was requested, by deletion",
+		},
+		[]string{metrics.NetworkLabelName, DeletionLabelName},
+	)
+	TasksExpired = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_tasks_expired_total",
+			Help: "Number of tasks whose warrant ended, their interception being ended with an IRI-END",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	TaskStateTransitions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_task_state_transitions_total",
+			Help: "Number of transitions of tasks to a state of their lifecycle, by state entered",
+		},
+		[]string{metrics.NetworkLabelName, TaskStateLabelName},
+	)
+	SubscriptionStateChanges = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_subscription_state_changes_total",
+			Help: "Number of changes of the subscriber states streamed by the state service",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	SubscriptionLive = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nprobe_subscription_live",
+			Help: "1 if subscriber states are streamed and tasks processed on their changes, 0 if eventd is polled",
+		},
+	)
+	HI1NotificationsSent = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_hi1_notifications_sent_total",
+			Help: "Number of HI1 notifications delivered, by notification",
+		},
+		[]string{metrics.NetworkLabelName, NotificationLabelName},
+	)
+	HI1NotificationFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_hi1_notification_failures_total",
+			Help: "Number of HI1 notifications that could not be delivered, by notification",
+		},
+		[]string{metrics.NetworkLabelName, NotificationLabelName},
+	)
+	SigningFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_record_signing_failures_total",
+			Help: "Number of records that could not be signed",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	EncryptionFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_record_encryption_failures_total",
+			Help: "Number of records whose payload could not be encrypted",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	AlertsRaised = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_alerts_raised_total",
+			Help: "Number of operational alerts raised, repeats within the repeat interval excluded",
+		},
+		[]string{AlertLabelName},
+	)
+	AlertFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_alert_failures_total",
+			Help: "Number of operational alerts that could not be forwarded to the syslog endpoint",
+		},
+		[]string{AlertLabelName},
+	)
+	CorrelationFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_bearer_correlation_failures_total",
+			Help: "Number of failures to allocate or release the correlation ID of a bearer",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	ExportLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "nprobe_record_export_latency_seconds",
+			Help:    "Latency of a single record write to the remote collector, by record class",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+		},
+		[]string{RecordClassLabelName},
+	)
+	DeliveryLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "nprobe_record_delivery_latency_seconds",
+			Help:    "Time from the submission of a record to its delivery, including queuing behind other records, by record class",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+		},
+		[]string{RecordClassLabelName},
+	)
+	EncodeToDeliverLatency = promauto.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       "nprobe_record_encode_to_deliver_latency_seconds",
+			Help:       "Time from the encoding of a record to its delivery, by destination",
+			Objectives: map[float64]float64{0.5: 0.05, 0.95: 0.01, 0.99: 0.001},
+		},
+		[]string{DestinationLabelName},
+	)
+	EventToDeliverLatency = promauto.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       "nprobe_record_event_to_deliver_latency_seconds",
+			Help:       "Time from the event of a record to the delivery of the record, by destination",
+			Objectives: map[float64]float64{0.5: 0.05, 0.95: 0.01, 0.99: 0.001},
+		},
+		[]string{DestinationLabelName},
+	)
+	RecordsSent = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_records_sent_total",
+			Help: "Number of records written to the remote collector, by record class",
+		},
+		[]string{RecordClassLabelName},
+	)
+	BytesSent = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_bytes_sent_total",
+			Help: "Number of record bytes written to the remote collector, by record class",
+		},
+		[]string{RecordClassLabelName},
+	)
+	RecordsThrottled = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_records_throttled_total",
+			Help: "Number of records throttled by the export rate limiter: delayed for a token, spilled back to their task or dropped while the queue is full",
+		},
+		[]string{ThrottleActionLabelName},
+	)
+	ExportQueueSize = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nprobe_exporter_queued_records",
+			Help: "Number of records waiting in the export queue",
+		},
+	)
+	StateReclaimed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_state_reclaimed_total",
+			Help: "Number of orphaned task states, deleted networks and idle sessions reclaimed by the state sweeper, by kind",
+		},
+		[]string{StateKindLabelName},
+	)
+	ExportBatchSize = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "nprobe_exporter_batch_records",
+			Help:    "Number of records delivered in a single write with batch export",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		},
+	)
+	SequenceHoles = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_sequence_holes_total",
+			Help: "Number of gaps in the sequence numbers of exported records skipped after the reorder timeout",
+		},
+	)
+	SequenceHoleRecords = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_sequence_hole_records_total",
+			Help: "Number of sequence numbers skipped in the gaps of exported records",
+		},
+	)
+	RecordsReordered = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_reordered_records_total",
+			Help: "Number of records held because they were submitted ahead of their sequence number",
+		},
+	)
+	LateRecords = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_late_records_total",
+			Help: "Number of records submitted after their sequence number was released or skipped",
+		},
+	)
+	TLSReconnects = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_tls_reconnects_total",
+			Help: "Number of TLS connections established by the records exporter",
+		},
+	)
+	TLSResumptions = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_tls_resumptions_total",
+			Help: "Number of TLS connections of the records exporter resuming a previous session",
+		},
+	)
+	CompressionRatio = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "nprobe_exporter_compression_ratio",
+			Help:    "Ratio of the compressed size of the records written on a compressed connection to their size, by compression",
+			Buckets: prometheus.LinearBuckets(0.1, 0.1, 10),
+		},
+		[]string{CompressionLabelName},
+	)
+	CompressionDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "nprobe_exporter_compression_seconds",
+			Help:    "Time spent compressing the records of a write on a compressed connection, by compression",
+			Buckets: prometheus.ExponentialBuckets(0.00001, 2, 14),
+		},
+		[]string{CompressionLabelName},
+	)
+	CompressedBytesSent = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_compressed_bytes_sent_total",
+			Help: "Number of bytes written on compressed connections once compressed, by compression",
+		},
+		[]string{CompressionLabelName},
+	)
+	KeepaliveFailures = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_keepalive_failures_total",
+			Help: "Number of connections closed because keepalives were not acknowledged",
+		},
+	)
+	AckTimeouts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_ack_timeouts_total",
+			Help: "Number of records not acknowledged by the LEMF in time",
+		},
+	)
+	DeliveryFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_exporter_delivery_failures_total",
+			Help: "Number of failed attempts to deliver a record, by failure class",
+		},
+		[]string{FailureClassLabelName},
+	)
+	DeliveryFailovers = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_delivery_failovers_total",
+			Help: "Number of switches between the primary and secondary delivery functions of a destination, by transition",
+		},
+		[]string{DestinationLabelName, TransitionLabelName},
+	)
+	DeliveryOnSecondary = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nprobe_delivery_on_secondary",
+			Help: "1 if the records of a destination are delivered to its secondary delivery function, 0 otherwise",
+		},
+		[]string{DestinationLabelName},
+	)
+	DeliveryBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nprobe_delivery_breaker_state",
+			Help: "State of the circuit breaker of a destination: 0 closed, 1 open and shedding its records, 2 half-open and probing it",
+		},
+		[]string{DestinationLabelName},
+	)
+	DeliveryBreakerTransitions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_delivery_breaker_transitions_total",
+			Help: "Number of transitions of the circuit breaker of a destination, by transition",
+		},
+		[]string{DestinationLabelName, TransitionLabelName},
+	)
+	ProcessingErrors = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_processing_errors_total",
+			Help: "Number of failed processing passes over all nprobe tasks",
+		},
+	)
+	FailClosed = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nprobe_fail_closed",
+			Help: "Set while records are withheld because their scope can't be evaluated: 1 for networks whose kill switch can't be read, the number of tasks whose target can't be resolved otherwise",
+		},
+		[]string{metrics.NetworkLabelName, ReasonLabelName},
+	)
+	RateAlarms = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nprobe_rate_alarms",
+			Help: "Number of tasks whose records are outside their activity bounds, by alarm",
+		},
+		[]string{metrics.NetworkLabelName, AlarmLabelName},
+	)
+	Leader = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nprobe_leader",
+			Help: "1 if this instance holds the lease to process tasks, 0 if it stands by",
+		},
+	)
+	ComponentHealth = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nprobe_component_healthy",
+			Help: "1 if the component of the service was healthy when last checked, 0.5 if degraded, 0 otherwise",
+		},
+		[]string{ComponentLabelName},
+	)
+	LastProcessingTime = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nprobe_last_processing_timestamp_seconds",
+			Help: "Unix time of the last successful processing pass",
+		},
+	)
+	WarmupPendingNetworks = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nprobe_warmup_pending_networks",
+			Help: "Number of networks left to load before the first pass of the current term",
+		},
+	)
+	WarmupDuration = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nprobe_warmup_duration_seconds",
+			Help: "Time taken to load the networks before the first pass of the last term",
+		},
+	)
+	CursorReplicationFailures = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_cursor_replication_failures_total",
+			Help: "Number of networks whose cursors failed to be replicated to the standby replicas",
+		},
+	)
+	ReplicatedCursorsRestored = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "nprobe_replicated_cursors_restored_total",
+			Help: "Number of task cursors restored from the replica of the previous term",
+		},
+	)
+	CertificateExpiry = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nprobe_certificate_expiry_timestamp_seconds",
+			Help: "Unix time the exporter certificates and the certificates of the delivery functions expire at",
+		},
+		[]string{CertificateKindLabelName, CertificateLabelName},
+	)
+	CertificateWarning = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nprobe_certificate_warning_seconds",
+			Help: "Time before the expiry of a certificate from which it is alarmed about",
+		},
+	)
+	ClockSkew = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nprobe_clock_skew_seconds",
+			Help: "Offset of the clock stamping the records from the NTP servers, as last measured",
+		},
+	)
+	ClockReliable = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "nprobe_clock_reliable",
+			Help: "1 if the clock stamping the records was synchronized or unchecked when last checked, 0 otherwise",
+		},
+	)
+	UnreliableTimestamps = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_unreliable_timestamp_records_total",
+			Help: "Number of records flagged as built while the clock stamping them was unreliable",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	StatesFetched = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_states_fetched_total",
+			Help: "Number of subscriber and MME states whose value was fetched from the state service by the state syncs",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	StateResyncs = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_state_resyncs_total",
+			Help: "Number of state syncs fetching all the states of the targets rather than the ones changed since the last sync",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+	StateSyncFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nprobe_state_sync_failures_total",
+			Help: "Number of state syncs which failed, the states of the targets being looked up by each task instead",
+		},
+		[]string{metrics.NetworkLabelName},
+	)
+)
@@ -14,25 +14,74 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"database/sql"
 	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	fegprotos "magma/feg/cloud/go/protos"
 	"magma/lte/cloud/go/lte"
 	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/alert"
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/debug"
 	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/lte/cloud/go/services/nprobe/ingest"
+	"magma/lte/cloud/go/services/nprobe/keyring"
+	"magma/lte/cloud/go/services/nprobe/killswitch"
+	"magma/lte/cloud/go/services/nprobe/latency"
+	"magma/lte/cloud/go/services/nprobe/leader"
+	"magma/lte/cloud/go/services/nprobe/logging"
 	manager "magma/lte/cloud/go/services/nprobe/nprobe_manager"
 	"magma/lte/cloud/go/services/nprobe/obsidian/handlers"
+	nprobe_protos "magma/lte/cloud/go/services/nprobe/protos"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/lte/cloud/go/services/nprobe/servicers"
+	"magma/lte/cloud/go/services/nprobe/snapshot"
 	np_storage "magma/lte/cloud/go/services/nprobe/storage"
+	"magma/lte/cloud/go/services/nprobe/subscription"
+	"magma/lte/cloud/go/services/nprobe/tracing"
 
 	"magma/orc8r/cloud/go/blobstore"
 	"magma/orc8r/cloud/go/obsidian"
 	"magma/orc8r/cloud/go/obsidian/swagger"
 	"magma/orc8r/cloud/go/obsidian/swagger/protos"
 	"magma/orc8r/cloud/go/service"
+	state_protos "magma/orc8r/cloud/go/services/state/protos"
+	streamer_protos "magma/orc8r/cloud/go/services/streamer/protos"
 	"magma/orc8r/cloud/go/sqorc"
 	"magma/orc8r/cloud/go/storage"
+	orc8rprotos "magma/orc8r/lib/go/protos"
+	"magma/orc8r/lib/go/registry"
+)
+
+var logger = logging.New(logging.ComponentMain)
 
-	"github.com/golang/glog"
+const (
+	// healthCheckInterval is the time between updates of the service health
+	// reported to the service registry
+	healthCheckInterval = 30 * time.Second
+	// storageHealthTimeout bounds the check of the database connection
+	storageHealthTimeout = 5 * time.Second
+	// sourceHealthTimeout bounds the check of an event source
+	sourceHealthTimeout = 5 * time.Second
+	// commitTimeout bounds the wait for the tasks to commit their state once
+	// their records in flight failed on shutdown
+	commitTimeout = 5 * time.Second
+	// endInterceptionTimeout bounds the delivery of the IRI-END records
+	// ending the HI2 streams once the drain timed out on shutdown
+	endInterceptionTimeout = 5 * time.Second
+	// certificateCheckInterval is the time between checks of the expiry of
+	// the exporter certificates and of the certificates of the delivery
+	// functions
+	certificateCheckInterval = 24 * time.Hour
 )
 
 func init() {
@@ -43,57 +92,567 @@ func main() {
 	// Create service
 	srv, err := service.NewOrchestratorService(lte.ModuleName, nprobe.ServiceName)
 	if err != nil {
-		glog.Fatalf("Error creating service: %v", err)
+		logger.Fatalf("Error creating service: %v", err)
+	}
+	// a process started twice on the same host is refused before it can
+	// process any task
+	if err := checkPorts(srv); err != nil {
+		logger.Fatalf("Ports of the service are not available, is nprobe already running? %v", err)
 	}
 
 	// Init storage
 	db, err := sqorc.Open(storage.GetSQLDriver(), storage.GetDatabaseSource())
 	if err != nil {
-		glog.Fatalf("Error opening db connection: %+v", err)
+		logger.Fatalf("Error opening db connection: %+v", err)
 	}
 	fact := blobstore.NewSQLBlobStorageFactory(nprobe.NProbeTableBlobstore, db, sqorc.GetSqlBuilder())
 	err = fact.InitializeFactory()
 	if err != nil {
-		glog.Fatalf("Error initializing nprobe table: %+v", err)
+		logger.Fatalf("Error initializing nprobe table: %+v", err)
+	}
+	serviceConfig := nprobe.GetServiceConfig()
+	// The components of the service log at their own verbosity once
+	// configured, on top of the verbosity set by -v
+	setLogLevels(serviceConfig.LogLevels)
+	// Operational alerts are logged, and forwarded to the SIEM of the LI
+	// operations team once a syslog endpoint is configured
+	alertConfig, err := alert.NewConfig(serviceConfig)
+	if err != nil {
+		logger.Fatalf("Invalid alert config: %v", err)
+	}
+	alert.Configure(alertConfig)
+	health.Certificates().SetWarning(alertConfig.CertificateExpiry)
+	// The records built by the service are stamped with its clock, which is
+	// checked against the NTP servers once configured
+	clockConfig, err := clock.NewConfig(serviceConfig)
+	if err != nil {
+		logger.Fatalf("Invalid clock config: %v", err)
+	}
+	clock.Configure(clockConfig)
+	// The stages of the processing of the tasks are traced once a collector
+	// is configured
+	tracingConfig, err := tracing.NewConfig(serviceConfig)
+	if err != nil {
+		logger.Fatalf("Invalid tracing config: %v", err)
+	}
+	if err := tracing.Configure(tracingConfig); err != nil {
+		logger.Fatalf("Failed to configure tracing: %v", err)
+	}
+	// The identities of the targets are logged as pseudonyms, whose key is
+	// shared by the replicas once configured
+	if err := loadPseudonymKey(serviceConfig.LogPseudonymKeyFile); err != nil {
+		logger.Fatalf("Failed to load pseudonym key: %v", err)
+	}
+	stateStore, err := newStateStore(serviceConfig, db, fact)
+	if err != nil {
+		logger.Fatalf("Failed to create state store: %v", err)
 	}
-	nprobeBlobstore := np_storage.NewNProbeBlobstore(fact)
+	// the quarantined events and the audit trail are sealed at rest once keys
+	// are configured
+	var atRestKeys *keyring.Keyring
+	if len(serviceConfig.AtRestKeyFiles) != 0 {
+		atRestKeys, err = keyring.LoadKeyring(serviceConfig.AtRestKeyFiles, serviceConfig.AtRestPrimaryKey)
+		if err != nil {
+			logger.Fatalf("Failed to load at-rest encryption keys: %v", err)
+		}
+	}
+	nprobeStorage := np_storage.WithStateStore(np_storage.NewEncryptedNProbeBlobstore(fact, atRestKeys), stateStore)
 
-	// Attach handlers
-	obsidian.AttachHandlers(srv.EchoServer, handlers.GetHandlers(nprobeBlobstore))
+	debugSettings := debug.NewSettings()
+	runtimeStats := debug.NewRuntime()
+	runtimeStats.Register("debug_capture", debugSettings.CapturedRecords)
+	killSwitch := killswitch.NewSwitch(nprobeStorage)
+
+	// Attach handlers. Every request is recorded in the audit log of its network.
+	audit := handlers.AuditRequests(nprobeStorage)
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetHandlers(nprobeStorage), audit)
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetDebugHandlers(debugSettings), audit)
+	// the fault handlers are only served by the builds with fault injection
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetFaultInjectionHandlers(), audit)
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetKillSwitchHandlers(killSwitch), audit)
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetProfilingHandlers(runtimeStats), audit)
+	if len(serviceConfig.SnapshotKeyFile) != 0 {
+		key, err := snapshot.LoadKey(serviceConfig.SnapshotKeyFile)
+		if err != nil {
+			logger.Fatalf("Failed to load snapshot key: %v", err)
+		}
+		obsidian.AttachHandlers(srv.EchoServer, handlers.GetSnapshotHandlers(nprobeStorage, key), audit)
+	}
 	protos.RegisterSwaggerSpecServer(srv.GrpcServer, swagger.NewSpecServicerFromFile(nprobe.ServiceName))
 
-	serviceConfig := nprobe.GetServiceConfig()
-	tlsConfig, err := exporter.NewTlsConfig(
-		serviceConfig.ExporterCrtFile,
-		serviceConfig.ExporterKeyFile,
-		serviceConfig.SkipVerifyServer,
+	// The health of the components is aggregated for the magma tooling and
+	// the NMS, and summarized in the service303 health
+	healthRegistry := health.NewRegistry()
+	healthRegistry.TrackCertificates(health.Certificates())
+	healthRegistry.Register(health.ComponentStorage, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), storageHealthTimeout)
+		defer cancel()
+		return db.PingContext(ctx)
+	})
+	fegprotos.RegisterServiceHealthServer(srv.GrpcServer, servicers.NewHealthServicer(healthRegistry))
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetHealthHandlers(healthRegistry), audit)
+
+	// Events streamed by gateways are buffered until their tasks are processed
+	ingested := ingest.NewBuffer(int(serviceConfig.IngestBufferSize))
+	runtimeStats.Register("ingest", func() (int, int) { return ingested.Len(), 0 })
+	nprobe_protos.RegisterEventIngestionServer(srv.GrpcServer, servicers.NewIngestionServicer(ingested))
+
+	// The subscriber states reported by the gateways are streamed by the state
+	// service, their changes processing the tasks early
+	stateChanges := subscription.NewSubscription()
+	stateChanges.SetEnabled(serviceConfig.EventSubscription)
+	state_protos.RegisterIndexerServer(srv.GrpcServer, servicers.NewIndexerServicer(stateChanges))
+
+	// The tasks are also served as typed models to the components which
+	// don't consume the REST API, e.g. AGW services and test tools, and are
+	// managed by the orc8r services and automation without going through it
+	nprobe_protos.RegisterNetworkProbeModelsServer(srv.GrpcServer, servicers.NewModelsServicer(nprobeStorage))
+
+	// The targets of the tasks delivering all records are streamed to the
+	// gateways, which mirror the user plane of these targets only
+	streamer_protos.RegisterStreamProviderServer(srv.GrpcServer, servicers.NewProviderServicer(nprobeStorage))
+
+	// Init records exporter. The client certificate is reloaded when rotated
+	// without closing the delivery connections. The networks with their own
+	// credentials present them instead.
+	certs, err := exporter.NewCertificateStore(serviceConfig.ExporterCrtFile, serviceConfig.ExporterKeyFile)
+	if err != nil {
+		logger.Fatalf("Failed to load exporter certificate: %v", err)
+	}
+	credentials := exporter.NewCredentialManager(certs, serviceConfig.NetworkCredentialsDir)
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetCertificateHandlers(certs), audit)
+	backend, err := newBackend(serviceConfig, credentials)
+	if err != nil {
+		logger.Fatalf("Failed to create exporter backend: %v", err)
+	}
+	recordExporter := exporter.NewRecordExporter(backend)
+	runtimeStats.Register("export_queue", recordExporter.QueueStats)
+	recordExporter.SetBatchConfig(exporter.NewBatchConfig(serviceConfig))
+	recordExporter.SetPipelineConfig(exporter.NewPipelineConfig(serviceConfig))
+	recordExporter.SetSequencerConfig(exporter.NewSequencerConfig(serviceConfig))
+	destination := health.DestinationComponent(exporter.GetDestinationName(serviceConfig))
+	healthRegistry.Register(destination, recordExporter.CheckDelivery)
+	// the service is degraded while the export queue nears its capacity or
+	// the events failing to be encoded spike
+	healthRegistry.Register(health.ComponentExportQueue, func() error {
+		return health.Degraded(recordExporter.CheckQueue())
+	})
+	encodingErrors := health.NewErrorRate(getHealthThresholds(serviceConfig))
+	healthRegistry.Register(health.ComponentEncoding, encodingErrors.Check)
+	healthRegistry.Register(health.ComponentClock, clock.Default().Check)
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetValidationHandlers(certs, recordExporter), audit)
+	// The records of the tasks overriding the delivery function, and of the
+	// networks with their own credentials, are delivered on a connection per
+	// destination
+	destinations, err := exporter.NewPool(serviceConfig, credentials.TLSConfig(serviceConfig.SkipVerifyServer))
+	if err != nil {
+		logger.Fatalf("Failed to create exporter pool: %v", err)
+	}
+	destinations.SetCredentials(credentials)
+	runtimeStats.Register("destination_queues", destinations.QueueStats)
+	nProbeManager, err := manager.NewNProbeManager(
+		serviceConfig,
+		nprobeStorage,
+		recordExporter,
+		debugSettings,
+		killSwitch,
+		ingested,
 	)
 	if err != nil {
-		glog.Fatalf("Failed to create new TlsConfig: %v", err)
+		logger.Fatalf("Failed to create new NProbeManager: %v", err)
 	}
+	for _, source := range nProbeManager.Sources {
+		source := source
+		healthRegistry.Register(health.SourceComponent(source.Name()), func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), sourceHealthTimeout)
+			defer cancel()
+			return source.Check(ctx)
+		})
+	}
+	nProbeManager.Destinations = destinations
+	nProbeManager.Credentials = credentials
+	nProbeManager.Keyring = atRestKeys
+	deliveryLatency := latency.NewTracker(latency.DefaultWindow)
+	nProbeManager.Latency = deliveryLatency
+	nProbeManager.Health = healthRegistry
+	nProbeManager.Certificates = health.Certificates()
+	nProbeManager.EncodingErrors = encodingErrors
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetLatencyHandlers(deliveryLatency), audit)
+	if serviceConfig.RecordStream {
+		recordStream := debug.NewRecordStream()
+		nProbeManager.RecordStream = recordStream
+		obsidian.AttachHandlers(srv.EchoServer, handlers.GetRecordStreamHandlers(recordStream), audit)
+	}
+	nProbeManager.RegisterRuntimeStats(runtimeStats)
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetPassReportHandlers(nProbeManager.GetPassReports), audit)
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetSigningHandlers(nProbeManager.GetSigner), audit)
+	// The delivery connections are inspected and re-established by the
+	// administrators, and by automation along with the tasks
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetConnectionHandlers(nProbeManager), audit)
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetDestinationTestHandlers(nProbeManager), audit)
+	obsidian.AttachHandlers(srv.EchoServer, handlers.GetDeadLetterHandlers(nprobeStorage, nProbeManager), audit)
+	nprobe_protos.RegisterNProbeServiceServer(srv.GrpcServer, servicers.NewNProbeServicer(nprobeStorage, nProbeManager))
 
-	// Init records exporter
-	recordExporter := exporter.NewRecordExporter(serviceConfig.DeliveryFunctionAddr, tlsConfig)
-	nProbeManager, err := manager.NewNProbeManager(serviceConfig, nprobeBlobstore, recordExporter)
+	// Reload the config on changes or SIGHUP. The exporter backend is replaced
+	// right away while the manager settings apply from the next pass. SIGHUP
+	// also reloads the credentials of the networks.
+	ctx, cancel := context.WithCancel(context.Background())
+	reloads := make(chan nprobe.Config, 1)
+	configWatcher := nprobe.NewConfigWatcher(serviceConfig)
+	go configWatcher.Run(ctx, func(update nprobe.ConfigUpdate) {
+		if update.CertificatesChanged ||
+			update.Previous.ExporterCrtFile != update.Current.ExporterCrtFile ||
+			update.Previous.ExporterKeyFile != update.Current.ExporterKeyFile {
+			err := certs.SetFiles(update.Current.ExporterCrtFile, update.Current.ExporterKeyFile)
+			if err != nil {
+				logger.Errorf("Failed to reload exporter certificate: %v", err)
+			}
+		}
+		if exporter.IsBackendConfigChanged(update.Previous, update.Current) {
+			backend, err := newBackend(update.Current, credentials)
+			if err != nil {
+				logger.Errorf("Failed to create exporter backend from reloaded config: %v", err)
+			} else {
+				recordExporter.SetBackend(backend)
+				healthRegistry.Unregister(destination)
+				destination = health.DestinationComponent(exporter.GetDestinationName(update.Current))
+				healthRegistry.Register(destination, recordExporter.CheckDelivery)
+			}
+		}
+		if atRestKeys != nil {
+			// rotated keys apply right away, encryption is enabled or disabled on restart
+			keys, err := keyring.LoadKeys(update.Current.AtRestKeyFiles)
+			if err == nil {
+				err = atRestKeys.Set(keys, update.Current.AtRestPrimaryKey)
+			}
+			if err != nil {
+				logger.Errorf("Failed to reload at-rest encryption keys: %v", err)
+			}
+		}
+		if alertConfig, err := alert.NewConfig(update.Current); err != nil {
+			logger.Errorf("Failed to apply reloaded alert config: %v", err)
+		} else {
+			alert.Configure(alertConfig)
+			health.Certificates().SetWarning(alertConfig.CertificateExpiry)
+		}
+		if clockConfig, err := clock.NewConfig(update.Current); err != nil {
+			logger.Errorf("Failed to apply reloaded clock config: %v", err)
+		} else {
+			clock.Configure(clockConfig)
+		}
+		if tracingConfig, err := tracing.NewConfig(update.Current); err != nil {
+			logger.Errorf("Failed to apply reloaded tracing config: %v", err)
+		} else if err := tracing.Configure(tracingConfig); err != nil {
+			logger.Errorf("Failed to apply reloaded tracing config: %v", err)
+		}
+		setLogLevels(update.Current.LogLevels)
+		if len(update.Previous.LogPseudonymKeyFile) != 0 || len(update.Current.LogPseudonymKeyFile) != 0 {
+			if err := loadPseudonymKey(update.Current.LogPseudonymKeyFile); err != nil {
+				logger.Errorf("Failed to reload pseudonym key: %v", err)
+			}
+		}
+		recordExporter.SetBatchConfig(exporter.NewBatchConfig(update.Current))
+		recordExporter.SetPipelineConfig(exporter.NewPipelineConfig(update.Current))
+		recordExporter.SetSequencerConfig(exporter.NewSequencerConfig(update.Current))
+		stateChanges.SetEnabled(update.Current.EventSubscription)
+		encodingErrors.SetThresholds(getHealthThresholds(update.Current))
+		credentials.SetDirectory(update.Current.NetworkCredentialsDir)
+		tlsConfig := credentials.TLSConfig(update.Current.SkipVerifyServer)
+		if err := destinations.SetConfig(update.Current, tlsConfig); err != nil {
+			logger.Errorf("Failed to apply reloaded config to the exporter pool: %v", err)
+		}
+		select {
+		case <-reloads:
+		default:
+		}
+		reloads <- update.Current
+	})
+	go checkCertificateExpiry(ctx)
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGHUP)
+		for range sigs {
+			configWatcher.Reload()
+			credentials.Reload()
+		}
+	}()
+
+	// Tasks are only processed by the instance holding the lease, whose term
+	// ends before another instance can take over. With leader election, the
+	// other replicas stand by. Otherwise the lease registers the single
+	// instance, and an instance started while another one is running, e.g.
+	// by mistake against the same database, refuses to process tasks.
+	holder, err := leader.NewInstanceID()
 	if err != nil {
-		glog.Fatalf("Failed to create new NProbeManager: %v", err)
+		logger.Fatalf("Failed to get the identity of this instance: %v", err)
+	}
+	elector := leader.NewElector(nprobeStorage, holder, time.Duration(serviceConfig.LeaseDurationSecs)*time.Second)
+	elector.OnTermEnd(recordExporter.Disconnect)
+	elector.OnTermEnd(destinations.Disconnect)
+	if !serviceConfig.LeaderElection {
+		if err := elector.Register(ctx); err != nil {
+			logger.Fatalf("Refusing to process tasks: %v", err)
+		}
 	}
+	go elector.Run(ctx)
+	go clock.Default().Run(ctx)
 
-	// Run LI service in Loop
+	// Run LI service in Loop. Tasks are processed early once gateways
+	// stream events or subscriber states change, batched for
+	// ingest_flush_interval_ms. eventd is polled less often while subscriber
+	// states are streamed.
+	loopDone := make(chan struct{})
 	go func() {
+		defer close(loopDone)
+		loopConfig := serviceConfig
+		var epoch uint64
 		for {
-			err := nProbeManager.ProcessNProbeTasks()
+			select {
+			case config := <-reloads:
+				if err := nProbeManager.ApplyConfig(config); err != nil {
+					logger.Errorf("Failed to apply reloaded config: %v", err)
+				} else {
+					loopConfig = config
+				}
+			default:
+			}
+
+			interval := time.Duration(loopConfig.UpdateIntervalSecs) * time.Second
+			backoff := time.Duration(loopConfig.BackOffIntervalSecs) * time.Second
+			ready := ingested.Ready()
+			changed := stateChanges.Changed()
+			stale := time.Duration(loopConfig.SubscriptionStaleSecs) * time.Second
+			if staleIn := stateChanges.StaleIn(time.Now(), stale); staleIn > 0 {
+				// eventd is polled at the update interval again once stale
+				pollInterval := time.Duration(loopConfig.SubscriptionPollIntervalSecs) * time.Second
+				if staleIn+interval < pollInterval {
+					pollInterval = staleIn + interval
+				}
+				if pollInterval > interval {
+					interval = pollInterval
+				}
+			}
+			term, leading := elector.Leading()
+			if !leading {
+				// events streamed to a standby replica are also fetched
+				// from eventd by the active one
+				ingested.Retain(nil)
+				healthRegistry.Report(health.ComponentManager, nil, 0)
+				select {
+				case <-ctx.Done():
+					return
+				case <-elector.Elected():
+				case <-ready:
+				}
+				continue
+			}
+			passCtx, cancelPass := elector.Context(ctx)
+			if term != epoch {
+				// the tasks may have been processed by another replica since.
+				// Their state is loaded again ahead of the first pass.
+				nProbeManager.DropCheckpoints()
+				nProbeManager.WarmUp(passCtx)
+				epoch = term
+			}
+			err := nProbeManager.ProcessNProbeTasks(passCtx)
+			cancelPass()
+			// the loop is stuck once it misses a few passes
+			healthRegistry.Report(health.ComponentManager, err, 3*(interval+backoff))
 			if err != nil {
-				glog.Errorf("Failed to process tasks: %v", err)
-				<-time.After(time.Duration(serviceConfig.BackOffIntervalSecs) * time.Second)
+				logger.Errorf("Failed to process tasks: %v", err)
+				interval += backoff
+				// streamed events don't shorten the back off
+				ready, changed = nil, nil
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			case <-nProbeManager.WarrantDue():
+				// tasks are activated and expired as their warrant starts or ends,
+				// and their statistics reported as their interval elapses
+			case <-changed:
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Duration(loopConfig.IngestFlushIntervalMs) * time.Millisecond):
+				}
+			case <-ready:
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Duration(loopConfig.IngestFlushIntervalMs) * time.Millisecond):
+				}
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if health.IsHealthy(healthRegistry.Check()) {
+				srv.Health = orc8rprotos.ServiceInfo_APP_HEALTHY
+			} else {
+				srv.Health = orc8rprotos.ServiceInfo_APP_UNHEALTHY
 			}
-			<-time.After(time.Duration(serviceConfig.UpdateIntervalSecs) * time.Second)
 		}
 	}()
 
+	// Drain in-flight records, end the HI2 streams with TS 102 232 END
+	// records and close the exporter connection on shutdown
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+		sig := <-sigs
+		logger.Infof("Received signal %v, draining in-flight records", sig)
+		cancel()
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), time.Duration(serviceConfig.ShutdownTimeoutSecs)*time.Second)
+		defer cancelDrain()
+		stopped := true
+		select {
+		case <-loopDone:
+		case <-drainCtx.Done():
+			// the records still in flight fail so that the tasks commit
+			// their state and the exporters can be closed
+			logger.Warningf("Timed out while draining in-flight records")
+			recordExporter.Disconnect()
+			destinations.Disconnect()
+			select {
+			case <-loopDone:
+			case <-time.After(commitTimeout):
+				stopped = false
+				logger.Warningf("Timed out while committing the state of the tasks, their cursors not checkpointed yet are lost")
+			}
+		}
+		// cursors are persisted and the HI2 streams ended before the lease is
+		// released, unless another replica already took over or tasks are
+		// still processed
+		if _, leading := elector.Leading(); leading && stopped {
+			nProbeManager.FlushCheckpoints()
+			endCtx := drainCtx
+			if drainCtx.Err() != nil {
+				// the IRI-END records are still attempted once the drain
+				// timed out, over new connections
+				var cancelEnd context.CancelFunc
+				endCtx, cancelEnd = context.WithTimeout(context.Background(), endInterceptionTimeout)
+				defer cancelEnd()
+			}
+			nProbeManager.EndInterception(endCtx)
+		}
+		elector.Resign()
+		recordExporter.Close()
+		destinations.Close()
+		tracing.Shutdown()
+		srv.GrpcServer.GracefulStop()
+	}()
+
 	// Run service
 	err = srv.Run()
 	if err != nil {
-		glog.Fatalf("Error while running service and echo server: %v", err)
+		logger.Fatalf("Error while running service and echo server: %v", err)
+	}
+}
+
+// newBackend creates the exporter backend selected in the service config
+func newBackend(serviceConfig nprobe.Config, credentials *exporter.CredentialManager) (exporter.Backend, error) {
+	return exporter.NewBackend(serviceConfig, credentials.TLSConfig(serviceConfig.SkipVerifyServer))
+}
+
+// checkCertificateExpiry alerts about the exporter certificates and the
+// certificates of the delivery functions once they near their expiry, at
+// start and then daily
+func checkCertificateExpiry(ctx context.Context) {
+	ticker := time.NewTicker(certificateCheckInterval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		for _, cert := range health.Certificates().Expiring(now) {
+			alert.CheckCertificate(cert.Describe(), cert.NotAfter, now)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadPseudonymKey sets the key of the pseudonyms of the identities logged,
+// drawn at random when no key file is set
+func loadPseudonymKey(keyFile string) error {
+	if len(keyFile) == 0 {
+		redact.SetKey(nil)
+		return nil
+	}
+	key, err := redact.LoadKey(keyFile)
+	if err != nil {
+		return err
+	}
+	redact.SetKey(key)
+	return nil
+}
+
+// setLogLevels applies the verbosity of the components set by the service
+// config, the unknown components being ignored
+func setLogLevels(levels map[string]uint32) {
+	for component := range levels {
+		if !logging.IsComponent(component) {
+			logger.Warningf("Ignoring log level of unknown component %s, expected one of %s", component, strings.Join(logging.Components, ", "))
+		}
+	}
+	logging.SetConfiguredLevels(levels)
+}
+
+// getHealthThresholds returns the window and the thresholds of the encode
+// failures from which the service is degraded
+func getHealthThresholds(serviceConfig nprobe.Config) (time.Duration, uint64, float64) {
+	return time.Duration(serviceConfig.HealthWindowSecs) * time.Second,
+		uint64(serviceConfig.HealthEncodeFailureMin),
+		float64(serviceConfig.HealthEncodeFailurePercent) / 100
+}
+
+// checkPorts fails if the gRPC or HTTP port of the service is already bound,
+// e.g. by another nprobe process of the host
+func checkPorts(srv *service.OrchestratorService) error {
+	port, err := registry.GetServicePort(srv.Type)
+	if err != nil {
+		return err
+	}
+	addresses := []string{fmt.Sprintf(":%d", port)}
+	if srv.EchoServer != nil {
+		addresses = append(addresses, srv.EchoServer.Server.Addr)
+	}
+	for _, address := range addresses {
+		lis, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+		lis.Close()
+	}
+	return nil
+}
+
+// newStateStore returns the store of the export state of the tasks selected
+// by the service config
+func newStateStore(serviceConfig nprobe.Config, db *sql.DB, fact blobstore.BlobStorageFactory) (np_storage.StateStore, error) {
+	switch serviceConfig.StateBackend {
+	case np_storage.StateBackendBlobstore:
+		return np_storage.NewNProbeBlobstore(fact), nil
+	case np_storage.StateBackendSQL:
+		return np_storage.NewSQLStateStore(db, sqorc.GetSqlBuilder())
+	case np_storage.StateBackendMemory:
+		// replicas would not share the state of the tasks they take over
+		if serviceConfig.LeaderElection {
+			return nil, fmt.Errorf("state backend %s is not supported with leader election", serviceConfig.StateBackend)
+		}
+		return np_storage.NewMemoryStateStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported state backend %s", serviceConfig.StateBackend)
 	}
 }
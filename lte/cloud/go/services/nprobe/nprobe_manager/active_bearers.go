@@ -0,0 +1,184 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// With ActiveBearerReports, the bearers of the target already active when
+// the interception of a task starts are reported as the LEA would otherwise
+// only see the sessions established afterwards: they are looked up in the
+// session state reported by sessiond, and the interception of each one is
+// begun with a start of interception with active bearer IRI-BEGIN, the
+// following records of the session continuing it. Symmetrically, when the
+// interception of the task stops, i.e. the task is deleted, expires or is
+// suspended by the kill switch, each session still open is ended with its
+// own IRI-END in place of the terminal record of the task.
+
+// deliverActiveBearers delivers the start of interception records of the
+// bearers of the target active when the interception of a task started. The
+// sessions already begun, e.g. by a former attempt, are skipped, and the
+// task is no longer pending once all of them are delivered.
+func (np *NProbeManager) deliverActiveBearers(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	matcher *targetMatcher,
+	exp *exporter.RecordExporter,
+) error {
+	taskID := string(task.TaskID)
+	startedAt := time.Time(state.ActivatedAt)
+	enricher := newBearerEnricher(networkID, np.getSyncedStates(networkID))
+	var events []*eventdM.Event
+	if imsi := matcher.getIMSI(); len(imsi) != 0 {
+		// the sessions are looked up unless synced, failing the task if
+		// they can't be
+		sessions, ok := enricher.sessions[imsi]
+		if !ok {
+			var err error
+			if sessions, err = enricher.loadSessions(ctx, imsi); err != nil {
+				return err
+			}
+		}
+		events = makeActiveBearerEvents(imsi, sessions, getOpenSessions(state), startedAt)
+	}
+
+	version := np.getModuleVersion(networkID, task)
+	operatorID := np.getOperatorID(networkID)
+	recordTask := np.getRecordTask(networkID, task, state)
+	filter := newRecordFilter(task.TaskDetails.RecordFilter)
+	open := getOpenSessions(state)
+	seq := getNextSequenceNumber(state)
+	var sessionIDs, timestamps []string
+	var records [][]byte
+	for _, event := range events {
+		enricher.enrich(ctx, event)
+		if !filter.matches(event, open) {
+			continue
+		}
+		record, err := encoding.MakeVersionedRecord(event, recordTask, operatorID, seq+uint32(len(records)), encoding.RecordClassBegin, version)
+		if err == nil {
+			record, err = np.prepareRecord(networkID, task, record)
+		}
+		if err != nil {
+			// the bearer is left to the quarantine, its next records
+			// beginning its interception
+			logger.Errorf("Failed to build start of interception record of task %s: %s", taskID, err)
+			countEncodeFailure(networkID, err)
+			np.countEncoding(err)
+			np.quarantineEvent(networkID, taskID, event, err)
+			continue
+		}
+		metrics.RecordsEncoded.WithLabelValues(networkID).Inc()
+		np.countEncoding(nil)
+		sessionIDs = append(sessionIDs, getSessionID(event))
+		timestamps = append(timestamps, event.Timestamp)
+		records = append(records, record)
+	}
+
+	var derr error
+	if len(records) != 0 {
+		delivery := exp.SubmitRecords(ctx, getBackoffKey(networkID, taskID), np.getTaskWeight(taskID), task.TaskDetails.CorrelationID, records, np.MaxExportRetries)
+		var delivered []models.NetworkProbeDeliveryRecord
+		begun := 0
+		for i := range records {
+			if derr = delivery.Wait(i); derr != nil {
+				break
+			}
+			begun++
+			metrics.RecordsExported.WithLabelValues(networkID).Inc()
+			advanceSessions(state, sessionIDs[i], encoding.RecordClassBegin)
+			np.beginUsage(networkID, task, sessionIDs[i], timestamps[i])
+			delivered = append(delivered, makeDeliveryRecord(task, records[i], seq+uint32(i), startedAt, time.Now()))
+		}
+		np.storeDeliveryRecords(networkID, taskID, delivered)
+		if begun != 0 {
+			state.SequenceNumber = seq + uint32(begun)
+			state.RecordsExported += uint64(begun)
+		}
+	}
+	if derr == ctx.Err() {
+		// shutting down, the remaining bearers are reported on restart
+		derr = nil
+	}
+	if derr == nil && ctx.Err() == nil {
+		state.ActiveBearersPending = false
+	} else if derr != nil {
+		metrics.ExportFailures.WithLabelValues(networkID).Inc()
+		if serr := np.updateDeliveryState(networkID, task, state, derr); serr != nil {
+			logger.Errorf("Failed to update delivery state for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(serr))
+		}
+	}
+	if err := np.storeState(networkID, taskID, state); err != nil {
+		return err
+	}
+	return derr
+}
+
+// makeActiveBearerEvents composes the events reporting the active bearers
+// of a subscriber at the start of interception, by session ID, leaving out
+// the sessions already open. Their bearer fields are filled from the
+// session state by the bearer enricher.
+func makeActiveBearerEvents(imsi string, sessions map[string]map[string]string, open map[string]bool, startedAt time.Time) []*eventdM.Event {
+	sessionIDs := make([]string, 0, len(sessions))
+	for sessionID := range sessions {
+		if !open[sessionID] {
+			sessionIDs = append(sessionIDs, sessionID)
+		}
+	}
+	sort.Strings(sessionIDs)
+	events := make([]*eventdM.Event, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		events = append(events, &eventdM.Event{
+			EventType:  nprobe.BearerActive,
+			StreamName: nprobe.ServiceName,
+			Timestamp:  startedAt.UTC().Format(time.RFC3339Nano),
+			Value:      map[string]interface{}{"imsi": imsi, "session_id": sessionID},
+		})
+	}
+	return events
+}
+
+// endActiveBearers ends the interception of each open session of a task
+// whose interception stops with an IRI-END, and stores its state. It returns
+// false when the terminal record of the task is due instead, i.e. active
+// bearers aren't reported or no session is open.
+func (np *NProbeManager) endActiveBearers(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	endedAt time.Time,
+) (bool, error) {
+	if !np.ActiveBearerReports || len(state.OpenSessions) == 0 {
+		return false, nil
+	}
+	_, err := np.endOpenSessions(ctx, networkID, task, state, endedAt)
+	if serr := np.storeState(networkID, string(task.TaskID), state); serr != nil && err == nil {
+		err = serr
+	}
+	return true, err
+}
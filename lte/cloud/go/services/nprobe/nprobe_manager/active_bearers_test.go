@@ -0,0 +1,58 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeActiveBearerEvents(t *testing.T) {
+	sessions := map[string]map[string]string{
+		"IMSI001010000000001-2": {"apn": "ims"},
+		"IMSI001010000000001-1": {"apn": "magma.ipv4", "ip_addr": "192.168.128.12"},
+		"IMSI001010000000001-3": {"apn": "internet"},
+	}
+	startedAt := time.Date(2021, 2, 18, 5, 13, 26, 0, time.UTC)
+
+	// the sessions already begun are left out, the others reported in order
+	events := makeActiveBearerEvents("IMSI001010000000001", sessions, map[string]bool{"IMSI001010000000001-3": true}, startedAt)
+	assert.Len(t, events, 2)
+	for i, sessionID := range []string{"IMSI001010000000001-1", "IMSI001010000000001-2"} {
+		assert.Equal(t, nprobe.BearerActive, events[i].EventType)
+		assert.Equal(t, "2021-02-18T05:13:26Z", events[i].Timestamp)
+		assert.Equal(t, sessionID, getSessionID(events[i]))
+		assert.Equal(t, encoding.RecordClassBegin, encoding.GetEventRecordClass(events[i].EventType))
+	}
+	assert.Empty(t, makeActiveBearerEvents("IMSI001010000000001", nil, nil, startedAt))
+}
+
+func TestTargetMatcherIMSI(t *testing.T) {
+	m := &targetMatcher{targetType: models.NetworkProbeTaskDetailsTargetTypeImsi, imsi: "001010000000001"}
+	assert.Equal(t, "IMSI001010000000001", m.getIMSI())
+	m = &targetMatcher{targetType: models.NetworkProbeTaskDetailsTargetTypeMsisdn, imsi: "IMSI001010000000001"}
+	assert.Equal(t, "IMSI001010000000001", m.getIMSI())
+
+	// IMEI targets designate the subscriber last seen with the device
+	m = &targetMatcher{targetType: models.NetworkProbeTaskDetailsTargetTypeImei, targetID: "356938035643809"}
+	assert.Empty(t, m.getIMSI())
+	m.boundIMSI = "001010000000002"
+	assert.Equal(t, "IMSI001010000000002", m.getIMSI())
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"fmt"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/alert"
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// raiseActivation raises the activation alert of a task the first time its
+// interception starts, and records it in the state of the task
+func (np *NProbeManager) raiseActivation(networkID string, task *models.NetworkProbeTask, state *models.NetworkProbeData) error {
+	if !time.Time(state.ActivatedAt).IsZero() {
+		return nil
+	}
+	taskID := string(task.TaskID)
+	now := clock.Now()
+	state.ActivatedAt = strfmt.DateTime(now)
+	if err := np.storeState(networkID, taskID, state); err != nil {
+		return err
+	}
+	alert.Raise(alert.Alert{
+		Name:        alert.TaskActivated,
+		Severity:    alert.SeverityLow,
+		Message:     fmt.Sprintf("interception of task %s started", taskID),
+		NetworkID:   networkID,
+		TaskID:      taskID,
+		Destination: np.getDestinationName(task),
+		At:          now,
+	})
+	return nil
+}
+
+// raiseDeliveryFailure raises the delivery failure alert of a task whose
+// delivery starts failing
+func (np *NProbeManager) raiseDeliveryFailure(networkID string, task *models.NetworkProbeTask, deliveryErr error) {
+	alert.Raise(alert.Alert{
+		Name:        alert.DeliveryFailure,
+		Severity:    alert.SeverityHigh,
+		Message:     fmt.Sprintf("delivery of task %s failed: %s", task.TaskID, redact.Error(deliveryErr)),
+		NetworkID:   networkID,
+		TaskID:      string(task.TaskID),
+		Destination: np.getDestinationName(task),
+	})
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// auditPruneInterval is the minimum time between two deletions of the
+// expired delivered records of a network
+const auditPruneInterval = time.Hour
+
+// makeDeliveryRecord returns the audit trail entry of a delivered record
+func makeDeliveryRecord(
+	task *models.NetworkProbeTask,
+	record []byte,
+	sequenceNumber uint32,
+	timestamp time.Time,
+	deliveredAt time.Time,
+) models.NetworkProbeDeliveryRecord {
+	hash := sha256.Sum256(record)
+	return models.NetworkProbeDeliveryRecord{
+		TargetID:       task.TaskDetails.TargetID,
+		SequenceNumber: sequenceNumber,
+		RecordHash:     hex.EncodeToString(hash[:]),
+		Timestamp:      strfmt.DateTime(timestamp.UTC()),
+		DeliveredAt:    strfmt.DateTime(deliveredAt.UTC()),
+	}
+}
+
+// storeDeliveryRecords appends delivered records to the audit trail of a task.
+// The records are already delivered, failing to audit them doesn't fail the task.
+func (np *NProbeManager) storeDeliveryRecords(networkID, taskID string, records []models.NetworkProbeDeliveryRecord) {
+	if !np.DeliveryAudit || len(records) == 0 {
+		return
+	}
+	np.signDeliveryRecords(networkID, taskID, records)
+	if err := np.Storage.StoreDeliveryRecords(networkID, taskID, records); err != nil {
+		logger.Errorf("Failed to audit %d delivered records of task %s: %v", len(records), taskID, err)
+	}
+}
+
+// makeSessionMapping returns the mapping of the correlation ID of a task to
+// the identifiers of the session reported by the record of an event. The TEID
+// and charging ID are only known if the gateway reports them.
+func makeSessionMapping(
+	task *models.NetworkProbeTask,
+	event *eventdM.Event,
+	sessionID string,
+	sequenceNumber uint32,
+) *models.NetworkProbeSessionMapping {
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok || len(sessionID) == 0 {
+		return nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, event.Timestamp)
+	if err != nil {
+		return nil
+	}
+	mapping := &models.NetworkProbeSessionMapping{
+		CorrelationID:       task.TaskDetails.CorrelationID,
+		TaskID:              string(task.TaskID),
+		TargetID:            task.TaskDetails.TargetID,
+		SessionID:           sessionID,
+		FirstSequenceNumber: sequenceNumber,
+		LastSequenceNumber:  sequenceNumber,
+		FirstSeen:           strfmt.DateTime(timestamp.UTC()),
+		LastSeen:            strfmt.DateTime(timestamp.UTC()),
+	}
+	mapping.BearerID, _ = eventData["bearer_id"].(string)
+	if len(mapping.BearerID) == 0 {
+		mapping.BearerID, _ = eventData["linked_bearer_id"].(string)
+	}
+	mapping.Teid, _ = eventData["teid"].(string)
+	mapping.ChargingID, _ = eventData["charging_id"].(string)
+	mapping.Apn, _ = eventData["apn"].(string)
+	mapping.IPAddr, _ = eventData["ip_addr"].(string)
+	return mapping
+}
+
+// storeSessionMappings maps the sessions reported by delivered records to the
+// correlation ID of their task. Like the audit trail, failing to map them
+// doesn't fail the task.
+func (np *NProbeManager) storeSessionMappings(networkID, taskID string, mappings []models.NetworkProbeSessionMapping) {
+	if !np.DeliveryAudit || len(mappings) == 0 {
+		return
+	}
+	if err := np.Storage.StoreSessionMappings(networkID, mappings); err != nil {
+		logger.Errorf("Failed to map %d sessions of task %s: %v", len(mappings), taskID, err)
+	}
+}
+
+// pruneDeliveryRecords deletes the delivered records and the session mappings
+// of a network older than the retention, at most once per auditPruneInterval
+func (np *NProbeManager) pruneDeliveryRecords(networkID string, now time.Time) {
+	if !np.DeliveryAudit || now.Sub(np.auditPrunedAt[networkID]) < auditPruneInterval {
+		return
+	}
+	if err := np.Storage.DeleteDeliveryRecordsBefore(networkID, now.Add(-np.DeliveryAuditRetention)); err != nil {
+		logger.Errorf("Failed to delete expired delivered records of network %s: %v", networkID, err)
+		return
+	}
+	if err := np.Storage.DeleteSessionMappingsBefore(networkID, now.Add(-np.DeliveryAuditRetention)); err != nil {
+		logger.Errorf("Failed to delete expired session mappings of network %s: %v", networkID, err)
+		return
+	}
+	np.auditPrunedAt[networkID] = now
+}
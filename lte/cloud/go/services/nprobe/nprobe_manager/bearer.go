@@ -0,0 +1,255 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	lteModels "magma/lte/cloud/go/services/lte/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/orc8r/cloud/go/services/configurator"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+	"magma/orc8r/cloud/go/services/state"
+	merrors "magma/orc8r/lib/go/errors"
+
+	"github.com/pkg/errors"
+)
+
+// bearerFields are the fields of the events of a bearer which can be
+// filled from the bearer context of its session
+var bearerFields = []string{
+	"apn", "ip_addr", "qci", "apn_ambr_ul", "apn_ambr_dl",
+	"arp_priority_level", "arp_preemption_capability", "arp_preemption_vulnerability",
+}
+
+// bearerEnricher fills the fields missing from the events of a bearer, e.g.
+// its QoS, ARP and APN-AMBR, from the session state reported by sessiond and the
+// configuration of the APN of the session. The states and APNs are looked up
+// once per pass of a task, failed lookups leaving the events as they are,
+// unless the states were synced by the pass.
+type bearerEnricher struct {
+	networkID string
+	// sessions are the bearer contexts of the sessions of each IMSI, by
+	// session ID
+	sessions map[string]map[string]map[string]string
+	// apns are the bearer fields of each APN configuration
+	apns map[string]map[string]string
+}
+
+// newBearerEnricher returns an enricher of the events of a network, using
+// the states synced when not nil
+func newBearerEnricher(networkID string, synced *syncedStates) *bearerEnricher {
+	b := &bearerEnricher{
+		networkID: networkID,
+		sessions:  map[string]map[string]map[string]string{},
+		apns:      map[string]map[string]string{},
+	}
+	if synced != nil {
+		for imsi, sessions := range synced.sessions {
+			b.sessions[imsi] = sessions
+		}
+	}
+	return b
+}
+
+// enrich fills the missing bearer fields of a bearer activation or
+// modification event, or of an active bearer. The fields reflect the session
+// state at the time the record is built, the event value is copied rather
+// than updated.
+func (b *bearerEnricher) enrich(ctx context.Context, event *eventdM.Event) {
+	switch event.EventType {
+	case nprobe.SessionCreated, nprobe.SessionUpdated, nprobe.BearerActive:
+	default:
+		return
+	}
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok || !isMissingBearerFields(eventData) {
+		return
+	}
+	imsi, _ := eventData["imsi"].(string)
+	sessionID, _ := eventData["session_id"].(string)
+	if len(imsi) == 0 || len(sessionID) == 0 {
+		return
+	}
+
+	fields := map[string]string{}
+	for key, value := range b.getSessions(ctx, imsi)[sessionID] {
+		fields[key] = value
+	}
+	apn, _ := eventData["apn"].(string)
+	if len(apn) == 0 {
+		apn = fields["apn"]
+	}
+	if len(apn) != 0 {
+		for key, value := range b.getAPN(apn) {
+			fields[key] = value
+		}
+	}
+	event.Value = applyBearerFields(eventData, fields)
+}
+
+// getSessions returns the bearer contexts of the sessions of an IMSI
+func (b *bearerEnricher) getSessions(ctx context.Context, imsi string) map[string]map[string]string {
+	if sessions, ok := b.sessions[imsi]; ok {
+		return sessions
+	}
+	sessions, err := b.loadSessions(ctx, imsi)
+	if err != nil {
+		logger.Warningf("Failed to get session state of %s, records left unenriched: %v", redact.Identity(imsi), redact.Error(err))
+		b.sessions[imsi] = nil
+	}
+	return sessions
+}
+
+// loadSessions looks up the bearer contexts of the sessions of an IMSI in
+// the session state reported by sessiond, none if it reported none
+func (b *bearerEnricher) loadSessions(ctx context.Context, imsi string) (map[string]map[string]string, error) {
+	var sessions map[string]map[string]string
+	st, err := state.GetState(ctx, b.networkID, lte.SubscriberStateType, imsi, serdes.State)
+	switch {
+	case err == merrors.ErrNotFound:
+	case err != nil:
+		return nil, err
+	default:
+		if reported, ok := st.ReportedState.(*state.ArbitraryJSON); ok {
+			sessions = getBearerContexts(*reported)
+		}
+	}
+	b.sessions[imsi] = sessions
+	return sessions, nil
+}
+
+// getAPN returns the bearer fields of the configuration of an APN
+func (b *bearerEnricher) getAPN(apn string) map[string]string {
+	if fields, ok := b.apns[apn]; ok {
+		return fields
+	}
+	var fields map[string]string
+	ent, err := configurator.LoadEntity(
+		b.networkID, lte.APNEntityType, apn,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	switch {
+	case errors.Cause(err) == merrors.ErrNotFound:
+	case err != nil:
+		logger.Warningf("Failed to load APN %s, records left unenriched: %v", apn, err)
+	default:
+		if config, ok := ent.Config.(*lteModels.ApnConfiguration); ok {
+			fields = getAPNBearerFields(config)
+		}
+	}
+	b.apns[apn] = fields
+	return fields
+}
+
+// getBearerContexts returns the bearer contexts of the sessions of a
+// subscriber state reported by sessiond, by session ID
+func getBearerContexts(reported map[string]interface{}) map[string]map[string]string {
+	sessions := map[string]map[string]string{}
+	for apn, apnSessions := range reported {
+		entries, ok := apnSessions.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, entry := range entries {
+			session, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			sessionID, _ := session["session_id"].(string)
+			if len(sessionID) == 0 {
+				continue
+			}
+			fields := map[string]string{"apn": apn}
+			if v, ok := session["apn"].(string); ok && len(v) != 0 {
+				fields["apn"] = v
+			}
+			if v, ok := session["ipv4"].(string); ok && len(v) != 0 {
+				fields["ip_addr"] = v
+			}
+			sessions[sessionID] = fields
+		}
+	}
+	return sessions
+}
+
+// getAPNBearerFields returns the QCI, ARP and APN-AMBR of an APN
+// configuration
+func getAPNBearerFields(config *lteModels.ApnConfiguration) map[string]string {
+	fields := map[string]string{}
+	if qos := config.QosProfile; qos != nil && qos.ClassID != nil {
+		fields["qci"] = strconv.FormatInt(int64(*qos.ClassID), 10)
+	}
+	if qos := config.QosProfile; qos != nil && qos.PriorityLevel != nil {
+		fields["arp_priority_level"] = strconv.FormatUint(uint64(*qos.PriorityLevel), 10)
+		if qos.PreemptionCapability != nil {
+			fields["arp_preemption_capability"] = strconv.FormatBool(*qos.PreemptionCapability)
+		}
+		if qos.PreemptionVulnerability != nil {
+			fields["arp_preemption_vulnerability"] = strconv.FormatBool(*qos.PreemptionVulnerability)
+		}
+	}
+	if ambr := config.Ambr; ambr != nil && ambr.MaxBandwidthUl != nil && ambr.MaxBandwidthDl != nil {
+		fields["apn_ambr_ul"] = strconv.FormatUint(uint64(*ambr.MaxBandwidthUl), 10)
+		fields["apn_ambr_dl"] = strconv.FormatUint(uint64(*ambr.MaxBandwidthDl), 10)
+	}
+	return fields
+}
+
+// isMissingBearerFields returns true if an event lacks any of the bearer fields
+func isMissingBearerFields(eventData map[string]interface{}) bool {
+	for _, key := range bearerFields {
+		if _, ok := eventData[key]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBearerFields returns a copy of the value of an event with its missing
+// bearer fields set. The fields reported by the event always prevail, and
+// the APN-AMBR is only set when missing in both directions and the ARP when
+// its priority level is missing, so that they are not made up of different
+// sources.
+func applyBearerFields(eventData map[string]interface{}, fields map[string]string) map[string]interface{} {
+	value := make(map[string]interface{}, len(eventData)+len(fields))
+	for key, v := range eventData {
+		value[key] = v
+	}
+	_, hasUL := eventData["apn_ambr_ul"]
+	_, hasDL := eventData["apn_ambr_dl"]
+	_, hasARP := eventData["arp_priority_level"]
+	for _, key := range bearerFields {
+		v, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if (key == "apn_ambr_ul" || key == "apn_ambr_dl") && (hasUL || hasDL) {
+			continue
+		}
+		if strings.HasPrefix(key, "arp_") && hasARP {
+			continue
+		}
+		if _, ok := value[key]; !ok {
+			value[key] = v
+		}
+	}
+	return value
+}
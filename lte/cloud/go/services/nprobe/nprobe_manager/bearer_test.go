@@ -0,0 +1,105 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	lteModels "magma/lte/cloud/go/services/lte/obsidian/models"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBearerEnrichment(t *testing.T) {
+	reported := map[string]interface{}{
+		"oai.ipv4": []interface{}{
+			map[string]interface{}{
+				"session_id":      "IMSI001010000000001-919642",
+				"apn":             "oai.ipv4",
+				"ipv4":            "192.168.128.12",
+				"lifecycle_state": "SESSION_ACTIVE",
+			},
+			// entries without session are skipped
+			map[string]interface{}{"ipv4": "192.168.128.13"},
+		},
+		"ims": "unexpected",
+	}
+	sessions := getBearerContexts(reported)
+	assert.Equal(t, map[string]map[string]string{
+		"IMSI001010000000001-919642": {"apn": "oai.ipv4", "ip_addr": "192.168.128.12"},
+	}, sessions)
+
+	config := &lteModels.ApnConfiguration{
+		Ambr: &lteModels.AggregatedMaximumBitrate{
+			MaxBandwidthUl: swag.Uint32(100000000),
+			MaxBandwidthDl: swag.Uint32(200000000),
+		},
+		QosProfile: &lteModels.QosProfile{
+			ClassID:                 swag.Int32(9),
+			PriorityLevel:           swag.Uint32(15),
+			PreemptionCapability:    swag.Bool(true),
+			PreemptionVulnerability: swag.Bool(false),
+		},
+	}
+	fields := getAPNBearerFields(config)
+	assert.Equal(t, map[string]string{
+		"qci":                          "9",
+		"apn_ambr_ul":                  "100000000",
+		"apn_ambr_dl":                  "200000000",
+		"arp_priority_level":           "15",
+		"arp_preemption_capability":    "true",
+		"arp_preemption_vulnerability": "false",
+	}, fields)
+	for key, value := range sessions["IMSI001010000000001-919642"] {
+		fields[key] = value
+	}
+
+	// the fields reported by the event prevail
+	eventData := map[string]interface{}{
+		"imsi":       "IMSI001010000000001",
+		"session_id": "IMSI001010000000001-919642",
+		"ip_addr":    "192.168.128.20",
+	}
+	assert.True(t, isMissingBearerFields(eventData))
+	value := applyBearerFields(eventData, fields)
+	assert.Equal(t, map[string]interface{}{
+		"imsi":                         "IMSI001010000000001",
+		"session_id":                   "IMSI001010000000001-919642",
+		"ip_addr":                      "192.168.128.20",
+		"apn":                          "oai.ipv4",
+		"qci":                          "9",
+		"apn_ambr_ul":                  "100000000",
+		"apn_ambr_dl":                  "200000000",
+		"arp_priority_level":           "15",
+		"arp_preemption_capability":    "true",
+		"arp_preemption_vulnerability": "false",
+	}, value)
+	assert.False(t, isMissingBearerFields(value))
+	// the event value is left untouched
+	assert.Len(t, eventData, 3)
+
+	// the APN-AMBR is not made up of different sources
+	eventData["apn_ambr_ul"] = "64000"
+	value = applyBearerFields(eventData, fields)
+	assert.Equal(t, "64000", value["apn_ambr_ul"])
+	assert.NotContains(t, value, "apn_ambr_dl")
+
+	// neither is the ARP
+	eventData["arp_priority_level"] = "1"
+	value = applyBearerFields(eventData, fields)
+	assert.Equal(t, "1", value["arp_priority_level"])
+	assert.NotContains(t, value, "arp_preemption_capability")
+	assert.NotContains(t, value, "arp_preemption_vulnerability")
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// exportCursor is the position of a task in its event stream
+type exportCursor struct {
+	lastExported     time.Time
+	exportedEventIDs []string
+	openSessions     []string
+	sequenceNumber   uint32
+	recordsExported  uint64
+}
+
+// taskCheckpoint tracks the export cursor of a task between checkpoints.
+// The cursor is kept in memory across passes until it is checkpointed.
+type taskCheckpoint struct {
+	networkID string
+	taskID    string
+	stored    exportCursor
+	current   exportCursor
+	storedAt  time.Time
+}
+
+func (c exportCursor) equal(other exportCursor) bool {
+	// the events processed at the watermark only ever grow until it moves,
+	// and the sessions open only change along with the records exported
+	return c.lastExported.Equal(other.lastExported) &&
+		len(c.exportedEventIDs) == len(other.exportedEventIDs) &&
+		c.sequenceNumber == other.sequenceNumber &&
+		c.recordsExported == other.recordsExported
+}
+
+func getExportCursor(state *models.NetworkProbeData) exportCursor {
+	return exportCursor{
+		lastExported:     time.Time(state.LastExported),
+		exportedEventIDs: append([]string(nil), state.ExportedEventIds...),
+		openSessions:     append([]string(nil), state.OpenSessions...),
+		sequenceNumber:   state.SequenceNumber,
+		recordsExported:  state.RecordsExported,
+	}
+}
+
+// restoreCursor applies the cursor not checkpointed yet of a task onto its
+// stored state. The cursor is dropped if the stored state was changed by
+// anything else than a checkpoint, e.g. restored from a snapshot.
+func (np *NProbeManager) restoreCursor(key string, state *models.NetworkProbeData) {
+	np.checkpointMutex.Lock()
+	defer np.checkpointMutex.Unlock()
+	checkpoint, ok := np.checkpoints[key]
+	if !ok {
+		return
+	}
+	if checkpoint.stored.sequenceNumber != state.SequenceNumber ||
+		checkpoint.stored.recordsExported != state.RecordsExported {
+		delete(np.checkpoints, key)
+		return
+	}
+	state.LastExported = strfmt.DateTime(checkpoint.current.lastExported)
+	state.ExportedEventIds = append([]string(nil), checkpoint.current.exportedEventIDs...)
+	state.OpenSessions = append([]string(nil), checkpoint.current.openSessions...)
+	state.SequenceNumber = checkpoint.current.sequenceNumber
+	state.RecordsExported = checkpoint.current.recordsExported
+}
+
+// isCheckpointDue returns true if the cursor of a task should be persisted.
+// Busy tasks are checkpointed every CheckpointMaxRecords records, bounding
+// the records replayed after a crash, while idle tasks are checkpointed at
+// most every CheckpointMaxInterval, bounding storage writes.
+func (np *NProbeManager) isCheckpointDue(key string, state *models.NetworkProbeData, now time.Time) bool {
+	np.checkpointMutex.Lock()
+	defer np.checkpointMutex.Unlock()
+	current := getExportCursor(state)
+	checkpoint, ok := np.checkpoints[key]
+	if !ok {
+		// the stored state is only known to be up to date once checkpointed
+		return true
+	}
+	checkpoint.current = current
+	if current.equal(checkpoint.stored) {
+		return false
+	}
+	return current.recordsExported-checkpoint.stored.recordsExported >= uint64(np.CheckpointMaxRecords) ||
+		now.Sub(checkpoint.storedAt) >= np.CheckpointMaxInterval
+}
+
+// storeState persists the state of a task, checkpointing its cursor
+func (np *NProbeManager) storeState(networkID, taskID string, state *models.NetworkProbeData) error {
+	pruneReservedRecords(state)
+	if err := np.Storage.StoreNProbeData(networkID, taskID, *state); err != nil {
+		return err
+	}
+	cursor := getExportCursor(state)
+	np.checkpointMutex.Lock()
+	defer np.checkpointMutex.Unlock()
+	np.checkpoints[getBackoffKey(networkID, taskID)] = &taskCheckpoint{
+		networkID: networkID,
+		taskID:    taskID,
+		stored:    cursor,
+		current:   cursor,
+		storedAt:  time.Now(),
+	}
+	return nil
+}
+
+// seedCheckpoint records the stored state of a task, e.g. loaded during the
+// warm-up, as checkpointed, so that it isn't stored again until its cursor
+// moves
+func (np *NProbeManager) seedCheckpoint(networkID, taskID string, state *models.NetworkProbeData) {
+	key := getBackoffKey(networkID, taskID)
+	cursor := getExportCursor(state)
+	np.checkpointMutex.Lock()
+	defer np.checkpointMutex.Unlock()
+	if _, ok := np.checkpoints[key]; ok {
+		return
+	}
+	np.checkpoints[key] = &taskCheckpoint{
+		networkID: networkID,
+		taskID:    taskID,
+		stored:    cursor,
+		current:   cursor,
+		storedAt:  time.Now(),
+	}
+}
+
+// pruneCheckpoints drops the cursor of tasks which no longer exist
+func (np *NProbeManager) pruneCheckpoints(keys map[string]bool) {
+	np.checkpointMutex.Lock()
+	defer np.checkpointMutex.Unlock()
+	for key := range np.checkpoints {
+		if !keys[key] {
+			delete(np.checkpoints, key)
+		}
+	}
+}
+
+// FlushCheckpoints persists the cursors not checkpointed yet, e.g. of tasks
+// which were not processed since their last records were delivered. It must
+// not be called while tasks are processed.
+func (np *NProbeManager) FlushCheckpoints() {
+	np.checkpointMutex.Lock()
+	var pending []*taskCheckpoint
+	for _, checkpoint := range np.checkpoints {
+		if !checkpoint.current.equal(checkpoint.stored) {
+			pending = append(pending, checkpoint)
+		}
+	}
+	np.checkpointMutex.Unlock()
+
+	for _, checkpoint := range pending {
+		state, err := np.Storage.GetNProbeData(checkpoint.networkID, checkpoint.taskID)
+		if err == nil {
+			np.restoreCursor(getBackoffKey(checkpoint.networkID, checkpoint.taskID), state)
+			err = np.storeState(checkpoint.networkID, checkpoint.taskID, state)
+		}
+		if err != nil {
+			logger.Errorf("Failed to checkpoint state of task %s: %v", checkpoint.taskID, err)
+		}
+	}
+}
+
+// DropCheckpoints drops the cursors not checkpointed yet, e.g. once another
+// instance may have processed the tasks since, so that the stored state of
+// the tasks, or the cursors it replicated, are used from the next pass.
+func (np *NProbeManager) DropCheckpoints() {
+	np.checkpointMutex.Lock()
+	defer np.checkpointMutex.Unlock()
+	np.checkpoints = map[string]*taskCheckpoint{}
+	np.replicas = map[string][]*models.NetworkProbeTaskCursor{}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+)
+
+// deliveryConnection is an exporter along with the destination it delivers to
+type deliveryConnection struct {
+	destination string
+	networkID   string
+	exporter    *exporter.RecordExporter
+}
+
+// GetConnections describes the delivery connection of the exporter of the
+// service config, then the ones of the destinations of the pool
+func (np *NProbeManager) GetConnections() []*models.NetworkProbeConnection {
+	connections := np.listConnections("")
+	ret := make([]*models.NetworkProbeConnection, 0, len(connections))
+	for _, conn := range connections {
+		ret = append(ret, toConnectionModel(conn, time.Now()))
+	}
+	return ret
+}
+
+// ReconnectExporters forces the exporters delivering to a destination, or all
+// of them when empty, to reconnect right away. All exporters are reconnected
+// even if some fail, the first failure being returned.
+func (np *NProbeManager) ReconnectExporters(destination string) ([]*models.NetworkProbeConnection, error) {
+	return np.applyConnections(destination, "reconnect", func(exp *exporter.RecordExporter) error {
+		return exp.Reconnect()
+	})
+}
+
+// DrainExporters waits for the records queued by the exporters delivering to
+// a destination, or all of them when empty, to be delivered, then reconnects
+// them. The exporters are drained concurrently until ctx is done.
+func (np *NProbeManager) DrainExporters(ctx context.Context, destination string) ([]*models.NetworkProbeConnection, error) {
+	connections := np.listConnections(destination)
+	if len(connections) == 0 {
+		return nil, exporter.ErrUnknownConnection
+	}
+	errs := make([]error, len(connections))
+	done := make(chan struct{})
+	for i, conn := range connections {
+		go func(i int, conn deliveryConnection) {
+			defer func() { done <- struct{}{} }()
+			errs[i] = conn.exporter.Drain(ctx)
+		}(i, conn)
+	}
+	for range connections {
+		<-done
+	}
+	return np.reportConnections(connections, "drain", errs)
+}
+
+// applyConnections applies an operation to the exporters delivering to a
+// destination, or all of them when empty
+func (np *NProbeManager) applyConnections(
+	destination, operation string,
+	apply func(exp *exporter.RecordExporter) error,
+) ([]*models.NetworkProbeConnection, error) {
+	connections := np.listConnections(destination)
+	if len(connections) == 0 {
+		return nil, exporter.ErrUnknownConnection
+	}
+	errs := make([]error, len(connections))
+	for i, conn := range connections {
+		errs[i] = apply(conn.exporter)
+	}
+	return np.reportConnections(connections, operation, errs)
+}
+
+// reportConnections describes the connections an operation was applied to,
+// and returns the first failure
+func (np *NProbeManager) reportConnections(connections []deliveryConnection, operation string, errs []error) ([]*models.NetworkProbeConnection, error) {
+	var first error
+	now := time.Now()
+	ret := make([]*models.NetworkProbeConnection, 0, len(connections))
+	for i, conn := range connections {
+		if errs[i] != nil {
+			logger.Errorf("Failed to %s exporter of %s: %v", operation, conn.destination, errs[i])
+			if first == nil {
+				first = errors.Wrapf(errs[i], "failed to %s exporter of %s", operation, conn.destination)
+			}
+		}
+		ret = append(ret, toConnectionModel(conn, now))
+	}
+	return ret, first
+}
+
+// listConnections returns the exporters delivering to a destination, or all
+// of them when empty, starting with the exporter of the service config
+func (np *NProbeManager) listConnections(destination string) []deliveryConnection {
+	var ret []deliveryConnection
+	normalized := exporter.NormalizeAddress(destination)
+	if np.Exporter != nil && (len(destination) == 0 || destination == np.destinationName || normalized == exporter.NormalizeAddress(np.DeliveryFunctionAddr)) {
+		ret = append(ret, deliveryConnection{destination: np.destinationName, exporter: np.Exporter})
+	}
+	if np.Destinations == nil {
+		return ret
+	}
+	for _, pooled := range np.Destinations.List() {
+		address := exporter.NormalizeAddress(pooled.Destination.Address)
+		if len(destination) != 0 && address != normalized {
+			continue
+		}
+		ret = append(ret, deliveryConnection{destination: address, networkID: pooled.Destination.Network, exporter: pooled.Exporter})
+	}
+	return ret
+}
+
+// toConnectionModel converts the connection of an exporter for the admin API
+func toConnectionModel(conn deliveryConnection, now time.Time) *models.NetworkProbeConnection {
+	info := conn.exporter.GetConnection()
+	ret := &models.NetworkProbeConnection{
+		Destination:        conn.destination,
+		NetworkID:          conn.networkID,
+		Address:            info.Address,
+		OnSecondary:        info.OnSecondary,
+		Connected:          info.Connected,
+		PeerAddress:        info.PeerAddress,
+		Handshake:          toHandshakeModel(info.Handshake),
+		Compression:        info.Compression,
+		BytesSent:          info.BytesSent,
+		StandbyConnections: int64(info.StandbyConnections),
+		QueuedRecords:      int64(info.QueuedRecords),
+		Breaker:            info.Breaker,
+	}
+	if !info.ConnectedAt.IsZero() {
+		ret.ConnectedAt = strfmt.DateTime(info.ConnectedAt)
+		ret.UptimeSecs = uint64(now.Sub(info.ConnectedAt) / time.Second)
+	}
+	if info.LastError != nil {
+		ret.LastError = info.LastError.Error()
+		ret.LastErrorAt = strfmt.DateTime(info.LastErrorAt)
+	}
+	if !info.BreakerOpenedAt.IsZero() {
+		ret.BreakerOpenedAt = strfmt.DateTime(info.BreakerOpenedAt)
+	}
+	return ret
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"strings"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// correlationPruneInterval is the minimum time between two deletions of the
+// expired bearer correlations of a network
+const correlationPruneInterval = time.Hour
+
+// getEventBearer returns the IMSI and the bearer ID of the bearer an event
+// relates to, the default bearer of the session if the event carries no
+// bearer ID of its own
+func getEventBearer(event *eventdM.Event) (string, string) {
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+	imsi, _ := eventData["imsi"].(string)
+	bearerID, _ := eventData["bearer_id"].(string)
+	if len(bearerID) == 0 {
+		bearerID, _ = eventData["linked_bearer_id"].(string)
+	}
+	return imsi, bearerID
+}
+
+// withCorrelationID returns a copy of a task whose records carry
+// correlationID rather than the correlation ID of the task
+func withCorrelationID(task *models.NetworkProbeTask, correlationID uint64) *models.NetworkProbeTask {
+	if correlationID == task.TaskDetails.CorrelationID {
+		return task
+	}
+	details := *task.TaskDetails
+	details.CorrelationID = correlationID
+	correlated := *task
+	correlated.TaskDetails = &details
+	return &correlated
+}
+
+// withBearerCorrelation returns the task the record of an event is built
+// with, carrying the correlation ID allocated to the bearer of the event.
+// The events without a bearer ID, or whose timestamp is invalid and fails
+// their record anyway, keep the correlation ID of the task.
+func (np *NProbeManager) withBearerCorrelation(
+	networkID string,
+	task *models.NetworkProbeTask,
+	event *eventdM.Event,
+) (*models.NetworkProbeTask, error) {
+	imsi, bearerID := getEventBearer(event)
+	if len(imsi) == 0 || len(bearerID) == 0 {
+		return task, nil
+	}
+	at, err := time.Parse(time.RFC3339, event.Timestamp)
+	if err != nil {
+		return task, nil
+	}
+
+	key := getBearerKey(networkID, imsi, bearerID)
+	np.bearerCorrelationMutex.Lock()
+	defer np.bearerCorrelationMutex.Unlock()
+	// the events before the activation of the bearer, e.g. of a task lagging
+	// behind, are of a former activation
+	if cached, ok := np.bearerCorrelations[key]; ok && !at.Before(time.Time(cached.AllocatedAt)) {
+		return withCorrelationID(task, cached.CorrelationID), nil
+	}
+	// allocations are serialized so that the tasks of the same target get
+	// the same correlation ID for a new bearer
+	correlation, err := np.Storage.AllocateCorrelationID(networkID, imsi, bearerID, at)
+	if err != nil {
+		metrics.CorrelationFailures.WithLabelValues(networkID).Inc()
+		return nil, err
+	}
+	// the correlation IDs of the released bearers are not cached, the
+	// storage telling the events before their release from those after
+	if time.Time(correlation.ReleasedAt).IsZero() {
+		np.bearerCorrelations[key] = correlation
+	}
+	return withCorrelationID(task, correlation.CorrelationID), nil
+}
+
+// releaseBearerCorrelation releases the correlation ID of the bearer ended
+// by an event once its record is built, or of all the bearers of a detached
+// IMSI, so that the next activation of the bearer gets a new one
+func (np *NProbeManager) releaseBearerCorrelation(networkID string, event *eventdM.Event) error {
+	imsi, bearerID := getEventBearer(event)
+	switch {
+	case len(imsi) == 0:
+		return nil
+	case event.EventType == nprobe.DetachSuccess:
+		bearerID = ""
+	case event.EventType != nprobe.SessionTerminated || len(bearerID) == 0:
+		return nil
+	}
+	at, err := time.Parse(time.RFC3339, event.Timestamp)
+	if err != nil {
+		return nil
+	}
+
+	np.bearerCorrelationMutex.Lock()
+	defer np.bearerCorrelationMutex.Unlock()
+	if err := np.Storage.ReleaseCorrelationIDs(networkID, imsi, bearerID, at); err != nil {
+		metrics.CorrelationFailures.WithLabelValues(networkID).Inc()
+		return err
+	}
+	prefix := getBearerKey(networkID, imsi, bearerID)
+	for key := range np.bearerCorrelations {
+		if key == prefix || (len(bearerID) == 0 && strings.HasPrefix(key, prefix)) {
+			delete(np.bearerCorrelations, key)
+		}
+	}
+	return nil
+}
+
+// clearBearerCorrelations forgets the correlation IDs of the active bearers,
+// e.g. once bearer correlation is disabled
+func (np *NProbeManager) clearBearerCorrelations() {
+	np.bearerCorrelationMutex.Lock()
+	defer np.bearerCorrelationMutex.Unlock()
+	np.bearerCorrelations = map[string]*models.NetworkProbeBearerCorrelation{}
+}
+
+// forgetBearerCorrelations forgets the correlation IDs of the active bearers
+// of a network, e.g. once it is deleted
+func (np *NProbeManager) forgetBearerCorrelations(networkID string) {
+	np.bearerCorrelationMutex.Lock()
+	defer np.bearerCorrelationMutex.Unlock()
+	for key := range np.bearerCorrelations {
+		if strings.HasPrefix(key, networkID+"/") {
+			delete(np.bearerCorrelations, key)
+		}
+	}
+}
+
+// pruneBearerCorrelations deletes the correlation IDs of a network released
+// for longer than the retention, at most once per correlationPruneInterval
+func (np *NProbeManager) pruneBearerCorrelations(networkID string, now time.Time) {
+	if !np.BearerCorrelation || now.Sub(np.correlationPrunedAt[networkID]) < correlationPruneInterval {
+		return
+	}
+	if err := np.Storage.DeleteBearerCorrelationsBefore(networkID, now.Add(-np.BearerCorrelationRetention)); err != nil {
+		logger.Errorf("Failed to delete expired bearer correlations of network %s: %v", networkID, err)
+		return
+	}
+	np.correlationPrunedAt[networkID] = now
+}
+
+// getBearerKey returns the key of a bearer of an IMSI in the cache of the
+// correlation IDs, the prefix of the keys of all its bearers when bearerID
+// is empty
+func getBearerKey(networkID, imsi, bearerID string) string {
+	return networkID + "/" + imsi + "/" + bearerID
+}
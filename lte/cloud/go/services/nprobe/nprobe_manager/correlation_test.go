@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+// correlationStorage allocates the correlation IDs of the bearers in order
+type correlationStorage struct {
+	storage.NProbeStorage
+	allocations int
+	releases    []string
+}
+
+func (s *correlationStorage) AllocateCorrelationID(
+	networkID, imsi, bearerID string,
+	at time.Time,
+) (*models.NetworkProbeBearerCorrelation, error) {
+	s.allocations++
+	return &models.NetworkProbeBearerCorrelation{
+		CorrelationID: uint64(100 + s.allocations),
+		Imsi:          imsi,
+		BearerID:      bearerID,
+		AllocatedAt:   strfmt.DateTime(at),
+	}, nil
+}
+
+func (s *correlationStorage) ReleaseCorrelationIDs(networkID, imsi, bearerID string, releasedAt time.Time) error {
+	s.releases = append(s.releases, imsi+"/"+bearerID)
+	return nil
+}
+
+func TestBearerCorrelation(t *testing.T) {
+	store := &correlationStorage{}
+	np := &NProbeManager{
+		Storage:            store,
+		BearerCorrelation:  true,
+		bearerCorrelations: map[string]*models.NetworkProbeBearerCorrelation{},
+	}
+	task := &models.NetworkProbeTask{TaskID: "task1", TaskDetails: &models.NetworkProbeTaskDetails{CorrelationID: 7}}
+	event := func(eventType, timestamp string, fields map[string]interface{}) *eventdM.Event {
+		value := map[string]interface{}{"imsi": "IMSI001010000000001"}
+		for key, field := range fields {
+			value[key] = field
+		}
+		return &eventdM.Event{EventType: eventType, Timestamp: timestamp, Value: value}
+	}
+
+	// the events of a bearer carry its correlation ID, allocated once
+	created := event(nprobe.SessionCreated, "2021-03-06T03:00:00Z", map[string]interface{}{"bearer_id": "5"})
+	correlated, err := np.withBearerCorrelation("n1", task, created)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(101), correlated.TaskDetails.CorrelationID)
+	assert.Equal(t, uint64(7), task.TaskDetails.CorrelationID)
+	updated := event(nprobe.SessionUpdated, "2021-03-06T03:10:00Z", map[string]interface{}{"linked_bearer_id": "5"})
+	correlated, err = np.withBearerCorrelation("n1", task, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(101), correlated.TaskDetails.CorrelationID)
+	assert.Equal(t, 1, store.allocations)
+
+	// the events before its activation are looked up again
+	earlier := event(nprobe.SessionUpdated, "2021-03-06T02:00:00Z", map[string]interface{}{"bearer_id": "5"})
+	_, err = np.withBearerCorrelation("n1", task, earlier)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, store.allocations)
+
+	// the events without bearer keep the correlation ID of the task
+	attached := event(nprobe.AttachSuccess, "2021-03-06T03:20:00Z", nil)
+	correlated, err = np.withBearerCorrelation("n1", task, attached)
+	assert.NoError(t, err)
+	assert.Equal(t, task, correlated)
+
+	// the bearers are released by their termination or the detach of the target
+	np.bearerCorrelations[getBearerKey("n1", "IMSI001010000000001", "6")] = &models.NetworkProbeBearerCorrelation{CorrelationID: 103}
+	terminated := event(nprobe.SessionTerminated, "2021-03-06T04:00:00Z", map[string]interface{}{"bearer_id": "5"})
+	assert.NoError(t, np.releaseBearerCorrelation("n1", terminated))
+	assert.Equal(t, []string{"IMSI001010000000001/5"}, store.releases)
+	assert.Len(t, np.bearerCorrelations, 1)
+	assert.NoError(t, np.releaseBearerCorrelation("n1", updated))
+	assert.NoError(t, np.releaseBearerCorrelation("n1", event(nprobe.DetachSuccess, "2021-03-06T05:00:00Z", nil)))
+	assert.Equal(t, []string{"IMSI001010000000001/5", "IMSI001010000000001/"}, store.releases)
+	assert.Empty(t, np.bearerCorrelations)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/orc8r/cloud/go/services/configurator"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// A record rejected by the LEMF is submitted again on the next runs of its
+// task, the records following it being held back. Once the same record was
+// rejected DeadLetterAfterRejections runs in a row, it is set aside in the
+// dead letter queue of its task, as delivered, and its task goes on with the
+// following records. Dead letters are kept until they are delivered again or
+// discarded through the API, so that no record is lost silently.
+
+// countRejection counts a rejection of the record of a task with the given
+// sequence number and returns the number of runs in a row it was rejected in
+func (np *NProbeManager) countRejection(key string, sequenceNumber uint32) uint32 {
+	np.backoffMutex.Lock()
+	defer np.backoffMutex.Unlock()
+	backoff, ok := np.backoffs[key]
+	if !ok {
+		backoff = &taskBackoff{}
+		np.backoffs[key] = backoff
+	}
+	if backoff.rejections == 0 || backoff.rejectedSequenceNumber != sequenceNumber {
+		backoff.rejectedSequenceNumber, backoff.rejections = sequenceNumber, 0
+	}
+	backoff.rejections++
+	return backoff.rejections
+}
+
+// clearRejections forgets the rejections of the records of a task
+func (np *NProbeManager) clearRejections(key string) {
+	np.backoffMutex.Lock()
+	defer np.backoffMutex.Unlock()
+	if backoff, ok := np.backoffs[key]; ok {
+		backoff.rejections = 0
+	}
+}
+
+// deadLetterRecord sets the record of an event rejected by the LEMF aside
+// once it was rejected enough runs in a row, and returns true if it was. A
+// record failing to be set aside is attempted again on the next run.
+func (np *NProbeManager) deadLetterRecord(networkID, taskID string, item *encodedEvent, record []byte, deliveryErr error) bool {
+	class := exporter.ClassifyError(deliveryErr)
+	if np.DeadLetterAfterRejections == 0 || class != exporter.FailureNack {
+		return false
+	}
+	key := getBackoffKey(networkID, taskID)
+	rejections := np.countRejection(key, item.sequenceNumber)
+	if rejections < np.DeadLetterAfterRejections {
+		return false
+	}
+
+	letterLogger := logger.With(logging.FieldNetworkID, networkID, logging.FieldTaskID, taskID, logging.FieldSeq, item.sequenceNumber)
+	letter := models.NetworkProbeDeadLetter{
+		ID:             strconv.FormatUint(uint64(item.sequenceNumber), 10),
+		SequenceNumber: item.sequenceNumber,
+		RecordClass:    item.class,
+		EventID:        item.eventID,
+		EventTimestamp: item.timestamp,
+		Record:         record,
+		FailureClass:   class,
+		Error:          redact.Error(deliveryErr),
+		Rejections:     rejections,
+		DeadLetteredAt: strfmt.DateTime(time.Now().UTC()),
+	}
+	if err := np.Storage.StoreDeadLetter(networkID, taskID, letter); err != nil {
+		letterLogger.Errorf("Failed to set record %d of task %s aside: %v", item.sequenceNumber, taskID, err)
+		return false
+	}
+	np.clearRejections(key)
+	letterLogger.Warningf("Set record %d of task %s of network %s aside after %d rejections", item.sequenceNumber, taskID, networkID, rejections)
+	metrics.RecordsDeadLettered.WithLabelValues(networkID).Inc()
+	return true
+}
+
+// RetryDeadLetter delivers a dead letter of a task again to the destination
+// of the task. The dead letter is deleted once delivered and returned with
+// the time it was delivered; otherwise its failure is recorded and returned
+// along with the error.
+func (np *NProbeManager) RetryDeadLetter(ctx context.Context, networkID, taskID, id string) (*models.NetworkProbeDeadLetter, error) {
+	letter, err := np.Storage.GetDeadLetter(networkID, taskID, id)
+	if err != nil {
+		return nil, err
+	}
+	ent, err := configurator.LoadEntity(
+		networkID, lte.NetworkProbeTaskEntityType, taskID,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, err
+	}
+	task := (&models.NetworkProbeTask{}).FromBackendModels(ent)
+	destinations, err := getNetworkProbeDestinations(networkID)
+	if err != nil {
+		return nil, err
+	}
+	np.resolveTaskDestinations(networkID, map[string]*models.NetworkProbeTask{taskID: task}, destinations)
+	exp, err := np.getExporter(networkID, task)
+	if err != nil {
+		return nil, err
+	}
+
+	letterLogger := logger.With(logging.FieldNetworkID, networkID, logging.FieldTaskID, taskID, logging.FieldSeq, letter.SequenceNumber)
+	delivery := np.submitRecords(ctx, exp, networkID, task, [][]byte{letter.Record}, false)
+	deliveryErr := delivery.Wait(0)
+	now := strfmt.DateTime(time.Now().UTC())
+	if deliveryErr == nil {
+		letterLogger.Infof("Delivered dead letter %s of task %s of network %s", id, taskID, networkID)
+		letter.DeliveredAt = now
+		if err := np.Storage.DeleteDeadLetter(networkID, taskID, id); err != nil {
+			letterLogger.Errorf("Failed to delete delivered dead letter %s of task %s: %v", id, taskID, err)
+		}
+		return letter, nil
+	}
+
+	letterLogger.Errorf("Failed to deliver dead letter %s of task %s of network %s (%s): %s", id, taskID, networkID, exporter.ClassifyError(deliveryErr), redact.Error(deliveryErr))
+	metrics.ExportFailures.WithLabelValues(networkID).Inc()
+	letter.Retries++
+	letter.LastRetriedAt = now
+	letter.FailureClass = exporter.ClassifyError(deliveryErr)
+	letter.Error = redact.Error(deliveryErr)
+	if err := np.Storage.StoreDeadLetter(networkID, taskID, *letter); err != nil {
+		return nil, err
+	}
+	return letter, deliveryErr
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/tasks"
+)
+
+// Deleting a task through the APIs only requests its deletion, so that the
+// LEA sees the end of its interception: the next runs of the task deliver
+// the records of its pending events, then its interception is ended with an
+// IRI-END and the task is deleted along with its state. Its deactivation is
+// notified over HI1 by the following pass. Paused tasks, whose events are
+// withheld, are ended right away, while completed one-shot tasks, whose
+// report was their last record, expired tasks, already ended, pending tasks,
+// not intercepted yet, and tasks held beyond the task quota of their network
+// are deleted without one. Tasks which can't be ended within
+// TaskDeletionTimeout, e.g. whose destination is gone for good, are deleted
+// without their IRI-END.
+
+// isTaskDeletionOverdue returns true if the deletion of a task was requested
+// longer than timeout ago
+func isTaskDeletionOverdue(deletion *models.NetworkProbeTaskDeletion, now time.Time, timeout time.Duration) bool {
+	return models.IsTaskDeletionPending(deletion) &&
+		timeout != 0 &&
+		now.Sub(time.Time(deletion.RequestedAt)) >= timeout
+}
+
+// endDeletedTask ends the interception of a task whose deletion was
+// requested with an IRI-END, then deletes the task
+func (np *NProbeManager) endDeletedTask(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	deletion *models.NetworkProbeTaskDeletion,
+) error {
+	taskID := string(task.TaskID)
+	requestedAt := time.Time(deletion.RequestedAt)
+	if ended, err := np.endActiveBearers(ctx, networkID, task, state, requestedAt); ended {
+		if err != nil {
+			return err
+		}
+		return np.deleteTask(networkID, taskID, metrics.DeletionEnded)
+	}
+	seq := getNextSequenceNumber(state)
+	record, err := encoding.MakeTerminalRecord(np.getRecordTask(networkID, task, state), np.getOperatorID(networkID), seq, requestedAt, np.getModuleVersion(networkID, task))
+	if err == nil {
+		record, err = np.prepareRecord(networkID, task, record)
+	}
+	if err != nil {
+		// the task can't be encoded at all, it is deleted once overdue
+		logger.Errorf("Failed to build IRI-END of deleted task %s: %s", taskID, err)
+		return err
+	}
+
+	exp, err := np.getExporter(networkID, task)
+	if err != nil {
+		return err
+	}
+	delivery := exp.SubmitRecords(
+		ctx,
+		getBackoffKey(networkID, taskID),
+		np.getTaskWeight(taskID),
+		task.TaskDetails.CorrelationID,
+		[][]byte{record},
+		np.MaxExportRetries,
+	)
+	if err := delivery.Wait(0); err != nil {
+		return err
+	}
+	metrics.RecordsExported.WithLabelValues(networkID).Inc()
+	np.storeDeliveryRecords(networkID, taskID, []models.NetworkProbeDeliveryRecord{
+		makeDeliveryRecord(task, record, seq, requestedAt, time.Now()),
+	})
+	return np.deleteTask(networkID, taskID, metrics.DeletionEnded)
+}
+
+// deleteTask deletes a task whose deletion was requested along with its state
+func (np *NProbeManager) deleteTask(networkID, taskID, deletion string) error {
+	if err := tasks.Delete(np.Storage, networkID, taskID); err != nil {
+		logger.Errorf("Failed to delete task %s: %v", taskID, err)
+		return err
+	}
+	logger.Infof("Deleted task %s of network %s", taskID, networkID)
+	metrics.TasksDeleted.WithLabelValues(networkID, deletion).Inc()
+	return nil
+}
+
+// deleteHeldTask deletes a task held beyond the task quota of its network if
+// its deletion was requested
+func (np *NProbeManager) deleteHeldTask(networkID, taskID string) {
+	deletion, err := np.Storage.GetTaskDeletion(networkID, taskID)
+	if err != nil {
+		logger.Errorf("Failed to get deletion of task %s: %v", taskID, err)
+		return
+	}
+	if models.IsTaskDeletionPending(deletion) {
+		np.deleteTask(networkID, taskID, metrics.DeletionHeld)
+	}
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTaskDeletionOverdue(t *testing.T) {
+	now := time.Unix(1615000000, 0)
+	deletion := &models.NetworkProbeTaskDeletion{}
+	assert.False(t, isTaskDeletionOverdue(deletion, now, time.Hour))
+
+	deletion.RequestedAt = strfmt.DateTime(now.Add(-30 * time.Minute))
+	assert.False(t, isTaskDeletionOverdue(deletion, now, time.Hour))
+	assert.True(t, isTaskDeletionOverdue(deletion, now.Add(30*time.Minute), time.Hour))
+	// tasks wait for their IRI-END forever without timeout
+	assert.False(t, isTaskDeletionOverdue(deletion, now.Add(24*time.Hour), 0))
+}
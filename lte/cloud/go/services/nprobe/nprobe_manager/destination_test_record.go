@@ -0,0 +1,113 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/orc8r/cloud/go/services/configurator"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// A destination is tested through the API, e.g. before the warrants of the
+// tasks delivered to it are activated, by delivering a test record to it
+// right away over the connection the records of its tasks are delivered on.
+// The record belongs to no task: it is built, encrypted and signed like the
+// records of a task of the delivery type of the destination, and marked by a
+// test indication carrying the ID of the test. It is sent once, so that the
+// round trip, which includes the acknowledgement of the LEMF when delivery is
+// acknowledged, reflects the path as is.
+
+// TestDestination delivers a test record to a destination of a network and
+// completes the test with the outcome. Failing to deliver the record is
+// reported by the test, an error being returned when the destination or its
+// exporter can't be loaded.
+func (np *NProbeManager) TestDestination(
+	ctx context.Context,
+	networkID string,
+	test *models.NetworkProbeDestinationTest,
+) (*models.NetworkProbeDestinationTest, error) {
+	ent, err := configurator.LoadEntity(
+		networkID, lte.NetworkProbeDestinationEntityType, test.DestinationID,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, err
+	}
+	details := (&models.NetworkProbeDestination{}).FromBackendModels(ent).DestinationDetails
+	task := np.makeDestinationTestTask(test.ID, details)
+
+	conn := deliveryConnection{destination: np.destinationName}
+	destination, err := np.getDeliveryDestination(networkID, task)
+	if err != nil {
+		return nil, err
+	}
+	if destination != nil {
+		conn.destination, conn.networkID = exporter.NormalizeAddress(destination.Address), destination.Network
+	}
+	if conn.exporter, err = np.getExporter(networkID, task); err != nil {
+		return nil, err
+	}
+
+	sentAt := clock.Now()
+	test.SentAt = strfmt.DateTime(sentAt.UTC())
+	record, err := encoding.MakeDestinationTestRecord(np.getOperatorID(networkID), 0, test.ID, sentAt, np.getModuleVersion(networkID, task))
+	if err == nil {
+		record, err = np.prepareRecord(networkID, task, record)
+	}
+	start := time.Now()
+	if err == nil {
+		delivery := conn.exporter.SubmitRecords(ctx, getBackoffKey(networkID, string(task.TaskID)), nprobe.DefaultTaskWeight, 0, [][]byte{record}, 0)
+		err = delivery.Wait(0)
+	}
+	roundTrip := time.Since(start)
+	test.RoundTripMs = uint64(roundTrip / time.Millisecond)
+	testLogger := logger.With(logging.FieldNetworkID, networkID, logging.FieldDestination, conn.destination)
+	if err != nil {
+		testLogger.Errorf("Failed to deliver test record %s to destination %s of network %s: %v", test.ID, test.DestinationID, networkID, err)
+		test.Error = err.Error()
+	} else {
+		testLogger.Infof("Delivered test record %s to destination %s of network %s in %v", test.ID, test.DestinationID, networkID, roundTrip)
+		test.Delivered, test.Acknowledged = true, conn.exporter.GetConnection().Acknowledged
+	}
+	test.Connection = toConnectionModel(conn, time.Now())
+	return test, nil
+}
+
+// makeDestinationTestTask returns the task the test record of a destination
+// is delivered as, delivered to the delivery function of the service config
+// when the destination is the one of the service config, and with the
+// settings of the destination otherwise
+func (np *NProbeManager) makeDestinationTestTask(testID string, details *models.NetworkProbeDestinationDetails) *models.NetworkProbeTask {
+	task := &models.NetworkProbeTask{
+		TaskID:      models.NetworkProbeTaskID(testID),
+		TaskDetails: &models.NetworkProbeTaskDetails{DeliveryType: details.DeliveryType},
+	}
+	if exporter.NormalizeAddress(details.DeliveryAddress) == exporter.NormalizeAddress(np.DeliveryFunctionAddr) {
+		return task
+	}
+	task.TaskDetails.Delivery = toTaskDelivery(details)
+	return task
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+)
+
+// Interception fails closed: the events of a task are only exported once the
+// scope of the task is evaluated, i.e. the kill switch of its network is read
+// and its target resolved to the events concerning it alone. A task whose
+// target can't be resolved, e.g. misconfigured or while subscriberdb is
+// unavailable, exports nothing until it can, rather than unscoped events.
+
+// resolveTarget returns the matcher of the events of a task target, and
+// records whether the task fails closed because its target can't be resolved
+func (np *NProbeManager) resolveTarget(networkID string, task *models.NetworkProbeTask) (*targetMatcher, error) {
+	matcher, err := np.getTargetMatcher(networkID, task)
+	np.setFailClosed(networkID, string(task.TaskID), err != nil)
+	return matcher, err
+}
+
+// setFailClosed records whether a task fails closed
+func (np *NProbeManager) setFailClosed(networkID, taskID string, failed bool) {
+	np.failClosedMutex.Lock()
+	defer np.failClosedMutex.Unlock()
+	key := getBackoffKey(networkID, taskID)
+	if failed {
+		np.failClosed[key] = networkID
+		return
+	}
+	delete(np.failClosed, key)
+}
+
+// countFailClosed returns the number of tasks failing closed in each network
+func (np *NProbeManager) countFailClosed() map[string]int {
+	np.failClosedMutex.Lock()
+	defer np.failClosedMutex.Unlock()
+	counts := map[string]int{}
+	for _, networkID := range np.failClosed {
+		counts[networkID]++
+	}
+	return counts
+}
+
+// reportFailClosed updates the fail closed metric of each network once the
+// tasks were processed. unread holds the networks whose kill switch can't be
+// read, whose tasks keep the state of the last pass they were processed in.
+func (np *NProbeManager) reportFailClosed(networks []string, unread map[string]bool) {
+	counts := np.countFailClosed()
+	for _, networkID := range networks {
+		killSwitch := 0.0
+		if unread[networkID] {
+			killSwitch = 1
+		}
+		metrics.FailClosed.WithLabelValues(networkID, metrics.FailClosedKillSwitch).Set(killSwitch)
+		metrics.FailClosed.WithLabelValues(networkID, metrics.FailClosedTargetFilter).Set(float64(counts[networkID]))
+	}
+}
+
+// pruneFailClosed drops the state of tasks which no longer exist
+func (np *NProbeManager) pruneFailClosed(keys map[string]bool) {
+	np.failClosedMutex.Lock()
+	defer np.failClosedMutex.Unlock()
+	for key := range np.failClosed {
+		if !keys[key] {
+			delete(np.failClosed, key)
+		}
+	}
+}
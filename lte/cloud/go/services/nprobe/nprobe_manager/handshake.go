@@ -0,0 +1,156 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"reflect"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/tasks"
+	"magma/orc8r/cloud/go/services/configurator"
+)
+
+// applyDestinationSettings applies the settings of the destinations whose
+// delivery address is the delivery function address: the SNI, ALPN, framing
+// and BER encoding settings customize the exporter connection, the module
+// version selects the encoding of the records of the tasks of their delivery type
+// and the synchronous export mode the way these records are delivered, while
+// the minimal records mode degrades the records of the events whose fields
+// can't be encoded instead of quarantining them. Their rate limit overrides
+// the export rate of the service config. The connection is shared by all
+// networks, so when their destinations disagree on the handshake or rate the
+// settings of the first network listed apply. The domain IDs configured by
+// the network config and all the destinations of each network are collected
+// for the module versions of its tasks to be checked against. The current
+// settings are kept if the destinations of a network can't be loaded.
+func (np *NProbeManager) applyDestinationSettings(networks []string) {
+	var settings *exporter.HandshakeSettings
+	var source string
+	limit := np.RateLimit
+	var limitSource string
+	versions := map[string]map[string]moduleSelection{}
+	domainIDs := map[string]encoding.DomainIDs{}
+	syncExports := map[string]map[string]bool{}
+	minimalRecords := map[string]map[string]bool{}
+	encryptions := map[string]map[string]*encoding.PayloadEncryption{}
+	for _, networkID := range networks {
+		destinations, err := getNetworkProbeDestinations(networkID)
+		if err != nil {
+			logger.Errorf("Failed to retrieve nprobe destinations for network %s: %s", networkID, err)
+			return
+		}
+		domainIDs[networkID] = encoding.DomainIDs{}
+		for _, err := range tasks.AddDomainIDs(domainIDs[networkID], np.getNetworkConfig(networkID), destinations) {
+			logger.Warningf("Ignoring conflicting domain ID of network %s: %v", networkID, err)
+		}
+		for _, destination := range destinations {
+			details := destination.DestinationDetails
+			if exporter.NormalizeAddress(details.DeliveryAddress) != exporter.NormalizeAddress(np.DeliveryFunctionAddr) {
+				continue
+			}
+			addModuleVersion(versions, networkID, destination)
+			addSynchronousExport(syncExports, networkID, destination)
+			addMinimalRecords(minimalRecords, networkID, destination)
+			addPayloadEncryption(encryptions, networkID, destination)
+			if details.RateLimit != 0 {
+				if len(limitSource) == 0 {
+					limit.RecordsPerSecond, limit.Burst, limitSource = details.RateLimit, details.BurstSize, networkID
+				} else if limit.RecordsPerSecond != details.RateLimit || limit.Burst != details.BurstSize {
+					logger.Warningf(
+						"Ignoring rate limit of destination %s of network %s conflicting with network %s",
+						destination.DestinationID, networkID, limitSource,
+					)
+				}
+			}
+			current := getDestinationHandshakeSettings(details)
+			if settings == nil {
+				settings, source = &current, networkID
+				continue
+			}
+			if !reflect.DeepEqual(*settings, current) {
+				logger.Warningf(
+					"Ignoring handshake settings of destination %s of network %s conflicting with network %s",
+					destination.DestinationID, networkID, source,
+				)
+			}
+		}
+	}
+	if settings == nil {
+		settings = &exporter.HandshakeSettings{}
+	}
+	np.Exporter.SetHandshakeSettings(*settings)
+	np.Exporter.SetRateLimit(limit)
+	np.setModuleVersions(versions, domainIDs)
+	np.setSynchronousExports(syncExports)
+	np.setMinimalRecords(minimalRecords)
+	np.setPayloadEncryptions(encryptions)
+}
+
+// getNetworkProbeDestinations retrieves the list of all destinations provisioned for a specific network
+func getNetworkProbeDestinations(networkID string) ([]*models.NetworkProbeDestination, error) {
+	ents, _, err := configurator.LoadAllEntitiesOfType(
+		networkID,
+		lte.NetworkProbeDestinationEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*models.NetworkProbeDestination, 0, len(ents))
+	for _, ent := range ents {
+		ret = append(ret, (&models.NetworkProbeDestination{}).FromBackendModels(ent))
+	}
+	return ret, nil
+}
+
+func getDestinationHandshakeSettings(details *models.NetworkProbeDestinationDetails) exporter.HandshakeSettings {
+	settings := exporter.HandshakeSettings{
+		ServerName:  details.TLSServerName,
+		Compression: details.Compression,
+		Framing:     details.Framing,
+		Encoding:    details.Encoding,
+		// validated to be at most 2
+		HeaderVersion:          uint16(details.HeaderVersion),
+		NegotiateHeaderVersion: details.NegotiateHeaderVersion,
+		WriteTimeout:           time.Duration(details.WriteTimeoutMs) * time.Millisecond,
+		Transport:              details.Transport,
+	}
+	if len(details.AlpnProtocols) != 0 {
+		settings.ALPNProtocols = details.AlpnProtocols
+	}
+	return settings
+}
+
+// toHandshakeModel converts the handshake of the exporter connection for the task state
+func toHandshakeModel(info *exporter.HandshakeInfo) *models.NetworkProbeHandshake {
+	if info == nil {
+		return nil
+	}
+	return &models.NetworkProbeHandshake{
+		ServerName:         info.ServerName,
+		NegotiatedProtocol: info.NegotiatedProtocol,
+		TLSVersion:         info.Version,
+		CipherSuite:        info.CipherSuite,
+		PeerSubject:        info.PeerSubject,
+		Resumed:            info.Resumed,
+		HeaderVersion:      uint32(info.HeaderVersion),
+	}
+}
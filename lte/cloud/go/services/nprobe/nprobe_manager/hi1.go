@@ -0,0 +1,225 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// HI1 notifications report the activation and the deactivation of the tasks
+// and the failures of the delivery of their records to the LEA, including the
+// certificates the delivery relies on nearing their expiry. They are not
+// part of the stream of the records of a task, and are best effort: failed
+// activations are notified again by the next pass of the task, but records
+// are never held back by a notification. The activation of a task is
+// recorded in its state, while its deactivation is found by the pass
+// following its deletion, from the tasks listed by the previous one, and
+// recorded in its state until reclaimed. The deactivation of the tasks
+// deleted while the service was down, or along with their network, is
+// notified when their state is reclaimed.
+
+const (
+	// certificateAlarmInterval is the time between the HI1 alarms of a task
+	// whose delivery relies on an expiring certificate
+	certificateAlarmInterval = 24 * time.Hour
+
+	// HI1 alarms of the certificates, within the size allowed for the alarm
+	// information
+	certificateExpiringAlarm = "certificate expiring"
+	certificateExpiredAlarm  = "certificate expired"
+)
+
+// getHI1Exporter returns the exporter delivering the HI1 notifications of
+// a task
+func (np *NProbeManager) getHI1Exporter(networkID string, task *models.NetworkProbeTask) (*exporter.RecordExporter, error) {
+	if len(np.HI1NotificationAddr) == 0 {
+		return np.getExporter(networkID, task)
+	}
+	if np.Destinations == nil {
+		return nil, fmt.Errorf("no exporter pool to deliver HI1 notifications to %s", np.HI1NotificationAddr)
+	}
+	return np.Destinations.Get(exporter.Destination{Address: np.HI1NotificationAddr})
+}
+
+// notifyHI1 delivers an HI1 notification of a task, whose header
+// identifiers are already set
+func (np *NProbeManager) notifyHI1(ctx context.Context, networkID string, task *models.NetworkProbeTask, operation, alarm string) error {
+	taskID := string(task.TaskID)
+	pdu, err := encoding.MakeHI1Notification(task, np.getOperatorID(networkID), operation, alarm, clock.Now())
+	if err != nil {
+		return err
+	}
+	exp, err := np.getHI1Exporter(networkID, task)
+	if err != nil {
+		return err
+	}
+	delivery := exp.SubmitRecords(
+		ctx,
+		getBackoffKey(networkID, taskID),
+		np.getTaskWeight(taskID),
+		task.TaskDetails.CorrelationID,
+		[][]byte{pdu},
+		np.MaxExportRetries,
+	)
+	if err := delivery.Wait(0); err != nil {
+		metrics.HI1NotificationFailures.WithLabelValues(networkID, operation).Inc()
+		return err
+	}
+	metrics.HI1NotificationsSent.WithLabelValues(networkID, operation).Inc()
+	logger.Infof("Notified %s of task %s over HI1", operation, taskID)
+	return nil
+}
+
+// notifyActivation notifies the activation of a task over HI1 unless already
+// notified, and records it in the state of the task
+func (np *NProbeManager) notifyActivation(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+) error {
+	if !np.HI1Notifications || !time.Time(state.Hi1ActivatedAt).IsZero() {
+		return nil
+	}
+	if err := np.notifyHI1(ctx, networkID, np.getRecordTask(networkID, task, state), encoding.HI1Activated, ""); err != nil {
+		return err
+	}
+	state.Hi1ActivatedAt = strfmt.DateTime(time.Now())
+	return np.storeState(networkID, string(task.TaskID), state)
+}
+
+// notifyDeactivations notifies the deactivation of the tasks of a network
+// listed by the previous pass and gone since, and keeps the tasks listed by
+// this pass for the next one
+func (np *NProbeManager) notifyDeactivations(ctx context.Context, networkID string, tasks map[string]*models.NetworkProbeTask) {
+	if !np.HI1Notifications {
+		delete(np.hi1Tasks, networkID)
+		return
+	}
+	previous := np.hi1Tasks[networkID]
+	np.hi1Tasks[networkID] = tasks
+	for taskID, task := range previous {
+		if _, ok := tasks[taskID]; ok {
+			continue
+		}
+		if err := np.notifyHI1(ctx, networkID, np.withHeaderIdentifiers(networkID, task), encoding.HI1Deactivated, ""); err != nil {
+			logger.Errorf("Failed to notify deactivation of task %s: %v", taskID, err)
+			continue
+		}
+		np.recordDeactivation(networkID, taskID)
+	}
+}
+
+// recordDeactivation records the notified deactivation of a deleted task in
+// its state, if not reclaimed yet, so that it isn't notified again when the
+// state is
+func (np *NProbeManager) recordDeactivation(networkID, taskID string) {
+	state, err := np.Storage.GetNProbeData(networkID, taskID)
+	if err != nil {
+		// the state is deleted along with the task by a requested deletion
+		return
+	}
+	state.Hi1DeactivatedAt = strfmt.DateTime(time.Now())
+	if err := np.Storage.StoreNProbeData(networkID, taskID, *state); err != nil {
+		logger.Errorf("Failed to record deactivation of task %s: %v", taskID, err)
+	}
+}
+
+// notifyDeliveryAlarm raises the HI1 alarm of a task whose delivery starts
+// failing. It is delivered in the background, the destination of the task
+// being likely unable to take it right away.
+func (np *NProbeManager) notifyDeliveryAlarm(networkID string, task *models.NetworkProbeTask, state *models.NetworkProbeData, deliveryErr error) {
+	if !np.HI1Notifications {
+		return
+	}
+	recordTask := np.getRecordTask(networkID, task, state)
+	go func() {
+		if err := np.notifyHI1(context.Background(), networkID, recordTask, encoding.HI1Alarm, deliveryErr.Error()); err != nil {
+			logger.Errorf("Failed to raise delivery alarm of task %s: %v", task.TaskID, err)
+		}
+	}()
+}
+
+// notifyCertificateAlarm raises the HI1 alarm of a task whose delivery
+// relies on a certificate expiring soon, or expired: the exporter
+// certificates or the certificate of the delivery function of the task. The
+// alarm is raised again every certificateAlarmInterval until the
+// certificate is renewed, in the background like the delivery alarms.
+func (np *NProbeManager) notifyCertificateAlarm(networkID string, task *models.NetworkProbeTask, state *models.NetworkProbeData, now time.Time) {
+	if !np.HI1Notifications || np.Certificates == nil {
+		return
+	}
+	taskID := string(task.TaskID)
+	destination := exporter.NormalizeAddress(np.getDestinationName(task))
+	alarm := ""
+	for _, cert := range np.Certificates.Expiring(now) {
+		if cert.Kind == health.CertificateServer && cert.Name != destination {
+			continue
+		}
+		if !cert.NotAfter.After(now) {
+			alarm = certificateExpiredAlarm
+			break
+		}
+		alarm = certificateExpiringAlarm
+	}
+
+	key := getBackoffKey(networkID, taskID)
+	np.certificateAlarmMutex.Lock()
+	if len(alarm) == 0 {
+		delete(np.certificateAlarms, key)
+		np.certificateAlarmMutex.Unlock()
+		return
+	}
+	if last, ok := np.certificateAlarms[key]; ok && now.Sub(last) < certificateAlarmInterval {
+		np.certificateAlarmMutex.Unlock()
+		return
+	}
+	np.certificateAlarms[key] = now
+	np.certificateAlarmMutex.Unlock()
+
+	recordTask := np.getRecordTask(networkID, task, state)
+	go func() {
+		if err := np.notifyHI1(context.Background(), networkID, recordTask, encoding.HI1Alarm, alarm); err != nil {
+			logger.Errorf("Failed to raise certificate alarm of task %s: %v", taskID, err)
+			// raised again by the next pass
+			np.certificateAlarmMutex.Lock()
+			if np.certificateAlarms[key] == now {
+				delete(np.certificateAlarms, key)
+			}
+			np.certificateAlarmMutex.Unlock()
+		}
+	}()
+}
+
+// pruneCertificateAlarms drops the alarms of tasks which no longer exist
+func (np *NProbeManager) pruneCertificateAlarms(keys map[string]bool) {
+	np.certificateAlarmMutex.Lock()
+	defer np.certificateAlarmMutex.Unlock()
+	for key := range np.certificateAlarms {
+		if !keys[key] {
+			delete(np.certificateAlarms, key)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+// pduBackend keeps the PDUs sent
+type pduBackend struct {
+	mutex sync.Mutex
+	pdus  [][]byte
+}
+
+func (b *pduBackend) Send(pdu []byte, correlationID uint64) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.pdus = append(b.pdus, pdu)
+	return nil
+}
+
+func (b *pduBackend) IsConnected() bool { return true }
+
+func (b *pduBackend) Close() {}
+
+func (b *pduBackend) getOperations() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var ret []string
+	for _, pdu := range b.pdus {
+		operation, _ := encoding.GetHI1Operation(pdu)
+		ret = append(ret, operation)
+	}
+	return ret
+}
+
+// hasPDU returns true if a PDU holding data was sent
+func (b *pduBackend) hasPDU(data string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, pdu := range b.pdus {
+		if bytes.Contains(pdu, []byte(data)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNotifyDeactivations(t *testing.T) {
+	backend := &pduBackend{}
+	np := &NProbeManager{
+		Exporter:         exporter.NewRecordExporter(backend),
+		MaxExportRetries: 1,
+		HI1Notifications: true,
+		Storage:          storage.WithStateStore(nil, storage.NewMemoryStateStore()),
+		hi1Tasks:         map[string]map[string]*models.NetworkProbeTask{},
+	}
+	defer np.Exporter.Close()
+	newTask := func(taskID string) *models.NetworkProbeTask {
+		return &models.NetworkProbeTask{
+			TaskID:      models.NetworkProbeTaskID(taskID),
+			TaskDetails: &models.NetworkProbeTaskDetails{TargetID: "IMSI001010000000001"},
+		}
+	}
+	task1 := newTask("29f28e1c-f230-486a-a860-f5a784ab9177")
+	task2 := newTask("609dcabd-5ab1-4c95-9681-a24681f105ac")
+	ctx := context.Background()
+
+	// the tasks listed by the first pass of a term are not compared
+	np.notifyDeactivations(ctx, "n0", map[string]*models.NetworkProbeTask{string(task1.TaskID): task1, string(task2.TaskID): task2})
+	assert.Empty(t, backend.getOperations())
+
+	assert.NoError(t, np.Storage.StoreNProbeData("n0", string(task2.TaskID), models.NetworkProbeData{TargetID: task2.TaskDetails.TargetID}))
+	np.notifyDeactivations(ctx, "n0", map[string]*models.NetworkProbeTask{string(task1.TaskID): task1})
+	assert.Equal(t, []string{encoding.HI1Deactivated}, backend.getOperations())
+	// the deactivation is recorded so that it isn't notified again when the
+	// state of the task is reclaimed
+	state, err := np.Storage.GetNProbeData("n0", string(task2.TaskID))
+	assert.NoError(t, err)
+	assert.False(t, time.Time(state.Hi1DeactivatedAt).IsZero())
+	np.notifyDeactivations(ctx, "n0", map[string]*models.NetworkProbeTask{string(task1.TaskID): task1})
+	assert.Len(t, backend.getOperations(), 1)
+
+	// the tasks are forgotten once disabled
+	np.HI1Notifications = false
+	np.notifyDeactivations(ctx, "n0", map[string]*models.NetworkProbeTask{})
+	assert.Empty(t, np.hi1Tasks)
+	assert.Len(t, backend.getOperations(), 1)
+
+	// activations are only notified once
+	np.HI1Notifications = true
+	state = &models.NetworkProbeData{Hi1ActivatedAt: strfmt.DateTime(time.Now())}
+	assert.NoError(t, np.notifyActivation(ctx, "n0", task1, state))
+	assert.Len(t, backend.getOperations(), 1)
+}
+
+func TestNotifyCertificateAlarm(t *testing.T) {
+	backend := &pduBackend{}
+	certificates := health.NewCertificateTracker()
+	certificates.SetWarning(30 * 24 * time.Hour)
+	np := &NProbeManager{
+		Exporter:          exporter.NewRecordExporter(backend),
+		MaxExportRetries:  1,
+		HI1Notifications:  true,
+		Certificates:      certificates,
+		certificateAlarms: map[string]time.Time{},
+		destinationName:   "10.10.0.2:6666",
+	}
+	defer np.Exporter.Close()
+	task := &models.NetworkProbeTask{
+		TaskID:      "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{TargetID: "IMSI001010000000001"},
+	}
+	state := &models.NetworkProbeData{}
+	key := getBackoffKey("n0", string(task.TaskID))
+	now := time.Unix(1615000000, 0)
+	getAlarmedAt := func() (time.Time, bool) {
+		np.certificateAlarmMutex.Lock()
+		defer np.certificateAlarmMutex.Unlock()
+		alarmedAt, ok := np.certificateAlarms[key]
+		return alarmedAt, ok
+	}
+
+	// the certificates of the delivery functions of other tasks are ignored
+	certificates.Observe(health.Certificate{Kind: health.CertificateServer, Name: "10.10.0.3:6666", Subject: "CN=lemf2", NotAfter: now.Add(time.Hour)})
+	certificates.Observe(health.Certificate{Kind: health.CertificateClient, Name: "client.crt", Subject: "CN=nprobe", NotAfter: now.Add(365 * 24 * time.Hour)})
+	np.notifyCertificateAlarm("n0", task, state, now)
+	_, ok := getAlarmedAt()
+	assert.False(t, ok)
+
+	certificates.Observe(health.Certificate{Kind: health.CertificateServer, Name: "10.10.0.2:6666", Subject: "CN=lemf", NotAfter: now.Add(10 * 24 * time.Hour)})
+	np.notifyCertificateAlarm("n0", task, state, now)
+	assert.Eventually(t, func() bool { return backend.hasPDU(certificateExpiringAlarm) }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{encoding.HI1Alarm}, backend.getOperations())
+
+	// the alarm is raised daily
+	np.notifyCertificateAlarm("n0", task, state, now.Add(time.Hour))
+	alarmedAt, _ := getAlarmedAt()
+	assert.Equal(t, now, alarmedAt)
+	certificates.Observe(health.Certificate{Kind: health.CertificateClient, Name: "client.crt", Subject: "CN=nprobe", NotAfter: now})
+	np.notifyCertificateAlarm("n0", task, state, now.Add(certificateAlarmInterval))
+	assert.Eventually(t, func() bool { return backend.hasPDU(certificateExpiredAlarm) }, time.Second, 10*time.Millisecond)
+	assert.Len(t, backend.getOperations(), 2)
+
+	// until the certificates are renewed
+	certificates.Observe(health.Certificate{Kind: health.CertificateClient, Name: "client.crt", Subject: "CN=nprobe", NotAfter: now.Add(365 * 24 * time.Hour)})
+	certificates.Forget(health.CertificateServer, "10.10.0.2:6666")
+	np.notifyCertificateAlarm("n0", task, state, now.Add(2*certificateAlarmInterval))
+	_, ok = getAlarmedAt()
+	assert.False(t, ok)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"sort"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// eventKey identifies an event streamed by a gateway and also fetched from eventd
+type eventKey struct {
+	streamName string
+	eventType  string
+	tag        string
+	timestamp  time.Time
+}
+
+// drainIngested returns the events streamed by the gateways of a network
+// since the previous pass
+func (np *NProbeManager) drainIngested(networkID string) []eventdM.Event {
+	if np.Ingested == nil {
+		return nil
+	}
+	return np.Ingested.Drain(networkID)
+}
+
+// mergeIngestedEvents adds the streamed events concerning a task to the
+// events fetched from eventd, in chronological order. The events gateways
+// stream are also logged to eventd, so streamed events already fetched are
+// skipped, and so are the events after a full page of fetched events as
+// they are fetched on the next pass anyway.
+func mergeIngestedEvents(
+	fetched []eventdM.Event,
+	ingested []eventdM.Event,
+	tags []string,
+	state *models.NetworkProbeData,
+	pageSize int,
+) []eventdM.Event {
+	if len(ingested) == 0 {
+		return fetched
+	}
+	start := getFetchStart(state)
+	var end time.Time
+	times := make([]time.Time, 0, len(fetched)+len(ingested))
+	seen := map[eventKey]bool{}
+	for _, event := range fetched {
+		// events whose timestamp is invalid keep their position
+		t, err := time.Parse(time.RFC3339, event.Timestamp)
+		if err != nil && len(times) != 0 {
+			t = times[len(times)-1]
+		}
+		times = append(times, t)
+		seen[getEventKey(&event, t)] = true
+		end = t
+	}
+	if len(fetched) < pageSize {
+		end = time.Time{}
+	}
+
+	// the caller keeps the events fetched in their own order
+	merged := make([]eventdM.Event, len(fetched), len(fetched)+len(ingested))
+	copy(merged, fetched)
+	for _, event := range ingested {
+		t, err := time.Parse(time.RFC3339, event.Timestamp)
+		if err != nil || t.Before(start) || (!end.IsZero() && t.After(end)) {
+			continue
+		}
+		if len(tags) != 0 && !containsTag(tags, event.Tag) {
+			continue
+		}
+		key := getEventKey(&event, t)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, event)
+		times = append(times, t)
+	}
+
+	sort.Stable(byTime{events: merged, times: times})
+	return merged
+}
+
+// getEventKey returns the key of an event, eventd keeping timestamps to the millisecond
+func getEventKey(event *eventdM.Event, t time.Time) eventKey {
+	return eventKey{
+		streamName: event.StreamName,
+		eventType:  event.EventType,
+		tag:        event.Tag,
+		timestamp:  t.UTC().Truncate(time.Millisecond),
+	}
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// byTime sorts events by their parsed timestamps, then by their IDs if set
+type byTime struct {
+	events []eventdM.Event
+	times  []time.Time
+	ids    []string
+}
+
+func (b byTime) Len() int { return len(b.events) }
+func (b byTime) Less(i, j int) bool {
+	if b.ids != nil && b.times[i].Equal(b.times[j]) {
+		return b.ids[i] < b.ids[j]
+	}
+	return b.times[i].Before(b.times[j])
+}
+func (b byTime) Swap(i, j int) {
+	b.events[i], b.events[j] = b.events[j], b.events[i]
+	b.times[i], b.times[j] = b.times[j], b.times[i]
+	if b.ids != nil {
+		b.ids[i], b.ids[j] = b.ids[j], b.ids[i]
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeTimedEvent(eventType, tag, timestamp string) eventdM.Event {
+	return eventdM.Event{StreamName: "mme", EventType: eventType, Tag: tag, Timestamp: timestamp}
+}
+
+func getEventTypes(events []eventdM.Event) []string {
+	var types []string
+	for _, event := range events {
+		types = append(types, event.EventType)
+	}
+	return types
+}
+
+func TestMergeIngestedEvents(t *testing.T) {
+	cursor, _ := time.Parse(time.RFC3339, "2021-02-18T10:00:00Z")
+	state := &models.NetworkProbeData{LastExported: strfmt.DateTime(cursor)}
+	tags := []string{"IMSI001010000000001", "001010000000001"}
+
+	fetched := []eventdM.Event{
+		makeTimedEvent("attach_success", "IMSI001010000000001", "2021-02-18T10:00:01.000Z"),
+		makeTimedEvent("session_created", "IMSI001010000000001", "2021-02-18T10:00:03.000Z"),
+	}
+	ingested := []eventdM.Event{
+		// already exported
+		makeTimedEvent("s1_setup_success", "IMSI001010000000001", "2021-02-18T09:59:59Z"),
+		// already fetched, with a finer timestamp
+		makeTimedEvent("attach_success", "IMSI001010000000001", "2021-02-18T10:00:01.000400Z"),
+		makeTimedEvent("session_updated", "IMSI001010000000001", "2021-02-18T10:00:04Z"),
+		makeTimedEvent("session_created", "001010000000001", "2021-02-18T10:00:02Z"),
+		// another subscriber
+		makeTimedEvent("detach_success", "IMSI001010000000002", "2021-02-18T10:00:02Z"),
+	}
+	merged := mergeIngestedEvents(fetched, ingested, tags, state, querySize)
+	assert.Equal(t, []string{"attach_success", "session_created", "session_created", "session_updated"}, getEventTypes(merged))
+	assert.Equal(t, "001010000000001", merged[1].Tag)
+
+	// all events are merged for untagged targets
+	merged = mergeIngestedEvents(nil, ingested, nil, state, querySize)
+	assert.Equal(t, []string{"attach_success", "session_created", "detach_success", "session_updated"}, getEventTypes(merged))
+
+	// events after a full page are fetched on the next pass
+	fetched = nil
+	for i := 0; i < querySize; i++ {
+		fetched = append(fetched, makeTimedEvent(fmt.Sprintf("e%d", i), "IMSI001010000000001", "2021-02-18T10:00:02Z"))
+	}
+	merged = mergeIngestedEvents(fetched, ingested, tags, state, querySize)
+	assert.Len(t, merged, querySize+2)
+	assert.Equal(t, "attach_success", merged[0].EventType)
+	assert.Equal(t, "session_created", merged[querySize+1].EventType)
+
+	// and so are the events after a full page of the configured size
+	merged = mergeIngestedEvents(fetched[:2], ingested, tags, state, 2)
+	assert.Equal(t, []string{"attach_success", "e0", "e1", "session_created"}, getEventTypes(merged))
+}
+
+func TestGetFetchPaging(t *testing.T) {
+	np := &NProbeManager{}
+	pageSize, maxPages := np.getFetchPaging()
+	assert.Equal(t, querySize, pageSize)
+	assert.Equal(t, 1, maxPages)
+
+	np.FetchPageSize, np.FetchMaxPages = 200, 5
+	pageSize, maxPages = np.getFetchPaging()
+	assert.Equal(t, 200, pageSize)
+	assert.Equal(t, 5, maxPages)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/tasks"
+)
+
+// Each pass decides the state of the lifecycle of a task from its conditions
+// before processing it, and processes it as its state calls for: pending
+// tasks are held until their warrant starts, active and failed tasks deliver
+// the records of their events, suspended tasks are silent, expiring tasks
+// deliver their pending events then end their interception, and terminated
+// tasks are left alone, or deleted once their deletion is requested. The
+// transitions are stored with their time and reason, those ending the
+// interception of a task or failing its processing as soon as they happen.
+// The tasks of a network suspended by the kill switch aren't processed, and
+// keep their state.
+
+// Reasons of the transitions decided by the manager
+const (
+	reasonDeletionTimedOut  = "deletion timed out"
+	reasonReported          = "one-shot report delivered"
+	reasonEnded             = "interception ended"
+	reasonDeletedPending    = "deleted before its warrant started"
+	reasonWarrantPending    = "warrant not started"
+	reasonDeletionRequested = "deletion requested"
+	reasonWarrantEnded      = "warrant ended"
+	reasonPaused            = "paused"
+	reasonFailed            = "processing failed: "
+	reasonIntercepting      = "intercepting"
+)
+
+// taskConditions are the conditions of a task deciding the state of its
+// lifecycle at the start of a pass
+type taskConditions struct {
+	deletionOverdue bool
+	deleting        bool
+	completed       bool
+	expired         bool
+	warrantPending  bool
+	warrantOver     bool
+	paused          bool
+	// failure is the class of the failures the last passes of the task
+	// ended with, empty once a pass succeeds
+	failure string
+}
+
+// evaluateTaskState returns the state of the lifecycle of a task in the
+// given conditions and the reason it is in it. The conditions are checked in
+// order of precedence, so that the same conditions always lead to the same
+// state.
+func evaluateTaskState(c taskConditions) (string, string) {
+	switch {
+	case c.deletionOverdue:
+		return models.NetworkProbeTaskLifecycleStateTerminated, reasonDeletionTimedOut
+	case c.completed:
+		return models.NetworkProbeTaskLifecycleStateTerminated, reasonReported
+	case c.expired:
+		return models.NetworkProbeTaskLifecycleStateTerminated, reasonEnded
+	case c.warrantPending && c.deleting:
+		return models.NetworkProbeTaskLifecycleStateTerminated, reasonDeletedPending
+	case c.warrantPending:
+		return models.NetworkProbeTaskLifecycleStatePending, reasonWarrantPending
+	case c.deleting:
+		return models.NetworkProbeTaskLifecycleStateExpiring, reasonDeletionRequested
+	case c.warrantOver:
+		return models.NetworkProbeTaskLifecycleStateExpiring, reasonWarrantEnded
+	case c.paused:
+		return models.NetworkProbeTaskLifecycleStateSuspended, reasonPaused
+	case len(c.failure) != 0:
+		return models.NetworkProbeTaskLifecycleStateFailed, reasonFailed + c.failure
+	}
+	return models.NetworkProbeTaskLifecycleStateActive, reasonIntercepting
+}
+
+// transitionTask moves a task to a state of its lifecycle, storing the
+// transition if it changes its state
+func (np *NProbeManager) transitionTask(networkID, taskID string, lifecycle *models.NetworkProbeTaskLifecycle, to, reason string) error {
+	from := lifecycle.State
+	if err := tasks.StoreTransition(np.Storage, networkID, taskID, lifecycle, to, reason, ""); err != nil {
+		return err
+	}
+	if from != lifecycle.State {
+		logger.Infof("Task %s of network %s moved from %s to %s: %s", taskID, networkID, from, to, reason)
+	}
+	return nil
+}
+
+// enterTaskState moves a task to a state of its lifecycle in the middle of a
+// pass. The transition is left to the next pass if it can't be stored.
+func (np *NProbeManager) enterTaskState(networkID, taskID, to, reason string) {
+	lifecycle, err := np.Storage.GetTaskLifecycle(networkID, taskID)
+	if err == nil {
+		err = np.transitionTask(networkID, taskID, lifecycle, to, reason)
+	}
+	if err != nil {
+		logger.Errorf("Failed to move task %s to %s: %v", taskID, to, err)
+	}
+}
+
+// failTask moves an active task whose pass failed to the failed state. It
+// stays failed until a pass succeeds.
+func (np *NProbeManager) failTask(networkID, taskID string) {
+	failure := np.getTaskFailure(getBackoffKey(networkID, taskID))
+	if len(failure) == 0 {
+		return
+	}
+	lifecycle, err := np.Storage.GetTaskLifecycle(networkID, taskID)
+	if err != nil {
+		logger.Errorf("Failed to get lifecycle of task %s: %v", taskID, err)
+		return
+	}
+	if lifecycle.State != models.NetworkProbeTaskLifecycleStateActive {
+		return
+	}
+	if err := np.transitionTask(networkID, taskID, lifecycle, models.NetworkProbeTaskLifecycleStateFailed, reasonFailed+failure); err != nil {
+		logger.Errorf("Failed to move task %s to %s: %v", taskID, models.NetworkProbeTaskLifecycleStateFailed, err)
+	}
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateTaskState(t *testing.T) {
+	tests := []struct {
+		conditions taskConditions
+		state      string
+		reason     string
+	}{
+		{taskConditions{}, models.NetworkProbeTaskLifecycleStateActive, reasonIntercepting},
+		{taskConditions{warrantPending: true}, models.NetworkProbeTaskLifecycleStatePending, reasonWarrantPending},
+		{taskConditions{warrantPending: true, paused: true}, models.NetworkProbeTaskLifecycleStatePending, reasonWarrantPending},
+		{taskConditions{paused: true, failure: exporter.FailureConnect}, models.NetworkProbeTaskLifecycleStateSuspended, reasonPaused},
+		{taskConditions{failure: exporter.FailureConnect}, models.NetworkProbeTaskLifecycleStateFailed, reasonFailed + exporter.FailureConnect},
+		// the pending events of expiring tasks are delivered even if paused
+		{taskConditions{warrantOver: true, paused: true}, models.NetworkProbeTaskLifecycleStateExpiring, reasonWarrantEnded},
+		{taskConditions{deleting: true, warrantOver: true}, models.NetworkProbeTaskLifecycleStateExpiring, reasonDeletionRequested},
+		// nothing was intercepted by a task deleted before its warrant started
+		{taskConditions{deleting: true, warrantPending: true}, models.NetworkProbeTaskLifecycleStateTerminated, reasonDeletedPending},
+		{taskConditions{expired: true, deleting: true}, models.NetworkProbeTaskLifecycleStateTerminated, reasonEnded},
+		{taskConditions{completed: true, paused: true}, models.NetworkProbeTaskLifecycleStateTerminated, reasonReported},
+		{taskConditions{deletionOverdue: true, deleting: true, expired: true}, models.NetworkProbeTaskLifecycleStateTerminated, reasonDeletionTimedOut},
+	}
+	for _, test := range tests {
+		state, reason := evaluateTaskState(test.conditions)
+		assert.Equal(t, test.state, state, "%+v", test.conditions)
+		assert.Equal(t, test.reason, reason, "%+v", test.conditions)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+	"magma/orc8r/cloud/go/services/state"
+	merrors "magma/orc8r/lib/go/errors"
+)
+
+// locatedEvents are the types of the EPS events whose record reports the
+// location of the target
+var locatedEvents = map[string]bool{
+	nprobe.SessionCreated:            true,
+	nprobe.SessionUpdated:            true,
+	nprobe.SessionTerminated:         true,
+	nprobe.HandoverSuccess:           true,
+	nprobe.PDNConnectivityRequested:  true,
+	nprobe.PDNDisconnectionRequested: true,
+	nprobe.TrackingAreaUpdate:        true,
+	nprobe.ServingSystemChanged:      true,
+	nprobe.UpdateLocationSuccess:     true,
+	nprobe.TargetReported:            true,
+}
+
+// isLocationAuthorized returns true if the records of a task may report the
+// location of its target, i.e. if its network authorizes the warrant type
+// of the task to. Networks authorizing no warrant type in particular
+// authorize all the tasks.
+func (np *NProbeManager) isLocationAuthorized(networkID string, task *models.NetworkProbeTask) bool {
+	warrantTypes := np.getNetworkConfig(networkID).LocationWarrantTypes
+	if len(warrantTypes) == 0 {
+		return true
+	}
+	for _, warrantType := range warrantTypes {
+		if warrantType == task.TaskDetails.WarrantType {
+			return true
+		}
+	}
+	return false
+}
+
+// locationEnricher fills the TAI and ECGI of the target in the EPS events
+// reporting no location with the UE context reported by the MME, as it is
+// when the record is built. The contexts are looked up once per pass of a
+// task, failed lookups leaving the events as they are, unless the states
+// were synced by the pass.
+type locationEnricher struct {
+	networkID string
+	// locations are the location fields of the UE context of each IMSI
+	locations map[string]map[string]string
+}
+
+// newLocationEnricher returns an enricher of the events of a network, using
+// the states synced when not nil
+func newLocationEnricher(networkID string, synced *syncedStates) *locationEnricher {
+	l := &locationEnricher{networkID: networkID, locations: map[string]map[string]string{}}
+	if synced != nil {
+		for imsi, fields := range synced.locations {
+			l.locations[imsi] = fields
+		}
+	}
+	return l
+}
+
+// enrich fills the location of the target of an event reporting none. The
+// event value is copied rather than updated.
+func (l *locationEnricher) enrich(ctx context.Context, event *eventdM.Event) {
+	if !locatedEvents[event.EventType] || event.StreamName == nprobe.ESStreamSGSN || encoding.HasLocation(event) {
+		return
+	}
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	imsi, _ := eventData["imsi"].(string)
+	if len(imsi) == 0 {
+		return
+	}
+	fields := l.getLocation(ctx, imsi)
+	if len(fields) == 0 {
+		return
+	}
+	value := make(map[string]interface{}, len(eventData)+len(fields))
+	for key, v := range eventData {
+		value[key] = v
+	}
+	for key, v := range fields {
+		value[key] = v
+	}
+	event.Value = value
+}
+
+// getLocation returns the location fields of the UE context of an IMSI
+func (l *locationEnricher) getLocation(ctx context.Context, imsi string) map[string]string {
+	if fields, ok := l.locations[imsi]; ok {
+		return fields
+	}
+	var fields map[string]string
+	st, err := state.GetState(ctx, l.networkID, lte.MMEStateType, imsi, serdes.State)
+	switch {
+	case err == merrors.ErrNotFound:
+	case err != nil:
+		logger.Warningf("Failed to get MME state of %s, records left without location: %v", redact.Identity(imsi), redact.Error(err))
+	default:
+		if reported, ok := st.ReportedState.(*state.ArbitraryJSON); ok {
+			fields = getUEContextLocation(*reported)
+		}
+	}
+	l.locations[imsi] = fields
+	return fields
+}
+
+// getUEContextLocation returns the tai and ecgi fields of the UE context of
+// a subscriber reported by the MME: the last visited registered TAI of its
+// EMM context and its E-UTRAN CGI. The context is the JSON mapping of its
+// protobuf message, whose field names are accepted as is or in lower camel
+// case.
+func getUEContextLocation(ueContext map[string]interface{}) map[string]string {
+	fields := map[string]string{}
+	emmContext, _ := getJSONField(ueContext, "emm_context", "emmContext").(map[string]interface{})
+	if tai, ok := getJSONField(emmContext, "lvr_tai", "lvrTai").(map[string]interface{}); ok {
+		mccMnc, _ := getJSONField(tai, "mcc_mnc", "mccMnc").(string)
+		tac, _ := getJSONField(tai, "tac").(float64)
+		digits, err := base64.StdEncoding.DecodeString(mccMnc)
+		if plmn := encoding.MakePLMNID(string(digits)); err == nil && plmn != nil && tac > 0 && tac <= 0xffff {
+			fields["tai"] = hex.EncodeToString(append(plmn, byte(uint16(tac)>>8), byte(tac)))
+		}
+	}
+	if ecgi, ok := getJSONField(ueContext, "e_utran_cgi", "eUtranCgi").(map[string]interface{}); ok {
+		plmnID, _ := getJSONField(ecgi, "plmn").(string)
+		enbID, _ := getJSONField(ecgi, "enb_id", "enbId").(float64)
+		cellID, _ := getJSONField(ecgi, "cell_id", "cellId").(float64)
+		plmn, err := base64.StdEncoding.DecodeString(plmnID)
+		if err == nil && len(plmn) == 3 && enbID > 0 && enbID < 1<<20 && cellID < 1<<8 {
+			eci := make([]byte, 4)
+			binary.BigEndian.PutUint32(eci, uint32(enbID)<<8|uint32(cellID))
+			fields["ecgi"] = hex.EncodeToString(append(plmn, eci...))
+		}
+	}
+	return fields
+}
+
+// getJSONField returns the value of the first of the names set in a JSON
+// object
+func getJSONField(object map[string]interface{}, names ...string) interface{} {
+	for _, name := range names {
+		if v, ok := object[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocationEnrichment(t *testing.T) {
+	reported := map[string]interface{}{
+		"msisdn": "33612345678",
+		"emmContext": map[string]interface{}{
+			// "00101"
+			"lvrTai": map[string]interface{}{"mccMnc": "MDAxMDE=", "tac": float64(1)},
+		},
+		"eUtranCgi": map[string]interface{}{"plmn": "APEQ", "enbId": float64(0x1a2), "cellId": float64(0xb3)},
+	}
+	fields := getUEContextLocation(reported)
+	assert.Equal(t, map[string]string{"tai": "00f1100001", "ecgi": "00f1100001a2b3"}, fields)
+
+	// the field names of the protobuf messages are accepted as well
+	reported = map[string]interface{}{
+		"e_utran_cgi": map[string]interface{}{"plmn": "APEQ", "enb_id": float64(0x1a2), "cell_id": float64(0xb3)},
+	}
+	assert.Equal(t, map[string]string{"ecgi": "00f1100001a2b3"}, getUEContextLocation(reported))
+	assert.Empty(t, getUEContextLocation(map[string]interface{}{"eUtranCgi": "unexpected"}))
+
+	enricher := newLocationEnricher("n0", nil)
+	enricher.locations["IMSI001010000000001"] = fields
+	event := &eventdM.Event{
+		EventType: nprobe.SessionCreated,
+		Value:     map[string]interface{}{"imsi": "IMSI001010000000001"},
+	}
+	eventData := event.Value
+	enricher.enrich(context.Background(), event)
+	assert.Equal(t, map[string]interface{}{"imsi": "IMSI001010000000001", "tai": "00f1100001", "ecgi": fields["ecgi"]}, event.Value)
+	assert.Equal(t, map[string]interface{}{"imsi": "IMSI001010000000001"}, eventData)
+
+	// the location reported by the event prevails
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "user_location": "1f00f110"}
+	enricher.enrich(context.Background(), event)
+	assert.Equal(t, map[string]interface{}{"imsi": "IMSI001010000000001", "user_location": "1f00f110"}, event.Value)
+
+	// the records of attachments don't report the location
+	event = &eventdM.Event{EventType: nprobe.AttachSuccess, Value: map[string]interface{}{"imsi": "IMSI001010000000001"}}
+	enricher.enrich(context.Background(), event)
+	assert.Equal(t, map[string]interface{}{"imsi": "IMSI001010000000001"}, event.Value)
+}
+
+func TestLocationAuthorization(t *testing.T) {
+	np := &NProbeManager{}
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{
+		"restricted": {LocationWarrantTypes: []string{"interception"}},
+	})
+	task := &models.NetworkProbeTask{TaskDetails: &models.NetworkProbeTaskDetails{}}
+	assert.True(t, np.isLocationAuthorized("n0", task))
+	assert.False(t, np.isLocationAuthorized("restricted", task))
+	task.TaskDetails.WarrantType = "communications_data"
+	assert.False(t, np.isLocationAuthorized("restricted", task))
+	task.TaskDetails.WarrantType = "interception"
+	assert.True(t, np.isLocationAuthorized("restricted", task))
+}
+
+func TestTargetReportLocation(t *testing.T) {
+	events := []eventdM.Event{
+		{EventType: nprobe.TrackingAreaUpdate, Value: map[string]interface{}{"imsi": "IMSI001010000000001", "user_location": "1f00f110"}},
+		{EventType: nprobe.TrackingAreaUpdate, Value: map[string]interface{}{"imsi": "IMSI001010000000001", "tai": "00f1100002"}},
+	}
+	// the location reported last prevails, whatever its fields
+	report := makeTargetReport(events, time.Now())
+	assert.Equal(t, map[string]interface{}{"imsi": "IMSI001010000000001", "tai": "00f1100002"}, report.Value)
+}
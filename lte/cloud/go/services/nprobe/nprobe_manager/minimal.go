@@ -0,0 +1,84 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"strings"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// addMinimalRecords enables minimal records for the tasks of the network
+// and delivery type of a destination accepting them
+func addMinimalRecords(minimalRecords map[string]map[string]bool, networkID string, destination *models.NetworkProbeDestination) {
+	details := destination.DestinationDetails
+	if !details.MinimalRecords {
+		return
+	}
+	if minimalRecords[networkID] == nil {
+		minimalRecords[networkID] = map[string]bool{}
+	}
+	minimalRecords[networkID][details.DeliveryType] = true
+}
+
+// setMinimalRecords replaces the tasks accepting minimal records
+func (np *NProbeManager) setMinimalRecords(minimalRecords map[string]map[string]bool) {
+	np.minimalRecordMutex.Lock()
+	defer np.minimalRecordMutex.Unlock()
+	np.minimalRecords = minimalRecords
+}
+
+// isMinimalRecordsEnabled returns true if a destination of the task accepts
+// minimal records for the events whose fields can't be encoded. A task with
+// its own delivery function sets it in its delivery.
+func (np *NProbeManager) isMinimalRecordsEnabled(networkID string, task *models.NetworkProbeTask) bool {
+	if delivery := task.TaskDetails.Delivery; delivery != nil {
+		return delivery.MinimalRecords
+	}
+	np.minimalRecordMutex.RLock()
+	defer np.minimalRecordMutex.RUnlock()
+	return np.minimalRecords[networkID][task.TaskDetails.DeliveryType]
+}
+
+// makeMinimalRecord builds the minimal record of an event whose full record
+// failed with encodeErr. The original error is returned if the minimal record
+// can't be built either, so that the event is quarantined as before.
+func (np *NProbeManager) makeMinimalRecord(
+	networkID, taskID string,
+	event *eventdM.Event,
+	task *models.NetworkProbeTask,
+	sequenceNbr uint32,
+	class string,
+	version *encoding.ModuleVersion,
+	encodeErr error,
+) ([]byte, error) {
+	record, missing, err := encoding.MakeMinimalRecord(event, task, np.getOperatorID(networkID), sequenceNbr, class, version)
+	if err == nil {
+		record, err = np.prepareRecord(networkID, task, record)
+	}
+	if err != nil {
+		logger.Errorf("Failed to build minimal record from event %s: %s", redact.Event(event), redact.Error(err))
+		return nil, encodeErr
+	}
+	logger.Warningf(
+		"Exporting minimal record %d of task %s without %s: %s",
+		sequenceNbr, taskID, strings.Join(missing, ","), encodeErr,
+	)
+	metrics.MinimalRecordsEncoded.WithLabelValues(networkID, encoding.GetErrorField(encodeErr)).Inc()
+	return record, nil
+}
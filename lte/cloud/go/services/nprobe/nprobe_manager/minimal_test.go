@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinimalRecords(t *testing.T) {
+	np := &NProbeManager{OperatorID: 49002, RecordValidation: encoding.ValidationStrict}
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:     "IMSI001010000000001",
+			TargetType:   "imsi",
+			DeliveryType: models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly,
+		},
+	}
+	newDestination := func(deliveryType string, minimal bool) *models.NetworkProbeDestination {
+		return &models.NetworkProbeDestination{
+			DestinationID:      "dest1",
+			DestinationDetails: &models.NetworkProbeDestinationDetails{DeliveryType: deliveryType, MinimalRecords: minimal},
+		}
+	}
+	minimalRecords := map[string]map[string]bool{}
+	addMinimalRecords(minimalRecords, "n0", newDestination(models.NetworkProbeDestinationDetailsDeliveryTypeAll, true))
+	addMinimalRecords(minimalRecords, "n1", newDestination(models.NetworkProbeDestinationDetailsDeliveryTypeEventsOnly, false))
+	addMinimalRecords(minimalRecords, "n2", newDestination(models.NetworkProbeDestinationDetailsDeliveryTypeEventsOnly, true))
+	np.setMinimalRecords(minimalRecords)
+	assert.False(t, np.isMinimalRecordsEnabled("n0", task))
+	assert.False(t, np.isMinimalRecordsEnabled("n1", task))
+	assert.True(t, np.isMinimalRecordsEnabled("n2", task))
+
+	version, err := encoding.GetModuleVersion("")
+	assert.NoError(t, err)
+	event := &eventdM.Event{
+		EventType:  nprobe.SessionCreated,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value: map[string]interface{}{
+			"imsi":       "IMSI001010000000001",
+			"session_id": "IMSI001010000000001-919642",
+			"ip_addr":    "192.168.128",
+		},
+	}
+	_, encodeErr := encoding.MakeVersionedRecord(event, task, np.OperatorID, 1, encoding.RecordClassBegin, version)
+	assert.Error(t, encodeErr)
+	record, err := np.makeMinimalRecord("n2", string(task.TaskID), event, task, 1, encoding.RecordClassBegin, version, encodeErr)
+	assert.NoError(t, err)
+	var decoded encoding.EpsIRIRecord
+	assert.NoError(t, decoded.Decode(record))
+	assert.Equal(t, []string{encoding.FieldBearerParams}, encoding.GetMissingParameters(&decoded.Header))
+
+	// events whose minimal record can't be built either keep their error
+	event.Timestamp = "18/02/2021"
+	_, err = np.makeMinimalRecord("n2", string(task.TaskID), event, task, 1, encoding.RecordClassBegin, version, encodeErr)
+	assert.Equal(t, encodeErr, err)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/orc8r/cloud/go/services/configurator"
+)
+
+// loadNetworkConfigs reads the delivery settings of the networks from the
+// configurator, once per processing cycle. The current settings are kept
+// if the networks can't be loaded.
+func (np *NProbeManager) loadNetworkConfigs(networks []string) {
+	loaded, _, err := configurator.LoadNetworks(networks, false, true, serdes.Network)
+	if err != nil {
+		logger.Errorf("Failed to retrieve nprobe network configs: %s", err)
+		return
+	}
+	configs := map[string]*models.NetworkProbeNetworkConfig{}
+	for _, network := range loaded {
+		if config, ok := network.Configs[lte.NetworkProbeConfigType].(*models.NetworkProbeNetworkConfig); ok {
+			configs[network.ID] = config
+		}
+	}
+	np.setNetworkConfigs(configs)
+}
+
+// setNetworkConfigs replaces the delivery settings of the networks
+func (np *NProbeManager) setNetworkConfigs(configs map[string]*models.NetworkProbeNetworkConfig) {
+	np.networkConfigMutex.Lock()
+	defer np.networkConfigMutex.Unlock()
+	np.networkConfigs = configs
+}
+
+// getNetworkConfig returns the delivery settings of a network, empty if the
+// network only uses the service config
+func (np *NProbeManager) getNetworkConfig(networkID string) *models.NetworkProbeNetworkConfig {
+	np.networkConfigMutex.RLock()
+	defer np.networkConfigMutex.RUnlock()
+	if config, ok := np.networkConfigs[networkID]; ok {
+		return config
+	}
+	return &models.NetworkProbeNetworkConfig{}
+}
+
+// getOperatorID returns the operator ID of the records of a network
+func (np *NProbeManager) getOperatorID(networkID string) uint32 {
+	if operatorID := np.getNetworkConfig(networkID).OperatorID; operatorID != 0 {
+		return operatorID
+	}
+	return np.OperatorID
+}
+
+// getHeaderIdentifiers returns the LIID and the delivery country code of the
+// headers of the records of a network whose task defines none
+func (np *NProbeManager) getHeaderIdentifiers(networkID string) (string, string) {
+	config := np.getNetworkConfig(networkID)
+	lawfulInterceptionID, deliveryCountryCode := config.LawfulInterceptionID, config.DeliveryCountryCode
+	if len(lawfulInterceptionID) == 0 {
+		lawfulInterceptionID = np.LawfulInterceptionID
+	}
+	if len(deliveryCountryCode) == 0 {
+		deliveryCountryCode = np.DeliveryCountryCode
+	}
+	return lawfulInterceptionID, deliveryCountryCode
+}
+
+// withHeaderIdentifiers returns the task as identified in the headers of its
+// records, the identifiers it defines taking precedence over the ones of its
+// network and of the service
+func (np *NProbeManager) withHeaderIdentifiers(networkID string, task *models.NetworkProbeTask) *models.NetworkProbeTask {
+	lawfulInterceptionID, deliveryCountryCode := np.getHeaderIdentifiers(networkID)
+	return encoding.WithHeaderIdentifiers(task, lawfulInterceptionID, deliveryCountryCode)
+}
+
+// isDeliveryHeld returns true if the records of a network must be delivered
+// to another delivery function than the one of the exporter. The tasks of
+// the network are held until the addresses agree, so that no record reaches
+// a delivery function it isn't meant for.
+func (np *NProbeManager) isDeliveryHeld(networkID string) bool {
+	address := np.getNetworkConfig(networkID).DeliveryFunctionAddress
+	if len(address) == 0 || exporter.NormalizeAddress(address) == exporter.NormalizeAddress(np.DeliveryFunctionAddr) {
+		return false
+	}
+	logger.Warningf(
+		"Holding the tasks of network %s delivered to %s while the exporter delivers to %s",
+		networkID, address, np.DeliveryFunctionAddr,
+	)
+	return true
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/tasks"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkConfigs(t *testing.T) {
+	np := &NProbeManager{OperatorID: 1, DeliveryFunctionAddr: "10.10.0.2:6666"}
+	task := &models.NetworkProbeTask{
+		TaskID:      "task1",
+		TaskDetails: &models.NetworkProbeTaskDetails{DeliveryType: models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly},
+	}
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{
+		"n1": {OperatorID: 49002, ModuleVersion: encoding.ModuleVersionR13, DeliveryFunctionAddress: "10.10.0.2:6666"},
+		"n2": {DeliveryFunctionAddress: "10.10.0.3:6666"},
+	})
+
+	// networks without config use the service config
+	assert.Equal(t, uint32(1), np.getOperatorID("n0"))
+	assert.Equal(t, encoding.DefaultModuleVersion, np.getModuleVersion("n0", task).Name)
+	assert.False(t, np.isDeliveryHeld("n0"))
+
+	assert.Equal(t, uint32(49002), np.getOperatorID("n1"))
+	assert.Equal(t, encoding.ModuleVersionR13, np.getModuleVersion("n1", task).Name)
+	assert.False(t, np.isDeliveryHeld("n1"))
+	assert.Equal(t, uint32(1), np.getOperatorID("n2"))
+	assert.True(t, np.isDeliveryHeld("n2"))
+
+	// the module version of a destination overrides the network config
+	np.setModuleVersions(map[string]map[string]moduleSelection{
+		"n1": {models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly: {version: encoding.ModuleVersionR14}},
+	}, nil)
+	assert.Equal(t, encoding.ModuleVersionR14, np.getModuleVersion("n1", task).Name)
+}
+
+func TestDomainIDs(t *testing.T) {
+	np := &NProbeManager{}
+	task := &models.NetworkProbeTask{
+		TaskID:      "task1",
+		TaskDetails: &models.NetworkProbeTaskDetails{DeliveryType: models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly},
+	}
+	config := &models.NetworkProbeNetworkConfig{ModuleVersion: encoding.ModuleVersionR13, Hi2DomainID: "1.3.6.1.4.1.99999.13"}
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{"n1": config})
+	domainIDs := encoding.DomainIDs{}
+	assert.Empty(t, tasks.AddDomainIDs(domainIDs, config, nil))
+
+	// the domain ID of the network config identifies the records of the
+	// module version
+	version := np.getModuleVersion("n1", task)
+	assert.Equal(t, encoding.ModuleVersionR13, version.Name)
+	assert.Equal(t, "1.3.6.1.4.1.99999.13", version.OID.String())
+
+	// a destination selecting the same module version without a domain ID
+	// keeps the one of the network, another module version is identified by
+	// the OID of its release
+	np.setModuleVersions(map[string]map[string]moduleSelection{
+		"n1": {models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly: {version: encoding.ModuleVersionR13}},
+	}, map[string]encoding.DomainIDs{"n1": domainIDs})
+	assert.Equal(t, "1.3.6.1.4.1.99999.13", np.getModuleVersion("n1", task).OID.String())
+	np.setModuleVersions(map[string]map[string]moduleSelection{
+		"n1": {models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly: {version: encoding.ModuleVersionR14}},
+	}, map[string]encoding.DomainIDs{"n1": domainIDs})
+	version = np.getModuleVersion("n1", task)
+	assert.Equal(t, encoding.ModuleVersionR14, version.Name)
+	r14, _ := encoding.GetModuleVersion(encoding.ModuleVersionR14)
+	assert.Equal(t, r14.OID, version.OID)
+
+	// the delivery of the task takes precedence
+	task.TaskDetails.Delivery = &models.NetworkProbeTaskDelivery{Hi2DomainID: "1.3.6.1.4.1.99999.14"}
+	version = np.getModuleVersion("n1", task)
+	assert.Equal(t, encoding.ModuleVersionR14, version.Name)
+	assert.Equal(t, "1.3.6.1.4.1.99999.14", version.OID.String())
+
+	// a domain ID can't identify the records of several releases in a network
+	task.TaskDetails.Delivery = &models.NetworkProbeTaskDelivery{ModuleVersion: encoding.ModuleVersionR15, Hi2DomainID: "1.3.6.1.4.1.99999.13"}
+	version = np.getModuleVersion("n1", task)
+	assert.Equal(t, encoding.DefaultModuleVersion, version.Name)
+	r15, _ := encoding.GetModuleVersion(encoding.DefaultModuleVersion)
+	assert.Equal(t, r15.OID, version.OID)
+
+	// the domain IDs are the ones of the current configs of the network
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{"n1": {}})
+	np.setModuleVersions(nil, map[string]encoding.DomainIDs{"n1": {}})
+	version = np.getModuleVersion("n1", task)
+	assert.Equal(t, encoding.ModuleVersionR15, version.Name)
+	assert.Equal(t, "1.3.6.1.4.1.99999.13", version.OID.String())
+}
+
+func TestHeaderIdentifiers(t *testing.T) {
+	np := &NProbeManager{LawfulInterceptionID: "LIID-0001", DeliveryCountryCode: "FR"}
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{
+		"n1": {LawfulInterceptionID: "LIID-0002", DeliveryCountryCode: "BE"},
+		"n2": {DeliveryCountryCode: "DE"},
+	})
+	task := &models.NetworkProbeTask{
+		TaskID:      "task1",
+		TaskDetails: &models.NetworkProbeTaskDetails{TargetID: "IMSI1234"},
+	}
+
+	// the network config overrides the service config
+	recordTask := np.withHeaderIdentifiers("n0", task)
+	assert.Equal(t, "LIID-0001", recordTask.TaskDetails.AuthorizationReference)
+	assert.Equal(t, "FR", recordTask.TaskDetails.DeliveryCountryCode)
+	recordTask = np.withHeaderIdentifiers("n1", task)
+	assert.Equal(t, "LIID-0002", recordTask.TaskDetails.AuthorizationReference)
+	assert.Equal(t, "BE", recordTask.TaskDetails.DeliveryCountryCode)
+	recordTask = np.withHeaderIdentifiers("n2", task)
+	assert.Equal(t, "LIID-0001", recordTask.TaskDetails.AuthorizationReference)
+	assert.Equal(t, "DE", recordTask.TaskDetails.DeliveryCountryCode)
+	assert.Empty(t, task.TaskDetails.AuthorizationReference)
+	assert.Empty(t, task.TaskDetails.DeliveryCountryCode)
+
+	// the identifiers of the task take precedence
+	task.TaskDetails.AuthorizationReference = "LIID-2021-0042"
+	task.TaskDetails.DeliveryCountryCode = "IT"
+	assert.Equal(t, task, np.withHeaderIdentifiers("n1", task))
+
+	// tasks are left as is without defaults
+	task.TaskDetails = &models.NetworkProbeTaskDetails{TargetID: "IMSI1234"}
+	np = &NProbeManager{}
+	assert.Equal(t, task, np.withHeaderIdentifiers("n0", task))
+}
@@ -15,38 +15,351 @@ package npmanager
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sync"
 	"time"
 
 	"magma/lte/cloud/go/lte"
 	"magma/lte/cloud/go/serdes"
 	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/debug"
 	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/eventsource"
 	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/lte/cloud/go/services/nprobe/ingest"
+	"magma/lte/cloud/go/services/nprobe/keyring"
+	"magma/lte/cloud/go/services/nprobe/killswitch"
+	"magma/lte/cloud/go/services/nprobe/latency"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
 	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/lte/cloud/go/services/nprobe/signing"
 	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/lte/cloud/go/services/nprobe/tasks"
+	"magma/lte/cloud/go/services/nprobe/tracing"
 	"magma/orc8r/cloud/go/services/configurator"
-	eventdC "magma/orc8r/cloud/go/services/eventd/eventd_client"
 	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+	storage2 "magma/orc8r/cloud/go/storage"
+	merrors "magma/orc8r/lib/go/errors"
 
 	strfmt "github.com/go-openapi/strfmt"
-	"github.com/golang/glog"
-	"github.com/olivere/elastic/v7"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var logger = logging.New(logging.ComponentManager)
+
 const (
 	LteNetwork = "lte"
 	querySize  = 50
 )
 
 // NProbeManager provides the main functionality for the nprobe
-// service. It collects ES events, encode records and export
+// service. It collects the events of its sources, encode records and export
 // them to a remote collector server.
 type NProbeManager struct {
-	ElasticClient    *elastic.Client
-	Storage          storage.NProbeStorage
-	Exporter         *exporter.RecordExporter
+	// Sources are the sources the events of the targets are fetched from
+	Sources  []eventsource.EventSource
+	Storage  storage.NProbeStorage
+	Exporter *exporter.RecordExporter
+	// Destinations delivers the records of the tasks overriding the delivery
+	// function of the service config, and of the networks with their own
+	// exporter credentials or SNI
+	Destinations *exporter.Pool
+	// Credentials holds the exporter credentials of the networks, none when nil
+	Credentials      *exporter.CredentialManager
+	Debug            *debug.Settings
+	KillSwitch       *killswitch.Switch
+	Ingested         *ingest.Buffer
 	OperatorID       uint32
 	MaxExportRetries uint32
+	MaxWorkers       uint32
+	UpdateInterval   time.Duration
+	MaxBackOff       time.Duration
+	TaskWeights      map[string]uint32
+	RecordValidation string
+	// DeadLetterAfterRejections is the number of runs in a row a record may
+	// be rejected by the LEMF in before it is set aside, never when 0
+	DeadLetterAfterRejections uint32
+	// RecordTransformers adapts the records to the national handover
+	// variants enabled, by delivery country code
+	RecordTransformers map[string]encoding.RecordTransformer
+	// LawfulInterceptionID and DeliveryCountryCode identify the headers of
+	// the records whose task and network define none
+	LawfulInterceptionID string
+	DeliveryCountryCode  string
+	// Region is the region of the delivery function of the exporter, whose
+	// networks and gateways are exported by the service
+	Region string
+	// Keyring seals the quarantined events and delivered records at rest,
+	// nil when encryption is disabled
+	Keyring *keyring.Keyring
+	// RecordSigning signs the delivered records with the key of signer,
+	// embedding their signature or keeping it in the audit trail
+	RecordSigning string
+	signerMutex   sync.Mutex
+	signer        *signing.Signer
+	// RateLimit paces the exported records unless overridden by a destination
+	RateLimit exporter.RateLimit
+
+	// DeliveryFunctionAddr is the address the exporter delivers to, the
+	// destinations with this delivery address customize its handshake
+	DeliveryFunctionAddr string
+	// SecondaryDeliveryFunctionAddr is the address the exporter fails over to
+	SecondaryDeliveryFunctionAddr string
+
+	// FetchPageSize is the number of events of a task fetched, encoded and
+	// delivered at once, and FetchMaxPages the number of pages a task
+	// processes per run
+	FetchPageSize uint32
+	FetchMaxPages uint32
+
+	CheckpointMaxRecords  uint32
+	CheckpointMaxInterval time.Duration
+	// StandbyReplication replicates the cursors not checkpointed yet after
+	// each pass, for a standby replica taking over to resume from them
+	StandbyReplication bool
+
+	DeliveryAudit          bool
+	DeliveryAuditRetention time.Duration
+
+	// BearerCorrelation correlates the records of each bearer by a
+	// correlation ID allocated for its lifetime rather than by the one of
+	// their task, the IDs of the released bearers being kept for
+	// BearerCorrelationRetention
+	BearerCorrelation          bool
+	BearerCorrelationRetention time.Duration
+
+	// SessionIdleTimeout is the time without activity of a target after
+	// which the interception of its open sessions is ended, never when 0
+	SessionIdleTimeout time.Duration
+
+	// TaskDeletionTimeout is the time after which the tasks whose deletion
+	// was requested are deleted without their IRI-END
+	TaskDeletionTimeout time.Duration
+
+	// BearerEnrichment fills the bearer fields missing from the events with
+	// the session state reported by sessiond
+	BearerEnrichment bool
+
+	// LocationEnrichment fills the location of the target missing from the
+	// EPS events with the UE context reported by the MME
+	LocationEnrichment bool
+
+	// ActiveBearerReports begins the interception of the bearers of the
+	// target already active when a task starts, and ends the interception
+	// of each session still open when it stops
+	ActiveBearerReports bool
+
+	// StateDeltaFetch syncs the states of the targets of each network once
+	// per pass, loading only the states changed since the last sync and all
+	// of them every StateResyncInterval
+	StateDeltaFetch     bool
+	StateResyncInterval time.Duration
+
+	// WarmupConcurrency is the number of networks loaded concurrently by
+	// the warm-up
+	WarmupConcurrency uint32
+
+	// DestinationProbe reconciles the destinations against DNS and their
+	// reachability when a term starts, each probe taking at most
+	// DestinationProbeTimeout
+	DestinationProbe        bool
+	DestinationProbeTimeout time.Duration
+	// Health reports the reachability of the destinations, not reported
+	// when nil
+	Health *health.Registry
+	// EncodingErrors tracks the events failing to be encoded for the health
+	// of the service, not tracked when nil
+	EncodingErrors *health.ErrorRate
+	// probedAddress is the delivery function of the exporter of the service
+	// config checked by the probes, empty if not probed
+	probedAddress string
+	// reachability is the error of the last probe of each destination, nil
+	// if reachable, and reprobing is set while the degraded destinations
+	// are probed again
+	reachabilityMutex sync.Mutex
+	reachability      map[string]error
+	reprobing         int32
+
+	// HI1Notifications notifies the activation and the deactivation of the
+	// tasks and their delivery alarms, to the ADMF at HI1NotificationAddr
+	// or to the destination of each task when empty
+	HI1Notifications    bool
+	HI1NotificationAddr string
+	// hi1Tasks are the tasks of each network listed by the last pass, whose
+	// deactivation is notified once they are gone
+	hi1Tasks map[string]map[string]*models.NetworkProbeTask
+	// Certificates tracks the expiry of the certificates the delivery relies
+	// on, whose HI1 alarms are raised on the tasks, not raised when nil
+	Certificates *health.CertificateTracker
+	// certificateAlarms holds the time the certificate alarm of each task
+	// was last raised at
+	certificateAlarmMutex sync.Mutex
+	certificateAlarms     map[string]time.Time
+
+	// Latency tracks the latency of the delivered records by destination,
+	// not tracked when nil
+	Latency *latency.Tracker
+	// RecordStream pushes the delivered records to the clients watching
+	// them live, not pushed when nil
+	RecordStream *debug.RecordStream
+	// destinationName is the destination of the exporter of the service config
+	destinationName string
+
+	backoffMutex sync.Mutex
+	backoffs     map[string]*taskBackoff
+
+	checkpointMutex sync.Mutex
+	checkpoints     map[string]*taskCheckpoint
+	// replicas are the cursors last replicated for each network
+	replicas map[string][]*models.NetworkProbeTaskCursor
+
+	bindingMutex sync.Mutex
+	imeiBindings map[string]string
+
+	// failClosed maps the tasks whose target can't be resolved to their network
+	failClosedMutex sync.Mutex
+	failClosed      map[string]string
+
+	// rateAlarms maps the tasks whose records are outside their activity
+	// bounds to their network and alarm
+	rateAlarmMutex sync.Mutex
+	rateAlarms     map[string]taskRateAlarm
+
+	// usage is the usage of the open sessions of the tasks reporting it,
+	// by task and session
+	usageMutex sync.Mutex
+	usage      map[string]map[string]*sessionUsage
+
+	// moduleVersions are the module versions and domain IDs selected by the
+	// destinations of each network, by delivery type, and domainIDs the
+	// domain IDs configured in each network
+	moduleVersionMutex sync.RWMutex
+	moduleVersions     map[string]map[string]moduleSelection
+	domainIDs          map[string]encoding.DomainIDs
+
+	// syncExports are the delivery types of each network whose destinations
+	// require synchronous export
+	syncExportMutex sync.RWMutex
+	syncExports     map[string]map[string]bool
+
+	// networkConfigs are the delivery settings of each network overriding
+	// the service config
+	networkConfigMutex sync.RWMutex
+	networkConfigs     map[string]*models.NetworkProbeNetworkConfig
+
+	// recordQuotas are the budgets of records left to the networks with a
+	// records quota
+	quotaMutex   sync.Mutex
+	recordQuotas map[string]*recordQuota
+
+	// minimalRecords are the delivery types of each network whose destinations
+	// accept minimal records
+	minimalRecordMutex sync.RWMutex
+	minimalRecords     map[string]map[string]bool
+
+	// payloadEncryptions are the payload encryptions of the records of each
+	// network and delivery type, nil when the key of a destination is invalid
+	payloadEncryptionMutex sync.RWMutex
+	payloadEncryptions     map[string]map[string]*encoding.PayloadEncryption
+	// destinationEncryptions are the payload encryptions of the records of
+	// the tasks delivered to the other destinations, by network and
+	// destination ID
+	destinationEncryptions map[string]map[string]*encoding.PayloadEncryption
+
+	// syncedStates are the states of the targets of each network synced by
+	// the current pass
+	syncedStateMutex sync.RWMutex
+	syncedStates     map[string]*syncedStates
+
+	// bearerCorrelations are the correlation IDs of the active bearers, by
+	// network, IMSI and bearer ID
+	bearerCorrelationMutex sync.Mutex
+	bearerCorrelations     map[string]*models.NetworkProbeBearerCorrelation
+
+	// auditPrunedAt is the last time the expired delivered records of each
+	// network were deleted, and correlationPrunedAt the last time its expired
+	// bearer correlations were
+	auditPrunedAt       map[string]time.Time
+	correlationPrunedAt map[string]time.Time
+
+	// reencryptedWith is the key the records of each network were last all
+	// sealed with
+	reencryptedWith map[string]string
+
+	// stateSweptAt is the last time the state of each network was swept, and
+	// networksSweptAt the last time the state of deleted networks was
+	stateSweptAt    map[string]time.Time
+	networksSweptAt time.Time
+
+	// warrants signals the starts and ends of the warrants of the tasks
+	warrants *warrantReaper
+	// statisticsReports signals the statistics reports of the tasks which
+	// are due, on the channel of the warrants
+	statisticsReports *warrantReaper
+
+	// warmedTasks are the tasks of each network loaded by the warm-up, until
+	// listed by the next pass
+	warmedTasks map[string]map[string]*models.NetworkProbeTask
+
+	// pass is the report of the current pass, passReports the reports of
+	// the last passes, newest first
+	pass               *passReport
+	passReportMutex    sync.Mutex
+	passReports        []*models.NetworkProbePassReport
+	passReportLoggedAt time.Time
+}
+
+// taskBackoff tracks the consecutive processing failures of a task
+type taskBackoff struct {
+	failures uint32
+	retryAt  time.Time
+	// failure is the class of the last failure failing the task, empty if
+	// its last failures say nothing of the task
+	failure string
+	// rejections is the number of runs in a row the record with
+	// rejectedSequenceNumber was rejected by the LEMF in
+	rejections             uint32
+	rejectedSequenceNumber uint32
+}
+
+// encodedEvent tracks the outcome of encoding an event until its record is delivered
+type encodedEvent struct {
+	timestamp string
+	eventID   string
+	// sequenceNumber is the sequence number of the record of the event
+	sequenceNumber uint32
+	// sessionID is the session the event relates to, if any
+	sessionID string
+	// class is the class of the record of the event
+	class string
+	// encodedAt is the time the record of the event was encoded
+	encodedAt time.Time
+	// mapping maps the correlation ID to the session of the event, if audited
+	mapping *models.NetworkProbeSessionMapping
+	// quarantined is true if the event failed to be encoded
+	quarantined bool
+	// skippable is true if a quarantined event can be skipped on the next fetch
+	skippable bool
+	// filtered is true if the event does not concern the task target
+	filtered bool
+	// dropped is true if the record was discarded by the exporter queue
+	dropped bool
+	// servingNetwork is the network serving the target once the event is
+	// processed, as last reported by an update location
+	servingNetwork *models.NetworkProbeServingNetwork
+}
+
+// taskJob is a task to be processed by a worker
+type taskJob struct {
+	networkID string
+	task      *models.NetworkProbeTask
+	// ingested are the events streamed by the gateways of the network
+	ingested []eventdM.Event
 }
 
 // NewNProbeManager creates and returns a new nprobe manager
@@ -54,18 +367,164 @@ func NewNProbeManager(
 	config nprobe.Config,
 	storage storage.NProbeStorage,
 	exporter *exporter.RecordExporter,
+	debugSettings *debug.Settings,
+	killSwitch *killswitch.Switch,
+	ingested *ingest.Buffer,
 ) (*NProbeManager, error) {
-	client, err := eventdC.GetElasticClient()
+	np := &NProbeManager{
+		Storage:             storage,
+		Exporter:            exporter,
+		Debug:               debugSettings,
+		KillSwitch:          killSwitch,
+		Ingested:            ingested,
+		backoffs:            map[string]*taskBackoff{},
+		checkpoints:         map[string]*taskCheckpoint{},
+		replicas:            map[string][]*models.NetworkProbeTaskCursor{},
+		imeiBindings:        map[string]string{},
+		failClosed:          map[string]string{},
+		rateAlarms:          map[string]taskRateAlarm{},
+		usage:               map[string]map[string]*sessionUsage{},
+		moduleVersions:      map[string]map[string]moduleSelection{},
+		domainIDs:           map[string]encoding.DomainIDs{},
+		auditPrunedAt:       map[string]time.Time{},
+		correlationPrunedAt: map[string]time.Time{},
+		bearerCorrelations:  map[string]*models.NetworkProbeBearerCorrelation{},
+		reencryptedWith:     map[string]string{},
+		stateSweptAt:        map[string]time.Time{},
+		warrants:            newWarrantReaper(),
+		reachability:        map[string]error{},
+		hi1Tasks:            map[string]map[string]*models.NetworkProbeTask{},
+		certificateAlarms:   map[string]time.Time{},
+		syncedStates:        map[string]*syncedStates{},
+	}
+	np.statisticsReports = newStatisticsReaper(np.warrants)
+	if err := np.ApplyConfig(config); err != nil {
+		return nil, err
+	}
+
+	sources, err := eventsource.New(config.EventSources)
 	if err != nil {
 		return nil, err
 	}
-	return &NProbeManager{
-		ElasticClient:    client,
-		Storage:          storage,
-		Exporter:         exporter,
-		OperatorID:       config.OperatorID,
-		MaxExportRetries: config.MaxExportRetries,
-	}, nil
+	np.Sources = sources
+
+	// close delivery connections as soon as interception is suspended
+	killSwitch.OnActivate(func(networkID string) {
+		exporter.Disconnect()
+	})
+	return np, nil
+}
+
+// ApplyConfig applies the settings of a service config, e.g. reloaded at
+// runtime. The current settings are kept if the config is invalid. It must
+// not be called while tasks are processed.
+func (np *NProbeManager) ApplyConfig(config nprobe.Config) error {
+	switch config.RecordValidation {
+	case encoding.ValidationStrict, encoding.ValidationFlag, encoding.ValidationDisabled:
+	default:
+		return fmt.Errorf("unsupported record validation %s", config.RecordValidation)
+	}
+	timestampFormat, err := encoding.NewTimestampFormat(config.TimestampEncoding, config.TimestampPrecision, config.TimestampZone)
+	if err != nil {
+		return err
+	}
+	rateLimit, err := exporter.NewRateLimit(config)
+	if err != nil {
+		return err
+	}
+	identifiers := &models.NetworkProbeNetworkConfig{
+		LawfulInterceptionID: config.LawfulInterceptionID,
+		DeliveryCountryCode:  config.DeliveryCountryCode,
+		Region:               config.Region,
+	}
+	if err := identifiers.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	transformers, err := getRecordTransformers(config.RecordTransformers)
+	if err != nil {
+		return err
+	}
+	if err := np.applySigningConfig(config); err != nil {
+		return err
+	}
+
+	np.OperatorID = config.OperatorID
+	np.MaxExportRetries = config.MaxExportRetries
+	np.MaxWorkers = config.MaxWorkers
+	np.WarmupConcurrency = config.WarmupConcurrency
+	np.UpdateInterval = time.Duration(config.UpdateIntervalSecs) * time.Second
+	np.MaxBackOff = time.Duration(config.BackOffIntervalSecs) * time.Second
+	np.TaskWeights = config.TaskWeights
+	np.DeadLetterAfterRejections = config.DeadLetterAfterRejections
+	np.RecordValidation = config.RecordValidation
+	np.RecordTransformers = transformers
+	np.RecordSigning = config.RecordSigning
+	np.LawfulInterceptionID = config.LawfulInterceptionID
+	np.DeliveryCountryCode = config.DeliveryCountryCode
+	np.Region = config.Region
+	np.RateLimit = rateLimit
+	np.DeliveryFunctionAddr = config.DeliveryFunctionAddr
+	np.SecondaryDeliveryFunctionAddr = config.SecondaryDeliveryFunctionAddr
+	np.destinationName = exporter.GetDestinationName(config)
+	np.probedAddress, _ = exporter.GetProbedAddress(config)
+	np.DestinationProbe = config.DestinationProbe
+	np.DestinationProbeTimeout = time.Duration(config.DestinationProbeTimeoutSecs) * time.Second
+	if !np.DestinationProbe {
+		np.clearReachability()
+	}
+	np.HI1Notifications = config.HI1Notifications
+	np.HI1NotificationAddr = config.HI1NotificationAddr
+	np.FetchPageSize = config.FetchPageSize
+	np.FetchMaxPages = config.FetchMaxPages
+	np.CheckpointMaxRecords = config.CheckpointMaxRecords
+	np.CheckpointMaxInterval = time.Duration(config.CheckpointMaxIntervalSecs) * time.Second
+	np.StandbyReplication = config.StandbyReplication
+	np.DeliveryAudit = config.DeliveryAudit
+	np.DeliveryAuditRetention = time.Duration(config.DeliveryAuditRetentionDays) * 24 * time.Hour
+	np.BearerCorrelation = config.BearerCorrelation
+	np.BearerCorrelationRetention = time.Duration(config.BearerCorrelationRetentionDays) * 24 * time.Hour
+	if !np.BearerCorrelation {
+		np.clearBearerCorrelations()
+	}
+	np.SessionIdleTimeout = time.Duration(config.SessionIdleTimeoutHours) * time.Hour
+	np.TaskDeletionTimeout = time.Duration(config.TaskDeletionTimeoutHours) * time.Hour
+	np.BearerEnrichment = config.BearerEnrichment
+	np.LocationEnrichment = config.LocationEnrichment
+	np.ActiveBearerReports = config.ActiveBearerReports
+	np.StateDeltaFetch = config.StateDeltaFetch
+	np.StateResyncInterval = time.Duration(config.StateResyncIntervalSecs) * time.Second
+	if !np.StateDeltaFetch {
+		np.clearSyncedStates()
+	}
+	encoding.SetTimestampFormat(timestampFormat)
+	if np.Ingested != nil {
+		np.Ingested.SetCapacity(int(config.IngestBufferSize))
+	}
+	return nil
+}
+
+// RegisterRuntimeStats registers the state the manager holds in memory
+// across passes with the runtime statistics of the service
+func (np *NProbeManager) RegisterRuntimeStats(runtime *debug.Runtime) {
+	runtime.Register("checkpoints", func() (int, int) {
+		np.checkpointMutex.Lock()
+		defer np.checkpointMutex.Unlock()
+		return len(np.checkpoints), 0
+	})
+	runtime.Register("session_usage", func() (int, int) {
+		np.usageMutex.Lock()
+		defer np.usageMutex.Unlock()
+		sessions := 0
+		for _, usage := range np.usage {
+			sessions += len(usage)
+		}
+		return sessions, 0
+	})
+	runtime.Register("bearer_correlations", func() (int, int) {
+		np.bearerCorrelationMutex.Lock()
+		defer np.bearerCorrelationMutex.Unlock()
+		return len(np.bearerCorrelations), 0
+	})
 }
 
 // getNetworkProbeTasks retrieves the list of all tasks provisioned for a specific network
@@ -87,113 +546,1151 @@ func getNetworkProbeTasks(networkID string) (map[string]*models.NetworkProbeTask
 	return ret, nil
 }
 
-// getEvents retrieves all events since start_time from fluentd
-func getEvents(
+// fetchEvents retrieves a page of events of a task from its watermark
+func (np *NProbeManager) fetchEvents(
+	ctx context.Context,
 	networkID string,
 	state *models.NetworkProbeData,
-	client *elastic.Client,
+	tags []string,
+	pageSize int,
 ) ([]eventdM.Event, error) {
+	start := getFetchStart(state)
+	query := eventsource.Query{NetworkID: networkID, Tags: tags, Start: &start, Size: pageSize}
+	events, err := np.fetchFromSources(ctx, query)
+	if err == nil && len(events) == pageSize && len(skipProcessedEvents(events, state)) == 0 {
+		// a full page of processed events shares the watermark, fetch past it
+		logger.Warningf("Skipping the events of target %s at %v after a full page of processed events", redact.Identity(state.TargetID), start)
+		next := start.Add(time.Millisecond)
+		query.Start = &next
+		events, err = np.fetchFromSources(ctx, query)
+	}
+	return events, err
+}
+
+// getFetchPaging returns the number of events of a page and the number of
+// pages a task fetches per run
+func (np *NProbeManager) getFetchPaging() (int, int) {
+	pageSize, maxPages := int(np.FetchPageSize), int(np.FetchMaxPages)
+	if pageSize == 0 {
+		pageSize = querySize
+	}
+	if maxPages == 0 {
+		maxPages = 1
+	}
+	return pageSize, maxPages
+}
+
+// isFrameDebugEnabled returns true if frame-level logging is enabled for the task,
+// either directly or through a destination sharing its delivery type.
+func (np *NProbeManager) isFrameDebugEnabled(networkID string, task *models.NetworkProbeTask) bool {
+	if np.Debug.IsTaskEnabled(networkID, string(task.TaskID)) {
+		return true
+	}
+	destinationIDs := np.Debug.GetDestinations(networkID)
+	if len(destinationIDs) == 0 {
+		return false
+	}
+
+	tks := make([]storage2.TypeAndKey, 0, len(destinationIDs))
+	for _, id := range destinationIDs {
+		tks = append(tks, storage2.TypeAndKey{Type: lte.NetworkProbeDestinationEntityType, Key: id})
+	}
+	ents, _, err := configurator.LoadEntities(
+		networkID, nil, nil, nil, tks,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		logger.Errorf("Failed to load debugged destinations for network %s: %v", networkID, err)
+		return false
+	}
+	for _, ent := range ents {
+		destination := (&models.NetworkProbeDestination{}).FromBackendModels(ent)
+		if destination.DestinationDetails.DeliveryType == task.TaskDetails.DeliveryType {
+			return true
+		}
+	}
+	return false
+}
+
+// quarantineEvent stores an event which failed to be encoded along with the
+// offending field so that the malformed gateway data can be inspected.
+func (np *NProbeManager) quarantineEvent(networkID, taskID string, event *eventdM.Event, encodeErr error) {
+	entry := models.NetworkProbeQuarantineEntry{
+		EventType:      event.EventType,
+		StreamName:     event.StreamName,
+		EventTimestamp: event.Timestamp,
+		Field:          encoding.GetErrorField(encodeErr),
+		Error:          encodeErr.Error(),
+		QuarantinedAt:  strfmt.DateTime(time.Now().UTC()),
+	}
+	if err := np.Storage.StoreQuarantineEntry(networkID, taskID, entry); err != nil {
+		logger.Errorf("Failed to quarantine event %s of task %s: %v", redact.Event(event), taskID, err)
+	}
+}
 
-	// build multi-stream es query
-	targetID := state.TargetID
-	startTime := time.Time(state.LastExported).Add(time.Millisecond * 1)
-	queryParams := eventdC.MultiStreamEventQueryParams{
-		NetworkID: networkID,
-		Streams:   nprobe.GetESStreams(),
-		Events:    nprobe.GetESEventTypes(),
-		Tags:      []string{targetID, targetID[4:]},
-		Start:     &startTime,
-		Size:      querySize,
+// countEncodeFailure counts an event whose record failed to be built in the
+// metrics, as a decode failure when its data could not be decoded
+func countEncodeFailure(networkID string, err error) {
+	if encoding.IsDecodingError(err) {
+		metrics.DecodeFailures.WithLabelValues(networkID, encoding.GetErrorField(err)).Inc()
+		return
 	}
+	metrics.EncodeFailures.WithLabelValues(networkID, encoding.GetErrorField(err)).Inc()
+}
 
-	return eventdC.GetMultiStreamEvents(context.Background(), queryParams, client)
+// countEncoding counts an event encoded into a record, or failing to be,
+// for the health of the service
+func (np *NProbeManager) countEncoding(err error) {
+	if np.EncodingErrors != nil {
+		np.EncodingErrors.Add(err != nil, time.Now())
+	}
 }
 
-// updateRecordState updates nprobe state with last sequence number and timestamp
+// updateRecordState updates nprobe state once a record was delivered. The
+// state is only persisted when a checkpoint of the task is due, or forced.
 func (np *NProbeManager) updateRecordState(
 	networkID, taskID string,
-	state models.NetworkProbeData,
-	timestamp string,
-	sequenceNumber uint32,
+	state *models.NetworkProbeData,
+	delivered *encodedEvent,
+	force bool,
+) error {
+	if delivered != nil {
+		if err := advanceWatermark(state, delivered.timestamp, delivered.eventID); err != nil {
+			return err
+		}
+		advanceSessions(state, delivered.sessionID, delivered.class)
+		if delivered.servingNetwork != nil {
+			state.ServingNetwork = delivered.servingNetwork
+		}
+		if !delivered.dropped {
+			state.RecordsExported++
+		}
+		if delivered.sequenceNumber >= state.SequenceNumber {
+			state.SequenceNumber = delivered.sequenceNumber + 1
+		}
+	}
+	if !force && !np.isCheckpointDue(getBackoffKey(networkID, taskID), state, time.Now()) {
+		return nil
+	}
+	return np.storeState(networkID, taskID, state)
+}
+
+// updateDeliveryState updates nprobe state with the exporter connection state and
+// handshake, and the last delivery error if any. State is only stored when it has changed.
+func (np *NProbeManager) updateDeliveryState(
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	deliveryErr error,
+) error {
+	taskID := string(task.TaskID)
+	exporterState := models.NetworkProbeDataExporterStateDisconnected
+	var handshake *models.NetworkProbeHandshake
+	if exp, err := np.getExporter(networkID, task); err == nil {
+		if exp.IsConnected() {
+			exporterState = models.NetworkProbeDataExporterStateConnected
+		}
+		handshake = toHandshakeModel(exp.GetHandshake())
+	}
+	if deliveryErr == nil && exporterState == state.ExporterState && reflect.DeepEqual(handshake, state.ExporterHandshake) {
+		return nil
+	}
+
+	if deliveryErr != nil && state.ExporterState != models.NetworkProbeDataExporterStateDisconnected {
+		np.notifyDeliveryAlarm(networkID, task, state, deliveryErr)
+		np.raiseDeliveryFailure(networkID, task, deliveryErr)
+	}
+	state.ExporterState = exporterState
+	state.ExporterHandshake = handshake
+	if deliveryErr != nil {
+		state.DeliveryErrors++
+		state.LastDeliveryError = deliveryErr.Error()
+		state.LastDeliveryErrorClass = exporter.ClassifyError(deliveryErr)
+	}
+	return np.storeState(networkID, taskID, state)
+}
+
+// deliverTerminalRecord delivers the terminal record of a task whose interception
+// was suspended by the kill switch, before any other record of the task.
+func (np *NProbeManager) deliverTerminalRecord(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
 ) error {
-	ptime, err := time.Parse(time.RFC3339, timestamp)
+	taskID := string(task.TaskID)
+	if ended, err := np.endActiveBearers(ctx, networkID, task, state, time.Time(state.SuspendedAt)); ended {
+		if err != nil {
+			return err
+		}
+		state.SuspendedAt = strfmt.DateTime{}
+		return np.storeState(networkID, taskID, state)
+	}
+	// records reserved before the suspension are generated again with new numbers
+	seq := getNextSequenceNumber(state)
+	record, err := encoding.MakeTerminalRecord(np.getRecordTask(networkID, task, state), np.getOperatorID(networkID), seq, time.Time(state.SuspendedAt), np.getModuleVersion(networkID, task))
+	if err == nil {
+		record, err = np.prepareRecord(networkID, task, record)
+	}
 	if err != nil {
-		return err
+		// the task can't be encoded at all, don't hold its other records back
+		logger.Errorf("Failed to build terminal record of task %s: %s", taskID, err)
+		state.SuspendedAt = strfmt.DateTime{}
+		return np.storeState(networkID, taskID, state)
 	}
 
-	// update state with last timestamp and sequence nbr
-	state.LastExported = strfmt.DateTime(ptime)
-	state.SequenceNumber = sequenceNumber
-	return np.Storage.StoreNProbeData(networkID, taskID, state)
+	exp, err := np.getExporter(networkID, task)
+	if err != nil {
+		return err
+	}
+	delivery := exp.SubmitRecords(
+		ctx,
+		getBackoffKey(networkID, taskID),
+		np.getTaskWeight(taskID),
+		task.TaskDetails.CorrelationID,
+		[][]byte{record},
+		np.MaxExportRetries,
+	)
+	if err := delivery.Wait(0); err != nil {
+		return err
+	}
+	metrics.RecordsExported.WithLabelValues(networkID).Inc()
+	suspendedAt := time.Time(state.SuspendedAt)
+	np.storeDeliveryRecords(networkID, taskID, []models.NetworkProbeDeliveryRecord{
+		makeDeliveryRecord(task, record, seq, suspendedAt, time.Now()),
+	})
+	state.RecordsExported++
+	state.SequenceNumber = seq + 1
+	state.SuspendedAt = strfmt.DateTime{}
+	// the interception of all sessions ended with the record
+	state.OpenSessions = nil
+	return np.storeState(networkID, taskID, state)
 }
 
-// processNProbeTask is the main function processing each task, managing state and exporting data
-func (np *NProbeManager) processNProbeTask(networkID string, task *models.NetworkProbeTask) error {
+// processNProbeTask is the main function processing each task, managing state and exporting data.
+// When the context is cancelled, no new record is built but the records already exported
+// are committed to the state.
+func (np *NProbeManager) processNProbeTask(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	ingested []eventdM.Event,
+) error {
 	taskID := string(task.TaskID)
 	state, err := np.Storage.GetNProbeData(networkID, taskID)
+	if errors.Cause(err) == merrors.ErrNotFound {
+		// the memory state store loses the states on restart, the task
+		// starts over from its creation as it did when created
+		logger.Warningf("No state for task %s, starting over from its creation", taskID)
+		state = &models.NetworkProbeData{
+			LastExported: task.TaskDetails.Timestamp,
+			TargetID:     task.TaskDetails.TargetID,
+		}
+		err = nil
+	}
+	if err != nil {
+		logger.Errorf("Failed to get state for record %s: %v", taskID, err)
+		return err
+	}
+	taskLogger := logger.With(logging.FieldNetworkID, networkID, logging.FieldTaskID, taskID, logging.FieldXID, state.Xid)
+	if time.Time(state.CreatedAt).IsZero() {
+		// the states stored before their creation time was recorded get it
+		// with their next checkpoint
+		state.CreatedAt = task.TaskDetails.Timestamp
+	}
+	np.restoreCursor(getBackoffKey(networkID, taskID), state)
+	deletion, err := np.Storage.GetTaskDeletion(networkID, taskID)
+	if err != nil {
+		taskLogger.Errorf("Failed to get deletion of task %s: %v", taskID, err)
+		return err
+	}
+	// paused tasks keep their state but generate no record until resumed
+	pause, err := np.Storage.GetTaskPause(networkID, taskID)
+	if err != nil {
+		taskLogger.Errorf("Failed to get pause state of task %s: %v", taskID, err)
+		return err
+	}
+	lifecycle, err := np.Storage.GetTaskLifecycle(networkID, taskID)
+	if err != nil {
+		taskLogger.Errorf("Failed to get lifecycle of task %s: %v", taskID, err)
+		return err
+	}
+
+	window := newWarrantWindow(task.TaskDetails)
+	now := time.Now()
+	deleting := models.IsTaskDeletionPending(deletion)
+	expiring := window.isOver(now)
+	conditions := taskConditions{
+		deletionOverdue: isTaskDeletionOverdue(deletion, now, np.TaskDeletionTimeout),
+		deleting:        deleting,
+		completed:       !time.Time(state.CompletedAt).IsZero(),
+		expired:         !time.Time(state.ExpiredAt).IsZero(),
+		warrantPending:  window.isPending(now),
+		warrantOver:     expiring,
+		paused:          pause.Paused,
+		failure:         np.getTaskFailure(getBackoffKey(networkID, taskID)),
+	}
+	next, reason := evaluateTaskState(conditions)
+	if next == models.NetworkProbeTaskLifecycleStateFailed &&
+		lifecycle.State != next && !tasks.CanTransition(lifecycle.State, next) {
+		// only active tasks fail, the others are retried as active
+		next, reason = models.NetworkProbeTaskLifecycleStateActive, reasonIntercepting
+	}
+	if lifecycle.State == models.NetworkProbeTaskLifecycleStateTerminated {
+		// terminated tasks never intercept again, only their deletion is left
+		next, reason = lifecycle.State, lifecycle.Reason
+	}
+	if err := np.transitionTask(networkID, taskID, lifecycle, next, reason); err != nil {
+		taskLogger.Errorf("Failed to move task %s to %s: %v", taskID, next, err)
+		return err
+	}
+
+	switch next {
+	case models.NetworkProbeTaskLifecycleStateTerminated:
+		if conditions.deletionOverdue {
+			taskLogger.Warningf("Failed to end interception of task %s within %v of its deletion, deleting it without IRI-END", taskID, np.TaskDeletionTimeout)
+			return np.deleteTask(networkID, taskID, metrics.DeletionTimedOut)
+		}
+		// nothing was intercepted by pending tasks, and the interception of
+		// the others already ended, there is no interception to end
+		if deleting {
+			return np.deleteTask(networkID, taskID, metrics.DeletionEnded)
+		}
+		// expired tasks were ended by their IRI-END and are expected to be
+		// silent, one-shot tasks are reported once and never polled for again
+		if conditions.expired {
+			return np.setRateAlarm(networkID, taskID, state, "", time.Now())
+		}
+		return nil
+	case models.NetworkProbeTaskLifecycleStatePending:
+		return np.holdPendingTask(networkID, task, state, window)
+	}
+	if window.isBounded() {
+		if !expiring {
+			np.scheduleWarrant(networkID, taskID, window.end)
+		}
+		if err := np.setWarrantState(networkID, taskID, state, models.NetworkProbeDataWarrantStateActive); err != nil {
+			taskLogger.Errorf("Failed to update warrant state of task %s: %v", taskID, err)
+			return err
+		}
+	}
+	if err := np.notifyActivation(ctx, networkID, task, state); err != nil {
+		// notified again by the next pass, the records aren't held back
+		taskLogger.Errorf("Failed to notify activation of task %s: %v", taskID, err)
+	}
+	np.notifyCertificateAlarm(networkID, task, state, now)
+	if np.ActiveBearerReports && !task.TaskDetails.OneShot && time.Time(state.ActivatedAt).IsZero() {
+		// stored along with the activation
+		state.ActiveBearersPending = true
+	}
+	if err := np.raiseActivation(networkID, task, state); err != nil {
+		taskLogger.Errorf("Failed to record activation of task %s: %v", taskID, err)
+	}
+
+	if next == models.NetworkProbeTaskLifecycleStateSuspended {
+		// suspended tasks are expected to be silent
+		return np.setRateAlarm(networkID, taskID, state, "", time.Now())
+	}
+	if next == models.NetworkProbeTaskLifecycleStateExpiring && pause.Paused {
+		// the pending events of paused tasks are not delivered
+		if deleting {
+			return np.endDeletedTask(ctx, networkID, task, state, deletion)
+		}
+		return np.expireTask(ctx, networkID, task, state, window)
+	}
+	if task.TaskDetails.OneShot {
+		return np.processOneShotTask(ctx, networkID, task, state)
+	}
+	if err := np.checkRateAlarm(networkID, task, state, time.Now()); err != nil {
+		taskLogger.Errorf("Failed to update rate alarm of task %s: %v", taskID, err)
+		return err
+	}
+	if addr, degraded := np.isDestinationDegraded(task); degraded {
+		// the events of the task stay in eventd until its destination is reached
+		taskLogger.V(2).Infof("Destination %s of targetID %s is unreachable, holding back its records", addr, redact.Identity(state.TargetID))
+		return nil
+	}
+
+	if !time.Time(state.SuspendedAt).IsZero() {
+		if err := np.deliverTerminalRecord(ctx, networkID, task, state); err != nil {
+			taskLogger.Errorf("Failed to deliver terminal record for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return err
+		}
+	}
+
+	rotation, err := np.Storage.GetTaskXIDRotation(networkID, taskID)
+	if err != nil {
+		taskLogger.Errorf("Failed to get XID rotation of task %s: %v", taskID, err)
+		return err
+	}
+	if isXIDRotationPending(task, state, rotation) {
+		if err := np.deliverXIDRotation(ctx, networkID, task, state, rotation); err != nil {
+			taskLogger.Errorf("Failed to deliver linkage records for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return err
+		}
+	}
+
+	testRecord, err := np.Storage.GetTaskTestRecord(networkID, taskID)
+	if err != nil {
+		taskLogger.Errorf("Failed to get test record of task %s: %v", taskID, err)
+		return err
+	}
+	if models.IsTestRecordPending(testRecord) {
+		if err := np.deliverTestRecord(ctx, networkID, task, state, testRecord); err != nil {
+			taskLogger.Errorf("Failed to deliver test record for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return err
+		}
+	}
+	if err := np.reportStatistics(ctx, networkID, task, state); err != nil {
+		taskLogger.Errorf("Failed to deliver statistics record for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+		return err
+	}
+
+	resumedAt := time.Time(pause.ResumedAt)
+	if resumedAt.After(time.Time(state.ResumeReportedAt)) {
+		done, err := np.deliverResumeReport(ctx, networkID, task, state, resumedAt)
+		if err != nil {
+			taskLogger.Errorf("Failed to deliver resume report for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return err
+		}
+		if !done {
+			return nil
+		}
+	}
+
+	matcher, err := np.resolveTarget(networkID, task)
+	if err != nil {
+		taskLogger.Errorf("Failed to resolve targetID %s, withholding its records: %s", redact.Identity(state.TargetID), redact.Error(err))
+		return err
+	}
+	if matcher == nil {
+		taskLogger.V(2).Infof("No subscriber currently matches targetID %s", redact.Identity(state.TargetID))
+		if deleting {
+			return np.endDeletedTask(ctx, networkID, task, state, deletion)
+		}
+		if expiring {
+			return np.expireTask(ctx, networkID, task, state, window)
+		}
+		return np.updateDeliveryState(networkID, task, state, nil)
+	}
+	exp, err := np.getExporter(networkID, task)
 	if err != nil {
-		glog.Errorf("Failed to get state for record %s: %v", taskID, err)
+		taskLogger.Errorf("Failed to get exporter of targetID %s, withholding its records: %s", redact.Identity(state.TargetID), redact.Error(err))
 		return err
 	}
+	if state.ActiveBearersPending {
+		if err := np.deliverActiveBearers(ctx, networkID, task, state, matcher, exp); err != nil {
+			taskLogger.Errorf("Failed to report active bearers of targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return err
+		}
+	}
 
-	events, err := getEvents(networkID, state, np.ElasticClient)
+	replay, err := np.Storage.GetTaskReplay(networkID, taskID)
 	if err != nil {
-		glog.Errorf("Failed to collect events for targetID %s: %s\n", state.TargetID, err)
+		taskLogger.Errorf("Failed to get replay of task %s: %v", taskID, err)
 		return err
 	}
+	if models.IsTaskReplayPending(replay) {
+		if err := np.deliverReplay(ctx, networkID, task, state, matcher, replay); err != nil {
+			taskLogger.Errorf("Failed to replay records for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return err
+		}
+	}
 
+	// events are processed a page at a time, each page being delivered and
+	// checkpointed before the next one is fetched, until the pages of a run
+	// are used up or the export queue backs up
+	pageSize, maxPages := np.getFetchPaging()
 	var nerr error
-	seq := state.SequenceNumber
-	for _, event := range events {
-		record, err := encoding.MakeRecord(&event, task, np.OperatorID, seq)
+	caughtUp := false
+	for page := 1; ; page++ {
+		if exp.IsBackpressured() {
+			taskLogger.V(2).Infof("Export queue backed up, deferring the events of targetID %s to the next run", redact.Identity(state.TargetID))
+			metrics.FetchesDeferred.WithLabelValues(networkID, metrics.FetchDeferredBackpressure).Inc()
+			break
+		}
+		if np.isRecordQuotaExceeded(networkID, time.Now()) {
+			taskLogger.V(2).Infof("Records quota of network %s used up, deferring the events of targetID %s to the next run", networkID, redact.Identity(state.TargetID))
+			metrics.FetchesDeferred.WithLabelValues(networkID, metrics.FetchDeferredQuota).Inc()
+			break
+		}
+		watermark, processedIDs := time.Time(state.LastExported), len(state.ExportedEventIds)
+		exported := state.RecordsExported
+		result, err := np.processEventPage(ctx, networkID, task, state, matcher, exp, ingested, pageSize)
+		np.consumeRecordQuota(networkID, state.RecordsExported-exported, time.Now())
 		if err != nil {
-			glog.Errorf("Failed to build record from event %v: %s\n", event, err)
+			return err
+		}
+		nerr = result.exportErr
+		if nerr != nil || ctx.Err() != nil {
+			break
+		}
+		// the records following a record set aside are fetched again
+		if result.fetched < pageSize && !result.deadLettered {
+			caughtUp = true
+			break
+		}
+		// a page which left the watermark in place would be fetched again
+		if time.Time(state.LastExported).Equal(watermark) && len(state.ExportedEventIds) == processedIDs {
+			caughtUp = true
+			break
+		}
+		if page >= maxPages {
+			taskLogger.V(2).Infof("Fetched %d pages of events of targetID %s, deferring the others to the next run", page, redact.Identity(state.TargetID))
+			metrics.FetchesDeferred.WithLabelValues(networkID, metrics.FetchDeferredMaxPages).Inc()
+			break
+		}
+	}
+
+	// the IMSI bound to an IMEI target is only known once all events are processed
+	if nerr == nil && ctx.Err() == nil {
+		np.saveBinding(getBackoffKey(networkID, taskID), matcher)
+	}
+
+	err = np.updateDeliveryState(networkID, task, state, nerr)
+	if err != nil {
+		taskLogger.Errorf("Failed to update delivery state for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+		return err
+	}
+	// deleted and expired tasks are ended once their pending events are delivered
+	if caughtUp && nerr == nil && ctx.Err() == nil {
+		if deleting {
+			if err := np.endDeletedTask(ctx, networkID, task, state, deletion); err != nil {
+				taskLogger.Errorf("Failed to end interception of deleted targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+				return err
+			}
+		} else if expiring {
+			if err := np.expireTask(ctx, networkID, task, state, window); err != nil {
+				taskLogger.Errorf("Failed to end interception of expired targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+				return err
+			}
+		}
+	}
+	return nerr
+}
+
+// pageResult is the outcome of the processing of a page of events of a task
+type pageResult struct {
+	// fetched is the number of events fetched from eventd, a full page
+	// meaning that more events may follow
+	fetched int
+	// exportErr is the error which stopped the delivery of the records
+	exportErr error
+	// deadLettered is true if a record was set aside, the records following
+	// it failing along with it
+	deadLettered bool
+}
+
+// processEventPage fetches a page of events of a task from its watermark,
+// encodes them and delivers their records. The state of the task is
+// updated as its records are delivered.
+func (np *NProbeManager) processEventPage(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	matcher *targetMatcher,
+	exp *exporter.RecordExporter,
+	ingested []eventdM.Event,
+	pageSize int,
+) (pageResult, error) {
+	taskID := string(task.TaskID)
+	taskLogger := logger.With(logging.FieldNetworkID, networkID, logging.FieldTaskID, taskID, logging.FieldXID, state.Xid)
+	fetchCtx, span := tracing.Start(ctx, tracing.SpanFetch)
+	events, err := np.fetchEvents(fetchCtx, networkID, state, matcher.tags, pageSize)
+	if err != nil {
+		tracing.End(span, err)
+		taskLogger.Errorf("Failed to collect events for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+		return pageResult{}, err
+	}
+	fetched := len(events)
+	metrics.EventsFetched.WithLabelValues(networkID).Add(float64(fetched))
+	np.pass.addEvents(fetched)
+	events = mergeIngestedEvents(events, ingested, matcher.tags, state, pageSize)
+	events = skipProcessedEvents(events, state)
+	orderEvents(events)
+	span.SetAttributes(tracing.Events.Int(len(events)))
+	tracing.End(span, nil)
+
+	// encode all events of the page first, then submit the records at once so
+	// that they are fairly scheduled with the records of the other tasks
+	frameDebug := np.isFrameDebugEnabled(networkID, task)
+	version := np.getModuleVersion(networkID, task)
+	operatorID := np.getOperatorID(networkID)
+	minimal := np.isMinimalRecordsEnabled(networkID, task)
+	recordTask := np.getRecordTask(networkID, task, state)
+	synced := np.getSyncedStates(networkID)
+	var enricher *bearerEnricher
+	if np.BearerEnrichment {
+		enricher = newBearerEnricher(networkID, synced)
+	}
+	// the location of the target is left out of the records of the tasks whose
+	// warrant doesn't authorize it, rather than looked up
+	locationAuthorized := np.isLocationAuthorized(networkID, task)
+	var locator *locationEnricher
+	if np.LocationEnrichment && locationAuthorized {
+		locator = newLocationEnricher(networkID, synced)
+	}
+	filter := newRecordFilter(task.TaskDetails.RecordFilter)
+	window := newWarrantWindow(task.TaskDetails)
+	var items []encodedEvent
+	var records [][]byte
+	// the spans encoding the records, which the spans delivering them link to
+	var recordSpans []trace.SpanContext
+	var reservations []*models.NetworkProbeReservedRecord
+	reserved := getReservedRecords(state)
+	seq := getNextSequenceNumber(state)
+	// sessions are tracked as if the records before were delivered, and so is
+	// the network serving the target
+	open := getOpenSessions(state)
+	servingNetwork := state.ServingNetwork
+	matchCtx, matchSpan := tracing.Start(ctx, tracing.SpanMatch, trace.WithAttributes(tracing.Events.Int(len(events))))
+	for i := range events {
+		if ctx.Err() != nil {
+			break
+		}
+		event := &events[i]
+		eventID := getEventID(event)
+		// events of gateways of other regions are exported by their instances,
+		// and the events out of the warrant of the task are not intercepted
+		if !matcher.matches(event) || !np.isEventInRegion(networkID, event) || !window.contains(event.Timestamp) {
+			items = append(items, encodedEvent{timestamp: event.Timestamp, eventID: eventID, filtered: true})
+			continue
+		}
+		if event.EventType == nprobe.FlowUsageReported {
+			_, isReserved := reserved[eventID]
+			report := np.trackUsage(networkID, task, event, open, isReserved)
+			if report == nil {
+				// flow reports only make up the usage reports of the sessions
+				items = append(items, encodedEvent{timestamp: event.Timestamp, eventID: eventID, filtered: true})
+				continue
+			}
+			event = report
+		}
+		// events whose record may have been delivered get the same sequence
+		// number and class
+		sessionID := getSessionID(event)
+		recordSeq, class := seq, getRecordClass(event, sessionID, open)
+		reservation, isReserved := reserved[eventID]
+		if isReserved {
+			recordSeq = reservation.SequenceNumber
+			if len(reservation.RecordClass) != 0 {
+				class = reservation.RecordClass
+			}
+		}
+		if enricher != nil {
+			enricher.enrich(ctx, event)
+		}
+		if locator != nil {
+			locator.enrich(ctx, event)
+		}
+		if !locationAuthorized {
+			event = encoding.WithoutLocation(event)
+		}
+		if serving := getServingNetwork(event); serving != nil {
+			servingNetwork = serving
+		} else {
+			withServingNetwork(event, servingNetwork)
+		}
+		// events left out by the record filter or the roaming option of the
+		// task are skipped, the usage of their flows still making up the usage
+		// reports
+		if !filter.matches(event, open) || !isRoamingIncluded(task, servingNetwork) {
+			items = append(items, encodedEvent{timestamp: event.Timestamp, eventID: eventID, filtered: true, servingNetwork: servingNetwork})
+			continue
+		}
+		eventTask := recordTask
+		if np.BearerCorrelation {
+			eventTask, err = np.withBearerCorrelation(networkID, recordTask, event)
+			if err != nil {
+				tracing.End(matchSpan, err)
+				taskLogger.Errorf("Failed to allocate the correlation ID of the bearer of event %s: %s", redact.Event(event), redact.Error(err))
+				return pageResult{}, err
+			}
+		}
+		_, encodeSpan := tracing.Start(matchCtx, tracing.SpanEncode, trace.WithAttributes(
+			tracing.EventID.String(eventID),
+			tracing.EventType.String(event.EventType),
+			tracing.SequenceNumber.Int64(int64(recordSeq)),
+			tracing.RecordClass.String(class),
+		))
+		record, err := encoding.MakeVersionedRecord(event, eventTask, operatorID, recordSeq, class, version)
+		if err == nil {
+			record, err = np.prepareRecord(networkID, task, record)
+		}
+		if err != nil && minimal && encoding.IsDegradable(err) {
+			record, err = np.makeMinimalRecord(networkID, taskID, event, eventTask, recordSeq, class, version, err)
+		}
+		tracing.End(encodeSpan, err)
+		if err != nil {
+			taskLogger.Errorf("Failed to build record from event %s: %s", redact.Event(event), redact.Error(err))
+			countEncodeFailure(networkID, err)
+			np.countEncoding(err)
+			np.pass.addError(passErrorEncode)
+			np.quarantineEvent(networkID, taskID, event, err)
+			// the timestamp of events whose record was rejected is always valid
+			_, rejected := err.(*encoding.ValidationError)
+			items = append(items, encodedEvent{
+				timestamp:   event.Timestamp,
+				eventID:     eventID,
+				quarantined: true,
+				skippable:   rejected || encoding.GetErrorField(err) != encoding.FieldTimestamp,
+			})
+			continue
+		}
+		metrics.RecordsEncoded.WithLabelValues(networkID).Inc()
+		np.countEncoding(nil)
+		if frameDebug {
+			taskLogger.Infof("Exporting frame %d of task %s (%d bytes): %s", recordSeq, taskID, len(record), redact.Bytes(record))
+			np.Debug.CaptureRecord(networkID, taskID, record)
+		}
+		if np.BearerCorrelation {
+			if err := np.releaseBearerCorrelation(networkID, event); err != nil {
+				tracing.End(matchSpan, err)
+				taskLogger.Errorf("Failed to release the correlation ID of the bearer of event %s: %s", redact.Event(event), redact.Error(err))
+				return pageResult{}, err
+			}
+		}
+		applyRecordClass(open, sessionID, class)
+		if len(sessionID) != 0 {
+			switch class {
+			case encoding.RecordClassBegin:
+				np.beginUsage(networkID, task, sessionID, event.Timestamp)
+			case encoding.RecordClassEnd:
+				np.endUsage(networkID, taskID, sessionID)
+			}
+		}
+		item := encodedEvent{
+			timestamp:      event.Timestamp,
+			eventID:        eventID,
+			sequenceNumber: recordSeq,
+			sessionID:      sessionID,
+			class:          class,
+			encodedAt:      time.Now(),
+			servingNetwork: servingNetwork,
+		}
+		if np.DeliveryAudit {
+			item.mapping = makeSessionMapping(eventTask, event, sessionID, recordSeq)
+		}
+		items = append(items, item)
+		records = append(records, record)
+		recordSpans = append(recordSpans, encodeSpan.SpanContext())
+		if !isReserved {
+			reservations = append(reservations, &models.NetworkProbeReservedRecord{
+				EventID:        eventID,
+				SequenceNumber: recordSeq,
+				RecordClass:    class,
+			})
+			seq++
+		}
+	}
+
+	matchSpan.SetAttributes(tracing.Records.Int(len(records)))
+	tracing.End(matchSpan, nil)
+
+	// the sequence numbers are persisted along with the cursor before the records
+	// are submitted, so that each event produces the same record until it is
+	// known to be delivered, e.g. if processing stops before the next checkpoint
+	if len(reservations) != 0 {
+		state.ReservedRecords = append(state.ReservedRecords, reservations...)
+		if err := np.storeState(networkID, taskID, state); err != nil {
+			taskLogger.Errorf("Failed to reserve sequence numbers for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return pageResult{}, err
+		}
+	}
+
+	// with synchronous export, the cursor is persisted after each record
+	// delivered before the next record is submitted
+	synchronous := np.isSynchronousExport(networkID, task)
+	destination := np.getDestinationName(task)
+	exportCtx, exportSpan := tracing.Start(ctx, tracing.SpanExport, trace.WithAttributes(tracing.Records.Int(len(records))))
+	exportCtx = tracing.WithRecordSpans(exportCtx, recordSpans...)
+	delivery := np.submitRecords(exportCtx, exp, networkID, task, records, synchronous)
+
+	var nerr error
+	var processed, deadLettered bool
+	var delivered []models.NetworkProbeDeliveryRecord
+	var mappings []models.NetworkProbeSessionMapping
+	activity := map[time.Time]uint64{}
+	var statistics deliveredStatistics
+	next := 0
+	for i := range items {
+		item := &items[i]
+		if item.quarantined || item.filtered {
+			if item.skippable || item.filtered {
+				// events whose timestamp is invalid can't be positioned
+				if err := advanceWatermark(state, item.timestamp, item.eventID); err == nil {
+					processed = true
+				}
+				if item.servingNetwork != nil {
+					state.ServingNetwork = item.servingNetwork
+				}
+			}
 			continue
 		}
 
-		nerr = np.Exporter.SendMessageWithRetries(record, np.MaxExportRetries)
+		nerr = delivery.Wait(next)
+		next++
+		if nerr != nil && ctx.Err() != nil {
+			// shutting down, the remaining records are exported on restart,
+			// including the ones failed by the connections closed once the
+			// drain timed out
+			nerr = nil
+			break
+		}
+		if nerr == exporter.ErrRecordDropped {
+			// the record is not submitted again, leaving a sequence gap
+			nerr = nil
+			item.dropped = true
+			processed = true
+			if err := np.updateRecordState(networkID, taskID, state, item, false); err != nil {
+				tracing.End(exportSpan, err)
+				taskLogger.Errorf("Failed to update state for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+				return pageResult{}, err
+			}
+			continue
+		}
 		if nerr != nil {
-			glog.Errorf("Failed to export record for targetID %s: %s\n", state.TargetID, nerr)
+			taskLogger.With(logging.FieldDestination, destination, logging.FieldSeq, item.sequenceNumber).Errorf("Failed to export record for targetID %s (%s): %s", redact.Identity(state.TargetID), exporter.ClassifyError(nerr), redact.Error(nerr))
+			metrics.ExportFailures.WithLabelValues(networkID).Inc()
+			np.pass.addError(passErrorExport)
+			if np.deadLetterRecord(networkID, taskID, item, records[next-1], nerr) {
+				// the record set aside is skipped like a dropped one
+				nerr = nil
+				item.dropped = true
+				processed, deadLettered = true, true
+				if err := np.updateRecordState(networkID, taskID, state, item, true); err != nil {
+					tracing.End(exportSpan, err)
+					taskLogger.Errorf("Failed to update state for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+					return pageResult{}, err
+				}
+			}
 			break
 		}
-		seq++
+		metrics.RecordsExported.WithLabelValues(networkID).Inc()
+		np.pass.addRecord()
+		statistics.add(records[next-1])
+		ptime, err := time.Parse(time.RFC3339, item.timestamp)
+		if err == nil {
+			activity[ptime.UTC().Truncate(time.Hour)]++
+			if np.Latency != nil {
+				now := time.Now()
+				np.Latency.Observe(destination, now.Sub(item.encodedAt), now.Sub(ptime))
+			}
+		}
+		if np.DeliveryAudit {
+			delivered = append(delivered, makeDeliveryRecord(task, records[next-1], item.sequenceNumber, ptime, time.Now()))
+		}
+		if np.RecordStream != nil {
+			np.RecordStream.Publish(networkID, taskID, records[next-1], time.Now())
+		}
+		if item.mapping != nil {
+			mappings = append(mappings, *item.mapping)
+		}
+		processed = true
+
+		// busy tasks are checkpointed while their records are delivered
+		err = np.updateRecordState(networkID, taskID, state, item, synchronous)
+		if err != nil {
+			tracing.End(exportSpan, err)
+			taskLogger.Errorf("Failed to update state for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return pageResult{}, err
+		}
 	}
+	tracing.End(exportSpan, nerr)
 
-	if seq > state.SequenceNumber {
-		idx := seq - state.SequenceNumber - 1
-		err = np.updateRecordState(networkID, taskID, *state, events[idx].Timestamp, seq)
+	np.storeDeliveryRecords(networkID, taskID, delivered)
+	np.storeSessionMappings(networkID, taskID, mappings)
+
+	// quarantined and filtered events are skipped as well so that they are not fetched again.
+	// The cursor is checkpointed before processing stops.
+	if processed {
+		err = np.updateRecordState(networkID, taskID, state, nil, ctx.Err() != nil)
 		if err != nil {
-			glog.Errorf("Failed to update state for targetID %s: %s\n", state.TargetID, err)
-			return err
+			taskLogger.Errorf("Failed to update state for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return pageResult{}, err
+		}
+	}
+
+	// activity rollups are informational, failing to update them doesn't fail the task
+	if len(activity) != 0 {
+		err = np.Storage.IncrementActivity(networkID, taskID, activity)
+		if err != nil {
+			taskLogger.Errorf("Failed to update activity for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+		}
+	}
+	np.countStatistics(networkID, task, statistics)
+	return pageResult{fetched: fetched, exportErr: nerr, deadLettered: deadLettered}, nil
+}
+
+// validateRecord verifies an encoded record of a task before it is exported,
+// identified by the domain ID of the module version of the task. Malformed
+// records are rejected in strict mode and only reported in flag mode.
+func (np *NProbeManager) validateRecord(networkID string, task *models.NetworkProbeTask, record []byte) error {
+	if np.RecordValidation == encoding.ValidationDisabled {
+		return nil
+	}
+	// the module version of the task is the only one added, its domain ID
+	// can't conflict
+	domainIDs := encoding.DomainIDs{}
+	domainIDs.Add(np.getModuleVersion(networkID, task))
+	err := encoding.ValidateDomainIDs(record, domainIDs)
+	if err == nil {
+		return nil
+	}
+	metrics.ValidationFailures.WithLabelValues(networkID, encoding.GetErrorField(err)).Inc()
+	if np.RecordValidation == encoding.ValidationFlag {
+		logger.Warningf("Exporting malformed record of task %s: %v", task.TaskID, err)
+		return nil
+	}
+	return err
+}
+
+// getTaskWeight returns the share of the delivery connection given to a task
+func (np *NProbeManager) getTaskWeight(taskID string) uint32 {
+	if weight, ok := np.TaskWeights[taskID]; ok && weight != 0 {
+		return weight
+	}
+	return nprobe.DefaultTaskWeight
+}
+
+// getBackoffKey returns the key identifying a task across networks
+func getBackoffKey(networkID, taskID string) string {
+	return networkID + "/" + taskID
+}
+
+// isBackingOff returns true if a task recently failed and must not be retried yet
+func (np *NProbeManager) isBackingOff(key string, now time.Time) bool {
+	np.backoffMutex.Lock()
+	defer np.backoffMutex.Unlock()
+	backoff, ok := np.backoffs[key]
+	return ok && now.Before(backoff.retryAt)
+}
+
+// recordTaskResult updates the backoff of a task. Each consecutive failure doubles
+// the time before the next attempt, starting from the update interval and capped
+// at the maximum backoff, depending on the class of the failure. A success
+// clears the backoff.
+func (np *NProbeManager) recordTaskResult(key string, err error) {
+	np.backoffMutex.Lock()
+	defer np.backoffMutex.Unlock()
+	if err == nil {
+		delete(np.backoffs, key)
+		return
+	}
+
+	backoff, ok := np.backoffs[key]
+	if !ok {
+		backoff = &taskBackoff{}
+		np.backoffs[key] = backoff
+	}
+	class := exporter.ClassifyError(err)
+	if class != exporter.FailureQueue && class != exporter.FailureBreakerOpen {
+		backoff.failures++
+		if errors.Cause(err) != context.Canceled {
+			backoff.failure = class
+		}
+	}
+	backoff.retryAt = time.Now().Add(np.getRetryDelay(class, backoff.failures))
+}
+
+// getTaskFailure returns the class of the failure failing a task, empty if
+// its last pass succeeded
+func (np *NProbeManager) getTaskFailure(key string) string {
+	np.backoffMutex.Lock()
+	defer np.backoffMutex.Unlock()
+	if backoff, ok := np.backoffs[key]; ok {
+		return backoff.failure
+	}
+	return ""
+}
+
+// getRetryDelay returns the time before the next attempt of a task after
+// consecutive failures, the last one of the given class. Handshakes fail
+// until the credentials or the certificate of the delivery function change,
+// so the task waits for the maximum backoff right away, while a record
+// refused by the export queue or shed by the circuit breaker of its
+// destination says nothing of the task, which is attempted again on the next
+// pass, the breaker probing the destination once its open interval elapsed.
+func (np *NProbeManager) getRetryDelay(class string, failures uint32) time.Duration {
+	switch class {
+	case exporter.FailureHandshake:
+		return np.MaxBackOff
+	case exporter.FailureQueue, exporter.FailureBreakerOpen:
+		return np.UpdateInterval
+	}
+	delay := np.UpdateInterval
+	for i := uint32(1); i < failures && delay < np.MaxBackOff; i++ {
+		delay *= 2
+	}
+	if delay > np.MaxBackOff {
+		delay = np.MaxBackOff
+	}
+	return delay
+}
+
+// pruneBackoffs drops the backoff of tasks which no longer exist
+func (np *NProbeManager) pruneBackoffs(keys map[string]bool) {
+	np.backoffMutex.Lock()
+	defer np.backoffMutex.Unlock()
+	for key := range np.backoffs {
+		if !keys[key] {
+			delete(np.backoffs, key)
+		}
+	}
+}
+
+// runWorker processes tasks until the jobs channel is closed
+func (np *NProbeManager) runWorker(ctx context.Context, jobs <-chan taskJob) {
+	for job := range jobs {
+		taskCtx, cancel := np.KillSwitch.Context(ctx, job.networkID)
+		taskCtx, span := tracing.Start(taskCtx, tracing.SpanTask, trace.WithAttributes(
+			tracing.NetworkID.String(job.networkID),
+			tracing.TaskID.String(string(job.task.TaskID)),
+		))
+		err := np.processNProbeTask(taskCtx, job.networkID, job.task, job.ingested)
+		tracing.End(span, err)
+		cancel()
+		if err != nil {
+			logger.Errorf("Failed to process events for targetID %s: %s", redact.Identity(job.task.TaskDetails.TargetID), redact.Error(err))
+			metrics.ProcessingErrors.Inc()
+			np.pass.addError(passErrorTask)
+		}
+		np.pass.addTask(false)
+		np.recordTaskResult(getBackoffKey(job.networkID, string(job.task.TaskID)), err)
+		if err != nil {
+			np.failTask(job.networkID, string(job.task.TaskID))
 		}
 	}
-	return nerr
 }
 
 // ProcessNProbeTasks runs in loop, retrieves all nprobe tasks and process them.
 // For each task, it collects latest events, creates the corresponding IRI record then
-// export them to a remote destination.
-func (np *NProbeManager) ProcessNProbeTasks() error {
+// export them to a remote destination. Tasks are processed concurrently by a bounded
+// pool of workers and a failing task is backed off without stalling the others.
+// Processing stops at the next record boundary once the context is cancelled.
+func (np *NProbeManager) ProcessNProbeTasks(ctx context.Context) error {
+	// the tasks loaded by the warm-up are only listed by the next pass
+	defer func() { np.warmedTasks = nil }()
+	np.pass = newPassReport(time.Now())
+	defer np.finishPass(np.pass)
+
 	networks, err := configurator.ListNetworksOfType(LteNetwork)
 	if err != nil {
-		glog.Errorf("Failed to retrieve lte network list: %s", err)
+		logger.Errorf("Failed to retrieve lte network list: %s", err)
+		metrics.ProcessingErrors.Inc()
+		np.pass.addError(passErrorListNetworks)
 		return err
 	}
 
+	np.loadNetworkConfigs(networks)
+	np.applyDestinationSettings(networks)
+	np.reprobeDegradedDestinations()
+
+	jobs := make(chan taskJob)
+	wg := sync.WaitGroup{}
+	for i := uint32(0); i < np.MaxWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			np.runWorker(ctx, jobs)
+		}()
+	}
+
+	now := time.Now()
+	keys := map[string]bool{}
+	var listed []string
+	listedTasks := map[string]map[string]*models.NetworkProbeTask{}
+	allListed := true
+	unread := map[string]bool{}
 	for _, networkID := range networks {
-		tasks, err := getNetworkProbeTasks(networkID)
+		// interception stays suspended if the kill switch state can't be read
+		suspended, err := np.KillSwitch.IsActive(networkID)
+		if err != nil {
+			logger.Errorf("Failed to retrieve kill switch of network %s: %s", networkID, err)
+			np.pass.addError(passErrorKillSwitch)
+			unread[networkID] = true
+		}
+		if suspended || err != nil {
+			// events streamed while interception is suspended are not delivered
+			np.drainIngested(networkID)
+			allListed = false
+			continue
+		}
+
+		if np.isDeliveryHeld(networkID) || np.isRegionHeld(networkID) {
+			allListed = false
+			continue
+		}
+
+		tasks, err := np.listNetworkProbeTasks(networkID)
 		if err != nil {
-			glog.Errorf("Failed to retrieve nprobe task for network %s: %s", networkID, err)
+			logger.Errorf("Failed to retrieve nprobe task for network %s: %s", networkID, err)
+			np.pass.addError(passErrorListTasks)
+			allListed = false
 			continue
 		}
+		listed = append(listed, networkID)
+		listedTasks[networkID] = tasks
+		np.syncStates(ctx, networkID, tasks, now)
+		ingested := np.drainIngested(networkID)
 
+		overQuota := np.getTasksOverQuota(networkID, tasks)
 		for _, task := range tasks {
-			err = np.processNProbeTask(networkID, task)
-			if err != nil {
-				glog.Errorf("Failed to process events for targetID %s: %s\n", task.TaskDetails.TargetID, err)
-				return err
+			key := getBackoffKey(networkID, string(task.TaskID))
+			keys[key] = true
+			if overQuota[string(task.TaskID)] {
+				np.deleteHeldTask(networkID, string(task.TaskID))
+				continue
+			}
+			if np.isBackingOff(key, now) {
+				np.pass.addTask(true)
+				continue
+			}
+			select {
+			case jobs <- taskJob{networkID: networkID, task: task, ingested: ingested}:
+			case <-ctx.Done():
 			}
 		}
 	}
+	close(jobs)
+	wg.Wait()
+	np.pass.setNetworks(len(networks), len(listed))
+	if np.Ingested != nil {
+		np.Ingested.Retain(networks)
+	}
+	np.reportFailClosed(networks, unread)
+	np.reportRateAlarms(networks)
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	if allListed {
+		np.retainDestinations(listedTasks)
+		np.pruneBackoffs(keys)
+		np.pruneCheckpoints(keys)
+		np.pruneBindings(keys)
+		np.pruneFailClosed(keys)
+		np.pruneRateAlarms(keys)
+		np.pruneCertificateAlarms(keys)
+		np.pruneUsage(keys)
+		np.pruneWarrants(keys)
+		np.pruneStatisticsReports(keys)
+	}
+	if np.StandbyReplication {
+		np.replicateCursors(listed, now)
+	}
+	for _, networkID := range listed {
+		np.notifyDeactivations(ctx, networkID, listedTasks[networkID])
+		np.pruneDeliveryRecords(networkID, now)
+		np.pruneBearerCorrelations(networkID, now)
+		np.reencryptRecords(networkID)
+		np.sweepState(ctx, networkID, listedTasks[networkID], now)
+	}
+	if allListed {
+		np.sweepDeletedNetworks(ctx, networks, now)
+	}
+	metrics.LastProcessingTime.SetToCurrentTime()
 	return nil
 }
@@ -0,0 +1,55 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelay(t *testing.T) {
+	np := &NProbeManager{
+		UpdateInterval: time.Minute,
+		MaxBackOff:     10 * time.Minute,
+		backoffs:       map[string]*taskBackoff{},
+	}
+	// transient failures double the delay up to the maximum backoff
+	assert.Equal(t, time.Minute, np.getRetryDelay(exporter.FailureConnect, 1))
+	assert.Equal(t, 4*time.Minute, np.getRetryDelay(exporter.FailureAckTimeout, 3))
+	assert.Equal(t, 10*time.Minute, np.getRetryDelay(exporter.FailureUnknown, 8))
+	// failing handshakes wait for the maximum backoff right away
+	assert.Equal(t, 10*time.Minute, np.getRetryDelay(exporter.FailureHandshake, 1))
+
+	// records refused by the export queue are not failures of the task
+	now := time.Now()
+	np.recordTaskResult("n1/t1", &exporter.DeliveryError{Class: exporter.FailureConnect, Err: errors.New("refused")})
+	np.recordTaskResult("n1/t1", exporter.ErrThrottled)
+	assert.Equal(t, uint32(1), np.backoffs["n1/t1"].failures)
+	// neither are records shed by an open circuit breaker
+	np.recordTaskResult("n1/t1", &exporter.DeliveryError{Class: exporter.FailureBreakerOpen, Err: errors.New("shed")})
+	assert.Equal(t, uint32(1), np.backoffs["n1/t1"].failures)
+	assert.Equal(t, exporter.FailureConnect, np.getTaskFailure("n1/t1"))
+	assert.Equal(t, time.Minute, np.getRetryDelay(exporter.FailureBreakerOpen, 1))
+	assert.True(t, np.isBackingOff("n1/t1", now))
+	assert.False(t, np.isBackingOff("n1/t1", now.Add(2*time.Minute)))
+
+	np.recordTaskResult("n1/t1", nil)
+	assert.False(t, np.isBackingOff("n1/t1", now))
+	assert.Empty(t, np.getTaskFailure("n1/t1"))
+}
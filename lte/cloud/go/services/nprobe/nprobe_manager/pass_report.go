@@ -0,0 +1,172 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+const (
+	// maxPassReports is the number of reports of the last passes kept
+	maxPassReports = 20
+	// passReportLogInterval is the minimum time between two logged pass reports
+	passReportLogInterval = 5 * time.Minute
+)
+
+// Types of the errors counted by the pass reports
+const (
+	passErrorListNetworks = "list_networks"
+	passErrorKillSwitch   = "kill_switch"
+	passErrorListTasks    = "list_tasks"
+	passErrorTask         = "task"
+	passErrorEncode       = "encode"
+	passErrorExport       = "export"
+)
+
+// passReport summarizes a processing pass while its tasks are processed.
+// A nil report counts nothing, e.g. for tasks processed outside of a pass.
+type passReport struct {
+	mutex  sync.Mutex
+	start  time.Time
+	report models.NetworkProbePassReport
+}
+
+func newPassReport(start time.Time) *passReport {
+	return &passReport{
+		start: start,
+		report: models.NetworkProbePassReport{
+			StartedAt: strfmt.DateTime(start),
+			Errors:    map[string]int64{},
+		},
+	}
+}
+
+// setNetworks counts the networks scanned and the ones whose tasks were processed
+func (r *passReport) setNetworks(scanned, processed int) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.report.NetworksScanned = int64(scanned)
+	r.report.NetworksProcessed = int64(processed)
+}
+
+func (r *passReport) addTask(backingOff bool) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if backingOff {
+		r.report.TasksBackingOff++
+	} else {
+		r.report.TasksProcessed++
+	}
+}
+
+func (r *passReport) addEvents(n int) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.report.EventsFetched += int64(n)
+}
+
+func (r *passReport) addRecord() {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.report.RecordsExported++
+}
+
+func (r *passReport) addError(errorType string) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.report.Errors[errorType]++
+}
+
+// finish returns the report of the pass once all its tasks are processed
+func (r *passReport) finish(end time.Time) *models.NetworkProbePassReport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ret := r.report
+	ret.DurationSeconds = end.Sub(r.start).Seconds()
+	return &ret
+}
+
+// finishPass keeps the report of a pass with the reports of the last passes,
+// and logs it unless a report was logged recently
+func (np *NProbeManager) finishPass(report *passReport) {
+	now := time.Now()
+	ret := report.finish(now)
+
+	np.passReportMutex.Lock()
+	np.passReports = append([]*models.NetworkProbePassReport{ret}, np.passReports...)
+	if len(np.passReports) > maxPassReports {
+		np.passReports = np.passReports[:maxPassReports]
+	}
+	if now.Sub(np.passReportLoggedAt) < passReportLogInterval {
+		np.passReportMutex.Unlock()
+		return
+	}
+	np.passReportLoggedAt = now
+	np.passReportMutex.Unlock()
+
+	logger.Infof(
+		"Processing pass took %s: %d/%d networks, %d tasks processed, %d backing off, %d events fetched, %d records exported, errors: %s",
+		time.Duration(ret.DurationSeconds*float64(time.Second)).Round(time.Millisecond),
+		ret.NetworksProcessed, ret.NetworksScanned, ret.TasksProcessed, ret.TasksBackingOff,
+		ret.EventsFetched, ret.RecordsExported, formatPassErrors(ret.Errors),
+	)
+}
+
+// GetPassReports returns the reports of the last passes, newest first
+func (np *NProbeManager) GetPassReports() []*models.NetworkProbePassReport {
+	np.passReportMutex.Lock()
+	defer np.passReportMutex.Unlock()
+	ret := make([]*models.NetworkProbePassReport, len(np.passReports))
+	copy(ret, np.passReports)
+	return ret
+}
+
+// formatPassErrors formats the errors of a pass by type, e.g. encode=2 task=1
+func formatPassErrors(errors map[string]int64) string {
+	if len(errors) == 0 {
+		return "none"
+	}
+	var ret []string
+	for errorType := range errors {
+		ret = append(ret, errorType)
+	}
+	sort.Strings(ret)
+	for i, errorType := range ret {
+		ret[i] = errorType + "=" + strconv.FormatInt(errors[errorType], 10)
+	}
+	return strings.Join(ret, " ")
+}
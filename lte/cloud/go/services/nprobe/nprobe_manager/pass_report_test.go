@@ -0,0 +1,66 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassReport(t *testing.T) {
+	np := &NProbeManager{}
+
+	// tasks processed outside of a pass are not counted
+	var none *passReport
+	none.addTask(false)
+	none.addError(passErrorTask)
+
+	start := time.Now().Add(-2 * time.Second)
+	report := newPassReport(start)
+	report.setNetworks(3, 2)
+	report.addTask(false)
+	report.addTask(false)
+	report.addTask(true)
+	report.addEvents(5)
+	report.addRecord()
+	report.addRecord()
+	report.addError(passErrorEncode)
+	report.addError(passErrorEncode)
+	report.addError(passErrorExport)
+	np.finishPass(report)
+
+	reports := np.GetPassReports()
+	assert.Len(t, reports, 1)
+	assert.Equal(t, int64(3), reports[0].NetworksScanned)
+	assert.Equal(t, int64(2), reports[0].NetworksProcessed)
+	assert.Equal(t, int64(2), reports[0].TasksProcessed)
+	assert.Equal(t, int64(1), reports[0].TasksBackingOff)
+	assert.Equal(t, int64(5), reports[0].EventsFetched)
+	assert.Equal(t, int64(2), reports[0].RecordsExported)
+	assert.Equal(t, map[string]int64{passErrorEncode: 2, passErrorExport: 1}, reports[0].Errors)
+	assert.True(t, reports[0].DurationSeconds >= 2)
+	assert.Equal(t, "encode=2 export=1", formatPassErrors(reports[0].Errors))
+	assert.Equal(t, "none", formatPassErrors(nil))
+
+	// only the last passes are kept, newest first
+	for i := 0; i < maxPassReports; i++ {
+		np.finishPass(newPassReport(time.Now()))
+	}
+	reports = np.GetPassReports()
+	assert.Len(t, reports, maxPassReports)
+	assert.Empty(t, reports[0].Errors)
+	assert.Empty(t, reports[maxPassReports-1].Errors)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+)
+
+// addPayloadEncryption selects the payload encryption of a destination for
+// the tasks of its network and delivery type. A destination whose key is
+// invalid leaves no encryption, so that its records are not delivered in
+// clear. When destinations disagree, the first one listed applies.
+func addPayloadEncryption(
+	encryptions map[string]map[string]*encoding.PayloadEncryption,
+	networkID string,
+	destination *models.NetworkProbeDestination,
+) {
+	details := destination.DestinationDetails
+	if details.PayloadEncryption == nil {
+		return
+	}
+	if encryptions[networkID] == nil {
+		encryptions[networkID] = map[string]*encoding.PayloadEncryption{}
+	}
+	if _, ok := encryptions[networkID][details.DeliveryType]; ok {
+		logger.Warningf(
+			"Ignoring payload encryption of destination %s of network %s conflicting with another destination",
+			destination.DestinationID, networkID,
+		)
+		return
+	}
+	encryptions[networkID][details.DeliveryType] = newPayloadEncryption(networkID, destination)
+}
+
+// newPayloadEncryption returns the payload encryption of a destination, nil
+// if its key is invalid
+func newPayloadEncryption(networkID string, destination *models.NetworkProbeDestination) *encoding.PayloadEncryption {
+	details := destination.DestinationDetails
+	key, err := hex.DecodeString(details.PayloadEncryption.Key)
+	var encryption *encoding.PayloadEncryption
+	if err == nil {
+		encryption, err = encoding.NewPayloadEncryption(details.PayloadEncryption.EncryptionType, key)
+	}
+	if err != nil {
+		logger.Errorf("Invalid payload encryption of destination %s of network %s: %s", destination.DestinationID, networkID, err)
+	}
+	return encryption
+}
+
+// setPayloadEncryptions replaces the payload encryptions selected by the
+// destinations
+func (np *NProbeManager) setPayloadEncryptions(encryptions map[string]map[string]*encoding.PayloadEncryption) {
+	np.payloadEncryptionMutex.Lock()
+	defer np.payloadEncryptionMutex.Unlock()
+	np.payloadEncryptions = encryptions
+}
+
+// setDestinationEncryptions replaces the payload encryptions of the
+// destinations of a network the tasks delivered to them were resolved with
+func (np *NProbeManager) setDestinationEncryptions(networkID string, encryptions map[string]*encoding.PayloadEncryption) {
+	np.payloadEncryptionMutex.Lock()
+	defer np.payloadEncryptionMutex.Unlock()
+	if np.destinationEncryptions == nil {
+		np.destinationEncryptions = map[string]map[string]*encoding.PayloadEncryption{}
+	}
+	np.destinationEncryptions[networkID] = encryptions
+}
+
+// encryptRecord encrypts the payload of a record of a task when its
+// destination requires it. The records of the tasks with their own delivery
+// function are not encrypted, unless delivered to a destination requiring it.
+func (np *NProbeManager) encryptRecord(networkID string, task *models.NetworkProbeTask, record []byte) ([]byte, error) {
+	destinationID := task.TaskDetails.DestinationID
+	if task.TaskDetails.Delivery != nil && len(destinationID) == 0 {
+		return record, nil
+	}
+	np.payloadEncryptionMutex.RLock()
+	encryption, ok := np.payloadEncryptions[networkID][task.TaskDetails.DeliveryType]
+	if task.TaskDetails.Delivery != nil {
+		encryption, ok = np.destinationEncryptions[networkID][destinationID]
+	}
+	np.payloadEncryptionMutex.RUnlock()
+	if !ok {
+		return record, nil
+	}
+	if encryption == nil {
+		metrics.EncryptionFailures.WithLabelValues(networkID).Inc()
+		return nil, fmt.Errorf("payload encryption of the %s destination of network %s is invalid", task.TaskDetails.DeliveryType, networkID)
+	}
+	encrypted, err := encryption.Encrypt(record)
+	if err != nil {
+		metrics.EncryptionFailures.WithLabelValues(networkID).Inc()
+		return nil, fmt.Errorf("failed to encrypt record: %v", err)
+	}
+	return encrypted, nil
+}
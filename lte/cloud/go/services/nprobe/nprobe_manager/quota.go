@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"sort"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+)
+
+// Networks may bound the tasks they provision and the records exported for
+// them per second. Tasks beyond the task quota are rejected when created,
+// while the ones provisioned beyond it anyway, e.g. once the quota is
+// lowered, are held by the manager, most recent first. The records quota is
+// a budget of records per second, refilled over time up to one second worth
+// of records: the tasks of a network stop fetching pages of events once it
+// is used up, deferring their events to the next runs.
+
+// recordQuota is the budget of records left to a network
+type recordQuota struct {
+	limit     uint32
+	available float64
+	updatedAt time.Time
+}
+
+// getTasksOverQuota returns the IDs of the tasks of a network held beyond its
+// task quota: the most recently created ones
+func (np *NProbeManager) getTasksOverQuota(networkID string, tasks map[string]*models.NetworkProbeTask) map[string]bool {
+	maxTasks := int(np.getNetworkConfig(networkID).MaxTasks)
+	if maxTasks == 0 || len(tasks) <= maxTasks {
+		return nil
+	}
+	ordered := make([]*models.NetworkProbeTask, 0, len(tasks))
+	for _, task := range tasks {
+		ordered = append(ordered, task)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		ti, tj := time.Time(ordered[i].TaskDetails.Timestamp), time.Time(ordered[j].TaskDetails.Timestamp)
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return ordered[i].TaskID < ordered[j].TaskID
+	})
+	held := map[string]bool{}
+	for _, task := range ordered[maxTasks:] {
+		held[string(task.TaskID)] = true
+	}
+	logger.Warningf("Network %s holds %d tasks beyond its quota of %d, holding the most recent ones", networkID, len(held), maxTasks)
+	metrics.QuotaExceeded.WithLabelValues(networkID, metrics.QuotaTasks).Inc()
+	return held
+}
+
+// isRecordQuotaExceeded returns true if a network used up its records quota
+func (np *NProbeManager) isRecordQuotaExceeded(networkID string, now time.Time) bool {
+	limit := np.getNetworkConfig(networkID).MaxRecordsPerSecond
+	np.quotaMutex.Lock()
+	defer np.quotaMutex.Unlock()
+	quota := np.refillRecordQuota(networkID, limit, now)
+	if quota == nil || quota.available > 0 {
+		return false
+	}
+	metrics.QuotaExceeded.WithLabelValues(networkID, metrics.QuotaRecords).Inc()
+	return true
+}
+
+// consumeRecordQuota deducts the records exported for a network from its
+// records quota. The last page of events may overdraw the quota, which is
+// then refilled before the network exports again.
+func (np *NProbeManager) consumeRecordQuota(networkID string, records uint64, now time.Time) {
+	if records == 0 {
+		return
+	}
+	limit := np.getNetworkConfig(networkID).MaxRecordsPerSecond
+	np.quotaMutex.Lock()
+	defer np.quotaMutex.Unlock()
+	if quota := np.refillRecordQuota(networkID, limit, now); quota != nil {
+		quota.available -= float64(records)
+	}
+}
+
+// refillRecordQuota returns the records quota of a network refilled for the
+// time elapsed, nil if the network has none. It must be called with the
+// quota mutex locked.
+func (np *NProbeManager) refillRecordQuota(networkID string, limit uint32, now time.Time) *recordQuota {
+	if limit == 0 {
+		delete(np.recordQuotas, networkID)
+		return nil
+	}
+	if np.recordQuotas == nil {
+		np.recordQuotas = map[string]*recordQuota{}
+	}
+	quota, ok := np.recordQuotas[networkID]
+	if !ok || quota.limit != limit {
+		quota = &recordQuota{limit: limit, available: float64(limit), updatedAt: now}
+		np.recordQuotas[networkID] = quota
+		return quota
+	}
+	quota.available += now.Sub(quota.updatedAt).Seconds() * float64(limit)
+	if quota.available > float64(limit) {
+		quota.available = float64(limit)
+	}
+	quota.updatedAt = now
+	return quota
+}
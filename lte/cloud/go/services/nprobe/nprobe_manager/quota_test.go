@@ -0,0 +1,80 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskQuota(t *testing.T) {
+	np := &NProbeManager{}
+	created := time.Unix(1615000000, 0)
+	newTask := func(taskID string, age time.Duration) *models.NetworkProbeTask {
+		return &models.NetworkProbeTask{
+			TaskID:      models.NetworkProbeTaskID(taskID),
+			TaskDetails: &models.NetworkProbeTaskDetails{Timestamp: strfmt.DateTime(created.Add(-age))},
+		}
+	}
+	tasks := map[string]*models.NetworkProbeTask{
+		"task1": newTask("task1", 3*time.Hour),
+		"task2": newTask("task2", time.Hour),
+		"task3": newTask("task3", 2*time.Hour),
+	}
+
+	// networks without quota hold no task
+	assert.Empty(t, np.getTasksOverQuota("n0", tasks))
+
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{
+		"n1": {MaxTasks: 2},
+		"n2": {MaxTasks: 3},
+	})
+	assert.Equal(t, map[string]bool{"task2": true}, np.getTasksOverQuota("n1", tasks))
+	assert.Empty(t, np.getTasksOverQuota("n2", tasks))
+}
+
+func TestRecordQuota(t *testing.T) {
+	np := &NProbeManager{}
+	now := time.Unix(1615000000, 0)
+	assert.False(t, np.isRecordQuotaExceeded("n1", now))
+
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{
+		"n1": {MaxRecordsPerSecond: 100},
+	})
+	assert.False(t, np.isRecordQuotaExceeded("n1", now))
+	np.consumeRecordQuota("n1", 60, now)
+	assert.False(t, np.isRecordQuotaExceeded("n1", now))
+
+	// the last page may overdraw the quota, which is refilled over time
+	np.consumeRecordQuota("n1", 90, now)
+	assert.True(t, np.isRecordQuotaExceeded("n1", now))
+	assert.True(t, np.isRecordQuotaExceeded("n1", now.Add(500*time.Millisecond)))
+	assert.False(t, np.isRecordQuotaExceeded("n1", now.Add(600*time.Millisecond)))
+
+	// the quota is refilled up to one second worth of records
+	later := now.Add(time.Hour)
+	np.consumeRecordQuota("n1", 100, later)
+	assert.True(t, np.isRecordQuotaExceeded("n1", later))
+
+	// and starts over when changed
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{
+		"n1": {MaxRecordsPerSecond: 200},
+	})
+	assert.False(t, np.isRecordQuotaExceeded("n1", later))
+}
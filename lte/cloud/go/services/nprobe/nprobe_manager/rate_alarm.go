@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// Tasks may bound the records expected in each hour of activity of their
+// target. Fewer records than the minimum in the last hour, e.g. once the
+// intercept is broken, raise a silence alarm. More records than the maximum
+// in the current or last hour raise a burst alarm. Hours are those of the
+// events, as counted by the activity rollup of the task.
+
+// taskRateAlarm is the rate alarm raised for a task
+type taskRateAlarm struct {
+	networkID string
+	alarm     string
+}
+
+// checkRateAlarm raises or clears the rate alarm of a task from its
+// activity rollup. The alarm is kept unchanged if the rollup can't be read.
+func (np *NProbeManager) checkRateAlarm(
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	now time.Time,
+) error {
+	taskID := string(task.TaskID)
+	details := task.TaskDetails
+	if details.MinRecordsPerHour == 0 && details.MaxRecordsPerHour == 0 {
+		return np.setRateAlarm(networkID, taskID, state, "", now)
+	}
+	activity, err := np.Storage.GetActivity(networkID, taskID)
+	if err != nil {
+		logger.Errorf("Failed to get activity of task %s, keeping its rate alarm: %v", taskID, err)
+		return nil
+	}
+	return np.setRateAlarm(networkID, taskID, state, getRateAlarm(details, activity, now), now)
+}
+
+// getRateAlarm returns the rate alarm of a task given its activity rollup,
+// empty if the records are within the bounds of the task. The last hour
+// is only checked for silence if the task covered all of it.
+func getRateAlarm(details *models.NetworkProbeTaskDetails, activity *models.NetworkProbeActivity, now time.Time) string {
+	current := now.UTC().Truncate(time.Hour)
+	last := current.Add(-time.Hour)
+	counts := map[int64]uint64{}
+	for _, bucket := range activity.Buckets {
+		counts[time.Time(bucket.Start).Unix()] += bucket.Count
+	}
+
+	maxRecords := uint64(details.MaxRecordsPerHour)
+	if maxRecords != 0 && (counts[current.Unix()] > maxRecords || counts[last.Unix()] > maxRecords) {
+		return models.NetworkProbeDataRateAlarmBurst
+	}
+	minRecords := uint64(details.MinRecordsPerHour)
+	if minRecords != 0 && !time.Time(details.Timestamp).After(last) && counts[last.Unix()] < minRecords {
+		return models.NetworkProbeDataRateAlarmSilence
+	}
+	return ""
+}
+
+// setRateAlarm stores the rate alarm of a task in its state when it changes,
+// so that it shows in the task status
+func (np *NProbeManager) setRateAlarm(networkID, taskID string, state *models.NetworkProbeData, alarm string, now time.Time) error {
+	np.rateAlarmMutex.Lock()
+	key := getBackoffKey(networkID, taskID)
+	if len(alarm) != 0 {
+		np.rateAlarms[key] = taskRateAlarm{networkID: networkID, alarm: alarm}
+	} else {
+		delete(np.rateAlarms, key)
+	}
+	np.rateAlarmMutex.Unlock()
+
+	if alarm == state.RateAlarm {
+		return nil
+	}
+	if len(alarm) != 0 {
+		logger.Warningf("Raising %s alarm of task %s", alarm, taskID)
+		state.RateAlarmSince = strfmt.DateTime(now.UTC())
+	} else {
+		logger.Infof("Clearing %s alarm of task %s", state.RateAlarm, taskID)
+		state.RateAlarmSince = strfmt.DateTime{}
+	}
+	state.RateAlarm = alarm
+	return np.storeState(networkID, taskID, state)
+}
+
+// reportRateAlarms updates the rate alarm metric of each network once the
+// tasks were processed
+func (np *NProbeManager) reportRateAlarms(networks []string) {
+	np.rateAlarmMutex.Lock()
+	counts := map[string]map[string]int{}
+	for _, alarm := range np.rateAlarms {
+		if counts[alarm.networkID] == nil {
+			counts[alarm.networkID] = map[string]int{}
+		}
+		counts[alarm.networkID][alarm.alarm]++
+	}
+	np.rateAlarmMutex.Unlock()
+
+	for _, networkID := range networks {
+		for _, alarm := range []string{models.NetworkProbeDataRateAlarmSilence, models.NetworkProbeDataRateAlarmBurst} {
+			metrics.RateAlarms.WithLabelValues(networkID, alarm).Set(float64(counts[networkID][alarm]))
+		}
+	}
+}
+
+// pruneRateAlarms drops the alarms of tasks which no longer exist
+func (np *NProbeManager) pruneRateAlarms(keys map[string]bool) {
+	np.rateAlarmMutex.Lock()
+	defer np.rateAlarmMutex.Unlock()
+	for key := range np.rateAlarms {
+		if !keys[key] {
+			delete(np.rateAlarms, key)
+		}
+	}
+}
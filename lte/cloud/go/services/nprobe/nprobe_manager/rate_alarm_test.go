@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRateAlarm(t *testing.T) {
+	now := time.Date(2021, 3, 6, 10, 20, 0, 0, time.UTC)
+	details := &models.NetworkProbeTaskDetails{
+		MinRecordsPerHour: 2,
+		MaxRecordsPerHour: 10,
+		Timestamp:         strfmt.DateTime(now.Add(-2 * time.Hour)),
+	}
+	makeActivity := func(last, current uint64) *models.NetworkProbeActivity {
+		return &models.NetworkProbeActivity{
+			Granularity: models.NetworkProbeActivityGranularityHour,
+			Buckets: []*models.NetworkProbeActivityBucket{
+				{Start: strfmt.DateTime(now.Truncate(time.Hour).Add(-time.Hour)), Count: last},
+				{Start: strfmt.DateTime(now.Truncate(time.Hour)), Count: current},
+			},
+		}
+	}
+
+	assert.Equal(t, "", getRateAlarm(details, makeActivity(2, 0), now))
+	assert.Equal(t, models.NetworkProbeDataRateAlarmSilence, getRateAlarm(details, makeActivity(1, 0), now))
+	assert.Equal(t, models.NetworkProbeDataRateAlarmSilence, getRateAlarm(details, &models.NetworkProbeActivity{}, now))
+	assert.Equal(t, models.NetworkProbeDataRateAlarmBurst, getRateAlarm(details, makeActivity(11, 0), now))
+	// bursts are raised within the hour
+	assert.Equal(t, models.NetworkProbeDataRateAlarmBurst, getRateAlarm(details, makeActivity(1, 11), now))
+
+	// the last hour isn't checked for silence until the task covered it
+	details.Timestamp = strfmt.DateTime(now.Add(-time.Hour))
+	assert.Equal(t, "", getRateAlarm(details, makeActivity(0, 0), now))
+
+	// unset bounds aren't checked
+	details.Timestamp = strfmt.DateTime(now.Add(-2 * time.Hour))
+	details.MinRecordsPerHour = 0
+	assert.Equal(t, "", getRateAlarm(details, makeActivity(0, 0), now))
+	details.MaxRecordsPerHour = 0
+	assert.Equal(t, "", getRateAlarm(details, makeActivity(0, 100), now))
+}
+
+func TestPruneRateAlarms(t *testing.T) {
+	np := &NProbeManager{rateAlarms: map[string]taskRateAlarm{
+		getBackoffKey("n1", "t1"): {networkID: "n1", alarm: models.NetworkProbeDataRateAlarmSilence},
+		getBackoffKey("n1", "t2"): {networkID: "n1", alarm: models.NetworkProbeDataRateAlarmBurst},
+	}}
+	np.pruneRateAlarms(map[string]bool{getBackoffKey("n1", "t2"): true})
+	assert.Equal(t, map[string]taskRateAlarm{
+		getBackoffKey("n1", "t2"): {networkID: "n1", alarm: models.NetworkProbeDataRateAlarmBurst},
+	}, np.rateAlarms)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+)
+
+// When a term starts, the destinations are reconciled against DNS and their
+// reachability: the delivery function and the destinations of the tasks are
+// resolved and connected to concurrently, rather than the first record of
+// each of them finding out minutes later. Unreachable destinations are
+// degraded: the records of their tasks are held back, their events staying
+// in eventd, until a probe run at the start of a following pass reaches them.
+
+// getProbedAddress returns the address of the destination of a task checked
+// by the probes, empty if not probed, e.g. for the kafka backend
+func (np *NProbeManager) getProbedAddress(task *models.NetworkProbeTask) string {
+	if destination := getTaskDestination(task); destination != nil {
+		return exporter.NormalizeAddress(destination.Address)
+	}
+	return np.probedAddress
+}
+
+// reconcileDestinations probes the destinations of the tasks loaded when a
+// term starts, replacing the results of the previous term
+func (np *NProbeManager) reconcileDestinations(ctx context.Context, tasks map[string]map[string]*models.NetworkProbeTask) {
+	if !np.DestinationProbe {
+		return
+	}
+	addrs := map[string]bool{}
+	if len(np.probedAddress) != 0 {
+		addrs[np.probedAddress] = true
+	}
+	for _, networkTasks := range tasks {
+		for _, task := range networkTasks {
+			if addr := np.getProbedAddress(task); len(addr) != 0 {
+				addrs[addr] = true
+			}
+		}
+	}
+	results := np.probeDestinations(ctx, addrs)
+	np.setReachability(results, true)
+
+	var unreachable []string
+	for addr, err := range results {
+		if err != nil {
+			unreachable = append(unreachable, addr)
+		}
+	}
+	sort.Strings(unreachable)
+	logger.Infof("Probed %d destinations, %d unreachable: %v", len(results), len(unreachable), unreachable)
+}
+
+// reprobeDegradedDestinations probes the degraded destinations again in the
+// background, unless they are still being probed. The destinations reached
+// are no longer degraded.
+func (np *NProbeManager) reprobeDegradedDestinations() {
+	addrs := map[string]bool{}
+	np.reachabilityMutex.Lock()
+	for addr, err := range np.reachability {
+		if err != nil {
+			addrs[addr] = true
+		}
+	}
+	np.reachabilityMutex.Unlock()
+	if len(addrs) == 0 || !atomic.CompareAndSwapInt32(&np.reprobing, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&np.reprobing, 0)
+		np.setReachability(np.probeDestinations(context.Background(), addrs), false)
+	}()
+}
+
+// probeDestinations probes destinations concurrently and returns the error
+// of each of them, nil if reachable
+func (np *NProbeManager) probeDestinations(ctx context.Context, addrs map[string]bool) map[string]error {
+	results := map[string]error{}
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for addr := range addrs {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			err := exporter.ProbeDestination(ctx, addr, np.DestinationProbeTimeout)
+			mutex.Lock()
+			results[addr] = err
+			mutex.Unlock()
+		}(addr)
+	}
+	wg.Wait()
+	return results
+}
+
+// setReachability records the results of destination probes. When replace is
+// set, the destinations which weren't probed are forgotten.
+func (np *NProbeManager) setReachability(results map[string]error, replace bool) {
+	np.reachabilityMutex.Lock()
+	defer np.reachabilityMutex.Unlock()
+	if replace {
+		for addr := range np.reachability {
+			if _, ok := results[addr]; !ok {
+				delete(np.reachability, addr)
+				if np.Health != nil {
+					np.Health.Unregister(health.ReachabilityComponent(addr))
+				}
+			}
+		}
+	}
+	for addr, err := range results {
+		previous, known := np.reachability[addr]
+		if err != nil {
+			logger.Warningf("Destination %s is unreachable, holding back its records: %v", addr, err)
+		} else if known && previous != nil {
+			logger.Infof("Destination %s is reachable again, delivering its records", addr)
+		}
+		np.reachability[addr] = err
+		if np.Health != nil {
+			np.Health.Report(health.ReachabilityComponent(addr), err, 0)
+		}
+	}
+}
+
+// clearReachability forgets the results of the destination probes, e.g.
+// once disabled, releasing the records held back
+func (np *NProbeManager) clearReachability() {
+	np.setReachability(map[string]error{}, true)
+}
+
+// isDestinationDegraded returns the address of the destination of a task and
+// whether its last probe failed
+func (np *NProbeManager) isDestinationDegraded(task *models.NetworkProbeTask) (string, bool) {
+	addr := np.getProbedAddress(task)
+	np.reachabilityMutex.Lock()
+	defer np.reachabilityMutex.Unlock()
+	return addr, np.reachability[addr] != nil
+}
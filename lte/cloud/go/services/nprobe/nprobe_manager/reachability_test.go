@@ -0,0 +1,88 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileDestinations(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	unreachable := closed.Addr().String()
+	closed.Close()
+
+	registry := health.NewRegistry()
+	np := &NProbeManager{
+		DestinationProbe:        true,
+		DestinationProbeTimeout: time.Second,
+		Health:                  registry,
+		probedAddress:           listener.Addr().String(),
+		reachability:            map[string]error{},
+	}
+	task := &models.NetworkProbeTask{
+		TaskID:      "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{TargetID: "IMSI001010000000001"},
+	}
+	overriding := &models.NetworkProbeTask{
+		TaskID: "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID: "IMSI001010000000002",
+			Delivery: &models.NetworkProbeTaskDelivery{DeliveryAddress: unreachable},
+		},
+	}
+	tasks := map[string]map[string]*models.NetworkProbeTask{
+		"n0": {string(task.TaskID): task},
+		"n1": {string(overriding.TaskID): overriding},
+	}
+
+	np.reconcileDestinations(context.Background(), tasks)
+	_, degraded := np.isDestinationDegraded(task)
+	assert.False(t, degraded)
+	addr, degraded := np.isDestinationDegraded(overriding)
+	assert.True(t, degraded)
+	assert.Equal(t, unreachable, addr)
+	statuses := registry.Check()
+	assert.Len(t, statuses, 2)
+	assert.False(t, health.IsHealthy(statuses))
+
+	// the destination reached again is no longer degraded
+	np.setReachability(map[string]error{unreachable: nil}, false)
+	_, degraded = np.isDestinationDegraded(overriding)
+	assert.False(t, degraded)
+	assert.True(t, health.IsHealthy(registry.Check()))
+
+	// the destinations no task delivers to are forgotten by the next term
+	np.reconcileDestinations(context.Background(), map[string]map[string]*models.NetworkProbeTask{})
+	assert.Len(t, registry.Check(), 1)
+
+	// nothing is probed once disabled
+	np.clearReachability()
+	np.DestinationProbe = false
+	np.reconcileDestinations(context.Background(), tasks)
+	assert.Empty(t, registry.Check())
+	_, degraded = np.isDestinationDegraded(overriding)
+	assert.False(t, degraded)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// Event categories of the record filters of the tasks
+const (
+	eventCategoryBearer          = "bearer"
+	eventCategoryPDNConnectivity = "pdn_connectivity"
+	eventCategoryLocation        = "location"
+	eventCategoryRegistration    = "registration"
+	eventCategorySMS             = "sms"
+)
+
+// eventCategories are the categories of the event types
+var eventCategories = map[string]string{
+	nprobe.SessionCreated:            eventCategoryBearer,
+	nprobe.SessionUpdated:            eventCategoryBearer,
+	nprobe.SessionTerminated:         eventCategoryBearer,
+	nprobe.SessionCreateFailure:      eventCategoryBearer,
+	nprobe.HandoverSuccess:           eventCategoryBearer,
+	nprobe.UsageReported:             eventCategoryBearer,
+	nprobe.BearerActive:              eventCategoryBearer,
+	nprobe.PDNConnectivityRequested:  eventCategoryPDNConnectivity,
+	nprobe.PDNDisconnectionRequested: eventCategoryPDNConnectivity,
+	nprobe.TargetReported:            eventCategoryLocation,
+	nprobe.TrackingAreaUpdate:        eventCategoryLocation,
+	nprobe.ServingSystemChanged:      eventCategoryLocation,
+	nprobe.UpdateLocationSuccess:     eventCategoryLocation,
+	nprobe.AttachSuccess:             eventCategoryRegistration,
+	nprobe.DetachSuccess:             eventCategoryRegistration,
+	nprobe.SMSTransferred:            eventCategorySMS,
+}
+
+// recordFilter selects the events of a task whose records are delivered,
+// as set by the record filter of the task. Events are matched once they are
+// known to concern the target, before their record is built.
+type recordFilter struct {
+	categories map[string]bool
+	apns       map[string]bool
+	// windows are the time windows of the filter, as minutes of the day
+	windows  []timeWindow
+	location *time.Location
+}
+
+type timeWindow struct {
+	start, end int
+}
+
+// newRecordFilter returns the filter of the records of a task, nil if all
+// its records are delivered
+func newRecordFilter(filter *models.NetworkProbeRecordFilter) *recordFilter {
+	if filter == nil {
+		return nil
+	}
+	ret := &recordFilter{location: time.UTC}
+	if len(filter.EventCategories) != 0 {
+		ret.categories = map[string]bool{}
+		for _, category := range filter.EventCategories {
+			ret.categories[category] = true
+		}
+	}
+	if len(filter.Apns) != 0 {
+		ret.apns = map[string]bool{}
+		for _, apn := range filter.Apns {
+			ret.apns[apn] = true
+		}
+	}
+	for _, window := range filter.TimeWindows {
+		if window == nil {
+			continue
+		}
+		start, startOK := parseTimeOfDay(window.Start)
+		end, endOK := parseTimeOfDay(window.End)
+		if !startOK || !endOK {
+			logger.Errorf("Ignoring invalid time window %s-%s of record filter", window.Start, window.End)
+			continue
+		}
+		ret.windows = append(ret.windows, timeWindow{start: start, end: end})
+	}
+	if len(filter.TimeZone) != 0 {
+		location, err := time.LoadLocation(filter.TimeZone)
+		if err != nil {
+			logger.Errorf("Matching the time windows of record filter in UTC, unknown time zone %s: %v", filter.TimeZone, err)
+		} else {
+			ret.location = location
+		}
+	}
+	return ret
+}
+
+// parseTimeOfDay returns the minutes of the day of a time given as HH:MM
+func parseTimeOfDay(value string) (int, bool) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// matches returns true if the record of an event is to be delivered given
+// the sessions open. The APNs only apply to the bearer and PDN connectivity
+// events, the ones which don't carry the APN of their session matching once
+// the session is open. The records ending open sessions are delivered
+// outside of the time windows, so that the sessions begun in a window are
+// always closed.
+func (f *recordFilter) matches(event *eventdM.Event, open map[string]bool) bool {
+	if f == nil {
+		return true
+	}
+	category := eventCategories[event.EventType]
+	if f.categories != nil && !f.categories[category] {
+		return false
+	}
+	sessionID := getSessionID(event)
+	if f.apns != nil && (category == eventCategoryBearer || category == eventCategoryPDNConnectivity) {
+		eventData, _ := event.Value.(map[string]interface{})
+		apn, _ := eventData["apn"].(string)
+		if len(apn) != 0 && !f.apns[apn] {
+			return false
+		}
+		if len(apn) == 0 && !open[sessionID] {
+			return false
+		}
+	}
+	if event.EventType == nprobe.SessionTerminated && open[sessionID] {
+		return true
+	}
+	return f.isInWindows(event.Timestamp)
+}
+
+// isInWindows returns true if an event timestamp is within a time window of
+// the filter. Events of invalid timestamps match, their record being
+// rejected as such when encoded.
+func (f *recordFilter) isInWindows(timestamp string) bool {
+	if len(f.windows) == 0 {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return true
+	}
+	t = t.In(f.location)
+	minute := t.Hour()*60 + t.Minute()
+	for _, window := range f.windows {
+		if window.start < window.end {
+			if minute >= window.start && minute < window.end {
+				return true
+			}
+		} else if minute >= window.start || minute < window.end {
+			// the window ends the next day
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordFilter(t *testing.T) {
+	newEvent := func(eventType, apn, timestamp string) *eventdM.Event {
+		value := map[string]interface{}{"imsi": "IMSI001010000000001", "session_id": "s1"}
+		if len(apn) != 0 {
+			value["apn"] = apn
+		}
+		return &eventdM.Event{EventType: eventType, Value: value, Timestamp: timestamp}
+	}
+	const noon = "2021-02-18T12:00:00Z"
+
+	// tasks without filter deliver all records
+	var none *recordFilter
+	assert.True(t, none.matches(newEvent(nprobe.AttachSuccess, "", noon), nil))
+	assert.Nil(t, newRecordFilter(nil))
+
+	categories := newRecordFilter(&models.NetworkProbeRecordFilter{EventCategories: []string{"bearer", "location"}})
+	assert.True(t, categories.matches(newEvent(nprobe.SessionCreated, "", noon), nil))
+	assert.True(t, categories.matches(newEvent(nprobe.UsageReported, "", noon), nil))
+	assert.True(t, categories.matches(newEvent(nprobe.TrackingAreaUpdate, "", noon), nil))
+	assert.False(t, categories.matches(newEvent(nprobe.AttachSuccess, "", noon), nil))
+	assert.False(t, categories.matches(newEvent(nprobe.PDNConnectivityRequested, "ims", noon), nil))
+	assert.False(t, categories.matches(newEvent("unknown", "", noon), nil))
+
+	apns := newRecordFilter(&models.NetworkProbeRecordFilter{Apns: []string{"ims"}})
+	assert.True(t, apns.matches(newEvent(nprobe.SessionCreated, "ims", noon), nil))
+	assert.True(t, apns.matches(newEvent(nprobe.PDNConnectivityRequested, "ims", noon), nil))
+	assert.False(t, apns.matches(newEvent(nprobe.SessionCreated, "internet", noon), nil))
+	// the events without APN match once their session is open
+	assert.False(t, apns.matches(newEvent(nprobe.SessionUpdated, "", noon), nil))
+	assert.True(t, apns.matches(newEvent(nprobe.SessionUpdated, "", noon), map[string]bool{"s1": true}))
+	// the APNs only apply to the bearer and PDN connectivity events
+	assert.True(t, apns.matches(newEvent(nprobe.AttachSuccess, "", noon), nil))
+
+	windows := newRecordFilter(&models.NetworkProbeRecordFilter{
+		TimeWindows: []*models.NetworkProbeTimeWindow{
+			{Start: "08:00", End: "12:00"},
+			{Start: "22:00", End: "02:00"},
+		},
+		TimeZone: "Europe/Paris",
+	})
+	matchesAt := func(timestamp string) bool {
+		return windows.matches(newEvent(nprobe.AttachSuccess, "", timestamp), nil)
+	}
+	assert.True(t, matchesAt("2021-02-18T07:00:00Z"))
+	assert.True(t, matchesAt("2021-02-18T10:59:59+00:00"))
+	// the end of a window is excluded
+	assert.False(t, matchesAt(noon))
+	assert.False(t, matchesAt("2021-02-18T11:00:00+00:00"))
+	// windows ending the next day
+	assert.True(t, matchesAt("2021-02-18T23:30:00Z"))
+	assert.True(t, matchesAt("2021-02-19T00:30:00Z"))
+	assert.False(t, matchesAt("2021-02-19T01:00:00Z"))
+	// the sessions begun in a window are closed
+	assert.False(t, windows.matches(newEvent(nprobe.SessionTerminated, "", noon), nil))
+	assert.True(t, windows.matches(newEvent(nprobe.SessionTerminated, "", noon), map[string]bool{"s1": true}))
+}
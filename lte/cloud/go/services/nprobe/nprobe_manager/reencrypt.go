@@ -0,0 +1,45 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import ()
+
+// reencryptBatchSize is the maximum number of records of a network sealed
+// again per processing pass
+const reencryptBatchSize = 500
+
+// reencryptRecords seals the quarantined events and delivered records of a
+// network which are not sealed with the primary key, e.g. after a key rotation
+// or once encryption is enabled, a batch per pass. The former key can be
+// removed once all the records are sealed with the new one.
+func (np *NProbeManager) reencryptRecords(networkID string) {
+	if np.Keyring == nil {
+		return
+	}
+	primary := np.Keyring.Primary()
+	if np.reencryptedWith[networkID] == primary {
+		return
+	}
+	count, err := np.Storage.ReencryptRecords(networkID, reencryptBatchSize)
+	if err != nil {
+		logger.Errorf("Failed to re-encrypt records of network %s: %v", networkID, err)
+		return
+	}
+	if count != 0 {
+		logger.Infof("Sealed %d records of network %s with key %s", count, networkID, primary)
+	}
+	if count < reencryptBatchSize {
+		np.reencryptedWith[networkID] = primary
+	}
+}
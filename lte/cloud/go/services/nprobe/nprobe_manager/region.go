@@ -0,0 +1,72 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// In geo-distributed deployments, each orc8r partition runs a service
+// instance exporting to the delivery function of its region. Networks set
+// the region of their gateways, and of the gateways located across a border,
+// so that the records of the events of a gateway are only exported to the
+// delivery function of the country it is located in. Networks setting no
+// region are exported by any instance.
+
+// hasRegions returns true if the records of a network are bound to regions
+func (np *NProbeManager) hasRegions(networkID string) bool {
+	config := np.getNetworkConfig(networkID)
+	return len(config.Region) != 0 || len(config.GatewayRegions) != 0
+}
+
+// isRegionHeld returns true if no gateway of a network bound to regions is
+// located in the region of the service. Instances without region hold all
+// the networks bound to regions.
+func (np *NProbeManager) isRegionHeld(networkID string) bool {
+	if !np.hasRegions(networkID) {
+		return false
+	}
+	config := np.getNetworkConfig(networkID)
+	if len(np.Region) != 0 {
+		if config.Region == np.Region {
+			return false
+		}
+		for _, region := range config.GatewayRegions {
+			if region == np.Region {
+				return false
+			}
+		}
+	}
+	logger.V(2).Infof("Holding the tasks of network %s exported in other regions than %q", networkID, np.Region)
+	return true
+}
+
+// getEventRegion returns the region of the gateway an event was reported by,
+// empty if its network is not bound to regions
+func (np *NProbeManager) getEventRegion(networkID string, event *eventdM.Event) string {
+	config := np.getNetworkConfig(networkID)
+	if region, ok := config.GatewayRegions[event.HardwareID]; ok {
+		return region
+	}
+	return config.Region
+}
+
+// isEventInRegion returns true if the record of an event may be exported to
+// the delivery function of the service region
+func (np *NProbeManager) isEventInRegion(networkID string, event *eventdM.Event) bool {
+	if !np.hasRegions(networkID) {
+		return true
+	}
+	return len(np.Region) != 0 && np.getEventRegion(networkID, event) == np.Region
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegions(t *testing.T) {
+	configs := map[string]*models.NetworkProbeNetworkConfig{
+		"fr": {Region: "eu-fr"},
+		"mc": {Region: "eu-mc"},
+		// a network of gateways in France, one of them across the border
+		"fr-mc": {Region: "eu-fr", GatewayRegions: map[string]string{"gw-nice-1": "eu-mc"}},
+	}
+	newEvent := func(hardwareID string) *eventdM.Event {
+		return &eventdM.Event{EventType: nprobe.AttachSuccess, HardwareID: hardwareID}
+	}
+
+	fr := &NProbeManager{Region: "eu-fr"}
+	fr.setNetworkConfigs(configs)
+	assert.False(t, fr.isRegionHeld("fr"))
+	assert.True(t, fr.isRegionHeld("mc"))
+	assert.False(t, fr.isRegionHeld("fr-mc"))
+	assert.True(t, fr.isEventInRegion("fr", newEvent("gw-paris-1")))
+	assert.True(t, fr.isEventInRegion("fr-mc", newEvent("gw-paris-1")))
+	assert.False(t, fr.isEventInRegion("fr-mc", newEvent("gw-nice-1")))
+
+	mc := &NProbeManager{Region: "eu-mc"}
+	mc.setNetworkConfigs(configs)
+	assert.True(t, mc.isRegionHeld("fr"))
+	assert.False(t, mc.isRegionHeld("mc"))
+	assert.False(t, mc.isRegionHeld("fr-mc"))
+	assert.False(t, mc.isEventInRegion("fr-mc", newEvent("gw-paris-1")))
+	assert.True(t, mc.isEventInRegion("fr-mc", newEvent("gw-nice-1")))
+
+	// networks without region are exported by any instance
+	assert.False(t, fr.isRegionHeld("n0"))
+	assert.True(t, mc.isEventInRegion("n0", newEvent("gw-paris-1")))
+
+	// instances without region export no network bound to a region
+	none := &NProbeManager{}
+	none.setNetworkConfigs(configs)
+	assert.False(t, none.isRegionHeld("n0"))
+	assert.True(t, none.isEventInRegion("n0", newEvent("gw-paris-1")))
+	for networkID := range configs {
+		assert.True(t, none.isRegionHeld(networkID))
+		assert.False(t, none.isEventInRegion(networkID, newEvent("gw-paris-1")))
+	}
+
+	// the gateway regions are validated
+	config := &models.NetworkProbeNetworkConfig{Region: "eu-fr", GatewayRegions: map[string]string{"gw-nice-1": "Monaco"}}
+	assert.Error(t, config.ValidateModel())
+	config.GatewayRegions["gw-nice-1"] = "eu-mc"
+	assert.NoError(t, config.ValidateModel())
+	config.Region = "EU FR"
+	assert.Error(t, config.ValidateModel())
+}
+
+func TestRegionsEnforcedOnRecords(t *testing.T) {
+	np := &NProbeManager{OperatorID: 49002, Region: "eu-mc"}
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{
+		"fr-mc": {Region: "eu-fr", GatewayRegions: map[string]string{"gw-nice-1": "eu-mc"}},
+	})
+	task := &models.NetworkProbeTask{
+		TaskID:      "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{TargetID: "IMSI001010000000001", TargetType: "imsi"},
+	}
+	state := &models.NetworkProbeData{TargetID: "IMSI001010000000001"}
+	matcher := &targetMatcher{targetType: models.NetworkProbeTaskDetailsTargetTypeImsi, targetID: "IMSI001010000000001"}
+	newEvent := func(hardwareID, timestamp string) eventdM.Event {
+		return eventdM.Event{
+			EventType:  nprobe.AttachSuccess,
+			StreamName: nprobe.ESStreamMME,
+			HardwareID: hardwareID,
+			Timestamp:  timestamp,
+			Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+		}
+	}
+	events := []eventdM.Event{
+		newEvent("gw-paris-1", "2021-02-18T05:13:26Z"),
+		newEvent("gw-nice-1", "2021-02-18T05:13:27Z"),
+		newEvent("gw-paris-2", "2021-02-18T05:13:28Z"),
+	}
+
+	// only the events of the gateway in Monaco are exported to its delivery function
+	records, timestamps := np.makeReplayRecords("fr-mc", task, state, matcher, events, 0)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "2021-02-18T05:13:27Z", timestamps[0].Format("2006-01-02T15:04:05Z07:00"))
+
+	np.Region = "eu-fr"
+	records, _ = np.makeReplayRecords("fr-mc", task, state, matcher, events, 0)
+	assert.Len(t, records, 2)
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/eventsource"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// A replay of a task is requested through the API when the LEMF lost records,
+// e.g. after a failure of its storage. The events of the target over the time
+// range requested are fetched again one page per pass of the task, and their
+// records delivered with new sequence numbers after the ones already
+// delivered, so that the stream of the task stays gapless and ordered.
+
+// deliverReplay delivers the records of the next page of events of a replay
+// and updates its progress. The sequence numbers of the page are persisted
+// before the records are submitted, so that an interrupted replay leaves a
+// gap rather than reusing them. Like the terminal record, the records
+// reserved before the replay are generated again with new numbers.
+func (np *NProbeManager) deliverReplay(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	matcher *targetMatcher,
+	replay *models.NetworkProbeTaskReplay,
+) error {
+	taskID := string(task.TaskID)
+	start, end := time.Time(replay.Start), time.Time(replay.End)
+	query := eventsource.Query{
+		NetworkID: networkID,
+		Tags:      matcher.tags,
+		From:      int(replay.ReplayedEvents),
+		Size:      querySize,
+		Start:     &start,
+		End:       &end,
+	}
+	events, err := np.fetchFromSources(ctx, query)
+	if err != nil {
+		return err
+	}
+	metrics.EventsFetched.WithLabelValues(networkID).Add(float64(len(events)))
+
+	exp, err := np.getExporter(networkID, task)
+	if err != nil {
+		return err
+	}
+	seq := getNextSequenceNumber(state)
+	records, timestamps := np.makeReplayRecords(networkID, task, state, matcher, events, seq)
+	if len(records) != 0 {
+		state.SequenceNumber = seq + uint32(len(records))
+		if err := np.storeState(networkID, taskID, state); err != nil {
+			return err
+		}
+		delivery := exp.SubmitRecords(
+			ctx,
+			getBackoffKey(networkID, taskID),
+			np.getTaskWeight(taskID),
+			task.TaskDetails.CorrelationID,
+			records,
+			np.MaxExportRetries,
+		)
+		var delivered []models.NetworkProbeDeliveryRecord
+		for i, record := range records {
+			if err = delivery.Wait(i); err != nil {
+				metrics.ExportFailures.WithLabelValues(networkID).Inc()
+				break
+			}
+			metrics.RecordsExported.WithLabelValues(networkID).Inc()
+			delivered = append(delivered, makeDeliveryRecord(task, record, seq+uint32(i), timestamps[i], time.Now()))
+		}
+		np.storeDeliveryRecords(networkID, taskID, delivered)
+		state.RecordsExported += uint64(len(delivered))
+		if serr := np.storeState(networkID, taskID, state); serr != nil && err == nil {
+			err = serr
+		}
+		if err != nil {
+			// the page is replayed again on the next pass
+			return err
+		}
+		replay.RecordsReplayed += uint64(len(records))
+	}
+
+	replay.ReplayedEvents += uint64(len(events))
+	if len(events) < querySize {
+		replay.CompletedAt = strfmt.DateTime(time.Now().UTC())
+		logger.Infof("Replayed %d records of task %s from %s to %s", replay.RecordsReplayed, taskID, replay.Start, replay.End)
+	}
+	return np.Storage.StoreTaskReplay(networkID, taskID, *replay)
+}
+
+// makeReplayRecords encodes the records of the events of the target replayed,
+// numbered from seq, and returns them along with the time of their events.
+// Records keep the class of their event type as the sessions of the target
+// before the time range are unknown. Events failing to be encoded are skipped,
+// they were quarantined when first processed.
+func (np *NProbeManager) makeReplayRecords(
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	matcher *targetMatcher,
+	events []eventdM.Event,
+	seq uint32,
+) ([][]byte, []time.Time) {
+	version := np.getModuleVersion(networkID, task)
+	operatorID := np.getOperatorID(networkID)
+	recordTask := np.getRecordTask(networkID, task, state)
+	// the device bound to the target is left as last seen
+	replayMatcher := *matcher
+	filter := newRecordFilter(task.TaskDetails.RecordFilter)
+	window := newWarrantWindow(task.TaskDetails)
+	open := map[string]bool{}
+	locationAuthorized := np.isLocationAuthorized(networkID, task)
+	var records [][]byte
+	var timestamps []time.Time
+	for i := range events {
+		event := &events[i]
+		// flow reports only make up the usage reports of the sessions, and the
+		// events out of the warrant of the task were never intercepted
+		if !replayMatcher.matches(event) || !np.isEventInRegion(networkID, event) || !window.contains(event.Timestamp) ||
+			event.EventType == nprobe.FlowUsageReported || !filter.matches(event, open) {
+			continue
+		}
+		// the location of past events isn't looked up, it is only left out
+		// when the warrant doesn't authorize it
+		if !locationAuthorized {
+			event = encoding.WithoutLocation(event)
+		}
+		class := encoding.GetEventRecordClass(event.EventType)
+		recordSeq := seq + uint32(len(records))
+		// replayed records carry the correlation ID their bearer had then,
+		// their bearers being released as they were processed
+		eventTask := recordTask
+		if np.BearerCorrelation {
+			var err error
+			eventTask, err = np.withBearerCorrelation(networkID, recordTask, event)
+			if err != nil {
+				logger.Errorf("Failed to replay record from event %s: %s", redact.Event(event), redact.Error(err))
+				continue
+			}
+		}
+		record, err := encoding.MakeVersionedRecord(event, eventTask, operatorID, recordSeq, class, version)
+		if err == nil {
+			record, err = np.prepareRecord(networkID, task, record)
+		}
+		if err != nil {
+			logger.Errorf("Failed to replay record from event %s: %s", redact.Event(event), redact.Error(err))
+			countEncodeFailure(networkID, err)
+			np.countEncoding(err)
+			continue
+		}
+		metrics.RecordsEncoded.WithLabelValues(networkID).Inc()
+		np.countEncoding(nil)
+		applyRecordClass(open, getSessionID(event), class)
+		timestamp, _ := time.Parse(time.RFC3339, event.Timestamp)
+		records = append(records, record)
+		timestamps = append(timestamps, timestamp)
+	}
+	return records, timestamps
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeReplayRecords(t *testing.T) {
+	np := &NProbeManager{OperatorID: 49002, RecordValidation: encoding.ValidationStrict}
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:   "IMEI3542900123456789",
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeImei,
+		},
+	}
+	state := &models.NetworkProbeData{TargetID: "IMEI3542900123456789", SequenceNumber: 12}
+	matcher := &targetMatcher{targetType: models.NetworkProbeTaskDetailsTargetTypeImei, targetID: "354290012345678"}
+	newEvent := func(eventType, timestamp string, value map[string]interface{}) eventdM.Event {
+		return eventdM.Event{EventType: eventType, StreamName: nprobe.ESStreamMME, Timestamp: timestamp, Value: value}
+	}
+	events := []eventdM.Event{
+		newEvent(nprobe.AttachSuccess, "2021-02-18T05:13:26Z", map[string]interface{}{
+			"imsi": "IMSI001010000000001",
+			"imei": "3542900123456789",
+		}),
+		// flow reports are not replayed
+		newEvent(nprobe.FlowUsageReported, "2021-02-18T05:13:27Z", map[string]interface{}{
+			"imsi": "IMSI001010000000001",
+		}),
+		// events of other subscribers are skipped
+		newEvent(nprobe.AttachSuccess, "2021-02-18T05:13:28Z", map[string]interface{}{
+			"imsi": "IMSI001010000000002",
+		}),
+		newEvent(nprobe.DetachSuccess, "2021-02-18T05:13:29Z", map[string]interface{}{
+			"imsi": "IMSI001010000000001",
+		}),
+	}
+
+	records, timestamps := np.makeReplayRecords("n0", task, state, matcher, events, 15)
+	assert.Len(t, records, 2)
+	assert.Equal(t, []time.Time{
+		time.Date(2021, 2, 18, 5, 13, 26, 0, time.UTC),
+		time.Date(2021, 2, 18, 5, 13, 29, 0, time.UTC),
+	}, timestamps)
+	for i, record := range records {
+		var decoded encoding.EpsIRIRecord
+		assert.NoError(t, decoded.Decode(record))
+		seq, ok := encoding.GetSequenceNumber(&decoded.Header)
+		assert.True(t, ok)
+		assert.Equal(t, uint32(15+i), seq)
+	}
+	// the device bound to the target is not changed by the replay
+	assert.Empty(t, matcher.boundIMSI)
+
+	replay := &models.NetworkProbeTaskReplay{}
+	assert.False(t, models.IsTaskReplayPending(replay))
+	replay.Start = strfmt.DateTime(time.Date(2021, 2, 18, 5, 0, 0, 0, time.UTC))
+	replay.End = strfmt.DateTime(time.Date(2021, 2, 18, 6, 0, 0, 0, time.UTC))
+	assert.True(t, models.IsTaskReplayPending(replay))
+	replay.CompletedAt = strfmt.DateTime(time.Now())
+	assert.False(t, models.IsTaskReplayPending(replay))
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// replicateCursors stores the cursors of the tasks of the networks processed
+// by a pass which were not checkpointed yet, so that a standby replica taking
+// over resumes the tasks from them rather than from their stored state,
+// which would replay the records delivered since their last checkpoint. The
+// cursors of a network are stored with a single write, and only once they
+// moved since they were last replicated. The records not acknowledged are not
+// replicated: they are generated again from the events after the cursor of
+// their task, with the sequence numbers reserved in its stored state.
+func (np *NProbeManager) replicateCursors(networkIDs []string, now time.Time) {
+	for _, networkID := range networkIDs {
+		cursors := np.getPendingCursors(networkID)
+		np.checkpointMutex.Lock()
+		replicated, ok := np.replicas[networkID]
+		np.checkpointMutex.Unlock()
+		if ok && reflect.DeepEqual(replicated, cursors) {
+			continue
+		}
+		replica := models.NetworkProbeCursorReplica{ReplicatedAt: strfmt.DateTime(now), Cursors: cursors}
+		if err := np.Storage.StoreCursorReplica(networkID, replica); err != nil {
+			logger.Errorf("Failed to replicate cursors of network %s: %v", networkID, err)
+			metrics.CursorReplicationFailures.Inc()
+			continue
+		}
+		np.checkpointMutex.Lock()
+		np.replicas[networkID] = cursors
+		np.checkpointMutex.Unlock()
+	}
+}
+
+// getPendingCursors returns the cursors of the tasks of a network which were
+// not checkpointed yet, by task ID
+func (np *NProbeManager) getPendingCursors(networkID string) []*models.NetworkProbeTaskCursor {
+	np.checkpointMutex.Lock()
+	defer np.checkpointMutex.Unlock()
+	var cursors []*models.NetworkProbeTaskCursor
+	for _, checkpoint := range np.checkpoints {
+		if checkpoint.networkID != networkID || checkpoint.current.equal(checkpoint.stored) {
+			continue
+		}
+		cursors = append(cursors, &models.NetworkProbeTaskCursor{
+			TaskID:                      checkpoint.taskID,
+			CheckpointedSequenceNumber:  checkpoint.stored.sequenceNumber,
+			CheckpointedRecordsExported: checkpoint.stored.recordsExported,
+			LastExported:                strfmt.DateTime(checkpoint.current.lastExported),
+			ExportedEventIds:            append([]string(nil), checkpoint.current.exportedEventIDs...),
+			OpenSessions:                append([]string(nil), checkpoint.current.openSessions...),
+			SequenceNumber:              checkpoint.current.sequenceNumber,
+			RecordsExported:             checkpoint.current.recordsExported,
+		})
+	}
+	sort.Slice(cursors, func(i, j int) bool { return cursors[i].TaskID < cursors[j].TaskID })
+	return cursors
+}
+
+// restoreReplicatedCursors applies the cursors replicated for the tasks of
+// a network onto their checkpoints seeded by the warm-up. A cursor is only
+// restored if it moved from the stored state of its task, which is otherwise
+// more recent, e.g. checkpointed after the cursor was replicated.
+func (np *NProbeManager) restoreReplicatedCursors(networkID string) {
+	replica, err := np.Storage.GetCursorReplica(networkID)
+	if err != nil {
+		logger.Errorf("Failed to get replicated cursors of network %s during warm-up: %v", networkID, err)
+		return
+	}
+	restored := 0
+	np.checkpointMutex.Lock()
+	for _, cursor := range replica.Cursors {
+		checkpoint, ok := np.checkpoints[getBackoffKey(networkID, cursor.TaskID)]
+		if !ok ||
+			checkpoint.stored.sequenceNumber != cursor.CheckpointedSequenceNumber ||
+			checkpoint.stored.recordsExported != cursor.CheckpointedRecordsExported {
+			continue
+		}
+		checkpoint.current = exportCursor{
+			lastExported:     time.Time(cursor.LastExported),
+			exportedEventIDs: append([]string(nil), cursor.ExportedEventIds...),
+			openSessions:     append([]string(nil), cursor.OpenSessions...),
+			sequenceNumber:   cursor.SequenceNumber,
+			recordsExported:  cursor.RecordsExported,
+		}
+		restored++
+	}
+	np.checkpointMutex.Unlock()
+	if restored != 0 {
+		metrics.ReplicatedCursorsRestored.Add(float64(restored))
+		logger.Infof("Restored %d cursors of network %s replicated at %s", restored, networkID, replica.ReplicatedAt)
+	}
+}
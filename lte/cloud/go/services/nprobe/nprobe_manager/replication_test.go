@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicateCursors(t *testing.T) {
+	store := storage.WithStateStore(nil, storage.NewMemoryStateStore())
+	active := &NProbeManager{
+		Storage:               store,
+		CheckpointMaxRecords:  50,
+		CheckpointMaxInterval: time.Hour,
+		checkpoints:           map[string]*taskCheckpoint{},
+		replicas:              map[string][]*models.NetworkProbeTaskCursor{},
+	}
+	lastExported := time.Unix(1615000000, 0).UTC()
+	stored := &models.NetworkProbeData{LastExported: strfmt.DateTime(lastExported), SequenceNumber: 12, RecordsExported: 12}
+	active.seedCheckpoint("n1", "task1", stored)
+	active.seedCheckpoint("n1", "task2", stored)
+
+	// the cursors checkpointed are not replicated
+	now := lastExported.Add(time.Hour)
+	active.replicateCursors([]string{"n1"}, now)
+	replica, err := store.GetCursorReplica("n1")
+	assert.NoError(t, err)
+	assert.Empty(t, replica.Cursors)
+	assert.Equal(t, strfmt.DateTime(now), replica.ReplicatedAt)
+
+	moved := &models.NetworkProbeData{
+		LastExported:     strfmt.DateTime(lastExported.Add(time.Minute)),
+		ExportedEventIds: []string{"e14"},
+		OpenSessions:     []string{"IMSI001010000000001/5"},
+		SequenceNumber:   14,
+		RecordsExported:  14,
+	}
+	assert.False(t, active.isCheckpointDue(getBackoffKey("n1", "task1"), moved, time.Now()))
+	active.replicateCursors([]string{"n1"}, now.Add(time.Second))
+	replica, err = store.GetCursorReplica("n1")
+	assert.NoError(t, err)
+	assert.Len(t, replica.Cursors, 1)
+	assert.Equal(t, "task1", replica.Cursors[0].TaskID)
+	assert.Equal(t, uint32(12), replica.Cursors[0].CheckpointedSequenceNumber)
+
+	// the replica is only stored again once the cursors move
+	active.replicateCursors([]string{"n1"}, now.Add(2*time.Second))
+	replica, err = store.GetCursorReplica("n1")
+	assert.NoError(t, err)
+	assert.Equal(t, strfmt.DateTime(now.Add(time.Second)), replica.ReplicatedAt)
+
+	// the standby resumes task1 from the replicated cursor, while task2 was
+	// checkpointed after the cursors were replicated
+	standby := &NProbeManager{
+		Storage:     store,
+		checkpoints: map[string]*taskCheckpoint{},
+		replicas:    map[string][]*models.NetworkProbeTaskCursor{},
+	}
+	standby.seedCheckpoint("n1", "task1", stored)
+	standby.seedCheckpoint("n1", "task2", &models.NetworkProbeData{SequenceNumber: 20, RecordsExported: 20})
+	err = store.StoreCursorReplica("n1", models.NetworkProbeCursorReplica{Cursors: append(replica.Cursors, &models.NetworkProbeTaskCursor{
+		TaskID:                      "task2",
+		CheckpointedSequenceNumber:  12,
+		CheckpointedRecordsExported: 12,
+		SequenceNumber:              15,
+		RecordsExported:             15,
+	})})
+	assert.NoError(t, err)
+	standby.restoreReplicatedCursors("n1")
+
+	state := &models.NetworkProbeData{LastExported: strfmt.DateTime(lastExported), SequenceNumber: 12, RecordsExported: 12}
+	standby.restoreCursor(getBackoffKey("n1", "task1"), state)
+	assert.Equal(t, moved, state)
+	state = &models.NetworkProbeData{SequenceNumber: 20, RecordsExported: 20}
+	standby.restoreCursor(getBackoffKey("n1", "task2"), state)
+	assert.Equal(t, uint32(20), state.SequenceNumber)
+}
@@ -0,0 +1,330 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/eventsource"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+const (
+	// reportEventID identifies the report of a one-shot task among the
+	// reserved records of the task
+	reportEventID = "one_shot_report"
+	// resumeReportEventID identifies the report of a task resumed after a
+	// pause among the reserved records of the task
+	resumeReportEventID = "resume_report"
+	// reportMaxPages bounds the pages of events searched backwards for the
+	// last events of the target of a one-shot task
+	reportMaxPages = 20
+)
+
+var (
+	// reportedFields are the fields of the events of a target which make up its report
+	reportedFields = []string{"imsi", "imei", "msisdn", "user_location", "tai", "ecgi", "spgw_ip"}
+	// sessionFields are the fields of the session of a target, reported until it terminates
+	sessionFields = []string{"session_id", "apn", "ip_addr", "ipv6_addr", "ipv6_prefix"}
+)
+
+// processOneShotTask delivers the single IRI-REPORT of a one-shot task, which
+// reports the location and state of its target as known from its events at
+// the time the task was created. The task is completed once it is delivered.
+func (np *NProbeManager) processOneShotTask(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+) error {
+	done, err := np.deliverTargetReport(ctx, networkID, task, state, reportEventID, getReportTime(task, state))
+	if !done {
+		return err
+	}
+	return np.completeOneShotTask(networkID, task, state)
+}
+
+// deliverResumeReport delivers the IRI-REPORT of the target of a task resumed
+// after a pause, reporting its location and state at the time of the
+// resumption. The events of the target while the task was paused are skipped.
+func (np *NProbeManager) deliverResumeReport(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	resumedAt time.Time,
+) (bool, error) {
+	done, err := np.deliverTargetReport(ctx, networkID, task, state, resumeReportEventID, resumedAt)
+	if !done {
+		return false, err
+	}
+	state.ResumeReportedAt = strfmt.DateTime(resumedAt)
+	if resumedAt.After(time.Time(state.LastExported)) {
+		state.LastExported = strfmt.DateTime(resumedAt)
+		state.ExportedEventIds = nil
+	}
+	return true, np.storeState(networkID, string(task.TaskID), state)
+}
+
+// deliverTargetReport delivers an IRI-REPORT of the location and state of the
+// target of a task as known from its events at reportedAt. It returns true
+// once the report is delivered, or dropped to the quarantine when it can't be
+// encoded, and false when it must be delivered again on the next pass.
+func (np *NProbeManager) deliverTargetReport(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	eventID string,
+	reportedAt time.Time,
+) (bool, error) {
+	taskID := string(task.TaskID)
+	matcher, err := np.resolveTarget(networkID, task)
+	if err != nil {
+		logger.Errorf("Failed to resolve targetID %s, withholding its report: %s", redact.Identity(state.TargetID), redact.Error(err))
+		return false, err
+	}
+	exp, err := np.getExporter(networkID, task)
+	if err != nil {
+		return false, err
+	}
+	var events []eventdM.Event
+	if matcher != nil {
+		events, err = np.fetchLastEvents(ctx, networkID, reportedAt, matcher)
+		if err != nil {
+			logger.Errorf("Failed to collect events for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return false, err
+		}
+	}
+	event := makeTargetReport(events, reportedAt)
+	switch {
+	case !np.isLocationAuthorized(networkID, task):
+		event = encoding.WithoutLocation(event)
+	case np.LocationEnrichment:
+		newLocationEnricher(networkID, np.getSyncedStates(networkID)).enrich(ctx, event)
+	}
+
+	// the report gets the same sequence number until it is known to be delivered
+	reservation, isReserved := getReservedRecords(state)[eventID]
+	seq := getNextSequenceNumber(state)
+	if isReserved {
+		seq = reservation.SequenceNumber
+	}
+	record, err := encoding.MakeVersionedRecord(
+		event, np.getRecordTask(networkID, task, state), np.getOperatorID(networkID), seq, encoding.GetEventRecordClass(event.EventType), np.getModuleVersion(networkID, task),
+	)
+	if err == nil {
+		record, err = np.prepareRecord(networkID, task, record)
+	}
+	if err != nil {
+		// the report can't be encoded at all, it is left to the quarantine
+		logger.Errorf("Failed to build report of task %s: %s", taskID, err)
+		countEncodeFailure(networkID, err)
+		np.countEncoding(err)
+		np.quarantineEvent(networkID, taskID, event, err)
+		dropReservedRecord(state, eventID)
+		return true, nil
+	}
+	metrics.RecordsEncoded.WithLabelValues(networkID).Inc()
+	np.countEncoding(nil)
+	if np.isFrameDebugEnabled(networkID, task) {
+		logger.Infof("Exporting frame %d of task %s (%d bytes): %s", seq, taskID, len(record), redact.Bytes(record))
+	}
+	if !isReserved {
+		state.ReservedRecords = append(state.ReservedRecords, &models.NetworkProbeReservedRecord{
+			EventID:        eventID,
+			SequenceNumber: seq,
+			RecordClass:    encoding.RecordClassReport,
+		})
+		if err := np.storeState(networkID, taskID, state); err != nil {
+			logger.Errorf("Failed to reserve sequence number for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+			return false, err
+		}
+	}
+
+	delivery := exp.SubmitRecords(
+		ctx,
+		getBackoffKey(networkID, taskID),
+		np.getTaskWeight(taskID),
+		task.TaskDetails.CorrelationID,
+		[][]byte{record},
+		np.MaxExportRetries,
+	)
+	if err := delivery.Wait(0); err != nil {
+		if err == ctx.Err() {
+			// shutting down, the report is delivered on restart
+			return false, nil
+		}
+		logger.Errorf("Failed to export report for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(err))
+		metrics.ExportFailures.WithLabelValues(networkID).Inc()
+		if serr := np.updateDeliveryState(networkID, task, state, err); serr != nil {
+			logger.Errorf("Failed to update delivery state for targetID %s: %s", redact.Identity(state.TargetID), redact.Error(serr))
+		}
+		return false, err
+	}
+	metrics.RecordsExported.WithLabelValues(networkID).Inc()
+	np.storeDeliveryRecords(networkID, taskID, []models.NetworkProbeDeliveryRecord{
+		makeDeliveryRecord(task, record, seq, reportedAt, time.Now()),
+	})
+	state.RecordsExported++
+	state.SequenceNumber = seq + 1
+	return true, nil
+}
+
+// dropReservedRecord drops the reservation of the record of an event which
+// won't be delivered
+func dropReservedRecord(state *models.NetworkProbeData, eventID string) {
+	var kept []*models.NetworkProbeReservedRecord
+	for _, record := range state.ReservedRecords {
+		if record != nil && record.EventID != eventID {
+			kept = append(kept, record)
+		}
+	}
+	state.ReservedRecords = kept
+}
+
+// completeOneShotTask marks a one-shot task completed so that it is no longer
+// processed. No terminal record is due as it never begins a session.
+func (np *NProbeManager) completeOneShotTask(networkID string, task *models.NetworkProbeTask, state *models.NetworkProbeData) error {
+	taskID := string(task.TaskID)
+	state.CompletedAt = strfmt.DateTime(time.Now())
+	state.SuspendedAt = strfmt.DateTime{}
+	if err := np.storeState(networkID, taskID, state); err != nil {
+		logger.Errorf("Failed to complete task %s: %s", taskID, err)
+		return err
+	}
+	np.enterTaskState(networkID, taskID, models.NetworkProbeTaskLifecycleStateTerminated, reasonReported)
+	return np.updateDeliveryState(networkID, task, state, nil)
+}
+
+// getReportTime returns the time as of which the target of a one-shot task is
+// reported, i.e. the time the task was created
+func getReportTime(task *models.NetworkProbeTask, state *models.NetworkProbeData) time.Time {
+	if !time.Time(task.TaskDetails.Timestamp).IsZero() {
+		return time.Time(task.TaskDetails.Timestamp)
+	}
+	return time.Time(state.LastExported)
+}
+
+// fetchLastEvents retrieves the last events of a target up to end, searching
+// each event source backwards one page at a time until events of the target
+// are found
+func (np *NProbeManager) fetchLastEvents(
+	ctx context.Context,
+	networkID string,
+	end time.Time,
+	matcher *targetMatcher,
+) ([]eventdM.Event, error) {
+	query := eventsource.Query{
+		NetworkID: networkID,
+		Tags:      matcher.tags,
+		End:       &end,
+		Size:      querySize,
+	}
+	var matched []eventdM.Event
+	for _, source := range np.Sources {
+		events, err := np.fetchLastSourceEvents(ctx, networkID, source, query, matcher)
+		if err != nil {
+			return nil, err
+		}
+		matched = append(matched, events...)
+	}
+	orderEvents(matched)
+	return matched, nil
+}
+
+// fetchLastSourceEvents retrieves the last events of a target of a query
+// from an event source
+func (np *NProbeManager) fetchLastSourceEvents(
+	ctx context.Context,
+	networkID string,
+	source eventsource.EventSource,
+	query eventsource.Query,
+	matcher *targetMatcher,
+) ([]eventdM.Event, error) {
+	count, err := source.Count(ctx, query)
+	if err != nil {
+		metrics.SourceFetchFailures.WithLabelValues(source.Name()).Inc()
+		return nil, err
+	}
+
+	var matched []eventdM.Event
+	for page := 0; page < reportMaxPages && count > 0; page++ {
+		query.From, query.Size = 0, int(count)
+		if count > querySize {
+			query.From, query.Size = int(count-querySize), querySize
+		}
+		events, err := fetchFromSource(ctx, source, query)
+		if err != nil {
+			return nil, err
+		}
+		for i := range events {
+			if matcher.matches(&events[i]) && np.isEventInRegion(networkID, &events[i]) {
+				matched = append(matched, events[i])
+			}
+		}
+		if len(matched) != 0 {
+			break
+		}
+		count = int64(query.From)
+	}
+	return matched, nil
+}
+
+// makeTargetReport composes the event reporting the location and state of a
+// target from its events, in chronological order. The fields of its session
+// are only reported until it terminates or the target detaches.
+func makeTargetReport(events []eventdM.Event, reportedAt time.Time) *eventdM.Event {
+	value := map[string]interface{}{}
+	for i := range events {
+		eventData, ok := events[i].Value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// the location of the target is the last one reported, whatever its fields
+		if encoding.HasLocation(&events[i]) {
+			for _, key := range encoding.LocationFields {
+				delete(value, key)
+			}
+		}
+		fields := reportedFields
+		switch events[i].EventType {
+		case nprobe.SessionTerminated, nprobe.DetachSuccess:
+			for _, key := range sessionFields {
+				delete(value, key)
+			}
+		case nprobe.SessionCreated, nprobe.SessionUpdated:
+			fields = append(append([]string(nil), reportedFields...), sessionFields...)
+		}
+		for _, key := range fields {
+			if v, ok := eventData[key]; ok {
+				value[key] = v
+			}
+		}
+	}
+	return &eventdM.Event{
+		EventType:  nprobe.TargetReported,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  reportedAt.UTC().Format(time.RFC3339Nano),
+		Value:      value,
+	}
+}
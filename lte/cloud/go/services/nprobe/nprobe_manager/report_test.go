@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeTargetReport(t *testing.T) {
+	reportedAt := time.Date(2020, 11, 16, 12, 0, 0, 0, time.UTC)
+	newEvent := func(eventType string, value map[string]interface{}) eventdM.Event {
+		return eventdM.Event{EventType: eventType, StreamName: "mme", Value: value}
+	}
+	events := []eventdM.Event{
+		newEvent(nprobe.AttachSuccess, map[string]interface{}{
+			"imsi":          "IMSI001010000000001",
+			"user_location": "1f00f110",
+		}),
+		newEvent(nprobe.SessionCreated, map[string]interface{}{
+			"imsi":       "IMSI001010000000001",
+			"session_id": "s1",
+			"apn":        "oai.ipv4",
+			"ip_addr":    "192.168.128.12",
+		}),
+		// the location of a session event only moves the target
+		newEvent(nprobe.SessionUpdated, map[string]interface{}{
+			"imsi":          "IMSI001010000000001",
+			"user_location": "1f00f111",
+		}),
+	}
+
+	report := makeTargetReport(events, reportedAt)
+	assert.Equal(t, nprobe.TargetReported, report.EventType)
+	assert.Equal(t, nprobe.ServiceName, report.StreamName)
+	assert.Equal(t, "2020-11-16T12:00:00Z", report.Timestamp)
+	assert.Equal(t, map[string]interface{}{
+		"imsi":          "IMSI001010000000001",
+		"user_location": "1f00f111",
+		"session_id":    "s1",
+		"apn":           "oai.ipv4",
+		"ip_addr":       "192.168.128.12",
+	}, report.Value)
+
+	// the session is no longer reported once terminated
+	events = append(events, newEvent(nprobe.SessionTerminated, map[string]interface{}{
+		"imsi":       "IMSI001010000000001",
+		"session_id": "s1",
+	}))
+	report = makeTargetReport(events, reportedAt)
+	assert.Equal(t, map[string]interface{}{
+		"imsi":          "IMSI001010000000001",
+		"user_location": "1f00f111",
+	}, report.Value)
+
+	// a target without events is reported without location
+	report = makeTargetReport(nil, reportedAt)
+	assert.Empty(t, report.Value)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"strings"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/go-openapi/strfmt"
+)
+
+// getServingNetwork returns the network serving the target reported by an
+// S6a update location event, nil for the other events or if its visited
+// PLMN is invalid. The target roams unless the IMSI belongs to the visited
+// PLMN, when the event doesn't tell.
+func getServingNetwork(event *eventdM.Event) *models.NetworkProbeServingNetwork {
+	if event.EventType != nprobe.UpdateLocationSuccess {
+		return nil
+	}
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	mccMnc, _ := eventData["visited_plmn_id"].(string)
+	if encoding.MakePLMNID(mccMnc) == nil {
+		return nil
+	}
+	mccMnc = strings.TrimRight(mccMnc, "fF")
+	roaming, ok := eventData["roaming"].(bool)
+	if !ok {
+		imsi, _ := eventData["imsi"].(string)
+		roaming = len(imsi) != 0 && encoding.IsRoaming(imsi, mccMnc)
+	}
+	serving := &models.NetworkProbeServingNetwork{VisitedPlmnID: mccMnc, Roaming: roaming}
+	serving.ServingNetwork, _ = eventData["serving_network"].(string)
+	if timestamp, err := time.Parse(time.RFC3339, event.Timestamp); err == nil {
+		serving.UpdatedAt = strfmt.DateTime(timestamp)
+	}
+	return serving
+}
+
+// withServingNetwork completes an EPS event with the network serving the
+// target, as last reported by an update location, unless it reports one.
+// The event value is copied rather than updated.
+func withServingNetwork(event *eventdM.Event, serving *models.NetworkProbeServingNetwork) {
+	if serving == nil || event.StreamName == nprobe.ESStreamSGSN || encoding.HasRoaming(event) {
+		return
+	}
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	value := make(map[string]interface{}, len(eventData)+len(encoding.RoamingFields))
+	for key, v := range eventData {
+		value[key] = v
+	}
+	value["visited_plmn_id"] = serving.VisitedPlmnID
+	value["roaming"] = serving.Roaming
+	if len(serving.ServingNetwork) != 0 {
+		value["serving_network"] = serving.ServingNetwork
+	}
+	event.Value = value
+}
+
+// isRoamingIncluded returns true if the roaming option of a task intercepts
+// the events of its target served by a network. Targets whose serving
+// network isn't known yet are taken as home subscribers.
+func isRoamingIncluded(task *models.NetworkProbeTask, serving *models.NetworkProbeServingNetwork) bool {
+	roaming := serving != nil && serving.Roaming
+	switch task.TaskDetails.Roaming {
+	case models.NetworkProbeTaskDetailsRoamingExclude:
+		return !roaming
+	case models.NetworkProbeTaskDetailsRoamingOnly:
+		return roaming
+	default:
+		return true
+	}
+}
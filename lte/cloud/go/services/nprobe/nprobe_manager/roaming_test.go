@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServingNetwork(t *testing.T) {
+	event := &eventdM.Event{
+		EventType: nprobe.UpdateLocationSuccess,
+		Timestamp: "2021-02-18T05:13:26Z",
+		Value: map[string]interface{}{
+			"imsi":            "IMSI001010000000001",
+			"visited_plmn_id": "20801F",
+			"serving_network": "epc.mnc001.mcc208.3gppnetwork.org",
+		},
+	}
+	serving := getServingNetwork(event)
+	assert.Equal(t, &models.NetworkProbeServingNetwork{
+		Roaming:        true,
+		ServingNetwork: "epc.mnc001.mcc208.3gppnetwork.org",
+		UpdatedAt:      strfmt.DateTime(time.Date(2021, 2, 18, 5, 13, 26, 0, time.UTC)),
+		VisitedPlmnID:  "20801",
+	}, serving)
+	assert.NoError(t, serving.Validate(strfmt.Default))
+
+	// the roaming status reported prevails
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "visited_plmn_id": "20801", "roaming": false}
+	assert.False(t, getServingNetwork(event).Roaming)
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "visited_plmn_id": "00101"}
+	assert.False(t, getServingNetwork(event).Roaming)
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "visited_plmn_id": "001"}
+	assert.Nil(t, getServingNetwork(event))
+	event = &eventdM.Event{EventType: nprobe.AttachSuccess, Value: map[string]interface{}{"visited_plmn_id": "20801"}}
+	assert.Nil(t, getServingNetwork(event))
+
+	// the other events are completed with the network serving the target
+	event = &eventdM.Event{EventType: nprobe.SessionCreated, Value: map[string]interface{}{"imsi": "IMSI001010000000001"}}
+	eventData := event.Value
+	withServingNetwork(event, serving)
+	assert.Equal(t, map[string]interface{}{
+		"imsi":            "IMSI001010000000001",
+		"visited_plmn_id": "20801",
+		"serving_network": "epc.mnc001.mcc208.3gppnetwork.org",
+		"roaming":         true,
+	}, event.Value)
+	assert.Equal(t, map[string]interface{}{"imsi": "IMSI001010000000001"}, eventData)
+
+	event.Value = map[string]interface{}{"imsi": "IMSI001010000000001", "visited_plmn_id": "00101"}
+	withServingNetwork(event, serving)
+	assert.Equal(t, map[string]interface{}{"imsi": "IMSI001010000000001", "visited_plmn_id": "00101"}, event.Value)
+	event = &eventdM.Event{EventType: nprobe.SessionCreated, StreamName: nprobe.ESStreamSGSN, Value: map[string]interface{}{}}
+	withServingNetwork(event, serving)
+	assert.Empty(t, event.Value)
+}
+
+func TestRoamingOption(t *testing.T) {
+	task := &models.NetworkProbeTask{TaskDetails: &models.NetworkProbeTaskDetails{}}
+	home := &models.NetworkProbeServingNetwork{VisitedPlmnID: "00101"}
+	visited := &models.NetworkProbeServingNetwork{VisitedPlmnID: "20801", Roaming: true}
+	for _, tc := range []struct {
+		roaming string
+		home    bool
+		visited bool
+	}{
+		{"", true, true},
+		{models.NetworkProbeTaskDetailsRoamingInclude, true, true},
+		{models.NetworkProbeTaskDetailsRoamingExclude, true, false},
+		{models.NetworkProbeTaskDetailsRoamingOnly, false, true},
+	} {
+		task.TaskDetails.Roaming = tc.roaming
+		assert.Equal(t, tc.home, isRoamingIncluded(task, home), tc.roaming)
+		assert.Equal(t, tc.home, isRoamingIncluded(task, nil), tc.roaming)
+		assert.Equal(t, tc.visited, isRoamingIncluded(task, visited), tc.roaming)
+	}
+}
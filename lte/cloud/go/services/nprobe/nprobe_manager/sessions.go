@@ -0,0 +1,103 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"sort"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// The class of the record of a session event depends on the state of the
+// session, as known from the records delivered for the task. The first
+// record of a session begins its interception, e.g. an IRI-BEGIN for a
+// session modified once interception started, and the next ones continue
+// it until it is terminated. A session terminated which was never begun is
+// reported. Events not related to a session are always reported.
+
+// getSessionID returns the ID of the session an event relates to, if any
+func getSessionID(event *eventdM.Event) string {
+	switch event.EventType {
+	case nprobe.SessionCreated, nprobe.SessionUpdated, nprobe.SessionTerminated, nprobe.HandoverSuccess,
+		nprobe.UsageReported, nprobe.BearerActive:
+	default:
+		return ""
+	}
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	sessionID, _ := eventData["session_id"].(string)
+	return sessionID
+}
+
+// getOpenSessions returns the sessions begun by the records delivered for a task
+func getOpenSessions(state *models.NetworkProbeData) map[string]bool {
+	open := make(map[string]bool, len(state.OpenSessions))
+	for _, sessionID := range state.OpenSessions {
+		open[sessionID] = true
+	}
+	return open
+}
+
+// getRecordClass returns the class of the record of an event given the
+// sessions open. Events whose session is unknown keep the class of their type.
+func getRecordClass(event *eventdM.Event, sessionID string, open map[string]bool) string {
+	class := encoding.GetEventRecordClass(event.EventType)
+	if len(sessionID) == 0 {
+		return class
+	}
+	switch class {
+	case encoding.RecordClassBegin, encoding.RecordClassContinue:
+		if open[sessionID] {
+			return encoding.RecordClassContinue
+		}
+		return encoding.RecordClassBegin
+	case encoding.RecordClassEnd:
+		if !open[sessionID] {
+			return encoding.RecordClassReport
+		}
+	}
+	return class
+}
+
+// applyRecordClass updates the sessions open with a record of a session
+func applyRecordClass(open map[string]bool, sessionID, class string) {
+	if len(sessionID) == 0 {
+		return
+	}
+	switch class {
+	case encoding.RecordClassBegin, encoding.RecordClassContinue:
+		open[sessionID] = true
+	case encoding.RecordClassEnd:
+		delete(open, sessionID)
+	}
+}
+
+// advanceSessions updates the sessions open of a task with a record delivered
+func advanceSessions(state *models.NetworkProbeData, sessionID, class string) {
+	if len(sessionID) == 0 {
+		return
+	}
+	open := getOpenSessions(state)
+	applyRecordClass(open, sessionID, class)
+	state.OpenSessions = nil
+	for id := range open {
+		state.OpenSessions = append(state.OpenSessions, id)
+	}
+	sort.Strings(state.OpenSessions)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordClasses(t *testing.T) {
+	newEvent := func(eventType, sessionID string) *eventdM.Event {
+		value := map[string]interface{}{"imsi": "IMSI001010000000001"}
+		if len(sessionID) != 0 {
+			value["session_id"] = sessionID
+		}
+		return &eventdM.Event{EventType: eventType, Value: value}
+	}
+	events := []*eventdM.Event{
+		newEvent(nprobe.AttachSuccess, ""),
+		// interception started while the session was active
+		newEvent(nprobe.SessionUpdated, "s1"),
+		newEvent(nprobe.SessionCreated, "s2"),
+		newEvent(nprobe.SessionUpdated, "s2"),
+		newEvent(nprobe.SessionCreated, "s2"),
+		newEvent(nprobe.SessionTerminated, "s2"),
+		// the session was never begun
+		newEvent(nprobe.SessionTerminated, "s3"),
+		newEvent(nprobe.SessionUpdated, ""),
+		newEvent(nprobe.DetachSuccess, ""),
+	}
+	expected := []string{
+		encoding.RecordClassReport,
+		encoding.RecordClassBegin,
+		encoding.RecordClassBegin,
+		encoding.RecordClassContinue,
+		encoding.RecordClassContinue,
+		encoding.RecordClassEnd,
+		encoding.RecordClassReport,
+		encoding.RecordClassContinue,
+		encoding.RecordClassReport,
+	}
+
+	state := &models.NetworkProbeData{}
+	open := getOpenSessions(state)
+	for i, event := range events {
+		sessionID := getSessionID(event)
+		class := getRecordClass(event, sessionID, open)
+		assert.Equal(t, expected[i], class, "event %d", i)
+		applyRecordClass(open, sessionID, class)
+		advanceSessions(state, sessionID, class)
+	}
+	assert.Equal(t, map[string]bool{"s1": true}, open)
+	assert.Equal(t, []string{"s1"}, state.OpenSessions)
+
+	// the sessions open are restored along with the cursor of the task
+	assert.Equal(t, []string{"s1"}, getExportCursor(state).openSessions)
+	advanceSessions(state, "s1", encoding.RecordClassEnd)
+	assert.Empty(t, state.OpenSessions)
+	assert.Equal(t, "", getSessionID(&eventdM.Event{EventType: nprobe.SessionCreated, Value: "s1"}))
+}
+
+func TestSessionMapping(t *testing.T) {
+	task := &models.NetworkProbeTask{
+		TaskID: "task1",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			CorrelationID: 7,
+		},
+	}
+	event := &eventdM.Event{
+		EventType: nprobe.SessionCreated,
+		Timestamp: "2021-02-18T05:13:26Z",
+		Value: map[string]interface{}{
+			"imsi":             "IMSI001010000000001",
+			"session_id":       "s1",
+			"linked_bearer_id": "5",
+			"charging_id":      "3456789",
+			"apn":              "internet",
+			"ip_addr":          "192.168.128.12",
+		},
+	}
+
+	mapping := makeSessionMapping(task, event, getSessionID(event), 12)
+	assert.Equal(t, uint64(7), mapping.CorrelationID)
+	assert.Equal(t, "task1", mapping.TaskID)
+	assert.Equal(t, "s1", mapping.SessionID)
+	assert.Equal(t, "5", mapping.BearerID)
+	assert.Equal(t, "3456789", mapping.ChargingID)
+	assert.Empty(t, mapping.Teid)
+	assert.Equal(t, "192.168.128.12", mapping.IPAddr)
+	assert.Equal(t, uint32(12), mapping.FirstSequenceNumber)
+	assert.Equal(t, mapping.FirstSeen, mapping.LastSeen)
+	assert.NoError(t, mapping.Validate(strfmt.Default))
+
+	// events not related to a session aren't mapped
+	event.EventType = nprobe.AttachSuccess
+	assert.Nil(t, makeSessionMapping(task, event, getSessionID(event), 13))
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/orc8r/cloud/go/services/configurator"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// EndInterception ends the HI2 streams of the intercepting tasks with an
+// IRI-END on shutdown, before the delivery connections are closed, as the
+// kill switch ends them. The tasks are intercepted again from their committed
+// state once the service restarts. It must not be called while tasks are
+// processed, and gives up once the context is done.
+func (np *NProbeManager) EndInterception(ctx context.Context) {
+	networks, err := configurator.ListNetworksOfType(LteNetwork)
+	if err != nil {
+		logger.Errorf("Failed to retrieve lte network list to end interception: %s", err)
+		return
+	}
+	endedAt := time.Now()
+	for _, networkID := range networks {
+		// the interception of suspended networks already ended
+		suspended, err := np.KillSwitch.IsActive(networkID)
+		if suspended || err != nil {
+			continue
+		}
+		tasks, err := getNetworkProbeTasks(networkID)
+		if err != nil {
+			logger.Errorf("Failed to retrieve nprobe tasks of network %s to end interception: %s", networkID, err)
+			continue
+		}
+		for taskID, task := range tasks {
+			if ctx.Err() != nil {
+				logger.Warningf("Timed out while ending interception, the remaining tasks are not ended")
+				return
+			}
+			if err := np.endTaskInterception(ctx, networkID, task, endedAt); err != nil {
+				logger.Errorf("Failed to end interception of task %s: %v", taskID, err)
+			}
+		}
+	}
+}
+
+// endTaskInterception ends the HI2 stream of a task with an IRI-END if it is
+// intercepting and delivered records since the stream began
+func (np *NProbeManager) endTaskInterception(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	endedAt time.Time,
+) error {
+	taskID := string(task.TaskID)
+	if task.TaskDetails.OneShot {
+		// the report of one-shot tasks is their only record
+		return nil
+	}
+	lifecycle, err := np.Storage.GetTaskLifecycle(networkID, taskID)
+	if err != nil {
+		return err
+	}
+	switch lifecycle.State {
+	case models.NetworkProbeTaskLifecycleStateActive, models.NetworkProbeTaskLifecycleStateExpiring:
+	default:
+		return nil
+	}
+	state, err := np.Storage.GetNProbeData(networkID, taskID)
+	if err != nil {
+		return err
+	}
+	np.restoreCursor(getBackoffKey(networkID, taskID), state)
+	if state.RecordsExported == 0 {
+		return nil
+	}
+	// the terminal record still pending after the kill switch is delivered
+	// as is
+	if time.Time(state.SuspendedAt).IsZero() {
+		state.SuspendedAt = strfmt.DateTime(endedAt)
+	}
+	return np.deliverTerminalRecord(ctx, networkID, task, state)
+}
@@ -0,0 +1,326 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/debug"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/eventsource"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/killswitch"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/orc8r/cloud/go/services/configurator"
+	configuratorTestInit "magma/orc8r/cloud/go/services/configurator/test_init"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+	"magma/orc8r/cloud/go/test_utils"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+// eventSource serves the events from the start of the queries
+type eventSource struct {
+	events []eventdM.Event
+}
+
+func (s *eventSource) Name() string { return "test" }
+
+func (s *eventSource) Fetch(ctx context.Context, query eventsource.Query) ([]eventdM.Event, error) {
+	var ret []eventdM.Event
+	for _, event := range s.events {
+		timestamp, _ := time.Parse(time.RFC3339, event.Timestamp)
+		if query.Start == nil || !timestamp.Before(*query.Start) {
+			ret = append(ret, event)
+		}
+	}
+	return ret, nil
+}
+
+func (s *eventSource) Count(ctx context.Context, query eventsource.Query) (int64, error) {
+	return int64(len(s.events)), nil
+}
+
+func (s *eventSource) Check(ctx context.Context) error { return nil }
+
+// shutdownBackend records the records delivered, calling onSend after each of
+// them, and holds the record at hangAt until it is closed, as a connection
+// awaiting an acknowledgement which never comes
+type shutdownBackend struct {
+	mutex   sync.Mutex
+	records [][]byte
+	onSend  func(sent int)
+	hangAt  int
+	hanging chan struct{}
+	closed  chan struct{}
+}
+
+func newShutdownBackend() *shutdownBackend {
+	return &shutdownBackend{hangAt: -1, hanging: make(chan struct{}), closed: make(chan struct{})}
+}
+
+func (b *shutdownBackend) Send(record []byte, correlationID uint64) error {
+	b.mutex.Lock()
+	if len(b.records) == b.hangAt {
+		b.hangAt = -1
+		b.mutex.Unlock()
+		close(b.hanging)
+		<-b.closed
+		return &exporter.DeliveryError{Class: exporter.FailureNack, Err: errors.New("connection closed before the record was acknowledged")}
+	}
+	b.records = append(b.records, record)
+	sent, onSend := len(b.records), b.onSend
+	b.mutex.Unlock()
+	if onSend != nil {
+		onSend(sent)
+	}
+	return nil
+}
+
+func (b *shutdownBackend) IsConnected() bool { return true }
+
+func (b *shutdownBackend) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	select {
+	case <-b.closed:
+	default:
+		close(b.closed)
+	}
+}
+
+// getSequenceNumbers returns the sequence numbers of the records delivered
+func (b *shutdownBackend) getSequenceNumbers(t *testing.T) []uint32 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var ret []uint32
+	for _, record := range b.records {
+		var decoded encoding.EpsIRIRecord
+		assert.NoError(t, decoded.Decode(record))
+		seq, _ := encoding.GetSequenceNumber(&decoded.Header)
+		ret = append(ret, seq)
+	}
+	return ret
+}
+
+func TestProcessEventPageShutdown(t *testing.T) {
+	taskID := "4f1dc9a2-8b3e-4c5d-9e6f-7a8b9c0d1e2f"
+	task := &models.NetworkProbeTask{
+		TaskID: models.NetworkProbeTaskID(taskID),
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			TargetType:    models.NetworkProbeTaskDetailsTargetTypeImsi,
+			CorrelationID: 7,
+		},
+	}
+	var events []eventdM.Event
+	for i, timestamp := range []string{
+		"2021-02-18T05:13:26Z",
+		"2021-02-18T05:13:27Z",
+		"2021-02-18T05:13:28Z",
+		"2021-02-18T05:13:29Z",
+		"2021-02-18T05:13:30Z",
+		"2021-02-18T05:13:31Z",
+	} {
+		eventType := nprobe.AttachSuccess
+		if i%2 == 1 {
+			eventType = nprobe.DetachSuccess
+		}
+		events = append(events, eventdM.Event{
+			EventType:  eventType,
+			StreamName: nprobe.ESStreamMME,
+			Timestamp:  timestamp,
+			Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+		})
+	}
+	expectedSeqs := []uint32{0, 1, 2, 3, 4, 5}
+
+	newManager := func(backend *shutdownBackend, name string) *NProbeManager {
+		return &NProbeManager{
+			Exporter:                  exporter.NewRecordExporter(backend),
+			Debug:                     debug.NewSettings(),
+			MaxExportRetries:          1,
+			DeadLetterAfterRejections: 1,
+			Sources:                   []eventsource.EventSource{&eventSource{events: events}},
+			Storage:                   storage.NewNProbeBlobstore(test_utils.NewSQLBlobstore(t, name)),
+			checkpoints:               map[string]*taskCheckpoint{},
+			pass:                      newPassReport(time.Now()),
+		}
+	}
+	// processPage processes the events from the committed state of the task,
+	// as the service does when it starts
+	processPage := func(ctx context.Context, np *NProbeManager) (pageResult, *models.NetworkProbeData) {
+		state, err := np.Storage.GetNProbeData("n0", taskID)
+		if err != nil {
+			state = &models.NetworkProbeData{TargetID: "IMSI001010000000001"}
+		}
+		matcher := &targetMatcher{targetType: models.NetworkProbeTaskDetailsTargetTypeImsi, imsi: "001010000000001"}
+		result, err := np.processEventPage(ctx, "n0", task, state, matcher, np.Exporter, nil, len(events))
+		assert.NoError(t, err)
+		committed, err := np.Storage.GetNProbeData("n0", taskID)
+		assert.NoError(t, err)
+		return result, committed
+	}
+
+	// all the records are delivered when they drain in time
+	backend := newShutdownBackend()
+	np := newManager(backend, "shutdown_drain_test_blobstore")
+	result, state := processPage(context.Background(), np)
+	np.Exporter.Close()
+	assert.NoError(t, result.exportErr)
+	assert.Equal(t, expectedSeqs, backend.getSequenceNumbers(t))
+	assert.Equal(t, uint32(6), state.SequenceNumber)
+	assert.Equal(t, uint64(6), state.RecordsExported)
+	assert.Empty(t, state.ReservedRecords)
+
+	// the records delivered before the context is cancelled are committed,
+	// the other ones are delivered on restart with the numbers they reserved
+	for _, delivered := range []int{1, 3, 5} {
+		backend = newShutdownBackend()
+		np = newManager(backend, "shutdown_cancel_test_blobstore")
+		ctx, cancel := context.WithCancel(context.Background())
+		backend.onSend = func(sent int) {
+			if sent == delivered {
+				cancel()
+			}
+		}
+		result, state = processPage(ctx, np)
+		cancel()
+		assert.NoError(t, result.exportErr)
+		assert.Equal(t, expectedSeqs[:delivered], backend.getSequenceNumbers(t))
+		assert.Equal(t, uint32(delivered), state.SequenceNumber)
+		assert.Equal(t, uint64(delivered), state.RecordsExported)
+		assert.Len(t, state.ReservedRecords, len(events)-delivered)
+
+		backend.onSend = nil
+		result, state = processPage(context.Background(), np)
+		np.Exporter.Close()
+		assert.NoError(t, result.exportErr)
+		assert.Equal(t, expectedSeqs, backend.getSequenceNumbers(t))
+		assert.Equal(t, uint32(6), state.SequenceNumber)
+		assert.Equal(t, uint64(6), state.RecordsExported)
+		assert.Empty(t, state.ReservedRecords)
+	}
+
+	// the record still awaiting its acknowledgement when the drain times out
+	// fails once its connection is closed, it is delivered on restart rather
+	// than set aside
+	backend = newShutdownBackend()
+	backend.hangAt = 2
+	np = newManager(backend, "shutdown_timeout_test_blobstore")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		result, state = processPage(ctx, np)
+	}()
+	<-backend.hanging
+	cancel()
+	np.Exporter.Disconnect()
+	<-done
+	assert.NoError(t, result.exportErr)
+	assert.False(t, result.deadLettered)
+	assert.Equal(t, expectedSeqs[:2], backend.getSequenceNumbers(t))
+	assert.Equal(t, uint32(2), state.SequenceNumber)
+	assert.Equal(t, uint64(2), state.RecordsExported)
+	assert.Len(t, state.ReservedRecords, 4)
+	letters, err := np.Storage.GetDeadLetters("n0", taskID)
+	assert.NoError(t, err)
+	assert.Empty(t, letters)
+
+	result, state = processPage(context.Background(), np)
+	np.Exporter.Close()
+	assert.NoError(t, result.exportErr)
+	assert.Equal(t, expectedSeqs, backend.getSequenceNumbers(t))
+	assert.Equal(t, uint32(6), state.SequenceNumber)
+	assert.Empty(t, state.ReservedRecords)
+}
+
+func TestEndInterception(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	assert.NoError(t, configurator.CreateNetwork(configurator.Network{ID: "n0", Type: LteNetwork}, serdes.Network))
+	assert.NoError(t, configurator.CreateNetwork(configurator.Network{ID: "n1", Type: LteNetwork}, serdes.Network))
+
+	backend := newShutdownBackend()
+	np := &NProbeManager{
+		Exporter:         exporter.NewRecordExporter(backend),
+		MaxExportRetries: 1,
+		Storage:          storage.NewNProbeBlobstore(test_utils.NewSQLBlobstore(t, "shutdown_end_test_blobstore")),
+		checkpoints:      map[string]*taskCheckpoint{},
+	}
+	np.KillSwitch = killswitch.NewSwitch(np.Storage)
+	defer np.Exporter.Close()
+
+	createTask := func(networkID, taskID string, lifecycle string, state models.NetworkProbeData) {
+		_, err := configurator.CreateEntity(
+			networkID,
+			configurator.NetworkEntity{
+				Type: lte.NetworkProbeTaskEntityType,
+				Key:  taskID,
+				Config: &models.NetworkProbeTaskDetails{
+					TargetID:      "IMSI001010000000001",
+					TargetType:    models.NetworkProbeTaskDetailsTargetTypeImsi,
+					CorrelationID: 7,
+					DeliveryType:  models.NetworkProbeTaskDetailsDeliveryTypeAll,
+				},
+			},
+			serdes.Entity,
+		)
+		assert.NoError(t, err)
+		assert.NoError(t, np.Storage.StoreTaskLifecycle(networkID, taskID, models.NetworkProbeTaskLifecycle{State: lifecycle}))
+		assert.NoError(t, np.Storage.StoreNProbeData(networkID, taskID, state))
+	}
+	activeID, suspendedID, idleID, killedID := "a1b2c3d4-0001-4c5d-9e6f-7a8b9c0d1e2f", "a1b2c3d4-0002-4c5d-9e6f-7a8b9c0d1e2f", "a1b2c3d4-0003-4c5d-9e6f-7a8b9c0d1e2f", "a1b2c3d4-0004-4c5d-9e6f-7a8b9c0d1e2f"
+	intercepting := models.NetworkProbeData{TargetID: "IMSI001010000000001", SequenceNumber: 3, RecordsExported: 3, OpenSessions: []string{"s1"}}
+	// the HI2 stream of the intercepting task is ended
+	createTask("n0", activeID, models.NetworkProbeTaskLifecycleStateActive, intercepting)
+	// the ones of the tasks not intercepting, or which didn't deliver any
+	// record, are not
+	createTask("n0", suspendedID, models.NetworkProbeTaskLifecycleStateSuspended, intercepting)
+	createTask("n0", idleID, models.NetworkProbeTaskLifecycleStateActive, models.NetworkProbeData{TargetID: "IMSI001010000000001"})
+	// nor the ones of the networks whose kill switch ended them already
+	createTask("n1", killedID, models.NetworkProbeTaskLifecycleStateActive, intercepting)
+	_, err := np.KillSwitch.Activate("n1", "admin", "test")
+	assert.NoError(t, err)
+
+	np.EndInterception(context.Background())
+	assert.Equal(t, []uint32{3}, backend.getSequenceNumbers(t))
+	assert.Equal(t, encoding.RecordClassEnd, encoding.GetRecordClass(backend.records[0]))
+	state, err := np.Storage.GetNProbeData("n0", activeID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(4), state.SequenceNumber)
+	assert.Equal(t, uint64(4), state.RecordsExported)
+	assert.Empty(t, state.OpenSessions)
+	assert.True(t, time.Time(state.SuspendedAt).IsZero())
+	for _, taskID := range []string{suspendedID, idleID} {
+		state, err = np.Storage.GetNProbeData("n0", taskID)
+		assert.NoError(t, err)
+		assert.Equal(t, strfmt.DateTime{}, state.SuspendedAt)
+	}
+
+	// nothing is ended once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	np.EndInterception(ctx)
+	assert.Len(t, backend.getSequenceNumbers(t), 1)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/signing"
+)
+
+// applySigningConfig loads the key signing the records when signing is
+// enabled. The key is loaded again on each reload, e.g. once rotated, while
+// the former key is kept when disabled so that it can still be served to
+// verify the records it signed.
+func (np *NProbeManager) applySigningConfig(config nprobe.Config) error {
+	switch config.RecordSigning {
+	case signing.ModeDisabled:
+		return nil
+	case signing.ModeEmbedded:
+	case signing.ModeDetached:
+		if !config.DeliveryAudit {
+			return errors.New("detached record signing requires delivery_audit")
+		}
+	default:
+		return fmt.Errorf("unsupported record signing %s", config.RecordSigning)
+	}
+	key, err := signing.LoadKey(config.RecordSigningKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load record signing key: %v", err)
+	}
+
+	np.signerMutex.Lock()
+	defer np.signerMutex.Unlock()
+	if np.signer == nil {
+		np.signer, err = signing.NewSigner(key)
+		return err
+	}
+	return np.signer.SetKey(key)
+}
+
+// GetSigner returns the signer of the records, nil if records were never
+// signed since the service started
+func (np *NProbeManager) GetSigner() *signing.Signer {
+	np.signerMutex.Lock()
+	defer np.signerMutex.Unlock()
+	return np.signer
+}
+
+// prepareRecord verifies an encoded record of a task before it is exported
+// and returns the record to deliver, flagged when built with an unreliable
+// clock, in the national variant of its delivery country when enabled, its
+// payload encrypted when its destination requires it, and signed when
+// signatures are embedded in the records
+func (np *NProbeManager) prepareRecord(networkID string, task *models.NetworkProbeTask, record []byte) ([]byte, error) {
+	if err := np.validateRecord(networkID, task, record); err != nil {
+		return nil, err
+	}
+	record, err := markTimestampQuality(networkID, record)
+	if err != nil {
+		return nil, err
+	}
+	record, err = np.transformRecord(networkID, task, record)
+	if err != nil {
+		return nil, err
+	}
+	record, err = np.encryptRecord(networkID, task, record)
+	if err != nil {
+		return nil, err
+	}
+	if np.RecordSigning != signing.ModeEmbedded {
+		return record, nil
+	}
+	signed, err := encoding.SignRecord(record, np.GetSigner())
+	if err != nil {
+		metrics.SigningFailures.WithLabelValues(networkID).Inc()
+		return nil, fmt.Errorf("failed to sign record: %v", err)
+	}
+	return signed, nil
+}
+
+// signDeliveryRecords signs the hashes of delivered records when signatures
+// are kept apart in the audit trail. Like the audit trail, failing to sign
+// them doesn't fail the task, the records being left unsigned.
+func (np *NProbeManager) signDeliveryRecords(networkID, taskID string, records []models.NetworkProbeDeliveryRecord) {
+	if np.RecordSigning != signing.ModeDetached {
+		return
+	}
+	signer := np.GetSigner()
+	for i := range records {
+		digest, err := hex.DecodeString(records[i].RecordHash)
+		if err == nil {
+			var signature []byte
+			signature, records[i].SigningKeyID, err = signer.SignDigest(digest)
+			records[i].Signature = hex.EncodeToString(signature)
+		}
+		if err != nil {
+			metrics.SigningFailures.WithLabelValues(networkID).Inc()
+			logger.Errorf("Failed to sign delivered record %d of task %s: %v", records[i].SequenceNumber, taskID, err)
+		}
+	}
+}
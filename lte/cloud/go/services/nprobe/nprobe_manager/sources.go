@@ -0,0 +1,65 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"errors"
+
+	"magma/lte/cloud/go/services/nprobe/eventsource"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// fetchFromSources returns the events of a query from the event sources.
+// The events of several sources are merged in chronological order, the
+// offset of the query then spanning the merged events.
+func (np *NProbeManager) fetchFromSources(ctx context.Context, query eventsource.Query) ([]eventdM.Event, error) {
+	switch len(np.Sources) {
+	case 0:
+		return nil, errors.New("no event source")
+	case 1:
+		return fetchFromSource(ctx, np.Sources[0], query)
+	}
+
+	merged := query
+	merged.From, merged.Size = 0, query.From+query.Size
+	pages := make([][]eventdM.Event, 0, len(np.Sources))
+	for _, source := range np.Sources {
+		events, err := fetchFromSource(ctx, source, merged)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, events)
+	}
+	events := eventsource.Merge(pages, merged.Size)
+	if len(events) <= query.From {
+		return nil, nil
+	}
+	events = events[query.From:]
+	if len(events) > query.Size {
+		events = events[:query.Size]
+	}
+	return events, nil
+}
+
+// fetchFromSource returns the events of a query from an event source
+func fetchFromSource(ctx context.Context, source eventsource.EventSource, query eventsource.Query) ([]eventdM.Event, error) {
+	events, err := source.Fetch(ctx, query)
+	if err != nil {
+		metrics.SourceFetchFailures.WithLabelValues(source.Name()).Inc()
+		return nil, err
+	}
+	return events, nil
+}
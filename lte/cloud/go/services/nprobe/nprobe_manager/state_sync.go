@@ -0,0 +1,287 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/orc8r/cloud/go/services/state"
+	state_types "magma/orc8r/cloud/go/services/state/types"
+	"magma/orc8r/lib/go/protos"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// With StateDeltaFetch, the subscriber and MME states of the IMSI targets of
+// a network are synced once per pass rather than looked up by each of its
+// tasks: the versions of the states are fetched without their values, and
+// only the states whose version changed since the last sync, or which
+// weren't synced yet, are loaded. The fields the enrichments derive from the
+// states are stored along with their versions as the state cursor of the
+// network, so that the instance taking over after a restart or a failover
+// carries on from them. All the states are loaded again every
+// StateResyncInterval, and when the cursor can't be read. The tasks of a
+// network whose states can't be synced, and of the targets which aren't
+// synced, e.g. MSISDN targets, look their states up as before.
+
+// syncedStates are the fields derived from the states of the targets of a
+// network synced by the current pass. The IMSIs without state are synced as
+// well, without fields.
+type syncedStates struct {
+	// sessions are the bearer contexts of the sessions of each IMSI, by
+	// session ID
+	sessions map[string]map[string]map[string]string
+	// locations are the location fields of the UE context of each IMSI
+	locations map[string]map[string]string
+}
+
+// syncStates syncs the states of the targets of the tasks of a network
+// listed by a pass
+func (np *NProbeManager) syncStates(ctx context.Context, networkID string, tasks map[string]*models.NetworkProbeTask, now time.Time) {
+	types := np.getSyncedStateTypes()
+	if !np.StateDeltaFetch || len(types) == 0 {
+		np.setSyncedStates(networkID, nil)
+		return
+	}
+	imsis := np.getTargetIMSIs(networkID, tasks)
+
+	cursor, err := np.Storage.GetStateCursor(networkID)
+	if err != nil {
+		logger.Errorf("Failed to get state cursor of network %s, resyncing its states: %v", networkID, err)
+		cursor = &models.NetworkProbeStateCursor{}
+	}
+	resync := now.Sub(time.Time(cursor.ResyncedAt)) >= np.StateResyncInterval
+	previous := cursor.States
+	if resync {
+		previous = nil
+	}
+	states, err := fetchStates(ctx, networkID, types, imsis, previous)
+	if err != nil {
+		logger.Errorf("Failed to sync states of network %s, looking them up per task: %v", networkID, redact.Error(err))
+		metrics.StateSyncFailures.WithLabelValues(networkID).Inc()
+		np.setSyncedStates(networkID, nil)
+		return
+	}
+	if resync {
+		metrics.StateResyncs.WithLabelValues(networkID).Inc()
+		cursor.ResyncedAt = strfmt.DateTime(now)
+	}
+	cursor.SyncedAt = strfmt.DateTime(now)
+	cursor.States = states
+	if err := np.Storage.StoreStateCursor(networkID, *cursor); err != nil {
+		// the states are synced all the same, the next sync loading again
+		// the states changed since the cursor last stored
+		logger.Errorf("Failed to store state cursor of network %s: %v", networkID, err)
+	}
+	np.setSyncedStates(networkID, newSyncedStates(types, imsis, states))
+}
+
+// getSyncedStateTypes returns the types of the states the enrichments and
+// active bearer reports enabled look up
+func (np *NProbeManager) getSyncedStateTypes() []string {
+	var types []string
+	if np.BearerEnrichment || np.ActiveBearerReports {
+		types = append(types, lte.SubscriberStateType)
+	}
+	if np.LocationEnrichment {
+		types = append(types, lte.MMEStateType)
+	}
+	return types
+}
+
+// getTargetIMSIs returns the IMSIs the tasks of a network target, directly
+// or through the IMSI last seen with their IMEI, prefixed as the states are
+// keyed and sorted
+func (np *NProbeManager) getTargetIMSIs(networkID string, tasks map[string]*models.NetworkProbeTask) []string {
+	unique := map[string]bool{}
+	np.bindingMutex.Lock()
+	for taskID, task := range tasks {
+		if task.TaskDetails == nil {
+			continue
+		}
+		var imsi string
+		switch task.TaskDetails.TargetType {
+		case models.NetworkProbeTaskDetailsTargetTypeImsi:
+			imsi = task.TaskDetails.TargetID
+		case models.NetworkProbeTaskDetailsTargetTypeImei:
+			imsi = np.imeiBindings[getBackoffKey(networkID, taskID)]
+		}
+		if len(normalizeIMSI(imsi)) != 0 {
+			unique[imsiPrefix+normalizeIMSI(imsi)] = true
+		}
+	}
+	np.bindingMutex.Unlock()
+	imsis := make([]string, 0, len(unique))
+	for imsi := range unique {
+		imsis = append(imsis, imsi)
+	}
+	sort.Strings(imsis)
+	return imsis
+}
+
+// fetchStates fetches the versions of the states of some types of the IMSIs
+// of a network, and loads the states which changed since synced. It returns
+// the states of the IMSIs currently reported, sorted.
+func fetchStates(
+	ctx context.Context,
+	networkID string,
+	types []string,
+	imsis []string,
+	previous []*models.NetworkProbeSyncedState,
+) ([]*models.NetworkProbeSyncedState, error) {
+	// an empty filter would select the states of all the devices
+	if len(imsis) == 0 {
+		return nil, nil
+	}
+	client, err := state.GetStateClient()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.GetStates(ctx, &protos.GetStatesRequest{
+		NetworkID:  networkID,
+		TypeFilter: types,
+		IdFilter:   imsis,
+	})
+	if err != nil {
+		return nil, err
+	}
+	states, changed := diffStates(previous, res.States)
+	loaded, err := state.GetStates(ctx, networkID, changed, serdes.State)
+	if err != nil {
+		return nil, err
+	}
+	metrics.StatesFetched.WithLabelValues(networkID).Add(float64(len(loaded)))
+	for _, id := range changed {
+		// the states deleted since their version was fetched are left out
+		if st, ok := loaded[id]; ok {
+			states = append(states, makeSyncedState(id, st))
+		}
+	}
+	sortSyncedStates(states)
+	return states, nil
+}
+
+// diffStates compares the versions of the states currently reported with
+// the states synced. It returns the synced states whose version is current
+// and the IDs of the states which changed or weren't synced. The synced
+// states no longer reported are dropped.
+func diffStates(previous []*models.NetworkProbeSyncedState, current []*protos.State) ([]*models.NetworkProbeSyncedState, state_types.IDs) {
+	synced := map[state_types.ID]*models.NetworkProbeSyncedState{}
+	for _, st := range previous {
+		synced[state_types.ID{Type: st.Type, DeviceID: st.DeviceID}] = st
+	}
+	var kept []*models.NetworkProbeSyncedState
+	var changed state_types.IDs
+	for _, st := range current {
+		id := state_types.ID{Type: st.Type, DeviceID: st.DeviceID}
+		if prev, ok := synced[id]; ok && prev.Version == st.Version {
+			kept = append(kept, prev)
+			continue
+		}
+		changed = append(changed, id)
+	}
+	return kept, changed
+}
+
+// makeSyncedState derives the fields the enrichments use from a state
+func makeSyncedState(id state_types.ID, st state_types.State) *models.NetworkProbeSyncedState {
+	synced := &models.NetworkProbeSyncedState{Type: id.Type, DeviceID: id.DeviceID, Version: st.Version}
+	reported, ok := st.ReportedState.(*state.ArbitraryJSON)
+	if !ok {
+		return synced
+	}
+	switch id.Type {
+	case lte.SubscriberStateType:
+		synced.Sessions = getBearerContexts(*reported)
+	case lte.MMEStateType:
+		synced.Location = getUEContextLocation(*reported)
+	}
+	return synced
+}
+
+func sortSyncedStates(states []*models.NetworkProbeSyncedState) {
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Type != states[j].Type {
+			return states[i].Type < states[j].Type
+		}
+		return states[i].DeviceID < states[j].DeviceID
+	})
+}
+
+// newSyncedStates indexes the states synced for some IMSIs, the IMSIs
+// without a state of a type being synced without its fields
+func newSyncedStates(types []string, imsis []string, states []*models.NetworkProbeSyncedState) *syncedStates {
+	synced := &syncedStates{}
+	for _, typ := range types {
+		switch typ {
+		case lte.SubscriberStateType:
+			synced.sessions = make(map[string]map[string]map[string]string, len(imsis))
+			for _, imsi := range imsis {
+				synced.sessions[imsi] = nil
+			}
+		case lte.MMEStateType:
+			synced.locations = make(map[string]map[string]string, len(imsis))
+			for _, imsi := range imsis {
+				synced.locations[imsi] = nil
+			}
+		}
+	}
+	for _, st := range states {
+		switch {
+		case st.Type == lte.SubscriberStateType && synced.sessions != nil:
+			synced.sessions[st.DeviceID] = st.Sessions
+		case st.Type == lte.MMEStateType && synced.locations != nil:
+			synced.locations[st.DeviceID] = st.Location
+		}
+	}
+	return synced
+}
+
+// getSyncedStates returns the states of the targets of a network synced by
+// the current pass, nil if they weren't
+func (np *NProbeManager) getSyncedStates(networkID string) *syncedStates {
+	np.syncedStateMutex.RLock()
+	defer np.syncedStateMutex.RUnlock()
+	return np.syncedStates[networkID]
+}
+
+// setSyncedStates sets the states of the targets of a network synced by the
+// current pass, forgetting them when nil
+func (np *NProbeManager) setSyncedStates(networkID string, synced *syncedStates) {
+	np.syncedStateMutex.Lock()
+	defer np.syncedStateMutex.Unlock()
+	if synced == nil {
+		delete(np.syncedStates, networkID)
+		return
+	}
+	if np.syncedStates == nil {
+		np.syncedStates = map[string]*syncedStates{}
+	}
+	np.syncedStates[networkID] = synced
+}
+
+// clearSyncedStates forgets the states synced, e.g. once delta fetch is
+// disabled
+func (np *NProbeManager) clearSyncedStates() {
+	np.syncedStateMutex.Lock()
+	defer np.syncedStateMutex.Unlock()
+	np.syncedStates = map[string]*syncedStates{}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	state_types "magma/orc8r/cloud/go/services/state/types"
+	"magma/orc8r/lib/go/protos"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffStates(t *testing.T) {
+	unchanged := &models.NetworkProbeSyncedState{Type: lte.SubscriberStateType, DeviceID: "IMSI001010000000001", Version: 3}
+	updated := &models.NetworkProbeSyncedState{Type: lte.MMEStateType, DeviceID: "IMSI001010000000001", Version: 1}
+	deleted := &models.NetworkProbeSyncedState{Type: lte.SubscriberStateType, DeviceID: "IMSI001010000000002", Version: 7}
+	current := []*protos.State{
+		{Type: lte.SubscriberStateType, DeviceID: "IMSI001010000000001", Version: 3},
+		{Type: lte.MMEStateType, DeviceID: "IMSI001010000000001", Version: 2},
+		{Type: lte.SubscriberStateType, DeviceID: "IMSI001010000000003", Version: 1},
+	}
+
+	// only the states changed or not synced yet are loaded
+	kept, changed := diffStates([]*models.NetworkProbeSyncedState{unchanged, updated, deleted}, current)
+	assert.Equal(t, []*models.NetworkProbeSyncedState{unchanged}, kept)
+	assert.Equal(t, state_types.IDs{
+		{Type: lte.MMEStateType, DeviceID: "IMSI001010000000001"},
+		{Type: lte.SubscriberStateType, DeviceID: "IMSI001010000000003"},
+	}, changed)
+
+	// all of them are loaded when resyncing
+	kept, changed = diffStates(nil, current)
+	assert.Empty(t, kept)
+	assert.Len(t, changed, 3)
+}
+
+func TestSyncedStates(t *testing.T) {
+	np := &NProbeManager{BearerEnrichment: true}
+	assert.Equal(t, []string{lte.SubscriberStateType}, np.getSyncedStateTypes())
+	np.LocationEnrichment = true
+	types := np.getSyncedStateTypes()
+	assert.Equal(t, []string{lte.SubscriberStateType, lte.MMEStateType}, types)
+
+	np.imeiBindings = map[string]string{getBackoffKey("n1", "task3"): "001010000000003"}
+	tasks := map[string]*models.NetworkProbeTask{
+		"task1": {TaskDetails: &models.NetworkProbeTaskDetails{TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi, TargetID: "IMSI001010000000001"}},
+		"task2": {TaskDetails: &models.NetworkProbeTaskDetails{TargetType: models.NetworkProbeTaskDetailsTargetTypeImsi, TargetID: "001010000000001"}},
+		"task3": {TaskDetails: &models.NetworkProbeTaskDetails{TargetType: models.NetworkProbeTaskDetailsTargetTypeImei, TargetID: "123456789012345"}},
+		"task4": {TaskDetails: &models.NetworkProbeTaskDetails{TargetType: models.NetworkProbeTaskDetailsTargetTypeMsisdn, TargetID: "+33612345678"}},
+	}
+	imsis := np.getTargetIMSIs("n1", tasks)
+	assert.Equal(t, []string{"IMSI001010000000001", "IMSI001010000000003"}, imsis)
+
+	sessions := map[string]map[string]string{"session1": {"apn": "internet"}}
+	location := map[string]string{"tai": "00f1100001"}
+	synced := newSyncedStates(types, imsis, []*models.NetworkProbeSyncedState{
+		{Type: lte.SubscriberStateType, DeviceID: "IMSI001010000000001", Sessions: sessions},
+		{Type: lte.MMEStateType, DeviceID: "IMSI001010000000003", Location: location},
+	})
+	np.setSyncedStates("n1", synced)
+
+	// the IMSIs without state are synced without fields, the others are
+	// looked up
+	enricher := newBearerEnricher("n1", np.getSyncedStates("n1"))
+	assert.Equal(t, map[string]map[string]map[string]string{
+		"IMSI001010000000001": sessions,
+		"IMSI001010000000003": nil,
+	}, enricher.sessions)
+	locator := newLocationEnricher("n1", np.getSyncedStates("n1"))
+	assert.Equal(t, map[string]map[string]string{
+		"IMSI001010000000001": nil,
+		"IMSI001010000000003": location,
+	}, locator.locations)
+	assert.Empty(t, newLocationEnricher("n2", np.getSyncedStates("n2")).locations)
+
+	np.setSyncedStates("n1", nil)
+	assert.Nil(t, np.getSyncedStates("n1"))
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// Tasks may report the records of the events of their target delivered over
+// a fixed interval, and their bytes, as some handover agreements require.
+// The records are counted as they are delivered, from the first pass of the
+// task. Once the interval elapsed, the next pass of the task delivers an
+// IRI-REPORT numbered in its stream, marked by a statistics indication
+// carrying the counts, and counting starts over. The statistics reaper wakes
+// the processing loop when a report is due. The intervals reported are kept
+// along with the one being counted, for the operator to check them through
+// the API.
+
+// deliveredStatistics are the records delivered by a pass of a task
+type deliveredStatistics struct {
+	records uint64
+	bytes   uint64
+}
+
+// add counts a delivered record
+func (s *deliveredStatistics) add(record []byte) {
+	s.records++
+	s.bytes += uint64(len(record))
+}
+
+// newStatisticsReaper returns the reaper signaling the statistics reports
+// which are due on the channel of the warrants
+func newStatisticsReaper(warrants *warrantReaper) *warrantReaper {
+	return &warrantReaper{due: map[string]time.Time{}, ready: warrants.ready}
+}
+
+// countStatistics adds the records delivered by a pass of a task to the
+// interval being counted. Statistics are informational, failing to count
+// them doesn't fail the task.
+func (np *NProbeManager) countStatistics(networkID string, task *models.NetworkProbeTask, statistics deliveredStatistics) {
+	if task.TaskDetails.StatisticsReportIntervalSecs == 0 || statistics.records == 0 {
+		return
+	}
+	taskID := string(task.TaskID)
+	if err := np.Storage.IncrementTaskStatistics(networkID, taskID, statistics.records, statistics.bytes, clock.Now()); err != nil {
+		logger.With(logging.FieldNetworkID, networkID, logging.FieldTaskID, taskID).Errorf("Failed to count statistics of task %s: %v", taskID, err)
+	}
+}
+
+// reportStatistics delivers the statistics record of a task once its
+// interval elapsed, and schedules the next report
+func (np *NProbeManager) reportStatistics(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+) error {
+	interval := time.Duration(task.TaskDetails.StatisticsReportIntervalSecs) * time.Second
+	if interval == 0 {
+		return nil
+	}
+	taskID := string(task.TaskID)
+	statistics, err := np.Storage.GetTaskStatistics(networkID, taskID)
+	if err != nil {
+		return err
+	}
+	now := clock.Now()
+	if statistics.Current == nil {
+		np.scheduleStatisticsReport(networkID, taskID, now.Add(interval))
+		return np.Storage.IncrementTaskStatistics(networkID, taskID, 0, 0, now)
+	}
+	start := time.Time(statistics.Current.Start)
+	if due := start.Add(interval); now.Before(due) {
+		np.scheduleStatisticsReport(networkID, taskID, due)
+		return nil
+	}
+
+	statisticsLogger := logger.With(logging.FieldNetworkID, networkID, logging.FieldTaskID, taskID)
+	reported := models.NetworkProbeStatisticsInterval{
+		Start:   statistics.Current.Start,
+		End:     strfmt.DateTime(now.UTC()),
+		Records: statistics.Current.Records,
+		Bytes:   statistics.Current.Bytes,
+	}
+	seq := getNextSequenceNumber(state)
+	record, err := encoding.MakeStatisticsRecord(
+		np.getRecordTask(networkID, task, state), np.getOperatorID(networkID), seq,
+		encoding.Statistics{Start: start, End: now, Records: reported.Records, Bytes: reported.Bytes},
+		np.getModuleVersion(networkID, task),
+	)
+	if err == nil {
+		record, err = np.prepareRecord(networkID, task, record)
+	}
+	if err != nil {
+		// the statistics are reported on the next pass, the records of the
+		// task aren't held back
+		statisticsLogger.Errorf("Failed to build statistics record of task %s: %v", taskID, err)
+		return nil
+	}
+
+	exp, err := np.getExporter(networkID, task)
+	if err != nil {
+		return err
+	}
+	delivery := exp.SubmitRecords(
+		ctx,
+		getBackoffKey(networkID, taskID),
+		np.getTaskWeight(taskID),
+		task.TaskDetails.CorrelationID,
+		[][]byte{record},
+		np.MaxExportRetries,
+	)
+	if err := delivery.Wait(0); err != nil {
+		return err
+	}
+	deliveredAt := time.Now()
+	metrics.RecordsExported.WithLabelValues(networkID).Inc()
+	np.storeDeliveryRecords(networkID, taskID, []models.NetworkProbeDeliveryRecord{
+		makeDeliveryRecord(task, record, seq, now, deliveredAt),
+	})
+	statisticsLogger.Infof("Delivered statistics record of task %s with sequence number %d, reporting %d records", taskID, seq, reported.Records)
+
+	state.RecordsExported++
+	state.SequenceNumber = seq + 1
+	if err := np.storeState(networkID, taskID, state); err != nil {
+		return err
+	}
+	reported.SequenceNumber = seq
+	reported.ReportedAt = strfmt.DateTime(deliveredAt.UTC())
+	np.scheduleStatisticsReport(networkID, taskID, now.Add(interval))
+	return np.Storage.ReportTaskStatistics(networkID, taskID, reported)
+}
+
+// scheduleStatisticsReport wakes the processing loop when the statistics of
+// a task are to be reported
+func (np *NProbeManager) scheduleStatisticsReport(networkID, taskID string, at time.Time) {
+	if np.statisticsReports != nil {
+		np.statisticsReports.schedule(getBackoffKey(networkID, taskID), at, time.Now())
+	}
+}
+
+// pruneStatisticsReports drops the statistics reports of the tasks which no
+// longer exist
+func (np *NProbeManager) pruneStatisticsReports(keys map[string]bool) {
+	if np.statisticsReports != nil {
+		np.statisticsReports.retain(keys, time.Now())
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatisticsReports(t *testing.T) {
+	var statistics deliveredStatistics
+	statistics.add(make([]byte, 100))
+	statistics.add(make([]byte, 20))
+	assert.Equal(t, deliveredStatistics{records: 2, bytes: 120}, statistics)
+
+	// tasks without a statistics interval are neither counted nor reported
+	np := &NProbeManager{warrants: newWarrantReaper()}
+	task := &models.NetworkProbeTask{
+		TaskID:      "task1",
+		TaskDetails: &models.NetworkProbeTaskDetails{TargetID: "IMSI1234"},
+	}
+	np.countStatistics("n1", task, statistics)
+	assert.NoError(t, np.reportStatistics(context.Background(), "n1", task, &models.NetworkProbeData{}))
+
+	// due reports wake the processing loop on the channel of the warrants
+	np.statisticsReports = newStatisticsReaper(np.warrants)
+	np.scheduleStatisticsReport("n1", "task1", time.Now().Add(20*time.Millisecond))
+	select {
+	case <-np.WarrantDue():
+	case <-time.After(time.Second):
+		t.Fatal("statistics report of task1 not signaled")
+	}
+
+	// the reports of the tasks gone are no longer signaled
+	np.scheduleStatisticsReport("n1", "task2", time.Now().Add(20*time.Millisecond))
+	np.pruneStatisticsReports(map[string]bool{getBackoffKey("n1", "task1"): true})
+	select {
+	case <-np.WarrantDue():
+		t.Fatal("statistics report of deleted task2 signaled")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/orc8r/cloud/go/services/configurator"
+)
+
+// stateSweepInterval is the minimum time between two sweeps of the state
+// of the tasks of a network
+const stateSweepInterval = time.Hour
+
+// sweepState reclaims the state left behind in a network, at most once per
+// stateSweepInterval: the state of the tasks which no longer exist, e.g.
+// deleted while being processed, and the sessions of the targets without
+// activity for SessionIdleTimeout, whose interception is ended with an
+// IRI-END. It must not be called while tasks are processed.
+func (np *NProbeManager) sweepState(
+	ctx context.Context,
+	networkID string,
+	tasks map[string]*models.NetworkProbeTask,
+	now time.Time,
+) {
+	if now.Sub(np.stateSweptAt[networkID]) < stateSweepInterval {
+		return
+	}
+	states, err := np.Storage.GetAllNProbeData(networkID)
+	if err != nil {
+		logger.Errorf("Failed to get states of network %s to sweep: %v", networkID, err)
+		return
+	}
+
+	sweepCtx, cancel := np.KillSwitch.Context(ctx, networkID)
+	defer cancel()
+	for taskID, state := range states {
+		if sweepCtx.Err() != nil {
+			return
+		}
+		state := state
+		task, ok := tasks[taskID]
+		if !ok {
+			exists, err := configurator.DoesEntityExist(networkID, lte.NetworkProbeTaskEntityType, taskID)
+			if err != nil || exists {
+				// the task may have been created since the tasks were listed
+				continue
+			}
+			np.reclaimTaskState(sweepCtx, networkID, taskID, &state, now)
+			continue
+		}
+		if err := np.closeIdleSessions(sweepCtx, networkID, task, &state, now); err != nil {
+			logger.Errorf("Failed to end idle sessions of task %s: %v", taskID, err)
+		}
+	}
+	np.stateSweptAt[networkID] = now
+}
+
+// sweepDeletedNetworks reclaims the state left behind by the networks which
+// no longer exist, at most once per stateSweepInterval, given the networks
+// listed by the pass. It must not be called while tasks are processed.
+func (np *NProbeManager) sweepDeletedNetworks(ctx context.Context, networks []string, now time.Time) {
+	if now.Sub(np.networksSweptAt) < stateSweepInterval {
+		return
+	}
+	stored, err := np.Storage.ListNProbeNetworks()
+	if err != nil {
+		logger.Errorf("Failed to list networks to sweep: %v", err)
+		return
+	}
+	listed := make(map[string]bool, len(networks))
+	for _, networkID := range networks {
+		listed[networkID] = true
+	}
+	for _, networkID := range stored {
+		if ctx.Err() != nil {
+			return
+		}
+		if listed[networkID] {
+			continue
+		}
+		exists, err := configurator.DoesNetworkExist(networkID)
+		if err != nil || exists {
+			// the network may have been created since the networks were listed
+			continue
+		}
+		np.reclaimNetworkState(ctx, networkID, now)
+	}
+	np.networksSweptAt = now
+}
+
+// reclaimNetworkState deletes the state of the tasks of a deleted network,
+// ending their interception first, along with its delivered records, session
+// mappings and released correlation IDs, and forgets the network
+func (np *NProbeManager) reclaimNetworkState(ctx context.Context, networkID string, now time.Time) {
+	states, err := np.Storage.GetAllNProbeData(networkID)
+	if err != nil {
+		logger.Errorf("Failed to get states of deleted network %s to reclaim: %v", networkID, err)
+		return
+	}
+	logger.Warningf("Reclaiming state of %d tasks of deleted network %s", len(states), networkID)
+	// the tasks listed by the last pass are notified as deactivated first,
+	// the others when their state is reclaimed
+	np.notifyDeactivations(ctx, networkID, nil)
+	delete(np.hi1Tasks, networkID)
+	for taskID, state := range states {
+		if ctx.Err() != nil {
+			return
+		}
+		state := state
+		np.reclaimTaskState(ctx, networkID, taskID, &state, now)
+	}
+
+	deletes := []func(networkID string, cutoff time.Time) error{
+		np.Storage.DeleteDeliveryRecordsBefore,
+		np.Storage.DeleteSessionMappingsBefore,
+		np.Storage.DeleteBearerCorrelationsBefore,
+	}
+	for _, del := range deletes {
+		if err := del(networkID, now); err != nil {
+			logger.Errorf("Failed to reclaim state of deleted network %s: %v", networkID, err)
+			return
+		}
+	}
+	np.forgetBearerCorrelations(networkID)
+	np.setSyncedStates(networkID, nil)
+	delete(np.auditPrunedAt, networkID)
+	delete(np.correlationPrunedAt, networkID)
+	delete(np.reencryptedWith, networkID)
+	delete(np.stateSweptAt, networkID)
+	metrics.StateReclaimed.WithLabelValues(metrics.ReclaimedNetwork).Inc()
+}
+
+// reclaimTaskState deletes the state of a task which no longer exists. The
+// interception of its open sessions is ended with an IRI-END and its
+// deactivation notified over HI1 if it wasn't, addressed to the task as
+// known from its state. Both are best effort, the task being gone.
+func (np *NProbeManager) reclaimTaskState(
+	ctx context.Context,
+	networkID, taskID string,
+	state *models.NetworkProbeData,
+	now time.Time,
+) {
+	task := getOrphanTask(taskID, state)
+	if len(state.OpenSessions) != 0 {
+		logger.Warningf("Reclaiming state of deleted task %s with %d sessions open", taskID, len(state.OpenSessions))
+		closed, err := np.endOpenSessions(ctx, networkID, task, state, now)
+		if err != nil {
+			logger.Errorf("Failed to end sessions of deleted task %s: %v", taskID, err)
+		}
+		metrics.StateReclaimed.WithLabelValues(metrics.ReclaimedSession).Add(float64(closed))
+	}
+	if np.HI1Notifications && !time.Time(state.Hi1ActivatedAt).IsZero() && time.Time(state.Hi1DeactivatedAt).IsZero() {
+		if err := np.notifyHI1(ctx, networkID, np.getRecordTask(networkID, task, state), encoding.HI1Deactivated, ""); err != nil {
+			logger.Errorf("Failed to notify deactivation of deleted task %s: %v", taskID, err)
+		}
+	}
+
+	// the state goes last so that it is swept again if anything fails
+	deletes := []func(networkID, taskID string) error{
+		np.Storage.DeleteQuarantineEntries,
+		np.Storage.DeleteActivity,
+		np.Storage.DeleteTaskPause,
+		np.Storage.DeleteTaskLifecycle,
+		np.Storage.DeleteTaskXIDRotation,
+		np.Storage.DeleteTaskReplay,
+		np.Storage.DeleteTaskTestRecord,
+		np.Storage.DeleteTaskStatistics,
+		np.Storage.DeleteBookmarks,
+		np.Storage.DeleteDeadLetters,
+		np.Storage.DeleteTaskDeletion,
+		np.Storage.DeleteNProbeData,
+	}
+	for _, del := range deletes {
+		if err := del(networkID, taskID); err != nil {
+			logger.Errorf("Failed to reclaim state of deleted task %s: %v", taskID, err)
+			return
+		}
+	}
+	metrics.StateReclaimed.WithLabelValues(metrics.ReclaimedTaskState).Inc()
+}
+
+// getOrphanTask returns a task which no longer exists as known from its
+// state, enough to address the records ending its interception: its XID and
+// target, of the type of an IMSI or an IMSI range if it is one
+func getOrphanTask(taskID string, state *models.NetworkProbeData) *models.NetworkProbeTask {
+	details := &models.NetworkProbeTaskDetails{TargetID: state.TargetID}
+	if _, err := models.ParseIMSIRange(state.TargetID); err == nil {
+		details.TargetType = models.NetworkProbeTaskDetailsTargetTypeImsiRange
+	} else if strings.HasPrefix(state.TargetID, "IMSI") {
+		details.TargetType = models.NetworkProbeTaskDetailsTargetTypeImsi
+	}
+	return &models.NetworkProbeTask{TaskID: models.NetworkProbeTaskID(taskID), TaskDetails: details}
+}
+
+// closeIdleSessions ends the interception of the open sessions of a task
+// whose target had no activity for SessionIdleTimeout, e.g. sessions whose
+// termination was never reported, delivering an IRI-END for each of them
+func (np *NProbeManager) closeIdleSessions(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	now time.Time,
+) error {
+	taskID := string(task.TaskID)
+	key := getBackoffKey(networkID, taskID)
+	np.restoreCursor(key, state)
+	if !isSessionSweepDue(state, now, np.SessionIdleTimeout) {
+		return nil
+	}
+	// paused tasks generate no record until resumed
+	pause, err := np.Storage.GetTaskPause(networkID, taskID)
+	if err != nil {
+		return err
+	}
+	if pause.Paused {
+		return nil
+	}
+
+	closed, derr := np.endOpenSessions(ctx, networkID, task, state, now)
+	if closed == 0 {
+		return derr
+	}
+	logger.Infof("Ended interception of %d idle sessions of task %s", closed, taskID)
+	metrics.StateReclaimed.WithLabelValues(metrics.ReclaimedSession).Add(float64(closed))
+	if err := np.storeState(networkID, taskID, state); err != nil {
+		return err
+	}
+	return derr
+}
+
+// endOpenSessions delivers an IRI-END for each open session of a task, in
+// order, and removes the sessions ended from its state. It returns the
+// number of sessions ended along with the error of the first delivery
+// failing.
+func (np *NProbeManager) endOpenSessions(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	now time.Time,
+) (int, error) {
+	taskID := string(task.TaskID)
+	version := np.getModuleVersion(networkID, task)
+	operatorID := np.getOperatorID(networkID)
+	recordTask := np.getRecordTask(networkID, task, state)
+	// records reserved and not delivered yet are generated again with new
+	// numbers, as after the terminal record of a task
+	seq := getNextSequenceNumber(state)
+	records := make([][]byte, 0, len(state.OpenSessions))
+	for i, sessionID := range state.OpenSessions {
+		record, err := encoding.MakeSessionEndRecord(recordTask, operatorID, seq+uint32(i), sessionID, now, version)
+		if err == nil {
+			record, err = np.prepareRecord(networkID, task, record)
+		}
+		if err != nil {
+			return 0, err
+		}
+		records = append(records, record)
+	}
+
+	exp, err := np.getExporter(networkID, task)
+	if err != nil {
+		return 0, err
+	}
+	delivery := exp.SubmitRecords(ctx, getBackoffKey(networkID, taskID), np.getTaskWeight(taskID), task.TaskDetails.CorrelationID, records, np.MaxExportRetries)
+	var delivered []models.NetworkProbeDeliveryRecord
+	var derr error
+	closed := 0
+	for i := range records {
+		if derr = delivery.Wait(i); derr != nil {
+			break
+		}
+		closed++
+		metrics.RecordsExported.WithLabelValues(networkID).Inc()
+		if np.DeliveryAudit {
+			delivered = append(delivered, makeDeliveryRecord(task, records[i], seq+uint32(i), now, time.Now()))
+		}
+	}
+	np.storeDeliveryRecords(networkID, taskID, delivered)
+
+	if closed != 0 {
+		state.SequenceNumber = seq + uint32(closed)
+	}
+	state.RecordsExported += uint64(closed)
+	state.OpenSessions = state.OpenSessions[closed:]
+	if len(state.OpenSessions) == 0 {
+		state.OpenSessions = nil
+	}
+	return closed, derr
+}
+
+// isSessionSweepDue returns true if the open sessions of a task are idle:
+// its target had no activity for timeout and none of its records is pending.
+// The sessions of suspended, completed and expired tasks are ended otherwise.
+func isSessionSweepDue(state *models.NetworkProbeData, now time.Time, timeout time.Duration) bool {
+	return timeout != 0 &&
+		len(state.OpenSessions) != 0 &&
+		getNextSequenceNumber(state) == state.SequenceNumber &&
+		time.Time(state.SuspendedAt).IsZero() &&
+		time.Time(state.CompletedAt).IsZero() &&
+		time.Time(state.ExpiredAt).IsZero() &&
+		now.Sub(time.Time(state.LastExported)) >= timeout
+}
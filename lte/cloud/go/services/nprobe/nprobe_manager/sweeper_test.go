@@ -0,0 +1,111 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/orc8r/cloud/go/test_utils"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionSweepDue(t *testing.T) {
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	timeout := 24 * time.Hour
+	idle := func() *models.NetworkProbeData {
+		return &models.NetworkProbeData{
+			LastExported:   strfmt.DateTime(now.Add(-25 * time.Hour)),
+			OpenSessions:   []string{"s1", "s2"},
+			SequenceNumber: 4,
+		}
+	}
+	assert.True(t, isSessionSweepDue(idle(), now, timeout))
+
+	// the target was active recently
+	state := idle()
+	state.LastExported = strfmt.DateTime(now.Add(-time.Hour))
+	assert.False(t, isSessionSweepDue(state, now, timeout))
+
+	// no session is open
+	state = idle()
+	state.OpenSessions = nil
+	assert.False(t, isSessionSweepDue(state, now, timeout))
+
+	// records of the task may still be delivered
+	state = idle()
+	state.ReservedRecords = []*models.NetworkProbeReservedRecord{{EventID: "e1", SequenceNumber: 4}}
+	assert.False(t, isSessionSweepDue(state, now, timeout))
+
+	// the terminal record of a suspended task ends all its sessions
+	state = idle()
+	state.SuspendedAt = strfmt.DateTime(now.Add(-time.Hour))
+	assert.False(t, isSessionSweepDue(state, now, timeout))
+
+	// sweeping is disabled
+	assert.False(t, isSessionSweepDue(idle(), now, 0))
+}
+
+func TestReclaimTaskState(t *testing.T) {
+	backend := &pduBackend{}
+	np := &NProbeManager{
+		Exporter:         exporter.NewRecordExporter(backend),
+		MaxExportRetries: 1,
+		HI1Notifications: true,
+		Storage:          storage.NewNProbeBlobstore(test_utils.NewSQLBlobstore(t, "sweeper_test_blobstore")),
+	}
+	defer np.Exporter.Close()
+	now := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	taskID := "29f28e1c-f230-486a-a860-f5a784ab9177"
+	state := models.NetworkProbeData{
+		TargetID:       "IMSI001010000000001",
+		Hi1ActivatedAt: strfmt.DateTime(now.Add(-time.Hour)),
+		OpenSessions:   []string{"s1"},
+		SequenceNumber: 4,
+	}
+	assert.NoError(t, np.Storage.StoreNProbeData("n0", taskID, state))
+
+	// the task is addressed as known from its state
+	task := getOrphanTask(taskID, &state)
+	assert.Equal(t, models.NetworkProbeTaskID(taskID), task.TaskID)
+	assert.Equal(t, "IMSI001010000000001", task.TaskDetails.TargetID)
+	assert.Equal(t, models.NetworkProbeTaskDetailsTargetTypeImsi, task.TaskDetails.TargetType)
+	rangeTask := getOrphanTask(taskID, &models.NetworkProbeData{TargetID: "IMSI99999000*"})
+	assert.Equal(t, models.NetworkProbeTaskDetailsTargetTypeImsiRange, rangeTask.TaskDetails.TargetType)
+
+	// the interception of the open sessions is ended and the deactivation
+	// notified before the state is deleted
+	np.reclaimTaskState(context.Background(), "n0", taskID, &state, now)
+	assert.Equal(t, []string{"", encoding.HI1Deactivated}, backend.getOperations())
+	assert.Empty(t, state.OpenSessions)
+	_, err := np.Storage.GetNProbeData("n0", taskID)
+	assert.Error(t, err)
+
+	// the deactivation already notified isn't again
+	state = models.NetworkProbeData{
+		TargetID:         "IMSI001010000000001",
+		Hi1ActivatedAt:   strfmt.DateTime(now.Add(-time.Hour)),
+		Hi1DeactivatedAt: strfmt.DateTime(now.Add(-time.Minute)),
+	}
+	assert.NoError(t, np.Storage.StoreNProbeData("n0", taskID, state))
+	np.reclaimTaskState(context.Background(), "n0", taskID, &state, now)
+	assert.Len(t, backend.getOperations(), 2)
+}
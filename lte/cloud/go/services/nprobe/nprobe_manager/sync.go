@@ -0,0 +1,107 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/tracing"
+)
+
+// recordDelivery reports the delivery result of each record of a pass
+type recordDelivery interface {
+	Wait(i int) error
+}
+
+// syncDelivery submits the records of a pass one at a time, each record
+// being submitted once the previous one was delivered and persisted
+type syncDelivery struct {
+	ctx           context.Context
+	exporter      *exporter.RecordExporter
+	taskKey       string
+	weight        uint32
+	correlationID uint64
+	records       [][]byte
+	retryCount    uint32
+}
+
+// Wait submits the i-th record and waits for its delivery, records must
+// be waited for in order. Records are never dropped by a full export queue,
+// they fail to be submitted again on the next pass instead.
+func (d *syncDelivery) Wait(i int) error {
+	ctx := tracing.WithRecordSpans(d.ctx, tracing.GetRecordSpan(d.ctx, i))
+	delivery := d.exporter.SubmitRecords(ctx, d.taskKey, d.weight, d.correlationID, d.records[i:i+1], d.retryCount)
+	err := delivery.Wait(0)
+	if err == exporter.ErrRecordDropped {
+		return exporter.ErrThrottled
+	}
+	return err
+}
+
+// addSynchronousExport enables synchronous export for the tasks of the
+// network and delivery type of a destination requesting it
+func addSynchronousExport(syncExports map[string]map[string]bool, networkID string, destination *models.NetworkProbeDestination) {
+	details := destination.DestinationDetails
+	if !details.SynchronousExport {
+		return
+	}
+	if syncExports[networkID] == nil {
+		syncExports[networkID] = map[string]bool{}
+	}
+	syncExports[networkID][details.DeliveryType] = true
+}
+
+// setSynchronousExports replaces the tasks exporting synchronously
+func (np *NProbeManager) setSynchronousExports(syncExports map[string]map[string]bool) {
+	np.syncExportMutex.Lock()
+	defer np.syncExportMutex.Unlock()
+	np.syncExports = syncExports
+}
+
+// isSynchronousExport returns true if a destination of the task requires
+// each of its records to be delivered before its cursor advances
+func (np *NProbeManager) isSynchronousExport(networkID string, task *models.NetworkProbeTask) bool {
+	np.syncExportMutex.RLock()
+	defer np.syncExportMutex.RUnlock()
+	return np.syncExports[networkID][task.TaskDetails.DeliveryType]
+}
+
+// submitRecords submits the records of a pass of a task. The records are
+// submitted at once so that they are fairly scheduled with the records of
+// the other tasks, or one at a time with synchronous export.
+func (np *NProbeManager) submitRecords(
+	ctx context.Context,
+	exp *exporter.RecordExporter,
+	networkID string,
+	task *models.NetworkProbeTask,
+	records [][]byte,
+	synchronous bool,
+) recordDelivery {
+	taskKey := getBackoffKey(networkID, string(task.TaskID))
+	weight := np.getTaskWeight(string(task.TaskID))
+	if synchronous {
+		return &syncDelivery{
+			ctx:           ctx,
+			exporter:      exp,
+			taskKey:       taskKey,
+			weight:        weight,
+			correlationID: task.TaskDetails.CorrelationID,
+			records:       records,
+			retryCount:    np.MaxExportRetries,
+		}
+	}
+	return exp.SubmitRecords(ctx, taskKey, weight, task.TaskDetails.CorrelationID, records, np.MaxExportRetries)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingBackend counts the records sent
+type countingBackend struct {
+	mutex sync.Mutex
+	sent  int
+}
+
+func (b *countingBackend) Send(record []byte, correlationID uint64) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.sent++
+	return nil
+}
+
+func (b *countingBackend) IsConnected() bool { return true }
+
+func (b *countingBackend) Close() {}
+
+func (b *countingBackend) getSent() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.sent
+}
+
+func TestSynchronousExport(t *testing.T) {
+	backend := &countingBackend{}
+	np := &NProbeManager{Exporter: exporter.NewRecordExporter(backend), MaxExportRetries: 1}
+	defer np.Exporter.Close()
+
+	task := &models.NetworkProbeTask{
+		TaskID:      "task1",
+		TaskDetails: &models.NetworkProbeTaskDetails{DeliveryType: models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly},
+	}
+	newDestination := func(deliveryType string, synchronous bool) *models.NetworkProbeDestination {
+		return &models.NetworkProbeDestination{
+			DestinationID:      "dest1",
+			DestinationDetails: &models.NetworkProbeDestinationDetails{DeliveryType: deliveryType, SynchronousExport: synchronous},
+		}
+	}
+	syncExports := map[string]map[string]bool{}
+	addSynchronousExport(syncExports, "n0", newDestination(models.NetworkProbeDestinationDetailsDeliveryTypeAll, true))
+	addSynchronousExport(syncExports, "n1", newDestination(models.NetworkProbeDestinationDetailsDeliveryTypeEventsOnly, false))
+	addSynchronousExport(syncExports, "n2", newDestination(models.NetworkProbeDestinationDetailsDeliveryTypeEventsOnly, true))
+	np.setSynchronousExports(syncExports)
+	assert.False(t, np.isSynchronousExport("n0", task))
+	assert.False(t, np.isSynchronousExport("n1", task))
+	assert.True(t, np.isSynchronousExport("n2", task))
+
+	// each record is only submitted once the previous one is waited for
+	records := [][]byte{{1}, {2}, {3}}
+	delivery := np.submitRecords(context.Background(), np.Exporter, "n2", task, records, true)
+	assert.Equal(t, 0, backend.getSent())
+	assert.NoError(t, delivery.Wait(0))
+	assert.Equal(t, 1, backend.getSent())
+	assert.NoError(t, delivery.Wait(1))
+	assert.NoError(t, delivery.Wait(2))
+	assert.Equal(t, 3, backend.getSent())
+}
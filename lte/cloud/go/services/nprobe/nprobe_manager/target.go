@@ -0,0 +1,239 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/lte/cloud/go/services/subscriberdb"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+	merrors "magma/orc8r/lib/go/errors"
+)
+
+const imsiPrefix = "IMSI"
+
+// targetMatcher selects the events concerning the target of a task. Events
+// are tagged with the IMSI of the subscriber, so IMSI targets are matched
+// through their tags, MSISDN targets through the IMSI they are assigned to,
+// and IMEI and IMSI range targets on the event data of all events of the
+// network. The events of the protected identities of the network are never
+// matched, whatever the target.
+type targetMatcher struct {
+	targetType string
+	targetID   string
+	// imsiRange holds the IMSIs of an IMSI range target
+	imsiRange *models.IMSIRange
+	// tags restricts the events fetched, all events are fetched when empty
+	tags []string
+	// imsi is the IMSI the MSISDN and IMSI targets resolve to
+	imsi string
+	// boundIMSI is the IMSI last seen with an IMEI target
+	boundIMSI string
+	// protected holds the protected identities of the network
+	protected []*models.NetworkProbeProtectedIdentity
+}
+
+// getTargetMatcher returns the matcher of the events of a task target. A nil
+// matcher is returned when no event can currently match, e.g. when a MSISDN
+// target is not assigned to any subscriber. An error is returned when the
+// target can't be resolved, in which case none of the events are exported.
+func (np *NProbeManager) getTargetMatcher(networkID string, task *models.NetworkProbeTask) (*targetMatcher, error) {
+	details := task.TaskDetails
+	if details == nil || len(strings.TrimPrefix(details.TargetID, imsiPrefix)) == 0 {
+		return nil, errors.New("missing target ID")
+	}
+	// the protected identities of the network may have changed since the
+	// task was created, they are never intercepted
+	protected := np.getNetworkConfig(networkID).ProtectedIdentities
+	if models.GetProtectedTarget(protected, details) != nil {
+		return nil, fmt.Errorf("%s target of task %s is a protected identity of network %s", details.TargetType, task.TaskID, networkID)
+	}
+	m := &targetMatcher{targetType: details.TargetType, targetID: details.TargetID, protected: protected}
+	switch details.TargetType {
+	case models.NetworkProbeTaskDetailsTargetTypeMsisdn:
+		imsi, err := subscriberdb.GetIMSIForMSISDN(networkID, strings.TrimPrefix(details.TargetID, "+"))
+		if err == merrors.ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(normalizeIMSI(imsi)) == 0 {
+			return nil, fmt.Errorf("MSISDN %s is assigned to an empty IMSI", redact.Identity(details.TargetID))
+		}
+		if models.GetProtectedIdentity(protected, models.NetworkProbeProtectedIdentityIdentityTypeImsi, imsi) != nil {
+			return nil, fmt.Errorf("MSISDN target of task %s is assigned to a protected IMSI of network %s", task.TaskID, networkID)
+		}
+		m.tags = getIMSITags(imsi)
+		m.imsi = imsi
+	case models.NetworkProbeTaskDetailsTargetTypeImei:
+		np.bindingMutex.Lock()
+		m.boundIMSI = np.imeiBindings[getBackoffKey(networkID, string(task.TaskID))]
+		np.bindingMutex.Unlock()
+	case models.NetworkProbeTaskDetailsTargetTypeImsi:
+		m.tags = getIMSITags(details.TargetID)
+		m.imsi = details.TargetID
+	case models.NetworkProbeTaskDetailsTargetTypeImsiRange:
+		imsiRange, err := models.ParseIMSIRange(details.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		// the test PLMNs of the network may have changed since the task was
+		// created, ranges outside of them are never intercepted
+		if !imsiRange.IsInPLMNs(np.getNetworkConfig(networkID).TestPlmnIds) {
+			return nil, fmt.Errorf("IMSI range %s is not in a test PLMN of network %s", redact.Identity(details.TargetID), networkID)
+		}
+		m.imsiRange = imsiRange
+	default:
+		return nil, fmt.Errorf("unsupported target type %q", details.TargetType)
+	}
+	return m, nil
+}
+
+// saveBinding keeps the IMSI bound to an IMEI target for the next pass
+func (np *NProbeManager) saveBinding(key string, m *targetMatcher) {
+	if m.targetType != models.NetworkProbeTaskDetailsTargetTypeImei {
+		return
+	}
+	np.bindingMutex.Lock()
+	defer np.bindingMutex.Unlock()
+	if len(m.boundIMSI) == 0 {
+		delete(np.imeiBindings, key)
+		return
+	}
+	np.imeiBindings[key] = m.boundIMSI
+}
+
+// pruneBindings drops the bindings of tasks which no longer exist
+func (np *NProbeManager) pruneBindings(keys map[string]bool) {
+	np.bindingMutex.Lock()
+	defer np.bindingMutex.Unlock()
+	for key := range np.imeiBindings {
+		if !keys[key] {
+			delete(np.imeiBindings, key)
+		}
+	}
+}
+
+// getIMSI returns the IMSI of the subscriber the target currently designates,
+// prefixed as the states of the subscribers are keyed, or empty while it is
+// unknown, e.g. for an IMEI target not seen yet, or for an IMSI range target
+// designating several subscribers
+func (m *targetMatcher) getIMSI() string {
+	imsi := m.imsi
+	if m.targetType == models.NetworkProbeTaskDetailsTargetTypeImei {
+		imsi = m.boundIMSI
+	}
+	if len(normalizeIMSI(imsi)) == 0 {
+		return ""
+	}
+	return imsiPrefix + normalizeIMSI(imsi)
+}
+
+// matches returns true if an event concerns the target. Events are expected
+// in chronological order so that IMEI targets follow the subscribers using
+// the device: an event carrying the IMEI binds its IMSI to the target until
+// the IMSI is seen with another device.
+func (m *targetMatcher) matches(event *eventdM.Event) bool {
+	if m.isProtected(event) {
+		return false
+	}
+	switch m.targetType {
+	case models.NetworkProbeTaskDetailsTargetTypeMsisdn:
+		// the MSISDN may have been reassigned since it was resolved
+		msisdn, ok := getEventField(event, "msisdn")
+		return !ok || normalizeMSISDN(msisdn) == normalizeMSISDN(m.targetID)
+	case models.NetworkProbeTaskDetailsTargetTypeImei:
+		imsi, _ := getEventField(event, "imsi")
+		imei, ok := getEventField(event, "imei")
+		if ok && isSameDevice(imei, m.targetID) {
+			m.boundIMSI = normalizeIMSI(imsi)
+			return true
+		}
+		if len(imsi) == 0 || normalizeIMSI(imsi) != m.boundIMSI {
+			return false
+		}
+		if ok && len(imei) != 0 {
+			// the subscriber moved to another device
+			m.boundIMSI = ""
+			return false
+		}
+		return true
+	case models.NetworkProbeTaskDetailsTargetTypeImsi:
+		// the events were fetched by the tags of the target
+		return true
+	case models.NetworkProbeTaskDetailsTargetTypeImsiRange:
+		imsi, ok := getEventField(event, "imsi")
+		if !ok {
+			imsi = event.Tag
+		}
+		return m.imsiRange.Contains(imsi)
+	default:
+		return false
+	}
+}
+
+// isProtected returns true if an event concerns a protected identity of the
+// network, through the IMSI it is tagged with or one of its identities
+func (m *targetMatcher) isProtected(event *eventdM.Event) bool {
+	if len(m.protected) == 0 {
+		return false
+	}
+	imsi, ok := getEventField(event, "imsi")
+	if !ok {
+		imsi = event.Tag
+	}
+	msisdn, _ := getEventField(event, "msisdn")
+	imei, _ := getEventField(event, "imei")
+	return models.GetProtectedIdentity(m.protected, models.NetworkProbeProtectedIdentityIdentityTypeImsi, imsi) != nil ||
+		models.GetProtectedIdentity(m.protected, models.NetworkProbeProtectedIdentityIdentityTypeMsisdn, msisdn) != nil ||
+		models.GetProtectedIdentity(m.protected, models.NetworkProbeProtectedIdentityIdentityTypeImei, imei) != nil
+}
+
+// getIMSITags returns the tags of the events of a subscriber, which are
+// tagged with the IMSI with or without prefix
+func getIMSITags(imsi string) []string {
+	digits := strings.TrimPrefix(imsi, imsiPrefix)
+	return []string{imsiPrefix + digits, digits}
+}
+
+func getEventField(event *eventdM.Event, key string) (string, bool) {
+	eventData, ok := event.Value.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	v, ok := eventData[key].(string)
+	return v, ok
+}
+
+func normalizeIMSI(imsi string) string {
+	return strings.TrimPrefix(imsi, imsiPrefix)
+}
+
+func normalizeMSISDN(msisdn string) string {
+	return strings.TrimPrefix(msisdn, "+")
+}
+
+// isSameDevice compares IMEIs on their TAC and serial number, ignoring
+// the check digit or software version
+func isSameDevice(imei, target string) bool {
+	if len(imei) < 14 || len(target) < 14 {
+		return imei == target
+	}
+	return imei[:14] == target[:14]
+}
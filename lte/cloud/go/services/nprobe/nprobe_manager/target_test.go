@@ -0,0 +1,206 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/lte/cloud/go/services/subscriberdb"
+	subscriberdb_test_init "magma/lte/cloud/go/services/subscriberdb/test_init"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeEvent(data map[string]interface{}) *eventdM.Event {
+	return &eventdM.Event{Value: data}
+}
+
+func TestGetIMSITags(t *testing.T) {
+	assert.Equal(t, []string{"IMSI001010000000001", "001010000000001"}, getIMSITags("IMSI001010000000001"))
+	assert.Equal(t, []string{"IMSI001010000000001", "001010000000001"}, getIMSITags("001010000000001"))
+}
+
+func TestMatchMSISDNTarget(t *testing.T) {
+	m := &targetMatcher{targetType: models.NetworkProbeTaskDetailsTargetTypeMsisdn, targetID: "+33612345678"}
+	assert.True(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI001010000000001"})))
+	assert.True(t, m.matches(makeEvent(map[string]interface{}{"msisdn": "33612345678"})))
+	// the MSISDN was reassigned since it was resolved
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"msisdn": "33687654321"})))
+}
+
+func TestResolveMSISDNTargetRedacted(t *testing.T) {
+	subscriberdb_test_init.StartTestService(t)
+	np := &NProbeManager{failClosed: map[string]string{}}
+	assert.NoError(t, subscriberdb.SetIMSIForMSISDN("n1", "33612345678", "IMSI"))
+
+	// the MSISDN of the target is redacted from the error, whatever its prefix
+	_, err := np.resolveTarget("n1", &models.NetworkProbeTask{
+		TaskID: "t1",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetType: models.NetworkProbeTaskDetailsTargetTypeMsisdn,
+			TargetID:   "+33612345678",
+		},
+	})
+	assert.EqualError(t, err, "MSISDN "+redact.Identity("+33612345678")+" is assigned to an empty IMSI")
+	assert.NotContains(t, err.Error(), "33612345678")
+}
+
+func TestMatchIMEITarget(t *testing.T) {
+	m := &targetMatcher{targetType: models.NetworkProbeTaskDetailsTargetTypeImei, targetID: "490154203237518"}
+
+	// events of other subscribers are filtered out until the device is seen
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI001010000000001"})))
+	assert.True(t, m.matches(makeEvent(map[string]interface{}{
+		"imsi": "IMSI001010000000001",
+		"imei": "4901542032375101",
+	})))
+	assert.Equal(t, "001010000000001", m.boundIMSI)
+
+	// events of the subscriber using the device follow
+	assert.True(t, m.matches(makeEvent(map[string]interface{}{"imsi": "001010000000001"})))
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI001010000000002"})))
+
+	// until the subscriber moves to another device
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{
+		"imsi": "IMSI001010000000001",
+		"imei": "356938035643809",
+	})))
+	assert.Empty(t, m.boundIMSI)
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI001010000000001"})))
+}
+
+func TestMatchIMSIRangeTarget(t *testing.T) {
+	np := &NProbeManager{failClosed: map[string]string{}}
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{
+		"n1": {TestPlmnIds: []string{"99999"}},
+	})
+	newTask := func(targetID string) *models.NetworkProbeTask {
+		return &models.NetworkProbeTask{
+			TaskID: "t1",
+			TaskDetails: &models.NetworkProbeTaskDetails{
+				TargetType: models.NetworkProbeTaskDetailsTargetTypeImsiRange,
+				TargetID:   targetID,
+			},
+		}
+	}
+
+	// the ranges outside of the test PLMNs of their network fail closed
+	_, err := np.resolveTarget("n0", newTask("IMSI99999*"))
+	assert.EqualError(t, err, "IMSI range "+redact.Identity("IMSI99999*")+" is not in a test PLMN of network n0")
+	assert.NotContains(t, err.Error(), "99999")
+	_, err = np.resolveTarget("n1", newTask("IMSI001010000000001-IMSI999990000000001"))
+	assert.Error(t, err)
+	_, err = np.resolveTarget("n1", newTask("IMSI99999*0"))
+	assert.Error(t, err)
+
+	// the events of all the subscribers are matched on their IMSI
+	m, err := np.resolveTarget("n1", newTask("IMSI99999000*"))
+	assert.NoError(t, err)
+	assert.Empty(t, m.tags)
+	assert.Empty(t, m.getIMSI())
+	assert.True(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI999990001234567"})))
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI999991001234567"})))
+	assert.True(t, m.matches(&eventdM.Event{Tag: "999990001234567", Value: map[string]interface{}{}}))
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{})))
+
+	m, err = np.resolveTarget("n1", newTask("IMSI999990000000010-999990000000019"))
+	assert.NoError(t, err)
+	assert.True(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI999990000000010"})))
+	assert.True(t, m.matches(makeEvent(map[string]interface{}{"imsi": "999990000000019"})))
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI999990000000020"})))
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI99999000000001"})))
+	// only the task of the network without test PLMNs still fails closed
+	assert.Equal(t, map[string]int{"n0": 1}, np.countFailClosed())
+}
+
+func TestMatchProtectedIdentities(t *testing.T) {
+	np := &NProbeManager{failClosed: map[string]string{}}
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{
+		"n1": {
+			TestPlmnIds: []string{"99999"},
+			ProtectedIdentities: []*models.NetworkProbeProtectedIdentity{
+				{IdentityType: models.NetworkProbeProtectedIdentityIdentityTypeImsi, Identity: "999990000000001"},
+				{IdentityType: models.NetworkProbeProtectedIdentityIdentityTypeMsisdn, Identity: "+33112"},
+				{IdentityType: models.NetworkProbeProtectedIdentityIdentityTypeImei, Identity: "35693803564380"},
+			},
+		},
+	})
+	newTask := func(targetType, targetID string) *models.NetworkProbeTask {
+		return &models.NetworkProbeTask{
+			TaskID:      "t1",
+			TaskDetails: &models.NetworkProbeTaskDetails{TargetType: targetType, TargetID: targetID},
+		}
+	}
+
+	// the tasks targeting a protected identity fail closed
+	_, err := np.resolveTarget("n1", newTask(models.NetworkProbeTaskDetailsTargetTypeImsi, "IMSI999990000000001"))
+	assert.EqualError(t, err, "imsi target of task t1 is a protected identity of network n1")
+	_, err = np.resolveTarget("n1", newTask(models.NetworkProbeTaskDetailsTargetTypeMsisdn, "33112"))
+	assert.Error(t, err)
+	_, err = np.resolveTarget("n1", newTask(models.NetworkProbeTaskDetailsTargetTypeImei, "356938035643809"))
+	assert.Error(t, err)
+	_, err = np.resolveTarget("n1", newTask(models.NetworkProbeTaskDetailsTargetTypeImsiRange, "IMSI99999000*"))
+	assert.Error(t, err)
+	assert.Equal(t, map[string]int{"n1": 1}, np.countFailClosed())
+
+	// the events of protected identities are never matched
+	m, err := np.resolveTarget("n1", newTask(models.NetworkProbeTaskDetailsTargetTypeImsiRange, "IMSI999990000000002-IMSI999990000000009"))
+	assert.NoError(t, err)
+	assert.True(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI999990000000002"})))
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI999990000000002", "msisdn": "33112"})))
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI999990000000003", "imei": "3569380356438012"})))
+
+	m, err = np.resolveTarget("n1", newTask(models.NetworkProbeTaskDetailsTargetTypeImei, "490154203237518"))
+	assert.NoError(t, err)
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI999990000000001", "imei": "4901542032375101"})))
+	assert.Empty(t, m.boundIMSI)
+	assert.Empty(t, np.countFailClosed())
+}
+
+func TestResolveTargetFailsClosed(t *testing.T) {
+	np := &NProbeManager{imeiBindings: map[string]string{}, failClosed: map[string]string{}}
+	newTask := func(taskID, targetType, targetID string) *models.NetworkProbeTask {
+		return &models.NetworkProbeTask{
+			TaskID:      models.NetworkProbeTaskID(taskID),
+			TaskDetails: &models.NetworkProbeTaskDetails{TargetType: targetType, TargetID: targetID},
+		}
+	}
+
+	// tasks whose target can't be resolved export nothing
+	m, err := np.resolveTarget("n1", newTask("t1", models.NetworkProbeTaskDetailsTargetTypeImsi, "IMSI"))
+	assert.EqualError(t, err, "missing target ID")
+	assert.Nil(t, m)
+	m, err = np.resolveTarget("n1", newTask("t2", "guti", "IMSI001010000000001"))
+	assert.EqualError(t, err, `unsupported target type "guti"`)
+	assert.Nil(t, m)
+	_, err = np.resolveTarget("n2", &models.NetworkProbeTask{TaskID: "t3"})
+	assert.Error(t, err)
+	assert.Equal(t, map[string]int{"n1": 2, "n2": 1}, np.countFailClosed())
+
+	// until it can
+	m, err = np.resolveTarget("n1", newTask("t1", models.NetworkProbeTaskDetailsTargetTypeImsi, "IMSI001010000000001"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"IMSI001010000000001", "001010000000001"}, m.tags)
+	assert.Equal(t, map[string]int{"n1": 1, "n2": 1}, np.countFailClosed())
+
+	np.pruneFailClosed(map[string]bool{getBackoffKey("n1", "t2"): true})
+	assert.Equal(t, map[string]int{"n1": 1}, np.countFailClosed())
+
+	// events are never matched for targets of unknown type
+	m = &targetMatcher{targetType: "guti", targetID: "IMSI001010000000001"}
+	assert.False(t, m.matches(makeEvent(map[string]interface{}{"imsi": "IMSI001010000000001"})))
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"fmt"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+)
+
+// getTaskDestination returns the delivery function the records of a task are
+// delivered to instead of the one of the service config, nil if none
+func getTaskDestination(task *models.NetworkProbeTask) *exporter.Destination {
+	delivery := task.TaskDetails.Delivery
+	if delivery == nil {
+		return nil
+	}
+	return &exporter.Destination{
+		Address:          delivery.DeliveryAddress,
+		SecondaryAddress: delivery.SecondaryDeliveryAddress,
+		Handshake: exporter.HandshakeSettings{
+			ServerName:    delivery.TLSServerName,
+			ALPNProtocols: delivery.AlpnProtocols,
+			Compression:   delivery.Compression,
+			Framing:       delivery.Framing,
+			Encoding:      delivery.Encoding,
+			// validated to be at most 2
+			HeaderVersion:          uint16(delivery.HeaderVersion),
+			NegotiateHeaderVersion: delivery.NegotiateHeaderVersion,
+			WriteTimeout:           time.Duration(delivery.WriteTimeoutMs) * time.Millisecond,
+			Transport:              delivery.Transport,
+		},
+		RecordsPerSecond: delivery.RateLimit,
+		Burst:            delivery.BurstSize,
+	}
+}
+
+// toTaskDelivery returns the delivery of the records of a task delivered to
+// a destination
+func toTaskDelivery(details *models.NetworkProbeDestinationDetails) *models.NetworkProbeTaskDelivery {
+	return &models.NetworkProbeTaskDelivery{
+		DeliveryAddress:        details.DeliveryAddress,
+		TLSServerName:          details.TLSServerName,
+		AlpnProtocols:          details.AlpnProtocols,
+		Compression:            details.Compression,
+		Framing:                details.Framing,
+		Encoding:               details.Encoding,
+		HeaderVersion:          details.HeaderVersion,
+		NegotiateHeaderVersion: details.NegotiateHeaderVersion,
+		WriteTimeoutMs:         details.WriteTimeoutMs,
+		Transport:              details.Transport,
+		ModuleVersion:          details.ModuleVersion,
+		Hi2DomainID:            details.Hi2DomainID,
+		MinimalRecords:         details.MinimalRecords,
+		RateLimit:              details.RateLimit,
+		BurstSize:              details.BurstSize,
+	}
+}
+
+// resolveTaskDestinations sets the delivery of the tasks delivered to a
+// destination of their network from the settings of the destination. The
+// tasks delivered to the destination of the delivery function of the service
+// config are delivered by its exporter, customized by the destination along
+// with the others of its delivery type. The tasks whose destination doesn't
+// exist get an empty delivery, so that their records are withheld rather
+// than delivered to the delivery function of the service config. The payload
+// encryptions of the destinations are selected along.
+func (np *NProbeManager) resolveTaskDestinations(networkID string, tasks map[string]*models.NetworkProbeTask, destinations []*models.NetworkProbeDestination) {
+	byID := make(map[string]*models.NetworkProbeDestinationDetails, len(destinations))
+	encryptions := map[string]*encoding.PayloadEncryption{}
+	for _, destination := range destinations {
+		byID[string(destination.DestinationID)] = destination.DestinationDetails
+		if destination.DestinationDetails.PayloadEncryption != nil {
+			encryptions[string(destination.DestinationID)] = newPayloadEncryption(networkID, destination)
+		}
+	}
+	np.setDestinationEncryptions(networkID, encryptions)
+	for _, task := range tasks {
+		destinationID := task.TaskDetails.DestinationID
+		if len(destinationID) == 0 {
+			continue
+		}
+		details := *task.TaskDetails
+		task.TaskDetails = &details
+		destination, ok := byID[destinationID]
+		switch {
+		case !ok:
+			details.Delivery = &models.NetworkProbeTaskDelivery{}
+		case exporter.NormalizeAddress(destination.DeliveryAddress) == exporter.NormalizeAddress(np.DeliveryFunctionAddr):
+			details.Delivery = nil
+		default:
+			details.Delivery = toTaskDelivery(destination)
+		}
+	}
+}
+
+// loadNetworkProbeTasks retrieves the tasks of a network, delivered to the
+// destinations they reference
+func (np *NProbeManager) loadNetworkProbeTasks(networkID string) (map[string]*models.NetworkProbeTask, error) {
+	tasks, err := getNetworkProbeTasks(networkID)
+	if err != nil {
+		return nil, err
+	}
+	destinations, err := getNetworkProbeDestinations(networkID)
+	if err != nil {
+		return nil, err
+	}
+	np.resolveTaskDestinations(networkID, tasks, destinations)
+	return tasks, nil
+}
+
+// getDeliveryDestination returns the destination the records of a task are
+// delivered to on a connection of their own, nil if they are delivered by
+// the exporter of the service config. The records of the networks with their
+// own exporter credentials or SNI are delivered to the delivery function of
+// the service config on a connection of their own, while the ones of their
+// tasks overriding the delivery function present their credentials too.
+func (np *NProbeManager) getDeliveryDestination(networkID string, task *models.NetworkProbeTask) (*exporter.Destination, error) {
+	credentials := false
+	if np.Credentials != nil {
+		var err error
+		if credentials, err = np.Credentials.HasCredentials(networkID); err != nil {
+			return nil, err
+		}
+	}
+	destination := getTaskDestination(task)
+	if destination != nil && len(destination.Address) == 0 {
+		return nil, fmt.Errorf("destination %s of task %s does not exist", task.TaskDetails.DestinationID, task.TaskID)
+	}
+	if destination == nil {
+		serverName := np.getNetworkConfig(networkID).TLSServerName
+		if !credentials && len(serverName) == 0 {
+			return nil, nil
+		}
+		destination = &exporter.Destination{
+			Address:          np.DeliveryFunctionAddr,
+			SecondaryAddress: np.SecondaryDeliveryFunctionAddr,
+			Handshake:        exporter.HandshakeSettings{ServerName: serverName},
+		}
+	}
+	if credentials {
+		destination.Network = networkID
+	}
+	return destination, nil
+}
+
+// getExporter returns the exporter delivering the records of a task. The
+// records of a task overriding the delivery function are never delivered to
+// the one of the service config, and the ones of a network whose
+// credentials fail to load are not delivered.
+func (np *NProbeManager) getExporter(networkID string, task *models.NetworkProbeTask) (*exporter.RecordExporter, error) {
+	destination, err := np.getDeliveryDestination(networkID, task)
+	if err != nil {
+		return nil, err
+	}
+	if destination == nil {
+		return np.Exporter, nil
+	}
+	if np.Destinations == nil {
+		return nil, fmt.Errorf("no exporter pool to deliver task %s to %s", task.TaskID, destination.Address)
+	}
+	return np.Destinations.Get(*destination)
+}
+
+// getDestinationName returns the destination the records of a task are
+// delivered to, as reported by the latency of the delivered records
+func (np *NProbeManager) getDestinationName(task *models.NetworkProbeTask) string {
+	if destination := getTaskDestination(task); destination != nil {
+		return exporter.NormalizeAddress(destination.Address)
+	}
+	return np.destinationName
+}
+
+// retainDestinations closes the connections to the delivery functions no
+// listed task delivers to, and the ones of the networks no longer delivering
+// with their own credentials or SNI
+func (np *NProbeManager) retainDestinations(listedTasks map[string]map[string]*models.NetworkProbeTask) {
+	if np.Destinations == nil {
+		return
+	}
+	var destinations []exporter.Destination
+	if len(np.HI1NotificationAddr) != 0 {
+		destinations = append(destinations, exporter.Destination{Address: np.HI1NotificationAddr})
+	}
+	for networkID, tasks := range listedTasks {
+		for _, task := range tasks {
+			if destination, err := np.getDeliveryDestination(networkID, task); err == nil && destination != nil {
+				destinations = append(destinations, *destination)
+			}
+		}
+	}
+	np.Destinations.Retain(destinations)
+}
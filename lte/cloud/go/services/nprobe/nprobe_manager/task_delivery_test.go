@@ -0,0 +1,192 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskDelivery(t *testing.T) {
+	np := &NProbeManager{}
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:     "IMSI001010000000001",
+			TargetType:   "imsi",
+			DeliveryType: models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly,
+			Delivery: &models.NetworkProbeTaskDelivery{
+				DeliveryAddress: "127.0.0.1:1",
+				TLSServerName:   "hi2.lea2.example.org",
+				ModuleVersion:   encoding.ModuleVersionR13,
+				MinimalRecords:  true,
+			},
+		},
+	}
+
+	// the records of the task are never delivered to the service delivery function
+	_, err := np.getExporter("n0", task)
+	assert.Error(t, err)
+
+	pool, err := exporter.NewPool(nprobe.Config{
+		ExporterBackend:      exporter.BackendTLS,
+		OutputFormat:         encoding.OutputFormatHI2,
+		ExportOverflowPolicy: exporter.OverflowSpill,
+	}, &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer pool.Close()
+	np.Destinations = pool
+	exp, err := np.getExporter("n0", task)
+	assert.NoError(t, err)
+	assert.False(t, exp == np.Exporter)
+	same, err := np.getExporter("n0", task)
+	assert.NoError(t, err)
+	assert.True(t, exp == same)
+
+	// the encoding options of the task take precedence
+	assert.Equal(t, encoding.ModuleVersionR13, np.getModuleVersion("n0", task).Name)
+	assert.True(t, np.isMinimalRecordsEnabled("n0", task))
+
+	// the connections no longer used by any task are closed
+	np.retainDestinations(map[string]map[string]*models.NetworkProbeTask{"n0": {string(task.TaskID): task}})
+	kept, err := np.getExporter("n0", task)
+	assert.NoError(t, err)
+	assert.True(t, exp == kept)
+	np.retainDestinations(map[string]map[string]*models.NetworkProbeTask{})
+	recreated, err := np.getExporter("n0", task)
+	assert.NoError(t, err)
+	assert.False(t, exp == recreated)
+
+	task.TaskDetails.Delivery = nil
+	exp, err = np.getExporter("n0", task)
+	assert.NoError(t, err)
+	assert.True(t, exp == np.Exporter)
+	assert.False(t, np.isMinimalRecordsEnabled("n0", task))
+}
+
+func TestNetworkDelivery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_credentials")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	np := &NProbeManager{DeliveryFunctionAddr: "10.10.0.2:6666", SecondaryDeliveryFunctionAddr: "10.10.0.3:6666"}
+	np.setNetworkConfigs(map[string]*models.NetworkProbeNetworkConfig{
+		"n1": {TLSServerName: "hi2.lea1.example.org"},
+	})
+	task := &models.NetworkProbeTask{
+		TaskID:      "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{TargetID: "IMSI001010000000001", TargetType: "imsi"},
+	}
+
+	// the records of the networks with their own SNI are delivered to the
+	// service delivery function on a connection of their own
+	destination, err := np.getDeliveryDestination("n0", task)
+	assert.NoError(t, err)
+	assert.Nil(t, destination)
+	destination, err = np.getDeliveryDestination("n1", task)
+	assert.NoError(t, err)
+	assert.Equal(t, &exporter.Destination{
+		Address:          "10.10.0.2:6666",
+		SecondaryAddress: "10.10.0.3:6666",
+		Handshake:        exporter.HandshakeSettings{ServerName: "hi2.lea1.example.org"},
+	}, destination)
+
+	// the SNI of the task takes precedence
+	task.TaskDetails.Delivery = &models.NetworkProbeTaskDelivery{DeliveryAddress: "127.0.0.1:1", TLSServerName: "hi2.lea2.example.org"}
+	destination, err = np.getDeliveryDestination("n1", task)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi2.lea2.example.org", destination.Handshake.ServerName)
+	assert.Empty(t, destination.Network)
+
+	// the records of a network whose credentials fail to load are not
+	// delivered with the ones of the service
+	np.Credentials = exporter.NewCredentialManager(nil, dir)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "n2"), 0700))
+	task.TaskDetails.Delivery = nil
+	_, err = np.getExporter("n2", task)
+	assert.Error(t, err)
+	destination, err = np.getDeliveryDestination("n0", task)
+	assert.NoError(t, err)
+	assert.Nil(t, destination)
+}
+
+func TestTaskDestinations(t *testing.T) {
+	np := &NProbeManager{DeliveryFunctionAddr: "10.10.0.2:6666"}
+	newTask := func(id, destinationID string) *models.NetworkProbeTask {
+		return &models.NetworkProbeTask{
+			TaskID: models.NetworkProbeTaskID(id),
+			TaskDetails: &models.NetworkProbeTaskDetails{
+				TargetID:      "IMSI001010000000001",
+				TargetType:    "imsi",
+				DeliveryType:  models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly,
+				DestinationID: destinationID,
+			},
+		}
+	}
+	tasks := map[string]*models.NetworkProbeTask{
+		"t0": newTask("t0", ""),
+		"t1": newTask("t1", "lea1"),
+		"t2": newTask("t2", "lea2"),
+		"t3": newTask("t3", "gone"),
+	}
+	details := tasks["t2"].TaskDetails
+	destinations := []*models.NetworkProbeDestination{
+		{
+			DestinationID: "lea1",
+			DestinationDetails: &models.NetworkProbeDestinationDetails{
+				DeliveryAddress: "10.10.0.2:6666",
+				DeliveryType:    models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly,
+			},
+		},
+		{
+			DestinationID: "lea2",
+			DestinationDetails: &models.NetworkProbeDestinationDetails{
+				DeliveryAddress: "127.0.0.1:1",
+				DeliveryType:    models.NetworkProbeTaskDetailsDeliveryTypeEventsOnly,
+				TLSServerName:   "hi2.lea2.example.org",
+				RateLimit:       200,
+				BurstSize:       400,
+			},
+		},
+	}
+	np.resolveTaskDestinations("n0", tasks, destinations)
+
+	// the tasks without destination and delivered to the service delivery
+	// function keep its exporter
+	assert.Nil(t, tasks["t0"].TaskDetails.Delivery)
+	assert.Nil(t, tasks["t1"].TaskDetails.Delivery)
+
+	// the other destinations are connected to with their own settings, the
+	// loaded details of the task being left untouched
+	assert.Nil(t, details.Delivery)
+	destination := getTaskDestination(tasks["t2"])
+	assert.Equal(t, "127.0.0.1:1", destination.Address)
+	assert.Equal(t, "hi2.lea2.example.org", destination.Handshake.ServerName)
+	assert.Equal(t, uint32(200), destination.RecordsPerSecond)
+	assert.Equal(t, uint32(400), destination.Burst)
+
+	// the records of the tasks whose destination is gone are withheld
+	_, err := np.getExporter("n0", tasks["t3"])
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// A test record is requested through the API, e.g. while the warrant of a
+// task is activated with the LEA, and delivered on the next pass of the task
+// through the exporter of its destination, like any of its records. It is
+// numbered in the stream of the task so that the LEA checks the whole path
+// to its LEMF, and marked by a test indication carrying the ID of the request.
+
+// deliverTestRecord delivers the test record requested for a task, and
+// records its sequence number and delivery time in the request
+func (np *NProbeManager) deliverTestRecord(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	testRecord *models.NetworkProbeTaskTestRecord,
+) error {
+	taskID := string(task.TaskID)
+	requestedAt := time.Time(testRecord.RequestedAt)
+	seq := getNextSequenceNumber(state)
+	record, err := encoding.MakeTestRecord(
+		np.getRecordTask(networkID, task, state), np.getOperatorID(networkID), seq, testRecord.ID, requestedAt, np.getModuleVersion(networkID, task),
+	)
+	if err == nil {
+		record, err = np.prepareRecord(networkID, task, record)
+	}
+	if err != nil {
+		// the test record stays pending, the records of the task aren't held back
+		logger.Errorf("Failed to build test record %s of task %s: %s", testRecord.ID, taskID, err)
+		return nil
+	}
+
+	exp, err := np.getExporter(networkID, task)
+	if err != nil {
+		return err
+	}
+	delivery := exp.SubmitRecords(
+		ctx,
+		getBackoffKey(networkID, taskID),
+		np.getTaskWeight(taskID),
+		task.TaskDetails.CorrelationID,
+		[][]byte{record},
+		np.MaxExportRetries,
+	)
+	if err := delivery.Wait(0); err != nil {
+		return err
+	}
+	deliveredAt := time.Now()
+	metrics.RecordsExported.WithLabelValues(networkID).Inc()
+	np.storeDeliveryRecords(networkID, taskID, []models.NetworkProbeDeliveryRecord{
+		makeDeliveryRecord(task, record, seq, requestedAt, deliveredAt),
+	})
+	logger.Infof("Delivered test record %s of task %s with sequence number %d", testRecord.ID, taskID, seq)
+
+	state.RecordsExported++
+	state.SequenceNumber = seq + 1
+	if err := np.storeState(networkID, taskID, state); err != nil {
+		return err
+	}
+	testRecord.SequenceNumber = seq
+	testRecord.DeliveredAt = strfmt.DateTime(deliveredAt.UTC())
+	return np.Storage.StoreTaskTestRecord(networkID, taskID, *testRecord)
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"fmt"
+
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+)
+
+// markTimestampQuality flags the records built while the clock of the
+// service is unreliable, so that the LEA knows not to rely on the times
+// stamped by the service. The flag is part of the record as signed.
+func markTimestampQuality(networkID string, record []byte) ([]byte, error) {
+	status := clock.GetStatus()
+	if clock.IsReliable(status.Quality) {
+		return record, nil
+	}
+	marked, err := encoding.MarkTimestampQuality(record, status.Quality)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flag the timestamp quality of record: %v", err)
+	}
+	metrics.UnreliableTimestamps.WithLabelValues(networkID).Inc()
+	return marked, nil
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"fmt"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+)
+
+// getRecordTransformers returns the registered transformers of the countries
+// whose national handover variant is enabled. Enabling a variant whose module
+// isn't built in the service is an error, rather than delivering records the
+// delivery functions of the country can't process.
+func getRecordTransformers(countryCodes []string) (map[string]encoding.RecordTransformer, error) {
+	transformers := map[string]encoding.RecordTransformer{}
+	for _, countryCode := range countryCodes {
+		transformer, err := encoding.GetRecordTransformer(countryCode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid record_transformers: %v, registered: %v", err, encoding.GetRecordTransformers())
+		}
+		transformers[countryCode] = transformer
+	}
+	return transformers, nil
+}
+
+// transformRecord adapts an encoded record of a task to the national variant
+// of the delivery country of its header, if enabled
+func (np *NProbeManager) transformRecord(networkID string, task *models.NetworkProbeTask, record []byte) ([]byte, error) {
+	if len(np.RecordTransformers) == 0 {
+		return record, nil
+	}
+	return encoding.TransformRecord(record, np.withHeaderIdentifiers(networkID, task), np.RecordTransformers)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"strconv"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+)
+
+// Tasks may report the usage of the open sessions of their target at a fixed
+// interval, as some national requirements mandate during long sessions.
+// Pipelined periodically reports the bytes carried so far by each flow of a
+// session, the usage of a session summing the last report of each of its
+// flows. Once the interval of the task elapsed since the session was begun
+// or last reported, the flow report makes up an IRI-CONTINUE reporting the
+// usage of the session. Flow reports are otherwise skipped.
+// The usage is tracked in memory: after a restart, the sessions are timed
+// from their next flow report and their flows summed as they report again.
+
+// flowUsage is the bytes carried by a flow so far
+type flowUsage struct {
+	tx uint64
+	rx uint64
+}
+
+// sessionUsage is the usage of an intercepted session
+type sessionUsage struct {
+	flows      map[string]flowUsage
+	startedAt  time.Time
+	reportedAt time.Time
+}
+
+// beginUsage starts timing a session begun by a record of a task reporting usage
+func (np *NProbeManager) beginUsage(networkID string, task *models.NetworkProbeTask, sessionID, timestamp string) {
+	startedAt, err := time.Parse(time.RFC3339, timestamp)
+	if task.TaskDetails.UsageReportIntervalSecs == 0 || err != nil {
+		return
+	}
+	np.usageMutex.Lock()
+	defer np.usageMutex.Unlock()
+	sessions := np.getSessionUsages(getBackoffKey(networkID, string(task.TaskID)))
+	sessions[sessionID] = &sessionUsage{flows: map[string]flowUsage{}, startedAt: startedAt}
+}
+
+// endUsage forgets the usage of a session whose interception ended
+func (np *NProbeManager) endUsage(networkID, taskID, sessionID string) {
+	np.usageMutex.Lock()
+	defer np.usageMutex.Unlock()
+	delete(np.usage[getBackoffKey(networkID, taskID)], sessionID)
+}
+
+// trackUsage records the usage reported by a flow and returns the usage
+// report of its session once due, nil if the flow report is to be skipped.
+// Only the sessions open are reported. The report of a flow report whose
+// record may have been delivered is always due so that it gets the same
+// sequence number.
+func (np *NProbeManager) trackUsage(
+	networkID string,
+	task *models.NetworkProbeTask,
+	event *eventdM.Event,
+	open map[string]bool,
+	isReserved bool,
+) *eventdM.Event {
+	interval := time.Duration(task.TaskDetails.UsageReportIntervalSecs) * time.Second
+	eventData, ok := event.Value.(map[string]interface{})
+	if interval == 0 || !ok {
+		return nil
+	}
+	sessionID, _ := eventData["session_id"].(string)
+	flowID, _ := eventData["flow_id"].(string)
+	reportedAt, err := time.Parse(time.RFC3339, event.Timestamp)
+	if !open[sessionID] || err != nil {
+		return nil
+	}
+
+	np.usageMutex.Lock()
+	defer np.usageMutex.Unlock()
+	sessions := np.getSessionUsages(getBackoffKey(networkID, string(task.TaskID)))
+	usage, ok := sessions[sessionID]
+	if !ok {
+		usage = &sessionUsage{flows: map[string]flowUsage{}, startedAt: reportedAt}
+		sessions[sessionID] = usage
+	}
+	usage.flows[flowID] = flowUsage{tx: getCount(eventData["bytes_tx"]), rx: getCount(eventData["bytes_rx"])}
+
+	last := usage.reportedAt
+	if last.IsZero() {
+		last = usage.startedAt
+	}
+	if !isReserved && reportedAt.Sub(last) < interval {
+		return nil
+	}
+	usage.reportedAt = reportedAt
+	return makeUsageReport(eventData, sessionID, usage, reportedAt, event.Timestamp)
+}
+
+// getSessionUsages returns the usage of the sessions of a task. It is called
+// with the usage locked.
+func (np *NProbeManager) getSessionUsages(key string) map[string]*sessionUsage {
+	sessions, ok := np.usage[key]
+	if !ok {
+		sessions = map[string]*sessionUsage{}
+		np.usage[key] = sessions
+	}
+	return sessions
+}
+
+// pruneUsage drops the usage of tasks which no longer exist
+func (np *NProbeManager) pruneUsage(keys map[string]bool) {
+	np.usageMutex.Lock()
+	defer np.usageMutex.Unlock()
+	for key := range np.usage {
+		if !keys[key] {
+			delete(np.usage, key)
+		}
+	}
+}
+
+// makeUsageReport composes the event reporting the usage of a session, with
+// the identity of the target carried by the flow report
+func makeUsageReport(
+	flowData map[string]interface{},
+	sessionID string,
+	usage *sessionUsage,
+	reportedAt time.Time,
+	timestamp string,
+) *eventdM.Event {
+	var tx, rx uint64
+	for _, flow := range usage.flows {
+		tx += flow.tx
+		rx += flow.rx
+	}
+	// flow reports may precede the record beginning the session
+	duration := reportedAt.Sub(usage.startedAt)
+	if duration < 0 {
+		duration = 0
+	}
+	value := map[string]interface{}{
+		"session_id":    sessionID,
+		"bytes_tx":      strconv.FormatUint(tx, 10),
+		"bytes_rx":      strconv.FormatUint(rx, 10),
+		"duration_secs": strconv.FormatInt(int64(duration/time.Second), 10),
+	}
+	for _, key := range []string{"imsi", "imei", "msisdn"} {
+		if v, ok := flowData[key]; ok {
+			value[key] = v
+		}
+	}
+	return &eventdM.Event{
+		EventType:  nprobe.UsageReported,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  timestamp,
+		Value:      value,
+	}
+}
+
+// getCount returns a byte count of a flow report, sent as a number or a
+// decimal string, 0 if invalid
+func getCount(v interface{}) uint64 {
+	switch count := v.(type) {
+	case float64:
+		if count > 0 {
+			return uint64(count)
+		}
+	case string:
+		n, _ := strconv.ParseUint(count, 10, 64)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackUsage(t *testing.T) {
+	np := &NProbeManager{usage: map[string]map[string]*sessionUsage{}}
+	task := &models.NetworkProbeTask{
+		TaskID: "t1",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:                "IMSI001010000000001",
+			UsageReportIntervalSecs: 600,
+		},
+	}
+	open := map[string]bool{"s1": true}
+	makeFlowReport := func(timestamp, flowID string, tx, rx interface{}) *eventdM.Event {
+		return &eventdM.Event{
+			EventType:  nprobe.FlowUsageReported,
+			StreamName: nprobe.ESStreamPipelined,
+			Timestamp:  timestamp,
+			Value: map[string]interface{}{
+				"imsi":       "IMSI001010000000001",
+				"session_id": "s1",
+				"flow_id":    flowID,
+				"bytes_tx":   tx,
+				"bytes_rx":   rx,
+			},
+		}
+	}
+
+	np.beginUsage("n1", task, "s1", "2021-03-06T10:00:00Z")
+	assert.Nil(t, np.trackUsage("n1", task, makeFlowReport("2021-03-06T10:05:00Z", "f1", 100.0, 1000.0), open, false))
+	assert.Nil(t, np.trackUsage("n1", task, makeFlowReport("2021-03-06T10:06:00Z", "f2", "20", "200"), open, false))
+
+	// the usage of the session is reported once the interval elapsed,
+	// summing the last report of each flow
+	report := np.trackUsage("n1", task, makeFlowReport("2021-03-06T10:10:00Z", "f1", 150.0, 1500.0), open, false)
+	assert.Equal(t, &eventdM.Event{
+		EventType:  nprobe.UsageReported,
+		StreamName: nprobe.ServiceName,
+		Timestamp:  "2021-03-06T10:10:00Z",
+		Value: map[string]interface{}{
+			"imsi":          "IMSI001010000000001",
+			"session_id":    "s1",
+			"bytes_tx":      "170",
+			"bytes_rx":      "1700",
+			"duration_secs": "600",
+		},
+	}, report)
+	assert.Nil(t, np.trackUsage("n1", task, makeFlowReport("2021-03-06T10:15:00Z", "f1", 160.0, 1600.0), open, false))
+
+	// reports whose record may have been delivered are always due
+	assert.NotNil(t, np.trackUsage("n1", task, makeFlowReport("2021-03-06T10:16:00Z", "f1", 160.0, 1600.0), open, true))
+
+	// sessions which aren't open aren't reported
+	assert.Nil(t, np.trackUsage("n1", task, makeFlowReport("2021-03-06T11:00:00Z", "f1", 200.0, 2000.0), map[string]bool{}, false))
+
+	// the usage of ended sessions and deleted tasks is forgotten
+	np.endUsage("n1", "t1", "s1")
+	assert.Empty(t, np.usage[getBackoffKey("n1", "t1")])
+	np.pruneUsage(map[string]bool{})
+	assert.Empty(t, np.usage)
+
+	// tasks not reporting usage skip flow reports
+	task.TaskDetails.UsageReportIntervalSecs = 0
+	assert.Nil(t, np.trackUsage("n1", task, makeFlowReport("2021-03-06T12:00:00Z", "f1", 300.0, 3000.0), open, false))
+}
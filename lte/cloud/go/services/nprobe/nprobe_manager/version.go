@@ -0,0 +1,115 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+)
+
+// moduleSelection is the module version and domain ID selected by a
+// destination. The domain ID is empty when the destination doesn't select
+// one.
+type moduleSelection struct {
+	version  string
+	domainID string
+}
+
+// addModuleVersion selects the module version and domain ID of a destination
+// for the tasks of its network and delivery type. When destinations
+// disagree, the first one listed applies.
+func addModuleVersion(
+	versions map[string]map[string]moduleSelection,
+	networkID string,
+	destination *models.NetworkProbeDestination,
+) {
+	details := destination.DestinationDetails
+	version, err := encoding.GetDomainModuleVersion(details.ModuleVersion, details.Hi2DomainID)
+	if err != nil {
+		logger.Errorf("Ignoring module version of destination %s of network %s: %s", destination.DestinationID, networkID, err)
+		return
+	}
+	if versions[networkID] == nil {
+		versions[networkID] = map[string]moduleSelection{}
+	}
+	selection := moduleSelection{version: version.Name, domainID: details.Hi2DomainID}
+	current, ok := versions[networkID][details.DeliveryType]
+	if ok && current != selection {
+		logger.Warningf(
+			"Ignoring module version %s and domain ID %q of destination %s of network %s conflicting with version %s and domain ID %q",
+			selection.version, selection.domainID, destination.DestinationID, networkID, current.version, current.domainID,
+		)
+		return
+	}
+	versions[networkID][details.DeliveryType] = selection
+}
+
+// setModuleVersions replaces the module versions selected by the destinations
+// and the domain IDs configured in the networks
+func (np *NProbeManager) setModuleVersions(versions map[string]map[string]moduleSelection, domainIDs map[string]encoding.DomainIDs) {
+	np.moduleVersionMutex.Lock()
+	defer np.moduleVersionMutex.Unlock()
+	np.moduleVersions = versions
+	np.domainIDs = domainIDs
+}
+
+// getModuleVersion returns the module version the records of a task are
+// encoded with. The module version is selected by the delivery of the task,
+// else a destination, else the network config, and defaults to the default
+// one. The domain ID identifying the records is selected along the module
+// version: a selection of the same module version without a domain ID keeps
+// the one selected before, and the records of a module version selected
+// without one are identified by the OID of its release. A domain ID
+// identifying the module version of another release in the network config
+// or destinations of the network falls back to the default module version.
+func (np *NProbeManager) getModuleVersion(networkID string, task *models.NetworkProbeTask) *encoding.ModuleVersion {
+	config := np.getNetworkConfig(networkID)
+	name, domainID := config.ModuleVersion, config.Hi2DomainID
+	if name == "" {
+		name = encoding.DefaultModuleVersion
+	}
+	np.moduleVersionMutex.RLock()
+	selection, ok := np.moduleVersions[networkID][task.TaskDetails.DeliveryType]
+	domainIDs := np.domainIDs[networkID]
+	np.moduleVersionMutex.RUnlock()
+	if ok {
+		name, domainID = selectModuleVersion(name, domainID, selection.version, selection.domainID)
+	}
+	if delivery := task.TaskDetails.Delivery; delivery != nil {
+		name, domainID = selectModuleVersion(name, domainID, delivery.ModuleVersion, delivery.Hi2DomainID)
+	}
+
+	version, err := encoding.GetDomainModuleVersion(name, domainID)
+	if err == nil {
+		err = domainIDs.Check(version)
+	}
+	if err != nil {
+		logger.Errorf("Ignoring module version %s and domain ID %q of task %s of network %s: %s", name, domainID, task.TaskID, networkID, err)
+		version, _ = encoding.GetModuleVersion(encoding.DefaultModuleVersion)
+	}
+	return version
+}
+
+// selectModuleVersion returns the module version and domain ID selected over
+// the current ones, the current domain ID being kept when the same module
+// version or none is selected without a domain ID
+func selectModuleVersion(name, domainID, selectedName, selectedDomainID string) (string, string) {
+	if selectedName != "" && selectedName != name {
+		return selectedName, selectedDomainID
+	}
+	if selectedDomainID != "" {
+		domainID = selectedDomainID
+	}
+	return name, domainID
+}
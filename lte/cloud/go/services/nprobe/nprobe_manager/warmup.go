@@ -0,0 +1,171 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/orc8r/cloud/go/services/configurator"
+	merrors "magma/orc8r/lib/go/errors"
+
+	"github.com/pkg/errors"
+)
+
+// warmupProgressInterval is the time between two progress reports of a warm-up
+const warmupProgressInterval = 10 * time.Second
+
+// WarmUp loads what the first pass of a term needs before it starts, with up
+// to WarmupConcurrency networks loaded at a time: the tasks of the networks,
+// the checkpoints of their stored state, and the connections to their
+// delivery functions, whose reachability is probed once loaded if enabled.
+// Otherwise the first pass loads them one network after the other, which
+// delays recovery after a restart in large deployments. A network failing to
+// load is loaded again by the pass.
+func (np *NProbeManager) WarmUp(ctx context.Context) {
+	networks, err := configurator.ListNetworksOfType(LteNetwork)
+	if err != nil {
+		logger.Errorf("Failed to retrieve lte network list for warm-up: %s", err)
+		return
+	}
+	np.loadNetworkConfigs(networks)
+	np.applyDestinationSettings(networks)
+
+	start := time.Now()
+	var loaded, tasksLoaded int64
+	metrics.WarmupPendingNetworks.Set(float64(len(networks)))
+
+	// the shared delivery connection is established along with the networks
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := np.Exporter.Connect(); err != nil {
+			logger.Warningf("Failed to connect to the delivery function during warm-up: %v", err)
+		}
+	}()
+
+	warmed := map[string]map[string]*models.NetworkProbeTask{}
+	warmedMutex := sync.Mutex{}
+	networkIDs := make(chan string)
+	for i := uint32(0); i < np.WarmupConcurrency && int(i) < len(networks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for networkID := range networkIDs {
+				tasks, ok := np.warmUpNetwork(ctx, networkID)
+				if ok {
+					warmedMutex.Lock()
+					warmed[networkID] = tasks
+					warmedMutex.Unlock()
+					atomic.AddInt64(&tasksLoaded, int64(len(tasks)))
+				}
+				atomic.AddInt64(&loaded, 1)
+				metrics.WarmupPendingNetworks.Dec()
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(warmupProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				logger.Infof("Warming up: %d/%d networks, %d tasks loaded",
+					atomic.LoadInt64(&loaded), len(networks), atomic.LoadInt64(&tasksLoaded))
+			}
+		}
+	}()
+
+	for _, networkID := range networks {
+		select {
+		case networkIDs <- networkID:
+		case <-ctx.Done():
+		}
+	}
+	close(networkIDs)
+	wg.Wait()
+	close(done)
+
+	np.warmedTasks = warmed
+	np.reconcileDestinations(ctx, warmed)
+	metrics.WarmupPendingNetworks.Set(0)
+	metrics.WarmupDuration.Set(time.Since(start).Seconds())
+	logger.Infof("Warmed up %d/%d networks, %d tasks loaded in %s",
+		len(warmed), len(networks), tasksLoaded, time.Since(start).Round(time.Millisecond))
+}
+
+// warmUpNetwork loads the tasks of a network, checkpoints their stored state,
+// or the cursors replicated by the previous term, and connects to their
+// delivery functions. The networks the pass doesn't
+// process, e.g. suspended by the kill switch, are skipped.
+func (np *NProbeManager) warmUpNetwork(ctx context.Context, networkID string) (map[string]*models.NetworkProbeTask, bool) {
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	suspended, err := np.KillSwitch.IsActive(networkID)
+	if suspended || err != nil || np.isDeliveryHeld(networkID) || np.isRegionHeld(networkID) {
+		return nil, false
+	}
+	tasks, err := np.loadNetworkProbeTasks(networkID)
+	if err != nil {
+		logger.Errorf("Failed to retrieve nprobe tasks of network %s during warm-up: %s", networkID, err)
+		return nil, false
+	}
+
+	connected := map[*exporter.RecordExporter]bool{np.Exporter: true}
+	for taskID, task := range tasks {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+		state, err := np.Storage.GetNProbeData(networkID, taskID)
+		if err == nil {
+			np.seedCheckpoint(networkID, taskID, state)
+		} else if errors.Cause(err) != merrors.ErrNotFound {
+			logger.Errorf("Failed to get state of task %s during warm-up: %v", taskID, err)
+		}
+
+		exp, err := np.getExporter(networkID, task)
+		if err != nil || connected[exp] {
+			continue
+		}
+		connected[exp] = true
+		if err := exp.Connect(); err != nil {
+			logger.Warningf("Failed to connect to the delivery function of task %s during warm-up: %v", taskID, err)
+		}
+	}
+	if np.StandbyReplication {
+		np.restoreReplicatedCursors(networkID)
+	}
+	return tasks, true
+}
+
+// listNetworkProbeTasks returns the tasks of a network loaded by the warm-up,
+// once, or retrieves them
+func (np *NProbeManager) listNetworkProbeTasks(networkID string) (map[string]*models.NetworkProbeTask, error) {
+	if tasks, ok := np.warmedTasks[networkID]; ok {
+		delete(np.warmedTasks, networkID)
+		return tasks, nil
+	}
+	return np.loadNetworkProbeTasks(networkID)
+}
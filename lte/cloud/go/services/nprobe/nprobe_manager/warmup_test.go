@@ -0,0 +1,56 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedCheckpoint(t *testing.T) {
+	np := &NProbeManager{
+		CheckpointMaxRecords:  50,
+		CheckpointMaxInterval: time.Hour,
+		checkpoints:           map[string]*taskCheckpoint{},
+	}
+	lastExported := time.Unix(1615000000, 0).UTC()
+	state := &models.NetworkProbeData{
+		LastExported:    strfmt.DateTime(lastExported),
+		SequenceNumber:  12,
+		RecordsExported: 12,
+	}
+	key := getBackoffKey("n1", "task1")
+
+	// without checkpoint, the state is stored on the first pass
+	assert.True(t, np.isCheckpointDue(key, state, time.Now()))
+
+	// the state loaded during the warm-up is not stored again until it moves
+	np.seedCheckpoint("n1", "task1", state)
+	assert.False(t, np.isCheckpointDue(key, state, time.Now()))
+	state.LastExported = strfmt.DateTime(lastExported.Add(time.Minute))
+	state.SequenceNumber, state.RecordsExported = 13, 13
+	assert.False(t, np.isCheckpointDue(key, state, time.Now()))
+	assert.True(t, np.isCheckpointDue(key, state, time.Now().Add(time.Hour)))
+
+	// a cursor not checkpointed yet is kept by the warm-up
+	np.seedCheckpoint("n1", "task1", &models.NetworkProbeData{SequenceNumber: 12, RecordsExported: 12})
+	restored := &models.NetworkProbeData{SequenceNumber: 12, RecordsExported: 12}
+	np.restoreCursor(key, restored)
+	assert.Equal(t, uint32(13), restored.SequenceNumber)
+}
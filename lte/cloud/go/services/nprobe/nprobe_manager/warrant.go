@@ -0,0 +1,275 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// The warrant of a task may be bounded in time by its start_time and
+// end_time, or its duration from its timestamp. The task is pending until its
+// warrant starts: its events before the start are skipped and it isn't
+// activated over HI1. Once its warrant ends, the records of its events up to
+// the end are delivered, then its interception is ended with an IRI-END and
+// the task expires. Expired tasks keep their state, visible through the APIs,
+// but are no longer processed until deleted. The warrant reaper wakes the
+// processing loop when a warrant starts or ends, so that tasks are activated
+// and expired on time rather than on the next poll of eventd.
+
+// warrantWindow is the time window of the warrant of a task, out of which
+// its events are not intercepted. Zero bounds leave the window open.
+type warrantWindow struct {
+	start, end time.Time
+}
+
+// newWarrantWindow returns the window of the warrant of a task
+func newWarrantWindow(details *models.NetworkProbeTaskDetails) warrantWindow {
+	start, end := models.GetWarrantWindow(details)
+	return warrantWindow{start: start, end: end}
+}
+
+// isBounded returns true if the warrant starts or ends at a given time
+func (w warrantWindow) isBounded() bool {
+	return !w.start.IsZero() || !w.end.IsZero()
+}
+
+// isPending returns true if the warrant didn't start yet
+func (w warrantWindow) isPending(now time.Time) bool {
+	return !w.start.IsZero() && now.Before(w.start)
+}
+
+// isOver returns true if the warrant ended
+func (w warrantWindow) isOver(now time.Time) bool {
+	return !w.end.IsZero() && !now.Before(w.end)
+}
+
+// contains returns true if an event timestamp is within the warrant. Events
+// of invalid timestamps match, their record being rejected as such when
+// encoded.
+func (w warrantWindow) contains(timestamp string) bool {
+	if !w.isBounded() {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return true
+	}
+	return !w.isPending(t) && !w.isOver(t)
+}
+
+// warrantReaper signals the starts and ends of the warrants of the tasks,
+// each task being scheduled by the pass processing it
+type warrantReaper struct {
+	mutex sync.Mutex
+	// due maps the tasks to the next start or end of their warrant
+	due   map[string]time.Time
+	timer *time.Timer
+	ready chan struct{}
+}
+
+func newWarrantReaper() *warrantReaper {
+	return &warrantReaper{
+		due:   map[string]time.Time{},
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// schedule signals the next start or end of the warrant of a task
+func (r *warrantReaper) schedule(key string, at time.Time, now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.due[key].Equal(at) {
+		return
+	}
+	r.due[key] = at
+	r.reset(now)
+}
+
+// retain stops signaling the warrants of the tasks which are not in keys
+func (r *warrantReaper) retain(keys map[string]bool, now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for key := range r.due {
+		if !keys[key] {
+			delete(r.due, key)
+		}
+	}
+	r.reset(now)
+}
+
+// reset arms the timer for the next start or end of a warrant. It must be
+// called with the mutex held.
+func (r *warrantReaper) reset(now time.Time) {
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	var next time.Time
+	for _, at := range r.due {
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	if next.IsZero() {
+		return
+	}
+	r.timer = time.AfterFunc(next.Sub(now), r.fire)
+}
+
+// fire signals the warrants which started or ended, and arms the timer for
+// the next ones
+func (r *warrantReaper) fire() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	now := time.Now()
+	for key, at := range r.due {
+		if !now.Before(at) {
+			delete(r.due, key)
+		}
+	}
+	r.reset(now)
+	select {
+	case r.ready <- struct{}{}:
+	default:
+	}
+}
+
+// WarrantDue returns a channel receiving a value once the warrant of a task
+// started or ended, or its statistics report is due, after the previous value
+// was received
+func (np *NProbeManager) WarrantDue() <-chan struct{} {
+	if np.warrants == nil {
+		return nil
+	}
+	return np.warrants.ready
+}
+
+// scheduleWarrant wakes the processing loop at the next start or end of the
+// warrant of a task
+func (np *NProbeManager) scheduleWarrant(networkID, taskID string, at time.Time) {
+	if np.warrants != nil {
+		np.warrants.schedule(getBackoffKey(networkID, taskID), at, time.Now())
+	}
+}
+
+// pruneWarrants drops the warrants of the tasks which no longer exist
+func (np *NProbeManager) pruneWarrants(keys map[string]bool) {
+	if np.warrants != nil {
+		np.warrants.retain(keys, time.Now())
+	}
+}
+
+// setWarrantState records the state of the time-bounded warrant of a task
+func (np *NProbeManager) setWarrantState(networkID, taskID string, state *models.NetworkProbeData, warrantState string) error {
+	if state.WarrantState == warrantState {
+		return nil
+	}
+	logger.Infof("Warrant of task %s is %s", taskID, warrantState)
+	state.WarrantState = warrantState
+	return np.storeState(networkID, taskID, state)
+}
+
+// holdPendingTask holds back a task until its warrant starts. Its watermark
+// is moved to the start of its warrant, so that its events before the start
+// are never fetched.
+func (np *NProbeManager) holdPendingTask(networkID string, task *models.NetworkProbeTask, state *models.NetworkProbeData, window warrantWindow) error {
+	taskID := string(task.TaskID)
+	np.scheduleWarrant(networkID, taskID, window.start)
+	// pending tasks are expected to be silent
+	if err := np.setRateAlarm(networkID, taskID, state, "", time.Now()); err != nil {
+		return err
+	}
+	if !time.Time(state.LastExported).Before(window.start) {
+		return np.setWarrantState(networkID, taskID, state, models.NetworkProbeDataWarrantStatePending)
+	}
+	state.LastExported = strfmt.DateTime(window.start)
+	state.ExportedEventIds = nil
+	state.WarrantState = models.NetworkProbeDataWarrantStatePending
+	return np.storeState(networkID, taskID, state)
+}
+
+// expireTask ends the interception of a task whose warrant ended with an
+// IRI-END, then records the task as expired
+func (np *NProbeManager) expireTask(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	window warrantWindow,
+) error {
+	taskID := string(task.TaskID)
+	if ended, err := np.endActiveBearers(ctx, networkID, task, state, window.end); ended {
+		if err != nil {
+			return err
+		}
+		return np.storeExpiredTask(networkID, taskID, state)
+	}
+	seq := getNextSequenceNumber(state)
+	record, err := encoding.MakeTerminalRecord(np.getRecordTask(networkID, task, state), np.getOperatorID(networkID), seq, window.end, np.getModuleVersion(networkID, task))
+	if err == nil {
+		record, err = np.prepareRecord(networkID, task, record)
+	}
+	if err != nil {
+		// the task can't be encoded at all, it must not outlive its warrant
+		logger.Errorf("Failed to build IRI-END of expired task %s, expiring it without: %s", taskID, err)
+		return np.storeExpiredTask(networkID, taskID, state)
+	}
+
+	exp, err := np.getExporter(networkID, task)
+	if err != nil {
+		return err
+	}
+	delivery := exp.SubmitRecords(
+		ctx,
+		getBackoffKey(networkID, taskID),
+		np.getTaskWeight(taskID),
+		task.TaskDetails.CorrelationID,
+		[][]byte{record},
+		np.MaxExportRetries,
+	)
+	if err := delivery.Wait(0); err != nil {
+		return err
+	}
+	metrics.RecordsExported.WithLabelValues(networkID).Inc()
+	np.storeDeliveryRecords(networkID, taskID, []models.NetworkProbeDeliveryRecord{
+		makeDeliveryRecord(task, record, seq, window.end, time.Now()),
+	})
+	state.RecordsExported++
+	state.SequenceNumber = seq + 1
+	// the interception of all sessions ended with the record
+	state.OpenSessions = nil
+	return np.storeExpiredTask(networkID, taskID, state)
+}
+
+// storeExpiredTask records a task as expired, after which it is no longer
+// processed
+func (np *NProbeManager) storeExpiredTask(networkID, taskID string, state *models.NetworkProbeData) error {
+	state.ExpiredAt = strfmt.DateTime(time.Now().UTC())
+	state.WarrantState = models.NetworkProbeDataWarrantStateExpired
+	if err := np.storeState(networkID, taskID, state); err != nil {
+		return err
+	}
+	logger.Infof("Warrant of task %s of network %s ended, its interception was ended", taskID, networkID)
+	metrics.TasksExpired.WithLabelValues(networkID).Inc()
+	np.enterTaskState(networkID, taskID, models.NetworkProbeTaskLifecycleStateTerminated, reasonEnded)
+	return nil
+}
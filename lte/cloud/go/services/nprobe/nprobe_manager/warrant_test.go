@@ -0,0 +1,94 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarrantWindow(t *testing.T) {
+	start := time.Date(2021, 5, 1, 8, 0, 0, 0, time.UTC)
+	end := start.Add(48 * time.Hour)
+	details := &models.NetworkProbeTaskDetails{
+		StartTime: strfmt.DateTime(start),
+		EndTime:   strfmt.DateTime(end),
+	}
+	window := newWarrantWindow(details)
+	assert.True(t, window.isBounded())
+	assert.True(t, window.isPending(start.Add(-time.Second)))
+	assert.False(t, window.isPending(start))
+	assert.False(t, window.isOver(end.Add(-time.Second)))
+	assert.True(t, window.isOver(end))
+
+	assert.False(t, window.contains("2021-05-01T07:59:59Z"))
+	assert.True(t, window.contains("2021-05-01T08:00:00Z"))
+	assert.True(t, window.contains("2021-05-03T07:59:59Z"))
+	assert.False(t, window.contains("2021-05-03T08:00:00Z"))
+	// invalid timestamps are rejected when encoded
+	assert.True(t, window.contains("yesterday"))
+
+	// the end is given by the duration from the timestamp when unset
+	duration := int64(3600)
+	details = &models.NetworkProbeTaskDetails{
+		Timestamp: strfmt.DateTime(start),
+		Duration:  &duration,
+	}
+	window = newWarrantWindow(details)
+	assert.True(t, window.isBounded())
+	assert.False(t, window.isPending(start.Add(-time.Hour)))
+	assert.True(t, window.isOver(start.Add(time.Hour)))
+	details.EndTime = strfmt.DateTime(end)
+	assert.False(t, newWarrantWindow(details).isOver(start.Add(time.Hour)))
+
+	// unbounded warrants never expire
+	window = newWarrantWindow(&models.NetworkProbeTaskDetails{Timestamp: strfmt.DateTime(start)})
+	assert.False(t, window.isBounded())
+	assert.False(t, window.isPending(time.Time{}))
+	assert.False(t, window.isOver(end))
+	assert.True(t, window.contains("2000-01-01T00:00:00Z"))
+}
+
+func TestWarrantReaper(t *testing.T) {
+	reaper := newWarrantReaper()
+	now := time.Now()
+	reaper.schedule("n1/t1", now.Add(time.Hour), now)
+	reaper.schedule("n1/t2", now.Add(20*time.Millisecond), now)
+	select {
+	case <-reaper.ready:
+	case <-time.After(time.Second):
+		t.Fatal("warrant of t2 not signaled")
+	}
+	reaper.mutex.Lock()
+	assert.Equal(t, map[string]time.Time{"n1/t1": now.Add(time.Hour)}, reaper.due)
+	reaper.mutex.Unlock()
+
+	// the warrants of the tasks gone are no longer signaled
+	reaper.schedule("n1/t3", time.Now().Add(20*time.Millisecond), time.Now())
+	reaper.retain(map[string]bool{"n1/t1": true}, time.Now())
+	select {
+	case <-reaper.ready:
+		t.Fatal("warrant of deleted t3 signaled")
+	case <-time.After(100 * time.Millisecond):
+	}
+	reaper.retain(nil, time.Now())
+	reaper.mutex.Lock()
+	assert.Nil(t, reaper.timer)
+	reaper.mutex.Unlock()
+}
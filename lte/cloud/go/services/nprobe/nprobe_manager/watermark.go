@@ -0,0 +1,152 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// The watermark of a task is the time of the last event it processed, to the
+// millisecond as kept by eventd, along with the IDs of the events processed
+// at that time. Events at the watermark are fetched again and the ones
+// already processed are skipped, so that no event is processed twice nor
+// skipped because it shares its timestamp with a processed one.
+
+// getEventID returns an ID identifying an event across fetches, whether
+// fetched from eventd or streamed by a gateway, derived from its content
+func getEventID(event *eventdM.Event) string {
+	timestamp := event.Timestamp
+	if t, err := time.Parse(time.RFC3339, event.Timestamp); err == nil {
+		timestamp = getWatermarkTime(t).Format(time.RFC3339Nano)
+	}
+	// the keys of decoded values are marshaled in order
+	value, _ := json.Marshal(event.Value)
+
+	h := sha256.New()
+	for _, field := range []string{event.StreamName, event.EventType, event.Tag, timestamp} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	h.Write(value)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// getWatermarkTime returns the time of an event as compared to the watermark
+func getWatermarkTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Millisecond)
+}
+
+// getFetchStart returns the time from which the events of a task are fetched.
+// States predating the watermark only track the time of the last event.
+func getFetchStart(state *models.NetworkProbeData) time.Time {
+	start := getWatermarkTime(time.Time(state.LastExported))
+	if len(state.ExportedEventIds) == 0 {
+		return start.Add(time.Millisecond)
+	}
+	return start
+}
+
+// skipProcessedEvents drops the events at the watermark already processed
+func skipProcessedEvents(events []eventdM.Event, state *models.NetworkProbeData) []eventdM.Event {
+	if len(state.ExportedEventIds) == 0 {
+		return events
+	}
+	processed := make(map[string]bool, len(state.ExportedEventIds))
+	for _, id := range state.ExportedEventIds {
+		processed[id] = true
+	}
+	ret := make([]eventdM.Event, 0, len(events))
+	for _, event := range events {
+		if !processed[getEventID(&event)] {
+			ret = append(ret, event)
+		}
+	}
+	return ret
+}
+
+// advanceWatermark moves the watermark of a task to an event it processed
+func advanceWatermark(state *models.NetworkProbeData, timestamp, eventID string) error {
+	ptime, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return err
+	}
+	if !getWatermarkTime(ptime).Equal(getWatermarkTime(time.Time(state.LastExported))) {
+		state.ExportedEventIds = nil
+	}
+	state.ExportedEventIds = append(state.ExportedEventIds, eventID)
+	state.LastExported = strfmt.DateTime(ptime)
+	return nil
+}
+
+// orderEvents sorts events by time, then by ID, so that the events of a task
+// are always processed in the same order whatever order they were fetched in.
+// Events whose timestamp is invalid keep their position.
+func orderEvents(events []eventdM.Event) {
+	times := make([]time.Time, 0, len(events))
+	ids := make([]string, 0, len(events))
+	for i := range events {
+		t, err := time.Parse(time.RFC3339, events[i].Timestamp)
+		if err != nil && len(times) != 0 {
+			t = times[len(times)-1]
+		}
+		times = append(times, getWatermarkTime(t))
+		ids = append(ids, getEventID(&events[i]))
+	}
+	sort.Stable(byTime{events: events, times: times, ids: ids})
+}
+
+// getReservedRecords returns the sequence numbers and classes reserved for
+// the events whose records were submitted but are not known to be delivered,
+// keyed by event ID
+func getReservedRecords(state *models.NetworkProbeData) map[string]*models.NetworkProbeReservedRecord {
+	reserved := map[string]*models.NetworkProbeReservedRecord{}
+	for _, record := range state.ReservedRecords {
+		if record != nil && record.SequenceNumber >= state.SequenceNumber {
+			reserved[record.EventID] = record
+		}
+	}
+	return reserved
+}
+
+// getNextSequenceNumber returns the sequence number of the next record of a
+// task, after the ones reserved
+func getNextSequenceNumber(state *models.NetworkProbeData) uint32 {
+	next := state.SequenceNumber
+	for _, record := range getReservedRecords(state) {
+		if record.SequenceNumber >= next {
+			next = record.SequenceNumber + 1
+		}
+	}
+	return next
+}
+
+// pruneReservedRecords drops the reservations of the records delivered
+func pruneReservedRecords(state *models.NetworkProbeData) {
+	var kept []*models.NetworkProbeReservedRecord
+	for _, record := range state.ReservedRecords {
+		if record != nil && record.SequenceNumber >= state.SequenceNumber {
+			kept = append(kept, record)
+		}
+	}
+	state.ReservedRecords = kept
+}
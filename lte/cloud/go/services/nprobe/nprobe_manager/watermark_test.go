@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEventID(t *testing.T) {
+	fetched := eventdM.Event{
+		StreamName: "mme",
+		EventType:  "attach_success",
+		Tag:        "IMSI001010000000001",
+		Timestamp:  "2021-02-18T10:00:01.000Z",
+		Value:      map[string]interface{}{"imsi": "IMSI001010000000001", "ip": "192.168.128.12"},
+	}
+	// the same event streamed by its gateway, with a finer timestamp
+	streamed := fetched
+	streamed.Timestamp = "2021-02-18T11:00:01.000400+01:00"
+	streamed.Value = map[string]interface{}{"ip": "192.168.128.12", "imsi": "IMSI001010000000001"}
+	assert.Equal(t, getEventID(&fetched), getEventID(&streamed))
+
+	other := fetched
+	other.Value = map[string]interface{}{"imsi": "IMSI001010000000002"}
+	assert.NotEqual(t, getEventID(&fetched), getEventID(&other))
+}
+
+func TestWatermark(t *testing.T) {
+	events := []eventdM.Event{
+		makeTimedEvent("session_created", "IMSI001010000000001", "2021-02-18T10:00:01.000Z"),
+		makeTimedEvent("attach_success", "IMSI001010000000001", "2021-02-18T10:00:01.000Z"),
+		makeTimedEvent("detach_success", "IMSI001010000000001", "2021-02-18T10:00:02.000Z"),
+	}
+	state := &models.NetworkProbeData{}
+	assert.NoError(t, advanceWatermark(state, events[0].Timestamp, getEventID(&events[0])))
+
+	// events sharing the time of the watermark are fetched again
+	assert.Equal(t, "2021-02-18T10:00:01Z", getFetchStart(state).Format(time.RFC3339Nano))
+	assert.Equal(t, events[1:], skipProcessedEvents(events, state))
+
+	assert.NoError(t, advanceWatermark(state, events[1].Timestamp, getEventID(&events[1])))
+	assert.Len(t, state.ExportedEventIds, 2)
+	assert.Equal(t, events[2:], skipProcessedEvents(events, state))
+	assert.NoError(t, advanceWatermark(state, events[2].Timestamp, getEventID(&events[2])))
+	assert.Equal(t, []string{getEventID(&events[2])}, state.ExportedEventIds)
+	assert.Error(t, advanceWatermark(state, "yesterday", "id"))
+
+	// states predating the watermark are fetched from the next millisecond
+	legacy := &models.NetworkProbeData{LastExported: state.LastExported}
+	assert.Equal(t, "2021-02-18T10:00:02.001Z", getFetchStart(legacy).Format(time.RFC3339Nano))
+	assert.Equal(t, events, skipProcessedEvents(events, legacy))
+}
+
+func TestOrderEvents(t *testing.T) {
+	events := []eventdM.Event{
+		makeTimedEvent("session_created", "IMSI001010000000001", "2021-02-18T10:00:02.000Z"),
+		makeTimedEvent("attach_success", "IMSI001010000000001", "2021-02-18T10:00:01.000Z"),
+		makeTimedEvent("session_updated", "IMSI001010000000001", "2021-02-18T10:00:01.000400Z"),
+		makeTimedEvent("detach_success", "IMSI001010000000001", "2021-02-18T10:00:01.000Z"),
+	}
+	reversed := make([]eventdM.Event, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		reversed = append(reversed, events[i])
+	}
+
+	orderEvents(events)
+	orderEvents(reversed)
+	assert.Equal(t, events, reversed)
+	assert.Equal(t, "session_created", events[3].EventType)
+}
+
+func TestReservedRecords(t *testing.T) {
+	state := &models.NetworkProbeData{
+		SequenceNumber: 5,
+		ReservedRecords: []*models.NetworkProbeReservedRecord{
+			{EventID: "a", SequenceNumber: 4},
+			{EventID: "b", SequenceNumber: 5},
+			{EventID: "c", SequenceNumber: 6},
+		},
+	}
+	assert.Equal(t, map[string]*models.NetworkProbeReservedRecord{
+		"b": {EventID: "b", SequenceNumber: 5},
+		"c": {EventID: "c", SequenceNumber: 6},
+	}, getReservedRecords(state))
+	assert.Equal(t, uint32(7), getNextSequenceNumber(state))
+
+	state.SequenceNumber = 6
+	pruneReservedRecords(state)
+	assert.Equal(t, []*models.NetworkProbeReservedRecord{{EventID: "c", SequenceNumber: 6}}, state.ReservedRecords)
+	state.SequenceNumber = 7
+	pruneReservedRecords(state)
+	assert.Empty(t, state.ReservedRecords)
+	assert.Equal(t, uint32(7), getNextSequenceNumber(state))
+
+	// the cursor of a task is restored along with its watermark
+	state.LastExported = strfmt.DateTime(time.Unix(1613642401, 0))
+	state.ExportedEventIds = []string{"d"}
+	assert.Equal(t, []string{"d"}, getExportCursor(state).exportedEventIDs)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	strfmt "github.com/go-openapi/strfmt"
+)
+
+// The records of a task are identified by its ID until its XID is rotated,
+// e.g. once its warrant is renewed under a new reference. The rotation is
+// requested through the API and applied on the next pass of the task: the
+// linkage records ending the stream of the current XID and starting the one
+// of the new XID are delivered together before any other record of the task,
+// after which its records are delivered under the new XID.
+
+// getRecordTask returns the task as identified in its records
+func (np *NProbeManager) getRecordTask(networkID string, task *models.NetworkProbeTask, state *models.NetworkProbeData) *models.NetworkProbeTask {
+	return encoding.WithXID(np.withHeaderIdentifiers(networkID, task), models.GetTaskXID(string(task.TaskID), state))
+}
+
+// isXIDRotationPending returns true if the XID rotation requested for a task
+// is not applied yet
+func isXIDRotationPending(task *models.NetworkProbeTask, state *models.NetworkProbeData, rotation *models.NetworkProbeTaskXidRotation) bool {
+	return len(rotation.Xid) != 0 && rotation.Xid != models.GetTaskXID(string(task.TaskID), state)
+}
+
+// deliverXIDRotation delivers the linkage records of a task whose XID
+// rotation was requested, after which its records are delivered under the
+// new XID. Like the terminal record, the records reserved before the rotation
+// are generated again with new numbers, under the new XID.
+func (np *NProbeManager) deliverXIDRotation(
+	ctx context.Context,
+	networkID string,
+	task *models.NetworkProbeTask,
+	state *models.NetworkProbeData,
+	rotation *models.NetworkProbeTaskXidRotation,
+) error {
+	taskID := string(task.TaskID)
+	previousXID := models.GetTaskXID(taskID, state)
+	rotatedAt := time.Time(rotation.RequestedAt)
+	seq := getNextSequenceNumber(state)
+	records, err := encoding.MakeLinkageRecords(
+		np.withHeaderIdentifiers(networkID, task), np.getOperatorID(networkID), seq, previousXID, rotation.Xid, rotatedAt, np.getModuleVersion(networkID, task),
+	)
+	if err == nil {
+		for i := range records {
+			if records[i], err = np.prepareRecord(networkID, task, records[i]); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		// the rotation is retried on the next pass rather than breaking the
+		// legal continuity of the records
+		logger.Errorf("Failed to build linkage records of task %s: %s", taskID, err)
+		return err
+	}
+
+	exp, err := np.getExporter(networkID, task)
+	if err != nil {
+		return err
+	}
+	delivery := exp.SubmitRecords(
+		ctx,
+		getBackoffKey(networkID, taskID),
+		np.getTaskWeight(taskID),
+		task.TaskDetails.CorrelationID,
+		records,
+		np.MaxExportRetries,
+	)
+	// both records are delivered again until the new stream is started
+	var delivered []models.NetworkProbeDeliveryRecord
+	for i, record := range records {
+		if err := delivery.Wait(i); err != nil {
+			return err
+		}
+		metrics.RecordsExported.WithLabelValues(networkID).Inc()
+		delivered = append(delivered, makeDeliveryRecord(task, record, seq+uint32(i), rotatedAt, time.Now()))
+	}
+	np.storeDeliveryRecords(networkID, taskID, delivered)
+	logger.Infof("Rotated XID of task %s from %s to %s", taskID, previousXID, rotation.Xid)
+
+	state.RecordsExported += uint64(len(records))
+	state.SequenceNumber = seq + uint32(len(records))
+	state.PreviousXid = previousXID
+	state.Xid = rotation.Xid
+	state.XidRotatedAt = strfmt.DateTime(time.Now().UTC())
+	return np.storeState(networkID, taskID, state)
+}
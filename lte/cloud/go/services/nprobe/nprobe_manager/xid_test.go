@@ -0,0 +1,49 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package npmanager
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXIDRotation(t *testing.T) {
+	np := &NProbeManager{}
+	task := &models.NetworkProbeTask{
+		TaskID:      "29f28e1c-f230-486a-a860-f5a784ab9177",
+		TaskDetails: &models.NetworkProbeTaskDetails{TargetID: "IMSI1234"},
+	}
+	state := &models.NetworkProbeData{TargetID: "IMSI1234"}
+	rotation := &models.NetworkProbeTaskXidRotation{}
+
+	// tasks never rotated are identified by their ID
+	assert.Equal(t, task, np.getRecordTask("n0", task, state))
+	assert.False(t, isXIDRotationPending(task, state, rotation))
+
+	rotation.Xid = "0a1d8c2e-0000-4000-8000-000000000001"
+	rotation.PreviousXid = string(task.TaskID)
+	assert.True(t, isXIDRotationPending(task, state, rotation))
+
+	// once applied, the records are identified by the new XID
+	state.Xid = rotation.Xid
+	state.PreviousXid = rotation.PreviousXid
+	assert.False(t, isXIDRotationPending(task, state, rotation))
+	recordTask := np.getRecordTask("n0", task, state)
+	assert.Equal(t, models.NetworkProbeTaskID(rotation.Xid), recordTask.TaskID)
+	assert.Equal(t, task.TaskDetails, recordTask.TaskDetails)
+	assert.Equal(t, models.NetworkProbeTaskID("29f28e1c-f230-486a-a860-f5a784ab9177"), task.TaskID)
+}
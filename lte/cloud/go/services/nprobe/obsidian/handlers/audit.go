@@ -0,0 +1,134 @@
+/*
+ * Copyright 2020 The Magma Authors.
+ *
+ * This source code is licensed under the BSD-style license found in the
+ * LICENSE file in the root directory of this source tree.
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+
+	"magma/orc8r/cloud/go/obsidian"
+	"magma/orc8r/cloud/go/obsidian/access"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+)
+
+// unknownActor identifies callers which presented no client certificate
+const unknownActor = "unknown"
+
+// AuditRequests returns the middleware recording every request made to the
+// nprobe handlers in the audit log of its network, whatever the handler
+// does: the client certificate and address of the caller, the hash of the
+// request body and the outcome of the request.
+func AuditRequests(storage storage.NProbeStorage) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			receivedAt := time.Now()
+			req := c.Request()
+			bodyHash, err := hashBody(req)
+			if err != nil {
+				return obsidian.HttpError(errors.Wrap(err, "failed to read request body"), http.StatusBadRequest)
+			}
+
+			herr := next(c)
+
+			networkID, nerr := obsidian.GetNetworkId(c)
+			if nerr != nil {
+				return herr
+			}
+			actor := getAuditActor(req)
+			entry := models.NetworkProbeAuditEntry{
+				Action:            models.NetworkProbeAuditEntryActionAPIRequest,
+				Actor:             actor,
+				CertificateSerial: req.Header.Get(access.CLIENT_CERT_SN_KEY),
+				Method:            req.Method,
+				Path:              req.URL.Path,
+				SourceIP:          c.RealIP(),
+				BodyHash:          bodyHash,
+				Timestamp:         strfmt.DateTime(receivedAt),
+			}
+			entry.StatusCode, entry.Error = getOutcome(c, herr)
+			// the request was already served, it is only left unaudited
+			if err := storage.StoreAuditEntry(networkID, entry); err != nil {
+				logger.Errorf("Failed to audit %s %s by %s: %v", entry.Method, entry.Path, actor, err)
+			}
+			return herr
+		}
+	}
+}
+
+// getAuditActor returns the operator a request is audited on behalf of, the
+// common name of its client certificate if any
+func getAuditActor(req *http.Request) string {
+	actor := req.Header.Get(access.CLIENT_CERT_CN_KEY)
+	if len(actor) == 0 {
+		return unknownActor
+	}
+	return actor
+}
+
+// hashBody returns the hex encoded SHA-256 hash of the body of a request,
+// which is left to be read by the handler, or an empty string if it has none
+func hashBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if len(body) == 0 {
+		return "", nil
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// getOutcome returns the status a request is answered with and the error
+// it failed with, before the error is handled by echo
+func getOutcome(c echo.Context, err error) (int64, string) {
+	if err == nil {
+		return int64(c.Response().Status), ""
+	}
+	if herr, ok := err.(*echo.HTTPError); ok {
+		return int64(herr.Code), fmt.Sprint(herr.Message)
+	}
+	return http.StatusInternalServerError, err.Error()
+}
+
+func getNetworkProbeAuditHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		ret, err := storage.GetAuditEntries(networkID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to get audit entries"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, ret)
+	}
+}
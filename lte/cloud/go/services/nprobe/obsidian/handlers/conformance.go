@@ -0,0 +1,79 @@
+/*
+ * Copyright 2020 The Magma Authors.
+ *
+ * This source code is licensed under the BSD-style license found in the
+ * LICENSE file in the root directory of this source tree.
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/tasks"
+
+	"magma/orc8r/cloud/go/obsidian"
+
+	"github.com/labstack/echo"
+)
+
+// checkConformance checks a record encoded by a third-party NE against the
+// rules followed by the records built by the service. The record is only
+// decoded, nothing is stored or exported.
+func checkConformance(c echo.Context) error {
+	networkID, nerr := obsidian.GetNetworkId(c)
+	if nerr != nil {
+		return nerr
+	}
+
+	payload := &models.NetworkProbeConformanceRequest{}
+	if err := c.Bind(payload); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err := payload.ValidateModel(); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if len(payload.Record) > encoding.DefaultMaxRecordSize {
+		err := fmt.Errorf("record of %d bytes exceeds the size limit of %d bytes", len(payload.Record), encoding.DefaultMaxRecordSize)
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+
+	// the records may be identified by the domain IDs configured in the network
+	domainIDs, err := tasks.GetDomainIDs(networkID, "")
+	if err != nil {
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	}
+	report := encoding.CheckConformance(payload.Record, time.Now(), domainIDs)
+	return c.JSON(http.StatusOK, toConformanceModel(report))
+}
+
+func toConformanceModel(report *encoding.ConformanceReport) *models.NetworkProbeConformanceReport {
+	ret := &models.NetworkProbeConformanceReport{
+		Conformant:     report.Conformant,
+		CorrelationID:  report.CorrelationID,
+		Event:          uint32(report.Event),
+		ModuleVersion:  report.ModuleVersion,
+		RecordClass:    report.Class,
+		SequenceNumber: report.SequenceNumber,
+		Xid:            report.XID,
+	}
+	for _, check := range report.Checks {
+		ret.Checks = append(ret.Checks, &models.NetworkProbeConformanceCheck{
+			Field:   check.Field,
+			Message: check.Message,
+			Result:  check.Result,
+			Rule:    check.Rule,
+		})
+	}
+	return ret
+}
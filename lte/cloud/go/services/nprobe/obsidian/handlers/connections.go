@@ -0,0 +1,123 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"magma/orc8r/cloud/go/obsidian"
+
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+)
+
+const (
+	NetworkProbeConnectionsPath = NetworkProbeAdminPath + obsidian.UrlSep + "connections"
+	NetworkProbeReconnectPath   = NetworkProbeConnectionsPath + obsidian.UrlSep + "reconnect"
+	NetworkProbeDrainPath       = NetworkProbeConnectionsPath + obsidian.UrlSep + "drain"
+
+	// DefaultDrainTimeout is the time a drain waits for the queues of the
+	// exporters to empty when the request sets none
+	DefaultDrainTimeout = 30 * time.Second
+)
+
+// Connections inspects and re-establishes the delivery connections of the
+// exporters. The exporters of a destination, or all of them when empty, are
+// reconnected or drained.
+type Connections interface {
+	GetConnections() []*models.NetworkProbeConnection
+	ReconnectExporters(destination string) ([]*models.NetworkProbeConnection, error)
+	DrainExporters(ctx context.Context, destination string) ([]*models.NetworkProbeConnection, error)
+}
+
+// GetConnectionHandlers returns the admin handlers inspecting the delivery
+// connections and forcing them to reconnect, so that operators recover a
+// connection without restarting the service.
+func GetConnectionHandlers(connections Connections) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeConnectionsPath, Methods: obsidian.GET, HandlerFunc: listConnectionsHandlerFunc(connections)},
+		{Path: NetworkProbeReconnectPath, Methods: obsidian.POST, HandlerFunc: reconnectHandlerFunc(connections)},
+		{Path: NetworkProbeDrainPath, Methods: obsidian.POST, HandlerFunc: drainHandlerFunc(connections)},
+	}
+}
+
+func listConnectionsHandlerFunc(connections Connections) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, nerr := getActor(c); nerr != nil {
+			return nerr
+		}
+		return c.JSON(http.StatusOK, connections.GetConnections())
+	}
+}
+
+func reconnectHandlerFunc(connections Connections) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+		destination := c.QueryParam("destination")
+		logger.With(logging.FieldDestination, destination).Infof("Reconnecting exporters on request of %s, destination: %q", actor, destination)
+		ret, err := connections.ReconnectExporters(destination)
+		if err != nil {
+			return toConnectionError(err)
+		}
+		return c.JSON(http.StatusOK, ret)
+	}
+}
+
+func drainHandlerFunc(connections Connections) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+		timeout := DefaultDrainTimeout
+		if param := c.QueryParam("timeout_secs"); len(param) != 0 {
+			secs, err := strconv.ParseUint(param, 10, 32)
+			if err != nil || secs == 0 {
+				return obsidian.HttpError(fmt.Errorf("invalid timeout_secs %s", param), http.StatusBadRequest)
+			}
+			timeout = time.Duration(secs) * time.Second
+		}
+		destination := c.QueryParam("destination")
+		logger.With(logging.FieldDestination, destination).Infof("Draining exporters on request of %s, destination: %q", actor, destination)
+		ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+		defer cancel()
+		ret, err := connections.DrainExporters(ctx, destination)
+		if err != nil {
+			return toConnectionError(err)
+		}
+		return c.JSON(http.StatusOK, ret)
+	}
+}
+
+// toConnectionError returns the status of a failed connection request
+func toConnectionError(err error) error {
+	switch errors.Cause(err) {
+	case exporter.ErrUnknownConnection:
+		return obsidian.HttpError(err, http.StatusNotFound)
+	case exporter.ErrDrainTimeout:
+		return obsidian.HttpError(err, http.StatusGatewayTimeout)
+	}
+	return obsidian.HttpError(err, http.StatusInternalServerError)
+}
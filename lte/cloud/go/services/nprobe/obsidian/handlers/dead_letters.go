@@ -0,0 +1,154 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+
+	"magma/orc8r/cloud/go/obsidian"
+	merrors "magma/orc8r/lib/go/errors"
+
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+)
+
+const (
+	NetworkProbeTaskDeadLettersPath     = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "dead_letters"
+	NetworkProbeTaskDeadLetterPath      = NetworkProbeTaskDeadLettersPath + obsidian.UrlSep + ":dead_letter_id"
+	NetworkProbeTaskDeadLetterRetryPath = NetworkProbeTaskDeadLetterPath + obsidian.UrlSep + "retry"
+)
+
+// DeadLetterRetrier delivers a dead letter of a task again. The dead letter
+// is returned along with the error when it fails to be delivered.
+type DeadLetterRetrier interface {
+	RetryDeadLetter(ctx context.Context, networkID, taskID, id string) (*models.NetworkProbeDeadLetter, error)
+}
+
+// GetDeadLetterHandlers returns the handlers listing, inspecting, delivering
+// again and discarding the records of the tasks set aside after being
+// rejected by their LEMF.
+func GetDeadLetterHandlers(storage storage.NProbeStorage, retrier DeadLetterRetrier) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeTaskDeadLettersPath, Methods: obsidian.GET, HandlerFunc: listDeadLettersHandlerFunc(storage)},
+		{Path: NetworkProbeTaskDeadLetterPath, Methods: obsidian.GET, HandlerFunc: getDeadLetterHandlerFunc(storage)},
+		{Path: NetworkProbeTaskDeadLetterPath, Methods: obsidian.DELETE, HandlerFunc: deleteDeadLetterHandlerFunc(storage)},
+		{Path: NetworkProbeTaskDeadLetterRetryPath, Methods: obsidian.POST, HandlerFunc: retryDeadLetterHandlerFunc(retrier)},
+	}
+}
+
+// listDeadLettersHandlerFunc lists the dead letters of a task without their
+// records, which are inspected one at a time
+func listDeadLettersHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		if _, nerr := getActor(c); nerr != nil {
+			return nerr
+		}
+
+		letters, err := storage.GetDeadLetters(values[0], values[1])
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load dead letters"), http.StatusInternalServerError)
+		}
+		for i := range letters {
+			letters[i].Record = nil
+		}
+		return c.JSON(http.StatusOK, letters)
+	}
+}
+
+// getDeadLetterHandlerFunc returns a dead letter with its record decoded,
+// the identities of the target being redacted
+func getDeadLetterHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id", "dead_letter_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		letter, err := storage.GetDeadLetter(values[0], values[1], values[2])
+		if errors.Cause(err) == merrors.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load dead letter"), http.StatusInternalServerError)
+		}
+		logger.With(logging.FieldNetworkID, values[0], logging.FieldTaskID, values[1], logging.FieldSeq, values[2]).Infof("Dead letter %s of task %s of network %s inspected by %s", values[2], values[1], values[0], actor)
+		letter.Decoded = renderRecord(c.Request().Context(), letter.Record)
+		return c.JSON(http.StatusOK, letter)
+	}
+}
+
+func deleteDeadLetterHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id", "dead_letter_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		logger.With(logging.FieldNetworkID, values[0], logging.FieldTaskID, values[1], logging.FieldSeq, values[2]).Warningf("Discarding dead letter %s of task %s of network %s on request of %s", values[2], values[1], values[0], actor)
+		if err := storage.DeleteDeadLetter(values[0], values[1], values[2]); err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to delete dead letter"), http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// retryDeadLetterHandlerFunc delivers a dead letter again, a record rejected
+// again being reported as a bad gateway
+func retryDeadLetterHandlerFunc(retrier DeadLetterRetrier) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id", "dead_letter_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		logger.With(logging.FieldNetworkID, values[0], logging.FieldTaskID, values[1], logging.FieldSeq, values[2]).Infof("Delivering dead letter %s of task %s of network %s again on request of %s", values[2], values[1], values[0], actor)
+		letter, err := retrier.RetryDeadLetter(c.Request().Context(), values[0], values[1], values[2])
+		if errors.Cause(err) == merrors.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		if err != nil && letter != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to deliver dead letter"), http.StatusBadGateway)
+		}
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to retry dead letter"), http.StatusInternalServerError)
+		}
+		letter.Record = nil
+		return c.JSON(http.StatusOK, letter)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"magma/orc8r/cloud/go/obsidian"
+	merrors "magma/orc8r/lib/go/errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+)
+
+const (
+	NetworkProbeDestinationTestPath = NetworkProbeDestinationDetailsPath + obsidian.UrlSep + "test"
+
+	// DefaultDestinationTestTimeout is the time a destination test waits for
+	// the delivery of its test record when the request sets none
+	DefaultDestinationTestTimeout = 30 * time.Second
+)
+
+// DestinationTester delivers a test record to a destination of a network and
+// completes the test with the outcome
+type DestinationTester interface {
+	TestDestination(ctx context.Context, networkID string, test *models.NetworkProbeDestinationTest) (*models.NetworkProbeDestinationTest, error)
+}
+
+// GetDestinationTestHandlers returns the handlers testing the path to the
+// LEMF of a destination, e.g. while its integration is validated, without
+// provisioning a task.
+func GetDestinationTestHandlers(tester DestinationTester) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeDestinationTestPath, Methods: obsidian.POST, HandlerFunc: testDestinationHandlerFunc(tester)},
+	}
+}
+
+// testDestinationHandlerFunc delivers a test record to a destination over
+// the live connection of its tasks and returns the round trip. A failed
+// delivery is reported by the test rather than by the status.
+func testDestinationHandlerFunc(tester DestinationTester) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "destination_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+		timeout := DefaultDestinationTestTimeout
+		if param := c.QueryParam("timeout_secs"); len(param) != 0 {
+			secs, err := strconv.ParseUint(param, 10, 32)
+			if err != nil || secs == 0 {
+				return obsidian.HttpError(fmt.Errorf("invalid timeout_secs %s", param), http.StatusBadRequest)
+			}
+			timeout = time.Duration(secs) * time.Second
+		}
+
+		id, err := uuid.NewV4()
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to generate destination test ID"), http.StatusInternalServerError)
+		}
+		networkID, destinationID := values[0], values[1]
+		logger.Infof("Testing destination %s of network %s on request of %s", destinationID, networkID, actor)
+		ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+		defer cancel()
+		ret, err := tester.TestDestination(ctx, networkID, &models.NetworkProbeDestinationTest{
+			ID:            id.String(),
+			DestinationID: destinationID,
+			RequestedBy:   actor,
+		})
+		if errors.Cause(err) == merrors.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to test destination"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, ret)
+	}
+}
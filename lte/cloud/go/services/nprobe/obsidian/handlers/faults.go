@@ -0,0 +1,105 @@
+//go:build with_fault_injection
+// +build with_fault_injection
+
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"magma/orc8r/cloud/go/obsidian"
+
+	"github.com/labstack/echo"
+)
+
+const NetworkProbeFaultsPath = NetworkProbeAdminPath + obsidian.UrlSep + "faults"
+
+// GetFaultInjectionHandlers returns the admin handlers injecting faults in
+// the connections to the delivery functions, e.g. to test the resilience of
+// the delivery path on a staging deployment. The faults cover the
+// connections of all networks, so they are served on the admin path, and
+// only by the builds with the with_fault_injection build tag.
+func GetFaultInjectionHandlers() []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeFaultsPath, Methods: obsidian.GET, HandlerFunc: getFaultsHandlerFunc},
+		{Path: NetworkProbeFaultsPath, Methods: obsidian.PUT, HandlerFunc: setFaultsHandlerFunc},
+		{Path: NetworkProbeFaultsPath, Methods: obsidian.DELETE, HandlerFunc: clearFaultsHandlerFunc},
+	}
+}
+
+func getFaultsHandlerFunc(c echo.Context) error {
+	if _, nerr := getActor(c); nerr != nil {
+		return nerr
+	}
+	ret := &models.NetworkProbeFaultInjection{}
+	if faults := exporter.GetFaults(); faults != nil {
+		ret = toFaultInjectionModel(*faults)
+	}
+	return c.JSON(http.StatusOK, ret)
+}
+
+func setFaultsHandlerFunc(c echo.Context) error {
+	actor, nerr := getActor(c)
+	if nerr != nil {
+		return nerr
+	}
+
+	payload := &models.NetworkProbeFaultInjection{}
+	if err := c.Bind(payload); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err := payload.ValidateModel(); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err := exporter.SetFaults(fromFaultInjectionModel(payload)); err != nil {
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	}
+	logger.Warningf("Faults injected in the connections to the delivery functions on request of %s", actor)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func clearFaultsHandlerFunc(c echo.Context) error {
+	if _, nerr := getActor(c); nerr != nil {
+		return nerr
+	}
+	exporter.ClearFaults()
+	return c.NoContent(http.StatusNoContent)
+}
+
+func fromFaultInjectionModel(m *models.NetworkProbeFaultInjection) exporter.Faults {
+	return exporter.Faults{
+		Destination:         m.Destination,
+		DropAfterBytes:      m.DropAfterBytes,
+		WriteDelay:          time.Duration(m.WriteDelayMs) * time.Millisecond,
+		CorruptAcks:         m.CorruptAcks,
+		FailReads:           m.FailReads,
+		RefuseRenegotiation: m.RefuseRenegotiation,
+	}
+}
+
+func toFaultInjectionModel(faults exporter.Faults) *models.NetworkProbeFaultInjection {
+	return &models.NetworkProbeFaultInjection{
+		Destination:         faults.Destination,
+		DropAfterBytes:      faults.DropAfterBytes,
+		WriteDelayMs:        uint32(faults.WriteDelay / time.Millisecond),
+		CorruptAcks:         faults.CorruptAcks,
+		FailReads:           faults.FailReads,
+		RefuseRenegotiation: faults.RefuseRenegotiation,
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !with_fault_injection
+// +build !with_fault_injection
+
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"magma/orc8r/cloud/go/obsidian"
+)
+
+// GetFaultInjectionHandlers stub: the builds without fault injection don't
+// serve the fault handlers
+func GetFaultInjectionHandlers() []obsidian.Handler {
+	return nil
+}
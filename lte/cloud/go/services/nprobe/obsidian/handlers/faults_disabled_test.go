@@ -0,0 +1,30 @@
+//go:build !with_fault_injection
+// +build !with_fault_injection
+
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers_test
+
+import (
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/handlers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the builds without fault injection don't serve the fault handlers at all
+func TestNetworkProbeFaultInjectionDisabled(t *testing.T) {
+	assert.Empty(t, handlers.GetFaultInjectionHandlers())
+}
@@ -0,0 +1,80 @@
+//go:build with_fault_injection
+// +build with_fault_injection
+
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/handlers"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/orc8r/cloud/go/obsidian"
+	"magma/orc8r/cloud/go/obsidian/access"
+	"magma/orc8r/cloud/go/obsidian/tests"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkProbeFaultInjection(t *testing.T) {
+	defer exporter.ClearFaults()
+
+	e := echo.New()
+	faultHandlers := handlers.GetFaultInjectionHandlers()
+	getFaults := tests.GetHandlerByPathAndMethod(t, faultHandlers, handlers.NetworkProbeFaultsPath, obsidian.GET).HandlerFunc
+	setFaults := tests.GetHandlerByPathAndMethod(t, faultHandlers, handlers.NetworkProbeFaultsPath, obsidian.PUT).HandlerFunc
+	clearFaults := tests.GetHandlerByPathAndMethod(t, faultHandlers, handlers.NetworkProbeFaultsPath, obsidian.DELETE).HandlerFunc
+	run := func(handler echo.HandlerFunc, method string, payload interface{}, actor string) (*httptest.ResponseRecorder, error) {
+		body, err := json.Marshal(payload)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(method, "/", bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if len(actor) != 0 {
+			req.Header.Set(access.CLIENT_CERT_CN_KEY, actor)
+		}
+		rec := httptest.NewRecorder()
+		return rec, handler(e.NewContext(req, rec))
+	}
+
+	// the faults cover all networks, so they are restricted to the administrators
+	_, err := run(setFaults, http.MethodPut, &models.NetworkProbeFaultInjection{Destination: "10.10.0.2:6666"}, "")
+	assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+
+	_, err = run(setFaults, http.MethodPut, &models.NetworkProbeFaultInjection{Destination: "10.10.0.2"}, "admin")
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	assert.Contains(t, err.Error(), "destination 10.10.0.2 is not a valid host:port address")
+
+	faults := &models.NetworkProbeFaultInjection{Destination: "10.10.0.2:6666", DropAfterBytes: 4096}
+	rec, err := run(setFaults, http.MethodPut, faults, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec, err = run(getFaults, http.MethodGet, nil, "admin")
+	assert.NoError(t, err)
+	var injected models.NetworkProbeFaultInjection
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &injected))
+	assert.Equal(t, *faults, injected)
+
+	rec, err = run(clearFaults, http.MethodDelete, nil, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Nil(t, exporter.GetFaults())
+}
@@ -14,25 +14,48 @@
 package handlers
 
 import (
-	"math/rand"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"magma/lte/cloud/go/lte"
 	"magma/lte/cloud/go/serdes"
 	"magma/lte/cloud/go/services/lte/obsidian/handlers"
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/debug"
+	"magma/lte/cloud/go/services/nprobe/declarative"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/lte/cloud/go/services/nprobe/killswitch"
+	"magma/lte/cloud/go/services/nprobe/logging"
 	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/lte/cloud/go/services/nprobe/signing"
+	"magma/lte/cloud/go/services/nprobe/snapshot"
 	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/lte/cloud/go/services/nprobe/tasks"
 
 	"magma/orc8r/cloud/go/obsidian"
+	"magma/orc8r/cloud/go/obsidian/access"
 	"magma/orc8r/cloud/go/services/configurator"
+	orc8rHandlers "magma/orc8r/cloud/go/services/orchestrator/obsidian/handlers"
+	storage2 "magma/orc8r/cloud/go/storage"
 	merrors "magma/orc8r/lib/go/errors"
 
 	strfmt "github.com/go-openapi/strfmt"
+	"github.com/gofrs/uuid"
 	"github.com/labstack/echo"
 	"github.com/pkg/errors"
 )
 
+var logger = logging.New(logging.ComponentHandlers)
+
 const (
 	NetworkProbePath = handlers.ManageNetworkPath + obsidian.UrlSep + "network_probe"
 
@@ -40,47 +63,259 @@ const (
 	NetworkProbeDestinationsPath = NetworkProbePath + obsidian.UrlSep + "destinations"
 
 	NetworkProbeTaskDetailsPath        = NetworkProbeTasksPath + obsidian.UrlSep + ":task_id"
+	NetworkProbeTaskValidatePath       = NetworkProbeTasksPath + obsidian.UrlSep + "validate"
+	NetworkProbeTasksBulkPath          = NetworkProbeTasksPath + obsidian.UrlSep + "bulk"
 	NetworkProbeDestinationDetailsPath = NetworkProbeDestinationsPath + obsidian.UrlSep + ":destination_id"
+
+	NetworkProbeTaskStatusPath     = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "status"
+	NetworkProbeTaskQuarantinePath = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "quarantine"
+	NetworkProbeTaskActivityPath   = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "activity"
+	NetworkProbeTaskStatisticsPath = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "statistics"
+	NetworkProbeTaskDeliveriesPath = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "deliveries"
+	NetworkProbeTaskPausePath      = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "pause"
+	NetworkProbeTaskResumePath     = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "resume"
+	NetworkProbeTaskLifecyclePath  = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "lifecycle"
+	NetworkProbeTaskRotateXIDPath  = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "rotate_xid"
+	NetworkProbeTaskReplayPath     = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "replay"
+	NetworkProbeTaskTestRecordPath = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "test_record"
+	NetworkProbeTaskBookmarksPath  = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "bookmarks"
+	NetworkProbeTaskRecordsPath    = NetworkProbeTaskDetailsPath + obsidian.UrlSep + "records"
+	NetworkProbeTaskBookmarkPath   = NetworkProbeTaskBookmarksPath + obsidian.UrlSep + ":bookmark_name"
+	NetworkProbeSnapshotPath       = NetworkProbePath + obsidian.UrlSep + "snapshot"
+	NetworkProbeDebugPath          = NetworkProbePath + obsidian.UrlSep + "debug"
+	NetworkProbeAuditPath          = NetworkProbePath + obsidian.UrlSep + "audit"
+	NetworkProbeSessionsPath       = NetworkProbePath + obsidian.UrlSep + "sessions"
+
+	NetworkProbeBearerCorrelationsPath = NetworkProbePath + obsidian.UrlSep + "bearer_correlations"
+	NetworkProbeSyncStatePath          = NetworkProbePath + obsidian.UrlSep + "sync_state"
+
+	NetworkProbeKillSwitchPath      = NetworkProbePath + obsidian.UrlSep + "kill_switch"
+	NetworkProbeKillSwitchAuditPath = NetworkProbeKillSwitchPath + obsidian.UrlSep + "audit"
+
+	NetworkProbeCertificatePath       = NetworkProbePath + obsidian.UrlSep + "certificate"
+	NetworkProbeCertificateReloadPath = NetworkProbeCertificatePath + obsidian.UrlSep + "reload"
+	NetworkProbeSigningKeyPath        = NetworkProbePath + obsidian.UrlSep + "signing_key"
+
+	NetworkProbeHealthPath       = NetworkProbePath + obsidian.UrlSep + "health"
+	NetworkProbeReadinessPath    = NetworkProbeHealthPath + obsidian.UrlSep + "ready"
+	NetworkProbeConformancePath  = NetworkProbePath + obsidian.UrlSep + "conformance"
+	NetworkProbeConfigPath       = NetworkProbePath + obsidian.UrlSep + "config"
+	NetworkProbeConfigExportPath = NetworkProbeConfigPath + obsidian.UrlSep + "export"
+	NetworkProbeConfigApplyPath  = NetworkProbeConfigPath + obsidian.UrlSep + "apply"
 )
 
+// defaultRecordCount is the number of captured records rendered when the
+// count isn't set
+const defaultRecordCount = 10
+
+// mimeApplicationYAML is the content type of the declarative configurations
+// exported and applied in YAML
+const mimeApplicationYAML = "application/yaml"
+
 func GetHandlers(storage storage.NProbeStorage) []obsidian.Handler {
 	ret := []obsidian.Handler{
-		{Path: NetworkProbeTasksPath, Methods: obsidian.GET, HandlerFunc: listNetworkProbeTasks},
+		{Path: NetworkProbeTasksPath, Methods: obsidian.GET, HandlerFunc: getListNetworkProbeTasksHandlerFunc(storage)},
 		{Path: NetworkProbeTasksPath, Methods: obsidian.POST, HandlerFunc: getCreateNetworkProbeTaskHandlerFunc(storage)},
+		{Path: NetworkProbeTasksBulkPath, Methods: obsidian.POST, HandlerFunc: getCreateNetworkProbeTasksBulkHandlerFunc(storage)},
 		{Path: NetworkProbeTaskDetailsPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTask},
-		{Path: NetworkProbeTaskDetailsPath, Methods: obsidian.PUT, HandlerFunc: updateNetworkProbeTask},
+		{Path: NetworkProbeTaskDetailsPath, Methods: obsidian.PUT, HandlerFunc: getUpdateNetworkProbeTaskHandlerFunc(storage)},
 		{Path: NetworkProbeTaskDetailsPath, Methods: obsidian.DELETE, HandlerFunc: getDeleteNetworkProbeTaskHandlerFunc(storage)},
+		{Path: NetworkProbeTaskStatusPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTaskStatusHandlerFunc(storage)},
+		{Path: NetworkProbeTaskQuarantinePath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTaskQuarantineHandlerFunc(storage)},
+		{Path: NetworkProbeTaskActivityPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTaskActivityHandlerFunc(storage)},
+		{Path: NetworkProbeTaskStatisticsPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTaskStatisticsHandlerFunc(storage)},
+		{Path: NetworkProbeTaskDeliveriesPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTaskDeliveriesHandlerFunc(storage)},
+		{Path: NetworkProbeTaskPausePath, Methods: obsidian.POST, HandlerFunc: getPauseNetworkProbeTaskHandlerFunc(storage)},
+		{Path: NetworkProbeTaskResumePath, Methods: obsidian.POST, HandlerFunc: getResumeNetworkProbeTaskHandlerFunc(storage)},
+		{Path: NetworkProbeTaskLifecyclePath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTaskLifecycleHandlerFunc(storage)},
+		{Path: NetworkProbeTaskLifecyclePath, Methods: obsidian.POST, HandlerFunc: getTransitionNetworkProbeTaskHandlerFunc(storage)},
+		{Path: NetworkProbeTaskRotateXIDPath, Methods: obsidian.POST, HandlerFunc: getRotateNetworkProbeTaskXIDHandlerFunc(storage)},
+		{Path: NetworkProbeTaskReplayPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTaskReplayHandlerFunc(storage)},
+		{Path: NetworkProbeTaskReplayPath, Methods: obsidian.POST, HandlerFunc: getReplayNetworkProbeTaskHandlerFunc(storage)},
+		{Path: NetworkProbeTaskTestRecordPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTaskTestRecordHandlerFunc(storage)},
+		{Path: NetworkProbeTaskTestRecordPath, Methods: obsidian.POST, HandlerFunc: getInjectNetworkProbeTaskTestRecordHandlerFunc(storage)},
+		{Path: NetworkProbeTaskBookmarksPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTaskBookmarksHandlerFunc(storage)},
+		{Path: NetworkProbeTaskBookmarkPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeTaskBookmarkHandlerFunc(storage)},
+		{Path: NetworkProbeTaskBookmarkPath, Methods: obsidian.PUT, HandlerFunc: getSetNetworkProbeTaskBookmarkHandlerFunc(storage)},
+		{Path: NetworkProbeTaskBookmarkPath, Methods: obsidian.DELETE, HandlerFunc: getDeleteNetworkProbeTaskBookmarkHandlerFunc(storage)},
 
 		{Path: NetworkProbeDestinationsPath, Methods: obsidian.GET, HandlerFunc: listNetworkProbeDestinations},
 		{Path: NetworkProbeDestinationsPath, Methods: obsidian.POST, HandlerFunc: createNetworkProbeDestination},
 		{Path: NetworkProbeDestinationDetailsPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeDestination},
 		{Path: NetworkProbeDestinationDetailsPath, Methods: obsidian.PUT, HandlerFunc: updateNetworkProbeDestination},
 		{Path: NetworkProbeDestinationDetailsPath, Methods: obsidian.DELETE, HandlerFunc: deleteNetworkProbeDestination},
+
+		{Path: NetworkProbeAuditPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeAuditHandlerFunc(storage)},
+		{Path: NetworkProbeSessionsPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeSessionsHandlerFunc(storage)},
+		{Path: NetworkProbeBearerCorrelationsPath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeBearerCorrelationsHandlerFunc(storage)},
+		{Path: NetworkProbeSyncStatePath, Methods: obsidian.GET, HandlerFunc: getNetworkProbeSyncStateHandlerFunc(storage)},
+
+		{Path: NetworkProbeConformancePath, Methods: obsidian.POST, HandlerFunc: checkConformance},
+
+		{Path: NetworkProbeConfigExportPath, Methods: obsidian.GET, HandlerFunc: exportNetworkProbeConfig},
+		{Path: NetworkProbeConfigApplyPath, Methods: obsidian.POST, HandlerFunc: applyNetworkProbeConfig},
 	}
+	ret = append(ret, orc8rHandlers.GetPartialNetworkHandlers(NetworkProbeConfigPath, &models.NetworkProbeNetworkConfig{}, lte.NetworkProbeConfigType, serdes.Network)...)
 	return ret
 }
 
-func listNetworkProbeTasks(c echo.Context) error {
-	networkID, nerr := obsidian.GetNetworkId(c)
-	if nerr != nil {
-		return nerr
+// GetSnapshotHandlers returns the admin handlers taking and restoring
+// encrypted snapshots of the nprobe state of a network.
+func GetSnapshotHandlers(storage storage.NProbeStorage, key []byte) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeSnapshotPath, Methods: obsidian.GET, HandlerFunc: getTakeSnapshotHandlerFunc(storage, key)},
+		{Path: NetworkProbeSnapshotPath, Methods: obsidian.POST, HandlerFunc: getRestoreSnapshotHandlerFunc(storage, key)},
 	}
+}
 
-	ents, _, err := configurator.LoadAllEntitiesOfType(
-		networkID, lte.NetworkProbeTaskEntityType,
+// GetDebugHandlers returns the admin handlers managing the runtime
+// debug settings of the nprobe service.
+func GetDebugHandlers(settings *debug.Settings) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeDebugPath, Methods: obsidian.GET, HandlerFunc: getDebugConfigHandlerFunc(settings)},
+		{Path: NetworkProbeDebugPath, Methods: obsidian.PUT, HandlerFunc: getUpdateDebugConfigHandlerFunc(settings)},
+		{Path: NetworkProbeDebugPath, Methods: obsidian.DELETE, HandlerFunc: getDeleteDebugConfigHandlerFunc(settings)},
+		{Path: NetworkProbeTaskRecordsPath, Methods: obsidian.GET, HandlerFunc: getTaskRecordsHandlerFunc(settings)},
+	}
+}
+
+// GetKillSwitchHandlers returns the admin handlers suspending and resuming
+// all interception of a network. Operations are attributed to the client
+// certificate of the caller and recorded in the audit log of the network.
+func GetKillSwitchHandlers(killSwitch *killswitch.Switch) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeKillSwitchPath, Methods: obsidian.GET, HandlerFunc: getKillSwitchHandlerFunc(killSwitch)},
+		{Path: NetworkProbeKillSwitchPath, Methods: obsidian.POST, HandlerFunc: getActivateKillSwitchHandlerFunc(killSwitch)},
+		{Path: NetworkProbeKillSwitchPath, Methods: obsidian.DELETE, HandlerFunc: getDeactivateKillSwitchHandlerFunc(killSwitch)},
+		{Path: NetworkProbeKillSwitchAuditPath, Methods: obsidian.GET, HandlerFunc: getKillSwitchAuditHandlerFunc(killSwitch)},
+	}
+}
+
+// GetCertificateHandlers returns the admin handlers inspecting and reloading
+// the exporter client certificate, e.g. once rotated.
+func GetCertificateHandlers(certs *exporter.CertificateStore) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeCertificatePath, Methods: obsidian.GET, HandlerFunc: getCertificateHandlerFunc(certs)},
+		{Path: NetworkProbeCertificateReloadPath, Methods: obsidian.POST, HandlerFunc: getReloadCertificateHandlerFunc(certs)},
+	}
+}
+
+// SigningKey returns the signer of the delivered records, nil if records
+// were never signed
+type SigningKey func() *signing.Signer
+
+// GetSigningHandlers returns the handlers serving the public key verifying
+// the signatures of the delivered records
+func GetSigningHandlers(signer SigningKey) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeSigningKeyPath, Methods: obsidian.GET, HandlerFunc: getSigningKeyHandlerFunc(signer)},
+	}
+}
+
+// GetValidationHandlers returns the handlers validating tasks without
+// provisioning them, which check the exporter certificate and delivery.
+func GetValidationHandlers(certs *exporter.CertificateStore, exp *exporter.RecordExporter) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeTaskValidatePath, Methods: obsidian.POST, HandlerFunc: getValidateNetworkProbeTaskHandlerFunc(certs, exp)},
+	}
+}
+
+// GetHealthHandlers returns the handlers reporting the health of the
+// service and of its components, e.g. for the NMS, and its readiness, e.g.
+// for the monitoring of the orchestrator.
+func GetHealthHandlers(registry *health.Registry) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeHealthPath, Methods: obsidian.GET, HandlerFunc: getHealthHandlerFunc(registry)},
+		{Path: NetworkProbeReadinessPath, Methods: obsidian.GET, HandlerFunc: getReadinessHandlerFunc(registry)},
+	}
+}
+
+// taskListParams are the query parameters selecting a page of the tasks
+var taskListParams = []string{"page_size", "page_token", "target_id", "state", "sort"}
+
+// getListNetworkProbeTasksHandlerFunc lists all tasks keyed by task ID, or a
+// page of them when queried with any of taskListParams. The pages are
+// selected from the states of the tasks, only the tasks of the page being
+// loaded.
+func getListNetworkProbeTasksHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+		for _, param := range taskListParams {
+			if len(c.QueryParam(param)) != 0 {
+				return listNetworkProbeTaskPage(c, storage, networkID)
+			}
+		}
+
+		ents, _, err := configurator.LoadAllEntitiesOfType(
+			networkID, lte.NetworkProbeTaskEntityType,
+			configurator.EntityLoadCriteria{LoadConfig: true},
+			serdes.Entity,
+		)
+		if err == merrors.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load existing NetworkProbeTasks"), http.StatusInternalServerError)
+		}
+
+		ret := make(map[string]*models.NetworkProbeTask, len(ents))
+		for _, ent := range ents {
+			ret[ent.Key] = (&models.NetworkProbeTask{}).FromBackendModels(ent)
+		}
+		return c.JSON(http.StatusOK, ret)
+	}
+}
+
+func listNetworkProbeTaskPage(c echo.Context, store storage.NProbeStorage, networkID string) error {
+	query := storage.TaskQuery{
+		TargetID:     c.QueryParam("target_id"),
+		WarrantState: c.QueryParam("state"),
+		Sort:         c.QueryParam("sort"),
+		PageToken:    c.QueryParam("page_token"),
+	}
+	if value := c.QueryParam("page_size"); len(value) != 0 {
+		pageSize, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "invalid page size"), http.StatusBadRequest)
+		}
+		query.PageSize = uint32(pageSize)
+	}
+	if err := query.Validate(); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+
+	taskIDs, nextPageToken, err := store.ListNProbeTasks(networkID, query)
+	if err != nil {
+		return obsidian.HttpError(errors.Wrap(err, "failed to list NetworkProbeTasks"), http.StatusInternalServerError)
+	}
+	ret := &models.NetworkProbeTaskPage{NextPageToken: &nextPageToken, Tasks: []*models.NetworkProbeTask{}}
+	if len(taskIDs) == 0 {
+		return c.JSON(http.StatusOK, ret)
+	}
+	tks := make(storage2.TKs, 0, len(taskIDs))
+	for _, taskID := range taskIDs {
+		tks = append(tks, storage2.TypeAndKey{Type: lte.NetworkProbeTaskEntityType, Key: taskID})
+	}
+	ents, _, err := configurator.LoadEntities(
+		networkID, nil, nil, nil, tks,
 		configurator.EntityLoadCriteria{LoadConfig: true},
 		serdes.Entity,
 	)
-	if err == merrors.ErrNotFound {
-		return echo.ErrNotFound
-	}
 	if err != nil {
-		return obsidian.HttpError(errors.Wrap(err, "failed to load existing NetworkProbeTasks"), http.StatusInternalServerError)
+		return obsidian.HttpError(errors.Wrap(err, "failed to load NetworkProbeTasks"), http.StatusInternalServerError)
 	}
-
-	ret := make(map[string]*models.NetworkProbeTask, len(ents))
+	// the tasks deleted since their state was listed are skipped
+	byID := make(map[string]configurator.NetworkEntity, len(ents))
 	for _, ent := range ents {
-		ret[ent.Key] = (&models.NetworkProbeTask{}).FromBackendModels(ent)
+		byID[ent.Key] = ent
+	}
+	for _, taskID := range taskIDs {
+		if ent, ok := byID[taskID]; ok {
+			ret.Tasks = append(ret.Tasks, (&models.NetworkProbeTask{}).FromBackendModels(ent))
+		}
 	}
 	return c.JSON(http.StatusOK, ret)
 }
@@ -100,36 +335,58 @@ func getCreateNetworkProbeTaskHandlerFunc(storage storage.NProbeStorage) echo.Ha
 			return obsidian.HttpError(err, http.StatusBadRequest)
 		}
 
-		// generate random correlation ID if not provided
-		if payload.TaskDetails.CorrelationID == 0 {
-			payload.TaskDetails.CorrelationID = rand.Uint64()
+		err := tasks.Create(storage, networkID, payload, getAuditActor(c.Request()))
+		if err == tasks.ErrTaskExists {
+			return obsidian.HttpError(err, http.StatusConflict)
+		}
+		if err == tasks.ErrUnknownDestination || err == tasks.ErrNotTestPLMN || errors.Cause(err) == tasks.ErrDomainIDConflict {
+			return obsidian.HttpError(err, http.StatusBadRequest)
 		}
+		if err == tasks.ErrProtectedTarget {
+			return obsidian.HttpError(err, http.StatusForbidden)
+		}
+		if err == tasks.ErrTaskQuotaExceeded {
+			return obsidian.HttpError(err, http.StatusTooManyRequests)
+		}
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusCreated)
+	}
+}
 
-		payload.TaskDetails.Timestamp = strfmt.DateTime(time.Now().UTC())
-		data := models.NetworkProbeData{
-			LastExported:   payload.TaskDetails.Timestamp,
-			TargetID:       payload.TaskDetails.TargetID,
-			SequenceNumber: 0,
+func getCreateNetworkProbeTasksBulkHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
 		}
 
-		taskID := string(payload.TaskID)
-		if err := storage.StoreNProbeData(networkID, taskID, data); err != nil {
-			return obsidian.HttpError(errors.Wrap(err, "failed to store NetworkProbeData"), http.StatusInternalServerError)
+		payload := &models.NetworkProbeTaskBulkRequest{}
+		if err := c.Bind(payload); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		if err := payload.ValidateModel(); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
 		}
 
-		_, err := configurator.CreateEntity(
-			networkID,
-			configurator.NetworkEntity{
-				Type:   lte.NetworkProbeTaskEntityType,
-				Key:    taskID,
-				Config: payload.TaskDetails,
-			},
-			serdes.Entity,
-		)
-		if err != nil {
+		result, err := tasks.CreateBulk(storage, networkID, payload, getAuditActor(c.Request()))
+		switch errors.Cause(err) {
+		case nil:
+			return c.JSON(http.StatusCreated, result)
+		case tasks.ErrInvalidTargets:
+			return c.JSON(http.StatusBadRequest, result)
+		case tasks.ErrTaskExists:
+			return c.JSON(http.StatusConflict, result)
+		case tasks.ErrUnknownDestination, tasks.ErrNotTestPLMN, tasks.ErrDomainIDConflict:
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		case tasks.ErrProtectedTarget:
+			return obsidian.HttpError(err, http.StatusForbidden)
+		case tasks.ErrTaskQuotaExceeded:
+			return obsidian.HttpError(err, http.StatusTooManyRequests)
+		default:
 			return obsidian.HttpError(err, http.StatusInternalServerError)
 		}
-		return c.NoContent(http.StatusCreated)
 	}
 }
 
@@ -157,27 +414,50 @@ func getNetworkProbeTask(c echo.Context) error {
 	return c.JSON(http.StatusOK, ret)
 }
 
-func updateNetworkProbeTask(c echo.Context) error {
-	networkID, nerr := obsidian.GetNetworkId(c)
-	if nerr != nil {
-		return nerr
-	}
+func getUpdateNetworkProbeTaskHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
 
-	payload := &models.NetworkProbeTask{}
-	if err := c.Bind(payload); err != nil {
-		return obsidian.HttpError(err, http.StatusBadRequest)
-	}
-	if err := payload.ValidateModel(); err != nil {
-		return obsidian.HttpError(err, http.StatusBadRequest)
-	}
+		payload := &models.NetworkProbeTask{}
+		if err := c.Bind(payload); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		if err := payload.ValidateModel(); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		err := tasks.CheckDestination(networkID, payload.TaskDetails)
+		if err == nil {
+			err = tasks.CheckTestPLMN(networkID, payload.TaskDetails)
+		}
+		if err == nil {
+			err = tasks.CheckTaskDomainID(networkID, payload.TaskDetails)
+		}
+		if err == nil {
+			err = tasks.CheckProtectedTarget(storage, networkID, payload, getAuditActor(c.Request()))
+		}
+		if err == tasks.ErrUnknownDestination || err == tasks.ErrNotTestPLMN || errors.Cause(err) == tasks.ErrDomainIDConflict {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		if err == tasks.ErrProtectedTarget {
+			return obsidian.HttpError(err, http.StatusForbidden)
+		}
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusInternalServerError)
+		}
 
-	_, err := configurator.UpdateEntity(networkID, payload.ToEntityUpdateCriteria(), serdes.Entity)
-	if err != nil {
-		return obsidian.HttpError(err, http.StatusInternalServerError)
+		_, err = configurator.UpdateEntity(networkID, payload.ToEntityUpdateCriteria(), serdes.Entity)
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusNoContent)
 	}
-	return c.NoContent(http.StatusNoContent)
 }
 
+// getDeleteNetworkProbeTaskHandlerFunc requests the deletion of a task. The
+// manager deletes it once its interception is ended with an IRI-END.
 func getDeleteNetworkProbeTaskHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		paramNames := []string{"network_id", "task_id"}
@@ -185,111 +465,813 @@ func getDeleteNetworkProbeTaskHandlerFunc(storage storage.NProbeStorage) echo.Ha
 		if nerr != nil {
 			return nerr
 		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
 
 		networkID, taskID := values[0], values[1]
-		storage.DeleteNProbeData(networkID, taskID)
-		err := configurator.DeleteEntity(networkID, lte.NetworkProbeTaskEntityType, taskID)
+		deletion, err := tasks.RequestDeletion(storage, networkID, taskID, actor)
+		if err == merrors.ErrNotFound {
+			return echo.ErrNotFound
+		}
 		if err != nil {
 			return obsidian.HttpError(err, http.StatusInternalServerError)
 		}
-		return c.NoContent(http.StatusNoContent)
+		return c.JSON(http.StatusAccepted, deletion)
 	}
 }
 
-func listNetworkProbeDestinations(c echo.Context) error {
-	networkID, nerr := obsidian.GetNetworkId(c)
-	if nerr != nil {
-		return nerr
-	}
-
-	ents, _, err := configurator.LoadAllEntitiesOfType(
-		networkID, lte.NetworkProbeDestinationEntityType,
-		configurator.EntityLoadCriteria{LoadConfig: true},
-		serdes.Entity,
-	)
-	if err != nil {
-		return obsidian.HttpError(err, http.StatusInternalServerError)
-	}
+func getNetworkProbeTaskStatusHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
 
-	ret := make(map[string]*models.NetworkProbeDestination, len(ents))
-	for _, ent := range ents {
-		ret[ent.Key] = (&models.NetworkProbeDestination{}).FromBackendModels(ent)
+		networkID, taskID := values[0], values[1]
+		data, err := storage.GetNProbeData(networkID, taskID)
+		if errors.Cause(err) == merrors.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load NetworkProbeData"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, data)
 	}
-	return c.JSON(http.StatusOK, ret)
 }
 
-func createNetworkProbeDestination(c echo.Context) error {
-	networkID, nerr := obsidian.GetNetworkId(c)
-	if nerr != nil {
-		return nerr
-	}
-
-	payload := &models.NetworkProbeDestination{}
-	if err := c.Bind(payload); err != nil {
-		return obsidian.HttpError(err, http.StatusBadRequest)
-	}
-	if err := payload.ValidateModel(); err != nil {
-		return obsidian.HttpError(err, http.StatusBadRequest)
-	}
+func getNetworkProbeTaskQuarantineHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
 
-	_, err := configurator.CreateEntity(
-		networkID,
-		configurator.NetworkEntity{
-			Type:   lte.NetworkProbeDestinationEntityType,
-			Key:    string(payload.DestinationID),
-			Config: payload.DestinationDetails,
-		},
-		serdes.Entity,
-	)
-	if err != nil {
-		return obsidian.HttpError(err, http.StatusInternalServerError)
+		networkID, taskID := values[0], values[1]
+		entries, err := storage.GetQuarantineEntries(networkID, taskID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load quarantine entries"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, entries)
 	}
-	return c.NoContent(http.StatusCreated)
 }
 
-func getNetworkProbeDestination(c echo.Context) error {
-	paramNames := []string{"network_id", "destination_id"}
-	values, nerr := obsidian.GetParamValues(c, paramNames...)
-	if nerr != nil {
-		return nerr
-	}
-
-	networkID, destinationID := values[0], values[1]
-	ent, err := configurator.LoadEntity(networkID,
-		lte.NetworkProbeDestinationEntityType,
-		destinationID,
-		configurator.EntityLoadCriteria{LoadConfig: true},
-		serdes.Entity)
-	if err == merrors.ErrNotFound {
-		return echo.ErrNotFound
-	}
-	if err != nil {
-		return obsidian.HttpError(err, http.StatusInternalServerError)
-	}
+func getNetworkProbeTaskActivityHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
 
-	ret := (&models.NetworkProbeDestination{}).FromBackendModels(ent)
-	return c.JSON(http.StatusOK, ret)
-}
+		granularity := c.QueryParam("granularity")
+		if len(granularity) == 0 {
+			granularity = models.NetworkProbeActivityGranularityHour
+		}
+		if granularity != models.NetworkProbeActivityGranularityHour &&
+			granularity != models.NetworkProbeActivityGranularityDay {
+			return obsidian.HttpError(errors.Errorf("invalid granularity %s", granularity), http.StatusBadRequest)
+		}
 
-func updateNetworkProbeDestination(c echo.Context) error {
-	networkID, nerr := obsidian.GetNetworkId(c)
-	if nerr != nil {
-		return nerr
+		networkID, taskID := values[0], values[1]
+		activity, err := storage.GetActivity(networkID, taskID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load activity"), http.StatusInternalServerError)
+		}
+		if granularity == models.NetworkProbeActivityGranularityDay {
+			activity = aggregateDailyActivity(activity)
+		}
+		return c.JSON(http.StatusOK, activity)
 	}
+}
 
-	payload := &models.NetworkProbeDestination{}
-	if err := c.Bind(payload); err != nil {
-		return obsidian.HttpError(err, http.StatusBadRequest)
-	}
-	if err := payload.ValidateModel(); err != nil {
-		return obsidian.HttpError(err, http.StatusBadRequest)
-	}
+// getNetworkProbeTaskStatisticsHandlerFunc returns the records delivered
+// for a task per statistics interval, as reported to its LEMF
+func getNetworkProbeTaskStatisticsHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
 
-	_, err := configurator.UpdateEntity(networkID, payload.ToEntityUpdateCriteria(), serdes.Entity)
-	if err != nil {
-		return obsidian.HttpError(err, http.StatusInternalServerError)
+		statistics, err := storage.GetTaskStatistics(values[0], values[1])
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load statistics"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, statistics)
 	}
-	return c.NoContent(http.StatusNoContent)
+}
+
+func getNetworkProbeTaskDeliveriesHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+
+		start, err := getTimeQueryParam(c, "start")
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		end, err := getTimeQueryParam(c, "end")
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+
+		networkID, taskID := values[0], values[1]
+		records, err := storage.GetDeliveryRecords(networkID, taskID, start, end)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load delivered records"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, records)
+	}
+}
+
+// getNetworkProbeSessionsHandlerFunc lists the sessions reported by the records
+// delivered with a correlation ID, or the correlation IDs a session was
+// reported with. Like every request, queries are recorded in the audit log.
+func getNetworkProbeSessionsHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		var correlationID uint64
+		if value := c.QueryParam("correlation_id"); len(value) != 0 {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || id == 0 {
+				return obsidian.HttpError(errors.Errorf("invalid correlation_id %s", value), http.StatusBadRequest)
+			}
+			correlationID = id
+		}
+		sessionID := c.QueryParam("session_id")
+		if correlationID == 0 && len(sessionID) == 0 {
+			return obsidian.HttpError(errors.New("correlation_id or session_id is required"), http.StatusBadRequest)
+		}
+
+		mappings, err := storage.GetSessionMappings(networkID, correlationID, sessionID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load session mappings"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, mappings)
+	}
+}
+
+// getNetworkProbeBearerCorrelationsHandlerFunc looks up the bearer a
+// correlation ID was allocated to, or the correlation IDs allocated to the
+// bearers of an IMSI, to correlate the CC and IRI records of a bearer
+func getNetworkProbeBearerCorrelationsHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		var correlationID uint64
+		if value := c.QueryParam("correlation_id"); len(value) != 0 {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || id == 0 {
+				return obsidian.HttpError(errors.Errorf("invalid correlation_id %s", value), http.StatusBadRequest)
+			}
+			correlationID = id
+		}
+		imsi := c.QueryParam("imsi")
+		if correlationID == 0 && len(imsi) == 0 {
+			return obsidian.HttpError(errors.New("correlation_id or imsi is required"), http.StatusBadRequest)
+		}
+
+		correlations, err := storage.GetBearerCorrelations(networkID, correlationID, imsi)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load bearer correlations"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, correlations)
+	}
+}
+
+// getNetworkProbeSyncStateHandlerFunc returns the canonical snapshot of the
+// tasks of a network, or of those given by task_id, and their state, which
+// an ADMF reconciles its warrant database against.
+func getNetworkProbeSyncStateHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		state, err := tasks.GetSyncState(storage, networkID, c.QueryParams()["task_id"], time.Now())
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load sync state"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, state)
+	}
+}
+
+// getPauseNetworkProbeTaskHandlerFunc pauses the record generation of a task,
+// suspending it. Its state is kept so that sequence numbers carry on once it
+// is resumed.
+func getPauseNetworkProbeTaskHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		networkID, taskID := values[0], values[1]
+		if _, nerr := getTaskState(storage, networkID, taskID); nerr != nil {
+			return nerr
+		}
+		pause, _, err := tasks.Suspend(storage, networkID, taskID, "", actor)
+		if err != nil {
+			return getTransitionError(err)
+		}
+		return c.JSON(http.StatusOK, pause)
+	}
+}
+
+// getResumeNetworkProbeTaskHandlerFunc resumes the record generation of a
+// paused task. The manager reports its target as of the resumption first.
+func getResumeNetworkProbeTaskHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		networkID, taskID := values[0], values[1]
+		if _, nerr := getTaskState(storage, networkID, taskID); nerr != nil {
+			return nerr
+		}
+		pause, _, err := tasks.Resume(storage, networkID, taskID, "", actor)
+		if err != nil {
+			return getTransitionError(err)
+		}
+		return c.JSON(http.StatusOK, pause)
+	}
+}
+
+// getNetworkProbeTaskLifecycleHandlerFunc returns the state of a task and
+// the transitions which led to it
+func getNetworkProbeTaskLifecycleHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+
+		networkID, taskID := values[0], values[1]
+		if _, nerr := getTaskState(storage, networkID, taskID); nerr != nil {
+			return nerr
+		}
+		lifecycle, err := storage.GetTaskLifecycle(networkID, taskID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load task lifecycle"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, lifecycle)
+	}
+}
+
+// getTransitionNetworkProbeTaskHandlerFunc moves a task to the state of the
+// request, suspending or resuming it. The other transitions follow its
+// warrant, deletion and processing.
+func getTransitionNetworkProbeTaskHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		payload := &models.NetworkProbeTaskTransition{}
+		if err := c.Bind(payload); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		if err := payload.ValidateModel(); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+
+		networkID, taskID := values[0], values[1]
+		if _, nerr := getTaskState(storage, networkID, taskID); nerr != nil {
+			return nerr
+		}
+		var lifecycle *models.NetworkProbeTaskLifecycle
+		var err error
+		if payload.To == models.NetworkProbeTaskTransitionToSuspended {
+			_, lifecycle, err = tasks.Suspend(storage, networkID, taskID, payload.Reason, actor)
+		} else {
+			_, lifecycle, err = tasks.Resume(storage, networkID, taskID, payload.Reason, actor)
+		}
+		if err != nil {
+			return getTransitionError(err)
+		}
+		return c.JSON(http.StatusOK, lifecycle)
+	}
+}
+
+// getTransitionError returns the HTTP error of a failed transition of a task,
+// a conflict with its current state or a storage failure
+func getTransitionError(err error) *echo.HTTPError {
+	if _, ok := err.(*tasks.TransitionError); ok || err == tasks.ErrTaskPaused || err == tasks.ErrTaskNotPaused {
+		return obsidian.HttpError(err, http.StatusConflict)
+	}
+	return obsidian.HttpError(err, http.StatusInternalServerError)
+}
+
+// getRotateNetworkProbeTaskXIDHandlerFunc requests a new XID for the records
+// of a task. The manager links the streams of both XIDs before delivering any
+// other record of the task under the new one.
+func getRotateNetworkProbeTaskXIDHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		networkID, taskID := values[0], values[1]
+		state, nerr := getTaskState(storage, networkID, taskID)
+		if nerr != nil {
+			return nerr
+		}
+		rotation, err := storage.GetTaskXIDRotation(networkID, taskID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load task XID rotation"), http.StatusInternalServerError)
+		}
+		currentXID := models.GetTaskXID(taskID, state)
+		if len(rotation.Xid) != 0 && rotation.Xid != currentXID {
+			return obsidian.HttpError(errors.New("previous XID rotation is not applied yet"), http.StatusConflict)
+		}
+
+		xid, err := uuid.NewV4()
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to generate XID"), http.StatusInternalServerError)
+		}
+		rotation = &models.NetworkProbeTaskXidRotation{
+			Xid:         xid.String(),
+			PreviousXid: currentXID,
+			RequestedAt: strfmt.DateTime(clock.Now().UTC()),
+			RequestedBy: actor,
+		}
+		if err := storage.StoreTaskXIDRotation(networkID, taskID, *rotation); err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to rotate task XID"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, rotation)
+	}
+}
+
+func getNetworkProbeTaskReplayHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+
+		networkID, taskID := values[0], values[1]
+		if _, nerr := getTaskState(storage, networkID, taskID); nerr != nil {
+			return nerr
+		}
+		replay, err := storage.GetTaskReplay(networkID, taskID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load task replay"), http.StatusInternalServerError)
+		}
+		if time.Time(replay.Start).IsZero() {
+			return obsidian.HttpError(errors.New("no replay was requested for the task"), http.StatusNotFound)
+		}
+		return c.JSON(http.StatusOK, replay)
+	}
+}
+
+// getReplayNetworkProbeTaskHandlerFunc requests the events of a task over a
+// time range to be encoded and delivered again, e.g. when the LEMF lost
+// records. The manager delivers them with new sequence numbers.
+func getReplayNetworkProbeTaskHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		payload := &models.NetworkProbeTaskReplay{}
+		if err := c.Bind(payload); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		if err := payload.ValidateModel(); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		now := time.Now().UTC()
+		if time.Time(payload.End).After(now) {
+			return obsidian.HttpError(fmt.Errorf("end %s is in the future", payload.End), http.StatusBadRequest)
+		}
+
+		networkID, taskID := values[0], values[1]
+		if _, nerr := getTaskState(storage, networkID, taskID); nerr != nil {
+			return nerr
+		}
+		previous, err := storage.GetTaskReplay(networkID, taskID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load task replay"), http.StatusInternalServerError)
+		}
+		if models.IsTaskReplayPending(previous) {
+			return obsidian.HttpError(errors.New("previous replay is not completed yet"), http.StatusConflict)
+		}
+
+		replay := &models.NetworkProbeTaskReplay{
+			Start:       payload.Start,
+			End:         payload.End,
+			RequestedAt: strfmt.DateTime(now),
+			RequestedBy: actor,
+		}
+		if err := storage.StoreTaskReplay(networkID, taskID, *replay); err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to request task replay"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, replay)
+	}
+}
+
+func getNetworkProbeTaskTestRecordHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+
+		networkID, taskID := values[0], values[1]
+		if _, nerr := getTaskState(storage, networkID, taskID); nerr != nil {
+			return nerr
+		}
+		testRecord, err := storage.GetTaskTestRecord(networkID, taskID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load task test record"), http.StatusInternalServerError)
+		}
+		if len(testRecord.ID) == 0 {
+			return obsidian.HttpError(errors.New("no test record was requested for the task"), http.StatusNotFound)
+		}
+		return c.JSON(http.StatusOK, testRecord)
+	}
+}
+
+// getInjectNetworkProbeTaskTestRecordHandlerFunc requests a test record to be
+// delivered in the stream of a task, e.g. to validate the path to its
+// destination with the LEA when its warrant is activated. The manager
+// delivers it with the next sequence number of the task.
+func getInjectNetworkProbeTaskTestRecordHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		networkID, taskID := values[0], values[1]
+		if _, nerr := getTaskState(storage, networkID, taskID); nerr != nil {
+			return nerr
+		}
+		previous, err := storage.GetTaskTestRecord(networkID, taskID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load task test record"), http.StatusInternalServerError)
+		}
+		if models.IsTestRecordPending(previous) {
+			return obsidian.HttpError(errors.New("previous test record is not delivered yet"), http.StatusConflict)
+		}
+
+		id, err := uuid.NewV4()
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to generate test record ID"), http.StatusInternalServerError)
+		}
+		testRecord := &models.NetworkProbeTaskTestRecord{
+			ID:          id.String(),
+			RequestedAt: strfmt.DateTime(clock.Now().UTC()),
+			RequestedBy: actor,
+		}
+		if err := storage.StoreTaskTestRecord(networkID, taskID, *testRecord); err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to request task test record"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, testRecord)
+	}
+}
+
+func getNetworkProbeTaskBookmarksHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+
+		networkID, taskID := values[0], values[1]
+		if _, nerr := getTaskState(storage, networkID, taskID); nerr != nil {
+			return nerr
+		}
+		bookmarks, err := storage.GetBookmarks(networkID, taskID)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load bookmarks"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, bookmarks)
+	}
+}
+
+func getNetworkProbeTaskBookmarkHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id", "bookmark_name"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+
+		bookmark, err := storage.GetBookmark(values[0], values[1], values[2])
+		if errors.Cause(err) == merrors.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to load bookmark"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, bookmark)
+	}
+}
+
+// getSetNetworkProbeTaskBookmarkHandlerFunc sets a bookmark on the record
+// stream of a task. Without a sequence number the last exported record is
+// bookmarked, so that auditors don't need to read the task state.
+func getSetNetworkProbeTaskBookmarkHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id", "bookmark_name"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		payload := &models.NetworkProbeBookmark{}
+		if err := c.Bind(payload); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		payload.Name = values[2]
+		if err := payload.ValidateModel(); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+
+		networkID, taskID := values[0], values[1]
+		data, nerr := getTaskState(storage, networkID, taskID)
+		if nerr != nil {
+			return nerr
+		}
+		now := strfmt.DateTime(time.Now().UTC())
+		switch {
+		case payload.SequenceNumber == 0 && data.SequenceNumber == 0:
+			return obsidian.HttpError(errors.New("no record was exported for the task yet"), http.StatusConflict)
+		case payload.SequenceNumber == 0:
+			payload.SequenceNumber = data.SequenceNumber - 1
+			payload.Timestamp = data.LastExported
+		case payload.SequenceNumber >= data.SequenceNumber:
+			err := fmt.Errorf("record %d was not exported for the task yet", payload.SequenceNumber)
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		case time.Time(payload.Timestamp).IsZero():
+			payload.Timestamp = now
+		}
+		payload.CreatedBy = actor
+		payload.CreatedAt = now
+		if err := storage.StoreBookmark(networkID, taskID, *payload); err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to store bookmark"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, payload)
+	}
+}
+
+func getDeleteNetworkProbeTaskBookmarkHandlerFunc(storage storage.NProbeStorage) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id", "bookmark_name"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+		if _, nerr := getActor(c); nerr != nil {
+			return nerr
+		}
+
+		if err := storage.DeleteBookmark(values[0], values[1], values[2]); err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to delete bookmark"), http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// getTaskState returns the state of an existing task
+func getTaskState(storage storage.NProbeStorage, networkID, taskID string) (*models.NetworkProbeData, *echo.HTTPError) {
+	data, err := storage.GetNProbeData(networkID, taskID)
+	if errors.Cause(err) == merrors.ErrNotFound {
+		return nil, echo.ErrNotFound
+	}
+	if err != nil {
+		return nil, obsidian.HttpError(errors.Wrap(err, "failed to load NetworkProbeData"), http.StatusInternalServerError)
+	}
+	return data, nil
+}
+
+// getTimeQueryParam parses an optional RFC3339 query parameter, the zero
+// time is returned when it is not set
+func getTimeQueryParam(c echo.Context, name string) (time.Time, error) {
+	value := c.QueryParam(name)
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid %s %s, expected RFC3339 date-time", name, value)
+	}
+	return t, nil
+}
+
+// aggregateDailyActivity sums the hourly buckets of an activity rollup per UTC day
+func aggregateDailyActivity(hourly *models.NetworkProbeActivity) *models.NetworkProbeActivity {
+	daily := &models.NetworkProbeActivity{Granularity: models.NetworkProbeActivityGranularityDay}
+	var current *models.NetworkProbeActivityBucket
+	for _, bucket := range hourly.Buckets {
+		start := time.Time(bucket.Start).UTC()
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		if current == nil || !time.Time(current.Start).Equal(day) {
+			current = &models.NetworkProbeActivityBucket{Start: strfmt.DateTime(day)}
+			daily.Buckets = append(daily.Buckets, current)
+		}
+		current.Count += bucket.Count
+	}
+	return daily
+}
+
+func listNetworkProbeDestinations(c echo.Context) error {
+	networkID, nerr := obsidian.GetNetworkId(c)
+	if nerr != nil {
+		return nerr
+	}
+
+	ents, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeDestinationEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	}
+
+	ret := make(map[string]*models.NetworkProbeDestination, len(ents))
+	for _, ent := range ents {
+		ret[ent.Key] = (&models.NetworkProbeDestination{}).FromBackendModels(ent)
+		ret[ent.Key].DestinationDetails.RedactPayloadEncryptionKey()
+	}
+	return c.JSON(http.StatusOK, ret)
+}
+
+func createNetworkProbeDestination(c echo.Context) error {
+	networkID, nerr := obsidian.GetNetworkId(c)
+	if nerr != nil {
+		return nerr
+	}
+
+	payload := &models.NetworkProbeDestination{}
+	if err := c.Bind(payload); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err := payload.ValidateModel(); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err := payload.DestinationDetails.KeepPayloadEncryptionKey(nil); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err := checkDestinationDomainID(networkID, payload); err != nil {
+		return err
+	}
+
+	_, err := configurator.CreateEntity(
+		networkID,
+		configurator.NetworkEntity{
+			Type:   lte.NetworkProbeDestinationEntityType,
+			Key:    string(payload.DestinationID),
+			Config: payload.DestinationDetails,
+		},
+		serdes.Entity,
+	)
+	if err != nil {
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	}
+	return c.NoContent(http.StatusCreated)
+}
+
+func getNetworkProbeDestination(c echo.Context) error {
+	paramNames := []string{"network_id", "destination_id"}
+	values, nerr := obsidian.GetParamValues(c, paramNames...)
+	if nerr != nil {
+		return nerr
+	}
+
+	networkID, destinationID := values[0], values[1]
+	ent, err := configurator.LoadEntity(networkID,
+		lte.NetworkProbeDestinationEntityType,
+		destinationID,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity)
+	if err == merrors.ErrNotFound {
+		return echo.ErrNotFound
+	}
+	if err != nil {
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	}
+
+	ret := (&models.NetworkProbeDestination{}).FromBackendModels(ent)
+	ret.DestinationDetails.RedactPayloadEncryptionKey()
+	return c.JSON(http.StatusOK, ret)
+}
+
+func updateNetworkProbeDestination(c echo.Context) error {
+	networkID, nerr := obsidian.GetNetworkId(c)
+	if nerr != nil {
+		return nerr
+	}
+
+	payload := &models.NetworkProbeDestination{}
+	if err := c.Bind(payload); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err := payload.ValidateModel(); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	// the payload encryption key is kept when left out
+	var current *models.NetworkProbeDestinationDetails
+	ent, err := configurator.LoadEntity(networkID,
+		lte.NetworkProbeDestinationEntityType,
+		string(payload.DestinationID),
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity)
+	switch {
+	case err == merrors.ErrNotFound:
+	case err != nil:
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	default:
+		current = (&models.NetworkProbeDestination{}).FromBackendModels(ent).DestinationDetails
+	}
+	if err := payload.DestinationDetails.KeepPayloadEncryptionKey(current); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err := checkDestinationDomainID(networkID, payload); err != nil {
+		return err
+	}
+
+	_, err = configurator.UpdateEntity(networkID, payload.ToEntityUpdateCriteria(), serdes.Entity)
+	if err != nil {
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// checkDestinationDomainID checks that the domain ID of a destination doesn't
+// identify the module version of another release in the network config or
+// the other destinations of its network
+func checkDestinationDomainID(networkID string, destination *models.NetworkProbeDestination) error {
+	details := destination.DestinationDetails
+	err := tasks.CheckDomainID(networkID, string(destination.DestinationID), details.ModuleVersion, details.Hi2DomainID)
+	if errors.Cause(err) == tasks.ErrDomainIDConflict {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err != nil {
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	}
+	return nil
 }
 
 func deleteNetworkProbeDestination(c echo.Context) error {
@@ -300,9 +1282,418 @@ func deleteNetworkProbeDestination(c echo.Context) error {
 	}
 
 	networkID, destinationID := values[0], values[1]
-	err := configurator.DeleteEntity(networkID, lte.NetworkProbeDestinationEntityType, destinationID)
+	// the records of the tasks delivered to the destination would be left undelivered
+	taskIDs, err := tasks.GetDestinationTasks(networkID, destinationID)
+	if err != nil {
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	}
+	if len(taskIDs) != 0 {
+		err := fmt.Errorf("destination %s is used by tasks %s", destinationID, strings.Join(taskIDs, ", "))
+		return obsidian.HttpError(err, http.StatusConflict)
+	}
+	err = configurator.DeleteEntity(networkID, lte.NetworkProbeDestinationEntityType, destinationID)
 	if err != nil {
 		return obsidian.HttpError(err, http.StatusInternalServerError)
 	}
 	return c.NoContent(http.StatusNoContent)
 }
+
+func exportNetworkProbeConfig(c echo.Context) error {
+	networkID, nerr := obsidian.GetNetworkId(c)
+	if nerr != nil {
+		return nerr
+	}
+
+	config, err := declarative.Export(networkID)
+	if err != nil {
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	}
+	switch c.QueryParam("format") {
+	case "", "json":
+		return c.JSON(http.StatusOK, config)
+	case "yaml":
+		content, err := declarative.MarshalYAML(config)
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusInternalServerError)
+		}
+		return c.Blob(http.StatusOK, mimeApplicationYAML, content)
+	default:
+		return obsidian.HttpError(fmt.Errorf("invalid format %s", c.QueryParam("format")), http.StatusBadRequest)
+	}
+}
+
+func applyNetworkProbeConfig(c echo.Context) error {
+	networkID, nerr := obsidian.GetNetworkId(c)
+	if nerr != nil {
+		return nerr
+	}
+	dryRun := false
+	if value := c.QueryParam("dry_run"); len(value) != 0 {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			return obsidian.HttpError(fmt.Errorf("invalid dry_run %s: %v", value, err), http.StatusBadRequest)
+		}
+	}
+
+	payload := &models.NetworkProbeDeclarativeConfig{}
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), mimeApplicationYAML) {
+		content, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		if err := declarative.UnmarshalYAML(content, payload); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+	} else if err := c.Bind(payload); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err := payload.ValidateModel(); err != nil {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+
+	diff, err := declarative.Apply(networkID, payload, dryRun)
+	if _, ok := errors.Cause(err).(*declarative.InvalidConfigError); ok {
+		return obsidian.HttpError(err, http.StatusBadRequest)
+	}
+	if err != nil {
+		return obsidian.HttpError(err, http.StatusInternalServerError)
+	}
+	return c.JSON(http.StatusOK, diff)
+}
+
+func getTakeSnapshotHandlerFunc(storage storage.NProbeStorage, key []byte) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		snap, err := snapshot.Take(networkID, storage)
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusInternalServerError)
+		}
+		archive, err := snapshot.Seal(snap, key)
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to seal snapshot"), http.StatusInternalServerError)
+		}
+		return c.Blob(http.StatusOK, echo.MIMEOctetStream, archive)
+	}
+}
+
+func getRestoreSnapshotHandlerFunc(storage storage.NProbeStorage, key []byte) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		archive, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		snap, err := snapshot.Open(archive, key)
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		err = snapshot.Restore(networkID, storage, snap, getAuditActor(c.Request()))
+		switch errors.Cause(err) {
+		case nil:
+		case tasks.ErrInvalidTargets, tasks.ErrUnknownDestination, tasks.ErrNotTestPLMN, tasks.ErrDomainIDConflict:
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		case tasks.ErrProtectedTarget:
+			return obsidian.HttpError(err, http.StatusForbidden)
+		case tasks.ErrTaskQuotaExceeded:
+			return obsidian.HttpError(err, http.StatusTooManyRequests)
+		default:
+			return obsidian.HttpError(err, http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func getDebugConfigHandlerFunc(settings *debug.Settings) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		ret := settings.Get(networkID)
+		if ret == nil {
+			ret = &models.NetworkProbeDebugConfig{}
+		}
+		return c.JSON(http.StatusOK, ret)
+	}
+}
+
+func getUpdateDebugConfigHandlerFunc(settings *debug.Settings) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		payload := &models.NetworkProbeDebugConfig{}
+		if err := c.Bind(payload); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		if err := payload.ValidateModel(); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		if err := settings.Set(networkID, payload); err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to apply debug settings"), http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func getDeleteDebugConfigHandlerFunc(settings *debug.Settings) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		if err := settings.Clear(networkID); err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to revert debug settings"), http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// getTaskRecordsHandlerFunc renders the most recent records captured for a
+// debugged task as JSON. Records that can't be decoded are rendered in hex
+// along with the decoding error.
+func getTaskRecordsHandlerFunc(settings *debug.Settings) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paramNames := []string{"network_id", "task_id"}
+		values, nerr := obsidian.GetParamValues(c, paramNames...)
+		if nerr != nil {
+			return nerr
+		}
+
+		count := defaultRecordCount
+		if value := c.QueryParam("count"); len(value) != 0 {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 || n > debug.MaxCapturedRecords {
+				return obsidian.HttpError(
+					errors.Errorf("invalid count %s, expected 1 to %d", value, debug.MaxCapturedRecords),
+					http.StatusBadRequest,
+				)
+			}
+			count = n
+		}
+
+		networkID, taskID := values[0], values[1]
+		ret := []json.RawMessage{}
+		for _, b := range settings.GetRecords(networkID, taskID, count) {
+			ret = append(ret, renderRecord(c.Request().Context(), b))
+		}
+		return c.JSON(http.StatusOK, ret)
+	}
+}
+
+// renderRecord decodes a record and renders it as JSON, the identities of
+// the targets being redacted
+func renderRecord(ctx context.Context, b []byte) json.RawMessage {
+	var record encoding.EpsIRIRecord
+	err := record.DecodeWithLimit(ctx, b, 0)
+	if err == nil {
+		redact.Record(&record)
+		var rendered []byte
+		if rendered, err = encoding.ToJSON(&record); err == nil {
+			return rendered
+		}
+	}
+	rendered, _ := json.Marshal(map[string]string{"error": redact.Error(err), "record": redact.Bytes(b)})
+	return rendered
+}
+
+// getActor returns the identity of the caller from its client certificate
+func getActor(c echo.Context) (string, *echo.HTTPError) {
+	actor := c.Request().Header.Get(access.CLIENT_CERT_CN_KEY)
+	if len(actor) == 0 {
+		return "", obsidian.HttpError(errors.New("missing client certificate"), http.StatusForbidden)
+	}
+	return actor, nil
+}
+
+func getKillSwitchHandlerFunc(killSwitch *killswitch.Switch) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		ret, err := killSwitch.Get(networkID)
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, ret)
+	}
+}
+
+func getActivateKillSwitchHandlerFunc(killSwitch *killswitch.Switch) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		payload := &models.NetworkProbeKillSwitch{}
+		if err := c.Bind(payload); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+		if err := payload.ValidateModel(); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+
+		ret, err := killSwitch.Activate(networkID, actor, payload.Reason)
+		if err == killswitch.ErrAlreadyActive {
+			return obsidian.HttpError(err, http.StatusConflict)
+		}
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to activate kill switch"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusCreated, ret)
+	}
+}
+
+func getDeactivateKillSwitchHandlerFunc(killSwitch *killswitch.Switch) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		err := killSwitch.Deactivate(networkID, actor)
+		if err == killswitch.ErrNotActive {
+			return obsidian.HttpError(err, http.StatusNotFound)
+		}
+		if err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to deactivate kill switch"), http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func getKillSwitchAuditHandlerFunc(killSwitch *killswitch.Switch) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		ret, err := killSwitch.GetAuditEntries(networkID)
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, ret)
+	}
+}
+
+func getCertificateHandlerFunc(certs *exporter.CertificateStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, nerr := obsidian.GetNetworkId(c); nerr != nil {
+			return nerr
+		}
+		return c.JSON(http.StatusOK, toCertificateModel(certs.GetInfo()))
+	}
+}
+
+func getReloadCertificateHandlerFunc(certs *exporter.CertificateStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, nerr := obsidian.GetNetworkId(c); nerr != nil {
+			return nerr
+		}
+
+		// the current certificate is kept if the files fail to load
+		if err := certs.Reload(); err != nil {
+			return obsidian.HttpError(errors.Wrap(err, "failed to reload exporter certificate"), http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, toCertificateModel(certs.GetInfo()))
+	}
+}
+
+func toCertificateModel(info exporter.CertificateInfo) *models.NetworkProbeCertificate {
+	return &models.NetworkProbeCertificate{
+		Subject:      info.Subject,
+		Issuer:       info.Issuer,
+		SerialNumber: info.SerialNumber,
+		NotBefore:    strfmt.DateTime(info.NotBefore.UTC()),
+		NotAfter:     strfmt.DateTime(info.NotAfter.UTC()),
+		LoadedAt:     strfmt.DateTime(info.LoadedAt.UTC()),
+	}
+}
+
+func getSigningKeyHandlerFunc(getSigner SigningKey) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, nerr := obsidian.GetNetworkId(c); nerr != nil {
+			return nerr
+		}
+		signer := getSigner()
+		if signer == nil {
+			return obsidian.HttpError(errors.New("record signing is not enabled"), http.StatusNotFound)
+		}
+		publicKey, err := signer.PublicKeyPEM()
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, &models.NetworkProbeSigningKey{
+			KeyID:     signer.KeyID(),
+			Algorithm: signing.Algorithm,
+			PublicKey: string(publicKey),
+		})
+	}
+}
+
+func getHealthHandlerFunc(registry *health.Registry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, nerr := obsidian.GetNetworkId(c); nerr != nil {
+			return nerr
+		}
+		return c.JSON(http.StatusOK, toHealthModel(registry.Check()))
+	}
+}
+
+// getReadinessHandlerFunc reports the service ready unless one of its
+// components is unhealthy
+func getReadinessHandlerFunc(registry *health.Registry) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, nerr := obsidian.GetNetworkId(c); nerr != nil {
+			return nerr
+		}
+		statuses := registry.Check()
+		if !health.IsHealthy(statuses) {
+			return c.JSON(http.StatusServiceUnavailable, toHealthModel(statuses))
+		}
+		return c.JSON(http.StatusOK, toHealthModel(statuses))
+	}
+}
+
+func toHealthModel(statuses []health.Status) *models.NetworkProbeServiceHealth {
+	ret := &models.NetworkProbeServiceHealth{Status: health.Summarize(statuses)}
+	for _, status := range statuses {
+		component := &models.NetworkProbeComponentHealth{
+			Name:      status.Component,
+			Status:    models.NetworkProbeComponentHealthStatusHealthy,
+			Message:   status.Message,
+			CheckedAt: strfmt.DateTime(status.CheckedAt.UTC()),
+		}
+		if status.Degraded {
+			component.Status = models.NetworkProbeComponentHealthStatusDegraded
+		} else if !status.Healthy {
+			component.Status = models.NetworkProbeComponentHealthStatusUnhealthy
+		}
+		ret.Components = append(ret.Components, component)
+	}
+	return ret
+}
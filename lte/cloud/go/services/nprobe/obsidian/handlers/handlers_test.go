@@ -14,22 +14,54 @@
 package handlers_test
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"magma/lte/cloud/go/lte"
 	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/debug"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/killswitch"
 	"magma/lte/cloud/go/services/nprobe/obsidian/handlers"
 	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/lte/cloud/go/services/nprobe/snapshot"
 	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/lte/cloud/go/services/nprobe/tasks"
 	"magma/orc8r/cloud/go/obsidian"
+	"magma/orc8r/cloud/go/obsidian/access"
 	"magma/orc8r/cloud/go/obsidian/tests"
 	"magma/orc8r/cloud/go/services/configurator"
 	configuratorTestInit "magma/orc8r/cloud/go/services/configurator/test_init"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
 	"magma/orc8r/cloud/go/test_utils"
+	merrors "magma/orc8r/lib/go/errors"
 
 	"github.com/go-openapi/strfmt"
 	"github.com/labstack/echo"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,7 +81,8 @@ func TestCreateNetworkProbeTask(t *testing.T) {
 
 	e := echo.New()
 	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks"
-	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
 	createNetworkProbeTask := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.POST).HandlerFunc
 
 	payload := &models.NetworkProbeTask{
@@ -91,6 +124,318 @@ func TestCreateNetworkProbeTask(t *testing.T) {
 	assert.Equal(t, expected_task.TargetType, actual_task.TargetType)
 	assert.Equal(t, expected_task.DeliveryType, actual_task.DeliveryType)
 	assert.Equal(t, expected_task.CorrelationID, actual_task.CorrelationID)
+
+	// Fail to create a task twice
+	tc.ExpectedStatus = 409
+	tc.ExpectedError = "task already exists"
+	tests.RunUnitTest(t, e, tc)
+
+	// Fail to create a task with a malformed MSISDN target
+	payload.TaskID = "test_msisdn"
+	payload.TaskDetails.TargetType = "msisdn"
+	payload.TaskDetails.TargetID = "+33-6123"
+	tc = tests.Test{
+		Method:                 "POST",
+		URL:                    testURLRoot,
+		Payload:                payload,
+		Handler:                createNetworkProbeTask,
+		ParamNames:             []string{"network_id"},
+		ParamValues:            []string{"n1"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "target_id +33-6123 is not a valid MSISDN",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// Fail to create a task whose activity bounds can't be met
+	payload.TaskID = "test_bounds"
+	payload.TaskDetails.TargetType = "imsi"
+	payload.TaskDetails.TargetID = "IMSI001010000000001"
+	payload.TaskDetails.MinRecordsPerHour = 10
+	payload.TaskDetails.MaxRecordsPerHour = 5
+	tc.Payload = payload
+	tc.ExpectedErrorSubstring = "min_records_per_hour 10 is greater than max_records_per_hour 5"
+	tests.RunUnitTest(t, e, tc)
+
+	// Fail to create a task reporting usage without the country code qualifying it
+	payload.TaskID = "test_usage"
+	payload.TaskDetails.MinRecordsPerHour = 0
+	payload.TaskDetails.UsageReportIntervalSecs = 900
+	tc.Payload = payload
+	tc.ExpectedErrorSubstring = "usage_report_interval_secs requires delivery_country_code"
+	tests.RunUnitTest(t, e, tc)
+
+	// Fail to create a task whose warrant ends before it starts
+	payload.TaskID = "test_warrant"
+	payload.TaskDetails.UsageReportIntervalSecs = 0
+	payload.TaskDetails.StartTime = strfmt.DateTime(time.Date(2020, 3, 12, 8, 0, 0, 0, time.UTC))
+	payload.TaskDetails.EndTime = strfmt.DateTime(time.Date(2020, 3, 11, 8, 0, 0, 0, time.UTC))
+	tc.Payload = payload
+	tc.ExpectedErrorSubstring = "end_time 2020-03-11T08:00:00.000Z is not after start_time 2020-03-12T08:00:00.000Z"
+	tests.RunUnitTest(t, e, tc)
+
+	// Fail to create a task targeting an IMSI range outside of the test PLMNs
+	// of the network
+	payload.TaskID = "test_range"
+	payload.TaskDetails.StartTime, payload.TaskDetails.EndTime = strfmt.DateTime{}, strfmt.DateTime{}
+	payload.TaskDetails.TargetType = "imsi_range"
+	payload.TaskDetails.TargetID = "IMSI99999000*"
+	tc.Payload = payload
+	tc.ExpectedErrorSubstring = "IMSI range of the task is not in a test PLMN of the network"
+	tests.RunUnitTest(t, e, tc)
+
+	payload.TaskDetails.TargetID = "IMSI999990000000019-IMSI999990000000010"
+	tc.ExpectedErrorSubstring = "IMSI range IMSI999990000000019-IMSI999990000000010 is empty"
+	tests.RunUnitTest(t, e, tc)
+
+	err = configurator.CreateNetwork(configurator.Network{
+		ID:      "n2",
+		Configs: map[string]interface{}{lte.NetworkProbeConfigType: &models.NetworkProbeNetworkConfig{TestPlmnIds: []string{"99999"}}},
+	}, serdes.Network)
+	assert.NoError(t, err)
+	payload.TaskDetails.TargetID = "IMSI99999000*"
+	tc = tests.Test{
+		Method:         "POST",
+		URL:            testURLRoot,
+		Payload:        payload,
+		Handler:        createNetworkProbeTask,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n2"},
+		ExpectedStatus: 201,
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// Fail to create a task targeting a protected identity of the network,
+	// the attempt being audited
+	err = configurator.CreateNetwork(configurator.Network{
+		ID: "n3",
+		Configs: map[string]interface{}{lte.NetworkProbeConfigType: &models.NetworkProbeNetworkConfig{
+			ProtectedIdentities: []*models.NetworkProbeProtectedIdentity{
+				{IdentityType: "msisdn", Identity: "+33112", Reason: "emergency services"},
+			},
+		}},
+	}, serdes.Network)
+	assert.NoError(t, err)
+	payload.TaskID = "test_protected"
+	payload.TaskDetails.TargetType = "msisdn"
+	payload.TaskDetails.TargetID = "33112"
+	tc.ParamValues = []string{"n3"}
+	tc.ExpectedStatus = 403
+	tc.ExpectedErrorSubstring = "target of the task is a protected identity of the network"
+	tests.RunUnitTest(t, e, tc)
+
+	entries, err := store.GetAuditEntries("n3")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, models.NetworkProbeAuditEntryActionRejectProtectedTarget, entries[0].Action)
+	assert.Equal(t, "unknown", entries[0].Actor)
+	assert.Contains(t, entries[0].Reason, "task test_protected targets protected msisdn")
+	assert.Contains(t, entries[0].Reason, "emergency services")
+	exists, err := configurator.DoesEntityExist("n3", lte.NetworkProbeTaskEntityType, "test_protected")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestCreateNetworkProbeTasksBulk(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(
+		configurator.Network{
+			ID:      "n1",
+			Configs: map[string]interface{}{lte.NetworkProbeConfigType: &models.NetworkProbeNetworkConfig{MaxTasks: 4}},
+		},
+		serdes.Network,
+	)
+	assert.NoError(t, err)
+	_, err = configurator.CreateEntity(
+		"n1",
+		configurator.NetworkEntity{Type: lte.NetworkProbeTaskEntityType, Key: "existing", Config: &models.NetworkProbeTaskDetails{}},
+		serdes.Entity,
+	)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/bulk"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	createNetworkProbeTasksBulk := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.POST).HandlerFunc
+
+	duration := int64(3600)
+	payload := &models.NetworkProbeTaskBulkRequest{
+		Template: &models.NetworkProbeTaskDetails{
+			TargetType:             "imsi",
+			DeliveryType:           "events_only",
+			Duration:               &duration,
+			AuthorizationReference: "warrant-42",
+		},
+		Targets: []*models.NetworkProbeTaskBulkTarget{
+			{TaskID: "imsi1", TargetID: "IMSI001010000000001"},
+			{TaskID: "msisdn1", TargetID: "+33-6123", TargetType: "msisdn"},
+			{TaskID: "imsi1", TargetID: "IMSI001010000000002"},
+			{TaskID: "existing", TargetID: "IMSI001010000000003"},
+		},
+	}
+	tc := tests.Test{
+		Method:         "POST",
+		URL:            testURLRoot,
+		Payload:        payload,
+		Handler:        createNetworkProbeTasksBulk,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 400,
+		ExpectedResult: &models.NetworkProbeTaskBulkResult{
+			Created: false,
+			Results: []*models.NetworkProbeTaskBulkTargetResult{
+				{TaskID: "imsi1", TargetID: "IMSI001010000000001", Status: "aborted"},
+				{TaskID: "msisdn1", TargetID: "+33-6123", Status: "invalid", Message: "target_id +33-6123 is not a valid MSISDN"},
+				{TaskID: "imsi1", TargetID: "IMSI001010000000002", Status: "duplicate", Message: "task imsi1 is requested more than once"},
+				{TaskID: "existing", TargetID: "IMSI001010000000003", Status: "exists", Message: "task already exists"},
+			},
+		},
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// nothing is created when a task of a target exists
+	payload.Targets = []*models.NetworkProbeTaskBulkTarget{
+		{TaskID: "imsi1", TargetID: "IMSI001010000000001"},
+		{TaskID: "existing", TargetID: "IMSI001010000000003"},
+	}
+	tc.Payload = payload
+	tc.ExpectedStatus = 409
+	tc.ExpectedResult = &models.NetworkProbeTaskBulkResult{
+		Created: false,
+		Results: []*models.NetworkProbeTaskBulkTargetResult{
+			{TaskID: "imsi1", TargetID: "IMSI001010000000001", Status: "aborted"},
+			{TaskID: "existing", TargetID: "IMSI001010000000003", Status: "exists", Message: "task already exists"},
+		},
+	}
+	tests.RunUnitTest(t, e, tc)
+	exists, err := configurator.DoesEntityExist("n1", lte.NetworkProbeTaskEntityType, "imsi1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	states, err := store.GetAllNProbeData("n1")
+	assert.NoError(t, err)
+	assert.Empty(t, states)
+
+	// the tasks of all the targets are created from the template
+	payload.Targets = []*models.NetworkProbeTaskBulkTarget{
+		{TaskID: "imsi1", TargetID: "IMSI001010000000001"},
+		{TaskID: "msisdn1", TargetID: "+33612345678", TargetType: "msisdn"},
+	}
+	tc.Payload = payload
+	tc.ExpectedStatus = 201
+	tc.ExpectedResult = &models.NetworkProbeTaskBulkResult{
+		Created: true,
+		Results: []*models.NetworkProbeTaskBulkTargetResult{
+			{TaskID: "imsi1", TargetID: "IMSI001010000000001", Status: "created"},
+			{TaskID: "msisdn1", TargetID: "+33612345678", Status: "created"},
+		},
+	}
+	tests.RunUnitTest(t, e, tc)
+	ent, err := configurator.LoadEntity("n1", lte.NetworkProbeTaskEntityType, "msisdn1", configurator.FullEntityLoadCriteria(), serdes.Entity)
+	assert.NoError(t, err)
+	details := ent.Config.(*models.NetworkProbeTaskDetails)
+	assert.Equal(t, "+33612345678", details.TargetID)
+	assert.Equal(t, "msisdn", details.TargetType)
+	assert.Equal(t, "events_only", details.DeliveryType)
+	assert.Equal(t, int64(3600), *details.Duration)
+	assert.Equal(t, "warrant-42", details.AuthorizationReference)
+	assert.NotZero(t, details.CorrelationID)
+	states, err = store.GetAllNProbeData("n1")
+	assert.NoError(t, err)
+	assert.Len(t, states, 2)
+
+	// the tasks must fit in the task quota of the network, all together
+	payload.Targets = []*models.NetworkProbeTaskBulkTarget{
+		{TaskID: "imsi2", TargetID: "IMSI001010000000002"},
+		{TaskID: "imsi3", TargetID: "IMSI001010000000003"},
+	}
+	tc.Payload = payload
+	tc.ExpectedStatus = 429
+	tc.ExpectedResult = nil
+	tc.ExpectedError = "task quota of the network exceeded"
+	tests.RunUnitTest(t, e, tc)
+	exists, err = configurator.DoesEntityExist("n1", lte.NetworkProbeTaskEntityType, "imsi2")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// Fail to create tasks without targets
+	payload.Targets = nil
+	tc.Payload = payload
+	tc.ExpectedStatus = 400
+	tc.ExpectedError = "targets must not be empty"
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestValidateNetworkProbeTask(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+	_, err = configurator.CreateEntity(
+		"n1",
+		configurator.NetworkEntity{Type: lte.NetworkProbeTaskEntityType, Key: "existing", Config: &models.NetworkProbeTaskDetails{}},
+		serdes.Entity,
+	)
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "nprobe_validate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	crtFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writeCertificate(t, crtFile, keyFile, time.Now().Add(10*24*time.Hour+time.Hour))
+	certs, err := exporter.NewCertificateStore(crtFile, keyFile)
+	assert.NoError(t, err)
+	backend, err := exporter.NewPcapBackend(dir, 1<<20, time.Hour)
+	assert.NoError(t, err)
+	exp := exporter.NewRecordExporter(backend)
+	defer exp.Close()
+
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/validate"
+	validationHandlers := handlers.GetValidationHandlers(certs, exp)
+	validateNetworkProbeTask := tests.GetHandlerByPathAndMethod(t, validationHandlers, testURLRoot, obsidian.POST).HandlerFunc
+
+	// the certificate expires soon and the pcap backend can't be probed
+	payload := &models.NetworkProbeTask{
+		TaskID: "test",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:     "+33612345678",
+			TargetType:   "msisdn",
+			DeliveryType: "all",
+		},
+	}
+	expected := &models.NetworkProbeTaskValidation{
+		Status: "warning",
+		Diagnostics: []*models.NetworkProbeTaskDiagnostic{
+			{Check: "target", Status: "ok", Message: "msisdn target +33612345678 is valid"},
+			{Check: "task_id", Status: "ok", Message: "task test is not provisioned"},
+			{Check: "certificate", Status: "warning", Message: "exporter certificate CN=nprobe expires in 10 days"},
+			{Check: "delivery", Status: "warning", Message: "delivery not probed: backend does not deliver records over tls"},
+		},
+	}
+	tc := tests.Test{
+		Method:         "POST",
+		URL:            testURLRoot,
+		Payload:        payload,
+		Handler:        validateNetworkProbeTask,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: expected,
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// nothing is provisioned, even when the task is invalid
+	payload.TaskID = "existing"
+	payload.TaskDetails.TargetID = "+33-6123"
+	expected.Status = "error"
+	expected.Diagnostics[0] = &models.NetworkProbeTaskDiagnostic{Check: "target", Status: "error", Message: "target_id +33-6123 is not a valid MSISDN"}
+	expected.Diagnostics[1] = &models.NetworkProbeTaskDiagnostic{Check: "task_id", Status: "error", Message: "task existing already exists"}
+	tc.Payload = payload
+	tc.ExpectedResult = expected
+	tests.RunUnitTest(t, e, tc)
+
+	exists, err := configurator.DoesEntityExist("n1", lte.NetworkProbeTaskEntityType, "test")
+	assert.NoError(t, err)
+	assert.False(t, exists)
 }
 
 func TestListNetworkProbeTasks(t *testing.T) {
@@ -100,7 +445,8 @@ func TestListNetworkProbeTasks(t *testing.T) {
 
 	e := echo.New()
 	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks"
-	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
 	listNetworkProbeTasks := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
 
 	tc := tests.Test{
@@ -171,6 +517,73 @@ func TestListNetworkProbeTasks(t *testing.T) {
 		}),
 	}
 	tests.RunUnitTest(t, e, tc)
+
+	// pages are selected from the states of the tasks
+	task1 := &models.NetworkProbeTask{
+		TaskID: "IMSI1234",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI1234",
+			TargetType:    "imsi",
+			DeliveryType:  "events_only",
+			CorrelationID: 8674665223082154000,
+		},
+	}
+	task2 := &models.NetworkProbeTask{
+		TaskID: "IMSI1235",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI1235",
+			TargetType:    "imsi",
+			DeliveryType:  "all",
+			CorrelationID: 8674665223082154099,
+		},
+	}
+	createdAt := time.Unix(1615000000, 0).UTC()
+	assert.NoError(t, store.StoreNProbeData("n1", "IMSI1234", models.NetworkProbeData{
+		TargetID:     "IMSI1234",
+		CreatedAt:    strfmt.DateTime(createdAt),
+		WarrantState: models.NetworkProbeDataWarrantStateExpired,
+	}))
+	assert.NoError(t, store.StoreNProbeData("n1", "IMSI1235", models.NetworkProbeData{
+		TargetID:  "IMSI1235",
+		CreatedAt: strfmt.DateTime(createdAt.Add(time.Minute)),
+	}))
+	nextPageToken := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d/IMSI1235", createdAt.Add(time.Minute).UnixNano())))
+	noPageToken := ""
+
+	tc.URL = testURLRoot + "?sort=-created_at&page_size=1"
+	tc.ExpectedResult = &models.NetworkProbeTaskPage{NextPageToken: &nextPageToken, Tasks: []*models.NetworkProbeTask{task2}}
+	tests.RunUnitTest(t, e, tc)
+
+	tc.URL = testURLRoot + "?sort=-created_at&page_size=1&page_token=" + nextPageToken
+	tc.ExpectedResult = &models.NetworkProbeTaskPage{NextPageToken: &noPageToken, Tasks: []*models.NetworkProbeTask{task1}}
+	tests.RunUnitTest(t, e, tc)
+
+	tc.URL = testURLRoot + "?target_id=IMSI1235"
+	tc.ExpectedResult = &models.NetworkProbeTaskPage{NextPageToken: &noPageToken, Tasks: []*models.NetworkProbeTask{task2}}
+	tests.RunUnitTest(t, e, tc)
+
+	// the tasks whose warrant is not time-bounded are active
+	tc.URL = testURLRoot + "?state=active"
+	tests.RunUnitTest(t, e, tc)
+
+	tc.URL = testURLRoot + "?state=expired&sort=created_at"
+	tc.ExpectedResult = &models.NetworkProbeTaskPage{NextPageToken: &noPageToken, Tasks: []*models.NetworkProbeTask{task1}}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:                 "GET",
+		URL:                    testURLRoot + "?sort=target_id",
+		Handler:                listNetworkProbeTasks,
+		ParamNames:             []string{"network_id"},
+		ParamValues:            []string{"n1"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "invalid sort target_id",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc.URL = testURLRoot + "?page_token=garbage"
+	tc.ExpectedErrorSubstring = "invalid page token"
+	tests.RunUnitTest(t, e, tc)
 }
 
 func TestGetNetworkProbeTask(t *testing.T) {
@@ -311,7 +724,8 @@ func TestDeleteNetworkProbeTask(t *testing.T) {
 
 	e := echo.New()
 	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id"
-	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
 	deleteNetworkProbeTask := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.DELETE).HandlerFunc
 
 	_, err = configurator.CreateEntities(
@@ -342,15 +756,42 @@ func TestDeleteNetworkProbeTask(t *testing.T) {
 	)
 	assert.NoError(t, err)
 
-	tc := tests.Test{
-		Method:         "DELETE",
-		URL:            testURLRoot,
-		Handler:        deleteNetworkProbeTask,
-		ParamNames:     []string{"network_id", "task_id"},
-		ParamValues:    []string{"n1", "IMSI1234"},
-		ExpectedStatus: 204,
+	runOnTask := func(taskID string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest("DELETE", "/", nil)
+		req.Header.Set(access.CLIENT_CERT_CN_KEY, "admin")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("network_id", "task_id")
+		c.SetParamValues("n1", taskID)
+		return rec, deleteNetworkProbeTask(c)
 	}
-	tests.RunUnitTest(t, e, tc)
+
+	_, err = runOnTask("IMSI1236")
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	// the deletion is requested, the manager deleting the task
+	rec, err := runOnTask("IMSI1234")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	requested := &models.NetworkProbeTaskDeletion{}
+	assert.NoError(t, requested.UnmarshalBinary(rec.Body.Bytes()))
+	assert.Equal(t, "admin", requested.RequestedBy)
+	assert.True(t, models.IsTaskDeletionPending(requested))
+	stored, err := store.GetTaskDeletion("n1", "IMSI1234")
+	assert.NoError(t, err)
+	assert.Equal(t, requested, stored)
+
+	// requesting it again keeps the first request
+	rec, err = runOnTask("IMSI1234")
+	assert.NoError(t, err)
+	again := &models.NetworkProbeTaskDeletion{}
+	assert.NoError(t, again.UnmarshalBinary(rec.Body.Bytes()))
+	assert.Equal(t, requested, again)
+
+	assert.NoError(t, tasks.Delete(store, "n1", "IMSI1234"))
+	stored, err = store.GetTaskDeletion("n1", "IMSI1234")
+	assert.NoError(t, err)
+	assert.False(t, models.IsTaskDeletionPending(stored))
 
 	actual, _, err := configurator.LoadAllEntitiesOfType("n1", lte.NetworkProbeTaskEntityType, configurator.FullEntityLoadCriteria(), serdes.Entity)
 	assert.NoError(t, err)
@@ -371,299 +812,2317 @@ func TestDeleteNetworkProbeTask(t *testing.T) {
 	assert.Equal(t, expected, actual[0])
 }
 
-func TestCreateNetworkProbeDestination(t *testing.T) {
+func TestGetNetworkProbeTaskStatus(t *testing.T) {
 	configuratorTestInit.StartTestService(t)
 	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
 	assert.NoError(t, err)
 
 	e := echo.New()
-	testURLRoot := "/magma/v1/lte/:network_id/network_probe/destinations"
-	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
-	createNetworkProbeDestination := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.POST).HandlerFunc
-
-	payload := &models.NetworkProbeDestination{
-		DestinationID: "test",
-		DestinationDetails: &models.NetworkProbeDestinationDetails{
-			DeliveryAddress: "127.0.0.1:4000",
-			DeliveryType:    "all",
-		},
-	}
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id/status"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getNetworkProbeTaskStatus := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
 
 	tc := tests.Test{
-		Method:         "POST",
+		Method:         "GET",
 		URL:            testURLRoot,
-		Payload:        payload,
-		Handler:        createNetworkProbeDestination,
-		ParamNames:     []string{"network_id"},
-		ParamValues:    []string{"n1"},
-		ExpectedStatus: 201,
+		Handler:        getNetworkProbeTaskStatus,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 404,
+		ExpectedError:  "Not Found",
 	}
 	tests.RunUnitTest(t, e, tc)
 
-	actual, err := configurator.LoadEntity("n1", lte.NetworkProbeDestinationEntityType, "test", configurator.FullEntityLoadCriteria(), serdes.Entity)
-	assert.NoError(t, err)
-	expected := configurator.NetworkEntity{
-		NetworkID: "n1",
-		Type:      lte.NetworkProbeDestinationEntityType,
-		Key:       "test",
-		Config:    payload.DestinationDetails,
-		GraphID:   "2",
+	data := models.NetworkProbeData{
+		TargetID:          "IMSI1234",
+		SequenceNumber:    12,
+		LastExported:      strfmt.DateTime(time.Unix(1615000000, 0).UTC()),
+		RecordsExported:   12,
+		DeliveryErrors:    1,
+		LastDeliveryError: "connection reset by peer",
+		ExporterState:     models.NetworkProbeDataExporterStateConnected,
 	}
-	assert.Equal(t, expected, actual)
-}
-
-func TestListNetworkProbeDestinations(t *testing.T) {
-	configuratorTestInit.StartTestService(t)
-	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	err = store.StoreNProbeData("n1", "IMSI1234", data)
 	assert.NoError(t, err)
 
-	e := echo.New()
-	testURLRoot := "/magma/v1/lte/:network_id/network_probe/destinations"
-	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
-	listNetworkProbeDestinations := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
-
-	tc := tests.Test{
+	tc = tests.Test{
 		Method:         "GET",
 		URL:            testURLRoot,
-		Handler:        listNetworkProbeDestinations,
-		ParamNames:     []string{"network_id"},
-		ParamValues:    []string{"n1"},
+		Handler:        getNetworkProbeTaskStatus,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
 		ExpectedStatus: 200,
-		ExpectedResult: tests.JSONMarshaler(map[string]*models.NetworkProbeDestination{}),
+		ExpectedResult: &data,
 	}
 	tests.RunUnitTest(t, e, tc)
+}
 
-	_, err = configurator.CreateEntities(
-		"n1",
-		[]configurator.NetworkEntity{
-			{
-				Key:  "1111-2222-3333",
-				Type: lte.NetworkProbeDestinationEntityType,
-				Config: &models.NetworkProbeDestinationDetails{
-					DeliveryAddress: "127.0.0.1:4000",
-					DeliveryType:    "all",
-				},
-			},
-			{
-				Key:  "2222-3333-4444",
-				Type: lte.NetworkProbeDestinationEntityType,
-				Config: &models.NetworkProbeDestinationDetails{
-					DeliveryAddress: "127.0.0.1:4001",
-					DeliveryType:    "all",
-				},
-			},
-		},
-		serdes.Entity,
-	)
+func TestPauseNetworkProbeTask(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	pauseTask := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/pause", obsidian.POST).HandlerFunc
+	resumeTask := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/resume", obsidian.POST).HandlerFunc
+	runOnTask := func(handler echo.HandlerFunc, taskID string) error {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set(access.CLIENT_CERT_CN_KEY, "admin")
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("network_id", "task_id")
+		c.SetParamValues("n1", taskID)
+		return handler(c)
+	}
+
+	err := runOnTask(pauseTask, "IMSI1234")
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	data := models.NetworkProbeData{TargetID: "IMSI1234", SequenceNumber: 12}
+	assert.NoError(t, store.StoreNProbeData("n1", "IMSI1234", data))
+	err = runOnTask(resumeTask, "IMSI1234")
+	assert.Equal(t, http.StatusConflict, err.(*echo.HTTPError).Code)
+
+	assert.NoError(t, runOnTask(pauseTask, "IMSI1234"))
+	err = runOnTask(pauseTask, "IMSI1234")
+	assert.Equal(t, http.StatusConflict, err.(*echo.HTTPError).Code)
+	pause, err := store.GetTaskPause("n1", "IMSI1234")
 	assert.NoError(t, err)
+	assert.True(t, pause.Paused)
+	assert.Equal(t, "admin", pause.PausedBy)
 
-	tc = tests.Test{
-		Method:         "GET",
-		URL:            testURLRoot,
-		Handler:        listNetworkProbeDestinations,
-		ParamNames:     []string{"network_id"},
-		ParamValues:    []string{"n1"},
-		ExpectedStatus: 200,
-		ExpectedResult: tests.JSONMarshaler(map[string]*models.NetworkProbeDestination{
-			"1111-2222-3333": {
+	// the state of the task is kept while it is paused
+	assert.NoError(t, runOnTask(resumeTask, "IMSI1234"))
+	pause, err = store.GetTaskPause("n1", "IMSI1234")
+	assert.NoError(t, err)
+	assert.False(t, pause.Paused)
+	assert.False(t, time.Time(pause.ResumedAt).IsZero())
+	state, err := store.GetNProbeData("n1", "IMSI1234")
+	assert.NoError(t, err)
+	assert.Equal(t, data, *state)
+}
+
+func TestNetworkProbeTaskLifecycle(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getLifecycle := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/lifecycle", obsidian.GET).HandlerFunc
+	transition := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/lifecycle", obsidian.POST).HandlerFunc
+	pauseTask := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/pause", obsidian.POST).HandlerFunc
+	runOnTask := func(handler echo.HandlerFunc, method, body string) (*models.NetworkProbeTaskLifecycle, error) {
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(access.CLIENT_CERT_CN_KEY, "admin")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("network_id", "task_id")
+		c.SetParamValues("n1", "IMSI1234")
+		if err := handler(c); err != nil {
+			return nil, err
+		}
+		lifecycle := &models.NetworkProbeTaskLifecycle{}
+		return lifecycle, lifecycle.UnmarshalBinary(rec.Body.Bytes())
+	}
+
+	_, err := runOnTask(getLifecycle, "GET", "")
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+	assert.NoError(t, store.StoreNProbeData("n1", "IMSI1234", models.NetworkProbeData{TargetID: "IMSI1234"}))
+
+	// tasks are pending until first processed
+	lifecycle, err := runOnTask(getLifecycle, "GET", "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.NetworkProbeTaskLifecycleStatePending, lifecycle.State)
+	assert.Empty(t, lifecycle.Transitions)
+
+	// only the suspension and the resumption of a task are requested
+	_, err = runOnTask(transition, "POST", `{"to":"terminated"}`)
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	_, err = runOnTask(transition, "POST", `{"to":"active"}`)
+	assert.EqualError(t, err, "code=409, message=task is not paused")
+
+	lifecycle, err = runOnTask(transition, "POST", `{"to":"suspended","reason":"agency request 2021-118"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, models.NetworkProbeTaskLifecycleStateSuspended, lifecycle.State)
+	assert.Equal(t, "agency request 2021-118", lifecycle.Reason)
+	assert.Len(t, lifecycle.Transitions, 1)
+	assert.Equal(t, models.NetworkProbeTaskTransitionFromPending, lifecycle.Transitions[0].From)
+	assert.Equal(t, "admin", lifecycle.Transitions[0].Actor)
+	assert.Equal(t, lifecycle.Since, lifecycle.Transitions[0].At)
+	pause, err := store.GetTaskPause("n1", "IMSI1234")
+	assert.NoError(t, err)
+	assert.True(t, pause.Paused)
+	_, err = runOnTask(pauseTask, "POST", "")
+	assert.EqualError(t, err, "code=409, message=task is already paused")
+
+	lifecycle, err = runOnTask(transition, "POST", `{"to":"active"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, models.NetworkProbeTaskLifecycleStateActive, lifecycle.State)
+	assert.Equal(t, "resumed", lifecycle.Reason)
+	assert.Len(t, lifecycle.Transitions, 2)
+	stored, err := runOnTask(getLifecycle, "GET", "")
+	assert.NoError(t, err)
+	assert.Equal(t, lifecycle, stored)
+
+	// terminated tasks can't be suspended
+	lifecycle.State = models.NetworkProbeTaskLifecycleStateTerminated
+	assert.NoError(t, store.StoreTaskLifecycle("n1", "IMSI1234", *lifecycle))
+	_, err = runOnTask(pauseTask, "POST", "")
+	assert.EqualError(t, err, "code=409, message=task can't move from terminated to suspended")
+}
+
+func TestRotateNetworkProbeTaskXID(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	rotateXID := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/rotate_xid", obsidian.POST).HandlerFunc
+	runOnTask := func(taskID string) error {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set(access.CLIENT_CERT_CN_KEY, "admin")
+		c := e.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("network_id", "task_id")
+		c.SetParamValues("n1", taskID)
+		return rotateXID(c)
+	}
+
+	taskID := "29f28e1c-f230-486a-a860-f5a784ab9177"
+	err := runOnTask(taskID)
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	data := models.NetworkProbeData{TargetID: "IMSI1234", SequenceNumber: 12}
+	assert.NoError(t, store.StoreNProbeData("n1", taskID, data))
+	assert.NoError(t, runOnTask(taskID))
+	rotation, err := store.GetTaskXIDRotation("n1", taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, taskID, rotation.PreviousXid)
+	assert.NotEqual(t, taskID, rotation.Xid)
+	assert.Equal(t, "admin", rotation.RequestedBy)
+
+	// the rotation must be applied before the next one
+	err = runOnTask(taskID)
+	assert.Equal(t, http.StatusConflict, err.(*echo.HTTPError).Code)
+
+	data.Xid = rotation.Xid
+	data.PreviousXid = rotation.PreviousXid
+	assert.NoError(t, store.StoreNProbeData("n1", taskID, data))
+	assert.NoError(t, runOnTask(taskID))
+	next, err := store.GetTaskXIDRotation("n1", taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, rotation.Xid, next.PreviousXid)
+}
+
+func TestReplayNetworkProbeTask(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getReplay := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/replay", obsidian.GET).HandlerFunc
+	replay := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/replay", obsidian.POST).HandlerFunc
+	runOnTask := func(handler echo.HandlerFunc, method, body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(access.CLIENT_CERT_CN_KEY, "admin")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("network_id", "task_id")
+		c.SetParamValues("n1", "IMSI1234")
+		return rec, handler(c)
+	}
+
+	body := `{"start":"2021-02-18T05:00:00Z","end":"2021-02-18T06:00:00Z"}`
+	_, err := runOnTask(replay, "POST", body)
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	assert.NoError(t, store.StoreNProbeData("n1", "IMSI1234", models.NetworkProbeData{TargetID: "IMSI1234", SequenceNumber: 12}))
+	_, err = runOnTask(getReplay, "GET", "")
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	// the time range must be in the past and not empty
+	_, err = runOnTask(replay, "POST", `{"start":"2021-02-18T06:00:00Z","end":"2021-02-18T05:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	_, err = runOnTask(replay, "POST", `{"start":"2021-02-18T06:00:00Z","end":"`+future+`"}`)
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+
+	_, err = runOnTask(replay, "POST", body)
+	assert.NoError(t, err)
+	rec, err := runOnTask(getReplay, "GET", "")
+	assert.NoError(t, err)
+	requested := &models.NetworkProbeTaskReplay{}
+	assert.NoError(t, requested.UnmarshalBinary(rec.Body.Bytes()))
+	assert.Equal(t, "2021-02-18T05:00:00.000Z", requested.Start.String())
+	assert.Equal(t, "admin", requested.RequestedBy)
+	assert.True(t, models.IsTaskReplayPending(requested))
+
+	// the replay must be completed before the next one
+	_, err = runOnTask(replay, "POST", body)
+	assert.Equal(t, http.StatusConflict, err.(*echo.HTTPError).Code)
+
+	requested.CompletedAt = strfmt.DateTime(time.Now().UTC())
+	assert.NoError(t, store.StoreTaskReplay("n1", "IMSI1234", *requested))
+	_, err = runOnTask(replay, "POST", body)
+	assert.NoError(t, err)
+}
+
+func TestInjectNetworkProbeTaskTestRecord(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getTestRecord := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/test_record", obsidian.GET).HandlerFunc
+	injectTestRecord := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/test_record", obsidian.POST).HandlerFunc
+	runOnTask := func(handler echo.HandlerFunc, method string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(method, "/", nil)
+		req.Header.Set(access.CLIENT_CERT_CN_KEY, "admin")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("network_id", "task_id")
+		c.SetParamValues("n1", "IMSI1234")
+		return rec, handler(c)
+	}
+
+	_, err := runOnTask(injectTestRecord, "POST")
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	assert.NoError(t, store.StoreNProbeData("n1", "IMSI1234", models.NetworkProbeData{TargetID: "IMSI1234", SequenceNumber: 12}))
+	_, err = runOnTask(getTestRecord, "GET")
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	_, err = runOnTask(injectTestRecord, "POST")
+	assert.NoError(t, err)
+	rec, err := runOnTask(getTestRecord, "GET")
+	assert.NoError(t, err)
+	requested := &models.NetworkProbeTaskTestRecord{}
+	assert.NoError(t, requested.UnmarshalBinary(rec.Body.Bytes()))
+	assert.NotEmpty(t, requested.ID)
+	assert.Equal(t, "admin", requested.RequestedBy)
+	assert.True(t, models.IsTestRecordPending(requested))
+
+	// the test record must be delivered before the next one
+	_, err = runOnTask(injectTestRecord, "POST")
+	assert.Equal(t, http.StatusConflict, err.(*echo.HTTPError).Code)
+
+	requested.SequenceNumber = 12
+	requested.DeliveredAt = strfmt.DateTime(time.Now().UTC())
+	assert.NoError(t, store.StoreTaskTestRecord("n1", "IMSI1234", *requested))
+	rec, err = runOnTask(injectTestRecord, "POST")
+	assert.NoError(t, err)
+	next := &models.NetworkProbeTaskTestRecord{}
+	assert.NoError(t, next.UnmarshalBinary(rec.Body.Bytes()))
+	assert.NotEqual(t, requested.ID, next.ID)
+}
+
+func TestNetworkProbeTaskBookmarks(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id/bookmarks"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	listBookmarks := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+	getBookmark := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/:bookmark_name", obsidian.GET).HandlerFunc
+	setBookmark := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/:bookmark_name", obsidian.PUT).HandlerFunc
+	deleteBookmark := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/:bookmark_name", obsidian.DELETE).HandlerFunc
+	runOnBookmark := func(handler echo.HandlerFunc, method, name, body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(access.CLIENT_CERT_CN_KEY, "auditor1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("network_id", "task_id", "bookmark_name")
+		c.SetParamValues("n1", "IMSI1234", name)
+		return rec, handler(c)
+	}
+
+	_, err := runOnBookmark(setBookmark, "PUT", "sample-1", `{}`)
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	lastExported := strfmt.DateTime(time.Unix(1613625206, 0).UTC())
+	data := models.NetworkProbeData{TargetID: "IMSI1234", SequenceNumber: 12, LastExported: lastExported}
+	assert.NoError(t, store.StoreNProbeData("n1", "IMSI1234", data))
+
+	// without a sequence number, the last exported record is bookmarked
+	_, err = runOnBookmark(setBookmark, "PUT", "sample-1", `{}`)
+	assert.NoError(t, err)
+	_, err = runOnBookmark(setBookmark, "PUT", "sample-0", `{"sequence_number":4}`)
+	assert.NoError(t, err)
+	_, err = runOnBookmark(setBookmark, "PUT", "sample-2", `{"sequence_number":12}`)
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	_, err = runOnBookmark(setBookmark, "PUT", "sample/2", `{}`)
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+
+	bookmark, err := store.GetBookmark("n1", "IMSI1234", "sample-1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(11), bookmark.SequenceNumber)
+	assert.Equal(t, lastExported, bookmark.Timestamp)
+	assert.Equal(t, "auditor1", bookmark.CreatedBy)
+
+	rec, err := runOnBookmark(getBookmark, "GET", "sample-1", "")
+	assert.NoError(t, err)
+	assert.Contains(t, rec.Body.String(), `"sequence_number":11`)
+	_, err = runOnBookmark(getBookmark, "GET", "sample-2", "")
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	_, err = runOnBookmark(deleteBookmark, "DELETE", "sample-1", "")
+	assert.NoError(t, err)
+	rec, err = runOnBookmark(listBookmarks, "GET", "", "")
+	assert.NoError(t, err)
+	bookmarks, err := store.GetBookmarks("n1", "IMSI1234")
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 1)
+	assert.Equal(t, "sample-0", bookmarks[0].Name)
+	assert.Contains(t, rec.Body.String(), `"name":"sample-0"`)
+}
+
+func TestGetNetworkProbeTaskQuarantine(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id/quarantine"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getNetworkProbeTaskQuarantine := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getNetworkProbeTaskQuarantine,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler([]models.NetworkProbeQuarantineEntry{}),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	entry := models.NetworkProbeQuarantineEntry{
+		EventType:      "session_created",
+		StreamName:     "sessiond",
+		EventTimestamp: "2021-02-18T05:13:26.019519+00:00",
+		Field:          "bearer_params",
+		Error:          "invalid bearer_params: ip_addr is not a valid IPv4 address: \"192.168.128\"",
+		QuarantinedAt:  strfmt.DateTime(time.Unix(1615000000, 0).UTC()),
+	}
+	err := store.StoreQuarantineEntry("n1", "IMSI1234", entry)
+	assert.NoError(t, err)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getNetworkProbeTaskQuarantine,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler([]models.NetworkProbeQuarantineEntry{entry}),
+	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestGetNetworkProbeTaskActivity(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id/activity"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getNetworkProbeTaskActivity := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getNetworkProbeTaskActivity,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 200,
+		ExpectedResult: &models.NetworkProbeActivity{Granularity: models.NetworkProbeActivityGranularityHour},
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	day := time.Now().UTC().Truncate(24 * time.Hour).Add(-24 * time.Hour)
+	err := store.IncrementActivity("n1", "IMSI1234", map[time.Time]uint64{
+		day.Add(time.Hour):      2,
+		day.Add(5 * time.Hour):  3,
+		day.Add(25 * time.Hour): 4,
+	})
+	assert.NoError(t, err)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getNetworkProbeTaskActivity,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 200,
+		ExpectedResult: &models.NetworkProbeActivity{
+			Granularity: models.NetworkProbeActivityGranularityHour,
+			Buckets: []*models.NetworkProbeActivityBucket{
+				{Start: strfmt.DateTime(day.Add(time.Hour)), Count: 2},
+				{Start: strfmt.DateTime(day.Add(5 * time.Hour)), Count: 3},
+				{Start: strfmt.DateTime(day.Add(25 * time.Hour)), Count: 4},
+			},
+		},
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot + "?granularity=day",
+		Handler:        getNetworkProbeTaskActivity,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 200,
+		ExpectedResult: &models.NetworkProbeActivity{
+			Granularity: models.NetworkProbeActivityGranularityDay,
+			Buckets: []*models.NetworkProbeActivityBucket{
+				{Start: strfmt.DateTime(day), Count: 5},
+				{Start: strfmt.DateTime(day.Add(24 * time.Hour)), Count: 4},
+			},
+		},
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:                 "GET",
+		URL:                    testURLRoot + "?granularity=week",
+		Handler:                getNetworkProbeTaskActivity,
+		ParamNames:             []string{"network_id", "task_id"},
+		ParamValues:            []string{"n1", "IMSI1234"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "invalid granularity week",
+	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestGetNetworkProbeTaskStatistics(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id/statistics"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getNetworkProbeTaskStatistics := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getNetworkProbeTaskStatistics,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 200,
+		ExpectedResult: &models.NetworkProbeTaskStatistics{},
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	start := time.Unix(1615000000, 0).UTC()
+	end := start.Add(24 * time.Hour)
+	assert.NoError(t, store.IncrementTaskStatistics("n1", "IMSI1234", 3, 300, start))
+	reported := models.NetworkProbeStatisticsInterval{
+		Start:          strfmt.DateTime(start),
+		End:            strfmt.DateTime(end),
+		Records:        3,
+		Bytes:          300,
+		SequenceNumber: 7,
+		ReportedAt:     strfmt.DateTime(end),
+	}
+	assert.NoError(t, store.ReportTaskStatistics("n1", "IMSI1234", reported))
+	assert.NoError(t, store.IncrementTaskStatistics("n1", "IMSI1234", 1, 120, end.Add(time.Hour)))
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getNetworkProbeTaskStatistics,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 200,
+		ExpectedResult: &models.NetworkProbeTaskStatistics{
+			Current:  &models.NetworkProbeStatisticsInterval{Start: strfmt.DateTime(end), Records: 1, Bytes: 120},
+			Reported: []*models.NetworkProbeStatisticsInterval{&reported},
+		},
+	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestGetNetworkProbeTaskDeliveries(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id/deliveries"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getNetworkProbeTaskDeliveries := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getNetworkProbeTaskDeliveries,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler([]models.NetworkProbeDeliveryRecord{}),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	deliveredAt := time.Unix(1615000000, 0).UTC()
+	records := []models.NetworkProbeDeliveryRecord{
+		{
+			TargetID:       "IMSI1234",
+			SequenceNumber: 0,
+			RecordHash:     "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			Timestamp:      strfmt.DateTime(deliveredAt.Add(-time.Minute)),
+			DeliveredAt:    strfmt.DateTime(deliveredAt),
+		},
+		{
+			TargetID:       "IMSI1234",
+			SequenceNumber: 1,
+			RecordHash:     "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752",
+			Timestamp:      strfmt.DateTime(deliveredAt.Add(time.Hour - time.Minute)),
+			DeliveredAt:    strfmt.DateTime(deliveredAt.Add(time.Hour)),
+		},
+	}
+	err := store.StoreDeliveryRecords("n1", "IMSI1234", records)
+	assert.NoError(t, err)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getNetworkProbeTaskDeliveries,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler(records),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot + "?start=2021-03-06T03:30:00Z",
+		Handler:        getNetworkProbeTaskDeliveries,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI1234"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler(records[1:]),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:                 "GET",
+		URL:                    testURLRoot + "?end=yesterday",
+		Handler:                getNetworkProbeTaskDeliveries,
+		ParamNames:             []string{"network_id", "task_id"},
+		ParamValues:            []string{"n1", "IMSI1234"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "invalid end yesterday",
+	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestGetNetworkProbeSessions(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/sessions"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getNetworkProbeSessions := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+
+	seenAt := time.Unix(1615000000, 0).UTC()
+	mappings := []models.NetworkProbeSessionMapping{
+		{
+			CorrelationID:       7,
+			TaskID:              "IMSI1234",
+			TargetID:            "IMSI1234",
+			SessionID:           "IMSI1234-1",
+			BearerID:            "5",
+			Apn:                 "internet",
+			FirstSequenceNumber: 0,
+			LastSequenceNumber:  0,
+			FirstSeen:           strfmt.DateTime(seenAt),
+			LastSeen:            strfmt.DateTime(seenAt),
+		},
+		{
+			CorrelationID:       7,
+			TaskID:              "IMSI1234",
+			TargetID:            "IMSI1234",
+			SessionID:           "IMSI1234-2",
+			BearerID:            "6",
+			Apn:                 "ims",
+			FirstSequenceNumber: 1,
+			LastSequenceNumber:  1,
+			FirstSeen:           strfmt.DateTime(seenAt.Add(time.Hour)),
+			LastSeen:            strfmt.DateTime(seenAt.Add(time.Hour)),
+		},
+	}
+	err := store.StoreSessionMappings("n1", mappings)
+	assert.NoError(t, err)
+
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot + "?correlation_id=7",
+		Handler:        getNetworkProbeSessions,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler(mappings),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot + "?session_id=IMSI1234-2",
+		Handler:        getNetworkProbeSessions,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler(mappings[1:]),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot + "?correlation_id=8",
+		Handler:        getNetworkProbeSessions,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler([]models.NetworkProbeSessionMapping{}),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:                 "GET",
+		URL:                    testURLRoot,
+		Handler:                getNetworkProbeSessions,
+		ParamNames:             []string{"network_id"},
+		ParamValues:            []string{"n1"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "correlation_id or session_id is required",
+	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestGetNetworkProbeBearerCorrelations(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/bearer_correlations"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getNetworkProbeBearerCorrelations := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+
+	activatedAt := time.Unix(1615000000, 0).UTC()
+	first, err := store.AllocateCorrelationID("n1", "IMSI1234", "5", activatedAt)
+	assert.NoError(t, err)
+	second, err := store.AllocateCorrelationID("n1", "IMSI1234", "6", activatedAt.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.CorrelationID, second.CorrelationID)
+	assert.NoError(t, store.ReleaseCorrelationIDs("n1", "IMSI1234", "5", activatedAt.Add(time.Hour)))
+	first.ReleasedAt = strfmt.DateTime(activatedAt.Add(time.Hour))
+
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot + "?imsi=IMSI1234",
+		Handler:        getNetworkProbeBearerCorrelations,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler([]models.NetworkProbeBearerCorrelation{*first, *second}),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot + "?correlation_id=" + strconv.FormatUint(second.CorrelationID, 10),
+		Handler:        getNetworkProbeBearerCorrelations,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler([]models.NetworkProbeBearerCorrelation{*second}),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// the bearer gets a new correlation ID once activated again
+	third, err := store.AllocateCorrelationID("n1", "IMSI1234", "5", activatedAt.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.CorrelationID, third.CorrelationID)
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot + "?correlation_id=" + strconv.FormatUint(first.CorrelationID, 10),
+		Handler:        getNetworkProbeBearerCorrelations,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler([]models.NetworkProbeBearerCorrelation{*first}),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:                 "GET",
+		URL:                    testURLRoot,
+		Handler:                getNetworkProbeBearerCorrelations,
+		ParamNames:             []string{"network_id"},
+		ParamValues:            []string{"n1"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "correlation_id or imsi is required",
+	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestGetNetworkProbeSyncState(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/sync_state"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetHandlers(store)
+	getNetworkProbeSyncState := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+	getSyncState := func(query string) *models.NetworkProbeSyncState {
+		req := httptest.NewRequest("GET", "/"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("network_id")
+		c.SetParamValues("n1")
+		assert.NoError(t, getNetworkProbeSyncState(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		state := &models.NetworkProbeSyncState{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), state))
+		assert.NoError(t, state.Validate(strfmt.Default))
+		return state
+	}
+
+	empty := getSyncState("")
+	assert.Equal(t, "n1", empty.NetworkID)
+	assert.Empty(t, empty.Tasks)
+
+	for _, taskID := range []string{"IMSI5678", "IMSI1234"} {
+		_, err = configurator.CreateEntity(
+			"n1",
+			configurator.NetworkEntity{
+				Key:  taskID,
+				Type: lte.NetworkProbeTaskEntityType,
+				Config: &models.NetworkProbeTaskDetails{
+					TargetID:      taskID,
+					TargetType:    "imsi",
+					DeliveryType:  "events_only",
+					CorrelationID: 8674665223082154000,
+				},
+			},
+			serdes.Entity,
+		)
+		assert.NoError(t, err)
+	}
+	lastExported := time.Unix(1615000000, 0).UTC()
+	assert.NoError(t, store.StoreNProbeData("n1", "IMSI1234", models.NetworkProbeData{
+		TargetID:        "IMSI1234",
+		SequenceNumber:  12,
+		RecordsExported: 12,
+		LastExported:    strfmt.DateTime(lastExported.In(time.FixedZone("CET", 3600))),
+	}))
+	assert.NoError(t, store.StoreTaskPause("n1", "IMSI5678", models.NetworkProbeTaskPause{Paused: true, PausedBy: "admin"}))
+
+	// the tasks are sorted, with their runtime state in UTC
+	state := getSyncState("")
+	assert.Len(t, state.Tasks, 2)
+	assert.NotEqual(t, empty.Digest, state.Digest)
+	first, second := state.Tasks[0], state.Tasks[1]
+	assert.Equal(t, "IMSI1234", first.TaskID)
+	assert.Equal(t, "IMSI1234", first.Xid)
+	assert.Equal(t, uint32(12), first.SequenceNumber)
+	assert.Equal(t, uint64(12), first.RecordsExported)
+	assert.Equal(t, lastExported, time.Time(first.LastExported))
+	assert.False(t, first.Paused)
+	assert.Equal(t, "IMSI5678", second.TaskID)
+	assert.True(t, second.Paused)
+	assert.Zero(t, second.SequenceNumber)
+	assert.NotEqual(t, first.ConfigDigest, second.ConfigDigest)
+
+	// the digest only changes with the provisioning of the tasks
+	assert.NoError(t, store.StoreNProbeData("n1", "IMSI1234", models.NetworkProbeData{TargetID: "IMSI1234", SequenceNumber: 13}))
+	assert.Equal(t, state.Digest, getSyncState("").Digest)
+	err = configurator.CreateOrUpdateEntityConfig("n1", lte.NetworkProbeTaskEntityType, "IMSI5678", &models.NetworkProbeTaskDetails{
+		TargetID:      "IMSI5678",
+		TargetType:    "imsi",
+		DeliveryType:  "all",
+		CorrelationID: 8674665223082154000,
+	}, serdes.Entity)
+	assert.NoError(t, err)
+	updated := getSyncState("")
+	assert.NotEqual(t, state.Digest, updated.Digest)
+	assert.Equal(t, first.ConfigDigest, updated.Tasks[0].ConfigDigest)
+	assert.NotEqual(t, second.ConfigDigest, updated.Tasks[1].ConfigDigest)
+
+	// the tasks requested which are not provisioned are reported missing
+	selected := getSyncState("?task_id=IMSI9999&task_id=IMSI1234")
+	assert.Len(t, selected.Tasks, 1)
+	assert.Equal(t, "IMSI1234", selected.Tasks[0].TaskID)
+	assert.Equal(t, []string{"IMSI9999"}, selected.MissingTaskIds)
+}
+
+func TestCreateNetworkProbeDestination(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/destinations"
+	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
+	createNetworkProbeDestination := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.POST).HandlerFunc
+
+	payload := &models.NetworkProbeDestination{
+		DestinationID: "test",
+		DestinationDetails: &models.NetworkProbeDestinationDetails{
+			DeliveryAddress: "127.0.0.1:4000",
+			DeliveryType:    "all",
+			TLSServerName:   "hi2.lemf.example.org",
+			AlpnProtocols:   []string{"x2"},
+		},
+	}
+
+	tc := tests.Test{
+		Method:         "POST",
+		URL:            testURLRoot,
+		Payload:        payload,
+		Handler:        createNetworkProbeDestination,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 201,
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	actual, err := configurator.LoadEntity("n1", lte.NetworkProbeDestinationEntityType, "test", configurator.FullEntityLoadCriteria(), serdes.Entity)
+	assert.NoError(t, err)
+	expected := configurator.NetworkEntity{
+		NetworkID: "n1",
+		Type:      lte.NetworkProbeDestinationEntityType,
+		Key:       "test",
+		Config:    payload.DestinationDetails,
+		GraphID:   "2",
+	}
+	assert.Equal(t, expected, actual)
+
+	payload = &models.NetworkProbeDestination{
+		DestinationID: "test2",
+		DestinationDetails: &models.NetworkProbeDestinationDetails{
+			DeliveryAddress: "127.0.0.1:4000",
+			DeliveryType:    "all",
+			TLSServerName:   "hi2.lemf.example.org:443",
+		},
+	}
+	tc = tests.Test{
+		Method:                 "POST",
+		URL:                    testURLRoot,
+		Payload:                payload,
+		Handler:                createNetworkProbeDestination,
+		ParamNames:             []string{"network_id"},
+		ParamValues:            []string{"n1"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "tls_server_name in body should match",
+	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestListNetworkProbeDestinations(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/destinations"
+	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
+	listNetworkProbeDestinations := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        listNetworkProbeDestinations,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler(map[string]*models.NetworkProbeDestination{}),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	_, err = configurator.CreateEntities(
+		"n1",
+		[]configurator.NetworkEntity{
+			{
+				Key:  "1111-2222-3333",
+				Type: lte.NetworkProbeDestinationEntityType,
+				Config: &models.NetworkProbeDestinationDetails{
+					DeliveryAddress: "127.0.0.1:4000",
+					DeliveryType:    "all",
+				},
+			},
+			{
+				Key:  "2222-3333-4444",
+				Type: lte.NetworkProbeDestinationEntityType,
+				Config: &models.NetworkProbeDestinationDetails{
+					DeliveryAddress: "127.0.0.1:4001",
+					DeliveryType:    "all",
+				},
+			},
+		},
+		serdes.Entity,
+	)
+	assert.NoError(t, err)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        listNetworkProbeDestinations,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler(map[string]*models.NetworkProbeDestination{
+			"1111-2222-3333": {
 				DestinationID: "1111-2222-3333",
 				DestinationDetails: &models.NetworkProbeDestinationDetails{
 					DeliveryAddress: "127.0.0.1:4000",
 					DeliveryType:    "all",
 				},
 			},
-			"2222-3333-4444": {
-				DestinationID: "2222-3333-4444",
-				DestinationDetails: &models.NetworkProbeDestinationDetails{
-					DeliveryAddress: "127.0.0.1:4001",
-					DeliveryType:    "all",
-				},
+			"2222-3333-4444": {
+				DestinationID: "2222-3333-4444",
+				DestinationDetails: &models.NetworkProbeDestinationDetails{
+					DeliveryAddress: "127.0.0.1:4001",
+					DeliveryType:    "all",
+				},
+			},
+		}),
+	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestGetNetworkProbeDestination(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/destinations/:destination_id"
+	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
+	getNetworkProbeDestination := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getNetworkProbeDestination,
+		ParamNames:     []string{"network_id", "destination_id"},
+		ParamValues:    []string{"n1", "1111-2222-3333"},
+		ExpectedStatus: 404,
+		ExpectedError:  "Not Found",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	_, err = configurator.CreateEntity(
+		"n1",
+		configurator.NetworkEntity{
+			Key:  "1111-2222-3333",
+			Type: lte.NetworkProbeDestinationEntityType,
+			Config: &models.NetworkProbeDestinationDetails{
+				DeliveryAddress: "127.0.0.1:4000",
+				DeliveryType:    "all",
+			},
+		},
+		serdes.Entity,
+	)
+	assert.NoError(t, err)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getNetworkProbeDestination,
+		ParamNames:     []string{"network_id", "destination_id"},
+		ParamValues:    []string{"n1", "1111-2222-3333"},
+		ExpectedStatus: 200,
+		ExpectedResult: &models.NetworkProbeDestination{
+			DestinationID: "1111-2222-3333",
+			DestinationDetails: &models.NetworkProbeDestinationDetails{
+				DeliveryAddress: "127.0.0.1:4000",
+				DeliveryType:    "all",
+			},
+		},
+	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestUpdateNetworkProbeDestination(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/destinations/:destination_id"
+	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
+	updateNetworkProbeDestination := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.PUT).HandlerFunc
+
+	// 404
+	payload := &models.NetworkProbeDestination{
+		DestinationID: "1111-2222-3333",
+		DestinationDetails: &models.NetworkProbeDestinationDetails{
+			DeliveryAddress: "127.0.0.1:4000",
+			DeliveryType:    "all",
+		},
+	}
+
+	tc := tests.Test{
+		Method:         "PUT",
+		URL:            testURLRoot,
+		Handler:        updateNetworkProbeDestination,
+		Payload:        payload,
+		ParamNames:     []string{"network_id", "destination_id"},
+		ParamValues:    []string{"n1", "1111-2222-3333"},
+		ExpectedStatus: 500,
+		ExpectedError:  "failed to load entity being updated: expected to load 1 ent for update, got 0",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// Add the NetworkProbeDestination
+	_, err = configurator.CreateEntity(
+		"n1",
+		configurator.NetworkEntity{
+			Key:  "1111-2222-3333",
+			Type: lte.NetworkProbeDestinationEntityType,
+			Config: &models.NetworkProbeDestinationDetails{
+				DeliveryAddress: "127.0.0.1:4000",
+				DeliveryType:    "all",
+			},
+		},
+		serdes.Entity,
+	)
+	assert.NoError(t, err)
+
+	tc = tests.Test{
+		Method:         "PUT",
+		URL:            testURLRoot,
+		Handler:        updateNetworkProbeDestination,
+		Payload:        payload,
+		ParamNames:     []string{"network_id", "destination_id"},
+		ParamValues:    []string{"n1", "1111-2222-3333"},
+		ExpectedStatus: 204,
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	actual, err := configurator.LoadEntity("n1", lte.NetworkProbeDestinationEntityType, "1111-2222-3333", configurator.FullEntityLoadCriteria(), serdes.Entity)
+	assert.NoError(t, err)
+	expected := configurator.NetworkEntity{
+		NetworkID: "n1",
+		Type:      lte.NetworkProbeDestinationEntityType,
+		Key:       "1111-2222-3333",
+		Config:    payload.DestinationDetails,
+		GraphID:   "2",
+		Version:   1,
+	}
+	assert.Equal(t, expected, actual)
+
+	// the payload encryption key is kept when sent back redacted
+	key := "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	payload.DestinationDetails.PayloadEncryption = &models.NetworkProbePayloadEncryption{
+		EncryptionType: models.NetworkProbePayloadEncryptionEncryptionTypeAes256Cbc,
+		Key:            key[:48],
+	}
+	tc.ExpectedStatus = 400
+	tc.ExpectedError = "payload_encryption key of 24 bytes, aes-256-cbc requires 32 bytes"
+	tests.RunUnitTest(t, e, tc)
+
+	payload.DestinationDetails.PayloadEncryption.Key = key
+	tc.ExpectedStatus = 204
+	tc.ExpectedError = ""
+	tests.RunUnitTest(t, e, tc)
+
+	payload.DestinationDetails.RedactPayloadEncryptionKey()
+	assert.Equal(t, "630dcd2966c43366", payload.DestinationDetails.PayloadEncryption.KeyID)
+	tests.RunUnitTest(t, e, tc)
+	actual, err = configurator.LoadEntity("n1", lte.NetworkProbeDestinationEntityType, "1111-2222-3333", configurator.FullEntityLoadCriteria(), serdes.Entity)
+	assert.NoError(t, err)
+	assert.Equal(t, key, actual.Config.(*models.NetworkProbeDestinationDetails).PayloadEncryption.Key)
+	assert.Empty(t, actual.Config.(*models.NetworkProbeDestinationDetails).PayloadEncryption.KeyID)
+
+	payload.DestinationDetails.PayloadEncryption = &models.NetworkProbePayloadEncryption{
+		EncryptionType: models.NetworkProbePayloadEncryptionEncryptionTypeAes256Cbc,
+		KeyID:          "0000000000000000",
+	}
+	tc.ExpectedStatus = 400
+	tc.ExpectedError = "payload_encryption key_id 0000000000000000 is not the one of the current key"
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestDeleteNetworkProbeDestination(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/destinations/:destination_id"
+	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
+	deleteNetworkProbeDestination := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.DELETE).HandlerFunc
+
+	_, err = configurator.CreateEntities(
+		"n1",
+		[]configurator.NetworkEntity{
+			{
+				Key:  "1111-2222-3333",
+				Type: lte.NetworkProbeDestinationEntityType,
+				Config: &models.NetworkProbeDestinationDetails{
+					DeliveryAddress: "127.0.0.1:4000",
+					DeliveryType:    "events_only",
+				},
+			},
+			{
+				Key:  "2222-3333-4444",
+				Type: lte.NetworkProbeDestinationEntityType,
+				Config: &models.NetworkProbeDestinationDetails{
+					DeliveryAddress: "127.0.0.1:4001",
+					DeliveryType:    "all",
+				},
+			},
+		},
+		serdes.Entity,
+	)
+	assert.NoError(t, err)
+
+	tc := tests.Test{
+		Method:         "DELETE",
+		URL:            testURLRoot,
+		Handler:        deleteNetworkProbeDestination,
+		ParamNames:     []string{"network_id", "destination_id"},
+		ParamValues:    []string{"n1", "1111-2222-3333"},
+		ExpectedStatus: 204,
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	actual, _, err := configurator.LoadAllEntitiesOfType("n1", lte.NetworkProbeDestinationEntityType, configurator.FullEntityLoadCriteria(), serdes.Entity)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(actual))
+	expected := configurator.NetworkEntity{
+		NetworkID: "n1",
+		Type:      lte.NetworkProbeDestinationEntityType,
+		Key:       "2222-3333-4444",
+		Config: &models.NetworkProbeDestinationDetails{
+			DeliveryAddress: "127.0.0.1:4001",
+			DeliveryType:    "all",
+		},
+		GraphID: "4",
+		Version: 0,
+	}
+	assert.Equal(t, expected, actual[0])
+}
+
+func TestCheckConformance(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/conformance"
+	checkConformance := tests.GetHandlerByPathAndMethod(t, handlers.GetHandlers(getNProbeBlobstore(t)), testURLRoot, obsidian.POST).HandlerFunc
+
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			CorrelationID: 0x866cb397915ffe4,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.AttachSuccess,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+	}
+	record, err := encoding.MakeRecord(&event, task, 49002, 7)
+	assert.NoError(t, err)
+
+	report := &models.NetworkProbeConformanceReport{}
+	rec := postConformance(t, e, checkConformance, &models.NetworkProbeConformanceRequest{Record: record})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, report.UnmarshalBinary(rec.Body.Bytes()))
+	assert.True(t, report.Conformant)
+	assert.Len(t, report.Checks, 6)
+	assert.Equal(t, "report", report.RecordClass)
+	assert.Equal(t, uint32(encoding.EutranAttach), report.Event)
+	assert.Equal(t, string(task.TaskID), report.Xid)
+	assert.Equal(t, uint32(7), report.SequenceNumber)
+
+	// malformed records are reported rather than rejected
+	report = &models.NetworkProbeConformanceReport{}
+	rec = postConformance(t, e, checkConformance, &models.NetworkProbeConformanceRequest{Record: record[:len(record)-1]})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, report.UnmarshalBinary(rec.Body.Bytes()))
+	assert.False(t, report.Conformant)
+	assert.Equal(t, &models.NetworkProbeConformanceCheck{
+		Field:   "der",
+		Message: "malformed der: invalid input size",
+		Result:  "failed",
+		Rule:    "decode",
+	}, report.Checks[0])
+
+	err = runWithActor(e, checkConformance, "POST", `{"record":""}`, "admin")
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+}
+
+// postConformance posts a conformance request and returns the response
+func postConformance(t *testing.T, e *echo.Echo, handler echo.HandlerFunc, payload *models.NetworkProbeConformanceRequest) *httptest.ResponseRecorder {
+	body, err := payload.MarshalBinary()
+	assert.NoError(t, err)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(string(body)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("network_id")
+	c.SetParamValues("n1")
+	assert.NoError(t, handler(c))
+	return rec
+}
+
+func TestNetworkProbeNetworkConfig(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/config"
+	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
+	getConfig := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+	updateConfig := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.PUT).HandlerFunc
+	deleteConfig := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.DELETE).HandlerFunc
+
+	// networks only use the service config by default
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 404,
+		ExpectedError:  "Not found",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	payload := &models.NetworkProbeNetworkConfig{
+		DeliveryFunctionAddress: "10.10.0.2:6666",
+		OperatorID:              49002,
+		ModuleVersion:           "r14",
+		Hi2DomainID:             "1.3.6.1.4.1.28458.14",
+	}
+	tc = tests.Test{
+		Method:         "PUT",
+		URL:            testURLRoot,
+		Payload:        payload,
+		Handler:        updateConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 204,
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	actual, err := configurator.LoadNetworkConfig("n1", lte.NetworkProbeConfigType, serdes.Network)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, actual)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: payload,
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// Fail to create a destination whose domain ID identifies r14 in the network config
+	destinationsURL := "/magma/v1/lte/:network_id/network_probe/destinations"
+	createDestination := tests.GetHandlerByPathAndMethod(t, handlers, destinationsURL, obsidian.POST).HandlerFunc
+	tc = tests.Test{
+		Method: "POST",
+		URL:    destinationsURL,
+		Payload: &models.NetworkProbeDestination{
+			DestinationID: "test",
+			DestinationDetails: &models.NetworkProbeDestinationDetails{
+				DeliveryAddress: "127.0.0.1:4000",
+				DeliveryType:    "all",
+				ModuleVersion:   "r13",
+				Hi2DomainID:     "1.3.6.1.4.1.28458.14",
+			},
+		},
+		Handler:                createDestination,
+		ParamNames:             []string{"network_id"},
+		ParamValues:            []string{"n1"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "domain ID 1.3.6.1.4.1.28458.14 already identifies module version r14",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// Fail to set a malformed delivery function address
+	tc = tests.Test{
+		Method:                 "PUT",
+		URL:                    testURLRoot,
+		Payload:                &models.NetworkProbeNetworkConfig{DeliveryFunctionAddress: "10.10.0.2"},
+		Handler:                updateConfig,
+		ParamNames:             []string{"network_id"},
+		ParamValues:            []string{"n1"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "delivery_function_address 10.10.0.2 is not a valid host:port address",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// Fail to set a domain ID which can't be encoded
+	tc = tests.Test{
+		Method:                 "PUT",
+		URL:                    testURLRoot,
+		Payload:                &models.NetworkProbeNetworkConfig{Hi2DomainID: "1.40.6.1"},
+		Handler:                updateConfig,
+		ParamNames:             []string{"network_id"},
+		ParamValues:            []string{"n1"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "hi2_domain_id 1.40.6.1 is not a valid OID: second arc 40 is not below 40",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:         "DELETE",
+		URL:            testURLRoot,
+		Handler:        deleteConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 204,
+	}
+	tests.RunUnitTest(t, e, tc)
+	_, err = configurator.LoadNetworkConfig("n1", lte.NetworkProbeConfigType, serdes.Network)
+	assert.Error(t, err)
+}
+
+func TestNetworkProbeConfigExportApply(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+	_, err = configurator.CreateEntities(
+		"n1",
+		[]configurator.NetworkEntity{
+			{
+				Key:    "lemf-b",
+				Type:   lte.NetworkProbeDestinationEntityType,
+				Config: &models.NetworkProbeDestinationDetails{DeliveryAddress: "127.0.0.1:4001", DeliveryType: "all"},
 			},
-		}),
+			{
+				Key:    "lemf-a",
+				Type:   lte.NetworkProbeDestinationEntityType,
+				Config: &models.NetworkProbeDestinationDetails{DeliveryAddress: "127.0.0.1:4000", DeliveryType: "all"},
+			},
+		},
+		serdes.Entity,
+	)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
+	exportConfig := tests.GetHandlerByPathAndMethod(t, handlers, "/magma/v1/lte/:network_id/network_probe/config/export", obsidian.GET).HandlerFunc
+	applyConfig := tests.GetHandlerByPathAndMethod(t, handlers, "/magma/v1/lte/:network_id/network_probe/config/apply", obsidian.POST).HandlerFunc
+
+	// destinations are exported sorted by ID
+	exported := &models.NetworkProbeDeclarativeConfig{
+		Destinations: []*models.NetworkProbeDestination{
+			{
+				DestinationID:      "lemf-a",
+				DestinationDetails: &models.NetworkProbeDestinationDetails{DeliveryAddress: "127.0.0.1:4000", DeliveryType: "all"},
+			},
+			{
+				DestinationID:      "lemf-b",
+				DestinationDetails: &models.NetworkProbeDestinationDetails{DeliveryAddress: "127.0.0.1:4001", DeliveryType: "all"},
+			},
+		},
+	}
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            "/magma/v1/lte/n1/network_probe/config/export",
+		Handler:        exportConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: exported,
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	desired := &models.NetworkProbeDeclarativeConfig{
+		NetworkConfig: &models.NetworkProbeNetworkConfig{OperatorID: 49002},
+		Destinations: []*models.NetworkProbeDestination{
+			{
+				DestinationID:      "lemf-b",
+				DestinationDetails: &models.NetworkProbeDestinationDetails{DeliveryAddress: "127.0.0.1:5001", DeliveryType: "all"},
+			},
+			{
+				DestinationID:      "lemf-c",
+				DestinationDetails: &models.NetworkProbeDestinationDetails{DeliveryAddress: "127.0.0.1:4002", DeliveryType: "all"},
+			},
+		},
+	}
+	diff := &models.NetworkProbeConfigDiff{
+		NetworkConfigChanged: true,
+		Created:              []string{"lemf-c"},
+		Updated:              []string{"lemf-b"},
+		Deleted:              []string{"lemf-a"},
+	}
+
+	// a dry run only computes the changes
+	tc = tests.Test{
+		Method:         "POST",
+		URL:            "/magma/v1/lte/n1/network_probe/config/apply?dry_run=true",
+		Payload:        desired,
+		Handler:        applyConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: diff,
+	}
+	tests.RunUnitTest(t, e, tc)
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            "/magma/v1/lte/n1/network_probe/config/export",
+		Handler:        exportConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: exported,
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	diff.Applied = true
+	tc = tests.Test{
+		Method:         "POST",
+		URL:            "/magma/v1/lte/n1/network_probe/config/apply",
+		Payload:        desired,
+		Handler:        applyConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: diff,
+	}
+	tests.RunUnitTest(t, e, tc)
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            "/magma/v1/lte/n1/network_probe/config/export",
+		Handler:        exportConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: desired,
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// the YAML export applies back without any change
+	req := httptest.NewRequest("GET", "/magma/v1/lte/n1/network_probe/config/export?format=yaml", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("network_id")
+	c.SetParamValues("n1")
+	assert.NoError(t, exportConfig(c))
+	assert.Equal(t, "application/yaml", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Body.String(), "delivery_address: 127.0.0.1:5001")
+
+	req = httptest.NewRequest("POST", "/magma/v1/lte/n1/network_probe/config/apply", strings.NewReader(rec.Body.String()))
+	req.Header.Set(echo.HeaderContentType, "application/yaml")
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	c.SetParamNames("network_id")
+	c.SetParamValues("n1")
+	assert.NoError(t, applyConfig(c))
+	actual := &models.NetworkProbeConfigDiff{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), actual))
+	assert.Equal(t, &models.NetworkProbeConfigDiff{Applied: true, Created: []string{}, Updated: []string{}, Deleted: []string{}}, actual)
+
+	// Fail to apply a destination declared twice
+	tc = tests.Test{
+		Method: "POST",
+		URL:    "/magma/v1/lte/n1/network_probe/config/apply",
+		Payload: &models.NetworkProbeDeclarativeConfig{
+			Destinations: []*models.NetworkProbeDestination{desired.Destinations[0], desired.Destinations[0]},
+		},
+		Handler:                applyConfig,
+		ParamNames:             []string{"network_id"},
+		ParamValues:            []string{"n1"},
+		ExpectedStatus:         400,
+		ExpectedErrorSubstring: "destination lemf-b is declared more than once",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// an empty configuration reverts the network to the service config
+	tc = tests.Test{
+		Method:         "POST",
+		URL:            "/magma/v1/lte/n1/network_probe/config/apply",
+		Payload:        &models.NetworkProbeDeclarativeConfig{},
+		Handler:        applyConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: &models.NetworkProbeConfigDiff{
+			Applied:              true,
+			NetworkConfigChanged: true,
+			Created:              []string{},
+			Updated:              []string{},
+			Deleted:              []string{"lemf-b", "lemf-c"},
+		},
+	}
+	tests.RunUnitTest(t, e, tc)
+	_, err = configurator.LoadNetworkConfig("n1", lte.NetworkProbeConfigType, serdes.Network)
+	assert.Error(t, err)
+}
+
+func TestNetworkProbeDebugConfig(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/debug"
+	settings := debug.NewSettings()
+	handlers := handlers.GetDebugHandlers(settings)
+	getDebugConfig := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+	updateDebugConfig := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.PUT).HandlerFunc
+	deleteDebugConfig := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.DELETE).HandlerFunc
+
+	// invalid verbosity
+	tc := tests.Test{
+		Method:         "PUT",
+		URL:            testURLRoot,
+		Handler:        updateDebugConfig,
+		Payload:        &models.NetworkProbeDebugConfig{Verbosity: 11},
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 400,
+		ExpectedError:  "validation failure list:\nverbosity in body should be less than or equal to 10",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	// unknown component
+	tc = tests.Test{
+		Method:         "PUT",
+		URL:            testURLRoot,
+		Handler:        updateDebugConfig,
+		Payload:        &models.NetworkProbeDebugConfig{ComponentVerbosity: map[string]uint32{"kafka": 2}},
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 400,
+		ExpectedError:  "unknown component kafka in component_verbosity, expected one of exporter, handlers, main, manager, servicers",
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	payload := &models.NetworkProbeDebugConfig{
+		Verbosity:  2,
+		TaskIds:    []models.NetworkProbeTaskID{"IMSI1234"},
+		ExpirySecs: 300,
+	}
+	tc = tests.Test{
+		Method:         "PUT",
+		URL:            testURLRoot,
+		Handler:        updateDebugConfig,
+		Payload:        payload,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 204,
+	}
+	tests.RunUnitTest(t, e, tc)
+	assert.True(t, settings.IsTaskEnabled("n1", "IMSI1234"))
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getDebugConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: settings.Get("n1"),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:         "DELETE",
+		URL:            testURLRoot,
+		Handler:        deleteDebugConfig,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 204,
+	}
+	tests.RunUnitTest(t, e, tc)
+	assert.Nil(t, settings.Get("n1"))
+}
+
+func TestGetNetworkProbeTaskRecords(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/tasks/:task_id/records"
+	settings := debug.NewSettings()
+	getRecords := tests.GetHandlerByPathAndMethod(t, handlers.GetDebugHandlers(settings), testURLRoot, obsidian.GET).HandlerFunc
+
+	// no records captured
+	tc := tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot,
+		Handler:        getRecords,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI001010000000001"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler([]json.RawMessage{}),
+	}
+	tests.RunUnitTest(t, e, tc)
+
+	task := &models.NetworkProbeTask{
+		TaskID:      "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{TargetID: "IMSI001010000000001"},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.AttachSuccess,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+	}
+	assert.NoError(t, settings.Set("n1", &models.NetworkProbeDebugConfig{TaskIds: []models.NetworkProbeTaskID{"IMSI001010000000001"}}))
+	var records []json.RawMessage
+	for seq := uint32(1); seq <= 3; seq++ {
+		record, err := encoding.MakeRecord(&event, task, 49002, seq)
+		assert.NoError(t, err)
+		settings.CaptureRecord("n1", "IMSI001010000000001", record)
+		if seq > 1 {
+			var decoded encoding.EpsIRIRecord
+			assert.NoError(t, decoded.Decode(record))
+			// the identity of the target is redacted
+			redact.Record(&decoded)
+			rendered, err := encoding.ToJSON(&decoded)
+			assert.NoError(t, err)
+			assert.NotContains(t, string(rendered), "001010000000001")
+			records = append(records, rendered)
+		}
+	}
+
+	tc.URL = testURLRoot + "?count=2"
+	tc.ExpectedResult = tests.JSONMarshaler(records)
+	tests.RunUnitTest(t, e, tc)
+
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot + "?count=0",
+		Handler:        getRecords,
+		ParamNames:     []string{"network_id", "task_id"},
+		ParamValues:    []string{"n1", "IMSI001010000000001"},
+		ExpectedStatus: 400,
+		ExpectedError:  "invalid count 0, expected 1 to 100",
+	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestRecordStream(t *testing.T) {
+	e := echo.New()
+	stream := debug.NewRecordStream()
+	streamRecords := tests.GetHandlerByPathAndMethod(t, handlers.GetRecordStreamHandlers(stream), handlers.NetworkProbeRecordStreamPath, obsidian.GET).HandlerFunc
+	e.GET(handlers.NetworkProbeRecordStreamPath, streamRecords)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	get := func(query, actor string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+handlers.NetworkProbeRecordStreamPath+query, nil)
+		assert.NoError(t, err)
+		if len(actor) != 0 {
+			req.Header.Set(access.CLIENT_CERT_CN_KEY, actor)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	taskID := "609dcabd-5ab1-4c95-9681-a24681f105ac"
+	resp := get("", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = get("?network_id=n1&task_id="+taskID, "admin")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+	assert.Eventually(t, func() bool { return stream.Watching() == 1 }, time.Second, 10*time.Millisecond)
+
+	task := &models.NetworkProbeTask{
+		TaskID:      models.NetworkProbeTaskID(taskID),
+		TaskDetails: &models.NetworkProbeTaskDetails{TargetID: "IMSI001010000000001"},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.AttachSuccess,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+	}
+	record, err := encoding.MakeRecord(&event, task, 49002, 1)
+	assert.NoError(t, err)
+	exportedAt := time.Unix(1615000000, 0).UTC()
+	stream.Publish("n1", "task2", record, exportedAt)
+	stream.Publish("n1", taskID, record, exportedAt)
+
+	// only the records of the task are streamed, decoded and redacted
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "event: record\n", line)
+	line, err = reader.ReadString('\n')
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "data: "))
+	var streamed struct {
+		NetworkID  string                 `json:"network_id"`
+		TaskID     string                 `json:"task_id"`
+		ExportedAt time.Time              `json:"exported_at"`
+		Record     map[string]interface{} `json:"record"`
 	}
-	tests.RunUnitTest(t, e, tc)
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &streamed))
+	assert.Equal(t, "n1", streamed.NetworkID)
+	assert.Equal(t, taskID, streamed.TaskID)
+	assert.True(t, exportedAt.Equal(streamed.ExportedAt))
+	assert.NotEmpty(t, streamed.Record)
+	assert.NotContains(t, line, "001010000000001")
+
+	// the watcher is closed with the connection
+	resp.Body.Close()
+	assert.Eventually(t, func() bool { return stream.Watching() == 0 }, time.Second, 10*time.Millisecond)
 }
 
-func TestGetNetworkProbeDestination(t *testing.T) {
-	configuratorTestInit.StartTestService(t)
-	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
-	assert.NoError(t, err)
+// runWithActor runs a handler on behalf of the caller identified by actor
+func runWithActor(e *echo.Echo, handler echo.HandlerFunc, method, body, actor string) error {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(access.CLIENT_CERT_CN_KEY, actor)
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("network_id")
+	c.SetParamValues("n1")
+	return handler(c)
+}
 
+func TestKillSwitch(t *testing.T) {
 	e := echo.New()
-	testURLRoot := "/magma/v1/lte/:network_id/network_probe/destinations/:destination_id"
-	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
-	getNetworkProbeDestination := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/kill_switch"
+	store := getNProbeBlobstore(t)
+	killSwitch := killswitch.NewSwitch(store)
+	handlers := handlers.GetKillSwitchHandlers(killSwitch)
+	getKillSwitch := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+	activateKillSwitch := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.POST).HandlerFunc
+	deactivateKillSwitch := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.DELETE).HandlerFunc
+	getAudit := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot+"/audit", obsidian.GET).HandlerFunc
+
+	err := store.StoreNProbeData("n1", "IMSI1234", models.NetworkProbeData{TargetID: "IMSI1234", SequenceNumber: 3})
+	assert.NoError(t, err)
 
+	// callers must be identified by their client certificate
 	tc := tests.Test{
-		Method:         "GET",
+		Method:         "POST",
 		URL:            testURLRoot,
-		Handler:        getNetworkProbeDestination,
-		ParamNames:     []string{"network_id", "destination_id"},
-		ParamValues:    []string{"n1", "1111-2222-3333"},
-		ExpectedStatus: 404,
-		ExpectedError:  "Not Found",
+		Handler:        activateKillSwitch,
+		Payload:        &models.NetworkProbeKillSwitch{Reason: "court order revoked"},
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 403,
+		ExpectedError:  "missing client certificate",
 	}
 	tests.RunUnitTest(t, e, tc)
 
-	_, err = configurator.CreateEntity(
-		"n1",
-		configurator.NetworkEntity{
-			Key:  "1111-2222-3333",
-			Type: lte.NetworkProbeDestinationEntityType,
-			Config: &models.NetworkProbeDestinationDetails{
-				DeliveryAddress: "127.0.0.1:4000",
-				DeliveryType:    "all",
-			},
-		},
-		serdes.Entity,
-	)
+	err = runWithActor(e, activateKillSwitch, "POST", `{}`, "admin")
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+
+	err = runWithActor(e, activateKillSwitch, "POST", `{"reason":"court order revoked"}`, "admin")
 	assert.NoError(t, err)
+	err = runWithActor(e, activateKillSwitch, "POST", `{"reason":"court order revoked"}`, "admin")
+	assert.Equal(t, http.StatusConflict, err.(*echo.HTTPError).Code)
 
+	ks, err := killSwitch.Get("n1")
+	assert.NoError(t, err)
+	assert.True(t, ks.Active)
+	assert.Equal(t, "admin", ks.ActivatedBy)
 	tc = tests.Test{
 		Method:         "GET",
 		URL:            testURLRoot,
-		Handler:        getNetworkProbeDestination,
-		ParamNames:     []string{"network_id", "destination_id"},
-		ParamValues:    []string{"n1", "1111-2222-3333"},
+		Handler:        getKillSwitch,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
 		ExpectedStatus: 200,
-		ExpectedResult: &models.NetworkProbeDestination{
-			DestinationID: "1111-2222-3333",
-			DestinationDetails: &models.NetworkProbeDestinationDetails{
-				DeliveryAddress: "127.0.0.1:4000",
-				DeliveryType:    "all",
-			},
-		},
+		ExpectedResult: ks,
 	}
 	tests.RunUnitTest(t, e, tc)
-}
 
-func TestUpdateNetworkProbeDestination(t *testing.T) {
-	configuratorTestInit.StartTestService(t)
-	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	// tasks are marked to deliver their terminal record
+	state, err := store.GetNProbeData("n1", "IMSI1234")
 	assert.NoError(t, err)
+	assert.True(t, time.Time(ks.ActivatedAt).Equal(time.Time(state.SuspendedAt)))
 
-	e := echo.New()
-	testURLRoot := "/magma/v1/lte/:network_id/network_probe/destinations/:destination_id"
-	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
-	updateNetworkProbeDestination := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.PUT).HandlerFunc
+	err = runWithActor(e, deactivateKillSwitch, "DELETE", "", "admin")
+	assert.NoError(t, err)
+	err = runWithActor(e, deactivateKillSwitch, "DELETE", "", "admin")
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
 
-	// 404
-	payload := &models.NetworkProbeDestination{
-		DestinationID: "1111-2222-3333",
-		DestinationDetails: &models.NetworkProbeDestinationDetails{
-			DeliveryAddress: "127.0.0.1:4000",
-			DeliveryType:    "all",
-		},
+	entries, err := killSwitch.GetAuditEntries("n1")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, models.NetworkProbeAuditEntryActionActivateKillSwitch, entries[0].Action)
+	assert.Equal(t, "court order revoked", entries[0].Reason)
+	assert.Equal(t, models.NetworkProbeAuditEntryActionDeactivateKillSwitch, entries[1].Action)
+	assert.Equal(t, "admin", entries[1].Actor)
+	tc = tests.Test{
+		Method:         "GET",
+		URL:            testURLRoot + "/audit",
+		Handler:        getAudit,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler(entries),
 	}
+	tests.RunUnitTest(t, e, tc)
+}
+
+func TestAuditRequests(t *testing.T) {
+	e := echo.New()
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/kill_switch"
+	store := getNProbeBlobstore(t)
+	killSwitch := killswitch.NewSwitch(store)
+	audit := handlers.AuditRequests(store)
+	killSwitchHandlers := handlers.GetKillSwitchHandlers(killSwitch)
+	activateKillSwitch := tests.GetHandlerByPathAndMethod(t, killSwitchHandlers, testURLRoot, obsidian.POST).HandlerFunc
+	getAudit := tests.GetHandlerByPathAndMethod(t, handlers.GetHandlers(store), "/magma/v1/lte/:network_id/network_probe/audit", obsidian.GET).HandlerFunc
+
+	// requests are audited whatever their outcome, the body is left to the handler
+	req := httptest.NewRequest("POST", "/magma/v1/lte/n1/network_probe/kill_switch", strings.NewReader(`{"reason":"court order revoked"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(access.CLIENT_CERT_CN_KEY, "admin")
+	req.Header.Set(access.CLIENT_CERT_SN_KEY, "7b")
+	req.Header.Set(echo.HeaderXRealIP, "10.0.2.1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("network_id")
+	c.SetParamValues("n1")
+	err := audit(activateKillSwitch)(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	err = audit(activateKillSwitch)(newContext(e, "POST", `{}`))
+	assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+
+	entries, err := store.GetAuditEntries("n1")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, models.NetworkProbeAuditEntryActionAPIRequest, entries[0].Action)
+	assert.Equal(t, "admin", entries[0].Actor)
+	assert.Equal(t, "7b", entries[0].CertificateSerial)
+	assert.Equal(t, "POST", entries[0].Method)
+	assert.Equal(t, "/magma/v1/lte/n1/network_probe/kill_switch", entries[0].Path)
+	assert.Equal(t, "10.0.2.1", entries[0].SourceIP)
+	assert.Equal(t, "cdf4cdcbcb3d2d9e69f77fa54797b8da30428b2dc2234734f2fd39c2cfc12ff6", entries[0].BodyHash)
+	assert.Equal(t, int64(http.StatusCreated), entries[0].StatusCode)
+	assert.Empty(t, entries[0].Error)
+	assert.Equal(t, models.NetworkProbeAuditEntryActionActivateKillSwitch, entries[1].Action)
+	assert.Equal(t, models.NetworkProbeAuditEntryActionAPIRequest, entries[2].Action)
+	assert.Equal(t, "unknown", entries[2].Actor)
+	assert.Equal(t, int64(http.StatusForbidden), entries[2].StatusCode)
+	assert.Equal(t, "missing client certificate", entries[2].Error)
+	assert.NotEqual(t, entries[0].BodyHash, entries[2].BodyHash)
+
+	// the kill switch audit log only lists its own operations
+	ksEntries, err := killSwitch.GetAuditEntries("n1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.NetworkProbeAuditEntry{entries[1]}, ksEntries)
 
 	tc := tests.Test{
-		Method:         "PUT",
-		URL:            testURLRoot,
-		Handler:        updateNetworkProbeDestination,
-		Payload:        payload,
-		ParamNames:     []string{"network_id", "destination_id"},
-		ParamValues:    []string{"n1", "1111-2222-3333"},
-		ExpectedStatus: 500,
-		ExpectedError:  "failed to load entity being updated: expected to load 1 ent for update, got 0",
+		Method:         "GET",
+		URL:            "/magma/v1/lte/n1/network_probe/audit",
+		Handler:        getAudit,
+		ParamNames:     []string{"network_id"},
+		ParamValues:    []string{"n1"},
+		ExpectedStatus: 200,
+		ExpectedResult: tests.JSONMarshaler(entries),
 	}
 	tests.RunUnitTest(t, e, tc)
+}
 
-	// Add the NetworkProbeDestination
-	_, err = configurator.CreateEntity(
-		"n1",
-		configurator.NetworkEntity{
-			Key:  "1111-2222-3333",
-			Type: lte.NetworkProbeDestinationEntityType,
-			Config: &models.NetworkProbeDestinationDetails{
-				DeliveryAddress: "127.0.0.1:4000",
-				DeliveryType:    "all",
-			},
-		},
-		serdes.Entity,
-	)
+// writeCertificate writes a self-signed client certificate and its key
+func writeCertificate(t *testing.T, crtFile, keyFile string, notAfter time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "nprobe"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
 	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	crtPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	assert.NoError(t, ioutil.WriteFile(crtFile, crtPem, 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, keyPem, 0600))
+}
 
-	tc = tests.Test{
-		Method:         "PUT",
-		URL:            testURLRoot,
-		Handler:        updateNetworkProbeDestination,
-		Payload:        payload,
-		ParamNames:     []string{"network_id", "destination_id"},
-		ParamValues:    []string{"n1", "1111-2222-3333"},
-		ExpectedStatus: 204,
+// newContext returns the context of a request made without client certificate
+func newContext(e *echo.Echo, method, body string) echo.Context {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("network_id")
+	c.SetParamValues("n1")
+	return c
+}
+
+func TestProfiling(t *testing.T) {
+	e := echo.New()
+	runtime := debug.NewRuntime()
+	runtime.Register("export_queue", func() (int, int) { return 2, 300 })
+	profilingHandlers := handlers.GetProfilingHandlers(runtime)
+	getRuntimeStats := tests.GetHandlerByPathAndMethod(t, profilingHandlers, handlers.NetworkProbeRuntimePath, obsidian.GET).HandlerFunc
+	getProfile := tests.GetHandlerByPathAndMethod(t, profilingHandlers, handlers.NetworkProbeProfilePath, obsidian.GET).HandlerFunc
+	run := func(handler echo.HandlerFunc, url, profile, actor string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if len(actor) != 0 {
+			req.Header.Set(access.CLIENT_CERT_CN_KEY, actor)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("profile")
+		c.SetParamValues(profile)
+		return rec, handler(c)
 	}
-	tests.RunUnitTest(t, e, tc)
 
-	actual, err := configurator.LoadEntity("n1", lte.NetworkProbeDestinationEntityType, "1111-2222-3333", configurator.FullEntityLoadCriteria(), serdes.Entity)
+	// callers are identified by their client certificate
+	_, err := run(getRuntimeStats, "/", "", "")
+	assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+
+	rec, err := run(getRuntimeStats, "/", "", "admin")
 	assert.NoError(t, err)
-	expected := configurator.NetworkEntity{
-		NetworkID: "n1",
-		Type:      lte.NetworkProbeDestinationEntityType,
-		Key:       "1111-2222-3333",
-		Config:    payload.DestinationDetails,
-		GraphID:   "2",
-		Version:   1,
+	assert.Equal(t, http.StatusOK, rec.Code)
+	stats := &models.NetworkProbeRuntimeStats{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), stats))
+	assert.True(t, stats.Goroutines > 0)
+	assert.Equal(t, []*models.NetworkProbeSubsystemStats{{Name: "export_queue", Items: 2, Bytes: 300}}, stats.Subsystems)
+
+	rec, err = run(getProfile, "/?debug=1", "goroutine", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile")
+
+	_, err = run(getProfile, "/", "unknown", "admin")
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+	_, err = run(getProfile, "/", "heap", "")
+	assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+}
+
+// fakeConnections records the exporters reconnected and drained
+type fakeConnections struct {
+	connections []*models.NetworkProbeConnection
+	reconnected []string
+	drained     []string
+}
+
+func (f *fakeConnections) GetConnections() []*models.NetworkProbeConnection {
+	return f.connections
+}
+
+func (f *fakeConnections) ReconnectExporters(destination string) ([]*models.NetworkProbeConnection, error) {
+	if destination == "unknown:4000" {
+		return nil, exporter.ErrUnknownConnection
 	}
-	assert.Equal(t, expected, actual)
+	f.reconnected = append(f.reconnected, destination)
+	return f.connections, nil
 }
 
-func TestDeleteNetworkProbeDestination(t *testing.T) {
+func (f *fakeConnections) DrainExporters(ctx context.Context, destination string) ([]*models.NetworkProbeConnection, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("drain without deadline")
+	}
+	f.drained = append(f.drained, destination)
+	return nil, errors.Wrap(exporter.ErrDrainTimeout, "failed to drain exporter of 10.0.2.1:4000")
+}
+
+func TestConnections(t *testing.T) {
+	e := echo.New()
+	connections := &fakeConnections{connections: []*models.NetworkProbeConnection{{Destination: "10.0.2.1:4000", Connected: true}}}
+	connectionHandlers := handlers.GetConnectionHandlers(connections)
+	listConnections := tests.GetHandlerByPathAndMethod(t, connectionHandlers, handlers.NetworkProbeConnectionsPath, obsidian.GET).HandlerFunc
+	reconnect := tests.GetHandlerByPathAndMethod(t, connectionHandlers, handlers.NetworkProbeReconnectPath, obsidian.POST).HandlerFunc
+	drain := tests.GetHandlerByPathAndMethod(t, connectionHandlers, handlers.NetworkProbeDrainPath, obsidian.POST).HandlerFunc
+	run := func(handler echo.HandlerFunc, method, url, actor string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(method, url, nil)
+		if len(actor) != 0 {
+			req.Header.Set(access.CLIENT_CERT_CN_KEY, actor)
+		}
+		rec := httptest.NewRecorder()
+		return rec, handler(e.NewContext(req, rec))
+	}
+
+	_, err := run(listConnections, http.MethodGet, "/", "")
+	assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+	rec, err := run(listConnections, http.MethodGet, "/", "admin")
+	assert.NoError(t, err)
+	var listed []*models.NetworkProbeConnection
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	assert.Equal(t, connections.connections, listed)
+
+	// the exporters of a destination, or all of them, are reconnected
+	_, err = run(reconnect, http.MethodPost, "/?destination=10.0.2.1:4000", "admin")
+	assert.NoError(t, err)
+	_, err = run(reconnect, http.MethodPost, "/", "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.2.1:4000", ""}, connections.reconnected)
+	_, err = run(reconnect, http.MethodPost, "/?destination=unknown:4000", "admin")
+	assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+
+	// the drain is bounded by its timeout
+	_, err = run(drain, http.MethodPost, "/?timeout_secs=0", "admin")
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	_, err = run(drain, http.MethodPost, "/?timeout_secs=5", "admin")
+	assert.Equal(t, http.StatusGatewayTimeout, err.(*echo.HTTPError).Code)
+	assert.Equal(t, []string{""}, connections.drained)
+}
+
+// fakeDestinationTester delivers the test records of the destination d1
+type fakeDestinationTester struct {
+	tests []*models.NetworkProbeDestinationTest
+}
+
+func (f *fakeDestinationTester) TestDestination(ctx context.Context, networkID string, test *models.NetworkProbeDestinationTest) (*models.NetworkProbeDestinationTest, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("test without deadline")
+	}
+	if networkID != "n1" || test.DestinationID != "d1" {
+		return nil, merrors.ErrNotFound
+	}
+	test.Delivered, test.Acknowledged, test.RoundTripMs = true, true, 12
+	f.tests = append(f.tests, test)
+	return test, nil
+}
+
+func TestDestinationTest(t *testing.T) {
+	e := echo.New()
+	tester := &fakeDestinationTester{}
+	testDestination := tests.GetHandlerByPathAndMethod(
+		t, handlers.GetDestinationTestHandlers(tester), handlers.NetworkProbeDestinationTestPath, obsidian.POST,
+	).HandlerFunc
+	run := func(url, destinationID, actor string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, url, nil)
+		if len(actor) != 0 {
+			req.Header.Set(access.CLIENT_CERT_CN_KEY, actor)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("network_id", "destination_id")
+		c.SetParamValues("n1", destinationID)
+		return rec, testDestination(c)
+	}
+
+	_, err := run("/", "d1", "")
+	assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+	_, err = run("/?timeout_secs=0", "d1", "admin")
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	_, err = run("/", "d2", "admin")
+	assert.Equal(t, echo.ErrNotFound, err)
+
+	// the test is identified and attributed to the requester
+	rec, err := run("/?timeout_secs=5", "d1", "admin")
+	assert.NoError(t, err)
+	var ret models.NetworkProbeDestinationTest
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ret))
+	assert.Len(t, tester.tests, 1)
+	assert.Equal(t, tester.tests[0].ID, ret.ID)
+	assert.NotEmpty(t, ret.ID)
+	assert.Equal(t, "d1", ret.DestinationID)
+	assert.Equal(t, "admin", ret.RequestedBy)
+	assert.True(t, ret.Acknowledged)
+}
+
+// fakeDeadLetterRetrier delivers the dead letter 1 and has the others
+// rejected again
+type fakeDeadLetterRetrier struct {
+	storage storage.NProbeStorage
+}
+
+func (f *fakeDeadLetterRetrier) RetryDeadLetter(ctx context.Context, networkID, taskID, id string) (*models.NetworkProbeDeadLetter, error) {
+	letter, err := f.storage.GetDeadLetter(networkID, taskID, id)
+	if err != nil {
+		return nil, err
+	}
+	if id != "1" {
+		letter.Retries++
+		return letter, errors.New("connection closed by the LEMF")
+	}
+	letter.DeliveredAt = strfmt.DateTime(time.Now().UTC())
+	return letter, f.storage.DeleteDeadLetter(networkID, taskID, id)
+}
+
+func TestDeadLetters(t *testing.T) {
+	e := echo.New()
+	store := getNProbeBlobstore(t)
+	obsidianHandlers := handlers.GetDeadLetterHandlers(store, &fakeDeadLetterRetrier{storage: store})
+	listDeadLetters := tests.GetHandlerByPathAndMethod(t, obsidianHandlers, handlers.NetworkProbeTaskDeadLettersPath, obsidian.GET).HandlerFunc
+	getDeadLetter := tests.GetHandlerByPathAndMethod(t, obsidianHandlers, handlers.NetworkProbeTaskDeadLetterPath, obsidian.GET).HandlerFunc
+	deleteDeadLetter := tests.GetHandlerByPathAndMethod(t, obsidianHandlers, handlers.NetworkProbeTaskDeadLetterPath, obsidian.DELETE).HandlerFunc
+	retryDeadLetter := tests.GetHandlerByPathAndMethod(t, obsidianHandlers, handlers.NetworkProbeTaskDeadLetterRetryPath, obsidian.POST).HandlerFunc
+	run := func(handler echo.HandlerFunc, method, id, actor string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(method, "/", nil)
+		if len(actor) != 0 {
+			req.Header.Set(access.CLIENT_CERT_CN_KEY, actor)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("network_id", "task_id", "dead_letter_id")
+		c.SetParamValues("n1", "t1", id)
+		return rec, handler(c)
+	}
+
+	for _, seq := range []uint32{2, 1, 3} {
+		assert.NoError(t, store.StoreDeadLetter("n1", "t1", models.NetworkProbeDeadLetter{
+			ID:             fmt.Sprint(seq),
+			SequenceNumber: seq,
+			Record:         []byte{0x30, 0x00},
+			FailureClass:   "remote_nack",
+			Rejections:     3,
+		}))
+	}
+
+	_, err := run(listDeadLetters, http.MethodGet, "", "")
+	assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+
+	// the records are left out of the list
+	rec, err := run(listDeadLetters, http.MethodGet, "", "admin")
+	assert.NoError(t, err)
+	var letters []models.NetworkProbeDeadLetter
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &letters))
+	assert.Len(t, letters, 3)
+	for i, letter := range letters {
+		assert.Equal(t, uint32(i+1), letter.SequenceNumber)
+		assert.Empty(t, letter.Record)
+	}
+
+	// a dead letter is inspected decoded
+	_, err = run(getDeadLetter, http.MethodGet, "4", "admin")
+	assert.Equal(t, echo.ErrNotFound, err)
+	rec, err = run(getDeadLetter, http.MethodGet, "2", "admin")
+	assert.NoError(t, err)
+	var letter models.NetworkProbeDeadLetter
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &letter))
+	assert.Equal(t, strfmt.Base64{0x30, 0x00}, letter.Record)
+	assert.NotNil(t, letter.Decoded)
+
+	// delivered dead letters are removed, the ones rejected again are kept
+	_, err = run(retryDeadLetter, http.MethodPost, "4", "admin")
+	assert.Equal(t, echo.ErrNotFound, err)
+	_, err = run(retryDeadLetter, http.MethodPost, "2", "admin")
+	assert.Equal(t, http.StatusBadGateway, err.(*echo.HTTPError).Code)
+	rec, err = run(retryDeadLetter, http.MethodPost, "1", "admin")
+	assert.NoError(t, err)
+	letter = models.NetworkProbeDeadLetter{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &letter))
+	assert.False(t, time.Time(letter.DeliveredAt).IsZero())
+
+	_, err = run(deleteDeadLetter, http.MethodDelete, "3", "admin")
+	assert.NoError(t, err)
+	remaining, err := store.GetDeadLetters("n1", "t1")
+	assert.NoError(t, err)
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "2", remaining[0].ID)
+}
+
+func TestSnapshot(t *testing.T) {
 	configuratorTestInit.StartTestService(t)
-	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	config := &models.NetworkProbeNetworkConfig{MaxTasks: 2}
+	err := configurator.CreateNetwork(configurator.Network{
+		ID:      "n1",
+		Configs: map[string]interface{}{lte.NetworkProbeConfigType: config},
+	}, serdes.Network)
 	assert.NoError(t, err)
+	for _, networkID := range []string{"n2", "n3"} {
+		assert.NoError(t, configurator.CreateNetwork(configurator.Network{ID: networkID}, serdes.Network))
+	}
 
 	e := echo.New()
-	testURLRoot := "/magma/v1/lte/:network_id/network_probe/destinations/:destination_id"
-	handlers := handlers.GetHandlers(getNProbeBlobstore(t))
-	deleteNetworkProbeDestination := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.DELETE).HandlerFunc
+	testURLRoot := "/magma/v1/lte/:network_id/network_probe/snapshot"
+	store := getNProbeBlobstore(t)
+	handlers := handlers.GetSnapshotHandlers(store, make([]byte, snapshot.KeySize))
+	takeSnapshot := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.GET).HandlerFunc
+	restoreSnapshot := tests.GetHandlerByPathAndMethod(t, handlers, testURLRoot, obsidian.POST).HandlerFunc
+	runOnNetwork := func(handler echo.HandlerFunc, method, networkID string, body []byte) ([]byte, error) {
+		req := httptest.NewRequest(method, "/", bytes.NewReader(body))
+		req.Header.Set(access.CLIENT_CERT_CN_KEY, "admin")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("network_id")
+		c.SetParamValues(networkID)
+		err := handler(c)
+		return rec.Body.Bytes(), err
+	}
 
-	_, err = configurator.CreateEntities(
-		"n1",
-		[]configurator.NetworkEntity{
-			{
-				Key:  "1111-2222-3333",
-				Type: lte.NetworkProbeDestinationEntityType,
-				Config: &models.NetworkProbeDestinationDetails{
-					DeliveryAddress: "127.0.0.1:4000",
-					DeliveryType:    "events_only",
-				},
-			},
-			{
-				Key:  "2222-3333-4444",
-				Type: lte.NetworkProbeDestinationEntityType,
-				Config: &models.NetworkProbeDestinationDetails{
-					DeliveryAddress: "127.0.0.1:4001",
-					DeliveryType:    "all",
-				},
-			},
+	taskID := "IMSI001010000000001"
+	task := &models.NetworkProbeTask{
+		TaskID: models.NetworkProbeTaskID(taskID),
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:     taskID,
+			TargetType:   "imsi",
+			DeliveryType: "all",
 		},
-		serdes.Entity,
-	)
+	}
+	assert.NoError(t, tasks.Create(store, "n1", task, "admin"))
+	assert.NoError(t, store.StoreNProbeData("n1", taskID, models.NetworkProbeData{TargetID: taskID, SequenceNumber: 42}))
+	assert.NoError(t, store.StoreTaskPause("n1", taskID, models.NetworkProbeTaskPause{Paused: true, PausedBy: "admin"}))
+	assert.NoError(t, store.StoreBookmark("n1", taskID, models.NetworkProbeBookmark{Name: "handover", SequenceNumber: 40}))
+	assert.NoError(t, store.StoreDeadLetter("n1", taskID, models.NetworkProbeDeadLetter{ID: "41", SequenceNumber: 41, Record: []byte{0x30, 0x00}}))
+	assert.NoError(t, store.StoreTaskDeletion("n1", taskID, models.NetworkProbeTaskDeletion{RequestedAt: strfmt.DateTime(time.Unix(1613625206, 0).UTC()), RequestedBy: "admin"}))
+	assert.NoError(t, store.IncrementTaskStatistics("n1", taskID, 3, 300, time.Unix(1613625206, 0)))
+	assert.NoError(t, store.IncrementActivity("n1", taskID, map[time.Time]uint64{time.Now(): 3}))
+	assert.NoError(t, store.StoreQuarantineEntry("n1", taskID, models.NetworkProbeQuarantineEntry{EventTimestamp: "1613625206", EventType: "attach", Field: "timestamp", Error: "invalid"}))
+	_, err = store.AllocateCorrelationID("n1", taskID, "5", time.Unix(1613625206, 0))
 	assert.NoError(t, err)
+	assert.NoError(t, store.StoreKillSwitch("n1", models.NetworkProbeKillSwitch{Active: true, ActivatedBy: "admin", Reason: "court order"}))
 
-	tc := tests.Test{
-		Method:         "DELETE",
-		URL:            testURLRoot,
-		Handler:        deleteNetworkProbeDestination,
-		ParamNames:     []string{"network_id", "destination_id"},
-		ParamValues:    []string{"n1", "1111-2222-3333"},
-		ExpectedStatus: 204,
-	}
-	tests.RunUnitTest(t, e, tc)
+	// the network config, kill switch, tasks and their state are restored to
+	// another network, the restore of the tasks being audited
+	archive, err := runOnNetwork(takeSnapshot, "GET", "n1", nil)
+	assert.NoError(t, err)
+	_, err = runOnNetwork(restoreSnapshot, "POST", "n2", archive)
+	assert.NoError(t, err)
 
-	actual, _, err := configurator.LoadAllEntitiesOfType("n1", lte.NetworkProbeDestinationEntityType, configurator.FullEntityLoadCriteria(), serdes.Entity)
+	restoredConfig, err := configurator.LoadNetworkConfig("n2", lte.NetworkProbeConfigType, serdes.Network)
 	assert.NoError(t, err)
-	assert.Equal(t, 1, len(actual))
-	expected := configurator.NetworkEntity{
-		NetworkID: "n1",
-		Type:      lte.NetworkProbeDestinationEntityType,
-		Key:       "2222-3333-4444",
-		Config: &models.NetworkProbeDestinationDetails{
-			DeliveryAddress: "127.0.0.1:4001",
-			DeliveryType:    "all",
+	assert.Equal(t, config, restoredConfig)
+	for _, get := range []func(networkID string) (interface{}, error){
+		func(networkID string) (interface{}, error) {
+			ent, err := configurator.LoadEntity(networkID, lte.NetworkProbeTaskEntityType, taskID, configurator.EntityLoadCriteria{LoadConfig: true}, serdes.Entity)
+			return ent.Config, err
 		},
-		GraphID: "4",
-		Version: 0,
+		func(networkID string) (interface{}, error) { return store.GetKillSwitch(networkID) },
+		func(networkID string) (interface{}, error) { return store.GetNProbeData(networkID, taskID) },
+		func(networkID string) (interface{}, error) { return store.GetTaskPause(networkID, taskID) },
+		func(networkID string) (interface{}, error) { return store.GetBookmarks(networkID, taskID) },
+		func(networkID string) (interface{}, error) { return store.GetDeadLetters(networkID, taskID) },
+		func(networkID string) (interface{}, error) { return store.GetTaskDeletion(networkID, taskID) },
+		func(networkID string) (interface{}, error) { return store.GetTaskStatistics(networkID, taskID) },
+		func(networkID string) (interface{}, error) { return store.GetActivity(networkID, taskID) },
+		func(networkID string) (interface{}, error) { return store.GetQuarantineEntries(networkID, taskID) },
+		func(networkID string) (interface{}, error) { return store.GetBearerCorrelations(networkID, 0, "") },
+	} {
+		expected, err := get("n1")
+		assert.NoError(t, err)
+		actual, err := get("n2")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
 	}
-	assert.Equal(t, expected, actual[0])
+	entries, err := store.GetAuditEntries("n2")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, models.NetworkProbeAuditEntryActionRestoreTask, entries[0].Action)
+	assert.Equal(t, "admin", entries[0].Actor)
+	assert.Equal(t, "task IMSI001010000000001 restored from a snapshot", entries[0].Reason)
+
+	// tasks targeting a protected identity of the network aren't restored,
+	// the attempt being audited
+	config.ProtectedIdentities = []*models.NetworkProbeProtectedIdentity{{IdentityType: "imsi", Identity: taskID}}
+	assert.NoError(t, configurator.UpdateNetworkConfig("n1", lte.NetworkProbeConfigType, config, serdes.Network))
+	archive, err = runOnNetwork(takeSnapshot, "GET", "n1", nil)
+	assert.NoError(t, err)
+	_, err = runOnNetwork(restoreSnapshot, "POST", "n3", archive)
+	assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+	entries, err = store.GetAuditEntries("n3")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, models.NetworkProbeAuditEntryActionRejectProtectedTarget, entries[0].Action)
+	exists, err := configurator.DoesEntityExist("n3", lte.NetworkProbeTaskEntityType, taskID)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	// nothing of the snapshot is written once a task is rejected
+	_, err = configurator.LoadNetworkConfig("n3", lte.NetworkProbeConfigType, serdes.Network)
+	assert.Equal(t, merrors.ErrNotFound, err)
+	killSwitch, err := store.GetKillSwitch("n3")
+	assert.NoError(t, err)
+	assert.False(t, killSwitch.Active)
+
+	// tasks beyond the task quota of the network aren't restored
+	config.ProtectedIdentities = nil
+	config.MaxTasks = 1
+	assert.NoError(t, configurator.UpdateNetworkConfig("n1", lte.NetworkProbeConfigType, config, serdes.Network))
+	task.TaskID, task.TaskDetails.TargetID = "IMSI001010000000002", "IMSI001010000000002"
+	_, err = configurator.CreateEntity("n1", configurator.NetworkEntity{
+		Type:   lte.NetworkProbeTaskEntityType,
+		Key:    string(task.TaskID),
+		Config: task.TaskDetails,
+	}, serdes.Entity)
+	assert.NoError(t, err)
+	archive, err = runOnNetwork(takeSnapshot, "GET", "n1", nil)
+	assert.NoError(t, err)
+	_, err = runOnNetwork(restoreSnapshot, "POST", "n3", archive)
+	assert.Equal(t, http.StatusTooManyRequests, err.(*echo.HTTPError).Code)
 }
@@ -0,0 +1,129 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	rpprof "runtime/pprof"
+
+	"magma/lte/cloud/go/services/nprobe/debug"
+	"magma/lte/cloud/go/services/nprobe/latency"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"magma/orc8r/cloud/go/obsidian"
+
+	"github.com/labstack/echo"
+)
+
+// The admin handlers are served outside of the networks: they cover the
+// whole process, so the access control of obsidian restricts them to the
+// administrators, i.e. the callers granted all networks.
+const (
+	NetworkProbeAdminPath   = obsidian.V1Root + "network_probe" + obsidian.UrlSep + "admin"
+	NetworkProbeRuntimePath = NetworkProbeAdminPath + obsidian.UrlSep + "runtime"
+	NetworkProbeProfilePath = NetworkProbeAdminPath + obsidian.UrlSep + "pprof" + obsidian.UrlSep + ":profile"
+	NetworkProbeStatsPath   = NetworkProbeAdminPath + obsidian.UrlSep + "stats"
+	NetworkProbePassesPath  = NetworkProbeAdminPath + obsidian.UrlSep + "passes"
+)
+
+const (
+	// cpuProfile is the profile recording the CPU for the seconds requested
+	cpuProfile = "profile"
+	// traceProfile is the profile recording an execution trace for the
+	// seconds requested
+	traceProfile = "trace"
+)
+
+// GetProfilingHandlers returns the admin handlers reporting the runtime
+// statistics of the service and serving its pprof profiles, so that the
+// memory growth of a long running service can be diagnosed in production.
+func GetProfilingHandlers(runtime *debug.Runtime) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeRuntimePath, Methods: obsidian.GET, HandlerFunc: getRuntimeStatsHandlerFunc(runtime)},
+		{Path: NetworkProbeProfilePath, Methods: obsidian.GET, HandlerFunc: getProfile},
+	}
+}
+
+// GetLatencyHandlers returns the admin handlers reporting the latency of the
+// records delivered to each destination
+func GetLatencyHandlers(tracker *latency.Tracker) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeStatsPath, Methods: obsidian.GET, HandlerFunc: getLatencyStatsHandlerFunc(tracker)},
+	}
+}
+
+func getLatencyStatsHandlerFunc(tracker *latency.Tracker) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, nerr := getActor(c); nerr != nil {
+			return nerr
+		}
+		return c.JSON(http.StatusOK, tracker.Get())
+	}
+}
+
+// PassReports returns the reports of the last processing passes, newest first
+type PassReports func() []*models.NetworkProbePassReport
+
+// GetPassReportHandlers returns the admin handlers reporting the outcome of
+// the last processing passes
+func GetPassReportHandlers(reports PassReports) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbePassesPath, Methods: obsidian.GET, HandlerFunc: getPassReportsHandlerFunc(reports)},
+	}
+}
+
+func getPassReportsHandlerFunc(reports PassReports) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, nerr := getActor(c); nerr != nil {
+			return nerr
+		}
+		return c.JSON(http.StatusOK, reports())
+	}
+}
+
+func getRuntimeStatsHandlerFunc(runtime *debug.Runtime) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, nerr := getActor(c); nerr != nil {
+			return nerr
+		}
+		return c.JSON(http.StatusOK, runtime.Get())
+	}
+}
+
+// getProfile serves a profile as net/http/pprof does, along with its query
+// parameters, e.g. seconds, debug or gc
+func getProfile(c echo.Context) error {
+	actor, nerr := getActor(c)
+	if nerr != nil {
+		return nerr
+	}
+
+	var handler http.Handler
+	switch name := c.Param("profile"); name {
+	case cpuProfile:
+		handler = http.HandlerFunc(pprof.Profile)
+	case traceProfile:
+		handler = http.HandlerFunc(pprof.Trace)
+	default:
+		if rpprof.Lookup(name) == nil {
+			return obsidian.HttpError(fmt.Errorf("unknown profile %s", name), http.StatusNotFound)
+		}
+		handler = pprof.Handler(name)
+	}
+	logger.Infof("Serving %s profile to %s", c.Param("profile"), actor)
+	handler.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
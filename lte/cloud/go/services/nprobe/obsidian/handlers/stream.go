@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/debug"
+
+	"magma/orc8r/cloud/go/obsidian"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	NetworkProbeRecordStreamPath = NetworkProbeAdminPath + obsidian.UrlSep + "records" + obsidian.UrlSep + "stream"
+
+	// streamKeepalive is the interval of the comments keeping an idle stream
+	// open through the proxies
+	streamKeepalive = 15 * time.Second
+)
+
+// streamedRecord is the data of the record events of the stream
+type streamedRecord struct {
+	NetworkID  string          `json:"network_id"`
+	TaskID     string          `json:"task_id"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Record     json.RawMessage `json:"record"`
+}
+
+// GetRecordStreamHandlers returns the admin handlers streaming the exported
+// records live, decoded, so that engineers validating a LEMF integration can
+// watch the flow. They are only served when the record stream is enabled.
+func GetRecordStreamHandlers(stream *debug.RecordStream) []obsidian.Handler {
+	return []obsidian.Handler{
+		{Path: NetworkProbeRecordStreamPath, Methods: obsidian.GET, HandlerFunc: getRecordStreamHandlerFunc(stream)},
+	}
+}
+
+// getRecordStreamHandlerFunc streams the records exported for a network, or
+// for all networks, optionally only those of a task, as server-sent events
+// until the client disconnects. Records are decoded and their identities
+// redacted as by the debug capture. The records dropped for a slow client
+// are reported in dropped events.
+func getRecordStreamHandlerFunc(stream *debug.RecordStream) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		actor, nerr := getActor(c)
+		if nerr != nil {
+			return nerr
+		}
+		watcher, err := stream.Watch(c.QueryParam("network_id"), c.QueryParam("task_id"))
+		if err == debug.ErrTooManyWatchers {
+			return obsidian.HttpError(err, http.StatusTooManyRequests)
+		}
+		if err != nil {
+			return obsidian.HttpError(err, http.StatusInternalServerError)
+		}
+		defer watcher.Close()
+		logger.Infof("Streaming exported records to %s", actor)
+
+		resp := c.Response()
+		resp.Header().Set(echo.HeaderContentType, "text/event-stream")
+		resp.Header().Set("Cache-Control", "no-cache")
+		resp.WriteHeader(http.StatusOK)
+		resp.Flush()
+
+		keepalive := time.NewTicker(streamKeepalive)
+		defer keepalive.Stop()
+		var dropped uint64
+		for {
+			select {
+			case <-c.Request().Context().Done():
+				logger.Infof("Stopped streaming exported records to %s", actor)
+				return nil
+			case <-keepalive.C:
+				_, err = fmt.Fprint(resp, ": keepalive\n\n")
+			case record := <-watcher.Records():
+				if n := watcher.Dropped(); n != dropped {
+					dropped = n
+					_, err = fmt.Fprintf(resp, "event: dropped\ndata: {\"dropped\":%d}\n\n", dropped)
+					if err != nil {
+						return nil
+					}
+				}
+				data, _ := json.Marshal(streamedRecord{
+					NetworkID:  record.NetworkID,
+					TaskID:     record.TaskID,
+					ExportedAt: record.ExportedAt.UTC(),
+					Record:     renderRecord(c.Request().Context(), record.Record),
+				})
+				_, err = fmt.Fprintf(resp, "event: record\ndata: %s\n\n", data)
+			}
+			if err != nil {
+				// the client is gone
+				return nil
+			}
+			resp.Flush()
+		}
+	}
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2020 The Magma Authors.
+ *
+ * This source code is licensed under the BSD-style license found in the
+ * LICENSE file in the root directory of this source tree.
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/tasks"
+
+	"magma/orc8r/cloud/go/obsidian"
+	"magma/orc8r/cloud/go/services/configurator"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	// certificateExpiryWarning is the remaining validity of the exporter
+	// certificate under which validated tasks get a warning
+	certificateExpiryWarning = 30 * 24 * time.Hour
+	// deliveryProbeTimeout bounds the handshake with the delivery function
+	deliveryProbeTimeout = 5 * time.Second
+)
+
+// diagnosticSeverity orders the statuses of the diagnostics
+var diagnosticSeverity = map[string]int{
+	models.NetworkProbeTaskDiagnosticStatusOk:      0,
+	models.NetworkProbeTaskDiagnosticStatusWarning: 1,
+	models.NetworkProbeTaskDiagnosticStatusError:   2,
+}
+
+// getValidateNetworkProbeTaskHandlerFunc runs the checks of task creation
+// and those of the delivery of its records without provisioning the task.
+// Failed checks are reported as diagnostics rather than request errors.
+func getValidateNetworkProbeTaskHandlerFunc(certs *exporter.CertificateStore, exp *exporter.RecordExporter) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		networkID, nerr := obsidian.GetNetworkId(c)
+		if nerr != nil {
+			return nerr
+		}
+
+		payload := &models.NetworkProbeTask{}
+		if err := c.Bind(payload); err != nil {
+			return obsidian.HttpError(err, http.StatusBadRequest)
+		}
+
+		diagnostics := []*models.NetworkProbeTaskDiagnostic{
+			validateTarget(networkID, payload),
+			validateTaskID(networkID, payload),
+			validateCertificate(certs.GetInfo(), time.Now()),
+			validateDelivery(exp),
+		}
+		ret := &models.NetworkProbeTaskValidation{
+			Status:      models.NetworkProbeTaskValidationStatusOk,
+			Diagnostics: diagnostics,
+		}
+		for _, diagnostic := range diagnostics {
+			if diagnosticSeverity[diagnostic.Status] > diagnosticSeverity[ret.Status] {
+				ret.Status = diagnostic.Status
+			}
+		}
+		return c.JSON(http.StatusOK, ret)
+	}
+}
+
+// validateTarget checks the task like its creation does, including the
+// format of the target identifier and the destination it is delivered to
+func validateTarget(networkID string, task *models.NetworkProbeTask) *models.NetworkProbeTaskDiagnostic {
+	if err := task.ValidateModel(); err != nil {
+		return newDiagnostic(models.NetworkProbeTaskDiagnosticCheckTarget, models.NetworkProbeTaskDiagnosticStatusError, err.Error())
+	}
+	if err := tasks.CheckDestination(networkID, task.TaskDetails); err != nil {
+		message := fmt.Sprintf("destination %s: %v", task.TaskDetails.DestinationID, err)
+		return newDiagnostic(models.NetworkProbeTaskDiagnosticCheckTarget, models.NetworkProbeTaskDiagnosticStatusError, message)
+	}
+	if err := tasks.CheckTestPLMN(networkID, task.TaskDetails); err != nil {
+		return newDiagnostic(models.NetworkProbeTaskDiagnosticCheckTarget, models.NetworkProbeTaskDiagnosticStatusError, err.Error())
+	}
+	if err := tasks.CheckTaskDomainID(networkID, task.TaskDetails); err != nil {
+		return newDiagnostic(models.NetworkProbeTaskDiagnosticCheckTarget, models.NetworkProbeTaskDiagnosticStatusError, err.Error())
+	}
+	// the dry run isn't audited, unlike the creation of the task
+	protected, err := tasks.GetProtectedTarget(networkID, task.TaskDetails)
+	if err != nil {
+		return newDiagnostic(models.NetworkProbeTaskDiagnosticCheckTarget, models.NetworkProbeTaskDiagnosticStatusError, err.Error())
+	}
+	if protected != nil {
+		return newDiagnostic(models.NetworkProbeTaskDiagnosticCheckTarget, models.NetworkProbeTaskDiagnosticStatusError, tasks.ErrProtectedTarget.Error())
+	}
+	details := task.TaskDetails
+	message := fmt.Sprintf("%s target %s is valid", details.TargetType, details.TargetID)
+	return newDiagnostic(models.NetworkProbeTaskDiagnosticCheckTarget, models.NetworkProbeTaskDiagnosticStatusOk, message)
+}
+
+// validateTaskID checks that no task of the network has the ID already
+func validateTaskID(networkID string, task *models.NetworkProbeTask) *models.NetworkProbeTaskDiagnostic {
+	check := models.NetworkProbeTaskDiagnosticCheckTaskID
+	if len(task.TaskID) == 0 {
+		return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusError, "task_id is required")
+	}
+	exists, err := configurator.DoesEntityExist(networkID, lte.NetworkProbeTaskEntityType, string(task.TaskID))
+	switch {
+	case err != nil:
+		return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusError, fmt.Sprintf("failed to look up task %s: %v", task.TaskID, err))
+	case exists:
+		return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusError, fmt.Sprintf("task %s already exists", task.TaskID))
+	}
+	return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusOk, fmt.Sprintf("task %s is not provisioned", task.TaskID))
+}
+
+// validateCertificate checks that the exporter client certificate is valid
+// and warns when it expires soon
+func validateCertificate(info exporter.CertificateInfo, now time.Time) *models.NetworkProbeTaskDiagnostic {
+	check := models.NetworkProbeTaskDiagnosticCheckCertificate
+	switch remaining := info.NotAfter.Sub(now); {
+	case now.Before(info.NotBefore):
+		message := fmt.Sprintf("exporter certificate %s is not valid before %s", info.Subject, info.NotBefore.UTC().Format(time.RFC3339))
+		return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusError, message)
+	case remaining <= 0:
+		message := fmt.Sprintf("exporter certificate %s expired at %s", info.Subject, info.NotAfter.UTC().Format(time.RFC3339))
+		return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusError, message)
+	case remaining < certificateExpiryWarning:
+		message := fmt.Sprintf("exporter certificate %s expires in %d days", info.Subject, int(remaining.Hours()/24))
+		return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusWarning, message)
+	}
+	message := fmt.Sprintf("exporter certificate %s is valid until %s", info.Subject, info.NotAfter.UTC().Format(time.RFC3339))
+	return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusOk, message)
+}
+
+// validateDelivery checks that the delivery function completes a handshake
+// with the exporter, backends not delivering over TLS are not probed
+func validateDelivery(exp *exporter.RecordExporter) *models.NetworkProbeTaskDiagnostic {
+	check := models.NetworkProbeTaskDiagnosticCheckDelivery
+	info, err := exp.Probe(deliveryProbeTimeout)
+	switch {
+	case err == exporter.ErrProbeUnsupported:
+		return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusWarning, "delivery not probed: "+err.Error())
+	case err != nil:
+		return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusError, fmt.Sprintf("delivery function unreachable: %v", err))
+	}
+	message := fmt.Sprintf("handshake completed with %s over %s", info.PeerSubject, info.Version)
+	return newDiagnostic(check, models.NetworkProbeTaskDiagnosticStatusOk, message)
+}
+
+func newDiagnostic(check, status, message string) *models.NetworkProbeTaskDiagnostic {
+	return &models.NetworkProbeTaskDiagnostic{Check: check, Status: status, Message: message}
+}
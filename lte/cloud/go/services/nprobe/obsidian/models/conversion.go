@@ -14,9 +14,18 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
 	"magma/lte/cloud/go/lte"
 	lte_mconfig "magma/lte/cloud/go/protos/mconfig"
+	nprobe_protos "magma/lte/cloud/go/services/nprobe/protos"
 	"magma/orc8r/cloud/go/services/configurator"
+	orc8rModels "magma/orc8r/cloud/go/services/orchestrator/obsidian/models"
+
+	"github.com/go-openapi/strfmt"
 )
 
 func (m *NetworkProbeTask) ToEntityUpdateCriteria() configurator.EntityUpdateCriteria {
@@ -33,6 +42,22 @@ func (m *NetworkProbeTask) FromBackendModels(ent configurator.NetworkEntity) *Ne
 	return m
 }
 
+// ToTasks returns the tasks of the targets of a bulk request, in order, their
+// details copied from the template. The nested settings of the template are
+// shared by the tasks rather than copied.
+func (m *NetworkProbeTaskBulkRequest) ToTasks() []*NetworkProbeTask {
+	ret := make([]*NetworkProbeTask, 0, len(m.Targets))
+	for _, target := range m.Targets {
+		details := *m.Template
+		details.TargetID = target.TargetID
+		if len(target.TargetType) != 0 {
+			details.TargetType = target.TargetType
+		}
+		ret = append(ret, &NetworkProbeTask{TaskID: target.TaskID, TaskDetails: &details})
+	}
+	return ret
+}
+
 func (m *NetworkProbeDestination) ToEntityUpdateCriteria() configurator.EntityUpdateCriteria {
 	return configurator.EntityUpdateCriteria{
 		Type:      lte.NetworkProbeDestinationEntityType,
@@ -47,6 +72,64 @@ func (m *NetworkProbeDestination) FromBackendModels(ent configurator.NetworkEnti
 	return m
 }
 
+// RedactPayloadEncryptionKey replaces the payload encryption key of a
+// destination by its key ID, the keys never being returned
+func (m *NetworkProbeDestinationDetails) RedactPayloadEncryptionKey() {
+	if m.PayloadEncryption == nil || len(m.PayloadEncryption.Key) == 0 {
+		return
+	}
+	redacted := *m.PayloadEncryption
+	redacted.KeyID = GetPayloadEncryptionKeyID(redacted.Key)
+	redacted.Key = ""
+	m.PayloadEncryption = &redacted
+}
+
+// KeepPayloadEncryptionKey keeps the payload encryption key of the current
+// details of a destination, nil if it doesn't exist yet, when the key is left
+// out, e.g. when redacted details are sent back. The key ID, when set, must
+// be the one of the current key.
+func (m *NetworkProbeDestinationDetails) KeepPayloadEncryptionKey(current *NetworkProbeDestinationDetails) error {
+	encryption := m.PayloadEncryption
+	if encryption == nil {
+		return nil
+	}
+	if len(encryption.Key) == 0 {
+		if current == nil || current.PayloadEncryption == nil || len(current.PayloadEncryption.Key) == 0 {
+			return fmt.Errorf("payload_encryption requires a key")
+		}
+		if len(encryption.KeyID) != 0 && encryption.KeyID != GetPayloadEncryptionKeyID(current.PayloadEncryption.Key) {
+			return fmt.Errorf("payload_encryption key_id %s is not the one of the current key", encryption.KeyID)
+		}
+		encryption.Key = current.PayloadEncryption.Key
+	}
+	// the key ID is never stored
+	encryption.KeyID = ""
+	return nil
+}
+
+// GetPayloadEncryptionKeyID returns the ID of a hex encoded payload
+// encryption key, the first 8 bytes of its SHA-256
+func GetPayloadEncryptionKeyID(key string) string {
+	decoded, err := hex.DecodeString(key)
+	if err != nil {
+		decoded = []byte(key)
+	}
+	sum := sha256.Sum256(decoded)
+	return hex.EncodeToString(sum[:8])
+}
+
+func (m *NetworkProbeNetworkConfig) GetFromNetwork(network configurator.Network) interface{} {
+	iConfig := orc8rModels.GetNetworkConfig(network, lte.NetworkProbeConfigType)
+	if iConfig == nil {
+		return nil
+	}
+	return iConfig.(*NetworkProbeNetworkConfig)
+}
+
+func (m *NetworkProbeNetworkConfig) ToUpdateCriteria(network configurator.Network) (configurator.NetworkUpdateCriteria, error) {
+	return orc8rModels.GetNetworkConfigUpdateCriteria(network.ID, lte.NetworkProbeConfigType, m), nil
+}
+
 func ToMConfigNProbeTask(task *NetworkProbeTask) *lte_mconfig.NProbeTask {
 	return &lte_mconfig.NProbeTask{
 		TaskId:        string(task.TaskID),
@@ -57,3 +140,311 @@ func ToMConfigNProbeTask(task *NetworkProbeTask) *lte_mconfig.NProbeTask {
 		CorrelationId: task.TaskDetails.CorrelationID,
 	}
 }
+
+// ToProtoNProbeTask returns the typed model of a task served over gRPC
+func ToProtoNProbeTask(task *NetworkProbeTask) *nprobe_protos.Task {
+	details := task.TaskDetails
+	ret := &nprobe_protos.Task{
+		TaskId:                       string(task.TaskID),
+		TargetId:                     details.TargetID,
+		TargetType:                   details.TargetType,
+		DeliveryType:                 details.DeliveryType,
+		CorrelationId:                details.CorrelationID,
+		Timestamp:                    formatDateTime(details.Timestamp),
+		OneShot:                      details.OneShot,
+		DomainId:                     details.DomainID,
+		DeliveryCountryCode:          details.DeliveryCountryCode,
+		AuthorizationReference:       details.AuthorizationReference,
+		MinRecordsPerHour:            details.MinRecordsPerHour,
+		MaxRecordsPerHour:            details.MaxRecordsPerHour,
+		UsageReportIntervalSecs:      details.UsageReportIntervalSecs,
+		BearerFilters:                ToProtoBearerFilters(details.BearerFilters),
+		IriDomain:                    details.IriDomain,
+		RecordFilter:                 toProtoRecordFilter(details.RecordFilter),
+		WarrantType:                  details.WarrantType,
+		StartTime:                    formatDateTime(details.StartTime),
+		EndTime:                      formatDateTime(details.EndTime),
+		Roaming:                      details.Roaming,
+		StatisticsReportIntervalSecs: details.StatisticsReportIntervalSecs,
+	}
+	if details.Duration != nil {
+		ret.Duration = *details.Duration
+	}
+	return ret
+}
+
+// FromProtoNProbeTask returns the task of a typed model received over gRPC,
+// failing if the times of its warrant are not in RFC3339 format. Its
+// timestamp is left to be set when the task is provisioned.
+func FromProtoNProbeTask(task *nprobe_protos.Task) (*NetworkProbeTask, error) {
+	startTime, err := parseDateTime("start_time", task.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	endTime, err := parseDateTime("end_time", task.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	ret := &NetworkProbeTask{
+		TaskID: NetworkProbeTaskID(task.TaskId),
+		TaskDetails: &NetworkProbeTaskDetails{
+			TargetID:                     task.TargetId,
+			TargetType:                   task.TargetType,
+			DeliveryType:                 task.DeliveryType,
+			CorrelationID:                task.CorrelationId,
+			OneShot:                      task.OneShot,
+			DomainID:                     task.DomainId,
+			DeliveryCountryCode:          task.DeliveryCountryCode,
+			AuthorizationReference:       task.AuthorizationReference,
+			MinRecordsPerHour:            task.MinRecordsPerHour,
+			MaxRecordsPerHour:            task.MaxRecordsPerHour,
+			UsageReportIntervalSecs:      task.UsageReportIntervalSecs,
+			IriDomain:                    task.IriDomain,
+			RecordFilter:                 fromProtoRecordFilter(task.RecordFilter),
+			WarrantType:                  task.WarrantType,
+			StartTime:                    startTime,
+			EndTime:                      endTime,
+			Roaming:                      task.Roaming,
+			StatisticsReportIntervalSecs: task.StatisticsReportIntervalSecs,
+		},
+	}
+	for _, filter := range task.BearerFilters {
+		ret.TaskDetails.BearerFilters = append(ret.TaskDetails.BearerFilters, &NetworkProbeBearerFilter{
+			Apn: filter.Apn,
+			Qci: filter.Qci,
+		})
+	}
+	if task.Duration != 0 {
+		duration := task.Duration
+		ret.TaskDetails.Duration = &duration
+	}
+	return ret, nil
+}
+
+// ToProtoBearerFilters returns the typed models of the bearer filters of a
+// task, nil when all the bearers are mirrored
+func ToProtoBearerFilters(filters []*NetworkProbeBearerFilter) []*nprobe_protos.BearerFilter {
+	var ret []*nprobe_protos.BearerFilter
+	for _, filter := range filters {
+		if filter != nil {
+			ret = append(ret, &nprobe_protos.BearerFilter{Apn: filter.Apn, Qci: filter.Qci})
+		}
+	}
+	return ret
+}
+
+// toProtoRecordFilter returns the typed model of the record filter of a task,
+// nil when all its records are delivered
+func toProtoRecordFilter(filter *NetworkProbeRecordFilter) *nprobe_protos.RecordFilter {
+	if filter == nil {
+		return nil
+	}
+	ret := &nprobe_protos.RecordFilter{
+		EventCategories: filter.EventCategories,
+		Apns:            filter.Apns,
+		TimeZone:        filter.TimeZone,
+	}
+	for _, window := range filter.TimeWindows {
+		if window != nil {
+			ret.TimeWindows = append(ret.TimeWindows, &nprobe_protos.TimeWindow{Start: window.Start, End: window.End})
+		}
+	}
+	return ret
+}
+
+func fromProtoRecordFilter(filter *nprobe_protos.RecordFilter) *NetworkProbeRecordFilter {
+	if filter == nil {
+		return nil
+	}
+	ret := &NetworkProbeRecordFilter{
+		EventCategories: filter.EventCategories,
+		Apns:            filter.Apns,
+		TimeZone:        filter.TimeZone,
+	}
+	for _, window := range filter.TimeWindows {
+		ret.TimeWindows = append(ret.TimeWindows, &NetworkProbeTimeWindow{Start: window.Start, End: window.End})
+	}
+	return ret
+}
+
+// ToProtoNProbeTaskStatus returns the typed model of the status of a task
+// served over gRPC
+func ToProtoNProbeTaskStatus(taskID string, data *NetworkProbeData) *nprobe_protos.TaskStatus {
+	return &nprobe_protos.TaskStatus{
+		TaskId:            taskID,
+		TargetId:          data.TargetID,
+		LastExported:      formatDateTime(data.LastExported),
+		SequenceNumber:    data.SequenceNumber,
+		RecordsExported:   data.RecordsExported,
+		DeliveryErrors:    data.DeliveryErrors,
+		LastDeliveryError: data.LastDeliveryError,
+		ExporterState:     data.ExporterState,
+		OpenSessions:      data.OpenSessions,
+		SuspendedAt:       formatDateTime(data.SuspendedAt),
+		CompletedAt:       formatDateTime(data.CompletedAt),
+		RateAlarm:         data.RateAlarm,
+		RateAlarmSince:    formatDateTime(data.RateAlarmSince),
+		Xid:               GetTaskXID(taskID, data),
+
+		LastDeliveryErrorClass: data.LastDeliveryErrorClass,
+		WarrantState:           data.WarrantState,
+		ExpiredAt:              formatDateTime(data.ExpiredAt),
+	}
+}
+
+// GetTaskXID returns the XID of the records of a task, its ID unless rotated
+func GetTaskXID(taskID string, data *NetworkProbeData) string {
+	if len(data.Xid) != 0 {
+		return data.Xid
+	}
+	return taskID
+}
+
+// IsTaskReplayPending returns true if a replay was requested for a task and
+// the events of its time range are not all replayed yet
+func IsTaskReplayPending(replay *NetworkProbeTaskReplay) bool {
+	return !time.Time(replay.Start).IsZero() && time.Time(replay.CompletedAt).IsZero()
+}
+
+// GetWarrantWindow returns the start and the end of the warrant of a task,
+// zero when unbounded. Without an end_time, the warrant ends once the
+// duration of the task from its timestamp elapsed.
+func GetWarrantWindow(details *NetworkProbeTaskDetails) (time.Time, time.Time) {
+	start, end := time.Time(details.StartTime), time.Time(details.EndTime)
+	timestamp := time.Time(details.Timestamp)
+	if end.IsZero() && details.Duration != nil && *details.Duration > 0 && !timestamp.IsZero() {
+		end = timestamp.Add(time.Duration(*details.Duration) * time.Second)
+	}
+	return start, end
+}
+
+// IsWithinWarrant returns true if the warrant of a task started and didn't
+// end at a given time
+func IsWithinWarrant(details *NetworkProbeTaskDetails, now time.Time) bool {
+	start, end := GetWarrantWindow(details)
+	return (start.IsZero() || !now.Before(start)) && (end.IsZero() || now.Before(end))
+}
+
+// IsTestRecordPending returns true if a test record was requested for a task
+// and is not delivered yet
+func IsTestRecordPending(testRecord *NetworkProbeTaskTestRecord) bool {
+	return len(testRecord.ID) != 0 && time.Time(testRecord.DeliveredAt).IsZero()
+}
+
+// IsTaskDeletionPending returns true if the deletion of a task was requested
+func IsTaskDeletionPending(deletion *NetworkProbeTaskDeletion) bool {
+	return !time.Time(deletion.RequestedAt).IsZero()
+}
+
+// ToProtoNProbeDestination returns the typed model of a destination served
+// over gRPC, without its payload encryption key
+func ToProtoNProbeDestination(destination *NetworkProbeDestination) *nprobe_protos.Destination {
+	details := destination.DestinationDetails
+	ret := &nprobe_protos.Destination{
+		DestinationId:     string(destination.DestinationID),
+		DeliveryAddress:   details.DeliveryAddress,
+		DeliveryType:      details.DeliveryType,
+		ModuleVersion:     details.ModuleVersion,
+		TlsServerName:     details.TLSServerName,
+		AlpnProtocols:     details.AlpnProtocols,
+		RateLimit:         details.RateLimit,
+		BurstSize:         details.BurstSize,
+		SynchronousExport: details.SynchronousExport,
+		MinimalRecords:    details.MinimalRecords,
+		Compression:       details.Compression,
+	}
+	if encryption := details.PayloadEncryption; encryption != nil {
+		ret.PayloadEncryptionType = encryption.EncryptionType
+		ret.PayloadEncryptionKeyId = GetPayloadEncryptionKeyID(encryption.Key)
+	}
+	return ret
+}
+
+// ToProtoNProbeConnections returns the typed models of the delivery
+// connections served over gRPC
+func ToProtoNProbeConnections(connections []*NetworkProbeConnection) *nprobe_protos.ConnectionList {
+	ret := &nprobe_protos.ConnectionList{}
+	for _, conn := range connections {
+		connection := &nprobe_protos.Connection{
+			Destination:        conn.Destination,
+			NetworkId:          conn.NetworkID,
+			Address:            conn.Address,
+			OnSecondary:        conn.OnSecondary,
+			Connected:          conn.Connected,
+			PeerAddress:        conn.PeerAddress,
+			Compression:        conn.Compression,
+			ConnectedAt:        formatDateTime(conn.ConnectedAt),
+			UptimeSecs:         conn.UptimeSecs,
+			BytesSent:          conn.BytesSent,
+			StandbyConnections: conn.StandbyConnections,
+			QueuedRecords:      conn.QueuedRecords,
+			LastError:          conn.LastError,
+			LastErrorAt:        formatDateTime(conn.LastErrorAt),
+			Breaker:            conn.Breaker,
+			BreakerOpenedAt:    formatDateTime(conn.BreakerOpenedAt),
+		}
+		if conn.Handshake != nil {
+			connection.TlsVersion = conn.Handshake.TLSVersion
+			connection.CipherSuite = conn.Handshake.CipherSuite
+			connection.NegotiatedProtocol = conn.Handshake.NegotiatedProtocol
+		}
+		ret.Connections = append(ret.Connections, connection)
+	}
+	return ret
+}
+
+// ToProtoNProbeSyncState returns the typed model of the snapshot of the tasks
+// of a network served over gRPC
+func ToProtoNProbeSyncState(state *NetworkProbeSyncState) *nprobe_protos.SyncState {
+	ret := &nprobe_protos.SyncState{
+		NetworkId:      state.NetworkID,
+		GeneratedAt:    formatDateTime(state.GeneratedAt),
+		Digest:         state.Digest,
+		MissingTaskIds: state.MissingTaskIds,
+	}
+	for _, task := range state.Tasks {
+		ret.Tasks = append(ret.Tasks, &nprobe_protos.TaskSyncState{
+			TaskId:                 task.TaskID,
+			TargetId:               task.TargetID,
+			TargetType:             task.TargetType,
+			DeliveryType:           task.DeliveryType,
+			CorrelationId:          task.CorrelationID,
+			AuthorizationReference: task.AuthorizationReference,
+			StartTime:              formatDateTime(task.StartTime),
+			EndTime:                formatDateTime(task.EndTime),
+			ConfigDigest:           task.ConfigDigest,
+			Xid:                    task.Xid,
+			SequenceNumber:         task.SequenceNumber,
+			RecordsExported:        task.RecordsExported,
+			LastExported:           formatDateTime(task.LastExported),
+			WarrantState:           task.WarrantState,
+			Paused:                 task.Paused,
+			DeletionRequested:      task.DeletionRequested,
+			SuspendedAt:            formatDateTime(task.SuspendedAt),
+			CompletedAt:            formatDateTime(task.CompletedAt),
+			ExpiredAt:              formatDateTime(task.ExpiredAt),
+		})
+	}
+	return ret
+}
+
+// formatDateTime formats a date-time in RFC3339 format, the zero date-time
+// being left empty
+func formatDateTime(t strfmt.DateTime) string {
+	if time.Time(t).IsZero() {
+		return ""
+	}
+	return time.Time(t).UTC().Format(time.RFC3339Nano)
+}
+
+// parseDateTime parses a time of a typed model, zero when empty
+func parseDateTime(name, value string) (strfmt.DateTime, error) {
+	if len(value) == 0 {
+		return strfmt.DateTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return strfmt.DateTime{}, fmt.Errorf("%s %s is not in RFC3339 format", name, value)
+	}
+	return strfmt.DateTime(t), nil
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2020 The Magma Authors.
+ *
+ * This source code is licensed under the BSD-style license found in the
+ * LICENSE file in the root directory of this source tree.
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// imsiWildcardRegex matches the IMSIs starting with the digits of at
+	// least an MCC and MNC
+	imsiWildcardRegex = regexp.MustCompile(`^(IMSI)?([0-9]{5,14})\*$`)
+	imsiBoundRegex    = regexp.MustCompile(`^(IMSI)?([0-9]{6,15})$`)
+)
+
+// IMSIRange is the set of IMSIs targeted by an imsi_range task, either the
+// IMSIs starting with a prefix or the IMSIs of the length of the bounds of
+// a range between them.
+type IMSIRange struct {
+	Prefix string
+	First  string
+	Last   string
+}
+
+// ParseIMSIRange parses the target ID of an imsi_range task, a prefix
+// followed by * or the first and last IMSIs of the range separated by a
+// dash, each with or without IMSI prefix
+func ParseIMSIRange(targetID string) (*IMSIRange, error) {
+	if match := imsiWildcardRegex.FindStringSubmatch(targetID); match != nil {
+		return &IMSIRange{Prefix: match[2]}, nil
+	}
+	bounds := strings.Split(targetID, "-")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("target_id %s is not an IMSI prefix followed by * nor an IMSI range", targetID)
+	}
+	first, last := imsiBoundRegex.FindStringSubmatch(bounds[0]), imsiBoundRegex.FindStringSubmatch(bounds[1])
+	if first == nil || last == nil {
+		return nil, fmt.Errorf("target_id %s is not a range of IMSIs", targetID)
+	}
+	if len(first[2]) != len(last[2]) {
+		return nil, fmt.Errorf("bounds of IMSI range %s are not of the same length", targetID)
+	}
+	if first[2] > last[2] {
+		return nil, fmt.Errorf("IMSI range %s is empty", targetID)
+	}
+	return &IMSIRange{First: first[2], Last: last[2]}, nil
+}
+
+// Contains returns true if an IMSI, with or without IMSI prefix, is in the
+// range
+func (r *IMSIRange) Contains(imsi string) bool {
+	digits := strings.TrimPrefix(imsi, "IMSI")
+	if len(digits) == 0 {
+		return false
+	}
+	if len(r.Prefix) != 0 {
+		return strings.HasPrefix(digits, r.Prefix)
+	}
+	return len(digits) == len(r.First) && digits >= r.First && digits <= r.Last
+}
+
+// IsInPLMNs returns true if all the IMSIs of the range belong to one of the
+// PLMNs of the MCCs and MNCs
+func (r *IMSIRange) IsInPLMNs(plmnIDs []string) bool {
+	for _, plmnID := range plmnIDs {
+		if len(plmnID) == 0 {
+			continue
+		}
+		if len(r.Prefix) != 0 && strings.HasPrefix(r.Prefix, plmnID) {
+			return true
+		}
+		if len(r.Prefix) == 0 && strings.HasPrefix(r.First, plmnID) && strings.HasPrefix(r.Last, plmnID) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,9 @@ package models
 // Editing this file might prove futile when you re-run the swagger generate command
 
 import (
+	"encoding/json"
+	"strconv"
+
 	strfmt "github.com/go-openapi/strfmt"
 
 	"github.com/go-openapi/errors"
@@ -17,42 +20,405 @@ import (
 // swagger:model network_probe_data
 type NetworkProbeData struct {
 
+	// The time the interception of the task started, once its warrant started, whose alert was raised then
+	// Format: date-time
+	ActivatedAt strfmt.DateTime `json:"activated_at,omitempty"`
+
+	// True while the bearers of the target active when the interception of the task started are being reported, each with a start of interception IRI-BEGIN
+	ActiveBearersPending bool `json:"active_bearers_pending,omitempty"`
+
+	// The time the report of a one-shot task was delivered, after which the task is no longer processed
+	// Format: date-time
+	CompletedAt strfmt.DateTime `json:"completed_at,omitempty"`
+
+	// The time the task was created, by which the tasks are sorted when listed
+	// Format: date-time
+	CreatedAt strfmt.DateTime `json:"created_at,omitempty"`
+
+	// Number of failed attempts to deliver records
+	DeliveryErrors uint64 `json:"delivery_errors,omitempty"`
+
+	// The time the IRI-END of an expired task was delivered, after which the task is no longer processed
+	// Format: date-time
+	ExpiredAt strfmt.DateTime `json:"expired_at,omitempty"`
+
+	// IDs of the events at last_exported already processed, which are skipped when fetched again
+	ExportedEventIds []string `json:"exported_event_ids,omitempty"`
+
+	// exporter handshake
+	ExporterHandshake *NetworkProbeHandshake `json:"exporter_handshake,omitempty"`
+
+	// State of the exporter connection at the last processing pass
+	// Enum: [connected disconnected]
+	ExporterState string `json:"exporter_state,omitempty"`
+
+	// The time the activation of the task was notified over HI1
+	// Format: date-time
+	Hi1ActivatedAt strfmt.DateTime `json:"hi1_activated_at,omitempty"`
+
+	// The time the deactivation of the task was notified over HI1, once the task was deleted and until its state is reclaimed
+	// Format: date-time
+	Hi1DeactivatedAt strfmt.DateTime `json:"hi1_deactivated_at,omitempty"`
+
+	// The last error reported while delivering records
+	LastDeliveryError string `json:"last_delivery_error,omitempty"`
+
+	// The class of the last error reported while delivering records
+	// Enum: [tls_handshake connect connection_reset write_timeout ack_timeout remote_nack encode queue breaker_open unknown]
+	LastDeliveryErrorClass string `json:"last_delivery_error_class,omitempty"`
+
 	// The timestamp in ISO 8601 format of last exported record
 	// Required: true
 	// Format: date-time
 	LastExported strfmt.DateTime `json:"last_exported"`
 
+	// IDs of the sessions of the target opened by the records delivered and not closed yet
+	OpenSessions []string `json:"open_sessions,omitempty"`
+
+	// The XID of the records of the task before its last rotation
+	PreviousXid string `json:"previous_xid,omitempty"`
+
+	// Number of records exported since the task creation
+	RecordsExported uint64 `json:"records_exported,omitempty"`
+
+	// Set while the records of the task are outside the activity bounds of the task
+	// Enum: [silence burst]
+	RateAlarm string `json:"rate_alarm,omitempty"`
+
+	// The time the rate alarm of the task was raised
+	// Format: date-time
+	RateAlarmSince strfmt.DateTime `json:"rate_alarm_since,omitempty"`
+
+	// Sequence numbers assigned to events whose records were submitted but are not known to be delivered yet
+	ReservedRecords []*NetworkProbeReservedRecord `json:"reserved_records,omitempty"`
+
+	// The time of the last resumption of the task whose IRI-REPORT was delivered
+	// Format: date-time
+	ResumeReportedAt strfmt.DateTime `json:"resume_reported_at,omitempty"`
+
 	// sequence number
 	// Required: true
 	SequenceNumber uint32 `json:"sequence_number"`
 
+	// serving network
+	ServingNetwork *NetworkProbeServingNetwork `json:"serving_network,omitempty"`
+
+	// The time interception was suspended by the kill switch, until the terminal record is delivered
+	// Format: date-time
+	SuspendedAt strfmt.DateTime `json:"suspended_at,omitempty"`
+
 	// target id
 	// Required: true
 	TargetID string `json:"target_id"`
+
+	// State of the time-bounded warrant of the task at the last processing pass, unset when the warrant has no start_time nor end_time
+	// Enum: [pending active expired]
+	WarrantState string `json:"warrant_state,omitempty"`
+
+	// The XID of the records of the task, its ID unless rotated
+	Xid string `json:"xid,omitempty"`
+
+	// The time the XID of the task was last rotated, once its linkage records were delivered
+	// Format: date-time
+	XidRotatedAt strfmt.DateTime `json:"xid_rotated_at,omitempty"`
 }
 
 // Validate validates this network probe data
 func (m *NetworkProbeData) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateActivatedAt(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateCompletedAt(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateCreatedAt(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateExpiredAt(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateExporterHandshake(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateExporterState(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateHi1ActivatedAt(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateHi1DeactivatedAt(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateLastDeliveryErrorClass(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateLastExported(formats); err != nil {
 		res = append(res, err)
 	}
 
+	if err := m.validateRateAlarm(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateRateAlarmSince(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateReservedRecords(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateResumeReportedAt(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateSequenceNumber(formats); err != nil {
 		res = append(res, err)
 	}
 
+	if err := m.validateServingNetwork(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateSuspendedAt(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateTargetID(formats); err != nil {
 		res = append(res, err)
 	}
 
+	if err := m.validateWarrantState(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateXidRotatedAt(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
 	return nil
 }
 
+func (m *NetworkProbeData) validateActivatedAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ActivatedAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("activated_at", "body", "date-time", m.ActivatedAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateCompletedAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.CompletedAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("completed_at", "body", "date-time", m.CompletedAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateCreatedAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.CreatedAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("created_at", "body", "date-time", m.CreatedAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateExpiredAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ExpiredAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("expired_at", "body", "date-time", m.ExpiredAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateExporterHandshake(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ExporterHandshake) { // not required
+		return nil
+	}
+
+	if m.ExporterHandshake != nil {
+		if err := m.ExporterHandshake.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("exporter_handshake")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+var networkProbeDataTypeExporterStatePropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["connected","disconnected"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeDataTypeExporterStatePropEnum = append(networkProbeDataTypeExporterStatePropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeDataExporterStateConnected captures enum value "connected"
+	NetworkProbeDataExporterStateConnected string = "connected"
+
+	// NetworkProbeDataExporterStateDisconnected captures enum value "disconnected"
+	NetworkProbeDataExporterStateDisconnected string = "disconnected"
+)
+
+// prop value enum
+func (m *NetworkProbeData) validateExporterStateEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeDataTypeExporterStatePropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeData) validateExporterState(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ExporterState) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateExporterStateEnum("exporter_state", "body", m.ExporterState); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateHi1ActivatedAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Hi1ActivatedAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("hi1_activated_at", "body", "date-time", m.Hi1ActivatedAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateHi1DeactivatedAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Hi1DeactivatedAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("hi1_deactivated_at", "body", "date-time", m.Hi1DeactivatedAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var networkProbeDataTypeLastDeliveryErrorClassPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["tls_handshake","connect","connection_reset","write_timeout","ack_timeout","remote_nack","encode","queue","breaker_open","unknown"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeDataTypeLastDeliveryErrorClassPropEnum = append(networkProbeDataTypeLastDeliveryErrorClassPropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeDataLastDeliveryErrorClassTLSHandshake captures enum value "tls_handshake"
+	NetworkProbeDataLastDeliveryErrorClassTLSHandshake string = "tls_handshake"
+
+	// NetworkProbeDataLastDeliveryErrorClassConnect captures enum value "connect"
+	NetworkProbeDataLastDeliveryErrorClassConnect string = "connect"
+
+	// NetworkProbeDataLastDeliveryErrorClassConnectionReset captures enum value "connection_reset"
+	NetworkProbeDataLastDeliveryErrorClassConnectionReset string = "connection_reset"
+
+	// NetworkProbeDataLastDeliveryErrorClassWriteTimeout captures enum value "write_timeout"
+	NetworkProbeDataLastDeliveryErrorClassWriteTimeout string = "write_timeout"
+
+	// NetworkProbeDataLastDeliveryErrorClassAckTimeout captures enum value "ack_timeout"
+	NetworkProbeDataLastDeliveryErrorClassAckTimeout string = "ack_timeout"
+
+	// NetworkProbeDataLastDeliveryErrorClassRemoteNack captures enum value "remote_nack"
+	NetworkProbeDataLastDeliveryErrorClassRemoteNack string = "remote_nack"
+
+	// NetworkProbeDataLastDeliveryErrorClassEncode captures enum value "encode"
+	NetworkProbeDataLastDeliveryErrorClassEncode string = "encode"
+
+	// NetworkProbeDataLastDeliveryErrorClassQueue captures enum value "queue"
+	NetworkProbeDataLastDeliveryErrorClassQueue string = "queue"
+
+	// NetworkProbeDataLastDeliveryErrorClassBreakerOpen captures enum value "breaker_open"
+	NetworkProbeDataLastDeliveryErrorClassBreakerOpen string = "breaker_open"
+
+	// NetworkProbeDataLastDeliveryErrorClassUnknown captures enum value "unknown"
+	NetworkProbeDataLastDeliveryErrorClassUnknown string = "unknown"
+)
+
+// prop value enum
+func (m *NetworkProbeData) validateLastDeliveryErrorClassEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeDataTypeLastDeliveryErrorClassPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeData) validateLastDeliveryErrorClass(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.LastDeliveryErrorClass) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateLastDeliveryErrorClassEnum("last_delivery_error_class", "body", m.LastDeliveryErrorClass); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *NetworkProbeData) validateLastExported(formats strfmt.Registry) error {
 
 	if err := validate.Required("last_exported", "body", strfmt.DateTime(m.LastExported)); err != nil {
@@ -66,6 +432,100 @@ func (m *NetworkProbeData) validateLastExported(formats strfmt.Registry) error {
 	return nil
 }
 
+var networkProbeDataTypeRateAlarmPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["silence","burst"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeDataTypeRateAlarmPropEnum = append(networkProbeDataTypeRateAlarmPropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeDataRateAlarmSilence captures enum value "silence"
+	NetworkProbeDataRateAlarmSilence string = "silence"
+
+	// NetworkProbeDataRateAlarmBurst captures enum value "burst"
+	NetworkProbeDataRateAlarmBurst string = "burst"
+)
+
+// prop value enum
+func (m *NetworkProbeData) validateRateAlarmEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeDataTypeRateAlarmPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeData) validateRateAlarm(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RateAlarm) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateRateAlarmEnum("rate_alarm", "body", m.RateAlarm); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateRateAlarmSince(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RateAlarmSince) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("rate_alarm_since", "body", "date-time", m.RateAlarmSince.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateReservedRecords(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ReservedRecords) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.ReservedRecords); i++ {
+		if swag.IsZero(m.ReservedRecords[i]) { // not required
+			continue
+		}
+
+		if m.ReservedRecords[i] != nil {
+			if err := m.ReservedRecords[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("reserved_records" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateResumeReportedAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ResumeReportedAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("resume_reported_at", "body", "date-time", m.ResumeReportedAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *NetworkProbeData) validateSequenceNumber(formats strfmt.Registry) error {
 
 	if err := validate.Required("sequence_number", "body", uint32(m.SequenceNumber)); err != nil {
@@ -75,6 +535,37 @@ func (m *NetworkProbeData) validateSequenceNumber(formats strfmt.Registry) error
 	return nil
 }
 
+func (m *NetworkProbeData) validateServingNetwork(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ServingNetwork) { // not required
+		return nil
+	}
+
+	if m.ServingNetwork != nil {
+		if err := m.ServingNetwork.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("serving_network")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateSuspendedAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.SuspendedAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("suspended_at", "body", "date-time", m.SuspendedAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *NetworkProbeData) validateTargetID(formats strfmt.Registry) error {
 
 	if err := validate.RequiredString("target_id", "body", string(m.TargetID)); err != nil {
@@ -84,6 +575,65 @@ func (m *NetworkProbeData) validateTargetID(formats strfmt.Registry) error {
 	return nil
 }
 
+var networkProbeDataTypeWarrantStatePropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["pending","active","expired"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeDataTypeWarrantStatePropEnum = append(networkProbeDataTypeWarrantStatePropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeDataWarrantStatePending captures enum value "pending"
+	NetworkProbeDataWarrantStatePending string = "pending"
+
+	// NetworkProbeDataWarrantStateActive captures enum value "active"
+	NetworkProbeDataWarrantStateActive string = "active"
+
+	// NetworkProbeDataWarrantStateExpired captures enum value "expired"
+	NetworkProbeDataWarrantStateExpired string = "expired"
+)
+
+// prop value enum
+func (m *NetworkProbeData) validateWarrantStateEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeDataTypeWarrantStatePropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeData) validateWarrantState(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.WarrantState) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateWarrantStateEnum("warrant_state", "body", m.WarrantState); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeData) validateXidRotatedAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.XidRotatedAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("xid_rotated_at", "body", "date-time", m.XidRotatedAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // MarshalBinary interface implementation
 func (m *NetworkProbeData) MarshalBinary() ([]byte, error) {
 	if m == nil {
@@ -7,6 +7,7 @@ package models
 
 import (
 	"encoding/json"
+	"strconv"
 
 	strfmt "github.com/go-openapi/strfmt"
 
@@ -19,7 +20,17 @@ import (
 // swagger:model network_probe_destination_details
 type NetworkProbeDestinationDetails struct {
 
-	// delivery address
+	// The application protocols offered when the exporter delivers to this address, by preference
+	AlpnProtocols []string `json:"alpn_protocols,omitempty"`
+
+	// The records delivered at once to this address after an idle period, which defaults to the rate limit
+	BurstSize uint32 `json:"burst_size,omitempty"`
+
+	// The compression offered when the exporter delivers to this address. The records are compressed once the delivery function selects the compression along with an application protocol, and delivered as they are otherwise.
+	// Enum: [gzip]
+	Compression string `json:"compression,omitempty"`
+
+	// The host:port address of the delivery function, IPv6 hosts being enclosed in brackets
 	// Required: true
 	DeliveryAddress string `json:"delivery_address"`
 
@@ -27,12 +38,69 @@ type NetworkProbeDestinationDetails struct {
 	// Required: true
 	// Enum: [all events_only]
 	DeliveryType string `json:"delivery_type"`
+
+	// The ASN.1 encoding of the records written to this address, which defaults to the export encoding of the service config. der writes the definite lengths of the distinguished encoding rules while ber writes the constructed elements with indefinite lengths, as required by some legacy LEMFs.
+	// Enum: [der ber]
+	Encoding string `json:"encoding,omitempty"`
+
+	// The framing of the records written to this address, which defaults to the export framing of the service config. native writes the records as built, length_prefixed writes each record after its length on 4 octets, ps_pdu writes each record as an ETSI TS 102 232-1 PS-PDU and raw_ber writes their BER payloads back to back. Keepalives and acknowledged delivery require the native or length_prefixed framing.
+	// Enum: [native length_prefixed ps_pdu raw_ber]
+	Framing string `json:"framing,omitempty"`
+
+	// The header version of the records written to this address, which defaults to the export header version of the service config. Version 1 is the header of the LEMFs not upgraded yet, whose payload direction is reserved. When negotiating, it is the newest version offered.
+	// Maximum: 2
+	// Minimum: 1
+	HeaderVersion uint32 `json:"header_version,omitempty"`
+
+	// The domain OID identifying the records delivered to this address in place of the one of their module version, in dotted notation, as expected by the LEMFs of some jurisdictions or vendors. The fields the records carry are still the ones of their module version.
+	// Pattern: ^[0-2](\.(0|[1-9][0-9]*))+$
+	Hi2DomainID string `json:"hi2_domain_id,omitempty"`
+
+	// The events whose fields can't be encoded are delivered to this address as minimal records instead of being quarantined. Minimal records carry the identity of the target, the bearer and timestamp of the event, and list the fields left out in a missing-parameter indicator of their header.
+	MinimalRecords bool `json:"minimal_records,omitempty"`
+
+	// The release of the HI2 EPS ASN.1 module the records delivered to this address are encoded with, which defaults to r15. It selects the domain OID of the records and the fields they carry.
+	// Enum: [r13 r14 r15]
+	ModuleVersion string `json:"module_version,omitempty"`
+
+	// The header version is negotiated with the delivery function on each new connection, in a keepalive offering the header version and then the older ones until one is acknowledged, even if the service config doesn't negotiate it. Negotiation requires the native or length_prefixed framing.
+	NegotiateHeaderVersion bool `json:"negotiate_header_version,omitempty"`
+
+	// payload encryption
+	PayloadEncryption *NetworkProbePayloadEncryption `json:"payload_encryption,omitempty"`
+
+	// The records per second delivered to this address, which overrides the export rate limit of the service config. Records are not paced when neither is set.
+	RateLimit uint32 `json:"rate_limit,omitempty"`
+
+	// Each record delivered to this address must be written, and acknowledged when the exporter acknowledges delivery, before the next one is submitted and the cursor of its task is persisted. Records are never dropped by a full export queue. This trades throughput for no-loss delivery.
+	SynchronousExport bool `json:"synchronous_export,omitempty"`
+
+	// The SNI sent when the exporter delivers to this address, which defaults to the host of the address. The server certificate is verified against this name.
+	// Max Length: 253
+	// Pattern: ^[A-Za-z0-9.-]+$
+	TLSServerName string `json:"tls_server_name,omitempty"`
+
+	// The transport of the connection to this address, which defaults to the export transport of the service config. sctp establishes a multi-homed SCTP association with the addresses of the delivery function separated by slashes, e.g. 10.10.0.2/10.20.0.2:4040, carrying the records in plaintext: the association must be protected by IPsec or a DTLS gateway, as stated by the sctp_security of the service config.
+	// Enum: [tcp sctp]
+	Transport string `json:"transport,omitempty"`
+
+	// The deadline in milliseconds of each write of the records to this address, which defaults to the export write timeout of the service config. A write past its deadline fails and the connection is replaced, counting towards the circuit breaker of the destination.
+	// Maximum: 60000
+	WriteTimeoutMs uint32 `json:"write_timeout_ms,omitempty"`
 }
 
 // Validate validates this network probe destination details
 func (m *NetworkProbeDestinationDetails) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateAlpnProtocols(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateCompression(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateDeliveryAddress(formats); err != nil {
 		res = append(res, err)
 	}
@@ -41,12 +109,112 @@ func (m *NetworkProbeDestinationDetails) Validate(formats strfmt.Registry) error
 		res = append(res, err)
 	}
 
+	if err := m.validateEncoding(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateFraming(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateHeaderVersion(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateHi2DomainID(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateModuleVersion(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validatePayloadEncryption(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateTLSServerName(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateTransport(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateWriteTimeoutMs(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
 	return nil
 }
 
+func (m *NetworkProbeDestinationDetails) validateAlpnProtocols(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.AlpnProtocols) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.AlpnProtocols); i++ {
+
+		if err := validate.MinLength("alpn_protocols"+"."+strconv.Itoa(i), "body", string(m.AlpnProtocols[i]), 1); err != nil {
+			return err
+		}
+
+		if err := validate.MaxLength("alpn_protocols"+"."+strconv.Itoa(i), "body", string(m.AlpnProtocols[i]), 255); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+}
+
+var networkProbeDestinationDetailsTypeCompressionPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["gzip","zstd"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeDestinationDetailsTypeCompressionPropEnum = append(networkProbeDestinationDetailsTypeCompressionPropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeDestinationDetailsCompressionGzip captures enum value "gzip"
+	NetworkProbeDestinationDetailsCompressionGzip string = "gzip"
+
+	// NetworkProbeDestinationDetailsCompressionZstd captures enum value "zstd"
+	NetworkProbeDestinationDetailsCompressionZstd string = "zstd"
+)
+
+// prop value enum
+func (m *NetworkProbeDestinationDetails) validateCompressionEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeDestinationDetailsTypeCompressionPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeDestinationDetails) validateCompression(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Compression) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateCompressionEnum("compression", "body", m.Compression); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *NetworkProbeDestinationDetails) validateDeliveryAddress(formats strfmt.Registry) error {
 
 	if err := validate.RequiredString("delivery_address", "body", string(m.DeliveryAddress)); err != nil {
@@ -99,6 +267,265 @@ func (m *NetworkProbeDestinationDetails) validateDeliveryType(formats strfmt.Reg
 	return nil
 }
 
+var networkProbeDestinationDetailsTypeEncodingPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["der","ber"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeDestinationDetailsTypeEncodingPropEnum = append(networkProbeDestinationDetailsTypeEncodingPropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeDestinationDetailsEncodingDer captures enum value "der"
+	NetworkProbeDestinationDetailsEncodingDer string = "der"
+
+	// NetworkProbeDestinationDetailsEncodingBer captures enum value "ber"
+	NetworkProbeDestinationDetailsEncodingBer string = "ber"
+)
+
+// prop value enum
+func (m *NetworkProbeDestinationDetails) validateEncodingEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeDestinationDetailsTypeEncodingPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeDestinationDetails) validateEncoding(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Encoding) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateEncodingEnum("encoding", "body", m.Encoding); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var networkProbeDestinationDetailsTypeFramingPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["native","length_prefixed","ps_pdu","raw_ber"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeDestinationDetailsTypeFramingPropEnum = append(networkProbeDestinationDetailsTypeFramingPropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeDestinationDetailsFramingNative captures enum value "native"
+	NetworkProbeDestinationDetailsFramingNative string = "native"
+
+	// NetworkProbeDestinationDetailsFramingLengthPrefixed captures enum value "length_prefixed"
+	NetworkProbeDestinationDetailsFramingLengthPrefixed string = "length_prefixed"
+
+	// NetworkProbeDestinationDetailsFramingPsPdu captures enum value "ps_pdu"
+	NetworkProbeDestinationDetailsFramingPsPdu string = "ps_pdu"
+
+	// NetworkProbeDestinationDetailsFramingRawBer captures enum value "raw_ber"
+	NetworkProbeDestinationDetailsFramingRawBer string = "raw_ber"
+)
+
+// prop value enum
+func (m *NetworkProbeDestinationDetails) validateFramingEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeDestinationDetailsTypeFramingPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeDestinationDetails) validateFraming(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Framing) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateFramingEnum("framing", "body", m.Framing); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeDestinationDetails) validateHeaderVersion(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.HeaderVersion) { // not required
+		return nil
+	}
+
+	if err := validate.MinimumInt("header_version", "body", int64(m.HeaderVersion), 1, false); err != nil {
+		return err
+	}
+
+	if err := validate.MaximumInt("header_version", "body", int64(m.HeaderVersion), 2, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeDestinationDetails) validateHi2DomainID(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Hi2DomainID) { // not required
+		return nil
+	}
+
+	if err := validate.Pattern("hi2_domain_id", "body", string(m.Hi2DomainID), `^[0-2](\.(0|[1-9][0-9]*))+$`); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var networkProbeDestinationDetailsTypeModuleVersionPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["r13","r14","r15"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeDestinationDetailsTypeModuleVersionPropEnum = append(networkProbeDestinationDetailsTypeModuleVersionPropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeDestinationDetailsModuleVersionR13 captures enum value "r13"
+	NetworkProbeDestinationDetailsModuleVersionR13 string = "r13"
+
+	// NetworkProbeDestinationDetailsModuleVersionR14 captures enum value "r14"
+	NetworkProbeDestinationDetailsModuleVersionR14 string = "r14"
+
+	// NetworkProbeDestinationDetailsModuleVersionR15 captures enum value "r15"
+	NetworkProbeDestinationDetailsModuleVersionR15 string = "r15"
+)
+
+// prop value enum
+func (m *NetworkProbeDestinationDetails) validateModuleVersionEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeDestinationDetailsTypeModuleVersionPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeDestinationDetails) validateModuleVersion(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ModuleVersion) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateModuleVersionEnum("module_version", "body", m.ModuleVersion); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeDestinationDetails) validatePayloadEncryption(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.PayloadEncryption) { // not required
+		return nil
+	}
+
+	if m.PayloadEncryption != nil {
+		if err := m.PayloadEncryption.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("payload_encryption")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeDestinationDetails) validateTLSServerName(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.TLSServerName) { // not required
+		return nil
+	}
+
+	if err := validate.MaxLength("tls_server_name", "body", string(m.TLSServerName), 253); err != nil {
+		return err
+	}
+
+	if err := validate.Pattern("tls_server_name", "body", string(m.TLSServerName), `^[A-Za-z0-9.-]+$`); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var networkProbeDestinationDetailsTypeTransportPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["tcp","sctp"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeDestinationDetailsTypeTransportPropEnum = append(networkProbeDestinationDetailsTypeTransportPropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeDestinationDetailsTransportTCP captures enum value "tcp"
+	NetworkProbeDestinationDetailsTransportTCP string = "tcp"
+
+	// NetworkProbeDestinationDetailsTransportSctp captures enum value "sctp"
+	NetworkProbeDestinationDetailsTransportSctp string = "sctp"
+)
+
+// prop value enum
+func (m *NetworkProbeDestinationDetails) validateTransportEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeDestinationDetailsTypeTransportPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeDestinationDetails) validateTransport(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Transport) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateTransportEnum("transport", "body", m.Transport); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeDestinationDetails) validateWriteTimeoutMs(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.WriteTimeoutMs) { // not required
+		return nil
+	}
+
+	if err := validate.MaximumInt("write_timeout_ms", "body", int64(m.WriteTimeoutMs), 60000, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // MarshalBinary interface implementation
 func (m *NetworkProbeDestinationDetails) MarshalBinary() ([]byte, error) {
 	if m == nil {
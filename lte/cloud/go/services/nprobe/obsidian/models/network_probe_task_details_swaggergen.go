@@ -7,6 +7,7 @@ package models
 
 import (
 	"encoding/json"
+	"strconv"
 
 	strfmt "github.com/go-openapi/strfmt"
 
@@ -19,39 +20,111 @@ import (
 // swagger:model network_probe_task_details
 type NetworkProbeTaskDetails struct {
 
+	// The lawful authorization reference (LIID) of the warrant.
+	// Max Length: 25
+	AuthorizationReference string `json:"authorization_reference,omitempty"`
+
+	// The bearers of the target mirrored by the gateways when delivery_type is all, any bearer matching one of the filters. All the bearers are mirrored when empty.
+	BearerFilters []*NetworkProbeBearerFilter `json:"bearer_filters,omitempty"`
+
 	// correlation id
 	CorrelationID uint64 `json:"correlation_id,omitempty"`
 
+	// The ISO 3166-1 alpha-2 country code of the delivery function.
+	// Pattern: ^[A-Z]{2}$
+	DeliveryCountryCode string `json:"delivery_country_code,omitempty"`
+
+	// delivery
+	Delivery *NetworkProbeTaskDelivery `json:"delivery,omitempty"`
+
 	// delivery type
 	// Required: true
 	// Enum: [all events_only]
 	DeliveryType string `json:"delivery_type"`
 
+	// The ID of the NetworkProbe Destination of the network the records of the task are delivered to, with its TLS profile, framing, encoding and rate limit, instead of the delivery function of the service config. Exclusive with delivery. The destination can't be removed while tasks are delivered to it.
+	DestinationID string `json:"destination_id,omitempty"`
+
 	// domain id
 	DomainID string `json:"domain_id,omitempty"`
 
-	// the duration in seconds after which the task will expire.
+	// the duration in seconds after which the task will expire, from its timestamp. Ignored when end_time is set.
 	// Minimum: 0
 	Duration *int64 `json:"duration,omitempty"`
 
-	// target id
+	// The end of the warrant of the task, after which its interception is ended with an IRI-END and the task expires. Given by duration when unset, never when neither is set.
+	// Format: date-time
+	EndTime strfmt.DateTime `json:"end_time,omitempty"`
+
+	// The domain of the IRI records of the task, eps for the records of the EPS bearers or umts for the ones of the PDP contexts of an interworking GPRS/UMTS core. Given by the stream of each event when empty, umts for the sgsn stream.
+	// Enum: [eps umts]
+	IriDomain string `json:"iri_domain,omitempty"`
+
+	// The most records expected in each hour of activity of the target, more records raise a burst alarm. Not checked when 0.
+	MaxRecordsPerHour uint32 `json:"max_records_per_hour,omitempty"`
+
+	// The least number of records expected in each hour of activity of the target, fewer records raise a silence alarm. Not checked when 0.
+	MinRecordsPerHour uint32 `json:"min_records_per_hour,omitempty"`
+
+	// Report the location and state of the target once, as known from its events at the time the task is created, in a single IRI-REPORT. The task is then completed and no other record is delivered for it.
+	OneShot bool `json:"one_shot,omitempty"`
+
+	// record filter
+	RecordFilter *NetworkProbeRecordFilter `json:"record_filter,omitempty"`
+
+	// Whether the events of the target are intercepted while it roams in a visited PLMN, as reported by its last S6a update location: include intercepts them (the default), exclude only intercepts the events in the home PLMN and only the ones while roaming, as the warrant requires. Targets whose update location is not known yet are taken for home subscribers.
+	// Enum: [include exclude only]
+	Roaming string `json:"roaming,omitempty"`
+
+	// The start of the warrant of the task. The events of the target before it are not intercepted, and the task is pending until then. Starts with the task when unset.
+	// Format: date-time
+	StartTime strfmt.DateTime `json:"start_time,omitempty"`
+
+	// The interval in seconds at which the number of records delivered for the target, and their bytes, is reported to the LEMF in an IRI-REPORT, as some handover agreements require. Not reported when 0.
+	StatisticsReportIntervalSecs uint32 `json:"statistics_report_interval_secs,omitempty"`
+
+	// The IMSI, MSISDN or IMEI of the target, as set by target_type. The IMSIs of an imsi_range target are set by a prefix followed by *, e.g. IMSI99999000*, or by the first and last IMSIs of the range separated by a dash, e.g. IMSI999990000000001-IMSI999990000000099. They must belong to a test PLMN of the network, for test drills exercising the interception of synthetic subscribers.
 	// Required: true
 	TargetID string `json:"target_id"`
 
 	// target type
 	// Required: true
-	// Enum: [imsi imei msisdn]
+	// Enum: [imsi imei msisdn imsi_range]
 	TargetType string `json:"target_type"`
 
 	// The timestamp in ISO 8601 format
 	// Format: date-time
 	Timestamp strfmt.DateTime `json:"timestamp,omitempty"`
+
+	// The interval in seconds at which the usage of the open sessions of the target, bytes up and down and duration, is reported in an IRI-CONTINUE as a national parameter. Requires delivery_country_code. Not reported when 0.
+	UsageReportIntervalSecs uint32 `json:"usage_report_interval_secs,omitempty"`
+
+	// The type of the warrant of the task. The records of the task omit the location of the target unless the network authorizes it for this type, with its location_warrant_types.
+	// Max Length: 32
+	// Pattern: ^[a-z0-9_-]+$
+	WarrantType string `json:"warrant_type,omitempty"`
 }
 
 // Validate validates this network probe task details
 func (m *NetworkProbeTaskDetails) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateAuthorizationReference(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateBearerFilters(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateDeliveryCountryCode(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateDelivery(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateDeliveryType(formats); err != nil {
 		res = append(res, err)
 	}
@@ -60,6 +133,26 @@ func (m *NetworkProbeTaskDetails) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateEndTime(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateIriDomain(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateRecordFilter(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateRoaming(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateStartTime(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateTargetID(formats); err != nil {
 		res = append(res, err)
 	}
@@ -72,12 +165,85 @@ func (m *NetworkProbeTaskDetails) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateWarrantType(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
 	return nil
 }
 
+func (m *NetworkProbeTaskDetails) validateAuthorizationReference(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.AuthorizationReference) { // not required
+		return nil
+	}
+
+	if err := validate.MaxLength("authorization_reference", "body", string(m.AuthorizationReference), 25); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeTaskDetails) validateBearerFilters(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.BearerFilters) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.BearerFilters); i++ {
+		if swag.IsZero(m.BearerFilters[i]) { // not required
+			continue
+		}
+
+		if m.BearerFilters[i] != nil {
+			if err := m.BearerFilters[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("bearer_filters" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeTaskDetails) validateDeliveryCountryCode(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.DeliveryCountryCode) { // not required
+		return nil
+	}
+
+	if err := validate.Pattern("delivery_country_code", "body", string(m.DeliveryCountryCode), `^[A-Z]{2}$`); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeTaskDetails) validateDelivery(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Delivery) { // not required
+		return nil
+	}
+
+	if m.Delivery != nil {
+		if err := m.Delivery.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("delivery")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
 var networkProbeTaskDetailsTypeDeliveryTypePropEnum []interface{}
 
 func init() {
@@ -134,6 +300,139 @@ func (m *NetworkProbeTaskDetails) validateDuration(formats strfmt.Registry) erro
 	return nil
 }
 
+func (m *NetworkProbeTaskDetails) validateEndTime(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.EndTime) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("end_time", "body", "date-time", m.EndTime.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var networkProbeTaskDetailsTypeIriDomainPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["eps","umts"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeTaskDetailsTypeIriDomainPropEnum = append(networkProbeTaskDetailsTypeIriDomainPropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeTaskDetailsIriDomainEps captures enum value "eps"
+	NetworkProbeTaskDetailsIriDomainEps string = "eps"
+
+	// NetworkProbeTaskDetailsIriDomainUmts captures enum value "umts"
+	NetworkProbeTaskDetailsIriDomainUmts string = "umts"
+)
+
+// prop value enum
+func (m *NetworkProbeTaskDetails) validateIriDomainEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeTaskDetailsTypeIriDomainPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeTaskDetails) validateIriDomain(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.IriDomain) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateIriDomainEnum("iri_domain", "body", m.IriDomain); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeTaskDetails) validateRecordFilter(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.RecordFilter) { // not required
+		return nil
+	}
+
+	if m.RecordFilter != nil {
+		if err := m.RecordFilter.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("record_filter")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+var networkProbeTaskDetailsTypeRoamingPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["include","exclude","only"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		networkProbeTaskDetailsTypeRoamingPropEnum = append(networkProbeTaskDetailsTypeRoamingPropEnum, v)
+	}
+}
+
+const (
+
+	// NetworkProbeTaskDetailsRoamingInclude captures enum value "include"
+	NetworkProbeTaskDetailsRoamingInclude string = "include"
+
+	// NetworkProbeTaskDetailsRoamingExclude captures enum value "exclude"
+	NetworkProbeTaskDetailsRoamingExclude string = "exclude"
+
+	// NetworkProbeTaskDetailsRoamingOnly captures enum value "only"
+	NetworkProbeTaskDetailsRoamingOnly string = "only"
+)
+
+// prop value enum
+func (m *NetworkProbeTaskDetails) validateRoamingEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, networkProbeTaskDetailsTypeRoamingPropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *NetworkProbeTaskDetails) validateRoaming(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Roaming) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateRoamingEnum("roaming", "body", m.Roaming); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *NetworkProbeTaskDetails) validateStartTime(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.StartTime) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("start_time", "body", "date-time", m.StartTime.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *NetworkProbeTaskDetails) validateTargetID(formats strfmt.Registry) error {
 
 	if err := validate.RequiredString("target_id", "body", string(m.TargetID)); err != nil {
@@ -147,7 +446,7 @@ var networkProbeTaskDetailsTypeTargetTypePropEnum []interface{}
 
 func init() {
 	var res []string
-	if err := json.Unmarshal([]byte(`["imsi","imei","msisdn"]`), &res); err != nil {
+	if err := json.Unmarshal([]byte(`["imsi","imei","msisdn","imsi_range"]`), &res); err != nil {
 		panic(err)
 	}
 	for _, v := range res {
@@ -165,6 +464,9 @@ const (
 
 	// NetworkProbeTaskDetailsTargetTypeMsisdn captures enum value "msisdn"
 	NetworkProbeTaskDetailsTargetTypeMsisdn string = "msisdn"
+
+	// NetworkProbeTaskDetailsTargetTypeImsiRange captures enum value "imsi_range"
+	NetworkProbeTaskDetailsTargetTypeImsiRange string = "imsi_range"
 )
 
 // prop value enum
@@ -202,6 +504,23 @@ func (m *NetworkProbeTaskDetails) validateTimestamp(formats strfmt.Registry) err
 	return nil
 }
 
+func (m *NetworkProbeTaskDetails) validateWarrantType(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.WarrantType) { // not required
+		return nil
+	}
+
+	if err := validate.MaxLength("warrant_type", "body", string(m.WarrantType), 32); err != nil {
+		return err
+	}
+
+	if err := validate.Pattern("warrant_type", "body", string(m.WarrantType), `^[a-z0-9_-]+$`); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // MarshalBinary interface implementation
 func (m *NetworkProbeTaskDetails) MarshalBinary() ([]byte, error) {
 	if m == nil {
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2020 The Magma Authors.
+ *
+ * This source code is licensed under the BSD-style license found in the
+ * LICENSE file in the root directory of this source tree.
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package models
+
+import "strings"
+
+// Matches returns true if an identity of the given type, in any of the forms
+// accepted as target ID, designates the protected identity
+func (m *NetworkProbeProtectedIdentity) Matches(identityType, identity string) bool {
+	if m == nil || m.IdentityType != identityType || len(identity) == 0 {
+		return false
+	}
+	switch identityType {
+	case NetworkProbeProtectedIdentityIdentityTypeImsi:
+		return strings.TrimPrefix(identity, "IMSI") == strings.TrimPrefix(m.Identity, "IMSI")
+	case NetworkProbeProtectedIdentityIdentityTypeMsisdn:
+		return strings.TrimPrefix(identity, "+") == strings.TrimPrefix(m.Identity, "+")
+	case NetworkProbeProtectedIdentityIdentityTypeImei:
+		// IMEIs are compared on their TAC and serial number, ignoring the
+		// check digit or software version
+		if len(identity) < 14 || len(m.Identity) < 14 {
+			return identity == m.Identity
+		}
+		return identity[:14] == m.Identity[:14]
+	default:
+		return false
+	}
+}
+
+// GetProtectedIdentity returns the protected identity an identity of the
+// given type designates, nil if it isn't protected
+func GetProtectedIdentity(identities []*NetworkProbeProtectedIdentity, identityType, identity string) *NetworkProbeProtectedIdentity {
+	for _, protected := range identities {
+		if protected.Matches(identityType, identity) {
+			return protected
+		}
+	}
+	return nil
+}
+
+// GetProtectedTarget returns the protected identity targeted by a task, nil
+// if its target isn't protected. imsi_range tasks are protected when one of
+// their IMSIs is. The target ID of the task is expected to be validated.
+func GetProtectedTarget(identities []*NetworkProbeProtectedIdentity, details *NetworkProbeTaskDetails) *NetworkProbeProtectedIdentity {
+	if details.TargetType != NetworkProbeTaskDetailsTargetTypeImsiRange {
+		return GetProtectedIdentity(identities, details.TargetType, details.TargetID)
+	}
+	imsiRange, err := ParseIMSIRange(details.TargetID)
+	if err != nil {
+		return nil
+	}
+	for _, protected := range identities {
+		if protected != nil && protected.IdentityType == NetworkProbeProtectedIdentityIdentityTypeImsi && imsiRange.Contains(protected.Identity) {
+			return protected
+		}
+	}
+	return nil
+}
@@ -20,6 +20,10 @@ import (
 )
 
 var (
+	// NetworkSerdes contains the package's configurator network config serdes
+	NetworkSerdes = serde.NewRegistry(
+		configurator.NewNetworkConfigSerde(lte.NetworkProbeConfigType, &NetworkProbeNetworkConfig{}),
+	)
 	// EntitySerdes contains the package's configurator network entity serdes
 	EntitySerdes = serde.NewRegistry(
 		configurator.NewNetworkEntityConfigSerde(lte.NetworkProbeTaskEntityType, &NetworkProbeTaskDetails{}),
@@ -14,13 +14,386 @@
 package models
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/logging"
+
 	strfmt "github.com/go-openapi/strfmt"
 )
 
+var (
+	msisdnRegex = regexp.MustCompile(`^\+?[0-9]{5,15}$`)
+	// IMEI with or without check digit, or IMEISV
+	imeiRegex   = regexp.MustCompile(`^[0-9]{14,16}$`)
+	regionRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+	// payloadEncryptionKeySizes are the key sizes of the payload encryptions
+	payloadEncryptionKeySizes = map[string]int{
+		NetworkProbePayloadEncryptionEncryptionTypeAes192Cbc: 24,
+		NetworkProbePayloadEncryptionEncryptionTypeAes256Cbc: 32,
+	}
+)
+
 func (m *NetworkProbeTask) ValidateModel() error {
-	return m.Validate(strfmt.Default)
+	if err := m.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	if err := m.TaskDetails.validateTargetIDFormat(); err != nil {
+		return err
+	}
+	if err := m.TaskDetails.validateActivityBounds(); err != nil {
+		return err
+	}
+	if err := m.TaskDetails.validateDeliveryHostPort(); err != nil {
+		return err
+	}
+	if m.TaskDetails.Delivery != nil && len(m.TaskDetails.DestinationID) != 0 {
+		return errors.New("delivery and destination_id are exclusive")
+	}
+	if m.TaskDetails.Delivery != nil {
+		if err := validateHi2DomainID(m.TaskDetails.Delivery.Hi2DomainID); err != nil {
+			return err
+		}
+	}
+	if err := m.TaskDetails.validateRecordFilterTimes(); err != nil {
+		return err
+	}
+	if err := m.TaskDetails.validateWarrantWindow(); err != nil {
+		return err
+	}
+	return m.TaskDetails.validateUsageReport()
+}
+
+// validateTargetIDFormat checks that the target ID is a valid identifier of
+// its type. IMSI targets accept any subscriber ID.
+func (m *NetworkProbeTaskDetails) validateTargetIDFormat() error {
+	switch m.TargetType {
+	case NetworkProbeTaskDetailsTargetTypeImsiRange:
+		if _, err := ParseIMSIRange(m.TargetID); err != nil {
+			return err
+		}
+	case NetworkProbeTaskDetailsTargetTypeMsisdn:
+		if !msisdnRegex.MatchString(m.TargetID) {
+			return fmt.Errorf("target_id %s is not a valid MSISDN", m.TargetID)
+		}
+	case NetworkProbeTaskDetailsTargetTypeImei:
+		if !imeiRegex.MatchString(m.TargetID) {
+			return fmt.Errorf("target_id %s is not a valid IMEI", m.TargetID)
+		}
+	}
+	return nil
+}
+
+// validateActivityBounds checks that the expected activity of the target
+// isn't empty when both of its bounds are set
+func (m *NetworkProbeTaskDetails) validateActivityBounds() error {
+	if m.MinRecordsPerHour != 0 && m.MaxRecordsPerHour != 0 && m.MinRecordsPerHour > m.MaxRecordsPerHour {
+		return fmt.Errorf(
+			"min_records_per_hour %d is greater than max_records_per_hour %d",
+			m.MinRecordsPerHour, m.MaxRecordsPerHour,
+		)
+	}
+	return nil
+}
+
+// validateDeliveryHostPort checks that the delivery addresses overriding the ones
+// of the service are host:port addresses
+func (m *NetworkProbeTaskDetails) validateDeliveryHostPort() error {
+	if m.Delivery == nil {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(m.Delivery.DeliveryAddress); err != nil {
+		return fmt.Errorf("delivery_address %s is not a valid host:port address: %v", m.Delivery.DeliveryAddress, err)
+	}
+	if err := validateMultiHoming("delivery_address", m.Delivery.DeliveryAddress, m.Delivery.Transport); err != nil {
+		return err
+	}
+	if len(m.Delivery.SecondaryDeliveryAddress) == 0 {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(m.Delivery.SecondaryDeliveryAddress); err != nil {
+		return fmt.Errorf("secondary_delivery_address %s is not a valid host:port address: %v", m.Delivery.SecondaryDeliveryAddress, err)
+	}
+	if err := validateMultiHoming("secondary_delivery_address", m.Delivery.SecondaryDeliveryAddress, m.Delivery.Transport); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateHi2DomainID checks that the arcs of a domain OID in dotted notation
+// can be DER encoded: the second arc is below 40 unless the first one is 2,
+// and the arcs fit in an int.
+func validateHi2DomainID(domainID string) error {
+	if len(domainID) == 0 {
+		return nil
+	}
+	arcs := strings.Split(domainID, ".")
+	for _, arc := range arcs {
+		if _, err := strconv.Atoi(arc); err != nil {
+			return fmt.Errorf("hi2_domain_id %s is not a valid OID: %v", domainID, err)
+		}
+	}
+	if second, _ := strconv.Atoi(arcs[1]); arcs[0] != "2" && second >= 40 {
+		return fmt.Errorf("hi2_domain_id %s is not a valid OID: second arc %d is not below 40", domainID, second)
+	}
+	return nil
+}
+
+// validateRecordFilterTimes checks that the time zone of the time windows
+// of the record filter is known and that the windows aren't empty
+func (m *NetworkProbeTaskDetails) validateRecordFilterTimes() error {
+	if m.RecordFilter == nil {
+		return nil
+	}
+	if _, err := time.LoadLocation(m.RecordFilter.TimeZone); err != nil {
+		return fmt.Errorf("time_zone %s is not a valid time zone: %v", m.RecordFilter.TimeZone, err)
+	}
+	for _, window := range m.RecordFilter.TimeWindows {
+		if window != nil && window.Start == window.End {
+			return fmt.Errorf("time window %s-%s is empty", window.Start, window.End)
+		}
+	}
+	return nil
+}
+
+// validateWarrantWindow checks that the warrant of the task doesn't end
+// before it starts
+func (m *NetworkProbeTaskDetails) validateWarrantWindow() error {
+	start, end := time.Time(m.StartTime), time.Time(m.EndTime)
+	if !start.IsZero() && !end.IsZero() && !end.After(start) {
+		return fmt.Errorf("end_time %s is not after start_time %s", m.EndTime, m.StartTime)
+	}
+	return nil
+}
+
+// validateUsageReport checks that the country code the usage reports are
+// qualified with is set when they are enabled
+func (m *NetworkProbeTaskDetails) validateUsageReport() error {
+	if m.UsageReportIntervalSecs != 0 && len(m.DeliveryCountryCode) == 0 {
+		return errors.New("usage_report_interval_secs requires delivery_country_code")
+	}
+	return nil
+}
+
+// ValidateModel checks that a bulk request holds a template and targets. The
+// template lacks a target, its settings being validated by the tasks built
+// for each target instead.
+func (m *NetworkProbeTaskBulkRequest) ValidateModel() error {
+	if m.Template == nil {
+		return errors.New("template is required")
+	}
+	if len(m.Targets) == 0 {
+		return errors.New("targets must not be empty")
+	}
+	for _, target := range m.Targets {
+		if target == nil {
+			return errors.New("targets must not be null")
+		}
+	}
+	return nil
 }
 
 func (m *NetworkProbeDestination) ValidateModel() error {
+	if err := m.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	if err := m.DestinationDetails.validateDeliveryHostPort(); err != nil {
+		return err
+	}
+	if err := validateHi2DomainID(m.DestinationDetails.Hi2DomainID); err != nil {
+		return err
+	}
+	return m.DestinationDetails.validatePayloadEncryptionKey()
+}
+
+// validateDeliveryHostPort checks that the delivery address is a host:port
+// address, IPv6 hosts being enclosed in brackets, e.g. [2001:db8::1]:4040
+func (m *NetworkProbeDestinationDetails) validateDeliveryHostPort() error {
+	if _, _, err := net.SplitHostPort(m.DeliveryAddress); err != nil {
+		return fmt.Errorf("delivery_address %s is not a valid host:port address: %v", m.DeliveryAddress, err)
+	}
+	return validateMultiHoming("delivery_address", m.DeliveryAddress, m.Transport)
+}
+
+// validateMultiHoming checks that an address listing the hosts of a
+// multi-homed delivery function, separated by slashes, isn't reached over
+// tcp, which connects to a single host
+func validateMultiHoming(field, addr, transport string) error {
+	host, _, _ := net.SplitHostPort(addr)
+	if strings.Contains(host, "/") && transport == NetworkProbeDestinationDetailsTransportTCP {
+		return fmt.Errorf("%s %s lists several hosts, which requires the sctp transport", field, addr)
+	}
+	return nil
+}
+
+// validatePayloadEncryptionKey checks that the payload encryption key is of
+// the size its encryption type requires. The key may be left out along with
+// the ID of the current key to keep it.
+func (m *NetworkProbeDestinationDetails) validatePayloadEncryptionKey() error {
+	encryption := m.PayloadEncryption
+	if encryption == nil {
+		return nil
+	}
+	if len(encryption.Key) == 0 {
+		if len(encryption.KeyID) == 0 {
+			return errors.New("payload_encryption requires a key")
+		}
+		return nil
+	}
+	key, err := hex.DecodeString(encryption.Key)
+	if err != nil {
+		return fmt.Errorf("payload_encryption key is not hex encoded: %v", err)
+	}
+	if size := payloadEncryptionKeySizes[encryption.EncryptionType]; len(key) != size {
+		return fmt.Errorf("payload_encryption key of %d bytes, %s requires %d bytes", len(key), encryption.EncryptionType, size)
+	}
+	return nil
+}
+
+func (m *NetworkProbeNetworkConfig) ValidateModel() error {
+	if err := m.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	if err := validateHi2DomainID(m.Hi2DomainID); err != nil {
+		return err
+	}
+	for hardwareID, region := range m.GatewayRegions {
+		if !regionRegex.MatchString(region) {
+			return fmt.Errorf("region %s of gateway %s is not a valid region", region, hardwareID)
+		}
+	}
+	for _, protected := range m.ProtectedIdentities {
+		if err := protected.validateIdentityFormat(); err != nil {
+			return err
+		}
+	}
+	if len(m.DeliveryFunctionAddress) == 0 {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(m.DeliveryFunctionAddress); err != nil {
+		return fmt.Errorf("delivery_function_address %s is not a valid host:port address: %v", m.DeliveryFunctionAddress, err)
+	}
+	return nil
+}
+
+// validateIdentityFormat checks that a protected identity is a valid
+// identifier of its type. IMSIs accept any subscriber ID, as IMSI targets.
+func (m *NetworkProbeProtectedIdentity) validateIdentityFormat() error {
+	if m == nil {
+		return errors.New("protected_identities must not be null")
+	}
+	switch m.IdentityType {
+	case NetworkProbeProtectedIdentityIdentityTypeMsisdn:
+		if !msisdnRegex.MatchString(m.Identity) {
+			return fmt.Errorf("protected identity %s is not a valid MSISDN", m.Identity)
+		}
+	case NetworkProbeProtectedIdentityIdentityTypeImei:
+		if !imeiRegex.MatchString(m.Identity) {
+			return fmt.Errorf("protected identity %s is not a valid IMEI", m.Identity)
+		}
+	}
+	return nil
+}
+
+func (m *NetworkProbeDeclarativeConfig) ValidateModel() error {
+	if err := m.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	if m.NetworkConfig != nil {
+		if err := m.NetworkConfig.ValidateModel(); err != nil {
+			return err
+		}
+	}
+	destinationIDs := map[NetworkProbeDestinationID]bool{}
+	for _, destination := range m.Destinations {
+		if destination == nil {
+			return errors.New("destinations must not be null")
+		}
+		if err := destination.ValidateModel(); err != nil {
+			return err
+		}
+		if destinationIDs[destination.DestinationID] {
+			return fmt.Errorf("destination %s is declared more than once", destination.DestinationID)
+		}
+		destinationIDs[destination.DestinationID] = true
+	}
+	return nil
+}
+
+func (m *NetworkProbeBookmark) ValidateModel() error {
 	return m.Validate(strfmt.Default)
 }
+
+func (m *NetworkProbeDebugConfig) ValidateModel() error {
+	if err := m.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	for component := range m.ComponentVerbosity {
+		if !logging.IsComponent(component) {
+			return fmt.Errorf("unknown component %s in component_verbosity, expected one of %s", component, strings.Join(logging.Components, ", "))
+		}
+	}
+	return nil
+}
+
+func (m *NetworkProbeFaultInjection) ValidateModel() error {
+	if err := m.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	if len(m.Destination) == 0 {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(m.Destination); err != nil {
+		return fmt.Errorf("destination %s is not a valid host:port address: %v", m.Destination, err)
+	}
+	return nil
+}
+
+func (m *NetworkProbeKillSwitch) ValidateModel() error {
+	if err := m.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	if len(m.Reason) == 0 {
+		return errors.New("a reason is required to activate the kill switch")
+	}
+	return nil
+}
+
+func (m *NetworkProbeConformanceRequest) ValidateModel() error {
+	if err := m.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	if len(m.Record) == 0 {
+		return errors.New("record is required")
+	}
+	return nil
+}
+
+func (m *NetworkProbeTaskTransition) ValidateModel() error {
+	if err := m.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	// the other transitions follow the warrant, the deletion and the
+	// processing of the task
+	if m.To != NetworkProbeTaskTransitionToSuspended && m.To != NetworkProbeTaskTransitionToActive {
+		return fmt.Errorf("a task can't be moved to %s, only suspended or resumed to active", m.To)
+	}
+	return nil
+}
+
+func (m *NetworkProbeTaskReplay) ValidateModel() error {
+	if err := m.Validate(strfmt.Default); err != nil {
+		return err
+	}
+	if !time.Time(m.End).After(time.Time(m.Start)) {
+		return fmt.Errorf("end %s is not after start %s", m.End, m.Start)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: delivery.proto
+
+package protos
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type DeliveredRecord struct {
+	// sequence identifies the record on its stream, echoed by its acknowledgement
+	Sequence uint64 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// correlation_id of the intercepted session the record belongs to
+	CorrelationId uint64 `protobuf:"varint,2,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	// payload is the ETSI record, a DER encoded HI2 PS-PDU or an X2 PDU
+	Payload              []byte   `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeliveredRecord) Reset()         { *m = DeliveredRecord{} }
+func (m *DeliveredRecord) String() string { return proto.CompactTextString(m) }
+func (*DeliveredRecord) ProtoMessage()    {}
+func (*DeliveredRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bf387bcb4e23d880, []int{0}
+}
+
+func (m *DeliveredRecord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeliveredRecord.Unmarshal(m, b)
+}
+func (m *DeliveredRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeliveredRecord.Marshal(b, m, deterministic)
+}
+func (m *DeliveredRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeliveredRecord.Merge(m, src)
+}
+func (m *DeliveredRecord) XXX_Size() int {
+	return xxx_messageInfo_DeliveredRecord.Size(m)
+}
+func (m *DeliveredRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeliveredRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeliveredRecord proto.InternalMessageInfo
+
+func (m *DeliveredRecord) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *DeliveredRecord) GetCorrelationId() uint64 {
+	if m != nil {
+		return m.CorrelationId
+	}
+	return 0
+}
+
+func (m *DeliveredRecord) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+type RecordAck struct {
+	// sequence of the acknowledged record
+	Sequence uint64 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// error refuses the record, e.g. when it can't be decoded, empty once taken charge of
+	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RecordAck) Reset()         { *m = RecordAck{} }
+func (m *RecordAck) String() string { return proto.CompactTextString(m) }
+func (*RecordAck) ProtoMessage()    {}
+func (*RecordAck) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bf387bcb4e23d880, []int{1}
+}
+
+func (m *RecordAck) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RecordAck.Unmarshal(m, b)
+}
+func (m *RecordAck) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RecordAck.Marshal(b, m, deterministic)
+}
+func (m *RecordAck) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecordAck.Merge(m, src)
+}
+func (m *RecordAck) XXX_Size() int {
+	return xxx_messageInfo_RecordAck.Size(m)
+}
+func (m *RecordAck) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecordAck.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecordAck proto.InternalMessageInfo
+
+func (m *RecordAck) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *RecordAck) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*DeliveredRecord)(nil), "magma.lte.nprobe.DeliveredRecord")
+	proto.RegisterType((*RecordAck)(nil), "magma.lte.nprobe.RecordAck")
+}
+
+func init() { proto.RegisterFile("delivery.proto", fileDescriptor_bf387bcb4e23d880) }
+
+var fileDescriptor_bf387bcb4e23d880 = []byte{
+	// 208 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0xe3, 0xe2, 0x4b, 0x49, 0xcd, 0xc9,
+	0x2c, 0x4b, 0x2d, 0xaa, 0xd4, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x12, 0xc8, 0x4d, 0x4c, 0xcf,
+	0x4d, 0xd4, 0xcb, 0x29, 0x49, 0xd5, 0xcb, 0x03, 0x8a, 0x24, 0xa5, 0x2a, 0xe5, 0x71, 0xf1, 0xbb,
+	0x40, 0xd4, 0xa4, 0xa6, 0x04, 0xa5, 0x26, 0xe7, 0x17, 0xa5, 0x08, 0x49, 0x71, 0x71, 0x14, 0xa7,
+	0x16, 0x96, 0xa6, 0xe6, 0x25, 0xa7, 0x4a, 0x30, 0x2a, 0x30, 0x6a, 0xb0, 0x04, 0xc1, 0xf9, 0x42,
+	0xaa, 0x5c, 0x7c, 0x40, 0x35, 0x45, 0xa9, 0x39, 0x89, 0x25, 0x99, 0xf9, 0x79, 0xf1, 0x99, 0x29,
+	0x12, 0x4c, 0x60, 0x15, 0xbc, 0x48, 0xa2, 0x9e, 0x29, 0x42, 0x12, 0x5c, 0xec, 0x05, 0x89, 0x95,
+	0x39, 0xf9, 0x89, 0x29, 0x12, 0xcc, 0x40, 0x79, 0x9e, 0x20, 0x18, 0x57, 0xc9, 0x96, 0x8b, 0x13,
+	0x62, 0x8d, 0x63, 0x72, 0x36, 0x5e, 0x9b, 0x44, 0xb8, 0x58, 0x53, 0x8b, 0x8a, 0xf2, 0x8b, 0xc0,
+	0x16, 0x70, 0x06, 0x41, 0x38, 0x46, 0x19, 0x5c, 0x7c, 0x10, 0xed, 0x50, 0x47, 0x57, 0x0a, 0x85,
+	0x71, 0xf1, 0x41, 0xd9, 0x10, 0x89, 0x62, 0x21, 0x45, 0x3d, 0x74, 0x5f, 0xea, 0xa1, 0x79, 0x51,
+	0x4a, 0x1a, 0x53, 0x09, 0xdc, 0x55, 0x4a, 0x0c, 0x1a, 0x8c, 0x06, 0x8c, 0x4e, 0x1c, 0x51, 0x6c,
+	0xe0, 0x30, 0x2b, 0x4e, 0x82, 0xd0, 0xc6, 0x00, 0x72, 0x8e, 0xeb, 0x60, 0x4d, 0x01, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// RecordDeliveryClient is the client API for RecordDelivery service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type RecordDeliveryClient interface {
+	// DeliverRecords streams records to the mediation function, which acknowledges
+	// each of them with its sequence once it took charge of it
+	DeliverRecords(ctx context.Context, opts ...grpc.CallOption) (RecordDelivery_DeliverRecordsClient, error)
+}
+
+type recordDeliveryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRecordDeliveryClient(cc grpc.ClientConnInterface) RecordDeliveryClient {
+	return &recordDeliveryClient{cc}
+}
+
+func (c *recordDeliveryClient) DeliverRecords(ctx context.Context, opts ...grpc.CallOption) (RecordDelivery_DeliverRecordsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_RecordDelivery_serviceDesc.Streams[0], "/magma.lte.nprobe.RecordDelivery/DeliverRecords", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &recordDeliveryDeliverRecordsClient{stream}
+	return x, nil
+}
+
+type RecordDelivery_DeliverRecordsClient interface {
+	Send(*DeliveredRecord) error
+	Recv() (*RecordAck, error)
+	grpc.ClientStream
+}
+
+type recordDeliveryDeliverRecordsClient struct {
+	grpc.ClientStream
+}
+
+func (x *recordDeliveryDeliverRecordsClient) Send(m *DeliveredRecord) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *recordDeliveryDeliverRecordsClient) Recv() (*RecordAck, error) {
+	m := new(RecordAck)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RecordDeliveryServer is the server API for RecordDelivery service.
+type RecordDeliveryServer interface {
+	// DeliverRecords streams records to the mediation function, which acknowledges
+	// each of them with its sequence once it took charge of it
+	DeliverRecords(RecordDelivery_DeliverRecordsServer) error
+}
+
+// UnimplementedRecordDeliveryServer can be embedded to have forward compatible implementations.
+type UnimplementedRecordDeliveryServer struct {
+}
+
+func (*UnimplementedRecordDeliveryServer) DeliverRecords(srv RecordDelivery_DeliverRecordsServer) error {
+	return status.Errorf(codes.Unimplemented, "method DeliverRecords not implemented")
+}
+
+func RegisterRecordDeliveryServer(s *grpc.Server, srv RecordDeliveryServer) {
+	s.RegisterService(&_RecordDelivery_serviceDesc, srv)
+}
+
+func _RecordDelivery_DeliverRecords_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RecordDeliveryServer).DeliverRecords(&recordDeliveryDeliverRecordsServer{stream})
+}
+
+type RecordDelivery_DeliverRecordsServer interface {
+	Send(*RecordAck) error
+	Recv() (*DeliveredRecord, error)
+	grpc.ServerStream
+}
+
+type recordDeliveryDeliverRecordsServer struct {
+	grpc.ServerStream
+}
+
+func (x *recordDeliveryDeliverRecordsServer) Send(m *RecordAck) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *recordDeliveryDeliverRecordsServer) Recv() (*DeliveredRecord, error) {
+	m := new(DeliveredRecord)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _RecordDelivery_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "magma.lte.nprobe.RecordDelivery",
+	HandlerType: (*RecordDeliveryServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DeliverRecords",
+			Handler:       _RecordDelivery_DeliverRecords_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "delivery.proto",
+}
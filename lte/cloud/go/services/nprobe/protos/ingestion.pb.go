@@ -0,0 +1,298 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: ingestion.proto
+
+package protos
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type IngestedEvent struct {
+	// stream_name of the event, e.g. mme or sessiond
+	StreamName string `protobuf:"bytes,1,opt,name=stream_name,json=streamName,proto3" json:"stream_name,omitempty"`
+	// event_type of the event, e.g. attach_success
+	EventType string `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	// tag of the event, usually the IMSI of the subscriber
+	Tag string `protobuf:"bytes,3,opt,name=tag,proto3" json:"tag,omitempty"`
+	// timestamp of the event in RFC3339 format
+	Timestamp string `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// value is the JSON encoded data of the event
+	Value                string   `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IngestedEvent) Reset()         { *m = IngestedEvent{} }
+func (m *IngestedEvent) String() string { return proto.CompactTextString(m) }
+func (*IngestedEvent) ProtoMessage()    {}
+func (*IngestedEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73098a93f29a5a93, []int{0}
+}
+
+func (m *IngestedEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IngestedEvent.Unmarshal(m, b)
+}
+func (m *IngestedEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IngestedEvent.Marshal(b, m, deterministic)
+}
+func (m *IngestedEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IngestedEvent.Merge(m, src)
+}
+func (m *IngestedEvent) XXX_Size() int {
+	return xxx_messageInfo_IngestedEvent.Size(m)
+}
+func (m *IngestedEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_IngestedEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IngestedEvent proto.InternalMessageInfo
+
+func (m *IngestedEvent) GetStreamName() string {
+	if m != nil {
+		return m.StreamName
+	}
+	return ""
+}
+
+func (m *IngestedEvent) GetEventType() string {
+	if m != nil {
+		return m.EventType
+	}
+	return ""
+}
+
+func (m *IngestedEvent) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+func (m *IngestedEvent) GetTimestamp() string {
+	if m != nil {
+		return m.Timestamp
+	}
+	return ""
+}
+
+func (m *IngestedEvent) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+type StreamEventsResponse struct {
+	// accepted is the number of events queued for processing
+	Accepted uint64 `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	// ignored is the number of events not relevant to interception
+	Ignored              uint64   `protobuf:"varint,2,opt,name=ignored,proto3" json:"ignored,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StreamEventsResponse) Reset()         { *m = StreamEventsResponse{} }
+func (m *StreamEventsResponse) String() string { return proto.CompactTextString(m) }
+func (*StreamEventsResponse) ProtoMessage()    {}
+func (*StreamEventsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_73098a93f29a5a93, []int{1}
+}
+
+func (m *StreamEventsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamEventsResponse.Unmarshal(m, b)
+}
+func (m *StreamEventsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamEventsResponse.Marshal(b, m, deterministic)
+}
+func (m *StreamEventsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamEventsResponse.Merge(m, src)
+}
+func (m *StreamEventsResponse) XXX_Size() int {
+	return xxx_messageInfo_StreamEventsResponse.Size(m)
+}
+func (m *StreamEventsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamEventsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamEventsResponse proto.InternalMessageInfo
+
+func (m *StreamEventsResponse) GetAccepted() uint64 {
+	if m != nil {
+		return m.Accepted
+	}
+	return 0
+}
+
+func (m *StreamEventsResponse) GetIgnored() uint64 {
+	if m != nil {
+		return m.Ignored
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*IngestedEvent)(nil), "magma.lte.nprobe.IngestedEvent")
+	proto.RegisterType((*StreamEventsResponse)(nil), "magma.lte.nprobe.StreamEventsResponse")
+}
+
+func init() { proto.RegisterFile("ingestion.proto", fileDescriptor_73098a93f29a5a93) }
+
+var fileDescriptor_73098a93f29a5a93 = []byte{
+	// 257 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x90, 0xcd, 0x4a, 0xc3, 0x50,
+	0x10, 0x85, 0x8d, 0x4d, 0xb5, 0x19, 0xff, 0xca, 0xd0, 0xc5, 0xa5, 0x28, 0x95, 0x2c, 0xa4, 0xab,
+	0x2c, 0xf4, 0x0d, 0x04, 0x17, 0x05, 0x71, 0x11, 0x5d, 0xe9, 0xa2, 0x4c, 0x9b, 0x21, 0x04, 0x7a,
+	0x7f, 0xc8, 0x1d, 0x0b, 0x7d, 0x10, 0xdf, 0x57, 0x3a, 0xa1, 0xc5, 0x9f, 0xae, 0xee, 0x3d, 0x67,
+	0x0e, 0x33, 0x87, 0x0f, 0xae, 0x1a, 0x57, 0x73, 0x94, 0xc6, 0xbb, 0x22, 0xb4, 0x5e, 0x3c, 0x0e,
+	0x2d, 0xd5, 0x96, 0x8a, 0x95, 0x70, 0xe1, 0x42, 0xeb, 0x17, 0x9c, 0x7f, 0x25, 0x70, 0x31, 0xd3,
+	0x14, 0x57, 0x4f, 0x6b, 0x76, 0x82, 0x13, 0x38, 0x8b, 0xd2, 0x32, 0xd9, 0xb9, 0x23, 0xcb, 0x26,
+	0xb9, 0x4d, 0xa6, 0x59, 0x09, 0x9d, 0xf5, 0x42, 0x96, 0xf1, 0x06, 0x80, 0xb7, 0xc9, 0xb9, 0x6c,
+	0x02, 0x9b, 0x63, 0x9d, 0x67, 0xea, 0xbc, 0x6d, 0x02, 0xe3, 0x10, 0x7a, 0x42, 0xb5, 0xe9, 0xa9,
+	0xbf, 0xfd, 0xe2, 0x35, 0x64, 0xd2, 0x58, 0x8e, 0x42, 0x36, 0x98, 0xb4, 0xcb, 0xef, 0x0d, 0x1c,
+	0x41, 0x7f, 0x4d, 0xab, 0x4f, 0x36, 0x7d, 0x9d, 0x74, 0x22, 0x7f, 0x86, 0xd1, 0xab, 0x9e, 0xd4,
+	0x52, 0xb1, 0xe4, 0x18, 0xbc, 0x8b, 0x8c, 0x63, 0x18, 0xd0, 0x72, 0xc9, 0x41, 0xb8, 0xd2, 0x6a,
+	0x69, 0xb9, 0xd7, 0x68, 0xe0, 0xb4, 0xa9, 0x9d, 0x6f, 0xb9, 0xd2, 0x56, 0x69, 0xb9, 0x93, 0xf7,
+	0x16, 0x2e, 0x75, 0xcf, 0x6c, 0xc7, 0x03, 0x3f, 0xe0, 0xfc, 0xe7, 0x7e, 0x9c, 0x14, 0x7f, 0xd1,
+	0x14, 0xbf, 0xb0, 0x8c, 0xef, 0xfe, 0x07, 0x0e, 0x15, 0xcc, 0x8f, 0xa6, 0xc9, 0xe3, 0xe0, 0xfd,
+	0x44, 0x79, 0xc7, 0x45, 0xf7, 0x3e, 0x7c, 0x0f, 0x00, 0xfa, 0x72, 0xc0, 0x4d, 0x8a, 0x01, 0x00,
+	0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// EventIngestionClient is the client API for EventIngestion service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type EventIngestionClient interface {
+	// StreamEvents ingests the events streamed by a gateway until the stream
+	// is closed. Receiving is paused while the events of the network are
+	// waiting to be processed.
+	StreamEvents(ctx context.Context, opts ...grpc.CallOption) (EventIngestion_StreamEventsClient, error)
+}
+
+type eventIngestionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEventIngestionClient(cc grpc.ClientConnInterface) EventIngestionClient {
+	return &eventIngestionClient{cc}
+}
+
+func (c *eventIngestionClient) StreamEvents(ctx context.Context, opts ...grpc.CallOption) (EventIngestion_StreamEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_EventIngestion_serviceDesc.Streams[0], "/magma.lte.nprobe.EventIngestion/StreamEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &eventIngestionStreamEventsClient{stream}
+	return x, nil
+}
+
+type EventIngestion_StreamEventsClient interface {
+	Send(*IngestedEvent) error
+	CloseAndRecv() (*StreamEventsResponse, error)
+	grpc.ClientStream
+}
+
+type eventIngestionStreamEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *eventIngestionStreamEventsClient) Send(m *IngestedEvent) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *eventIngestionStreamEventsClient) CloseAndRecv() (*StreamEventsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(StreamEventsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EventIngestionServer is the server API for EventIngestion service.
+type EventIngestionServer interface {
+	// StreamEvents ingests the events streamed by a gateway until the stream
+	// is closed. Receiving is paused while the events of the network are
+	// waiting to be processed.
+	StreamEvents(EventIngestion_StreamEventsServer) error
+}
+
+// UnimplementedEventIngestionServer can be embedded to have forward compatible implementations.
+type UnimplementedEventIngestionServer struct {
+}
+
+func (*UnimplementedEventIngestionServer) StreamEvents(srv EventIngestion_StreamEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+
+func RegisterEventIngestionServer(s *grpc.Server, srv EventIngestionServer) {
+	s.RegisterService(&_EventIngestion_serviceDesc, srv)
+}
+
+func _EventIngestion_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(EventIngestionServer).StreamEvents(&eventIngestionStreamEventsServer{stream})
+}
+
+type EventIngestion_StreamEventsServer interface {
+	SendAndClose(*StreamEventsResponse) error
+	Recv() (*IngestedEvent, error)
+	grpc.ServerStream
+}
+
+type eventIngestionStreamEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *eventIngestionStreamEventsServer) SendAndClose(m *StreamEventsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *eventIngestionStreamEventsServer) Recv() (*IngestedEvent, error) {
+	m := new(IngestedEvent)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _EventIngestion_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "magma.lte.nprobe.EventIngestion",
+	HandlerType: (*EventIngestionServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _EventIngestion_StreamEvents_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "ingestion.proto",
+}
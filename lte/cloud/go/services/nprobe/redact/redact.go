@@ -0,0 +1,200 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package redact keeps the identities of the targets, e.g. IMSIs and
+// MSISDNs, out of the logs and debug endpoints of the service unless it runs
+// with --log-sensitive. Identities are replaced by stable pseudonyms, keyed
+// hashes of their digits, so that the lines about a target can still be
+// correlated without revealing it. Metrics are never labelled by target.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/pkg/errors"
+)
+
+// KeySize is the size of the pseudonym keys
+const KeySize = 32
+
+// pseudonymLen is the number of octets of the keyed hash kept in pseudonyms
+const pseudonymLen = 8
+
+var logSensitive = flag.Bool("log-sensitive", false, "Log the identities of the targets, e.g. IMSIs and MSISDNs, in clear instead of their pseudonyms")
+
+var (
+	// identities matches the identities with a type prefix in free text,
+	// e.g. IMSI001010000000001 or MSISDN 33612345678
+	identities = regexp.MustCompile(`\b(IMSI|MSISDN|IMEI)([ :=]?)(\d{5,16})\b`)
+
+	// sensitiveFields are the fields of the events holding identities
+	sensitiveFields = map[string]bool{
+		"imsi":   true,
+		"imei":   true,
+		"imeisv": true,
+		"msisdn": true,
+	}
+)
+
+var (
+	mutex sync.RWMutex
+	// key is random until set, so that the pseudonyms are only stable for
+	// the lifetime of the process
+	key = newRandomKey()
+)
+
+func newRandomKey() []byte {
+	ret := make([]byte, KeySize)
+	if _, err := rand.Read(ret); err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// LoadKey reads a hex encoded pseudonym key from a file
+func LoadKey(keyFile string) ([]byte, error) {
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid pseudonym key encoding")
+	}
+	if len(ret) != KeySize {
+		return nil, fmt.Errorf("invalid pseudonym key size %d, expected %d", len(ret), KeySize)
+	}
+	return ret, nil
+}
+
+// SetKey sets the key of the pseudonyms, e.g. shared by the replicas so that
+// their pseudonyms match. A nil key draws a random one.
+func SetKey(k []byte) {
+	if k == nil {
+		k = newRandomKey()
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	key = k
+}
+
+// Sensitive returns true if identities are logged in clear
+func Sensitive() bool {
+	return *logSensitive
+}
+
+// pseudonym returns the keyed hash of a value, in hex
+func pseudonym(value []byte) string {
+	mutex.RLock()
+	mac := hmac.New(sha256.New, key)
+	mutex.RUnlock()
+	mac.Write(value)
+	return hex.EncodeToString(mac.Sum(nil)[:pseudonymLen])
+}
+
+// Identity returns the pseudonym of an identity, its type prefix followed by
+// the keyed hash of the rest, e.g. IMSI#5d41402abc4b2a76
+func Identity(id string) string {
+	if Sensitive() || len(id) == 0 {
+		return id
+	}
+	rest := strings.TrimLeft(id, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+	prefix := id[:len(id)-len(rest)]
+	return prefix + "#" + pseudonym([]byte(strings.TrimLeft(rest, " :=+")))
+}
+
+// Text returns a text with the identities carrying their type prefix
+// replaced by their pseudonym
+func Text(s string) string {
+	if Sensitive() {
+		return s
+	}
+	return identities.ReplaceAllStringFunc(s, func(match string) string {
+		parts := identities.FindStringSubmatch(match)
+		return parts[1] + parts[2] + "#" + pseudonym([]byte(parts[3]))
+	})
+}
+
+// Error returns the message of an error with its identities redacted
+func Error(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return Text(err.Error())
+}
+
+// Bytes returns binary data, e.g. an encoded record, in hex or as its
+// pseudonym when redacted
+func Bytes(b []byte) string {
+	if Sensitive() {
+		return hex.EncodeToString(b)
+	}
+	return "#" + pseudonym(b)
+}
+
+// Event returns an event as formatted with %v, the identities of its value
+// being redacted
+func Event(event *eventdM.Event) string {
+	if Sensitive() || event == nil {
+		return fmt.Sprintf("%v", event)
+	}
+	ret := *event
+	if value, ok := event.Value.(map[string]interface{}); ok {
+		redacted := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			if s, ok := v.(string); ok && sensitiveFields[strings.ToLower(k)] {
+				v = Identity(s)
+			}
+			redacted[k] = v
+		}
+		ret.Value = redacted
+	}
+	return Text(fmt.Sprintf("%v", ret))
+}
+
+// Record replaces the identities of a decoded record by their pseudonyms, in
+// its party information and target ID attribute
+func Record(record *encoding.EpsIRIRecord) {
+	if Sensitive() {
+		return
+	}
+	redactBytes := func(b []byte) []byte {
+		if len(b) == 0 {
+			return b
+		}
+		return []byte(Identity(string(b)))
+	}
+	for i := range record.Payload.PartyInformation {
+		identity := &record.Payload.PartyInformation[i].PartyIdentity
+		identity.IMSI = redactBytes(identity.IMSI)
+		identity.IMEI = redactBytes(identity.IMEI)
+		identity.MSISDN = redactBytes(identity.MSISDN)
+	}
+	for i, attr := range record.Header.ConditionalAttributes {
+		if attr.Tag == encoding.AttributeTargetID {
+			record.Header.ConditionalAttributes[i] = encoding.NewAttribute(attr.Tag, redactBytes(attr.Value))
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentity(t *testing.T) {
+	SetKey(bytes.Repeat([]byte{0x2a}, KeySize))
+	defer SetKey(nil)
+
+	imsi := Identity("IMSI001010000000001")
+	assert.True(t, strings.HasPrefix(imsi, "IMSI#"))
+	assert.Len(t, imsi, len("IMSI#")+2*pseudonymLen)
+	assert.NotContains(t, imsi, "001010000000001")
+	// pseudonyms are stable for a key, and only depend on the digits
+	assert.Equal(t, imsi, Identity("IMSI001010000000001"))
+	assert.NotEqual(t, imsi, Identity("IMSI001010000000002"))
+	assert.Equal(t, "#"+strings.TrimPrefix(imsi, "IMSI#"), Identity("001010000000001"))
+	assert.Equal(t, Identity("33612345678"), Identity("+33612345678"))
+	assert.Empty(t, Identity(""))
+
+	SetKey(bytes.Repeat([]byte{0x2b}, KeySize))
+	assert.NotEqual(t, imsi, Identity("IMSI001010000000001"))
+}
+
+func TestText(t *testing.T) {
+	SetKey(bytes.Repeat([]byte{0x2a}, KeySize))
+	defer SetKey(nil)
+
+	assert.Equal(t,
+		"Failed to get session state of "+Identity("IMSI001010000000001")+": not found",
+		Text("Failed to get session state of IMSI001010000000001: not found"),
+	)
+	assert.Equal(t,
+		"MSISDN "+Identity("33612345678")+" is assigned to an empty IMSI",
+		Text("MSISDN 33612345678 is assigned to an empty IMSI"),
+	)
+	assert.Equal(t, "no identity in sequence 12345678", Text("no identity in sequence 12345678"))
+	assert.Equal(t, Text("IMSI001010000000001 refused"), Error(errors.New("IMSI001010000000001 refused")))
+	assert.Equal(t, "<nil>", Error(nil))
+	assert.Equal(t, "#"+pseudonym([]byte{0x01, 0x02}), Bytes([]byte{0x01, 0x02}))
+}
+
+func TestEvent(t *testing.T) {
+	event := &eventdM.Event{
+		EventType: "attach_success",
+		Value:     map[string]interface{}{"imsi": "IMSI001010000000001", "msisdn": "33612345678", "apn": "magma.ipv4"},
+	}
+	redacted := Event(event)
+	assert.NotContains(t, redacted, "001010000000001")
+	assert.NotContains(t, redacted, "33612345678")
+	assert.Contains(t, redacted, Identity("IMSI001010000000001"))
+	assert.Contains(t, redacted, "magma.ipv4")
+	// the event itself is left unchanged
+	assert.Equal(t, "33612345678", event.Value.(map[string]interface{})["msisdn"])
+}
+
+func TestRecord(t *testing.T) {
+	record := &encoding.EpsIRIRecord{}
+	record.Header.ConditionalAttributes = []encoding.Attribute{
+		encoding.NewAttribute(encoding.AttributeTargetID, []byte("IMSI001010000000001")),
+		encoding.NewAttribute(encoding.AttributeNetworkFn, []byte("mme")),
+	}
+	record.Payload.PartyInformation = []encoding.PartyInformation{{}}
+	record.Payload.PartyInformation[0].PartyIdentity.IMSI = []byte("IMSI001010000000001")
+	record.Payload.PartyInformation[0].PartyIdentity.MSISDN = []byte("33612345678")
+
+	Record(record)
+	identity := record.Payload.PartyInformation[0].PartyIdentity
+	assert.Equal(t, Identity("IMSI001010000000001"), string(identity.IMSI))
+	assert.Equal(t, Identity("33612345678"), string(identity.MSISDN))
+	assert.Empty(t, identity.IMEI)
+	assert.Equal(t, encoding.NewAttribute(encoding.AttributeTargetID, []byte(Identity("IMSI001010000000001"))), record.Header.ConditionalAttributes[0])
+	assert.Equal(t, []byte("mme"), record.Header.ConditionalAttributes[1].Value)
+}
+
+func TestLogSensitive(t *testing.T) {
+	assert.NoError(t, flag.Set("log-sensitive", "true"))
+	defer flag.Set("log-sensitive", "false")
+
+	assert.Equal(t, "IMSI001010000000001", Identity("IMSI001010000000001"))
+	assert.Equal(t, "MSISDN 33612345678", Text("MSISDN 33612345678"))
+	assert.Equal(t, "0102", Bytes([]byte{0x01, 0x02}))
+}
+
+func TestLoadKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_redact")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "pseudonym.key")
+
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte(strings.Repeat("2a", KeySize)+"\n"), 0600))
+	key, err := LoadKey(keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{0x2a}, KeySize), key)
+
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte("2a2a"), 0600))
+	_, err = LoadKey(keyFile)
+	assert.EqualError(t, err, "invalid pseudonym key size 2, expected 32")
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	fegprotos "magma/feg/cloud/go/protos"
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/orc8r/lib/go/protos"
+)
+
+type healthServicer struct {
+	fegprotos.UnimplementedServiceHealthServer
+	registry *health.Registry
+}
+
+// NewHealthServicer returns a servicer reporting the aggregated health of
+// the components in registry. Disabling the service is not supported.
+func NewHealthServicer(registry *health.Registry) fegprotos.ServiceHealthServer {
+	return &healthServicer{registry: registry}
+}
+
+// GetHealthStatus returns the health of the service, unhealthy if any of
+// its components is, along with the components failing. A degraded service
+// is healthy, its degraded components being reported in the message.
+func (s *healthServicer) GetHealthStatus(ctx context.Context, req *protos.Void) (*fegprotos.HealthStatus, error) {
+	statuses := s.registry.Check()
+	var failures, degraded []string
+	for _, status := range statuses {
+		if !status.Healthy {
+			failures = append(failures, fmt.Sprintf("%s: %s", status.Component, status.Message))
+		} else if status.Degraded {
+			degraded = append(degraded, fmt.Sprintf("%s: %s", status.Component, status.Message))
+		}
+	}
+	if len(failures) != 0 {
+		return &fegprotos.HealthStatus{
+			Health:        fegprotos.HealthStatus_UNHEALTHY,
+			HealthMessage: strings.Join(failures, "; "),
+		}, nil
+	}
+	if len(degraded) != 0 {
+		return &fegprotos.HealthStatus{
+			Health:        fegprotos.HealthStatus_HEALTHY,
+			HealthMessage: "Degraded: " + strings.Join(degraded, "; "),
+		}, nil
+	}
+	return &fegprotos.HealthStatus{
+		Health:        fegprotos.HealthStatus_HEALTHY,
+		HealthMessage: fmt.Sprintf("All %d components are healthy", len(statuses)),
+	}, nil
+}
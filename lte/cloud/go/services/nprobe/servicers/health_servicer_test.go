@@ -0,0 +1,66 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	fegprotos "magma/feg/cloud/go/protos"
+	"magma/lte/cloud/go/services/nprobe/health"
+	"magma/orc8r/lib/go/protos"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetHealthStatus(t *testing.T) {
+	registry := health.NewRegistry()
+	servicer := NewHealthServicer(registry)
+	registry.Register(health.ComponentStorage, func() error { return nil })
+	registry.Report(health.ComponentManager, nil, 0)
+
+	res, err := servicer.GetHealthStatus(context.Background(), &protos.Void{})
+	assert.NoError(t, err)
+	assert.Equal(t, &fegprotos.HealthStatus{
+		Health:        fegprotos.HealthStatus_HEALTHY,
+		HealthMessage: "All 2 components are healthy",
+	}, res)
+
+	// the degraded components are listed by a healthy service
+	registry.Register(health.ComponentExportQueue, func() error {
+		return health.Degraded(errors.New("export queue holds 750 of 1000 records"))
+	})
+	res, err = servicer.GetHealthStatus(context.Background(), &protos.Void{})
+	assert.NoError(t, err)
+	assert.Equal(t, &fegprotos.HealthStatus{
+		Health:        fegprotos.HealthStatus_HEALTHY,
+		HealthMessage: "Degraded: export_queue: export queue holds 750 of 1000 records",
+	}, res)
+
+	// the failing components are listed
+	registry.Register(health.DestinationComponent("10.10.0.2:6666"), func() error { return errors.New("connection refused") })
+	registry.Report(health.ComponentManager, errors.New("failed to load networks"), 0)
+	res, err = servicer.GetHealthStatus(context.Background(), &protos.Void{})
+	assert.NoError(t, err)
+	assert.Equal(t, &fegprotos.HealthStatus{
+		Health:        fegprotos.HealthStatus_UNHEALTHY,
+		HealthMessage: "destination:10.10.0.2:6666: connection refused; manager: failed to load networks",
+	}, res)
+
+	_, err = servicer.Disable(context.Background(), &fegprotos.DisableMessage{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
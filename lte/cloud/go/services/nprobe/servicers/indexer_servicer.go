@@ -0,0 +1,64 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"context"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/services/nprobe/subscription"
+	"magma/orc8r/cloud/go/services/state/protos"
+	state_types "magma/orc8r/cloud/go/services/state/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type indexerServicer struct {
+	subscription *subscription.Subscription
+}
+
+// NewIndexerServicer returns the state indexer of the nprobe service. It
+// indexes nothing, the subscriber states reported by the gateways being
+// streamed to the subscription which signals their changes to the manager.
+func NewIndexerServicer(subscription *subscription.Subscription) protos.IndexerServer {
+	return &indexerServicer{subscription: subscription}
+}
+
+func (i *indexerServicer) Index(ctx context.Context, req *protos.IndexRequest) (*protos.IndexResponse, error) {
+	states, err := state_types.MakeSerializedStatesByID(req.States)
+	if err != nil {
+		return nil, err
+	}
+	reported := map[string][]byte{}
+	for id, st := range states.Filter(lte.SubscriberStateType) {
+		reported[id.DeviceID] = st.SerializedReportedState
+	}
+	if changed := i.subscription.Report(req.NetworkId, reported, time.Now()); changed != 0 {
+		logger.V(2).Infof("%d subscriber states of network %s changed", changed, req.NetworkId)
+	}
+	return &protos.IndexResponse{}, nil
+}
+
+func (i *indexerServicer) PrepareReindex(ctx context.Context, req *protos.PrepareReindexRequest) (*protos.PrepareReindexResponse, error) {
+	return &protos.PrepareReindexResponse{}, nil
+}
+
+func (i *indexerServicer) CompleteReindex(ctx context.Context, req *protos.CompleteReindexRequest) (*protos.CompleteReindexResponse, error) {
+	if req.FromVersion == 0 && req.ToVersion == 1 {
+		return &protos.CompleteReindexResponse{}, nil
+	}
+	return nil, status.Errorf(codes.InvalidArgument, "unsupported from/to for CompleteReindex: %v to %v", req.FromVersion, req.ToVersion)
+}
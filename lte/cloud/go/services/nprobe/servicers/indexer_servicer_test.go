@@ -0,0 +1,65 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"context"
+	"testing"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/services/nprobe/subscription"
+	"magma/orc8r/cloud/go/services/state/protos"
+	state_types "magma/orc8r/cloud/go/services/state/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexSubscriberStates(t *testing.T) {
+	stateChanges := subscription.NewSubscription()
+	stateChanges.SetEnabled(true)
+	servicer := NewIndexerServicer(stateChanges)
+	index := func(states state_types.SerializedStatesByID) {
+		pStates, err := state_types.MakeProtoStates(states)
+		assert.NoError(t, err)
+		_, err = servicer.Index(context.Background(), &protos.IndexRequest{NetworkId: "n1", States: pStates})
+		assert.NoError(t, err)
+	}
+	isSignaled := func() bool {
+		select {
+		case <-stateChanges.Changed():
+			return true
+		default:
+			return false
+		}
+	}
+
+	imsi1 := state_types.ID{Type: lte.SubscriberStateType, DeviceID: "IMSI001010000000001"}
+	enb := state_types.ID{Type: lte.EnodebStateType, DeviceID: "enb1"}
+	index(state_types.SerializedStatesByID{
+		imsi1: {SerializedReportedState: []byte(`{"sessions":1}`), ReporterID: "hw1", TimeMs: 1000},
+	})
+	assert.True(t, isSignaled())
+
+	// states reported again unchanged and other types of states are ignored
+	index(state_types.SerializedStatesByID{
+		imsi1: {SerializedReportedState: []byte(`{"sessions":1}`), ReporterID: "hw1", TimeMs: 2000},
+		enb:   {SerializedReportedState: []byte(`{"enb_configured":true}`), ReporterID: "hw1", TimeMs: 2000},
+	})
+	assert.False(t, isSignaled())
+
+	index(state_types.SerializedStatesByID{
+		imsi1: {SerializedReportedState: []byte(`{"sessions":0}`), ReporterID: "hw1", TimeMs: 3000},
+	})
+	assert.True(t, isSignaled())
+}
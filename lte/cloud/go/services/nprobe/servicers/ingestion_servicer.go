@@ -0,0 +1,130 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/ingest"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	nprobe_protos "magma/lte/cloud/go/services/nprobe/protos"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/lte/cloud/go/services/nprobe/tracing"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+	"magma/orc8r/lib/go/protos"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ingestionServicer struct {
+	buffer *ingest.Buffer
+}
+
+// NewIngestionServicer returns a servicer queuing the events streamed by
+// gateways in buffer
+func NewIngestionServicer(buffer *ingest.Buffer) nprobe_protos.EventIngestionServer {
+	return &ingestionServicer{buffer: buffer}
+}
+
+// StreamEvents queues the intercepted events streamed by a registered
+// gateway for the network of the gateway. The stream isn't read while the
+// buffer of the network is full so that the gateway is slowed down by flow
+// control instead of events being dropped. The stream is traced as a child
+// of the span of the gateway carried in its traceparent metadata, if any.
+func (s *ingestionServicer) StreamEvents(stream nprobe_protos.EventIngestion_StreamEventsServer) (err error) {
+	gateway := protos.GetClientGateway(stream.Context())
+	if gateway == nil {
+		return status.Errorf(codes.PermissionDenied, "missing gateway identity")
+	}
+	if !gateway.Registered() {
+		return status.Errorf(codes.PermissionDenied, "gateway is not registered")
+	}
+	networkID := gateway.NetworkId
+
+	res := &nprobe_protos.StreamEventsResponse{}
+	_, span := tracing.Start(
+		tracing.ExtractIncoming(stream.Context()),
+		tracing.SpanIngest,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(tracing.NetworkID.String(networkID), tracing.GatewayID.String(gateway.LogicalId)),
+	)
+	defer func() {
+		span.SetAttributes(tracing.Events.Int64(int64(res.Accepted)))
+		tracing.End(span, err)
+	}()
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(res)
+		}
+		if err != nil {
+			return err
+		}
+
+		event, err := toEvent(in, gateway.HardwareId)
+		if err != nil {
+			logger.Warningf("Ignoring event streamed by gateway %s of network %s: %s", gateway.LogicalId, networkID, redact.Error(err))
+			res.Ignored++
+			continue
+		}
+		if event == nil {
+			res.Ignored++
+			continue
+		}
+		err = s.buffer.Put(stream.Context(), networkID, *event)
+		if err != nil {
+			return status.Errorf(codes.Canceled, "stream closed while waiting to queue events: %v", err)
+		}
+		metrics.EventsIngested.WithLabelValues(networkID).Inc()
+		res.Accepted++
+	}
+}
+
+// toEvent converts a streamed event into an eventd event. A nil event is
+// returned for the events which are not intercepted.
+func toEvent(in *nprobe_protos.IngestedEvent, hardwareID string) (*eventdM.Event, error) {
+	if !contains(nprobe.GetESStreams(), in.StreamName) || !contains(nprobe.GetESEventTypes(), in.EventType) {
+		return nil, nil
+	}
+	if _, err := time.Parse(time.RFC3339, in.Timestamp); err != nil {
+		return nil, fmt.Errorf("invalid timestamp %q", in.Timestamp)
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal([]byte(in.Value), &value); err != nil {
+		return nil, fmt.Errorf("invalid value of %s event: %v", in.EventType, err)
+	}
+	return &eventdM.Event{
+		StreamName: in.StreamName,
+		EventType:  in.EventType,
+		HardwareID: hardwareID,
+		Tag:        in.Tag,
+		Timestamp:  in.Timestamp,
+		Value:      value,
+	}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
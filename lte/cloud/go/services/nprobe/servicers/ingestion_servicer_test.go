@@ -0,0 +1,122 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/ingest"
+	nprobe_protos "magma/lte/cloud/go/services/nprobe/protos"
+	"magma/lte/cloud/go/services/nprobe/tracing"
+	"magma/orc8r/lib/go/protos"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/sdk/export/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type mockEventStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events []*nprobe_protos.IngestedEvent
+	res    *nprobe_protos.StreamEventsResponse
+}
+
+func (s *mockEventStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mockEventStream) Recv() (*nprobe_protos.IngestedEvent, error) {
+	if len(s.events) == 0 {
+		return nil, io.EOF
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+func (s *mockEventStream) SendAndClose(res *nprobe_protos.StreamEventsResponse) error {
+	s.res = res
+	return nil
+}
+
+func TestStreamEvents(t *testing.T) {
+	buffer := ingest.NewBuffer(10)
+	servicer := NewIngestionServicer(buffer)
+	events := []*nprobe_protos.IngestedEvent{
+		{
+			StreamName: "mme",
+			EventType:  "attach_success",
+			Tag:        "IMSI001010000000001",
+			Timestamp:  "2021-02-18T10:00:01Z",
+			Value:      `{"imsi":"IMSI001010000000001"}`,
+		},
+		// not intercepted
+		{StreamName: "magmad", EventType: "restarted_services", Timestamp: "2021-02-18T10:00:02Z", Value: "{}"},
+		// malformed
+		{StreamName: "mme", EventType: "detach_success", Timestamp: "yesterday", Value: "{}"},
+		{StreamName: "mme", EventType: "detach_success", Timestamp: "2021-02-18T10:00:03Z", Value: "imsi"},
+	}
+
+	// gateways must be registered
+	stream := &mockEventStream{ctx: context.Background(), events: events}
+	err := servicer.StreamEvents(stream)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	unregistered := protos.NewGatewayIdentity("hw1", "", "").NewContextWithIdentity(context.Background())
+	stream = &mockEventStream{ctx: unregistered, events: events}
+	err = servicer.StreamEvents(stream)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// events are queued for the network of the gateway
+	ctx := protos.NewGatewayIdentity("hw1", "n1", "g1").NewContextWithIdentity(context.Background())
+	stream = &mockEventStream{ctx: ctx, events: events}
+	err = servicer.StreamEvents(stream)
+	assert.NoError(t, err)
+	assert.Equal(t, &nprobe_protos.StreamEventsResponse{Accepted: 1, Ignored: 3}, stream.res)
+
+	queued := buffer.Drain("n1")
+	assert.Len(t, queued, 1)
+	assert.Equal(t, "hw1", queued[0].HardwareID)
+	assert.Equal(t, "attach_success", queued[0].EventType)
+	assert.Equal(t, map[string]interface{}{"imsi": "IMSI001010000000001"}, queued[0].Value)
+}
+
+func TestStreamEventsTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	defer tracing.SetExporter(exporter, 1)()
+	servicer := NewIngestionServicer(ingest.NewBuffer(10))
+
+	// the stream continues the trace of the gateway
+	ctx := protos.NewGatewayIdentity("hw1", "n1", "g1").NewContextWithIdentity(context.Background())
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+	stream := &mockEventStream{ctx: ctx, events: []*nprobe_protos.IngestedEvent{
+		{StreamName: "mme", EventType: "attach_success", Timestamp: "2021-02-18T10:00:01Z", Value: "{}"},
+	}}
+	assert.NoError(t, servicer.StreamEvents(stream))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, tracing.SpanIngest, spans[0].Name)
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].ParentSpanID.String())
+	assert.Contains(t, spans[0].Attributes, tracing.NetworkID.String("n1"))
+	assert.Contains(t, spans[0].Attributes, tracing.Events.Int64(1))
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"context"
+	"sort"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	nprobe_protos "magma/lte/cloud/go/services/nprobe/protos"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/orc8r/cloud/go/services/configurator"
+	merrors "magma/orc8r/lib/go/errors"
+	"magma/orc8r/lib/go/protos"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type modelsServicer struct {
+	storage storage.NProbeStorage
+}
+
+// NewModelsServicer returns a servicer serving the tasks, destinations and
+// task statuses as typed models
+func NewModelsServicer(storage storage.NProbeStorage) nprobe_protos.NetworkProbeModelsServer {
+	return &modelsServicer{storage: storage}
+}
+
+func (s *modelsServicer) ListTasks(ctx context.Context, req *nprobe_protos.NetworkRequest) (*nprobe_protos.TaskList, error) {
+	networkID, err := getModelsNetworkID(ctx, req.NetworkId)
+	if err != nil {
+		return nil, err
+	}
+	ents, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeTaskEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load tasks: %v", err)
+	}
+
+	ret := &nprobe_protos.TaskList{}
+	for _, ent := range ents {
+		task := (&models.NetworkProbeTask{}).FromBackendModels(ent)
+		ret.Tasks = append(ret.Tasks, models.ToProtoNProbeTask(task))
+	}
+	sort.Slice(ret.Tasks, func(i, j int) bool { return ret.Tasks[i].TaskId < ret.Tasks[j].TaskId })
+	return ret, nil
+}
+
+func (s *modelsServicer) GetTask(ctx context.Context, req *nprobe_protos.TaskRequest) (*nprobe_protos.Task, error) {
+	networkID, err := getModelsNetworkID(ctx, req.NetworkId)
+	if err != nil {
+		return nil, err
+	}
+	ent, err := configurator.LoadEntity(
+		networkID, lte.NetworkProbeTaskEntityType, req.TaskId,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if errors.Cause(err) == merrors.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "task %s not found", req.TaskId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load task: %v", err)
+	}
+	return models.ToProtoNProbeTask((&models.NetworkProbeTask{}).FromBackendModels(ent)), nil
+}
+
+func (s *modelsServicer) GetTaskStatus(ctx context.Context, req *nprobe_protos.TaskRequest) (*nprobe_protos.TaskStatus, error) {
+	networkID, err := getModelsNetworkID(ctx, req.NetworkId)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.storage.GetNProbeData(networkID, req.TaskId)
+	if errors.Cause(err) == merrors.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "no status for task %s", req.TaskId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load task status: %v", err)
+	}
+	return models.ToProtoNProbeTaskStatus(req.TaskId, data), nil
+}
+
+func (s *modelsServicer) ListDestinations(ctx context.Context, req *nprobe_protos.NetworkRequest) (*nprobe_protos.DestinationList, error) {
+	networkID, err := getModelsNetworkID(ctx, req.NetworkId)
+	if err != nil {
+		return nil, err
+	}
+	ents, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeDestinationEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load destinations: %v", err)
+	}
+
+	ret := &nprobe_protos.DestinationList{}
+	for _, ent := range ents {
+		destination := (&models.NetworkProbeDestination{}).FromBackendModels(ent)
+		ret.Destinations = append(ret.Destinations, models.ToProtoNProbeDestination(destination))
+	}
+	sort.Slice(ret.Destinations, func(i, j int) bool {
+		return ret.Destinations[i].DestinationId < ret.Destinations[j].DestinationId
+	})
+	return ret, nil
+}
+
+// getModelsNetworkID returns the network whose models are requested. Gateways
+// must be registered and are only served the models of their own network,
+// which is the default network of their requests.
+func getModelsNetworkID(ctx context.Context, networkID string) (string, error) {
+	gateway := protos.GetClientGateway(ctx)
+	if gateway == nil {
+		if len(networkID) == 0 {
+			return "", status.Errorf(codes.InvalidArgument, "missing network ID")
+		}
+		return networkID, nil
+	}
+	if !gateway.Registered() {
+		return "", status.Errorf(codes.PermissionDenied, "gateway is not registered")
+	}
+	if len(networkID) != 0 && networkID != gateway.NetworkId {
+		return "", status.Errorf(codes.PermissionDenied, "gateway can't access network %s", networkID)
+	}
+	return gateway.NetworkId, nil
+}
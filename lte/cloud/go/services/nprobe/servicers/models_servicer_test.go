@@ -0,0 +1,148 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	nprobe_protos "magma/lte/cloud/go/services/nprobe/protos"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/orc8r/cloud/go/services/configurator"
+	configuratorTestInit "magma/orc8r/cloud/go/services/configurator/test_init"
+	"magma/orc8r/cloud/go/test_utils"
+	"magma/orc8r/lib/go/protos"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNetworkProbeModels(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	for _, networkID := range []string{"n1", "n2"} {
+		err := configurator.CreateNetwork(configurator.Network{ID: networkID}, serdes.Network)
+		assert.NoError(t, err)
+	}
+	timestamp := time.Date(2021, 3, 6, 3, 6, 40, 0, time.UTC)
+	duration := int64(300)
+	_, err := configurator.CreateEntities("n1", []configurator.NetworkEntity{
+		{
+			Type: lte.NetworkProbeTaskEntityType,
+			Key:  "task2",
+			Config: &models.NetworkProbeTaskDetails{
+				TargetID:     "IMSI001010000000002",
+				TargetType:   "imsi",
+				DeliveryType: "events_only",
+				Timestamp:    strfmt.DateTime(timestamp),
+			},
+		},
+		{
+			Type: lte.NetworkProbeTaskEntityType,
+			Key:  "task1",
+			Config: &models.NetworkProbeTaskDetails{
+				TargetID:      "IMSI001010000000001",
+				TargetType:    "imsi",
+				DeliveryType:  "all",
+				CorrelationID: 42,
+				Duration:      &duration,
+				Timestamp:     strfmt.DateTime(timestamp),
+			},
+		},
+		{
+			Type: lte.NetworkProbeDestinationEntityType,
+			Key:  "df1",
+			Config: &models.NetworkProbeDestinationDetails{
+				DeliveryAddress: "10.10.0.2:6666",
+				DeliveryType:    "all",
+				AlpnProtocols:   []string{"li-x2"},
+				RateLimit:       100,
+			},
+		},
+	}, serdes.Entity)
+	assert.NoError(t, err)
+
+	fact := test_utils.NewSQLBlobstore(t, "nprobe_models_servicer_test_blobstore")
+	store := storage.NewNProbeBlobstore(fact)
+	err = store.StoreNProbeData("n1", "task1", models.NetworkProbeData{
+		TargetID:        "IMSI001010000000001",
+		LastExported:    strfmt.DateTime(timestamp),
+		SequenceNumber:  7,
+		RecordsExported: 7,
+		OpenSessions:    []string{"IMSI001010000000001-919642"},
+	})
+	assert.NoError(t, err)
+	servicer := NewModelsServicer(store)
+
+	// the network is required unless requested by a gateway
+	_, err = servicer.ListTasks(context.Background(), &nprobe_protos.NetworkRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	tasks, err := servicer.ListTasks(context.Background(), &nprobe_protos.NetworkRequest{NetworkId: "n1"})
+	assert.NoError(t, err)
+	expected := &nprobe_protos.Task{
+		TaskId:        "task1",
+		TargetId:      "IMSI001010000000001",
+		TargetType:    "imsi",
+		DeliveryType:  "all",
+		CorrelationId: 42,
+		Timestamp:     "2021-03-06T03:06:40Z",
+		Duration:      300,
+	}
+	assert.Len(t, tasks.Tasks, 2)
+	assert.Equal(t, expected, tasks.Tasks[0])
+	assert.Equal(t, "task2", tasks.Tasks[1].TaskId)
+
+	// gateways are served the models of their network
+	ctx := protos.NewGatewayIdentity("hw1", "n1", "g1").NewContextWithIdentity(context.Background())
+	task, err := servicer.GetTask(ctx, &nprobe_protos.TaskRequest{TaskId: "task1"})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, task)
+	_, err = servicer.GetTask(ctx, &nprobe_protos.TaskRequest{NetworkId: "n2", TaskId: "task1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = servicer.GetTask(ctx, &nprobe_protos.TaskRequest{TaskId: "task3"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	unregistered := protos.NewGatewayIdentity("hw2", "", "").NewContextWithIdentity(context.Background())
+	_, err = servicer.ListTasks(unregistered, &nprobe_protos.NetworkRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	taskStatus, err := servicer.GetTaskStatus(ctx, &nprobe_protos.TaskRequest{TaskId: "task1"})
+	assert.NoError(t, err)
+	assert.Equal(t, &nprobe_protos.TaskStatus{
+		TaskId:          "task1",
+		TargetId:        "IMSI001010000000001",
+		LastExported:    "2021-03-06T03:06:40Z",
+		SequenceNumber:  7,
+		RecordsExported: 7,
+		OpenSessions:    []string{"IMSI001010000000001-919642"},
+		Xid:             "task1",
+	}, taskStatus)
+	_, err = servicer.GetTaskStatus(ctx, &nprobe_protos.TaskRequest{TaskId: "task2"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	destinations, err := servicer.ListDestinations(ctx, &nprobe_protos.NetworkRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*nprobe_protos.Destination{{
+		DestinationId:   "df1",
+		DeliveryAddress: "10.10.0.2:6666",
+		DeliveryType:    "all",
+		AlpnProtocols:   []string{"li-x2"},
+		RateLimit:       100,
+	}}, destinations.Destinations)
+}
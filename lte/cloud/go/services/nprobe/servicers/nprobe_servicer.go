@@ -0,0 +1,274 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	nprobe_protos "magma/lte/cloud/go/services/nprobe/protos"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/lte/cloud/go/services/nprobe/tasks"
+	"magma/orc8r/cloud/go/services/configurator"
+	merrors "magma/orc8r/lib/go/errors"
+	"magma/orc8r/lib/go/protos"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var logger = logging.New(logging.ComponentServicers)
+
+const (
+	// nprobeServicePrefix prefixes the methods of NProbeService in the audit log
+	nprobeServicePrefix = "/magma.lte.nprobe.NProbeService/"
+
+	// unknownActor identifies callers which presented no identity
+	unknownActor = "unknown"
+)
+
+// defaultDrainTimeout is the time a drain waits for the queues of the
+// exporters to empty when the request sets none
+const defaultDrainTimeout = 30 * time.Second
+
+// Connections inspects and re-establishes the delivery connections of the
+// exporters. The exporters of a destination, or all of them when empty, are
+// reconnected or drained.
+type Connections interface {
+	GetConnections() []*models.NetworkProbeConnection
+	ReconnectExporters(destination string) ([]*models.NetworkProbeConnection, error)
+	DrainExporters(ctx context.Context, destination string) ([]*models.NetworkProbeConnection, error)
+}
+
+type nprobeServicer struct {
+	storage     storage.NProbeStorage
+	models      nprobe_protos.NetworkProbeModelsServer
+	connections Connections
+}
+
+// NewNProbeServicer returns a servicer managing the tasks of the networks and
+// the delivery connections of the exporters on behalf of orc8r services and
+// automation
+func NewNProbeServicer(storage storage.NProbeStorage, connections Connections) nprobe_protos.NProbeServiceServer {
+	return &nprobeServicer{storage: storage, models: NewModelsServicer(storage), connections: connections}
+}
+
+func (s *nprobeServicer) CreateTask(ctx context.Context, req *nprobe_protos.CreateTaskRequest) (ret *nprobe_protos.Task, err error) {
+	defer s.audit(ctx, "CreateTask", req.NetworkId, req, req.Reason, &err)
+	if err := checkNProbeCaller(ctx, req.NetworkId); err != nil {
+		return nil, err
+	}
+	if req.Task == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "missing task")
+	}
+
+	task, err := models.FromProtoNProbeTask(req.Task)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid task: %v", err)
+	}
+	if err := task.ValidateModel(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid task: %v", err)
+	}
+	err = tasks.Create(s.storage, req.NetworkId, task, getActor(ctx))
+	if err == tasks.ErrTaskExists {
+		return nil, status.Errorf(codes.AlreadyExists, "task %s already exists", task.TaskID)
+	}
+	if err == tasks.ErrNotTestPLMN || errors.Cause(err) == tasks.ErrDomainIDConflict {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid task: %v", err)
+	}
+	if err == tasks.ErrProtectedTarget {
+		return nil, status.Errorf(codes.PermissionDenied, "task %s: %v", task.TaskID, err)
+	}
+	if err == tasks.ErrTaskQuotaExceeded {
+		return nil, status.Errorf(codes.ResourceExhausted, "task quota of network %s exceeded", req.NetworkId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create task: %v", err)
+	}
+	return models.ToProtoNProbeTask(task), nil
+}
+
+func (s *nprobeServicer) DeleteTask(ctx context.Context, req *nprobe_protos.DeleteTaskRequest) (ret *nprobe_protos.DeleteTaskResponse, err error) {
+	defer s.audit(ctx, "DeleteTask", req.NetworkId, req, req.Reason, &err)
+	if err := checkNProbeCaller(ctx, req.NetworkId); err != nil {
+		return nil, err
+	}
+
+	actor := unknownActor
+	if id := protos.GetClientIdentity(ctx); id != nil {
+		actor = id.HashString()
+	}
+	_, err = tasks.RequestDeletion(s.storage, req.NetworkId, req.TaskId, actor)
+	if err == merrors.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "task %s not found", req.TaskId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete task: %v", err)
+	}
+	return &nprobe_protos.DeleteTaskResponse{}, nil
+}
+
+func (s *nprobeServicer) ListTasks(ctx context.Context, req *nprobe_protos.NetworkRequest) (*nprobe_protos.TaskList, error) {
+	if err := checkNProbeCaller(ctx, req.NetworkId); err != nil {
+		return nil, err
+	}
+	return s.models.ListTasks(ctx, req)
+}
+
+func (s *nprobeServicer) GetTaskState(ctx context.Context, req *nprobe_protos.TaskRequest) (*nprobe_protos.TaskStatus, error) {
+	if err := checkNProbeCaller(ctx, req.NetworkId); err != nil {
+		return nil, err
+	}
+	_, err := configurator.LoadEntity(
+		req.NetworkId, lte.NetworkProbeTaskEntityType, req.TaskId,
+		configurator.EntityLoadCriteria{},
+		serdes.Entity,
+	)
+	if errors.Cause(err) == merrors.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "task %s not found", req.TaskId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load task: %v", err)
+	}
+	return s.models.GetTaskStatus(ctx, req)
+}
+
+func (s *nprobeServicer) ListConnections(ctx context.Context, req *nprobe_protos.ConnectionRequest) (*nprobe_protos.ConnectionList, error) {
+	if protos.GetClientGateway(ctx) != nil {
+		return nil, status.Errorf(codes.PermissionDenied, "gateways can't manage connections")
+	}
+	var connections []*models.NetworkProbeConnection
+	for _, conn := range s.connections.GetConnections() {
+		if len(req.Destination) == 0 || conn.Destination == req.Destination {
+			connections = append(connections, conn)
+		}
+	}
+	return models.ToProtoNProbeConnections(connections), nil
+}
+
+func (s *nprobeServicer) ReconnectExporters(ctx context.Context, req *nprobe_protos.ConnectionRequest) (*nprobe_protos.ConnectionList, error) {
+	if protos.GetClientGateway(ctx) != nil {
+		return nil, status.Errorf(codes.PermissionDenied, "gateways can't manage connections")
+	}
+	logger.Infof("Reconnecting exporters on request of %s, destination: %q", getActor(ctx), req.Destination)
+	connections, err := s.connections.ReconnectExporters(req.Destination)
+	if err != nil {
+		return nil, toConnectionStatus(err)
+	}
+	return models.ToProtoNProbeConnections(connections), nil
+}
+
+func (s *nprobeServicer) DrainExporters(ctx context.Context, req *nprobe_protos.ConnectionRequest) (*nprobe_protos.ConnectionList, error) {
+	if protos.GetClientGateway(ctx) != nil {
+		return nil, status.Errorf(codes.PermissionDenied, "gateways can't manage connections")
+	}
+	timeout := defaultDrainTimeout
+	if req.TimeoutSecs != 0 {
+		timeout = time.Duration(req.TimeoutSecs) * time.Second
+	}
+	logger.Infof("Draining exporters on request of %s, destination: %q", getActor(ctx), req.Destination)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	connections, err := s.connections.DrainExporters(ctx, req.Destination)
+	if err != nil {
+		return nil, toConnectionStatus(err)
+	}
+	return models.ToProtoNProbeConnections(connections), nil
+}
+
+func (s *nprobeServicer) GetSyncState(ctx context.Context, req *nprobe_protos.SyncStateRequest) (*nprobe_protos.SyncState, error) {
+	if err := checkNProbeCaller(ctx, req.NetworkId); err != nil {
+		return nil, err
+	}
+	state, err := tasks.GetSyncState(s.storage, req.NetworkId, req.TaskIds, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load sync state: %v", err)
+	}
+	return models.ToProtoNProbeSyncState(state), nil
+}
+
+// toConnectionStatus returns the status of a failed connection request
+func toConnectionStatus(err error) error {
+	switch errors.Cause(err) {
+	case exporter.ErrUnknownConnection:
+		return status.Error(codes.NotFound, err.Error())
+	case exporter.ErrDrainTimeout:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// getActor identifies the caller in the logs and the audit log
+func getActor(ctx context.Context) string {
+	if id := protos.GetClientIdentity(ctx); id != nil {
+		return id.HashString()
+	}
+	return unknownActor
+}
+
+// checkNProbeCaller refuses the calls made by gateways, which only read the
+// models of their network, and the calls missing their network
+func checkNProbeCaller(ctx context.Context, networkID string) error {
+	if protos.GetClientGateway(ctx) != nil {
+		return status.Errorf(codes.PermissionDenied, "gateways can't manage tasks")
+	}
+	if len(networkID) == 0 {
+		return status.Errorf(codes.InvalidArgument, "missing network ID")
+	}
+	return nil
+}
+
+// audit records a call changing the tasks of a network in its audit log, the
+// same way the REST API records its requests
+func (s *nprobeServicer) audit(ctx context.Context, method, networkID string, req proto.Message, reason string, err *error) {
+	if len(networkID) == 0 {
+		return
+	}
+	actor := getActor(ctx)
+	entry := models.NetworkProbeAuditEntry{
+		Action:    models.NetworkProbeAuditEntryActionGrpcRequest,
+		Actor:     actor,
+		Path:      nprobeServicePrefix + method,
+		BodyHash:  hashRequest(req),
+		Reason:    reason,
+		Timestamp: strfmt.DateTime(time.Now()),
+	}
+	if *err != nil {
+		entry.Error = status.Convert(*err).Message()
+	}
+	// the call was already served, it is only left unaudited
+	if aerr := s.storage.StoreAuditEntry(networkID, entry); aerr != nil {
+		logger.Errorf("Failed to audit %s by %s: %v", entry.Path, actor, aerr)
+	}
+}
+
+// hashRequest returns the hex encoded SHA-256 hash of a request
+func hashRequest(req proto.Message) string {
+	body, err := proto.Marshal(req)
+	if err != nil || len(body) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"context"
+	"testing"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	nprobe_protos "magma/lte/cloud/go/services/nprobe/protos"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/lte/cloud/go/services/nprobe/tasks"
+	"magma/orc8r/cloud/go/services/configurator"
+	configuratorTestInit "magma/orc8r/cloud/go/services/configurator/test_init"
+	"magma/orc8r/cloud/go/test_utils"
+	"magma/orc8r/lib/go/protos"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNProbeService(t *testing.T) {
+	configuratorTestInit.StartTestService(t)
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+
+	fact := test_utils.NewSQLBlobstore(t, "nprobe_servicer_test_blobstore")
+	store := storage.NewNProbeBlobstore(fact)
+	servicer := NewNProbeServicer(store, nil)
+	ctx := protos.NewOperatorIdentity("admin").NewContextWithIdentity(context.Background())
+
+	task := &nprobe_protos.Task{
+		TaskId:                       "task1",
+		TargetId:                     "IMSI001010000000001",
+		TargetType:                   "imsi",
+		DeliveryType:                 "all",
+		CorrelationId:                42,
+		Duration:                     300,
+		EndTime:                      "2030-01-01T00:00:00Z",
+		Roaming:                      "exclude",
+		StatisticsReportIntervalSecs: 86400,
+	}
+	created, err := servicer.CreateTask(ctx, &nprobe_protos.CreateTaskRequest{NetworkId: "n1", Task: task, Reason: "warrant 1"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, created.Timestamp)
+	created.Timestamp = ""
+	assert.True(t, proto.Equal(task, created))
+
+	ent, err := configurator.LoadEntity(
+		"n1", lte.NetworkProbeTaskEntityType, "task1",
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(42), ent.Config.(*models.NetworkProbeTaskDetails).CorrelationID)
+
+	_, err = servicer.CreateTask(ctx, &nprobe_protos.CreateTaskRequest{NetworkId: "n1", Task: task})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	invalid := &nprobe_protos.Task{TaskId: "task2", TargetId: "IMSI001010000000002", TargetType: "ip"}
+	_, err = servicer.CreateTask(ctx, &nprobe_protos.CreateTaskRequest{NetworkId: "n1", Task: invalid})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	invalid = &nprobe_protos.Task{TaskId: "task2", TargetId: "IMSI001010000000002", TargetType: "imsi", DeliveryType: "all", StartTime: "tomorrow"}
+	_, err = servicer.CreateTask(ctx, &nprobe_protos.CreateTaskRequest{NetworkId: "n1", Task: invalid})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = servicer.CreateTask(ctx, &nprobe_protos.CreateTaskRequest{Task: task})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// gateways only read the models of their network
+	gwCtx := protos.NewGatewayIdentity("hw1", "n1", "g1").NewContextWithIdentity(context.Background())
+	_, err = servicer.ListTasks(gwCtx, &nprobe_protos.NetworkRequest{NetworkId: "n1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	taskList, err := servicer.ListTasks(ctx, &nprobe_protos.NetworkRequest{NetworkId: "n1"})
+	assert.NoError(t, err)
+	assert.Len(t, taskList.Tasks, 1)
+	taskStatus, err := servicer.GetTaskState(ctx, &nprobe_protos.TaskRequest{NetworkId: "n1", TaskId: "task1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "IMSI001010000000001", taskStatus.TargetId)
+	assert.Equal(t, "task1", taskStatus.Xid)
+
+	// the task is only deleted by the manager once its interception is ended
+	_, err = servicer.DeleteTask(ctx, &nprobe_protos.DeleteTaskRequest{NetworkId: "n1", TaskId: "task1", Reason: "warrant 1 revoked"})
+	assert.NoError(t, err)
+	deletion, err := store.GetTaskDeletion("n1", "task1")
+	assert.NoError(t, err)
+	assert.True(t, models.IsTaskDeletionPending(deletion))
+	assert.Equal(t, protos.NewOperatorIdentity("admin").HashString(), deletion.RequestedBy)
+	_, err = servicer.GetTaskState(ctx, &nprobe_protos.TaskRequest{NetworkId: "n1", TaskId: "task1"})
+	assert.NoError(t, err)
+	syncState, err := servicer.GetSyncState(ctx, &nprobe_protos.SyncStateRequest{NetworkId: "n1", TaskIds: []string{"task1", "task2"}})
+	assert.NoError(t, err)
+	assert.Len(t, syncState.Tasks, 1)
+	assert.Equal(t, "2030-01-01T00:00:00Z", syncState.Tasks[0].EndTime)
+	assert.True(t, syncState.Tasks[0].DeletionRequested)
+	assert.Equal(t, []string{"task2"}, syncState.MissingTaskIds)
+	_, err = servicer.GetSyncState(gwCtx, &nprobe_protos.SyncStateRequest{NetworkId: "n1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	assert.NoError(t, tasks.Delete(store, "n1", "task1"))
+	_, err = servicer.DeleteTask(ctx, &nprobe_protos.DeleteTaskRequest{NetworkId: "n1", TaskId: "task1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = servicer.GetTaskState(ctx, &nprobe_protos.TaskRequest{NetworkId: "n1", TaskId: "task1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = store.GetNProbeData("n1", "task1")
+	assert.Error(t, err)
+
+	// the changes are audited, whether they succeed or not
+	entries, err := store.GetAuditEntries("n1")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 6)
+	for _, entry := range entries {
+		assert.Equal(t, models.NetworkProbeAuditEntryActionGrpcRequest, entry.Action)
+		assert.Equal(t, protos.NewOperatorIdentity("admin").HashString(), entry.Actor)
+		assert.NotEmpty(t, entry.BodyHash)
+	}
+	assert.Equal(t, "/magma.lte.nprobe.NProbeService/CreateTask", entries[0].Path)
+	assert.Equal(t, "warrant 1", entries[0].Reason)
+	assert.Empty(t, entries[0].Error)
+	assert.NotEmpty(t, entries[1].Error)
+	assert.Equal(t, "/magma.lte.nprobe.NProbeService/CreateTask", entries[3].Path)
+	assert.Equal(t, "invalid task: start_time tomorrow is not in RFC3339 format", entries[3].Error)
+	assert.Equal(t, "/magma.lte.nprobe.NProbeService/DeleteTask", entries[4].Path)
+	assert.Equal(t, "warrant 1 revoked", entries[4].Reason)
+}
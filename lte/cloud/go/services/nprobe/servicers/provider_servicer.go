@@ -0,0 +1,52 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicers
+
+import (
+	"context"
+	"fmt"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	nprobe_streamer "magma/lte/cloud/go/services/nprobe/streamer"
+	streamer_protos "magma/orc8r/cloud/go/services/streamer/protos"
+	"magma/orc8r/cloud/go/services/streamer/providers"
+	"magma/orc8r/lib/go/protos"
+)
+
+type providerServicer struct {
+	storage storage.NProbeStorage
+}
+
+// NewProviderServicer returns a servicer streaming the interception targets
+// of a network to its gateways
+func NewProviderServicer(storage storage.NProbeStorage) streamer_protos.StreamProviderServer {
+	return &providerServicer{storage: storage}
+}
+
+func (s *providerServicer) GetUpdates(ctx context.Context, req *protos.StreamRequest) (*protos.DataUpdateBatch, error) {
+	var streamer providers.StreamProvider
+	switch req.GetStreamName() {
+	case lte.NetworkProbeTargetsStreamName:
+		streamer = &nprobe_streamer.TargetsProvider{Storage: s.storage}
+	default:
+		return nil, fmt.Errorf("GetUpdates failed: unknown stream name provided: %s", req.GetStreamName())
+	}
+
+	updates, err := streamer.GetUpdates(ctx, req.GetGatewayId(), req.GetExtraArgs())
+	if err != nil {
+		return &protos.DataUpdateBatch{}, err
+	}
+	return &protos.DataUpdateBatch{Updates: updates}, nil
+}
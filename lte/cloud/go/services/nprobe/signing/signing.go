@@ -0,0 +1,207 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signing provides the signatures proving that the records delivered
+// by the nprobe service were not tampered with. Records are signed with ECDSA
+// P-256 over their SHA-256 digest, the signature being either embedded in
+// the record or kept apart in the audit trail of its task. Signatures are
+// verified with the public key of the signing key, identified by its key ID.
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"sync"
+)
+
+const (
+	// ModeDisabled leaves the delivered records unsigned
+	ModeDisabled = ""
+	// ModeEmbedded carries the signature of each record in an integrity
+	// check attribute of its header
+	ModeEmbedded = "embedded"
+	// ModeDetached keeps the signature of each record in the audit trail of
+	// its task, the record being delivered unchanged
+	ModeDetached = "detached"
+
+	// Algorithm identifies the signatures made by a Signer
+	Algorithm = "ecdsa-p256-sha256"
+
+	// keyIDSize is the number of bytes of the digest of a public key
+	// identifying it
+	keyIDSize = 8
+)
+
+// ecdsaSignature is the ASN.1 encoding of ECDSA signatures
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// Signer signs digests with an ECDSA P-256 key, which can be replaced at
+// runtime, e.g. once rotated. It implements crypto.Signer.
+type Signer struct {
+	mutex sync.RWMutex
+	key   *ecdsa.PrivateKey
+	keyID string
+}
+
+// NewSigner creates a signer signing with key
+func NewSigner(key *ecdsa.PrivateKey) (*Signer, error) {
+	s := &Signer{}
+	if err := s.SetKey(key); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadSigner creates a signer from a PEM encoded ECDSA private key file
+func LoadSigner(keyFile string) (*Signer, error) {
+	key, err := LoadKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigner(key)
+}
+
+// LoadKey reads a PEM encoded ECDSA private key file, in SEC 1 or PKCS #8 form
+func LoadKey(keyFile string) (*ecdsa.PrivateKey, error) {
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %s", keyFile)
+	}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T, expected ECDSA", key)
+		}
+		return ecKey, nil
+	}
+	return nil, fmt.Errorf("unsupported PEM block type %s", block.Type)
+}
+
+// SetKey replaces the key of the signer. The key is left unchanged if the
+// new one is invalid.
+func (s *Signer) SetKey(key *ecdsa.PrivateKey) error {
+	if key == nil {
+		return errors.New("missing signing key")
+	}
+	if key.Curve != elliptic.P256() {
+		return fmt.Errorf("unsupported curve %s, expected P-256", key.Curve.Params().Name)
+	}
+	keyID, err := GetKeyID(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.key, s.keyID = key, keyID
+	return nil
+}
+
+// KeyID returns the ID of the current signing key
+func (s *Signer) KeyID() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.keyID
+}
+
+// Public returns the public key of the current signing key
+func (s *Signer) Public() crypto.PublicKey {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return &s.key.PublicKey
+}
+
+// Sign signs a SHA-256 digest and returns the ASN.1 encoded signature
+func (s *Signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.mutex.RLock()
+	key := s.key
+	s.mutex.RUnlock()
+	if opts != nil && opts.HashFunc() != crypto.SHA256 {
+		return nil, fmt.Errorf("unsupported hash function %v, expected SHA-256", opts.HashFunc())
+	}
+	if len(digest) != sha256.Size {
+		return nil, fmt.Errorf("invalid digest size %d, expected %d", len(digest), sha256.Size)
+	}
+	return key.Sign(rand, digest, crypto.SHA256)
+}
+
+// SignDigest signs a SHA-256 digest and returns the signature along with
+// the ID of the key it was signed with
+func (s *Signer) SignDigest(digest []byte) ([]byte, string, error) {
+	s.mutex.RLock()
+	key, keyID := s.key, s.keyID
+	s.mutex.RUnlock()
+	if len(digest) != sha256.Size {
+		return nil, "", fmt.Errorf("invalid digest size %d, expected %d", len(digest), sha256.Size)
+	}
+	signature, err := key.Sign(rand.Reader, digest, crypto.SHA256)
+	return signature, keyID, err
+}
+
+// PublicKeyPEM returns the PEM encoded public key of the current signing key
+func (s *Signer) PublicKeyPEM() ([]byte, error) {
+	s.mutex.RLock()
+	key := s.key
+	s.mutex.RUnlock()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// GetKeyID returns the ID of a public key: the first bytes of the SHA-256
+// digest of its PKIX encoding, hex encoded
+func GetKeyID(key *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(der)
+	return hex.EncodeToString(digest[:keyIDSize]), nil
+}
+
+// Verify returns true if signature is a valid signature of a SHA-256
+// digest by the private key of key
+func Verify(key *ecdsa.PublicKey, digest, signature []byte) bool {
+	var sig ecdsaSignature
+	rest, err := asn1.Unmarshal(signature, &sig)
+	if err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil {
+		return false
+	}
+	return ecdsa.Verify(key, digest, sig.R, sig.S)
+}
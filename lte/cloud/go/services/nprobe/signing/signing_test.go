@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	signer, err := NewSigner(key)
+	assert.NoError(t, err)
+	keyID, err := GetKeyID(&key.PublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, keyID, signer.KeyID())
+	assert.Len(t, keyID, 2*keyIDSize)
+
+	digest := sha256.Sum256([]byte("record"))
+	signature, signedKeyID, err := signer.SignDigest(digest[:])
+	assert.NoError(t, err)
+	assert.Equal(t, keyID, signedKeyID)
+	assert.True(t, Verify(&key.PublicKey, digest[:], signature))
+	other := sha256.Sum256([]byte("tampered"))
+	assert.False(t, Verify(&key.PublicKey, other[:], signature))
+	assert.False(t, Verify(&key.PublicKey, digest[:], []byte("garbage")))
+
+	_, _, err = signer.SignDigest([]byte("short"))
+	assert.Error(t, err)
+
+	// invalid keys leave the current one in place
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	assert.Error(t, signer.SetKey(p384Key))
+	assert.Error(t, signer.SetKey(nil))
+	assert.Equal(t, keyID, signer.KeyID())
+
+	rotated, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	assert.NoError(t, signer.SetKey(rotated))
+	assert.NotEqual(t, keyID, signer.KeyID())
+	publicKey, err := signer.PublicKeyPEM()
+	assert.NoError(t, err)
+	block, _ := pem.Decode(publicKey)
+	assert.Equal(t, "PUBLIC KEY", block.Type)
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, &rotated.PublicKey, parsed)
+}
+
+func TestLoadKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signing")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	sec1, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	for name, block := range map[string]*pem.Block{
+		"sec1.key":  {Type: "EC PRIVATE KEY", Bytes: sec1},
+		"pkcs8.key": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		keyFile := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(block), 0600))
+		signer, err := LoadSigner(keyFile)
+		assert.NoError(t, err, name)
+		assert.Equal(t, &key.PublicKey, signer.Public(), name)
+	}
+
+	keyFile := filepath.Join(dir, "invalid.key")
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte("not a key"), 0600))
+	_, err = LoadKey(keyFile)
+	assert.Error(t, err)
+	_, err = LoadKey(filepath.Join(dir, "missing.key"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,387 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package snapshot provides portable, encrypted archives of the nprobe
+// service state of a network. Archives hold the network config, kill switch,
+// tasks, destinations, bearer correlations and the per-task state (sequence
+// numbers, cursors, counters, lifecycle, pending deletions, bookmarks, dead
+// letters, statistics, activity, test records and quarantined events) so
+// that interception can be resumed on another orc8r without breaking
+// continuity.
+package snapshot
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/lte/cloud/go/services/nprobe/tasks"
+	"magma/orc8r/cloud/go/services/configurator"
+	merrors "magma/orc8r/lib/go/errors"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FormatVersion is the version of the snapshot archive format
+	FormatVersion = 2
+	// KeySize is the size in bytes of the AES-256 key sealing archives
+	KeySize = 32
+)
+
+// Snapshot represents the complete nprobe state of a network.
+type Snapshot struct {
+	Version            int                                    `json:"version"`
+	NetworkID          string                                 `json:"network_id"`
+	CreatedAt          time.Time                              `json:"created_at"`
+	NetworkConfig      *models.NetworkProbeNetworkConfig      `json:"network_config,omitempty"`
+	KillSwitch         *models.NetworkProbeKillSwitch         `json:"kill_switch,omitempty"`
+	Tasks              []*models.NetworkProbeTask             `json:"tasks"`
+	Destinations       []*models.NetworkProbeDestination      `json:"destinations"`
+	States             map[string]models.NetworkProbeData     `json:"states"`
+	TaskStates         map[string]*TaskState                  `json:"task_states,omitempty"`
+	BearerCorrelations []models.NetworkProbeBearerCorrelation `json:"bearer_correlations,omitempty"`
+}
+
+// TaskState represents the state of a task kept apart from its exporter state.
+type TaskState struct {
+	Pause       *models.NetworkProbeTaskPause       `json:"pause,omitempty"`
+	Lifecycle   *models.NetworkProbeTaskLifecycle   `json:"lifecycle,omitempty"`
+	XIDRotation *models.NetworkProbeTaskXidRotation `json:"xid_rotation,omitempty"`
+	Replay      *models.NetworkProbeTaskReplay      `json:"replay,omitempty"`
+	// Deletion is the pending deletion of the task, which keeps a revoked
+	// task from being intercepted again once restored
+	Deletion    *models.NetworkProbeTaskDeletion     `json:"deletion,omitempty"`
+	TestRecord  *models.NetworkProbeTaskTestRecord   `json:"test_record,omitempty"`
+	Statistics  *models.NetworkProbeTaskStatistics   `json:"statistics,omitempty"`
+	Activity    *models.NetworkProbeActivity         `json:"activity,omitempty"`
+	Bookmarks   []models.NetworkProbeBookmark        `json:"bookmarks,omitempty"`
+	DeadLetters []models.NetworkProbeDeadLetter      `json:"dead_letters,omitempty"`
+	Quarantine  []models.NetworkProbeQuarantineEntry `json:"quarantine,omitempty"`
+}
+
+// LoadKey reads an hex encoded AES-256 key from a file.
+func LoadKey(keyFile string) ([]byte, error) {
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid snapshot key encoding")
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid snapshot key size %d, expected %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
+// Take collects the network config, kill switch, tasks, destinations, bearer
+// correlations and states of a network.
+func Take(networkID string, store storage.NProbeStorage) (*Snapshot, error) {
+	snap := &Snapshot{
+		Version:    FormatVersion,
+		NetworkID:  networkID,
+		CreatedAt:  time.Now().UTC(),
+		TaskStates: map[string]*TaskState{},
+	}
+
+	config, err := configurator.LoadNetworkConfig(networkID, lte.NetworkProbeConfigType, serdes.Network)
+	switch {
+	case errors.Cause(err) == merrors.ErrNotFound:
+	case err != nil:
+		return nil, errors.Wrap(err, "failed to load NetworkProbeNetworkConfig")
+	default:
+		snap.NetworkConfig = config.(*models.NetworkProbeNetworkConfig)
+	}
+	snap.KillSwitch, err = store.GetKillSwitch(networkID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load kill switch")
+	}
+
+	taskEnts, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeTaskEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load NetworkProbeTasks")
+	}
+	for _, ent := range taskEnts {
+		task := (&models.NetworkProbeTask{}).FromBackendModels(ent)
+		snap.Tasks = append(snap.Tasks, task)
+		snap.TaskStates[string(task.TaskID)], err = takeTaskState(networkID, string(task.TaskID), store)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load state of task %s", task.TaskID)
+		}
+	}
+
+	destEnts, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeDestinationEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load NetworkProbeDestinations")
+	}
+	for _, ent := range destEnts {
+		snap.Destinations = append(snap.Destinations, (&models.NetworkProbeDestination{}).FromBackendModels(ent))
+	}
+
+	snap.States, err = store.GetAllNProbeData(networkID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load NetworkProbeData")
+	}
+	snap.BearerCorrelations, err = store.GetBearerCorrelations(networkID, 0, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load bearer correlations")
+	}
+	return snap, nil
+}
+
+func takeTaskState(networkID, taskID string, store storage.NProbeStorage) (*TaskState, error) {
+	var err error
+	state := &TaskState{}
+	if state.Pause, err = store.GetTaskPause(networkID, taskID); err != nil {
+		return nil, err
+	}
+	if state.Lifecycle, err = store.GetTaskLifecycle(networkID, taskID); err != nil {
+		return nil, err
+	}
+	if state.XIDRotation, err = store.GetTaskXIDRotation(networkID, taskID); err != nil {
+		return nil, err
+	}
+	if state.Replay, err = store.GetTaskReplay(networkID, taskID); err != nil {
+		return nil, err
+	}
+	if state.Deletion, err = store.GetTaskDeletion(networkID, taskID); err != nil {
+		return nil, err
+	}
+	if state.TestRecord, err = store.GetTaskTestRecord(networkID, taskID); err != nil {
+		return nil, err
+	}
+	if state.Statistics, err = store.GetTaskStatistics(networkID, taskID); err != nil {
+		return nil, err
+	}
+	if state.Activity, err = store.GetActivity(networkID, taskID); err != nil {
+		return nil, err
+	}
+	if state.Bookmarks, err = store.GetBookmarks(networkID, taskID); err != nil {
+		return nil, err
+	}
+	if state.DeadLetters, err = store.GetDeadLetters(networkID, taskID); err != nil {
+		return nil, err
+	}
+	if state.Quarantine, err = store.GetQuarantineEntries(networkID, taskID); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Restore writes back the content of a snapshot to a network. Existing tasks
+// and destinations are overwritten, others are left untouched. The tasks are
+// checked against the restored network config and destinations as their
+// creation would be, nothing being written unless they all pass, and their
+// restore is audited as done by the actor. An active kill switch is
+// restored, while an inactive one never resumes the interception of the
+// network.
+func Restore(networkID string, store storage.NProbeStorage, snap *Snapshot, actor string) error {
+	if snap.Version != FormatVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	restored := tasks.RestoredNetwork{Config: snap.NetworkConfig, Destinations: snap.Destinations}
+	if err := tasks.CheckRestore(store, networkID, restored, snap.Tasks, actor); err != nil {
+		return err
+	}
+
+	if snap.NetworkConfig != nil {
+		update := configurator.NetworkUpdateCriteria{
+			ID:                   networkID,
+			ConfigsToAddOrUpdate: map[string]interface{}{lte.NetworkProbeConfigType: snap.NetworkConfig},
+		}
+		if err := configurator.UpdateNetworks([]configurator.NetworkUpdateCriteria{update}, serdes.Network); err != nil {
+			return errors.Wrap(err, "failed to restore NetworkProbeNetworkConfig")
+		}
+	}
+	if snap.KillSwitch != nil && snap.KillSwitch.Active {
+		if err := store.StoreKillSwitch(networkID, *snap.KillSwitch); err != nil {
+			return errors.Wrap(err, "failed to restore kill switch")
+		}
+	}
+
+	for _, dest := range snap.Destinations {
+		err := writeEntity(networkID, lte.NetworkProbeDestinationEntityType, string(dest.DestinationID), dest.DestinationDetails)
+		if err != nil {
+			return errors.Wrapf(err, "failed to restore destination %s", dest.DestinationID)
+		}
+	}
+
+	// restore states before tasks so that tasks are never processed
+	// with a reset sequence number.
+	for taskID, data := range snap.States {
+		if err := store.StoreNProbeData(networkID, taskID, data); err != nil {
+			return errors.Wrapf(err, "failed to restore state of task %s", taskID)
+		}
+	}
+	for taskID, state := range snap.TaskStates {
+		if err := restoreTaskState(networkID, taskID, store, state); err != nil {
+			return errors.Wrapf(err, "failed to restore state of task %s", taskID)
+		}
+	}
+	if err := store.StoreBearerCorrelations(networkID, snap.BearerCorrelations); err != nil {
+		return errors.Wrap(err, "failed to restore bearer correlations")
+	}
+
+	for _, task := range snap.Tasks {
+		if err := tasks.Restore(store, networkID, task, actor); err != nil {
+			return errors.Wrapf(err, "failed to restore task %s", task.TaskID)
+		}
+	}
+	return nil
+}
+
+func restoreTaskState(networkID, taskID string, store storage.NProbeStorage, state *TaskState) error {
+	if state.Pause != nil {
+		if err := store.StoreTaskPause(networkID, taskID, *state.Pause); err != nil {
+			return err
+		}
+	}
+	if state.Lifecycle != nil {
+		if err := store.StoreTaskLifecycle(networkID, taskID, *state.Lifecycle); err != nil {
+			return err
+		}
+	}
+	if state.XIDRotation != nil {
+		if err := store.StoreTaskXIDRotation(networkID, taskID, *state.XIDRotation); err != nil {
+			return err
+		}
+	}
+	if state.Replay != nil {
+		if err := store.StoreTaskReplay(networkID, taskID, *state.Replay); err != nil {
+			return err
+		}
+	}
+	if state.Deletion != nil {
+		if err := store.StoreTaskDeletion(networkID, taskID, *state.Deletion); err != nil {
+			return err
+		}
+	}
+	if state.TestRecord != nil {
+		if err := store.StoreTaskTestRecord(networkID, taskID, *state.TestRecord); err != nil {
+			return err
+		}
+	}
+	if state.Statistics != nil {
+		if err := store.StoreTaskStatistics(networkID, taskID, *state.Statistics); err != nil {
+			return err
+		}
+	}
+	if state.Activity != nil {
+		if err := store.StoreActivity(networkID, taskID, *state.Activity); err != nil {
+			return err
+		}
+	}
+	for _, bookmark := range state.Bookmarks {
+		if err := store.StoreBookmark(networkID, taskID, bookmark); err != nil {
+			return err
+		}
+	}
+	for _, letter := range state.DeadLetters {
+		if err := store.StoreDeadLetter(networkID, taskID, letter); err != nil {
+			return err
+		}
+	}
+	for _, entry := range state.Quarantine {
+		if err := store.StoreQuarantineEntry(networkID, taskID, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeEntity(networkID, entityType, key string, config interface{}) error {
+	exists, err := configurator.DoesEntityExist(networkID, entityType, key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return configurator.CreateOrUpdateEntityConfig(networkID, entityType, key, config, serdes.Entity)
+	}
+	_, err = configurator.CreateEntity(
+		networkID,
+		configurator.NetworkEntity{Type: entityType, Key: key, Config: config},
+		serdes.Entity,
+	)
+	return err
+}
+
+// Seal serializes and encrypts a snapshot with AES-256-GCM.
+// The random nonce is prepended to the returned archive.
+func Seal(snap *Snapshot, key []byte) ([]byte, error) {
+	plaintext, err := json.Marshal(snap)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal snapshot")
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts and deserializes an archive created by Seal.
+func Open(archive []byte, key []byte) (*Snapshot, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(archive) < gcm.NonceSize() {
+		return nil, errors.New("snapshot archive too small")
+	}
+
+	nonce, ciphertext := archive[:gcm.NonceSize()], archive[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt snapshot")
+	}
+
+	snap := &Snapshot{}
+	if err := json.Unmarshal(plaintext, snap); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal snapshot")
+	}
+	return snap, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealOpen(t *testing.T) {
+	key := make([]byte, KeySize)
+	snap := &Snapshot{
+		Version:   FormatVersion,
+		NetworkID: "n1",
+		CreatedAt: time.Unix(1615000000, 0).UTC(),
+		Tasks: []*models.NetworkProbeTask{
+			{
+				TaskID: "IMSI1234",
+				TaskDetails: &models.NetworkProbeTaskDetails{
+					TargetID:      "IMSI1234",
+					TargetType:    "imsi",
+					DeliveryType:  "events_only",
+					CorrelationID: 8674665223082154000,
+				},
+			},
+		},
+		States: map[string]models.NetworkProbeData{
+			"IMSI1234": {
+				TargetID:       "IMSI1234",
+				SequenceNumber: 42,
+				LastExported:   strfmt.DateTime(time.Unix(1615000000, 0).UTC()),
+			},
+		},
+		NetworkConfig: &models.NetworkProbeNetworkConfig{MaxTasks: 10},
+		KillSwitch:    &models.NetworkProbeKillSwitch{Active: true, ActivatedBy: "admin", Reason: "court order"},
+		TaskStates: map[string]*TaskState{
+			"IMSI1234": {
+				Pause:     &models.NetworkProbeTaskPause{Paused: true, PausedBy: "admin"},
+				Lifecycle: &models.NetworkProbeTaskLifecycle{State: models.NetworkProbeTaskLifecycleStateSuspended},
+				Deletion:  &models.NetworkProbeTaskDeletion{RequestedAt: strfmt.DateTime(time.Unix(1615000000, 0).UTC()), RequestedBy: "admin"},
+				Statistics: &models.NetworkProbeTaskStatistics{
+					Current: &models.NetworkProbeStatisticsInterval{Start: strfmt.DateTime(time.Unix(1615000000, 0).UTC()), Records: 3},
+				},
+				Bookmarks:  []models.NetworkProbeBookmark{{Name: "handover", SequenceNumber: 40}},
+				Quarantine: []models.NetworkProbeQuarantineEntry{{EventType: "attach", Field: "timestamp", Error: "invalid"}},
+			},
+		},
+		BearerCorrelations: []models.NetworkProbeBearerCorrelation{
+			{CorrelationID: 9, Imsi: "IMSI1234", BearerID: "5"},
+		},
+	}
+
+	archive, err := Seal(snap, key)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(archive), "IMSI1234")
+
+	actual, err := Open(archive, key)
+	assert.NoError(t, err)
+	assert.Equal(t, snap, actual)
+
+	// wrong key
+	wrongKey := make([]byte, KeySize)
+	wrongKey[0] = 1
+	_, err = Open(archive, wrongKey)
+	assert.Error(t, err)
+
+	// truncated archive
+	_, err = Open(archive[:4], key)
+	assert.Error(t, err)
+}
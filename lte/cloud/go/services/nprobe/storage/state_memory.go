@@ -0,0 +1,213 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	merrors "magma/orc8r/lib/go/errors"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+)
+
+// NewMemoryStateStore returns a state store keeping the export state of the
+// tasks in memory. It is not shared between replicas and is lost on restart.
+func NewMemoryStateStore() StateStore {
+	return &memoryStateStore{
+		states:       map[string]map[string][]byte{},
+		replicas:     map[string][]byte{},
+		stateCursors: map[string][]byte{},
+	}
+}
+
+// memoryStateStore holds the states marshaled, so that callers never share
+// the pointers held by a state
+type memoryStateStore struct {
+	mutex        sync.RWMutex
+	states       map[string]map[string][]byte
+	replicas     map[string][]byte
+	stateCursors map[string][]byte
+}
+
+// StoreNProbeData stores current state for a given networkID and taskID
+func (m *memoryStateStore) StoreNProbeData(networkID, taskID string, data models.NetworkProbeData) error {
+	marshaledData, err := data.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeData")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.states[networkID] == nil {
+		m.states[networkID] = map[string][]byte{}
+	}
+	m.states[networkID][taskID] = marshaledData
+	return nil
+}
+
+// GetNProbeData returns the state keyed by networkID and taskID
+func (m *memoryStateStore) GetNProbeData(networkID, taskID string) (*models.NetworkProbeData, error) {
+	m.mutex.RLock()
+	marshaledData, ok := m.states[networkID][taskID]
+	m.mutex.RUnlock()
+	if !ok {
+		return nil, errors.Wrap(merrors.ErrNotFound, fmt.Sprintf("failed to get nprobe data %s", taskID))
+	}
+	data, err := unmarshalNProbeData(marshaledData)
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// GetAllNProbeData returns all states of a network keyed by taskID
+func (m *memoryStateStore) GetAllNProbeData(networkID string) (map[string]models.NetworkProbeData, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	ret := make(map[string]models.NetworkProbeData, len(m.states[networkID]))
+	for taskID, marshaledData := range m.states[networkID] {
+		data, err := unmarshalNProbeData(marshaledData)
+		if err != nil {
+			return nil, err
+		}
+		ret[taskID] = data
+	}
+	return ret, nil
+}
+
+// ListNProbeNetworks returns the networks holding the state of a task, sorted
+func (m *memoryStateStore) ListNProbeNetworks() ([]string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	ret := make([]string, 0, len(m.states))
+	for networkID, states := range m.states {
+		if len(states) != 0 {
+			ret = append(ret, networkID)
+		}
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// ListNProbeTasks returns the IDs of a page of the tasks of a network
+// selected by their state, along with the token of the next page
+func (m *memoryStateStore) ListNProbeTasks(networkID string, query TaskQuery) ([]string, string, error) {
+	states, err := m.GetAllNProbeData(networkID)
+	if err != nil {
+		return nil, "", err
+	}
+	return listNProbeTasks(states, query)
+}
+
+// DeleteNProbeData deletes a state for a given networkID and taskID
+func (m *memoryStateStore) DeleteNProbeData(networkID, taskID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.states[networkID], taskID)
+	return nil
+}
+
+// SuspendAllNProbeData marks the state of all tasks of a network as
+// suspended at the given time, unless already suspended
+func (m *memoryStateStore) SuspendAllNProbeData(networkID string, suspendedAt time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for taskID, marshaledData := range m.states[networkID] {
+		data, err := unmarshalNProbeData(marshaledData)
+		if err != nil {
+			return err
+		}
+		if !time.Time(data.SuspendedAt).IsZero() {
+			continue
+		}
+		data.SuspendedAt = strfmt.DateTime(suspendedAt)
+		marshaledData, err = data.MarshalBinary()
+		if err != nil {
+			return errors.Wrap(err, "Error marshaling NetworkProbeData")
+		}
+		m.states[networkID][taskID] = marshaledData
+	}
+	return nil
+}
+
+// StoreCursorReplica replaces the cursors of the tasks of a network
+// replicated to the standby instances
+func (m *memoryStateStore) StoreCursorReplica(networkID string, replica models.NetworkProbeCursorReplica) error {
+	marshaledReplica, err := replica.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeCursorReplica")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.replicas[networkID] = marshaledReplica
+	return nil
+}
+
+// GetCursorReplica returns the cursors of the tasks of a network replicated
+// to the standby instances, empty if none was stored
+func (m *memoryStateStore) GetCursorReplica(networkID string) (*models.NetworkProbeCursorReplica, error) {
+	m.mutex.RLock()
+	marshaledReplica, ok := m.replicas[networkID]
+	m.mutex.RUnlock()
+	replica := &models.NetworkProbeCursorReplica{}
+	if !ok {
+		return replica, nil
+	}
+	if err := replica.UnmarshalBinary(marshaledReplica); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeCursorReplica")
+	}
+	return replica, nil
+}
+
+// StoreStateCursor replaces the versions of the states of the targets of a
+// network last fetched from the state service
+func (m *memoryStateStore) StoreStateCursor(networkID string, cursor models.NetworkProbeStateCursor) error {
+	marshaledCursor, err := cursor.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeStateCursor")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stateCursors[networkID] = marshaledCursor
+	return nil
+}
+
+// GetStateCursor returns the versions of the states of the targets of a
+// network last fetched from the state service, empty if none was stored
+func (m *memoryStateStore) GetStateCursor(networkID string) (*models.NetworkProbeStateCursor, error) {
+	m.mutex.RLock()
+	marshaledCursor, ok := m.stateCursors[networkID]
+	m.mutex.RUnlock()
+	cursor := &models.NetworkProbeStateCursor{}
+	if !ok {
+		return cursor, nil
+	}
+	if err := cursor.UnmarshalBinary(marshaledCursor); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeStateCursor")
+	}
+	return cursor, nil
+}
+
+func unmarshalNProbeData(marshaledData []byte) (models.NetworkProbeData, error) {
+	data := models.NetworkProbeData{}
+	err := data.UnmarshalBinary(marshaledData)
+	if err != nil {
+		return models.NetworkProbeData{}, errors.Wrap(err, "Error unmarshaling NetworkProbeData")
+	}
+	return data, nil
+}
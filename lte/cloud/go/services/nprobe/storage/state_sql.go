@@ -0,0 +1,577 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/orc8r/cloud/go/sqorc"
+	merrors "magma/orc8r/lib/go/errors"
+
+	"github.com/Masterminds/squirrel"
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+)
+
+const (
+	stateTableName = "nprobe_task_state"
+
+	stateNidCol   = "network_id"
+	stateTidCol   = "task_id"
+	stateValueCol = "state"
+
+	// the index of the states selects and sorts the listed tasks
+	indexTableName = "nprobe_task_index"
+
+	indexNidCol          = "network_id"
+	indexTidCol          = "task_id"
+	indexTargetCol       = "target_id"
+	indexWarrantStateCol = "warrant_state"
+	indexCreatedAtCol    = "created_at"
+
+	replicaTableName = "nprobe_cursor_replica"
+
+	replicaNidCol   = "network_id"
+	replicaValueCol = "replica"
+
+	stateCursorTableName = "nprobe_state_cursor"
+
+	stateCursorNidCol   = "network_id"
+	stateCursorValueCol = "cursor"
+)
+
+type sqlStateStore struct {
+	db      *sql.DB
+	builder sqorc.StatementBuilder
+}
+
+// NewSQLStateStore returns a state store keeping the export state of the
+// tasks in a dedicated table of db, which is created if missing
+func NewSQLStateStore(db *sql.DB, builder sqorc.StatementBuilder) (StateStore, error) {
+	s := &sqlStateStore{db: db, builder: builder}
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		_, err := s.builder.CreateTable(stateTableName).
+			IfNotExists().
+			Column(stateNidCol).Type(sqorc.ColumnTypeText).NotNull().EndColumn().
+			Column(stateTidCol).Type(sqorc.ColumnTypeText).NotNull().EndColumn().
+			Column(stateValueCol).Type(sqorc.ColumnTypeBytes).NotNull().EndColumn().
+			PrimaryKey(stateNidCol, stateTidCol).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return nil, errors.Wrap(err, "initialize nprobe task state table")
+		}
+		_, err = s.builder.CreateTable(indexTableName).
+			IfNotExists().
+			Column(indexNidCol).Type(sqorc.ColumnTypeText).NotNull().EndColumn().
+			Column(indexTidCol).Type(sqorc.ColumnTypeText).NotNull().EndColumn().
+			Column(indexTargetCol).Type(sqorc.ColumnTypeText).NotNull().EndColumn().
+			Column(indexWarrantStateCol).Type(sqorc.ColumnTypeText).NotNull().EndColumn().
+			Column(indexCreatedAtCol).Type(sqorc.ColumnTypeBigInt).NotNull().EndColumn().
+			PrimaryKey(indexNidCol, indexTidCol).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return nil, errors.Wrap(err, "initialize nprobe task index table")
+		}
+		_, err = s.builder.CreateIndex("nprobe_task_index_created_at_idx").
+			IfNotExists().
+			On(indexTableName).
+			Columns(indexNidCol, indexCreatedAtCol, indexTidCol).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return nil, errors.Wrap(err, "create nprobe task creation index")
+		}
+		_, err = s.builder.CreateIndex("nprobe_task_index_target_idx").
+			IfNotExists().
+			On(indexTableName).
+			Columns(indexNidCol, indexTargetCol).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return nil, errors.Wrap(err, "create nprobe task target index")
+		}
+		if err := s.indexMissing(tx); err != nil {
+			return nil, err
+		}
+		_, err = s.builder.CreateTable(replicaTableName).
+			IfNotExists().
+			Column(replicaNidCol).Type(sqorc.ColumnTypeText).PrimaryKey().EndColumn().
+			Column(replicaValueCol).Type(sqorc.ColumnTypeBytes).NotNull().EndColumn().
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return nil, errors.Wrap(err, "initialize nprobe cursor replica table")
+		}
+		_, err = s.builder.CreateTable(stateCursorTableName).
+			IfNotExists().
+			Column(stateCursorNidCol).Type(sqorc.ColumnTypeText).PrimaryKey().EndColumn().
+			Column(stateCursorValueCol).Type(sqorc.ColumnTypeBytes).NotNull().EndColumn().
+			RunWith(tx).
+			Exec()
+		return nil, errors.Wrap(err, "initialize nprobe state cursor table")
+	}
+	_, err := sqorc.ExecInTx(s.db, nil, nil, txFn)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// StoreNProbeData stores current state for a given networkID and taskID
+func (s *sqlStateStore) StoreNProbeData(networkID, taskID string, data models.NetworkProbeData) error {
+	marshaledData, err := data.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeData")
+	}
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		_, err := s.builder.
+			Insert(stateTableName).
+			Columns(stateNidCol, stateTidCol, stateValueCol).
+			Values(networkID, taskID, marshaledData).
+			OnConflict(
+				[]sqorc.UpsertValue{{Column: stateValueCol, Value: marshaledData}},
+				stateNidCol, stateTidCol,
+			).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return nil, errors.Wrapf(err, "store nprobe data %s", taskID)
+		}
+		return nil, s.index(tx, networkID, taskID, data)
+	}
+	_, err = sqorc.ExecInTx(s.db, nil, nil, txFn)
+	return err
+}
+
+// GetNProbeData returns the state keyed by networkID and taskID
+func (s *sqlStateStore) GetNProbeData(networkID, taskID string) (*models.NetworkProbeData, error) {
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		var marshaledData []byte
+		err := s.builder.
+			Select(stateValueCol).
+			From(stateTableName).
+			Where(squirrel.Eq{stateNidCol: networkID, stateTidCol: taskID}).
+			RunWith(tx).
+			QueryRow().
+			Scan(&marshaledData)
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrapf(merrors.ErrNotFound, "failed to get nprobe data %s", taskID)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get nprobe data %s", taskID)
+		}
+		data, err := unmarshalNProbeData(marshaledData)
+		if err != nil {
+			return nil, err
+		}
+		return &data, nil
+	}
+	txRet, err := sqorc.ExecInTx(s.db, nil, nil, txFn)
+	if err != nil {
+		return nil, err
+	}
+	return txRet.(*models.NetworkProbeData), nil
+}
+
+// GetAllNProbeData returns all states of a network keyed by taskID
+func (s *sqlStateStore) GetAllNProbeData(networkID string) (map[string]models.NetworkProbeData, error) {
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		return s.getAll(tx, networkID)
+	}
+	txRet, err := sqorc.ExecInTx(s.db, nil, nil, txFn)
+	if err != nil {
+		return nil, err
+	}
+	return txRet.(map[string]models.NetworkProbeData), nil
+}
+
+// ListNProbeNetworks returns the networks holding the state of a task, sorted
+func (s *sqlStateStore) ListNProbeNetworks() ([]string, error) {
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		rows, err := s.builder.
+			Select(stateNidCol).
+			Distinct().
+			From(stateTableName).
+			OrderBy(stateNidCol).
+			RunWith(tx).
+			Query()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list nprobe networks")
+		}
+		defer sqorc.CloseRowsLogOnError(rows, "ListNProbeNetworks")
+
+		ret := []string{}
+		for rows.Next() {
+			var networkID string
+			if err = rows.Scan(&networkID); err != nil {
+				return nil, errors.Wrap(err, "failed to list nprobe networks, SQL row scan error")
+			}
+			ret = append(ret, networkID)
+		}
+		if err = rows.Err(); err != nil {
+			return nil, errors.Wrap(err, "failed to list nprobe networks, SQL rows error")
+		}
+		return ret, nil
+	}
+	txRet, err := sqorc.ExecInTx(s.db, nil, nil, txFn)
+	if err != nil {
+		return nil, err
+	}
+	return txRet.([]string), nil
+}
+
+// ListNProbeTasks returns the IDs of a page of the tasks of a network
+// selected by their state, along with the token of the next page. The tasks
+// are selected, sorted and paged by the index of the states.
+func (s *sqlStateStore) ListNProbeTasks(networkID string, query TaskQuery) ([]string, string, error) {
+	after, err := parsePageToken(query.PageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	builder := s.builder.
+		Select(indexTidCol, indexCreatedAtCol).
+		From(indexTableName).
+		Where(squirrel.Eq{indexNidCol: networkID})
+	if len(query.TargetID) != 0 {
+		builder = builder.Where(squirrel.Eq{indexTargetCol: query.TargetID})
+	}
+	if states := query.warrantStates(); states != nil {
+		builder = builder.Where(squirrel.Eq{indexWarrantStateCol: states})
+	}
+	switch query.Sort {
+	case TaskSortCreatedAt:
+		if after != nil {
+			builder = builder.Where(squirrel.Or{
+				squirrel.Gt{indexCreatedAtCol: after.createdAt},
+				squirrel.And{squirrel.Eq{indexCreatedAtCol: after.createdAt}, squirrel.Gt{indexTidCol: after.taskID}},
+			})
+		}
+		builder = builder.OrderBy(indexCreatedAtCol+" ASC", indexTidCol+" ASC")
+	case TaskSortCreatedAtDesc:
+		if after != nil {
+			builder = builder.Where(squirrel.Or{
+				squirrel.Lt{indexCreatedAtCol: after.createdAt},
+				squirrel.And{squirrel.Eq{indexCreatedAtCol: after.createdAt}, squirrel.Lt{indexTidCol: after.taskID}},
+			})
+		}
+		builder = builder.OrderBy(indexCreatedAtCol+" DESC", indexTidCol+" DESC")
+	default:
+		if after != nil {
+			builder = builder.Where(squirrel.Gt{indexTidCol: after.taskID})
+		}
+		builder = builder.OrderBy(indexTidCol + " ASC")
+	}
+	if query.PageSize != 0 {
+		// one more task tells whether there is a next page
+		builder = builder.Limit(uint64(query.PageSize) + 1)
+	}
+
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		rows, err := builder.RunWith(tx).Query()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list nprobe tasks")
+		}
+		defer sqorc.CloseRowsLogOnError(rows, "ListNProbeTasks")
+
+		var ret []taskCursor
+		for rows.Next() {
+			var cursor taskCursor
+			if err := rows.Scan(&cursor.taskID, &cursor.createdAt); err != nil {
+				return nil, errors.Wrap(err, "failed to list nprobe tasks, SQL row scan error")
+			}
+			ret = append(ret, cursor)
+		}
+		return ret, errors.Wrap(rows.Err(), "failed to list nprobe tasks, SQL rows error")
+	}
+	txRet, err := sqorc.ExecInTx(s.db, nil, nil, txFn)
+	if err != nil {
+		return nil, "", err
+	}
+	ret, nextPageToken := getPage(txRet.([]taskCursor), query.PageSize)
+	return ret, nextPageToken, nil
+}
+
+// DeleteNProbeData deletes a state for a given networkID and taskID
+func (s *sqlStateStore) DeleteNProbeData(networkID, taskID string) error {
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		_, err := s.builder.
+			Delete(stateTableName).
+			Where(squirrel.Eq{stateNidCol: networkID, stateTidCol: taskID}).
+			RunWith(tx).
+			Exec()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to delete nprobe data %s", taskID)
+		}
+		_, err = s.builder.
+			Delete(indexTableName).
+			Where(squirrel.Eq{indexNidCol: networkID, indexTidCol: taskID}).
+			RunWith(tx).
+			Exec()
+		return nil, errors.Wrapf(err, "failed to delete nprobe task index %s", taskID)
+	}
+	_, err := sqorc.ExecInTx(s.db, nil, nil, txFn)
+	return err
+}
+
+// SuspendAllNProbeData marks the state of all tasks of a network as
+// suspended at the given time, unless already suspended
+func (s *sqlStateStore) SuspendAllNProbeData(networkID string, suspendedAt time.Time) error {
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		states, err := s.getAll(tx, networkID)
+		if err != nil {
+			return nil, err
+		}
+		for taskID, data := range states {
+			if !time.Time(data.SuspendedAt).IsZero() {
+				continue
+			}
+			data.SuspendedAt = strfmt.DateTime(suspendedAt)
+			marshaledData, err := data.MarshalBinary()
+			if err != nil {
+				return nil, errors.Wrap(err, "Error marshaling NetworkProbeData")
+			}
+			_, err = s.builder.
+				Update(stateTableName).
+				Set(stateValueCol, marshaledData).
+				Where(squirrel.Eq{stateNidCol: networkID, stateTidCol: taskID}).
+				RunWith(tx).
+				Exec()
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to suspend nprobe data %s", taskID)
+			}
+		}
+		return nil, nil
+	}
+	_, err := sqorc.ExecInTx(s.db, nil, nil, txFn)
+	return err
+}
+
+// StoreCursorReplica replaces the cursors of the tasks of a network
+// replicated to the standby instances
+func (s *sqlStateStore) StoreCursorReplica(networkID string, replica models.NetworkProbeCursorReplica) error {
+	marshaledReplica, err := replica.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeCursorReplica")
+	}
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		_, err := s.builder.
+			Insert(replicaTableName).
+			Columns(replicaNidCol, replicaValueCol).
+			Values(networkID, marshaledReplica).
+			OnConflict(
+				[]sqorc.UpsertValue{{Column: replicaValueCol, Value: marshaledReplica}},
+				replicaNidCol,
+			).
+			RunWith(tx).
+			Exec()
+		return nil, errors.Wrapf(err, "store cursor replica of network %s", networkID)
+	}
+	_, err = sqorc.ExecInTx(s.db, nil, nil, txFn)
+	return err
+}
+
+// GetCursorReplica returns the cursors of the tasks of a network replicated
+// to the standby instances, empty if none was stored
+func (s *sqlStateStore) GetCursorReplica(networkID string) (*models.NetworkProbeCursorReplica, error) {
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		replica := &models.NetworkProbeCursorReplica{}
+		var marshaledReplica []byte
+		err := s.builder.
+			Select(replicaValueCol).
+			From(replicaTableName).
+			Where(squirrel.Eq{replicaNidCol: networkID}).
+			RunWith(tx).
+			QueryRow().
+			Scan(&marshaledReplica)
+		if err == sql.ErrNoRows {
+			return replica, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get cursor replica of network %s", networkID)
+		}
+		if err := replica.UnmarshalBinary(marshaledReplica); err != nil {
+			return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeCursorReplica")
+		}
+		return replica, nil
+	}
+	txRet, err := sqorc.ExecInTx(s.db, nil, nil, txFn)
+	if err != nil {
+		return nil, err
+	}
+	return txRet.(*models.NetworkProbeCursorReplica), nil
+}
+
+// StoreStateCursor replaces the versions of the states of the targets of a
+// network last fetched from the state service
+func (s *sqlStateStore) StoreStateCursor(networkID string, cursor models.NetworkProbeStateCursor) error {
+	marshaledCursor, err := cursor.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeStateCursor")
+	}
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		_, err := s.builder.
+			Insert(stateCursorTableName).
+			Columns(stateCursorNidCol, stateCursorValueCol).
+			Values(networkID, marshaledCursor).
+			OnConflict(
+				[]sqorc.UpsertValue{{Column: stateCursorValueCol, Value: marshaledCursor}},
+				stateCursorNidCol,
+			).
+			RunWith(tx).
+			Exec()
+		return nil, errors.Wrapf(err, "store state cursor of network %s", networkID)
+	}
+	_, err = sqorc.ExecInTx(s.db, nil, nil, txFn)
+	return err
+}
+
+// GetStateCursor returns the versions of the states of the targets of a
+// network last fetched from the state service, empty if none was stored
+func (s *sqlStateStore) GetStateCursor(networkID string) (*models.NetworkProbeStateCursor, error) {
+	txFn := func(tx *sql.Tx) (interface{}, error) {
+		cursor := &models.NetworkProbeStateCursor{}
+		var marshaledCursor []byte
+		err := s.builder.
+			Select(stateCursorValueCol).
+			From(stateCursorTableName).
+			Where(squirrel.Eq{stateCursorNidCol: networkID}).
+			RunWith(tx).
+			QueryRow().
+			Scan(&marshaledCursor)
+		if err == sql.ErrNoRows {
+			return cursor, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get state cursor of network %s", networkID)
+		}
+		if err := cursor.UnmarshalBinary(marshaledCursor); err != nil {
+			return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeStateCursor")
+		}
+		return cursor, nil
+	}
+	txRet, err := sqorc.ExecInTx(s.db, nil, nil, txFn)
+	if err != nil {
+		return nil, err
+	}
+	return txRet.(*models.NetworkProbeStateCursor), nil
+}
+
+func (s *sqlStateStore) getAll(tx *sql.Tx, networkID string) (map[string]models.NetworkProbeData, error) {
+	rows, err := s.builder.
+		Select(stateTidCol, stateValueCol).
+		From(stateTableName).
+		Where(squirrel.Eq{stateNidCol: networkID}).
+		RunWith(tx).
+		Query()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get all nprobe data")
+	}
+	defer sqorc.CloseRowsLogOnError(rows, "GetAllNProbeData")
+
+	ret := map[string]models.NetworkProbeData{}
+	for rows.Next() {
+		var taskID string
+		var marshaledData []byte
+		err = rows.Scan(&taskID, &marshaledData)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get all nprobe data, SQL row scan error")
+		}
+		data, err := unmarshalNProbeData(marshaledData)
+		if err != nil {
+			return nil, err
+		}
+		ret[taskID] = data
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get all nprobe data, SQL rows error")
+	}
+	return ret, nil
+}
+
+// index stores the index entry of the state of a task
+func (s *sqlStateStore) index(tx *sql.Tx, networkID, taskID string, data models.NetworkProbeData) error {
+	createdAt := getCreatedAt(data)
+	_, err := s.builder.
+		Insert(indexTableName).
+		Columns(indexNidCol, indexTidCol, indexTargetCol, indexWarrantStateCol, indexCreatedAtCol).
+		Values(networkID, taskID, data.TargetID, data.WarrantState, createdAt).
+		OnConflict(
+			[]sqorc.UpsertValue{
+				{Column: indexTargetCol, Value: data.TargetID},
+				{Column: indexWarrantStateCol, Value: data.WarrantState},
+				{Column: indexCreatedAtCol, Value: createdAt},
+			},
+			indexNidCol, indexTidCol,
+		).
+		RunWith(tx).
+		Exec()
+	return errors.Wrapf(err, "index nprobe data %s", taskID)
+}
+
+// indexMissing indexes the states stored before the index was created
+func (s *sqlStateStore) indexMissing(tx *sql.Tx) error {
+	rows, err := s.builder.
+		Select(stateNidCol, stateTidCol, stateValueCol).
+		From(stateTableName).
+		Where(fmt.Sprintf(
+			"NOT EXISTS (SELECT 1 FROM %s WHERE %s.%s = %s.%s AND %s.%s = %s.%s)",
+			indexTableName,
+			indexTableName, indexNidCol, stateTableName, stateNidCol,
+			indexTableName, indexTidCol, stateTableName, stateTidCol,
+		)).
+		RunWith(tx).
+		Query()
+	if err != nil {
+		return errors.Wrap(err, "failed to get unindexed nprobe data")
+	}
+	type unindexed struct {
+		networkID string
+		taskID    string
+		data      models.NetworkProbeData
+	}
+	var states []unindexed
+	for rows.Next() {
+		var state unindexed
+		var marshaledData []byte
+		if err := rows.Scan(&state.networkID, &state.taskID, &marshaledData); err != nil {
+			sqorc.CloseRowsLogOnError(rows, "indexMissing")
+			return errors.Wrap(err, "failed to get unindexed nprobe data, SQL row scan error")
+		}
+		state.data, err = unmarshalNProbeData(marshaledData)
+		if err != nil {
+			sqorc.CloseRowsLogOnError(rows, "indexMissing")
+			return err
+		}
+		states = append(states, state)
+	}
+	err = rows.Err()
+	sqorc.CloseRowsLogOnError(rows, "indexMissing")
+	if err != nil {
+		return errors.Wrap(err, "failed to get unindexed nprobe data, SQL rows error")
+	}
+	// the rows are read before writing, which the drivers don't support on
+	// the same transaction
+	for _, state := range states {
+		if err := s.index(tx, state.networkID, state.taskID, state.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
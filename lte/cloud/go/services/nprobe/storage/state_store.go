@@ -0,0 +1,261 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// StateBackendBlobstore keeps the export state of the tasks in the
+	// orc8r blobstore along with the rest of the nprobe storage
+	StateBackendBlobstore = "blobstore"
+	// StateBackendSQL keeps the export state of the tasks in a dedicated
+	// table, written with a single upsert per checkpoint
+	StateBackendSQL = "sql"
+	// StateBackendMemory keeps the export state of the tasks in memory, it
+	// is lost on restart
+	StateBackendMemory = "memory"
+)
+
+const (
+	// TaskSortID lists the tasks by ID
+	TaskSortID = ""
+	// TaskSortCreatedAt lists the tasks by creation time, oldest first
+	TaskSortCreatedAt = "created_at"
+	// TaskSortCreatedAtDesc lists the tasks by creation time, newest first
+	TaskSortCreatedAtDesc = "-created_at"
+)
+
+// ErrInvalidPageToken is returned when listing tasks with a page token not
+// returned by a previous listing
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// TaskQuery selects a page of the tasks of a network by their state
+type TaskQuery struct {
+	// TargetID selects the tasks of a target when set
+	TargetID string
+	// WarrantState selects the tasks whose warrant is in this state when
+	// set. The tasks whose warrant is not time-bounded are active.
+	WarrantState string
+	// Sort is the order of the tasks, one of the TaskSort values
+	Sort string
+	// PageSize is the maximum number of tasks of the page, unlimited if zero
+	PageSize uint32
+	// PageToken is the token of the next page returned with the previous
+	// one, the first page being returned when empty
+	PageToken string
+}
+
+// Validate returns an error if the query can't be run
+func (q TaskQuery) Validate() error {
+	switch q.Sort {
+	case TaskSortID, TaskSortCreatedAt, TaskSortCreatedAtDesc:
+	default:
+		return fmt.Errorf("invalid sort %s", q.Sort)
+	}
+	switch q.WarrantState {
+	case "", models.NetworkProbeDataWarrantStatePending, models.NetworkProbeDataWarrantStateActive, models.NetworkProbeDataWarrantStateExpired:
+	default:
+		return fmt.Errorf("invalid state %s", q.WarrantState)
+	}
+	_, err := parsePageToken(q.PageToken)
+	return err
+}
+
+// matches returns true if a task is selected by the query, regardless of
+// its page
+func (q TaskQuery) matches(data models.NetworkProbeData) bool {
+	if len(q.TargetID) != 0 && data.TargetID != q.TargetID {
+		return false
+	}
+	if q.WarrantState == models.NetworkProbeDataWarrantStateActive {
+		return data.WarrantState == "" || data.WarrantState == q.WarrantState
+	}
+	return len(q.WarrantState) == 0 || data.WarrantState == q.WarrantState
+}
+
+// warrantStates returns the warrant states stored for the tasks selected by
+// the query, all when nil
+func (q TaskQuery) warrantStates() []string {
+	switch q.WarrantState {
+	case "":
+		return nil
+	case models.NetworkProbeDataWarrantStateActive:
+		return []string{"", q.WarrantState}
+	default:
+		return []string{q.WarrantState}
+	}
+}
+
+// taskCursor is the position of a task in the listings, which the page
+// tokens hold for the last task of their previous page
+type taskCursor struct {
+	createdAt int64
+	taskID    string
+}
+
+func getTaskCursor(taskID string, data models.NetworkProbeData) taskCursor {
+	return taskCursor{createdAt: getCreatedAt(data), taskID: taskID}
+}
+
+// getCreatedAt returns the creation time of a task in nanoseconds since the
+// epoch, zero if unknown
+func getCreatedAt(data models.NetworkProbeData) int64 {
+	createdAt := time.Time(data.CreatedAt)
+	if createdAt.IsZero() {
+		return 0
+	}
+	return createdAt.UnixNano()
+}
+
+// before returns true if the task of c is listed before the one of other
+func (c taskCursor) before(other taskCursor, order string) bool {
+	switch order {
+	case TaskSortCreatedAt:
+		if c.createdAt != other.createdAt {
+			return c.createdAt < other.createdAt
+		}
+	case TaskSortCreatedAtDesc:
+		if c.createdAt != other.createdAt {
+			return c.createdAt > other.createdAt
+		}
+		return c.taskID > other.taskID
+	}
+	return c.taskID < other.taskID
+}
+
+func (c taskCursor) pageToken() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d/%s", c.createdAt, c.taskID)))
+}
+
+// parsePageToken returns the cursor held by a page token, nil for the first
+// page
+func parsePageToken(token string) (*taskCursor, error) {
+	if len(token) == 0 {
+		return nil, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	parts := strings.SplitN(string(decoded), "/", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return nil, ErrInvalidPageToken
+	}
+	createdAt, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	return &taskCursor{createdAt: createdAt, taskID: parts[1]}, nil
+}
+
+// getPage returns the IDs of the tasks of a page, given the tasks following
+// the previous one in order, and the token of the next page
+func getPage(cursors []taskCursor, pageSize uint32) ([]string, string) {
+	nextPageToken := ""
+	if pageSize != 0 && uint32(len(cursors)) > pageSize {
+		cursors = cursors[:pageSize]
+		nextPageToken = cursors[pageSize-1].pageToken()
+	}
+	ret := make([]string, 0, len(cursors))
+	for _, cursor := range cursors {
+		ret = append(ret, cursor.taskID)
+	}
+	return ret, nextPageToken
+}
+
+// listNProbeTasks returns a page of the tasks of a network from all their
+// states, for the stores which can't select and sort them otherwise
+func listNProbeTasks(states map[string]models.NetworkProbeData, query TaskQuery) ([]string, string, error) {
+	after, err := parsePageToken(query.PageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	var cursors []taskCursor
+	for taskID, data := range states {
+		cursor := getTaskCursor(taskID, data)
+		if !query.matches(data) || (after != nil && !after.before(cursor, query.Sort)) {
+			continue
+		}
+		cursors = append(cursors, cursor)
+	}
+	sort.Slice(cursors, func(i, j int) bool { return cursors[i].before(cursors[j], query.Sort) })
+	ret, nextPageToken := getPage(cursors, query.PageSize)
+	return ret, nextPageToken, nil
+}
+
+// WithStateStore returns a storage keeping the export state of the tasks in
+// state and everything else in base
+func WithStateStore(base NProbeStorage, state StateStore) NProbeStorage {
+	return &stateOverride{NProbeStorage: base, state: state}
+}
+
+type stateOverride struct {
+	NProbeStorage
+	state StateStore
+}
+
+func (s *stateOverride) StoreNProbeData(networkID, taskID string, data models.NetworkProbeData) error {
+	return s.state.StoreNProbeData(networkID, taskID, data)
+}
+
+func (s *stateOverride) GetNProbeData(networkID, taskID string) (*models.NetworkProbeData, error) {
+	return s.state.GetNProbeData(networkID, taskID)
+}
+
+func (s *stateOverride) GetAllNProbeData(networkID string) (map[string]models.NetworkProbeData, error) {
+	return s.state.GetAllNProbeData(networkID)
+}
+
+func (s *stateOverride) ListNProbeNetworks() ([]string, error) {
+	return s.state.ListNProbeNetworks()
+}
+
+func (s *stateOverride) ListNProbeTasks(networkID string, query TaskQuery) ([]string, string, error) {
+	return s.state.ListNProbeTasks(networkID, query)
+}
+
+func (s *stateOverride) DeleteNProbeData(networkID, taskID string) error {
+	return s.state.DeleteNProbeData(networkID, taskID)
+}
+
+func (s *stateOverride) SuspendAllNProbeData(networkID string, suspendedAt time.Time) error {
+	return s.state.SuspendAllNProbeData(networkID, suspendedAt)
+}
+
+func (s *stateOverride) StoreCursorReplica(networkID string, replica models.NetworkProbeCursorReplica) error {
+	return s.state.StoreCursorReplica(networkID, replica)
+}
+
+func (s *stateOverride) GetCursorReplica(networkID string) (*models.NetworkProbeCursorReplica, error) {
+	return s.state.GetCursorReplica(networkID)
+}
+
+func (s *stateOverride) StoreStateCursor(networkID string, cursor models.NetworkProbeStateCursor) error {
+	return s.state.StoreStateCursor(networkID, cursor)
+}
+
+func (s *stateOverride) GetStateCursor(networkID string) (*models.NetworkProbeStateCursor, error) {
+	return s.state.GetStateCursor(networkID)
+}
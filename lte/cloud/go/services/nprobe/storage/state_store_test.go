@@ -0,0 +1,238 @@
+/*
+ * Copyright 2020 The Magma Authors.
+ *
+ * This source code is licensed under the BSD-style license found in the
+ * LICENSE file in the root directory of this source tree.
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/orc8r/cloud/go/sqorc"
+	merrors "magma/orc8r/lib/go/errors"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStateStore(t *testing.T) {
+	testStateStore(t, NewMemoryStateStore())
+	testListNProbeTasks(t, NewMemoryStateStore())
+}
+
+func TestSQLStateStore(t *testing.T) {
+	db, err := sqorc.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	store, err := NewSQLStateStore(db, sqorc.GetSqlBuilder())
+	assert.NoError(t, err)
+	testStateStore(t, store)
+
+	// the table is only created when missing
+	_, err = NewSQLStateStore(db, sqorc.GetSqlBuilder())
+	assert.NoError(t, err)
+	all, err := store.GetAllNProbeData(placeholderNetworkID)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestSQLStateStoreIndex(t *testing.T) {
+	db, err := sqorc.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	store, err := NewSQLStateStore(db, sqorc.GetSqlBuilder())
+	assert.NoError(t, err)
+	testListNProbeTasks(t, store)
+
+	// the states stored before the index was created are indexed
+	_, err = db.Exec("DROP TABLE " + indexTableName)
+	assert.NoError(t, err)
+	store, err = NewSQLStateStore(db, sqorc.GetSqlBuilder())
+	assert.NoError(t, err)
+	taskIDs, _, err := store.ListNProbeTasks(placeholderNetworkID, TaskQuery{Sort: TaskSortCreatedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"task3", "task2", "task1"}, taskIDs)
+
+	// deleted states are no longer listed
+	assert.NoError(t, store.DeleteNProbeData(placeholderNetworkID, "task2"))
+	taskIDs, _, err = store.ListNProbeTasks(placeholderNetworkID, TaskQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"task1", "task3"}, taskIDs)
+}
+
+func testStateStore(t *testing.T, store StateStore) {
+	exported := time.Unix(1600000000, 0).UTC()
+	data1 := models.NetworkProbeData{
+		LastExported:   strfmt.DateTime(exported),
+		TargetID:       "imsi01",
+		SequenceNumber: 1,
+	}
+	data2 := models.NetworkProbeData{
+		LastExported:   strfmt.DateTime(exported),
+		TargetID:       "imsi02",
+		SequenceNumber: 2,
+	}
+
+	_, err := store.GetNProbeData(placeholderNetworkID, "task1")
+	assert.Equal(t, merrors.ErrNotFound, errors.Cause(err))
+
+	assert.NoError(t, store.StoreNProbeData(placeholderNetworkID, "task1", data1))
+	assert.NoError(t, store.StoreNProbeData(placeholderNetworkID, "task2", data2))
+	assert.NoError(t, store.StoreNProbeData("other_network", "task1", data2))
+	networks, err := store.ListNProbeNetworks()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"other_network", placeholderNetworkID}, networks)
+
+	// states are replaced on every checkpoint
+	data1.SequenceNumber = 5
+	assert.NoError(t, store.StoreNProbeData(placeholderNetworkID, "task1", data1))
+	got, err := store.GetNProbeData(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(5), got.SequenceNumber)
+	assert.Equal(t, "imsi01", got.TargetID)
+
+	all, err := store.GetAllNProbeData(placeholderNetworkID)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.Equal(t, "imsi02", all["task2"].TargetID)
+
+	// only the tasks not yet suspended are marked
+	suspendedAt := time.Unix(1600000100, 0).UTC()
+	data2.SuspendedAt = strfmt.DateTime(exported)
+	assert.NoError(t, store.StoreNProbeData(placeholderNetworkID, "task2", data2))
+	assert.NoError(t, store.SuspendAllNProbeData(placeholderNetworkID, suspendedAt))
+	all, err = store.GetAllNProbeData(placeholderNetworkID)
+	assert.NoError(t, err)
+	assert.True(t, time.Time(all["task1"].SuspendedAt).Equal(suspendedAt))
+	assert.True(t, time.Time(all["task2"].SuspendedAt).Equal(exported))
+	got, err = store.GetNProbeData("other_network", "task1")
+	assert.NoError(t, err)
+	assert.True(t, time.Time(got.SuspendedAt).IsZero())
+
+	assert.NoError(t, store.DeleteNProbeData(placeholderNetworkID, "task1"))
+	_, err = store.GetNProbeData(placeholderNetworkID, "task1")
+	assert.Equal(t, merrors.ErrNotFound, errors.Cause(err))
+	assert.NoError(t, store.StoreNProbeData(placeholderNetworkID, "task1", data1))
+
+	// the networks whose states are all deleted are no longer listed
+	assert.NoError(t, store.DeleteNProbeData("other_network", "task1"))
+	networks, err = store.ListNProbeNetworks()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{placeholderNetworkID}, networks)
+
+	// the cursor replica of a network is replaced on every replication
+	replica, err := store.GetCursorReplica(placeholderNetworkID)
+	assert.NoError(t, err)
+	assert.Empty(t, replica.Cursors)
+	cursor := &models.NetworkProbeTaskCursor{TaskID: "task1", CheckpointedSequenceNumber: 5, SequenceNumber: 7}
+	assert.NoError(t, store.StoreCursorReplica(placeholderNetworkID, models.NetworkProbeCursorReplica{
+		ReplicatedAt: strfmt.DateTime(exported),
+		Cursors:      []*models.NetworkProbeTaskCursor{cursor},
+	}))
+	replica, err = store.GetCursorReplica(placeholderNetworkID)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.NetworkProbeTaskCursor{cursor}, replica.Cursors)
+	assert.NoError(t, store.StoreCursorReplica(placeholderNetworkID, models.NetworkProbeCursorReplica{ReplicatedAt: strfmt.DateTime(suspendedAt)}))
+	replica, err = store.GetCursorReplica(placeholderNetworkID)
+	assert.NoError(t, err)
+	assert.Empty(t, replica.Cursors)
+	assert.True(t, time.Time(replica.ReplicatedAt).Equal(suspendedAt))
+	replica, err = store.GetCursorReplica("other_network")
+	assert.NoError(t, err)
+	assert.Empty(t, replica.Cursors)
+
+	// the state cursor of a network is replaced on every sync
+	stateCursor, err := store.GetStateCursor(placeholderNetworkID)
+	assert.NoError(t, err)
+	assert.Empty(t, stateCursor.States)
+	synced := &models.NetworkProbeSyncedState{
+		Type:     "subscriber",
+		DeviceID: "IMSI1234",
+		Version:  3,
+		Sessions: map[string]map[string]string{"apn1": {"bearer_id": "5"}},
+	}
+	assert.NoError(t, store.StoreStateCursor(placeholderNetworkID, models.NetworkProbeStateCursor{
+		SyncedAt:   strfmt.DateTime(exported),
+		ResyncedAt: strfmt.DateTime(exported),
+		States:     []*models.NetworkProbeSyncedState{synced},
+	}))
+	stateCursor, err = store.GetStateCursor(placeholderNetworkID)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.NetworkProbeSyncedState{synced}, stateCursor.States)
+	assert.True(t, time.Time(stateCursor.ResyncedAt).Equal(exported))
+	stateCursor, err = store.GetStateCursor("other_network")
+	assert.NoError(t, err)
+	assert.Empty(t, stateCursor.States)
+}
+
+func testListNProbeTasks(t *testing.T, store StateStore) {
+	createdAt := time.Unix(1600000000, 0).UTC()
+	states := map[string]models.NetworkProbeData{
+		"task1": {TargetID: "imsi01", CreatedAt: strfmt.DateTime(createdAt.Add(2 * time.Minute)), WarrantState: models.NetworkProbeDataWarrantStateActive},
+		"task2": {TargetID: "imsi02", CreatedAt: strfmt.DateTime(createdAt.Add(time.Minute)), WarrantState: models.NetworkProbeDataWarrantStatePending},
+		"task3": {TargetID: "imsi01", CreatedAt: strfmt.DateTime(createdAt)},
+		"task4": {TargetID: "imsi01", CreatedAt: strfmt.DateTime(createdAt), WarrantState: models.NetworkProbeDataWarrantStateExpired},
+	}
+	for taskID, data := range states {
+		assert.NoError(t, store.StoreNProbeData(placeholderNetworkID, taskID, data))
+	}
+	assert.NoError(t, store.StoreNProbeData("other_network", "task5", states["task1"]))
+	// the last state of a task is indexed
+	assert.NoError(t, store.DeleteNProbeData(placeholderNetworkID, "task4"))
+	data := states["task1"]
+	data.SequenceNumber = 3
+	assert.NoError(t, store.StoreNProbeData(placeholderNetworkID, "task1", data))
+
+	taskIDs, nextPageToken, err := store.ListNProbeTasks(placeholderNetworkID, TaskQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"task1", "task2", "task3"}, taskIDs)
+	assert.Empty(t, nextPageToken)
+
+	// the tasks created at the same time are listed by ID
+	taskIDs, nextPageToken, err = store.ListNProbeTasks(placeholderNetworkID, TaskQuery{Sort: TaskSortCreatedAt, PageSize: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"task3", "task2"}, taskIDs)
+	assert.NotEmpty(t, nextPageToken)
+	taskIDs, nextPageToken, err = store.ListNProbeTasks(placeholderNetworkID, TaskQuery{Sort: TaskSortCreatedAt, PageSize: 2, PageToken: nextPageToken})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"task1"}, taskIDs)
+	assert.Empty(t, nextPageToken)
+
+	var pages [][]string
+	query := TaskQuery{Sort: TaskSortCreatedAtDesc, PageSize: 1}
+	for {
+		taskIDs, query.PageToken, err = store.ListNProbeTasks(placeholderNetworkID, query)
+		assert.NoError(t, err)
+		pages = append(pages, taskIDs)
+		if len(query.PageToken) == 0 {
+			break
+		}
+	}
+	assert.Equal(t, [][]string{{"task1"}, {"task2"}, {"task3"}}, pages)
+
+	// the tasks whose warrant is not time-bounded are active
+	taskIDs, _, err = store.ListNProbeTasks(placeholderNetworkID, TaskQuery{WarrantState: models.NetworkProbeDataWarrantStateActive, Sort: TaskSortCreatedAt})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"task3", "task1"}, taskIDs)
+	taskIDs, _, err = store.ListNProbeTasks(placeholderNetworkID, TaskQuery{WarrantState: models.NetworkProbeDataWarrantStatePending})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"task2"}, taskIDs)
+	taskIDs, _, err = store.ListNProbeTasks(placeholderNetworkID, TaskQuery{TargetID: "imsi01", PageSize: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"task1"}, taskIDs)
+	taskIDs, _, err = store.ListNProbeTasks(placeholderNetworkID, TaskQuery{TargetID: "imsi03"})
+	assert.NoError(t, err)
+	assert.Empty(t, taskIDs)
+
+	_, _, err = store.ListNProbeTasks(placeholderNetworkID, TaskQuery{PageToken: "task1"})
+	assert.Equal(t, ErrInvalidPageToken, err)
+}
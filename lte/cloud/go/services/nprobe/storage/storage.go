@@ -13,16 +13,277 @@ limitations under the License.
 
 package storage
 
-import "magma/lte/cloud/go/services/nprobe/obsidian/models"
+import (
+	"time"
 
-// NProbeStorage is the storage interface to manage nprobe service state.
-type NProbeStorage interface {
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+)
+
+// StateStore is the storage interface to manage the export state of the
+// tasks, e.g. their cursor and sequence numbers, which is written on every
+// checkpoint.
+type StateStore interface {
 	// StoreNProbeData stores current state for a given networkID and taskID
 	StoreNProbeData(networkID, taskID string, data models.NetworkProbeData) error
 
-	// GetNProbeData returns the state keyed by networkID and taskID
+	// GetNProbeData returns the state keyed by networkID and taskID, or
+	// ErrNotFound
 	GetNProbeData(networkID, taskID string) (*models.NetworkProbeData, error)
 
+	// GetAllNProbeData returns all states of a network keyed by taskID
+	GetAllNProbeData(networkID string) (map[string]models.NetworkProbeData, error)
+
+	// ListNProbeNetworks returns the networks holding the state of a task,
+	// sorted, e.g. to find the state left behind by deleted networks
+	ListNProbeNetworks() ([]string, error)
+
+	// ListNProbeTasks returns the IDs of a page of the tasks of a network
+	// selected by their state, along with the token of the next page, empty
+	// after the last one
+	ListNProbeTasks(networkID string, query TaskQuery) ([]string, string, error)
+
 	// DeleteNProbeData deletes a state for a given networkID and taskID
 	DeleteNProbeData(networkID, taskID string) error
+
+	// SuspendAllNProbeData marks the state of all tasks of a network as
+	// suspended at the given time, unless already suspended
+	SuspendAllNProbeData(networkID string, suspendedAt time.Time) error
+
+	// StoreCursorReplica replaces the cursors of the tasks of a network
+	// replicated to the standby instances
+	StoreCursorReplica(networkID string, replica models.NetworkProbeCursorReplica) error
+
+	// GetCursorReplica returns the cursors of the tasks of a network
+	// replicated to the standby instances, empty if none was stored
+	GetCursorReplica(networkID string) (*models.NetworkProbeCursorReplica, error)
+
+	// StoreStateCursor replaces the versions of the states of the targets of
+	// a network last fetched from the state service
+	StoreStateCursor(networkID string, cursor models.NetworkProbeStateCursor) error
+
+	// GetStateCursor returns the versions of the states of the targets of a
+	// network last fetched from the state service, empty if none was stored
+	GetStateCursor(networkID string) (*models.NetworkProbeStateCursor, error)
+}
+
+// NProbeStorage is the storage interface to manage nprobe service state.
+type NProbeStorage interface {
+	StateStore
+
+	// StoreQuarantineEntry stores an event of a task which failed to be encoded
+	StoreQuarantineEntry(networkID, taskID string, entry models.NetworkProbeQuarantineEntry) error
+
+	// GetQuarantineEntries returns the quarantined events of a task
+	GetQuarantineEntries(networkID, taskID string) ([]models.NetworkProbeQuarantineEntry, error)
+
+	// DeleteQuarantineEntries deletes the quarantined events of a task
+	DeleteQuarantineEntries(networkID, taskID string) error
+
+	// IncrementActivity adds exported record counts, keyed by the start of
+	// their hour, to the hourly activity rollup of a task
+	IncrementActivity(networkID, taskID string, counts map[time.Time]uint64) error
+
+	// StoreActivity replaces the hourly activity rollup of a task, e.g. when
+	// restored from a snapshot
+	StoreActivity(networkID, taskID string, activity models.NetworkProbeActivity) error
+
+	// GetActivity returns the hourly activity rollup of a task
+	GetActivity(networkID, taskID string) (*models.NetworkProbeActivity, error)
+
+	// DeleteActivity deletes the activity rollup of a task
+	DeleteActivity(networkID, taskID string) error
+
+	// StoreTaskPause stores the pause state of a task
+	StoreTaskPause(networkID, taskID string, pause models.NetworkProbeTaskPause) error
+
+	// GetTaskPause returns the pause state of a task, not paused if none was stored
+	GetTaskPause(networkID, taskID string) (*models.NetworkProbeTaskPause, error)
+
+	// DeleteTaskPause deletes the pause state of a task
+	DeleteTaskPause(networkID, taskID string) error
+
+	// StoreTaskLifecycle stores the lifecycle state of a task and its last transitions
+	StoreTaskLifecycle(networkID, taskID string, lifecycle models.NetworkProbeTaskLifecycle) error
+
+	// GetTaskLifecycle returns the lifecycle state of a task, pending if none was stored
+	GetTaskLifecycle(networkID, taskID string) (*models.NetworkProbeTaskLifecycle, error)
+
+	// DeleteTaskLifecycle deletes the lifecycle state of a task
+	DeleteTaskLifecycle(networkID, taskID string) error
+
+	// StoreTaskXIDRotation stores the last XID rotation requested for a task
+	StoreTaskXIDRotation(networkID, taskID string, rotation models.NetworkProbeTaskXidRotation) error
+
+	// GetTaskXIDRotation returns the last XID rotation requested for a task,
+	// empty if its XID was never rotated
+	GetTaskXIDRotation(networkID, taskID string) (*models.NetworkProbeTaskXidRotation, error)
+
+	// DeleteTaskXIDRotation deletes the XID rotation of a task
+	DeleteTaskXIDRotation(networkID, taskID string) error
+
+	// StoreTaskReplay stores the last replay requested for a task
+	StoreTaskReplay(networkID, taskID string, replay models.NetworkProbeTaskReplay) error
+
+	// GetTaskReplay returns the last replay requested for a task,
+	// empty if none was requested
+	GetTaskReplay(networkID, taskID string) (*models.NetworkProbeTaskReplay, error)
+
+	// DeleteTaskReplay deletes the replay of a task
+	DeleteTaskReplay(networkID, taskID string) error
+
+	// StoreTaskTestRecord stores the last test record requested for a task
+	StoreTaskTestRecord(networkID, taskID string, testRecord models.NetworkProbeTaskTestRecord) error
+
+	// GetTaskTestRecord returns the last test record requested for a task,
+	// empty if none was requested
+	GetTaskTestRecord(networkID, taskID string) (*models.NetworkProbeTaskTestRecord, error)
+
+	// DeleteTaskTestRecord deletes the test record of a task
+	DeleteTaskTestRecord(networkID, taskID string) error
+
+	// IncrementTaskStatistics adds delivered records and their bytes to the
+	// statistics interval of a task being counted, started at now if none is
+	IncrementTaskStatistics(networkID, taskID string, records, bytes uint64, now time.Time) error
+
+	// ReportTaskStatistics records an interval of a task as reported,
+	// deducting its records and bytes from the interval being counted, which
+	// is restarted at its end
+	ReportTaskStatistics(networkID, taskID string, reported models.NetworkProbeStatisticsInterval) error
+
+	// StoreTaskStatistics replaces the statistics of a task, e.g. when
+	// restored from a snapshot
+	StoreTaskStatistics(networkID, taskID string, statistics models.NetworkProbeTaskStatistics) error
+
+	// GetTaskStatistics returns the statistics of a task, empty if none were
+	// counted
+	GetTaskStatistics(networkID, taskID string) (*models.NetworkProbeTaskStatistics, error)
+
+	// DeleteTaskStatistics deletes the statistics of a task
+	DeleteTaskStatistics(networkID, taskID string) error
+
+	// StoreTaskDeletion stores the deletion requested for a task
+	StoreTaskDeletion(networkID, taskID string, deletion models.NetworkProbeTaskDeletion) error
+
+	// GetTaskDeletion returns the deletion requested for a task, empty if
+	// none was requested
+	GetTaskDeletion(networkID, taskID string) (*models.NetworkProbeTaskDeletion, error)
+
+	// DeleteTaskDeletion deletes the deletion requested for a task
+	DeleteTaskDeletion(networkID, taskID string) error
+
+	// StoreBookmark stores a bookmark of a task, replacing the one of the same name
+	StoreBookmark(networkID, taskID string, bookmark models.NetworkProbeBookmark) error
+
+	// GetBookmark returns the bookmark of a task with the given name
+	GetBookmark(networkID, taskID, name string) (*models.NetworkProbeBookmark, error)
+
+	// GetBookmarks returns the bookmarks of a task by sequence number
+	GetBookmarks(networkID, taskID string) ([]models.NetworkProbeBookmark, error)
+
+	// DeleteBookmark deletes the bookmark of a task with the given name
+	DeleteBookmark(networkID, taskID, name string) error
+
+	// DeleteBookmarks deletes all the bookmarks of a task
+	DeleteBookmarks(networkID, taskID string) error
+
+	// StoreDeadLetter stores a record of a task set aside after being
+	// rejected by its destination, replacing the one of the same ID
+	StoreDeadLetter(networkID, taskID string, letter models.NetworkProbeDeadLetter) error
+
+	// GetDeadLetter returns the dead letter of a task with the given ID, or
+	// ErrNotFound
+	GetDeadLetter(networkID, taskID, id string) (*models.NetworkProbeDeadLetter, error)
+
+	// GetDeadLetters returns the dead letters of a task by sequence number
+	GetDeadLetters(networkID, taskID string) ([]models.NetworkProbeDeadLetter, error)
+
+	// DeleteDeadLetter deletes the dead letter of a task with the given ID
+	DeleteDeadLetter(networkID, taskID, id string) error
+
+	// DeleteDeadLetters deletes all the dead letters of a task
+	DeleteDeadLetters(networkID, taskID string) error
+
+	// StoreKillSwitch stores the kill switch of a network
+	StoreKillSwitch(networkID string, killSwitch models.NetworkProbeKillSwitch) error
+
+	// GetKillSwitch returns the kill switch of a network, inactive if none was stored
+	GetKillSwitch(networkID string) (*models.NetworkProbeKillSwitch, error)
+
+	// DeleteKillSwitch deletes the kill switch of a network
+	DeleteKillSwitch(networkID string) error
+
+	// StoreAuditEntry appends an entry to the audit log of a network
+	StoreAuditEntry(networkID string, entry models.NetworkProbeAuditEntry) error
+
+	// GetAuditEntries returns the audit log of a network, oldest first
+	GetAuditEntries(networkID string) ([]models.NetworkProbeAuditEntry, error)
+
+	// StoreDeliveryRecords appends records delivered for a task to its audit trail
+	StoreDeliveryRecords(networkID, taskID string, records []models.NetworkProbeDeliveryRecord) error
+
+	// GetDeliveryRecords returns the records delivered for a task from start
+	// (inclusive) to end (exclusive), oldest first. Zero times leave the range open.
+	GetDeliveryRecords(networkID, taskID string, start, end time.Time) ([]models.NetworkProbeDeliveryRecord, error)
+
+	// DeleteDeliveryRecordsBefore deletes the records delivered for all tasks of
+	// a network before the cutoff
+	DeleteDeliveryRecordsBefore(networkID string, cutoff time.Time) error
+
+	// StoreSessionMappings merges the sessions reported by delivered records
+	// into the mappings of their correlation ID, in delivery order
+	StoreSessionMappings(networkID string, mappings []models.NetworkProbeSessionMapping) error
+
+	// GetSessionMappings returns the sessions mapped to a correlation ID, or to
+	// any correlation ID if zero, optionally only those of a session, first
+	// seen first
+	GetSessionMappings(networkID string, correlationID uint64, sessionID string) ([]models.NetworkProbeSessionMapping, error)
+
+	// DeleteSessionMappingsBefore deletes the session mappings of a network
+	// last seen before the cutoff
+	DeleteSessionMappingsBefore(networkID string, cutoff time.Time) error
+
+	// AllocateCorrelationID returns the correlation ID of the bearer of an
+	// IMSI at the time of an event, allocating one unique in the network if
+	// the bearer has none or was released before
+	AllocateCorrelationID(networkID, imsi, bearerID string, at time.Time) (*models.NetworkProbeBearerCorrelation, error)
+
+	// ReleaseCorrelationIDs releases the correlation ID of the bearer of an
+	// IMSI, or of all its bearers if bearerID is empty, as of releasedAt
+	ReleaseCorrelationIDs(networkID, imsi, bearerID string, releasedAt time.Time) error
+
+	// GetBearerCorrelations returns the bearer a correlation ID was allocated
+	// to, or the bearers of any correlation ID if zero, optionally only those
+	// of an IMSI, first allocated first
+	GetBearerCorrelations(networkID string, correlationID uint64, imsi string) ([]models.NetworkProbeBearerCorrelation, error)
+
+	// StoreBearerCorrelations stores correlation IDs allocated elsewhere, e.g.
+	// restored from a snapshot, each bearer being mapped to the correlation
+	// ID allocated to it last
+	StoreBearerCorrelations(networkID string, correlations []models.NetworkProbeBearerCorrelation) error
+
+	// DeleteBearerCorrelationsBefore deletes the correlation IDs of a network
+	// released before the cutoff
+	DeleteBearerCorrelationsBefore(networkID string, cutoff time.Time) error
+
+	// ReencryptRecords seals again with the primary key up to maxRecords
+	// quarantined events, dead letters, delivery records, session mappings
+	// and bearer correlations of a network sealed otherwise, and returns the
+	// number of values sealed again
+	ReencryptRecords(networkID string, maxRecords int) (int, error)
+
+	// AcquireLease acquires the lease of the service for holder until now plus
+	// duration, or renews it if holder already holds it. The lease is returned
+	// as stored after the call, still held by another instance if not expired.
+	AcquireLease(holder string, now time.Time, duration time.Duration) (*Lease, error)
+
+	// ReleaseLease releases the lease of the service if held by holder
+	ReleaseLease(holder string) error
+}
+
+// Lease grants the processing of tasks to a single nprobe instance until it
+// expires. Its epoch is incremented each time another instance acquires it.
+type Lease struct {
+	Holder    string    `json:"holder"`
+	Epoch     uint64    `json:"epoch"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
@@ -14,17 +14,101 @@ limitations under the License.
 package storage
 
 import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"magma/lte/cloud/go/services/nprobe/keyring"
 	"magma/lte/cloud/go/services/nprobe/obsidian/models"
 	"magma/orc8r/cloud/go/blobstore"
+	configurator_storage "magma/orc8r/cloud/go/services/configurator/storage"
 	"magma/orc8r/cloud/go/storage"
+	merrors "magma/orc8r/lib/go/errors"
 
+	strfmt "github.com/go-openapi/strfmt"
 	"github.com/pkg/errors"
 )
 
-// NProbeBlobType is the blobstore type field for nprobe service
-const NProbeBlobType = "nprobe"
+const (
+	// NProbeBlobType is the blobstore type field for nprobe service
+	NProbeBlobType = "nprobe"
+	// NProbeQuarantineBlobType is the blobstore type field for quarantined events
+	NProbeQuarantineBlobType = "nprobe_quarantine"
+	// NProbeActivityBlobType is the blobstore type field for activity rollups
+	NProbeActivityBlobType = "nprobe_activity"
+	// NProbeKillSwitchBlobType is the blobstore type field for the kill switch
+	NProbeKillSwitchBlobType = "nprobe_kill_switch"
+	// NProbeAuditBlobType is the blobstore type field for audit entries
+	NProbeAuditBlobType = "nprobe_audit"
+	// NProbeDeliveryBlobType is the blobstore type field for the audit trail of delivered records
+	NProbeDeliveryBlobType = "nprobe_delivery"
+	// NProbeSessionMappingBlobType is the blobstore type field for the sessions mapped to correlation IDs
+	NProbeSessionMappingBlobType = "nprobe_session_mapping"
+	// NProbeBearerBlobType is the blobstore type field for the correlation ID
+	// last allocated to each bearer
+	NProbeBearerBlobType = "nprobe_bearer"
+	// NProbeBearerCorrelationBlobType is the blobstore type field for the
+	// bearers the correlation IDs were allocated to
+	NProbeBearerCorrelationBlobType = "nprobe_bearer_correlation"
+	// NProbeLeaseBlobType is the blobstore type field for the lease of the service
+	NProbeLeaseBlobType = "nprobe_lease"
+	// NProbeTaskPauseBlobType is the blobstore type field for the pause state of tasks
+	NProbeTaskPauseBlobType = "nprobe_task_pause"
+	// NProbeTaskLifecycleBlobType is the blobstore type field for the lifecycle state of tasks
+	NProbeTaskLifecycleBlobType = "nprobe_task_lifecycle"
+	// NProbeTaskXIDRotationBlobType is the blobstore type field for the XID rotation of tasks
+	NProbeTaskXIDRotationBlobType = "nprobe_task_xid_rotation"
+	// NProbeTaskReplayBlobType is the blobstore type field for the replay of tasks
+	NProbeTaskReplayBlobType = "nprobe_task_replay"
+	// NProbeTaskTestRecordBlobType is the blobstore type field for the test records of tasks
+	NProbeTaskTestRecordBlobType = "nprobe_task_test_record"
+	// NProbeTaskStatisticsBlobType is the blobstore type field for the statistics of tasks
+	NProbeTaskStatisticsBlobType = "nprobe_task_statistics"
+	// NProbeTaskDeletionBlobType is the blobstore type field for the deletions requested for tasks
+	NProbeTaskDeletionBlobType = "nprobe_task_deletion"
+	// NProbeBookmarkBlobType is the blobstore type field for the bookmarks of tasks
+	NProbeBookmarkBlobType = "nprobe_bookmark"
+	// NProbeDeadLetterBlobType is the blobstore type field for the records of
+	// tasks set aside after being rejected by their destination
+	NProbeDeadLetterBlobType = "nprobe_dead_letter"
+	// NProbeCursorReplicaBlobType is the blobstore type field for the cursors
+	// replicated to the standby instances
+	NProbeCursorReplicaBlobType = "nprobe_cursor_replica"
+	// NProbeStateCursorBlobType is the blobstore type field for the versions
+	// of the states last fetched from the state service
+	NProbeStateCursorBlobType = "nprobe_state_cursor"
+
+	// killSwitchKey is the key of the single kill switch of a network
+	killSwitchKey = "kill_switch"
+	// cursorReplicaKey is the key of the single cursor replica of a network
+	cursorReplicaKey = "cursor_replica"
+	// stateCursorKey is the key of the single state cursor of a network
+	stateCursorKey = "state_cursor"
+	// leaseKey is the key of the single lease of the service, stored in the
+	// internal network as it spans all networks
+	leaseKey = "leader"
+
+	// ActivityRetention is the time hourly activity buckets are kept for
+	ActivityRetention = 31 * 24 * time.Hour
+
+	// MaxReportedStatistics is the number of reported statistics intervals
+	// kept per task
+	MaxReportedStatistics = 100
+
+	// maxCorrelationIDAttempts is the number of correlation IDs drawn for a
+	// bearer before giving up on colliding with allocated ones
+	maxCorrelationIDAttempts = 8
+)
+
+// ErrCorrelationIDCollision is returned when no correlation ID drawn for a
+// bearer is free in its network
+var ErrCorrelationIDCollision = errors.New("all correlation IDs drawn collide with allocated ones")
 
 // NewNProbeBlobstore returns a nprobe storage implementation
 // backed by the provided blobstore factory.
@@ -32,8 +116,23 @@ func NewNProbeBlobstore(factory blobstore.BlobStorageFactory) NProbeStorage {
 	return &nprobeBlobStore{factory: factory}
 }
 
+// NewEncryptedNProbeBlobstore returns a nprobe storage implementation backed
+// by the provided blobstore factory, sealing the quarantined events and the
+// audit trail of delivered records with the keyring.
+func NewEncryptedNProbeBlobstore(factory blobstore.BlobStorageFactory, keys *keyring.Keyring) NProbeStorage {
+	return &nprobeBlobStore{factory: factory, keys: keys}
+}
+
 type nprobeBlobStore struct {
 	factory blobstore.BlobStorageFactory
+	// keys seals the interception data at rest, nil leaving it unencrypted
+	keys *keyring.Keyring
+	// drawCorrelationID draws the candidate correlation IDs of the bearers,
+	// drawCryptoCorrelationID when nil
+	drawCorrelationID func() (uint64, error)
+	// auditSequence breaks the ties between the audit entries stored with
+	// the same timestamp, keeping them in the order they were stored
+	auditSequence uint64
 }
 
 // StoreNProbeData stores current state for a given networkID and taskID
@@ -79,6 +178,66 @@ func (c *nprobeBlobStore) GetNProbeData(networkID, taskID string) (*models.Netwo
 	return &data, store.Commit()
 }
 
+// GetAllNProbeData returns all states of a network keyed by taskID
+func (c *nprobeBlobStore) GetAllNProbeData(networkID string) (map[string]models.NetworkProbeData, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := blobstore.GetAllOfType(store, networkID, NProbeBlobType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get all nprobe data")
+	}
+
+	ret := make(map[string]models.NetworkProbeData, len(blobs))
+	for _, blob := range blobs {
+		data, err := nprobeDataFromBlob(blob)
+		if err != nil {
+			return nil, err
+		}
+		ret[blob.Key] = data
+	}
+	return ret, store.Commit()
+}
+
+// ListNProbeNetworks returns the networks holding the state of a task, sorted
+func (c *nprobeBlobStore) ListNProbeNetworks() ([]string, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobsByNetwork, err := store.Search(
+		blobstore.CreateSearchFilter(nil, []string{NProbeBlobType}, nil, nil),
+		blobstore.LoadCriteria{LoadValue: false},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nprobe networks")
+	}
+	ret := make([]string, 0, len(blobsByNetwork))
+	for networkID, blobs := range blobsByNetwork {
+		if len(blobs) != 0 {
+			ret = append(ret, networkID)
+		}
+	}
+	sort.Strings(ret)
+	return ret, store.Commit()
+}
+
+// ListNProbeTasks returns the IDs of a page of the tasks of a network
+// selected by their state, along with the token of the next page. The
+// blobstore having no secondary index, all states are read.
+func (c *nprobeBlobStore) ListNProbeTasks(networkID string, query TaskQuery) ([]string, string, error) {
+	states, err := c.GetAllNProbeData(networkID)
+	if err != nil {
+		return nil, "", err
+	}
+	return listNProbeTasks(states, query)
+}
+
 // DeleteNProbeData returns the state keyed by networkID and taskID
 func (c *nprobeBlobStore) DeleteNProbeData(networkID, taskID string) error {
 	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
@@ -99,6 +258,2159 @@ func (c *nprobeBlobStore) DeleteNProbeData(networkID, taskID string) error {
 	return store.Commit()
 }
 
+// StoreQuarantineEntry stores an event of a task which failed to be encoded
+func (c *nprobeBlobStore) StoreQuarantineEntry(networkID, taskID string, entry models.NetworkProbeQuarantineEntry) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	entryBlob, err := quarantineEntryToBlob(taskID, entry)
+	if err != nil {
+		return err
+	}
+	entryBlob.Value, err = c.keys.Seal(entryBlob.Value)
+	if err != nil {
+		return errors.Wrap(err, "failed to seal quarantine entry")
+	}
+
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{entryBlob})
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to store quarantine entry %s", entryBlob.Key))
+	}
+	return store.Commit()
+}
+
+// GetQuarantineEntries returns the quarantined events of a task
+func (c *nprobeBlobStore) GetQuarantineEntries(networkID, taskID string) ([]models.NetworkProbeQuarantineEntry, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := searchQuarantineEntries(store, networkID, taskID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]models.NetworkProbeQuarantineEntry, 0, len(blobs))
+	for _, blob := range blobs {
+		value, err := c.keys.Open(blob.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to open quarantine entry %s", blob.Key))
+		}
+		entry := models.NetworkProbeQuarantineEntry{}
+		if err := entry.UnmarshalBinary(value); err != nil {
+			return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeQuarantineEntry")
+		}
+		ret = append(ret, entry)
+	}
+	return ret, store.Commit()
+}
+
+// DeleteQuarantineEntries deletes the quarantined events of a task
+func (c *nprobeBlobStore) DeleteQuarantineEntries(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := searchQuarantineEntries(store, networkID, taskID, false)
+	if err != nil {
+		return err
+	}
+	if len(blobs) == 0 {
+		return store.Commit()
+	}
+
+	err = store.Delete(networkID, blobs.TKs())
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to delete quarantine entries of %s", taskID))
+	}
+	return store.Commit()
+}
+
+// IncrementActivity adds exported record counts, keyed by the start of
+// their hour, to the hourly activity rollup of a task. Buckets older than
+// the retention are dropped.
+func (c *nprobeBlobStore) IncrementActivity(networkID, taskID string, counts map[time.Time]uint64) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	activity, err := getActivity(store, networkID, taskID)
+	if err != nil {
+		return err
+	}
+	mergeActivity(activity, counts, time.Now().Add(-ActivityRetention))
+
+	marshaledActivity, err := activity.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeActivity")
+	}
+	blob := blobstore.Blob{Type: NProbeActivityBlobType, Key: taskID, Value: marshaledActivity}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to store activity of %s", taskID))
+	}
+	return store.Commit()
+}
+
+// StoreActivity replaces the hourly activity rollup of a task
+func (c *nprobeBlobStore) StoreActivity(networkID, taskID string, activity models.NetworkProbeActivity) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledActivity, err := activity.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeActivity")
+	}
+	blob := blobstore.Blob{Type: NProbeActivityBlobType, Key: taskID, Value: marshaledActivity}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to store activity of %s", taskID))
+	}
+	return store.Commit()
+}
+
+// GetActivity returns the hourly activity rollup of a task
+func (c *nprobeBlobStore) GetActivity(networkID, taskID string) (*models.NetworkProbeActivity, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	activity, err := getActivity(store, networkID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return activity, store.Commit()
+}
+
+// DeleteActivity deletes the activity rollup of a task
+func (c *nprobeBlobStore) DeleteActivity(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeActivityBlobType, Key: taskID},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to delete activity of %s", taskID))
+	}
+	return store.Commit()
+}
+
+// SuspendAllNProbeData marks the state of all tasks of a network as
+// suspended at the given time, unless already suspended
+func (c *nprobeBlobStore) SuspendAllNProbeData(networkID string, suspendedAt time.Time) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := blobstore.GetAllOfType(store, networkID, NProbeBlobType)
+	if err != nil {
+		return errors.Wrap(err, "failed to get all nprobe data")
+	}
+
+	var updated blobstore.Blobs
+	for _, blob := range blobs {
+		data, err := nprobeDataFromBlob(blob)
+		if err != nil {
+			return err
+		}
+		if !time.Time(data.SuspendedAt).IsZero() {
+			continue
+		}
+		data.SuspendedAt = strfmt.DateTime(suspendedAt)
+		dataBlob, err := nprobeDataToBlob(blob.Key, data)
+		if err != nil {
+			return err
+		}
+		updated = append(updated, dataBlob)
+	}
+	if len(updated) == 0 {
+		return store.Commit()
+	}
+
+	err = store.CreateOrUpdate(networkID, updated)
+	if err != nil {
+		return errors.Wrap(err, "failed to suspend nprobe data")
+	}
+	return store.Commit()
+}
+
+// StoreCursorReplica replaces the cursors of the tasks of a network
+// replicated to the standby instances
+func (c *nprobeBlobStore) StoreCursorReplica(networkID string, replica models.NetworkProbeCursorReplica) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledReplica, err := replica.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeCursorReplica")
+	}
+	blob := blobstore.Blob{Type: NProbeCursorReplicaBlobType, Key: cursorReplicaKey, Value: marshaledReplica}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to store cursor replica")
+	}
+	return store.Commit()
+}
+
+// GetCursorReplica returns the cursors of the tasks of a network replicated
+// to the standby instances, empty if none was stored
+func (c *nprobeBlobStore) GetCursorReplica(networkID string) (*models.NetworkProbeCursorReplica, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	replica := &models.NetworkProbeCursorReplica{}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeCursorReplicaBlobType, Key: cursorReplicaKey})
+	if err == merrors.ErrNotFound {
+		return replica, store.Commit()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cursor replica")
+	}
+	if err := replica.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeCursorReplica")
+	}
+	return replica, store.Commit()
+}
+
+// StoreStateCursor replaces the versions of the states of the targets of a
+// network last fetched from the state service
+func (c *nprobeBlobStore) StoreStateCursor(networkID string, cursor models.NetworkProbeStateCursor) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledCursor, err := cursor.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeStateCursor")
+	}
+	blob := blobstore.Blob{Type: NProbeStateCursorBlobType, Key: stateCursorKey, Value: marshaledCursor}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to store state cursor")
+	}
+	return store.Commit()
+}
+
+// GetStateCursor returns the versions of the states of the targets of a
+// network last fetched from the state service, empty if none was stored
+func (c *nprobeBlobStore) GetStateCursor(networkID string) (*models.NetworkProbeStateCursor, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	cursor := &models.NetworkProbeStateCursor{}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeStateCursorBlobType, Key: stateCursorKey})
+	if err == merrors.ErrNotFound {
+		return cursor, store.Commit()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get state cursor")
+	}
+	if err := cursor.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeStateCursor")
+	}
+	return cursor, store.Commit()
+}
+
+// StoreTaskPause stores the pause state of a task
+func (c *nprobeBlobStore) StoreTaskPause(networkID, taskID string, pause models.NetworkProbeTaskPause) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledPause, err := pause.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeTaskPause")
+	}
+	blob := blobstore.Blob{Type: NProbeTaskPauseBlobType, Key: taskID, Value: marshaledPause}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to store task pause")
+	}
+	return store.Commit()
+}
+
+// GetTaskPause returns the pause state of a task, not paused if none was stored
+func (c *nprobeBlobStore) GetTaskPause(networkID, taskID string) (*models.NetworkProbeTaskPause, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	pause := &models.NetworkProbeTaskPause{}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeTaskPauseBlobType, Key: taskID})
+	if err == merrors.ErrNotFound {
+		return pause, store.Commit()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task pause")
+	}
+	if err := pause.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeTaskPause")
+	}
+	return pause, store.Commit()
+}
+
+// DeleteTaskPause deletes the pause state of a task
+func (c *nprobeBlobStore) DeleteTaskPause(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeTaskPauseBlobType, Key: taskID},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete task pause")
+	}
+	return store.Commit()
+}
+
+// StoreTaskLifecycle stores the lifecycle state of a task and its last transitions
+func (c *nprobeBlobStore) StoreTaskLifecycle(networkID, taskID string, lifecycle models.NetworkProbeTaskLifecycle) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledLifecycle, err := lifecycle.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeTaskLifecycle")
+	}
+	blob := blobstore.Blob{Type: NProbeTaskLifecycleBlobType, Key: taskID, Value: marshaledLifecycle}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to store task lifecycle")
+	}
+	return store.Commit()
+}
+
+// GetTaskLifecycle returns the lifecycle state of a task, pending if none was stored
+func (c *nprobeBlobStore) GetTaskLifecycle(networkID, taskID string) (*models.NetworkProbeTaskLifecycle, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	lifecycle := &models.NetworkProbeTaskLifecycle{State: models.NetworkProbeTaskLifecycleStatePending}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeTaskLifecycleBlobType, Key: taskID})
+	if err == merrors.ErrNotFound {
+		return lifecycle, store.Commit()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task lifecycle")
+	}
+	if err := lifecycle.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeTaskLifecycle")
+	}
+	return lifecycle, store.Commit()
+}
+
+// DeleteTaskLifecycle deletes the lifecycle state of a task
+func (c *nprobeBlobStore) DeleteTaskLifecycle(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeTaskLifecycleBlobType, Key: taskID},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete task lifecycle")
+	}
+	return store.Commit()
+}
+
+// StoreTaskXIDRotation stores the last XID rotation requested for a task
+func (c *nprobeBlobStore) StoreTaskXIDRotation(networkID, taskID string, rotation models.NetworkProbeTaskXidRotation) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledRotation, err := rotation.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeTaskXidRotation")
+	}
+	blob := blobstore.Blob{Type: NProbeTaskXIDRotationBlobType, Key: taskID, Value: marshaledRotation}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to store task XID rotation")
+	}
+	return store.Commit()
+}
+
+// GetTaskXIDRotation returns the last XID rotation requested for a task,
+// empty if its XID was never rotated
+func (c *nprobeBlobStore) GetTaskXIDRotation(networkID, taskID string) (*models.NetworkProbeTaskXidRotation, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	rotation := &models.NetworkProbeTaskXidRotation{}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeTaskXIDRotationBlobType, Key: taskID})
+	if err == merrors.ErrNotFound {
+		return rotation, store.Commit()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task XID rotation")
+	}
+	if err := rotation.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeTaskXidRotation")
+	}
+	return rotation, store.Commit()
+}
+
+// DeleteTaskXIDRotation deletes the XID rotation of a task
+func (c *nprobeBlobStore) DeleteTaskXIDRotation(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeTaskXIDRotationBlobType, Key: taskID},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete task XID rotation")
+	}
+	return store.Commit()
+}
+
+// StoreTaskReplay stores the last replay requested for a task
+func (c *nprobeBlobStore) StoreTaskReplay(networkID, taskID string, replay models.NetworkProbeTaskReplay) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledReplay, err := replay.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeTaskReplay")
+	}
+	blob := blobstore.Blob{Type: NProbeTaskReplayBlobType, Key: taskID, Value: marshaledReplay}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to store task replay")
+	}
+	return store.Commit()
+}
+
+// GetTaskReplay returns the last replay requested for a task,
+// empty if none was requested
+func (c *nprobeBlobStore) GetTaskReplay(networkID, taskID string) (*models.NetworkProbeTaskReplay, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	replay := &models.NetworkProbeTaskReplay{}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeTaskReplayBlobType, Key: taskID})
+	if err == merrors.ErrNotFound {
+		return replay, store.Commit()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task replay")
+	}
+	if err := replay.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeTaskReplay")
+	}
+	return replay, store.Commit()
+}
+
+// DeleteTaskReplay deletes the replay of a task
+func (c *nprobeBlobStore) DeleteTaskReplay(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeTaskReplayBlobType, Key: taskID},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete task replay")
+	}
+	return store.Commit()
+}
+
+// StoreTaskTestRecord stores the last test record requested for a task
+func (c *nprobeBlobStore) StoreTaskTestRecord(networkID, taskID string, testRecord models.NetworkProbeTaskTestRecord) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledTestRecord, err := testRecord.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeTaskTestRecord")
+	}
+	blob := blobstore.Blob{Type: NProbeTaskTestRecordBlobType, Key: taskID, Value: marshaledTestRecord}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to store task test record")
+	}
+	return store.Commit()
+}
+
+// GetTaskTestRecord returns the last test record requested for a task,
+// empty if none was requested
+func (c *nprobeBlobStore) GetTaskTestRecord(networkID, taskID string) (*models.NetworkProbeTaskTestRecord, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	testRecord := &models.NetworkProbeTaskTestRecord{}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeTaskTestRecordBlobType, Key: taskID})
+	if err == merrors.ErrNotFound {
+		return testRecord, store.Commit()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task test record")
+	}
+	if err := testRecord.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeTaskTestRecord")
+	}
+	return testRecord, store.Commit()
+}
+
+// DeleteTaskTestRecord deletes the test record of a task
+func (c *nprobeBlobStore) DeleteTaskTestRecord(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeTaskTestRecordBlobType, Key: taskID},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete task test record")
+	}
+	return store.Commit()
+}
+
+// IncrementTaskStatistics adds delivered records and their bytes to the
+// statistics interval of a task being counted, started at now if none is
+func (c *nprobeBlobStore) IncrementTaskStatistics(networkID, taskID string, records, bytes uint64, now time.Time) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	statistics, err := getTaskStatistics(store, networkID, taskID)
+	if err != nil {
+		return err
+	}
+	if statistics.Current == nil {
+		statistics.Current = &models.NetworkProbeStatisticsInterval{Start: strfmt.DateTime(now.UTC())}
+	}
+	statistics.Current.Records += records
+	statistics.Current.Bytes += bytes
+	if err := putTaskStatistics(store, networkID, taskID, statistics); err != nil {
+		return err
+	}
+	return store.Commit()
+}
+
+// ReportTaskStatistics records an interval of a task as reported, deducting
+// its records and bytes from the interval being counted, which is restarted
+// at its end. The oldest reported intervals beyond MaxReportedStatistics are
+// dropped.
+func (c *nprobeBlobStore) ReportTaskStatistics(networkID, taskID string, reported models.NetworkProbeStatisticsInterval) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	statistics, err := getTaskStatistics(store, networkID, taskID)
+	if err != nil {
+		return err
+	}
+	next := &models.NetworkProbeStatisticsInterval{Start: reported.End}
+	if current := statistics.Current; current != nil && current.Records > reported.Records {
+		next.Records = current.Records - reported.Records
+	}
+	if current := statistics.Current; current != nil && current.Bytes > reported.Bytes {
+		next.Bytes = current.Bytes - reported.Bytes
+	}
+	statistics.Current = next
+	statistics.Reported = append(statistics.Reported, &reported)
+	if len(statistics.Reported) > MaxReportedStatistics {
+		statistics.Reported = statistics.Reported[len(statistics.Reported)-MaxReportedStatistics:]
+	}
+	if err := putTaskStatistics(store, networkID, taskID, statistics); err != nil {
+		return err
+	}
+	return store.Commit()
+}
+
+// StoreTaskStatistics replaces the statistics of a task
+func (c *nprobeBlobStore) StoreTaskStatistics(networkID, taskID string, statistics models.NetworkProbeTaskStatistics) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	if err := putTaskStatistics(store, networkID, taskID, &statistics); err != nil {
+		return err
+	}
+	return store.Commit()
+}
+
+// GetTaskStatistics returns the statistics of a task, empty if none were
+// counted
+func (c *nprobeBlobStore) GetTaskStatistics(networkID, taskID string) (*models.NetworkProbeTaskStatistics, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	statistics, err := getTaskStatistics(store, networkID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return statistics, store.Commit()
+}
+
+// DeleteTaskStatistics deletes the statistics of a task
+func (c *nprobeBlobStore) DeleteTaskStatistics(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeTaskStatisticsBlobType, Key: taskID},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete task statistics")
+	}
+	return store.Commit()
+}
+
+// StoreTaskDeletion stores the deletion requested for a task
+func (c *nprobeBlobStore) StoreTaskDeletion(networkID, taskID string, deletion models.NetworkProbeTaskDeletion) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledDeletion, err := deletion.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeTaskDeletion")
+	}
+	blob := blobstore.Blob{Type: NProbeTaskDeletionBlobType, Key: taskID, Value: marshaledDeletion}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to store task deletion")
+	}
+	return store.Commit()
+}
+
+// GetTaskDeletion returns the deletion requested for a task, empty if none
+// was requested
+func (c *nprobeBlobStore) GetTaskDeletion(networkID, taskID string) (*models.NetworkProbeTaskDeletion, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	deletion := &models.NetworkProbeTaskDeletion{}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeTaskDeletionBlobType, Key: taskID})
+	if err == merrors.ErrNotFound {
+		return deletion, store.Commit()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task deletion")
+	}
+	if err := deletion.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeTaskDeletion")
+	}
+	return deletion, store.Commit()
+}
+
+// DeleteTaskDeletion deletes the deletion requested for a task
+func (c *nprobeBlobStore) DeleteTaskDeletion(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeTaskDeletionBlobType, Key: taskID},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete task deletion")
+	}
+	return store.Commit()
+}
+
+// StoreBookmark stores a bookmark of a task, replacing the one of the same name
+func (c *nprobeBlobStore) StoreBookmark(networkID, taskID string, bookmark models.NetworkProbeBookmark) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledBookmark, err := bookmark.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeBookmark")
+	}
+	blob := blobstore.Blob{Type: NProbeBookmarkBlobType, Key: bookmarkKey(taskID, bookmark.Name), Value: marshaledBookmark}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to store bookmark %s", blob.Key))
+	}
+	return store.Commit()
+}
+
+// GetBookmark returns the bookmark of a task with the given name
+func (c *nprobeBlobStore) GetBookmark(networkID, taskID, name string) (*models.NetworkProbeBookmark, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeBookmarkBlobType, Key: bookmarkKey(taskID, name)})
+	if err == merrors.ErrNotFound {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get bookmark")
+	}
+	bookmark := &models.NetworkProbeBookmark{}
+	if err := bookmark.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeBookmark")
+	}
+	return bookmark, store.Commit()
+}
+
+// GetBookmarks returns the bookmarks of a task by sequence number
+func (c *nprobeBlobStore) GetBookmarks(networkID, taskID string) ([]models.NetworkProbeBookmark, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := searchBookmarks(store, networkID, taskID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]models.NetworkProbeBookmark, 0, len(blobs))
+	for _, blob := range blobs {
+		bookmark := models.NetworkProbeBookmark{}
+		if err := bookmark.UnmarshalBinary(blob.Value); err != nil {
+			return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeBookmark")
+		}
+		ret = append(ret, bookmark)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].SequenceNumber != ret[j].SequenceNumber {
+			return ret[i].SequenceNumber < ret[j].SequenceNumber
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, store.Commit()
+}
+
+// DeleteBookmark deletes the bookmark of a task with the given name
+func (c *nprobeBlobStore) DeleteBookmark(networkID, taskID, name string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeBookmarkBlobType, Key: bookmarkKey(taskID, name)},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete bookmark")
+	}
+	return store.Commit()
+}
+
+// DeleteBookmarks deletes all the bookmarks of a task
+func (c *nprobeBlobStore) DeleteBookmarks(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := searchBookmarks(store, networkID, taskID, false)
+	if err != nil {
+		return err
+	}
+	if len(blobs) == 0 {
+		return store.Commit()
+	}
+
+	err = store.Delete(networkID, blobs.TKs())
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to delete bookmarks of %s", taskID))
+	}
+	return store.Commit()
+}
+
+// StoreDeadLetter stores a record of a task set aside after being rejected
+// by its destination, replacing the one of the same ID. Like the quarantined
+// events, dead letters are sealed at rest.
+func (c *nprobeBlobStore) StoreDeadLetter(networkID, taskID string, letter models.NetworkProbeDeadLetter) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledLetter, err := letter.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeDeadLetter")
+	}
+	blob := blobstore.Blob{Type: NProbeDeadLetterBlobType, Key: deadLetterKey(taskID, letter.ID)}
+	blob.Value, err = c.keys.Seal(marshaledLetter)
+	if err != nil {
+		return errors.Wrap(err, "failed to seal dead letter")
+	}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to store dead letter %s", blob.Key))
+	}
+	return store.Commit()
+}
+
+// GetDeadLetter returns the dead letter of a task with the given ID
+func (c *nprobeBlobStore) GetDeadLetter(networkID, taskID, id string) (*models.NetworkProbeDeadLetter, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeDeadLetterBlobType, Key: deadLetterKey(taskID, id)})
+	if err == merrors.ErrNotFound {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get dead letter")
+	}
+	letter, err := c.openDeadLetter(blob)
+	if err != nil {
+		return nil, err
+	}
+	return letter, store.Commit()
+}
+
+// GetDeadLetters returns the dead letters of a task by sequence number
+func (c *nprobeBlobStore) GetDeadLetters(networkID, taskID string) ([]models.NetworkProbeDeadLetter, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := searchDeadLetters(store, networkID, taskID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]models.NetworkProbeDeadLetter, 0, len(blobs))
+	for _, blob := range blobs {
+		letter, err := c.openDeadLetter(blob)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, *letter)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].SequenceNumber != ret[j].SequenceNumber {
+			return ret[i].SequenceNumber < ret[j].SequenceNumber
+		}
+		return time.Time(ret[i].DeadLetteredAt).Before(time.Time(ret[j].DeadLetteredAt))
+	})
+	return ret, store.Commit()
+}
+
+// DeleteDeadLetter deletes the dead letter of a task with the given ID
+func (c *nprobeBlobStore) DeleteDeadLetter(networkID, taskID, id string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeDeadLetterBlobType, Key: deadLetterKey(taskID, id)},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete dead letter")
+	}
+	return store.Commit()
+}
+
+// DeleteDeadLetters deletes all the dead letters of a task
+func (c *nprobeBlobStore) DeleteDeadLetters(networkID, taskID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := searchDeadLetters(store, networkID, taskID, false)
+	if err != nil {
+		return err
+	}
+	if len(blobs) == 0 {
+		return store.Commit()
+	}
+
+	err = store.Delete(networkID, blobs.TKs())
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to delete dead letters of %s", taskID))
+	}
+	return store.Commit()
+}
+
+func (c *nprobeBlobStore) openDeadLetter(blob blobstore.Blob) (*models.NetworkProbeDeadLetter, error) {
+	value, err := c.keys.Open(blob.Value)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to open dead letter %s", blob.Key))
+	}
+	letter := &models.NetworkProbeDeadLetter{}
+	if err := letter.UnmarshalBinary(value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeDeadLetter")
+	}
+	return letter, nil
+}
+
+// StoreKillSwitch stores the kill switch of a network
+func (c *nprobeBlobStore) StoreKillSwitch(networkID string, killSwitch models.NetworkProbeKillSwitch) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledKillSwitch, err := killSwitch.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeKillSwitch")
+	}
+	blob := blobstore.Blob{Type: NProbeKillSwitchBlobType, Key: killSwitchKey, Value: marshaledKillSwitch}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to store kill switch")
+	}
+	return store.Commit()
+}
+
+// GetKillSwitch returns the kill switch of a network, inactive if none was stored
+func (c *nprobeBlobStore) GetKillSwitch(networkID string) (*models.NetworkProbeKillSwitch, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	killSwitch := &models.NetworkProbeKillSwitch{}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeKillSwitchBlobType, Key: killSwitchKey})
+	if err == merrors.ErrNotFound {
+		return killSwitch, store.Commit()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kill switch")
+	}
+	if err := killSwitch.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeKillSwitch")
+	}
+	return killSwitch, store.Commit()
+}
+
+// DeleteKillSwitch deletes the kill switch of a network
+func (c *nprobeBlobStore) DeleteKillSwitch(networkID string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	err = store.Delete(
+		networkID,
+		[]storage.TypeAndKey{
+			{Type: NProbeKillSwitchBlobType, Key: killSwitchKey},
+		},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete kill switch")
+	}
+	return store.Commit()
+}
+
+// StoreAuditEntry appends an entry to the audit log of a network
+func (c *nprobeBlobStore) StoreAuditEntry(networkID string, entry models.NetworkProbeAuditEntry) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	marshaledEntry, err := entry.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeAuditEntry")
+	}
+	blob := blobstore.Blob{
+		Type:  NProbeAuditBlobType,
+		Key:   auditEntryKey(time.Time(entry.Timestamp), atomic.AddUint64(&c.auditSequence, 1)),
+		Value: marshaledEntry,
+	}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to store audit entry")
+	}
+	return store.Commit()
+}
+
+// GetAuditEntries returns the audit log of a network, oldest first
+func (c *nprobeBlobStore) GetAuditEntries(networkID string) ([]models.NetworkProbeAuditEntry, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := blobstore.GetAllOfType(store, networkID, NProbeAuditBlobType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get audit entries")
+	}
+
+	// the timestamps of the entries are truncated once marshaled, their keys
+	// keep them in order
+	sort.SliceStable(blobs, func(i, j int) bool {
+		return blobs[i].Key < blobs[j].Key
+	})
+	ret := make([]models.NetworkProbeAuditEntry, 0, len(blobs))
+	for _, blob := range blobs {
+		entry := models.NetworkProbeAuditEntry{}
+		if err := entry.UnmarshalBinary(blob.Value); err != nil {
+			return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeAuditEntry")
+		}
+		ret = append(ret, entry)
+	}
+	return ret, store.Commit()
+}
+
+// auditEntryKey returns the key of an audit entry, ordering the entries by
+// timestamp then by the sequence they were stored in
+func auditEntryKey(timestamp time.Time, seq uint64) string {
+	return fmt.Sprintf("%020d-%020d", timestamp.UnixNano(), seq)
+}
+
+// StoreDeliveryRecords appends records delivered for a task to its audit trail
+func (c *nprobeBlobStore) StoreDeliveryRecords(networkID, taskID string, records []models.NetworkProbeDeliveryRecord) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs := make(blobstore.Blobs, 0, len(records))
+	for _, record := range records {
+		marshaledRecord, err := record.MarshalBinary()
+		if err != nil {
+			return errors.Wrap(err, "Error marshaling NetworkProbeDeliveryRecord")
+		}
+		marshaledRecord, err = c.keys.Seal(marshaledRecord)
+		if err != nil {
+			return errors.Wrap(err, "failed to seal delivery record")
+		}
+		blobs = append(blobs, blobstore.Blob{
+			Type:  NProbeDeliveryBlobType,
+			Key:   deliveryRecordKey(taskID, record),
+			Value: marshaledRecord,
+		})
+	}
+	err = store.CreateOrUpdate(networkID, blobs)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to store delivery records of %s", taskID))
+	}
+	return store.Commit()
+}
+
+// GetDeliveryRecords returns the records delivered for a task from start
+// (inclusive) to end (exclusive), oldest first. Zero times leave the range open.
+func (c *nprobeBlobStore) GetDeliveryRecords(
+	networkID, taskID string,
+	start, end time.Time,
+) ([]models.NetworkProbeDeliveryRecord, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	// select the records from their keys before loading them
+	prefix := deliveryKeyPrefix(taskID)
+	keys, err := searchDeliveryRecordKeys(store, networkID, &prefix)
+	if err != nil {
+		return nil, err
+	}
+	var tks []storage.TypeAndKey
+	for _, key := range keys {
+		deliveredAt, ok := getDeliveredAt(key)
+		if !ok || (!start.IsZero() && deliveredAt.Before(start)) || (!end.IsZero() && !deliveredAt.Before(end)) {
+			continue
+		}
+		tks = append(tks, storage.TypeAndKey{Type: NProbeDeliveryBlobType, Key: key})
+	}
+	if len(tks) == 0 {
+		return []models.NetworkProbeDeliveryRecord{}, store.Commit()
+	}
+
+	blobs, err := store.GetMany(networkID, tks)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to get delivery records of %s", taskID))
+	}
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].Key < blobs[j].Key })
+	ret := make([]models.NetworkProbeDeliveryRecord, 0, len(blobs))
+	for _, blob := range blobs {
+		value, err := c.keys.Open(blob.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to open delivery record %s", blob.Key))
+		}
+		record := models.NetworkProbeDeliveryRecord{}
+		if err := record.UnmarshalBinary(value); err != nil {
+			return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeDeliveryRecord")
+		}
+		ret = append(ret, record)
+	}
+	return ret, store.Commit()
+}
+
+// DeleteDeliveryRecordsBefore deletes the records delivered for all tasks of
+// a network before the cutoff
+func (c *nprobeBlobStore) DeleteDeliveryRecordsBefore(networkID string, cutoff time.Time) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	keys, err := searchDeliveryRecordKeys(store, networkID, nil)
+	if err != nil {
+		return err
+	}
+	var expired []storage.TypeAndKey
+	for _, key := range keys {
+		if deliveredAt, ok := getDeliveredAt(key); ok && deliveredAt.Before(cutoff) {
+			expired = append(expired, storage.TypeAndKey{Type: NProbeDeliveryBlobType, Key: key})
+		}
+	}
+	if len(expired) == 0 {
+		return store.Commit()
+	}
+
+	err = store.Delete(networkID, expired)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete expired delivery records")
+	}
+	return store.Commit()
+}
+
+// StoreSessionMappings merges the sessions reported by delivered records
+// into the mappings of their correlation ID, in delivery order
+func (c *nprobeBlobStore) StoreSessionMappings(networkID string, mappings []models.NetworkProbeSessionMapping) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	var tks []storage.TypeAndKey
+	for _, mapping := range mappings {
+		tks = append(tks, storage.TypeAndKey{Type: NProbeSessionMappingBlobType, Key: sessionMappingKey(mapping)})
+	}
+	blobs, err := store.GetMany(networkID, tks)
+	if err != nil {
+		return errors.Wrap(err, "failed to get session mappings")
+	}
+	merged := map[string]*models.NetworkProbeSessionMapping{}
+	for _, blob := range blobs {
+		mapping, err := c.openSessionMapping(blob)
+		if err != nil {
+			return err
+		}
+		merged[blob.Key] = mapping
+	}
+	for i := range mappings {
+		key := sessionMappingKey(mappings[i])
+		if current, ok := merged[key]; ok {
+			mergeSessionMapping(current, &mappings[i])
+			continue
+		}
+		mapping := mappings[i]
+		merged[key] = &mapping
+	}
+
+	updated := make(blobstore.Blobs, 0, len(merged))
+	for key, mapping := range merged {
+		marshaledMapping, err := mapping.MarshalBinary()
+		if err != nil {
+			return errors.Wrap(err, "Error marshaling NetworkProbeSessionMapping")
+		}
+		marshaledMapping, err = c.keys.Seal(marshaledMapping)
+		if err != nil {
+			return errors.Wrap(err, "failed to seal session mapping")
+		}
+		updated = append(updated, blobstore.Blob{Type: NProbeSessionMappingBlobType, Key: key, Value: marshaledMapping})
+	}
+	err = store.CreateOrUpdate(networkID, updated)
+	if err != nil {
+		return errors.Wrap(err, "failed to store session mappings")
+	}
+	return store.Commit()
+}
+
+// GetSessionMappings returns the sessions mapped to a correlation ID, or to
+// any correlation ID if zero, optionally only those of a session, first
+// seen first
+func (c *nprobeBlobStore) GetSessionMappings(
+	networkID string,
+	correlationID uint64,
+	sessionID string,
+) ([]models.NetworkProbeSessionMapping, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	var prefix *string
+	if correlationID != 0 {
+		p := sessionMappingKeyPrefix(correlationID)
+		prefix = &p
+	}
+	blobs, err := searchSessionMappings(store, networkID, prefix)
+	if err != nil {
+		return nil, err
+	}
+	ret := []models.NetworkProbeSessionMapping{}
+	for _, blob := range blobs {
+		mapping, err := c.openSessionMapping(blob)
+		if err != nil {
+			return nil, err
+		}
+		if len(sessionID) != 0 && mapping.SessionID != sessionID {
+			continue
+		}
+		ret = append(ret, *mapping)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return time.Time(ret[i].FirstSeen).Before(time.Time(ret[j].FirstSeen))
+	})
+	return ret, store.Commit()
+}
+
+// DeleteSessionMappingsBefore deletes the session mappings of a network
+// last seen before the cutoff
+func (c *nprobeBlobStore) DeleteSessionMappingsBefore(networkID string, cutoff time.Time) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := searchSessionMappings(store, networkID, nil)
+	if err != nil {
+		return err
+	}
+	var expired []storage.TypeAndKey
+	for _, blob := range blobs {
+		mapping, err := c.openSessionMapping(blob)
+		if err != nil {
+			return err
+		}
+		if time.Time(mapping.LastSeen).Before(cutoff) {
+			expired = append(expired, storage.TypeAndKey{Type: NProbeSessionMappingBlobType, Key: blob.Key})
+		}
+	}
+	if len(expired) == 0 {
+		return store.Commit()
+	}
+
+	err = store.Delete(networkID, expired)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete expired session mappings")
+	}
+	return store.Commit()
+}
+
+func (c *nprobeBlobStore) openSessionMapping(blob blobstore.Blob) (*models.NetworkProbeSessionMapping, error) {
+	value, err := c.keys.Open(blob.Value)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to open session mapping %s", blob.Key))
+	}
+	mapping := &models.NetworkProbeSessionMapping{}
+	if err := mapping.UnmarshalBinary(value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeSessionMapping")
+	}
+	return mapping, nil
+}
+
+// AllocateCorrelationID returns the correlation ID of the bearer of an IMSI
+// at the time of an event, so that the records of a bearer carry the same
+// correlation ID for its lifetime. A bearer released before the event gets a
+// new correlation ID, while the events up to its release keep the former one,
+// e.g. when reprocessed or processed by a task lagging behind. Correlation IDs
+// are drawn at random and checked against those allocated in the network,
+// released ones included until they are deleted, so that they remain unique
+// across restarts.
+func (c *nprobeBlobStore) AllocateCorrelationID(
+	networkID string,
+	imsi string,
+	bearerID string,
+	at time.Time,
+) (*models.NetworkProbeBearerCorrelation, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	key := bearerKey(imsi, bearerID)
+	current, err := c.getBearerCorrelation(store, networkID, key)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		releasedAt := time.Time(current.ReleasedAt)
+		switch {
+		case at.Before(time.Time(current.AllocatedAt)):
+			// the event is of a former activation of the bearer, if still kept
+			former, err := c.findBearerCorrelation(store, networkID, imsi, bearerID, at)
+			if err != nil {
+				return nil, err
+			}
+			if former == nil {
+				former = current
+			}
+			return former, store.Commit()
+		case releasedAt.IsZero() || !at.After(releasedAt):
+			return current, store.Commit()
+		}
+	}
+
+	draw := c.drawCorrelationID
+	if draw == nil {
+		draw = drawCryptoCorrelationID
+	}
+	var correlationID uint64
+	for attempt := 0; attempt < maxCorrelationIDAttempts && correlationID == 0; attempt++ {
+		candidate, err := draw()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to draw correlation ID")
+		}
+		if candidate == 0 {
+			continue
+		}
+		_, err = store.Get(networkID, storage.TypeAndKey{Type: NProbeBearerCorrelationBlobType, Key: correlationIDKey(candidate)})
+		if err == merrors.ErrNotFound {
+			correlationID = candidate
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to check correlation ID")
+		}
+	}
+	if correlationID == 0 {
+		return nil, ErrCorrelationIDCollision
+	}
+
+	correlation := &models.NetworkProbeBearerCorrelation{
+		CorrelationID: correlationID,
+		Imsi:          imsi,
+		BearerID:      bearerID,
+		AllocatedAt:   strfmt.DateTime(at.UTC()),
+	}
+	correlationBlob, err := c.bearerCorrelationToBlob(correlation)
+	if err != nil {
+		return nil, err
+	}
+	bearerBlob := blobstore.Blob{
+		Type:  NProbeBearerBlobType,
+		Key:   key,
+		Value: []byte(strconv.FormatUint(correlationID, 10)),
+	}
+	err = store.CreateOrUpdate(networkID, blobstore.Blobs{bearerBlob, correlationBlob})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store correlation ID")
+	}
+	return correlation, store.Commit()
+}
+
+// ReleaseCorrelationIDs releases the correlation ID of the bearer of an IMSI,
+// or of all its bearers if bearerID is empty, as of releasedAt. Correlation
+// IDs already released are left as they are.
+func (c *nprobeBlobStore) ReleaseCorrelationIDs(networkID, imsi, bearerID string, releasedAt time.Time) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	keys := []string{bearerKey(imsi, bearerID)}
+	if len(bearerID) == 0 {
+		prefix := bearerKey(imsi, "")
+		filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeBearerBlobType}, nil, &prefix)
+		blobsByNetwork, err := store.Search(filter, blobstore.LoadCriteria{LoadValue: false})
+		if err != nil {
+			return errors.Wrap(err, "failed to search bearers")
+		}
+		keys = keys[:0]
+		for _, blob := range blobsByNetwork[networkID] {
+			keys = append(keys, blob.Key)
+		}
+	}
+	var released blobstore.Blobs
+	for _, key := range keys {
+		correlation, err := c.getBearerCorrelation(store, networkID, key)
+		if err != nil {
+			return err
+		}
+		if correlation == nil || !time.Time(correlation.ReleasedAt).IsZero() {
+			continue
+		}
+		correlation.ReleasedAt = strfmt.DateTime(releasedAt.UTC())
+		blob, err := c.bearerCorrelationToBlob(correlation)
+		if err != nil {
+			return err
+		}
+		released = append(released, blob)
+	}
+	if len(released) == 0 {
+		return store.Commit()
+	}
+
+	err = store.CreateOrUpdate(networkID, released)
+	if err != nil {
+		return errors.Wrap(err, "failed to release correlation IDs")
+	}
+	return store.Commit()
+}
+
+// GetBearerCorrelations returns the bearer a correlation ID was allocated to,
+// or the bearers of any correlation ID if zero, optionally only those of an
+// IMSI, first allocated first
+func (c *nprobeBlobStore) GetBearerCorrelations(
+	networkID string,
+	correlationID uint64,
+	imsi string,
+) ([]models.NetworkProbeBearerCorrelation, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	var blobs blobstore.Blobs
+	if correlationID != 0 {
+		blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeBearerCorrelationBlobType, Key: correlationIDKey(correlationID)})
+		if err != nil && err != merrors.ErrNotFound {
+			return nil, errors.Wrap(err, "failed to get bearer correlation")
+		}
+		if err == nil {
+			blobs = blobstore.Blobs{blob}
+		}
+	} else {
+		blobs, err = searchBearerCorrelations(store, networkID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ret := []models.NetworkProbeBearerCorrelation{}
+	for _, blob := range blobs {
+		correlation, err := c.openBearerCorrelation(blob)
+		if err != nil {
+			return nil, err
+		}
+		if len(imsi) != 0 && correlation.Imsi != imsi {
+			continue
+		}
+		ret = append(ret, *correlation)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return time.Time(ret[i].AllocatedAt).Before(time.Time(ret[j].AllocatedAt))
+	})
+	return ret, store.Commit()
+}
+
+// StoreBearerCorrelations stores correlation IDs allocated elsewhere, each
+// bearer being mapped to the correlation ID allocated to it last
+func (c *nprobeBlobStore) StoreBearerCorrelations(networkID string, correlations []models.NetworkProbeBearerCorrelation) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	var blobs blobstore.Blobs
+	latest := map[string]models.NetworkProbeBearerCorrelation{}
+	for i := range correlations {
+		correlation := correlations[i]
+		blob, err := c.bearerCorrelationToBlob(&correlation)
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, blob)
+		key := bearerKey(correlation.Imsi, correlation.BearerID)
+		if last, ok := latest[key]; !ok || time.Time(last.AllocatedAt).Before(time.Time(correlation.AllocatedAt)) {
+			latest[key] = correlation
+		}
+	}
+	keys := make([]string, 0, len(latest))
+	for key := range latest {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		blobs = append(blobs, blobstore.Blob{
+			Type:  NProbeBearerBlobType,
+			Key:   key,
+			Value: []byte(strconv.FormatUint(latest[key].CorrelationID, 10)),
+		})
+	}
+	if len(blobs) == 0 {
+		return store.Commit()
+	}
+	if err := store.CreateOrUpdate(networkID, blobs); err != nil {
+		return errors.Wrap(err, "failed to store correlation IDs")
+	}
+	return store.Commit()
+}
+
+// DeleteBearerCorrelationsBefore deletes the correlation IDs of a network
+// released before the cutoff, along with their bearer if it wasn't allocated
+// another one since. Their correlation IDs may then be drawn again.
+func (c *nprobeBlobStore) DeleteBearerCorrelationsBefore(networkID string, cutoff time.Time) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	blobs, err := searchBearerCorrelations(store, networkID)
+	if err != nil {
+		return err
+	}
+	var expired []storage.TypeAndKey
+	expiredIDs := map[string]bool{}
+	for _, blob := range blobs {
+		correlation, err := c.openBearerCorrelation(blob)
+		if err != nil {
+			return err
+		}
+		releasedAt := time.Time(correlation.ReleasedAt)
+		if !releasedAt.IsZero() && releasedAt.Before(cutoff) {
+			expired = append(expired, storage.TypeAndKey{Type: NProbeBearerCorrelationBlobType, Key: blob.Key})
+			expiredIDs[strconv.FormatUint(correlation.CorrelationID, 10)] = true
+		}
+	}
+	if len(expired) == 0 {
+		return store.Commit()
+	}
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeBearerBlobType}, nil, nil)
+	bearersByNetwork, err := store.Search(filter, blobstore.LoadCriteria{LoadValue: true})
+	if err != nil {
+		return errors.Wrap(err, "failed to search bearers")
+	}
+	for _, blob := range bearersByNetwork[networkID] {
+		if expiredIDs[string(blob.Value)] {
+			expired = append(expired, storage.TypeAndKey{Type: NProbeBearerBlobType, Key: blob.Key})
+		}
+	}
+
+	err = store.Delete(networkID, expired)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete expired bearer correlations")
+	}
+	return store.Commit()
+}
+
+// getBearerCorrelation returns the correlation ID last allocated to a
+// bearer, nil if it was never allocated one
+func (c *nprobeBlobStore) getBearerCorrelation(
+	store blobstore.TransactionalBlobStorage,
+	networkID string,
+	key string,
+) (*models.NetworkProbeBearerCorrelation, error) {
+	bearerBlob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeBearerBlobType, Key: key})
+	if err == merrors.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get bearer")
+	}
+	correlationID, err := strconv.ParseUint(string(bearerBlob.Value), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("invalid correlation ID of bearer %s", key))
+	}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeBearerCorrelationBlobType, Key: correlationIDKey(correlationID)})
+	if err == merrors.ErrNotFound {
+		// the correlation ID was deleted, along with the bearer
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get bearer correlation")
+	}
+	return c.openBearerCorrelation(blob)
+}
+
+// findBearerCorrelation returns the correlation ID a bearer was allocated at
+// the time of an event, nil if none was or it was deleted since
+func (c *nprobeBlobStore) findBearerCorrelation(
+	store blobstore.TransactionalBlobStorage,
+	networkID string,
+	imsi string,
+	bearerID string,
+	at time.Time,
+) (*models.NetworkProbeBearerCorrelation, error) {
+	blobs, err := searchBearerCorrelations(store, networkID)
+	if err != nil {
+		return nil, err
+	}
+	var found *models.NetworkProbeBearerCorrelation
+	for _, blob := range blobs {
+		correlation, err := c.openBearerCorrelation(blob)
+		if err != nil {
+			return nil, err
+		}
+		allocatedAt, releasedAt := time.Time(correlation.AllocatedAt), time.Time(correlation.ReleasedAt)
+		if correlation.Imsi != imsi || correlation.BearerID != bearerID || at.Before(allocatedAt) ||
+			(!releasedAt.IsZero() && at.After(releasedAt)) {
+			continue
+		}
+		if found == nil || allocatedAt.After(time.Time(found.AllocatedAt)) {
+			found = correlation
+		}
+	}
+	return found, nil
+}
+
+func (c *nprobeBlobStore) bearerCorrelationToBlob(correlation *models.NetworkProbeBearerCorrelation) (blobstore.Blob, error) {
+	marshaledCorrelation, err := correlation.MarshalBinary()
+	if err != nil {
+		return blobstore.Blob{}, errors.Wrap(err, "Error marshaling NetworkProbeBearerCorrelation")
+	}
+	marshaledCorrelation, err = c.keys.Seal(marshaledCorrelation)
+	if err != nil {
+		return blobstore.Blob{}, errors.Wrap(err, "failed to seal bearer correlation")
+	}
+	return blobstore.Blob{
+		Type:  NProbeBearerCorrelationBlobType,
+		Key:   correlationIDKey(correlation.CorrelationID),
+		Value: marshaledCorrelation,
+	}, nil
+}
+
+func (c *nprobeBlobStore) openBearerCorrelation(blob blobstore.Blob) (*models.NetworkProbeBearerCorrelation, error) {
+	value, err := c.keys.Open(blob.Value)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to open bearer correlation %s", blob.Key))
+	}
+	correlation := &models.NetworkProbeBearerCorrelation{}
+	if err := correlation.UnmarshalBinary(value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeBearerCorrelation")
+	}
+	return correlation, nil
+}
+
+// ReencryptRecords seals again with the primary key of the keyring up to
+// maxRecords quarantined events, dead letters, delivery records, session mappings and bearer
+// correlations of a network sealed with another key, or not sealed, and returns the number of values sealed again.
+// With encryption disabled, the sealed values are stored unencrypted.
+func (c *nprobeBlobStore) ReencryptRecords(networkID string, maxRecords int) (int, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{ReadOnly: false})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	filter := blobstore.CreateSearchFilter(
+		&networkID,
+		[]string{NProbeQuarantineBlobType, NProbeDeadLetterBlobType, NProbeDeliveryBlobType, NProbeSessionMappingBlobType, NProbeBearerCorrelationBlobType},
+		nil,
+		nil,
+	)
+	blobsByNetwork, err := store.Search(filter, blobstore.LoadCriteria{LoadValue: true})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to search sealed records")
+	}
+	var resealed blobstore.Blobs
+	for _, blob := range blobsByNetwork[networkID] {
+		if len(resealed) >= maxRecords {
+			break
+		}
+		if c.keys.IsCurrent(blob.Value) {
+			continue
+		}
+		value, err := c.keys.Open(blob.Value)
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("failed to open %s %s", blob.Type, blob.Key))
+		}
+		blob.Value, err = c.keys.Seal(value)
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("failed to seal %s %s", blob.Type, blob.Key))
+		}
+		resealed = append(resealed, blob)
+	}
+	if len(resealed) == 0 {
+		return 0, store.Commit()
+	}
+
+	err = store.CreateOrUpdate(networkID, resealed)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to store sealed records")
+	}
+	return len(resealed), store.Commit()
+}
+
+// AcquireLease acquires the lease of the service for holder until now plus
+// duration, or renews it if holder already holds it. The lease is returned
+// as stored after the call, still held by another instance if not expired.
+// Instances competing for the lease are serialized by the transaction.
+func (c *nprobeBlobStore) AcquireLease(holder string, now time.Time, duration time.Duration) (*Lease, error) {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{Isolation: storage.LevelSerializable})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	lease, err := getLease(store)
+	if err != nil {
+		return nil, err
+	}
+	if lease.Holder != holder && now.Before(lease.ExpiresAt) {
+		return lease, store.Commit()
+	}
+	if lease.Holder != holder {
+		lease.Holder = holder
+		lease.Epoch++
+	}
+	lease.ExpiresAt = now.Add(duration)
+
+	blob, err := leaseToBlob(lease)
+	if err != nil {
+		return nil, err
+	}
+	err = store.CreateOrUpdate(configurator_storage.InternalNetworkID, blobstore.Blobs{blob})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store lease")
+	}
+	return lease, store.Commit()
+}
+
+// ReleaseLease releases the lease of the service if held by holder. The
+// lease is expired rather than deleted so that its epoch keeps increasing.
+func (c *nprobeBlobStore) ReleaseLease(holder string) error {
+	store, err := c.factory.StartTransaction(&storage.TxOptions{Isolation: storage.LevelSerializable})
+	if err != nil {
+		return errors.Wrap(err, "failed to start transaction")
+	}
+	defer store.Rollback()
+
+	lease, err := getLease(store)
+	if err != nil {
+		return err
+	}
+	if lease.Holder != holder {
+		return store.Commit()
+	}
+	lease.ExpiresAt = time.Time{}
+
+	blob, err := leaseToBlob(lease)
+	if err != nil {
+		return err
+	}
+	err = store.CreateOrUpdate(configurator_storage.InternalNetworkID, blobstore.Blobs{blob})
+	if err != nil {
+		return errors.Wrap(err, "failed to release lease")
+	}
+	return store.Commit()
+}
+
+// getLease loads the lease of the service, an expired one if none was stored
+func getLease(store blobstore.TransactionalBlobStorage) (*Lease, error) {
+	lease := &Lease{}
+	blob, err := store.Get(configurator_storage.InternalNetworkID, storage.TypeAndKey{Type: NProbeLeaseBlobType, Key: leaseKey})
+	if err == merrors.ErrNotFound {
+		return lease, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get lease")
+	}
+	if err := json.Unmarshal(blob.Value, lease); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling Lease")
+	}
+	return lease, nil
+}
+
+func leaseToBlob(lease *Lease) (blobstore.Blob, error) {
+	marshaledLease, err := json.Marshal(lease)
+	if err != nil {
+		return blobstore.Blob{}, errors.Wrap(err, "Error marshaling Lease")
+	}
+	return blobstore.Blob{
+		Type:  NProbeLeaseBlobType,
+		Key:   leaseKey,
+		Value: marshaledLease,
+	}, nil
+}
+
+// getActivity loads the activity rollup of a task, an empty one if none was stored
+func getActivity(store blobstore.TransactionalBlobStorage, networkID, taskID string) (*models.NetworkProbeActivity, error) {
+	activity := &models.NetworkProbeActivity{Granularity: models.NetworkProbeActivityGranularityHour}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeActivityBlobType, Key: taskID})
+	if err == merrors.ErrNotFound {
+		return activity, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to get activity of %s", taskID))
+	}
+	if err := activity.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeActivity")
+	}
+	return activity, nil
+}
+
+func getTaskStatistics(store blobstore.TransactionalBlobStorage, networkID, taskID string) (*models.NetworkProbeTaskStatistics, error) {
+	statistics := &models.NetworkProbeTaskStatistics{}
+	blob, err := store.Get(networkID, storage.TypeAndKey{Type: NProbeTaskStatisticsBlobType, Key: taskID})
+	if err == merrors.ErrNotFound {
+		return statistics, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task statistics")
+	}
+	if err := statistics.UnmarshalBinary(blob.Value); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshaling NetworkProbeTaskStatistics")
+	}
+	return statistics, nil
+}
+
+func putTaskStatistics(store blobstore.TransactionalBlobStorage, networkID, taskID string, statistics *models.NetworkProbeTaskStatistics) error {
+	marshaledStatistics, err := statistics.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling NetworkProbeTaskStatistics")
+	}
+	blob := blobstore.Blob{Type: NProbeTaskStatisticsBlobType, Key: taskID, Value: marshaledStatistics}
+	if err := store.CreateOrUpdate(networkID, blobstore.Blobs{blob}); err != nil {
+		return errors.Wrap(err, "failed to store task statistics")
+	}
+	return nil
+}
+
+// mergeActivity adds counts to the buckets of an activity rollup and
+// drops buckets started before the cutoff. Buckets are kept sorted.
+func mergeActivity(activity *models.NetworkProbeActivity, counts map[time.Time]uint64, cutoff time.Time) {
+	byStart := map[int64]uint64{}
+	for _, bucket := range activity.Buckets {
+		byStart[time.Time(bucket.Start).Unix()] += bucket.Count
+	}
+	for start, count := range counts {
+		byStart[start.Truncate(time.Hour).Unix()] += count
+	}
+
+	buckets := make([]*models.NetworkProbeActivityBucket, 0, len(byStart))
+	for start, count := range byStart {
+		if start < cutoff.Unix() {
+			continue
+		}
+		buckets = append(buckets, &models.NetworkProbeActivityBucket{
+			Start: strfmt.DateTime(time.Unix(start, 0).UTC()),
+			Count: count,
+		})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return time.Time(buckets[i].Start).Before(time.Time(buckets[j].Start))
+	})
+	activity.Buckets = buckets
+}
+
+func searchQuarantineEntries(
+	store blobstore.TransactionalBlobStorage,
+	networkID, taskID string,
+	loadValue bool,
+) (blobstore.Blobs, error) {
+	prefix := quarantineKeyPrefix(taskID)
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeQuarantineBlobType}, nil, &prefix)
+	blobsByNetwork, err := store.Search(filter, blobstore.LoadCriteria{LoadValue: loadValue})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to search quarantine entries of %s", taskID))
+	}
+	return blobsByNetwork[networkID], nil
+}
+
+// quarantineKeyPrefix returns the prefix of the blob keys of the entries of a task
+func quarantineKeyPrefix(taskID string) string {
+	return taskID + "/"
+}
+
+func searchBookmarks(
+	store blobstore.TransactionalBlobStorage,
+	networkID, taskID string,
+	loadValue bool,
+) (blobstore.Blobs, error) {
+	prefix := bookmarkKeyPrefix(taskID)
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeBookmarkBlobType}, nil, &prefix)
+	blobsByNetwork, err := store.Search(filter, blobstore.LoadCriteria{LoadValue: loadValue})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to search bookmarks of %s", taskID))
+	}
+	return blobsByNetwork[networkID], nil
+}
+
+// bookmarkKeyPrefix returns the prefix of the blob keys of the bookmarks of a task
+func bookmarkKeyPrefix(taskID string) string {
+	return taskID + "/"
+}
+
+func bookmarkKey(taskID, name string) string {
+	return bookmarkKeyPrefix(taskID) + name
+}
+
+func searchDeadLetters(
+	store blobstore.TransactionalBlobStorage,
+	networkID, taskID string,
+	loadValue bool,
+) (blobstore.Blobs, error) {
+	prefix := deadLetterKeyPrefix(taskID)
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeDeadLetterBlobType}, nil, &prefix)
+	blobsByNetwork, err := store.Search(filter, blobstore.LoadCriteria{LoadValue: loadValue})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to search dead letters of %s", taskID))
+	}
+	return blobsByNetwork[networkID], nil
+}
+
+// deadLetterKeyPrefix returns the prefix of the blob keys of the dead letters of a task
+func deadLetterKeyPrefix(taskID string) string {
+	return taskID + "/"
+}
+
+func deadLetterKey(taskID, id string) string {
+	return deadLetterKeyPrefix(taskID) + id
+}
+
+func searchDeliveryRecordKeys(store blobstore.TransactionalBlobStorage, networkID string, prefix *string) ([]string, error) {
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeDeliveryBlobType}, nil, prefix)
+	blobsByNetwork, err := store.Search(filter, blobstore.LoadCriteria{LoadValue: false})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search delivery records")
+	}
+	return blobsByNetwork[networkID].Keys(), nil
+}
+
+// deliveryKeyPrefix returns the prefix of the blob keys of the delivery records of a task
+func deliveryKeyPrefix(taskID string) string {
+	return taskID + "/"
+}
+
+// deliveryRecordKey returns the blob key of a delivery record. Keys of a task
+// sort in delivery order and carry the delivery time so that records can be
+// selected without loading them.
+func deliveryRecordKey(taskID string, record models.NetworkProbeDeliveryRecord) string {
+	return fmt.Sprintf("%s%020d/%010d", deliveryKeyPrefix(taskID), time.Time(record.DeliveredAt).UnixNano(), record.SequenceNumber)
+}
+
+// getDeliveredAt returns the delivery time carried by a delivery record key
+func getDeliveredAt(key string) (time.Time, bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) < 3 {
+		return time.Time{}, false
+	}
+	nanos, err := strconv.ParseInt(parts[len(parts)-2], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// searchSessionMappings returns the session mappings of a network whose key
+// starts with prefix, if set
+func searchSessionMappings(store blobstore.TransactionalBlobStorage, networkID string, prefix *string) (blobstore.Blobs, error) {
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeSessionMappingBlobType}, nil, prefix)
+	blobsByNetwork, err := store.Search(filter, blobstore.LoadCriteria{LoadValue: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search session mappings")
+	}
+	return blobsByNetwork[networkID], nil
+}
+
+// searchBearerCorrelations returns the bearer correlations of a network
+func searchBearerCorrelations(store blobstore.TransactionalBlobStorage, networkID string) (blobstore.Blobs, error) {
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeBearerCorrelationBlobType}, nil, nil)
+	blobsByNetwork, err := store.Search(filter, blobstore.LoadCriteria{LoadValue: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search bearer correlations")
+	}
+	return blobsByNetwork[networkID], nil
+}
+
+// bearerKey returns the blob key of a bearer of an IMSI, the prefix of the
+// keys of all its bearers when bearerID is empty
+func bearerKey(imsi, bearerID string) string {
+	return imsi + "/" + bearerID
+}
+
+// correlationIDKey returns the blob key of a bearer correlation
+func correlationIDKey(correlationID uint64) string {
+	return fmt.Sprintf("%020d", correlationID)
+}
+
+// drawCryptoCorrelationID draws a correlation ID from the crypto random
+// source, so that the IDs drawn differ from one restart to the next
+func drawCryptoCorrelationID() (uint64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+// sessionMappingKeyPrefix returns the prefix of the blob keys of the session
+// mappings of a correlation ID
+func sessionMappingKeyPrefix(correlationID uint64) string {
+	return fmt.Sprintf("%020d/", correlationID)
+}
+
+// sessionMappingKey returns the blob key of a session mapping. Tasks sharing
+// a correlation ID keep their own mapping of a session.
+func sessionMappingKey(mapping models.NetworkProbeSessionMapping) string {
+	return sessionMappingKeyPrefix(mapping.CorrelationID) + mapping.TaskID + "/" + mapping.SessionID
+}
+
+// mergeSessionMapping extends a session mapping with the mapping of a record
+// delivered after, keeping the identifiers last reported
+func mergeSessionMapping(current, next *models.NetworkProbeSessionMapping) {
+	current.LastSequenceNumber = next.LastSequenceNumber
+	current.LastSeen = next.LastSeen
+	current.TargetID = next.TargetID
+	for _, field := range []struct{ current, next *string }{
+		{&current.BearerID, &next.BearerID},
+		{&current.Teid, &next.Teid},
+		{&current.ChargingID, &next.ChargingID},
+		{&current.Apn, &next.Apn},
+		{&current.IPAddr, &next.IPAddr},
+	} {
+		if len(*field.next) != 0 {
+			*field.current = *field.next
+		}
+	}
+}
+
+func quarantineEntryToBlob(taskID string, entry models.NetworkProbeQuarantineEntry) (blobstore.Blob, error) {
+	marshaledEntry, err := entry.MarshalBinary()
+	if err != nil {
+		return blobstore.Blob{}, errors.Wrap(err, "Error marshaling NetworkProbeQuarantineEntry")
+	}
+	return blobstore.Blob{
+		Type:  NProbeQuarantineBlobType,
+		Key:   quarantineKeyPrefix(taskID) + entry.EventTimestamp + "/" + entry.EventType,
+		Value: marshaledEntry,
+	}, nil
+}
+
 func nprobeDataToBlob(taskID string, data models.NetworkProbeData) (blobstore.Blob, error) {
 	marshaledData, err := data.MarshalBinary()
 	if err != nil {
@@ -14,13 +14,18 @@
 package storage
 
 import (
+	"bytes"
+	"sort"
 	"testing"
 	"time"
 
+	"magma/lte/cloud/go/services/nprobe/keyring"
 	"magma/lte/cloud/go/services/nprobe/obsidian/models"
 	"magma/orc8r/cloud/go/blobstore"
 	"magma/orc8r/cloud/go/blobstore/mocks"
+	configurator_storage "magma/orc8r/cloud/go/services/configurator/storage"
 	"magma/orc8r/cloud/go/storage"
+	merrors "magma/orc8r/lib/go/errors"
 
 	strfmt "github.com/go-openapi/strfmt"
 	"github.com/stretchr/testify/assert"
@@ -92,3 +97,1284 @@ func TestStoreNProbeData(t *testing.T) {
 	blobFactMock.AssertExpectations(t)
 	blobStoreMock.AssertExpectations(t)
 }
+
+func TestGetAllNProbeData(t *testing.T) {
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+
+	nprobeData := models.NetworkProbeData{
+		LastExported:   strfmt.DateTime(time.Now()),
+		TargetID:       "imsi01",
+		SequenceNumber: 7,
+	}
+	blob, err := nprobeDataToBlob("task_id1", nprobeData)
+	assert.NoError(t, err)
+
+	filter := blobstore.CreateSearchFilter(nil, []string{NProbeBlobType}, nil, nil)
+	criteria := blobstore.LoadCriteria{LoadValue: true}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, criteria).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {blob}}, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	allData, err := store.GetAllNProbeData(placeholderNetworkID)
+	assert.NoError(t, err)
+	assert.Len(t, allData, 1)
+	assert.Equal(t, "imsi01", allData["task_id1"].TargetID)
+	assert.Equal(t, uint32(7), allData["task_id1"].SequenceNumber)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestListNProbeNetworks(t *testing.T) {
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+
+	filter := blobstore.CreateSearchFilter(nil, []string{NProbeBlobType}, nil, nil)
+	criteria := blobstore.LoadCriteria{LoadValue: false}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, criteria).
+		Return(map[string]blobstore.Blobs{
+			placeholderNetworkID: {{Type: NProbeBlobType, Key: "task_id1"}},
+			"deleted_network":    {{Type: NProbeBlobType, Key: "task_id2"}},
+		}, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	networks, err := store.ListNProbeNetworks()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"deleted_network", placeholderNetworkID}, networks)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestQuarantineEntries(t *testing.T) {
+	taskID := "task_id1"
+	entry := models.NetworkProbeQuarantineEntry{
+		EventType:      "session_created",
+		StreamName:     "sessiond",
+		EventTimestamp: "2021-02-18T05:13:26.019519+00:00",
+		Field:          "bearer_params",
+		Error:          "invalid bearer_params: ip_addr is not a valid IPv4 address",
+	}
+	blob, err := quarantineEntryToBlob(taskID, entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "task_id1/2021-02-18T05:13:26.019519+00:00/session_created", blob.Key)
+
+	// Store quarantine entry
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{blob}).
+		Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	err = store.StoreQuarantineEntry(placeholderNetworkID, taskID, entry)
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Get quarantine entries
+	networkID := placeholderNetworkID
+	prefix := "task_id1/"
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeQuarantineBlobType}, nil, &prefix)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: true}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {blob}}, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	entries, err := store.GetQuarantineEntries(placeholderNetworkID, taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.NetworkProbeQuarantineEntry{entry}, entries)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Delete quarantine entries
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: false}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {blob}}, nil).Once()
+	blobStoreMock.On("Delete", placeholderNetworkID, []storage.TypeAndKey{{Type: NProbeQuarantineBlobType, Key: blob.Key}}).
+		Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	err = store.DeleteQuarantineEntries(placeholderNetworkID, taskID)
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestActivity(t *testing.T) {
+	var blobFactMock *mocks.BlobStorageFactory
+	var blobStoreMock *mocks.TransactionalBlobStorage
+
+	taskID := "task_id1"
+	hour := time.Now().UTC().Truncate(time.Hour)
+	tk := storage.TypeAndKey{Type: NProbeActivityBlobType, Key: taskID}
+	stored := &models.NetworkProbeActivity{
+		Granularity: models.NetworkProbeActivityGranularityHour,
+		Buckets: []*models.NetworkProbeActivityBucket{
+			{Start: strfmt.DateTime(hour.Add(-ActivityRetention - time.Hour)), Count: 7},
+			{Start: strfmt.DateTime(hour.Add(-time.Hour)), Count: 2},
+			{Start: strfmt.DateTime(hour), Count: 3},
+		},
+	}
+	marshaledStored, err := stored.MarshalBinary()
+	assert.NoError(t, err)
+
+	// Increment activity, expired buckets are dropped
+	expected := &models.NetworkProbeActivity{
+		Granularity: models.NetworkProbeActivityGranularityHour,
+		Buckets: []*models.NetworkProbeActivityBucket{
+			{Start: strfmt.DateTime(hour.Add(-time.Hour)), Count: 2},
+			{Start: strfmt.DateTime(hour), Count: 8},
+		},
+	}
+	marshaledExpected, err := expected.MarshalBinary()
+	assert.NoError(t, err)
+
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).
+		Return(blobstore.Blob{Type: tk.Type, Key: tk.Key, Value: marshaledStored}, nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{
+		{Type: NProbeActivityBlobType, Key: taskID, Value: marshaledExpected},
+	}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	err = store.IncrementActivity(placeholderNetworkID, taskID, map[time.Time]uint64{
+		hour.Add(10 * time.Minute): 5,
+	})
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Get activity
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).
+		Return(blobstore.Blob{Type: tk.Type, Key: tk.Key, Value: marshaledExpected}, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	activity, err := store.GetActivity(placeholderNetworkID, taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, marshaledExpected, mustMarshal(t, activity))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Delete activity
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Delete", placeholderNetworkID, []storage.TypeAndKey{tk}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	err = store.DeleteActivity(placeholderNetworkID, taskID)
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func mustMarshal(t *testing.T, activity *models.NetworkProbeActivity) []byte {
+	marshaled, err := activity.MarshalBinary()
+	assert.NoError(t, err)
+	return marshaled
+}
+
+func TestDeliveryRecords(t *testing.T) {
+	taskID := "task_id1"
+	deliveredAt := time.Unix(1613625206, 0).UTC()
+	records := []models.NetworkProbeDeliveryRecord{
+		{
+			TargetID:       "IMSI001010000000001",
+			SequenceNumber: 9,
+			RecordHash:     "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			Timestamp:      strfmt.DateTime(deliveredAt.Add(-time.Second)),
+			DeliveredAt:    strfmt.DateTime(deliveredAt),
+		},
+		{
+			TargetID:       "IMSI001010000000001",
+			SequenceNumber: 10,
+			RecordHash:     "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752",
+			Timestamp:      strfmt.DateTime(deliveredAt.Add(time.Minute - time.Second)),
+			DeliveredAt:    strfmt.DateTime(deliveredAt.Add(time.Minute)),
+		},
+	}
+	var blobs blobstore.Blobs
+	for _, record := range records {
+		marshaled, err := record.MarshalBinary()
+		assert.NoError(t, err)
+		blobs = append(blobs, blobstore.Blob{
+			Type:  NProbeDeliveryBlobType,
+			Key:   deliveryRecordKey(taskID, record),
+			Value: marshaled,
+		})
+	}
+	assert.Equal(t, "task_id1/01613625206000000000/0000000009", blobs[0].Key)
+
+	// Store delivery records
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobs).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	err := store.StoreDeliveryRecords(placeholderNetworkID, taskID, records)
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Get delivery records, only the ones delivered in the range are loaded
+	networkID := placeholderNetworkID
+	prefix := "task_id1/"
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeDeliveryBlobType}, nil, &prefix)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: false}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {
+			{Type: NProbeDeliveryBlobType, Key: blobs[1].Key},
+			{Type: NProbeDeliveryBlobType, Key: blobs[0].Key},
+		}}, nil).Once()
+	blobStoreMock.On("GetMany", placeholderNetworkID, blobs[1:].TKs()).
+		Return(blobstore.Blobs{blobs[1]}, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	delivered, err := store.GetDeliveryRecords(placeholderNetworkID, taskID, deliveredAt.Add(time.Second), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, delivered, 1)
+	assert.Equal(t, records[1].RecordHash, delivered[0].RecordHash)
+	assert.Equal(t, records[1].SequenceNumber, delivered[0].SequenceNumber)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Delete expired delivery records of all tasks
+	filter = blobstore.CreateSearchFilter(&networkID, []string{NProbeDeliveryBlobType}, nil, nil)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: false}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {
+			{Type: NProbeDeliveryBlobType, Key: blobs[0].Key},
+			{Type: NProbeDeliveryBlobType, Key: blobs[1].Key},
+		}}, nil).Once()
+	blobStoreMock.On("Delete", placeholderNetworkID, blobs[:1].TKs()).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	err = store.DeleteDeliveryRecordsBefore(placeholderNetworkID, deliveredAt.Add(time.Second))
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestSessionMappings(t *testing.T) {
+	firstSeen := time.Unix(1613625206, 0).UTC()
+	existing := models.NetworkProbeSessionMapping{
+		CorrelationID:       7,
+		TaskID:              "task_id1",
+		TargetID:            "IMSI001010000000001",
+		SessionID:           "IMSI001010000000001-1234",
+		BearerID:            "5",
+		Teid:                "0x0000a1b2",
+		Apn:                 "internet",
+		IPAddr:              "192.168.128.12",
+		FirstSequenceNumber: 3,
+		LastSequenceNumber:  3,
+		FirstSeen:           strfmt.DateTime(firstSeen),
+		LastSeen:            strfmt.DateTime(firstSeen),
+	}
+	marshaled, err := existing.MarshalBinary()
+	assert.NoError(t, err)
+	existingBlob := blobstore.Blob{Type: NProbeSessionMappingBlobType, Key: sessionMappingKey(existing), Value: marshaled}
+	assert.Equal(t, "00000000000000000007/task_id1/IMSI001010000000001-1234", existingBlob.Key)
+
+	// Store session mappings, merged into the mapping of the session
+	update := existing
+	update.Teid = ""
+	update.IPAddr = "192.168.128.13"
+	update.FirstSequenceNumber, update.LastSequenceNumber = 8, 8
+	update.FirstSeen = strfmt.DateTime(firstSeen.Add(time.Hour))
+	update.LastSeen = update.FirstSeen
+	other := existing
+	other.SessionID = "IMSI001010000000001-5678"
+
+	var stored blobstore.Blobs
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("GetMany", placeholderNetworkID, mock.Anything).
+		Return(blobstore.Blobs{existingBlob}, nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(blobstore.Blobs) }).
+		Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	err = store.StoreSessionMappings(placeholderNetworkID, []models.NetworkProbeSessionMapping{update, other})
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+	assert.Len(t, stored, 2)
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Key < stored[j].Key })
+	merged := models.NetworkProbeSessionMapping{}
+	assert.NoError(t, merged.UnmarshalBinary(stored[0].Value))
+	assert.Equal(t, uint32(3), merged.FirstSequenceNumber)
+	assert.Equal(t, uint32(8), merged.LastSequenceNumber)
+	assert.Equal(t, existing.FirstSeen, merged.FirstSeen)
+	assert.Equal(t, update.LastSeen, merged.LastSeen)
+	assert.Equal(t, "0x0000a1b2", merged.Teid)
+	assert.Equal(t, "192.168.128.13", merged.IPAddr)
+	assert.Equal(t, sessionMappingKey(other), stored[1].Key)
+
+	// Get the mappings of a session of a correlation ID
+	networkID := placeholderNetworkID
+	prefix := "00000000000000000007/"
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeSessionMappingBlobType}, nil, &prefix)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: true}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: stored}, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	mappings, err := store.GetSessionMappings(placeholderNetworkID, 7, other.SessionID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.NetworkProbeSessionMapping{other}, mappings)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Delete the mappings last seen before the cutoff
+	filter = blobstore.CreateSearchFilter(&networkID, []string{NProbeSessionMappingBlobType}, nil, nil)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: true}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: stored}, nil).Once()
+	blobStoreMock.On("Delete", placeholderNetworkID, stored[1:].TKs()).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	err = store.DeleteSessionMappingsBefore(placeholderNetworkID, firstSeen.Add(time.Minute))
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestBearerCorrelations(t *testing.T) {
+	allocatedAt := time.Unix(1613625206, 0).UTC()
+	bearerTK := storage.TypeAndKey{Type: NProbeBearerBlobType, Key: "IMSI001010000000001/5"}
+	correlation := models.NetworkProbeBearerCorrelation{
+		CorrelationID: 9,
+		Imsi:          "IMSI001010000000001",
+		BearerID:      "5",
+		AllocatedAt:   strfmt.DateTime(allocatedAt),
+	}
+	marshaled, err := correlation.MarshalBinary()
+	assert.NoError(t, err)
+	correlationBlob := blobstore.Blob{Type: NProbeBearerCorrelationBlobType, Key: "00000000000000000009", Value: marshaled}
+	bearerBlob := blobstore.Blob{Type: NProbeBearerBlobType, Key: bearerTK.Key, Value: []byte("9")}
+	draws := func(ids ...uint64) func() (uint64, error) {
+		return func() (uint64, error) {
+			id := ids[0]
+			ids = ids[1:]
+			return id, nil
+		}
+	}
+
+	// Allocate a correlation ID to a new bearer, redrawn while colliding
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, bearerTK).Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, storage.TypeAndKey{Type: NProbeBearerCorrelationBlobType, Key: "00000000000000000007"}).
+		Return(blobstore.Blob{}, nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, storage.TypeAndKey{Type: NProbeBearerCorrelationBlobType, Key: "00000000000000000009"}).
+		Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{bearerBlob, correlationBlob}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := &nprobeBlobStore{factory: blobFactMock, drawCorrelationID: draws(7, 0, 9)}
+	allocated, err := store.AllocateCorrelationID(placeholderNetworkID, "IMSI001010000000001", "5", allocatedAt)
+	assert.NoError(t, err)
+	assert.Equal(t, correlation, *allocated)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// The events of a bearer up to its release get its correlation ID
+	released := correlation
+	released.ReleasedAt = strfmt.DateTime(allocatedAt.Add(time.Hour))
+	marshaledReleased, err := released.MarshalBinary()
+	assert.NoError(t, err)
+	releasedBlob := blobstore.Blob{Type: NProbeBearerCorrelationBlobType, Key: correlationBlob.Key, Value: marshaledReleased}
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, bearerTK).Return(bearerBlob, nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, storage.TypeAndKey{Type: NProbeBearerCorrelationBlobType, Key: correlationBlob.Key}).
+		Return(releasedBlob, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = &nprobeBlobStore{factory: blobFactMock}
+	allocated, err = store.AllocateCorrelationID(placeholderNetworkID, "IMSI001010000000001", "5", allocatedAt.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, released, *allocated)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Bearers are not allocated a correlation ID once all drawn collide
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, bearerTK).Return(bearerBlob, nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, storage.TypeAndKey{Type: NProbeBearerCorrelationBlobType, Key: correlationBlob.Key}).
+		Return(releasedBlob, nil).Times(1 + maxCorrelationIDAttempts)
+
+	store = &nprobeBlobStore{factory: blobFactMock, drawCorrelationID: func() (uint64, error) { return 9, nil }}
+	_, err = store.AllocateCorrelationID(placeholderNetworkID, "IMSI001010000000001", "5", allocatedAt.Add(2*time.Hour))
+	assert.Equal(t, ErrCorrelationIDCollision, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Release the bearers of an IMSI
+	networkID := placeholderNetworkID
+	prefix := "IMSI001010000000001/"
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeBearerBlobType}, nil, &prefix)
+	var stored blobstore.Blobs
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: false}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {{Type: NProbeBearerBlobType, Key: bearerTK.Key}}}, nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, bearerTK).Return(bearerBlob, nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, storage.TypeAndKey{Type: NProbeBearerCorrelationBlobType, Key: correlationBlob.Key}).
+		Return(correlationBlob, nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(blobstore.Blobs) }).
+		Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = &nprobeBlobStore{factory: blobFactMock}
+	err = store.ReleaseCorrelationIDs(placeholderNetworkID, "IMSI001010000000001", "", allocatedAt.Add(time.Hour))
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+	assert.Equal(t, blobstore.Blobs{releasedBlob}, stored)
+
+	// Delete the correlation IDs released before the cutoff, with their bearer
+	other := blobstore.Blob{Type: NProbeBearerBlobType, Key: "IMSI001010000000001/6", Value: []byte("11")}
+	correlationFilter := blobstore.CreateSearchFilter(&networkID, []string{NProbeBearerCorrelationBlobType}, nil, nil)
+	bearerFilter := blobstore.CreateSearchFilter(&networkID, []string{NProbeBearerBlobType}, nil, nil)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", correlationFilter, blobstore.LoadCriteria{LoadValue: true}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {releasedBlob}}, nil).Once()
+	blobStoreMock.On("Search", bearerFilter, blobstore.LoadCriteria{LoadValue: true}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {bearerBlob, other}}, nil).Once()
+	blobStoreMock.On("Delete", placeholderNetworkID, []storage.TypeAndKey{
+		{Type: NProbeBearerCorrelationBlobType, Key: correlationBlob.Key},
+		bearerTK,
+	}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = &nprobeBlobStore{factory: blobFactMock}
+	err = store.DeleteBearerCorrelationsBefore(placeholderNetworkID, allocatedAt.Add(2*time.Hour))
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Store correlation IDs allocated elsewhere, the bearer being mapped to
+	// the one allocated last
+	reallocated := models.NetworkProbeBearerCorrelation{
+		CorrelationID: 11,
+		Imsi:          "IMSI001010000000001",
+		BearerID:      "5",
+		AllocatedAt:   strfmt.DateTime(allocatedAt.Add(2 * time.Hour)),
+	}
+	marshaledReallocated, err := reallocated.MarshalBinary()
+	assert.NoError(t, err)
+	reallocatedBlob := blobstore.Blob{Type: NProbeBearerCorrelationBlobType, Key: "00000000000000000011", Value: marshaledReallocated}
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{
+		reallocatedBlob,
+		releasedBlob,
+		{Type: NProbeBearerBlobType, Key: bearerTK.Key, Value: []byte("11")},
+	}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = &nprobeBlobStore{factory: blobFactMock}
+	err = store.StoreBearerCorrelations(placeholderNetworkID, []models.NetworkProbeBearerCorrelation{reallocated, released})
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestEncryptedRecords(t *testing.T) {
+	keys, err := keyring.NewKeyring(map[string][]byte{"k1": bytes.Repeat([]byte{1}, keyring.KeySize)}, "k1")
+	assert.NoError(t, err)
+	taskID := "task_id1"
+	entry := models.NetworkProbeQuarantineEntry{
+		EventType:      "session_created",
+		StreamName:     "sessiond",
+		EventTimestamp: "2021-02-18T05:13:26.019519+00:00",
+		Field:          "bearer_params",
+		Error:          "invalid bearer_params: ip_addr is not a valid IPv4 address",
+	}
+	plain, err := quarantineEntryToBlob(taskID, entry)
+	assert.NoError(t, err)
+
+	// Store quarantine entry, sealed
+	var stored blobstore.Blobs
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(blobstore.Blobs) }).
+		Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewEncryptedNProbeBlobstore(blobFactMock, keys)
+	err = store.StoreQuarantineEntry(placeholderNetworkID, taskID, entry)
+	assert.NoError(t, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+	assert.Len(t, stored, 1)
+	assert.Equal(t, plain.Key, stored[0].Key)
+	assert.Equal(t, "k1", keyring.GetKeyID(stored[0].Value))
+	assert.False(t, bytes.Contains(stored[0].Value, []byte("bearer_params")))
+	sealed := stored[0]
+
+	// Get quarantine entries, sealed or stored before encryption was enabled
+	networkID := placeholderNetworkID
+	prefix := "task_id1/"
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeQuarantineBlobType}, nil, &prefix)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: true}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {sealed, plain}}, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewEncryptedNProbeBlobstore(blobFactMock, keys)
+	entries, err := store.GetQuarantineEntries(placeholderNetworkID, taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.NetworkProbeQuarantineEntry{entry, entry}, entries)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Re-encrypt records after a key rotation, the values sealed with the
+	// former key or not sealed are sealed with the new one
+	assert.NoError(t, keys.Set(map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, keyring.KeySize),
+		"k2": bytes.Repeat([]byte{2}, keyring.KeySize),
+	}, "k2"))
+	current, err := keys.Seal(plain.Value)
+	assert.NoError(t, err)
+	upToDate := blobstore.Blob{Type: NProbeDeliveryBlobType, Key: "task_id1/01613625206000000000/0000000009", Value: current}
+	filter = blobstore.CreateSearchFilter(
+		&networkID,
+		[]string{NProbeQuarantineBlobType, NProbeDeadLetterBlobType, NProbeDeliveryBlobType, NProbeSessionMappingBlobType, NProbeBearerCorrelationBlobType},
+		nil,
+		nil,
+	)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: true}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {sealed, upToDate, plain}}, nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(blobstore.Blobs) }).
+		Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewEncryptedNProbeBlobstore(blobFactMock, keys)
+	count, err := store.ReencryptRecords(placeholderNetworkID, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+	assert.Len(t, stored, 2)
+	for _, blob := range stored {
+		assert.Equal(t, "k2", keyring.GetKeyID(blob.Value))
+		value, err := keys.Open(blob.Value)
+		assert.NoError(t, err)
+		assert.Equal(t, plain.Value, value)
+	}
+}
+
+func TestLease(t *testing.T) {
+	now := time.Unix(1613625206, 0).UTC()
+	tk := storage.TypeAndKey{Type: NProbeLeaseBlobType, Key: leaseKey}
+
+	// Acquire the lease, none was stored yet
+	expected, err := leaseToBlob(&Lease{Holder: "nprobe-0", Epoch: 1, ExpiresAt: now.Add(15 * time.Second)})
+	assert.NoError(t, err)
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", &storage.TxOptions{Isolation: storage.LevelSerializable}).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", configurator_storage.InternalNetworkID, tk).Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+	blobStoreMock.On("CreateOrUpdate", configurator_storage.InternalNetworkID, blobstore.Blobs{expected}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	lease, err := store.AcquireLease("nprobe-0", now, 15*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, &Lease{Holder: "nprobe-0", Epoch: 1, ExpiresAt: now.Add(15 * time.Second)}, lease)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// The lease can't be acquired by another instance until it expires
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", configurator_storage.InternalNetworkID, tk).Return(expected, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	lease, err = store.AcquireLease("nprobe-1", now.Add(10*time.Second), 15*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "nprobe-0", lease.Holder)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Once expired, it is acquired for a new epoch
+	acquired, err := leaseToBlob(&Lease{Holder: "nprobe-1", Epoch: 2, ExpiresAt: now.Add(30 * time.Second)})
+	assert.NoError(t, err)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", configurator_storage.InternalNetworkID, tk).Return(expected, nil).Once()
+	blobStoreMock.On("CreateOrUpdate", configurator_storage.InternalNetworkID, blobstore.Blobs{acquired}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	lease, err = store.AcquireLease("nprobe-1", now.Add(15*time.Second), 15*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), lease.Epoch)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Releasing the lease expires it, keeping its epoch
+	released, err := leaseToBlob(&Lease{Holder: "nprobe-1", Epoch: 2})
+	assert.NoError(t, err)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", configurator_storage.InternalNetworkID, tk).Return(acquired, nil).Once()
+	blobStoreMock.On("CreateOrUpdate", configurator_storage.InternalNetworkID, blobstore.Blobs{released}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.ReleaseLease("nprobe-1"))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestTaskPause(t *testing.T) {
+	tk := storage.TypeAndKey{Type: NProbeTaskPauseBlobType, Key: "task1"}
+	pause := models.NetworkProbeTaskPause{
+		Paused:   true,
+		PausedAt: strfmt.DateTime(time.Unix(1613625206, 0).UTC()),
+		PausedBy: "operator1",
+	}
+	marshaledPause, err := pause.MarshalBinary()
+	assert.NoError(t, err)
+	blob := blobstore.Blob{Type: NProbeTaskPauseBlobType, Key: "task1", Value: marshaledPause}
+
+	// Store the pause state
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{blob}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.StoreTaskPause(placeholderNetworkID, "task1", pause))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Get it back
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blob, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err := store.GetTaskPause(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, pause, *actual)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Tasks never paused are not paused
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err = store.GetTaskPause(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.False(t, actual.Paused)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestTaskLifecycle(t *testing.T) {
+	tk := storage.TypeAndKey{Type: NProbeTaskLifecycleBlobType, Key: "task1"}
+	lifecycle := models.NetworkProbeTaskLifecycle{
+		State:  models.NetworkProbeTaskLifecycleStateSuspended,
+		Since:  strfmt.DateTime(time.Unix(1613625206, 0).UTC()),
+		Reason: "paused",
+		Transitions: []*models.NetworkProbeTaskTransition{
+			{
+				From:   models.NetworkProbeTaskTransitionFromActive,
+				To:     models.NetworkProbeTaskTransitionToSuspended,
+				At:     strfmt.DateTime(time.Unix(1613625206, 0).UTC()),
+				Reason: "paused",
+				Actor:  "operator1",
+			},
+		},
+	}
+	marshaledLifecycle, err := lifecycle.MarshalBinary()
+	assert.NoError(t, err)
+	blob := blobstore.Blob{Type: NProbeTaskLifecycleBlobType, Key: "task1", Value: marshaledLifecycle}
+
+	// Store the lifecycle state
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{blob}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.StoreTaskLifecycle(placeholderNetworkID, "task1", lifecycle))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Get it back
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blob, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err := store.GetTaskLifecycle(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, lifecycle, *actual)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Tasks without any transition are pending
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err = store.GetTaskLifecycle(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.NetworkProbeTaskLifecycleStatePending, actual.State)
+	assert.Empty(t, actual.Transitions)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestTaskXIDRotation(t *testing.T) {
+	tk := storage.TypeAndKey{Type: NProbeTaskXIDRotationBlobType, Key: "task1"}
+	rotation := models.NetworkProbeTaskXidRotation{
+		Xid:         "0a1d8c2e-0000-4000-8000-000000000001",
+		PreviousXid: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		RequestedAt: strfmt.DateTime(time.Unix(1613625206, 0).UTC()),
+		RequestedBy: "operator1",
+	}
+	marshaledRotation, err := rotation.MarshalBinary()
+	assert.NoError(t, err)
+	blob := blobstore.Blob{Type: NProbeTaskXIDRotationBlobType, Key: "task1", Value: marshaledRotation}
+
+	// Store the rotation
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{blob}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.StoreTaskXIDRotation(placeholderNetworkID, "task1", rotation))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Get it back
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blob, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err := store.GetTaskXIDRotation(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, rotation, *actual)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Tasks never rotated have no rotation
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err = store.GetTaskXIDRotation(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Empty(t, actual.Xid)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestTaskReplay(t *testing.T) {
+	tk := storage.TypeAndKey{Type: NProbeTaskReplayBlobType, Key: "task1"}
+	replay := models.NetworkProbeTaskReplay{
+		Start:          strfmt.DateTime(time.Unix(1613620000, 0).UTC()),
+		End:            strfmt.DateTime(time.Unix(1613623600, 0).UTC()),
+		RequestedAt:    strfmt.DateTime(time.Unix(1613625206, 0).UTC()),
+		RequestedBy:    "operator1",
+		ReplayedEvents: 50,
+	}
+	marshaledReplay, err := replay.MarshalBinary()
+	assert.NoError(t, err)
+	blob := blobstore.Blob{Type: NProbeTaskReplayBlobType, Key: "task1", Value: marshaledReplay}
+
+	// Store the replay
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{blob}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.StoreTaskReplay(placeholderNetworkID, "task1", replay))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Get it back
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blob, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err := store.GetTaskReplay(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, replay, *actual)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Tasks never replayed have no replay
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err = store.GetTaskReplay(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.True(t, time.Time(actual.Start).IsZero())
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestTaskTestRecord(t *testing.T) {
+	tk := storage.TypeAndKey{Type: NProbeTaskTestRecordBlobType, Key: "task1"}
+	testRecord := models.NetworkProbeTaskTestRecord{
+		ID:             "5b0c3b9e-7d4a-4a4e-9d1e-3f1f2b6f0a11",
+		RequestedAt:    strfmt.DateTime(time.Unix(1613625206, 0).UTC()),
+		RequestedBy:    "operator1",
+		SequenceNumber: 12,
+		DeliveredAt:    strfmt.DateTime(time.Unix(1613625210, 0).UTC()),
+	}
+	marshaledTestRecord, err := testRecord.MarshalBinary()
+	assert.NoError(t, err)
+	blob := blobstore.Blob{Type: NProbeTaskTestRecordBlobType, Key: "task1", Value: marshaledTestRecord}
+
+	// Store the test record
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{blob}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.StoreTaskTestRecord(placeholderNetworkID, "task1", testRecord))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Get it back
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blob, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err := store.GetTaskTestRecord(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, testRecord, *actual)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Tasks never tested have no test record
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err = store.GetTaskTestRecord(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Empty(t, actual.ID)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestTaskStatistics(t *testing.T) {
+	tk := storage.TypeAndKey{Type: NProbeTaskStatisticsBlobType, Key: "task1"}
+	start := time.Unix(1613625206, 0).UTC()
+	end := start.Add(24 * time.Hour)
+	marshal := func(statistics models.NetworkProbeTaskStatistics) []byte {
+		marshaled, err := statistics.MarshalBinary()
+		assert.NoError(t, err)
+		return marshaled
+	}
+
+	// Counting starts with the first records of a task
+	counted := models.NetworkProbeTaskStatistics{
+		Current: &models.NetworkProbeStatisticsInterval{Start: strfmt.DateTime(start), Records: 3, Bytes: 300},
+	}
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{
+		{Type: NProbeTaskStatisticsBlobType, Key: "task1", Value: marshal(counted)},
+	}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.IncrementTaskStatistics(placeholderNetworkID, "task1", 3, 300, start))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Reporting an interval restarts counting at its end, with the records
+	// counted since it was reported
+	counted.Current.Records, counted.Current.Bytes = 5, 500
+	reported := models.NetworkProbeStatisticsInterval{
+		Start:          strfmt.DateTime(start),
+		End:            strfmt.DateTime(end),
+		Records:        3,
+		Bytes:          300,
+		SequenceNumber: 12,
+		ReportedAt:     strfmt.DateTime(end),
+	}
+	expected := models.NetworkProbeTaskStatistics{
+		Current:  &models.NetworkProbeStatisticsInterval{Start: strfmt.DateTime(end), Records: 2, Bytes: 200},
+		Reported: []*models.NetworkProbeStatisticsInterval{&reported},
+	}
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).
+		Return(blobstore.Blob{Type: tk.Type, Key: tk.Key, Value: marshal(counted)}, nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{
+		{Type: NProbeTaskStatisticsBlobType, Key: "task1", Value: marshal(expected)},
+	}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.ReportTaskStatistics(placeholderNetworkID, "task1", reported))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Get them back
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).
+		Return(blobstore.Blob{Type: tk.Type, Key: tk.Key, Value: marshal(expected)}, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err := store.GetTaskStatistics(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, *actual)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Storing them replaces them as is, e.g. when restored
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{
+		{Type: NProbeTaskStatisticsBlobType, Key: "task1", Value: marshal(counted)},
+	}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.StoreTaskStatistics(placeholderNetworkID, "task1", counted))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestTaskDeletion(t *testing.T) {
+	tk := storage.TypeAndKey{Type: NProbeTaskDeletionBlobType, Key: "task1"}
+	deletion := models.NetworkProbeTaskDeletion{
+		RequestedAt: strfmt.DateTime(time.Unix(1613625206, 0).UTC()),
+		RequestedBy: "operator1",
+	}
+	marshaledDeletion, err := deletion.MarshalBinary()
+	assert.NoError(t, err)
+	blob := blobstore.Blob{Type: NProbeTaskDeletionBlobType, Key: "task1", Value: marshaledDeletion}
+
+	// Store the deletion
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{blob}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.StoreTaskDeletion(placeholderNetworkID, "task1", deletion))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Get it back
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blob, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err := store.GetTaskDeletion(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, deletion, *actual)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Tasks whose deletion wasn't requested have none
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, tk).Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	actual, err = store.GetTaskDeletion(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.False(t, models.IsTaskDeletionPending(actual))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestBookmarks(t *testing.T) {
+	first := models.NetworkProbeBookmark{
+		Name:           "sample-b",
+		SequenceNumber: 12,
+		Timestamp:      strfmt.DateTime(time.Unix(1613625206, 0).UTC()),
+		CreatedBy:      "auditor1",
+	}
+	second := first
+	second.Name, second.SequenceNumber = "sample-a", 40
+	marshaledFirst, err := first.MarshalBinary()
+	assert.NoError(t, err)
+	marshaledSecond, err := second.MarshalBinary()
+	assert.NoError(t, err)
+	firstBlob := blobstore.Blob{Type: NProbeBookmarkBlobType, Key: "task1/sample-b", Value: marshaledFirst}
+	secondBlob := blobstore.Blob{Type: NProbeBookmarkBlobType, Key: "task1/sample-a", Value: marshaledSecond}
+
+	// Store a bookmark
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{firstBlob}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.StoreBookmark(placeholderNetworkID, "task1", first))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Bookmarks are listed by sequence number
+	networkID := placeholderNetworkID
+	prefix := "task1/"
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeBookmarkBlobType}, nil, &prefix)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: true}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {secondBlob, firstBlob}}, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	bookmarks, err := store.GetBookmarks(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.NetworkProbeBookmark{first, second}, bookmarks)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Unknown bookmarks are not found
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, storage.TypeAndKey{Type: NProbeBookmarkBlobType, Key: "task1/sample-c"}).
+		Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	_, err = store.GetBookmark(placeholderNetworkID, "task1", "sample-c")
+	assert.Equal(t, merrors.ErrNotFound, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
+
+func TestDeadLetters(t *testing.T) {
+	first := models.NetworkProbeDeadLetter{
+		ID:             "letter1",
+		SequenceNumber: 40,
+		Record:         strfmt.Base64{0x30, 0x03},
+		FailureClass:   "remote_nack",
+		Rejections:     3,
+		DeadLetteredAt: strfmt.DateTime(time.Unix(1613625206, 0).UTC()),
+	}
+	second := first
+	second.ID, second.SequenceNumber = "letter2", 12
+	marshaledFirst, err := first.MarshalBinary()
+	assert.NoError(t, err)
+	marshaledSecond, err := second.MarshalBinary()
+	assert.NoError(t, err)
+	firstBlob := blobstore.Blob{Type: NProbeDeadLetterBlobType, Key: "task1/letter1", Value: marshaledFirst}
+	secondBlob := blobstore.Blob{Type: NProbeDeadLetterBlobType, Key: "task1/letter2", Value: marshaledSecond}
+
+	// Store a dead letter
+	blobFactMock := &mocks.BlobStorageFactory{}
+	blobStoreMock := &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("CreateOrUpdate", placeholderNetworkID, blobstore.Blobs{firstBlob}).Return(nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store := NewNProbeBlobstore(blobFactMock)
+	assert.NoError(t, store.StoreDeadLetter(placeholderNetworkID, "task1", first))
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Dead letters are listed by sequence number
+	networkID := placeholderNetworkID
+	prefix := "task1/"
+	filter := blobstore.CreateSearchFilter(&networkID, []string{NProbeDeadLetterBlobType}, nil, &prefix)
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Search", filter, blobstore.LoadCriteria{LoadValue: true}).
+		Return(map[string]blobstore.Blobs{placeholderNetworkID: {firstBlob, secondBlob}}, nil).Once()
+	blobStoreMock.On("Commit").Return(nil).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	letters, err := store.GetDeadLetters(placeholderNetworkID, "task1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.NetworkProbeDeadLetter{second, first}, letters)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+
+	// Unknown dead letters are not found
+	blobFactMock = &mocks.BlobStorageFactory{}
+	blobStoreMock = &mocks.TransactionalBlobStorage{}
+	blobFactMock.On("StartTransaction", mock.Anything).Return(blobStoreMock, nil).Once()
+	blobStoreMock.On("Rollback").Return(nil).Once()
+	blobStoreMock.On("Get", placeholderNetworkID, storage.TypeAndKey{Type: NProbeDeadLetterBlobType, Key: "task1/letter3"}).
+		Return(blobstore.Blob{}, merrors.ErrNotFound).Once()
+
+	store = NewNProbeBlobstore(blobFactMock)
+	_, err = store.GetDeadLetter(placeholderNetworkID, "task1", "letter3")
+	assert.Equal(t, merrors.ErrNotFound, err)
+	blobFactMock.AssertExpectations(t)
+	blobStoreMock.AssertExpectations(t)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streamer
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	nprobe_protos "magma/lte/cloud/go/services/nprobe/protos"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/lte/cloud/go/services/subscriberdb"
+	"magma/orc8r/cloud/go/services/configurator"
+	merrors "magma/orc8r/lib/go/errors"
+	"magma/orc8r/lib/go/protos"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/pkg/errors"
+)
+
+var logger = logging.New(logging.ComponentMain)
+
+const imsiPrefix = "IMSI"
+
+// TargetsProvider streams to the gateways of a network the targets whose
+// user plane they mirror: the targets of the tasks delivering all records,
+// unless paused or suspended by the kill switch of the network, or targeting
+// a protected identity of the network. Each update
+// replaces the targets of the gateway, so that the filters of the tasks
+// deleted are removed by the next one.
+type TargetsProvider struct {
+	Storage storage.NProbeStorage
+}
+
+func (p *TargetsProvider) GetUpdates(ctx context.Context, gatewayId string, extraArgs *any.Any) ([]*protos.DataUpdate, error) {
+	gateway, err := configurator.LoadEntityForPhysicalID(gatewayId, configurator.EntityLoadCriteria{}, serdes.Entity)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load magmad gateway for physical ID %s", gatewayId)
+	}
+	networkID := gateway.NetworkID
+	killSwitch, err := p.Storage.GetKillSwitch(networkID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load kill switch of network %s", networkID)
+	}
+	if killSwitch.Active {
+		return []*protos.DataUpdate{}, nil
+	}
+	protected, err := getProtectedIdentities(networkID)
+	if err != nil {
+		return nil, err
+	}
+	ents, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeTaskEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "load tasks of network %s", networkID)
+	}
+
+	targets := make([]*nprobe_protos.InterceptionTarget, 0, len(ents))
+	for _, ent := range ents {
+		task := (&models.NetworkProbeTask{}).FromBackendModels(ent)
+		target, err := p.getTarget(networkID, task, protected)
+		if err != nil {
+			return nil, err
+		}
+		if target != nil {
+			targets = append(targets, target)
+		}
+	}
+	return targetsToUpdates(targets)
+}
+
+// getTarget returns the target of a task mirrored by the gateways, nil if
+// none of its bearers are
+func (p *TargetsProvider) getTarget(networkID string, task *models.NetworkProbeTask, protected []*models.NetworkProbeProtectedIdentity) (*nprobe_protos.InterceptionTarget, error) {
+	details := task.TaskDetails
+	if details == nil || details.DeliveryType != models.NetworkProbeTaskDetailsDeliveryTypeAll || details.OneShot {
+		return nil, nil
+	}
+	// the bearers of a target are mirrored within its warrant only
+	if !models.IsWithinWarrant(details, time.Now()) {
+		return nil, nil
+	}
+	if models.GetProtectedTarget(protected, details) != nil {
+		logger.V(2).Infof("Not streaming protected %s target of task %s", details.TargetType, task.TaskID)
+		return nil, nil
+	}
+	pause, err := p.Storage.GetTaskPause(networkID, string(task.TaskID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "load pause state of task %s", task.TaskID)
+	}
+	if pause.Paused {
+		return nil, nil
+	}
+	lifecycle, err := p.Storage.GetTaskLifecycle(networkID, string(task.TaskID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "load lifecycle state of task %s", task.TaskID)
+	}
+	// the pending, suspended, expiring, terminated and failed tasks don't
+	// intercept
+	if lifecycle.State != models.NetworkProbeTaskLifecycleStateActive {
+		return nil, nil
+	}
+
+	var imsi string
+	switch details.TargetType {
+	case models.NetworkProbeTaskDetailsTargetTypeImsi:
+		imsi = details.TargetID
+	case models.NetworkProbeTaskDetailsTargetTypeMsisdn:
+		imsi, err = subscriberdb.GetIMSIForMSISDN(networkID, strings.TrimPrefix(details.TargetID, "+"))
+		if err == merrors.ErrNotFound {
+			// mirrored once the MSISDN is assigned
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolve MSISDN of task %s", task.TaskID)
+		}
+	default:
+		// the IMEI of a target is only known from its events and the IMSIs of
+		// a range aren't enumerated, their bearers aren't mirrored by the
+		// gateways
+		logger.V(2).Infof("Not streaming %s target of task %s", details.TargetType, task.TaskID)
+		return nil, nil
+	}
+	digits := strings.TrimPrefix(imsi, imsiPrefix)
+	if len(digits) == 0 {
+		return nil, nil
+	}
+	if models.GetProtectedIdentity(protected, models.NetworkProbeProtectedIdentityIdentityTypeImsi, digits) != nil {
+		logger.V(2).Infof("Not streaming %s target of task %s assigned to a protected IMSI", details.TargetType, task.TaskID)
+		return nil, nil
+	}
+	return &nprobe_protos.InterceptionTarget{
+		TaskId:        string(task.TaskID),
+		Imsi:          imsiPrefix + digits,
+		CorrelationId: details.CorrelationID,
+		BearerFilters: models.ToProtoBearerFilters(details.BearerFilters),
+	}, nil
+}
+
+func targetsToUpdates(targets []*nprobe_protos.InterceptionTarget) ([]*protos.DataUpdate, error) {
+	ret := make([]*protos.DataUpdate, 0, len(targets))
+	for _, target := range targets {
+		marshaledProto, err := proto.Marshal(target)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, &protos.DataUpdate{Key: target.TaskId, Value: marshaledProto})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Key < ret[j].Key })
+	return ret, nil
+}
+
+// getProtectedIdentities returns the protected identities of a network,
+// whose bearers are never mirrored
+func getProtectedIdentities(networkID string) ([]*models.NetworkProbeProtectedIdentity, error) {
+	config, err := configurator.LoadNetworkConfig(networkID, lte.NetworkProbeConfigType, serdes.Network)
+	if err == merrors.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "load network probe config of network %s", networkID)
+	}
+	return config.(*models.NetworkProbeNetworkConfig).ProtectedIdentities, nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package streamer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	nprobe_protos "magma/lte/cloud/go/services/nprobe/protos"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/lte/cloud/go/services/nprobe/streamer"
+	"magma/lte/cloud/go/services/subscriberdb"
+	subscriberdb_test_init "magma/lte/cloud/go/services/subscriberdb/test_init"
+	"magma/orc8r/cloud/go/orc8r"
+	"magma/orc8r/cloud/go/services/configurator"
+	configurator_test_init "magma/orc8r/cloud/go/services/configurator/test_init"
+	"magma/orc8r/cloud/go/test_utils"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetsProvider(t *testing.T) {
+	configurator_test_init.StartTestService(t)
+	subscriberdb_test_init.StartTestService(t)
+	store := storage.NewNProbeBlobstore(test_utils.NewSQLBlobstore(t, "nprobe_streamer_test_blobstore"))
+	provider := &streamer.TargetsProvider{Storage: store}
+
+	err := configurator.CreateNetwork(configurator.Network{ID: "n1"}, serdes.Network)
+	assert.NoError(t, err)
+	_, err = configurator.CreateEntity("n1", configurator.NetworkEntity{Type: orc8r.MagmadGatewayType, Key: "g1", PhysicalID: "hw1"}, serdes.Entity)
+	assert.NoError(t, err)
+	assert.NoError(t, subscriberdb.SetIMSIForMSISDN("n1", "33612345678", "IMSI001010000000003"))
+	expired := newTask("task7", "IMSI001010000000007", models.NetworkProbeTaskDetailsTargetTypeImsi, "all")
+	expired.Config.(*models.NetworkProbeTaskDetails).EndTime = strfmt.DateTime(time.Now().Add(-time.Hour))
+	pending := newTask("task8", "IMSI001010000000008", models.NetworkProbeTaskDetailsTargetTypeImsi, "all")
+	pending.Config.(*models.NetworkProbeTaskDetails).StartTime = strfmt.DateTime(time.Now().Add(time.Hour))
+	_, err = configurator.CreateEntities("n1", []configurator.NetworkEntity{
+		newTask("task1", "IMSI001010000000001", models.NetworkProbeTaskDetailsTargetTypeImsi, "all", &models.NetworkProbeBearerFilter{Apn: "internet", Qci: 9}),
+		newTask("task2", "IMSI001010000000002", models.NetworkProbeTaskDetailsTargetTypeImsi, "events_only"),
+		newTask("task3", "+33612345678", models.NetworkProbeTaskDetailsTargetTypeMsisdn, "all"),
+		newTask("task4", "33699999999", models.NetworkProbeTaskDetailsTargetTypeMsisdn, "all"),
+		newTask("task5", "356938035643809", models.NetworkProbeTaskDetailsTargetTypeImei, "all"),
+		newTask("task6", "IMSI001010000000006", models.NetworkProbeTaskDetailsTargetTypeImsi, "all"),
+		expired,
+		pending,
+		newTask("task9", "IMSI001010000000009", models.NetworkProbeTaskDetailsTargetTypeImsi, "all"),
+	}, serdes.Entity)
+	assert.NoError(t, err)
+	for _, taskID := range []string{"task1", "task2", "task3", "task4", "task5", "task6", "task7", "task8"} {
+		assert.NoError(t, store.StoreTaskLifecycle("n1", taskID, models.NetworkProbeTaskLifecycle{State: models.NetworkProbeTaskLifecycleStateActive}))
+	}
+	assert.NoError(t, store.StoreTaskPause("n1", "task6", models.NetworkProbeTaskPause{Paused: true}))
+
+	// only the IMSI and assigned MSISDN targets of the active tasks delivering
+	// all records are streamed, the events only, IMEI, paused, out of warrant
+	// and pending ones aren't
+	updates, err := provider.GetUpdates(context.Background(), "hw1", nil)
+	assert.NoError(t, err)
+	assert.Len(t, updates, 2)
+	expected := []*nprobe_protos.InterceptionTarget{
+		{
+			TaskId:        "task1",
+			Imsi:          "IMSI001010000000001",
+			CorrelationId: 1,
+			BearerFilters: []*nprobe_protos.BearerFilter{{Apn: "internet", Qci: 9}},
+		},
+		{TaskId: "task3", Imsi: "IMSI001010000000003", CorrelationId: 3},
+	}
+	for i, update := range updates {
+		assert.Equal(t, expected[i].TaskId, update.Key)
+		target := &nprobe_protos.InterceptionTarget{}
+		assert.NoError(t, proto.Unmarshal(update.Value, target))
+		assert.True(t, proto.Equal(expected[i], target))
+	}
+
+	// nor are the targets of the tasks which no longer intercept
+	assert.NoError(t, store.StoreTaskLifecycle("n1", "task3", models.NetworkProbeTaskLifecycle{State: models.NetworkProbeTaskLifecycleStateSuspended}))
+	updates, err = provider.GetUpdates(context.Background(), "hw1", nil)
+	assert.NoError(t, err)
+	assert.Len(t, updates, 1)
+	assert.Equal(t, "task1", updates[0].Key)
+	assert.NoError(t, store.StoreTaskLifecycle("n1", "task3", models.NetworkProbeTaskLifecycle{State: models.NetworkProbeTaskLifecycleStateActive}))
+
+	// the filters of the deleted tasks are removed by the next update
+	assert.NoError(t, configurator.DeleteEntity("n1", lte.NetworkProbeTaskEntityType, "task1"))
+	updates, err = provider.GetUpdates(context.Background(), "hw1", nil)
+	assert.NoError(t, err)
+	assert.Len(t, updates, 1)
+	assert.Equal(t, "task3", updates[0].Key)
+
+	// nor are the targets assigned to a protected identity of the network
+	err = configurator.UpdateNetworkConfig("n1", lte.NetworkProbeConfigType, &models.NetworkProbeNetworkConfig{
+		ProtectedIdentities: []*models.NetworkProbeProtectedIdentity{
+			{IdentityType: models.NetworkProbeProtectedIdentityIdentityTypeImsi, Identity: "IMSI001010000000003"},
+		},
+	}, serdes.Network)
+	assert.NoError(t, err)
+	updates, err = provider.GetUpdates(context.Background(), "hw1", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, updates)
+
+	// nothing is mirrored while the kill switch is active
+	assert.NoError(t, store.StoreKillSwitch("n1", models.NetworkProbeKillSwitch{Active: true}))
+	updates, err = provider.GetUpdates(context.Background(), "hw1", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, updates)
+
+	_, err = provider.GetUpdates(context.Background(), "hw2", nil)
+	assert.Error(t, err)
+}
+
+func newTask(taskID, targetID, targetType, deliveryType string, filters ...*models.NetworkProbeBearerFilter) configurator.NetworkEntity {
+	return configurator.NetworkEntity{
+		Type: lte.NetworkProbeTaskEntityType,
+		Key:  taskID,
+		Config: &models.NetworkProbeTaskDetails{
+			TargetID:      targetID,
+			TargetType:    targetType,
+			DeliveryType:  deliveryType,
+			CorrelationID: uint64(taskID[len(taskID)-1] - '0'),
+			BearerFilters: filters,
+		},
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package subscription follows the subscriber states the gateways report to
+// the orc8r state service, which streams them to the service as a state
+// indexer, so that the tasks are processed as soon as the sessions of the
+// subscribers change rather than on the next poll of eventd.
+package subscription
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/logging"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+)
+
+var logger = logging.New(logging.ComponentManager)
+
+// Subscription signals the changes of the subscriber states reported by the
+// gateways, e.g. on attach, detach or bearer changes. States are compared by
+// hash, those reported again unchanged being ignored. The subscription is
+// live while states were reported within its stale interval: the manager
+// falls back to polling eventd at the update interval otherwise.
+type Subscription struct {
+	mutex      sync.Mutex
+	enabled    bool
+	live       bool
+	hashes     map[string]map[string][sha256.Size]byte
+	reportedAt time.Time
+	changed    chan struct{}
+}
+
+// NewSubscription creates a disabled subscription
+func NewSubscription() *Subscription {
+	return &Subscription{
+		hashes:  map[string]map[string][sha256.Size]byte{},
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// SetEnabled enables or disables the subscription. The states reported while
+// it is disabled are ignored.
+func (s *Subscription) SetEnabled(enabled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.enabled == enabled {
+		return
+	}
+	s.enabled = enabled
+	if !enabled {
+		s.hashes = map[string]map[string][sha256.Size]byte{}
+		s.reportedAt = time.Time{}
+		s.setLive(false)
+	}
+}
+
+// Report records the subscriber states reported for a network, keyed by
+// IMSI, and signals them if any changed. It returns the number of states
+// which changed, those seen for the first time included.
+func (s *Subscription) Report(networkID string, states map[string][]byte, now time.Time) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.enabled {
+		return 0
+	}
+	s.reportedAt = now
+	hashes, ok := s.hashes[networkID]
+	if !ok {
+		hashes = map[string][sha256.Size]byte{}
+		s.hashes[networkID] = hashes
+	}
+	changed := 0
+	for imsi, state := range states {
+		hash := sha256.Sum256(state)
+		if previous, ok := hashes[imsi]; ok && previous == hash {
+			continue
+		}
+		hashes[imsi] = hash
+		changed++
+	}
+	if changed == 0 {
+		return 0
+	}
+	metrics.SubscriptionStateChanges.WithLabelValues(networkID).Add(float64(changed))
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+	return changed
+}
+
+// Changed returns a channel receiving a value once states changed after the
+// previous value was received
+func (s *Subscription) Changed() <-chan struct{} {
+	return s.changed
+}
+
+// StaleIn returns the time left until the subscription is stale, once no
+// state was reported for staleAfter, or 0 if it is disabled or already stale
+func (s *Subscription) StaleIn(now time.Time, staleAfter time.Duration) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var staleIn time.Duration
+	if s.enabled && !s.reportedAt.IsZero() {
+		staleIn = s.reportedAt.Add(staleAfter).Sub(now)
+	}
+	if staleIn < 0 {
+		staleIn = 0
+	}
+	s.setLive(staleIn > 0)
+	return staleIn
+}
+
+// setLive logs the transitions of the subscription. It must be called with
+// the mutex held.
+func (s *Subscription) setLive(live bool) {
+	if live == s.live {
+		return
+	}
+	s.live = live
+	if live {
+		logger.Infof("Subscriber states are streamed, processing tasks on their changes")
+		metrics.SubscriptionLive.Set(1)
+	} else {
+		logger.Warningf("Subscriber states are no longer streamed, falling back to polling eventd")
+		metrics.SubscriptionLive.Set(0)
+	}
+}
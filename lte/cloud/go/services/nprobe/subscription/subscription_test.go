@@ -0,0 +1,70 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subscription
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func isSignaled(s *Subscription) bool {
+	select {
+	case <-s.Changed():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	now := time.Unix(1615000000, 0)
+	s := NewSubscription()
+	states := map[string][]byte{
+		"IMSI001010000000001": []byte(`{"sessions":1}`),
+		"IMSI001010000000002": []byte(`{"sessions":0}`),
+	}
+
+	// states reported while disabled are ignored
+	assert.Equal(t, 0, s.Report("n1", states, now))
+	assert.False(t, isSignaled(s))
+	assert.Equal(t, time.Duration(0), s.StaleIn(now, time.Minute))
+
+	s.SetEnabled(true)
+	assert.Equal(t, time.Duration(0), s.StaleIn(now, time.Minute))
+	assert.Equal(t, 2, s.Report("n1", states, now))
+	assert.True(t, isSignaled(s))
+	assert.False(t, isSignaled(s))
+
+	// states reported again unchanged aren't signaled, but keep it live
+	later := now.Add(30 * time.Second)
+	assert.Equal(t, 0, s.Report("n1", states, later))
+	assert.False(t, isSignaled(s))
+	assert.Equal(t, time.Minute, s.StaleIn(later, time.Minute))
+	assert.Equal(t, 20*time.Second, s.StaleIn(later.Add(40*time.Second), time.Minute))
+	assert.Equal(t, time.Duration(0), s.StaleIn(later.Add(time.Minute), time.Minute))
+
+	// the states of other networks are tracked apart
+	assert.Equal(t, 2, s.Report("n2", states, later))
+	changed := map[string][]byte{"IMSI001010000000002": []byte(`{"sessions":1}`)}
+	assert.Equal(t, 1, s.Report("n1", changed, later))
+	assert.True(t, isSignaled(s))
+
+	// the states are seen again once enabled back
+	s.SetEnabled(false)
+	assert.Equal(t, time.Duration(0), s.StaleIn(later, time.Minute))
+	s.SetEnabled(true)
+	assert.Equal(t, 2, s.Report("n1", states, later))
+}
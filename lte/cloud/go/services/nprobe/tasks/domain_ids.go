@@ -0,0 +1,139 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"fmt"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/orc8r/cloud/go/services/configurator"
+	merrors "magma/orc8r/lib/go/errors"
+
+	"github.com/pkg/errors"
+)
+
+// AddDomainIDs adds the domain IDs configured by the network config and the
+// destinations of a network. A domain ID identifying the module version of
+// another release than the network config or a destination listed before is
+// left out, and the conflict returned among the others.
+func AddDomainIDs(
+	domainIDs encoding.DomainIDs,
+	config *models.NetworkProbeNetworkConfig,
+	destinations []*models.NetworkProbeDestination,
+) []error {
+	var ret []error
+	if config != nil {
+		if err := addDomainID(domainIDs, config.ModuleVersion, config.Hi2DomainID); err != nil {
+			ret = append(ret, fmt.Errorf("network config: %v", err))
+		}
+	}
+	for _, destination := range destinations {
+		details := destination.DestinationDetails
+		if err := addDomainID(domainIDs, details.ModuleVersion, details.Hi2DomainID); err != nil {
+			ret = append(ret, fmt.Errorf("destination %s: %v", destination.DestinationID, err))
+		}
+	}
+	return ret
+}
+
+// GetDomainIDs returns the domain IDs configured by the stored network config
+// and destinations of a network, leaving out the destination of the given ID
+// if any. The conflicting domain IDs are left out.
+func GetDomainIDs(networkID, exceptDestinationID string) (encoding.DomainIDs, error) {
+	var config *models.NetworkProbeNetworkConfig
+	loaded, err := configurator.LoadNetworkConfig(networkID, lte.NetworkProbeConfigType, serdes.Network)
+	switch {
+	case err == nil:
+		config = loaded.(*models.NetworkProbeNetworkConfig)
+	case err != merrors.ErrNotFound:
+		return nil, errors.Wrap(err, "failed to load NetworkProbeNetworkConfig")
+	}
+	ents, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeDestinationEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load destinations")
+	}
+	destinations := make([]*models.NetworkProbeDestination, 0, len(ents))
+	for _, ent := range ents {
+		if ent.Key != exceptDestinationID {
+			destinations = append(destinations, (&models.NetworkProbeDestination{}).FromBackendModels(ent))
+		}
+	}
+	domainIDs := encoding.DomainIDs{}
+	AddDomainIDs(domainIDs, config, destinations)
+	return domainIDs, nil
+}
+
+// CheckDomainID returns ErrDomainIDConflict if the domain ID selected along
+// a module version already identifies the module version of another release
+// in the stored network config and destinations of a network. The
+// destination of the given ID, if any, is the one being updated and is left
+// out.
+func CheckDomainID(networkID, destinationID, moduleVersion, domainID string) error {
+	if len(domainID) == 0 {
+		return nil
+	}
+	if _, err := encoding.GetDomainModuleVersion(moduleVersion, domainID); err != nil {
+		return errors.Wrap(ErrDomainIDConflict, err.Error())
+	}
+	domainIDs, err := GetDomainIDs(networkID, destinationID)
+	if err != nil {
+		return err
+	}
+	return checkDomainID(domainIDs, moduleVersion, domainID)
+}
+
+// checkDomainID returns ErrDomainIDConflict if the domain ID selected along
+// a module version conflicts with domain IDs
+func checkDomainID(domainIDs encoding.DomainIDs, moduleVersion, domainID string) error {
+	if len(domainID) == 0 {
+		return nil
+	}
+	version, err := encoding.GetDomainModuleVersion(moduleVersion, domainID)
+	if err != nil {
+		return errors.Wrap(ErrDomainIDConflict, err.Error())
+	}
+	if err := domainIDs.Check(version); err != nil {
+		return errors.Wrap(ErrDomainIDConflict, err.Error())
+	}
+	return nil
+}
+
+// CheckTaskDomainID returns ErrDomainIDConflict if the domain ID of the
+// delivery of a task conflicts with the network config or destinations of
+// its network
+func CheckTaskDomainID(networkID string, details *models.NetworkProbeTaskDetails) error {
+	if details.Delivery == nil {
+		return nil
+	}
+	return CheckDomainID(networkID, "", details.Delivery.ModuleVersion, details.Delivery.Hi2DomainID)
+}
+
+// addDomainID adds the domain ID selected along a module version, if any
+func addDomainID(domainIDs encoding.DomainIDs, moduleVersion, domainID string) error {
+	if len(domainID) == 0 {
+		return nil
+	}
+	version, err := encoding.GetDomainModuleVersion(moduleVersion, domainID)
+	if err != nil {
+		return err
+	}
+	return domainIDs.Add(version)
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"fmt"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+)
+
+// MaxTransitions is the number of last transitions kept in the lifecycle of a task
+const MaxTransitions = 32
+
+var (
+	// ErrTaskPaused is returned when suspending a task which is already paused
+	ErrTaskPaused = errors.New("task is already paused")
+	// ErrTaskNotPaused is returned when resuming a task which isn't paused
+	ErrTaskNotPaused = errors.New("task is not paused")
+)
+
+// transitions are the states each state of the lifecycle of a task may move
+// to. Terminated tasks never move again, and only active tasks fail, the
+// other states not delivering the events of their target.
+var transitions = map[string][]string{
+	models.NetworkProbeTaskLifecycleStatePending: {
+		models.NetworkProbeTaskLifecycleStateActive,
+		models.NetworkProbeTaskLifecycleStateSuspended,
+		models.NetworkProbeTaskLifecycleStateExpiring,
+		models.NetworkProbeTaskLifecycleStateTerminated,
+	},
+	models.NetworkProbeTaskLifecycleStateActive: {
+		models.NetworkProbeTaskLifecycleStatePending,
+		models.NetworkProbeTaskLifecycleStateSuspended,
+		models.NetworkProbeTaskLifecycleStateExpiring,
+		models.NetworkProbeTaskLifecycleStateTerminated,
+		models.NetworkProbeTaskLifecycleStateFailed,
+	},
+	models.NetworkProbeTaskLifecycleStateSuspended: {
+		models.NetworkProbeTaskLifecycleStatePending,
+		models.NetworkProbeTaskLifecycleStateActive,
+		models.NetworkProbeTaskLifecycleStateExpiring,
+		models.NetworkProbeTaskLifecycleStateTerminated,
+	},
+	models.NetworkProbeTaskLifecycleStateExpiring: {
+		models.NetworkProbeTaskLifecycleStatePending,
+		models.NetworkProbeTaskLifecycleStateActive,
+		models.NetworkProbeTaskLifecycleStateSuspended,
+		models.NetworkProbeTaskLifecycleStateTerminated,
+	},
+	models.NetworkProbeTaskLifecycleStateFailed: {
+		models.NetworkProbeTaskLifecycleStatePending,
+		models.NetworkProbeTaskLifecycleStateActive,
+		models.NetworkProbeTaskLifecycleStateSuspended,
+		models.NetworkProbeTaskLifecycleStateExpiring,
+		models.NetworkProbeTaskLifecycleStateTerminated,
+	},
+}
+
+// TransitionError is returned for a transition the lifecycle of a task
+// doesn't allow from its current state
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("task can't move from %s to %s", e.From, e.To)
+}
+
+// CanTransition returns true if the lifecycle of a task allows moving from a
+// state to another
+func CanTransition(from, to string) bool {
+	for _, state := range transitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the lifecycle of a task to a state, recording the
+// transition with its time, its reason and the operator who requested it,
+// empty for the transitions of the manager. It returns false if the task is
+// already in the state, and a TransitionError if the lifecycle doesn't allow
+// the transition. Only the last MaxTransitions transitions are kept.
+func Transition(lifecycle *models.NetworkProbeTaskLifecycle, to, reason, actor string, at time.Time) (bool, error) {
+	from := lifecycle.State
+	if len(from) == 0 {
+		from = models.NetworkProbeTaskLifecycleStatePending
+	}
+	if from == to {
+		return false, nil
+	}
+	if !CanTransition(from, to) {
+		return false, &TransitionError{From: from, To: to}
+	}
+	lifecycle.State = to
+	lifecycle.Since = strfmt.DateTime(at.UTC())
+	lifecycle.Reason = reason
+	lifecycle.Transitions = append(lifecycle.Transitions, &models.NetworkProbeTaskTransition{
+		From:   from,
+		To:     to,
+		At:     lifecycle.Since,
+		Reason: reason,
+		Actor:  actor,
+	})
+	if len(lifecycle.Transitions) > MaxTransitions {
+		lifecycle.Transitions = lifecycle.Transitions[len(lifecycle.Transitions)-MaxTransitions:]
+	}
+	return true, nil
+}
+
+// StoreTransition moves a stored task to a state, storing its lifecycle if
+// the transition changed its state
+func StoreTransition(store storage.NProbeStorage, networkID, taskID string, lifecycle *models.NetworkProbeTaskLifecycle, to, reason, actor string) error {
+	moved, err := Transition(lifecycle, to, reason, actor, clock.Now())
+	if err != nil || !moved {
+		return err
+	}
+	if err := store.StoreTaskLifecycle(networkID, taskID, *lifecycle); err != nil {
+		return errors.Wrap(err, "failed to store task lifecycle")
+	}
+	metrics.TaskStateTransitions.WithLabelValues(networkID, to).Inc()
+	return nil
+}
+
+// Suspend pauses the record generation of a task at the request of an
+// operator, moving it to the suspended state. Its state is kept so that
+// sequence numbers carry on once it is resumed.
+func Suspend(store storage.NProbeStorage, networkID, taskID, reason, actor string) (*models.NetworkProbeTaskPause, *models.NetworkProbeTaskLifecycle, error) {
+	pause, lifecycle, err := getPauseAndLifecycle(store, networkID, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pause.Paused {
+		return nil, nil, ErrTaskPaused
+	}
+	if !CanTransition(lifecycle.State, models.NetworkProbeTaskLifecycleStateSuspended) {
+		return nil, nil, &TransitionError{From: lifecycle.State, To: models.NetworkProbeTaskLifecycleStateSuspended}
+	}
+
+	pause.Paused = true
+	pause.PausedAt = strfmt.DateTime(clock.Now().UTC())
+	pause.PausedBy = actor
+	if err := store.StoreTaskPause(networkID, taskID, *pause); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to pause task")
+	}
+	if len(reason) == 0 {
+		reason = "paused"
+	}
+	err = StoreTransition(store, networkID, taskID, lifecycle, models.NetworkProbeTaskLifecycleStateSuspended, reason, actor)
+	return pause, lifecycle, err
+}
+
+// Resume resumes the record generation of a paused task at the request of an
+// operator, moving it back to the active state. The manager reports its
+// target as of the resumption first, or moves it to the state its warrant or
+// deletion call for.
+func Resume(store storage.NProbeStorage, networkID, taskID, reason, actor string) (*models.NetworkProbeTaskPause, *models.NetworkProbeTaskLifecycle, error) {
+	pause, lifecycle, err := getPauseAndLifecycle(store, networkID, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !pause.Paused {
+		return nil, nil, ErrTaskNotPaused
+	}
+	// a task paused before its lifecycle was recorded may still be active
+	if lifecycle.State != models.NetworkProbeTaskLifecycleStateActive &&
+		!CanTransition(lifecycle.State, models.NetworkProbeTaskLifecycleStateActive) {
+		return nil, nil, &TransitionError{From: lifecycle.State, To: models.NetworkProbeTaskLifecycleStateActive}
+	}
+
+	pause.Paused = false
+	pause.ResumedAt = strfmt.DateTime(clock.Now().UTC())
+	pause.ResumedBy = actor
+	if err := store.StoreTaskPause(networkID, taskID, *pause); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to resume task")
+	}
+	if len(reason) == 0 {
+		reason = "resumed"
+	}
+	err = StoreTransition(store, networkID, taskID, lifecycle, models.NetworkProbeTaskLifecycleStateActive, reason, actor)
+	return pause, lifecycle, err
+}
+
+func getPauseAndLifecycle(store storage.NProbeStorage, networkID, taskID string) (*models.NetworkProbeTaskPause, *models.NetworkProbeTaskLifecycle, error) {
+	pause, err := store.GetTaskPause(networkID, taskID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load task pause")
+	}
+	lifecycle, err := store.GetTaskLifecycle(networkID, taskID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load task lifecycle")
+	}
+	return pause, lifecycle, nil
+}
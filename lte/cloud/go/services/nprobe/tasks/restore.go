@@ -0,0 +1,193 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"fmt"
+	"sort"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/orc8r/cloud/go/services/configurator"
+	merrors "magma/orc8r/lib/go/errors"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+)
+
+// RestoredNetwork is the network config and the destinations a restore
+// writes to a network along with its tasks
+type RestoredNetwork struct {
+	// Config replaces the stored network config, nil keeping it
+	Config *models.NetworkProbeNetworkConfig
+	// Destinations are added to the stored destinations, replacing those of
+	// the same ID
+	Destinations []*models.NetworkProbeDestination
+}
+
+// CheckRestore checks the tasks restored to a network, e.g. from a snapshot,
+// as their creation would be once the network config and destinations
+// restored along are written, so that nothing is written until all checks
+// pass. Invalid tasks are rejected with an error wrapping ErrInvalidTargets,
+// tasks beyond the task quota of the network with ErrTaskQuotaExceeded, and
+// tasks targeting a protected identity with ErrProtectedTarget, each of them
+// being audited. Existing tasks are overwritten and don't count against the
+// quota.
+func CheckRestore(
+	store storage.NProbeStorage,
+	networkID string,
+	restored RestoredNetwork,
+	tasks []*models.NetworkProbeTask,
+	actor string,
+) error {
+	config := restored.Config
+	if config == nil {
+		loaded, err := configurator.LoadNetworkConfig(networkID, lte.NetworkProbeConfigType, serdes.Network)
+		switch {
+		case err == nil:
+			config = loaded.(*models.NetworkProbeNetworkConfig)
+		case errors.Cause(err) != merrors.ErrNotFound:
+			return errors.Wrap(err, "failed to load NetworkProbeNetworkConfig")
+		}
+	}
+	destinations, err := getRestoredDestinations(networkID, restored.Destinations)
+	if err != nil {
+		return err
+	}
+	domainIDs := encoding.DomainIDs{}
+	AddDomainIDs(domainIDs, config, destinations)
+	existing, err := configurator.ListEntityKeys(networkID, lte.NetworkProbeTaskEntityType)
+	if err != nil {
+		return errors.Wrap(err, "failed to list tasks")
+	}
+	exists := map[string]bool{}
+	for _, taskID := range existing {
+		exists[taskID] = true
+	}
+	destinationExists := map[string]bool{}
+	for _, destination := range destinations {
+		destinationExists[string(destination.DestinationID)] = true
+	}
+
+	newTasks := 0
+	for _, task := range tasks {
+		if err := task.ValidateModel(); err != nil {
+			return errors.Wrapf(ErrInvalidTargets, "task %s: %v", task.TaskID, err)
+		}
+		details := task.TaskDetails
+		if len(details.DestinationID) != 0 && !destinationExists[details.DestinationID] {
+			return ErrUnknownDestination
+		}
+		if details.TargetType == models.NetworkProbeTaskDetailsTargetTypeImsiRange {
+			imsiRange, err := models.ParseIMSIRange(details.TargetID)
+			if err != nil {
+				return err
+			}
+			if err := checkTestPLMN(config, imsiRange); err != nil {
+				return err
+			}
+		}
+		if details.Delivery != nil {
+			if err := checkDomainID(domainIDs, details.Delivery.ModuleVersion, details.Delivery.Hi2DomainID); err != nil {
+				return err
+			}
+		}
+		if !exists[string(task.TaskID)] {
+			newTasks++
+		}
+	}
+	// every protected target restored is audited
+	var protectedErr error
+	if config != nil {
+		for _, task := range tasks {
+			protected := models.GetProtectedTarget(config.ProtectedIdentities, task.TaskDetails)
+			if protected == nil {
+				continue
+			}
+			protectedErr = rejectProtectedTarget(store, networkID, task, protected, actor)
+			if protectedErr != ErrProtectedTarget {
+				break
+			}
+		}
+	}
+	if protectedErr != nil {
+		return protectedErr
+	}
+	return checkTaskCount(networkID, config, len(existing)+newTasks)
+}
+
+// getRestoredDestinations returns the stored destinations of a network once
+// the restored ones are written, sorted by ID
+func getRestoredDestinations(networkID string, restored []*models.NetworkProbeDestination) ([]*models.NetworkProbeDestination, error) {
+	ents, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeDestinationEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load destinations")
+	}
+	byID := map[string]*models.NetworkProbeDestination{}
+	for _, ent := range ents {
+		byID[ent.Key] = (&models.NetworkProbeDestination{}).FromBackendModels(ent)
+	}
+	for _, destination := range restored {
+		byID[string(destination.DestinationID)] = destination
+	}
+	ret := make([]*models.NetworkProbeDestination, 0, len(byID))
+	for _, destination := range byID {
+		ret = append(ret, destination)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].DestinationID < ret[j].DestinationID })
+	return ret, nil
+}
+
+// Restore provisions a task checked by CheckRestore in a network, keeping its
+// correlation ID and timestamp. An existing task is overwritten, and its
+// state left untouched. The restore is recorded in the audit log of the
+// network before taking effect.
+func Restore(store storage.NProbeStorage, networkID string, task *models.NetworkProbeTask, actor string) error {
+	taskID := string(task.TaskID)
+	err := store.StoreAuditEntry(networkID, models.NetworkProbeAuditEntry{
+		Action:    models.NetworkProbeAuditEntryActionRestoreTask,
+		Actor:     actor,
+		Reason:    fmt.Sprintf("task %s restored from a snapshot", taskID),
+		Timestamp: strfmt.DateTime(clock.Now().UTC()),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to audit task restore")
+	}
+
+	exists, err := configurator.DoesEntityExist(networkID, lte.NetworkProbeTaskEntityType, taskID)
+	if err != nil {
+		return errors.Wrap(err, "failed to check whether the task exists")
+	}
+	if exists {
+		return configurator.CreateOrUpdateEntityConfig(networkID, lte.NetworkProbeTaskEntityType, taskID, task.TaskDetails, serdes.Entity)
+	}
+	_, err = configurator.CreateEntity(
+		networkID,
+		configurator.NetworkEntity{
+			Type:   lte.NetworkProbeTaskEntityType,
+			Key:    taskID,
+			Config: task.TaskDetails,
+		},
+		serdes.Entity,
+	)
+	return err
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tasks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/orc8r/cloud/go/services/configurator"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+)
+
+// GetSyncState returns the snapshot of the tasks of a network, or of the
+// given ones, with their runtime state, for an ADMF to reconcile its warrants
+// against. The snapshot is canonical: tasks are sorted by ID and timestamps
+// are in UTC, so that two snapshots of the same state are identical but for
+// their generation time. Its digest covers the IDs and config digests of the
+// tasks only, so that it changes with their provisioning and not with their
+// delivery. The task IDs requested which are not provisioned are listed as
+// missing.
+func GetSyncState(store storage.NProbeStorage, networkID string, taskIDs []string, now time.Time) (*models.NetworkProbeSyncState, error) {
+	ents, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeTaskEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load tasks")
+	}
+	states, err := store.GetAllNProbeData(networkID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load task states")
+	}
+
+	requested := map[string]bool{}
+	for _, taskID := range taskIDs {
+		requested[taskID] = true
+	}
+	ret := &models.NetworkProbeSyncState{
+		NetworkID:   networkID,
+		GeneratedAt: strfmt.DateTime(now.UTC()),
+		Tasks:       []*models.NetworkProbeTaskSyncState{},
+	}
+	for _, ent := range ents {
+		if len(requested) != 0 && !requested[ent.Key] {
+			continue
+		}
+		delete(requested, ent.Key)
+		task := (&models.NetworkProbeTask{}).FromBackendModels(ent)
+		state, err := getTaskSyncState(store, networkID, task, states[ent.Key])
+		if err != nil {
+			return nil, err
+		}
+		ret.Tasks = append(ret.Tasks, state)
+	}
+	sort.Slice(ret.Tasks, func(i, j int) bool { return ret.Tasks[i].TaskID < ret.Tasks[j].TaskID })
+	for taskID := range requested {
+		ret.MissingTaskIds = append(ret.MissingTaskIds, taskID)
+	}
+	sort.Strings(ret.MissingTaskIds)
+
+	digest := sha256.New()
+	for _, task := range ret.Tasks {
+		fmt.Fprintf(digest, "%s %s\n", task.TaskID, task.ConfigDigest)
+	}
+	ret.Digest = hex.EncodeToString(digest.Sum(nil))
+	return ret, nil
+}
+
+// getTaskSyncState returns the provisioning and runtime state of a task. The
+// state of a task not processed yet is left empty.
+func getTaskSyncState(
+	store storage.NProbeStorage,
+	networkID string,
+	task *models.NetworkProbeTask,
+	data models.NetworkProbeData,
+) (*models.NetworkProbeTaskSyncState, error) {
+	taskID := string(task.TaskID)
+	config, err := json.Marshal(task.TaskDetails)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to marshal task %s", taskID))
+	}
+	configDigest := sha256.Sum256(config)
+	pause, err := store.GetTaskPause(networkID, taskID)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to load pause of task %s", taskID))
+	}
+	deletion, err := store.GetTaskDeletion(networkID, taskID)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to load deletion of task %s", taskID))
+	}
+
+	details := task.TaskDetails
+	return &models.NetworkProbeTaskSyncState{
+		TaskID:                 taskID,
+		TargetID:               details.TargetID,
+		TargetType:             details.TargetType,
+		DeliveryType:           details.DeliveryType,
+		CorrelationID:          details.CorrelationID,
+		AuthorizationReference: details.AuthorizationReference,
+		StartTime:              toUTC(details.StartTime),
+		EndTime:                toUTC(details.EndTime),
+		ConfigDigest:           hex.EncodeToString(configDigest[:]),
+		Xid:                    models.GetTaskXID(taskID, &data),
+		SequenceNumber:         data.SequenceNumber,
+		RecordsExported:        data.RecordsExported,
+		LastExported:           toUTC(data.LastExported),
+		WarrantState:           data.WarrantState,
+		Paused:                 pause.Paused,
+		DeletionRequested:      models.IsTaskDeletionPending(deletion),
+		SuspendedAt:            toUTC(data.SuspendedAt),
+		CompletedAt:            toUTC(data.CompletedAt),
+		ExpiredAt:              toUTC(data.ExpiredAt),
+	}, nil
+}
+
+// toUTC returns a timestamp in UTC, left unset if unset
+func toUTC(t strfmt.DateTime) strfmt.DateTime {
+	if time.Time(t).IsZero() {
+		return t
+	}
+	return strfmt.DateTime(time.Time(t).UTC())
+}
@@ -0,0 +1,446 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tasks provisions the interception tasks of a network, whether
+// they are managed through the REST API or the gRPC API.
+package tasks
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"magma/lte/cloud/go/lte"
+	"magma/lte/cloud/go/serdes"
+	"magma/lte/cloud/go/services/nprobe/clock"
+	"magma/lte/cloud/go/services/nprobe/metrics"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	"magma/lte/cloud/go/services/nprobe/redact"
+	"magma/lte/cloud/go/services/nprobe/storage"
+	"magma/orc8r/cloud/go/services/configurator"
+	merrors "magma/orc8r/lib/go/errors"
+
+	strfmt "github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrTaskExists is returned when creating a task whose ID is already used
+	// in its network
+	ErrTaskExists = errors.New("task already exists")
+	// ErrTaskQuotaExceeded is returned when creating a task in a network which
+	// holds as many tasks as its max_tasks quota
+	ErrTaskQuotaExceeded = errors.New("task quota of the network exceeded")
+	// ErrInvalidTargets is returned when creating the tasks of a bulk request
+	// some of whose targets are invalid or duplicated
+	ErrInvalidTargets = errors.New("invalid targets")
+	// ErrUnknownDestination is returned when provisioning a task delivered to
+	// a destination which doesn't exist in its network
+	ErrUnknownDestination = errors.New("destination of the task does not exist")
+	// ErrNotTestPLMN is returned when provisioning an imsi_range task whose
+	// IMSIs don't belong to a test PLMN of its network
+	ErrNotTestPLMN = errors.New("IMSI range of the task is not in a test PLMN of the network")
+	// ErrProtectedTarget is returned when provisioning a task whose target is
+	// a protected identity of its network
+	ErrProtectedTarget = errors.New("target of the task is a protected identity of the network")
+	// ErrDomainIDConflict is returned when provisioning a task or destination
+	// whose domain ID identifies the module version of another release in
+	// the network config or destinations of its network
+	ErrDomainIDConflict = errors.New("domain ID conflicts with the network config or destinations of the network")
+)
+
+// Create provisions a validated task in a network. Its events are intercepted
+// from now on, and its correlation ID is drawn at random if not set. The
+// state of an existing task is left untouched. Tasks beyond the task quota of
+// the network are rejected with ErrTaskQuotaExceeded, and tasks targeting a
+// protected identity with ErrProtectedTarget, the attempt of the actor being
+// audited.
+func Create(store storage.NProbeStorage, networkID string, task *models.NetworkProbeTask, actor string) error {
+	taskID := string(task.TaskID)
+	exists, err := configurator.DoesEntityExist(networkID, lte.NetworkProbeTaskEntityType, taskID)
+	if err != nil {
+		return errors.Wrap(err, "failed to check whether the task exists")
+	}
+	if exists {
+		return ErrTaskExists
+	}
+	if err := CheckDestination(networkID, task.TaskDetails); err != nil {
+		return err
+	}
+	if err := CheckTestPLMN(networkID, task.TaskDetails); err != nil {
+		return err
+	}
+	if err := CheckTaskDomainID(networkID, task.TaskDetails); err != nil {
+		return err
+	}
+	if err := CheckProtectedTarget(store, networkID, task, actor); err != nil {
+		return err
+	}
+	if err := checkTaskQuota(networkID, 1); err != nil {
+		return err
+	}
+
+	if err := storeInitialState(store, networkID, task); err != nil {
+		return err
+	}
+
+	_, err = configurator.CreateEntity(
+		networkID,
+		configurator.NetworkEntity{
+			Type:   lte.NetworkProbeTaskEntityType,
+			Key:    taskID,
+			Config: task.TaskDetails,
+		},
+		serdes.Entity,
+	)
+	return err
+}
+
+// CreateBulk provisions the tasks of the targets of a bulk request, all or
+// none. The tasks are validated first, and none is created if any target is
+// invalid or duplicated, returning ErrInvalidTargets, or if the task of a
+// target already exists, returning ErrTaskExists. The result reports the
+// status of each target either way. Tasks beyond the task quota of the
+// network are rejected with ErrTaskQuotaExceeded, and tasks targeting a
+// protected identity with ErrProtectedTarget, each of them being audited.
+func CreateBulk(store storage.NProbeStorage, networkID string, request *models.NetworkProbeTaskBulkRequest, actor string) (*models.NetworkProbeTaskBulkResult, error) {
+	existing, err := configurator.ListEntityKeys(networkID, lte.NetworkProbeTaskEntityType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tasks")
+	}
+	exists := map[string]bool{}
+	for _, taskID := range existing {
+		exists[taskID] = true
+	}
+
+	tasks := request.ToTasks()
+	result := &models.NetworkProbeTaskBulkResult{}
+	var invalid, conflicting bool
+	seen := map[string]bool{}
+	for _, task := range tasks {
+		taskID := string(task.TaskID)
+		targetResult := &models.NetworkProbeTaskBulkTargetResult{
+			TaskID:   task.TaskID,
+			TargetID: task.TaskDetails.TargetID,
+			Status:   models.NetworkProbeTaskBulkTargetResultStatusAborted,
+		}
+		if err := task.ValidateModel(); err != nil {
+			targetResult.Status = models.NetworkProbeTaskBulkTargetResultStatusInvalid
+			targetResult.Message = err.Error()
+			invalid = true
+		} else if seen[taskID] {
+			targetResult.Status = models.NetworkProbeTaskBulkTargetResultStatusDuplicate
+			targetResult.Message = fmt.Sprintf("task %s is requested more than once", taskID)
+			invalid = true
+		} else if exists[taskID] {
+			targetResult.Status = models.NetworkProbeTaskBulkTargetResultStatusExists
+			targetResult.Message = ErrTaskExists.Error()
+			conflicting = true
+		}
+		seen[taskID] = true
+		result.Results = append(result.Results, targetResult)
+	}
+	if invalid {
+		return result, ErrInvalidTargets
+	}
+	if conflicting {
+		return result, ErrTaskExists
+	}
+	// the tasks share the destination of the template
+	if err := CheckDestination(networkID, request.Template); err != nil {
+		return nil, err
+	}
+	if err := CheckTaskDomainID(networkID, request.Template); err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		if err := CheckTestPLMN(networkID, task.TaskDetails); err != nil {
+			return nil, err
+		}
+	}
+	// every protected target of the request is audited
+	var protectedErr error
+	for _, task := range tasks {
+		if err := CheckProtectedTarget(store, networkID, task, actor); err != nil {
+			protectedErr = err
+			if err != ErrProtectedTarget {
+				break
+			}
+		}
+	}
+	if protectedErr != nil {
+		return nil, protectedErr
+	}
+	if err := checkTaskQuota(networkID, len(tasks)); err != nil {
+		return nil, err
+	}
+
+	var entities configurator.NetworkEntities
+	for i, task := range tasks {
+		if err := storeInitialState(store, networkID, task); err != nil {
+			deleteInitialStates(store, networkID, tasks[:i])
+			return nil, err
+		}
+		entities = append(entities, configurator.NetworkEntity{
+			Type:   lte.NetworkProbeTaskEntityType,
+			Key:    string(task.TaskID),
+			Config: task.TaskDetails,
+		})
+	}
+	// the entities are created in a single transaction
+	if _, err := configurator.CreateEntities(networkID, entities, serdes.Entity); err != nil {
+		deleteInitialStates(store, networkID, tasks)
+		return nil, errors.Wrap(err, "failed to create tasks")
+	}
+	result.Created = true
+	for _, targetResult := range result.Results {
+		targetResult.Status = models.NetworkProbeTaskBulkTargetResultStatusCreated
+	}
+	return result, nil
+}
+
+// storeInitialState stores the state of a task about to be created, drawing
+// its correlation ID at random if not set
+func storeInitialState(store storage.NProbeStorage, networkID string, task *models.NetworkProbeTask) error {
+	if task.TaskDetails.CorrelationID == 0 {
+		task.TaskDetails.CorrelationID = rand.Uint64()
+	}
+	task.TaskDetails.Timestamp = strfmt.DateTime(time.Now().UTC())
+	data := models.NetworkProbeData{
+		LastExported:   task.TaskDetails.Timestamp,
+		CreatedAt:      task.TaskDetails.Timestamp,
+		TargetID:       task.TaskDetails.TargetID,
+		SequenceNumber: 0,
+	}
+	if err := store.StoreNProbeData(networkID, string(task.TaskID), data); err != nil {
+		return errors.Wrap(err, "failed to store NetworkProbeData")
+	}
+	return nil
+}
+
+// deleteInitialStates deletes the states stored for tasks which failed to be
+// created
+func deleteInitialStates(store storage.NProbeStorage, networkID string, tasks []*models.NetworkProbeTask) {
+	for _, task := range tasks {
+		store.DeleteNProbeData(networkID, string(task.TaskID))
+	}
+}
+
+// checkTaskQuota returns ErrTaskQuotaExceeded if a network can't hold a
+// number of new tasks within its quota. Tasks created concurrently may
+// exceed the quota, the most recent ones being held by the manager until
+// others are deleted.
+func checkTaskQuota(networkID string, newTasks int) error {
+	config, err := configurator.LoadNetworkConfig(networkID, lte.NetworkProbeConfigType, serdes.Network)
+	if err == merrors.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to load NetworkProbeNetworkConfig")
+	}
+	maxTasks := config.(*models.NetworkProbeNetworkConfig).MaxTasks
+	if maxTasks == 0 {
+		return nil
+	}
+	taskIDs, err := configurator.ListEntityKeys(networkID, lte.NetworkProbeTaskEntityType)
+	if err != nil {
+		return errors.Wrap(err, "failed to list tasks")
+	}
+	return checkTaskCount(networkID, config.(*models.NetworkProbeNetworkConfig), len(taskIDs)+newTasks)
+}
+
+// checkTaskCount returns ErrTaskQuotaExceeded if a network would hold more
+// tasks than the quota of its config, nil if it has no config
+func checkTaskCount(networkID string, config *models.NetworkProbeNetworkConfig, count int) error {
+	if config == nil || config.MaxTasks == 0 {
+		return nil
+	}
+	if uint32(count) > config.MaxTasks {
+		metrics.QuotaExceeded.WithLabelValues(networkID, metrics.QuotaTasks).Inc()
+		return ErrTaskQuotaExceeded
+	}
+	return nil
+}
+
+// CheckDestination returns ErrUnknownDestination if a task is delivered to
+// a destination which doesn't exist in its network
+func CheckDestination(networkID string, details *models.NetworkProbeTaskDetails) error {
+	if len(details.DestinationID) == 0 {
+		return nil
+	}
+	exists, err := configurator.DoesEntityExist(networkID, lte.NetworkProbeDestinationEntityType, details.DestinationID)
+	if err != nil {
+		return errors.Wrap(err, "failed to check whether the destination exists")
+	}
+	if !exists {
+		return ErrUnknownDestination
+	}
+	return nil
+}
+
+// CheckTestPLMN returns ErrNotTestPLMN if the IMSIs of an imsi_range task
+// don't belong to a test PLMN of its network. The target ID of the task is
+// expected to be validated.
+func CheckTestPLMN(networkID string, details *models.NetworkProbeTaskDetails) error {
+	if details.TargetType != models.NetworkProbeTaskDetailsTargetTypeImsiRange {
+		return nil
+	}
+	imsiRange, err := models.ParseIMSIRange(details.TargetID)
+	if err != nil {
+		return err
+	}
+	config, err := configurator.LoadNetworkConfig(networkID, lte.NetworkProbeConfigType, serdes.Network)
+	if err == merrors.ErrNotFound {
+		return ErrNotTestPLMN
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to load NetworkProbeNetworkConfig")
+	}
+	return checkTestPLMN(config.(*models.NetworkProbeNetworkConfig), imsiRange)
+}
+
+// checkTestPLMN returns ErrNotTestPLMN if the IMSIs of a range don't belong
+// to a test PLMN of a network config, nil meaning the network has none
+func checkTestPLMN(config *models.NetworkProbeNetworkConfig, imsiRange *models.IMSIRange) error {
+	if config == nil || !imsiRange.IsInPLMNs(config.TestPlmnIds) {
+		return ErrNotTestPLMN
+	}
+	return nil
+}
+
+// CheckProtectedTarget returns ErrProtectedTarget if the target of a task is
+// a protected identity of its network, the attempt of the actor to target it
+// being recorded in the audit log of the network. The target ID of the task
+// is expected to be validated.
+func CheckProtectedTarget(store storage.NProbeStorage, networkID string, task *models.NetworkProbeTask, actor string) error {
+	protected, err := GetProtectedTarget(networkID, task.TaskDetails)
+	if protected == nil || err != nil {
+		return err
+	}
+	return rejectProtectedTarget(store, networkID, task, protected, actor)
+}
+
+// rejectProtectedTarget records the attempt of the actor to target a
+// protected identity with a task in the audit log of its network, and returns
+// ErrProtectedTarget
+func rejectProtectedTarget(
+	store storage.NProbeStorage,
+	networkID string,
+	task *models.NetworkProbeTask,
+	protected *models.NetworkProbeProtectedIdentity,
+	actor string,
+) error {
+	details := task.TaskDetails
+	reason := fmt.Sprintf("task %s targets protected %s %s", task.TaskID, details.TargetType, redact.Identity(details.TargetID))
+	if len(protected.Reason) != 0 {
+		reason += ": " + protected.Reason
+	}
+	metrics.ProtectedTargetsRejected.WithLabelValues(networkID).Inc()
+	err := store.StoreAuditEntry(networkID, models.NetworkProbeAuditEntry{
+		Action:    models.NetworkProbeAuditEntryActionRejectProtectedTarget,
+		Actor:     actor,
+		Reason:    reason,
+		Timestamp: strfmt.DateTime(clock.Now().UTC()),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to audit protected target")
+	}
+	return ErrProtectedTarget
+}
+
+// GetProtectedTarget returns the protected identity of a network the target
+// of a task designates, nil if none does
+func GetProtectedTarget(networkID string, details *models.NetworkProbeTaskDetails) (*models.NetworkProbeProtectedIdentity, error) {
+	config, err := configurator.LoadNetworkConfig(networkID, lte.NetworkProbeConfigType, serdes.Network)
+	if err == merrors.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load NetworkProbeNetworkConfig")
+	}
+	return models.GetProtectedTarget(config.(*models.NetworkProbeNetworkConfig).ProtectedIdentities, details), nil
+}
+
+// GetDestinationTasks returns the IDs of the tasks of a network delivered to
+// a destination, sorted
+func GetDestinationTasks(networkID, destinationID string) ([]string, error) {
+	ents, _, err := configurator.LoadAllEntitiesOfType(
+		networkID, lte.NetworkProbeTaskEntityType,
+		configurator.EntityLoadCriteria{LoadConfig: true},
+		serdes.Entity,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load tasks")
+	}
+	var ret []string
+	for _, ent := range ents {
+		if ent.Config.(*models.NetworkProbeTaskDetails).DestinationID == destinationID {
+			ret = append(ret, ent.Key)
+		}
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// RequestDeletion requests the deletion of a task of a network, returning
+// merrors.ErrNotFound if it doesn't exist. The manager delivers the records
+// of its pending events and ends its interception with an IRI-END before
+// deleting it. Requesting the deletion again keeps the first request.
+func RequestDeletion(store storage.NProbeStorage, networkID, taskID, actor string) (*models.NetworkProbeTaskDeletion, error) {
+	exists, err := configurator.DoesEntityExist(networkID, lte.NetworkProbeTaskEntityType, taskID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check whether the task exists")
+	}
+	if !exists {
+		return nil, merrors.ErrNotFound
+	}
+	deletion, err := store.GetTaskDeletion(networkID, taskID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load task deletion")
+	}
+	if models.IsTaskDeletionPending(deletion) {
+		return deletion, nil
+	}
+	deletion = &models.NetworkProbeTaskDeletion{
+		RequestedAt: strfmt.DateTime(clock.Now().UTC()),
+		RequestedBy: actor,
+	}
+	if err := store.StoreTaskDeletion(networkID, taskID, *deletion); err != nil {
+		return nil, errors.Wrap(err, "failed to request task deletion")
+	}
+	return deletion, nil
+}
+
+// Delete deletes a task of a network along with the state kept for it. The
+// audit trail of its delivered records and the mapping of its sessions are
+// kept until they expire. Tasks are deleted by the manager once their
+// deletion is requested by RequestDeletion.
+func Delete(store storage.NProbeStorage, networkID, taskID string) error {
+	store.DeleteNProbeData(networkID, taskID)
+	store.DeleteQuarantineEntries(networkID, taskID)
+	store.DeleteActivity(networkID, taskID)
+	store.DeleteTaskPause(networkID, taskID)
+	store.DeleteTaskLifecycle(networkID, taskID)
+	store.DeleteTaskXIDRotation(networkID, taskID)
+	store.DeleteTaskReplay(networkID, taskID)
+	store.DeleteTaskTestRecord(networkID, taskID)
+	store.DeleteTaskStatistics(networkID, taskID)
+	store.DeleteBookmarks(networkID, taskID)
+	store.DeleteDeadLetters(networkID, taskID)
+	if err := configurator.DeleteEntity(networkID, lte.NetworkProbeTaskEntityType, taskID); err != nil {
+		return err
+	}
+	// the request goes last so that the task is deleted again if anything fails
+	return store.DeleteTaskDeletion(networkID, taskID)
+}
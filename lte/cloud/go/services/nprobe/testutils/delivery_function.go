@@ -0,0 +1,361 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testutils provides a mock delivery function for the integration
+// tests of the records exporter and nprobe manager
+package testutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+)
+
+// MockDeliveryFunctionConfig holds the behavior of a mock delivery function
+type MockDeliveryFunctionConfig struct {
+	// TLSConfig is the server config, a self-signed certificate for
+	// CN=nprobe-df is presented when nil
+	TLSConfig *tls.Config
+	// AckRecords acknowledges each record received, as expected by the
+	// exporter with acknowledged delivery
+	AckRecords bool
+	// KeepaliveInterval is the time between keepalives sent to the
+	// exporter, none are sent when 0
+	KeepaliveInterval time.Duration
+	// ValidateRecords validates the encoding of the records received,
+	// reporting the malformed ones as framing errors
+	ValidateRecords bool
+	// MaxRecordSize bounds the size of the PDUs received, connections
+	// sending larger PDUs are closed
+	MaxRecordSize int
+	// DiscardRecords only counts the records received instead of keeping
+	// them, e.g. for load tests delivering millions of records
+	DiscardRecords bool
+	// HeaderVersion is the only header version of the PDUs accepted, the
+	// others being reported as framing errors and left unacknowledged, as
+	// by a LEMF not upgraded yet. All the supported versions are accepted
+	// when 0.
+	HeaderVersion uint16
+}
+
+// MockDeliveryFunction is a TLS server speaking enough of TS 102 232 to stand
+// for a LEMF: it accepts connections, reads and checks the framing of the
+// PDUs received, acknowledges keepalives and, if set, records, and keeps the
+// records received for inspection. Segmented records are reassembled, and
+// kept and acknowledged once their last segment is received.
+type MockDeliveryFunction struct {
+	config   MockDeliveryFunctionConfig
+	listener net.Listener
+
+	mutex         sync.Mutex
+	received      *sync.Cond
+	records       [][]byte
+	recordCount   int
+	framingErrors []error
+	keepalives    int
+	keepaliveAcks int
+	conns         map[net.Conn]struct{}
+	closed        bool
+	wg            sync.WaitGroup
+}
+
+// NewMockDeliveryFunction starts a mock delivery function listening on a
+// local port
+func NewMockDeliveryFunction(config MockDeliveryFunctionConfig) (*MockDeliveryFunction, error) {
+	tlsConfig := config.TLSConfig
+	if tlsConfig == nil {
+		cert, err := newSelfSignedCertificate("nprobe-df")
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	df := &MockDeliveryFunction{
+		config:   config,
+		listener: listener,
+		conns:    map[net.Conn]struct{}{},
+	}
+	df.received = sync.NewCond(&df.mutex)
+	df.wg.Add(1)
+	go df.accept()
+	return df, nil
+}
+
+// Addr returns the host:port the delivery function listens on
+func (df *MockDeliveryFunction) Addr() string {
+	return df.listener.Addr().String()
+}
+
+// Records returns the records received so far
+func (df *MockDeliveryFunction) Records() [][]byte {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	return append([][]byte{}, df.records...)
+}
+
+// RecordsReceived returns the number of records received so far, including
+// the discarded ones
+func (df *MockDeliveryFunction) RecordsReceived() int {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	return df.recordCount
+}
+
+// WaitRecords waits for count records to be received and returns the ones
+// kept, failing once the timeout elapses
+func (df *MockDeliveryFunction) WaitRecords(count int, timeout time.Duration) ([][]byte, error) {
+	timer := time.AfterFunc(timeout, func() {
+		df.mutex.Lock()
+		defer df.mutex.Unlock()
+		df.received.Broadcast()
+	})
+	defer timer.Stop()
+	deadline := time.Now().Add(timeout)
+
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	for df.recordCount < count {
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("received %d of %d records in %v", df.recordCount, count, timeout)
+		}
+		df.received.Wait()
+	}
+	return append([][]byte{}, df.records...), nil
+}
+
+// FramingErrors returns the errors met reading the PDUs received
+func (df *MockDeliveryFunction) FramingErrors() []error {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	return append([]error{}, df.framingErrors...)
+}
+
+// KeepalivesReceived returns the number of keepalives received
+func (df *MockDeliveryFunction) KeepalivesReceived() int {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	return df.keepalives
+}
+
+// KeepaliveAcksReceived returns the number of acknowledgements of the
+// keepalives sent
+func (df *MockDeliveryFunction) KeepaliveAcksReceived() int {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	return df.keepaliveAcks
+}
+
+// Reset forgets the records and errors received so far
+func (df *MockDeliveryFunction) Reset() {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	df.records = nil
+	df.recordCount = 0
+	df.framingErrors = nil
+	df.keepalives = 0
+	df.keepaliveAcks = 0
+}
+
+// DropConnections closes the open connections, e.g. to test reconnects.
+// New connections are still accepted.
+func (df *MockDeliveryFunction) DropConnections() {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	for conn := range df.conns {
+		conn.Close()
+	}
+}
+
+// Close stops the delivery function and closes its connections
+func (df *MockDeliveryFunction) Close() {
+	df.mutex.Lock()
+	df.closed = true
+	df.listener.Close()
+	for conn := range df.conns {
+		conn.Close()
+	}
+	df.mutex.Unlock()
+	df.wg.Wait()
+}
+
+func (df *MockDeliveryFunction) accept() {
+	defer df.wg.Done()
+	for {
+		conn, err := df.listener.Accept()
+		if err != nil {
+			return
+		}
+		df.mutex.Lock()
+		if df.closed {
+			df.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		df.conns[conn] = struct{}{}
+		df.wg.Add(1)
+		df.mutex.Unlock()
+		go df.serve(conn)
+	}
+}
+
+// serve reads the PDUs of a connection until it is closed. The connection
+// is closed on PDUs that can't be framed, the stream being lost.
+func (df *MockDeliveryFunction) serve(conn net.Conn) {
+	defer df.wg.Done()
+	defer func() {
+		df.mutex.Lock()
+		delete(df.conns, conn)
+		df.mutex.Unlock()
+		conn.Close()
+	}()
+
+	var writeMutex sync.Mutex
+	write := func(pdu []byte) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		_, err := conn.Write(pdu)
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	if df.config.KeepaliveInterval > 0 {
+		go df.keepalive(write, done)
+	}
+	var reassembler encoding.Reassembler
+
+	for {
+		pdu, err := encoding.ReadPDU(conn, df.config.MaxRecordSize)
+		if err != nil {
+			if err == encoding.ErrRecordTooLarge {
+				df.addFramingError(err)
+			}
+			return
+		}
+		hdr, err := encoding.ParsePDUHeader(pdu)
+		if err != nil {
+			df.addFramingError(err)
+			return
+		}
+		if !encoding.IsHeaderVersionSupported(hdr.Version) || (df.config.HeaderVersion != 0 && hdr.Version != df.config.HeaderVersion) {
+			df.addFramingError(fmt.Errorf("unsupported header version %d", hdr.Version))
+			continue
+		}
+
+		switch hdr.PduType {
+		case encoding.HeaderPduTypeKeepalive:
+			df.mutex.Lock()
+			df.keepalives++
+			df.mutex.Unlock()
+			if err := write(encoding.MakeKeepaliveAck(hdr)); err != nil {
+				return
+			}
+		case encoding.HeaderPduTypeKeepaliveAck:
+			df.mutex.Lock()
+			df.keepaliveAcks++
+			df.mutex.Unlock()
+		case encoding.HeaderPduType:
+			record, err := reassembler.Add(pdu)
+			if err != nil {
+				df.addFramingError(err)
+				continue
+			}
+			if record == nil {
+				// more segments to come
+				continue
+			}
+			if df.config.ValidateRecords {
+				if err := encoding.Validate(record); err != nil {
+					df.addFramingError(err)
+					continue
+				}
+			}
+			df.mutex.Lock()
+			df.recordCount++
+			if !df.config.DiscardRecords {
+				df.records = append(df.records, record)
+			}
+			df.received.Broadcast()
+			df.mutex.Unlock()
+			if df.config.AckRecords {
+				if err := write(encoding.MakeKeepaliveAck(hdr)); err != nil {
+					return
+				}
+			}
+		default:
+			df.addFramingError(fmt.Errorf("unexpected PDU type %d", hdr.PduType))
+		}
+	}
+}
+
+// keepalive sends keepalives on a connection until it is closed
+func (df *MockDeliveryFunction) keepalive(write func([]byte) error, done chan struct{}) {
+	ticker := time.NewTicker(df.config.KeepaliveInterval)
+	defer ticker.Stop()
+	seqNbr := uint32(0)
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		seqNbr++
+		keepalive := encoding.MakeKeepalive(seqNbr)
+		if df.config.HeaderVersion != 0 {
+			keepalive, _ = encoding.ConvertHeaderVersion(keepalive, df.config.HeaderVersion)
+		}
+		if err := write(keepalive); err != nil {
+			return
+		}
+	}
+}
+
+func (df *MockDeliveryFunction) addFramingError(err error) {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	df.framingErrors = append(df.framingErrors, err)
+}
+
+// newSelfSignedCertificate returns a certificate valid for a day
+func newSelfSignedCertificate(commonName string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
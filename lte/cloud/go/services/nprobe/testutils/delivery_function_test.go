@@ -0,0 +1,151 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testutils
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeRecords(t *testing.T, count int) [][]byte {
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			CorrelationID: 1,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.AttachSuccess,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+	}
+	records := make([][]byte, count)
+	for i := range records {
+		record, err := encoding.MakeRecord(&event, task, 1, uint32(i+1))
+		assert.NoError(t, err)
+		records[i] = record
+	}
+	return records
+}
+
+func TestMockDeliveryFunction(t *testing.T) {
+	df, err := NewMockDeliveryFunction(MockDeliveryFunctionConfig{
+		AckRecords:        true,
+		ValidateRecords:   true,
+		KeepaliveInterval: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	defer df.Close()
+
+	backend := exporter.NewTLSBackend(df.Addr(), &tls.Config{InsecureSkipVerify: true}, exporter.TLSBackendConfig{
+		AckTimeout:        time.Second,
+		KeepaliveInterval: 10 * time.Millisecond,
+	})
+	exp := exporter.NewRecordExporter(backend)
+	defer exp.Close()
+
+	// records are delivered once acknowledged
+	records := makeRecords(t, 3)
+	delivery := exp.SubmitRecords(context.Background(), "task", 1, 1, records, nprobe.DefaultMaxExportRetries)
+	for i := range records {
+		assert.NoError(t, delivery.Wait(i))
+	}
+	assert.Equal(t, records, df.Records())
+	assert.Empty(t, df.FramingErrors())
+
+	// keepalives are exchanged both ways
+	assert.Eventually(t, func() bool {
+		return df.KeepalivesReceived() > 0 && df.KeepaliveAcksReceived() > 0
+	}, time.Second, 10*time.Millisecond)
+
+	// the exporter reconnects once the connection is dropped
+	df.Reset()
+	df.DropConnections()
+	assert.Eventually(t, func() bool { return !backend.IsConnected() }, time.Second, 10*time.Millisecond)
+	delivery = exp.SubmitRecords(context.Background(), "task", 1, 1, records[:1], nprobe.DefaultMaxExportRetries)
+	assert.NoError(t, delivery.Wait(0))
+	received, err := df.WaitRecords(1, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, records[:1], received)
+
+	// malformed records are reported and not acknowledged
+	malformed := append([]byte{}, records[0]...)
+	malformed[binary.BigEndian.Uint32(malformed[4:8])] = 0
+	assert.Error(t, backend.Send(malformed, 1))
+	assert.Len(t, df.FramingErrors(), 1)
+
+	_, err = df.WaitRecords(2, 10*time.Millisecond)
+	assert.EqualError(t, err, "received 1 of 2 records in 10ms")
+	assert.Equal(t, 1, df.RecordsReceived())
+}
+
+func TestMockDeliveryFunctionDiscardRecords(t *testing.T) {
+	df, err := NewMockDeliveryFunction(MockDeliveryFunctionConfig{AckRecords: true, DiscardRecords: true})
+	assert.NoError(t, err)
+	defer df.Close()
+	backend := exporter.NewTLSBackend(df.Addr(), &tls.Config{InsecureSkipVerify: true}, exporter.TLSBackendConfig{AckTimeout: time.Second})
+	exp := exporter.NewRecordExporter(backend)
+	defer exp.Close()
+
+	records := makeRecords(t, 3)
+	delivery := exp.SubmitRecords(context.Background(), "task", 1, 1, records, nprobe.DefaultMaxExportRetries)
+	for i := range records {
+		assert.NoError(t, delivery.Wait(i))
+	}
+	received, err := df.WaitRecords(3, time.Second)
+	assert.NoError(t, err)
+	assert.Empty(t, received)
+	assert.Equal(t, 3, df.RecordsReceived())
+}
+
+func TestMockDeliveryFunctionSegmentedRecords(t *testing.T) {
+	// the delivery function refuses the PDUs beyond its size limit
+	const maxRecordSize = 128
+	df, err := NewMockDeliveryFunction(MockDeliveryFunctionConfig{
+		AckRecords:      true,
+		ValidateRecords: true,
+		MaxRecordSize:   maxRecordSize,
+	})
+	assert.NoError(t, err)
+	defer df.Close()
+	backend := exporter.NewTLSBackend(df.Addr(), &tls.Config{InsecureSkipVerify: true}, exporter.TLSBackendConfig{
+		AckTimeout:    time.Second,
+		MaxRecordSize: maxRecordSize,
+	})
+	defer backend.Close()
+
+	// records beyond the limit are delivered once reassembled
+	records := makeRecords(t, 2)
+	assert.True(t, len(records[0]) > maxRecordSize)
+	assert.NoError(t, backend.Send(records[0], 1))
+	errs := backend.SendBatch([]exporter.BatchRecord{{Record: records[1], CorrelationID: 1}})
+	assert.Equal(t, []error{nil}, errs)
+	received, err := df.WaitRecords(2, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, records, received)
+	assert.Empty(t, df.FramingErrors())
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing traces the stages the records go through, from the fetch of
+// the events of a task to their match, their encoding and the delivery of
+// their records, with OpenTelemetry spans exported to a Zipkin-compatible
+// collector such as Jaeger. The span delivering a record is linked to the
+// span encoding it, so that the journey of a record, and where it stalled,
+// can be followed across the export queue. The trace context is propagated
+// across processes in the W3C traceparent format: the spans of the streams of
+// events ingested from the gateways follow the spans of the gateways, and the
+// records produced to Kafka carry the context of the span delivering them.
+// Spans never carry the identity of a target, only the IDs of its task,
+// events and records.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/logging"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var logger = logging.New(logging.ComponentMain)
+
+// Spans of the stages of the processing of a task
+const (
+	// SpanTask is a run of a task, the root of its trace
+	SpanTask = "nprobe.task"
+	// SpanFetch is the fetch of a page of events of a task from its sources
+	SpanFetch = "nprobe.fetch"
+	// SpanMatch is the match of the events of a page against the target,
+	// the warrant and the record filter of a task
+	SpanMatch = "nprobe.match"
+	// SpanEncode is the encoding of a matched event into its record
+	SpanEncode = "nprobe.encode"
+	// SpanExport is the submission of the records of a page to the export
+	// queue, until they are delivered
+	SpanExport = "nprobe.export"
+	// SpanDeliver is the delivery of a record by the exporter, from the time
+	// it was queued
+	SpanDeliver = "nprobe.deliver"
+	// SpanIngest is a stream of events ingested from a gateway, a child of
+	// the span of the gateway streaming them
+	SpanIngest = "nprobe.ingest"
+)
+
+// Attributes of the spans
+const (
+	NetworkID      = label.Key("nprobe.network_id")
+	GatewayID      = label.Key("nprobe.gateway_id")
+	TaskID         = label.Key("nprobe.task_id")
+	EventID        = label.Key("nprobe.event_id")
+	EventType      = label.Key("nprobe.event_type")
+	Events         = label.Key("nprobe.events")
+	Records        = label.Key("nprobe.records")
+	SequenceNumber = label.Key("nprobe.sequence_number")
+	RecordClass    = label.Key("nprobe.record_class")
+	RecordBytes    = label.Key("nprobe.record_bytes")
+	Failure        = label.Key("nprobe.failure")
+)
+
+// Config is the collector the spans are exported to, tracing being disabled
+// without one
+type Config struct {
+	// Endpoint is the URL of the HTTP endpoint of a collector accepting
+	// Zipkin v2 spans, e.g. http://jaeger-collector:9411/api/v2/spans
+	Endpoint string
+	// SampleRatio is the share of the runs of tasks traced, the spans of a
+	// traced run being all exported. The traces started by the gateways
+	// follow the sampling of the gateways.
+	SampleRatio float64
+}
+
+// NewConfig returns the tracing set in the service config
+func NewConfig(config nprobe.Config) (Config, error) {
+	ret := Config{Endpoint: config.TracingEndpoint, SampleRatio: config.TracingSampleRatio}
+	if len(ret.Endpoint) == 0 {
+		return ret, nil
+	}
+	endpoint, err := url.Parse(ret.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || len(endpoint.Host) == 0 {
+		return Config{}, fmt.Errorf("invalid tracing_endpoint %s, expected the http(s) URL of a Zipkin-compatible collector", ret.Endpoint)
+	}
+	if ret.SampleRatio <= 0 || ret.SampleRatio > 1 {
+		return Config{}, fmt.Errorf("invalid tracing_sample_ratio %v, expected a ratio within (0, 1]", ret.SampleRatio)
+	}
+	return ret, nil
+}
+
+// shutdownTimeout bounds the flush of the spans of a collector replaced
+const shutdownTimeout = 10 * time.Second
+
+var (
+	providerMutex sync.RWMutex
+	current       *sdktrace.TracerProvider
+	configured    Config
+
+	noopTracer = trace.NewNoopTracerProvider().Tracer(nprobe.ServiceName)
+)
+
+// Configure exports the spans to the collector of the config, or disables
+// tracing without one. The spans of the previous collector not exported yet
+// are flushed, the collector being kept while the config is unchanged.
+func Configure(config Config) error {
+	providerMutex.RLock()
+	unchanged := config == configured
+	providerMutex.RUnlock()
+	if unchanged {
+		return nil
+	}
+	var next *sdktrace.TracerProvider
+	if len(config.Endpoint) != 0 {
+		next = newProvider(sdktrace.WithBatcher(newZipkinExporter(config.Endpoint)), config.SampleRatio)
+	}
+	setProvider(config, next)
+	return nil
+}
+
+// SetExporter hands the spans to exporter as they end rather than to the
+// configured collector, sampling the given share of the runs, e.g. to record
+// them in tests. It returns a function restoring the previous provider.
+func SetExporter(exporter export.SpanExporter, sampleRatio float64) func() {
+	providerMutex.Lock()
+	previous, previousConfig := current, configured
+	current = newProvider(sdktrace.WithSyncer(exporter), sampleRatio)
+	configured = Config{}
+	providerMutex.Unlock()
+	return func() {
+		providerMutex.Lock()
+		current, configured = previous, previousConfig
+		providerMutex.Unlock()
+	}
+}
+
+// newProvider returns a provider of the spans of the service, the runs
+// started by the service being sampled at the given ratio
+func newProvider(exporter sdktrace.TracerProviderOption, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		exporter,
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))}),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.ServiceNameKey.String(nprobe.ServiceName))),
+	)
+}
+
+// Shutdown flushes the spans not exported yet and disables tracing
+func Shutdown() {
+	setProvider(Config{}, nil)
+}
+
+// setProvider replaces the provider of the spans, nil disabling tracing,
+// and shuts the previous one down
+func setProvider(config Config, next *sdktrace.TracerProvider) {
+	providerMutex.Lock()
+	previous := current
+	current = next
+	configured = config
+	providerMutex.Unlock()
+	if previous != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := previous.Shutdown(ctx); err != nil {
+			logger.Warningf("Failed to flush the spans of the previous tracing collector: %v", err)
+		}
+	}
+}
+
+func getTracer() trace.Tracer {
+	providerMutex.RLock()
+	defer providerMutex.RUnlock()
+	if current == nil {
+		return noopTracer
+	}
+	return current.Tracer(nprobe.ServiceName)
+}
+
+// Start starts a span of the service as a child of the span of ctx, if any,
+// and returns the context of the span
+func Start(ctx context.Context, name string, opts ...trace.SpanOption) (context.Context, trace.Span) {
+	return getTracer().Start(ctx, name, opts...)
+}
+
+// End ends a span, marking it failed with err if not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// recordSpansKey is the context key of the spans encoding the records of a
+// submission
+type recordSpansKey struct{}
+
+// WithRecordSpans returns a copy of ctx carrying the contexts of the spans
+// encoding the records submitted with it, in the order of the records
+func WithRecordSpans(ctx context.Context, spans ...trace.SpanContext) context.Context {
+	return context.WithValue(ctx, recordSpansKey{}, spans)
+}
+
+// GetRecordSpan returns the context of the span encoding the i-th record
+// submitted with ctx, invalid if it wasn't traced
+func GetRecordSpan(ctx context.Context, i int) trace.SpanContext {
+	spans, _ := ctx.Value(recordSpansKey{}).([]trace.SpanContext)
+	if i < 0 || i >= len(spans) {
+		return trace.SpanContext{}
+	}
+	return spans[i]
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/export/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestNewConfig(t *testing.T) {
+	config, err := NewConfig(nprobe.Config{
+		TracingEndpoint:    "http://jaeger-collector:9411/api/v2/spans",
+		TracingSampleRatio: 0.1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Endpoint: "http://jaeger-collector:9411/api/v2/spans", SampleRatio: 0.1}, config)
+
+	// tracing is disabled without a collector
+	config, err = NewConfig(nprobe.Config{TracingSampleRatio: 0.1})
+	assert.NoError(t, err)
+	assert.Empty(t, config.Endpoint)
+
+	_, err = NewConfig(nprobe.Config{TracingEndpoint: "jaeger-collector:9411", TracingSampleRatio: 0.1})
+	assert.EqualError(t, err, "invalid tracing_endpoint jaeger-collector:9411, expected the http(s) URL of a Zipkin-compatible collector")
+	_, err = NewConfig(nprobe.Config{TracingEndpoint: "https://jaeger-collector/api/v2/spans", TracingSampleRatio: 1.5})
+	assert.EqualError(t, err, "invalid tracing_sample_ratio 1.5, expected a ratio within (0, 1]")
+}
+
+func TestConfigure(t *testing.T) {
+	posted := make(chan []zipkinSpan, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var spans []zipkinSpan
+		assert.NoError(t, json.Unmarshal(body, &spans))
+		posted <- spans
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer collector.Close()
+
+	config := Config{Endpoint: collector.URL, SampleRatio: 1}
+	assert.NoError(t, Configure(config))
+	ctx, span := Start(context.Background(), SpanTask, trace.WithAttributes(TaskID.String("task1")))
+	assert.True(t, span.IsRecording())
+	_, child := Start(ctx, SpanFetch)
+	child.AddEvent("retried", trace.WithAttributes(Records.Int(2)))
+	End(child, errors.New("state unavailable"))
+	span.End()
+
+	// the provider is kept while the config is unchanged
+	providerMutex.RLock()
+	configuredProvider := current
+	providerMutex.RUnlock()
+	assert.NoError(t, Configure(config))
+	assert.True(t, configuredProvider == current)
+
+	// the spans are flushed to the collector once shut down, and aren't
+	// recorded anymore
+	Shutdown()
+	spans := <-posted
+	assert.Len(t, spans, 2)
+	assert.Equal(t, SpanFetch, spans[0].Name)
+	assert.Equal(t, span.SpanContext().SpanID.String(), spans[0].ParentID)
+	assert.Equal(t, "state unavailable", spans[0].Tags["error"])
+	assert.Equal(t, "retried nprobe.records=2", spans[0].Annotations[0].Value)
+	assert.Equal(t, SpanTask, spans[1].Name)
+	assert.Empty(t, spans[1].ParentID)
+	assert.Equal(t, "task1", spans[1].Tags[string(TaskID)])
+	assert.Equal(t, nprobe.ServiceName, spans[1].LocalEndpoint.ServiceName)
+	_, span = Start(context.Background(), SpanTask)
+	assert.False(t, span.IsRecording())
+	span.End()
+}
+
+func TestSampling(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	defer SetExporter(exporter, 0.5)()
+
+	// the children of a run follow the sampling of the run
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		ctx, span := Start(context.Background(), SpanTask)
+		_, child := Start(ctx, SpanFetch)
+		assert.Equal(t, span.SpanContext().IsSampled(), child.SpanContext().IsSampled())
+		assert.Equal(t, span.SpanContext().TraceID, child.SpanContext().TraceID)
+		if span.SpanContext().IsSampled() {
+			sampled++
+		}
+		child.End()
+		span.End()
+	}
+	assert.InDelta(t, 500, sampled, 100)
+	assert.Len(t, exporter.GetSpans(), 2*sampled)
+}
+
+func TestEnd(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	defer SetExporter(exporter, 1)()
+
+	_, span := Start(context.Background(), SpanFetch)
+	End(span, nil)
+	_, span = Start(context.Background(), SpanFetch, trace.WithTimestamp(time.Unix(1, 0)))
+	End(span, errors.New("state unavailable"))
+	// spans are ended once
+	span.End()
+	ended := exporter.GetSpans()
+	assert.Len(t, ended, 2)
+	assert.Equal(t, codes.Unset, ended[0].StatusCode)
+	assert.Equal(t, codes.Error, ended[1].StatusCode)
+	assert.Equal(t, "state unavailable", ended[1].StatusMessage)
+	assert.Equal(t, time.Unix(1, 0), ended[1].StartTime)
+}
+
+func TestRecordSpans(t *testing.T) {
+	defer SetExporter(tracetest.NewNoopExporter(), 1)()
+	_, first := Start(context.Background(), SpanEncode)
+	_, second := Start(context.Background(), SpanEncode)
+
+	ctx := WithRecordSpans(context.Background(), first.SpanContext(), second.SpanContext())
+	assert.Equal(t, first.SpanContext(), GetRecordSpan(ctx, 0))
+	assert.Equal(t, second.SpanContext(), GetRecordSpan(ctx, 1))
+	assert.False(t, GetRecordSpan(ctx, 2).IsValid())
+	assert.Equal(t, trace.SpanContext{}, GetRecordSpan(context.Background(), 0))
+}
+
+func TestPropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	defer SetExporter(exporter, 0.5)()
+
+	// the spans of a gateway are continued whatever the sample ratio
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	))
+	ctx, span := Start(ExtractIncoming(ctx), SpanIngest, trace.WithSpanKind(trace.SpanKindServer))
+	assert.True(t, span.SpanContext().IsSampled())
+	assert.Equal(t, traceID, span.SpanContext().TraceID)
+
+	// the context of the span is carried along to the next process
+	md := metadata.MD{}
+	Inject(ctx, MetadataCarrier(md))
+	assert.Equal(t, []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-" + span.SpanContext().SpanID.String() + "-01"}, md.Get("traceparent"))
+	span.End()
+
+	ended := exporter.GetSpans()
+	assert.Len(t, ended, 1)
+	assert.Equal(t, "00f067aa0ba902b7", ended[0].ParentSpanID.String())
+	assert.True(t, ended[0].HasRemoteParent)
+	assert.Equal(t, "SERVER", toZipkinSpan(ended[0]).Kind)
+
+	// the runs started without a remote span follow the sample ratio
+	ctx = ExtractIncoming(metadata.NewIncomingContext(context.Background(), metadata.MD{}))
+	assert.False(t, trace.RemoteSpanContextFromContext(ctx).IsValid())
+	assert.Equal(t, context.Background(), ExtractIncoming(context.Background()))
+}
+
+var _ export.SpanExporter = &zipkinExporter{}
@@ -0,0 +1,154 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+
+	"go.opentelemetry.io/otel/codes"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exportTimeout bounds the export of a batch to the collector
+const exportTimeout = 10 * time.Second
+
+// zipkinExporter posts the spans to a collector in the Zipkin v2 JSON format,
+// which Jaeger collectors accept as well. The Zipkin and Jaeger exporters of
+// OpenTelemetry aren't used as they require newer gRPC and protobuf modules
+// than the rest of the controller.
+type zipkinExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newZipkinExporter(endpoint string) *zipkinExporter {
+	return &zipkinExporter{endpoint: endpoint, client: &http.Client{Timeout: exportTimeout}}
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+type zipkinSpan struct {
+	TraceID       string             `json:"traceId"`
+	ID            string             `json:"id"`
+	ParentID      string             `json:"parentId,omitempty"`
+	Kind          string             `json:"kind,omitempty"`
+	Name          string             `json:"name"`
+	Timestamp     int64              `json:"timestamp"`
+	Duration      int64              `json:"duration"`
+	LocalEndpoint zipkinEndpoint     `json:"localEndpoint"`
+	Annotations   []zipkinAnnotation `json:"annotations,omitempty"`
+	Tags          map[string]string  `json:"tags,omitempty"`
+}
+
+// linksTag is the tag listing the spans a span is linked to, Zipkin having
+// no links of its own
+const linksTag = "nprobe.links"
+
+// zipkinKinds are the Zipkin kinds of the spans, the internal spans having
+// none
+var zipkinKinds = map[trace.SpanKind]string{
+	trace.SpanKindServer:   "SERVER",
+	trace.SpanKindClient:   "CLIENT",
+	trace.SpanKindProducer: "PRODUCER",
+	trace.SpanKindConsumer: "CONSUMER",
+}
+
+// toZipkinSpan converts a span, the attributes of its events being appended
+// to their annotation
+func toZipkinSpan(span *export.SpanSnapshot) zipkinSpan {
+	ret := zipkinSpan{
+		TraceID:       span.SpanContext.TraceID.String(),
+		ID:            span.SpanContext.SpanID.String(),
+		Kind:          zipkinKinds[span.SpanKind],
+		Name:          span.Name,
+		Timestamp:     span.StartTime.UnixNano() / int64(time.Microsecond),
+		Duration:      span.EndTime.Sub(span.StartTime).Nanoseconds() / int64(time.Microsecond),
+		LocalEndpoint: zipkinEndpoint{ServiceName: nprobe.ServiceName},
+		Tags:          map[string]string{},
+	}
+	if span.ParentSpanID.IsValid() {
+		ret.ParentID = span.ParentSpanID.String()
+	}
+	for _, attribute := range span.Attributes {
+		ret.Tags[string(attribute.Key)] = attribute.Value.Emit()
+	}
+	if len(span.Links) != 0 {
+		var links []string
+		for _, link := range span.Links {
+			links = append(links, link.TraceID.String()+":"+link.SpanID.String())
+		}
+		ret.Tags[linksTag] = strings.Join(links, ",")
+	}
+	if span.StatusCode == codes.Error {
+		ret.Tags["error"] = span.StatusMessage
+	}
+	for _, event := range span.MessageEvents {
+		value := event.Name
+		for _, attribute := range event.Attributes {
+			value += fmt.Sprintf(" %s=%s", attribute.Key, attribute.Value.Emit())
+		}
+		ret.Annotations = append(ret.Annotations, zipkinAnnotation{
+			Timestamp: event.Time.UnixNano() / int64(time.Microsecond),
+			Value:     value,
+		})
+	}
+	return ret
+}
+
+// ExportSpans posts a batch of ended spans to the collector
+func (e *zipkinExporter) ExportSpans(ctx context.Context, spans []*export.SpanSnapshot) error {
+	converted := make([]zipkinSpan, 0, len(spans))
+	for _, span := range spans {
+		converted = append(converted, toZipkinSpan(span))
+	}
+	body, err := json.Marshal(converted)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Shutdown does nothing, the spans being posted as they are exported
+func (e *zipkinExporter) Shutdown(context.Context) error {
+	return nil
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const encodingDir = "../../services/nprobe/encoding"
+
+// TestGeneratedFile checks that the generated files of the encoding package
+// are up to date with their schemas
+func TestGeneratedFile(t *testing.T) {
+	eps := readSchema(t, "EpsHI2Operations.asn")
+	generated, err := generate(eps, nil, "encoding", "schema/EpsHI2Operations.asn")
+	assert.NoError(t, err)
+	committed, err := ioutil.ReadFile(filepath.Join(encodingDir, "asn1_gen.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(committed), string(generated), "asn1_gen.go is out of date, run go generate")
+
+	umts := readSchema(t, "UmtsHI2Operations.asn")
+	generated, err = generate(umts, []*module{eps}, "encoding", "schema/UmtsHI2Operations.asn")
+	assert.NoError(t, err)
+	committed, err = ioutil.ReadFile(filepath.Join(encodingDir, "umts_gen.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(committed), string(generated), "umts_gen.go is out of date, run go generate")
+}
+
+func readSchema(t *testing.T, name string) *module {
+	src, err := ioutil.ReadFile(filepath.Join(encodingDir, "schema", name))
+	assert.NoError(t, err)
+	m, err := parseModule(string(src))
+	assert.NoError(t, err)
+	return m
+}
+
+func TestGenerate(t *testing.T) {
+	m, err := parseModule(`M DEFINITIONS IMPLICIT TAGS ::= BEGIN
+-- Outer holds an inner value
+Outer ::= SEQUENCE {
+	id [0] OBJECT IDENTIFIER,
+	inner [1] Inner OPTIONAL, -- go:name=Value
+	list [2] SET SIZE (1..10) OF Inner OPTIONAL,
+	...,
+	count [3] Count OPTIONAL -- go:type=int64
+}
+Inner ::= CHOICE {
+	data [1] OCTET STRING (SIZE (1..8)),
+	flag [2] NULL
+}
+Count ::= INTEGER (0..65535)
+END`)
+	assert.NoError(t, err)
+	out, err := generate(m, nil, "p", "m.asn")
+	assert.NoError(t, err)
+	src := string(out)
+	assert.Contains(t, src, "// Code generated by asn1gen from m.asn. DO NOT EDIT.")
+	assert.Contains(t, src, "// Outer holds an inner value\ntype Outer struct {")
+	assert.Contains(t, src, "Id    asn1.ObjectIdentifier `asn1:\"tag:0\"`")
+	assert.Contains(t, src, "Value Inner                 `asn1:\"optional,tag:1\"`")
+	assert.Contains(t, src, "List  []Inner               `asn1:\"set,optional,tag:2\"`")
+	assert.Contains(t, src, "Count int64                 `asn1:\"optional,tag:3\"`")
+	// the alternatives of a CHOICE are optional
+	assert.Contains(t, src, "Data []byte `asn1:\"optional,tag:1\"`")
+	assert.Contains(t, src, "func appendOuter(b []byte, class byte, tag int, v *Outer) ([]byte, error) {")
+	assert.Contains(t, src, "func appendInner(b []byte, class byte, tag int, v *Inner) []byte {")
+	assert.Contains(t, src, "func isZeroInner(v *Inner) bool {")
+	assert.NotContains(t, src, "func isZeroOuter(")
+}
+
+func TestGenerateImports(t *testing.T) {
+	base, err := parseModule(`Base DEFINITIONS IMPLICIT TAGS ::= BEGIN
+Inner ::= SEQUENCE {
+	data [1] OCTET STRING OPTIONAL
+}
+Holder ::= SEQUENCE {
+	inner [0] Inner OPTIONAL
+}
+Unused ::= SEQUENCE {
+	flag [1] NULL
+}
+END`)
+	assert.NoError(t, err)
+	m, err := parseModule(`M DEFINITIONS IMPLICIT TAGS ::= BEGIN
+IMPORTS Inner FROM Base {0 4 0};
+Outer ::= SEQUENCE {
+	inner [0] Inner OPTIONAL
+}
+END`)
+	assert.NoError(t, err)
+	out, err := generate(m, []*module{base}, "p", "m.asn")
+	assert.NoError(t, err)
+	src := string(out)
+	// the imported structs are the ones of their module
+	assert.Contains(t, src, "Inner Inner `asn1:\"optional,tag:0\"`")
+	assert.Contains(t, src, "if !isZeroInner(&v.Inner) {")
+	assert.NotContains(t, src, "type Inner struct")
+	assert.NotContains(t, src, "func appendInner(")
+
+	importErrors := []struct {
+		module string
+		err    string
+	}{
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN IMPORTS Inner FROM Other; END", "module Other, which is not given"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN IMPORTS Outer FROM Base; END", "Outer is not defined by module Base"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN IMPORTS Unused FROM Base; A ::= SEQUENCE { a [0] Unused OPTIONAL } END",
+			"which module Base does not generate"},
+	}
+	for _, test := range importErrors {
+		m, err := parseModule(test.module)
+		assert.NoError(t, err, test.module)
+		_, err = generate(m, []*module{base}, "p", "m.asn")
+		if assert.Error(t, err, test.module) {
+			assert.Contains(t, err.Error(), test.err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		module string
+		err    string
+	}{
+		{"M DEFINITIONS ::= BEGIN END", "only modules with IMPLICIT TAGS are supported"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a OCTET STRING } END", "component a has no tag"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a [0] INTEGER DEFAULT 1 } END", "unsupported default value"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a [0] NULL, b [0] NULL } END", "reuses a name or a tag"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a [0] 5 } END", `unsupported type "5"`},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= NULL A ::= NULL END", "type A defined twice"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a [0] NULL -- go:size=1\n} END", `invalid directive "go:size=1"`},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a [0] NULL", "found the end of the module"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN IMPORTS A FROM B; A ::= NULL END", "type A defined twice"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN IMPORTS A B; END", `expected "FROM"`},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN IMPORTS FROM B; END", "no symbol imported from B"},
+	}
+	for _, test := range tests {
+		_, err := parseModule(test.module)
+		if assert.Error(t, err, test.module) {
+			assert.Contains(t, err.Error(), test.err)
+		}
+	}
+
+	generateErrors := []struct {
+		module string
+		err    string
+	}{
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a [0] REAL } END", "undefined type REAL"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a [0] SEQUENCE { b [0] NULL } } END", "must be named"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a [0] SET OF NULL } END", "only collections of SEQUENCE or CHOICE types"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a [0] A OPTIONAL } END", "struct A contains itself"},
+		{"M DEFINITIONS IMPLICIT TAGS ::= BEGIN A ::= SEQUENCE { a [0] NULL -- go:type=int64\n} END", "can't be a int64"},
+	}
+	for _, test := range generateErrors {
+		m, err := parseModule(test.module)
+		assert.NoError(t, err, test.module)
+		_, err = generate(m, nil, "p", "m.asn")
+		if assert.Error(t, err, test.module) {
+			assert.Contains(t, err.Error(), test.err)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+)
+
+// goStruct is the Go struct of a SEQUENCE or CHOICE type
+type goStruct struct {
+	name       string
+	doc        []string
+	fields     []*goField
+	assignment *typeAssignment
+}
+
+// goField is a field of a Go struct. Fields of a constructed type hold the
+// struct of the type, or of the elements of a collection.
+type goField struct {
+	name     string
+	goType   string
+	kind     string
+	tag      int
+	optional bool
+	elem     string
+}
+
+// generate generates the Go structs of the constructed types of a module
+// and their encoding functions. The structs of the types imported from other
+// modules are the ones generated from these modules in the same package.
+func generate(m *module, imported []*module, pkg, source string) ([]byte, error) {
+	g, err := newGenerator(m, imported)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by asn1gen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	if g.usesPackage {
+		b.WriteString("import \"encoding/asn1\"\n\n")
+	}
+	for _, s := range g.structs {
+		g.writeStruct(&b, s)
+	}
+	for _, s := range g.structs {
+		g.writeAppend(&b, s)
+		if g.zeroChecked[s.name] {
+			g.writeIsZero(&b, s)
+		}
+	}
+	out, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code: %v", err)
+	}
+	return out, nil
+}
+
+// generator maps the types of a module to Go structs
+type generator struct {
+	module      *module
+	assignments map[string]*typeAssignment
+	structs     []*goStruct
+	byName      map[string]*goStruct
+	// external are the generators of the modules types are imported from,
+	// by module name
+	external    map[string]*generator
+	usesPackage bool
+	// zeroChecked are the structs of optional fields, which are omitted
+	// when zero
+	zeroChecked map[string]bool
+	// failing are the structs whose encoding can fail, e.g. holding an
+	// OBJECT IDENTIFIER
+	failing map[string]bool
+}
+
+func newGenerator(m *module, imported []*module) (*generator, error) {
+	g := &generator{
+		module:      m,
+		assignments: map[string]*typeAssignment{},
+		byName:      map[string]*goStruct{},
+		external:    map[string]*generator{},
+		zeroChecked: map[string]bool{},
+		failing:     map[string]bool{},
+	}
+	for _, im := range imported {
+		external, err := newGenerator(im, nil)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %v", im.name, err)
+		}
+		g.external[im.name] = external
+	}
+	for symbol, from := range m.imports {
+		external, ok := g.external[from]
+		if !ok {
+			return nil, fmt.Errorf("%s is imported from module %s, which is not given", symbol, from)
+		}
+		if _, ok := external.assignments[symbol]; !ok {
+			return nil, fmt.Errorf("%s is not defined by module %s", symbol, from)
+		}
+	}
+	for _, t := range m.types {
+		g.assignments[t.name] = t
+	}
+	for _, t := range m.types {
+		if t.typ.kind != kindSequence && t.typ.kind != kindChoice {
+			continue
+		}
+		name := getTypeName(t)
+		if _, ok := g.getStruct(name); ok {
+			return nil, fmt.Errorf("line %d: struct %s generated twice", t.line, name)
+		}
+		s := &goStruct{name: name, doc: t.doc, assignment: t}
+		g.byName[name] = s
+		g.structs = append(g.structs, s)
+	}
+	for _, s := range g.structs {
+		if err := g.resolveFields(s); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range g.structs {
+		for _, f := range s.fields {
+			if f.kind == kindSequence && f.optional {
+				if err := g.checkZero(f.elem); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for _, s := range g.structs {
+		if _, err := g.isFailing(s.name, map[string]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// resolveFields maps the components of the type of a struct to its fields.
+// The alternatives of a CHOICE are optional, but for a single one.
+func (g *generator) resolveFields(s *goStruct) error {
+	t := s.assignment
+	names := map[string]bool{}
+	for _, c := range t.typ.components {
+		f := &goField{name: getFieldName(c), tag: c.tag, optional: c.optional}
+		if t.typ.kind == kindChoice && len(t.typ.components) > 1 {
+			f.optional = true
+		}
+		if names[f.name] {
+			return fmt.Errorf("line %d: field %s of %s generated twice", c.line, f.name, s.name)
+		}
+		names[f.name] = true
+
+		typ, structName, err := g.resolve(c.typ)
+		if err != nil {
+			return fmt.Errorf("line %d: component %s: %v", c.line, c.name, err)
+		}
+		f.kind = typ.kind
+		switch typ.kind {
+		case kindSequence, kindChoice:
+			f.kind, f.elem = kindSequence, structName
+			f.goType = f.elem
+		case kindSetOf, kindSequenceOf:
+			elem, elemName, err := g.resolve(typ.elem)
+			if err != nil {
+				return fmt.Errorf("line %d: component %s: %v", c.line, c.name, err)
+			}
+			if elem.kind != kindSequence && elem.kind != kindChoice {
+				return fmt.Errorf("line %d: component %s: only collections of SEQUENCE or CHOICE types are supported", c.line, c.name)
+			}
+			f.elem = elemName
+			f.goType = "[]" + f.elem
+		case kindOctetString, kindNull, kindGeneralizedTime:
+			// NULL and GeneralizedTime share the encoding of the octet
+			// string of their contents octets
+			f.kind, f.goType = kindOctetString, "[]byte"
+		case kindInteger:
+			f.goType = "int"
+		case kindEnumerated:
+			f.goType = "asn1.Enumerated"
+			g.usesPackage = true
+		case kindOID:
+			f.goType = "asn1.ObjectIdentifier"
+			g.usesPackage = true
+		case kindPrintableString:
+			f.goType = "string"
+		}
+		if goType, ok := c.directives["type"]; ok {
+			if f.kind != kindInteger || (goType != "int" && goType != "int32" && goType != "int64") {
+				return fmt.Errorf("line %d: component %s can't be a %s", c.line, c.name, goType)
+			}
+			f.goType = goType
+		}
+		s.fields = append(s.fields, f)
+	}
+	return nil
+}
+
+// resolve follows the references of a type to its definition, in the
+// module it is imported from if not defined by the module. Constructed types
+// are returned along with the name of their struct.
+func (g *generator) resolve(typ *asnType) (*asnType, string, error) {
+	seen := map[string]bool{}
+	scope := g
+	for typ.kind == kindReference {
+		t, ok := scope.assignments[typ.ref]
+		if !ok {
+			from, imported := scope.module.imports[typ.ref]
+			if !imported {
+				return nil, "", fmt.Errorf("undefined type %s", typ.ref)
+			}
+			scope = g.external[from]
+			t = scope.assignments[typ.ref]
+		}
+		if seen[scope.module.name+"."+t.name] {
+			return nil, "", fmt.Errorf("type %s references itself", typ.ref)
+		}
+		seen[scope.module.name+"."+t.name] = true
+		if t.typ.kind == kindSequence || t.typ.kind == kindChoice {
+			return t.typ, getTypeName(t), nil
+		}
+		typ = t.typ
+	}
+	if typ.kind == kindSequence || typ.kind == kindChoice {
+		return nil, "", fmt.Errorf("inline %s types are not supported, they must be named", typ.kind)
+	}
+	return typ, "", nil
+}
+
+// getStruct returns the struct of the given name, generated from the module
+// or from a module it imports types from
+func (g *generator) getStruct(name string) (*goStruct, bool) {
+	if s, ok := g.byName[name]; ok {
+		return s, true
+	}
+	for _, external := range g.external {
+		if s, ok := external.byName[name]; ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// checkZero marks a struct as compared to its zero value, along with the
+// structs of its fields. The structs of imported types must be compared by
+// their own module, which generates their function.
+func (g *generator) checkZero(name string) error {
+	if g.zeroChecked[name] {
+		return nil
+	}
+	if _, ok := g.byName[name]; !ok {
+		for _, external := range g.external {
+			if _, ok := external.byName[name]; ok && !external.zeroChecked[name] {
+				return fmt.Errorf("%s is compared to its zero value, which module %s does not generate", name, external.module.name)
+			}
+		}
+		return nil
+	}
+	g.zeroChecked[name] = true
+	for _, f := range g.byName[name].fields {
+		if f.kind == kindSequence {
+			if err := g.checkZero(f.elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isFailing returns true if the encoding of a struct can fail
+func (g *generator) isFailing(name string, visiting map[string]bool) (bool, error) {
+	if failing, ok := g.failing[name]; ok {
+		return failing, nil
+	}
+	for _, external := range g.external {
+		if failing, ok := external.failing[name]; ok {
+			return failing, nil
+		}
+	}
+	if visiting[name] {
+		return false, fmt.Errorf("struct %s contains itself", name)
+	}
+	visiting[name] = true
+	failing := false
+	for _, f := range g.byName[name].fields {
+		switch f.kind {
+		case kindOID, kindPrintableString:
+			failing = true
+		case kindSequence, kindSetOf, kindSequenceOf:
+			elemFailing, err := g.isFailing(f.elem, visiting)
+			if err != nil {
+				return false, err
+			}
+			failing = failing || elemFailing
+		}
+	}
+	g.failing[name] = failing
+	return failing, nil
+}
+
+func (g *generator) writeStruct(b *bytes.Buffer, s *goStruct) {
+	for _, line := range s.doc {
+		b.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+	}
+	fmt.Fprintf(b, "type %s struct {\n", s.name)
+	for _, f := range s.fields {
+		var params []string
+		switch f.kind {
+		case kindSetOf:
+			params = append(params, "set")
+		case kindPrintableString:
+			params = append(params, "printable")
+		}
+		if f.optional {
+			params = append(params, "optional")
+		}
+		params = append(params, fmt.Sprintf("tag:%d", f.tag))
+		fmt.Fprintf(b, "\t%s %s `asn1:\"%s\"`\n", f.name, f.goType, strings.Join(params, ","))
+	}
+	b.WriteString("}\n\n")
+}
+
+// writeAppend writes the function appending the encoding of a struct with
+// the given identifier
+func (g *generator) writeAppend(b *bytes.Buffer, s *goStruct) {
+	failing := g.failing[s.name]
+	result := "[]byte"
+	if failing {
+		result = "([]byte, error)"
+	}
+	fmt.Fprintf(b, "// append%s appends the encoding of v with the given identifier\n", s.name)
+	fmt.Fprintf(b, "func append%s(b []byte, class byte, tag int, v *%s) %s {\n", s.name, s.name, result)
+	if failing {
+		b.WriteString("var err error\n")
+	}
+	b.WriteString("b, offset := beginElement(b, class, tag)\n")
+	for _, f := range s.fields {
+		value := "v." + f.name
+		switch f.kind {
+		case kindOctetString:
+			if f.optional {
+				fmt.Fprintf(b, "b = appendOptionalBytes(b, %d, %s)\n", f.tag, value)
+			} else {
+				fmt.Fprintf(b, "b = appendBytes(b, %d, %s)\n", f.tag, value)
+			}
+		case kindEnumerated, kindInteger:
+			if f.optional {
+				fmt.Fprintf(b, "if %s != 0 {\n", value)
+			}
+			switch f.goType {
+			case "asn1.Enumerated":
+				fmt.Fprintf(b, "b = appendEnumerated(b, %d, %s)\n", f.tag, value)
+			case "int64":
+				fmt.Fprintf(b, "b = appendInteger(b, %d, %s)\n", f.tag, value)
+			default:
+				fmt.Fprintf(b, "b = appendInteger(b, %d, int64(%s))\n", f.tag, value)
+			}
+			if f.optional {
+				b.WriteString("}\n")
+			}
+		case kindOID, kindPrintableString:
+			if f.optional {
+				if f.kind == kindOID {
+					fmt.Fprintf(b, "if %s != nil {\n", value)
+				} else {
+					fmt.Fprintf(b, "if %s != \"\" {\n", value)
+				}
+			}
+			appendFunc := "appendObjectIdentifier"
+			if f.kind == kindPrintableString {
+				appendFunc = "appendPrintableString"
+			}
+			writeFailingCall(b, fmt.Sprintf("%s(b, %d, %s)", appendFunc, f.tag, value))
+			if f.optional {
+				b.WriteString("}\n")
+			}
+		case kindSequence:
+			if f.optional {
+				fmt.Fprintf(b, "if !isZero%s(&%s) {\n", f.elem, value)
+			}
+			call := fmt.Sprintf("append%s(b, classContextSpecific|constructedForm, %d, &%s)", f.elem, f.tag, value)
+			if elemFailing, _ := g.isFailing(f.elem, nil); elemFailing {
+				writeFailingCall(b, call)
+			} else {
+				fmt.Fprintf(b, "b = %s\n", call)
+			}
+			if f.optional {
+				b.WriteString("}\n")
+			}
+		case kindSetOf, kindSequenceOf:
+			// the elements are encoded in order, DER sorting the elements
+			// of a SET OF being left to the caller
+			if f.optional {
+				fmt.Fprintf(b, "if %s != nil {\n", value)
+			} else {
+				b.WriteString("{\n")
+			}
+			b.WriteString("var elements int\n")
+			fmt.Fprintf(b, "b, elements = beginElement(b, classContextSpecific|constructedForm, %d)\n", f.tag)
+			fmt.Fprintf(b, "for i := range %s {\n", value)
+			call := fmt.Sprintf("append%s(b, constructedForm, tagUniversalSequence, &%s[i])", f.elem, value)
+			if elemFailing, _ := g.isFailing(f.elem, nil); elemFailing {
+				writeFailingCall(b, call)
+			} else {
+				fmt.Fprintf(b, "b = %s\n", call)
+			}
+			b.WriteString("}\n")
+			b.WriteString("b = endElement(b, elements)\n")
+			b.WriteString("}\n")
+		}
+	}
+	if failing {
+		b.WriteString("return endElement(b, offset), nil\n")
+	} else {
+		b.WriteString("return endElement(b, offset)\n")
+	}
+	b.WriteString("}\n\n")
+}
+
+// writeFailingCall writes the call of an append function which can fail
+func writeFailingCall(b *bytes.Buffer, call string) {
+	fmt.Fprintf(b, "if b, err = %s; err != nil {\nreturn nil, err\n}\n", call)
+}
+
+// writeIsZero writes the function comparing a struct to its zero value as
+// encoding/asn1 does to omit optional fields
+func (g *generator) writeIsZero(b *bytes.Buffer, s *goStruct) {
+	fmt.Fprintf(b, "// isZero%s returns true if v is the zero value as encoding/asn1\n", s.name)
+	b.WriteString("// compares it, omitted when optional\n")
+	fmt.Fprintf(b, "func isZero%s(v *%s) bool {\n", s.name, s.name)
+	var conditions []string
+	for _, f := range s.fields {
+		value := "v." + f.name
+		switch f.kind {
+		case kindOctetString, kindOID, kindSetOf, kindSequenceOf:
+			conditions = append(conditions, value+" == nil")
+		case kindEnumerated, kindInteger:
+			conditions = append(conditions, value+" == 0")
+		case kindPrintableString:
+			conditions = append(conditions, value+" == \"\"")
+		case kindSequence:
+			conditions = append(conditions, fmt.Sprintf("isZero%s(&%s)", f.elem, value))
+		}
+	}
+	if len(conditions) == 0 {
+		conditions = []string{"true"}
+	}
+	fmt.Fprintf(b, "return %s\n}\n\n", strings.Join(conditions, " &&\n"))
+}
+
+// getTypeName returns the name of the struct of a type, set by its go:name
+// directive or derived from its name
+func getTypeName(t *typeAssignment) string {
+	if name, ok := t.directives["name"]; ok {
+		return name
+	}
+	return toGoName(t.name)
+}
+
+// getFieldName returns the name of the field of a component, set by its
+// go:name directive or derived from its name
+func getFieldName(c *component) string {
+	if name, ok := c.directives["name"]; ok {
+		return name
+	}
+	return toGoName(c.name)
+}
+
+// toGoName capitalizes each hyphen-separated part of an ASN.1 name, e.g.
+// Network-Identifier is NetworkIdentifier and iP-value IPValue
+func toGoName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "-") {
+		if len(part) == 0 {
+			continue
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// asn1gen generates the Go structs of an ASN.1 module, tagged for
+// encoding/asn1, along with the functions encoding them without reflection
+// exactly as encoding/asn1 does. It is run by go generate in the nprobe
+// encoding package, which provides the primitives the generated functions
+// build on, e.g. appendBytes and beginElement.
+//
+// Usage:
+//
+//	asn1gen -schema schema/EpsHI2Operations.asn -out asn1_gen.go
+//	asn1gen -schema schema/UmtsHI2Operations.asn -import schema/EpsHI2Operations.asn -out umts_gen.go
+//
+// The structs of the types imported from the modules given by -import are
+// not generated again, they are the ones generated from these modules in the
+// same package.
+//
+// Only the subset of ASN.1 the IRI records need is supported: modules with
+// IMPLICIT TAGS whose SEQUENCE and CHOICE types have context-specific tags,
+// with OCTET STRING, NULL, GeneralizedTime, INTEGER, ENUMERATED, OBJECT
+// IDENTIFIER and PrintableString components, and SET OF or SEQUENCE OF
+// components of SEQUENCE types. Constraints are ignored. Directives in the
+// comments of a type or a component set the name of its Go struct or field
+// (go:name=Name) or the Go type of an INTEGER (go:type=int64). The other
+// comments preceding a type are the doc comment of its struct.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	schemaFile := flag.String("schema", "", "ASN.1 module to generate the structs of")
+	outFile := flag.String("out", "", "Go file to generate")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package of the generated file, the one of go generate by default")
+	importFiles := flag.String("import", "", "comma-separated ASN.1 modules the schema imports types from")
+	flag.Parse()
+
+	if len(*schemaFile) == 0 || len(*outFile) == 0 || len(*pkg) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: asn1gen -schema MODULE [-import MODULE,...] -out FILE [-package PACKAGE]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	var imports []string
+	if len(*importFiles) != 0 {
+		imports = strings.Split(*importFiles, ",")
+	}
+	if err := run(*schemaFile, imports, *outFile, *pkg); err != nil {
+		fmt.Fprintf(os.Stderr, "asn1gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(schemaFile string, importFiles []string, outFile, pkg string) error {
+	m, err := readModule(schemaFile)
+	if err != nil {
+		return err
+	}
+	var imported []*module
+	for _, file := range importFiles {
+		im, err := readModule(file)
+		if err != nil {
+			return err
+		}
+		imported = append(imported, im)
+	}
+	out, err := generate(m, imported, pkg, filepath.ToSlash(schemaFile))
+	if err != nil {
+		return fmt.Errorf("%s: %v", schemaFile, err)
+	}
+	return ioutil.WriteFile(outFile, out, 0644)
+}
+
+// readModule reads and parses an ASN.1 module
+func readModule(file string) (*module, error) {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseModule(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return m, nil
+}
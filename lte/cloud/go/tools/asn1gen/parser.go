@@ -0,0 +1,546 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Kinds of the ASN.1 types
+const (
+	kindSequence        = "SEQUENCE"
+	kindChoice          = "CHOICE"
+	kindSetOf           = "SET OF"
+	kindSequenceOf      = "SEQUENCE OF"
+	kindOctetString     = "OCTET STRING"
+	kindNull            = "NULL"
+	kindGeneralizedTime = "GeneralizedTime"
+	kindInteger         = "INTEGER"
+	kindEnumerated      = "ENUMERATED"
+	kindOID             = "OBJECT IDENTIFIER"
+	kindPrintableString = "PrintableString"
+	kindReference       = "reference"
+)
+
+// module is a parsed ASN.1 module. Imported types are mapped to the module
+// they are imported from.
+type module struct {
+	name    string
+	types   []*typeAssignment
+	imports map[string]string
+}
+
+// typeAssignment is the definition of a named type of a module
+type typeAssignment struct {
+	name       string
+	line       int
+	doc        []string
+	directives map[string]string
+	typ        *asnType
+}
+
+// asnType is a type of a module. Constructed types list their components,
+// collection types their element and references the name of their type.
+type asnType struct {
+	kind       string
+	components []*component
+	elem       *asnType
+	ref        string
+	line       int
+}
+
+// component is a component of a SEQUENCE or an alternative of a CHOICE
+type component struct {
+	name       string
+	line       int
+	tag        int
+	typ        *asnType
+	optional   bool
+	directives map[string]string
+}
+
+// token is a lexical item of a module
+type token struct {
+	text string
+	line int
+}
+
+// parser parses a module from its tokens. The comments of the module are
+// kept by line, with the lines holding tokens, to find the doc comments and
+// directives of its types and components.
+type parser struct {
+	tokens     []token
+	pos        int
+	comments   map[int][]string
+	tokenLines map[int]bool
+}
+
+// parseModule parses an ASN.1 module
+func parseModule(src string) (*module, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseModule()
+}
+
+func newParser(src string) (*parser, error) {
+	p := &parser{comments: map[int][]string{}, tokenLines: map[int]bool{}}
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "--"):
+			// comments end at the end of the line or at the next "--"
+			end := i + 2
+			for end < len(src) && src[end] != '\n' && !strings.HasPrefix(src[end:], "--") {
+				end++
+			}
+			p.comments[line] = append(p.comments[line], strings.TrimSpace(src[i+2:end]))
+			i = end
+			if strings.HasPrefix(src[i:], "--") {
+				i += 2
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+end], "\n")
+			i += end + 2
+		case strings.HasPrefix(src[i:], "::="):
+			p.addToken("::=", line)
+			i += 3
+		case strings.HasPrefix(src[i:], "..."):
+			p.addToken("...", line)
+			i += 3
+		case strings.HasPrefix(src[i:], ".."):
+			p.addToken("..", line)
+			i += 2
+		case strings.ContainsRune("{}[](),|;", rune(c)):
+			p.addToken(string(c), line)
+			i++
+		case isLetter(c) || isDigit(c):
+			end := i + 1
+			for end < len(src) && (isLetter(src[end]) || isDigit(src[end]) ||
+				(src[end] == '-' && end+1 < len(src) && src[end+1] != '-' && (isLetter(src[end+1]) || isDigit(src[end+1])))) {
+				end++
+			}
+			p.addToken(src[i:end], line)
+			i = end
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+		}
+	}
+	return p, nil
+}
+
+func (p *parser) addToken(text string, line int) {
+	p.tokens = append(p.tokens, token{text: text, line: line})
+	p.tokenLines[line] = true
+}
+
+func (p *parser) parseModule() (*module, error) {
+	name, err := p.typeReference()
+	if err != nil {
+		return nil, err
+	}
+	m := &module{name: name.text, imports: map[string]string{}}
+	if p.peek() == "{" {
+		if err := p.skipBalanced("{", "}"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("DEFINITIONS"); err != nil {
+		return nil, err
+	}
+	tagging := ""
+	if next := p.peek(); next == "IMPLICIT" || next == "EXPLICIT" || next == "AUTOMATIC" {
+		tagging = p.next().text
+		if err := p.expect("TAGS"); err != nil {
+			return nil, err
+		}
+	}
+	if tagging != "IMPLICIT" {
+		return nil, fmt.Errorf("module %s: only modules with IMPLICIT TAGS are supported", m.name)
+	}
+	if err := p.expect("::="); err != nil {
+		return nil, err
+	}
+	if err := p.expect("BEGIN"); err != nil {
+		return nil, err
+	}
+
+	if p.peek() == "IMPORTS" {
+		p.next()
+		if err := p.parseImports(m); err != nil {
+			return nil, err
+		}
+	}
+
+	defined := map[string]bool{}
+	for p.peek() != "END" {
+		assignment, err := p.parseAssignment()
+		if err != nil {
+			return nil, err
+		}
+		if defined[assignment.name] || m.imports[assignment.name] != "" {
+			return nil, fmt.Errorf("line %d: type %s defined twice", assignment.line, assignment.name)
+		}
+		defined[assignment.name] = true
+		m.types = append(m.types, assignment)
+	}
+	return m, p.expect("END")
+}
+
+// parseImports parses the symbols imported from other modules, up to the
+// semicolon ending the IMPORTS
+func (p *parser) parseImports(m *module) error {
+	var symbols []token
+	for p.peek() != ";" {
+		if p.peek() != "FROM" {
+			symbol, err := p.typeReference()
+			if err != nil {
+				return err
+			}
+			symbols = append(symbols, symbol)
+			if p.peek() == "," {
+				p.next()
+			}
+			continue
+		}
+		p.next()
+		from, err := p.typeReference()
+		if err != nil {
+			return err
+		}
+		if len(symbols) == 0 {
+			return fmt.Errorf("line %d: no symbol imported from %s", from.line, from.text)
+		}
+		for _, symbol := range symbols {
+			if m.imports[symbol.text] != "" {
+				return fmt.Errorf("line %d: %s imported twice", symbol.line, symbol.text)
+			}
+			m.imports[symbol.text] = from.text
+		}
+		symbols = nil
+		if p.peek() == "{" {
+			if err := p.skipBalanced("{", "}"); err != nil {
+				return err
+			}
+		}
+	}
+	if len(symbols) != 0 {
+		return fmt.Errorf("line %d: expected \"FROM\", found \";\"", symbols[len(symbols)-1].line)
+	}
+	return p.expect(";")
+}
+
+// parseAssignment parses the definition of a named type, whose doc comment
+// and directives are the comment lines right above it
+func (p *parser) parseAssignment() (*typeAssignment, error) {
+	name, err := p.typeReference()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("::="); err != nil {
+		return nil, err
+	}
+	typ, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for line := name.line - 1; !p.tokenLines[line] && len(p.comments[line]) != 0; line-- {
+		lines = append([]string{strings.Join(p.comments[line], " ")}, lines...)
+	}
+	var doc []string
+	directives := map[string]string{}
+	for _, line := range lines {
+		if isDirective(line) {
+			if err := parseDirectives(line, directives); err != nil {
+				return nil, fmt.Errorf("line %d: %v", name.line, err)
+			}
+			continue
+		}
+		doc = append(doc, line)
+	}
+	return &typeAssignment{name: name.text, line: name.line, doc: doc, directives: directives, typ: typ}, nil
+}
+
+func (p *parser) parseType() (*asnType, error) {
+	tok := p.next()
+	typ := &asnType{line: tok.line}
+	switch tok.text {
+	case "SEQUENCE", "SET":
+		if p.peek() == "{" {
+			if tok.text == "SET" {
+				return nil, fmt.Errorf("line %d: SET types are not supported", tok.line)
+			}
+			components, err := p.parseComponents()
+			if err != nil {
+				return nil, err
+			}
+			typ.kind, typ.components = kindSequence, components
+			return typ, nil
+		}
+		if err := p.skipConstraints(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("OF"); err != nil {
+			return nil, err
+		}
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		typ.kind, typ.elem = kindSequenceOf, elem
+		if tok.text == "SET" {
+			typ.kind = kindSetOf
+		}
+		return typ, nil
+	case "CHOICE":
+		components, err := p.parseComponents()
+		if err != nil {
+			return nil, err
+		}
+		typ.kind, typ.components = kindChoice, components
+		return typ, nil
+	case "OCTET":
+		if err := p.expect("STRING"); err != nil {
+			return nil, err
+		}
+		typ.kind = kindOctetString
+	case "OBJECT":
+		if err := p.expect("IDENTIFIER"); err != nil {
+			return nil, err
+		}
+		typ.kind = kindOID
+	case "ENUMERATED":
+		if err := p.skipBalanced("{", "}"); err != nil {
+			return nil, err
+		}
+		typ.kind = kindEnumerated
+	case "INTEGER":
+		if p.peek() == "{" {
+			if err := p.skipBalanced("{", "}"); err != nil {
+				return nil, err
+			}
+		}
+		typ.kind = kindInteger
+	case kindNull, kindGeneralizedTime, kindPrintableString:
+		typ.kind = tok.text
+	default:
+		if !isTypeReference(tok.text) {
+			return nil, fmt.Errorf("line %d: unsupported type %q", tok.line, tok.text)
+		}
+		typ.kind, typ.ref = kindReference, tok.text
+	}
+	return typ, p.skipConstraints()
+}
+
+// parseComponents parses the components of a SEQUENCE or the alternatives
+// of a CHOICE. Extension markers are skipped, the extension additions being
+// components like the others.
+func (p *parser) parseComponents() ([]*component, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var components []*component
+	names, tags := map[string]bool{}, map[int]bool{}
+	for p.peek() != "}" {
+		if p.peek() == "..." {
+			p.next()
+		} else {
+			c, err := p.parseComponent()
+			if err != nil {
+				return nil, err
+			}
+			if names[c.name] || tags[c.tag] {
+				return nil, fmt.Errorf("line %d: component %s reuses a name or a tag", c.line, c.name)
+			}
+			names[c.name], tags[c.tag] = true, true
+			components = append(components, c)
+		}
+		if p.peek() != "," {
+			break
+		}
+		p.next()
+	}
+	return components, p.expect("}")
+}
+
+// parseComponent parses a tagged component, whose directives are found in
+// the comments of the lines it spans
+func (p *parser) parseComponent() (*component, error) {
+	name := p.next()
+	if len(name.text) == 0 || !unicode.IsLower(rune(name.text[0])) {
+		return nil, fmt.Errorf("line %d: expected a component, found %q", name.line, name.text)
+	}
+	if p.peek() != "[" {
+		return nil, fmt.Errorf("line %d: component %s has no tag", name.line, name.text)
+	}
+	p.next()
+	tag := p.next()
+	number, err := strconv.Atoi(tag.text)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: component %s has an unsupported tag %q", tag.line, name.text, tag.text)
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	typ, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	c := &component{name: name.text, line: name.line, tag: number, typ: typ, directives: map[string]string{}}
+	switch p.peek() {
+	case "OPTIONAL":
+		p.next()
+		c.optional = true
+	case "DEFAULT":
+		return nil, fmt.Errorf("line %d: component %s has an unsupported default value", name.line, name.text)
+	}
+
+	last := p.tokens[p.pos-1].line
+	if p.peek() == "," {
+		last = p.tokens[p.pos].line
+	}
+	for line := name.line; line <= last; line++ {
+		for _, comment := range p.comments[line] {
+			if !isDirective(comment) {
+				continue
+			}
+			if err := parseDirectives(comment, c.directives); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// peek returns the text of the next token, empty at the end of the module
+func (p *parser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+// next consumes the next token
+func (p *parser) next() token {
+	if p.pos >= len(p.tokens) {
+		line := 0
+		if len(p.tokens) != 0 {
+			line = p.tokens[len(p.tokens)-1].line
+		}
+		return token{line: line}
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+func (p *parser) expect(text string) error {
+	tok := p.next()
+	if tok.text != text {
+		if len(tok.text) == 0 {
+			return fmt.Errorf("line %d: expected %q, found the end of the module", tok.line, text)
+		}
+		return fmt.Errorf("line %d: expected %q, found %q", tok.line, text, tok.text)
+	}
+	return nil
+}
+
+func (p *parser) typeReference() (token, error) {
+	tok := p.next()
+	if !isTypeReference(tok.text) {
+		return tok, fmt.Errorf("line %d: expected a type reference, found %q", tok.line, tok.text)
+	}
+	return tok, nil
+}
+
+// skipConstraints skips the constraints following a type, e.g. (SIZE (1..8)),
+// and the size constraint of a SET OF or SEQUENCE OF
+func (p *parser) skipConstraints() error {
+	if p.peek() == "SIZE" {
+		p.next()
+	}
+	for p.peek() == "(" {
+		if err := p.skipBalanced("(", ")"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipBalanced skips the tokens from an opening token to the matching
+// closing one
+func (p *parser) skipBalanced(open, close string) error {
+	if err := p.expect(open); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; {
+		tok := p.next()
+		switch tok.text {
+		case open:
+			depth++
+		case close:
+			depth--
+		case "":
+			return fmt.Errorf("line %d: unbalanced %q", tok.line, open)
+		}
+	}
+	return nil
+}
+
+// isDirective returns true if a comment holds go: directives
+func isDirective(comment string) bool {
+	return strings.HasPrefix(comment, "go:")
+}
+
+// parseDirectives parses the go:key=value directives of a comment
+func parseDirectives(comment string, directives map[string]string) error {
+	for _, field := range strings.Fields(comment) {
+		if !strings.HasPrefix(field, "go:") {
+			return fmt.Errorf("unexpected %q among directives", field)
+		}
+		kv := strings.SplitN(strings.TrimPrefix(field, "go:"), "=", 2)
+		if len(kv) != 2 || (kv[0] != "name" && kv[0] != "type") || len(kv[1]) == 0 {
+			return fmt.Errorf("invalid directive %q", field)
+		}
+		directives[kv[0]] = kv[1]
+	}
+	return nil
+}
+
+func isTypeReference(s string) bool {
+	return len(s) != 0 && unicode.IsUpper(rune(s[0]))
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+)
+
+// Formats of record files
+const (
+	formatAuto   = "auto"
+	formatHex    = "hex"
+	formatBase64 = "base64"
+	formatRaw    = "raw"
+)
+
+// readRecords reads the records of a file, "-" reading stdin
+func readRecords(path, format string) ([][]byte, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeFormat(content, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	records, err := splitRecords(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return records, nil
+}
+
+// decodeFormat returns the binary content of a record file. With the auto
+// format, text files are read as hex if they only hold hex digits, as base64
+// otherwise, and other files as raw.
+func decodeFormat(content []byte, format string) ([]byte, error) {
+	text := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, string(content))
+
+	if format == formatAuto {
+		switch {
+		case !isText(content):
+			format = formatRaw
+		case isHex(text):
+			format = formatHex
+		default:
+			format = formatBase64
+		}
+	}
+	switch format {
+	case formatHex:
+		return hex.DecodeString(strings.TrimPrefix(text, "0x"))
+	case formatBase64:
+		return base64.StdEncoding.DecodeString(text)
+	case formatRaw:
+		return content, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// encodeFormat returns the content of a record file in the given format
+func encodeFormat(b []byte, format string) ([]byte, error) {
+	switch format {
+	case formatHex:
+		return []byte(hex.EncodeToString(b) + "\n"), nil
+	case formatBase64:
+		return []byte(base64.StdEncoding.EncodeToString(b) + "\n"), nil
+	case formatRaw:
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %s", format)
+	}
+}
+
+// splitRecords splits records sent back to back by their header and
+// payload lengths
+func splitRecords(b []byte) ([][]byte, error) {
+	var records [][]byte
+	for len(b) != 0 {
+		if len(b) < int(encoding.HeaderFixLen) {
+			return nil, fmt.Errorf("%d trailing bytes after %d records", len(b), len(records))
+		}
+		size := uint64(binary.BigEndian.Uint32(b[4:8])) + uint64(binary.BigEndian.Uint32(b[8:12]))
+		if size < uint64(encoding.HeaderFixLen) || size > uint64(len(b)) {
+			return nil, fmt.Errorf("record %d declares %d bytes, %d left", len(records), size, len(b))
+		}
+		records = append(records, b[:size])
+		b = b[size:]
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no record")
+	}
+	return records, nil
+}
+
+func isText(b []byte) bool {
+	for _, c := range b {
+		if (c < 0x20 || c > 0x7e) && !unicode.IsSpace(rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(s string) bool {
+	s = strings.TrimPrefix(s, "0x")
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return len(s)%2 == 0
+}
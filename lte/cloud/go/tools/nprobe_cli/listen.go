@@ -0,0 +1,139 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+)
+
+// transcript writes the records received by the listener, decoded and
+// validated, in a human readable form
+type transcript struct {
+	mutex     sync.Mutex
+	w         io.Writer
+	records   int
+	malformed int
+}
+
+func runListen(args []string) error {
+	fs := flag.NewFlagSet("listen", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:6666", "loopback address the records are accepted on")
+	output := fs.String("out", "-", "file the transcript is appended to, stdout by default")
+	parseFlags(fs, args, 0)
+
+	// records are received in plaintext, they must not leave the host
+	if !exporter.IsLoopbackAddress(*addr) {
+		return fmt.Errorf("%s is not a loopback address", *addr)
+	}
+	w := io.Writer(os.Stdout)
+	if *output != "-" {
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	fmt.Fprintf(os.Stderr, "accepting records on %s\n", ln.Addr())
+
+	t := &transcript{w: w}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go t.receive(conn)
+	}
+}
+
+// receive reads the records of a connection until it is closed
+func (t *transcript) receive(conn net.Conn) {
+	defer conn.Close()
+	peer := conn.RemoteAddr().String()
+	t.writeEvent(peer, "connected")
+	for {
+		pdu, err := encoding.ReadPDU(conn, 0)
+		if err == io.EOF {
+			t.writeEvent(peer, "disconnected")
+			return
+		}
+		if err != nil {
+			t.writeEvent(peer, fmt.Sprintf("closing connection: %v", err))
+			return
+		}
+		t.writeRecord(peer, pdu, time.Now())
+	}
+}
+
+func (t *transcript) writeEvent(peer, event string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	fmt.Fprintf(t.w, "### %s %s\n", peer, event)
+}
+
+func (t *transcript) writeRecord(peer string, pdu []byte, receivedAt time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.records++
+	entry, valid := formatRecord(t.records, peer, pdu, receivedAt)
+	if !valid {
+		t.malformed++
+	}
+	fmt.Fprint(t.w, entry)
+	if !valid {
+		fmt.Fprintf(os.Stderr, "record %d from %s is malformed, %d of %d records\n", t.records, peer, t.malformed, t.records)
+	}
+}
+
+// formatRecord renders the transcript entry of a received record: a summary
+// line, the record decoded as JSON and the outcome of its validation. It
+// returns false if the record can't be decoded or is malformed.
+func formatRecord(n int, peer string, pdu []byte, receivedAt time.Time) (string, bool) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- record %d from %s at %s, %d bytes", n, peer, receivedAt.UTC().Format(time.RFC3339Nano), len(pdu))
+	if hdr, err := encoding.ParsePDUHeader(pdu); err == nil {
+		if seqNbr, ok := encoding.GetSequenceNumber(hdr); ok {
+			fmt.Fprintf(&b, ", xid %s, correlation ID %d, sequence number %d", hdr.XID, hdr.CorrelationID, seqNbr)
+		}
+	}
+	b.WriteString("\n")
+
+	rendered, err := renderRecord(pdu)
+	if err != nil {
+		fmt.Fprintf(&b, "undecodable: %v\n%x\n\n", err, pdu)
+		return b.String(), false
+	}
+	b.WriteString(rendered + "\n")
+	if err := encoding.Validate(pdu); err != nil {
+		fmt.Fprintf(&b, "malformed: %v\n\n", err)
+		return b.String(), false
+	}
+	b.WriteString("valid\n\n")
+	return b.String(), true
+}
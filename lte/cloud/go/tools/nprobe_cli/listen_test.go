@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatRecord(t *testing.T) {
+	receivedAt := time.Date(2021, 2, 18, 5, 13, 26, 0, time.UTC)
+	entry, valid := formatRecord(1, "127.0.0.1:50000", makeTestRecord(t, 7), receivedAt)
+	assert.True(t, valid)
+	assert.Contains(t, entry, "--- record 1 from 127.0.0.1:50000 at 2021-02-18T05:13:26Z")
+	assert.Contains(t, entry, "sequence number 7")
+	assert.True(t, strings.HasSuffix(entry, "valid\n\n"))
+
+	entry, valid = formatRecord(2, "127.0.0.1:50000", []byte{0, 1, 2}, receivedAt)
+	assert.False(t, valid)
+	assert.Contains(t, entry, "undecodable")
+}
+
+func TestListen(t *testing.T) {
+	_, err := exporter.NewDevBackend("10.10.0.2:6666")
+	assert.Error(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	var out bytes.Buffer
+	tr := &transcript{w: &out}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := ln.Accept()
+		if err == nil {
+			tr.receive(conn)
+		}
+	}()
+
+	backend, err := exporter.NewDevBackend(ln.Addr().String())
+	assert.NoError(t, err)
+	assert.NoError(t, backend.Send(makeTestRecord(t, 1), 1))
+	assert.NoError(t, backend.Send(makeTestRecord(t, 2), 1))
+	assert.True(t, backend.IsConnected())
+	backend.Close()
+	<-done
+
+	assert.Equal(t, 2, tr.records)
+	assert.Equal(t, 0, tr.malformed)
+	assert.Contains(t, out.String(), "sequence number 2")
+	assert.Contains(t, out.String(), "disconnected")
+}
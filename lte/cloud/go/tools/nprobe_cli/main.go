@@ -0,0 +1,90 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// nprobe_cli decodes, crafts and sends the ETSI HI2 records produced by
+// nprobe, for interop testing with LEMFs. Record files hold one or more
+// records back to back, in hex, base64 or raw binary. "-" reads stdin.
+//
+// Usage:
+//
+//	nprobe_cli decode [-format auto|hex|base64|raw] FILE...
+//	    prints the records as JSON, along with their validation errors
+//
+//	nprobe_cli encode [-format ...] [-output-format hex|base64|raw] \
+//	  [-xid UUID] [-correlation-id N] [-seq N] [-target-id ID] [-class CLASS] FILE
+//	    re-encodes the records with the given fields replaced
+//
+//	nprobe_cli send -addr lemf.test:6666 [-cert client.crt -key client.key] \
+//	  [-ca ca.crt] [-server-name NAME] [-ack-timeout 5s] [-format ...] FILE...
+//	    delivers the records over tls with the exporter of nprobe
+//
+//	nprobe_cli listen [-addr 127.0.0.1:6666] [-out FILE]
+//	    accepts the records of the dev exporter backend of nprobe in plaintext
+//	    on a loopback address, and writes them decoded and validated
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `usage: nprobe_cli COMMAND [flags] FILE...
+
+commands:
+  decode  print records as JSON
+  encode  re-encode records with modified fields
+  send    deliver records to a delivery function over tls
+  listen  transcribe the records delivered by the dev exporter backend
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "decode":
+		err = runDecode(os.Args[2:])
+	case "encode":
+		err = runEncode(os.Args[2:])
+	case "send":
+		err = runSend(os.Args[2:])
+	case "listen":
+		err = runListen(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nprobe_cli %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses the flags of a command, exiting with its usage when
+// fewer than minArgs files are given
+func parseFlags(fs *flag.FlagSet, args []string, minArgs int) {
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nprobe_cli %s [flags] FILE...\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	// flag.ExitOnError exits on malformed flags
+	_ = fs.Parse(args)
+	if fs.NArg() < minArgs {
+		fs.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+
+	"github.com/gofrs/uuid"
+)
+
+// recordChanges are the fields replaced in the records re-encoded, the
+// fields left empty are kept
+type recordChanges struct {
+	xid           string
+	correlationID uint64
+	seqNbr        int64
+	targetID      string
+	class         string
+}
+
+func runDecode(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	format := fs.String("format", formatAuto, "format of the record files: auto, hex, base64 or raw")
+	parseFlags(fs, args, 1)
+
+	for _, path := range fs.Args() {
+		records, err := readRecords(path, *format)
+		if err != nil {
+			return err
+		}
+		for i, b := range records {
+			rendered, err := renderRecord(b)
+			if err != nil {
+				return fmt.Errorf("%s: record %d: %v", path, i, err)
+			}
+			fmt.Println(rendered)
+			if err := encoding.Validate(b); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: record %d is malformed: %v\n", path, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// renderRecord decodes a record and renders it as indented JSON
+func renderRecord(b []byte) (string, error) {
+	var record encoding.EpsIRIRecord
+	if err := record.DecodeWithLimit(context.Background(), b, 0); err != nil {
+		return "", err
+	}
+	rendered, err := encoding.ToJSON(&record)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, rendered, "", "  "); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func runEncode(args []string) error {
+	fs := flag.NewFlagSet("encode", flag.ExitOnError)
+	format := fs.String("format", formatAuto, "format of the record file: auto, hex, base64 or raw")
+	outputFormat := fs.String("output-format", formatHex, "format of the records written: hex, base64 or raw")
+	output := fs.String("out", "-", "file the records are written to, stdout by default")
+	var changes recordChanges
+	fs.StringVar(&changes.xid, "xid", "", "XID of the task")
+	fs.Uint64Var(&changes.correlationID, "correlation-id", 0, "correlation ID of the header and EPS correlation number")
+	fs.Int64Var(&changes.seqNbr, "seq", -1, "sequence number, incremented for each following record")
+	fs.StringVar(&changes.targetID, "target-id", "", "target ID attribute of the header")
+	fs.StringVar(&changes.class, "class", "", "record class: begin, continue, end or report")
+	parseFlags(fs, args, 1)
+
+	var out []byte
+	for _, path := range fs.Args() {
+		records, err := readRecords(path, *format)
+		if err != nil {
+			return err
+		}
+		for i, b := range records {
+			encoded, err := modifyRecord(b, &changes)
+			if err != nil {
+				return fmt.Errorf("%s: record %d: %v", path, i, err)
+			}
+			if err := encoding.Validate(encoded); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: record %d is malformed: %v\n", path, i, err)
+			}
+			out = append(out, encoded...)
+			if changes.seqNbr >= 0 {
+				changes.seqNbr++
+			}
+		}
+	}
+	content, err := encodeFormat(out, *outputFormat)
+	if err != nil {
+		return err
+	}
+	if *output == "-" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return ioutil.WriteFile(*output, content, 0644)
+}
+
+// modifyRecord decodes a record, replaces its fields and encodes it again
+func modifyRecord(b []byte, changes *recordChanges) ([]byte, error) {
+	var record encoding.EpsIRIRecord
+	if err := record.DecodeWithLimit(context.Background(), b, 0); err != nil {
+		return nil, err
+	}
+	hdr := &record.Header
+	if len(changes.xid) != 0 {
+		xid, err := uuid.FromString(changes.xid)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xid %s: %v", changes.xid, err)
+		}
+		hdr.XID = xid
+	}
+	if changes.correlationID != 0 {
+		hdr.CorrelationID = changes.correlationID
+		record.Payload.EPSCorrelationNumber = make([]byte, 8)
+		binary.BigEndian.PutUint64(record.Payload.EPSCorrelationNumber, changes.correlationID)
+	}
+	if changes.seqNbr >= 0 {
+		value := make([]byte, 4)
+		binary.BigEndian.PutUint32(value, uint32(changes.seqNbr))
+		setAttribute(hdr, encoding.AttributeSeqNumber, value)
+	}
+	if len(changes.targetID) != 0 {
+		setAttribute(hdr, encoding.AttributeTargetID, []byte(changes.targetID))
+	}
+	switch changes.class {
+	case "":
+	case encoding.RecordClassBegin, encoding.RecordClassContinue, encoding.RecordClassEnd, encoding.RecordClassReport:
+		record.Class = changes.class
+	default:
+		return nil, fmt.Errorf("unsupported record class %s", changes.class)
+	}
+	return record.Encode()
+}
+
+// setAttribute replaces the value of a conditional attribute of the header,
+// or appends the attribute if missing, and updates the header length
+func setAttribute(hdr *encoding.EpsIRIHeader, tag uint16, value []byte) {
+	found := false
+	for i := range hdr.ConditionalAttributes {
+		if hdr.ConditionalAttributes[i].Tag == tag {
+			hdr.ConditionalAttributes[i] = encoding.NewAttribute(tag, value)
+			found = true
+		}
+	}
+	if !found {
+		hdr.ConditionalAttributes = append(hdr.ConditionalAttributes, encoding.NewAttribute(tag, value))
+	}
+	length := encoding.HeaderFixLen
+	for _, attr := range hdr.ConditionalAttributes {
+		length += 4 + uint32(attr.Len)
+	}
+	hdr.HeaderLength = length
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeTestRecord(t *testing.T, seqNbr uint32) []byte {
+	task := &models.NetworkProbeTask{
+		TaskID: "609dcabd-5ab1-4c95-9681-a24681f105ac",
+		TaskDetails: &models.NetworkProbeTaskDetails{
+			TargetID:      "IMSI001010000000001",
+			CorrelationID: 0x866cb397915ffe4,
+		},
+	}
+	event := eventdM.Event{
+		EventType:  nprobe.AttachSuccess,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  "2021-02-18T05:13:26.019519+00:00",
+		Value:      map[string]interface{}{"imsi": "IMSI001010000000001"},
+	}
+	record, err := encoding.MakeRecord(&event, task, 49002, seqNbr)
+	assert.NoError(t, err)
+	return record
+}
+
+func TestFormats(t *testing.T) {
+	first, second := makeTestRecord(t, 1), makeTestRecord(t, 2)
+	b := append(append([]byte{}, first...), second...)
+	for _, format := range []string{formatHex, formatBase64, formatRaw} {
+		content, err := encodeFormat(b, format)
+		assert.NoError(t, err)
+		decoded, err := decodeFormat(content, formatAuto)
+		assert.NoError(t, err)
+		assert.Equal(t, b, decoded, format)
+	}
+
+	records, err := splitRecords(b)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{first, second}, records)
+	_, err = splitRecords(b[:len(b)-1])
+	assert.EqualError(t, err, fmt.Sprintf("record 1 declares %d bytes, %d left", len(second), len(second)-1))
+}
+
+func TestModifyRecord(t *testing.T) {
+	b, err := modifyRecord(makeTestRecord(t, 1), &recordChanges{
+		xid:           "0a1d8c2e-0000-4000-8000-000000000001",
+		correlationID: 42,
+		seqNbr:        7,
+		targetID:      "IMSI001010000000002",
+		class:         encoding.RecordClassReport,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, encoding.Validate(b))
+
+	var record encoding.EpsIRIRecord
+	assert.NoError(t, record.Decode(b))
+	assert.Equal(t, "0a1d8c2e-0000-4000-8000-000000000001", record.Header.XID.String())
+	assert.Equal(t, uint64(42), record.Header.CorrelationID)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 42}, record.Payload.EPSCorrelationNumber)
+	seqNbr, ok := encoding.GetSequenceNumber(&record.Header)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(7), seqNbr)
+	assert.Equal(t, encoding.RecordClassReport, record.Class)
+	for _, attr := range record.Header.ConditionalAttributes {
+		if attr.Tag == encoding.AttributeTargetID {
+			assert.Equal(t, "IMSI001010000000002", string(attr.Value))
+		}
+	}
+
+	_, err = modifyRecord(makeTestRecord(t, 1), &recordChanges{seqNbr: -1, class: "middle"})
+	assert.EqualError(t, err, "unsupported record class middle")
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+)
+
+func runSend(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	format := fs.String("format", formatAuto, "format of the record files: auto, hex, base64 or raw")
+	addr := fs.String("addr", "", "host:port of the delivery function")
+	crtFile := fs.String("cert", "", "client certificate presented to the delivery function")
+	keyFile := fs.String("key", "", "client key")
+	caFile := fs.String("ca", "", "CA certificate of the delivery function, the system CAs are used when not set")
+	serverName := fs.String("server-name", "", "name the certificate of the delivery function is verified against, the host of -addr by default")
+	skipVerify := fs.Bool("skip-verify", false, "don't verify the certificate of the delivery function")
+	ackTimeout := fs.Duration("ack-timeout", 0, "time the delivery function has to acknowledge each record, records aren't acknowledged when 0")
+	retries := fs.Uint("retries", 0, "number of times a record is sent again after a failure")
+	timeout := fs.Duration("timeout", time.Minute, "time given to deliver all records")
+	parseFlags(fs, args, 1)
+
+	if len(*addr) == 0 {
+		return fmt.Errorf("-addr is required")
+	}
+	var records [][]byte
+	for _, path := range fs.Args() {
+		read, err := readRecords(path, *format)
+		if err != nil {
+			return err
+		}
+		records = append(records, read...)
+	}
+	tlsConfig, err := newTLSConfig(*crtFile, *keyFile, *caFile, *serverName, *skipVerify)
+	if err != nil {
+		return err
+	}
+
+	backend := exporter.NewTLSBackend(*addr, tlsConfig, exporter.TLSBackendConfig{AckTimeout: *ackTimeout})
+	exp := exporter.NewRecordExporter(backend)
+	defer exp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	var correlationID uint64
+	if hdr, err := encoding.ParsePDUHeader(records[0]); err == nil {
+		correlationID = hdr.CorrelationID
+	}
+	delivery := exp.SubmitRecords(ctx, "nprobe_cli", 1, correlationID, records, uint32(*retries))
+	failed := 0
+	for i, record := range records {
+		seqNbr := "-"
+		if hdr, err := encoding.ParsePDUHeader(record); err == nil {
+			if n, ok := encoding.GetSequenceNumber(hdr); ok {
+				seqNbr = fmt.Sprint(n)
+			}
+		}
+		if err := delivery.Wait(i); err != nil {
+			fmt.Printf("record %d (sequence number %s): %v\n", i, seqNbr, err)
+			failed++
+			continue
+		}
+		fmt.Printf("record %d (sequence number %s): delivered\n", i, seqNbr)
+	}
+	if failed != 0 {
+		return fmt.Errorf("%d of %d records not delivered", failed, len(records))
+	}
+	return nil
+}
+
+// newTLSConfig returns the tls config of the connection to the delivery
+// function, presenting the client certificate if set
+func newTLSConfig(crtFile, keyFile, caFile, serverName string, skipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: skipVerify, ServerName: serverName}
+	if len(crtFile) != 0 || len(keyFile) != 0 {
+		certs, err := exporter.NewCertificateStore(crtFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config = exporter.NewTlsConfig(certs, skipVerify)
+		config.ServerName = serverName
+	}
+	if len(caFile) != 0 {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimTarget(t *testing.T) {
+	target := newSimTarget(7)
+	assert.Equal(t, "IMSI001010000000007", target.task.TaskDetails.TargetID)
+	rnd := rand.New(rand.NewSource(1))
+	now := time.Unix(1615000000, 0)
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		attached, bearers := target.attached, target.bearers
+		event := target.nextEvent(rnd, now)
+		seen[event.EventType] = true
+		assert.Equal(t, target.task.TaskDetails.TargetID, event.Value.(map[string]interface{})["imsi"])
+		switch event.EventType {
+		case nprobe.AttachSuccess:
+			assert.False(t, attached)
+		case nprobe.DetachSuccess:
+			assert.True(t, attached)
+			assert.Zero(t, target.bearers)
+		case nprobe.SessionCreated:
+			assert.True(t, attached)
+			assert.Equal(t, bearers+1, target.bearers)
+		case nprobe.SessionUpdated:
+			assert.NotZero(t, bearers)
+		case nprobe.SessionTerminated:
+			assert.Equal(t, bearers-1, target.bearers)
+		}
+		assert.LessOrEqual(t, target.bearers, maxBearers)
+	}
+	assert.Len(t, seen, 5)
+}
+
+func TestLatencySampler(t *testing.T) {
+	sampler := newLatencySampler(1)
+	assert.Equal(t, []time.Duration{0}, sampler.percentiles(50))
+	for i := 1; i <= 100; i++ {
+		sampler.observe(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, []time.Duration{50 * time.Millisecond, 99 * time.Millisecond, 100 * time.Millisecond}, sampler.percentiles(50, 99, 100))
+	assert.Equal(t, 100*time.Millisecond, sampler.getMax())
+
+	// the sample is bounded
+	for i := 0; i < maxLatencySamples; i++ {
+		sampler.observe(time.Millisecond)
+	}
+	assert.Len(t, sampler.samples, maxLatencySamples)
+	assert.Equal(t, uint64(maxLatencySamples+100), sampler.seen)
+}
+
+func TestRunLoad(t *testing.T) {
+	report, err := runLoad(loadConfig{
+		targets:        50,
+		rate:           1000,
+		duration:       300 * time.Millisecond,
+		workers:        4,
+		workerQueue:    100,
+		maxBatch:       10,
+		validate:       true,
+		sampleInterval: 50 * time.Millisecond,
+		drainTimeout:   5 * time.Second,
+		seed:           1,
+	})
+	assert.NoError(t, err)
+	assert.NotZero(t, report.EventsGenerated)
+	assert.Equal(t, report.EventsGenerated-report.EventsDropped, report.RecordsDelivered+report.RecordsFailed)
+	assert.Zero(t, report.RecordsFailed)
+	assert.Equal(t, int(report.RecordsDelivered), report.RecordsReceived)
+	assert.NotZero(t, report.RecordsPerSec)
+	assert.NotZero(t, report.LatencyP50Ms)
+	assert.LessOrEqual(t, report.LatencyP99Ms, report.LatencyMaxMs)
+	assert.NotZero(t, report.PeakHeapMB)
+	assert.NotZero(t, report.BytesPerRecord)
+
+	dir, err := ioutil.TempDir("", "nprobe_load")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "load.json")
+	assert.NoError(t, writeReport(path, report))
+	baseline, err := readReport(path)
+	assert.NoError(t, err)
+	assert.Equal(t, report, baseline)
+	assert.Empty(t, compareReports(baseline, report, 10))
+}
+
+func TestCompareReports(t *testing.T) {
+	baseline := &loadReport{RecordsPerSec: 1000, LatencyP99Ms: 20, PeakHeapMB: 100, BytesPerRecord: 4000}
+	assert.Empty(t, compareReports(baseline, &loadReport{RecordsPerSec: 950, LatencyP99Ms: 21, PeakHeapMB: 105, BytesPerRecord: 3000}, 10))
+
+	errs := compareReports(baseline, &loadReport{RecordsPerSec: 800, LatencyP99Ms: 30, PeakHeapMB: 100, BytesPerRecord: 5000}, 10)
+	assert.Len(t, errs, 3)
+	assert.EqualError(t, errs[0], "throughput dropped from 1000.00 to 800.00")
+	assert.EqualError(t, errs[1], "p99 latency grew from 20.00 to 30.00")
+	assert.EqualError(t, errs[2], "allocated bytes per record grew from 4000.00 to 5000.00")
+
+	// metrics missing from the baseline are not compared
+	assert.Empty(t, compareReports(&loadReport{}, &loadReport{RecordsPerSec: 1}, 10))
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// nprobe_load measures the throughput, latency and memory of the nprobe
+// record pipeline under the load of thousands of targets, so that the
+// performance changes can be checked for regressions. Simulated targets
+// attach, activate, modify and deactivate bearers and detach at random, at a
+// constant event rate across all targets. Workers encode the events of their
+// targets and deliver the records like the manager does, over acknowledged
+// tls delivery to the mock LEMF of the testutils package.
+//
+// The report is logged, and written as json with -report. Given a -baseline
+// report, the run fails, exiting with status 1, when its throughput, p99
+// latency, peak heap or bytes allocated per record regress by more than
+// -tolerance percent. The mock LEMF runs in the same process, its share of
+// the cpu and memory being measured along.
+//
+// Usage:
+//
+//	nprobe_load -targets 10000 -rate 5000 -duration 5m -report load.json -logtostderr
+//	nprobe_load -targets 10000 -rate 5000 -duration 5m -baseline load.json -logtostderr
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/testutils"
+
+	"github.com/golang/glog"
+)
+
+// loadConfig holds the settings of a load test run
+type loadConfig struct {
+	targets          int
+	rate             int
+	duration         time.Duration
+	workers          int
+	workerQueue      int
+	maxBatch         int
+	batchRecords     uint
+	validate         bool
+	sampleInterval   time.Duration
+	drainTimeout     time.Duration
+	seed             int64
+	reportFile       string
+	baselineFile     string
+	tolerance        float64
+	cpuProfileFile   string
+	heapProfileFile  string
+	progressInterval time.Duration
+}
+
+func main() {
+	var config loadConfig
+	flag.IntVar(&config.targets, "targets", 5000, "number of simulated targets, each intercepted by a task")
+	flag.IntVar(&config.rate, "rate", 2000, "events generated per second across all targets")
+	flag.DurationVar(&config.duration, "duration", time.Minute, "time the events are generated for")
+	flag.IntVar(&config.workers, "workers", 32, "workers encoding and delivering the records, the targets being spread over them")
+	flag.IntVar(&config.workerQueue, "worker-queue", 1000, "events queued per worker, the events beyond being dropped")
+	flag.IntVar(&config.maxBatch, "max-batch", 100, "events encoded by a worker per pass")
+	flag.UintVar(&config.batchRecords, "batch-records", 0, "records written at once by the exporter, 0 writing them one at a time")
+	flag.BoolVar(&config.validate, "validate", false, "validate the records received by the mock LEMF")
+	flag.DurationVar(&config.sampleInterval, "sample-interval", time.Second, "time between the memory samples")
+	flag.DurationVar(&config.progressInterval, "progress-interval", 10*time.Second, "time between the progress logs")
+	flag.DurationVar(&config.drainTimeout, "drain-timeout", time.Minute, "time given to deliver the last records once the events stop")
+	flag.Int64Var(&config.seed, "seed", 1, "seed of the simulated events")
+	flag.StringVar(&config.reportFile, "report", "", "file the json report is written to")
+	flag.StringVar(&config.baselineFile, "baseline", "", "json report of a previous run to check this one against")
+	flag.Float64Var(&config.tolerance, "tolerance", 10, "regression over the baseline tolerated, in percent")
+	flag.StringVar(&config.cpuProfileFile, "cpuprofile", "", "file the cpu profile of the run is written to")
+	flag.StringVar(&config.heapProfileFile, "memprofile", "", "file the heap profile is written to at the end of the run")
+	flag.Parse()
+
+	if config.targets <= 0 || config.rate <= 0 || config.workers <= 0 || config.maxBatch <= 0 || config.sampleInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: nprobe_load [flags]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	var baseline *loadReport
+	if config.baselineFile != "" {
+		var err error
+		if baseline, err = readReport(config.baselineFile); err != nil {
+			glog.Exitf("Failed to read baseline: %v", err)
+		}
+	}
+	if config.cpuProfileFile != "" {
+		f, err := os.Create(config.cpuProfileFile)
+		if err != nil {
+			glog.Exitf("Failed to create cpu profile: %v", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			glog.Exitf("Failed to start cpu profile: %v", err)
+		}
+	}
+
+	report, err := runLoad(config)
+	if config.cpuProfileFile != "" {
+		pprof.StopCPUProfile()
+	}
+	if err != nil {
+		glog.Exitf("Load test failed: %v", err)
+	}
+	if config.heapProfileFile != "" {
+		if err := writeHeapProfile(config.heapProfileFile); err != nil {
+			glog.Errorf("Failed to write heap profile: %v", err)
+		}
+	}
+	glog.Infof("Load test report:\n%s", report)
+	if config.reportFile != "" {
+		if err := writeReport(config.reportFile, report); err != nil {
+			glog.Exitf("Failed to write report: %v", err)
+		}
+	}
+	if baseline != nil {
+		if errs := compareReports(baseline, report, config.tolerance); len(errs) != 0 {
+			for _, err := range errs {
+				glog.Errorf("Regression over %s: %v", config.baselineFile, err)
+			}
+			glog.Flush()
+			os.Exit(1)
+		}
+		glog.Infof("No regression over %s beyond %.0f%%", config.baselineFile, config.tolerance)
+	}
+	glog.Flush()
+}
+
+// runLoad runs the load test and returns its report
+func runLoad(config loadConfig) (*loadReport, error) {
+	df, err := testutils.NewMockDeliveryFunction(testutils.MockDeliveryFunctionConfig{
+		AckRecords:      true,
+		ValidateRecords: config.validate,
+		DiscardRecords:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start mock LEMF: %v", err)
+	}
+	defer df.Close()
+
+	backend := exporter.NewTLSBackend(df.Addr(), &tls.Config{InsecureSkipVerify: true}, exporter.TLSBackendConfig{
+		KeepaliveInterval: 10 * time.Second,
+		AckTimeout:        5 * time.Second,
+	})
+	exp := exporter.NewRecordExporter(backend)
+	defer exp.Close()
+	exp.SetBatchConfig(exporter.BatchConfig{MaxRecords: uint32(config.batchRecords)})
+
+	targets := make([]*simTarget, config.targets)
+	for i := range targets {
+		targets[i] = newSimTarget(i)
+	}
+	queues := make([]chan loadEvent, config.workers)
+	for i := range queues {
+		queues[i] = make(chan loadEvent, config.workerQueue)
+	}
+	gen := newGenerator(targets, queues, config.seed)
+	stats := &workerStats{}
+	latencies := newLatencySampler(config.seed)
+	memory := &memorySampler{}
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	memory.sample()
+	start := time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wg := sync.WaitGroup{}
+	for _, queue := range queues {
+		wg.Add(1)
+		go func(queue chan loadEvent) {
+			defer wg.Done()
+			runWorker(ctx, queue, exp, config.maxBatch, stats, latencies)
+		}(queue)
+	}
+	genCtx, stopGenerator := context.WithTimeout(ctx, config.duration)
+	defer stopGenerator()
+	go monitor(genCtx, config, gen, stats, memory, start)
+	gen.run(genCtx, config.rate)
+	generatedFor := time.Since(start)
+
+	// the queued events are delivered before reporting, unless they can't be
+	// in time
+	for _, queue := range queues {
+		close(queue)
+	}
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(config.drainTimeout):
+		cancel()
+		<-drained
+		glog.Warningf("Records not delivered within %v of the end of the events", config.drainTimeout)
+	}
+	elapsed := time.Since(start)
+	memory.sample()
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	delivered := atomic.LoadUint64(&stats.delivered)
+	report := &loadReport{
+		Targets:          config.targets,
+		Rate:             config.rate,
+		Workers:          config.workers,
+		DurationSecs:     generatedFor.Seconds(),
+		EventsGenerated:  atomic.LoadUint64(&gen.generated),
+		EventsDropped:    atomic.LoadUint64(&gen.dropped),
+		RecordsDelivered: delivered,
+		RecordsFailed:    atomic.LoadUint64(&stats.failed),
+		RecordsReceived:  df.RecordsReceived(),
+		RecordsPerSec:    float64(delivered) / elapsed.Seconds(),
+		BytesPerSec:      float64(atomic.LoadUint64(&stats.bytes)) / elapsed.Seconds(),
+		PeakHeapMB:       float64(atomic.LoadUint64(&memory.peakHeapInuse)) / (1 << 20),
+		PeakGoroutines:   atomic.LoadInt64(&memory.peakGoroutines),
+	}
+	percentiles := latencies.percentiles(50, 95, 99)
+	report.LatencyP50Ms, report.LatencyP95Ms, report.LatencyP99Ms = toMs(percentiles[0]), toMs(percentiles[1]), toMs(percentiles[2])
+	report.LatencyMaxMs = toMs(latencies.getMax())
+	if delivered != 0 {
+		report.BytesPerRecord = float64(after.TotalAlloc-before.TotalAlloc) / float64(delivered)
+		report.AllocsPerRecord = float64(after.Mallocs-before.Mallocs) / float64(delivered)
+	}
+	if errs := df.FramingErrors(); len(errs) != 0 {
+		return report, fmt.Errorf("mock LEMF received %d malformed records, first: %v", len(errs), errs[0])
+	}
+	return report, nil
+}
+
+// monitor samples the memory and logs the progress of the run until ctx is
+// done
+func monitor(ctx context.Context, config loadConfig, gen *generator, stats *workerStats, memory *memorySampler, start time.Time) {
+	sampleTicker := time.NewTicker(config.sampleInterval)
+	defer sampleTicker.Stop()
+	var progress <-chan time.Time
+	if config.progressInterval > 0 {
+		progressTicker := time.NewTicker(config.progressInterval)
+		defer progressTicker.Stop()
+		progress = progressTicker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sampleTicker.C:
+			memory.sample()
+		case <-progress:
+			glog.Infof(
+				"After %v: %d events generated, %d dropped, %d records delivered, %d failed, peak heap %d MB",
+				time.Since(start).Truncate(time.Second), atomic.LoadUint64(&gen.generated), atomic.LoadUint64(&gen.dropped),
+				atomic.LoadUint64(&stats.delivered), atomic.LoadUint64(&stats.failed), atomic.LoadUint64(&memory.peakHeapInuse)>>20,
+			)
+		}
+	}
+}
+
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
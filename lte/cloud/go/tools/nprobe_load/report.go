@@ -0,0 +1,189 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxLatencySamples bounds the latencies kept to compute the percentiles,
+// so that long runs don't inflate the memory measured
+const maxLatencySamples = 100000
+
+// latencySampler keeps a uniform sample of the latencies observed, by
+// reservoir sampling, along with their maximum
+type latencySampler struct {
+	mutex   sync.Mutex
+	samples []time.Duration
+	seen    uint64
+	max     time.Duration
+	rnd     *rand.Rand
+}
+
+func newLatencySampler(seed int64) *latencySampler {
+	return &latencySampler{rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (s *latencySampler) observe(latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.seen++
+	if latency > s.max {
+		s.max = latency
+	}
+	if len(s.samples) < maxLatencySamples {
+		s.samples = append(s.samples, latency)
+	} else if i := s.rnd.Int63n(int64(s.seen)); i < maxLatencySamples {
+		s.samples[i] = latency
+	}
+}
+
+// percentiles returns the latencies at each of the percentiles, 0 if none
+// was observed
+func (s *latencySampler) percentiles(ps ...float64) []time.Duration {
+	s.mutex.Lock()
+	sorted := append([]time.Duration{}, s.samples...)
+	s.mutex.Unlock()
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	ret := make([]time.Duration, len(ps))
+	if len(sorted) == 0 {
+		return ret
+	}
+	for i, p := range ps {
+		k := int(p/100*float64(len(sorted))+0.5) - 1
+		if k < 0 {
+			k = 0
+		} else if k >= len(sorted) {
+			k = len(sorted) - 1
+		}
+		ret[i] = sorted[k]
+	}
+	return ret
+}
+
+func (s *latencySampler) getMax() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.max
+}
+
+// memorySampler tracks the peak heap in use and goroutines during a run
+type memorySampler struct {
+	peakHeapInuse  uint64
+	peakGoroutines int64
+}
+
+func (m *memorySampler) sample() {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	if mem.HeapInuse > atomic.LoadUint64(&m.peakHeapInuse) {
+		atomic.StoreUint64(&m.peakHeapInuse, mem.HeapInuse)
+	}
+	if goroutines := int64(runtime.NumGoroutine()); goroutines > atomic.LoadInt64(&m.peakGoroutines) {
+		atomic.StoreInt64(&m.peakGoroutines, goroutines)
+	}
+}
+
+// loadReport holds the results of a load test run. It is written as json so
+// that runs can be compared against a baseline.
+type loadReport struct {
+	Targets          int     `json:"targets"`
+	Rate             int     `json:"rate"`
+	Workers          int     `json:"workers"`
+	DurationSecs     float64 `json:"duration_secs"`
+	EventsGenerated  uint64  `json:"events_generated"`
+	EventsDropped    uint64  `json:"events_dropped"`
+	RecordsDelivered uint64  `json:"records_delivered"`
+	RecordsFailed    uint64  `json:"records_failed"`
+	RecordsReceived  int     `json:"records_received"`
+	RecordsPerSec    float64 `json:"records_per_sec"`
+	BytesPerSec      float64 `json:"bytes_per_sec"`
+	LatencyP50Ms     float64 `json:"latency_p50_ms"`
+	LatencyP95Ms     float64 `json:"latency_p95_ms"`
+	LatencyP99Ms     float64 `json:"latency_p99_ms"`
+	LatencyMaxMs     float64 `json:"latency_max_ms"`
+	PeakHeapMB       float64 `json:"peak_heap_mb"`
+	PeakGoroutines   int64   `json:"peak_goroutines"`
+	BytesPerRecord   float64 `json:"allocated_bytes_per_record"`
+	AllocsPerRecord  float64 `json:"allocs_per_record"`
+}
+
+func (r *loadReport) String() string {
+	return fmt.Sprintf(
+		"%d targets at %d events/s over %d workers for %.0fs: %d events generated, %d dropped, "+
+			"%d records delivered, %d failed, %d received by the LEMF\n"+
+			"throughput %.0f records/s, %.0f KB/s\n"+
+			"latency p50 %.2fms, p95 %.2fms, p99 %.2fms, max %.2fms\n"+
+			"peak heap %.1f MB, peak %d goroutines, %.0f bytes and %.1f allocations per record",
+		r.Targets, r.Rate, r.Workers, r.DurationSecs, r.EventsGenerated, r.EventsDropped,
+		r.RecordsDelivered, r.RecordsFailed, r.RecordsReceived,
+		r.RecordsPerSec, r.BytesPerSec/1024,
+		r.LatencyP50Ms, r.LatencyP95Ms, r.LatencyP99Ms, r.LatencyMaxMs,
+		r.PeakHeapMB, r.PeakGoroutines, r.BytesPerRecord, r.AllocsPerRecord,
+	)
+}
+
+func writeReport(path string, report *loadReport) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+func readReport(path string) (*loadReport, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report := &loadReport{}
+	if err := json.Unmarshal(b, report); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %v", path, err)
+	}
+	return report, nil
+}
+
+// compareReports returns the regressions of a run over a baseline beyond a
+// tolerance, in percent. Throughput regresses when it drops, latency and
+// memory when they grow.
+func compareReports(baseline, report *loadReport, tolerance float64) []error {
+	var errs []error
+	lower := func(name string, base, value float64) {
+		if base > 0 && value < base*(1-tolerance/100) {
+			errs = append(errs, fmt.Errorf("%s dropped from %.2f to %.2f", name, base, value))
+		}
+	}
+	higher := func(name string, base, value float64) {
+		if base > 0 && value > base*(1+tolerance/100) {
+			errs = append(errs, fmt.Errorf("%s grew from %.2f to %.2f", name, base, value))
+		}
+	}
+	lower("throughput", baseline.RecordsPerSec, report.RecordsPerSec)
+	higher("p99 latency", baseline.LatencyP99Ms, report.LatencyP99Ms)
+	higher("peak heap", baseline.PeakHeapMB, report.PeakHeapMB)
+	higher("allocated bytes per record", baseline.BytesPerRecord, report.BytesPerRecord)
+	return errs
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/gofrs/uuid"
+)
+
+// maxBearers bounds the bearers a simulated target activates at once
+const maxBearers = 4
+
+// simTarget is a simulated subscriber, intercepted by a task of its own. Its
+// attach and bearer state is only changed by the generator, its sequence
+// numbers only by the worker it is assigned to.
+type simTarget struct {
+	task *models.NetworkProbeTask
+	key  string
+
+	attached   bool
+	bearers    int
+	sessions   int
+	nextSeqNbr uint32
+}
+
+func newSimTarget(i int) *simTarget {
+	xid := uuid.Must(uuid.NewV4())
+	return &simTarget{
+		task: &models.NetworkProbeTask{
+			TaskID: models.NetworkProbeTaskID(xid.String()),
+			TaskDetails: &models.NetworkProbeTaskDetails{
+				TargetID:      fmt.Sprintf("IMSI0010100%08d", i),
+				TargetType:    models.NetworkProbeTaskDetailsTargetTypeImsi,
+				DeliveryType:  models.NetworkProbeTaskDetailsDeliveryTypeAll,
+				CorrelationID: uint64(i) + 1,
+			},
+		},
+		key:        xid.String(),
+		nextSeqNbr: 1,
+	}
+}
+
+// nextEventType moves the target to a random state reachable from its
+// current one and returns the event reporting the transition. Detached
+// targets attach, attached ones activate, modify and deactivate bearers or
+// detach, dropping their bearers.
+func (t *simTarget) nextEventType(rnd *rand.Rand) string {
+	if !t.attached {
+		t.attached = true
+		return nprobe.AttachSuccess
+	}
+	if t.bearers == 0 {
+		if rnd.Intn(8) == 0 {
+			t.attached = false
+			return nprobe.DetachSuccess
+		}
+		t.bearers++
+		t.sessions++
+		return nprobe.SessionCreated
+	}
+	switch n := rnd.Intn(10); {
+	case n < 4:
+		return nprobe.SessionUpdated
+	case n < 6 && t.bearers < maxBearers:
+		t.bearers++
+		t.sessions++
+		return nprobe.SessionCreated
+	case n < 9:
+		t.bearers--
+		return nprobe.SessionTerminated
+	}
+	t.attached = false
+	t.bearers = 0
+	return nprobe.DetachSuccess
+}
+
+// nextEvent returns the next simulated event of the target
+func (t *simTarget) nextEvent(rnd *rand.Rand, now time.Time) eventdM.Event {
+	imsi := t.task.TaskDetails.TargetID
+	eventType := t.nextEventType(rnd)
+	return eventdM.Event{
+		EventType:  eventType,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  now.UTC().Format(time.RFC3339Nano),
+		Tag:        imsi,
+		Value: map[string]interface{}{
+			"imsi":       imsi,
+			"session_id": fmt.Sprintf("%s-%d", imsi, t.sessions),
+			"apn":        "internet",
+			"ip_addr":    fmt.Sprintf("192.168.%d.%d", 128+t.bearers, t.sessions%250+1),
+		},
+	}
+}
+
+// loadEvent is an event generated for a target, timed from its generation
+type loadEvent struct {
+	target      *simTarget
+	event       eventdM.Event
+	generatedAt time.Time
+}
+
+// generator offers the events of random targets to the workers at a
+// constant rate. A target is always assigned to the same worker so that its
+// records are encoded and delivered in order.
+type generator struct {
+	targets []*simTarget
+	workers []chan loadEvent
+	rnd     *rand.Rand
+
+	generated uint64
+	dropped   uint64
+}
+
+func newGenerator(targets []*simTarget, workers []chan loadEvent, seed int64) *generator {
+	return &generator{targets: targets, workers: workers, rnd: rand.New(rand.NewSource(seed))}
+}
+
+// run generates rate events per second until ctx is cancelled. Events are
+// dropped when their worker falls behind, the pipeline not keeping up with
+// the rate.
+func (g *generator) run(ctx context.Context, rate int) {
+	const tick = 10 * time.Millisecond
+	perTick := float64(rate) * tick.Seconds()
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	credit := 0.0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for credit += perTick; credit >= 1; credit-- {
+				i := g.rnd.Intn(len(g.targets))
+				target := g.targets[i]
+				event := loadEvent{target: target, event: target.nextEvent(g.rnd, now), generatedAt: time.Now()}
+				atomic.AddUint64(&g.generated, 1)
+				select {
+				case g.workers[i%len(g.workers)] <- event:
+				default:
+					atomic.AddUint64(&g.dropped, 1)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+
+	"github.com/golang/glog"
+)
+
+// workerStats are the counters of the records of the workers
+type workerStats struct {
+	encoded   uint64
+	delivered uint64
+	failed    uint64
+	bytes     uint64
+}
+
+// pendingRecords are the records of a target encoded in a pass of a worker
+type pendingRecords struct {
+	target      *simTarget
+	records     [][]byte
+	generatedAt []time.Time
+}
+
+// runWorker encodes the events of its targets and delivers their records
+// like the manager does on its passes: the events queued since the last
+// pass, up to maxBatch, are encoded, submitted at once per target and waited
+// for. The latency of each record is measured from the generation of its
+// event to its acknowledgement. The worker returns once events is closed
+// and drained.
+func runWorker(
+	ctx context.Context,
+	events chan loadEvent,
+	exp *exporter.RecordExporter,
+	maxBatch int,
+	stats *workerStats,
+	latencies *latencySampler,
+) {
+	for {
+		first, ok := <-events
+		if !ok {
+			return
+		}
+		pass := []loadEvent{first}
+	drain:
+		for len(pass) < maxBatch {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					break drain
+				}
+				pass = append(pass, event)
+			default:
+				break drain
+			}
+		}
+		deliver(ctx, exp, encodePass(pass, stats), stats, latencies)
+	}
+}
+
+// encodePass encodes the events of a pass, grouped by target in the order
+// they were generated
+func encodePass(pass []loadEvent, stats *workerStats) []*pendingRecords {
+	var ret []*pendingRecords
+	byTarget := map[*simTarget]*pendingRecords{}
+	for _, event := range pass {
+		pending, ok := byTarget[event.target]
+		if !ok {
+			pending = &pendingRecords{target: event.target}
+			byTarget[event.target] = pending
+			ret = append(ret, pending)
+		}
+		record, err := encoding.MakeRecord(&event.event, event.target.task, 0, event.target.nextSeqNbr)
+		if err != nil {
+			glog.Fatalf("Failed to encode simulated event %v: %v", event.event, err)
+		}
+		event.target.nextSeqNbr++
+		pending.records = append(pending.records, record)
+		pending.generatedAt = append(pending.generatedAt, event.generatedAt)
+		atomic.AddUint64(&stats.encoded, 1)
+		atomic.AddUint64(&stats.bytes, uint64(len(record)))
+	}
+	return ret
+}
+
+// deliver submits the records of each target and waits for their results
+func deliver(ctx context.Context, exp *exporter.RecordExporter, pass []*pendingRecords, stats *workerStats, latencies *latencySampler) {
+	deliveries := make([]*exporter.Delivery, len(pass))
+	for i, pending := range pass {
+		details := pending.target.task.TaskDetails
+		deliveries[i] = exp.SubmitRecords(ctx, pending.target.key, 1, details.CorrelationID, pending.records, 3)
+	}
+	for i, pending := range pass {
+		for j := range pending.records {
+			if err := deliveries[i].Wait(j); err != nil {
+				atomic.AddUint64(&stats.failed, 1)
+				continue
+			}
+			latencies.observe(time.Since(pending.generatedAt[j]))
+			atomic.AddUint64(&stats.delivered, 1)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiClient creates nprobe tasks and destinations through the orc8r REST
+// API so that they are validated as if provisioned by an operator
+type apiClient struct {
+	baseURL   string
+	networkID string
+	client    *http.Client
+}
+
+func newAPIClient(baseURL, networkID, crtFile, keyFile, caFile string) (*apiClient, error) {
+	tlsConfig := &tls.Config{}
+	if len(crtFile) != 0 {
+		cert, err := tls.LoadX509KeyPair(crtFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if len(caFile) != 0 {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+	}
+	return &apiClient{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		networkID: networkID,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+// createIfMissing creates an object of a collection unless an object with
+// the same ID exists, and returns true if it was created
+func (c *apiClient) createIfMissing(collection, id string, payload interface{}) (bool, error) {
+	objectURL := c.getURL(collection, id)
+	resp, err := c.client.Get(objectURL)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return false, nil
+	case http.StatusNotFound:
+	default:
+		return false, fmt.Errorf("GET %s: unexpected status %s", objectURL, resp.Status)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	collectionURL := c.getURL(collection, "")
+	resp, err = c.client.Post(collectionURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return false, fmt.Errorf("POST %s: unexpected status %s: %s", collectionURL, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return true, nil
+}
+
+func (c *apiClient) getURL(collection, id string) string {
+	u := fmt.Sprintf("%s/lte/%s/network_probe/%s", c.baseURL, url.PathEscape(c.networkID), collection)
+	if len(id) != 0 {
+		u += "/" + url.PathEscape(id)
+	}
+	return u
+}
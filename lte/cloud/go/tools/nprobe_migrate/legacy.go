@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v2"
+)
+
+const legacyIDPrefix = "legacy-"
+
+// legacyConfig is the part of a legacy liagentd gateway config describing
+// the static interception targets and the collector they are delivered to
+type legacyConfig struct {
+	ProxyAddr string         `yaml:"proxy_addr"`
+	ProxyPort int            `yaml:"proxy_port"`
+	Targets   []legacyTarget `yaml:"targets"`
+}
+
+// legacyTarget is a statically configured interception target
+type legacyTarget struct {
+	TargetID      string `yaml:"target_id"`
+	TargetType    string `yaml:"target_type"`
+	DeliveryType  string `yaml:"delivery_type"`
+	CorrelationID uint64 `yaml:"correlation_id"`
+	DomainID      string `yaml:"domain_id"`
+}
+
+// migration holds the destinations and tasks converted from legacy configs
+type migration struct {
+	Destinations []*models.NetworkProbeDestination
+	Tasks        []*models.NetworkProbeTask
+}
+
+func loadLegacyConfig(path string) (*legacyConfig, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &legacyConfig{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// convertLegacyConfigs converts the configs of several gateways. Targets
+// configured on several gateways are migrated once, with the settings of
+// the first gateway configuring them.
+func convertLegacyConfigs(configs map[string]*legacyConfig) (*migration, error) {
+	paths := make([]string, 0, len(configs))
+	for path := range configs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	ret := &migration{}
+	destinations := map[string]bool{}
+	tasks := map[string]*models.NetworkProbeTask{}
+	for _, path := range paths {
+		config := configs[path]
+		if len(config.ProxyAddr) != 0 {
+			destination := convertLegacyDestination(config)
+			if !destinations[string(destination.DestinationID)] {
+				destinations[string(destination.DestinationID)] = true
+				ret.Destinations = append(ret.Destinations, destination)
+			}
+		}
+
+		for _, target := range config.Targets {
+			task := convertLegacyTarget(target)
+			if err := task.ValidateModel(); err != nil {
+				return nil, fmt.Errorf("invalid target %s in %s: %v", target.TargetID, path, err)
+			}
+			existing, ok := tasks[string(task.TaskID)]
+			if !ok {
+				tasks[string(task.TaskID)] = task
+				ret.Tasks = append(ret.Tasks, task)
+				continue
+			}
+			if !reflect.DeepEqual(existing.TaskDetails, task.TaskDetails) {
+				glog.Warningf("Ignoring settings of target %s in %s conflicting with another gateway", target.TargetID, path)
+			}
+		}
+	}
+	return ret, nil
+}
+
+func convertLegacyDestination(config *legacyConfig) *models.NetworkProbeDestination {
+	address := net.JoinHostPort(config.ProxyAddr, strconv.Itoa(config.ProxyPort))
+	return &models.NetworkProbeDestination{
+		DestinationID: models.NetworkProbeDestinationID(makeLegacyID(address)),
+		DestinationDetails: &models.NetworkProbeDestinationDetails{
+			DeliveryAddress: address,
+			DeliveryType:    models.NetworkProbeDestinationDetailsDeliveryTypeAll,
+		},
+	}
+}
+
+// convertLegacyTarget converts a static target into a task. Legacy targets
+// are IMSIs delivered with all events unless configured otherwise.
+func convertLegacyTarget(target legacyTarget) *models.NetworkProbeTask {
+	details := &models.NetworkProbeTaskDetails{
+		TargetID:      target.TargetID,
+		TargetType:    strings.ToLower(target.TargetType),
+		DeliveryType:  target.DeliveryType,
+		CorrelationID: target.CorrelationID,
+		DomainID:      target.DomainID,
+	}
+	if len(details.TargetType) == 0 {
+		details.TargetType = models.NetworkProbeTaskDetailsTargetTypeImsi
+	}
+	if len(details.DeliveryType) == 0 {
+		details.DeliveryType = models.NetworkProbeTaskDetailsDeliveryTypeAll
+	}
+	return &models.NetworkProbeTask{
+		TaskID:      models.NetworkProbeTaskID(makeLegacyID(details.TargetType + "-" + details.TargetID)),
+		TaskDetails: details,
+	}
+}
+
+// makeLegacyID derives a stable ID from a legacy setting so that the
+// migration can be run again without creating duplicates
+func makeLegacyID(value string) string {
+	id := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(value))
+	return legacyIDPrefix + id
+}
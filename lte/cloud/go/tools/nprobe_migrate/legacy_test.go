@@ -0,0 +1,106 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertLegacyConfigs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nprobe_migrate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	gw1 := filepath.Join(dir, "gw1.yml")
+	assert.NoError(t, ioutil.WriteFile(gw1, []byte(`
+enable: true
+proxy_addr: 192.168.0.100
+proxy_port: 6666
+targets:
+  - target_id: IMSI001010000000001
+  - target_id: "+33612345678"
+    target_type: MSISDN
+    delivery_type: events_only
+    correlation_id: 42
+`), 0600))
+	gw2 := filepath.Join(dir, "gw2.yml")
+	assert.NoError(t, ioutil.WriteFile(gw2, []byte(`
+proxy_addr: 192.168.0.100
+proxy_port: 6666
+targets:
+  - target_id: IMSI001010000000001
+    delivery_type: events_only
+  - target_id: IMSI001010000000002
+`), 0600))
+
+	configs := map[string]*legacyConfig{}
+	for _, path := range []string{gw1, gw2} {
+		configs[path], err = loadLegacyConfig(path)
+		assert.NoError(t, err)
+	}
+	m, err := convertLegacyConfigs(configs)
+	assert.NoError(t, err)
+
+	// the collector shared by the gateways is migrated once
+	assert.Equal(t, []*models.NetworkProbeDestination{
+		{
+			DestinationID: "legacy-192-168-0-100-6666",
+			DestinationDetails: &models.NetworkProbeDestinationDetails{
+				DeliveryAddress: "192.168.0.100:6666",
+				DeliveryType:    "all",
+			},
+		},
+	}, m.Destinations)
+
+	// so are targets, with the settings of the first gateway
+	assert.Equal(t, []*models.NetworkProbeTask{
+		{
+			TaskID: "legacy-imsi-imsi001010000000001",
+			TaskDetails: &models.NetworkProbeTaskDetails{
+				TargetID:     "IMSI001010000000001",
+				TargetType:   "imsi",
+				DeliveryType: "all",
+			},
+		},
+		{
+			TaskID: "legacy-msisdn--33612345678",
+			TaskDetails: &models.NetworkProbeTaskDetails{
+				TargetID:      "+33612345678",
+				TargetType:    "msisdn",
+				DeliveryType:  "events_only",
+				CorrelationID: 42,
+			},
+		},
+		{
+			TaskID: "legacy-imsi-imsi001010000000002",
+			TaskDetails: &models.NetworkProbeTaskDetails{
+				TargetID:     "IMSI001010000000002",
+				TargetType:   "imsi",
+				DeliveryType: "all",
+			},
+		},
+	}, m.Tasks)
+
+	// invalid targets fail the migration
+	configs[gw2].Targets = append(configs[gw2].Targets, legacyTarget{TargetID: "+33-6123", TargetType: "msisdn"})
+	_, err = convertLegacyConfigs(configs)
+	assert.EqualError(t, err, "invalid target +33-6123 in "+gw2+": target_id +33-6123 is not a valid MSISDN")
+}
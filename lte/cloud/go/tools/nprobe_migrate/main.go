@@ -0,0 +1,107 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// nprobe_migrate converts the static interception targets of legacy
+// liagentd gateway configs into nprobe tasks, and the collector they were
+// delivered to into an nprobe destination. Objects are created through the
+// orc8r REST API and existing ones are left untouched, so the migration can
+// be run again, e.g. once more gateways are upgraded.
+//
+// Usage:
+//
+//	nprobe_migrate -api https://api.magma.test/magma/v1 -network lte_network \
+//	  -cert admin_operator.pem -key admin_operator.key.pem \
+//	  gw1/liagentd.yml gw2/liagentd.yml
+//
+// Legacy configs list their targets as follows, target_type defaults to imsi
+// and delivery_type to all:
+//
+//	proxy_addr: 192.168.0.100
+//	proxy_port: 6666
+//	targets:
+//	  - target_id: IMSI001010000000001
+//	  - target_id: "+33612345678"
+//	    target_type: msisdn
+//	    delivery_type: events_only
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/golang/glog"
+)
+
+func main() {
+	apiURL := flag.String("api", "", "orc8r REST API base URL, e.g. https://api.magma.test/magma/v1")
+	networkID := flag.String("network", "", "network the tasks and destinations are created in")
+	crtFile := flag.String("cert", "", "admin operator client certificate")
+	keyFile := flag.String("key", "", "admin operator client key")
+	caFile := flag.String("ca", "", "CA certificate of the API, the system CAs are used when not set")
+	dryRun := flag.Bool("dry-run", false, "print the converted tasks and destinations without creating them")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: nprobe_migrate [flags] LIAGENTD_CONFIG...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	configs := map[string]*legacyConfig{}
+	for _, path := range flag.Args() {
+		config, err := loadLegacyConfig(path)
+		if err != nil {
+			glog.Fatalf("Failed to load legacy config: %v", err)
+		}
+		configs[path] = config
+	}
+	m, err := convertLegacyConfigs(configs)
+	if err != nil {
+		glog.Fatalf("Failed to convert legacy configs: %v", err)
+	}
+
+	if *dryRun {
+		out, _ := json.MarshalIndent(m, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	if len(*apiURL) == 0 || len(*networkID) == 0 {
+		glog.Fatal("-api and -network are required unless -dry-run is set")
+	}
+	client, err := newAPIClient(*apiURL, *networkID, *crtFile, *keyFile, *caFile)
+	if err != nil {
+		glog.Fatalf("Failed to create API client: %v", err)
+	}
+
+	for _, destination := range m.Destinations {
+		created, err := client.createIfMissing("destinations", string(destination.DestinationID), destination)
+		printResult("destination", string(destination.DestinationID), created, err)
+	}
+	for _, task := range m.Tasks {
+		created, err := client.createIfMissing("tasks", string(task.TaskID), task)
+		printResult("task", string(task.TaskID), created, err)
+	}
+}
+
+func printResult(kind, id string, created bool, err error) {
+	switch {
+	case err != nil:
+		glog.Fatalf("Failed to migrate %s %s: %v", kind, id, err)
+	case created:
+		fmt.Printf("created %s %s\n", kind, id)
+	default:
+		fmt.Printf("skipped existing %s %s\n", kind, id)
+	}
+}
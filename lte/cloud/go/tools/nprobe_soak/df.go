@@ -0,0 +1,203 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/encoding"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang/glog"
+)
+
+// mockDF is a delivery function accepting records over tls. It acknowledges
+// each record and keepalive and checks that the records of each task arrive
+// without sequence gaps. Records delivered again, e.g. when an acknowledgement
+// is lost on a reconnection, are counted as duplicates.
+type mockDF struct {
+	listener net.Listener
+
+	mutex      sync.Mutex
+	conns      map[net.Conn]bool
+	lastSeqNbr map[uuid.UUID]uint32
+	received   uint64
+	duplicates uint64
+	gaps       uint64
+	malformed  uint64
+}
+
+// dfStats are the counters of the records received by the mock DF
+type dfStats struct {
+	received   uint64
+	duplicates uint64
+	gaps       uint64
+	malformed  uint64
+	conns      int
+}
+
+func newMockDF() (*mockDF, error) {
+	cert, err := newSelfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		return nil, err
+	}
+	df := &mockDF{
+		listener:   listener,
+		conns:      map[net.Conn]bool{},
+		lastSeqNbr: map[uuid.UUID]uint32{},
+	}
+	go df.accept()
+	return df, nil
+}
+
+// addr returns the address the mock DF listens on
+func (df *mockDF) addr() string {
+	return df.listener.Addr().String()
+}
+
+func (df *mockDF) accept() {
+	for {
+		conn, err := df.listener.Accept()
+		if err != nil {
+			return
+		}
+		df.mutex.Lock()
+		df.conns[conn] = true
+		df.mutex.Unlock()
+		go df.serve(conn)
+	}
+}
+
+// serve reads the PDUs of a connection until it is closed
+func (df *mockDF) serve(conn net.Conn) {
+	defer func() {
+		df.mutex.Lock()
+		delete(df.conns, conn)
+		df.mutex.Unlock()
+		conn.Close()
+	}()
+	for {
+		pdu, err := encoding.ReadPDU(conn, encoding.DefaultMaxRecordSize)
+		if err != nil {
+			return
+		}
+		hdr, err := encoding.ParsePDUHeader(pdu)
+		if err != nil {
+			glog.Errorf("Mock DF failed to parse PDU header: %v", err)
+			return
+		}
+		if hdr.PduType == encoding.HeaderPduTypeKeepaliveAck {
+			continue
+		}
+		if hdr.PduType != encoding.HeaderPduTypeKeepalive {
+			df.receive(pdu, hdr)
+		}
+		if _, err := conn.Write(encoding.MakeKeepaliveAck(hdr)); err != nil {
+			return
+		}
+	}
+}
+
+// receive checks the sequence number of a record against the last one
+// received for its task
+func (df *mockDF) receive(pdu []byte, hdr *encoding.EpsIRIHeader) {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	df.received++
+	seqNbr, ok := encoding.GetSequenceNumber(hdr)
+	if !ok || encoding.Validate(pdu) != nil {
+		df.malformed++
+		return
+	}
+	last := df.lastSeqNbr[hdr.XID]
+	switch {
+	case seqNbr <= last:
+		df.duplicates++
+		return
+	case seqNbr != last+1:
+		glog.Errorf("Sequence gap for XID %s: received %d after %d", hdr.XID, seqNbr, last)
+		df.gaps++
+	}
+	df.lastSeqNbr[hdr.XID] = seqNbr
+}
+
+// disconnect closes the current connections, the exporter reconnects on
+// the next record
+func (df *mockDF) disconnect() {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	for conn := range df.conns {
+		conn.Close()
+	}
+}
+
+// getLastSeqNbr returns the last sequence number received for a task
+func (df *mockDF) getLastSeqNbr(xid uuid.UUID) uint32 {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	return df.lastSeqNbr[xid]
+}
+
+func (df *mockDF) stats() dfStats {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	return dfStats{
+		received:   df.received,
+		duplicates: df.duplicates,
+		gaps:       df.gaps,
+		malformed:  df.malformed,
+		conns:      len(df.conns),
+	}
+}
+
+func (df *mockDF) close() {
+	df.listener.Close()
+	df.disconnect()
+}
+
+// newSelfSignedCertificate creates the server certificate of the mock DF,
+// which the exporter does not verify
+func newSelfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "nprobe-soak-df"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe"
+	"magma/lte/cloud/go/services/nprobe/encoding"
+	"magma/lte/cloud/go/services/nprobe/exporter"
+	"magma/lte/cloud/go/services/nprobe/obsidian/models"
+	eventdM "magma/orc8r/cloud/go/services/eventd/obsidian/models"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang/glog"
+)
+
+// eventCycle is the sequence of events each synthetic target goes through
+var eventCycle = []string{
+	nprobe.AttachSuccess,
+	nprobe.SessionCreated,
+	nprobe.SessionUpdated,
+	nprobe.SessionTerminated,
+	nprobe.DetachSuccess,
+}
+
+// soakTask is a synthetic interception task. Like the manager, it encodes
+// the events received since its last pass, submits their records at once
+// and delivers the records which failed again with the same sequence numbers
+// on its next pass.
+type soakTask struct {
+	task   *models.NetworkProbeTask
+	xid    uuid.UUID
+	events chan eventdM.Event
+
+	// pending are the records not delivered yet, in sequence order
+	pending    [][]byte
+	nextSeqNbr uint32
+	cycle      int
+
+	dropped   uint64
+	failures  uint64
+	delivered uint32
+}
+
+func newSoakTask(i int, bufferSize int) *soakTask {
+	xid := uuid.Must(uuid.NewV4())
+	imsi := fmt.Sprintf("IMSI0010100%08d", i)
+	return &soakTask{
+		task: &models.NetworkProbeTask{
+			TaskID: models.NetworkProbeTaskID(xid.String()),
+			TaskDetails: &models.NetworkProbeTaskDetails{
+				TargetID:      imsi,
+				TargetType:    models.NetworkProbeTaskDetailsTargetTypeImsi,
+				DeliveryType:  models.NetworkProbeTaskDetailsDeliveryTypeAll,
+				CorrelationID: uint64(i) + 1,
+			},
+		},
+		xid:        xid,
+		events:     make(chan eventdM.Event, bufferSize),
+		nextSeqNbr: 1,
+	}
+}
+
+// nextEvent returns the next synthetic event of the target of the task
+func (t *soakTask) nextEvent(now time.Time) eventdM.Event {
+	imsi := t.task.TaskDetails.TargetID
+	eventType := eventCycle[t.cycle%len(eventCycle)]
+	t.cycle++
+	return eventdM.Event{
+		EventType:  eventType,
+		StreamName: nprobe.ESStreamSessionD,
+		Timestamp:  now.UTC().Format(time.RFC3339Nano),
+		Tag:        imsi,
+		Value: map[string]interface{}{
+			"imsi":       imsi,
+			"session_id": fmt.Sprintf("%s-%d", imsi, t.cycle/len(eventCycle)),
+			"apn":        "internet",
+			"ip_addr":    "192.168.128.12",
+		},
+	}
+}
+
+// offer queues an event for the next pass of the task. Events are dropped
+// when the task falls behind, they are never encoded so this is no gap.
+func (t *soakTask) offer(event eventdM.Event) {
+	select {
+	case t.events <- event:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+// run processes the events of the task on each pass until ctx is cancelled
+func (t *soakTask) run(ctx context.Context, exp *exporter.RecordExporter, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t.encodeEvents()
+		t.deliver(ctx, exp)
+	}
+}
+
+// encodeEvents encodes the queued events after the pending records
+func (t *soakTask) encodeEvents() {
+	for {
+		select {
+		case event := <-t.events:
+			record, err := encoding.MakeRecord(&event, t.task, 0, t.nextSeqNbr)
+			if err != nil {
+				glog.Fatalf("Failed to encode synthetic event %v: %v", event, err)
+			}
+			t.pending = append(t.pending, record)
+			atomic.AddUint32(&t.nextSeqNbr, 1)
+		default:
+			return
+		}
+	}
+}
+
+// deliver submits the pending records and keeps the ones which failed
+func (t *soakTask) deliver(ctx context.Context, exp *exporter.RecordExporter) {
+	if len(t.pending) == 0 {
+		return
+	}
+	delivery := exp.SubmitRecords(ctx, t.xid.String(), 1, t.task.TaskDetails.CorrelationID, t.pending, 3)
+	for i := range t.pending {
+		if err := delivery.Wait(i); err != nil {
+			atomic.AddUint64(&t.failures, 1)
+			t.pending = t.pending[i:]
+			atomic.AddUint32(&t.delivered, uint32(i))
+			return
+		}
+	}
+	atomic.AddUint32(&t.delivered, uint32(len(t.pending)))
+	t.pending = nil
+}
+
+// isDrained returns true once all the events offered to the task are delivered
+func (t *soakTask) isDrained() bool {
+	return len(t.events) == 0 && atomic.LoadUint32(&t.delivered) == t.encoded()
+}
+
+// encoded returns the number of records encoded by the task
+func (t *soakTask) encoded() uint32 {
+	return atomic.LoadUint32(&t.nextSeqNbr) - 1
+}
+
+// getDelivered returns the number of records of the task known to be delivered
+func (t *soakTask) getDelivered() uint32 {
+	return atomic.LoadUint32(&t.delivered)
+}
+
+// runFirehose offers events to the tasks in turn at the given rate per
+// second until ctx is cancelled
+func runFirehose(ctx context.Context, tasks []*soakTask, rate int) {
+	const tick = 10 * time.Millisecond
+	perTick := float64(rate) * tick.Seconds()
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	credit := 0.0
+	next := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for credit += perTick; credit >= 1; credit-- {
+				task := tasks[next%len(tasks)]
+				task.offer(task.nextEvent(now))
+				next++
+			}
+		}
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2020 The Magma Authors.
+
+This source code is licensed under the BSD-style license found in the
+LICENSE file in the root directory of this source tree.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// nprobe_soak runs the nprobe record pipeline for hours to catch the leaks
+// and drift the unit tests can't. A synthetic firehose feeds the events of
+// many targets to tasks which encode and submit their records like the
+// manager does. The records are exported over acknowledged tls delivery to a
+// mock delivery function which validates them and periodically drops the
+// connections.
+//
+// The soak test fails, exiting with status 1, when
+//   - the records of a task reach the mock DF with a sequence gap,
+//   - a record is malformed or not delivered once the firehose stops,
+//   - the heap in use exceeds -max-heap-mb,
+//   - the goroutines outgrow the count measured after -warmup by more
+//     than -max-goroutine-growth.
+//
+// Usage:
+//
+//	nprobe_soak -duration 6h -tasks 200 -rate 1000 -logtostderr
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"magma/lte/cloud/go/services/nprobe/exporter"
+
+	"github.com/golang/glog"
+)
+
+// soakConfig holds the settings of a soak test run
+type soakConfig struct {
+	duration           time.Duration
+	tasks              int
+	rate               int
+	passInterval       time.Duration
+	taskBuffer         int
+	disconnectInterval time.Duration
+	checkInterval      time.Duration
+	warmup             time.Duration
+	drainTimeout       time.Duration
+	maxHeapMB          uint64
+	maxGoroutineGrowth int
+}
+
+func main() {
+	var config soakConfig
+	flag.DurationVar(&config.duration, "duration", 4*time.Hour, "time the firehose runs for")
+	flag.IntVar(&config.tasks, "tasks", 100, "number of synthetic tasks")
+	flag.IntVar(&config.rate, "rate", 500, "events generated per second across all tasks")
+	flag.DurationVar(&config.passInterval, "pass-interval", time.Second, "time between the passes of a task")
+	flag.IntVar(&config.taskBuffer, "task-buffer", 1000, "events buffered per task between passes")
+	flag.DurationVar(&config.disconnectInterval, "disconnect-interval", 10*time.Minute, "time between connection drops by the mock DF, 0 disables them")
+	flag.DurationVar(&config.checkInterval, "check-interval", time.Minute, "time between memory and goroutine checks")
+	flag.DurationVar(&config.warmup, "warmup", 5*time.Minute, "time after which the goroutine baseline is measured")
+	flag.DurationVar(&config.drainTimeout, "drain-timeout", 2*time.Minute, "time given to deliver the last records once the firehose stops")
+	flag.Uint64Var(&config.maxHeapMB, "max-heap-mb", 512, "maximum heap in use")
+	flag.IntVar(&config.maxGoroutineGrowth, "max-goroutine-growth", 50, "maximum goroutines started over the baseline")
+	flag.Parse()
+
+	if config.tasks <= 0 || config.rate <= 0 || config.passInterval <= 0 || config.checkInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: nprobe_soak [flags]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if errs := runSoak(config); len(errs) != 0 {
+		for _, err := range errs {
+			glog.Errorf("Soak test failed: %v", err)
+		}
+		glog.Flush()
+		os.Exit(1)
+	}
+	glog.Info("Soak test passed")
+	glog.Flush()
+}
+
+// runSoak runs the soak test and returns the failures detected
+func runSoak(config soakConfig) []error {
+	df, err := newMockDF()
+	if err != nil {
+		return []error{fmt.Errorf("failed to start mock DF: %v", err)}
+	}
+	defer df.close()
+
+	backend := exporter.NewTLSBackend(df.addr(), &tls.Config{InsecureSkipVerify: true}, exporter.TLSBackendConfig{
+		KeepaliveInterval: 10 * time.Second,
+		AckTimeout:        5 * time.Second,
+	})
+	exp := exporter.NewRecordExporter(backend)
+	defer exp.Close()
+
+	tasks := make([]*soakTask, config.tasks)
+	for i := range tasks {
+		tasks[i] = newSoakTask(i, config.taskBuffer)
+	}
+	tasksCtx, stopTasks := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task *soakTask) {
+			defer wg.Done()
+			task.run(tasksCtx, exp, config.passInterval)
+		}(task)
+	}
+
+	firehoseCtx, stopFirehose := context.WithTimeout(context.Background(), config.duration)
+	defer stopFirehose()
+	go runFirehose(firehoseCtx, tasks, config.rate)
+	if config.disconnectInterval > 0 {
+		go runDisconnects(firehoseCtx, df, config.disconnectInterval)
+	}
+
+	glog.Infof("Soaking %d tasks at %d events/s for %v, mock DF at %s", config.tasks, config.rate, config.duration, df.addr())
+	errs := monitor(firehoseCtx, config, df, tasks)
+	stopFirehose()
+
+	// the records of the last events are delivered before checking them
+	deadline := time.Now().Add(config.drainTimeout)
+	for !areDrained(tasks) && time.Now().Before(deadline) {
+		time.Sleep(config.passInterval)
+	}
+	stopTasks()
+	wg.Wait()
+	return append(errs, checkDelivery(df, tasks)...)
+}
+
+// monitor periodically checks the heap and goroutines until ctx is done
+func monitor(ctx context.Context, config soakConfig, df *mockDF, tasks []*soakTask) []error {
+	var errs []error
+	start := time.Now()
+	baseline := 0
+	ticker := time.NewTicker(config.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return errs
+		case <-ticker.C:
+		}
+
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		goroutines := runtime.NumGoroutine()
+		stats := df.stats()
+		glog.Infof(
+			"After %v: heap %d MB, %d goroutines, %d records received, %d duplicates, %d gaps, %d malformed, %d events dropped, %d delivery failures",
+			time.Since(start).Truncate(time.Second), mem.HeapInuse>>20, goroutines,
+			stats.received, stats.duplicates, stats.gaps, stats.malformed, countDropped(tasks), countFailures(tasks),
+		)
+
+		if mem.HeapInuse>>20 > config.maxHeapMB {
+			errs = append(errs, fmt.Errorf("heap in use %d MB exceeds %d MB", mem.HeapInuse>>20, config.maxHeapMB))
+		}
+		if baseline == 0 && time.Since(start) >= config.warmup {
+			baseline = goroutines
+			glog.Infof("Goroutine baseline set to %d", baseline)
+		} else if baseline != 0 && goroutines > baseline+config.maxGoroutineGrowth {
+			errs = append(errs, fmt.Errorf("%d goroutines outgrew the baseline of %d", goroutines, baseline))
+		}
+		if stats.gaps != 0 || stats.malformed != 0 {
+			// no point soaking further, the records are checked once drained
+			return errs
+		}
+	}
+}
+
+// checkDelivery verifies that the records of every task reached the mock DF
+func checkDelivery(df *mockDF, tasks []*soakTask) []error {
+	var errs []error
+	for _, task := range tasks {
+		encoded := task.encoded()
+		if received := df.getLastSeqNbr(task.xid); received != encoded {
+			errs = append(errs, fmt.Errorf("task %s encoded %d records, mock DF received up to %d", task.xid, encoded, received))
+		}
+	}
+	stats := df.stats()
+	if stats.gaps != 0 || stats.malformed != 0 {
+		errs = append(errs, fmt.Errorf("%d sequence gaps and %d malformed records", stats.gaps, stats.malformed))
+	}
+	glog.Infof("Mock DF received %d records, %d duplicates", stats.received, stats.duplicates)
+	return errs
+}
+
+// runDisconnects drops the connections of the mock DF periodically
+func runDisconnects(ctx context.Context, df *mockDF, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			glog.Info("Mock DF dropping its connections")
+			df.disconnect()
+		}
+	}
+}
+
+func areDrained(tasks []*soakTask) bool {
+	for _, task := range tasks {
+		if !task.isDrained() {
+			return false
+		}
+	}
+	return true
+}
+
+func countDropped(tasks []*soakTask) uint64 {
+	var dropped uint64
+	for _, task := range tasks {
+		dropped += atomic.LoadUint64(&task.dropped)
+	}
+	return dropped
+}
+
+func countFailures(tasks []*soakTask) uint64 {
+	var failures uint64
+	for _, task := range tasks {
+		failures += atomic.LoadUint64(&task.failures)
+	}
+	return failures
+}